@@ -0,0 +1,127 @@
+// Package benchgate compares two `go test -bench` outputs and reports
+// benchmarks whose cost regressed beyond a threshold. It complements benchstat:
+// benchstat produces the human-readable comparison, benchgate turns it into a
+// pass/fail CI gate without requiring extra tooling.
+package benchgate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result aggregates the samples recorded for a single benchmark.
+type Result struct {
+	Name string
+	// Metrics maps units (ns/op, B/op, allocs/op, custom) to the mean value
+	// across all samples found for the benchmark.
+	Metrics map[string]float64
+	samples map[string]int
+}
+
+// Regression describes a metric that grew beyond the allowed threshold.
+type Regression struct {
+	Name  string
+	Unit  string
+	Base  float64
+	Head  float64
+	Delta float64 // relative change, 0.10 == +10%
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s %s: %.2f -> %.2f (%+.1f%%)", r.Name, r.Unit, r.Base, r.Head, r.Delta*100)
+}
+
+// Parse reads benchmark lines produced by `go test -bench`. Repeated runs
+// (-count=N) of the same benchmark are averaged. The GOMAXPROCS suffix is
+// stripped so results from different machines line up.
+func Parse(r io.Reader) (map[string]*Result, error) {
+	results := map[string]*Result{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		name := trimProcs(fields[0])
+		res := results[name]
+		if res == nil {
+			res = &Result{Name: name, Metrics: map[string]float64{}, samples: map[string]int{}}
+			results[name] = res
+		}
+		for i := 2; i+1 < len(fields); i += 2 {
+			val, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("benchgate: %s: parse %q: %w", name, fields[i], err)
+			}
+			unit := fields[i+1]
+			n := res.samples[unit]
+			res.Metrics[unit] = (res.Metrics[unit]*float64(n) + val) / float64(n+1)
+			res.samples[unit] = n + 1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("benchgate: read: %w", err)
+	}
+	return results, nil
+}
+
+// Compare returns metrics listed in units whose head value exceeds base by
+// more than threshold (0.10 == 10%). Benchmarks missing from either side are
+// ignored. Results are sorted by name then unit for stable output.
+func Compare(base, head map[string]*Result, threshold float64, units ...string) []Regression {
+	if len(units) == 0 {
+		units = []string{"ns/op", "allocs/op"}
+	}
+	var out []Regression
+	for name, h := range head {
+		b, ok := base[name]
+		if !ok {
+			continue
+		}
+		for _, unit := range units {
+			bv, okB := b.Metrics[unit]
+			hv, okH := h.Metrics[unit]
+			if !okB || !okH {
+				continue
+			}
+			var delta float64
+			switch {
+			case bv == 0 && hv == 0:
+				continue
+			case bv == 0:
+				delta = 1
+			default:
+				delta = (hv - bv) / bv
+			}
+			if delta > threshold {
+				out = append(out, Regression{Name: name, Unit: unit, Base: bv, Head: hv, Delta: delta})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Unit < out[j].Unit
+	})
+	return out
+}
+
+func trimProcs(name string) string {
+	idx := strings.LastIndex(name, "-")
+	if idx <= 0 {
+		return name
+	}
+	if _, err := strconv.Atoi(name[idx+1:]); err != nil {
+		return name
+	}
+	return name[:idx]
+}
@@ -0,0 +1,67 @@
+package benchgate
+
+import (
+	"strings"
+	"testing"
+)
+
+const baseOutput = `goos: linux
+goarch: amd64
+pkg: github.com/cexll/agentsdk-go/bench
+BenchmarkAgentLoopIteration-8   	   1000	     16000 ns/op	    9600 B/op	     120 allocs/op
+BenchmarkAgentLoopIteration-8   	   1000	     18000 ns/op	    9600 B/op	     120 allocs/op
+BenchmarkSettingsLoad-8         	   1000	     30000 ns/op	    3200 B/op	      60 allocs/op
+PASS
+`
+
+const headOutput = `BenchmarkAgentLoopIteration-16  	   1000	     17500 ns/op	    4800 B/op	      60 allocs/op
+BenchmarkSettingsLoad-16        	   1000	     45000 ns/op	    3200 B/op	      60 allocs/op
+BenchmarkNew-16                 	   1000	       100 ns/op
+`
+
+func TestParseAveragesSamplesAndStripsProcs(t *testing.T) {
+	res, err := Parse(strings.NewReader(baseOutput))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	loop := res["BenchmarkAgentLoopIteration"]
+	if loop == nil {
+		t.Fatalf("missing benchmark, got %v", res)
+	}
+	if got := loop.Metrics["ns/op"]; got != 17000 {
+		t.Fatalf("expected averaged ns/op 17000, got %v", got)
+	}
+	if got := loop.Metrics["allocs/op"]; got != 120 {
+		t.Fatalf("unexpected allocs/op %v", got)
+	}
+}
+
+func TestCompareReportsRegressions(t *testing.T) {
+	base, err := Parse(strings.NewReader(baseOutput))
+	if err != nil {
+		t.Fatalf("parse base: %v", err)
+	}
+	head, err := Parse(strings.NewReader(headOutput))
+	if err != nil {
+		t.Fatalf("parse head: %v", err)
+	}
+	regs := Compare(base, head, 0.10)
+	if len(regs) != 1 {
+		t.Fatalf("expected one regression, got %v", regs)
+	}
+	if regs[0].Name != "BenchmarkSettingsLoad" || regs[0].Unit != "ns/op" {
+		t.Fatalf("unexpected regression %v", regs[0])
+	}
+	if !strings.Contains(regs[0].String(), "+50.0%") {
+		t.Fatalf("unexpected format %q", regs[0].String())
+	}
+	if regs := Compare(base, head, 0.60); len(regs) != 0 {
+		t.Fatalf("expected no regressions above 60%%, got %v", regs)
+	}
+}
+
+func TestParseRejectsMalformedMetric(t *testing.T) {
+	if _, err := Parse(strings.NewReader("BenchmarkX-8 10 abc ns/op\n")); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
@@ -0,0 +1,63 @@
+// Command benchgate fails when benchmarks in a head run regress against a
+// baseline run:
+//
+//	go test ./bench -bench . -count 5 > base.txt
+//	go test ./bench -bench . -count 5 > head.txt
+//	go run ./bench/cmd/benchgate -threshold 0.15 base.txt head.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/bench/benchgate"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(argv []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("benchgate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	threshold := flags.Float64("threshold", 0.10, "Maximum allowed relative regression (0.10 == 10%)")
+	units := flags.String("units", "ns/op,allocs/op", "Comma separated metrics to gate on")
+	if err := flags.Parse(argv); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("usage: benchgate [-threshold 0.10] base.txt head.txt")
+	}
+	base, err := parseFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	head, err := parseFile(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	regs := benchgate.Compare(base, head, *threshold, strings.Split(*units, ",")...)
+	if len(regs) == 0 {
+		fmt.Fprintln(stdout, "benchgate: no regressions")
+		return nil
+	}
+	for _, r := range regs {
+		fmt.Fprintln(stdout, r.String())
+	}
+	return fmt.Errorf("benchgate: %d regression(s) above %.0f%%", len(regs), *threshold*100)
+}
+
+func parseFile(path string) (map[string]*benchgate.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return benchgate.Parse(f)
+}
@@ -0,0 +1,133 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/agent"
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+)
+
+// loopModel emits one tool call per iteration until toolRounds is reached.
+// Outputs are preallocated so allocations reported by the benchmark belong
+// to the agent loop rather than the model stub.
+type loopModel struct {
+	toolRounds int
+	call       *agent.ModelOutput
+	done       *agent.ModelOutput
+}
+
+func newLoopModel(rounds int) loopModel {
+	return loopModel{
+		toolRounds: rounds,
+		call: &agent.ModelOutput{ToolCalls: []agent.ToolCall{{
+			ID:    "call",
+			Name:  "echo",
+			Input: map[string]any{"text": "hi"},
+		}}},
+		done: &agent.ModelOutput{Content: "done", Done: true},
+	}
+}
+
+func (m loopModel) Generate(_ context.Context, c *agent.Context) (*agent.ModelOutput, error) {
+	if c.Iteration >= m.toolRounds {
+		return m.done, nil
+	}
+	return m.call, nil
+}
+
+type echoExecutor struct{}
+
+func (echoExecutor) Execute(_ context.Context, call agent.ToolCall, _ *agent.Context) (agent.ToolResult, error) {
+	return agent.ToolResult{Name: call.Name, Output: "hi"}, nil
+}
+
+// BenchmarkAgentLoopIteration measures the loop overhead per iteration with
+// a trivial model and tool so the numbers reflect the runtime itself.
+func BenchmarkAgentLoopIteration(b *testing.B) {
+	const rounds = 10
+	chain := middleware.NewChain([]middleware.Middleware{middleware.Funcs{Identifier: "noop"}})
+	ag, err := agent.New(newLoopModel(rounds), echoExecutor{}, agent.Options{
+		MaxIterations: rounds + 1,
+		Middleware:    chain,
+	})
+	if err != nil {
+		b.Fatalf("agent: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := ag.Run(context.Background(), agent.NewContext()); err != nil {
+			b.Fatalf("run: %v", err)
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*(rounds+1)), "ns/iteration")
+}
+
+func BenchmarkMiddlewareChainDispatch(b *testing.B) {
+	mws := make([]middleware.Middleware, 0, 8)
+	for i := 0; i < 8; i++ {
+		mws = append(mws, middleware.Funcs{
+			Identifier: "mw",
+			OnBeforeModel: func(context.Context, *middleware.State) error {
+				return nil
+			},
+		})
+	}
+	chain := middleware.NewChain(mws)
+	st := &middleware.State{Values: map[string]any{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := chain.Execute(context.Background(), middleware.StageBeforeModel, st); err != nil {
+			b.Fatalf("execute: %v", err)
+		}
+	}
+}
+
+func BenchmarkSettingsLoad(b *testing.B) {
+	root := b.TempDir()
+	b.Setenv("HOME", root)
+	writeSettingsFile(b, root)
+	loader := &config.SettingsLoader{ProjectRoot: root}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := loader.Load(); err != nil {
+			b.Fatalf("load: %v", err)
+		}
+	}
+}
+
+// BenchmarkStreamEventSSEEncode mirrors the data-frame encoding performed by
+// SSE servers that forward RunStream events.
+func BenchmarkStreamEventSSEEncode(b *testing.B) {
+	idx := 0
+	evt := api.StreamEvent{
+		Type:  api.EventContentBlockDelta,
+		Index: &idx,
+		Delta: &api.Delta{Type: "text_delta", Text: "streamed token fragment"},
+	}
+	var buf bytes.Buffer
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		buf.Reset()
+		payload, err := json.Marshal(evt)
+		if err != nil {
+			b.Fatalf("marshal: %v", err)
+		}
+		buf.WriteString("data: ")
+		buf.Write(payload)
+		buf.WriteString("\n\n")
+	}
+}
@@ -0,0 +1,132 @@
+// Command loadtest drives an agentsdk HTTP server (see examples/03-http) with
+// concurrent /v1/run or /v1/run/stream calls and prints latency, error and
+// cost figures.
+//
+//	loadtest -url http://localhost:8080 -stream -c 8 -duration 1m -prompts prompts.txt
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/loadtest"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(argv []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("agentsdk-loadtest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	baseURL := flags.String("url", "", "Server base URL, e.g. http://localhost:8080")
+	stream := flags.Bool("stream", false, "Use /v1/run/stream and measure time to first event")
+	concurrency := flags.Int("c", 4, "Concurrent workers")
+	requests := flags.Int("n", 0, "Total requests (0 = one round of prompts unless -duration is set)")
+	duration := flags.Duration("duration", 0, "Test duration")
+	timeout := flags.Duration("timeout", 2*time.Minute, "Per-request timeout")
+	promptsFile := flags.String("prompts", "", "File with one prompt per line")
+	sessionPrefix := flags.String("session-prefix", "loadtest", "Session ID prefix")
+	sharedSession := flags.Bool("shared-session", false, "Send every request on the same session")
+	inputPrice := flags.Float64("input-price", 0, "USD per million input tokens (0 = use server-reported cost)")
+	outputPrice := flags.Float64("output-price", 0, "USD per million output tokens")
+	asJSON := flags.Bool("json", false, "Print the report as JSON")
+
+	var prompts multiValue
+	flags.Var(&prompts, "prompt", "Prompt literal (repeatable)")
+	var headers multiValue
+	flags.Var(&headers, "header", "Extra request header 'Key: Value' (repeatable)")
+
+	if err := flags.Parse(argv); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*baseURL) == "" {
+		return errors.New("-url is required")
+	}
+	if *promptsFile != "" {
+		lines, err := readPrompts(*promptsFile)
+		if err != nil {
+			return err
+		}
+		prompts = append(prompts, lines...)
+	}
+	header := http.Header{}
+	for _, h := range headers {
+		key, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return fmt.Errorf("invalid header %q", h)
+		}
+		header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+
+	cfg := loadtest.Config{
+		Concurrency:   *concurrency,
+		Requests:      *requests,
+		Duration:      *duration,
+		Prompts:       prompts,
+		SessionPrefix: *sessionPrefix,
+		SharedSession: *sharedSession,
+		Timeout:       *timeout,
+	}
+	if *inputPrice > 0 || *outputPrice > 0 {
+		in, out := *inputPrice, *outputPrice
+		cfg.CostFunc = func(u model.Usage) float64 {
+			return (float64(u.InputTokens)*in + float64(u.OutputTokens)*out) / 1e6
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := loadtest.Run(ctx, loadtest.HTTPTarget{BaseURL: *baseURL, Stream: *stream, Header: header}, cfg)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return report.WriteText(stdout)
+}
+
+func readPrompts(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open prompts: %w", err)
+	}
+	defer f.Close()
+	var out []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			out = append(out, line)
+		}
+	}
+	return out, scanner.Err()
+}
+
+type multiValue []string
+
+func (m *multiValue) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *multiValue) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
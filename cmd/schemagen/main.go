@@ -0,0 +1,48 @@
+// Command schemagen writes the JSON Schemas of the stream-event, envelope
+// and session-transcript wire formats (see api.JSONSchemas) so non-Go
+// clients can generate their types. It runs from `go generate ./pkg/api`.
+//
+//	schemagen -out schemas
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(argv []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("agentsdk-schemagen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	out := flags.String("out", "schemas", "Directory the versioned schema files are written under")
+	if err := flags.Parse(argv); err != nil {
+		return err
+	}
+
+	files, err := api.JSONSchemas()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		path := filepath.Join(*out, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("create schema dir: %w", err)
+		}
+		if err := os.WriteFile(path, f.Data, 0o644); err != nil {
+			return fmt.Errorf("write schema: %w", err)
+		}
+		fmt.Fprintln(stdout, path)
+	}
+	return nil
+}
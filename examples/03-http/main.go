@@ -12,13 +12,21 @@ import (
 	"time"
 
 	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/artifact"
+	"github.com/cexll/agentsdk-go/pkg/core/tracecontext"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
 	modelpkg "github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/session"
 )
 
 const (
 	defaultAddr       = ":8080"
 	defaultModel      = "claude-3-5-sonnet-20241022"
 	defaultRunTimeout = 60 * time.Minute // 60分钟，适配 codex 等长时间任务
+	// maxConcurrentRuns bounds the model calls in flight; excess requests
+	// queue for up to runQueueTimeout and then get 503.
+	maxConcurrentRuns = 16
+	runQueueTimeout   = 30 * time.Second
 )
 
 func main() {
@@ -31,11 +39,37 @@ func main() {
 		log.Fatalf("resolve project root: %v", err)
 	}
 
+	artifacts, err := artifact.NewFileStore(filepath.Join(projectRoot, ".claude", "artifacts"))
+	if err != nil {
+		log.Fatalf("artifact store: %v", err)
+	}
+
+	// Replicas behind a load balancer share sessions through Redis when
+	// AGENTSDK_REDIS_ADDR is set; otherwise history stays on local disk.
+	var sessions session.Store
+	if redisAddr := os.Getenv("AGENTSDK_REDIS_ADDR"); redisAddr != "" {
+		store, err := session.NewRedisStore(context.Background(), session.RedisOptions{
+			Addr:     redisAddr,
+			Password: os.Getenv("AGENTSDK_REDIS_PASSWORD"),
+			TTL:      7 * 24 * time.Hour,
+		})
+		if err != nil {
+			log.Fatalf("redis session store: %v", err)
+		}
+		defer store.Close()
+		sessions = store
+	}
+
 	runtime, err := api.New(context.Background(), api.Options{
-		EntryPoint:   api.EntryPointPlatform,
-		ProjectRoot:  projectRoot,
-		ModelFactory: &modelpkg.AnthropicProvider{ModelName: modelName},
-		Timeout:      defaultRunTimeout,
+		EntryPoint:    api.EntryPointPlatform,
+		ProjectRoot:   projectRoot,
+		ModelFactory:  &modelpkg.AnthropicProvider{ModelName: modelName},
+		Timeout:       defaultRunTimeout,
+		ArtifactStore: artifacts,
+		SessionStore:  sessions,
+
+		MaxConcurrentRuns: maxConcurrentRuns,
+		RunQueueTimeout:   runQueueTimeout,
 	})
 	if err != nil {
 		log.Fatalf("build runtime: %v", err)
@@ -51,13 +85,24 @@ func main() {
 		runtime:        runtime,
 		defaultTimeout: defaultRunTimeout,
 		staticDir:      staticDir,
+		adminToken:     os.Getenv("AGENTSDK_ADMIN_TOKEN"),
 	}
 	mux := http.NewServeMux()
 	srv.registerRoutes(mux)
 
+	// Inbound traceparent/tracestate headers are joined (or a new trace is
+	// started) and echoed as Traceresponse / X-Trace-Id; runs forward them to
+	// the model provider and MCP servers.
+	// Polling clients re-sending the same GET, or a POST marked with
+	// X-Agentsdk-Cacheable, are answered from cache with ETag/304 support.
+	cache := middleware.NewHTTPCache(middleware.HTTPCacheConfig{
+		TTL:    30 * time.Second,
+		Routes: map[string]time.Duration{"/health": 0, "/v1/run/stream": 0, "/v1/run/events": 0, "/v1/runs": 0, "/v1/uploads": 0, "/v1/admin/": 0},
+	})
+
 	server := &http.Server{
 		Addr:              addr,
-		Handler:           mux,
+		Handler:           tracecontext.Handler(cache.Handler(mux)),
 		ReadHeaderTimeout: 5 * time.Second,
 		IdleTimeout:       60 * time.Second,
 	}
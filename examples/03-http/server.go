@@ -6,11 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/artifact"
+	"github.com/cexll/agentsdk-go/pkg/core/tracecontext"
 	modelpkg "github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
 )
 
 const (
@@ -22,6 +27,7 @@ type httpServer struct {
 	runtime        *api.Runtime
 	defaultTimeout time.Duration
 	staticDir      string
+	adminToken     string
 }
 
 func (s *httpServer) registerRoutes(mux *http.ServeMux) {
@@ -29,6 +35,19 @@ func (s *httpServer) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/health", s.handleHealth)
 	mux.HandleFunc("/v1/run", s.handleRun)
 	mux.HandleFunc("/v1/run/stream", s.handleStream)
+	mux.HandleFunc("POST /v1/run/events", s.handleEvents)
+	mux.HandleFunc("GET /v1/runs/{id}/events", s.handleResume)
+	mux.HandleFunc("GET /v1/runs", s.handleListRuns)
+	mux.HandleFunc("DELETE /v1/runs/{id}", s.handleCancelRun)
+	if store := s.runtime.ArtifactStore(); store != nil {
+		mux.Handle("/v1/artifacts/", http.StripPrefix("/v1/artifacts/", artifact.Handler(store)))
+	}
+	if uploads, err := s.runtime.UploadHandler(); err == nil {
+		mux.Handle("/v1/uploads", uploads)
+	}
+	if admin, err := s.runtime.AdminHandler(s.adminToken); err == nil {
+		mux.Handle("/v1/admin/", http.StripPrefix("/v1/admin", admin))
+	}
 
 	// Static files
 	fs := http.FileServer(http.Dir(s.staticDir))
@@ -63,7 +82,7 @@ func (s *httpServer) handleRun(w http.ResponseWriter, r *http.Request) {
 		s.writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
 		return
 	}
-	if req.Prompt == "" {
+	if req.Prompt == "" && len(req.AttachmentIDs) == 0 {
 		s.writeJSON(w, http.StatusBadRequest, errorResponse{"prompt is required"})
 		return
 	}
@@ -76,10 +95,22 @@ func (s *httpServer) handleRun(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	resp, err := s.runtime.Run(ctx, api.Request{
-		Prompt:    req.Prompt,
-		SessionID: sessionID,
+		Prompt:        req.Prompt,
+		SessionID:     sessionID,
+		RequestID:     req.RunID,
+		AttachmentIDs: req.AttachmentIDs,
 	})
+	if errors.Is(err, api.ErrRunCanceled) {
+		s.writeJSON(w, http.StatusConflict, errorResponse{err.Error()})
+		return
+	}
+	if errors.Is(err, api.ErrQueueFull) {
+		w.Header().Set("Retry-After", "5")
+		s.writeJSON(w, http.StatusServiceUnavailable, errorResponse{err.Error()})
+		return
+	}
 	if err != nil {
+		log.Printf("run failed session=%s trace_id=%s: %v", sessionID, traceID(ctx), err)
 		s.writeJSON(w, http.StatusBadGateway, errorResponse{err.Error()})
 		return
 	}
@@ -91,10 +122,12 @@ func (s *httpServer) handleRun(w http.ResponseWriter, r *http.Request) {
 
 	s.writeJSON(w, http.StatusOK, runResponse{
 		SessionID:  sessionID,
+		TraceID:    resp.TraceID,
 		Output:     result.Output,
 		StopReason: result.StopReason,
 		Usage:      result.Usage,
 		ToolCalls:  result.ToolCalls,
+		Artifacts:  resp.Artifacts,
 	})
 }
 
@@ -109,7 +142,7 @@ func (s *httpServer) handleStream(w http.ResponseWriter, r *http.Request) {
 		s.writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
 		return
 	}
-	if req.Prompt == "" {
+	if req.Prompt == "" && len(req.AttachmentIDs) == 0 {
 		s.writeJSON(w, http.StatusBadRequest, errorResponse{"prompt is required"})
 		return
 	}
@@ -127,10 +160,13 @@ func (s *httpServer) handleStream(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	events, err := s.runtime.RunStream(ctx, api.Request{
-		Prompt:    req.Prompt,
-		SessionID: sessionID,
+		Prompt:        req.Prompt,
+		SessionID:     sessionID,
+		RequestID:     req.RunID,
+		AttachmentIDs: req.AttachmentIDs,
 	})
 	if err != nil {
+		log.Printf("stream failed session=%s trace_id=%s: %v", sessionID, traceID(ctx), err)
 		s.writeJSON(w, http.StatusBadGateway, errorResponse{err.Error()})
 		return
 	}
@@ -163,6 +199,130 @@ func (s *httpServer) handleStream(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleListRuns lists in-flight runs with their IDs and current iteration.
+func (s *httpServer) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, map[string]any{"runs": s.runtime.Runs()})
+}
+
+// handleCancelRun aborts an in-flight run; the caller waiting on it gets
+// 409 (/v1/run) or a final error event (streams).
+func (s *httpServer) handleCancelRun(w http.ResponseWriter, r *http.Request) {
+	err := s.runtime.Cancel(r.PathValue("id"))
+	if errors.Is(err, api.ErrRunNotFound) {
+		s.writeJSON(w, http.StatusNotFound, errorResponse{err.Error()})
+		return
+	}
+	if err != nil {
+		s.writeJSON(w, http.StatusInternalServerError, errorResponse{err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEvents streams a run as versioned envelopes. The run is detached
+// from the connection: a client that drops reconnects to
+// /v1/runs/{id}/events with Last-Event-ID and receives what it missed.
+func (s *httpServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	var req runRequest
+	if err := s.decode(r, &req); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	if req.Prompt == "" && len(req.AttachmentIDs) == 0 {
+		s.writeJSON(w, http.StatusBadRequest, errorResponse{"prompt is required"})
+		return
+	}
+
+	sessionID := req.ensureSessionID()
+	runCtx, cancelRun := s.requestContext(context.WithoutCancel(r.Context()), req.TimeoutMs)
+	events, err := s.runtime.RunEvents(runCtx, api.Request{
+		Prompt:        req.Prompt,
+		SessionID:     sessionID,
+		RequestID:     req.RunID,
+		AttachmentIDs: req.AttachmentIDs,
+	})
+	if err != nil {
+		cancelRun()
+		log.Printf("stream failed session=%s trace_id=%s: %v", sessionID, traceID(r.Context()), err)
+		s.writeJSON(w, http.StatusBadGateway, errorResponse{err.Error()})
+		return
+	}
+	// Keep the run going after the client leaves; its events stay
+	// buffered for resumption.
+	defer func() {
+		go func() {
+			for range events {
+			}
+			cancelRun()
+		}()
+	}()
+	s.writeEnvelopes(w, r, events)
+}
+
+// handleResume replays the events of a run after Last-Event-ID (or the
+// last_seq query parameter) and follows it until it ends.
+func (s *httpServer) handleResume(w http.ResponseWriter, r *http.Request) {
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = r.URL.Query().Get("last_seq")
+	}
+	var lastSeq uint64
+	if last != "" {
+		n, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			s.writeJSON(w, http.StatusBadRequest, errorResponse{"invalid last event id"})
+			return
+		}
+		lastSeq = n
+	}
+	events, err := s.runtime.ResumeStream(r.Context(), r.PathValue("id"), lastSeq)
+	if errors.Is(err, api.ErrRunNotFound) {
+		s.writeJSON(w, http.StatusNotFound, errorResponse{err.Error()})
+		return
+	}
+	if err != nil {
+		s.writeJSON(w, http.StatusInternalServerError, errorResponse{err.Error()})
+		return
+	}
+	s.writeEnvelopes(w, r, events)
+}
+
+// writeEnvelopes writes envelopes as SSE frames whose id is the sequence
+// number, so EventSource reconnects send it back as Last-Event-ID.
+func (s *httpServer) writeEnvelopes(w http.ResponseWriter, r *http.Request, events <-chan api.Envelope) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeJSON(w, http.StatusInternalServerError, errorResponse{"streaming unsupported"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ticker := time.NewTicker(streamPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case env, ok := <-events:
+			if !ok {
+				return
+			}
+			payload, err := json.Marshal(env)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", env.Seq, env.Type, payload)
+			flusher.Flush()
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
 func (s *httpServer) decode(r *http.Request, dest any) error {
 	if r.Body == nil {
 		return errors.New("request body is empty")
@@ -202,9 +362,11 @@ func (s *httpServer) writeJSON(w http.ResponseWriter, status int, payload any) {
 }
 
 type runRequest struct {
-	Prompt    string `json:"prompt"`
-	SessionID string `json:"session_id"`
-	TimeoutMs int    `json:"timeout_ms"`
+	Prompt        string   `json:"prompt"`
+	SessionID     string   `json:"session_id"`
+	RunID         string   `json:"run_id"`
+	TimeoutMs     int      `json:"timeout_ms"`
+	AttachmentIDs []string `json:"attachment_ids"`
 }
 
 func (r *runRequest) ensureSessionID() string {
@@ -216,10 +378,18 @@ func (r *runRequest) ensureSessionID() string {
 
 type runResponse struct {
 	SessionID  string              `json:"session_id"`
+	TraceID    string              `json:"trace_id,omitempty"`
 	Output     string              `json:"output"`
 	StopReason string              `json:"stop_reason"`
 	Usage      modelpkg.Usage      `json:"usage"`
 	ToolCalls  []modelpkg.ToolCall `json:"tool_calls"`
+	Artifacts  []tool.Artifact     `json:"artifacts,omitempty"`
+}
+
+// traceID returns the trace joined by tracecontext.Handler, for log lines.
+func traceID(ctx context.Context) string {
+	tc, _ := tracecontext.FromContext(ctx)
+	return tc.TraceID
 }
 
 type errorResponse struct {
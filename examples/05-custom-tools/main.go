@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/cexll/agentsdk-go/pkg/api"
 	"github.com/cexll/agentsdk-go/pkg/model"
@@ -28,16 +29,33 @@ func (t *EchoTool) Execute(ctx context.Context, params map[string]any) (*tool.To
 	return &tool.ToolResult{Output: fmt.Sprint(params["text"])}, nil
 }
 
+// ShoutParams defines the shout tool's input; the schema is generated from
+// the json and jsonschema tags.
+type ShoutParams struct {
+	Text   string `json:"text" jsonschema:"text to shout"`
+	Repeat int    `json:"repeat,omitempty" jsonschema:"how many times to repeat it"`
+}
+
+func shout(ctx context.Context, p ShoutParams) (string, error) {
+	n := max(p.Repeat, 1)
+	return strings.TrimSpace(strings.Repeat(strings.ToUpper(p.Text)+" ", n)), nil
+}
+
 func main() {
 	ctx := context.Background()
 
+	shoutTool, err := tool.FromFunc("shout", "return the text in upper case", shout)
+	if err != nil {
+		log.Fatalf("build shout tool: %v", err)
+	}
+
 	provider := &model.AnthropicProvider{ModelName: "claude-sonnet-4-5-20250929"}
 
 	rt, err := api.New(ctx, api.Options{
 		ProjectRoot:         ".",
 		ModelFactory:        provider,
-		EnabledBuiltinTools: []string{"bash", "file_read"}, // nil=all, []string{}=none
-		CustomTools:         []tool.Tool{&EchoTool{}},      // appended when Tools is empty
+		EnabledBuiltinTools: []string{"bash", "file_read"},       // nil=all, []string{}=none
+		CustomTools:         []tool.Tool{&EchoTool{}, shoutTool}, // appended when Tools is empty
 	})
 	if err != nil {
 		log.Fatalf("build runtime: %v", err)
@@ -45,7 +63,7 @@ func main() {
 	defer rt.Close()
 
 	resp, err := rt.Run(ctx, api.Request{
-		Prompt:    "Use the echo tool to repeat 'hello from custom tool'",
+		Prompt:    "Use the echo tool to repeat 'hello from custom tool', then shout it twice",
 		SessionID: "custom-tools-demo",
 	})
 	if err != nil {
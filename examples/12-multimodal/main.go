@@ -1,9 +1,11 @@
 // Package main demonstrates multimodal content block support.
 //
-// Three demos:
+// Five demos:
 //  1. Text-only via ContentBlocks (backward-compatible path)
 //  2. Text + base64 image (programmatically generated PNG)
-//  3. Text + URL image (public image)
+//  3. Prompt + ContentBlocks combined
+//  4. Prompt + Request.Attachments (raw bytes, media type detected)
+//  5. A tool returning an image that is fed back to the model
 //
 // Usage:
 //
@@ -25,6 +27,7 @@ import (
 
 	"github.com/cexll/agentsdk-go/pkg/api"
 	modelpkg "github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
 )
 
 func main() {
@@ -32,8 +35,20 @@ func main() {
 		ModelName: "claude-sonnet-4-5-20250929",
 	}
 
+	snapshot, err := tool.FromFunc("snapshot", "Render the current canvas as a PNG image.", func(context.Context, struct{}) (*tool.ToolResult, error) {
+		return &tool.ToolResult{
+			Success: true,
+			Output:  "Canvas rendered.",
+			Media:   []tool.Media{{Kind: tool.MediaImage, MediaType: "image/png", Data: generateTestPNG()}},
+		}, nil
+	})
+	if err != nil {
+		log.Fatalf("build tool: %v", err)
+	}
+
 	rt, err := api.New(context.Background(), api.Options{
 		ModelFactory: provider,
+		Tools:        []tool.Tool{snapshot},
 	})
 	if err != nil {
 		log.Fatalf("build runtime: %v", err)
@@ -99,6 +114,35 @@ func main() {
 	}
 	printResult("Prompt+Blocks", resp)
 
+	// ── Demo 4: Prompt + Attachments ─────────────────────────────────
+	fmt.Println("\n═══════════════════════════════════════════════════")
+	fmt.Println(" Demo 4: Prompt + Attachments")
+	fmt.Println("═══════════════════════════════════════════════════")
+
+	resp, err = rt.Run(ctx, api.Request{
+		Prompt:      "How many squares does the attached image have?",
+		Attachments: []api.Attachment{{Name: "checkerboard.png", Data: pngData}},
+		SessionID:   "multimodal-demo-4",
+	})
+	if err != nil {
+		log.Fatalf("demo4: %v", err)
+	}
+	printResult("Attachments", resp)
+
+	// ── Demo 5: Tool returning an image ──────────────────────────────
+	fmt.Println("\n═══════════════════════════════════════════════════")
+	fmt.Println(" Demo 5: Tool Result Image")
+	fmt.Println("═══════════════════════════════════════════════════")
+
+	resp, err = rt.Run(ctx, api.Request{
+		Prompt:    "Take a snapshot of the canvas and tell me which colors it uses.",
+		SessionID: "multimodal-demo-5",
+	})
+	if err != nil {
+		log.Fatalf("demo5: %v", err)
+	}
+	printResult("Tool Image", resp)
+
 	fmt.Println("\nAll demos completed.")
 }
 
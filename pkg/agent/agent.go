@@ -43,6 +43,14 @@ type ModelOutput struct {
 	Content   string
 	ToolCalls []ToolCall
 	Done      bool
+	// Continue asks Run for another model turn although the output has no
+	// tool calls, for example when a Stop hook rejected the final answer.
+	Continue bool
+	// Usage is the token usage of the call, counted against the budget.
+	Usage model.Usage
+	// StopReason is set to StopReasonBudgetExceeded when Run stops on the
+	// budget.
+	StopReason string
 }
 
 // Agent drives the core loop, invoking middleware, model, and tools.
@@ -68,7 +76,8 @@ func New(model Model, tools ToolExecutor, opts Options) (*Agent, error) {
 }
 
 // Run executes the agent loop. It terminates when the model returns a final
-// output (Done or no tool calls), the context is canceled, or an error occurs.
+// output (Done, or no tool calls without Continue), the context is canceled,
+// or an error occurs.
 func (a *Agent) Run(ctx context.Context, c *Context) (*ModelOutput, error) {
 	if a == nil {
 		return nil, errors.New("agent is nil")
@@ -86,15 +95,16 @@ func (a *Agent) Run(ctx context.Context, c *Context) (*ModelOutput, error) {
 		defer cancel()
 	}
 
-	stateValues := map[string]any{}
-	if len(c.Values) > 0 {
-		for k, v := range c.Values {
-			stateValues[k] = v
-		}
+	// Middleware writes land on c.Values so callers can read and persist
+	// them after the run.
+	if c.Values == nil {
+		c.Values = map[string]any{}
 	}
+	budget := &Budget{MaxTokens: a.opts.MaxTokens, MaxCostUSD: a.opts.MaxCostUSD}
+	BudgetKey.Store(c.Values, budget)
 	state := &middleware.State{
 		Agent:  c,
-		Values: stateValues,
+		Values: c.Values,
 	}
 	ctx = context.WithValue(ctx, model.MiddlewareStateKey, state)
 
@@ -112,6 +122,15 @@ func (a *Agent) Run(ctx context.Context, c *Context) (*ModelOutput, error) {
 		if a.opts.MaxIterations > 0 && iteration >= a.opts.MaxIterations {
 			return last, ErrMaxIterations
 		}
+		if budget.Exceeded {
+			// Tool results of the last turn are recorded; stop before
+			// spending on another model call.
+			last.StopReason = StopReasonBudgetExceeded
+			if err := a.mw.Execute(ctx, middleware.StageAfterAgent, state); err != nil {
+				return last, err
+			}
+			return last, nil
+		}
 
 		c.Iteration = iteration
 		state.Iteration = iteration
@@ -120,9 +139,9 @@ func (a *Agent) Run(ctx context.Context, c *Context) (*ModelOutput, error) {
 			return last, err
 		}
 
-		// Inject middleware state into context so model can populate ModelInput/ModelOutput
-		modelCtx := context.WithValue(ctx, model.MiddlewareStateKey, state)
-		out, err := a.model.Generate(modelCtx, c)
+		// ctx already carries the middleware state so the model can populate
+		// ModelInput/ModelOutput without a per-iteration context allocation.
+		out, err := a.model.Generate(ctx, c)
 		if err != nil {
 			return last, err
 		}
@@ -133,12 +152,13 @@ func (a *Agent) Run(ctx context.Context, c *Context) (*ModelOutput, error) {
 		last = out
 		c.LastModelOutput = out
 		state.ModelOutput = out
+		budget.add(out.Usage, a.cost(out.Usage))
 
 		if err := a.mw.Execute(ctx, middleware.StageAfterModel, state); err != nil {
 			return last, err
 		}
 
-		if out.Done || len(out.ToolCalls) == 0 {
+		if out.Done || (len(out.ToolCalls) == 0 && !out.Continue) {
 			if err := a.mw.Execute(ctx, middleware.StageAfterAgent, state); err != nil {
 				return last, err
 			}
@@ -187,3 +207,10 @@ func (a *Agent) Run(ctx context.Context, c *Context) (*ModelOutput, error) {
 		iteration++
 	}
 }
+
+func (a *Agent) cost(usage model.Usage) float64 {
+	if a.opts.CostFunc != nil {
+		return a.opts.CostFunc(usage)
+	}
+	return usage.CostUSD
+}
@@ -201,6 +201,39 @@ func TestAgentMaxIterations(t *testing.T) {
 	}
 }
 
+func TestAgentContinueRunsAnotherTurn(t *testing.T) {
+	model := &scriptedModel{
+		outputs: []*ModelOutput{
+			{Content: "early", Continue: true},
+			{Content: "done", Done: true},
+		},
+	}
+	log := []string{}
+	chain := middleware.NewChain([]middleware.Middleware{middlewareRecorder(&log)})
+
+	ag, err := New(model, &stubTools{}, Options{Middleware: chain})
+	if err != nil {
+		t.Fatalf("new agent: %v", err)
+	}
+	out, err := ag.Run(context.Background(), NewContext())
+	if err != nil || out.Content != "done" {
+		t.Fatalf("expected second turn to finish, got %+v, %v", out, err)
+	}
+	expected := []string{"before_agent:0", "before_model:0", "after_model:0", "before_model:1", "after_model:1", "after_agent:1"}
+	if !reflect.DeepEqual(log, expected) {
+		t.Fatalf("middleware order mismatch:\n got %v\nwant %v", log, expected)
+	}
+
+	model = &scriptedModel{outputs: []*ModelOutput{{Content: "again", Continue: true}}}
+	ag, err = New(model, &stubTools{}, Options{Middleware: middleware.NewChain(nil), MaxIterations: 3})
+	if err != nil {
+		t.Fatalf("new agent: %v", err)
+	}
+	if _, err := ag.Run(context.Background(), NewContext()); !errors.Is(err, ErrMaxIterations) {
+		t.Fatalf("continuations should count against MaxIterations, got %v", err)
+	}
+}
+
 func TestAgentTimeout(t *testing.T) {
 	model := &scriptedModel{
 		outputs: []*ModelOutput{{}},
@@ -493,3 +526,47 @@ func TestAgent_Run_ToolExecutionError(t *testing.T) {
 		})
 	}
 }
+
+type fixedRoundsModel struct {
+	rounds int
+	call   *ModelOutput
+	done   *ModelOutput
+}
+
+func (m *fixedRoundsModel) Generate(_ context.Context, c *Context) (*ModelOutput, error) {
+	if c.Iteration >= m.rounds {
+		return m.done, nil
+	}
+	return m.call, nil
+}
+
+type echoTools struct{}
+
+func (echoTools) Execute(_ context.Context, call ToolCall, _ *Context) (ToolResult, error) {
+	return ToolResult{Name: call.Name, Output: "ok"}, nil
+}
+
+func TestRunAllocationsPerIterationStayBounded(t *testing.T) {
+	const rounds = 20
+	mdl := &fixedRoundsModel{
+		rounds: rounds,
+		call:   &ModelOutput{ToolCalls: []ToolCall{{ID: "1", Name: "echo"}}},
+		done:   &ModelOutput{Content: "done", Done: true},
+	}
+	chain := middleware.NewChain([]middleware.Middleware{middleware.Funcs{Identifier: "noop"}})
+	ag, err := New(mdl, echoTools{}, Options{Middleware: chain})
+	if err != nil {
+		t.Fatalf("new agent: %v", err)
+	}
+
+	allocs := testing.AllocsPerRun(20, func() {
+		if _, err := ag.Run(context.Background(), &Context{}); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	})
+	// Boxing ToolCall/ToolResult into middleware.State plus amortised
+	// ToolResults growth; the chain and model call must not allocate.
+	if perIter := allocs / rounds; perIter > 3 {
+		t.Fatalf("expected <=3 allocs per iteration, got %.2f (%.0f total)", perIter, allocs)
+	}
+}
@@ -0,0 +1,54 @@
+package agent
+
+import "github.com/cexll/agentsdk-go/pkg/model"
+
+// StopReasonBudgetExceeded is reported in ModelOutput.StopReason when Run
+// stops because Options.MaxTokens or Options.MaxCostUSD was reached.
+const StopReasonBudgetExceeded = "budget_exceeded"
+
+// BudgetStateKey is the middleware.State.Values key holding the run's
+// *Budget; prefer BudgetKey.
+const BudgetStateKey = "agent.budget"
+
+// Budget tracks cumulative model usage against the run's limits. Run
+// updates it after every model call, before StageAfterModel.
+type Budget struct {
+	// MaxTokens and MaxCostUSD mirror Options; zero means unlimited.
+	MaxTokens  int
+	MaxCostUSD float64
+
+	InputTokens  int
+	OutputTokens int
+	// TotalTokens is InputTokens plus OutputTokens.
+	TotalTokens int
+	CostUSD     float64
+	// Exceeded is set once either limit has been reached; Run then stops
+	// before the next model call.
+	Exceeded bool
+}
+
+// RemainingTokens is the token allowance left, or -1 without a limit.
+func (b *Budget) RemainingTokens() int {
+	if b == nil || b.MaxTokens <= 0 {
+		return -1
+	}
+	return max(b.MaxTokens-b.TotalTokens, 0)
+}
+
+// RemainingCostUSD is the spend left, or -1 without a limit.
+func (b *Budget) RemainingCostUSD() float64 {
+	if b == nil || b.MaxCostUSD <= 0 {
+		return -1
+	}
+	return max(b.MaxCostUSD-b.CostUSD, 0)
+}
+
+func (b *Budget) add(usage model.Usage, cost float64) {
+	b.InputTokens += usage.InputTokens
+	b.OutputTokens += usage.OutputTokens
+	b.TotalTokens = b.InputTokens + b.OutputTokens
+	b.CostUSD += cost
+	if (b.MaxTokens > 0 && b.TotalTokens >= b.MaxTokens) || (b.MaxCostUSD > 0 && b.CostUSD >= b.MaxCostUSD) {
+		b.Exceeded = true
+	}
+}
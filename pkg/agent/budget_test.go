@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func TestRunStopsWhenTokenBudgetIsExhausted(t *testing.T) {
+	call := []ToolCall{{ID: "1", Name: "echo"}}
+	mdl := &scriptedModel{outputs: []*ModelOutput{
+		{ToolCalls: call, Usage: model.Usage{InputTokens: 60, OutputTokens: 10}},
+		{ToolCalls: call, Usage: model.Usage{InputTokens: 80, OutputTokens: 10}},
+		{Content: "never reached", Done: true},
+	}}
+	tools := &stubTools{}
+	var seen []*Budget
+	observer := middleware.Funcs{Identifier: "observer", OnAfterModel: func(_ context.Context, st *middleware.State) error {
+		b := *st.Values[BudgetStateKey].(*Budget)
+		seen = append(seen, &b)
+		return nil
+	}}
+	ag, err := New(mdl, tools, Options{MaxTokens: 150, Middleware: middleware.NewChain([]middleware.Middleware{observer})})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	out, err := ag.Run(context.Background(), NewContext())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out.StopReason != StopReasonBudgetExceeded || mdl.idx != 2 || len(tools.calls) != 2 {
+		t.Fatalf("stop=%q model calls=%d tool calls=%d", out.StopReason, mdl.idx, len(tools.calls))
+	}
+	if len(seen) != 2 || seen[0].Exceeded || seen[0].RemainingTokens() != 80 || !seen[1].Exceeded || seen[1].TotalTokens != 160 {
+		t.Fatalf("budget snapshots %+v %+v", seen[0], seen[1])
+	}
+}
+
+func TestRunStopsWhenCostBudgetIsExhausted(t *testing.T) {
+	mdl := &scriptedModel{outputs: []*ModelOutput{
+		{ToolCalls: []ToolCall{{ID: "1", Name: "echo"}}, Usage: model.Usage{OutputTokens: 1000}},
+		{Content: "never reached", Done: true},
+	}}
+	perToken := func(u model.Usage) float64 { return float64(u.OutputTokens) * 0.001 }
+	ag, _ := New(mdl, &stubTools{}, Options{MaxCostUSD: 0.5, CostFunc: perToken})
+	out, err := ag.Run(context.Background(), NewContext())
+	if err != nil || out.StopReason != StopReasonBudgetExceeded || mdl.idx != 1 {
+		t.Fatalf("out=%+v err=%v calls=%d", out, err, mdl.idx)
+	}
+
+	// Without a limit the budget only tracks usage.
+	if b := (&Budget{}); b.RemainingTokens() != -1 || b.RemainingCostUSD() != -1 {
+		t.Fatal("unlimited budget should report -1")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
 )
 
 // Options controls runtime behavior of the Agent.
@@ -15,6 +16,14 @@ type Options struct {
 	Timeout time.Duration
 	// Middleware chain. Defaults to an empty chain when nil.
 	Middleware *middleware.Chain
+	// MaxTokens caps cumulative input plus output tokens across all model
+	// calls of a Run. Zero means no limit.
+	MaxTokens int
+	// MaxCostUSD caps cumulative model spend of a Run. Zero means no limit.
+	MaxCostUSD float64
+	// CostFunc prices one model call for MaxCostUSD; nil uses the
+	// gateway-reported ModelOutput.Usage.CostUSD.
+	CostFunc func(model.Usage) float64
 }
 
 func (o Options) withDefaults() Options {
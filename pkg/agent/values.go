@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Key identifies a typed entry in Context.Values. Keys are registered once
+// per process under a unique name, which is both the map key and the field
+// name used when values are persisted, so two packages cannot silently
+// claim the same slot. Prefix names with the owner ("mytool.cursor").
+type Key[T any] struct {
+	name string
+}
+
+// KeyOption configures a Key at registration.
+type KeyOption func(*keySpec)
+
+// Transient marks a key as per-run state that MarshalValues skips, e.g.
+// handles, registries or values recomputed for every request.
+func Transient() KeyOption {
+	return func(s *keySpec) { s.transient = true }
+}
+
+type keySpec struct {
+	typ       reflect.Type
+	transient bool
+	decode    func(json.RawMessage) (any, error)
+}
+
+var (
+	keysMu sync.RWMutex
+	keys   = map[string]keySpec{}
+)
+
+// NewKey registers a key of type T under name. It panics when name is empty
+// or already registered, so collisions surface at init time; declare keys
+// as package-level variables.
+func NewKey[T any](name string, opts ...KeyOption) Key[T] {
+	if name == "" {
+		panic("agent: empty context key name")
+	}
+	spec := keySpec{
+		typ: reflect.TypeFor[T](),
+		decode: func(raw json.RawMessage) (any, error) {
+			var v T
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return nil, err
+			}
+			return v, nil
+		},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&spec)
+		}
+	}
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if prev, ok := keys[name]; ok {
+		panic(fmt.Sprintf("agent: context key %q already registered as %s", name, prev.typ))
+	}
+	keys[name] = spec
+	return Key[T]{name: name}
+}
+
+// Name returns the key's registered name.
+func (k Key[T]) Name() string { return k.name }
+
+// Lookup returns the value stored under k in values, such as
+// middleware.State.Values. Entries restored from JSON before k was
+// registered are decoded on first access.
+func (k Key[T]) Lookup(values map[string]any) (T, bool) {
+	var zero T
+	raw, ok := values[k.name]
+	if !ok || raw == nil {
+		return zero, false
+	}
+	if v, ok := raw.(T); ok {
+		return v, true
+	}
+	if data, ok := raw.(json.RawMessage); ok {
+		var v T
+		if err := json.Unmarshal(data, &v); err == nil {
+			values[k.name] = v
+			return v, true
+		}
+	}
+	return zero, false
+}
+
+// Store sets the value of k in values.
+func (k Key[T]) Store(values map[string]any, v T) {
+	if values != nil {
+		values[k.name] = v
+	}
+}
+
+// Get returns the value of k in c.
+func Get[T any](c *Context, k Key[T]) (T, bool) {
+	if c == nil {
+		var zero T
+		return zero, false
+	}
+	return k.Lookup(c.Values)
+}
+
+// Set stores v under k in c.
+func Set[T any](c *Context, k Key[T], v T) {
+	if c == nil {
+		return
+	}
+	if c.Values == nil {
+		c.Values = map[string]any{}
+	}
+	k.Store(c.Values, v)
+}
+
+// Delete removes k from c.
+func Delete[T any](c *Context, k Key[T]) {
+	if c != nil {
+		delete(c.Values, k.name)
+	}
+}
+
+// MarshalValues encodes the values of registered, non-transient keys as a
+// JSON object. Entries restored by UnmarshalValues under names no key
+// claims are written back unchanged; other ad-hoc string keys are skipped.
+func (c *Context) MarshalValues() ([]byte, error) {
+	out := map[string]json.RawMessage{}
+	if c != nil {
+		keysMu.RLock()
+		defer keysMu.RUnlock()
+		names := make([]string, 0, len(c.Values))
+		for name := range c.Values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			v := c.Values[name]
+			spec, registered := keys[name]
+			if raw, ok := v.(json.RawMessage); ok && (!registered || !spec.transient) {
+				out[name] = raw
+				continue
+			}
+			if !registered || spec.transient || v == nil {
+				continue
+			}
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("agent: encode context value %q: %w", name, err)
+			}
+			out[name] = data
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalValues decodes data produced by MarshalValues into c.Values,
+// replacing entries with the same name. Values of registered keys are
+// decoded to their type; the rest are kept as json.RawMessage.
+func (c *Context) UnmarshalValues(data []byte) error {
+	if c == nil || len(data) == 0 {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("agent: decode context values: %w", err)
+	}
+	if c.Values == nil {
+		c.Values = map[string]any{}
+	}
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	for name, msg := range raw {
+		spec, ok := keys[name]
+		if !ok {
+			c.Values[name] = msg
+			continue
+		}
+		v, err := spec.decode(msg)
+		if err != nil {
+			return fmt.Errorf("agent: decode context value %q: %w", name, err)
+		}
+		c.Values[name] = v
+	}
+	return nil
+}
+
+// Keys the runtime sets on every run. Their names predate typed keys and
+// stay unprefixed so existing Values readers keep working.
+var (
+	SessionIDKey   = NewKey[string]("session_id", Transient())
+	RequestIDKey   = NewKey[string]("request_id", Transient())
+	ForceSkillsKey = NewKey[[]string]("request.force_skills", Transient())
+	ExperimentsKey = NewKey[map[string]string]("experiments", Transient())
+	BudgetKey      = NewKey[*Budget](BudgetStateKey, Transient())
+)
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+)
+
+type cursor struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+var (
+	testCursorKey = NewKey[cursor]("agenttest.cursor")
+	testHandleKey = NewKey[*Budget]("agenttest.handle", Transient())
+)
+
+func TestContextValuesGetSetDelete(t *testing.T) {
+	c := &Context{}
+	if _, ok := Get(c, testCursorKey); ok {
+		t.Fatal("unexpected value on empty context")
+	}
+	Set(c, testCursorKey, cursor{File: "a.go", Line: 3})
+	got, ok := Get(c, testCursorKey)
+	if !ok || got.Line != 3 || c.Values["agenttest.cursor"] == nil {
+		t.Fatalf("got %+v %v", got, ok)
+	}
+	c.Values["agenttest.cursor"] = "wrong type"
+	if _, ok := Get(c, testCursorKey); ok {
+		t.Fatal("mistyped value should not be returned")
+	}
+	Delete(c, testCursorKey)
+	if _, ok := c.Values["agenttest.cursor"]; ok {
+		t.Fatal("Delete kept the value")
+	}
+}
+
+func TestNewKeyRejectsDuplicates(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "already registered") {
+			t.Fatalf("expected duplicate panic, got %v", r)
+		}
+	}()
+	NewKey[string]("agenttest.cursor")
+}
+
+func TestContextValuesRoundTrip(t *testing.T) {
+	c := NewContext()
+	Set(c, testCursorKey, cursor{File: "main.go", Line: 7})
+	Set(c, testHandleKey, &Budget{})
+	Set(c, SessionIDKey, "s1")
+	c.Values["adhoc"] = 1
+	c.Values["other.plugin"] = json.RawMessage(`{"kept":true}`)
+
+	data, err := c.MarshalValues()
+	if err != nil {
+		t.Fatalf("MarshalValues: %v", err)
+	}
+	if string(data) != `{"agenttest.cursor":{"file":"main.go","line":7},"other.plugin":{"kept":true}}` {
+		t.Fatalf("unexpected encoding %s", data)
+	}
+
+	restored := NewContext()
+	if err := restored.UnmarshalValues(data); err != nil {
+		t.Fatalf("UnmarshalValues: %v", err)
+	}
+	if got, ok := Get(restored, testCursorKey); !ok || got.File != "main.go" {
+		t.Fatalf("cursor not restored: %+v", restored.Values)
+	}
+	if _, ok := restored.Values["other.plugin"].(json.RawMessage); !ok {
+		t.Fatalf("unknown value should stay raw: %#v", restored.Values["other.plugin"])
+	}
+	again, err := restored.MarshalValues()
+	if err != nil || string(again) != string(data) {
+		t.Fatalf("second round trip changed values: %s %v", again, err)
+	}
+	if err := restored.UnmarshalValues([]byte(`{"agenttest.cursor":"x"}`)); err == nil {
+		t.Fatal("expected decode error for mistyped value")
+	}
+}
+
+func TestKeyLookupDecodesRawValues(t *testing.T) {
+	values := map[string]any{"agenttest.cursor": json.RawMessage(`{"file":"x.go","line":1}`)}
+	got, ok := testCursorKey.Lookup(values)
+	if !ok || got.File != "x.go" {
+		t.Fatalf("got %+v %v", got, ok)
+	}
+	if _, ok := values["agenttest.cursor"].(cursor); !ok {
+		t.Fatal("decoded value should replace the raw entry")
+	}
+}
+
+func TestRunSharesValuesWithMiddleware(t *testing.T) {
+	mdl := &scriptedModel{outputs: []*ModelOutput{{Content: "done", Done: true}}}
+	mw := middleware.Funcs{Identifier: "cursor", OnAfterAgent: func(_ context.Context, st *middleware.State) error {
+		testCursorKey.Store(st.Values, cursor{File: "set-by-middleware"})
+		return nil
+	}}
+	ag, err := New(mdl, &stubTools{}, Options{Middleware: middleware.NewChain([]middleware.Middleware{mw})})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	c := NewContext()
+	if _, err := ag.Run(context.Background(), c); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got, ok := Get(c, testCursorKey); !ok || got.File != "set-by-middleware" {
+		t.Fatalf("middleware value not visible on Context: %+v", c.Values)
+	}
+	if _, ok := Get(c, BudgetKey); !ok {
+		t.Fatal("budget should be stored under BudgetKey")
+	}
+}
@@ -0,0 +1,95 @@
+// Package agentsdk is the stable public surface of agentsdk-go.
+//
+// It re-exports the types and constructors that downstream programs need to
+// embed an agent: the runtime (pkg/api), the model interface and providers
+// (pkg/model), the tool contract (pkg/tool) and middleware (pkg/middleware).
+// Everything declared here follows semantic versioning: identifiers are only
+// added, never removed or changed incompatibly, until APILevel is bumped.
+// The underlying packages remain importable but may change between minor
+// releases.
+//
+// The exported surface is pinned by testdata/api.golden; an incompatible
+// change fails TestAPISurface and must be accompanied by an APILevel bump.
+package agentsdk
+
+import (
+	"context"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// APILevel identifies the compatibility generation of this package. It is
+// incremented only for breaking changes; additions keep the same level.
+const APILevel = 1
+
+// Runtime.
+type (
+	Runtime     = api.Runtime
+	Options     = api.Options
+	Request     = api.Request
+	Response    = api.Response
+	Result      = api.Result
+	StreamEvent = api.StreamEvent
+	EntryPoint  = api.EntryPoint
+	ModelTier   = api.ModelTier
+
+	ModelFactory     = api.ModelFactory
+	ModelFactoryFunc = api.ModelFactoryFunc
+)
+
+const (
+	EntryPointCLI      = api.EntryPointCLI
+	EntryPointCI       = api.EntryPointCI
+	EntryPointPlatform = api.EntryPointPlatform
+
+	ModelTierLow  = api.ModelTierLow
+	ModelTierMid  = api.ModelTierMid
+	ModelTierHigh = api.ModelTierHigh
+)
+
+var (
+	ErrMissingModel        = api.ErrMissingModel
+	ErrConcurrentExecution = api.ErrConcurrentExecution
+	ErrRuntimeClosed       = api.ErrRuntimeClosed
+)
+
+// New builds a Runtime. See api.New.
+func New(ctx context.Context, opts Options) (*Runtime, error) {
+	return api.New(ctx, opts)
+}
+
+// NewFromFile builds a Runtime from a YAML or JSON agent definition. See
+// api.NewFromFile.
+func NewFromFile(ctx context.Context, path string, overrides ...func(*Options)) (*Runtime, error) {
+	return api.NewFromFile(ctx, path, overrides...)
+}
+
+// Models.
+type (
+	Model             = model.Model
+	ModelRequest      = model.Request
+	ModelResponse     = model.Response
+	Message           = model.Message
+	ToolCall          = model.ToolCall
+	Usage             = model.Usage
+	AnthropicProvider = model.AnthropicProvider
+	OpenAIProvider    = model.OpenAIProvider
+)
+
+// Tools.
+type (
+	Tool       = tool.Tool
+	ToolResult = tool.ToolResult
+	JSONSchema = tool.JSONSchema
+	Artifact   = tool.Artifact
+)
+
+// Middleware.
+type (
+	Middleware      = middleware.Middleware
+	MiddlewareState = middleware.State
+	MiddlewareFuncs = middleware.Funcs
+)
@@ -0,0 +1,135 @@
+package agentsdk
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/api.golden")
+
+// TestAPISurface fails when the exported surface of this package differs
+// from testdata/api.golden. Additions are compatible: regenerate the golden
+// file with -update. Removals or changed declarations are breaking and also
+// require bumping APILevel.
+func TestAPISurface(t *testing.T) {
+	got := apiSurface(t)
+	golden := filepath.Join("testdata", "api.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(strings.Join(got, "\n")+"\n"), 0o600); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+		return
+	}
+	raw, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	want := strings.Split(strings.TrimSpace(string(raw)), "\n")
+
+	have := map[string]bool{}
+	for _, line := range got {
+		have[line] = true
+	}
+	var removed []string
+	for _, line := range want {
+		if !have[line] {
+			removed = append(removed, line)
+		}
+	}
+	if len(removed) > 0 {
+		t.Fatalf("incompatible API change, missing or changed:\n  %s\nbump APILevel and rerun with -update", strings.Join(removed, "\n  "))
+	}
+	if len(got) != len(want) {
+		t.Fatalf("API surface grew; rerun with -update to record the additions")
+	}
+}
+
+func apiSurface(t *testing.T) []string {
+	t.Helper()
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	render := func(node any) string {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, fset, node); err != nil {
+			t.Fatalf("print: %v", err)
+		}
+		return strings.Join(strings.Fields(buf.String()), " ")
+	}
+	var lines []string
+	for _, file := range pkgs["agentsdk"].Files {
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Name.IsExported() && d.Recv == nil {
+					lines = append(lines, "func "+d.Name.Name+strings.TrimPrefix(render(d.Type), "func"))
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						if s.Name.IsExported() {
+							lines = append(lines, "type "+render(s))
+						}
+					case *ast.ValueSpec:
+						for i, name := range s.Names {
+							if name.IsExported() && i < len(s.Values) {
+								lines = append(lines, d.Tok.String()+" "+name.Name+" = "+render(s.Values[i]))
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// The assignments below pin the members of the re-exported types that
+// downstream code relies on. They fail to compile if an underlying package
+// renames or retypes them.
+var (
+	_ = Options{
+		EntryPoint:   EntryPointCLI,
+		ProjectRoot:  "",
+		Model:        Model(nil),
+		ModelFactory: ModelFactory(nil),
+		SystemPrompt: "",
+		Tools:        []Tool(nil),
+		Middleware:   []Middleware(nil),
+	}
+	_ = Request{Prompt: "", SessionID: "", Model: ModelTierLow, Tags: map[string]string(nil)}
+	_ = Response{Result: (*Result)(nil), Artifacts: []Artifact(nil)}
+	_ = Result{Output: "", StopReason: "", Usage: Usage{}, ToolCalls: []ToolCall(nil)}
+	_ = ToolResult{Success: false, Output: "", Data: nil}
+
+	_ func(*Runtime, context.Context, Request) (*Response, error)          = (*Runtime).Run
+	_ func(*Runtime, context.Context, Request) (<-chan StreamEvent, error) = (*Runtime).RunStream
+	_ func(*Runtime) error                                                 = (*Runtime).Close
+
+	_ ModelFactory = ModelFactoryFunc(nil)
+	_ ModelFactory = (*AnthropicProvider)(nil)
+	_ ModelFactory = (*OpenAIProvider)(nil)
+	_ Middleware   = MiddlewareFuncs{}
+)
+
+func TestNewRequiresModel(t *testing.T) {
+	if _, err := New(context.Background(), Options{ProjectRoot: t.TempDir()}); err == nil {
+		t.Fatalf("expected error without model")
+	}
+}
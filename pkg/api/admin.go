@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// ErrAdminTokenRequired is returned by AdminHandler when no token is given;
+// the admin API is never served unauthenticated.
+var ErrAdminTokenRequired = errors.New("api: admin token is required")
+
+// adminPingTimeout bounds MCP health pings issued by the admin API.
+const adminPingTimeout = 5 * time.Second
+
+// redactedValue replaces secret values in admin settings output.
+const redactedValue = "[redacted]"
+
+// SettingsSnapshot is the effective settings plus the layer that last set
+// each top-level key. Env values and MCP headers/env are redacted.
+type SettingsSnapshot struct {
+	Settings   *config.Settings          `json:"settings"`
+	Provenance config.SettingsProvenance `json:"provenance"`
+}
+
+// RunsSnapshot lists in-flight runs, callers queued behind busy sessions and
+// the MaxConcurrentRuns queue.
+type RunsSnapshot struct {
+	Active     []ActiveRun   `json:"active"`
+	QueueDepth int           `json:"queue_depth"`
+	RunQueue   RunQueueStats `json:"run_queue"`
+}
+
+// SettingsSnapshot returns the effective settings with layer provenance.
+func (rt *Runtime) SettingsSnapshot() SettingsSnapshot {
+	if rt == nil {
+		return SettingsSnapshot{}
+	}
+	return SettingsSnapshot{
+		Settings:   redactSettings(rt.Settings()),
+		Provenance: maps.Clone(rt.provenance),
+	}
+}
+
+// MCPStatus pings every connected MCP server and reports its health.
+func (rt *Runtime) MCPStatus(ctx context.Context) []tool.MCPServerStatus {
+	if rt == nil || rt.registry == nil {
+		return nil
+	}
+	return rt.registry.MCPStatus(ctx)
+}
+
+// ActiveRuns lists runs that currently hold their session.
+//
+// Deprecated: use Runs.
+func (rt *Runtime) ActiveRuns() []ActiveRun {
+	return rt.Runs()
+}
+
+// QueueDepth reports callers waiting for a busy session.
+func (rt *Runtime) QueueDepth() int {
+	if rt == nil || rt.sessionGate == nil {
+		return 0
+	}
+	return int(rt.sessionGate.waiting.Load())
+}
+
+// AdminHandler serves read-only operational state behind bearer-token auth.
+// Mount it with http.StripPrefix; it answers GET on:
+//
+//	/settings    effective settings with layer provenance
+//	/mcp         MCP server health
+//	/runs        active runs, queue depth and run queue stats
+//	/compliance  violations of Options.ComplianceBaseline
+//	/schemas     JSON Schemas of stream events and transcripts (see SchemaHandler)
+func (rt *Runtime) AdminHandler(token string) (http.Handler, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrAdminTokenRequired
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/settings", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, rt.SettingsSnapshot())
+	})
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), adminPingTimeout)
+		defer cancel()
+		writeAdminJSON(w, map[string]any{"servers": rt.MCPStatus(ctx)})
+	})
+	mux.HandleFunc("/runs", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, RunsSnapshot{Active: rt.Runs(), QueueDepth: rt.QueueDepth(), RunQueue: rt.RunQueueStats()})
+	})
+	mux.HandleFunc("/compliance", func(w http.ResponseWriter, r *http.Request) {
+		violations := rt.Compliance()
+		writeAdminJSON(w, map[string]any{
+			"baseline":   rt.opts.ComplianceBaseline != nil,
+			"compliant":  len(violations) == 0,
+			"violations": violations,
+		})
+	})
+	mux.Handle("/schemas/", http.StripPrefix("/schemas", SchemaHandler()))
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET supported", http.StatusMethodNotAllowed)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, "/") {
+			r.URL.Path = "/" + r.URL.Path
+		}
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+func writeAdminJSON(w http.ResponseWriter, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+// redactSettings hides values that commonly carry credentials.
+func redactSettings(s *config.Settings) *config.Settings {
+	if s == nil {
+		return nil
+	}
+	for k := range s.Env {
+		s.Env[k] = redactedValue
+	}
+	if s.MCP != nil {
+		for name, server := range s.MCP.Servers {
+			for k := range server.Env {
+				server.Env[k] = redactedValue
+			}
+			for k := range server.Headers {
+				server.Headers[k] = redactedValue
+			}
+			s.MCP.Servers[name] = server
+		}
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+)
+
+func TestAdminHandlerReportsSettingsAndRuns(t *testing.T) {
+	mdl := newBlockingModel()
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:         t.TempDir(),
+		Model:               mdl,
+		EnabledBuiltinTools: []string{},
+		RulesEnabled:        boolPtr(false),
+		SettingsOverrides:   &config.Settings{Model: "override", Env: map[string]string{"API_TOKEN": "secret"}},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	if _, err := rt.AdminHandler(" "); !errors.Is(err, ErrAdminTokenRequired) {
+		t.Fatalf("expected ErrAdminTokenRequired, got %v", err)
+	}
+	h, err := rt.AdminHandler("s3cret")
+	if err != nil {
+		t.Fatalf("admin handler: %v", err)
+	}
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("/settings", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad token status %d", rec.Code)
+	}
+	var snap SettingsSnapshot
+	if rec := get("/settings", "s3cret"); rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &snap) != nil {
+		t.Fatalf("settings status %d: %s", rec.Code, rec.Body)
+	}
+	if snap.Settings.Model != "override" || snap.Provenance["model"] != config.LayerRuntime || snap.Settings.Env["API_TOKEN"] != redactedValue {
+		t.Fatalf("unexpected snapshot %+v provenance=%v", snap.Settings, snap.Provenance)
+	}
+
+	// One run holds the session, a second queues behind it.
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := rt.Run(context.Background(), Request{Prompt: "hi", SessionID: "busy"})
+			errs <- err
+		}()
+	}
+	<-mdl.started
+	deadline := time.Now().Add(2 * time.Second)
+	for rt.QueueDepth() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	var runs RunsSnapshot
+	if rec := get("/runs", "s3cret"); json.Unmarshal(rec.Body.Bytes(), &runs) != nil {
+		t.Fatalf("runs body %s", rec.Body)
+	}
+	if len(runs.Active) != 1 || runs.Active[0].SessionID != "busy" || runs.QueueDepth != 1 {
+		t.Fatalf("unexpected runs %+v", runs)
+	}
+	mdl.Unblock()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	}
+	if len(rt.ActiveRuns()) != 0 || rt.QueueDepth() != 0 {
+		t.Fatalf("runs should drain, active=%v depth=%d", rt.ActiveRuns(), rt.QueueDepth())
+	}
+
+	if rec := get("/mcp", "s3cret"); rec.Code != http.StatusOK {
+		t.Fatalf("mcp status %d", rec.Code)
+	}
+}
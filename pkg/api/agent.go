@@ -2,12 +2,16 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"maps"
 	"net/url"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -16,18 +20,23 @@ import (
 	"github.com/cexll/agentsdk-go/pkg/config"
 	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
 	corehooks "github.com/cexll/agentsdk-go/pkg/core/hooks"
+	"github.com/cexll/agentsdk-go/pkg/core/tracecontext"
 	"github.com/cexll/agentsdk-go/pkg/message"
 	"github.com/cexll/agentsdk-go/pkg/middleware"
 	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/prompts"
+	"github.com/cexll/agentsdk-go/pkg/runtime/blackboard"
 	"github.com/cexll/agentsdk-go/pkg/runtime/commands"
 	"github.com/cexll/agentsdk-go/pkg/runtime/skills"
 	"github.com/cexll/agentsdk-go/pkg/runtime/subagents"
 	"github.com/cexll/agentsdk-go/pkg/runtime/tasks"
 	"github.com/cexll/agentsdk-go/pkg/sandbox"
 	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/session"
 	"github.com/cexll/agentsdk-go/pkg/tool"
 	toolbuiltin "github.com/cexll/agentsdk-go/pkg/tool/builtin"
-	"github.com/google/uuid"
+	"github.com/cexll/agentsdk-go/pkg/voice"
+	"github.com/cexll/agentsdk-go/pkg/workspace"
 )
 
 type streamContextKey string
@@ -44,6 +53,18 @@ func withStreamEmit(ctx context.Context, emit streamEmitFunc) context.Context {
 	return context.WithValue(ctx, streamEmitCtxKey, emit)
 }
 
+// toolUseIDCtxKey carries the model's tool call ID to permission
+// resolvers.
+const toolUseIDCtxKey streamContextKey = "agentsdk.tool_use_id"
+
+func toolUseIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(toolUseIDCtxKey).(string)
+	return id
+}
+
 func streamEmitFromContext(ctx context.Context) streamEmitFunc {
 	if ctx == nil {
 		return nil
@@ -55,15 +76,22 @@ func streamEmitFromContext(ctx context.Context) streamEmitFunc {
 }
 
 // Runtime exposes the unified SDK surface that powers CLI/CI/enterprise entrypoints.
+//
+// A Runtime is safe for concurrent Run and RunStream calls on distinct session
+// IDs; per-run state lives in preparedRun and never on the Runtime itself.
 type Runtime struct {
 	opts        Options
 	mode        ModeContext
 	settings    *config.Settings
+	provenance  config.SettingsProvenance
+	warmKey     string
 	cfg         *config.Settings
 	fs          *config.FS
 	rulesLoader *config.RulesLoader
 	sandbox     *sandbox.Manager
 	sbRoot      string
+	isolator    sandbox.CommandIsolator
+	egress      *sandbox.EgressProxy
 	registry    *tool.Registry
 	executor    *tool.Executor
 	// recorder is retained for backward compatibility.
@@ -72,14 +100,32 @@ type Runtime struct {
 	hooks            *corehooks.Executor
 	histories        *historyStore
 	historyPersister *diskHistoryPersister
+	sessionStore     session.Store
 	sessionGate      *sessionGate
+	sessionTags      sessionTagIndex
+	sessionValues    sessionValueStore
+	sessionArtifacts sessionArtifactIndex
+	lineage          sessionLineageIndex
+	active           activeRuns
+	experiments      experimentStats
+	prompts          *prompts.Library
+	scratch          *scratchSpace
+	replay           *replayStore
+	queue            *runQueue
+	tasks            *tasks.TaskStore
+	rateLimiter      *model.RateLimiter
+	workspace        *workspace.Info
+	projectMemory    projectMemoryCache
 
 	cmdExec   *commands.Executor
 	skReg     *skills.Registry
 	subMgr    *subagents.Manager
+	taskTool  *toolbuiltin.TaskTool
+	defWatch  *definitionsWatcher
 	tokens    *tokenTracker
 	compactor *compactor
 	tracer    Tracer
+	audit     AuditLogger
 
 	mu sync.RWMutex
 
@@ -94,7 +140,18 @@ type Runtime struct {
 func New(ctx context.Context, opts Options) (*Runtime, error) {
 	opts = opts.withDefaults()
 	opts = opts.frozen()
+	if err := validateExperiments(opts.Experiments); err != nil {
+		return nil, err
+	}
 	mode := opts.modeContext()
+	warmKey := warmOptionsKey(opts)
+	warm, err := decodeWarmState(opts, warmKey)
+	if err != nil {
+		return nil, err
+	}
+	if warm != nil {
+		opts.EmbedFS = warmFS(warm.Files, opts.EmbedFS)
+	}
 
 	// 初始化文件系统抽象层
 	fsLayer := config.NewFS(opts.ProjectRoot, opts.EmbedFS)
@@ -104,18 +161,29 @@ func New(ctx context.Context, opts Options) (*Runtime, error) {
 		log.Printf("claude hooks materializer warning: %v", err)
 	}
 
-	if memory, err := config.LoadClaudeMD(opts.ProjectRoot, fsLayer); err != nil {
-		log.Printf("claude.md loader warning: %v", err)
-	} else if strings.TrimSpace(memory) != "" {
-		if strings.TrimSpace(opts.SystemPrompt) == "" {
-			opts.SystemPrompt = fmt.Sprintf("## Memory\n\n%s", strings.TrimSpace(memory))
-		} else {
-			opts.SystemPrompt = fmt.Sprintf("%s\n\n## Memory\n\n%s", strings.TrimSpace(opts.SystemPrompt), strings.TrimSpace(memory))
+	var (
+		settings   *config.Settings
+		provenance config.SettingsProvenance
+	)
+	if warm != nil {
+		opts.SystemPrompt = warm.SystemPrompt
+		settings, provenance = warm.Settings, warm.Provenance
+	} else {
+		if memory, err := config.LoadClaudeMD(opts.ProjectRoot, fsLayer); err != nil {
+			log.Printf("claude.md loader warning: %v", err)
+		} else if strings.TrimSpace(memory) != "" {
+			if strings.TrimSpace(opts.SystemPrompt) == "" {
+				opts.SystemPrompt = fmt.Sprintf("## Memory\n\n%s", strings.TrimSpace(memory))
+			} else {
+				opts.SystemPrompt = fmt.Sprintf("%s\n\n## Memory\n\n%s", strings.TrimSpace(opts.SystemPrompt), strings.TrimSpace(memory))
+			}
+		}
+		settings, provenance, err = loadSettingsWithProvenance(opts)
+		if err != nil {
+			return nil, err
 		}
 	}
-
-	settings, err := loadSettings(opts)
-	if err != nil {
+	if err := checkCompliance(settings, opts); err != nil {
 		return nil, err
 	}
 
@@ -144,26 +212,61 @@ func New(ctx context.Context, opts Options) (*Runtime, error) {
 			log.Printf("subagent loader warning: %v", err)
 		}
 	}
+	if subMgr == nil && opts.WatchDefinitions {
+		// Keep a manager to reload into when agents appear later.
+		subMgr = subagents.NewManager()
+	}
+	taskStore, err := openTaskLedger(opts)
+	if err != nil {
+		return nil, err
+	}
 	registry := tool.NewRegistry()
-	taskTool, err := registerTools(registry, opts, settings, skReg, cmdExec)
+	taskTool, err := registerTools(registry, opts, settings, skReg, cmdExec, taskStore)
 	if err != nil {
 		return nil, err
 	}
+	if err := applyToolPolicies(registry, settings); err != nil {
+		return nil, err
+	}
 	mcpServers := collectMCPServers(settings, opts.MCPServers)
-	if err := registerMCPServers(ctx, registry, sbox, mcpServers); err != nil {
+	if err := registerMCPServers(registry, sbox, mcpServers); err != nil {
 		return nil, err
 	}
-	executor := tool.NewExecutor(registry, sbox).WithOutputPersister(tool.NewOutputPersister())
+	if len(mcpServers) > 0 && opts.MCPHealthInterval >= 0 {
+		registry.StartMCPHealthChecks(opts.MCPHealthInterval)
+	}
+	executor := tool.NewExecutor(registry, sbox).WithOutputPersister(newOutputPersister(opts, settings))
+	if opts.ArtifactStore != nil {
+		executor = executor.WithArtifactStore(opts.ArtifactStore)
+	}
+	retryPolicy := tool.DefaultRetryPolicy()
+	if opts.ToolRetry != nil {
+		retryPolicy = *opts.ToolRetry
+	}
+	executor = executor.WithRetryPolicy(retryPolicy)
 
 	recorder := defaultHookRecorder()
 	hooks := newHookExecutor(opts, recorder, settings)
-	compactor := newCompactor(opts.ProjectRoot, opts.AutoCompact, opts.Model, opts.TokenLimit, hooks)
+	compactCfg := opts.Compaction
+	if !compactCfg.Enabled {
+		compactCfg = opts.AutoCompact
+	}
+	compactor := newCompactor(opts.ProjectRoot, compactCfg, opts.Model, opts.TokenLimit, hooks)
+	compactor.setAtRest(opts.Compression, opts.Encryption)
+	compactor.observe(opts.Middleware)
 
 	// Initialize tracer (noop without 'otel' build tag)
 	tracer, err := NewTracer(opts.OTEL)
 	if err != nil {
 		return nil, fmt.Errorf("otel tracer init: %w", err)
 	}
+	audit := opts.AuditLogger
+	if audit == nil {
+		audit, err = NewAuditLogger(opts.OTEL)
+		if err != nil {
+			return nil, fmt.Errorf("otel audit logger init: %w", err)
+		}
+	}
 
 	var rulesLoader *config.RulesLoader
 	if opts.RulesEnabled == nil || (opts.RulesEnabled != nil && *opts.RulesEnabled) {
@@ -182,42 +285,107 @@ func New(ctx context.Context, opts Options) (*Runtime, error) {
 	if settings != nil && settings.CleanupPeriodDays != nil {
 		retainDays = *settings.CleanupPeriodDays
 	}
+	sessionStore := opts.SessionStore
+	if sessionStore != nil && (opts.Compression != nil || opts.Encryption != nil) {
+		sealable, ok := sessionStore.(session.Sealable)
+		if !ok {
+			return nil, fmt.Errorf("api: session store %T cannot apply Compression or Encryption", sessionStore)
+		}
+		sealable.SetSealer(atRestSealer{codec: opts.Compression, enc: opts.Encryption})
+	}
+	if sessionStore != nil {
+		histories.loader = func(sessionID string) ([]message.Message, error) {
+			return sessionStore.Load(context.Background(), sessionID)
+		}
+		if retainDays > 0 {
+			if _, err := sessionStore.Cleanup(ctx, time.Now().AddDate(0, 0, -retainDays)); err != nil {
+				log.Printf("session cleanup warning: %v", err)
+			}
+		}
+	}
 	if retainDays > 0 {
-		historyPersister = newDiskHistoryPersister(opts.ProjectRoot)
+		if sessionStore == nil {
+			historyPersister = newDiskHistoryPersister(opts.ProjectRoot)
+		}
 		if historyPersister != nil {
+			historyPersister.codec = opts.Compression
+			historyPersister.enc = opts.Encryption
 			histories.loader = historyPersister.Load
 			if err := historyPersister.Cleanup(retainDays); err != nil {
 				log.Printf("history cleanup warning: %v", err)
 			}
 		}
+		pruneArtifacts(opts.ArtifactStore, retainDays)
+	}
+
+	egress, err := startEgressProxy(settings, sbox)
+	if err != nil {
+		return nil, fmt.Errorf("api: start egress proxy: %w", err)
 	}
+	isolator := buildCommandIsolator(opts, settings, sbRoot, egress)
+	if isolator != nil && !isolator.Status().Enforced {
+		log.Printf("sandbox: bash commands are not isolated: %s", isolator.Status().Reason)
+	}
+	if isolator != nil && isolator.Status().EgressReason != "" {
+		log.Printf("sandbox: bash egress is not enforced: %s", isolator.Status().EgressReason)
+	}
+	attachIsolator(registry, isolator)
 
 	rt := &Runtime{
 		opts:             opts,
 		mode:             mode,
 		settings:         settings,
+		provenance:       provenance,
+		warmKey:          warmKey,
 		cfg:              projectConfigFromSettings(settings),
 		fs:               fsLayer,
 		rulesLoader:      rulesLoader,
 		sandbox:          sbox,
 		sbRoot:           sbRoot,
+		isolator:         isolator,
+		egress:           egress,
 		registry:         registry,
 		executor:         executor,
 		recorder:         recorder,
 		hooks:            hooks,
 		histories:        histories,
 		historyPersister: historyPersister,
+		sessionStore:     sessionStore,
 		cmdExec:          cmdExec,
 		skReg:            skReg,
 		subMgr:           subMgr,
+		taskTool:         taskTool,
 		tokens:           newTokenTracker(opts.TokenTracking, opts.TokenCallback),
 		compactor:        compactor,
 		tracer:           tracer,
+		audit:            audit,
+		prompts:          loadPromptLibrary(opts),
+		scratch:          newScratchSpace(opts.ScratchDir, opts.ScratchRetention),
+		replay:           newReplayStore(opts.StreamReplayLimit, opts.StreamReplayRetention),
+		queue:            newRunQueue(opts.MaxConcurrentRuns, opts.RunQueueTimeout),
+		tasks:            taskStore,
+		rateLimiter:      opts.RateLimiter,
+	}
+	if rt.rateLimiter == nil {
+		rt.rateLimiter = model.NewRateLimiter()
 	}
 	rt.sessionGate = newSessionGate()
+	histories.onEvict = rt.forgetEvicted
+	rt.scratch.startJanitor()
+	if ws, ok := workspace.Detect(opts.ProjectRoot); ok {
+		rt.workspace = &ws
+	}
 
 	if taskTool != nil {
 		taskTool.SetRunner(rt.taskRunner())
+		if subMgr != nil {
+			taskTool.SetSubagents(subMgr.List())
+		}
+	}
+	if opts.WatchDefinitions {
+		if err := rt.watchDefinitions(); err != nil {
+			log.Printf("definitions watcher warning: %v", err)
+		}
 	}
 	return rt, nil
 }
@@ -241,6 +409,9 @@ func (rt *Runtime) Run(ctx context.Context, req Request) (*Response, error) {
 	if rt == nil {
 		return nil, ErrRuntimeClosed
 	}
+	if err := rt.checkVoice(req); err != nil {
+		return nil, err
+	}
 	if err := rt.beginRun(); err != nil {
 		return nil, err
 	}
@@ -251,22 +422,46 @@ func (rt *Runtime) Run(ctx context.Context, req Request) (*Response, error) {
 		sessionID = defaultSessionID(rt.mode.EntryPoint)
 	}
 	req.SessionID = sessionID
+	if strings.TrimSpace(req.RequestID) == "" {
+		req.RequestID = newID(rt.idGenerator(), IDRun)
+	}
 
 	if err := rt.sessionGate.Acquire(ctx, sessionID); err != nil {
 		return nil, ErrConcurrentExecution
 	}
 	defer rt.sessionGate.Release(sessionID)
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	runID, run := rt.active.add(req.RequestID, sessionID, false, cancel)
+	defer rt.active.remove(runID)
+	ctx = withActiveRun(ctx, run)
+	if err := rt.admitRun(ctx, run); err != nil {
+		return nil, err
+	}
+	defer rt.queue.release()
 
+	transcript, err := rt.transcribe(ctx, &req)
+	if err != nil {
+		return nil, canceledRunError(ctx, err)
+	}
 	prep, err := rt.prepare(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, canceledRunError(ctx, err)
 	}
 	defer rt.persistHistory(prep.normalized.SessionID, prep.history)
+	started := time.Now()
 	result, err := rt.runAgent(prep)
+	rt.publishSessionEnd(prep.normalized.SessionID, err)
+	rt.experiments.recordRun(prep.normalized.Tags, result, time.Since(started), err)
 	if err != nil {
-		return nil, err
+		return nil, canceledRunError(ctx, err)
+	}
+	resp := rt.buildResponse(prep, result)
+	resp.Transcript = transcript
+	if req.Speak {
+		rt.speak(prep.ctx, resp)
 	}
-	return rt.buildResponse(prep, result), nil
+	return resp, nil
 }
 
 // RunStream executes the pipeline asynchronously and returns events over a channel.
@@ -274,46 +469,87 @@ func (rt *Runtime) RunStream(ctx context.Context, req Request) (<-chan StreamEve
 	if rt == nil {
 		return nil, ErrRuntimeClosed
 	}
-	if strings.TrimSpace(req.Prompt) == "" && len(req.ContentBlocks) == 0 {
+	if strings.TrimSpace(req.Prompt) == "" && strings.TrimSpace(req.PromptTemplate) == "" && len(req.ContentBlocks) == 0 && len(req.AttachmentIDs) == 0 && len(req.Attachments) == 0 && req.Audio == nil {
 		return nil, errors.New("api: prompt is empty")
 	}
+	if err := rt.checkVoice(req); err != nil {
+		return nil, err
+	}
 	sessionID := strings.TrimSpace(req.SessionID)
 	if sessionID == "" {
 		sessionID = defaultSessionID(rt.mode.EntryPoint)
 	}
 	req.SessionID = sessionID
+	if strings.TrimSpace(req.RequestID) == "" {
+		req.RequestID = newID(rt.idGenerator(), IDRun)
+	}
 
 	if err := rt.beginRun(); err != nil {
 		return nil, err
 	}
 
 	// 缓冲区增大以吸收前端延迟（逐字符渲染等）导致的背压，避免 progress emit 阻塞工具执行
-	out := make(chan StreamEvent, 512)
+	stream := make(chan StreamEvent, 512)
+	out := make(chan StreamEvent)
 	progressChan := make(chan StreamEvent, 256)
 	baseCtx := ctx
 	if baseCtx == nil {
 		baseCtx = context.Background()
 	}
+	baseCtx, cancel := context.WithCancelCause(baseCtx)
 	progressMW := newProgressMiddleware(progressChan)
-	ctxWithEmit := withStreamEmit(baseCtx, progressMW.streamEmit())
+	ids := newRunIDs(rt.idGenerator(), req.RequestID)
+	ctxWithEmit := withRunIDs(withStreamEmit(baseCtx, progressMW.streamEmit()), ids)
+	// Every event, including those sent before the run starts, passes the
+	// stamper so consumers can correlate it without heuristics.
+	go func() {
+		defer close(stream)
+		stamper := &eventStamper{ids: ids}
+		for evt := range out {
+			stream <- stamper.stamp(evt)
+		}
+	}()
 	go func() {
 		defer rt.endRun()
 		defer close(out)
+		defer cancel(nil)
 		if err := rt.sessionGate.Acquire(ctxWithEmit, sessionID); err != nil {
 			isErr := true
 			out <- StreamEvent{Type: EventError, Output: ErrConcurrentExecution.Error(), IsError: &isErr}
 			return
 		}
 		defer rt.sessionGate.Release(sessionID)
+		runID, run := rt.active.add(req.RequestID, sessionID, true, cancel)
+		defer rt.active.remove(runID)
+		ctxWithEmit = withActiveRun(ctxWithEmit, run)
+		if err := rt.admitRun(ctxWithEmit, run); err != nil {
+			isErr := true
+			out <- StreamEvent{Type: EventError, Output: err.Error(), IsError: &isErr}
+			return
+		}
+		defer rt.queue.release()
 
+		if req.Audio != nil {
+			transcript, err := rt.transcribe(ctxWithEmit, &req)
+			if err != nil {
+				isErr := true
+				out <- StreamEvent{Type: EventError, Output: canceledRunError(ctxWithEmit, err).Error(), IsError: &isErr}
+				return
+			}
+			out <- StreamEvent{Type: EventTranscript, SessionID: sessionID, Output: transcript}
+		}
 		prep, err := rt.prepare(ctxWithEmit, req)
 		if err != nil {
 			isErr := true
-			out <- StreamEvent{Type: EventError, Output: err.Error(), IsError: &isErr}
+			out <- StreamEvent{Type: EventError, Output: canceledRunError(ctxWithEmit, err).Error(), IsError: &isErr}
 			return
 		}
 		defer rt.persistHistory(prep.normalized.SessionID, prep.history)
 
+		var speech *voice.Stream
+		if req.Speak {
+			speech = rt.newSpeechStream(ctxWithEmit, sessionID, out)
+		}
 		done := make(chan struct{})
 		go func() {
 			defer close(done)
@@ -327,38 +563,38 @@ func (rt *Runtime) RunStream(ctx context.Context, req Request) (<-chan StreamEve
 				case <-ctxWithEmit.Done():
 					dropping = true
 				}
+				if speech != nil && event.Type == EventContentBlockDelta && event.Delta != nil && event.Delta.Type == "text_delta" {
+					speech.Write(event.Delta.Text)
+				}
 			}
 		}()
 
 		var runErr error
 		var result runResult
-		defer func() {
-			if rt.hooks != nil {
-				reason := "completed"
-				if runErr != nil {
-					reason = "error"
-				}
-				//nolint:errcheck // session end events are non-critical notifications
-				rt.hooks.Publish(coreevents.Event{
-					Type:      coreevents.SessionEnd,
-					SessionID: req.SessionID,
-					Payload:   coreevents.SessionEndPayload{SessionID: req.SessionID, Reason: reason},
-				})
-			}
-		}()
+		defer func() { rt.publishSessionEnd(req.SessionID, runErr) }()
 
+		started := time.Now()
 		result, runErr = rt.runAgentWithMiddleware(prep, progressMW)
+		rt.experiments.recordRun(prep.normalized.Tags, result, time.Since(started), runErr)
 		close(progressChan)
 		<-done
+		if speech != nil {
+			if err := speech.Close(); err != nil {
+				log.Printf("api: text-to-speech failed: %v", err)
+			}
+		}
 
 		if runErr != nil {
 			isErr := true
-			out <- StreamEvent{Type: EventError, Output: runErr.Error(), IsError: &isErr}
+			out <- StreamEvent{Type: EventError, Output: canceledRunError(ctxWithEmit, runErr).Error(), IsError: &isErr}
 			return
 		}
-		rt.buildResponse(prep, result)
+		resp := rt.buildResponse(prep, result)
+		for _, channel := range sortedChannels(resp.Outputs) {
+			out <- StreamEvent{Type: EventChannelOutput, Name: channel, SessionID: sessionID, Output: resp.Outputs[channel]}
+		}
 	}()
-	return out, nil
+	return stream, nil
 }
 
 // Close releases held resources.
@@ -390,11 +626,28 @@ func (rt *Runtime) Close() error {
 				}
 			}
 		}
+		rt.scratch.close()
+		if e := rt.shellSessions().Close(); e != nil {
+			err = errors.Join(err, e)
+		}
+		if closer, ok := rt.isolator.(io.Closer); ok {
+			if e := closer.Close(); e != nil {
+				err = errors.Join(err, e)
+			}
+		}
+		if e := rt.egress.Close(); e != nil {
+			err = errors.Join(err, e)
+		}
 		if rt.rulesLoader != nil {
 			if e := rt.rulesLoader.Close(); e != nil {
 				err = errors.Join(err, e)
 			}
 		}
+		if rt.defWatch != nil {
+			if e := rt.defWatch.Close(); e != nil {
+				err = errors.Join(err, e)
+			}
+		}
 		if rt.registry != nil {
 			rt.registry.Close()
 		}
@@ -403,6 +656,12 @@ func (rt *Runtime) Close() error {
 				err = errors.Join(err, e)
 			}
 		}
+		// Caller-supplied audit loggers may be shared; only flush our own.
+		if rt.audit != nil && rt.opts.AuditLogger == nil {
+			if e := rt.audit.Shutdown(); e != nil {
+				err = errors.Join(err, e)
+			}
+		}
 		rt.closeErr = err
 	})
 	return rt.closeErr
@@ -455,35 +714,86 @@ type preparedRun struct {
 	subagentResult *subagents.Result
 	mode           ModeContext
 	toolWhitelist  map[string]struct{}
+	template       *RequestTemplate
+	trace          tracecontext.TraceContext
+	scratch        string
+	scope          workspaceScope
+	provenance     *provenanceStamper
+	ids            *runIDs
 }
 
 type runResult struct {
 	output *agent.ModelOutput
 	usage  model.Usage
 	reason string
+	// contentFilter is the category of a content-filter stop, if any.
+	contentFilter string
+	artifacts     []tool.Artifact
+	findings      []security.Finding
 }
 
 func (rt *Runtime) prepare(ctx context.Context, req Request) (preparedRun, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	// Nested runs (subagents, workflows) inherit the caller's blackboard.
+	if _, ok := blackboard.FromContext(ctx); !ok {
+		ctx = blackboard.WithBoard(ctx, blackboard.New())
+	}
 	fallbackSession := defaultSessionID(rt.mode.EntryPoint)
 	normalized := req.normalized(rt.mode, fallbackSession)
+	attached, err := attachmentBlocks(normalized.Attachments)
+	if err != nil {
+		return preparedRun{}, err
+	}
+	normalized.ContentBlocks = append(normalized.ContentBlocks, attached...)
+	attachments, err := rt.resolveAttachments(ctx, normalized.SessionID, normalized.AttachmentIDs)
+	if err != nil {
+		return preparedRun{}, err
+	}
+	normalized.ContentBlocks = append(normalized.ContentBlocks, attachments...)
+	if normalized.SessionID == "" {
+		normalized.SessionID = fallbackSession
+	}
+	// Auto-generate RequestID if not provided
+	if normalized.RequestID == "" {
+		normalized.RequestID = newID(rt.idGenerator(), IDRun)
+	}
+	scratch := rt.scratch.runDir(normalized.SessionID, normalized.RequestID)
+	if scratch != "" {
+		if normalized.PromptVars == nil {
+			normalized.PromptVars = map[string]any{}
+		}
+		if _, ok := normalized.PromptVars[scratchPromptVar]; !ok {
+			normalized.PromptVars[scratchPromptVar] = scratch
+		}
+	}
+	if err := rt.renderPrompt(&normalized); err != nil {
+		return preparedRun{}, err
+	}
 	prompt := strings.TrimSpace(normalized.Prompt)
 	if prompt == "" && len(normalized.ContentBlocks) == 0 {
 		return preparedRun{}, errors.New("api: prompt is empty")
 	}
 
-	if normalized.SessionID == "" {
-		normalized.SessionID = fallbackSession
+	template, err := rt.applyTemplate(&normalized)
+	if err != nil {
+		return preparedRun{}, err
 	}
+	template = rt.applyExperiments(&normalized, template)
+	ctx = rt.applyFlags(ctx, &normalized)
 
-	// Auto-generate RequestID if not provided (UUID tracking)
-	if normalized.RequestID == "" {
-		normalized.RequestID = uuid.New().String()
+	trace := runTraceContext(ctx, normalized)
+	ctx = tracecontext.WithContext(ctx, trace)
+	ctx = model.ContextWithRateLimiter(ctx, rt.rateLimiter)
+	if rt.registry != nil {
+		// MCP servers connect on the first run that offers their tools.
+		rt.registry.ConnectMCP(ctx)
 	}
 
 	history := rt.histories.Get(normalized.SessionID)
+	rt.refreshHistory(ctx, normalized.SessionID, history)
+	rt.sessionTags.note(normalized.SessionID, normalized.Tags)
 	recorder := defaultHookRecorder()
 
 	if rt.compactor != nil {
@@ -513,8 +823,8 @@ func (rt *Runtime) prepare(ctx context.Context, req Request) (preparedRun, error
 	}
 	prompt = promptAfterSubagent
 	activation.Prompt = prompt
-	whitelist := combineToolWhitelists(normalized.ToolWhitelist, nil)
-	return preparedRun{
+	whitelist := rt.flaggedTools(ctx, combineToolWhitelists(normalized.ToolWhitelist, nil))
+	prep := preparedRun{
 		ctx:            ctx,
 		prompt:         prompt,
 		contentBlocks:  normalized.ContentBlocks,
@@ -526,7 +836,32 @@ func (rt *Runtime) prepare(ctx context.Context, req Request) (preparedRun, error
 		subagentResult: subRes,
 		mode:           normalized.Mode,
 		toolWhitelist:  whitelist,
-	}, nil
+		template:       template,
+		trace:          trace,
+		scratch:        scratch,
+		scope:          rt.scopeWorkspace(normalized.WorkDir),
+		ids:            runIDsFor(ctx, rt.idGenerator(), normalized.RequestID),
+	}
+	prep.provenance = rt.newProvenanceStamper(prep)
+	return prep, nil
+}
+
+// publishSessionEnd runs SessionEnd hooks once a run has finished. They are
+// notifications: failures are ignored.
+func (rt *Runtime) publishSessionEnd(sessionID string, runErr error) {
+	if rt.hooks == nil {
+		return
+	}
+	reason := "completed"
+	if runErr != nil {
+		reason = "error"
+	}
+	//nolint:errcheck // session end events are non-critical notifications
+	rt.hooks.Publish(coreevents.Event{
+		Type:      coreevents.SessionEnd,
+		SessionID: sessionID,
+		Payload:   coreevents.SessionEndPayload{SessionID: sessionID, Reason: reason},
+	})
 }
 
 func (rt *Runtime) runAgent(prep preparedRun) (runResult, error) {
@@ -556,21 +891,51 @@ func (rt *Runtime) runAgentWithMiddleware(prep preparedRun, extras ...middleware
 		enableCache = *prep.normalized.EnablePromptCache
 	}
 
-	hookAdapter := &runtimeHookAdapter{executor: rt.hooks, recorder: prep.recorder}
+	span := rt.startRunSpan(&prep)
+	var runErr error
+	var spanAttrs map[string]any
+	defer func() { rt.endRunSpan(span, spanAttrs, runErr) }()
+	defer rt.scratch.release(prep.scratch)
+	defer rt.releaseRunShell(prep.normalized.RequestID)
+
+	audit := newAuditEmitter(rt.audit, prep.normalized.SessionID, prep.normalized.RequestID)
+	permMode := rt.permissionMode(prep.template.permissionMode())
+	prep.ctx = withPermissionMode(prep.ctx, permMode)
+	if audit != nil {
+		audit.traceID = prep.trace.TraceID
+	}
+	hookAdapter := &runtimeHookAdapter{executor: rt.hooks, recorder: prep.recorder, audit: audit, sessionID: prep.normalized.SessionID}
+	var sessionContext string
+	if prep.history.Len() == 0 {
+		sessionContext = hookAdapter.startSession(prep.ctx, coreevents.SessionStartPayload{
+			SessionID: prep.normalized.SessionID,
+			Source:    "startup",
+			AgentType: prep.normalized.TargetSubagent,
+		})
+	}
+	userMiddleware := make([]middleware.Middleware, 0, len(rt.opts.Middleware)+len(extras))
+	userMiddleware = append(userMiddleware, flaggedMiddleware(prep.ctx, rt.opts.Middleware)...)
+	userMiddleware = redactorsFirst(append(userMiddleware, extras...))
+	redact := redactorsOf(userMiddleware)
 	modelAdapter := &conversationModel{
 		base:          selectedModel,
 		history:       prep.history,
 		prompt:        prep.prompt,
+		promptContext: sessionContext,
 		contentBlocks: prep.contentBlocks,
 		trimmer:       rt.newTrimmer(),
 		tools:         availableTools(rt.registry, prep.toolWhitelist),
-		systemPrompt:  rt.opts.SystemPrompt,
+		systemPrompt:  rt.taskLedgerPrompt(prep.scope.systemPrompt(rt, prep.template.systemPrompt(rt.opts.SystemPrompt))),
 		rulesLoader:   rt.rulesLoader,
 		enableCache:   enableCache,
 		hooks:         hookAdapter,
 		recorder:      prep.recorder,
 		compactor:     rt.compactor,
 		sessionID:     prep.normalized.SessionID,
+		run:           activeRunFromContext(prep.ctx),
+		ids:           prep.ids,
+		contentFilter: rt.opts.ContentFilter,
+		redact:        redact,
 	}
 
 	toolExec := &runtimeToolExecutor{
@@ -581,42 +946,58 @@ func (rt *Runtime) runAgentWithMiddleware(prep preparedRun, extras ...middleware
 		root:               rt.sbRoot,
 		host:               "localhost",
 		sessionID:          prep.normalized.SessionID,
-		permissionResolver: buildPermissionResolver(hookAdapter, rt.opts.PermissionRequestHandler, rt.opts.ApprovalQueue, rt.opts.ApprovalApprover, rt.opts.ApprovalWhitelistTTL, rt.opts.ApprovalWait),
-	}
-
-	chainItems := make([]middleware.Middleware, 0, len(rt.opts.Middleware)+len(extras))
-	if len(rt.opts.Middleware) > 0 {
-		chainItems = append(chainItems, rt.opts.Middleware...)
-	}
-	if len(extras) > 0 {
-		chainItems = append(chainItems, extras...)
-	}
+		audit:              audit,
+		scratch:            prep.scratch,
+		workDir:            prep.scope.workDir,
+		provenance:         prep.provenance,
+		scan:               rt.newFileScanner(),
+		ids:                prep.ids,
+		redact:             redact,
+		permissionResolver: applyPermissionMode(permMode, buildPermissionResolver(hookAdapter, rt.rememberPermissions(rt.opts.permissionHandler()), rt.opts.ApprovalQueue, rt.opts.ApprovalApprover, rt.opts.ApprovalWhitelistTTL, rt.opts.ApprovalWait)),
+	}
+
+	chainItems := make([]middleware.Middleware, 0, len(userMiddleware)+1)
+	chainItems = append(chainItems, rt.toolRefresher(modelAdapter, prep.toolWhitelist))
+	chainItems = append(chainItems, userMiddleware...)
 	chain := middleware.NewChain(chainItems, middleware.WithTimeout(rt.opts.MiddlewareTimeout))
 	ag, err := agent.New(modelAdapter, toolExec, agent.Options{
 		MaxIterations: rt.opts.MaxIterations,
 		Timeout:       rt.opts.Timeout,
 		Middleware:    chain,
+		MaxTokens:     rt.opts.MaxTokens,
+		MaxCostUSD:    rt.opts.MaxCostUSD,
 	})
 	if err != nil {
+		runErr = err
 		return runResult{}, err
 	}
 
 	agentCtx := agent.NewContext()
+	rt.restoreSessionValues(prep.normalized.SessionID, agentCtx)
 	if sessionID := strings.TrimSpace(prep.normalized.SessionID); sessionID != "" {
-		agentCtx.Values["session_id"] = sessionID
+		agent.Set(agentCtx, agent.SessionIDKey, sessionID)
 	}
 	// Propagate RequestID through agent context for distributed tracing
 	if requestID := strings.TrimSpace(prep.normalized.RequestID); requestID != "" {
-		agentCtx.Values["request_id"] = requestID
+		agent.Set(agentCtx, agent.RequestIDKey, requestID)
 	}
 	if len(prep.normalized.ForceSkills) > 0 {
-		agentCtx.Values["request.force_skills"] = append([]string(nil), prep.normalized.ForceSkills...)
+		agent.Set(agentCtx, agent.ForceSkillsKey, append([]string(nil), prep.normalized.ForceSkills...))
 	}
 	if rt.skReg != nil {
-		agentCtx.Values["skills.registry"] = rt.skReg
+		agent.Set(agentCtx, skillsRegistryKey, rt.skReg)
+	}
+	experiments := experimentAssignments(prep.normalized.Tags)
+	if len(experiments) > 0 {
+		agent.Set(agentCtx, agent.ExperimentsKey, experiments)
 	}
 	out, err := ag.Run(prep.ctx, agentCtx)
+	rt.saveSessionValues(prep.normalized.SessionID, agentCtx)
+	if modelAdapter.contentFiltered != "" {
+		spanAttrs = map[string]any{"agent.content_filter": modelAdapter.contentFiltered}
+	}
 	if err != nil {
+		runErr = err
 		return runResult{}, err
 	}
 	if rt.tokens != nil && rt.tokens.IsEnabled() {
@@ -631,6 +1012,7 @@ func (rt *Runtime) runAgentWithMiddleware(prep preparedRun, extras ...middleware
 			Model:         stats.Model,
 			SessionID:     stats.SessionID,
 			RequestID:     stats.RequestID,
+			Experiments:   experiments,
 		}
 		if rt.hooks != nil {
 			//nolint:errcheck // token usage events are non-critical notifications
@@ -650,7 +1032,11 @@ func (rt *Runtime) runAgentWithMiddleware(prep preparedRun, extras ...middleware
 			})
 		}
 	}
-	return runResult{output: out, usage: modelAdapter.usage, reason: modelAdapter.stopReason}, nil
+	reason := modelAdapter.stopReason
+	if out != nil && out.StopReason != "" {
+		reason = out.StopReason
+	}
+	return runResult{output: out, usage: modelAdapter.usage, reason: reason, contentFilter: modelAdapter.contentFiltered, artifacts: toolExec.collected(), findings: toolExec.scan.collected()}, nil
 }
 
 func (rt *Runtime) buildResponse(prep preparedRun, result runResult) *Response {
@@ -661,6 +1047,7 @@ func (rt *Runtime) buildResponse(prep preparedRun, result runResult) *Response {
 	resp := &Response{
 		Mode:            prep.mode,
 		RequestID:       prep.normalized.RequestID,
+		TraceID:         prep.trace.TraceID,
 		Result:          convertRunResult(result),
 		CommandResults:  prep.commandResults,
 		SkillResults:    prep.skillResults,
@@ -670,7 +1057,12 @@ func (rt *Runtime) buildResponse(prep preparedRun, result runResult) *Response {
 		Settings:        rt.Settings(),
 		SandboxSnapshot: rt.sandboxReport(),
 		Tags:            maps.Clone(prep.normalized.Tags),
+		Artifacts:       result.artifacts,
+		Findings:        result.findings,
 	}
+	resp.Provenance = prep.provenance.response(prep.ctx, resp.Result)
+	rt.sessionArtifacts.note(prep.normalized.SessionID, result.artifacts)
+	resp.Outputs = renderChannelOutputs(prep.normalized.Channels, resp)
 	return resp
 }
 
@@ -707,6 +1099,10 @@ func (rt *Runtime) sandboxReport() SandboxReport {
 		}
 	}
 	report.AllowedDomains = cloneStrings(cleanedDomains)
+	if rt.isolator != nil {
+		report.OS = rt.isolator.Status()
+	}
+	report.Egress = rt.egress.Status()
 	return report
 }
 
@@ -718,12 +1114,16 @@ func convertRunResult(res runResult) *Result {
 	for i, call := range res.output.ToolCalls {
 		toolCalls[i] = model.ToolCall{Name: call.Name, Arguments: call.Input}
 	}
-	return &Result{
+	out := &Result{
 		Output:     res.output.Content,
 		ToolCalls:  toolCalls,
 		Usage:      res.usage,
 		StopReason: res.reason,
 	}
+	if res.reason == model.StopReasonContentFilter {
+		out.ContentFilter = res.contentFilter
+	}
+	return out
 }
 
 func (rt *Runtime) executeCommands(ctx context.Context, prompt string, req *Request) ([]CommandExecution, string, error) {
@@ -865,6 +1265,9 @@ func (rt *Runtime) runTaskInvocation(ctx context.Context, req toolbuiltin.TaskRe
 	if prompt == "" {
 		return nil, errors.New("api: task prompt is empty")
 	}
+	if def, ok := rt.subMgr.Lookup(req.SubagentType); ok && def.SystemPrompt != "" {
+		return rt.runSubagentLoop(ctx, def, req)
+	}
 	sessionID := strings.TrimSpace(req.Resume)
 	if sessionID == "" {
 		sessionID = defaultSessionID(rt.mode.EntryPoint)
@@ -967,6 +1370,7 @@ type conversationModel struct {
 	base          model.Model
 	history       *message.History
 	prompt        string
+	promptContext string // SessionStart hook context, sent with the prompt
 	contentBlocks []model.ContentBlock
 	trimmer       *message.Trimmer
 	tools         []model.ToolDefinition
@@ -979,23 +1383,47 @@ type conversationModel struct {
 	recorder      *hookRecorder
 	compactor     *compactor
 	sessionID     string
+	run           *activeRun // registry entry updated with the iteration; may be nil
+	ids           *runIDs    // mints IDs for tool calls the model left unnamed; may be nil
+
+	contentFilter        ContentFilterPolicy
+	contentFilterRetried bool   // the once-per-run rephrase has been used
+	contentFiltered      string // category of the last filtered response, for telemetry
+
+	redact redactors // masks secrets before they enter the history
+
+	stopHookActive    bool // a Stop hook has already sent the model back to work
+	stopContinuations int
 }
 
-func (m *conversationModel) Generate(ctx context.Context, _ *agent.Context) (*agent.ModelOutput, error) {
+func (m *conversationModel) Generate(ctx context.Context, agentCtx *agent.Context) (*agent.ModelOutput, error) {
 	if m.base == nil {
 		return nil, errors.New("model is nil")
 	}
+	if agentCtx != nil {
+		m.run.setIteration(agentCtx.Iteration)
+	}
 
 	if strings.TrimSpace(m.prompt) != "" || len(m.contentBlocks) > 0 {
-		userMsg := message.Message{Role: "user", Content: strings.TrimSpace(m.prompt)}
+		// UserPromptSubmit hooks run first so a blocked prompt never
+		// reaches the history.
+		extra, err := m.hooks.submitPrompt(ctx, m.redact.text(m.prompt))
+		if err != nil {
+			return nil, err
+		}
+		content := strings.TrimSpace(m.prompt)
+		for _, text := range []string{m.promptContext, extra} {
+			if text != "" {
+				content = strings.TrimSpace(content + "\n\n" + text)
+			}
+		}
+		userMsg := message.Message{Role: "user", Content: m.redact.text(content)}
 		if len(m.contentBlocks) > 0 {
-			userMsg.ContentBlocks = convertAPIContentBlocks(m.contentBlocks)
+			userMsg.ContentBlocks = m.redact.blocks(convertAPIContentBlocks(m.contentBlocks))
 		}
 		m.history.Append(userMsg)
-		if err := m.hooks.UserPrompt(ctx, m.prompt); err != nil {
-			return nil, err
-		}
 		m.prompt = ""
+		m.promptContext = ""
 		m.contentBlocks = nil
 	}
 
@@ -1005,6 +1433,76 @@ func (m *conversationModel) Generate(ctx context.Context, _ *agent.Context) (*ag
 		}
 	}
 
+	resp, err := m.complete(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if category, filtered := model.ContentFilterCategory(resp.StopReason); filtered {
+		resp, err = m.handleContentFilter(ctx, resp, category)
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.usage = resp.Usage
+	m.stopReason = resp.StopReason
+
+	// Populate middleware state with model response and usage
+	if st, ok := ctx.Value(model.MiddlewareStateKey).(*middleware.State); ok && st != nil {
+		st.ModelOutput = resp
+		if st.Values == nil {
+			st.Values = map[string]any{}
+		}
+		st.Values["model.response"] = resp
+		st.Values["model.usage"] = resp.Usage
+		st.Values["model.stop_reason"] = resp.StopReason
+	}
+
+	assistant := message.Message{Role: resp.Message.Role, Content: m.redact.text(strings.TrimSpace(resp.Message.Content)), ReasoningContent: m.redact.text(resp.Message.ReasoningContent)}
+	if len(resp.Message.ToolCalls) > 0 {
+		assistant.ToolCalls = make([]message.ToolCall, len(resp.Message.ToolCalls))
+		for i, call := range resp.Message.ToolCalls {
+			assistant.ToolCalls[i] = message.ToolCall{ID: m.ids.toolCall(call.ID), Name: call.Name, Arguments: m.redact.arguments(call.Arguments)}
+		}
+	}
+	m.history.Append(assistant)
+
+	out := &agent.ModelOutput{Content: assistant.Content, Done: len(assistant.ToolCalls) == 0, Usage: resp.Usage}
+	if len(assistant.ToolCalls) > 0 {
+		out.ToolCalls = make([]agent.ToolCall, len(assistant.ToolCalls))
+		for i, call := range assistant.ToolCalls {
+			// Tools run with the model's original arguments; only the
+			// history copy is masked.
+			out.ToolCalls[i] = agent.ToolCall{ID: call.ID, Name: call.Name, Input: resp.Message.ToolCalls[i].Arguments}
+		}
+		for _, tc := range out.ToolCalls {
+			if len(tc.Input) == 0 {
+				log.Printf("WARNING: tool call %q (id=%s) has empty arguments — "+
+					"this usually means the API proxy stripped tool_use.input", tc.Name, tc.ID)
+			}
+		}
+	}
+	if out.Done && m.stopContinuations < maxStopHookContinuations {
+		feedback, err := m.hooks.stop(ctx, coreevents.StopPayload{Reason: resp.StopReason, StopHookActive: m.stopHookActive})
+		if err != nil {
+			return nil, err
+		}
+		if feedback != "" {
+			// A Stop hook blocked the stop: hand its feedback to the model
+			// and ask the agent loop for another turn, so it goes through
+			// middleware, MaxIterations and the budget like any other.
+			m.stopHookActive = true
+			m.stopContinuations++
+			m.history.Append(message.Message{Role: "user", Content: m.redact.text(feedback)})
+			out.Done = false
+			out.Continue = true
+		}
+	}
+	return out, nil
+}
+
+// complete sends the trimmed history to the model and returns its final
+// response, streaming content blocks live when a stream is attached.
+func (m *conversationModel) complete(ctx context.Context) (*model.Response, error) {
 	snapshot := m.history.All()
 	if m.trimmer != nil {
 		snapshot = m.trimmer.Trim(snapshot)
@@ -1038,9 +1536,12 @@ func (m *conversationModel) Generate(ctx context.Context, _ *agent.Context) (*ag
 	// in non-streaming mode but work correctly with streaming. Streaming is
 	// also the production-standard path for the Anthropic API.
 	var resp *model.Response
+	live := newLiveBlocks(ctx)
 	if err := m.base.CompleteStream(ctx, req, func(sr model.StreamResult) error {
 		if sr.Final && sr.Response != nil {
 			resp = sr.Response
+		} else if live != nil {
+			live.handle(sr)
 		}
 		return nil
 	}); err != nil {
@@ -1049,43 +1550,16 @@ func (m *conversationModel) Generate(ctx context.Context, _ *agent.Context) (*ag
 	if resp == nil {
 		return nil, errors.New("model returned no final response")
 	}
-	m.usage = resp.Usage
-	m.stopReason = resp.StopReason
-
-	// Populate middleware state with model response and usage
-	if st, ok := ctx.Value(model.MiddlewareStateKey).(*middleware.State); ok && st != nil {
-		st.ModelOutput = resp
-		if st.Values == nil {
-			st.Values = map[string]any{}
-		}
-		st.Values["model.response"] = resp
-		st.Values["model.usage"] = resp.Usage
-		st.Values["model.stop_reason"] = resp.StopReason
-	}
-
-	assistant := message.Message{Role: resp.Message.Role, Content: strings.TrimSpace(resp.Message.Content), ReasoningContent: resp.Message.ReasoningContent}
-	if len(resp.Message.ToolCalls) > 0 {
-		assistant.ToolCalls = make([]message.ToolCall, len(resp.Message.ToolCalls))
-		for i, call := range resp.Message.ToolCalls {
-			assistant.ToolCalls[i] = message.ToolCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments}
-		}
-	}
-	m.history.Append(assistant)
-
-	out := &agent.ModelOutput{Content: assistant.Content, Done: len(assistant.ToolCalls) == 0}
-	if len(assistant.ToolCalls) > 0 {
-		out.ToolCalls = make([]agent.ToolCall, len(assistant.ToolCalls))
-		for i, call := range assistant.ToolCalls {
-			out.ToolCalls[i] = agent.ToolCall{ID: call.ID, Name: call.Name, Input: call.Arguments}
-		}
-		for _, tc := range out.ToolCalls {
-			if len(tc.Input) == 0 {
-				log.Printf("WARNING: tool call %q (id=%s) has empty arguments — "+
-					"this usually means the API proxy stripped tool_use.input", tc.Name, tc.ID)
+	if live != nil {
+		live.finish(resp.Message)
+		if st, ok := ctx.Value(model.MiddlewareStateKey).(*middleware.State); ok && st != nil {
+			if st.Values == nil {
+				st.Values = map[string]any{}
 			}
+			st.Values[liveStreamStateKey] = true
 		}
 	}
-	return out, nil
+	return resp, nil
 }
 
 type runtimeToolExecutor struct {
@@ -1096,8 +1570,39 @@ type runtimeToolExecutor struct {
 	root      string
 	host      string
 	sessionID string
+	audit     *auditEmitter
+	// scratch is the run scratch directory; empty disables scratch space.
+	scratch string
+	// workDir is the default bash working directory; empty keeps root.
+	workDir string
+	// provenance stamps files written by Write and Edit; nil disables it.
+	provenance *provenanceStamper
+	// scan scans files written by Write and Edit; nil disables it.
+	scan *fileScanner
+	// ids provides the iteration IDs stamped on audit records; may be nil.
+	ids *runIDs
 
 	permissionResolver tool.PermissionResolver
+	// redact masks secrets in tool output before it enters the history.
+	redact redactors
+
+	mu        sync.Mutex
+	artifacts []tool.Artifact
+}
+
+func (t *runtimeToolExecutor) collect(artifacts []tool.Artifact) {
+	if len(artifacts) == 0 {
+		return
+	}
+	t.mu.Lock()
+	t.artifacts = append(t.artifacts, artifacts...)
+	t.mu.Unlock()
+}
+
+func (t *runtimeToolExecutor) collected() []tool.Artifact {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.artifacts)
 }
 
 func (t *runtimeToolExecutor) measureUsage() sandbox.ResourceUsage {
@@ -1130,13 +1635,18 @@ func (t *runtimeToolExecutor) isAllowed(ctx context.Context, name string) bool {
 	return reqAllowed && subAllowed
 }
 
-func (t *runtimeToolExecutor) Execute(ctx context.Context, call agent.ToolCall, _ *agent.Context) (agent.ToolResult, error) {
+func (t *runtimeToolExecutor) Execute(ctx context.Context, call agent.ToolCall, agentCtx *agent.Context) (agent.ToolResult, error) {
 	if t.executor == nil {
 		return agent.ToolResult{}, errors.New("tool executor not initialised")
 	}
 	if !t.isAllowed(ctx, call.Name) {
 		return agent.ToolResult{}, fmt.Errorf("tool %s is not whitelisted", call.Name)
 	}
+	var iterationID string
+	if agentCtx != nil {
+		iterationID = t.ids.iteration(agentCtx.Iteration)
+	}
+	ctx = withCallIDs(ctx, call.ID, iterationID)
 
 	// Defensive check: if tool call has empty/nil arguments but the tool requires
 	// parameters, return a diagnostic error instead of executing with missing params.
@@ -1154,16 +1664,17 @@ func (t *runtimeToolExecutor) Execute(ctx context.Context, call agent.ToolCall,
 						t.history.Append(message.Message{
 							Role: "tool",
 							ToolCalls: []message.ToolCall{{
-								ID:     call.ID,
-								Name:   call.Name,
-								Result: errMsg,
+								ID:      call.ID,
+								Name:    call.Name,
+								Result:  errMsg,
+								IsError: true,
 							}},
 						})
 					}
 					return agent.ToolResult{
 						Name:     call.Name,
 						Output:   errMsg,
-						Metadata: map[string]any{"error": "empty_arguments"},
+						Metadata: map[string]any{"error": "empty_arguments", "is_error": true, "error_detail": tool.NewToolError(tool.ErrorInvalidInput, errors.New(errMsg))},
 					}, nil
 				}
 			}
@@ -1171,19 +1682,29 @@ func (t *runtimeToolExecutor) Execute(ctx context.Context, call agent.ToolCall,
 	}
 
 	// Helper to append tool result to history
-	appendToolResult := func(content string) {
+	var media []message.ContentBlock
+	appendToolResult := func(content string, isError bool) {
 		if t.history != nil {
 			t.history.Append(message.Message{
 				Role: "tool",
 				ToolCalls: []message.ToolCall{{
-					ID:     call.ID,
-					Name:   call.Name,
-					Result: content,
+					ID:           call.ID,
+					Name:         call.Name,
+					Result:       t.redact.text(content),
+					IsError:      isError,
+					ResultBlocks: media,
 				}},
 			})
 		}
 	}
 
+	if call.ID != "" {
+		ctx = context.WithValue(ctx, toolUseIDCtxKey, call.ID)
+	}
+	resolver := t.permissionResolver
+	if emit := streamEmitFromContext(ctx); emit != nil {
+		resolver = announcePermissionRequests(emit, call.ID, resolver)
+	}
 	params, preErr := t.hooks.PreToolUse(ctx, coreToolUsePayload(call))
 	if preErr != nil {
 		if errors.Is(preErr, ErrToolUseRequiresApproval) && t.permissionResolver != nil {
@@ -1191,7 +1712,7 @@ func (t *runtimeToolExecutor) Execute(ctx context.Context, call agent.ToolCall,
 			if params != nil {
 				checkParams = params
 			}
-			decision, err := t.permissionResolver(ctx, tool.Call{
+			decision, err := resolver(ctx, tool.Call{
 				Name:      call.Name,
 				Params:    checkParams,
 				SessionID: t.sessionID,
@@ -1206,8 +1727,14 @@ func (t *runtimeToolExecutor) Execute(ctx context.Context, call agent.ToolCall,
 				switch decision.Action {
 				case security.PermissionAllow:
 					preErr = nil
+					if decision.UpdatedInput != nil {
+						params = decision.UpdatedInput
+					}
 				case security.PermissionDeny:
 					preErr = fmt.Errorf("%w: %s", ErrToolUseDenied, call.Name)
+					if decision.Reason != "" {
+						preErr = fmt.Errorf("%w: %s: %s", ErrToolUseDenied, call.Name, decision.Reason)
+					}
 				default:
 					preErr = fmt.Errorf("%w: %s", ErrToolUseRequiresApproval, call.Name)
 				}
@@ -1216,9 +1743,10 @@ func (t *runtimeToolExecutor) Execute(ctx context.Context, call agent.ToolCall,
 	}
 	if preErr != nil {
 		// Hook denied execution - still need to add tool_result to history
-		errContent := fmt.Sprintf(`{"error":%q}`, preErr.Error())
-		appendToolResult(errContent)
-		return agent.ToolResult{Name: call.Name, Output: errContent, Metadata: map[string]any{"error": preErr.Error()}}, preErr
+		detail := tool.NewToolError(tool.ErrorPermission, preErr)
+		errContent := tool.ErrorContent(detail, "")
+		appendToolResult(errContent, true)
+		return agent.ToolResult{Name: call.Name, Output: errContent, Metadata: map[string]any{"error": preErr.Error(), "is_error": true, "error_detail": detail}}, preErr
 	}
 	if params != nil {
 		call.Input = params
@@ -1247,37 +1775,118 @@ func (t *runtimeToolExecutor) Execute(ctx context.Context, call agent.ToolCall,
 	if t.host != "" {
 		callSpec.Host = t.host
 	}
+	if t.ids != nil {
+		ctx = tool.WithRunID(ctx, t.ids.run)
+	}
+	if t.workDir != "" {
+		ctx = tool.WithWorkDir(ctx, t.workDir)
+	}
+	if t.scratch != "" {
+		iteration := 0
+		if agentCtx != nil {
+			iteration = agentCtx.Iteration
+		}
+		ctx = scratchContext(ctx, t.scratch, iteration)
+	}
 	exec := t.executor
-	if t.permissionResolver != nil {
-		exec = exec.WithPermissionResolver(t.permissionResolver)
+	if resolver != nil {
+		exec = exec.WithPermissionResolver(resolver)
 	}
+	if observer := t.audit.permissionObserver(); observer != nil {
+		exec = exec.WithPermissionObserver(observer)
+	}
+	snap := t.scan.snapshot(call.Name, call.Input)
 	result, err := exec.Execute(ctx, callSpec)
+	t.audit.sandboxViolation(ctx, call.Name, err)
+	if err == nil && result != nil && result.Result != nil && !isDryRun(result.Result.Data) {
+		if scanErr := t.scan.check(ctx, snap); scanErr != nil {
+			err = scanErr
+			result.Result.Output = ""
+			result.Result.Data = nil
+			result.Result.ErrorDetail = tool.NewToolError(tool.ErrorPermission, scanErr)
+		}
+	}
 	toolResult := agent.ToolResult{Name: call.Name}
 	meta := map[string]any{}
 	content := ""
+	if result != nil && result.Attempts > 1 {
+		meta["attempts"] = result.Attempts
+		failures := make([]string, len(result.AttemptErrors))
+		for i, failure := range result.AttemptErrors {
+			failures[i] = failure.Error()
+		}
+		meta["attempt_errors"] = failures
+	}
 	if result != nil && result.Result != nil {
 		toolResult.Output = result.Result.Output
 		meta["data"] = result.Result.Data
 		if result.Result.OutputRef != nil {
 			meta["output_ref"] = result.Result.OutputRef
 		}
+		if len(result.Result.Artifacts) > 0 {
+			meta["artifacts"] = result.Result.Artifacts
+			t.collect(result.Result.Artifacts)
+		}
+		if err == nil {
+			if stamped, ok := t.provenance.file(ctx, call.Name, result.Result.Data); ok {
+				t.collect([]tool.Artifact{stamped})
+			}
+		}
 		content = result.Result.Output
+		if result.Result.Content != nil {
+			meta["content"] = result.Result.Content
+			if content == "" {
+				if encoded, encErr := json.Marshal(result.Result.Content); encErr == nil {
+					content = string(encoded)
+					toolResult.Output = content
+				}
+			}
+		}
+		media = mediaContentBlocks(result.Result.Media)
 	}
 	if err != nil {
+		var detail *tool.ToolError
+		if result != nil && result.Result != nil {
+			detail = result.Result.ErrorDetail
+		}
+		if detail == nil {
+			detail = tool.ClassifyError(err)
+		}
 		meta["error"] = err.Error()
-		content = fmt.Sprintf(`{"error":%q}`, err.Error())
+		meta["is_error"] = true
+		meta["error_detail"] = detail
+		content = tool.ErrorContent(detail, content)
 	}
 	if len(meta) > 0 {
 		toolResult.Metadata = meta
 	}
+	if len(t.redact) > 0 {
+		toolResult.Output = t.redact.text(toolResult.Output)
+		if toolResult.Metadata != nil {
+			toolResult.Metadata, _ = t.redact.value(toolResult.Metadata).(map[string]any)
+		}
+	}
 
-	if hookErr := t.hooks.PostToolUse(ctx, coreToolResultPayload(call, result, err)); hookErr != nil && err == nil {
+	payload := coreToolResultPayload(call, result, err)
+	payload.Result = t.redact.value(payload.Result)
+	feedback, hookErr := t.hooks.postToolUse(ctx, payload)
+	if feedback != "" {
+		// PostToolUse hooks that exit 2 or return "block" report back to
+		// the model; the tool has already run.
+		feedback = t.redact.text("PostToolUse hook feedback: " + feedback)
+		content = strings.TrimSpace(content + "\n\n" + feedback)
+		toolResult.Output = strings.TrimSpace(toolResult.Output + "\n\n" + feedback)
+		if _, blocked := blockingFeedback(hookErr); blocked {
+			hookErr = nil
+		}
+	}
+	if hookErr != nil && err == nil {
 		// Hook failed - still need to add tool_result to history
-		appendToolResult(content)
+		appendToolResult(content, false)
 		return toolResult, hookErr
 	}
 
-	appendToolResult(content)
+	appendToolResult(content, err != nil)
 	return toolResult, err
 }
 
@@ -1295,7 +1904,92 @@ func coreToolResultPayload(call agent.ToolCall, res *tool.CallResult, err error)
 	return payload
 }
 
-func buildPermissionResolver(hooks *runtimeHookAdapter, handler PermissionRequestHandler, approvals *security.ApprovalQueue, approver string, whitelistTTL time.Duration, approvalWait bool) tool.PermissionResolver {
+// announcePermissionRequests emits a permission_request stream event for
+// every PermissionAsk before next (which may be nil) decides it.
+func announcePermissionRequests(emit streamEmitFunc, toolUseID string, next tool.PermissionResolver) tool.PermissionResolver {
+	return func(ctx context.Context, call tool.Call, decision security.PermissionDecision) (security.PermissionDecision, error) {
+		if decision.Action == security.PermissionAsk {
+			emit(ctx, StreamEvent{
+				Type:      EventPermissionRequest,
+				ToolUseID: toolUseID,
+				Name:      call.Name,
+				SessionID: call.SessionID,
+				Output: map[string]any{
+					"input":          call.Params,
+					"rule":           decision.Rule,
+					"target":         decision.Target,
+					"reason":         buildPermissionReason(decision),
+					"suggested_rule": security.SuggestRule(decision),
+					"protected":      decision.Protected,
+					"owners":         decision.Owners,
+				},
+			})
+		}
+		if next == nil {
+			return decision, nil
+		}
+		return next(ctx, call, decision)
+	}
+}
+
+// permissionHandler returns Options.PermissionHandler, falling back to
+// PermissionRequestHandler.
+func (o Options) permissionHandler() PermissionHandler {
+	if o.PermissionHandler != nil {
+		return o.PermissionHandler
+	}
+	return legacyPermissionHandler(o.PermissionRequestHandler)
+}
+
+// rememberPermissions wraps handler so that results with Remember set are
+// written to settings.local.json and applied to the running sandbox.
+func (rt *Runtime) rememberPermissions(handler PermissionHandler) PermissionHandler {
+	if handler == nil {
+		return nil
+	}
+	return func(ctx context.Context, req PermissionRequest) (PermissionResult, error) {
+		result, err := handler(ctx, req)
+		if err != nil || !result.Remember {
+			return result, err
+		}
+		var action security.PermissionAction
+		switch result.Decision {
+		case coreevents.PermissionAllow:
+			action = security.PermissionAllow
+		case coreevents.PermissionDeny:
+			action = security.PermissionDeny
+		default:
+			return result, nil
+		}
+		rule := strings.TrimSpace(result.Rule)
+		if rule == "" {
+			rule = req.SuggestedRule
+		}
+		if rule == "" {
+			return result, nil
+		}
+		if err := config.AddLocalPermissionRule(rt.opts.ProjectRoot, string(action), rule); err != nil {
+			log.Printf("permission rule %q not remembered: %v", rule, err)
+			return result, nil
+		}
+		if err := rt.sandbox.AddPermissionRule(action, rule); err != nil {
+			log.Printf("permission rule %q not applied: %v", rule, err)
+		}
+		return result, nil
+	}
+}
+
+func legacyPermissionHandler(handler PermissionRequestHandler) PermissionHandler {
+	if handler == nil {
+		return nil
+	}
+	return func(ctx context.Context, req PermissionRequest) (PermissionResult, error) {
+		decision, err := handler(ctx, req)
+		return PermissionResult{Decision: decision}, err
+	}
+}
+
+func buildPermissionResolver(hooks *runtimeHookAdapter, handler PermissionHandler, approvals *security.ApprovalQueue, approver string, whitelistTTL time.Duration, approvalWait bool) tool.PermissionResolver {
 	if hooks == nil && handler == nil && approvals == nil {
 		return nil
 	}
@@ -1305,12 +1999,16 @@ func buildPermissionResolver(hooks *runtimeHookAdapter, handler PermissionReques
 		}
 
 		req := PermissionRequest{
-			ToolName:   call.Name,
-			ToolParams: call.Params,
-			SessionID:  call.SessionID,
-			Rule:       decision.Rule,
-			Target:     decision.Target,
-			Reason:     buildPermissionReason(decision),
+			ToolName:      call.Name,
+			ToolParams:    call.Params,
+			SessionID:     call.SessionID,
+			ToolUseID:     toolUseIDFromContext(ctx),
+			Rule:          decision.Rule,
+			Target:        decision.Target,
+			Reason:        buildPermissionReason(decision),
+			SuggestedRule: security.SuggestRule(decision),
+			Protected:     decision.Protected,
+			Owners:        decision.Owners,
 		}
 
 		var record *security.ApprovalRecord
@@ -1355,25 +2053,33 @@ func buildPermissionResolver(hooks *runtimeHookAdapter, handler PermissionReques
 		}
 
 		if handler != nil {
-			hostDecision, err := handler(ctx, req)
+			result, err := handler(ctx, req)
 			if err != nil {
 				return decision, err
 			}
-			switch hostDecision {
+			switch result.Decision {
 			case coreevents.PermissionAllow:
 				if record != nil {
 					if _, err := approvals.Approve(record.ID, approvalActor(approver), whitelistTTL); err != nil {
 						return decision, err
 					}
 				}
-				return decisionWithAction(decision, security.PermissionAllow), nil
+				allowed := decisionWithAction(decision, security.PermissionAllow)
+				allowed.UpdatedInput = result.UpdatedInput
+				return allowed, nil
 			case coreevents.PermissionDeny:
+				reason := strings.TrimSpace(result.Message)
+				if reason == "" {
+					reason = "denied by host"
+				}
 				if record != nil {
-					if _, err := approvals.Deny(record.ID, approvalActor(approver), "denied by host"); err != nil {
+					if _, err := approvals.Deny(record.ID, approvalActor(approver), reason); err != nil {
 						return decision, err
 					}
 				}
-				return decisionWithAction(decision, security.PermissionDeny), nil
+				denied := decisionWithAction(decision, security.PermissionDeny)
+				denied.Reason = strings.TrimSpace(result.Message)
+				return denied, nil
 			}
 		}
 
@@ -1397,16 +2103,21 @@ func buildPermissionResolver(hooks *runtimeHookAdapter, handler PermissionReques
 func buildPermissionReason(decision security.PermissionDecision) string {
 	rule := strings.TrimSpace(decision.Rule)
 	target := strings.TrimSpace(decision.Target)
+	var reason string
 	switch {
 	case rule == "" && target == "":
 		return ""
 	case rule == "":
-		return fmt.Sprintf("target %q", target)
+		reason = fmt.Sprintf("target %q", target)
 	case target == "":
-		return fmt.Sprintf("rule %q", rule)
+		reason = fmt.Sprintf("rule %q", rule)
 	default:
-		return fmt.Sprintf("rule %q for %s", rule, target)
+		reason = fmt.Sprintf("rule %q for %s", rule, target)
 	}
+	if len(decision.Owners) > 0 {
+		reason += fmt.Sprintf(" (owned by %s)", strings.Join(decision.Owners, ", "))
+	}
+	return reason
 }
 
 func formatApprovalCommand(toolName, target string) string {
@@ -1435,7 +2146,7 @@ func approvalActor(approver string) string {
 
 // ----------------- config + registries -----------------
 
-func registerTools(registry *tool.Registry, opts Options, settings *config.Settings, skReg *skills.Registry, cmdExec *commands.Executor) (*toolbuiltin.TaskTool, error) {
+func registerTools(registry *tool.Registry, opts Options, settings *config.Settings, skReg *skills.Registry, cmdExec *commands.Executor, taskStore *tasks.TaskStore) (*toolbuiltin.TaskTool, error) {
 	entry := effectiveEntryPoint(opts)
 	tools := opts.Tools
 	var taskTool *toolbuiltin.TaskTool
@@ -1449,7 +2160,7 @@ func registerTools(registry *tool.Registry, opts Options, settings *config.Setti
 			cmdExec = commands.NewExecutor()
 		}
 
-		factories := builtinToolFactories(opts.ProjectRoot, sandboxDisabled, entry, settings, skReg, cmdExec)
+		factories := builtinToolFactories(opts.ProjectRoot, opts.ScratchDir, sandboxDisabled, entry, settings, skReg, cmdExec, taskStore)
 		names := builtinOrder(entry)
 		selectedNames := filterBuiltinNames(opts.EnabledBuiltinTools, names)
 		for _, name := range selectedNames {
@@ -1521,9 +2232,32 @@ func registerTools(registry *tool.Registry, opts Options, settings *config.Setti
 	return taskTool, nil
 }
 
-func builtinToolFactories(root string, sandboxDisabled bool, entry EntryPoint, settings *config.Settings, skReg *skills.Registry, cmdExec *commands.Executor) map[string]func() tool.Tool {
+func builtinToolFactories(root, scratchRoot string, sandboxDisabled bool, entry EntryPoint, settings *config.Settings, skReg *skills.Registry, cmdExec *commands.Executor, taskStore *tasks.TaskStore) map[string]func() tool.Tool {
 	factories := map[string]func() tool.Tool{}
 
+	// fileSandbox confines file tools, and Bash's redirections, to root,
+	// permissions.additionalDirectories and the scratch space.
+	additionalDirs := additionalSandboxPaths(settings)
+	fileSandbox := func() *security.Sandbox {
+		if sandboxDisabled {
+			return security.NewDisabledSandbox()
+		}
+		sb := security.NewSandbox(root)
+		for _, dir := range additionalDirs {
+			sb.Allow(dir)
+			if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+				sb.Allow(resolved)
+			}
+		}
+		if scratchRoot != "" {
+			sb.Allow(scratchRoot)
+			if resolved, err := filepath.EvalSymlinks(filepath.Dir(scratchRoot)); err == nil {
+				sb.Allow(filepath.Join(resolved, filepath.Base(scratchRoot)))
+			}
+		}
+		return sb
+	}
+
 	var (
 		syncThresholdBytes  int
 		asyncThresholdBytes int
@@ -1539,17 +2273,14 @@ func builtinToolFactories(root string, sandboxDisabled bool, entry EntryPoint, s
 	if asyncThresholdBytes > 0 {
 		toolbuiltin.DefaultAsyncTaskManager().SetMaxOutputLen(asyncThresholdBytes)
 	}
+	shells := newShellSessions(settings)
 
 	bashCtor := func() tool.Tool {
-		var bash *toolbuiltin.BashTool
-		if sandboxDisabled {
-			bash = toolbuiltin.NewBashToolWithSandbox(root, security.NewDisabledSandbox())
-		} else {
-			bash = toolbuiltin.NewBashToolWithRoot(root)
-		}
+		bash := toolbuiltin.NewBashToolWithSandbox(root, fileSandbox())
 		if syncThresholdBytes > 0 {
 			bash.SetOutputThresholdBytes(syncThresholdBytes)
 		}
+		bash.SetShellSessions(shells)
 		if entry == EntryPointCLI {
 			bash.AllowShellMetachars(true)
 		}
@@ -1557,22 +2288,25 @@ func builtinToolFactories(root string, sandboxDisabled bool, entry EntryPoint, s
 	}
 
 	readCtor := func() tool.Tool {
-		if sandboxDisabled {
-			return toolbuiltin.NewReadToolWithSandbox(root, security.NewDisabledSandbox())
+		// Read may also open spilled tool output (see newOutputPersister).
+		sb := fileSandbox()
+		if !sandboxDisabled {
+			outDir := toolOutputBaseDir()
+			sb.Allow(outDir)
+			if resolved, err := filepath.EvalSymlinks(filepath.Dir(outDir)); err == nil {
+				sb.Allow(filepath.Join(resolved, filepath.Base(outDir)))
+			}
 		}
-		return toolbuiltin.NewReadToolWithRoot(root)
+		return toolbuiltin.NewReadToolWithSandbox(root, sb)
 	}
 	writeCtor := func() tool.Tool {
-		if sandboxDisabled {
-			return toolbuiltin.NewWriteToolWithSandbox(root, security.NewDisabledSandbox())
-		}
-		return toolbuiltin.NewWriteToolWithRoot(root)
+		return toolbuiltin.NewWriteToolWithSandbox(root, fileSandbox())
 	}
 	editCtor := func() tool.Tool {
-		if sandboxDisabled {
-			return toolbuiltin.NewEditToolWithSandbox(root, security.NewDisabledSandbox())
-		}
-		return toolbuiltin.NewEditToolWithRoot(root)
+		return toolbuiltin.NewEditToolWithSandbox(root, fileSandbox())
+	}
+	notebookEditCtor := func() tool.Tool {
+		return toolbuiltin.NewNotebookEditToolWithSandbox(root, fileSandbox())
 	}
 
 	respectGitignore := true
@@ -1580,35 +2314,55 @@ func builtinToolFactories(root string, sandboxDisabled bool, entry EntryPoint, s
 		respectGitignore = *settings.RespectGitignore
 	}
 	grepCtor := func() tool.Tool {
-		if sandboxDisabled {
-			grep := toolbuiltin.NewGrepToolWithSandbox(root, security.NewDisabledSandbox())
-			grep.SetRespectGitignore(respectGitignore)
-			return grep
-		}
-		grep := toolbuiltin.NewGrepToolWithRoot(root)
+		grep := toolbuiltin.NewGrepToolWithSandbox(root, fileSandbox())
 		grep.SetRespectGitignore(respectGitignore)
 		return grep
 	}
 	globCtor := func() tool.Tool {
-		if sandboxDisabled {
-			glob := toolbuiltin.NewGlobToolWithSandbox(root, security.NewDisabledSandbox())
-			glob.SetRespectGitignore(respectGitignore)
-			return glob
-		}
-		glob := toolbuiltin.NewGlobToolWithRoot(root)
+		glob := toolbuiltin.NewGlobToolWithSandbox(root, fileSandbox())
 		glob.SetRespectGitignore(respectGitignore)
 		return glob
 	}
-	taskStore := tasks.NewTaskStore()
+	runTestsCtor := func() tool.Tool {
+		if sandboxDisabled {
+			return toolbuiltin.NewRunTestsToolWithSandbox(root, security.NewDisabledSandbox())
+		}
+		return toolbuiltin.NewRunTestsTool(root)
+	}
+	lintCtor := func() tool.Tool {
+		if sandboxDisabled {
+			return toolbuiltin.NewLintToolWithSandbox(root, security.NewDisabledSandbox())
+		}
+		return toolbuiltin.NewLintTool(root)
+	}
+	dependencyAuditCtor := func() tool.Tool {
+		if sandboxDisabled {
+			return toolbuiltin.NewDependencyAuditToolWithSandbox(root, security.NewDisabledSandbox())
+		}
+		return toolbuiltin.NewDependencyAuditTool(root)
+	}
+	if taskStore == nil {
+		taskStore = tasks.NewTaskStore()
+	}
 
 	factories["bash"] = bashCtor
 	factories["file_read"] = readCtor
 	factories["file_write"] = writeCtor
 	factories["file_edit"] = editCtor
+	factories["notebook_edit"] = notebookEditCtor
 	factories["grep"] = grepCtor
 	factories["glob"] = globCtor
-	factories["web_fetch"] = func() tool.Tool { return toolbuiltin.NewWebFetchTool(nil) }
-	factories["web_search"] = func() tool.Tool { return toolbuiltin.NewWebSearchTool(nil) }
+	factories["run_tests"] = runTestsCtor
+	factories["lint"] = lintCtor
+	factories["dependency_audit"] = dependencyAuditCtor
+	factories["web_fetch"] = func() tool.Tool {
+		opts := &toolbuiltin.WebFetchOptions{}
+		if settings != nil && settings.Sandbox != nil && settings.Sandbox.Network != nil {
+			opts.PrivateHostAllowlist = append([]string(nil), settings.Sandbox.Network.AllowPrivateHosts...)
+		}
+		return toolbuiltin.NewWebFetchTool(opts)
+	}
+	factories["web_search"] = func() tool.Tool { return newWebSearchTool(settings) }
 	factories["bash_output"] = func() tool.Tool { return toolbuiltin.NewBashOutputTool(nil) }
 	factories["bash_status"] = func() tool.Tool { return toolbuiltin.NewBashStatusTool() }
 	factories["kill_task"] = func() tool.Tool { return toolbuiltin.NewKillTaskTool() }
@@ -1619,6 +2373,7 @@ func builtinToolFactories(root string, sandboxDisabled bool, entry EntryPoint, s
 	factories["ask_user_question"] = func() tool.Tool { return toolbuiltin.NewAskUserQuestionTool() }
 	factories["skill"] = func() tool.Tool { return toolbuiltin.NewSkillTool(skReg, nil) }
 	factories["slash_command"] = func() tool.Tool { return toolbuiltin.NewSlashCommandTool(cmdExec) }
+	factories["blackboard"] = func() tool.Tool { return toolbuiltin.NewBlackboardTool() }
 
 	if shouldRegisterTaskTool(entry) {
 		factories["task"] = func() tool.Tool { return toolbuiltin.NewTaskTool() }
@@ -1633,6 +2388,7 @@ func builtinOrder(entry EntryPoint) []string {
 		"file_read",
 		"file_write",
 		"file_edit",
+		"notebook_edit",
 		"web_fetch",
 		"web_search",
 		"bash_output",
@@ -1647,6 +2403,10 @@ func builtinOrder(entry EntryPoint) []string {
 		"slash_command",
 		"grep",
 		"glob",
+		"run_tests",
+		"lint",
+		"dependency_audit",
+		"blackboard",
 	}
 	if shouldRegisterTaskTool(entry) {
 		order = append(order, "task")
@@ -1711,24 +2471,20 @@ func effectiveEntryPoint(opts Options) EntryPoint {
 	return entry
 }
 
-func registerMCPServers(ctx context.Context, registry *tool.Registry, manager *sandbox.Manager, servers []mcpServer) error {
+// registerMCPServers records the servers for lazy connection: they are
+// dialed by the first run that needs tools, so a server that is down at
+// startup degrades to a failed MCPStatus entry instead of failing New.
+func registerMCPServers(registry *tool.Registry, manager *sandbox.Manager, servers []mcpServer) error {
 	for _, server := range servers {
 		spec := server.Spec
 		if err := enforceSandboxHost(manager, spec); err != nil {
 			return err
 		}
-		opts := tool.MCPServerOptions{Headers: server.Headers, Env: server.Env}
+		opts := tool.MCPServerOptions{Headers: server.Headers, Env: server.Env, Command: server.Command, Args: server.Args}
 		if server.TimeoutSeconds > 0 {
 			opts.Timeout = time.Duration(server.TimeoutSeconds) * time.Second
 		}
-
-		var err error
-		if len(opts.Headers) == 0 && len(opts.Env) == 0 && opts.Timeout <= 0 {
-			err = registry.RegisterMCPServer(ctx, spec, server.Name)
-		} else {
-			err = registry.RegisterMCPServerWithOptions(ctx, spec, server.Name, opts)
-		}
-		if err != nil {
+		if err := registry.RegisterLazyMCPServer(spec, server.Name, opts); err != nil {
 			return fmt.Errorf("api: register MCP %s: %w", spec, err)
 		}
 	}
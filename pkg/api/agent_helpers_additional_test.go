@@ -79,7 +79,7 @@ func TestRegisterToolsDisallowedAndDuplicates(t *testing.T) {
 		},
 	}
 	settings := &config.Settings{DisallowedTools: []string{"bash"}}
-	taskTool, err := registerTools(reg, opts, settings, nil, nil)
+	taskTool, err := registerTools(reg, opts, settings, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("register tools failed: %v", err)
 	}
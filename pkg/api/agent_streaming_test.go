@@ -2,7 +2,9 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/cexll/agentsdk-go/pkg/model"
@@ -86,3 +88,81 @@ func (s *streamingStubTool) StreamExecute(ctx context.Context, params map[string
 	}
 	return &tool.ToolResult{Success: true, Output: "chunk-1\nchunk-err", Data: params}, nil
 }
+
+// deltaStreamModel streams text and tool arguments in fragments before the
+// final response, like the Anthropic and OpenAI providers.
+type deltaStreamModel struct {
+	stubModel
+	streamed bool
+}
+
+func (m *deltaStreamModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	if m.streamed {
+		return cb(model.StreamResult{Final: true, Response: &model.Response{Message: model.Message{Role: "assistant", Content: "done"}}})
+	}
+	m.streamed = true
+	call := model.ToolCall{ID: "call_1", Name: "echo", Arguments: map[string]any{"text": "hi"}}
+	for _, sr := range []model.StreamResult{
+		{Delta: "Let me "},
+		{Delta: "check."},
+		{ToolCallDelta: &model.ToolCallDelta{Index: 0, ID: "call_1", Name: "echo"}},
+		{ToolCallDelta: &model.ToolCallDelta{Index: 0, PartialJSON: `{"text":`}},
+		{ToolCallDelta: &model.ToolCallDelta{Index: 0, PartialJSON: `"hi"}`}},
+		{ToolCall: &call},
+		{Final: true, Response: &model.Response{Message: model.Message{Role: "assistant", Content: "Let me check.", ToolCalls: []model.ToolCall{call}}}},
+	} {
+		if err := cb(sr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRunStreamEmitsLiveDeltas(t *testing.T) {
+	rt, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: &deltaStreamModel{}, Tools: []tool.Tool{&echoTool{}}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	stream, err := rt.RunStream(context.Background(), Request{Prompt: "go"})
+	if err != nil {
+		t.Fatalf("RunStream: %v", err)
+	}
+	var texts, fragments []string
+	var starts []string
+	for evt := range stream {
+		if evt.Type == EventMessageStop {
+			break
+		}
+		switch evt.Type {
+		case EventContentBlockStart:
+			starts = append(starts, evt.ContentBlock.Type)
+		case EventContentBlockDelta:
+			switch evt.Delta.Type {
+			case "text_delta":
+				texts = append(texts, evt.Delta.Text)
+			case "input_json_delta":
+				if evt.ToolUseID != "call_1" || evt.Name != "echo" {
+					t.Fatalf("input delta not tagged with the call: %+v", evt)
+				}
+				var fragment string
+				if err := json.Unmarshal(evt.Delta.PartialJSON, &fragment); err != nil {
+					t.Fatalf("partial_json: %v", err)
+				}
+				fragments = append(fragments, fragment)
+			}
+		}
+	}
+	for range stream {
+	}
+	if strings.Join(starts, ",") != "text,tool_use" {
+		t.Fatalf("blocks = %v", starts)
+	}
+	if len(texts) != 2 || strings.Join(texts, "") != "Let me check." {
+		t.Fatalf("text deltas = %q", texts)
+	}
+	if len(fragments) != 2 || strings.Join(fragments, "") != `{"text":"hi"}` {
+		t.Fatalf("input fragments = %q", fragments)
+	}
+}
@@ -2,11 +2,13 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -182,6 +184,140 @@ func TestRuntimePermissionAskHandlerDenies(t *testing.T) {
 	}
 }
 
+func TestRuntimePermissionHandlerRewritesInput(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{"permissions":{"ask":["echo"]},"sandbox":{"enabled":true}}`)
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "1", Name: "echo", Arguments: map[string]any{"text": "hi"}}}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	var seen PermissionRequest
+	rt, err := New(context.Background(), Options{
+		ProjectRoot: root,
+		Model:       mdl,
+		Tools:       []tool.Tool{&echoTool{}},
+		PermissionHandler: func(_ context.Context, req PermissionRequest) (PermissionResult, error) {
+			seen = req
+			return PermissionResult{Decision: coreevents.PermissionAllow, UpdatedInput: map[string]any{"text": "hello"}}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	stream, err := rt.RunStream(context.Background(), Request{Prompt: "call tool", ToolWhitelist: []string{"echo"}})
+	if err != nil {
+		t.Fatalf("run stream: %v", err)
+	}
+	var announced map[string]any
+	for evt := range stream {
+		if evt.Type == EventPermissionRequest {
+			announced, _ = evt.Output.(map[string]any)
+			if evt.ToolUseID != "1" {
+				t.Fatalf("permission_request without tool use id: %+v", evt)
+			}
+		}
+	}
+	if announced == nil || announced["suggested_rule"] != "echo(hi)" {
+		t.Fatalf("expected permission_request event, got %+v", announced)
+	}
+	if seen.ToolName != "echo" || seen.ToolUseID != "1" || seen.SuggestedRule != "echo(hi)" || seen.ToolParams["text"] != "hi" {
+		t.Fatalf("unexpected permission request %+v", seen)
+	}
+	if len(mdl.requests) != 2 {
+		t.Fatalf("expected a second model call, got %d", len(mdl.requests))
+	}
+	msgs := mdl.requests[1].Messages
+	last := msgs[len(msgs)-1]
+	if len(last.ToolCalls) != 1 || last.ToolCalls[0].Result != "hello" {
+		t.Fatalf("tool did not run with the rewritten input: %+v", last)
+	}
+}
+
+func TestRuntimePermissionHandlerDenyMessageReachesModel(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{"permissions":{"ask":["echo"]},"sandbox":{"enabled":true}}`)
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "1", Name: "echo", Arguments: map[string]any{"text": "hi"}}}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	toolImpl := &echoTool{}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot: root,
+		Model:       mdl,
+		Tools:       []tool.Tool{toolImpl},
+		PermissionHandler: func(context.Context, PermissionRequest) (PermissionResult, error) {
+			return PermissionResult{Decision: coreevents.PermissionDeny, Message: "use the staging echo instead"}, nil
+		},
+		// The richer handler wins over the legacy one.
+		PermissionRequestHandler: func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error) {
+			return coreevents.PermissionAllow, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	if _, err := rt.Run(context.Background(), Request{Prompt: "call tool", ToolWhitelist: []string{"echo"}}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if toolImpl.calls != 0 {
+		t.Fatalf("tool should not execute when denied, got %d", toolImpl.calls)
+	}
+	msgs := mdl.requests[1].Messages
+	last := msgs[len(msgs)-1]
+	if len(last.ToolCalls) != 1 || !last.ToolCalls[0].IsError || !strings.Contains(last.ToolCalls[0].Result, "use the staging echo instead") {
+		t.Fatalf("denial message not reported to the model: %+v", last)
+	}
+}
+
+func TestRuntimePermissionHandlerRememberPersistsRule(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{"permissions":{"ask":["echo"]},"sandbox":{"enabled":true}}`)
+	call := func(text string) *model.Response {
+		return &model.Response{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: text, Name: "echo", Arguments: map[string]any{"text": text}}}}}
+	}
+	done := &model.Response{Message: model.Message{Role: "assistant", Content: "done"}}
+	mdl := &stubModel{responses: []*model.Response{call("hi"), done, call("hi"), done, call("bye"), done}}
+	toolImpl := &echoTool{}
+	var prompts []string
+	rt, err := New(context.Background(), Options{
+		ProjectRoot: root,
+		Model:       mdl,
+		Tools:       []tool.Tool{toolImpl},
+		PermissionHandler: func(_ context.Context, req PermissionRequest) (PermissionResult, error) {
+			prompts = append(prompts, req.SuggestedRule)
+			return PermissionResult{Decision: coreevents.PermissionAllow, Remember: true}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	for i := 0; i < 3; i++ {
+		if _, err := rt.Run(context.Background(), Request{Prompt: "call tool", ToolWhitelist: []string{"echo"}}); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+	}
+	if toolImpl.calls != 3 {
+		t.Fatalf("expected 3 tool executions, got %d", toolImpl.calls)
+	}
+	if len(prompts) != 2 || prompts[0] != "echo(hi)" || prompts[1] != "echo(bye)" {
+		t.Fatalf("expected a prompt per new target only, got %v", prompts)
+	}
+	data, err := os.ReadFile(filepath.Join(root, ".claude", "settings.local.json"))
+	if err != nil {
+		t.Fatalf("read local settings: %v", err)
+	}
+	var local config.Settings
+	if err := json.Unmarshal(data, &local); err != nil {
+		t.Fatalf("decode local settings: %v", err)
+	}
+	if local.Permissions == nil || len(local.Permissions.Allow) != 2 || local.Permissions.Allow[0] != "echo(hi)" {
+		t.Fatalf("unexpected remembered rules %s", data)
+	}
+}
+
 func TestRuntimePermissionAskAutoWhitelist(t *testing.T) {
 	root := newClaudeProjectWithSettings(t, `{"permissions":{"ask":["echo"]},"sandbox":{"enabled":true}}`)
 	mdl := &stubModel{responses: []*model.Response{
@@ -339,13 +475,16 @@ func TestRuntimeToolExecutor_ErrorHistory(t *testing.T) {
 			if len(msgs[0].ToolCalls) == 0 {
 				t.Fatal("expected at least one ToolCall in history")
 			}
-			var payload map[string]string
+			var payload map[string]any
 			if unmarshalErr := json.Unmarshal([]byte(msgs[0].ToolCalls[0].Result), &payload); unmarshalErr != nil {
 				t.Fatalf("history tool result not valid json: %v", unmarshalErr)
 			}
-			if payload["error"] != fail.err.Error() {
+			if payload["error"] != fail.err.Error() || payload["category"] != string(tool.ErrorExecution) {
 				t.Fatalf("expected error field, got %+v", payload)
 			}
+			if !msgs[0].ToolCalls[0].IsError || res.Metadata["is_error"] != true {
+				t.Fatalf("expected IsError, got %+v / %+v", msgs[0].ToolCalls[0], res.Metadata)
+			}
 			if msgs[0].Role != "tool" || len(msgs[0].ToolCalls) != 1 || msgs[0].ToolCalls[0].Name != call.Name {
 				t.Fatalf("tool history entry malformed: %+v", msgs[0])
 			}
@@ -353,6 +492,103 @@ func TestRuntimeToolExecutor_ErrorHistory(t *testing.T) {
 	}
 }
 
+func TestRuntimeToolExecutor_InvalidInputReturnsViolations(t *testing.T) {
+	type lookupParams struct {
+		Key   string `json:"key"`
+		Limit int    `json:"limit,omitempty"`
+	}
+	calls := 0
+	lookup, err := tool.FromFunc("lookup", "look up a key", func(ctx context.Context, p lookupParams) (string, error) {
+		calls++
+		return p.Key, nil
+	})
+	if err != nil {
+		t.Fatalf("build tool: %v", err)
+	}
+	reg := tool.NewRegistry()
+	if err := reg.Register(lookup); err != nil {
+		t.Fatalf("register tool: %v", err)
+	}
+	history := message.NewHistory()
+	rtExec := &runtimeToolExecutor{
+		executor: tool.NewExecutor(reg, nil),
+		hooks:    &runtimeHookAdapter{},
+		history:  history,
+	}
+
+	call := agent.ToolCall{ID: "c1", Name: "lookup", Input: map[string]any{"limit": "many"}}
+	res, err := rtExec.Execute(context.Background(), call, agent.NewContext())
+	if err == nil || calls != 0 {
+		t.Fatalf("expected validation failure before execution, err=%v calls=%d", err, calls)
+	}
+	msgs := history.All()
+	if len(msgs) != 1 || len(msgs[0].ToolCalls) != 1 || !msgs[0].ToolCalls[0].IsError {
+		t.Fatalf("expected error tool result in history, got %+v", msgs)
+	}
+	var payload struct {
+		Category   string           `json:"category"`
+		Violations []tool.Violation `json:"violations"`
+	}
+	if err := json.Unmarshal([]byte(msgs[0].ToolCalls[0].Result), &payload); err != nil {
+		t.Fatalf("tool result not valid json: %v", err)
+	}
+	if payload.Category != string(tool.ErrorInvalidInput) || len(payload.Violations) != 2 {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+	if payload.Violations[0].Field != "key" || payload.Violations[1].Field != "limit" {
+		t.Fatalf("unexpected violations %+v", payload.Violations)
+	}
+	if detail, ok := res.Metadata["error_detail"].(*tool.ToolError); !ok || len(detail.Violations) != 2 {
+		t.Fatalf("expected error detail metadata, got %+v", res.Metadata)
+	}
+}
+
+func TestRuntimeToolExecutor_StructuredContentAndMedia(t *testing.T) {
+	shot, err := tool.FromFunc("screenshot", "capture the screen", func(context.Context, struct{}) (*tool.ToolResult, error) {
+		return &tool.ToolResult{
+			Success: true,
+			Content: map[string]any{"width": 2},
+			Media:   []tool.Media{{Kind: tool.MediaImage, MediaType: "image/png", Data: []byte("png")}},
+		}, nil
+	})
+	if err != nil {
+		t.Fatalf("build tool: %v", err)
+	}
+	reg := tool.NewRegistry()
+	if err := reg.Register(shot); err != nil {
+		t.Fatalf("register tool: %v", err)
+	}
+	history := message.NewHistory()
+	rtExec := &runtimeToolExecutor{
+		executor: tool.NewExecutor(reg, nil),
+		hooks:    &runtimeHookAdapter{},
+		history:  history,
+	}
+
+	res, err := rtExec.Execute(context.Background(), agent.ToolCall{ID: "c1", Name: "screenshot", Input: map[string]any{}}, agent.NewContext())
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if res.Output != `{"width":2}` || res.Metadata["content"] == nil {
+		t.Fatalf("structured content not surfaced: %+v", res)
+	}
+	msgs := history.All()
+	if len(msgs) != 1 || len(msgs[0].ToolCalls) != 1 {
+		t.Fatalf("unexpected history %+v", msgs)
+	}
+	call := msgs[0].ToolCalls[0]
+	if call.Result != `{"width":2}` {
+		t.Fatalf("expected JSON content as result, got %q", call.Result)
+	}
+	if len(call.ResultBlocks) != 1 || call.ResultBlocks[0].Type != message.ContentBlockImage || call.ResultBlocks[0].Data != base64.StdEncoding.EncodeToString([]byte("png")) {
+		t.Fatalf("unexpected result blocks %+v", call.ResultBlocks)
+	}
+	converted := convertMessages(msgs)
+	if len(converted[0].ToolCalls[0].ResultBlocks) != 1 || converted[0].ToolCalls[0].ResultBlocks[0].MediaType != "image/png" {
+		t.Fatalf("result blocks lost in model conversion: %+v", converted[0].ToolCalls)
+	}
+}
+
 func TestRuntimeToolExecutor_PreToolUseDenialAddsToolResult(t *testing.T) {
 	reg := tool.NewRegistry()
 	impl := &echoTool{}
@@ -394,11 +630,11 @@ func TestRuntimeToolExecutor_PreToolUseDenialAddsToolResult(t *testing.T) {
 	if len(msgs[0].ToolCalls) != 1 {
 		t.Fatalf("expected tool history entry, got %+v", msgs[0])
 	}
-	var payload map[string]string
+	var payload map[string]any
 	if unmarshalErr := json.Unmarshal([]byte(msgs[0].ToolCalls[0].Result), &payload); unmarshalErr != nil {
 		t.Fatalf("history tool result not valid json: %v", unmarshalErr)
 	}
-	if got := payload["error"]; got == "" {
+	if got, _ := payload["error"].(string); got == "" || payload["category"] != string(tool.ErrorPermission) {
 		t.Fatalf("expected error field, got %+v", payload)
 	}
 }
@@ -435,7 +671,7 @@ func TestRuntimeToolExecutor_PropagatesOutputRef(t *testing.T) {
 }
 
 func TestNewRejectsDisallowedMCPServer(t *testing.T) {
-	root := newClaudeProject(t)
+	root := newClaudeProjectWithSettings(t, `{"model":"claude-3-opus","sandbox":{"enabled":true}}`)
 	mdl := &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "ok"}}}}
 	opts := Options{
 		ProjectRoot: root,
@@ -456,7 +692,7 @@ func TestRegisterToolsFiltersDisallowedTools(t *testing.T) {
 		Tools:           []tool.Tool{allowed, blocked},
 		DisallowedTools: []string{"FAIL"},
 	}
-	if _, err := registerTools(reg, opts, nil, nil, nil); err != nil {
+	if _, err := registerTools(reg, opts, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 	}
 	if _, err := reg.Get(allowed.Name()); err != nil {
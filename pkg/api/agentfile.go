@@ -0,0 +1,288 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// AgentFile is a declarative agent definition loaded by NewFromFile. The
+// same schema is accepted as YAML or JSON. Relative paths resolve against the
+// directory containing the file.
+type AgentFile struct {
+	// Name is informational and not used by the runtime.
+	Name         string                       `json:"name"`
+	EntryPoint   EntryPoint                   `json:"entryPoint"`
+	ProjectRoot  string                       `json:"projectRoot"`
+	Model        AgentFileModel               `json:"model"`
+	ModelPool    map[ModelTier]AgentFileModel `json:"modelPool"`
+	SystemPrompt string                       `json:"systemPrompt"`
+	// Tools lists the built-in tools to enable; omit to enable all.
+	Tools           []string         `json:"tools"`
+	DisallowedTools []string         `json:"disallowedTools"`
+	Middleware      []MiddlewareSpec `json:"middleware"`
+	Budgets         AgentFileBudgets `json:"budgets"`
+	// Subagents maps subagent types to the model tier they run on.
+	Subagents map[string]ModelTier             `json:"subagents"`
+	Templates map[string]config.TemplateConfig `json:"templates"`
+}
+
+// AgentFileModel selects a model provider. The API key is read from the
+// environment variable named by APIKeyEnv so secrets stay out of the file.
+type AgentFileModel struct {
+	Provider    string   `json:"provider"` // anthropic (default), openai, claude-cli, gateway/litellm or openrouter
+	Name        string   `json:"name"`
+	BaseURL     string   `json:"baseURL"`
+	APIKeyEnv   string   `json:"apiKeyEnv"`
+	MaxTokens   int      `json:"maxTokens"`
+	Temperature *float64 `json:"temperature"`
+}
+
+// AgentFileBudgets bounds a run.
+type AgentFileBudgets struct {
+	MaxIterations int    `json:"maxIterations"`
+	Timeout       string `json:"timeout"` // Go duration, e.g. "10m"
+	TokenLimit    int    `json:"tokenLimit"`
+	MaxSessions   int    `json:"maxSessions"`
+}
+
+// MiddlewareSpec references a middleware registered with RegisterMiddleware.
+// In the file it is either a bare name or a mapping with name and config.
+type MiddlewareSpec struct {
+	Name   string         `json:"name"`
+	Config map[string]any `json:"config"`
+}
+
+func (s *MiddlewareSpec) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		s.Name = name
+		return nil
+	}
+	type plain MiddlewareSpec
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*plain)(s))
+}
+
+// MiddlewareFactory builds a middleware from its agent file config. dir is
+// the directory containing the agent file.
+type MiddlewareFactory func(dir string, cfg map[string]any) (middleware.Middleware, error)
+
+var (
+	middlewareFactoriesMu sync.RWMutex
+	middlewareFactories   = map[string]MiddlewareFactory{
+		"trace": func(dir string, cfg map[string]any) (middleware.Middleware, error) {
+			out, _ := cfg["dir"].(string)
+			if out == "" {
+				out = ".trace"
+			}
+			if !filepath.IsAbs(out) {
+				out = filepath.Join(dir, out)
+			}
+			return middleware.NewTraceMiddleware(out), nil
+		},
+		"redactor": func(_ string, cfg map[string]any) (middleware.Middleware, error) {
+			var rc middleware.RedactorConfig
+			rc.Mask, _ = cfg["mask"].(string)
+			rc.DisableBuiltins, _ = cfg["disableBuiltins"].(bool)
+			if raw, ok := cfg["patterns"].([]any); ok {
+				for _, p := range raw {
+					pattern, ok := p.(string)
+					if !ok {
+						return nil, fmt.Errorf("api: redactor patterns must be strings, got %T", p)
+					}
+					rc.Patterns = append(rc.Patterns, pattern)
+				}
+			}
+			return middleware.NewRedactor(rc)
+		},
+	}
+)
+
+// RegisterMiddleware makes a middleware available to agent files by name.
+// Registering an existing name replaces it.
+func RegisterMiddleware(name string, factory MiddlewareFactory) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || factory == nil {
+		return
+	}
+	middlewareFactoriesMu.Lock()
+	defer middlewareFactoriesMu.Unlock()
+	middlewareFactories[name] = factory
+}
+
+func lookupMiddleware(name string) (MiddlewareFactory, bool) {
+	middlewareFactoriesMu.RLock()
+	defer middlewareFactoriesMu.RUnlock()
+	factory, ok := middlewareFactories[strings.ToLower(strings.TrimSpace(name))]
+	return factory, ok
+}
+
+// LoadAgentFile parses an agent definition file.
+func LoadAgentFile(path string) (*AgentFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("api: read agent file: %w", err)
+	}
+	// YAML is a superset of JSON. Decode generically, then re-encode so the
+	// json tags shared with settings types apply to both formats.
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("api: parse agent file %s: %w", path, err)
+	}
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("api: parse agent file %s: %w", path, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(normalized))
+	dec.DisallowUnknownFields()
+	var file AgentFile
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("api: parse agent file %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// Options converts the definition into runtime Options. dir is the directory
+// relative paths resolve against.
+func (f *AgentFile) Options(dir string) (Options, error) {
+	if f == nil {
+		return Options{}, errors.New("api: agent file is nil")
+	}
+	opts := Options{
+		EntryPoint:           f.EntryPoint,
+		SystemPrompt:         f.SystemPrompt,
+		DisallowedTools:      cloneStrings(f.DisallowedTools),
+		MaxIterations:        f.Budgets.MaxIterations,
+		TokenLimit:           f.Budgets.TokenLimit,
+		MaxSessions:          f.Budgets.MaxSessions,
+		SubagentModelMapping: f.Subagents,
+	}
+	if f.Tools != nil {
+		opts.EnabledBuiltinTools = cloneStrings(f.Tools)
+	}
+	if root := strings.TrimSpace(f.ProjectRoot); root != "" {
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(dir, root)
+		}
+		opts.ProjectRoot = root
+	}
+	if timeout := strings.TrimSpace(f.Budgets.Timeout); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return Options{}, fmt.Errorf("api: budgets.timeout: %w", err)
+		}
+		opts.Timeout = d
+	}
+
+	provider, err := f.Model.provider()
+	if err != nil {
+		return Options{}, fmt.Errorf("api: model: %w", err)
+	}
+	opts.ModelFactory = provider
+
+	if len(f.ModelPool) > 0 {
+		opts.ModelPool = make(map[ModelTier]model.Model, len(f.ModelPool))
+		tiers := make([]string, 0, len(f.ModelPool))
+		for tier := range f.ModelPool {
+			tiers = append(tiers, string(tier))
+		}
+		sort.Strings(tiers)
+		for _, tier := range tiers {
+			p, err := f.ModelPool[ModelTier(tier)].provider()
+			if err != nil {
+				return Options{}, fmt.Errorf("api: modelPool.%s: %w", tier, err)
+			}
+			mdl, err := p.Model(context.Background())
+			if err != nil {
+				return Options{}, fmt.Errorf("api: modelPool.%s: %w", tier, err)
+			}
+			opts.ModelPool[ModelTier(tier)] = mdl
+		}
+	}
+
+	for i, spec := range f.Middleware {
+		factory, ok := lookupMiddleware(spec.Name)
+		if !ok {
+			return Options{}, fmt.Errorf("api: middleware[%d]: unknown middleware %q", i, spec.Name)
+		}
+		mw, err := factory(dir, spec.Config)
+		if err != nil {
+			return Options{}, fmt.Errorf("api: middleware[%d] %s: %w", i, spec.Name, err)
+		}
+		opts.Middleware = append(opts.Middleware, mw)
+	}
+
+	if len(f.Templates) > 0 {
+		opts.Templates = make(map[string]RequestTemplate, len(f.Templates))
+		for name, cfg := range f.Templates {
+			opts.Templates[name] = templateFromConfig(cfg)
+		}
+	}
+	return opts, nil
+}
+
+func (m AgentFileModel) provider() (ModelFactory, error) {
+	apiKey := ""
+	if env := strings.TrimSpace(m.APIKeyEnv); env != "" {
+		apiKey = os.Getenv(env)
+		if apiKey == "" {
+			return nil, fmt.Errorf("environment variable %s is empty", env)
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(m.Provider)) {
+	case "", "anthropic":
+		return &model.AnthropicProvider{APIKey: apiKey, BaseURL: m.BaseURL, ModelName: m.Name, MaxTokens: m.MaxTokens, Temperature: m.Temperature}, nil
+	case "openai":
+		return &model.OpenAIProvider{APIKey: apiKey, BaseURL: m.BaseURL, ModelName: m.Name, MaxTokens: m.MaxTokens, Temperature: m.Temperature}, nil
+	case "claude-cli":
+		return &model.ClaudeCLIProvider{ModelName: m.Name}, nil
+	case "gateway", "litellm":
+		return &model.GatewayProvider{APIKey: apiKey, BaseURL: m.BaseURL, ModelName: m.Name, MaxTokens: m.MaxTokens, Temperature: m.Temperature}, nil
+	case "openrouter":
+		baseURL := m.BaseURL
+		if baseURL == "" {
+			baseURL = model.DefaultOpenRouterBaseURL
+		}
+		return &model.GatewayProvider{APIKey: apiKey, BaseURL: baseURL, ModelName: m.Name, MaxTokens: m.MaxTokens, Temperature: m.Temperature}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %q", m.Provider)
+	}
+}
+
+// NewFromFile builds a Runtime from an agent definition file. overrides are
+// applied after the file, e.g. to inject tools or a model in tests.
+func NewFromFile(ctx context.Context, path string, overrides ...func(*Options)) (*Runtime, error) {
+	file, err := LoadAgentFile(path)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("api: resolve agent file path: %w", err)
+	}
+	opts, err := file.Options(filepath.Dir(abs))
+	if err != nil {
+		return nil, err
+	}
+	for _, apply := range overrides {
+		if apply != nil {
+			apply(&opts)
+		}
+	}
+	return New(ctx, opts)
+}
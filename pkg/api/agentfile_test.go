@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func writeAgentFile(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write agent file: %v", err)
+	}
+	return path
+}
+
+func TestNewFromFileBuildsRuntime(t *testing.T) {
+	root := newClaudeProject(t)
+	var gotConfig map[string]any
+	RegisterMiddleware("agentfile-test", func(dir string, cfg map[string]any) (middleware.Middleware, error) {
+		gotConfig = cfg
+		return middleware.Funcs{Identifier: "agentfile-test"}, nil
+	})
+	path := writeAgentFile(t, root, "agent.yaml", `
+name: reviewer
+entryPoint: ci
+projectRoot: .
+model:
+  provider: openai
+  name: gpt-test
+systemPrompt: You review code.
+tools: [glob, grep]
+middleware:
+  - name: agentfile-test
+    config: {level: 2}
+budgets:
+  maxIterations: 3
+  timeout: 90s
+  tokenLimit: 1000
+subagents:
+  explore: low
+templates:
+  terse:
+    systemPrompt: Be terse.
+`)
+
+	mdl := &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "ok"}}}}
+	rt, err := NewFromFile(context.Background(), path, func(o *Options) { o.Model = mdl })
+	if err != nil {
+		t.Fatalf("new from file: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	if rt.opts.EntryPoint != EntryPointCI || rt.opts.MaxIterations != 3 || rt.opts.Timeout != 90*time.Second || rt.opts.TokenLimit != 1000 {
+		t.Fatalf("unexpected options %+v", rt.opts)
+	}
+	if rt.opts.SubagentModelMapping["explore"] != ModelTierLow {
+		t.Fatalf("expected subagent mapping, got %v", rt.opts.SubagentModelMapping)
+	}
+	if _, ok := rt.opts.Templates["terse"]; !ok {
+		t.Fatalf("expected template from file")
+	}
+	if gotConfig["level"] != float64(2) {
+		t.Fatalf("expected middleware config, got %v", gotConfig)
+	}
+	if _, err := rt.Run(context.Background(), Request{Prompt: "hi"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	req := mdl.requests[0]
+	if !strings.Contains(req.System, "You review code.") {
+		t.Fatalf("expected system prompt from file, got %q", req.System)
+	}
+	if len(req.Tools) != 2 {
+		t.Fatalf("expected two tools, got %+v", req.Tools)
+	}
+}
+
+func TestAgentFileOptions(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AGENTFILE_TEST_KEY", "secret")
+	path := writeAgentFile(t, dir, "agent.json", `{"model":{"name":"claude-test","apiKeyEnv":"AGENTFILE_TEST_KEY"},"projectRoot":"work"}`)
+	file, err := LoadAgentFile(path)
+	if err != nil {
+		t.Fatalf("load json: %v", err)
+	}
+	opts, err := file.Options(dir)
+	if err != nil {
+		t.Fatalf("options: %v", err)
+	}
+	provider, ok := opts.ModelFactory.(*model.AnthropicProvider)
+	if !ok || provider.APIKey != "secret" || provider.ModelName != "claude-test" {
+		t.Fatalf("unexpected model factory %#v", opts.ModelFactory)
+	}
+	if opts.ProjectRoot != filepath.Join(dir, "work") {
+		t.Fatalf("expected project root relative to file, got %q", opts.ProjectRoot)
+	}
+	if opts.EnabledBuiltinTools != nil {
+		t.Fatalf("omitted tools should enable all builtins, got %v", opts.EnabledBuiltinTools)
+	}
+
+	cases := map[string]string{
+		"unknown field":      "modle: {}\n",
+		"unknown middleware": "middleware: [nope]\n",
+		"bad provider":       "model: {provider: carrier-pigeon}\n",
+		"bad timeout":        "budgets: {timeout: soon}\n",
+		"missing key":        "model: {apiKeyEnv: AGENTFILE_TEST_UNSET}\n",
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := writeAgentFile(t, t.TempDir(), "agent.yaml", body)
+			if _, err := NewFromFile(context.Background(), path); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
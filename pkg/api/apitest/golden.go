@@ -0,0 +1,293 @@
+// Package apitest records agent runs as normalized transcripts and compares
+// them against golden files, so changes to middleware or loop behaviour show
+// up as reviewable diffs.
+//
+// A typical test scripts the model, runs one request and checks the result:
+//
+//	tr, err := apitest.Record(ctx, api.Options{ProjectRoot: t.TempDir()}, req,
+//		model.Response{Message: model.Message{Role: "assistant", Content: "done"}})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	got, err := tr.Marshal()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	apitest.AssertGolden(t, "testdata/done.golden", got)
+//
+// Run `go test -update` to rewrite golden files after an intended change.
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+var update = flag.Bool("update", false, "rewrite golden transcript files")
+
+// ErrScriptExhausted is returned when the agent asks for more completions
+// than the script provides.
+var ErrScriptExhausted = errors.New("apitest: scripted responses exhausted")
+
+// ScriptedModel replays Responses in order and records every request it
+// receives.
+type ScriptedModel struct {
+	mu        sync.Mutex
+	responses []model.Response
+	requests  []model.Request
+	played    []model.Response
+}
+
+// NewScriptedModel returns a model that answers with responses in order.
+func NewScriptedModel(responses ...model.Response) *ScriptedModel {
+	return &ScriptedModel{responses: append([]model.Response(nil), responses...)}
+}
+
+// Complete returns the next scripted response.
+func (m *ScriptedModel) Complete(_ context.Context, req model.Request) (*model.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, req)
+	if len(m.responses) == 0 {
+		return nil, ErrScriptExhausted
+	}
+	resp := m.responses[0]
+	m.responses = m.responses[1:]
+	m.played = append(m.played, resp)
+	return &resp, nil
+}
+
+// CompleteStream delivers the next scripted response as a single final chunk.
+func (m *ScriptedModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	resp, err := m.Complete(ctx, req)
+	if err != nil {
+		return err
+	}
+	if cb == nil {
+		return nil
+	}
+	return cb(model.StreamResult{Final: true, Response: resp})
+}
+
+// Requests returns a copy of the requests received so far.
+func (m *ScriptedModel) Requests() []model.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]model.Request(nil), m.requests...)
+}
+
+// Played returns a copy of the responses handed out so far.
+func (m *ScriptedModel) Played() []model.Response {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]model.Response(nil), m.played...)
+}
+
+// Transcript is the serializable record of one run.
+type Transcript struct {
+	Turns  []Turn       `json:"turns"`
+	Result *ResultEntry `json:"result,omitempty"`
+	Error  string       `json:"error,omitempty"`
+
+	root string
+}
+
+// Turn pairs the request sent to the model with the scripted reply.
+type Turn struct {
+	System   string         `json:"system,omitempty"`
+	Tools    []string       `json:"tools,omitempty"`
+	Messages []MessageEntry `json:"messages"`
+	Reply    *MessageEntry  `json:"reply,omitempty"`
+}
+
+// MessageEntry is the transcript form of a model message.
+type MessageEntry struct {
+	Role      string          `json:"role"`
+	Content   string          `json:"content,omitempty"`
+	ToolCalls []ToolCallEntry `json:"tool_calls,omitempty"`
+}
+
+// ToolCallEntry is the transcript form of a tool call or tool result.
+type ToolCallEntry struct {
+	ID        string         `json:"id,omitempty"`
+	Name      string         `json:"name,omitempty"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Result    string         `json:"result,omitempty"`
+	IsError   bool           `json:"is_error,omitempty"`
+}
+
+// ResultEntry is the transcript form of api.Result.
+type ResultEntry struct {
+	Output     string `json:"output"`
+	StopReason string `json:"stop_reason,omitempty"`
+	ToolCalls  int    `json:"tool_calls,omitempty"`
+}
+
+// Record runs req against a runtime built from opts whose model replays
+// script. opts.Model and opts.ModelFactory are replaced. Run errors are
+// recorded in the transcript; only runtime construction errors are returned.
+func Record(ctx context.Context, opts api.Options, req api.Request, script ...model.Response) (*Transcript, error) {
+	mdl := NewScriptedModel(script...)
+	opts.Model = mdl
+	opts.ModelFactory = nil
+	if opts.EntryPoint == "" {
+		opts.EntryPoint = api.EntryPointCI
+	}
+	rt, err := api.New(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("apitest: new runtime: %w", err)
+	}
+	defer rt.Close()
+
+	resp, runErr := rt.Run(ctx, req)
+	tr := &Transcript{root: opts.ProjectRoot}
+	replies := mdl.Played()
+	for i, r := range mdl.Requests() {
+		turn := Turn{System: r.System, Messages: convertMessages(r.Messages)}
+		for _, def := range r.Tools {
+			turn.Tools = append(turn.Tools, def.Name)
+		}
+		sort.Strings(turn.Tools)
+		if i < len(replies) {
+			reply := convertMessage(replies[i].Message)
+			turn.Reply = &reply
+		}
+		tr.Turns = append(tr.Turns, turn)
+	}
+	if runErr != nil {
+		tr.Error = runErr.Error()
+	}
+	if resp != nil && resp.Result != nil {
+		tr.Result = &ResultEntry{
+			Output:     resp.Result.Output,
+			StopReason: resp.Result.StopReason,
+			ToolCalls:  len(resp.Result.ToolCalls),
+		}
+	}
+	return tr, nil
+}
+
+func convertMessages(msgs []model.Message) []MessageEntry {
+	out := make([]MessageEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		out = append(out, convertMessage(msg))
+	}
+	return out
+}
+
+func convertMessage(msg model.Message) MessageEntry {
+	entry := MessageEntry{Role: msg.Role, Content: msg.TextContent()}
+	for _, call := range msg.ToolCalls {
+		entry.ToolCalls = append(entry.ToolCalls, ToolCallEntry{
+			ID:        call.ID,
+			Name:      call.Name,
+			Arguments: call.Arguments,
+			Result:    call.Result,
+			IsError:   call.IsError,
+		})
+	}
+	return entry
+}
+
+var (
+	uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	timePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+	datePattern = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+)
+
+// Marshal returns the transcript as indented JSON with the project root,
+// UUIDs and timestamps replaced by stable placeholders.
+func (t *Transcript) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(t); err != nil {
+		return nil, fmt.Errorf("apitest: marshal transcript: %w", err)
+	}
+	return Normalize(buf.Bytes(), t.root), nil
+}
+
+// Normalize replaces root, UUIDs and timestamps in data with placeholders.
+// Each distinct UUID maps to its own <id-N> so references stay linked.
+func Normalize(data []byte, root string) []byte {
+	out := string(data)
+	if root = strings.TrimSpace(root); root != "" {
+		if resolved, err := filepath.EvalSymlinks(root); err == nil && resolved != root {
+			out = strings.ReplaceAll(out, resolved, "<root>")
+		}
+		out = strings.ReplaceAll(out, root, "<root>")
+	}
+	ids := map[string]string{}
+	out = uuidPattern.ReplaceAllStringFunc(out, func(id string) string {
+		key := strings.ToLower(id)
+		if name, ok := ids[key]; ok {
+			return name
+		}
+		name := fmt.Sprintf("<id-%d>", len(ids)+1)
+		ids[key] = name
+		return name
+	})
+	out = timePattern.ReplaceAllString(out, "<time>")
+	out = datePattern.ReplaceAllString(out, "<date>")
+	return []byte(out)
+}
+
+// AssertGolden compares got with the golden file at path. With -update the
+// file is rewritten instead.
+func AssertGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("apitest: create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o600); err != nil {
+			t.Fatalf("apitest: write golden: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("apitest: read golden (rerun with -update to create it): %v", err)
+	}
+	if bytes.Equal(want, got) {
+		return
+	}
+	t.Fatalf("apitest: transcript differs from %s (rerun with -update to accept):\n%s", path, diffLines(string(want), string(got)))
+}
+
+// diffLines reports the first differing line with a little context.
+func diffLines(want, got string) string {
+	w := strings.Split(want, "\n")
+	g := strings.Split(got, "\n")
+	i := 0
+	for i < len(w) && i < len(g) && w[i] == g[i] {
+		i++
+	}
+	start := max(i-2, 0)
+	var b strings.Builder
+	for j := start; j < i; j++ {
+		fmt.Fprintf(&b, "  %s\n", w[j])
+	}
+	for j := i; j < min(i+3, len(w)); j++ {
+		fmt.Fprintf(&b, "- %s\n", w[j])
+	}
+	for j := i; j < min(i+3, len(g)); j++ {
+		fmt.Fprintf(&b, "+ %s\n", g[j])
+	}
+	return b.String()
+}
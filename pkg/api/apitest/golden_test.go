@@ -0,0 +1,59 @@
+package apitest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+type upperTool struct{}
+
+func (upperTool) Name() string             { return "upper" }
+func (upperTool) Description() string      { return "upper-cases text" }
+func (upperTool) Schema() *tool.JSONSchema { return nil }
+func (upperTool) Execute(_ context.Context, params map[string]any) (*tool.ToolResult, error) {
+	text, _ := params["text"].(string)
+	return &tool.ToolResult{Success: true, Output: strings.ToUpper(text)}, nil
+}
+
+func TestRecordToolLoopMatchesGolden(t *testing.T) {
+	tr, err := Record(context.Background(), api.Options{
+		ProjectRoot:  t.TempDir(),
+		SystemPrompt: "You shout.",
+		Tools:        []tool.Tool{upperTool{}},
+	}, api.Request{Prompt: "shout hello", SessionID: "golden"},
+		model.Response{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "call_1", Name: "upper", Arguments: map[string]any{"text": "hello"}}}}},
+		model.Response{Message: model.Message{Role: "assistant", Content: "HELLO"}, StopReason: "end_turn"},
+	)
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	got, err := tr.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	AssertGolden(t, "testdata/tool_loop.golden", got)
+}
+
+func TestRecordCapturesScriptExhaustion(t *testing.T) {
+	tr, err := Record(context.Background(), api.Options{ProjectRoot: t.TempDir()}, api.Request{Prompt: "hi", SessionID: "s"})
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if !strings.Contains(tr.Error, ErrScriptExhausted.Error()) || len(tr.Turns) != 1 || tr.Turns[0].Reply != nil {
+		t.Fatalf("transcript %+v", tr)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	in := "/tmp/x/file 123e4567-e89b-12d3-a456-426614174000 at 2026-01-02T03:04:05Z then 123E4567-E89B-12D3-A456-426614174000 and 00000000-0000-0000-0000-000000000001 on 2026-01-02"
+	got := string(Normalize([]byte(in), "/tmp/x"))
+	want := "<root>/file <id-1> at <time> then <id-1> and <id-2> on <date>"
+	if got != want {
+		t.Fatalf("got %q", got)
+	}
+}
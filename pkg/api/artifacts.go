@@ -0,0 +1,33 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cexll/agentsdk-go/pkg/artifact"
+)
+
+// ErrArtifactsDisabled is returned by Runtime.Artifact when no
+// Options.ArtifactStore is configured.
+var ErrArtifactsDisabled = errors.New("api: artifact store not configured")
+
+// Artifact fetches a tool-produced artifact by ID from Options.ArtifactStore.
+// Serve artifacts over HTTP with artifact.Handler(rt.ArtifactStore()).
+func (rt *Runtime) Artifact(ctx context.Context, id string) (artifact.Artifact, []byte, error) {
+	store := rt.ArtifactStore()
+	if store == nil {
+		return artifact.Artifact{}, nil, ErrArtifactsDisabled
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return store.Get(ctx, id)
+}
+
+// ArtifactStore returns the configured artifact store, or nil.
+func (rt *Runtime) ArtifactStore() artifact.Store {
+	if rt == nil {
+		return nil
+	}
+	return rt.opts.ArtifactStore
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/artifact"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+type artifactTool struct{}
+
+func (artifactTool) Name() string             { return "report" }
+func (artifactTool) Description() string      { return "emits an artifact" }
+func (artifactTool) Schema() *tool.JSONSchema { return &tool.JSONSchema{Type: "object"} }
+func (artifactTool) Execute(context.Context, map[string]interface{}) (*tool.ToolResult, error) {
+	return &tool.ToolResult{Success: true, Output: "written", Artifacts: []tool.Artifact{{Name: "r.txt", MediaType: "text/plain", Data: []byte("body")}}}, nil
+}
+
+func TestRuntimeArtifactsReferencedInResponse(t *testing.T) {
+	root := newClaudeProject(t)
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "1", Name: "report", Arguments: map[string]any{"x": 1}}}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	store := artifact.NewMemoryStore()
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl, Tools: []tool.Tool{artifactTool{}}, ArtifactStore: store})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	resp, err := rt.Run(context.Background(), Request{Prompt: "report", SessionID: "sess"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(resp.Artifacts) != 1 || resp.Artifacts[0].ID == "" || resp.Artifacts[0].Data != nil {
+		t.Fatalf("expected artifact reference, got %+v", resp.Artifacts)
+	}
+	meta, data, err := rt.Artifact(context.Background(), resp.Artifacts[0].ID)
+	if err != nil || string(data) != "body" || meta.SessionID != "sess" {
+		t.Fatalf("artifact mismatch %+v %q err=%v", meta, data, err)
+	}
+}
+
+func TestRuntimeArtifactWithoutStore(t *testing.T) {
+	rt, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: &stubModel{}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	if _, _, err := rt.Artifact(context.Background(), "x"); !errors.Is(err, ErrArtifactsDisabled) {
+		t.Fatalf("expected ErrArtifactsDisabled, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+	"github.com/cexll/agentsdk-go/pkg/sandbox"
+	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// auditEmitter binds an AuditLogger to the session/request of a single run so
+// every record can be correlated without threading IDs through call sites.
+// A nil emitter is valid and drops all records.
+type auditEmitter struct {
+	logger    AuditLogger
+	sessionID string
+	requestID string
+	traceID   string
+}
+
+func newAuditEmitter(logger AuditLogger, sessionID, requestID string) *auditEmitter {
+	if logger == nil {
+		return nil
+	}
+	return &auditEmitter{logger: logger, sessionID: sessionID, requestID: requestID}
+}
+
+func (a *auditEmitter) emit(ctx context.Context, rec AuditRecord) {
+	if a == nil || a.logger == nil {
+		return
+	}
+	rec.Timestamp = time.Now()
+	rec.SessionID = a.sessionID
+	rec.RequestID = a.requestID
+	rec.TraceID = a.traceID
+	ids := callIDsFromContext(ctx)
+	if rec.ToolUseID == "" {
+		rec.ToolUseID = ids.toolUseID
+	}
+	if rec.IterationID == "" {
+		rec.IterationID = ids.iterationID
+	}
+	a.logger.Emit(ctx, rec)
+}
+
+func (a *auditEmitter) hook(ctx context.Context, event coreevents.EventType, toolName, decision, reason string) {
+	a.emit(ctx, AuditRecord{
+		Kind:     AuditHook,
+		Event:    string(event),
+		Tool:     toolName,
+		Decision: decision,
+		Reason:   reason,
+	})
+}
+
+// permissionObserver reports every sandbox permission evaluation.
+func (a *auditEmitter) permissionObserver() tool.PermissionObserver {
+	if a == nil {
+		return nil
+	}
+	return func(ctx context.Context, call tool.Call, decision security.PermissionDecision) {
+		a.emit(ctx, AuditRecord{
+			Kind:      AuditPermission,
+			Tool:      call.Name,
+			Decision:  string(decision.Action),
+			Rule:      decision.Rule,
+			Target:    decision.Target,
+			Reason:    buildPermissionReason(decision),
+			Protected: decision.Protected,
+			Owners:    decision.Owners,
+		})
+	}
+}
+
+// sandboxViolation records err when it stems from a sandbox policy check.
+func (a *auditEmitter) sandboxViolation(ctx context.Context, toolName string, err error) {
+	if a == nil || !isSandboxViolation(err) {
+		return
+	}
+	a.emit(ctx, AuditRecord{
+		Kind:     AuditSandbox,
+		Tool:     toolName,
+		Decision: "deny",
+		Reason:   err.Error(),
+	})
+}
+
+func isSandboxViolation(err error) bool {
+	return errors.Is(err, sandbox.ErrPathDenied) ||
+		errors.Is(err, sandbox.ErrSymlinkDetected) ||
+		errors.Is(err, sandbox.ErrDomainDenied) ||
+		errors.Is(err, sandbox.ErrResourceExceeded)
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// auditFrameBytes is how much JSON FileAuditLogger buffers before writing a
+// compressed frame.
+const auditFrameBytes = 64 << 10
+
+// FileAuditLogger appends audit records to a JSON Lines file. Without a
+// Compressor every record is written as it arrives. With one, records are
+// buffered and written as independent compressed frames, which gzip and zstd
+// decode as a single stream, and the file name gets the codec extension.
+// With AuditFileEncryption each record (or compressed frame) is sealed like
+// a history snapshot and written as a base64 line. Shutdown flushes the last
+// frame; ReadAuditLog reads the file back.
+type FileAuditLogger struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	codec Compressor
+	enc   Encryptor
+	buf   bytes.Buffer
+	err   error
+}
+
+// AuditFileOption customizes NewFileAuditLogger and ReadAuditLog.
+type AuditFileOption func(*auditFileConfig)
+
+type auditFileConfig struct {
+	enc Encryptor
+}
+
+// AuditFileEncryption encrypts each record with enc (e.g.
+// NewAESGCMEncryptor). ReadAuditLog needs the same option.
+func AuditFileEncryption(enc Encryptor) AuditFileOption {
+	return func(c *auditFileConfig) { c.enc = enc }
+}
+
+func newAuditFileConfig(opts []AuditFileOption) auditFileConfig {
+	var cfg auditFileConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	return cfg
+}
+
+// NewFileAuditLogger opens path (plus the codec extension) for appending,
+// creating it and its directory when missing.
+func NewFileAuditLogger(path string, codec Compressor, opts ...AuditFileOption) (*FileAuditLogger, error) {
+	cfg := newAuditFileConfig(opts)
+	path += compressorExtension(codec)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("api: audit log dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("api: open audit log: %w", err)
+	}
+	return &FileAuditLogger{path: path, file: f, codec: codec, enc: cfg.enc}, nil
+}
+
+// Emit appends rec. Write errors are kept and returned by Shutdown.
+func (l *FileAuditLogger) Emit(_ context.Context, rec AuditRecord) {
+	line, err := json.Marshal(rec)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil || l.file == nil {
+		l.err = errors.Join(l.err, err)
+		return
+	}
+	l.buf.Write(line)
+	l.buf.WriteByte('\n')
+	if l.codec == nil || l.buf.Len() >= auditFrameBytes {
+		l.flushLocked()
+	}
+}
+
+// Shutdown writes buffered records and closes the file.
+func (l *FileAuditLogger) Shutdown() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return l.err
+	}
+	l.flushLocked()
+	if err := l.file.Close(); err != nil {
+		l.err = errors.Join(l.err, err)
+	}
+	l.file = nil
+	return l.err
+}
+
+// PurgeSession rewrites the log without sessionID's records and returns how
+// many were removed. Runtime.PurgeData calls it for purged sessions.
+func (l *FileAuditLogger) PurgeSession(sessionID string) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return 0, nil
+	}
+	l.flushLocked()
+	records, err := readAuditFile(l.path, l.codec, l.enc)
+	if err != nil {
+		return 0, err
+	}
+	var kept, frame bytes.Buffer
+	removed := 0
+	for _, rec := range records {
+		if rec.SessionID == sessionID {
+			removed++
+			continue
+		}
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return 0, fmt.Errorf("api: encode audit record: %w", err)
+		}
+		frame.Write(line)
+		frame.WriteByte('\n')
+		// Without a codec every record is its own frame, as in Emit.
+		if l.codec == nil {
+			if err := l.appendFrame(&kept, frame.Bytes()); err != nil {
+				return 0, err
+			}
+			frame.Reset()
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := l.appendFrame(&kept, frame.Bytes()); err != nil {
+		return 0, err
+	}
+	if err := atomicWriteFile(l.path, kept.Bytes(), 0o600); err != nil {
+		return 0, fmt.Errorf("api: rewrite audit log: %w", err)
+	}
+	// The old descriptor points at the replaced file; append to the new one.
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return removed, fmt.Errorf("api: reopen audit log: %w", err)
+	}
+	_ = l.file.Close()
+	l.file = f
+	return removed, nil
+}
+
+func (l *FileAuditLogger) flushLocked() {
+	if l.buf.Len() == 0 {
+		return
+	}
+	var out bytes.Buffer
+	err := l.appendFrame(&out, l.buf.Bytes())
+	l.buf.Reset()
+	if err != nil {
+		l.err = errors.Join(l.err, err)
+		return
+	}
+	if _, err := l.file.Write(out.Bytes()); err != nil {
+		l.err = errors.Join(l.err, fmt.Errorf("api: write audit log: %w", err))
+	}
+}
+
+// appendFrame writes frame to out as it is stored in the file: compressed
+// with the codec and, with an encryptor, sealed and base64-encoded on its
+// own line.
+func (l *FileAuditLogger) appendFrame(out *bytes.Buffer, frame []byte) error {
+	if len(frame) == 0 {
+		return nil
+	}
+	if l.enc != nil {
+		sealed, err := sealAtRest(frame, l.codec, l.enc)
+		if err != nil {
+			return fmt.Errorf("api: seal audit log: %w", err)
+		}
+		out.WriteString(base64.StdEncoding.EncodeToString(sealed))
+		out.WriteByte('\n')
+		return nil
+	}
+	if l.codec != nil {
+		packed, err := l.codec.Compress(frame)
+		if err != nil {
+			return fmt.Errorf("api: compress audit log: %w", err)
+		}
+		frame = packed
+	}
+	out.Write(frame)
+	return nil
+}
+
+// ReadAuditLog returns the records a FileAuditLogger wrote to path with
+// codec and opts.
+func ReadAuditLog(path string, codec Compressor, opts ...AuditFileOption) ([]AuditRecord, error) {
+	cfg := newAuditFileConfig(opts)
+	return readAuditFile(path+compressorExtension(codec), codec, cfg.enc)
+}
+
+func readAuditFile(path string, codec Compressor, enc Encryptor) ([]AuditRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("api: read audit log: %w", err)
+	}
+	if enc != nil {
+		if data, err = openAuditFrames(data, codec, enc); err != nil {
+			return nil, err
+		}
+	} else if codec != nil {
+		if data, err = codec.Decompress(data); err != nil {
+			return nil, fmt.Errorf("api: decompress audit log: %w", err)
+		}
+	}
+	var out []AuditRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("api: decode audit record: %w", err)
+		}
+		out = append(out, rec)
+	}
+	return out, scanner.Err()
+}
+
+// openAuditFrames decodes the base64 lines of an encrypted log and returns
+// the JSON Lines they hold.
+func openAuditFrames(data []byte, codec Compressor, enc Encryptor) ([]byte, error) {
+	var out bytes.Buffer
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		sealed := make([]byte, base64.StdEncoding.DecodedLen(len(line)))
+		n, err := base64.StdEncoding.Decode(sealed, line)
+		if err != nil {
+			return nil, fmt.Errorf("api: decode audit frame: %w", err)
+		}
+		frame, err := enc.Decrypt(sealed[:n])
+		if err != nil {
+			return nil, fmt.Errorf("api: decrypt audit log: %w", err)
+		}
+		if codec != nil {
+			if frame, err = codec.Decompress(frame); err != nil {
+				return nil, fmt.Errorf("api: decompress audit log: %w", err)
+			}
+		}
+		out.Write(frame)
+	}
+	return out.Bytes(), nil
+}
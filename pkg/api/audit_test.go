@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+	corehooks "github.com/cexll/agentsdk-go/pkg/core/hooks"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/sandbox"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+type recordingAuditLogger struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (l *recordingAuditLogger) Emit(_ context.Context, rec AuditRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, rec)
+}
+
+func (l *recordingAuditLogger) Shutdown() error { return nil }
+
+func (l *recordingAuditLogger) byKind(kind AuditKind) []AuditRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []AuditRecord
+	for _, rec := range l.records {
+		if rec.Kind == kind {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+func TestAuditEmitterNilSafe(t *testing.T) {
+	var a *auditEmitter
+	a.hook(context.Background(), coreevents.PreToolUse, "Bash", "allow", "")
+	a.sandboxViolation(context.Background(), "Bash", sandbox.ErrPathDenied)
+	if a.permissionObserver() != nil {
+		t.Fatal("nil emitter should not produce an observer")
+	}
+	if newAuditEmitter(nil, "s", "r") != nil {
+		t.Fatal("nil logger should produce nil emitter")
+	}
+}
+
+func TestAuditEmitterSandboxViolation(t *testing.T) {
+	logger := &recordingAuditLogger{}
+	a := newAuditEmitter(logger, "sess", "req")
+
+	a.sandboxViolation(context.Background(), "file_read", fmt.Errorf("wrap: %w", sandbox.ErrPathDenied))
+	a.sandboxViolation(context.Background(), "file_read", fmt.Errorf("unrelated"))
+	a.sandboxViolation(context.Background(), "file_read", nil)
+
+	recs := logger.byKind(AuditSandbox)
+	if len(recs) != 1 {
+		t.Fatalf("expected one sandbox record, got %+v", logger.records)
+	}
+	if recs[0].SessionID != "sess" || recs[0].RequestID != "req" || recs[0].Decision != "deny" || recs[0].Timestamp.IsZero() {
+		t.Fatalf("unexpected record %+v", recs[0])
+	}
+}
+
+func TestPreToolUseEmitsHookAudit(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir, "deny.sh", `#!/bin/sh
+printf '{"decision":"deny","reason":"blocked"}'
+`)
+	exec := corehooks.NewExecutor()
+	exec.Register(corehooks.ShellHook{Event: coreevents.PreToolUse, Command: script})
+	logger := &recordingAuditLogger{}
+	adapter := &runtimeHookAdapter{executor: exec, audit: newAuditEmitter(logger, "sess", "req")}
+
+	if _, err := adapter.PreToolUse(context.Background(), coreevents.ToolUsePayload{Name: "Echo"}); err == nil {
+		t.Fatal("expected deny error")
+	}
+	recs := logger.byKind(AuditHook)
+	if len(recs) != 1 {
+		t.Fatalf("expected one hook record, got %+v", logger.records)
+	}
+	if recs[0].Event != string(coreevents.PreToolUse) || recs[0].Decision != "deny" || recs[0].Tool != "Echo" {
+		t.Fatalf("unexpected record %+v", recs[0])
+	}
+}
+
+func TestRuntimeEmitsPermissionAudit(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{"permissions":{"deny":["echo"]},"sandbox":{"enabled":true}}`)
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "1", Name: "echo", Arguments: map[string]any{"text": "hi"}}}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	logger := &recordingAuditLogger{}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot: root,
+		Model:       mdl,
+		Tools:       []tool.Tool{&echoTool{}},
+		AuditLogger: logger,
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	if _, err := rt.Run(context.Background(), Request{Prompt: "call tool", SessionID: "sess", RequestID: "req"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	recs := logger.byKind(AuditPermission)
+	if len(recs) != 1 {
+		t.Fatalf("expected one permission record, got %+v", logger.records)
+	}
+	rec := recs[0]
+	if rec.Tool != "echo" || rec.Decision != "deny" || rec.SessionID != "sess" || rec.RequestID != "req" {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+}
+
+func TestNewAuditLoggerNoop(t *testing.T) {
+	logger, err := NewAuditLogger(OTELConfig{Enabled: true, Logs: true})
+	if err != nil {
+		t.Fatalf("new audit logger: %v", err)
+	}
+	logger.Emit(context.Background(), AuditRecord{Kind: AuditHook})
+	if err := logger.Shutdown(); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+}
+
+func TestFileAuditLoggerCompressesRecords(t *testing.T) {
+	for _, codec := range []Compressor{nil, NewGzipCompressor(gzip.DefaultCompression), NewZstdCompressor(0)} {
+		t.Run("codec"+compressorExtension(codec), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+			logger, err := NewFileAuditLogger(path, codec)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			// Enough records to write several frames.
+			const n = 2000
+			for i := 0; i < n; i++ {
+				logger.Emit(context.Background(), AuditRecord{Kind: AuditPermission, Tool: "bash", Target: strings.Repeat("x", 40), Decision: "allow"})
+			}
+			if err := logger.Shutdown(); err != nil {
+				t.Fatalf("shutdown: %v", err)
+			}
+			records, err := ReadAuditLog(path, codec)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if len(records) != n || records[n-1].Kind != AuditPermission || records[n-1].Tool != "bash" {
+				t.Fatalf("expected %d records, got %d", n, len(records))
+			}
+		})
+	}
+}
+
+func TestFileAuditLoggerEncryptsRecords(t *testing.T) {
+	ring, err := NewKeyRing("k1", bytes.Repeat([]byte{3}, 32))
+	if err != nil {
+		t.Fatalf("key ring: %v", err)
+	}
+	enc := NewAESGCMEncryptor(ring)
+	for _, codec := range []Compressor{nil, NewZstdCompressor(0)} {
+		t.Run("codec"+compressorExtension(codec), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+			logger, err := NewFileAuditLogger(path, codec, AuditFileEncryption(enc))
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			ctx := context.Background()
+			for _, id := range []string{"s1", "s2", "s1"} {
+				logger.Emit(ctx, AuditRecord{Kind: AuditPermission, SessionID: id, Target: "secret-target"})
+			}
+			if n, err := logger.PurgeSession("s2"); err != nil || n != 1 {
+				t.Fatalf("purge n=%d err=%v", n, err)
+			}
+			logger.Emit(ctx, AuditRecord{Kind: AuditHook, SessionID: "s1", Target: "secret-target"})
+			if err := logger.Shutdown(); err != nil {
+				t.Fatalf("shutdown: %v", err)
+			}
+
+			raw, err := os.ReadFile(path + compressorExtension(codec))
+			if err != nil {
+				t.Fatalf("read raw: %v", err)
+			}
+			if bytes.Contains(raw, []byte("secret-target")) {
+				t.Fatalf("audit log stored in the clear")
+			}
+			if codec == nil {
+				// One sealed line per record.
+				if lines := bytes.Count(raw, []byte{'\n'}); lines != 3 {
+					t.Fatalf("expected 3 sealed lines, got %d", lines)
+				}
+			}
+			records, err := ReadAuditLog(path, codec, AuditFileEncryption(enc))
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if len(records) != 3 || records[2].Kind != AuditHook {
+				t.Fatalf("unexpected records %+v", records)
+			}
+			if _, err := ReadAuditLog(path, codec); err == nil {
+				t.Fatal("expected reading without the encryptor to fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/google/uuid"
+)
+
+// ErrBatchSkipped marks batch items that never started because the batch
+// was aborted by BatchFailFast or its context.
+var ErrBatchSkipped = errors.New("api: batch item skipped")
+
+// defaultBatchConcurrency is the worker count when neither BatchConcurrency
+// nor Options.MaxConcurrentRuns sets one.
+const defaultBatchConcurrency = 4
+
+// BatchOption configures RunBatch.
+type BatchOption func(*batchConfig)
+
+type batchConfig struct {
+	concurrency int
+	noWarmup    bool
+	failFast    bool
+	onItem      func(BatchItem)
+}
+
+// BatchConcurrency sets the number of requests running at once. It defaults
+// to Options.MaxConcurrentRuns, or 4 when that is unlimited.
+func BatchConcurrency(n int) BatchOption {
+	return func(c *batchConfig) { c.concurrency = n }
+}
+
+// BatchNoWarmup starts all workers at once and leaves prompt caching to each
+// request's EnablePromptCache.
+func BatchNoWarmup() BatchOption {
+	return func(c *batchConfig) { c.noWarmup = true }
+}
+
+// BatchFailFast stops starting new requests after the first failure; the
+// requests already running finish and the rest are reported with
+// ErrBatchSkipped.
+func BatchFailFast() BatchOption {
+	return func(c *batchConfig) { c.failFast = true }
+}
+
+// BatchOnItem calls fn as each request finishes, in completion order. Calls
+// are serialized, so fn may update progress state without locking.
+func BatchOnItem(fn func(BatchItem)) BatchOption {
+	return func(c *batchConfig) { c.onItem = fn }
+}
+
+// BatchItem is the outcome of one RunBatch request.
+type BatchItem struct {
+	// Index is the position of the request in the RunBatch slice.
+	Index    int
+	Response *Response
+	Err      error
+	Duration time.Duration
+}
+
+// BatchResult aggregates a RunBatch call.
+type BatchResult struct {
+	// Items holds one entry per request, in request order.
+	Items     []BatchItem
+	Succeeded int
+	Failed    int
+	// Usage sums Result.Usage of the successful responses.
+	Usage    model.Usage
+	Duration time.Duration
+}
+
+// RunBatch runs independent requests on a shared pool of workers, for eval
+// suites and labeling jobs. Requests without a SessionID get their own
+// session ("batch-<id>-<index>") so they never share history.
+//
+// Unless BatchNoWarmup is given, requests that leave EnablePromptCache unset
+// run with prompt caching on, and the first request runs alone before the
+// others start: it writes the shared system prompt and tool definitions to
+// the provider cache, which the rest then read instead of each paying to
+// create it.
+//
+// Failures of single requests are reported in BatchResult.Items and do not
+// fail the batch. RunBatch returns an error only when the runtime is closed
+// or ctx ends first; the partial result is returned with it.
+func (rt *Runtime) RunBatch(ctx context.Context, reqs []Request, opts ...BatchOption) (*BatchResult, error) {
+	if rt == nil {
+		return nil, ErrRuntimeClosed
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cfg := batchConfig{concurrency: rt.opts.MaxConcurrentRuns}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = defaultBatchConcurrency
+	}
+
+	started := time.Now()
+	batchID := uuid.NewString()[:8]
+	prepared := make([]Request, len(reqs))
+	for i, req := range reqs {
+		if strings.TrimSpace(req.SessionID) == "" {
+			req.SessionID = fmt.Sprintf("batch-%s-%d", batchID, i)
+		}
+		if !cfg.noWarmup && req.EnablePromptCache == nil {
+			enabled := true
+			req.EnablePromptCache = &enabled
+		}
+		prepared[i] = req
+	}
+
+	b := &batchRun{rt: rt, cfg: cfg, reqs: prepared, items: make([]BatchItem, len(reqs)), stop: make(chan struct{})}
+
+	next := 0
+	if !cfg.noWarmup && len(prepared) > 1 {
+		b.run(ctx, 0)
+		next = 1
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(cfg.concurrency, len(prepared)-next); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				b.run(ctx, i)
+			}
+		}()
+	}
+	for i := next; i < len(prepared); i++ {
+		if b.stopped(ctx) {
+			b.skip(i)
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			b.skip(i)
+		case <-b.stop:
+			b.skip(i)
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	res := &BatchResult{Items: b.items, Duration: time.Since(started)}
+	for _, item := range res.Items {
+		if item.Err != nil {
+			res.Failed++
+			continue
+		}
+		res.Succeeded++
+		if item.Response != nil && item.Response.Result != nil {
+			addUsage(&res.Usage, item.Response.Result.Usage)
+		}
+	}
+	return res, ctx.Err()
+}
+
+// batchRun is the shared state of one RunBatch call.
+type batchRun struct {
+	rt   *Runtime
+	cfg  batchConfig
+	reqs []Request
+	// stop is closed by the first failure under BatchFailFast.
+	stop chan struct{}
+
+	mu     sync.Mutex
+	items  []BatchItem
+	failed bool
+}
+
+func (b *batchRun) stopped(ctx context.Context) bool {
+	select {
+	case <-b.stop:
+		return true
+	default:
+		return ctx.Err() != nil
+	}
+}
+
+func (b *batchRun) run(ctx context.Context, i int) {
+	if b.stopped(ctx) {
+		b.skip(i)
+		return
+	}
+	started := time.Now()
+	resp, err := b.rt.Run(ctx, b.reqs[i])
+	b.finish(BatchItem{Index: i, Response: resp, Err: err, Duration: time.Since(started)})
+}
+
+func (b *batchRun) skip(i int) {
+	b.finish(BatchItem{Index: i, Err: ErrBatchSkipped})
+}
+
+func (b *batchRun) finish(item BatchItem) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.items[item.Index] = item
+	if item.Err != nil && !errors.Is(item.Err, ErrBatchSkipped) && b.cfg.failFast && !b.failed {
+		b.failed = true
+		close(b.stop)
+	}
+	if b.cfg.onItem != nil {
+		b.cfg.onItem(item)
+	}
+}
+
+// addUsage adds the token counts and cost of u to total.
+func addUsage(total *model.Usage, u model.Usage) {
+	total.InputTokens += u.InputTokens
+	total.OutputTokens += u.OutputTokens
+	total.TotalTokens += u.TotalTokens
+	total.CacheReadTokens += u.CacheReadTokens
+	total.CacheCreationTokens += u.CacheCreationTokens
+	total.CostUSD += u.CostUSD
+}
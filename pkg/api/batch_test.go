@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+// batchModel answers every prompt and records the calls it saw.
+type batchModel struct {
+	mu       sync.Mutex
+	prompts  []string
+	cached   []bool
+	history  []int
+	inflight int
+	peak     int
+	release  chan struct{}
+}
+
+func (m *batchModel) Complete(_ context.Context, req model.Request) (*model.Response, error) {
+	prompt := req.Messages[len(req.Messages)-1].Content
+	m.mu.Lock()
+	m.prompts = append(m.prompts, prompt)
+	m.cached = append(m.cached, req.EnablePromptCache)
+	m.history = append(m.history, len(req.Messages))
+	m.inflight++
+	m.peak = max(m.peak, m.inflight)
+	m.mu.Unlock()
+	if m.release != nil && prompt != "p0" {
+		<-m.release
+	}
+	m.mu.Lock()
+	m.inflight--
+	m.mu.Unlock()
+	if strings.HasPrefix(prompt, "fail") {
+		return nil, errors.New("model failed")
+	}
+	return &model.Response{
+		Message: model.Message{Role: "assistant", Content: "re: " + prompt},
+		Usage:   model.Usage{InputTokens: 10, OutputTokens: 2, TotalTokens: 12, CacheReadTokens: 5},
+	}, nil
+}
+
+func (m *batchModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	resp, err := m.Complete(ctx, req)
+	if err != nil {
+		return err
+	}
+	return cb(model.StreamResult{Final: true, Response: resp})
+}
+
+func newBatchRuntime(t *testing.T, mdl model.Model) *Runtime {
+	t.Helper()
+	rt, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: mdl})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	return rt
+}
+
+func TestRunBatchWarmupAndUsage(t *testing.T) {
+	mdl := &batchModel{release: make(chan struct{})}
+	rt := newBatchRuntime(t, mdl)
+
+	reqs := []Request{{Prompt: "p0"}, {Prompt: "p1"}, {Prompt: "p2"}, {Prompt: "p3"}}
+	var done []int
+	go func() {
+		// Hold the workers until both have started so the pool size shows.
+		for {
+			mdl.mu.Lock()
+			n := mdl.inflight
+			mdl.mu.Unlock()
+			if n == 2 {
+				close(mdl.release)
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	res, err := rt.RunBatch(context.Background(), reqs, BatchConcurrency(2), BatchOnItem(func(item BatchItem) {
+		done = append(done, item.Index)
+	}))
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if res.Succeeded != 4 || res.Failed != 0 || len(done) != 4 {
+		t.Fatalf("result = %+v, callbacks = %v", res, done)
+	}
+	if mdl.prompts[0] != "p0" || mdl.peak != 2 {
+		t.Fatalf("warmup did not run first alone: prompts=%v peak=%d", mdl.prompts, mdl.peak)
+	}
+	for i, cached := range mdl.cached {
+		if !cached || mdl.history[i] != 1 {
+			t.Fatalf("call %d: cached=%v history=%d, want a cached fresh session", i, cached, mdl.history[i])
+		}
+	}
+	for i, item := range res.Items {
+		if item.Index != i || item.Response == nil || item.Response.Result.Output != "re: p"+string(rune('0'+i)) {
+			t.Fatalf("item %d = %+v", i, item)
+		}
+	}
+	if res.Usage.InputTokens != 40 || res.Usage.TotalTokens != 48 || res.Usage.CacheReadTokens != 20 {
+		t.Fatalf("usage = %+v", res.Usage)
+	}
+}
+
+func TestRunBatchFailFast(t *testing.T) {
+	mdl := &batchModel{}
+	rt := newBatchRuntime(t, mdl)
+
+	reqs := []Request{{Prompt: "fail-0"}, {Prompt: "p1"}, {Prompt: "p2"}}
+	res, err := rt.RunBatch(context.Background(), reqs, BatchFailFast())
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if res.Failed != 3 || res.Succeeded != 0 {
+		t.Fatalf("result = %+v", res)
+	}
+	if errors.Is(res.Items[0].Err, ErrBatchSkipped) || !errors.Is(res.Items[1].Err, ErrBatchSkipped) || !errors.Is(res.Items[2].Err, ErrBatchSkipped) {
+		t.Fatalf("items = %+v", res.Items)
+	}
+	if len(mdl.prompts) != 1 {
+		t.Fatalf("skipped requests reached the model: %v", mdl.prompts)
+	}
+
+	res, err = rt.RunBatch(context.Background(), reqs, BatchNoWarmup())
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if res.Failed != 1 || res.Succeeded != 2 {
+		t.Fatalf("failure stopped the batch without BatchFailFast: %+v", res)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err = rt.RunBatch(ctx, reqs)
+	if !errors.Is(err, context.Canceled) || res == nil || res.Failed != 3 {
+		t.Fatalf("expected canceled batch, got %+v, %v", res, err)
+	}
+}
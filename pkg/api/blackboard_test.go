@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/runtime/blackboard"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+type boardProbeTool struct {
+	seen []*blackboard.Board
+}
+
+func (p *boardProbeTool) Name() string             { return "probe" }
+func (p *boardProbeTool) Description() string      { return "records the run blackboard" }
+func (p *boardProbeTool) Schema() *tool.JSONSchema { return &tool.JSONSchema{Type: "object"} }
+func (p *boardProbeTool) Execute(ctx context.Context, _ map[string]interface{}) (*tool.ToolResult, error) {
+	board, _ := blackboard.FromContext(ctx)
+	p.seen = append(p.seen, board)
+	return &tool.ToolResult{Success: true, Output: "ok"}, nil
+}
+
+func TestRuntimeScopesBlackboardPerRun(t *testing.T) {
+	root := newClaudeProject(t)
+	call := &model.Response{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "1", Name: "probe", Arguments: map[string]any{"x": 1}}}}}
+	done := &model.Response{Message: model.Message{Role: "assistant", Content: "done"}}
+	mdl := &stubModel{responses: []*model.Response{call, done, call, done, call, done}}
+	probe := &boardProbeTool{}
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl, Tools: []tool.Tool{probe}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	for i := 0; i < 2; i++ {
+		if _, err := rt.Run(context.Background(), Request{Prompt: "go", SessionID: "s"}); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	}
+	shared := blackboard.New()
+	if _, err := rt.Run(blackboard.WithBoard(context.Background(), shared), Request{Prompt: "go", SessionID: "s"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(probe.seen) != 3 || probe.seen[0] == nil || probe.seen[0] == probe.seen[1] {
+		t.Fatalf("expected a fresh board per run, got %v", probe.seen)
+	}
+	if probe.seen[2] != shared {
+		t.Fatal("expected caller-provided board to be inherited")
+	}
+}
@@ -0,0 +1,357 @@
+package api
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EventChannelOutput carries a ChannelOutput at the end of RunStream, one
+// event per rendered channel. Name holds the channel.
+const EventChannelOutput = "channel_output"
+
+// Content types reported by ChannelOutput.
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeText = "text/plain"
+)
+
+// ChannelOutput is the final result rendered for one delivery channel.
+type ChannelOutput struct {
+	Channel     string `json:"channel"`
+	ContentType string `json:"content_type"`
+	// Text is a plain-text rendering, usable as a notification fallback.
+	Text string `json:"text"`
+	// Payload is the channel-native document (Slack message, Teams activity);
+	// nil for text channels.
+	Payload map[string]any `json:"payload,omitempty"`
+	// Err is set when the adapter failed; the run itself still succeeds.
+	Err error `json:"-"`
+}
+
+// OutputAdapter renders a run result for a channel named in Request.Channels.
+type OutputAdapter interface {
+	Format(resp *Response) (ChannelOutput, error)
+}
+
+// OutputAdapterFunc adapts a function to OutputAdapter.
+type OutputAdapterFunc func(resp *Response) (ChannelOutput, error)
+
+// Format implements OutputAdapter.
+func (fn OutputAdapterFunc) Format(resp *Response) (ChannelOutput, error) {
+	if fn == nil {
+		return ChannelOutput{}, errors.New("api: output adapter is nil")
+	}
+	return fn(resp)
+}
+
+var (
+	outputAdaptersMu sync.RWMutex
+	outputAdapters   = map[string]OutputAdapter{
+		"slack": OutputAdapterFunc(formatSlack),
+		"teams": OutputAdapterFunc(formatTeams),
+		"text":  OutputAdapterFunc(formatPlainText),
+		"plain": OutputAdapterFunc(formatPlainText),
+	}
+)
+
+// RegisterOutputAdapter makes an adapter available for a channel name.
+// Channel names are case-insensitive; registering an existing name replaces
+// it, which also allows overriding the built-in slack, teams and text
+// adapters.
+func RegisterOutputAdapter(channel string, adapter OutputAdapter) {
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	if channel == "" || adapter == nil {
+		return
+	}
+	outputAdaptersMu.Lock()
+	defer outputAdaptersMu.Unlock()
+	outputAdapters[channel] = adapter
+}
+
+func lookupOutputAdapter(channel string) (OutputAdapter, bool) {
+	outputAdaptersMu.RLock()
+	defer outputAdaptersMu.RUnlock()
+	adapter, ok := outputAdapters[strings.ToLower(strings.TrimSpace(channel))]
+	return adapter, ok
+}
+
+// renderChannelOutputs formats resp for every requested channel that has an
+// adapter. Channels without one are skipped.
+func renderChannelOutputs(channels []string, resp *Response) map[string]ChannelOutput {
+	if len(channels) == 0 || resp == nil {
+		return nil
+	}
+	var outputs map[string]ChannelOutput
+	for _, channel := range channels {
+		adapter, ok := lookupOutputAdapter(channel)
+		if !ok {
+			continue
+		}
+		if _, done := outputs[channel]; done {
+			continue
+		}
+		out, err := adapter.Format(resp)
+		out.Channel = channel
+		out.Err = err
+		if outputs == nil {
+			outputs = map[string]ChannelOutput{}
+		}
+		outputs[channel] = out
+	}
+	return outputs
+}
+
+// sortedChannels returns output keys in a stable order for streaming.
+func sortedChannels(outputs map[string]ChannelOutput) []string {
+	keys := make([]string, 0, len(outputs))
+	for k := range outputs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func responseText(resp *Response) string {
+	if resp == nil || resp.Result == nil {
+		return ""
+	}
+	return strings.TrimSpace(resp.Result.Output)
+}
+
+// ----------------- markdown segmentation -----------------
+
+type mdSegmentKind int
+
+const (
+	mdParagraph mdSegmentKind = iota
+	mdHeading
+	mdCode
+)
+
+type mdSegment struct {
+	kind mdSegmentKind
+	text string
+}
+
+var mdHeadingRe = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*\s*$`)
+
+// splitMarkdown separates fenced code, headings and paragraphs so adapters
+// can map each to a native element.
+func splitMarkdown(src string) []mdSegment {
+	var (
+		segments []mdSegment
+		buf      []string
+		inFence  bool
+	)
+	flush := func(kind mdSegmentKind) {
+		text := strings.Join(buf, "\n")
+		if kind != mdCode {
+			text = strings.TrimSpace(text)
+		}
+		if text != "" {
+			segments = append(segments, mdSegment{kind: kind, text: text})
+		}
+		buf = buf[:0]
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if inFence {
+				flush(mdCode)
+			} else {
+				flush(mdParagraph)
+			}
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			buf = append(buf, line)
+			continue
+		}
+		if m := mdHeadingRe.FindStringSubmatch(trimmed); m != nil {
+			flush(mdParagraph)
+			segments = append(segments, mdSegment{kind: mdHeading, text: m[1]})
+			continue
+		}
+		if trimmed == "" {
+			flush(mdParagraph)
+			continue
+		}
+		buf = append(buf, line)
+	}
+	if inFence {
+		flush(mdCode)
+	} else {
+		flush(mdParagraph)
+	}
+	return segments
+}
+
+var (
+	mdBoldRe       = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	mdStrikeRe     = regexp.MustCompile(`~~(.+?)~~`)
+	mdLinkRe       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdBulletRe     = regexp.MustCompile(`(?m)^(\s*)[-*+]\s+`)
+	mdInlineCodeRe = regexp.MustCompile("`([^`]+)`")
+	mdItalicRe     = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*?)\*`)
+)
+
+// ----------------- slack -----------------
+
+// slackSectionLimit is the maximum length of a section block's text.
+const slackSectionLimit = 3000
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackMrkdwn converts common markdown to Slack mrkdwn.
+func slackMrkdwn(text string) string {
+	text = slackEscaper.Replace(text)
+	text = mdBulletRe.ReplaceAllString(text, "$1• ")
+	text = mdItalicRe.ReplaceAllString(text, "${1}_${2}_")
+	text = mdBoldRe.ReplaceAllStringFunc(text, func(m string) string {
+		return "*" + m[2:len(m)-2] + "*"
+	})
+	text = mdStrikeRe.ReplaceAllString(text, "~$1~")
+	return mdLinkRe.ReplaceAllString(text, "<$2|$1>")
+}
+
+func formatSlack(resp *Response) (ChannelOutput, error) {
+	var blocks []any
+	section := func(text string) {
+		for _, chunk := range chunkText(text, slackSectionLimit) {
+			blocks = append(blocks, map[string]any{
+				"type": "section",
+				"text": map[string]any{"type": "mrkdwn", "text": chunk},
+			})
+		}
+	}
+	for _, seg := range splitMarkdown(responseText(resp)) {
+		switch seg.kind {
+		case mdHeading:
+			if len(seg.text) <= 150 {
+				blocks = append(blocks, map[string]any{
+					"type": "header",
+					"text": map[string]any{"type": "plain_text", "text": seg.text},
+				})
+			} else {
+				section("*" + slackMrkdwn(seg.text) + "*")
+			}
+		case mdCode:
+			for _, chunk := range chunkText(slackEscaper.Replace(seg.text), slackSectionLimit-8) {
+				section("```\n" + chunk + "\n```")
+			}
+		default:
+			section(slackMrkdwn(seg.text))
+		}
+	}
+	fallback := plainText(responseText(resp))
+	return ChannelOutput{
+		ContentType: ContentTypeJSON,
+		Text:        fallback,
+		Payload:     map[string]any{"text": fallback, "blocks": blocks},
+	}, nil
+}
+
+// chunkText splits text on line boundaries into pieces of at most limit
+// bytes; single overlong lines are cut.
+func chunkText(text string, limit int) []string {
+	if len(text) <= limit {
+		return []string{text}
+	}
+	var (
+		chunks []string
+		cur    strings.Builder
+	)
+	for _, line := range strings.Split(text, "\n") {
+		for len(line) > limit {
+			if cur.Len() > 0 {
+				chunks = append(chunks, cur.String())
+				cur.Reset()
+			}
+			chunks = append(chunks, line[:limit])
+			line = line[limit:]
+		}
+		if cur.Len() > 0 && cur.Len()+1+len(line) > limit {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+		}
+		if cur.Len() > 0 {
+			cur.WriteByte('\n')
+		}
+		cur.WriteString(line)
+	}
+	if cur.Len() > 0 {
+		chunks = append(chunks, cur.String())
+	}
+	return chunks
+}
+
+// ----------------- teams -----------------
+
+func formatTeams(resp *Response) (ChannelOutput, error) {
+	body := []any{}
+	for _, seg := range splitMarkdown(responseText(resp)) {
+		block := map[string]any{"type": "TextBlock", "text": seg.text, "wrap": true}
+		switch seg.kind {
+		case mdHeading:
+			block["weight"] = "Bolder"
+			block["size"] = "Medium"
+		case mdCode:
+			block["fontType"] = "Monospace"
+		}
+		body = append(body, block)
+	}
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	fallback := plainText(responseText(resp))
+	return ChannelOutput{
+		ContentType: ContentTypeJSON,
+		Text:        fallback,
+		Payload: map[string]any{
+			"type":    "message",
+			"summary": firstLine(fallback),
+			"attachments": []any{map[string]any{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content":     card,
+			}},
+		},
+	}, nil
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
+
+// ----------------- plain text -----------------
+
+func formatPlainText(resp *Response) (ChannelOutput, error) {
+	return ChannelOutput{ContentType: ContentTypeText, Text: plainText(responseText(resp))}, nil
+}
+
+// plainText strips markdown markup while keeping the text readable.
+func plainText(src string) string {
+	segments := splitMarkdown(src)
+	parts := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		if seg.kind == mdCode {
+			parts = append(parts, seg.text)
+			continue
+		}
+		text := mdLinkRe.ReplaceAllString(seg.text, "$1 ($2)")
+		text = mdBoldRe.ReplaceAllString(text, "$1$2")
+		text = mdStrikeRe.ReplaceAllString(text, "$1")
+		text = mdItalicRe.ReplaceAllString(text, "$1$2")
+		text = mdInlineCodeRe.ReplaceAllString(text, "$1")
+		text = mdBulletRe.ReplaceAllString(text, "$1- ")
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const channelMarkdown = "# Deploy report\n\nAll **green** & see [logs](https://ci/1).\n\n- api ok\n- web ok\n\n```\nmake deploy\n```"
+
+func TestRunRendersRequestedChannels(t *testing.T) {
+	rt := newTestRuntime(t, staticModel{content: channelMarkdown}, CompactConfig{})
+	resp, err := rt.Run(context.Background(), Request{Prompt: "report", Channels: []string{"slack", "teams", "text", "cli"}})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(resp.Outputs) != 3 {
+		t.Fatalf("expected slack/teams/text outputs, got %v", resp.Outputs)
+	}
+
+	slack := resp.Outputs["slack"]
+	blocks, _ := slack.Payload["blocks"].([]any)
+	if slack.ContentType != ContentTypeJSON || len(blocks) != 4 {
+		t.Fatalf("unexpected slack output %+v", slack)
+	}
+	if header := blocks[0].(map[string]any); header["type"] != "header" {
+		t.Fatalf("first block should be a header: %v", header)
+	}
+	para := blocks[1].(map[string]any)["text"].(map[string]any)["text"].(string)
+	if para != "All *green* &amp; see <https://ci/1|logs>." {
+		t.Fatalf("mrkdwn = %q", para)
+	}
+	bullets := blocks[2].(map[string]any)["text"].(map[string]any)["text"].(string)
+	if bullets != "• api ok\n• web ok" {
+		t.Fatalf("bullets = %q", bullets)
+	}
+
+	teams := resp.Outputs["teams"]
+	attachments, _ := teams.Payload["attachments"].([]any)
+	if len(attachments) != 1 {
+		t.Fatalf("unexpected teams payload %v", teams.Payload)
+	}
+	card := attachments[0].(map[string]any)["content"].(map[string]any)
+	body := card["body"].([]any)
+	if card["type"] != "AdaptiveCard" || body[0].(map[string]any)["weight"] != "Bolder" || body[3].(map[string]any)["fontType"] != "Monospace" {
+		t.Fatalf("unexpected card %v", card)
+	}
+
+	text := resp.Outputs["text"]
+	want := "Deploy report\n\nAll green & see logs (https://ci/1).\n\n- api ok\n- web ok\n\nmake deploy"
+	if text.ContentType != ContentTypeText || text.Text != want || text.Payload != nil {
+		t.Fatalf("plain text = %q", text.Text)
+	}
+}
+
+func TestRegisterOutputAdapterAndStream(t *testing.T) {
+	RegisterOutputAdapter("Pager", OutputAdapterFunc(func(resp *Response) (ChannelOutput, error) {
+		return ChannelOutput{ContentType: ContentTypeText, Text: strings.ToUpper(resp.Result.Output)}, nil
+	}))
+	RegisterOutputAdapter("broken", OutputAdapterFunc(func(*Response) (ChannelOutput, error) {
+		return ChannelOutput{}, errors.New("boom")
+	}))
+	rt := newTestRuntime(t, staticModel{content: "hello"}, CompactConfig{})
+	events, err := rt.RunStream(context.Background(), Request{Prompt: "hi", Channels: []string{"pager", "broken"}})
+	if err != nil {
+		t.Fatalf("run stream: %v", err)
+	}
+	got := map[string]ChannelOutput{}
+	for evt := range events {
+		if evt.Type == EventChannelOutput {
+			got[evt.Name] = evt.Output.(ChannelOutput)
+		}
+	}
+	if got["pager"].Text != "HELLO" || got["pager"].Channel != "pager" {
+		t.Fatalf("custom adapter output %+v", got["pager"])
+	}
+	if got["broken"].Err == nil {
+		t.Fatalf("expected adapter error to be recorded, got %+v", got["broken"])
+	}
+}
@@ -11,16 +11,61 @@ import (
 
 	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
 	corehooks "github.com/cexll/agentsdk-go/pkg/core/hooks"
+	"github.com/cexll/agentsdk-go/pkg/core/retry"
 	"github.com/cexll/agentsdk-go/pkg/message"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
 	"github.com/cexll/agentsdk-go/pkg/model"
 )
 
+// CompactStrategy selects how older turns are shrunk when the history
+// approaches the context window.
+type CompactStrategy string
+
+const (
+	// CompactSummarize replaces older turns with a model-written summary
+	// (default).
+	CompactSummarize CompactStrategy = "summarize"
+	// CompactTruncate drops older turns without calling a model.
+	CompactTruncate CompactStrategy = "truncate"
+	// CompactDropToolOutputs keeps older turns but replaces tool outputs
+	// with a placeholder.
+	CompactDropToolOutputs CompactStrategy = "drop-tool-outputs"
+)
+
+// droppedToolOutput replaces tool results removed by CompactDropToolOutputs.
+const droppedToolOutput = "[tool output removed during context compaction]"
+
+// CompactionEvent describes one compaction. Middleware implementing
+// CompactionObserver receives it, and it is stored in the run's
+// middleware.State.Values under CompactionStateKey.
+type CompactionEvent struct {
+	SessionID         string
+	Strategy          CompactStrategy
+	Summary           string
+	OriginalMessages  int
+	PreservedMessages int
+	TokensBefore      int
+	TokensAfter       int
+}
+
+// CompactionStateKey is the middleware.State.Values key holding the most
+// recent CompactionEvent of the run.
+const CompactionStateKey = "compaction.event"
+
+// CompactionObserver is implemented by middleware that wants to observe
+// context compaction.
+type CompactionObserver interface {
+	OnCompaction(ctx context.Context, evt CompactionEvent)
+}
+
 // CompactConfig controls automatic context compaction.
 type CompactConfig struct {
-	Enabled       bool    `json:"enabled"`
-	Threshold     float64 `json:"threshold"`      // trigger ratio (default 0.8)
-	PreserveCount int     `json:"preserve_count"` // keep latest N messages (default 5)
-	SummaryModel  string  `json:"summary_model"`  // model tier/name used for summary
+	Enabled bool `json:"enabled"`
+	// Strategy is summarize (default), truncate or drop-tool-outputs.
+	Strategy      CompactStrategy `json:"strategy"`
+	Threshold     float64         `json:"threshold"`      // trigger ratio (default 0.8)
+	PreserveCount int             `json:"preserve_count"` // keep latest N messages (default 5)
+	SummaryModel  string          `json:"summary_model"`  // model tier/name used for summary
 
 	PreserveInitial  bool `json:"preserve_initial"`   // keep initial messages when compacting
 	InitialCount     int  `json:"initial_count"`      // keep first N messages from the compacted prefix
@@ -57,6 +102,11 @@ func (c CompactConfig) withDefaults() CompactConfig {
 		cfg.PreserveCount = 1
 	}
 	cfg.SummaryModel = strings.TrimSpace(cfg.SummaryModel)
+	switch cfg.Strategy {
+	case CompactTruncate, CompactDropToolOutputs:
+	default:
+		cfg.Strategy = CompactSummarize
+	}
 	if cfg.InitialCount < 0 {
 		cfg.InitialCount = 0
 	}
@@ -78,12 +128,13 @@ func (c CompactConfig) withDefaults() CompactConfig {
 }
 
 type compactor struct {
-	cfg     CompactConfig
-	model   model.Model
-	limit   int
-	hooks   *corehooks.Executor
-	rollout *RolloutWriter
-	mu      sync.Mutex
+	cfg       CompactConfig
+	model     model.Model
+	limit     int
+	hooks     *corehooks.Executor
+	rollout   *RolloutWriter
+	observers []CompactionObserver
+	mu        sync.Mutex
 }
 
 func newCompactor(projectRoot string, cfg CompactConfig, mdl model.Model, tokenLimit int, hooks *corehooks.Executor) *compactor {
@@ -105,6 +156,27 @@ func newCompactor(projectRoot string, cfg CompactConfig, mdl model.Model, tokenL
 	}
 }
 
+// observe registers the middleware that implement CompactionObserver.
+func (c *compactor) observe(items []middleware.Middleware) {
+	if c == nil {
+		return
+	}
+	for _, mw := range items {
+		if obs, ok := mw.(CompactionObserver); ok {
+			c.observers = append(c.observers, obs)
+		}
+	}
+}
+
+// setAtRest enables compression/encryption for rollout files written by c.
+func (c *compactor) setAtRest(codec Compressor, enc Encryptor) {
+	if c == nil || c.rollout == nil {
+		return
+	}
+	c.rollout.codec = codec
+	c.rollout.enc = enc
+}
+
 func (c *compactor) shouldCompact(msgCount, tokenCount int) bool {
 	if c == nil || !c.cfg.Enabled {
 		return false
@@ -120,6 +192,7 @@ func (c *compactor) shouldCompact(msgCount, tokenCount int) bool {
 }
 
 type compactResult struct {
+	strategy      CompactStrategy
 	summary       string
 	originalMsgs  int
 	preservedMsgs int
@@ -165,7 +238,7 @@ func (c *compactor) maybeCompact(ctx context.Context, hist *message.History, ses
 		}
 		return compactResult{}, false, err
 	}
-	c.postCompact(sessionID, res, recorder)
+	c.postCompact(ctx, sessionID, res, recorder)
 	return res, true, nil
 }
 
@@ -195,8 +268,9 @@ func (c *compactor) preCompact(ctx context.Context, sessionID string, payload co
 	return true, nil
 }
 
-func (c *compactor) postCompact(sessionID string, res compactResult, recorder *hookRecorder) {
+func (c *compactor) postCompact(ctx context.Context, sessionID string, res compactResult, recorder *hookRecorder) {
 	payload := coreevents.ContextCompactedPayload{
+		Strategy:              string(res.strategy),
 		Summary:               res.summary,
 		OriginalMessages:      res.originalMsgs,
 		PreservedMessages:     res.preservedMsgs,
@@ -218,6 +292,25 @@ func (c *compactor) postCompact(sessionID string, res compactResult, recorder *h
 			log.Printf("api: write compaction rollout: %v", err)
 		}
 	}
+
+	event := CompactionEvent{
+		SessionID:         sessionID,
+		Strategy:          res.strategy,
+		Summary:           res.summary,
+		OriginalMessages:  res.originalMsgs,
+		PreservedMessages: res.preservedMsgs,
+		TokensBefore:      res.tokensBefore,
+		TokensAfter:       res.tokensAfter,
+	}
+	if st, ok := ctx.Value(model.MiddlewareStateKey).(*middleware.State); ok && st != nil {
+		if st.Values == nil {
+			st.Values = map[string]any{}
+		}
+		st.Values[CompactionStateKey] = event
+	}
+	for _, obs := range c.observers {
+		obs.OnCompaction(ctx, event)
+	}
 }
 
 func (c *compactor) record(recorder *hookRecorder, evt coreevents.Event) {
@@ -228,7 +321,7 @@ func (c *compactor) record(recorder *hookRecorder, evt coreevents.Event) {
 }
 
 func (c *compactor) compact(ctx context.Context, hist *message.History, snapshot []message.Message, tokensBefore int) (compactResult, error) {
-	if c.model == nil {
+	if c.model == nil && c.cfg.Strategy == CompactSummarize {
 		return compactResult{}, errors.New("api: summary model is nil")
 	}
 	preserve := c.cfg.PreserveCount
@@ -236,6 +329,9 @@ func (c *compactor) compact(ctx context.Context, hist *message.History, snapshot
 		return compactResult{}, nil
 	}
 	cut := len(snapshot) - preserve
+	if c.cfg.Strategy == CompactDropToolOutputs {
+		return c.dropToolOutputs(hist, snapshot, cut, tokensBefore)
+	}
 	older := snapshot[:cut]
 	kept := snapshot[cut:]
 
@@ -293,39 +389,88 @@ func (c *compactor) compact(ctx context.Context, hist *message.History, snapshot
 		return compactResult{}, errNoCompaction
 	}
 
+	var marker message.Message
+	summary := ""
+	if c.cfg.Strategy == CompactTruncate {
+		marker = message.Message{Role: "system", Content: fmt.Sprintf("已截断 %d 条较早的消息", len(summarize))}
+	} else {
+		text, err := c.summarize(ctx, summarize)
+		if err != nil {
+			return compactResult{}, err
+		}
+		summary = text
+		marker = message.Message{Role: "system", Content: fmt.Sprintf("对话摘要：\n%s", summary)}
+	}
+
+	newMsgs := make([]message.Message, 0, len(initial)+1+len(userText)+len(kept))
+	newMsgs = append(newMsgs, message.CloneMessages(initial)...)
+	newMsgs = append(newMsgs, marker)
+	newMsgs = append(newMsgs, message.CloneMessages(userText)...)
+	newMsgs = append(newMsgs, message.CloneMessages(kept)...)
+	hist.Replace(newMsgs)
+
+	tokensAfter := hist.TokenCount()
+	preservedMsgs := len(initial) + len(userText) + len(kept)
+	return compactResult{
+		strategy:      c.cfg.Strategy,
+		summary:       summary,
+		originalMsgs:  len(snapshot),
+		preservedMsgs: preservedMsgs,
+		tokensBefore:  tokensBefore,
+		tokensAfter:   tokensAfter,
+	}, nil
+}
+
+// summarize asks the summary model to condense msgs.
+func (c *compactor) summarize(ctx context.Context, msgs []message.Message) (string, error) {
 	req := model.Request{
-		Messages:  convertMessages(summarize),
+		Messages:  convertMessages(msgs),
 		System:    summarySystemPrompt,
 		Model:     c.cfg.SummaryModel,
 		MaxTokens: summaryMaxTokens,
 	}
 	resp, err := c.completeSummary(ctx, req)
 	if err != nil {
-		return compactResult{}, fmt.Errorf("api: compact summary: %w", err)
+		return "", fmt.Errorf("api: compact summary: %w", err)
 	}
 	summary := strings.TrimSpace(resp.Message.Content)
 	if summary == "" {
 		summary = "对话摘要为空"
 	}
+	return summary, nil
+}
 
-	newMsgs := make([]message.Message, 0, len(initial)+1+len(userText)+len(kept))
-	newMsgs = append(newMsgs, message.CloneMessages(initial)...)
-	newMsgs = append(newMsgs, message.Message{
-		Role:    "system",
-		Content: fmt.Sprintf("对话摘要：\n%s", summary),
-	})
-	newMsgs = append(newMsgs, message.CloneMessages(userText)...)
-	newMsgs = append(newMsgs, message.CloneMessages(kept)...)
+// dropToolOutputs replaces tool results before cut with a placeholder,
+// keeping every message so tool calls stay paired with their results.
+func (c *compactor) dropToolOutputs(hist *message.History, snapshot []message.Message, cut, tokensBefore int) (compactResult, error) {
+	newMsgs := message.CloneMessages(snapshot)
+	dropped := 0
+	for i := 0; i < cut; i++ {
+		msg := &newMsgs[i]
+		if msg.Role != "tool" {
+			continue
+		}
+		for j := range msg.ToolCalls {
+			if msg.ToolCalls[j].Result != droppedToolOutput {
+				msg.ToolCalls[j].Result = droppedToolOutput
+				dropped++
+			}
+		}
+		if msg.Content != "" && msg.Content != droppedToolOutput {
+			msg.Content = droppedToolOutput
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		return compactResult{}, errNoCompaction
+	}
 	hist.Replace(newMsgs)
-
-	tokensAfter := hist.TokenCount()
-	preservedMsgs := len(initial) + len(userText) + len(kept)
 	return compactResult{
-		summary:       summary,
+		strategy:      CompactDropToolOutputs,
 		originalMsgs:  len(snapshot),
-		preservedMsgs: preservedMsgs,
+		preservedMsgs: len(newMsgs),
 		tokensBefore:  tokensBefore,
-		tokensAfter:   tokensAfter,
+		tokensAfter:   hist.TokenCount(),
 	}, nil
 }
 
@@ -341,39 +486,39 @@ func (c *compactor) completeSummary(ctx context.Context, req model.Request) (*mo
 		attempts = 1
 	}
 
-	var lastErr error
-	for attempt := 1; attempt <= attempts; attempt++ {
+	var (
+		resp    *model.Response
+		attempt int
+	)
+	loop := retry.Loop{
+		Stage:      "compact",
+		MaxRetries: attempts - 1,
+		Backoff:    func(int) time.Duration { return c.cfg.RetryDelay },
+	}
+	err := loop.Do(ctx, func(ctx context.Context) error {
+		attempt++
 		if attempt > 1 {
-			if delay := c.cfg.RetryDelay; delay > 0 {
-				timer := time.NewTimer(delay)
-				select {
-				case <-ctx.Done():
-					timer.Stop()
-					return nil, ctx.Err()
-				case <-timer.C:
-				}
-			}
 			if fallback := strings.TrimSpace(c.cfg.FallbackModel); fallback != "" {
 				req.Model = fallback
 			}
 		}
-		var resp *model.Response
+		resp = nil
 		err := c.model.CompleteStream(ctx, req, func(sr model.StreamResult) error {
 			if sr.Final && sr.Response != nil {
 				resp = sr.Response
 			}
 			return nil
 		})
-		if err == nil && resp != nil {
-			return resp, nil
-		}
 		if err == nil && resp == nil {
 			err = errors.New("api: compact summary returned no final response")
 		}
-		lastErr = err
-		if attempts > 1 {
+		if err != nil && attempts > 1 {
 			log.Printf("api: compact summary attempt %d/%d failed: %v", attempt, attempts, err)
 		}
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil, lastErr
+	return resp, nil
 }
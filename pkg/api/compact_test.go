@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/cexll/agentsdk-go/pkg/message"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
 	"github.com/cexll/agentsdk-go/pkg/model"
 )
 
@@ -71,3 +72,64 @@ func TestCompactorCompleteSummaryError(t *testing.T) {
 		t.Fatalf("expected summary error")
 	}
 }
+
+type compactionRecorder struct {
+	middleware.Funcs
+	events []CompactionEvent
+}
+
+func (r *compactionRecorder) OnCompaction(_ context.Context, evt CompactionEvent) {
+	r.events = append(r.events, evt)
+}
+
+func TestCompactorStrategies(t *testing.T) {
+	t.Parallel()
+
+	build := func() *message.History {
+		hist := message.NewHistory()
+		hist.Append(message.Message{Role: "user", Content: "list files"})
+		hist.Append(message.Message{Role: "assistant", ToolCalls: []message.ToolCall{{ID: "t1", Name: "bash"}}})
+		hist.Append(message.Message{Role: "tool", ToolCalls: []message.ToolCall{{ID: "t1", Name: "bash", Result: "a.go\nb.go"}}})
+		hist.Append(message.Message{Role: "user", Content: "thanks"})
+		return hist
+	}
+
+	t.Run("truncate", func(t *testing.T) {
+		hist := build()
+		rec := &compactionRecorder{}
+		comp := newCompactor("", CompactConfig{Enabled: true, Strategy: CompactTruncate, PreserveCount: 1, Threshold: 0.1}, nil, 1, nil)
+		comp.observe([]middleware.Middleware{rec, middleware.Funcs{}})
+		st := &middleware.State{}
+		ctx := context.WithValue(context.Background(), model.MiddlewareStateKey, st)
+		res, ok, err := comp.maybeCompact(ctx, hist, "sess", nil)
+		if err != nil || !ok || res.summary != "" {
+			t.Fatalf("res=%+v ok=%v err=%v", res, ok, err)
+		}
+		msgs := hist.All()
+		if len(msgs) != 2 || msgs[0].Role != "system" || msgs[1].Content != "thanks" {
+			t.Fatalf("unexpected history %+v", msgs)
+		}
+		if len(rec.events) != 1 || rec.events[0].Strategy != CompactTruncate || rec.events[0].SessionID != "sess" {
+			t.Fatalf("observer events %+v", rec.events)
+		}
+		if evt, ok := st.Values[CompactionStateKey].(CompactionEvent); !ok || evt.OriginalMessages != 4 {
+			t.Fatalf("state value %+v", st.Values)
+		}
+	})
+
+	t.Run("drop tool outputs", func(t *testing.T) {
+		hist := build()
+		comp := newCompactor("", CompactConfig{Enabled: true, Strategy: CompactDropToolOutputs, PreserveCount: 1, Threshold: 0.1}, nil, 1, nil)
+		res, ok, err := comp.maybeCompact(context.Background(), hist, "sess", nil)
+		if err != nil || !ok || res.preservedMsgs != 4 {
+			t.Fatalf("res=%+v ok=%v err=%v", res, ok, err)
+		}
+		msgs := hist.All()
+		if len(msgs) != 4 || msgs[2].ToolCalls[0].Result != droppedToolOutput || msgs[2].ToolCalls[0].ID != "t1" {
+			t.Fatalf("unexpected history %+v", msgs)
+		}
+		if _, ok, _ := comp.maybeCompact(context.Background(), hist, "sess", nil); ok {
+			t.Fatal("second pass should have nothing left to drop")
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+)
+
+// Compliance checks the effective settings against
+// Options.ComplianceBaseline. It returns nil without a baseline.
+func (rt *Runtime) Compliance() []config.ComplianceViolation {
+	if rt == nil || rt.opts.ComplianceBaseline == nil {
+		return nil
+	}
+	return config.CheckCompliance(rt.Settings(), rt.opts.ComplianceBaseline)
+}
+
+// checkCompliance runs the startup compliance check: violations fail New
+// with EnforceCompliance and are logged otherwise.
+func checkCompliance(settings *config.Settings, opts Options) error {
+	violations := config.CheckCompliance(settings, opts.ComplianceBaseline)
+	if len(violations) == 0 {
+		return nil
+	}
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = v.String()
+	}
+	if opts.EnforceCompliance {
+		return fmt.Errorf("%w: %s", ErrNonCompliant, strings.Join(lines, "; "))
+	}
+	for _, line := range lines {
+		log.Printf("api: compliance: %s", line)
+	}
+	return nil
+}
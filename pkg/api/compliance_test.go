@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+)
+
+func TestComplianceCheckedAtStartup(t *testing.T) {
+	baseline := &config.CompliancePolicy{RequireSandbox: true, DeniedTools: []string{"WebFetch"}}
+	opts := Options{
+		ProjectRoot:         t.TempDir(),
+		Model:               &stubModel{},
+		EnabledBuiltinTools: []string{},
+		ComplianceBaseline:  baseline,
+		EnforceCompliance:   true,
+	}
+	_, err := New(context.Background(), opts)
+	if !errors.Is(err, ErrNonCompliant) || !strings.Contains(err.Error(), "sandbox.enabled: sandbox must be enabled") {
+		t.Fatalf("expected ErrNonCompliant, got %v", err)
+	}
+
+	opts.EnforceCompliance = false
+	rt, err := New(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("report-only compliance should not fail New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	if got := rt.Compliance(); len(got) != 2 {
+		t.Fatalf("violations = %+v", got)
+	}
+
+	h, err := rt.AdminHandler("tok")
+	if err != nil {
+		t.Fatalf("admin handler: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/compliance", nil)
+	req.Header.Set("Authorization", "Bearer tok")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var body struct {
+		Baseline   bool                         `json:"baseline"`
+		Compliant  bool                         `json:"compliant"`
+		Violations []config.ComplianceViolation `json:"violations"`
+	}
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &body) != nil {
+		t.Fatalf("compliance status %d: %s", rec.Code, rec.Body)
+	}
+	if !body.Baseline || body.Compliant || len(body.Violations) != 2 || body.Violations[1].Rule != "deniedTools" {
+		t.Fatalf("unexpected compliance body %+v", body)
+	}
+
+	opts.ComplianceBaseline = nil
+	opts.EnforceCompliance = true
+	plain, err := New(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("no baseline: %v", err)
+	}
+	defer plain.Close()
+	if plain.Compliance() != nil {
+		t.Fatal("no baseline should report nothing")
+	}
+}
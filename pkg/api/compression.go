@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressor transparently compresses data the runtime persists to disk
+// (session transcripts, compaction rollouts and FileAuditLogger files). The
+// extension identifies the codec on disk so files written without
+// compression, or with another built-in codec, remain readable.
+// Implementations must be safe for concurrent use.
+type Compressor interface {
+	// Extension is appended to persisted file names, e.g. ".gz".
+	Extension() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+type gzipCompressor struct {
+	level int
+}
+
+// NewGzipCompressor returns a stdlib gzip Compressor. Levels outside
+// gzip.HuffmanOnly..gzip.BestCompression fall back to gzip.DefaultCompression.
+// Other codecs can be plugged in by implementing Compressor.
+func NewGzipCompressor(level int) Compressor {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	return gzipCompressor{level: level}
+}
+
+func (gzipCompressor) Extension() string { return ".gz" }
+
+func (c gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, fmt.Errorf("gzip writer: %w", err)
+	}
+	if _, err := zw.Write(data); err != nil {
+		_ = zw.Close()
+		return nil, fmt.Errorf("gzip write: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("gzip close: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gzip reader: %w", err)
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("gzip read: %w", err)
+	}
+	return out, nil
+}
+
+type zstdCompressor struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+	err error
+}
+
+// NewZstdCompressor returns a zstd Compressor. level is a zstd.EncoderLevel;
+// values outside zstd.SpeedFastest..zstd.SpeedBestCompression fall back to
+// zstd.SpeedDefault.
+func NewZstdCompressor(level int) Compressor {
+	l := zstd.EncoderLevel(level)
+	if l < zstd.SpeedFastest || l > zstd.SpeedBestCompression {
+		l = zstd.SpeedDefault
+	}
+	c := &zstdCompressor{}
+	c.enc, c.err = zstd.NewWriter(nil, zstd.WithEncoderLevel(l))
+	if c.err == nil {
+		c.dec, c.err = zstd.NewReader(nil)
+	}
+	return c
+}
+
+func (*zstdCompressor) Extension() string { return ".zst" }
+
+func (c *zstdCompressor) Compress(data []byte) ([]byte, error) {
+	if c.err != nil {
+		return nil, fmt.Errorf("zstd: %w", c.err)
+	}
+	return c.enc.EncodeAll(data, nil), nil
+}
+
+func (c *zstdCompressor) Decompress(data []byte) ([]byte, error) {
+	if c.err != nil {
+		return nil, fmt.Errorf("zstd: %w", c.err)
+	}
+	out, err := c.dec.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("zstd read: %w", err)
+	}
+	return out, nil
+}
+
+// builtinCompressors are used to read files written with a codec other than
+// the configured one.
+var builtinCompressors = sync.OnceValue(func() []Compressor {
+	return []Compressor{NewZstdCompressor(0), NewGzipCompressor(gzip.DefaultCompression)}
+})
+
+// storedCodecs lists the codecs a persisted file may have been written with,
+// c first, so files stay readable after Options.Compression changes. A nil
+// entry stands for uncompressed files and comes first when c is nil.
+func storedCodecs(c Compressor) []Compressor {
+	out := []Compressor{c}
+	for _, b := range builtinCompressors() {
+		if compressorExtension(b) != compressorExtension(c) {
+			out = append(out, b)
+		}
+	}
+	if c != nil {
+		out = append(out, nil)
+	}
+	return out
+}
+
+func compressorExtension(c Compressor) string {
+	if c == nil {
+		return ""
+	}
+	return c.Extension()
+}
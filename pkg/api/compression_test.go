@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+func TestGzipCompressorRoundTrip(t *testing.T) {
+	c := NewGzipCompressor(99) // invalid level falls back to default
+	if c.Extension() != ".gz" {
+		t.Fatalf("unexpected extension %q", c.Extension())
+	}
+	payload := bytes.Repeat([]byte(`{"role":"user","content":"hello"}`), 64)
+	packed, err := c.Compress(payload)
+	if err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	if len(packed) >= len(payload) {
+		t.Fatalf("expected compressed size < %d, got %d", len(payload), len(packed))
+	}
+	unpacked, err := c.Decompress(packed)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if !bytes.Equal(unpacked, payload) {
+		t.Fatal("round trip mismatch")
+	}
+	if _, err := c.Decompress([]byte("not gzip")); err == nil {
+		t.Fatal("expected error for invalid input")
+	}
+}
+
+func TestZstdCompressorRoundTrip(t *testing.T) {
+	c := NewZstdCompressor(99) // invalid level falls back to default
+	if c.Extension() != ".zst" {
+		t.Fatalf("unexpected extension %q", c.Extension())
+	}
+	payload := bytes.Repeat([]byte(`{"role":"user","content":"hello"}`), 64)
+	packed, err := c.Compress(payload)
+	if err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	if len(packed) >= len(payload) || !bytes.HasPrefix(packed, []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+		t.Fatalf("expected a smaller zstd frame, got %d bytes", len(packed))
+	}
+	unpacked, err := c.Decompress(packed)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if !bytes.Equal(unpacked, payload) {
+		t.Fatal("round trip mismatch")
+	}
+	if _, err := c.Decompress([]byte("not zstd")); err == nil {
+		t.Fatal("expected error for invalid input")
+	}
+}
+
+func TestDiskHistoryPersisterReadsAcrossCodecs(t *testing.T) {
+	p := newDiskHistoryPersister(t.TempDir())
+	p.codec = NewGzipCompressor(gzip.BestSpeed)
+	if err := p.Save("sess", []message.Message{{Role: "user", Content: "gzip"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// Switching codecs keeps the gzip snapshot readable.
+	p.codec = NewZstdCompressor(0)
+	loaded, err := p.Load("sess")
+	if err != nil || len(loaded) != 1 || loaded[0].Content != "gzip" {
+		t.Fatalf("gzip snapshot not readable with zstd configured: %v err=%v", loaded, err)
+	}
+	if ids := p.SessionIDs(); len(ids) != 1 || ids[0] != "sess" {
+		t.Fatalf("unexpected session ids %v", ids)
+	}
+
+	if err := p.Save("sess", []message.Message{{Role: "user", Content: "zstd"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := os.Stat(p.filePath("sess") + ".gz"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected gzip snapshot removed, got %v", err)
+	}
+	p.codec = nil
+	loaded, err = p.Load("sess")
+	if err != nil || len(loaded) != 1 || loaded[0].Content != "zstd" {
+		t.Fatalf("zstd snapshot not readable without compression: %v err=%v", loaded, err)
+	}
+	if n, err := p.Delete("sess"); err != nil || n != 1 {
+		t.Fatalf("delete removed %d files, err=%v", n, err)
+	}
+}
+
+func TestDiskHistoryPersisterCompressedSaveLoad(t *testing.T) {
+	root := t.TempDir()
+	p := newDiskHistoryPersister(root)
+	p.codec = NewGzipCompressor(gzip.BestSpeed)
+
+	// An uncompressed snapshot from before compression was enabled.
+	legacy, err := json.Marshal(SessionTranscript{Version: 1, Messages: []message.Message{{Role: "user", Content: "old"}}})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := os.MkdirAll(p.dir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(p.filePath("sess"), legacy, 0o600); err != nil {
+		t.Fatalf("write legacy: %v", err)
+	}
+	loaded, err := p.Load("sess")
+	if err != nil || len(loaded) != 1 || loaded[0].Content != "old" {
+		t.Fatalf("legacy load mismatch %v err=%v", loaded, err)
+	}
+
+	if err := p.Save("sess", []message.Message{{Role: "user", Content: "new"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := os.Stat(p.filePath("sess")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected legacy snapshot removed, got %v", err)
+	}
+	raw, err := os.ReadFile(p.filePath("sess") + ".gz")
+	if err != nil {
+		t.Fatalf("read compressed: %v", err)
+	}
+	if !bytes.HasPrefix(raw, []byte{0x1f, 0x8b}) {
+		t.Fatal("expected gzip header")
+	}
+	loaded, err = p.Load("sess")
+	if err != nil || len(loaded) != 1 || loaded[0].Content != "new" {
+		t.Fatalf("compressed load mismatch %v err=%v", loaded, err)
+	}
+}
+
+func TestRolloutWriterCompressesEvents(t *testing.T) {
+	root := t.TempDir()
+	writer := newRolloutWriter(root, "rollouts")
+	writer.codec = NewGzipCompressor(gzip.DefaultCompression)
+
+	if err := writer.WriteCompactEvent("sess", compactResult{summary: "summary"}); err != nil {
+		t.Fatalf("WriteCompactEvent: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "rollouts"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one rollout file, got %v err=%v", entries, err)
+	}
+	name := entries[0].Name()
+	if !strings.HasSuffix(name, "_compact.json.gz") {
+		t.Fatalf("unexpected file name %q", name)
+	}
+	raw, err := os.ReadFile(filepath.Join(root, "rollouts", name))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	data, err := writer.codec.Decompress(raw)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if !strings.Contains(string(data), `"summary": "summary"`) {
+		t.Fatalf("unexpected payload %s", data)
+	}
+}
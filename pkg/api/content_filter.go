@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+// ContentFilterPolicy decides how a run reacts when the provider stops a
+// response with a safety or content-filter reason.
+type ContentFilterPolicy string
+
+const (
+	// ContentFilterReport ends the run normally with Result.StopReason set
+	// to model.StopReasonContentFilter and Result.ContentFilter to the
+	// provider's category. It is the default.
+	ContentFilterReport ContentFilterPolicy = ""
+	// ContentFilterAbort fails the run with a *model.ContentFilterError.
+	ContentFilterAbort ContentFilterPolicy = "abort"
+	// ContentFilterRetry asks the model once per run to rephrase; a second
+	// filtered response aborts.
+	ContentFilterRetry ContentFilterPolicy = "retry"
+)
+
+// contentFilterRetryPrompt is the user turn added before a retry.
+const contentFilterRetryPrompt = "Your previous response was stopped by the provider's content filter. " +
+	"Answer again, rephrased so it complies with the content policy, " +
+	"or briefly explain what you cannot help with."
+
+// handleContentFilter applies the run's policy to a filtered response and
+// returns the response to continue with.
+func (m *conversationModel) handleContentFilter(ctx context.Context, resp *model.Response, category string) (*model.Response, error) {
+	m.contentFiltered = category
+	policy := m.contentFilter
+	if policy == ContentFilterRetry && !m.contentFilterRetried {
+		m.contentFilterRetried = true
+		m.auditContentFilter(ctx, "retry", category)
+		m.history.Append(message.Message{Role: "user", Content: contentFilterRetryPrompt})
+		retry, err := m.complete(ctx)
+		if err != nil {
+			return nil, err
+		}
+		addUsage(&retry.Usage, resp.Usage)
+		next, filtered := model.ContentFilterCategory(retry.StopReason)
+		if !filtered {
+			return retry, nil
+		}
+		resp, category = retry, next
+		m.contentFiltered = category
+	}
+	if policy == ContentFilterReport {
+		m.auditContentFilter(ctx, "report", category)
+		resp.StopReason = model.StopReasonContentFilter
+		return resp, nil
+	}
+	m.auditContentFilter(ctx, "abort", category)
+	return nil, &model.ContentFilterError{Provider: resp.Usage.Provider, Category: category}
+}
+
+func (m *conversationModel) auditContentFilter(ctx context.Context, action, category string) {
+	if m.hooks == nil {
+		return
+	}
+	m.hooks.audit.emit(ctx, AuditRecord{Kind: AuditContentFilter, Decision: action, Reason: category})
+}
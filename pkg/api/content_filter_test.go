@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func refusal(text string) *model.Response {
+	return &model.Response{
+		Message:    model.Message{Role: "assistant", Content: text},
+		Usage:      model.Usage{InputTokens: 10, OutputTokens: 2},
+		StopReason: "refusal",
+	}
+}
+
+func newContentFilterRuntime(t *testing.T, mdl model.Model, policy ContentFilterPolicy, logger AuditLogger) *Runtime {
+	t.Helper()
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:   newClaudeProject(t),
+		Model:         mdl,
+		ContentFilter: policy,
+		AuditLogger:   logger,
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	return rt
+}
+
+func TestContentFilterReportedByDefault(t *testing.T) {
+	logger := &recordingAuditLogger{}
+	rt := newContentFilterRuntime(t, &stubModel{responses: []*model.Response{refusal("")}}, ContentFilterReport, logger)
+
+	resp, err := rt.Run(context.Background(), Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if resp.Result.StopReason != model.StopReasonContentFilter || resp.Result.ContentFilter != "refusal" {
+		t.Fatalf("unexpected result %+v", resp.Result)
+	}
+	recs := logger.byKind(AuditContentFilter)
+	if len(recs) != 1 || recs[0].Decision != "report" || recs[0].Reason != "refusal" {
+		t.Fatalf("unexpected audit records %+v", logger.records)
+	}
+}
+
+func TestContentFilterAbort(t *testing.T) {
+	rt := newContentFilterRuntime(t, &stubModel{responses: []*model.Response{refusal("partial")}}, ContentFilterAbort, nil)
+
+	_, err := rt.Run(context.Background(), Request{Prompt: "hi"})
+	var cf *model.ContentFilterError
+	if !errors.Is(err, model.ErrContentFiltered) || !errors.As(err, &cf) || cf.Category != "refusal" {
+		t.Fatalf("expected content filter error, got %v", err)
+	}
+}
+
+func TestContentFilterRetryRephrasesOnce(t *testing.T) {
+	mdl := &stubModel{responses: []*model.Response{
+		refusal(""),
+		{Message: model.Message{Role: "assistant", Content: "rephrased"}, Usage: model.Usage{InputTokens: 12, OutputTokens: 3}, StopReason: "end_turn"},
+	}}
+	logger := &recordingAuditLogger{}
+	rt := newContentFilterRuntime(t, mdl, ContentFilterRetry, logger)
+
+	resp, err := rt.Run(context.Background(), Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if resp.Result.Output != "rephrased" || resp.Result.StopReason != "end_turn" || resp.Result.ContentFilter != "" {
+		t.Fatalf("unexpected result %+v", resp.Result)
+	}
+	if resp.Result.Usage.InputTokens != 22 || resp.Result.Usage.OutputTokens != 5 {
+		t.Fatalf("usage of the filtered call should be kept, got %+v", resp.Result.Usage)
+	}
+	if len(mdl.requests) != 2 {
+		t.Fatalf("expected one retry, got %d requests", len(mdl.requests))
+	}
+	msgs := mdl.requests[1].Messages
+	if last := msgs[len(msgs)-1]; last.Role != "user" || last.Content != contentFilterRetryPrompt {
+		t.Fatalf("expected rephrase prompt, got %+v", last)
+	}
+	if recs := logger.byKind(AuditContentFilter); len(recs) != 1 || recs[0].Decision != "retry" {
+		t.Fatalf("unexpected audit records %+v", logger.records)
+	}
+
+	// A second filtered response aborts.
+	rt = newContentFilterRuntime(t, &stubModel{responses: []*model.Response{refusal("")}}, ContentFilterRetry, nil)
+	if _, err := rt.Run(context.Background(), Request{Prompt: "hi"}); !errors.Is(err, model.ErrContentFiltered) {
+		t.Fatalf("expected abort after retry, got %v", err)
+	}
+}
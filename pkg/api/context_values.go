@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/cexll/agentsdk-go/pkg/agent"
+	"github.com/cexll/agentsdk-go/pkg/runtime/skills"
+)
+
+// skillsRegistryKey exposes the runtime's skill registry to middleware.
+var skillsRegistryKey = agent.NewKey[*skills.Registry]("skills.registry", agent.Transient())
+
+// sessionValueStore keeps each session's persistent agent.Context values
+// between runs, encoded by agent.Context.MarshalValues.
+type sessionValueStore struct {
+	mu   sync.Mutex
+	data map[string]json.RawMessage
+}
+
+func (s *sessionValueStore) get(sessionID string) (json.RawMessage, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.data[sessionID]
+	return data, ok
+}
+
+func (s *sessionValueStore) put(sessionID string, data json.RawMessage) {
+	if s == nil || sessionID == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		s.data = map[string]json.RawMessage{}
+	}
+	s.data[sessionID] = data
+}
+
+func (s *sessionValueStore) forget(sessionID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, sessionID)
+}
+
+// sessionValuesFor returns the stored values of a session, loading them
+// from its transcript on first use.
+func (rt *Runtime) sessionValuesFor(sessionID string) json.RawMessage {
+	if data, ok := rt.sessionValues.get(sessionID); ok {
+		return data
+	}
+	data, err := rt.historyPersister.LoadValues(sessionID)
+	if err != nil {
+		log.Printf("api: load context values %q: %v", sessionID, err)
+	}
+	rt.sessionValues.put(sessionID, data)
+	return data
+}
+
+// restoreSessionValues seeds c with the values earlier runs of the session
+// left behind.
+func (rt *Runtime) restoreSessionValues(sessionID string, c *agent.Context) {
+	sessionID = strings.TrimSpace(sessionID)
+	if rt == nil || sessionID == "" {
+		return
+	}
+	if err := c.UnmarshalValues(rt.sessionValuesFor(sessionID)); err != nil {
+		log.Printf("api: restore context values %q: %v", sessionID, err)
+	}
+}
+
+// saveSessionValues records the persistent values of c for the session's
+// next run and its transcript.
+func (rt *Runtime) saveSessionValues(sessionID string, c *agent.Context) {
+	sessionID = strings.TrimSpace(sessionID)
+	if rt == nil || sessionID == "" {
+		return
+	}
+	data, err := c.MarshalValues()
+	if err != nil {
+		log.Printf("api: save context values %q: %v", sessionID, err)
+		return
+	}
+	if bytes.Equal(data, []byte("{}")) {
+		data = nil
+	}
+	rt.sessionValues.put(sessionID, data)
+}
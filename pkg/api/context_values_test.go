@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/agent"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+var runCountKey = agent.NewKey[int]("apitest.run_count")
+
+func newCountingRuntime(t *testing.T, root string, seen *[]int) *Runtime {
+	t.Helper()
+	counter := middleware.Funcs{Identifier: "counter", OnBeforeAgent: func(_ context.Context, st *middleware.State) error {
+		n, _ := runCountKey.Lookup(st.Values)
+		runCountKey.Store(st.Values, n+1)
+		*seen = append(*seen, n+1)
+		if id, ok := agent.SessionIDKey.Lookup(st.Values); !ok || id != "s" {
+			t.Errorf("session id %q %v", id, ok)
+		}
+		return nil
+	}}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot: root,
+		Model:       &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "ok"}}}},
+		Middleware:  []middleware.Middleware{counter},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	return rt
+}
+
+func TestContextValuesPersistAcrossRunsAndRestarts(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{"cleanupPeriodDays": 7}`)
+	var seen []int
+	rt := newCountingRuntime(t, root, &seen)
+	for i := 0; i < 2; i++ {
+		if _, err := rt.Run(context.Background(), Request{Prompt: "hi", SessionID: "s"}); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	}
+	_ = rt.Close()
+
+	data, err := os.ReadFile(filepath.Join(root, ".claude", "history", "s.json"))
+	if err != nil {
+		t.Fatalf("read transcript: %v", err)
+	}
+	var transcript SessionTranscript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		t.Fatalf("decode transcript: %v", err)
+	}
+	if string(transcript.Values) != `{"apitest.run_count":2}` {
+		t.Fatalf("transcript values %s", transcript.Values)
+	}
+
+	restarted := newCountingRuntime(t, root, &seen)
+	if _, err := restarted.Run(context.Background(), Request{Prompt: "hi", SessionID: "s"}); err != nil {
+		t.Fatalf("run after restart: %v", err)
+	}
+	if len(seen) != 3 || seen[2] != 3 {
+		t.Fatalf("counter did not survive restart: %v", seen)
+	}
+	if _, err := restarted.PurgeData(context.Background(), PurgeSelector{SessionIDs: []string{"s"}}); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if _, ok := restarted.sessionValues.get("s"); ok {
+		t.Fatal("purge should drop cached values")
+	}
+}
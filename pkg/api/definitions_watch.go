@@ -0,0 +1,192 @@
+package api
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Definition kinds reported in DefinitionsReloadedPayload.Kind.
+const (
+	DefinitionsSkills = "skills"
+	DefinitionsAgents = "agents"
+)
+
+// definitionsReloadDelay coalesces the burst of events an editor or a
+// checkout produces into one reload.
+const definitionsReloadDelay = 100 * time.Millisecond
+
+// definitionsWatcher reloads skills and subagents when files under
+// .claude/skills or .claude/agents change.
+type definitionsWatcher struct {
+	watcher *fsnotify.Watcher
+	claude  string
+	once    sync.Once
+	done    chan struct{}
+}
+
+// watchDefinitions starts the WatchDefinitions watcher. Without a .claude
+// directory it is a no-op, like the rules watcher.
+func (rt *Runtime) watchDefinitions() error {
+	claude := filepath.Join(rt.opts.ProjectRoot, ".claude")
+	if info, err := os.Stat(claude); err != nil || !info.IsDir() {
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	w := &definitionsWatcher{watcher: watcher, claude: claude, done: make(chan struct{})}
+	// .claude itself is watched so skills/ and agents/ created later are
+	// picked up.
+	if err := watcher.Add(claude); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+	for _, kind := range []string{DefinitionsSkills, DefinitionsAgents} {
+		w.addTree(filepath.Join(claude, kind))
+	}
+	rt.defWatch = w
+	go w.loop(rt)
+	return nil
+}
+
+// addTree watches dir and its subdirectories; skills live one level down.
+func (w *definitionsWatcher) addTree(dir string) {
+	//nolint:errcheck // a missing tree is watched once it is created
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if err := w.watcher.Add(path); err != nil {
+			log.Printf("definitions watcher: watch %s: %v", path, err)
+		}
+		return nil
+	})
+}
+
+// kindOf returns the definition kind a changed path belongs to, or "".
+func (w *definitionsWatcher) kindOf(path string) string {
+	rel, err := filepath.Rel(w.claude, path)
+	if err != nil {
+		return ""
+	}
+	top, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	switch top {
+	case DefinitionsSkills, DefinitionsAgents:
+		return top
+	}
+	return ""
+}
+
+func (w *definitionsWatcher) loop(rt *Runtime) {
+	pending := map[string]struct{}{}
+	timer := time.NewTimer(definitionsReloadDelay)
+	timer.Stop()
+	for {
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			kind := w.kindOf(event.Name)
+			if kind == "" || event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					w.addTree(event.Name)
+				}
+			}
+			pending[kind] = struct{}{}
+			timer.Reset(definitionsReloadDelay)
+		case <-timer.C:
+			for kind := range pending {
+				rt.reloadDefinitions(kind)
+				delete(pending, kind)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("definitions watcher: %v", err)
+		}
+	}
+}
+
+// Close stops the watcher.
+func (w *definitionsWatcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.watcher.Close()
+	})
+	return err
+}
+
+// ReloadDefinitions reloads skills and subagents from .claude and from
+// Options.Skills and Options.Subagents, replacing the registered sets in
+// place, and publishes a DefinitionsReloaded event per kind. WatchDefinitions
+// calls it on file changes; call it directly to reload on demand.
+func (rt *Runtime) ReloadDefinitions() {
+	if rt == nil {
+		return
+	}
+	rt.reloadDefinitions(DefinitionsSkills)
+	rt.reloadDefinitions(DefinitionsAgents)
+}
+
+func (rt *Runtime) reloadDefinitions(kind string) {
+	payload := coreevents.DefinitionsReloadedPayload{Kind: kind}
+	var errs []error
+	switch kind {
+	case DefinitionsSkills:
+		if rt.skReg == nil {
+			return
+		}
+		next, loadErrs := buildSkillsRegistry(rt.opts)
+		rt.skReg.Replace(next)
+		errs = loadErrs
+		for _, def := range rt.skReg.List() {
+			payload.Names = append(payload.Names, def.Name)
+		}
+	case DefinitionsAgents:
+		if rt.subMgr == nil {
+			return
+		}
+		next, loadErrs := buildSubagentsManager(rt.opts)
+		rt.subMgr.Replace(next)
+		errs = loadErrs
+		defs := rt.subMgr.List()
+		for _, def := range defs {
+			payload.Names = append(payload.Names, def.Name)
+		}
+		if rt.taskTool != nil {
+			rt.taskTool.SetSubagents(defs)
+		}
+	default:
+		return
+	}
+	for _, err := range errs {
+		log.Printf("%s reload warning: %v", kind, err)
+		payload.Errors = append(payload.Errors, err.Error())
+	}
+	if rt.hooks != nil {
+		//nolint:errcheck // reload events are non-critical notifications
+		rt.hooks.Publish(coreevents.Event{Type: coreevents.DefinitionsReloaded, Payload: payload})
+	}
+}
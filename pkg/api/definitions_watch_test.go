@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+	corehooks "github.com/cexll/agentsdk-go/pkg/core/hooks"
+)
+
+func TestWatchDefinitionsReloadsSkillsAndAgents(t *testing.T) {
+	root := newClaudeProject(t)
+	reloaded := make(chan coreevents.DefinitionsReloadedPayload, 8)
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:      root,
+		Model:            &stubModel{},
+		WatchDefinitions: true,
+		Hooks: Hooks{DefinitionsReloaded: []DefinitionsReloadedFunc{
+			func(_ context.Context, p coreevents.DefinitionsReloadedPayload) (*corehooks.HookOutput, error) {
+				reloaded <- p
+				return nil, nil
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	wait := func(kind, name string) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for {
+			select {
+			case p := <-reloaded:
+				if p.Kind == kind && slices.Contains(p.Names, name) {
+					return
+				}
+			case <-deadline:
+				t.Fatalf("no %s reload listing %q", kind, name)
+			}
+		}
+	}
+
+	agents := filepath.Join(root, ".claude", "agents")
+	if err := os.MkdirAll(agents, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	agent := "---\nname: reviewer\ndescription: Reviews diffs.\n---\nYou review diffs.\n"
+	if err := os.WriteFile(filepath.Join(agents, "reviewer.md"), []byte(agent), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wait(DefinitionsAgents, "reviewer")
+	if def, ok := rt.subMgr.Lookup("reviewer"); !ok || def.SystemPrompt != "You review diffs." {
+		t.Fatalf("reviewer not reloaded: %+v", def)
+	}
+	task, err := rt.registry.Get("Task")
+	if err != nil {
+		t.Fatalf("task tool: %v", err)
+	}
+	if !strings.Contains(task.Description(), "- reviewer: Reviews diffs.") {
+		t.Fatalf("task description not refreshed:\n%s", task.Description())
+	}
+
+	skill := filepath.Join(root, ".claude", "skills", "lint")
+	if err := os.MkdirAll(skill, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skill, "SKILL.md"), []byte("---\nname: lint\ndescription: Run linters\n---\nRun golangci-lint.\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wait(DefinitionsSkills, "lint")
+	if _, ok := rt.skReg.Get("lint"); !ok {
+		t.Fatal("lint skill not registered after reload")
+	}
+
+	if err := os.Remove(filepath.Join(agents, "reviewer.md")); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(5 * time.Second)
+	for {
+		if _, ok := rt.subMgr.Lookup("reviewer"); !ok {
+			break
+		}
+		select {
+		case <-reloaded:
+		case <-deadline:
+			t.Fatal("removed agent still registered")
+		}
+	}
+}
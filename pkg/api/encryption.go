@@ -0,0 +1,236 @@
+package api
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var (
+	// ErrEncryptedData is returned when encrypted data is read without an Encryptor.
+	ErrEncryptedData = errors.New("api: data is encrypted but no encryptor is configured")
+	// ErrUnknownKey is returned when no key is available for the stored key ID.
+	ErrUnknownKey = errors.New("api: unknown encryption key")
+)
+
+// encryptedMagic prefixes every payload written by the AES-GCM encryptor so
+// plaintext files persisted earlier remain readable.
+var encryptedMagic = []byte("AGSDKENC1")
+
+// Encryptor protects data the runtime persists to disk (session history and
+// compaction rollouts). Encryption is applied after compression.
+type Encryptor interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// KeyProvider supplies AES keys, typically backed by a KMS or secret store.
+// Keys must be 16, 24 or 32 bytes long.
+type KeyProvider interface {
+	// CurrentKey returns the key used for new writes.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key identified by id so data written before a rotation
+	// can still be decrypted.
+	Key(id string) ([]byte, error)
+}
+
+// KeyRing is an in-memory KeyProvider. Rotate installs a new current key
+// while keeping previous keys available for decryption.
+type KeyRing struct {
+	mu      sync.RWMutex
+	keys    map[string][]byte
+	current string
+}
+
+// NewKeyRing creates a KeyRing whose current key is key.
+func NewKeyRing(id string, key []byte) (*KeyRing, error) {
+	r := &KeyRing{keys: map[string][]byte{}}
+	if err := r.Rotate(id, key); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Rotate adds key under id and makes it the current key.
+func (r *KeyRing) Rotate(id string, key []byte) error {
+	if id == "" || len(id) > 255 {
+		return fmt.Errorf("api: key id must be 1-255 bytes")
+	}
+	if _, err := aes.NewCipher(key); err != nil {
+		return fmt.Errorf("api: invalid key %q: %w", id, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[id] = append([]byte(nil), key...)
+	r.current = id
+	return nil
+}
+
+func (r *KeyRing) CurrentKey() (string, []byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[r.current]
+	if !ok {
+		return "", nil, ErrUnknownKey
+	}
+	return r.current, key, nil
+}
+
+func (r *KeyRing) Key(id string) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+type aesGCMEncryptor struct {
+	keys KeyProvider
+}
+
+// NewAESGCMEncryptor returns an Encryptor using AES-GCM with keys from
+// provider. Each payload records its key ID, so rotating the provider's
+// current key never breaks reads of older data.
+func NewAESGCMEncryptor(provider KeyProvider) Encryptor {
+	return &aesGCMEncryptor{keys: provider}
+}
+
+// Layout: magic | keyID length (1 byte) | keyID | nonce | sealed data.
+func (e *aesGCMEncryptor) Encrypt(plaintext []byte) ([]byte, error) {
+	id, key, err := e.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if id == "" || len(id) > 255 {
+		return nil, fmt.Errorf("api: key id must be 1-255 bytes")
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, len(encryptedMagic)+1+len(id)+aead.NonceSize())
+	header = append(header, encryptedMagic...)
+	header = append(header, byte(len(id)))
+	header = append(header, id...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("api: read nonce: %w", err)
+	}
+	header = append(header, nonce...)
+	// The header is authenticated so the key ID cannot be swapped.
+	aad := append([]byte(nil), header...)
+	return aead.Seal(header, nonce, plaintext, aad), nil
+}
+
+func (e *aesGCMEncryptor) Decrypt(data []byte) ([]byte, error) {
+	if !isEncrypted(data) {
+		return nil, errors.New("api: missing encryption header")
+	}
+	rest := data[len(encryptedMagic):]
+	if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+		return nil, errors.New("api: truncated encryption header")
+	}
+	idLen := int(rest[0])
+	id := string(rest[1 : 1+idLen])
+	key, err := e.keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	headerLen := len(encryptedMagic) + 1 + idLen + aead.NonceSize()
+	if len(data) < headerLen {
+		return nil, errors.New("api: truncated encryption header")
+	}
+	header := data[:headerLen]
+	nonce := header[headerLen-aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, data[headerLen:], header)
+	if err != nil {
+		return nil, fmt.Errorf("api: decrypt: %w", err)
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("api: cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+// sealAtRest applies compression then encryption to data bound for disk.
+func sealAtRest(data []byte, codec Compressor, enc Encryptor) ([]byte, error) {
+	var err error
+	if codec != nil {
+		if data, err = codec.Compress(data); err != nil {
+			return nil, fmt.Errorf("compress: %w", err)
+		}
+	}
+	if enc != nil {
+		if data, err = enc.Encrypt(data); err != nil {
+			return nil, fmt.Errorf("encrypt: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// atRestSealer applies Options.Compression and Options.Encryption to
+// SessionStore payloads.
+type atRestSealer struct {
+	codec Compressor
+	enc   Encryptor
+}
+
+func (s atRestSealer) Seal(data []byte) ([]byte, error) {
+	return sealAtRest(data, s.codec, s.enc)
+}
+
+// Open decrypts and decompresses data. Plain JSON, and payloads compressed
+// with another built-in codec, remain readable.
+func (s atRestSealer) Open(data []byte) ([]byte, error) {
+	data, err := openEncrypted(data, s.enc)
+	if err != nil || json.Valid(data) {
+		return data, err
+	}
+	for _, codec := range storedCodecs(s.codec) {
+		if codec == nil {
+			continue
+		}
+		if out, decErr := codec.Decompress(data); decErr == nil {
+			return out, nil
+		} else if err == nil {
+			err = decErr
+		}
+	}
+	if err == nil {
+		err = errors.New("payload is neither JSON nor compressed")
+	}
+	return nil, fmt.Errorf("decompress: %w", err)
+}
+
+// openEncrypted decrypts data when it carries the encryption header and
+// passes plaintext through untouched for backward compatibility.
+func openEncrypted(data []byte, enc Encryptor) ([]byte, error) {
+	if !isEncrypted(data) {
+		return data, nil
+	}
+	if enc == nil {
+		return nil, ErrEncryptedData
+	}
+	return enc.Decrypt(data)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+func testKey(b byte) []byte { return bytes.Repeat([]byte{b}, 32) }
+
+func TestAESGCMEncryptorRoundTripAndRotation(t *testing.T) {
+	ring, err := NewKeyRing("k1", testKey(1))
+	if err != nil {
+		t.Fatalf("key ring: %v", err)
+	}
+	enc := NewAESGCMEncryptor(ring)
+
+	old, err := enc.Encrypt([]byte("secret v1"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if bytes.Contains(old, []byte("secret")) {
+		t.Fatal("ciphertext leaks plaintext")
+	}
+
+	if err := ring.Rotate("k2", testKey(2)); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	fresh, err := enc.Encrypt([]byte("secret v2"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	for want, data := range map[string][]byte{"secret v1": old, "secret v2": fresh} {
+		got, err := enc.Decrypt(data)
+		if err != nil || string(got) != want {
+			t.Fatalf("decrypt mismatch %q err=%v", got, err)
+		}
+	}
+
+	tampered := append([]byte(nil), fresh...)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := enc.Decrypt(tampered); err == nil {
+		t.Fatal("expected tamper detection")
+	}
+
+	other := NewAESGCMEncryptor(mustKeyRing(t, "k3", testKey(3)))
+	if _, err := other.Decrypt(old); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+	if _, err := enc.Decrypt([]byte("plain")); err == nil {
+		t.Fatal("expected header error")
+	}
+}
+
+func TestKeyRingRejectsInvalidKeys(t *testing.T) {
+	if _, err := NewKeyRing("k", []byte("short")); err == nil {
+		t.Fatal("expected invalid key length error")
+	}
+	if _, err := NewKeyRing("", testKey(1)); err == nil {
+		t.Fatal("expected empty id error")
+	}
+}
+
+func TestOpenEncryptedPassesPlaintextThrough(t *testing.T) {
+	data, err := openEncrypted([]byte(`{"version":1}`), nil)
+	if err != nil || string(data) != `{"version":1}` {
+		t.Fatalf("unexpected %q err=%v", data, err)
+	}
+	sealed, err := sealAtRest([]byte("x"), nil, NewAESGCMEncryptor(mustKeyRing(t, "k", testKey(1))))
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if _, err := openEncrypted(sealed, nil); !errors.Is(err, ErrEncryptedData) {
+		t.Fatalf("expected ErrEncryptedData, got %v", err)
+	}
+}
+
+func TestDiskHistoryPersisterEncryptedAtRest(t *testing.T) {
+	root := t.TempDir()
+	p := newDiskHistoryPersister(root)
+	p.codec = NewGzipCompressor(gzip.BestSpeed)
+	p.enc = NewAESGCMEncryptor(mustKeyRing(t, "k1", testKey(1)))
+
+	// Plaintext snapshot written before encryption was enabled.
+	legacy, err := json.Marshal(SessionTranscript{Version: 1, Messages: []message.Message{{Role: "user", Content: "legacy"}}})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := os.MkdirAll(p.dir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(p.filePath("old"), legacy, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if msgs, err := p.Load("old"); err != nil || len(msgs) != 1 || msgs[0].Content != "legacy" {
+		t.Fatalf("legacy load mismatch %v err=%v", msgs, err)
+	}
+
+	if err := p.Save("sess", []message.Message{{Role: "user", Content: "proprietary code"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	raw, err := os.ReadFile(p.filePath("sess") + ".gz")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !isEncrypted(raw) {
+		t.Fatal("expected encrypted snapshot")
+	}
+	msgs, err := p.Load("sess")
+	if err != nil || len(msgs) != 1 || msgs[0].Content != "proprietary code" {
+		t.Fatalf("load mismatch %v err=%v", msgs, err)
+	}
+
+	p.enc = nil
+	if _, err := p.Load("sess"); !errors.Is(err, ErrEncryptedData) {
+		t.Fatalf("expected ErrEncryptedData without encryptor, got %v", err)
+	}
+}
+
+func mustKeyRing(t *testing.T, id string, key []byte) *KeyRing {
+	t.Helper()
+	ring, err := NewKeyRing(id, key)
+	if err != nil {
+		t.Fatalf("key ring: %v", err)
+	}
+	return ring
+}
@@ -0,0 +1,354 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventSchemaVersion is the version of the Envelope schema. It changes only
+// when a payload field is removed or changes meaning; new event types and
+// fields are added without a bump, so consumers should ignore unknown types.
+const EventSchemaVersion = 1
+
+// EnvelopeType identifies the payload of an Envelope.
+type EnvelopeType string
+
+const (
+	// EnvelopeModelDelta carries ModelDelta: text or tool input as the model
+	// generates it.
+	EnvelopeModelDelta EnvelopeType = "model_delta"
+	// EnvelopeToolStart carries ToolStart when a tool call begins.
+	EnvelopeToolStart EnvelopeType = "tool_start"
+	// EnvelopeToolOutput carries ToolOutput for streamed tool output.
+	EnvelopeToolOutput EnvelopeType = "tool_output"
+	// EnvelopeToolResult carries ToolResultPayload when a tool call ends.
+	EnvelopeToolResult EnvelopeType = "tool_result"
+	// EnvelopePermissionRequest carries PermissionRequestPayload when a tool
+	// call needs approval.
+	EnvelopePermissionRequest EnvelopeType = "permission_request"
+	// EnvelopeIterationEnd carries IterationEnd after each model call.
+	EnvelopeIterationEnd EnvelopeType = "iteration_end"
+	// EnvelopeDone carries Done; it is the last envelope of a successful run.
+	EnvelopeDone EnvelopeType = "done"
+	// EnvelopeError carries ErrorPayload; it is the last envelope of a failed
+	// run.
+	EnvelopeError EnvelopeType = "error"
+)
+
+// Envelope is one event of RunEvents. Seq starts at 1 and increases by one
+// per envelope, so consumers can detect gaps after reconnecting.
+type Envelope struct {
+	Version   int          `json:"version"`
+	Type      EnvelopeType `json:"type"`
+	Seq       uint64       `json:"seq"`
+	Timestamp time.Time    `json:"timestamp"`
+	SessionID string       `json:"session_id,omitempty"`
+	// RunID is the Request.RequestID of the run; pass it to ResumeStream.
+	RunID string `json:"run_id,omitempty"`
+	// Payload is one of ModelDelta, ToolStart, ToolOutput,
+	// ToolResultPayload, PermissionRequestPayload, IterationEnd, Done or
+	// ErrorPayload, matching Type. Unknown types decode to json.RawMessage.
+	Payload any `json:"payload"`
+}
+
+// ModelDelta is a fragment of model output.
+type ModelDelta struct {
+	// Iteration is the model call the fragment belongs to.
+	Iteration int `json:"iteration"`
+	// Index is the content block within the model response.
+	Index int `json:"index"`
+	// Kind is "text" or "tool_input".
+	Kind string `json:"kind"`
+	Text string `json:"text,omitempty"`
+	// ToolUseID, Name and PartialJSON are set for tool input. The
+	// PartialJSON fragments of one ToolUseID concatenate to its arguments.
+	ToolUseID   string `json:"tool_use_id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+}
+
+// ToolStart reports a tool call about to run.
+type ToolStart struct {
+	Iteration int    `json:"iteration"`
+	ToolUseID string `json:"tool_use_id"`
+	Name      string `json:"name"`
+}
+
+// ToolOutput is a chunk of output a streaming tool emitted while running.
+type ToolOutput struct {
+	ToolUseID string `json:"tool_use_id"`
+	Name      string `json:"name"`
+	Chunk     string `json:"chunk"`
+	Stderr    bool   `json:"stderr,omitempty"`
+}
+
+// ToolResultPayload reports a finished tool call.
+type ToolResultPayload struct {
+	ToolUseID string         `json:"tool_use_id"`
+	Name      string         `json:"name"`
+	Output    string         `json:"output,omitempty"`
+	IsError   bool           `json:"is_error,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+// PermissionRequestPayload reports a tool call waiting for approval.
+type PermissionRequestPayload struct {
+	ToolUseID string         `json:"tool_use_id,omitempty"`
+	ToolName  string         `json:"tool_name"`
+	Input     map[string]any `json:"input,omitempty"`
+	Rule      string         `json:"rule,omitempty"`
+	Target    string         `json:"target,omitempty"`
+	Reason    string         `json:"reason,omitempty"`
+	// SuggestedRule is a permission rule matching the call.
+	SuggestedRule string `json:"suggested_rule,omitempty"`
+	// Protected is the protected-path pattern the call edits; Owners are
+	// the CODEOWNERS owners of Target.
+	Protected string   `json:"protected,omitempty"`
+	Owners    []string `json:"owners,omitempty"`
+}
+
+// IterationEnd closes one model call.
+type IterationEnd struct {
+	Iteration  int    `json:"iteration"`
+	StopReason string `json:"stop_reason,omitempty"`
+	Usage      Usage  `json:"usage"`
+}
+
+// Done ends a successful run.
+type Done struct {
+	StopReason string `json:"stop_reason,omitempty"`
+	Iterations int    `json:"iterations"`
+	// Usage sums the model calls of the run.
+	Usage Usage `json:"usage"`
+}
+
+// ErrorPayload ends a failed run.
+type ErrorPayload struct {
+	Message string `json:"message"`
+}
+
+// UnmarshalJSON decodes Payload into the Go type matching Type.
+func (e *Envelope) UnmarshalJSON(data []byte) error {
+	type plain Envelope
+	var raw struct {
+		plain
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*e = Envelope(raw.plain)
+	var payload any
+	switch e.Type {
+	case EnvelopeModelDelta:
+		payload = &ModelDelta{}
+	case EnvelopeToolStart:
+		payload = &ToolStart{}
+	case EnvelopeToolOutput:
+		payload = &ToolOutput{}
+	case EnvelopeToolResult:
+		payload = &ToolResultPayload{}
+	case EnvelopePermissionRequest:
+		payload = &PermissionRequestPayload{}
+	case EnvelopeIterationEnd:
+		payload = &IterationEnd{}
+	case EnvelopeDone:
+		payload = &Done{}
+	case EnvelopeError:
+		payload = &ErrorPayload{}
+	default:
+		e.Payload = raw.Payload
+		return nil
+	}
+	if len(raw.Payload) > 0 && string(raw.Payload) != "null" {
+		if err := json.Unmarshal(raw.Payload, payload); err != nil {
+			return fmt.Errorf("api: decode %s payload: %w", e.Type, err)
+		}
+	}
+	// Store values, not pointers, so decoded envelopes match encoded ones.
+	switch p := payload.(type) {
+	case *ModelDelta:
+		e.Payload = *p
+	case *ToolStart:
+		e.Payload = *p
+	case *ToolOutput:
+		e.Payload = *p
+	case *ToolResultPayload:
+		e.Payload = *p
+	case *PermissionRequestPayload:
+		e.Payload = *p
+	case *IterationEnd:
+		e.Payload = *p
+	case *Done:
+		e.Payload = *p
+	case *ErrorPayload:
+		e.Payload = *p
+	}
+	return nil
+}
+
+// EnvelopeEncoder converts a RunStream event sequence into envelopes. It
+// keeps the sequence number and per-run totals, so use one per run.
+type EnvelopeEncoder struct {
+	sessionID  string
+	runID      string
+	seq        uint64
+	iteration  int
+	iterations int
+	stopReason string
+	usage      Usage
+	failed     bool
+	now        func() time.Time
+}
+
+// NewEnvelopeEncoder starts a sequence for sessionID.
+func NewEnvelopeEncoder(sessionID string) *EnvelopeEncoder {
+	return &EnvelopeEncoder{sessionID: sessionID, now: time.Now}
+}
+
+// Encode converts one stream event. ok is false for events the envelope
+// schema does not carry (message framing, pings, channel and voice output).
+func (e *EnvelopeEncoder) Encode(evt StreamEvent) (Envelope, bool) {
+	switch evt.Type {
+	case EventIterationStart:
+		if evt.Iteration != nil {
+			e.iteration = *evt.Iteration
+		}
+		return Envelope{}, false
+	case EventContentBlockDelta:
+		if evt.Delta == nil {
+			return Envelope{}, false
+		}
+		delta := ModelDelta{Iteration: e.iteration, ToolUseID: evt.ToolUseID, Name: evt.Name}
+		if evt.Index != nil {
+			delta.Index = *evt.Index
+		}
+		switch evt.Delta.Type {
+		case "text_delta":
+			delta.Kind, delta.Text = "text", evt.Delta.Text
+		case "input_json_delta":
+			delta.Kind = "tool_input"
+			if err := json.Unmarshal(evt.Delta.PartialJSON, &delta.PartialJSON); err != nil {
+				delta.PartialJSON = string(evt.Delta.PartialJSON)
+			}
+		default:
+			return Envelope{}, false
+		}
+		return e.wrap(EnvelopeModelDelta, delta), true
+	case EventToolExecutionStart:
+		return e.wrap(EnvelopeToolStart, ToolStart{Iteration: e.iteration, ToolUseID: evt.ToolUseID, Name: evt.Name}), true
+	case EventToolExecutionOutput:
+		// Streaming tools set IsStderr; the runtime's own copy of the final
+		// output does not and is repeated in tool_result.
+		if evt.IsStderr == nil {
+			return Envelope{}, false
+		}
+		return e.wrap(EnvelopeToolOutput, ToolOutput{ToolUseID: evt.ToolUseID, Name: evt.Name, Chunk: fmt.Sprint(evt.Output), Stderr: *evt.IsStderr}), true
+	case EventToolExecutionResult:
+		res := ToolResultPayload{ToolUseID: evt.ToolUseID, Name: evt.Name}
+		if payload, ok := evt.Output.(map[string]any); ok {
+			res.Output, _ = payload["output"].(string)
+			res.Metadata, _ = payload["metadata"].(map[string]any)
+			res.IsError, _ = res.Metadata["is_error"].(bool)
+		}
+		return e.wrap(EnvelopeToolResult, res), true
+	case EventPermissionRequest:
+		req := PermissionRequestPayload{ToolUseID: evt.ToolUseID, ToolName: evt.Name}
+		if payload, ok := evt.Output.(map[string]any); ok {
+			req.Rule, _ = payload["rule"].(string)
+			req.Target, _ = payload["target"].(string)
+			req.Reason, _ = payload["reason"].(string)
+			req.Input, _ = payload["input"].(map[string]any)
+			req.SuggestedRule, _ = payload["suggested_rule"].(string)
+			req.Protected, _ = payload["protected"].(string)
+			req.Owners, _ = payload["owners"].([]string)
+		}
+		return e.wrap(EnvelopePermissionRequest, req), true
+	case EventMessageDelta:
+		end := IterationEnd{Iteration: e.iteration}
+		if evt.Delta != nil {
+			end.StopReason = evt.Delta.StopReason
+			e.stopReason = end.StopReason
+		}
+		if evt.Usage != nil {
+			end.Usage = *evt.Usage
+			e.usage.InputTokens += evt.Usage.InputTokens
+			e.usage.OutputTokens += evt.Usage.OutputTokens
+		}
+		e.iterations++
+		return e.wrap(EnvelopeIterationEnd, end), true
+	case EventError:
+		e.failed = true
+		return e.wrap(EnvelopeError, ErrorPayload{Message: fmt.Sprint(evt.Output)}), true
+	}
+	return Envelope{}, false
+}
+
+// Done returns the closing envelope once the stream has ended. ok is false
+// when the run failed; the error envelope was its last.
+func (e *EnvelopeEncoder) Done() (Envelope, bool) {
+	if e.failed {
+		return Envelope{}, false
+	}
+	return e.wrap(EnvelopeDone, Done{StopReason: e.stopReason, Iterations: e.iterations, Usage: e.usage}), true
+}
+
+func (e *EnvelopeEncoder) wrap(typ EnvelopeType, payload any) Envelope {
+	e.seq++
+	return Envelope{
+		Version:   EventSchemaVersion,
+		Type:      typ,
+		Seq:       e.seq,
+		Timestamp: e.now().UTC(),
+		SessionID: e.sessionID,
+		RunID:     e.runID,
+		Payload:   payload,
+	}
+}
+
+// RunEvents is RunStream with the versioned Envelope schema: every envelope
+// has a type, sequence number, timestamp and typed payload, and the channel
+// always ends with a done or error envelope.
+//
+// The envelopes are buffered under the run ID (Request.RequestID, generated
+// when empty) so a consumer that stops reading can catch up with
+// ResumeStream. The run itself is bound to ctx, not to the returned channel;
+// callers that want a run to outlive a disconnecting client should pass a
+// context that is not canceled with the client.
+func (rt *Runtime) RunEvents(ctx context.Context, req Request) (<-chan Envelope, error) {
+	if rt == nil || rt.replay == nil {
+		return nil, ErrRuntimeClosed
+	}
+	sessionID := strings.TrimSpace(req.SessionID)
+	if sessionID == "" {
+		sessionID = defaultSessionID(rt.mode.EntryPoint)
+		req.SessionID = sessionID
+	}
+	runID := strings.TrimSpace(req.RequestID)
+	if runID == "" {
+		runID = newID(rt.idGenerator(), IDRun)
+		req.RequestID = runID
+	}
+	events, err := rt.RunStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	buf := rt.replay.open(runID, sessionID)
+	go func() {
+		defer buf.finish()
+		enc := NewEnvelopeEncoder(sessionID)
+		enc.runID = runID
+		for evt := range events {
+			if env, ok := enc.Encode(evt); ok {
+				buf.append(env)
+			}
+		}
+		if env, ok := enc.Done(); ok {
+			buf.append(env)
+		}
+	}()
+	return rt.ResumeStream(ctx, runID, 0)
+}
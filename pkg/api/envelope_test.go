@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+func TestRunEventsEnvelopeSequence(t *testing.T) {
+	rt, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: &deltaStreamModel{}, Tools: []tool.Tool{&echoTool{}}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	events, err := rt.RunEvents(context.Background(), Request{Prompt: "go", SessionID: "s"})
+	if err != nil {
+		t.Fatalf("RunEvents: %v", err)
+	}
+	var envs []Envelope
+	for env := range events {
+		envs = append(envs, env)
+	}
+	if len(envs) == 0 {
+		t.Fatal("no envelopes")
+	}
+	var types []EnvelopeType
+	for i, env := range envs {
+		if env.Seq != uint64(i+1) || env.Version != EventSchemaVersion || env.SessionID != "s" || env.Timestamp.IsZero() {
+			t.Fatalf("bad envelope %d: %+v", i, env)
+		}
+		if len(types) == 0 || types[len(types)-1] != env.Type {
+			types = append(types, env.Type)
+		}
+	}
+	want := []EnvelopeType{EnvelopeModelDelta, EnvelopeIterationEnd, EnvelopeToolStart, EnvelopeToolResult, EnvelopeModelDelta, EnvelopeIterationEnd, EnvelopeDone}
+	if !reflect.DeepEqual(types, want) {
+		t.Fatalf("types = %v, want %v", types, want)
+	}
+	done, ok := envs[len(envs)-1].Payload.(Done)
+	if !ok || done.Iterations != 2 || done.StopReason != "end_turn" {
+		t.Fatalf("done = %+v", envs[len(envs)-1].Payload)
+	}
+	for _, env := range envs {
+		if res, ok := env.Payload.(ToolResultPayload); ok && (res.ToolUseID != "call_1" || res.Output != "hi") {
+			t.Fatalf("tool result = %+v", res)
+		}
+	}
+}
+
+func TestEnvelopeJSONRoundTrip(t *testing.T) {
+	enc := NewEnvelopeEncoder("s")
+	idx := 1
+	in, ok := enc.Encode(StreamEvent{Type: EventContentBlockDelta, Index: &idx, ToolUseID: "t1", Name: "bash", Delta: &Delta{Type: "input_json_delta", PartialJSON: json.RawMessage(`"{\"cmd\":"`)}})
+	if !ok {
+		t.Fatal("delta not encoded")
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var out Envelope
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	delta, ok := out.Payload.(ModelDelta)
+	if !ok || delta.Kind != "tool_input" || delta.PartialJSON != `{"cmd":` || delta.Index != 1 || out.Seq != 1 {
+		t.Fatalf("round trip = %+v", out)
+	}
+
+	var unknown Envelope
+	if err := json.Unmarshal([]byte(`{"version":1,"type":"future","seq":9,"payload":{"x":1}}`), &unknown); err != nil {
+		t.Fatalf("unknown type: %v", err)
+	}
+	if raw, ok := unknown.Payload.(json.RawMessage); !ok || string(raw) != `{"x":1}` {
+		t.Fatalf("unknown payload = %#v", unknown.Payload)
+	}
+}
+
+func TestEnvelopeErrorEndsStream(t *testing.T) {
+	enc := NewEnvelopeEncoder("s")
+	env, ok := enc.Encode(StreamEvent{Type: EventError, Output: "boom"})
+	if !ok || env.Payload != (ErrorPayload{Message: "boom"}) {
+		t.Fatalf("error envelope = %+v", env)
+	}
+	if _, ok := enc.Done(); ok {
+		t.Fatal("done after error")
+	}
+}
+
+func TestAnnouncePermissionRequests(t *testing.T) {
+	var got []StreamEvent
+	emit := func(_ context.Context, evt StreamEvent) { got = append(got, evt) }
+	resolver := announcePermissionRequests(emit, "t1", nil)
+	decision := security.PermissionDecision{Action: security.PermissionAsk, Tool: "bash", Rule: "Bash(rm:*)", Target: "rm -rf x"}
+	out, err := resolver(context.Background(), tool.Call{Name: "bash", SessionID: "s"}, decision)
+	if err != nil || out.Action != security.PermissionAsk {
+		t.Fatalf("resolver changed decision: %+v err=%v", out, err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("events = %+v", got)
+	}
+	env, ok := NewEnvelopeEncoder("s").Encode(got[0])
+	req, _ := env.Payload.(PermissionRequestPayload)
+	if !ok || req.ToolUseID != "t1" || req.ToolName != "bash" || req.Rule != "Bash(rm:*)" || req.Target != "rm -rf x" {
+		t.Fatalf("permission envelope = %+v", env)
+	}
+	if _, err := resolver(context.Background(), tool.Call{Name: "bash"}, security.PermissionDecision{Action: security.PermissionAllow}); err != nil || len(got) != 1 {
+		t.Fatalf("allow decisions should not be announced")
+	}
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/flags"
+)
+
+// ErrInvalidExperiment is returned by New when Options.Experiments is malformed.
+var ErrInvalidExperiment = errors.New("api: invalid experiment")
+
+// experimentTagPrefix prefixes the request tag recording a run's variant,
+// e.g. "experiment.prompt-v2" = "concise".
+const experimentTagPrefix = "experiment."
+
+// ExperimentTag is the Request/Response tag holding the variant assigned for
+// the named experiment. Setting it on a request pins that variant.
+func ExperimentTag(name string) string { return experimentTagPrefix + name }
+
+// Experiment splits runs between variants of system prompt, model tier and
+// tool set. Assignment is deterministic: the request's "tenant" tag, or the
+// session ID when absent, is hashed with the experiment name so the same
+// tenant or session always lands on the same variant.
+type Experiment struct {
+	Name     string
+	Variants []Variant
+}
+
+// Variant is one arm of an Experiment. Empty fields leave the request,
+// template or runtime defaults untouched; explicit request fields win over
+// Model and Tools.
+type Variant struct {
+	Name string
+	// Weight is the variant's relative share of traffic; zero counts as 1.
+	Weight int
+	// SystemPrompt replaces Options.SystemPrompt and template prompts.
+	SystemPrompt string
+	// Model is the tier used when the request sets none.
+	Model ModelTier
+	// Tools is the tool whitelist used when the request sets none.
+	Tools []string
+}
+
+func (v Variant) weight() int {
+	if v.Weight <= 0 {
+		return 1
+	}
+	return v.Weight
+}
+
+func validateExperiments(exps []Experiment) error {
+	seen := make(map[string]struct{}, len(exps))
+	for i, exp := range exps {
+		name := strings.TrimSpace(exp.Name)
+		if name == "" {
+			return fmt.Errorf("%w: experiment %d: name is required", ErrInvalidExperiment, i)
+		}
+		if _, dup := seen[name]; dup {
+			return fmt.Errorf("%w: duplicate experiment %q", ErrInvalidExperiment, name)
+		}
+		seen[name] = struct{}{}
+		if len(exp.Variants) == 0 {
+			return fmt.Errorf("%w: experiment %q has no variants", ErrInvalidExperiment, name)
+		}
+		variants := make(map[string]struct{}, len(exp.Variants))
+		for j, v := range exp.Variants {
+			vname := strings.TrimSpace(v.Name)
+			if vname == "" {
+				return fmt.Errorf("%w: experiment %q: variant %d: name is required", ErrInvalidExperiment, name, j)
+			}
+			if _, dup := variants[vname]; dup {
+				return fmt.Errorf("%w: experiment %q: duplicate variant %q", ErrInvalidExperiment, name, vname)
+			}
+			variants[vname] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// Assign returns the variant for key. A key always maps to the same variant
+// as long as the experiment's name and variants are unchanged.
+func (e Experiment) Assign(key string) Variant {
+	total := 0
+	for _, v := range e.Variants {
+		total += v.weight()
+	}
+	point := flags.Bucket(e.Name, key) / 100 * float64(total)
+	acc := 0
+	for _, v := range e.Variants {
+		acc += v.weight()
+		if point < float64(acc) {
+			return v
+		}
+	}
+	return e.Variants[len(e.Variants)-1]
+}
+
+func (e Experiment) variant(name string) (Variant, bool) {
+	for _, v := range e.Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Variant{}, false
+}
+
+// applyExperiments assigns a variant per experiment, records it in the
+// request tags and applies it on top of tpl. The returned template carries
+// the variant's system prompt.
+func (rt *Runtime) applyExperiments(req *Request, tpl *RequestTemplate) *RequestTemplate {
+	if len(rt.opts.Experiments) == 0 {
+		return tpl
+	}
+	key := req.Tags[tenantTag]
+	if key == "" {
+		key = req.SessionID
+	}
+	for _, exp := range rt.opts.Experiments {
+		v, ok := exp.variant(req.Tags[ExperimentTag(exp.Name)])
+		if !ok {
+			v = exp.Assign(key)
+		}
+		req.Tags[ExperimentTag(exp.Name)] = v.Name
+		if len(req.ToolWhitelist) == 0 && len(v.Tools) > 0 {
+			req.ToolWhitelist = cloneStrings(v.Tools)
+		}
+		if req.Model == "" {
+			req.Model = v.Model
+		}
+		if strings.TrimSpace(v.SystemPrompt) != "" {
+			next := RequestTemplate{}
+			if tpl != nil {
+				next = *tpl
+			}
+			next.SystemPrompt = v.SystemPrompt
+			tpl = &next
+		}
+	}
+	return tpl
+}
+
+// experimentAssignments extracts the experiment variants recorded in tags.
+func experimentAssignments(tags map[string]string) map[string]string {
+	var out map[string]string
+	for k, v := range tags {
+		name, ok := strings.CutPrefix(k, experimentTagPrefix)
+		if !ok || name == "" || v == "" {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string)
+		}
+		out[name] = v
+	}
+	return out
+}
+
+// VariantReport aggregates the outcomes of runs assigned to one variant.
+type VariantReport struct {
+	Experiment   string        `json:"experiment"`
+	Variant      string        `json:"variant"`
+	Runs         int           `json:"runs"`
+	Errors       int           `json:"errors"`
+	InputTokens  int64         `json:"input_tokens"`
+	OutputTokens int64         `json:"output_tokens"`
+	CostUSD      float64       `json:"cost_usd,omitempty"`
+	MeanLatency  time.Duration `json:"mean_latency"`
+	// Metrics holds outcomes reported with RecordExperimentOutcome.
+	Metrics map[string]MetricSummary `json:"metrics,omitempty"`
+}
+
+// ErrorRate is Errors divided by Runs.
+func (r VariantReport) ErrorRate() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Runs)
+}
+
+// MetricSummary aggregates one custom outcome metric.
+type MetricSummary struct {
+	Count int     `json:"count"`
+	Sum   float64 `json:"sum"`
+	Mean  float64 `json:"mean"`
+}
+
+type variantKey struct{ experiment, variant string }
+
+type variantStats struct {
+	runs, errors  int
+	input, output int64
+	cost          float64
+	latency       time.Duration
+	metrics       map[string]MetricSummary
+}
+
+// experimentStats accumulates per-variant outcomes in memory.
+type experimentStats struct {
+	mu    sync.Mutex
+	stats map[variantKey]*variantStats
+}
+
+func (s *experimentStats) entry(k variantKey) *variantStats {
+	if s.stats == nil {
+		s.stats = make(map[variantKey]*variantStats)
+	}
+	st, ok := s.stats[k]
+	if !ok {
+		st = &variantStats{}
+		s.stats[k] = st
+	}
+	return st
+}
+
+func (s *experimentStats) recordRun(tags map[string]string, result runResult, elapsed time.Duration, err error) {
+	assignments := experimentAssignments(tags)
+	if len(assignments) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for exp, variant := range assignments {
+		st := s.entry(variantKey{exp, variant})
+		st.runs++
+		st.latency += elapsed
+		if err != nil {
+			st.errors++
+			continue
+		}
+		st.input += int64(result.usage.InputTokens)
+		st.output += int64(result.usage.OutputTokens)
+		st.cost += result.usage.CostUSD
+	}
+}
+
+func (s *experimentStats) recordMetric(tags map[string]string, metric string, value float64) {
+	assignments := experimentAssignments(tags)
+	if len(assignments) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for exp, variant := range assignments {
+		st := s.entry(variantKey{exp, variant})
+		if st.metrics == nil {
+			st.metrics = make(map[string]MetricSummary)
+		}
+		m := st.metrics[metric]
+		m.Count++
+		m.Sum += value
+		m.Mean = m.Sum / float64(m.Count)
+		st.metrics[metric] = m
+	}
+}
+
+func (s *experimentStats) report() []VariantReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]VariantReport, 0, len(s.stats))
+	for k, st := range s.stats {
+		r := VariantReport{
+			Experiment:   k.experiment,
+			Variant:      k.variant,
+			Runs:         st.runs,
+			Errors:       st.errors,
+			InputTokens:  st.input,
+			OutputTokens: st.output,
+			CostUSD:      st.cost,
+		}
+		if st.runs > 0 {
+			r.MeanLatency = st.latency / time.Duration(st.runs)
+		}
+		if len(st.metrics) > 0 {
+			r.Metrics = maps.Clone(st.metrics)
+		}
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Experiment != out[j].Experiment {
+			return out[i].Experiment < out[j].Experiment
+		}
+		return out[i].Variant < out[j].Variant
+	})
+	return out
+}
+
+// ExperimentReport aggregates run outcomes per experiment variant, sorted by
+// experiment then variant.
+func (rt *Runtime) ExperimentReport() []VariantReport {
+	if rt == nil {
+		return nil
+	}
+	return rt.experiments.report()
+}
+
+// RecordExperimentOutcome adds a custom outcome (user rating, task success,
+// conversion) to the variants recorded in tags, typically Response.Tags.
+func (rt *Runtime) RecordExperimentOutcome(tags map[string]string, metric string, value float64) {
+	metric = strings.TrimSpace(metric)
+	if rt == nil || metric == "" {
+		return
+	}
+	rt.experiments.recordMetric(tags, metric, value)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+type systemCaptureModel struct {
+	mu     sync.Mutex
+	system string
+	prompt string
+	tools  []string
+}
+
+func (m *systemCaptureModel) Complete(_ context.Context, req model.Request) (*model.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.system = req.System
+	if n := len(req.Messages); n > 0 {
+		m.prompt = req.Messages[n-1].Content
+	}
+	m.tools = m.tools[:0]
+	for _, def := range req.Tools {
+		m.tools = append(m.tools, def.Name)
+	}
+	return &model.Response{Message: model.Message{Role: "assistant", Content: "ok"}, Usage: model.Usage{InputTokens: 10, OutputTokens: 2}}, nil
+}
+
+func (m *systemCaptureModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	resp, err := m.Complete(ctx, req)
+	if err != nil {
+		return err
+	}
+	return cb(model.StreamResult{Final: true, Response: resp})
+}
+
+func TestExperimentsAssignApplyAndReport(t *testing.T) {
+	mdl := &systemCaptureModel{}
+	exp := Experiment{Name: "prompt", Variants: []Variant{
+		{Name: "control"},
+		{Name: "concise", SystemPrompt: "Be concise.", Tools: []string{"beta"}},
+	}}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:         t.TempDir(),
+		Model:               mdl,
+		SystemPrompt:        "base",
+		EnabledBuiltinTools: []string{},
+		CustomTools:         []tool.Tool{&namedTool{name: "stable"}, &namedTool{name: "beta"}},
+		RulesEnabled:        boolPtr(false),
+		Experiments:         []Experiment{exp},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	// Assignment is sticky per session.
+	seen := map[string]int{}
+	for i := 0; i < 40; i++ {
+		session := fmt.Sprintf("s%d", i)
+		resp, err := rt.Run(context.Background(), Request{Prompt: "hi", SessionID: session})
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		variant := resp.Tags[ExperimentTag("prompt")]
+		if variant != exp.Assign(session).Name {
+			t.Fatalf("session %s got %q, want %q", session, variant, exp.Assign(session).Name)
+		}
+		seen[variant]++
+		if variant == "concise" && (!strings.HasPrefix(mdl.system, "Be concise.") || len(mdl.tools) != 1) {
+			t.Fatalf("variant not applied: system=%q tools=%v", mdl.system, mdl.tools)
+		}
+		if variant == "control" && (!strings.HasPrefix(mdl.system, "base") || len(mdl.tools) != 2) {
+			t.Fatalf("control altered: system=%q tools=%v", mdl.system, mdl.tools)
+		}
+	}
+	if seen["control"] == 0 || seen["concise"] == 0 {
+		t.Fatalf("expected both variants, got %v", seen)
+	}
+
+	// A request tag pins the variant.
+	resp, err := rt.Run(context.Background(), Request{Prompt: "hi", SessionID: "pinned", Tags: map[string]string{ExperimentTag("prompt"): "concise"}})
+	if err != nil || resp.Tags[ExperimentTag("prompt")] != "concise" {
+		t.Fatalf("pinned run tags=%v err=%v", resp.Tags, err)
+	}
+	rt.RecordExperimentOutcome(resp.Tags, "thumbs_up", 1)
+
+	report := rt.ExperimentReport()
+	if len(report) != 2 || report[0].Variant != "concise" || report[1].Variant != "control" {
+		t.Fatalf("report %+v", report)
+	}
+	concise := report[0]
+	if concise.Runs != seen["concise"]+1 || concise.InputTokens != int64(10*concise.Runs) || concise.ErrorRate() != 0 {
+		t.Fatalf("concise report %+v", concise)
+	}
+	if m := concise.Metrics["thumbs_up"]; m.Count != 1 || m.Mean != 1 {
+		t.Fatalf("metric %+v", m)
+	}
+}
+
+func TestExperimentsValidation(t *testing.T) {
+	for _, exps := range [][]Experiment{
+		{{Name: ""}},
+		{{Name: "a"}},
+		{{Name: "a", Variants: []Variant{{Name: "x"}, {Name: "x"}}}},
+		{{Name: "a", Variants: []Variant{{Name: "x"}}}, {Name: "a", Variants: []Variant{{Name: "y"}}}},
+	} {
+		if err := validateExperiments(exps); !errors.Is(err, ErrInvalidExperiment) {
+			t.Fatalf("%+v: expected ErrInvalidExperiment, got %v", exps, err)
+		}
+	}
+	weighted := Experiment{Name: "w", Variants: []Variant{{Name: "rare"}, {Name: "mostly", Weight: 99}}}
+	counts := map[string]int{}
+	for i := 0; i < 200; i++ {
+		counts[weighted.Assign(fmt.Sprint(i)).Name]++
+	}
+	if counts["mostly"] < 180 {
+		t.Fatalf("weights ignored: %v", counts)
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cexll/agentsdk-go/pkg/security"
+)
+
+// ErrFileScanBlocked is returned for a Write or Edit call whose file drew
+// findings while FileScanOptions.Block is set.
+var ErrFileScanBlocked = errors.New("api: file write blocked by scan findings")
+
+// FileScanOptions configures the scan of files written by Write and Edit;
+// see Options.FileScan.
+type FileScanOptions struct {
+	// Analyzers run after the built-in secret scanner, e.g. adapters for
+	// linters or SAST tools.
+	Analyzers []security.Analyzer
+	// NoSecrets disables the built-in secret scanner.
+	NoSecrets bool
+	// Block restores a file that drew findings to its previous content, or
+	// removes it if it was new, and fails the tool call with
+	// ErrFileScanBlocked so the model can correct it. Without Block the
+	// write stands and the findings are only reported.
+	Block bool
+}
+
+// fileScanner scans the files written during one run.
+type fileScanner struct {
+	analyzers []security.Analyzer
+	block     bool
+	root      string
+
+	mu       sync.Mutex
+	findings []security.Finding
+}
+
+func (rt *Runtime) newFileScanner() *fileScanner {
+	cfg := rt.opts.FileScan
+	if cfg == nil {
+		return nil
+	}
+	var analyzers []security.Analyzer
+	if !cfg.NoSecrets {
+		analyzers = append(analyzers, security.NewSecretScanner())
+	}
+	analyzers = append(analyzers, cfg.Analyzers...)
+	if len(analyzers) == 0 {
+		return nil
+	}
+	return &fileScanner{analyzers: analyzers, block: cfg.Block, root: rt.sbRoot}
+}
+
+// fileSnapshot is the state of a file before a write, kept so a blocked
+// write can be undone.
+type fileSnapshot struct {
+	name    string
+	path    string
+	content []byte
+	mode    fs.FileMode
+	exists  bool
+}
+
+// snapshot records the file a Write or Edit call is about to change. It
+// returns nil for other tools.
+func (s *fileScanner) snapshot(toolName string, params map[string]any) *fileSnapshot {
+	if s == nil || !fileWriteTools[toolName] {
+		return nil
+	}
+	name, _ := params["file_path"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.root, path)
+	}
+	snap := &fileSnapshot{name: name, path: path}
+	if info, err := os.Stat(path); err == nil {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		snap.content, snap.mode, snap.exists = content, info.Mode().Perm(), true
+	}
+	return snap
+}
+
+// check scans the file behind snap after a successful write and records
+// the findings. With blocking enabled a file with findings is restored and
+// ErrFileScanBlocked returned.
+func (s *fileScanner) check(ctx context.Context, snap *fileSnapshot) error {
+	if s == nil || snap == nil {
+		return nil
+	}
+	content, err := os.ReadFile(snap.path)
+	if err != nil {
+		return nil
+	}
+	findings := security.ScanFile(ctx, s.analyzers, snap.name, content)
+	if len(findings) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	s.findings = append(s.findings, findings...)
+	s.mu.Unlock()
+	if !s.block {
+		return nil
+	}
+	if err := snap.restore(); err != nil {
+		log.Printf("api: file scan: restore %s: %v", snap.name, err)
+	}
+	lines := make([]string, len(findings))
+	for i, f := range findings {
+		lines[i] = f.String()
+	}
+	return fmt.Errorf("%w; the file was not changed:\n%s", ErrFileScanBlocked, strings.Join(lines, "\n"))
+}
+
+func (snap *fileSnapshot) restore() error {
+	if !snap.exists {
+		return os.Remove(snap.path)
+	}
+	return os.WriteFile(snap.path, snap.content, snap.mode)
+}
+
+func (s *fileScanner) collected() []security.Finding {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.findings)
+}
+
+// isDryRun reports whether a Write or Edit result is a dry-run preview,
+// which leaves the file untouched.
+func isDryRun(data any) bool {
+	fields, _ := data.(map[string]any)
+	dry, _ := fields["dry_run"].(bool)
+	return dry
+}
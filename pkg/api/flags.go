@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/flags"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+)
+
+const (
+	// tenantTag is the request tag used as the flag targeting tenant.
+	tenantTag = "tenant"
+	// noToolsEntry matches no tool name.
+	noToolsEntry = "\x00"
+)
+
+// applyFlags binds Options.Flags to the run context and applies the model
+// flag to requests that did not pick a tier.
+func (rt *Runtime) applyFlags(ctx context.Context, req *Request) context.Context {
+	if rt.opts.Flags == nil {
+		return ctx
+	}
+	target := flags.Target{SessionID: req.SessionID, Tenant: req.Tags[tenantTag], Attributes: req.Tags}
+	ctx = flags.WithProvider(ctx, rt.opts.Flags, target)
+	if req.Model == "" {
+		if tier := flags.Value(ctx, flags.ModelFlag, ""); tier != "" {
+			req.Model = ModelTier(strings.ToLower(tier))
+		}
+	}
+	return ctx
+}
+
+// flaggedTools removes tools whose "tool.<name>" flag is off from the run's
+// whitelist. A nil whitelist (all tools) becomes explicit when any tool is
+// switched off.
+func (rt *Runtime) flaggedTools(ctx context.Context, whitelist map[string]struct{}) map[string]struct{} {
+	if rt.opts.Flags == nil || rt.registry == nil {
+		return whitelist
+	}
+	var disabled []string
+	for _, t := range rt.registry.List() {
+		if !flags.Enabled(ctx, flags.ToolFlag(t.Name()), true) {
+			disabled = append(disabled, canonicalToolName(t.Name()))
+		}
+	}
+	if len(disabled) == 0 {
+		return whitelist
+	}
+	if whitelist == nil {
+		whitelist = make(map[string]struct{})
+		for _, t := range rt.registry.List() {
+			whitelist[canonicalToolName(t.Name())] = struct{}{}
+		}
+	}
+	for _, name := range disabled {
+		delete(whitelist, name)
+	}
+	if len(whitelist) == 0 {
+		// An empty whitelist means "all tools"; keep it non-empty so every
+		// tool stays off.
+		whitelist[noToolsEntry] = struct{}{}
+	}
+	return whitelist
+}
+
+// flaggedMiddleware drops middleware whose "middleware.<name>" flag is off.
+func flaggedMiddleware(ctx context.Context, items []middleware.Middleware) []middleware.Middleware {
+	if _, ok := flags.TargetFromContext(ctx); !ok {
+		return items
+	}
+	out := make([]middleware.Middleware, 0, len(items))
+	for _, mw := range items {
+		if mw != nil && !flags.Enabled(ctx, flags.MiddlewareFlag(mw.Name()), true) {
+			continue
+		}
+		out = append(out, mw)
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/flags"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+type toolCaptureModel struct {
+	mu    sync.Mutex
+	tools []string
+}
+
+func (m *toolCaptureModel) Complete(_ context.Context, req model.Request) (*model.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tools = m.tools[:0]
+	for _, def := range req.Tools {
+		m.tools = append(m.tools, def.Name)
+	}
+	return &model.Response{Message: model.Message{Role: "assistant", Content: "ok"}}, nil
+}
+
+func (m *toolCaptureModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	resp, err := m.Complete(ctx, req)
+	if err != nil {
+		return err
+	}
+	return cb(model.StreamResult{Final: true, Response: resp})
+}
+
+func TestFlagsGateToolsAndMiddleware(t *testing.T) {
+	mdl := &toolCaptureModel{}
+	var canaryRuns int
+	canary := middleware.Funcs{Identifier: "canary", OnBeforeAgent: func(context.Context, *middleware.State) error {
+		canaryRuns++
+		return nil
+	}}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:         t.TempDir(),
+		Model:               mdl,
+		EnabledBuiltinTools: []string{},
+		CustomTools:         []tool.Tool{&namedTool{name: "stable"}, &namedTool{name: "beta"}},
+		RulesEnabled:        boolPtr(false),
+		Middleware:          []middleware.Middleware{canary},
+		Flags: flags.StaticProvider{
+			flags.ToolFlag("beta"):         {Tenants: []string{"early"}},
+			flags.MiddlewareFlag("canary"): {Tenants: []string{"early"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	if _, err := rt.Run(context.Background(), Request{Prompt: "hi", SessionID: "a", Tags: map[string]string{"tenant": "other"}}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(mdl.tools) != 1 || mdl.tools[0] != "stable" || canaryRuns != 0 {
+		t.Fatalf("gated run tools=%v canary=%d", mdl.tools, canaryRuns)
+	}
+	if _, err := rt.Run(context.Background(), Request{Prompt: "hi", SessionID: "b", Tags: map[string]string{"tenant": "early"}}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(mdl.tools) != 2 || canaryRuns != 1 {
+		t.Fatalf("early tenant tools=%v canary=%d", mdl.tools, canaryRuns)
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrSessionNotFound is returned when forking a session without history.
+	ErrSessionNotFound = errors.New("api: session not found")
+	// ErrSessionExists is returned when the requested fork ID already has
+	// history.
+	ErrSessionExists = errors.New("api: session already exists")
+)
+
+const (
+	// SessionParentTag is set on a fork to the session it was forked from.
+	SessionParentTag = "session.parent"
+	// SessionForkPointTag is set on a fork to the number of parent messages
+	// it shares.
+	SessionForkPointTag = "session.fork_point"
+)
+
+// SessionLineage describes where a session sits in its fork tree.
+type SessionLineage struct {
+	SessionID string
+	// ParentID is empty for sessions that were not forked.
+	ParentID string
+	// ForkPoint is the number of parent messages the fork started with.
+	ForkPoint int
+	CreatedAt time.Time
+	// Children lists sessions forked from this one, oldest first.
+	Children []string
+}
+
+// ForkOption customizes ForkSession.
+type ForkOption func(*forkConfig)
+
+type forkConfig struct {
+	id string
+	at int
+}
+
+// ForkAs names the new session instead of generating "<parent>~<id>".
+func ForkAs(sessionID string) ForkOption {
+	return func(c *forkConfig) { c.id = strings.TrimSpace(sessionID) }
+}
+
+// ForkAt keeps only the first n messages of the parent. A cut inside a tool
+// exchange moves back so tool calls stay paired with their results. Zero or
+// a value past the end copies the whole history.
+func ForkAt(n int) ForkOption {
+	return func(c *forkConfig) { c.at = n }
+}
+
+// ForkSession copies the history of sessionID into a new session and returns
+// its ID. Runs on the fork never touch the parent, so callers can explore an
+// alternative path from any point. The fork inherits the parent's tags plus
+// SessionParentTag and SessionForkPointTag; SessionLineage reports the tree.
+func (rt *Runtime) ForkSession(ctx context.Context, sessionID string, opts ...ForkOption) (string, error) {
+	if rt == nil {
+		return "", errors.New("api: runtime is nil")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent := strings.TrimSpace(sessionID)
+	if parent == "" {
+		return "", ErrSessionNotFound
+	}
+	var cfg forkConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	child := cfg.id
+	if child == "" {
+		child = parent + "~" + uuid.NewString()[:8]
+	}
+	if child == parent {
+		return "", fmt.Errorf("%w: %q", ErrSessionExists, child)
+	}
+	if err := rt.beginRun(); err != nil {
+		return "", err
+	}
+	defer rt.endRun()
+
+	// Hold the parent so an in-flight run finishes before it is copied.
+	if err := rt.sessionGate.Acquire(ctx, parent); err != nil {
+		return "", err
+	}
+	parentHist := rt.histories.Get(parent)
+	rt.refreshHistory(ctx, parent, parentHist)
+	msgs := parentHist.All()
+	rt.sessionGate.Release(parent)
+	if len(msgs) == 0 {
+		rt.histories.Delete(parent)
+		return "", fmt.Errorf("%w: %q", ErrSessionNotFound, parent)
+	}
+	point := forkPoint(msgs, cfg.at)
+
+	if err := rt.sessionGate.Acquire(ctx, child); err != nil {
+		return "", err
+	}
+	defer rt.sessionGate.Release(child)
+	hist := rt.histories.Get(child)
+	if hist.Len() > 0 {
+		return "", fmt.Errorf("%w: %q", ErrSessionExists, child)
+	}
+	hist.Replace(message.CloneMessages(msgs[:point]))
+	rt.sessionValues.put(child, rt.sessionValuesFor(parent))
+	rt.persistHistory(child, hist)
+
+	tags := map[string]string{}
+	for k, v := range rt.sessionTags.snapshot()[parent] {
+		tags[k] = v
+	}
+	tags[SessionParentTag] = parent
+	tags[SessionForkPointTag] = strconv.Itoa(point)
+	rt.sessionTags.note(child, tags)
+	rt.lineage.add(parent, child, point)
+	return child, nil
+}
+
+// SessionLineage reports the parent and children of sessionID. ok is false
+// when the session takes part in no fork.
+func (rt *Runtime) SessionLineage(sessionID string) (SessionLineage, bool) {
+	if rt == nil {
+		return SessionLineage{}, false
+	}
+	return rt.lineage.get(strings.TrimSpace(sessionID))
+}
+
+// forkPoint clamps at to msgs and moves it off tool results so the fork
+// does not start with an unanswered tool call.
+func forkPoint(msgs []message.Message, at int) int {
+	n := len(msgs)
+	if at > 0 && at < n {
+		n = at
+	}
+	for n > 0 && n < len(msgs) && msgs[n].Role == "tool" {
+		n--
+	}
+	return n
+}
+
+// sessionLineageIndex records fork relationships.
+type sessionLineageIndex struct {
+	mu    sync.Mutex
+	nodes map[string]*SessionLineage
+}
+
+func (i *sessionLineageIndex) node(id string) *SessionLineage {
+	if i.nodes == nil {
+		i.nodes = map[string]*SessionLineage{}
+	}
+	n := i.nodes[id]
+	if n == nil {
+		n = &SessionLineage{SessionID: id}
+		i.nodes[id] = n
+	}
+	return n
+}
+
+func (i *sessionLineageIndex) add(parent, child string, point int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	p := i.node(parent)
+	p.Children = append(p.Children, child)
+	c := i.node(child)
+	c.ParentID = parent
+	c.ForkPoint = point
+	c.CreatedAt = time.Now()
+}
+
+func (i *sessionLineageIndex) get(id string) (SessionLineage, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	n, ok := i.nodes[id]
+	if !ok {
+		return SessionLineage{}, false
+	}
+	out := *n
+	out.Children = append([]string(nil), n.Children...)
+	return out, true
+}
+
+// forget drops sessionID from the tree; its children keep their ParentID.
+func (i *sessionLineageIndex) forget(id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	n, ok := i.nodes[id]
+	if !ok {
+		return
+	}
+	if p := i.nodes[n.ParentID]; p != nil {
+		kept := p.Children[:0]
+		for _, c := range p.Children {
+			if c != id {
+				kept = append(kept, c)
+			}
+		}
+		p.Children = kept
+	}
+	delete(i.nodes, id)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func TestForkSessionIsolatesHistory(t *testing.T) {
+	ctx := context.Background()
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", Content: "one"}},
+		{Message: model.Message{Role: "assistant", Content: "two"}},
+		{Message: model.Message{Role: "assistant", Content: "branch"}},
+	}}
+	rt, err := New(ctx, Options{ProjectRoot: t.TempDir(), Model: mdl})
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	defer rt.Close()
+
+	if _, err := rt.Run(ctx, Request{Prompt: "first", SessionID: "main"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if _, err := rt.Run(ctx, Request{Prompt: "second", SessionID: "main"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	parentLen := rt.histories.Get("main").Len()
+
+	child, err := rt.ForkSession(ctx, "main", ForkAs("alt"), ForkAt(2))
+	if err != nil {
+		t.Fatalf("fork: %v", err)
+	}
+	if child != "alt" {
+		t.Fatalf("child id = %q", child)
+	}
+	if got := rt.histories.Get("alt").Len(); got != 2 {
+		t.Fatalf("fork history len = %d, want 2", got)
+	}
+	if _, err := rt.Run(ctx, Request{Prompt: "other way", SessionID: "alt"}); err != nil {
+		t.Fatalf("run fork: %v", err)
+	}
+	if got := rt.histories.Get("main").Len(); got != parentLen {
+		t.Fatalf("parent history changed: %d -> %d", parentLen, got)
+	}
+
+	lin, ok := rt.SessionLineage("alt")
+	if !ok || lin.ParentID != "main" || lin.ForkPoint != 2 {
+		t.Fatalf("unexpected lineage %+v", lin)
+	}
+	root, ok := rt.SessionLineage("main")
+	if !ok || len(root.Children) != 1 || root.Children[0] != "alt" {
+		t.Fatalf("unexpected parent lineage %+v", root)
+	}
+	tags := rt.sessionTags.snapshot()["alt"]
+	if tags[SessionParentTag] != "main" || tags[SessionForkPointTag] != "2" {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+
+	if _, err := rt.ForkSession(ctx, "main", ForkAs("alt")); !errors.Is(err, ErrSessionExists) {
+		t.Fatalf("expected ErrSessionExists, got %v", err)
+	}
+	if _, err := rt.ForkSession(ctx, "missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+	auto, err := rt.ForkSession(ctx, "main")
+	if err != nil || auto == "" || auto == "main" {
+		t.Fatalf("auto fork = %q, %v", auto, err)
+	}
+	if got := rt.histories.Get(auto).Len(); got != parentLen {
+		t.Fatalf("full fork len = %d, want %d", got, parentLen)
+	}
+}
+
+func TestForkPointKeepsToolPairs(t *testing.T) {
+	msgs := []message.Message{
+		{Role: "user", Content: "go"},
+		{Role: "assistant", ToolCalls: []message.ToolCall{{ID: "c1", Name: "bash"}}},
+		{Role: "tool", ToolCalls: []message.ToolCall{{ID: "c1", Result: "ok"}}},
+		{Role: "tool", ToolCalls: []message.ToolCall{{ID: "c2", Result: "ok"}}},
+		{Role: "assistant", Content: "done"},
+	}
+	cases := map[int]int{0: 5, 9: 5, 1: 1, 2: 1, 3: 1, 4: 4}
+	for at, want := range cases {
+		if got := forkPoint(msgs, at); got != want {
+			t.Fatalf("forkPoint(%d) = %d, want %d", at, got, want)
+		}
+	}
+}
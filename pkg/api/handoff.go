@@ -0,0 +1,441 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+	"github.com/cexll/agentsdk-go/pkg/runtime/tasks"
+	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/google/uuid"
+)
+
+// HandoffVersion is the bundle format written by Runtime.Handoff.
+const HandoffVersion = 1
+
+// HandoffFromTag is set on an imported session to the session it continues.
+const HandoffFromTag = "handoff.from"
+
+var (
+	// ErrHandoffKeyMissing is returned by Handoff without Options.HandoffKey.
+	ErrHandoffKeyMissing = errors.New("api: handoff signing key not configured")
+	// ErrHandoffSignature is returned when a bundle's signature does not
+	// match its contents.
+	ErrHandoffSignature = errors.New("api: handoff signature invalid")
+	// ErrHandoffUntrusted is returned when a bundle is signed by a key that
+	// is not trusted.
+	ErrHandoffUntrusted = errors.New("api: handoff signed by untrusted key")
+)
+
+// HandoffBundle is everything another runtime or a human operator needs to
+// continue a session.
+type HandoffBundle struct {
+	Version   int       `json:"version"`
+	SessionID string    `json:"session_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Summary   string    `json:"summary"`
+	// Messages is the full history, present with HandoffWithHistory.
+	Messages []message.Message `json:"messages,omitempty"`
+	// Tasks lists the unfinished tasks owned by the session.
+	Tasks     []tasks.Task      `json:"tasks,omitempty"`
+	Artifacts []HandoffArtifact `json:"artifacts,omitempty"`
+	Grants    []HandoffGrant    `json:"grants,omitempty"`
+	// WhitelistUntil is the end of the session's approval whitelist.
+	WhitelistUntil *time.Time        `json:"whitelist_until,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+// HandoffArtifact references an artifact stored by a tool run or recorded
+// on a task. Payloads stay in their store.
+type HandoffArtifact struct {
+	// ID is the artifact store ID; empty for task artifact references.
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	SizeBytes int64  `json:"size_bytes,omitempty"`
+	// TaskID is the task that recorded the reference in Name.
+	TaskID string `json:"task_id,omitempty"`
+}
+
+// HandoffGrant is an approval given to the session.
+type HandoffGrant struct {
+	Command    string     `json:"command"`
+	Approver   string     `json:"approver,omitempty"`
+	ApprovedAt *time.Time `json:"approved_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// SignedHandoff is the portable form of a HandoffBundle: the bundle JSON
+// exactly as signed, plus the signer's key ID and ed25519 signature.
+type SignedHandoff struct {
+	Bundle    json.RawMessage `json:"bundle"`
+	KeyID     string          `json:"key_id"`
+	Signature []byte          `json:"signature"`
+}
+
+// Decode returns the bundle without checking the signature, e.g. to show
+// it to an operator before deciding to import it.
+func (s *SignedHandoff) Decode() (*HandoffBundle, error) {
+	if s == nil || len(s.Bundle) == 0 {
+		return nil, errors.New("api: handoff bundle is empty")
+	}
+	var bundle HandoffBundle
+	if err := json.Unmarshal(s.Bundle, &bundle); err != nil {
+		return nil, fmt.Errorf("api: decode handoff: %w", err)
+	}
+	if bundle.Version > HandoffVersion {
+		return nil, fmt.Errorf("api: handoff version %d not supported", bundle.Version)
+	}
+	return &bundle, nil
+}
+
+// HandoffKeyID identifies a signing key in SignedHandoff.KeyID.
+func HandoffKeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// VerifyHandoff checks that signed was signed by one of trusted and
+// returns its bundle.
+func VerifyHandoff(signed *SignedHandoff, trusted ...ed25519.PublicKey) (*HandoffBundle, error) {
+	if signed == nil {
+		return nil, errors.New("api: handoff bundle is nil")
+	}
+	for _, key := range trusted {
+		if len(key) != ed25519.PublicKeySize || HandoffKeyID(key) != signed.KeyID {
+			continue
+		}
+		if !ed25519.Verify(key, signed.Bundle, signed.Signature) {
+			return nil, ErrHandoffSignature
+		}
+		return signed.Decode()
+	}
+	return nil, fmt.Errorf("%w: %q", ErrHandoffUntrusted, signed.KeyID)
+}
+
+// HandoffOption customizes Handoff.
+type HandoffOption func(*handoffConfig)
+
+type handoffConfig struct {
+	summary     string
+	withHistory bool
+}
+
+// HandoffSummary uses summary instead of asking the model for one.
+func HandoffSummary(summary string) HandoffOption {
+	return func(c *handoffConfig) { c.summary = strings.TrimSpace(summary) }
+}
+
+// HandoffWithHistory includes the full message history in the bundle.
+func HandoffWithHistory() HandoffOption {
+	return func(c *handoffConfig) { c.withHistory = true }
+}
+
+// Handoff packages sessionID for another runtime or an operator: a summary
+// of the conversation (written by the model unless HandoffSummary is
+// given), the session's unfinished tasks, the artifacts its runs and tasks
+// produced, and its approval grants. The bundle is signed with
+// Options.HandoffKey.
+func (rt *Runtime) Handoff(ctx context.Context, sessionID string, opts ...HandoffOption) (*SignedHandoff, error) {
+	if rt == nil {
+		return nil, errors.New("api: runtime is nil")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(rt.opts.HandoffKey) != ed25519.PrivateKeySize {
+		return nil, ErrHandoffKeyMissing
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return nil, ErrSessionNotFound
+	}
+	var cfg handoffConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	if err := rt.beginRun(); err != nil {
+		return nil, err
+	}
+	defer rt.endRun()
+
+	// Hold the session so an in-flight run finishes before it is copied.
+	if err := rt.sessionGate.Acquire(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	hist := rt.histories.Get(sessionID)
+	rt.refreshHistory(ctx, sessionID, hist)
+	msgs := hist.All()
+	rt.sessionGate.Release(sessionID)
+	if len(msgs) == 0 {
+		rt.histories.Delete(sessionID)
+		return nil, fmt.Errorf("%w: %q", ErrSessionNotFound, sessionID)
+	}
+
+	bundle := HandoffBundle{
+		Version:   HandoffVersion,
+		SessionID: sessionID,
+		CreatedAt: time.Now().UTC(),
+		Summary:   cfg.summary,
+		Tags:      maps.Clone(rt.sessionTags.snapshot()[sessionID]),
+		Artifacts: rt.sessionArtifacts.list(sessionID),
+	}
+	if bundle.Summary == "" {
+		// Reuse the compaction summarizer; without compaction, summarize
+		// with the runtime model.
+		summarizer := rt.compactor
+		if summarizer == nil {
+			summarizer = &compactor{cfg: CompactConfig{}.withDefaults(), model: rt.opts.Model}
+		}
+		summary, err := summarizer.summarize(ctx, msgs)
+		if err != nil {
+			return nil, fmt.Errorf("api: handoff summary: %w", err)
+		}
+		bundle.Summary = summary
+	}
+	if cfg.withHistory {
+		bundle.Messages = msgs
+	}
+	var open []*tasks.Task
+	if rt.tasks != nil {
+		open = rt.tasks.List()
+	}
+	for _, task := range open {
+		if task == nil || task.Session != sessionID || task.Status == tasks.TaskCompleted {
+			continue
+		}
+		bundle.Tasks = append(bundle.Tasks, *task)
+		for _, ref := range task.Artifacts {
+			bundle.Artifacts = append(bundle.Artifacts, HandoffArtifact{Name: ref, TaskID: task.ID})
+		}
+	}
+	if queue := rt.opts.ApprovalQueue; queue != nil {
+		for _, rec := range queue.SessionRecords(sessionID) {
+			if rec.State != security.ApprovalApproved || rec.AutoApproved {
+				continue
+			}
+			bundle.Grants = append(bundle.Grants, HandoffGrant{
+				Command:    rec.Command,
+				Approver:   rec.Approver,
+				ApprovedAt: rec.ApprovedAt,
+				ExpiresAt:  rec.ExpiresAt,
+			})
+		}
+		if until, ok := queue.WhitelistExpiry(sessionID); ok {
+			bundle.WhitelistUntil = &until
+		}
+	}
+
+	payload, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("api: encode handoff: %w", err)
+	}
+	key := rt.opts.HandoffKey
+	return &SignedHandoff{
+		Bundle:    payload,
+		KeyID:     HandoffKeyID(key.Public().(ed25519.PublicKey)),
+		Signature: ed25519.Sign(key, payload),
+	}, nil
+}
+
+// ImportOption customizes ImportHandoff.
+type ImportOption func(*importConfig)
+
+type importConfig struct {
+	id string
+}
+
+// ImportAs names the imported session instead of reusing the bundle's
+// session ID.
+func ImportAs(sessionID string) ImportOption {
+	return func(c *importConfig) { c.id = strings.TrimSpace(sessionID) }
+}
+
+// ImportHandoff verifies signed against Options.HandoffTrustedKeys and the
+// runtime's own key, then starts a session that continues it and returns
+// the session ID. The session starts from the bundle's history, or from its
+// summary when the history was left out. Open tasks are claimed by the new
+// session (tasks missing from this runtime's store are recreated with new
+// IDs), and an unexpired approval whitelist carries over to
+// Options.ApprovalQueue.
+func (rt *Runtime) ImportHandoff(ctx context.Context, signed *SignedHandoff, opts ...ImportOption) (string, error) {
+	if rt == nil {
+		return "", errors.New("api: runtime is nil")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	trusted := append([]ed25519.PublicKey(nil), rt.opts.HandoffTrustedKeys...)
+	if len(rt.opts.HandoffKey) == ed25519.PrivateKeySize {
+		trusted = append(trusted, rt.opts.HandoffKey.Public().(ed25519.PublicKey))
+	}
+	bundle, err := VerifyHandoff(signed, trusted...)
+	if err != nil {
+		return "", err
+	}
+	var cfg importConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	id := cfg.id
+	if id == "" {
+		id = bundle.SessionID
+	}
+	if id == "" {
+		id = "handoff-" + uuid.NewString()[:8]
+	}
+	if err := rt.beginRun(); err != nil {
+		return "", err
+	}
+	defer rt.endRun()
+
+	if err := rt.sessionGate.Acquire(ctx, id); err != nil {
+		return "", err
+	}
+	defer rt.sessionGate.Release(id)
+	hist := rt.histories.Get(id)
+	rt.refreshHistory(ctx, id, hist)
+	if hist.Len() > 0 {
+		return "", fmt.Errorf("%w: %q", ErrSessionExists, id)
+	}
+	msgs := message.CloneMessages(bundle.Messages)
+	if len(msgs) == 0 {
+		msgs = []message.Message{{
+			Role:    "system",
+			Content: fmt.Sprintf("Handoff from session %s:\n%s", bundle.SessionID, bundle.Summary),
+		}}
+	}
+	hist.Replace(msgs)
+	rt.persistHistory(id, hist)
+
+	tags := maps.Clone(bundle.Tags)
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	tags[HandoffFromTag] = bundle.SessionID
+	rt.sessionTags.note(id, tags)
+
+	if err := importHandoffTasks(rt.tasks, id, bundle.Tasks); err != nil {
+		return id, err
+	}
+	for _, a := range bundle.Artifacts {
+		if a.ID != "" {
+			rt.sessionArtifacts.add(id, a)
+		}
+	}
+	if bundle.WhitelistUntil != nil && rt.opts.ApprovalQueue != nil {
+		if err := rt.opts.ApprovalQueue.Whitelist(id, *bundle.WhitelistUntil); err != nil {
+			return id, fmt.Errorf("api: import handoff grants: %w", err)
+		}
+	}
+	return id, nil
+}
+
+// importHandoffTasks claims the bundle's tasks for sessionID. Tasks the
+// store already holds, as with a shared ledger, keep their IDs.
+func importHandoffTasks(store *tasks.TaskStore, sessionID string, bundled []tasks.Task) error {
+	if store == nil || len(bundled) == 0 {
+		return nil
+	}
+	ids := make(map[string]string, len(bundled))
+	var created []tasks.Task
+	for _, task := range bundled {
+		if _, err := store.Get(task.ID); err == nil {
+			ids[task.ID] = task.ID
+			continue
+		}
+		fresh, err := store.Create(task.Subject, task.Description, task.ActiveForm)
+		if err != nil {
+			return fmt.Errorf("api: import handoff task %s: %w", task.ID, err)
+		}
+		ids[task.ID] = fresh.ID
+		created = append(created, task)
+	}
+	for _, task := range bundled {
+		update := tasks.TaskUpdate{Session: &sessionID}
+		if !containsTask(created, task.ID) {
+			if _, err := store.Update(ids[task.ID], update); err != nil {
+				return fmt.Errorf("api: import handoff task %s: %w", task.ID, err)
+			}
+			continue
+		}
+		status, owner := task.Status, task.Owner
+		update.Status, update.Owner, update.AddArtifacts = &status, &owner, task.Artifacts
+		if _, err := store.Update(ids[task.ID], update); err != nil {
+			return fmt.Errorf("api: import handoff task %s: %w", task.ID, err)
+		}
+		for _, blocker := range task.BlockedBy {
+			if mapped, ok := ids[blocker]; ok {
+				if err := store.AddDependency(ids[task.ID], mapped); err != nil {
+					return fmt.Errorf("api: import handoff task %s: %w", task.ID, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func containsTask(list []tasks.Task, id string) bool {
+	for _, task := range list {
+		if task.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionArtifactIndex remembers the stored artifacts produced by each
+// session's runs, for Handoff.
+type sessionArtifactIndex struct {
+	mu       sync.Mutex
+	sessions map[string][]HandoffArtifact
+}
+
+func (i *sessionArtifactIndex) note(sessionID string, artifacts []tool.Artifact) {
+	for _, a := range artifacts {
+		if a.ID == "" {
+			continue
+		}
+		i.add(sessionID, HandoffArtifact{ID: a.ID, Name: a.Name, MediaType: a.MediaType, SizeBytes: a.SizeBytes})
+	}
+}
+
+func (i *sessionArtifactIndex) add(sessionID string, a HandoffArtifact) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, existing := range i.sessions[sessionID] {
+		if existing.ID == a.ID {
+			return
+		}
+	}
+	if i.sessions == nil {
+		i.sessions = map[string][]HandoffArtifact{}
+	}
+	i.sessions[sessionID] = append(i.sessions[sessionID], a)
+}
+
+func (i *sessionArtifactIndex) list(sessionID string) []HandoffArtifact {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]HandoffArtifact(nil), i.sessions[sessionID]...)
+}
+
+func (i *sessionArtifactIndex) forget(sessionID string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.sessions, sessionID)
+}
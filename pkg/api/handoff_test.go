@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/runtime/tasks"
+	"github.com/cexll/agentsdk-go/pkg/security"
+)
+
+func newHandoffRuntime(t *testing.T, key ed25519.PrivateKey, trusted ...ed25519.PublicKey) (*Runtime, *security.ApprovalQueue) {
+	t.Helper()
+	queue, err := security.NewApprovalQueue("")
+	if err != nil {
+		t.Fatalf("queue: %v", err)
+	}
+	opts := Options{ProjectRoot: newClaudeProject(t), Model: &systemModel{}, ApprovalQueue: queue}
+	WithHandoffKeys(key, trusted...)(&opts)
+	rt, err := New(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	return rt, queue
+}
+
+func TestHandoffRoundTrip(t *testing.T) {
+	pubA, keyA, _ := ed25519.GenerateKey(nil)
+	_, keyB, _ := ed25519.GenerateKey(nil)
+	src, srcQueue := newHandoffRuntime(t, keyA)
+	dst, dstQueue := newHandoffRuntime(t, keyB, pubA)
+
+	if _, err := src.Run(context.Background(), Request{Prompt: "start the migration", SessionID: "eng-1"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	session := "eng-1"
+	first, _ := src.Tasks().Create("schema", "", "migrating schema")
+	second, _ := src.Tasks().Create("backfill", "", "backfilling")
+	_ = src.Tasks().AddDependency(second.ID, first.ID)
+	for _, id := range []string{first.ID, second.ID} {
+		if _, err := src.Tasks().Update(id, tasks.TaskUpdate{Session: &session, AddArtifacts: []string{"branch:" + id}}); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := srcQueue.Whitelist(session, until); err != nil {
+		t.Fatalf("whitelist: %v", err)
+	}
+
+	signed, err := src.Handoff(context.Background(), session)
+	if err != nil {
+		t.Fatalf("handoff: %v", err)
+	}
+	raw, _ := json.Marshal(signed)
+	var wire SignedHandoff
+	if err := json.Unmarshal(raw, &wire); err != nil {
+		t.Fatalf("decode wire: %v", err)
+	}
+	bundle, err := wire.Decode()
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if bundle.Summary != "ok" || len(bundle.Tasks) != 2 || len(bundle.Artifacts) != 2 || bundle.Messages != nil {
+		t.Fatalf("unexpected bundle %+v", bundle)
+	}
+
+	id, err := dst.ImportHandoff(context.Background(), &wire)
+	if err != nil || id != session {
+		t.Fatalf("import = %q, %v", id, err)
+	}
+	msgs := dst.histories.Get(id).All()
+	if len(msgs) != 1 || msgs[0].Role != "system" || !strings.Contains(msgs[0].Content, "ok") {
+		t.Fatalf("history not seeded from summary: %+v", msgs)
+	}
+	imported := dst.Tasks().List()
+	if len(imported) != 2 || imported[0].Session != session || imported[1].Session != session {
+		t.Fatalf("tasks not claimed: %+v", imported)
+	}
+	if len(imported[1].BlockedBy) != 1 || imported[1].BlockedBy[0] != imported[0].ID {
+		t.Fatalf("dependency not remapped: %+v", imported[1])
+	}
+	if got, ok := dstQueue.WhitelistExpiry(session); !ok || !got.Equal(until) {
+		t.Fatalf("whitelist not carried over: %v %v", got, ok)
+	}
+	if _, err := dst.ImportHandoff(context.Background(), &wire); !errors.Is(err, ErrSessionExists) {
+		t.Fatalf("expected ErrSessionExists, got %v", err)
+	}
+	if id, err := dst.ImportHandoff(context.Background(), &wire, ImportAs("eng-1b")); err != nil || id != "eng-1b" {
+		t.Fatalf("ImportAs = %q, %v", id, err)
+	}
+}
+
+func TestHandoffSignatureChecks(t *testing.T) {
+	_, keyA, _ := ed25519.GenerateKey(nil)
+	_, keyB, _ := ed25519.GenerateKey(nil)
+	src, _ := newHandoffRuntime(t, keyA)
+	other, _ := newHandoffRuntime(t, keyB)
+
+	if _, err := src.Run(context.Background(), Request{Prompt: "hi", SessionID: "s"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	signed, err := src.Handoff(context.Background(), "s", HandoffSummary("done so far"), HandoffWithHistory())
+	if err != nil {
+		t.Fatalf("handoff: %v", err)
+	}
+	if _, err := other.ImportHandoff(context.Background(), signed); !errors.Is(err, ErrHandoffUntrusted) {
+		t.Fatalf("expected ErrHandoffUntrusted, got %v", err)
+	}
+	tampered := *signed
+	tampered.Bundle = []byte(strings.Replace(string(signed.Bundle), "done so far", "rm -rf", 1))
+	if _, err := src.ImportHandoff(context.Background(), &tampered, ImportAs("t")); !errors.Is(err, ErrHandoffSignature) {
+		t.Fatalf("expected ErrHandoffSignature, got %v", err)
+	}
+	id, err := src.ImportHandoff(context.Background(), signed, ImportAs("s-copy"))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if msgs := src.histories.Get(id).All(); len(msgs) != 2 || msgs[0].Content != "hi" {
+		t.Fatalf("history not restored: %+v", msgs)
+	}
+	if tags := src.sessionTags.snapshot()[id]; tags[HandoffFromTag] != "s" {
+		t.Fatalf("handoff tag missing: %v", tags)
+	}
+
+	if _, err := src.Handoff(context.Background(), "missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+	unsigned, _ := newHandoffRuntime(t, nil)
+	if _, err := unsigned.Handoff(context.Background(), "s"); !errors.Is(err, ErrHandoffKeyMissing) {
+		t.Fatalf("expected ErrHandoffKeyMissing, got %v", err)
+	}
+}
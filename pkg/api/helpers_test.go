@@ -91,7 +91,7 @@ func TestEnforceSandboxHostIgnoresSTDIO(t *testing.T) {
 func TestRegisterMCPServersDeniesUnauthorizedHost(t *testing.T) {
 	registry := tool.NewRegistry()
 	mgr := sandbox.NewManager(nil, sandbox.NewDomainAllowList("allowed.example"), nil)
-	err := registerMCPServers(context.Background(), registry, mgr, []mcpServer{{Spec: "http://denied.example"}})
+	err := registerMCPServers(registry, mgr, []mcpServer{{Spec: "http://denied.example"}})
 	if err == nil {
 		t.Fatal("expected host denial error")
 	}
@@ -103,7 +103,7 @@ func TestRegisterMCPServersDeniesUnauthorizedHost(t *testing.T) {
 func TestRegisterMCPServersPropagatesRegistryErrors(t *testing.T) {
 	registry := tool.NewRegistry()
 	mgr := sandbox.NewManager(nil, sandbox.NewDomainAllowList(), nil)
-	err := registerMCPServers(context.Background(), registry, mgr, []mcpServer{{Spec: ""}})
+	err := registerMCPServers(registry, mgr, []mcpServer{{Spec: ""}})
 	if err == nil {
 		t.Fatal("expected registry error")
 	}
@@ -204,14 +204,14 @@ func TestProjectConfigFromSettings(t *testing.T) {
 func TestRegisterToolsUsesDefaultImplementations(t *testing.T) {
 	registry := tool.NewRegistry()
 	opts := Options{ProjectRoot: t.TempDir()}
-	if taskTool, err := registerTools(registry, opts, nil, nil, nil); err != nil {
+	if taskTool, err := registerTools(registry, opts, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 		_ = taskTool
 	} else if taskTool == nil {
 		t.Fatal("expected task tool to be registered")
 	}
 	tools := registry.List()
-	expected := []string{"Bash", "Read", "Write", "Edit", "WebFetch", "WebSearch", "BashOutput", "BashStatus", "KillTask", "TaskCreate", "TaskList", "TaskGet", "TaskUpdate", "AskUserQuestion", "Skill", "SlashCommand", "Grep", "Glob", "Task"}
+	expected := []string{"Bash", "Read", "Write", "Edit", "NotebookEdit", "WebFetch", "WebSearch", "BashOutput", "BashStatus", "KillTask", "TaskCreate", "TaskList", "TaskGet", "TaskUpdate", "AskUserQuestion", "Skill", "SlashCommand", "Grep", "Glob", "RunTests", "Lint", "DependencyAudit", "Blackboard", "Task"}
 	if len(tools) != len(expected) {
 		t.Fatalf("expected %d default tools, got %d", len(expected), len(tools))
 	}
@@ -233,7 +233,7 @@ func TestRegisterToolsRespectsEnabledWhitelist(t *testing.T) {
 	registry := tool.NewRegistry()
 	root := t.TempDir()
 	opts := Options{ProjectRoot: root, EnabledBuiltinTools: []string{"bash", "grep"}}
-	if taskTool, err := registerTools(registry, opts, nil, nil, nil); err != nil {
+	if taskTool, err := registerTools(registry, opts, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 	} else if taskTool != nil {
 		t.Fatalf("task tool should not be auto-registered when not whitelisted")
@@ -257,7 +257,7 @@ func TestRegisterToolsDisablesAllBuiltinsWhenEmptyWhitelist(t *testing.T) {
 	registry := tool.NewRegistry()
 	root := t.TempDir()
 	opts := Options{ProjectRoot: root, EnabledBuiltinTools: []string{}}
-	if _, err := registerTools(registry, opts, nil, nil, nil); err != nil {
+	if _, err := registerTools(registry, opts, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 	}
 	if got := len(registry.List()); got != 0 {
@@ -270,7 +270,7 @@ func TestRegisterToolsSkipsDuplicateNames(t *testing.T) {
 	root := t.TempDir()
 	dup := &namedTool{name: "Bash"}
 	opts := Options{ProjectRoot: root, CustomTools: []tool.Tool{dup}}
-	if _, err := registerTools(registry, opts, nil, nil, nil); err != nil {
+	if _, err := registerTools(registry, opts, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 	}
 	tools := registry.List()
@@ -286,7 +286,7 @@ func TestRegisterToolsSkipsDuplicateNames(t *testing.T) {
 func TestRegisterToolsWhitelistCaseInsensitive(t *testing.T) {
 	registry := tool.NewRegistry()
 	opts := Options{ProjectRoot: t.TempDir(), EnabledBuiltinTools: []string{"BASH", "GrEp", "FILE_READ"}}
-	if _, err := registerTools(registry, opts, nil, nil, nil); err != nil {
+	if _, err := registerTools(registry, opts, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 	}
 	seen := map[string]struct{}{}
@@ -306,7 +306,7 @@ func TestRegisterToolsWhitelistCaseInsensitive(t *testing.T) {
 func TestRegisterToolsIgnoresUnknownWhitelistEntries(t *testing.T) {
 	registry := tool.NewRegistry()
 	opts := Options{ProjectRoot: t.TempDir(), EnabledBuiltinTools: []string{"missing"}}
-	if _, err := registerTools(registry, opts, nil, nil, nil); err != nil {
+	if _, err := registerTools(registry, opts, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 	}
 	if got := len(registry.List()); got != 0 {
@@ -318,7 +318,7 @@ func TestRegisterToolsAppendsCustomTools(t *testing.T) {
 	registry := tool.NewRegistry()
 	custom := &namedTool{name: "custom"}
 	opts := Options{ProjectRoot: t.TempDir(), EnabledBuiltinTools: []string{}, CustomTools: []tool.Tool{nil, custom}}
-	if _, err := registerTools(registry, opts, nil, nil, nil); err != nil {
+	if _, err := registerTools(registry, opts, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 	}
 	tools := registry.List()
@@ -336,7 +336,7 @@ func TestRegisterToolsLegacyToolsOverride(t *testing.T) {
 		EnabledBuiltinTools: []string{"bash"},
 		CustomTools:         []tool.Tool{&namedTool{name: "custom"}},
 	}
-	if taskTool, err := registerTools(registry, opts, nil, nil, nil); err != nil {
+	if taskTool, err := registerTools(registry, opts, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 	} else if taskTool != nil {
 		t.Fatalf("task tool should not be auto-wired when legacy Tools provided")
@@ -350,7 +350,7 @@ func TestRegisterToolsLegacyToolsOverride(t *testing.T) {
 func TestRegisterToolsTaskNotAddedForCI(t *testing.T) {
 	registry := tool.NewRegistry()
 	opts := Options{ProjectRoot: t.TempDir(), EntryPoint: EntryPointCI}
-	if taskTool, err := registerTools(registry, opts, nil, nil, nil); err != nil {
+	if taskTool, err := registerTools(registry, opts, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 	} else if taskTool != nil {
 		t.Fatalf("task tool should not be attached in CI entrypoint")
@@ -362,15 +362,15 @@ func TestRegisterToolsTaskNotAddedForCI(t *testing.T) {
 	if _, ok := seen["Task"]; ok {
 		t.Fatal("Task tool should be absent in CI mode")
 	}
-	if len(seen) != 18 { // all built-ins except Task
-		t.Fatalf("expected 18 built-ins without Task, got %d", len(seen))
+	if len(seen) != 23 { // all built-ins except Task
+		t.Fatalf("expected 23 built-ins without Task, got %d", len(seen))
 	}
 }
 
 func TestRegisterToolsSkipsNilEntries(t *testing.T) {
 	registry := tool.NewRegistry()
 	opts := Options{ProjectRoot: t.TempDir(), Tools: []tool.Tool{nil, &namedTool{name: "echo"}}}
-	if taskTool, err := registerTools(registry, opts, nil, nil, nil); err != nil {
+	if taskTool, err := registerTools(registry, opts, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 		_ = taskTool
 	} else if taskTool != nil {
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,13 +16,25 @@ import (
 
 type diskHistoryPersister struct {
 	dir string
+	// codec compresses and enc encrypts new snapshots; files written without
+	// them remain readable.
+	codec Compressor
+	enc   Encryptor
 }
 
-type persistedHistory struct {
+// TranscriptVersion is the version of the SessionTranscript schema.
+const TranscriptVersion = 1
+
+// SessionTranscript is the persisted form of a session's history, written
+// to .claude/history/<session>.json.
+type SessionTranscript struct {
 	Version   int               `json:"version"`
 	SessionID string            `json:"session_id,omitempty"`
 	UpdatedAt time.Time         `json:"updated_at,omitempty"`
 	Messages  []message.Message `json:"messages,omitempty"`
+	// Values holds the session's persistent agent.Context values, as
+	// written by agent.Context.MarshalValues.
+	Values json.RawMessage `json:"values,omitempty"`
 }
 
 func newDiskHistoryPersister(projectRoot string) *diskHistoryPersister {
@@ -39,14 +52,14 @@ func (p *diskHistoryPersister) Load(sessionID string) ([]message.Message, error)
 	if path == "" {
 		return nil, nil
 	}
-	data, err := os.ReadFile(path)
+	data, err := p.readSnapshot(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("read history: %w", err)
 	}
-	var wrapper persistedHistory
+	var wrapper SessionTranscript
 	if err := json.Unmarshal(data, &wrapper); err == nil {
 		if wrapper.Version != 0 || wrapper.SessionID != "" || !wrapper.UpdatedAt.IsZero() || wrapper.Messages != nil {
 			return message.CloneMessages(wrapper.Messages), nil
@@ -59,7 +72,56 @@ func (p *diskHistoryPersister) Load(sessionID string) ([]message.Message, error)
 	return message.CloneMessages(msgs), nil
 }
 
+// LoadValues returns the context values stored with the session's
+// transcript, or nil when there are none.
+func (p *diskHistoryPersister) LoadValues(sessionID string) (json.RawMessage, error) {
+	path := p.filePath(sessionID)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := p.readSnapshot(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	var wrapper SessionTranscript
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		// Legacy snapshots are bare message arrays without values.
+		return nil, nil
+	}
+	return wrapper.Values, nil
+}
+
+// readSnapshot prefers the snapshot written with the configured codec and
+// falls back to other built-in codecs and to the plain JSON file written
+// before compression was enabled.
+func (p *diskHistoryPersister) readSnapshot(path string) ([]byte, error) {
+	var err error
+	for _, codec := range storedCodecs(p.codec) {
+		var data []byte
+		data, err = os.ReadFile(path + compressorExtension(codec))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if data, err = openEncrypted(data, p.enc); err != nil || codec == nil {
+			return data, err
+		}
+		return codec.Decompress(data)
+	}
+	return nil, err
+}
+
 func (p *diskHistoryPersister) Save(sessionID string, msgs []message.Message) error {
+	return p.SaveSession(sessionID, msgs, nil)
+}
+
+// SaveSession writes msgs together with the session's context values.
+func (p *diskHistoryPersister) SaveSession(sessionID string, msgs []message.Message, values json.RawMessage) error {
 	path := p.filePath(sessionID)
 	if path == "" {
 		return nil
@@ -67,16 +129,22 @@ func (p *diskHistoryPersister) Save(sessionID string, msgs []message.Message) er
 	if err := os.MkdirAll(p.dir, 0o700); err != nil {
 		return fmt.Errorf("mkdir history dir: %w", err)
 	}
-	payload := persistedHistory{
-		Version:   1,
+	payload := SessionTranscript{
+		Version:   TranscriptVersion,
 		SessionID: sessionID,
 		UpdatedAt: time.Now().UTC(),
 		Messages:  message.CloneMessages(msgs),
+		Values:    values,
 	}
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("encode history: %w", err)
 	}
+	if data, err = sealAtRest(data, p.codec, p.enc); err != nil {
+		return fmt.Errorf("seal history: %w", err)
+	}
+	base := path
+	path += compressorExtension(p.codec)
 
 	tmp, err := os.CreateTemp(p.dir, sanitizePathComponent(sessionID)+".*.tmp")
 	if err != nil {
@@ -101,6 +169,10 @@ func (p *diskHistoryPersister) Save(sessionID string, msgs []message.Message) er
 			return fmt.Errorf("rename history: %w", retry)
 		}
 	}
+	// The new snapshot supersedes copies written with another codec.
+	for _, codec := range storedCodecs(p.codec)[1:] {
+		_ = os.Remove(base + compressorExtension(codec))
+	}
 	return nil
 }
 
@@ -129,7 +201,7 @@ func (p *diskHistoryPersister) Cleanup(retainDays int) error {
 			continue
 		}
 		name := entry.Name()
-		if !strings.HasSuffix(strings.ToLower(name), ".json") {
+		if p.snapshotStem(name) == "" {
 			continue
 		}
 		info, err := entry.Info()
@@ -161,8 +233,25 @@ func (p *diskHistoryPersister) filePath(sessionID string) string {
 	return filepath.Join(dir, name+".json")
 }
 
+// refreshHistory reloads the session from Options.SessionStore so a run
+// continues from the latest history even when another replica wrote it. On
+// a load error the in-memory copy is used.
+func (rt *Runtime) refreshHistory(ctx context.Context, sessionID string, history *message.History) {
+	if rt.sessionStore == nil || history == nil {
+		return
+	}
+	msgs, err := rt.sessionStore.Load(ctx, sessionID)
+	if err != nil {
+		log.Printf("api: load session %q: %v", sessionID, err)
+		return
+	}
+	if len(msgs) > 0 {
+		history.Replace(msgs)
+	}
+}
+
 func (rt *Runtime) persistHistory(sessionID string, history *message.History) {
-	if rt == nil || rt.historyPersister == nil || history == nil {
+	if rt == nil || (rt.historyPersister == nil && rt.sessionStore == nil) || history == nil {
 		return
 	}
 	sessionID = strings.TrimSpace(sessionID)
@@ -173,7 +262,13 @@ func (rt *Runtime) persistHistory(sessionID string, history *message.History) {
 	if len(snapshot) == 0 {
 		return
 	}
-	if err := rt.historyPersister.Save(sessionID, snapshot); err != nil {
+	if rt.sessionStore != nil {
+		if err := rt.sessionStore.Save(context.Background(), sessionID, snapshot); err != nil {
+			log.Printf("api: persist session %q: %v", sessionID, err)
+		}
+		return
+	}
+	if err := rt.historyPersister.SaveSession(sessionID, snapshot, rt.sessionValuesFor(sessionID)); err != nil {
 		log.Printf("api: persist history %q: %v", sessionID, err)
 	}
 }
@@ -6,10 +6,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/cexll/agentsdk-go/pkg/agent"
 	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
 	corehooks "github.com/cexll/agentsdk-go/pkg/core/hooks"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
 )
 
 func TestPreToolUseAllowsInputModification(t *testing.T) {
@@ -188,3 +193,177 @@ func writeScript(t *testing.T, dir, name, content string) string {
 	}
 	return path
 }
+
+func TestRuntimeRunsSettingsHooks(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "session-end")
+	prompt := writeScript(t, dir, "prompt.sh", `#!/bin/sh
+if grep -q forbidden; then echo "prompt mentions a forbidden topic" >&2; exit 2; fi
+printf '{"hookSpecificOutput":{"additionalContext":"ticket ABC-1 is open"}}'
+`)
+	stop := writeScript(t, dir, "stop.sh", `#!/bin/sh
+if grep -q '"stop_hook_active":true'; then exit 0; fi
+echo "run the tests before finishing" >&2
+exit 2
+`)
+	entry := func(matcher, command string) string {
+		return fmt.Sprintf(`[{"matcher":%q,"hooks":[{"type":"command","command":%q}]}]`, matcher, command)
+	}
+	root := newClaudeProjectWithSettings(t, `{"hooks":{`+
+		`"SessionStart":`+entry("*", "echo 'project uses tabs'")+`,`+
+		`"UserPromptSubmit":`+entry("", prompt)+`,`+
+		`"PreToolUse":`+entry("echo", "echo 'echo is disabled here' >&2; exit 2")+`,`+
+		`"Stop":`+entry("", stop)+`,`+
+		`"SessionEnd":`+entry("*", "touch "+marker)+`}}`)
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "c1", Name: "echo", Arguments: map[string]any{"text": "hi"}}}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+		{Message: model.Message{Role: "assistant", Content: "tests pass"}},
+	}}
+	echo := &paramsTool{}
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl, Tools: []tool.Tool{echo}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	defer rt.Close()
+
+	resp, err := rt.Run(context.Background(), Request{Prompt: "fix the bug", SessionID: "hooks"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if resp.Result.Output != "tests pass" {
+		t.Fatalf("Stop hook should have sent the model back to work, got %q", resp.Result.Output)
+	}
+	if echo.params != nil {
+		t.Fatalf("PreToolUse exit 2 should block the tool, ran with %v", echo.params)
+	}
+	if len(mdl.requests) != 3 {
+		t.Fatalf("expected 3 model calls, got %d", len(mdl.requests))
+	}
+	first := mdl.requests[0].Messages[0].Content
+	for _, want := range []string{"fix the bug", "project uses tabs", "ticket ABC-1 is open"} {
+		if !strings.Contains(first, want) {
+			t.Fatalf("prompt %q missing %q", first, want)
+		}
+	}
+	if got := fmt.Sprint(mdl.requests[1].Messages); !strings.Contains(got, "echo is disabled here") {
+		t.Fatalf("blocked tool feedback missing: %s", got)
+	}
+	last := mdl.requests[2].Messages
+	if msg := last[len(last)-1]; msg.Role != "user" || msg.Content != "run the tests before finishing" {
+		t.Fatalf("Stop hook feedback not sent: %+v", msg)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("SessionEnd hook did not run: %v", err)
+	}
+
+	_, err = rt.Run(context.Background(), Request{Prompt: "tell me something forbidden", SessionID: "blocked"})
+	if !errors.Is(err, ErrPromptBlocked) || !strings.Contains(err.Error(), "forbidden topic") {
+		t.Fatalf("expected ErrPromptBlocked, got %v", err)
+	}
+	if len(mdl.requests) != 3 {
+		t.Fatal("blocked prompt must not reach the model")
+	}
+}
+
+func TestRuntimeRunsGoHooks(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{}`)
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "c1", Name: "echo", Arguments: map[string]any{"text": "hi"}}}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+		{Message: model.Message{Role: "assistant", Content: "tests pass"}},
+	}}
+	echo := &paramsTool{}
+	var post []string
+	hooks := Hooks{
+		PreToolUse: []PreToolUseFunc{func(_ context.Context, p coreevents.ToolUsePayload) (*corehooks.HookOutput, error) {
+			return &corehooks.HookOutput{HookSpecificOutput: &corehooks.HookSpecificOutput{
+				UpdatedInput: map[string]any{"text": "rewritten"},
+			}}, nil
+		}},
+		PostToolUse: []PostToolUseFunc{func(_ context.Context, p coreevents.ToolResultPayload) (*corehooks.HookOutput, error) {
+			post = append(post, p.Name)
+			return nil, nil
+		}},
+		Stop: []StopFunc{func(_ context.Context, p coreevents.StopPayload) (*corehooks.HookOutput, error) {
+			if p.StopHookActive {
+				return nil, nil
+			}
+			return nil, errors.New("run the tests before finishing")
+		}},
+	}
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl, Tools: []tool.Tool{echo}, Hooks: hooks})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	defer rt.Close()
+
+	resp, err := rt.Run(context.Background(), Request{Prompt: "fix the bug", SessionID: "go-hooks"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if resp.Result.Output != "tests pass" {
+		t.Fatalf("Stop callback should have sent the model back to work, got %q", resp.Result.Output)
+	}
+	if echo.params["text"] != "rewritten" {
+		t.Fatalf("PreToolUse callback should rewrite input, got %v", echo.params)
+	}
+	if len(post) != 1 || post[0] != "echo" {
+		t.Fatalf("PostToolUse callback calls = %v", post)
+	}
+	last := mdl.requests[2].Messages
+	if msg := last[len(last)-1]; msg.Role != "user" || msg.Content != "run the tests before finishing" {
+		t.Fatalf("Stop callback feedback not sent: %+v", msg)
+	}
+}
+
+func TestStopHookContinuationRunsThroughAgentLoop(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{}`)
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+		{Message: model.Message{Role: "assistant", Content: "tests pass"}},
+	}}
+	var beforeModel, afterModel []int
+	counter := middleware.Funcs{
+		Identifier: "counter",
+		OnBeforeModel: func(_ context.Context, st *middleware.State) error {
+			beforeModel = append(beforeModel, st.Iteration)
+			return nil
+		},
+		OnAfterModel: func(_ context.Context, st *middleware.State) error {
+			afterModel = append(afterModel, st.Iteration)
+			return nil
+		},
+	}
+	hooks := Hooks{Stop: []StopFunc{func(_ context.Context, p coreevents.StopPayload) (*corehooks.HookOutput, error) {
+		if p.StopHookActive {
+			return nil, nil
+		}
+		return nil, errors.New("run the tests before finishing")
+	}}}
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl, Hooks: hooks, Middleware: []middleware.Middleware{counter}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	defer rt.Close()
+
+	resp, err := rt.Run(context.Background(), Request{Prompt: "fix the bug", SessionID: "stop-loop"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if resp.Result.Output != "tests pass" {
+		t.Fatalf("Stop hook should have sent the model back to work, got %q", resp.Result.Output)
+	}
+	if fmt.Sprint(beforeModel) != "[0 1]" || fmt.Sprint(afterModel) != "[0 1]" {
+		t.Fatalf("each continuation should be its own iteration, before=%v after=%v", beforeModel, afterModel)
+	}
+
+	capped, err := New(context.Background(), Options{ProjectRoot: root, Model: &stubModel{}, Hooks: hooks, MaxIterations: 1})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	defer capped.Close()
+	if _, err := capped.Run(context.Background(), Request{Prompt: "fix the bug", SessionID: "stop-capped"}); !errors.Is(err, agent.ErrMaxIterations) {
+		t.Fatalf("continuations should count against MaxIterations, got %v", err)
+	}
+}
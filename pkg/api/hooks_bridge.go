@@ -1,7 +1,10 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"time"
 
@@ -22,6 +25,9 @@ func newHookExecutor(opts Options, recorder HookRecorder, settings *config.Setti
 	if len(opts.TypedHooks) > 0 {
 		exec.Register(opts.TypedHooks...)
 	}
+	if hooks := opts.Hooks.shellHooks(); len(hooks) > 0 {
+		exec.Register(hooks...)
+	}
 	if !hooksDisabled(settings) {
 		hooks := buildSettingsHooks(settings, opts.ProjectRoot)
 		if len(hooks) > 0 {
@@ -36,6 +42,98 @@ func hooksDisabled(settings *config.Settings) bool {
 	return settings != nil && settings.DisableAllHooks != nil && *settings.DisableAllHooks
 }
 
+// Typed in-process hook callbacks. Each returns the output a shell hook
+// would print on exit 0 (nil means allow); a returned error blocks like
+// exit code 2 and its text is the feedback.
+type (
+	PreToolUseFunc        func(context.Context, coreevents.ToolUsePayload) (*corehooks.HookOutput, error)
+	PostToolUseFunc       func(context.Context, coreevents.ToolResultPayload) (*corehooks.HookOutput, error)
+	UserPromptSubmitFunc  func(context.Context, coreevents.UserPromptPayload) (*corehooks.HookOutput, error)
+	StopFunc              func(context.Context, coreevents.StopPayload) (*corehooks.HookOutput, error)
+	SessionStartFunc      func(context.Context, coreevents.SessionStartPayload) (*corehooks.HookOutput, error)
+	SessionEndFunc        func(context.Context, coreevents.SessionEndPayload) (*corehooks.HookOutput, error)
+	PermissionRequestFunc func(context.Context, coreevents.PermissionRequestPayload) (*corehooks.HookOutput, error)
+	SubagentStartFunc     func(context.Context, coreevents.SubagentStartPayload) (*corehooks.HookOutput, error)
+	SubagentStopFunc      func(context.Context, coreevents.SubagentStopPayload) (*corehooks.HookOutput, error)
+	PreCompactFunc        func(context.Context, coreevents.PreCompactPayload) (*corehooks.HookOutput, error)
+	// DefinitionsReloadedFunc is notified after WatchDefinitions reloaded
+	// skills or subagents; its output is ignored.
+	DefinitionsReloadedFunc func(context.Context, coreevents.DefinitionsReloadedPayload) (*corehooks.HookOutput, error)
+)
+
+// Hooks registers Go callbacks that run in-process alongside shell hooks,
+// in registration order after TypedHooks and before settings hooks. They
+// are not affected by disableAllHooks.
+type Hooks struct {
+	PreToolUse          []PreToolUseFunc
+	PostToolUse         []PostToolUseFunc
+	UserPromptSubmit    []UserPromptSubmitFunc
+	Stop                []StopFunc
+	SessionStart        []SessionStartFunc
+	SessionEnd          []SessionEndFunc
+	PermissionRequest   []PermissionRequestFunc
+	SubagentStart       []SubagentStartFunc
+	SubagentStop        []SubagentStopFunc
+	PreCompact          []PreCompactFunc
+	DefinitionsReloaded []DefinitionsReloadedFunc
+}
+
+func (h Hooks) clone() Hooks {
+	return Hooks{
+		PreToolUse:          slices.Clone(h.PreToolUse),
+		PostToolUse:         slices.Clone(h.PostToolUse),
+		UserPromptSubmit:    slices.Clone(h.UserPromptSubmit),
+		Stop:                slices.Clone(h.Stop),
+		SessionStart:        slices.Clone(h.SessionStart),
+		SessionEnd:          slices.Clone(h.SessionEnd),
+		PermissionRequest:   slices.Clone(h.PermissionRequest),
+		SubagentStart:       slices.Clone(h.SubagentStart),
+		SubagentStop:        slices.Clone(h.SubagentStop),
+		PreCompact:          slices.Clone(h.PreCompact),
+		DefinitionsReloaded: slices.Clone(h.DefinitionsReloaded),
+	}
+}
+
+// shellHooks wraps the callbacks as executor hooks.
+func (h Hooks) shellHooks() []corehooks.ShellHook {
+	var hooks []corehooks.ShellHook
+	hooks = append(hooks, callbackHooks(coreevents.PreToolUse, h.PreToolUse)...)
+	hooks = append(hooks, callbackHooks(coreevents.PostToolUse, h.PostToolUse)...)
+	hooks = append(hooks, callbackHooks(coreevents.UserPromptSubmit, h.UserPromptSubmit)...)
+	hooks = append(hooks, callbackHooks(coreevents.Stop, h.Stop)...)
+	hooks = append(hooks, callbackHooks(coreevents.SessionStart, h.SessionStart)...)
+	hooks = append(hooks, callbackHooks(coreevents.SessionEnd, h.SessionEnd)...)
+	hooks = append(hooks, callbackHooks(coreevents.PermissionRequest, h.PermissionRequest)...)
+	hooks = append(hooks, callbackHooks(coreevents.SubagentStart, h.SubagentStart)...)
+	hooks = append(hooks, callbackHooks(coreevents.SubagentStop, h.SubagentStop)...)
+	hooks = append(hooks, callbackHooks(coreevents.PreCompact, h.PreCompact)...)
+	hooks = append(hooks, callbackHooks(coreevents.DefinitionsReloaded, h.DefinitionsReloaded)...)
+	return hooks
+}
+
+// callbackHooks binds typed callbacks to an event. Events whose payload is
+// not of the expected type are skipped.
+func callbackHooks[P any, F ~func(context.Context, P) (*corehooks.HookOutput, error)](event coreevents.EventType, fns []F) []corehooks.ShellHook {
+	var hooks []corehooks.ShellHook
+	for i, fn := range fns {
+		if fn == nil {
+			continue
+		}
+		hooks = append(hooks, corehooks.ShellHook{
+			Event: event,
+			Name:  fmt.Sprintf("go:%s:%d", event, i),
+			Callback: func(ctx context.Context, evt coreevents.Event) (*corehooks.HookOutput, error) {
+				payload, ok := evt.Payload.(P)
+				if !ok {
+					return nil, nil
+				}
+				return fn(ctx, payload)
+			},
+		})
+	}
+	return hooks
+}
+
 // buildSettingsHooks converts settings.Hooks config to ShellHook structs.
 func buildSettingsHooks(settings *config.Settings, projectRoot string) []corehooks.ShellHook {
 	if settings == nil || settings.Hooks == nil {
@@ -58,6 +156,14 @@ func buildSettingsHooks(settings *config.Settings, projectRoot string) []corehoo
 			if err != nil {
 				continue
 			}
+			name := "settings:" + prefix + ":" + normalizedMatcher
+			if len(entry.Paths) > 0 {
+				if sel, err = sel.WithPaths(entry.Paths...); err != nil {
+					log.Printf("hooks: skipping %s hook with paths %v: %v", prefix, entry.Paths, err)
+					continue
+				}
+				name += "(" + strings.Join(entry.Paths, ",") + ")"
+			}
 			for _, hookDef := range entry.Hooks {
 				switch hookDef.Type {
 				case "command", "":
@@ -74,7 +180,7 @@ func buildSettingsHooks(settings *config.Settings, projectRoot string) []corehoo
 						Selector:      sel,
 						Timeout:       timeout,
 						Env:           env,
-						Name:          "settings:" + prefix + ":" + normalizedMatcher,
+						Name:          name,
 						Async:         hookDef.Async,
 						Once:          hookDef.Once,
 						StatusMessage: hookDef.StatusMessage,
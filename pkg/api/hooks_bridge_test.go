@@ -77,3 +77,32 @@ func TestHooksDisabledFlag(t *testing.T) {
 		t.Fatal("expected hooks disabled")
 	}
 }
+
+func TestBuildSettingsHooksAppliesPathGlobs(t *testing.T) {
+	settings := &config.Settings{
+		Hooks: &config.HooksConfig{
+			PostToolUse: []config.HookMatcherEntry{{
+				Matcher: "Edit|Write",
+				Paths:   []string{"*.go"},
+				Hooks:   []config.HookDefinition{{Type: "command", Command: "gofmt -w"}},
+			}},
+		},
+	}
+	hooks := buildSettingsHooks(settings, "")
+	if len(hooks) != 1 {
+		t.Fatalf("expected 1 hook, got %d", len(hooks))
+	}
+	sel := hooks[0].Selector
+	match := func(tool, path string) bool {
+		return sel.Match(coreevents.Event{Type: coreevents.PostToolUse, Payload: coreevents.ToolResultPayload{
+			Name:   tool,
+			Params: map[string]any{"file_path": path},
+		}})
+	}
+	if !match("Edit", "/repo/main.go") || !match("Write", "pkg/x.go") {
+		t.Fatal("expected Edit/Write on .go files to match")
+	}
+	if match("Edit", "/repo/README.md") || match("Read", "/repo/main.go") {
+		t.Fatal("expected other tools and files not to match")
+	}
+}
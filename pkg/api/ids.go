@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// IDKind names what an ID generated by an IDGenerator identifies.
+type IDKind string
+
+const (
+	// IDRun identifies one Run, RunStream or CallTool invocation; it becomes
+	// Request.RequestID when the caller leaves that empty.
+	IDRun IDKind = "run"
+	// IDIteration identifies one model turn within a run.
+	IDIteration IDKind = "iteration"
+	// IDToolCall identifies a tool call the model returned without an ID,
+	// or one made through CallTool.
+	IDToolCall IDKind = "tool_call"
+	// IDEvent identifies one StreamEvent.
+	IDEvent IDKind = "event"
+)
+
+// IDGenerator mints the IDs the runtime stamps on stream events, history,
+// audit records and traces. Implementations must be safe for concurrent use
+// and should return IDs unique within their kind.
+type IDGenerator interface {
+	NewID(kind IDKind) string
+}
+
+// IDGeneratorFunc adapts a function to IDGenerator.
+type IDGeneratorFunc func(kind IDKind) string
+
+// NewID implements IDGenerator.
+func (f IDGeneratorFunc) NewID(kind IDKind) string { return f(kind) }
+
+// UUIDGenerator is the default IDGenerator: a random UUID for every kind.
+type UUIDGenerator struct{}
+
+// NewID implements IDGenerator.
+func (UUIDGenerator) NewID(IDKind) string { return uuid.New().String() }
+
+// idGenerator returns the configured generator or the UUID default.
+func (rt *Runtime) idGenerator() IDGenerator {
+	if rt != nil && rt.opts.IDGenerator != nil {
+		return rt.opts.IDGenerator
+	}
+	return UUIDGenerator{}
+}
+
+// newID mints an ID, falling back to a UUID when the generator returns an
+// empty one.
+func newID(gen IDGenerator, kind IDKind) string {
+	if gen != nil {
+		if id := strings.TrimSpace(gen.NewID(kind)); id != "" {
+			return id
+		}
+	}
+	return uuid.New().String()
+}
+
+// runIDs hands out the IDs of one run. Iteration IDs are minted once per
+// iteration so stream events, audit records and tool results of the same
+// turn agree.
+type runIDs struct {
+	gen IDGenerator
+	run string
+
+	mu         sync.Mutex
+	iterations map[int]string
+}
+
+func newRunIDs(gen IDGenerator, run string) *runIDs {
+	return &runIDs{gen: gen, run: run, iterations: map[int]string{}}
+}
+
+// iteration returns the ID of the given iteration, minting it on first use.
+func (r *runIDs) iteration(n int) string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, ok := r.iterations[n]
+	if !ok {
+		id = newID(r.gen, IDIteration)
+		r.iterations[n] = id
+	}
+	return id
+}
+
+// toolCall returns id, or a new tool-call ID when id is empty.
+func (r *runIDs) toolCall(id string) string {
+	if id != "" || r == nil {
+		return id
+	}
+	return newID(r.gen, IDToolCall)
+}
+
+type runIDsCtxKey struct{}
+
+// withRunIDs lets prepare reuse the IDs a stream relay already stamps with.
+func withRunIDs(ctx context.Context, ids *runIDs) context.Context {
+	return context.WithValue(ctx, runIDsCtxKey{}, ids)
+}
+
+// runIDsFor returns the IDs on ctx when they belong to run, otherwise new
+// ones; nested runs inherit ctx but are runs of their own.
+func runIDsFor(ctx context.Context, gen IDGenerator, run string) *runIDs {
+	if ids, ok := ctx.Value(runIDsCtxKey{}).(*runIDs); ok && ids != nil && ids.run == run {
+		return ids
+	}
+	return newRunIDs(gen, run)
+}
+
+// eventStamper stamps the events of one stream with the run ID, a fresh
+// event ID and the ID of the iteration they belong to. It is used from a
+// single goroutine.
+type eventStamper struct {
+	ids     *runIDs
+	current string
+}
+
+func (s *eventStamper) stamp(evt StreamEvent) StreamEvent {
+	if s == nil || s.ids == nil {
+		return evt
+	}
+	if evt.RunID == "" {
+		evt.RunID = s.ids.run
+	}
+	if evt.EventID == "" {
+		evt.EventID = newID(s.ids.gen, IDEvent)
+	}
+	if evt.Iteration != nil {
+		s.current = s.ids.iteration(*evt.Iteration)
+	}
+	if evt.IterationID == "" {
+		evt.IterationID = s.current
+	}
+	switch evt.Type {
+	case EventIterationStop, EventAgentStop, EventError:
+		s.current = ""
+	}
+	return evt
+}
+
+type callIDsCtxKey struct{}
+
+type callIDs struct {
+	toolUseID   string
+	iterationID string
+}
+
+// withCallIDs tags ctx with the tool call being executed so audit records
+// emitted beneath it can be correlated with the call.
+func withCallIDs(ctx context.Context, toolUseID, iterationID string) context.Context {
+	return context.WithValue(ctx, callIDsCtxKey{}, callIDs{toolUseID: toolUseID, iterationID: iterationID})
+}
+
+func callIDsFromContext(ctx context.Context) callIDs {
+	if ctx == nil {
+		return callIDs{}
+	}
+	ids, _ := ctx.Value(callIDsCtxKey{}).(callIDs)
+	return ids
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// sequentialIDs numbers IDs per kind: run-1, event-1, event-2...
+type sequentialIDs struct {
+	mu   sync.Mutex
+	next map[IDKind]int
+}
+
+func (s *sequentialIDs) NewID(kind IDKind) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next == nil {
+		s.next = map[IDKind]int{}
+	}
+	s.next[kind]++
+	return fmt.Sprintf("%s-%d", kind, s.next[kind])
+}
+
+func TestNewIDFallsBackToUUID(t *testing.T) {
+	empty := IDGeneratorFunc(func(IDKind) string { return " " })
+	if id := newID(empty, IDRun); len(id) != 36 {
+		t.Fatalf("expected uuid fallback, got %q", id)
+	}
+	if id := newID(nil, IDEvent); len(id) != 36 {
+		t.Fatalf("expected uuid for nil generator, got %q", id)
+	}
+	ids := newRunIDs(&sequentialIDs{}, "run")
+	if a, b := ids.iteration(0), ids.iteration(0); a != b || a != "iteration-1" {
+		t.Fatalf("iteration ids should be stable, got %q and %q", a, b)
+	}
+	if got := ids.toolCall("toolu_1"); got != "toolu_1" {
+		t.Fatalf("model tool call id should be kept, got %q", got)
+	}
+	var nilIDs *runIDs
+	if nilIDs.iteration(1) != "" || nilIDs.toolCall("") != "" {
+		t.Fatal("nil runIDs should mint nothing")
+	}
+}
+
+func TestRunStreamStampsCorrelationIDs(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{"permissions":{"deny":["echo"]},"sandbox":{"enabled":true}}`)
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{Name: "echo", Arguments: map[string]any{"text": "hi"}}}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	logger := &recordingAuditLogger{}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot: root,
+		Model:       mdl,
+		Tools:       []tool.Tool{&echoTool{}},
+		AuditLogger: logger,
+		IDGenerator: &sequentialIDs{},
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	events, err := rt.RunStream(context.Background(), Request{Prompt: "call tool", SessionID: "sess"})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	seen := map[string]bool{}
+	var toolStart *StreamEvent
+	for evt := range events {
+		if evt.RunID != "run-1" {
+			t.Fatalf("event %s has run id %q", evt.Type, evt.RunID)
+		}
+		if evt.EventID == "" || seen[evt.EventID] {
+			t.Fatalf("event %s has missing or duplicate id %q", evt.Type, evt.EventID)
+		}
+		seen[evt.EventID] = true
+		if evt.Type == EventToolExecutionStart {
+			evt := evt
+			toolStart = &evt
+		}
+	}
+	if toolStart == nil {
+		t.Fatal("expected tool_execution_start event")
+	}
+	if toolStart.ToolUseID != "tool_call-1" || toolStart.IterationID != "iteration-1" {
+		t.Fatalf("unexpected tool event ids %+v", toolStart)
+	}
+
+	msgs := rt.histories.Get("sess").All()
+	var historyID string
+	for _, msg := range msgs {
+		if msg.Role == "assistant" && len(msg.ToolCalls) > 0 {
+			historyID = msg.ToolCalls[0].ID
+		}
+	}
+	if historyID != toolStart.ToolUseID {
+		t.Fatalf("history tool call id %q does not match stream %q", historyID, toolStart.ToolUseID)
+	}
+
+	recs := logger.byKind(AuditPermission)
+	if len(recs) != 1 {
+		t.Fatalf("expected one permission record, got %+v", logger.records)
+	}
+	if recs[0].RequestID != "run-1" || recs[0].ToolUseID != toolStart.ToolUseID || recs[0].IterationID != toolStart.IterationID {
+		t.Fatalf("audit record not correlated: %+v", recs[0])
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/runtime/tasks"
+)
+
+// DefaultTaskLedgerPath is the conventional ledger location, relative to
+// the project root.
+const DefaultTaskLedgerPath = ".claude/task-ledger.json"
+
+// maxLedgerPromptTasks caps the open tasks listed in the system prompt.
+const maxLedgerPromptTasks = 20
+
+// openTaskLedger opens the configured ledger, or returns a fresh in-memory
+// store when none is configured.
+func openTaskLedger(opts Options) (*tasks.TaskStore, error) {
+	path := strings.TrimSpace(opts.TaskLedgerPath)
+	if path == "" {
+		return tasks.NewTaskStore(), nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(opts.ProjectRoot, path)
+	}
+	store, err := tasks.OpenLedger(path)
+	if err != nil {
+		return nil, fmt.Errorf("api: open task ledger: %w", err)
+	}
+	return store, nil
+}
+
+// Tasks returns the task store shared by the Task* tools. With
+// TaskLedgerPath set it is backed by the ledger file, so tasks created by
+// earlier sessions are visible here.
+func (rt *Runtime) Tasks() *tasks.TaskStore {
+	if rt == nil {
+		return nil
+	}
+	return rt.tasks
+}
+
+// taskLedgerPrompt appends the unfinished ledger tasks to systemPrompt so a
+// new session can resume them without re-deriving state. In-memory stores
+// leave the prompt unchanged.
+func (rt *Runtime) taskLedgerPrompt(systemPrompt string) string {
+	if rt == nil || rt.tasks == nil || rt.tasks.Path() == "" {
+		return systemPrompt
+	}
+	var open []*tasks.Task
+	for _, task := range rt.tasks.List() {
+		if task != nil && task.Status != tasks.TaskCompleted {
+			open = append(open, task)
+		}
+	}
+	if len(open) == 0 {
+		return systemPrompt
+	}
+	var b strings.Builder
+	b.WriteString("## Task Ledger\n\nUnfinished tasks from earlier sessions. Use TaskGet/TaskUpdate to resume them.\n")
+	for i, task := range open {
+		if i == maxLedgerPromptTasks {
+			fmt.Fprintf(&b, "- ... and %d more (TaskList)\n", len(open)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- [%s] %s %s", task.Status, task.ID, strings.TrimSpace(task.Subject))
+		if task.Session != "" {
+			fmt.Fprintf(&b, " (session=%s)", task.Session)
+		}
+		if len(task.BlockedBy) > 0 {
+			fmt.Fprintf(&b, " blockedBy=%s", strings.Join(task.BlockedBy, ","))
+		}
+		if len(task.Artifacts) > 0 {
+			fmt.Fprintf(&b, " artifacts=%s", strings.Join(task.Artifacts, ","))
+		}
+		b.WriteByte('\n')
+	}
+	section := strings.TrimRight(b.String(), "\n")
+	if strings.TrimSpace(systemPrompt) == "" {
+		return section
+	}
+	return systemPrompt + "\n\n" + section
+}
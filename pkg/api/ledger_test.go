@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/runtime/tasks"
+)
+
+// systemModel records the system prompt of each call.
+type systemModel struct {
+	mu      sync.Mutex
+	systems []string
+}
+
+func (m *systemModel) Complete(_ context.Context, req model.Request) (*model.Response, error) {
+	m.mu.Lock()
+	m.systems = append(m.systems, req.System)
+	m.mu.Unlock()
+	return &model.Response{Message: model.Message{Role: "assistant", Content: "ok"}}, nil
+}
+
+func (m *systemModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	resp, err := m.Complete(ctx, req)
+	if err != nil {
+		return err
+	}
+	return cb(model.StreamResult{Final: true, Response: resp})
+}
+
+func TestTaskLedgerSurvivesRestart(t *testing.T) {
+	root := newClaudeProject(t)
+	opts := Options{ProjectRoot: root, Model: &systemModel{}, TaskLedgerPath: DefaultTaskLedgerPath}
+
+	rt, err := New(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	store := rt.Tasks()
+	if !strings.HasPrefix(store.Path(), root) {
+		t.Fatalf("ledger not under project root: %q", store.Path())
+	}
+	open, err := store.Create("port parser", "", "porting parser")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	done, _ := store.Create("write spec", "", "writing spec")
+	completed := tasks.TaskCompleted
+	if _, err := store.Update(done.ID, tasks.TaskUpdate{Status: &completed}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	session := "day-1"
+	if _, err := store.Update(open.ID, tasks.TaskUpdate{Session: &session, AddArtifacts: []string{"parser/new.go"}}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	_ = rt.Close()
+
+	mdl := &systemModel{}
+	opts.Model = mdl
+	rt, err = New(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	if got := rt.Tasks().List(); len(got) != 2 || got[0].Session != "day-1" {
+		t.Fatalf("ledger not restored: %+v", got)
+	}
+	if _, err := rt.Run(context.Background(), Request{Prompt: "continue", SessionID: "day-2"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	system := mdl.systems[0]
+	if !strings.Contains(system, "## Task Ledger") || !strings.Contains(system, open.ID+" port parser (session=day-1) artifacts=parser/new.go") {
+		t.Fatalf("open task missing from system prompt:\n%s", system)
+	}
+	if strings.Contains(system, "write spec") {
+		t.Fatalf("completed task listed in system prompt:\n%s", system)
+	}
+}
+
+func TestTaskLedgerPromptInMemory(t *testing.T) {
+	rt := &Runtime{tasks: tasks.NewTaskStore()}
+	if _, err := rt.tasks.Create("a", "", "doing a"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if got := rt.taskLedgerPrompt("base"); got != "base" {
+		t.Fatalf("in-memory store changed prompt: %q", got)
+	}
+}
@@ -14,6 +14,9 @@ type mcpServer struct {
 	Headers        map[string]string
 	Env            map[string]string
 	TimeoutSeconds int
+	// Command and Args start a stdio server without re-splitting the spec.
+	Command string
+	Args    []string
 }
 
 // collectMCPServers merges explicit API inputs, settings.json entries, and
@@ -49,11 +52,16 @@ func collectMCPServers(settings *config.Settings, explicit []string) []mcpServer
 		for name, cfg := range settings.MCP.Servers {
 			// Convert MCPServerConfig to spec string
 			spec := ""
+			var (
+				command string
+				args    []string
+			)
 			switch cfg.Type {
 			case "http", "sse":
 				spec = cfg.URL
 			case "stdio":
 				spec = fmt.Sprintf("stdio://%s %s", cfg.Command, strings.Join(cfg.Args, " "))
+				command, args = strings.TrimSpace(cfg.Command), cfg.Args
 			default:
 				if cfg.URL != "" {
 					spec = cfg.URL
@@ -66,6 +74,8 @@ func collectMCPServers(settings *config.Settings, explicit []string) []mcpServer
 				Headers:        cfg.Headers,
 				Env:            cfg.Env,
 				TimeoutSeconds: cfg.TimeoutSeconds,
+				Command:        command,
+				Args:           args,
 			})
 		}
 	}
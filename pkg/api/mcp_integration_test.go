@@ -31,15 +31,22 @@ func TestRegisterMCPServersNotBlockedByBuiltinWhitelist(t *testing.T) {
 
 	reg := tool.NewRegistry()
 	// Builtins disabled; MCP should still attempt registration.
-	if _, err := registerTools(reg, Options{ProjectRoot: t.TempDir(), EnabledBuiltinTools: []string{}}, nil, nil, nil); err != nil {
+	if _, err := registerTools(reg, Options{ProjectRoot: t.TempDir(), EnabledBuiltinTools: []string{}}, nil, nil, nil, nil); err != nil {
 		t.Fatalf("register tools: %v", err)
 	}
 
-	err := registerMCPServers(context.Background(), reg, nil, []mcpServer{{Spec: "stdio://dummy"}})
-	if err == nil || !errors.Is(err, counter.err) {
-		t.Fatalf("expected dial error propagated, got %v", err)
+	if err := registerMCPServers(reg, nil, []mcpServer{{Spec: "stdio://dummy"}}); err != nil {
+		t.Fatalf("register MCP servers: %v", err)
 	}
+	if counter.calls != 0 {
+		t.Fatalf("expected lazy registration, got %d dials", counter.calls)
+	}
+	reg.ConnectMCP(context.Background())
 	if counter.calls != 1 {
 		t.Fatalf("expected MCP dial invoked once, got %d", counter.calls)
 	}
+	status := reg.MCPStatus(context.Background())
+	if len(status) != 1 || status[0].State != tool.MCPStateFailed || status[0].Error == "" {
+		t.Fatalf("expected failed server in status, got %+v", status)
+	}
 }
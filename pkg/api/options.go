@@ -2,9 +2,11 @@ package api
 
 import (
 	"context"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"io/fs"
+	"log"
 	"maps"
 	"os"
 	"path/filepath"
@@ -12,18 +14,23 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cexll/agentsdk-go/pkg/artifact"
 	"github.com/cexll/agentsdk-go/pkg/config"
 	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
 	corehooks "github.com/cexll/agentsdk-go/pkg/core/hooks"
 	coremw "github.com/cexll/agentsdk-go/pkg/core/middleware"
+	"github.com/cexll/agentsdk-go/pkg/flags"
 	"github.com/cexll/agentsdk-go/pkg/middleware"
 	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/prompts"
 	"github.com/cexll/agentsdk-go/pkg/runtime/commands"
 	"github.com/cexll/agentsdk-go/pkg/runtime/skills"
 	"github.com/cexll/agentsdk-go/pkg/runtime/subagents"
 	"github.com/cexll/agentsdk-go/pkg/sandbox"
 	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/session"
 	"github.com/cexll/agentsdk-go/pkg/tool"
+	"github.com/cexll/agentsdk-go/pkg/voice"
 )
 
 var (
@@ -32,6 +39,10 @@ var (
 	ErrRuntimeClosed           = errors.New("api: runtime is closed")
 	ErrToolUseDenied           = errors.New("api: tool use denied by hook")
 	ErrToolUseRequiresApproval = errors.New("api: tool use requires approval")
+	// ErrPromptBlocked is returned when a UserPromptSubmit hook rejects the
+	// prompt by exiting with code 2 or returning a "block" decision.
+	ErrPromptBlocked = errors.New("api: prompt blocked by hook")
+	ErrNonCompliant  = errors.New("api: settings violate the compliance baseline")
 )
 
 type EntryPoint string
@@ -103,15 +114,50 @@ type PermissionRequest struct {
 	ToolName   string
 	ToolParams map[string]any
 	SessionID  string
-	Rule       string
-	Target     string
-	Reason     string
-	Approval   *security.ApprovalRecord
+	// ToolUseID identifies the model's tool call; it matches the ToolUseID
+	// of the permission_request stream event.
+	ToolUseID string
+	Rule      string
+	Target    string
+	Reason    string
+	// SuggestedRule is a permission rule matching this call that a host
+	// can offer to "always allow"; see security.SuggestRule.
+	SuggestedRule string
+	// Protected is the protected-path pattern the call edits, and Owners
+	// the CODEOWNERS owners of Target; hosts can route approval to them.
+	Protected string
+	Owners    []string
+	Approval  *security.ApprovalRecord
 }
 
 // PermissionRequestHandler lets hosts synchronously allow/deny PermissionAsk decisions.
 type PermissionRequestHandler func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error)
 
+// PermissionResult answers a PermissionRequest.
+type PermissionResult struct {
+	// Decision is PermissionAllow or PermissionDeny; PermissionAsk (or the
+	// zero value) leaves the request pending.
+	Decision coreevents.PermissionDecisionType
+	// UpdatedInput, when Decision is PermissionAllow, replaces the tool
+	// input. It is validated against the tool schema before the call runs.
+	UpdatedInput map[string]any
+	// Message explains a denial; the model sees it in the tool error.
+	Message string
+	// Remember persists the decision as a permission rule in
+	// .claude/settings.local.json, so later calls it matches are decided
+	// without prompting. In future runs the rule is an ordinary settings
+	// rule, and an ask rule for the tool prompts again.
+	Remember bool
+	// Rule overrides the remembered rule; it defaults to the request's
+	// SuggestedRule.
+	Rule string
+}
+
+// PermissionHandler is invoked when a tool call requires approval. The
+// agent loop waits for it to return; RunStream emits permission_request
+// before calling it.
+type PermissionHandler func(context.Context, PermissionRequest) (PermissionResult, error)
+
 // SkillRegistration wires runtime skill definitions + handlers.
 type SkillRegistration struct {
 	Definition skills.Definition
@@ -124,7 +170,11 @@ type CommandRegistration struct {
 	Handler    commands.Handler
 }
 
-// SubagentRegistration wires runtime subagents into the dispatcher.
+// SubagentRegistration wires runtime subagents into the dispatcher. Handler
+// may be nil when Definition.SystemPrompt is set: the subagent is then
+// defined entirely in code, like a .claude/agents file, with its tools in
+// Definition.BaseContext.ToolWhitelist and its model alias (haiku, sonnet,
+// opus) in Definition.BaseContext.Model.
 type SubagentRegistration struct {
 	Definition subagents.Definition
 	Handler    subagents.Handler
@@ -158,6 +208,19 @@ type Options struct {
 	SettingsPath      string
 	SettingsOverrides *config.Settings
 	SettingsLoader    *config.SettingsLoader
+	// WarmState is a blob from Runtime.ExportWarmState. New takes the
+	// settings, system prompt and .claude skill/command/agent/prompt files
+	// from it instead of reading them from disk; files present on disk
+	// still win. A blob exported under a different SystemPrompt,
+	// SettingsPath, SettingsOverrides or EntryPoint fails with
+	// ErrWarmStateMismatch.
+	WarmState []byte
+	// ComplianceBaseline is the organisation baseline the effective
+	// settings are checked against at startup and by Runtime.Compliance.
+	// Violations are logged unless EnforceCompliance makes New fail with
+	// ErrNonCompliant.
+	ComplianceBaseline *config.CompliancePolicy
+	EnforceCompliance  bool
 
 	Model        model.Model
 	ModelFactory ModelFactory
@@ -176,6 +239,9 @@ type Options struct {
 	DefaultEnableCache bool
 
 	SystemPrompt string
+	// Templates registers request presets selectable via Request.Template.
+	// They take precedence over templates of the same name in settings.
+	Templates    map[string]RequestTemplate
 	RulesEnabled *bool // nil = 默认启用，false = 禁用
 
 	Middleware        []middleware.Middleware
@@ -184,6 +250,11 @@ type Options struct {
 	Timeout           time.Duration
 	TokenLimit        int
 	MaxSessions       int
+	// MaxTokens and MaxCostUSD budget the cumulative model usage of each
+	// run; see agent.Options. A run that reaches the budget ends with
+	// Result.StopReason "budget_exceeded".
+	MaxTokens  int
+	MaxCostUSD float64
 
 	Tools []tool.Tool
 
@@ -202,14 +273,25 @@ type Options struct {
 	// when Tools is empty. Ignored when Tools is non-empty (legacy override takes priority).
 	CustomTools []tool.Tool
 	MCPServers  []string
+	// MCPHealthInterval is how often connected MCP servers are pinged and
+	// failed ones retried. Zero uses tool.DefaultMCPHealthInterval; a
+	// negative value disables background checks.
+	MCPHealthInterval time.Duration
 
 	TypedHooks     []corehooks.ShellHook
 	HookMiddleware []coremw.Middleware
 	HookTimeout    time.Duration
+	// Hooks are typed Go callbacks with the same blocking and input
+	// rewriting semantics as shell hooks, without spawning a process.
+	Hooks Hooks
 
 	Skills    []SkillRegistration
 	Commands  []CommandRegistration
 	Subagents []SubagentRegistration
+	// WatchDefinitions watches .claude/skills and .claude/agents and reloads
+	// changed definitions into the running Runtime, publishing a
+	// DefinitionsReloaded event after each reload.
+	WatchDefinitions bool
 
 	Sandbox SandboxOptions
 
@@ -226,6 +308,19 @@ type Options struct {
 	// PermissionAllow continues tool execution; PermissionDeny rejects it; PermissionAsk
 	// leaves the request pending.
 	PermissionRequestHandler PermissionRequestHandler
+	// PermissionHandler answers approval prompts like PermissionRequestHandler
+	// and can also rewrite the tool input or explain a denial. It takes
+	// precedence when both are set.
+	PermissionHandler PermissionHandler
+	// SessionStore persists conversation history so a SessionID resumes
+	// after a restart (e.g. session.NewFileStore, session.NewSQLiteStore).
+	// Sessions idle longer than settings.cleanupPeriodDays are removed at
+	// startup. Nil keeps the built-in .claude/history snapshots, which are
+	// written only when cleanupPeriodDays is positive. Compression and
+	// Encryption apply to the store's payloads; New fails when they are set
+	// and the store does not implement session.Sealable.
+	SessionStore session.Store
+
 	// ApprovalQueue optionally persists permission decisions and supports session whitelists.
 	ApprovalQueue *security.ApprovalQueue
 	// ApprovalApprover labels approvals/denials stored in ApprovalQueue.
@@ -235,13 +330,142 @@ type Options struct {
 	// ApprovalWait blocks tool execution until a pending approval is resolved.
 	ApprovalWait bool
 
-	// AutoCompact enables automatic context compaction for long sessions.
+	// Compaction summarizes, truncates or strips older turns when the history
+	// approaches the context window. Middleware implementing
+	// CompactionObserver is notified of each compaction.
+	Compaction CompactConfig
+	// AutoCompact is used when Compaction is not enabled.
+	//
+	// Deprecated: use Compaction.
 	AutoCompact CompactConfig
 
+	// Compression compresses persisted session history, including
+	// SessionStore payloads, and compaction rollouts (e.g. NewZstdCompressor). Uncompressed files and files written
+	// with another built-in codec remain readable. Nil stores plain JSON.
+	Compression Compressor
+
+	// Encryption encrypts persisted session history, including SessionStore
+	// payloads, and compaction rollouts at rest (e.g. NewAESGCMEncryptor). Plaintext files written earlier
+	// remain readable; encrypted files cannot be read without it.
+	Encryption Encryptor
+
+	// ArtifactStore receives files tools emit via ToolResult.Artifacts. Stored
+	// artifacts are referenced by ID in tool metadata and Response.Artifacts
+	// and can be fetched with Runtime.Artifact. Nil keeps payloads inline.
+	ArtifactStore artifact.Store
+
+	// ToolRetry retries transient failures (timeouts, unreachable services,
+	// lock conflicts) of tools that implement tool.IdempotentTool before the
+	// model sees the error. Nil uses tool.DefaultRetryPolicy; MaxRetries 0
+	// disables retries. Attempts are reported in tool metadata.
+	ToolRetry *tool.RetryPolicy
+
+	// ToolOutputLimit bounds tool output before it reaches the model. Output
+	// over MaxBytes keeps its head and tail inline and the full text is saved
+	// under the tool-output directory, which the Read tool may open. A zero
+	// value keeps the defaults (64KiB, 8KiB head and tail, spill to file);
+	// otherwise the limit is used as is, with MaxBytes 0 meaning 64KiB.
+	ToolOutputLimit tool.OutputLimit
+	// ToolOutputLimits overrides ToolOutputLimit per tool name
+	// (case-insensitive).
+	ToolOutputLimits map[string]tool.OutputLimit
+
+	// ScratchDir is the root of the per-run scratch directories handed to
+	// tools (see tool.ScratchFromContext). Each run gets
+	// <ScratchDir>/<session>/<request>, each iteration an iter-<n> directory
+	// below it; bash sees them as AGENTSDK_SCRATCH_DIR, AGENTSDK_RUN_SCRATCH_DIR
+	// and TMPDIR, and prompt templates as {{.scratch_dir}}. Defaults to
+	// /tmp/agentsdk/scratch.
+	ScratchDir string
+	// ScratchRetention bounds how long directories of runs that did not
+	// clean up (crashes, killed processes) survive before the janitor
+	// removes them. Defaults to 24h.
+	ScratchRetention time.Duration
+
+	// StreamReplayLimit caps the envelopes buffered per RunEvents run for
+	// ResumeStream; older ones are dropped first. Defaults to 10000.
+	StreamReplayLimit int
+	// StreamReplayRetention is how long a finished run stays resumable.
+	// Defaults to 5 minutes.
+	StreamReplayRetention time.Duration
+
+	// MaxConcurrentRuns caps the runs executing at once across all
+	// sessions; further Run and RunStream calls queue in arrival order
+	// after acquiring their session. 0 means unlimited.
+	MaxConcurrentRuns int
+	// RunQueueTimeout bounds the time a queued run waits for a slot before
+	// failing with ErrQueueFull. 0 waits until the request context ends.
+	RunQueueTimeout time.Duration
+
+	// Provenance stamps files written by Write and Edit with a trailing
+	// comment naming the model, run, timestamp and digest, sets
+	// Response.Provenance, and records both in ArtifactStore when one is
+	// configured. Nil disables provenance.
+	Provenance *ProvenanceOptions
+
+	// FileScan scans every file written by Write and Edit with a built-in
+	// secret scanner and any configured analyzers. Findings are reported in
+	// Response.Findings and, with FileScanOptions.Block, reject the write.
+	// Nil disables scanning.
+	FileScan *FileScanOptions
+
+	// ContentFilter decides what happens when the provider stops a response
+	// with a safety or content-filter reason: report it in Result (the
+	// default), abort the run, or ask the model once to rephrase.
+	ContentFilter ContentFilterPolicy
+
+	// IDGenerator mints run, iteration, tool-call and stream event IDs.
+	// The same IDs appear on StreamEvent, the session history, AuditRecord
+	// and the agent trace span. Nil uses random UUIDs.
+	IDGenerator IDGenerator
+
+	// RateLimiter tracks provider rate-limit headers per API key and paces
+	// model calls of concurrent runs as quotas approach exhaustion, delaying
+	// iterations instead of failing them. Nil gives the runtime its own;
+	// share one between runtimes that use the same keys.
+	RateLimiter *model.RateLimiter
+
+	// HandoffKey signs the bundles produced by Runtime.Handoff.
+	HandoffKey ed25519.PrivateKey
+	// HandoffTrustedKeys are the signers whose bundles ImportHandoff
+	// accepts, in addition to the public half of HandoffKey.
+	HandoffTrustedKeys []ed25519.PublicKey
+
+	// TaskLedgerPath persists the task store as a project-scoped ledger so
+	// tasks, their owning sessions and artifacts survive restarts. Relative
+	// paths resolve against ProjectRoot. Empty keeps tasks in memory.
+	TaskLedgerPath string
+
 	// OTEL configures OpenTelemetry distributed tracing.
 	// Requires build tag 'otel' for actual instrumentation; otherwise no-op.
 	OTEL OTELConfig
 
+	// AuditLogger receives structured records for permission evaluations,
+	// hook executions and sandbox violations. When nil, NewAuditLogger(OTEL)
+	// is used, which emits OTEL log records only with build tag 'otel'.
+	AuditLogger AuditLogger
+
+	// SpeechToText transcribes Request.Audio before a run.
+	SpeechToText voice.SpeechToText
+	// TextToSpeech synthesizes replies for requests with Speak set.
+	TextToSpeech voice.TextToSpeech
+
+	// Flags is consulted at run start to gate tools ("tool.<name>"),
+	// middleware ("middleware.<name>") and the model tier ("model") per
+	// tenant or session. The evaluation target is bound to the run context
+	// for flags.Enabled / flags.Value.
+	Flags flags.Provider
+
+	// Prompts resolves Request.PromptTemplate and
+	// RequestTemplate.SystemPromptRef. When nil, templates are loaded from
+	// .claude/prompts (project root first, then EmbedFS).
+	Prompts *prompts.Library
+
+	// Experiments assigns each run a variant per experiment, recorded in
+	// Response.Tags under ExperimentTag(name) and aggregated by
+	// Runtime.ExperimentReport.
+	Experiments []Experiment
+
 	fsLayer *config.FS
 }
 
@@ -270,6 +494,37 @@ type Request struct {
 	TargetSubagent    string
 	ToolWhitelist     []string
 	ForceSkills       []string
+	// Template selects a preset from Options.Templates or settings templates.
+	Template string
+	// PromptTemplate references a prompt template ("name" for the latest
+	// version, "name@version" to pin one) rendered with PromptVars. The
+	// result becomes the prompt; a non-empty Prompt is appended after it.
+	PromptTemplate string
+	// PromptVars are the variables for PromptTemplate and for the selected
+	// template's SystemPromptRef.
+	PromptVars map[string]any
+	// AttachmentIDs references uploaded artifacts (see Runtime.UploadHandler)
+	// that are added to the prompt as content blocks.
+	AttachmentIDs []string
+	// Attachments are images, PDFs and text files sent with the prompt as
+	// content blocks, ahead of those resolved from AttachmentIDs.
+	Attachments []Attachment
+	// Audio is spoken input transcribed with Options.SpeechToText; the
+	// transcript is appended to Prompt.
+	Audio *voice.Audio
+	// Speak synthesizes the reply with Options.TextToSpeech: Response.Audio
+	// for Run, audio_delta events for RunStream.
+	Speak bool
+	// Traceparent and Tracestate are the caller's W3C trace context. When
+	// empty the run joins the trace on ctx (see tracecontext.Handler) or
+	// starts a new one; model and MCP HTTP calls carry it downstream.
+	Traceparent string `json:"traceparent,omitempty"`
+	Tracestate  string `json:"tracestate,omitempty"`
+	// WorkDir is the file or directory the request is about, absolute or
+	// relative to ProjectRoot. In a monorepo it selects the sub-project that
+	// scopes the bash working directory and extra CLAUDE.md memory; see
+	// Runtime.Workspace.
+	WorkDir string `json:"work_dir,omitempty"`
 }
 
 // Response aggregates the final agent result together with metadata emitted
@@ -277,6 +532,7 @@ type Request struct {
 type Response struct {
 	Mode           ModeContext
 	RequestID      string `json:"request_id,omitempty"` // UUID for distributed tracing
+	TraceID        string `json:"trace_id,omitempty"`   // W3C trace ID the run joined
 	Result         *Result
 	SkillResults   []SkillExecution
 	CommandResults []CommandExecution
@@ -287,6 +543,20 @@ type Response struct {
 	Settings        *config.Settings
 	SandboxSnapshot SandboxReport
 	Tags            map[string]string
+	// Artifacts lists the artifacts emitted by tools during the run.
+	Artifacts []tool.Artifact
+	// Outputs holds the result rendered for each Request.Channels entry that
+	// has a registered OutputAdapter, keyed by channel.
+	Outputs map[string]ChannelOutput
+	// Transcript is the Request.Audio transcription, if any.
+	Transcript string
+	// Audio is the synthesized reply when Request.Speak was set.
+	Audio *voice.Audio
+	// Provenance attributes Result.Output when Options.Provenance is set.
+	Provenance *artifact.Provenance
+	// Findings lists what Options.FileScan reported on files written during
+	// the run, including blocked writes.
+	Findings []security.Finding
 }
 
 // Result represents the agent execution result.
@@ -295,6 +565,9 @@ type Result struct {
 	StopReason string
 	Usage      model.Usage
 	ToolCalls  []model.ToolCall
+	// ContentFilter is the provider's category ("refusal", "safety"...)
+	// when StopReason is model.StopReasonContentFilter.
+	ContentFilter string
 }
 
 // SkillExecution records individual skill invocations.
@@ -317,6 +590,12 @@ type SandboxReport struct {
 	AllowedPaths   []string
 	AllowedDomains []string
 	ResourceLimits sandbox.ResourceLimits
+	// OS reports the OS or container isolation of Bash commands; Backend is
+	// empty when settings.sandbox.enabled is not true.
+	OS sandbox.OSStatus
+	// Egress lists the egress proxy addresses and every request it allowed
+	// or blocked (the most recent 1000).
+	Egress sandbox.EgressStatus
 }
 
 // WithMaxSessions caps how many parallel session histories are retained.
@@ -348,13 +627,184 @@ func WithTokenCallback(fn TokenCallback) func(*Options) {
 	}
 }
 
+// WithPermissionHandler sets the callback that answers tool approval
+// prompts.
+func WithPermissionHandler(fn PermissionHandler) func(*Options) {
+	return func(o *Options) {
+		o.PermissionHandler = fn
+	}
+}
+
+// WithCompaction configures context window compaction.
+func WithCompaction(config CompactConfig) func(*Options) {
+	return func(o *Options) {
+		o.Compaction = config
+	}
+}
+
 // WithAutoCompact configures automatic context compaction.
+//
+// Deprecated: use WithCompaction.
 func WithAutoCompact(config CompactConfig) func(*Options) {
 	return func(o *Options) {
 		o.AutoCompact = config
 	}
 }
 
+// WithCompression enables compression of persisted session data.
+func WithCompression(c Compressor) func(*Options) {
+	return func(o *Options) {
+		o.Compression = c
+	}
+}
+
+// WithEncryption enables at-rest encryption of persisted session data.
+func WithEncryption(e Encryptor) func(*Options) {
+	return func(o *Options) {
+		o.Encryption = e
+	}
+}
+
+// WithTemplates registers request presets selectable via Request.Template.
+func WithTemplates(templates map[string]RequestTemplate) func(*Options) {
+	return func(o *Options) {
+		o.Templates = templates
+	}
+}
+
+// WithExperiments registers prompt/model experiments; see Options.Experiments.
+func WithExperiments(experiments ...Experiment) func(*Options) {
+	return func(o *Options) {
+		o.Experiments = append(o.Experiments, experiments...)
+	}
+}
+
+// WithArtifactStore stores tool-produced artifacts in store.
+func WithArtifactStore(store artifact.Store) func(*Options) {
+	return func(o *Options) {
+		o.ArtifactStore = store
+	}
+}
+
+// WithToolRetry sets the retry policy for idempotent tools; see
+// Options.ToolRetry.
+func WithToolRetry(policy tool.RetryPolicy) func(*Options) {
+	return func(o *Options) {
+		o.ToolRetry = &policy
+	}
+}
+
+// WithToolOutputLimit sets the default tool output limit; see
+// Options.ToolOutputLimit.
+func WithToolOutputLimit(limit tool.OutputLimit) func(*Options) {
+	return func(o *Options) {
+		o.ToolOutputLimit = limit
+	}
+}
+
+// WithToolOutputLimitFor sets the output limit of the named tool; see
+// Options.ToolOutputLimits.
+func WithToolOutputLimitFor(name string, limit tool.OutputLimit) func(*Options) {
+	return func(o *Options) {
+		if o.ToolOutputLimits == nil {
+			o.ToolOutputLimits = map[string]tool.OutputLimit{}
+		}
+		o.ToolOutputLimits[name] = limit
+	}
+}
+
+// WithScratchDir roots run scratch directories at dir; see Options.ScratchDir.
+func WithScratchDir(dir string) func(*Options) {
+	return func(o *Options) {
+		o.ScratchDir = dir
+	}
+}
+
+// WithScratchRetention sets how long abandoned scratch directories are kept.
+func WithScratchRetention(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.ScratchRetention = d
+	}
+}
+
+// WithStreamReplayLimit caps the envelopes buffered per run for
+// ResumeStream.
+func WithStreamReplayLimit(n int) func(*Options) {
+	return func(o *Options) {
+		o.StreamReplayLimit = n
+	}
+}
+
+// WithStreamReplayRetention sets how long finished runs stay resumable.
+func WithStreamReplayRetention(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.StreamReplayRetention = d
+	}
+}
+
+// WithMaxConcurrentRuns caps the runs executing at once; timeout bounds the
+// wait for a slot (0 waits until the request context ends).
+func WithMaxConcurrentRuns(n int, timeout time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.MaxConcurrentRuns = n
+		o.RunQueueTimeout = timeout
+	}
+}
+
+// WithHandoffKeys signs handoff bundles with key and accepts bundles signed
+// by key or any of trusted.
+func WithHandoffKeys(key ed25519.PrivateKey, trusted ...ed25519.PublicKey) func(*Options) {
+	return func(o *Options) {
+		o.HandoffKey = key
+		o.HandoffTrustedKeys = append(o.HandoffTrustedKeys, trusted...)
+	}
+}
+
+// WithProvenance stamps generated files and responses with provenance
+// metadata; see Options.Provenance.
+func WithProvenance(cfg ProvenanceOptions) func(*Options) {
+	return func(o *Options) {
+		o.Provenance = &cfg
+	}
+}
+
+// WithFileScan scans files written by Write and Edit; see Options.FileScan.
+func WithFileScan(cfg FileScanOptions) func(*Options) {
+	return func(o *Options) {
+		o.FileScan = &cfg
+	}
+}
+
+// WithContentFilter sets the content-filter policy; see Options.ContentFilter.
+func WithContentFilter(policy ContentFilterPolicy) func(*Options) {
+	return func(o *Options) {
+		o.ContentFilter = policy
+	}
+}
+
+// WithIDGenerator mints correlation IDs with gen; see Options.IDGenerator.
+func WithIDGenerator(gen IDGenerator) func(*Options) {
+	return func(o *Options) {
+		o.IDGenerator = gen
+	}
+}
+
+// WithRateLimiter shares rl between runtimes so calls on the same provider
+// key are paced together.
+func WithRateLimiter(rl *model.RateLimiter) func(*Options) {
+	return func(o *Options) {
+		o.RateLimiter = rl
+	}
+}
+
+// WithTaskLedger persists tasks to the ledger file at path; see
+// DefaultTaskLedgerPath.
+func WithTaskLedger(path string) func(*Options) {
+	return func(o *Options) {
+		o.TaskLedgerPath = path
+	}
+}
+
 // WithOTEL configures OpenTelemetry distributed tracing.
 // Requires build tag 'otel' for actual instrumentation; otherwise no-op.
 func WithOTEL(config OTELConfig) func(*Options) {
@@ -401,6 +851,20 @@ func (o Options) withDefaults() Options {
 		o.Sandbox.NetworkAllow = defaultNetworkAllowList(o.EntryPoint)
 	}
 
+	if strings.TrimSpace(o.ScratchDir) == "" {
+		o.ScratchDir = scratchBaseDir()
+	}
+	if o.ScratchRetention <= 0 {
+		o.ScratchRetention = defaultScratchRetention
+	}
+
+	if o.StreamReplayLimit <= 0 {
+		o.StreamReplayLimit = defaultStreamReplayLimit
+	}
+	if o.StreamReplayRetention <= 0 {
+		o.StreamReplayRetention = defaultStreamReplayRetention
+	}
+
 	if o.MaxSessions <= 0 {
 		o.MaxSessions = defaultMaxSessions
 	}
@@ -430,6 +894,15 @@ func (o Options) frozen() Options {
 	if len(o.MCPServers) > 0 {
 		o.MCPServers = append([]string(nil), o.MCPServers...)
 	}
+	if len(o.WarmState) > 0 {
+		o.WarmState = append([]byte(nil), o.WarmState...)
+	}
+	if len(o.ToolOutputLimits) > 0 {
+		o.ToolOutputLimits = maps.Clone(o.ToolOutputLimits)
+	}
+	if len(o.HandoffTrustedKeys) > 0 {
+		o.HandoffTrustedKeys = append([]ed25519.PublicKey(nil), o.HandoffTrustedKeys...)
+	}
 	if len(o.TypedHooks) > 0 {
 		hooks := make([]corehooks.ShellHook, len(o.TypedHooks))
 		for i, hook := range o.TypedHooks {
@@ -440,6 +913,7 @@ func (o Options) frozen() Options {
 		}
 		o.TypedHooks = hooks
 	}
+	o.Hooks = o.Hooks.clone()
 	if len(o.HookMiddleware) > 0 {
 		o.HookMiddleware = append([]coremw.Middleware(nil), o.HookMiddleware...)
 	}
@@ -483,6 +957,26 @@ func (o Options) frozen() Options {
 	if len(o.SubagentModelMapping) > 0 {
 		o.SubagentModelMapping = maps.Clone(o.SubagentModelMapping)
 	}
+	if len(o.Templates) > 0 {
+		templates := make(map[string]RequestTemplate, len(o.Templates))
+		for name, tpl := range o.Templates {
+			tpl.Tools = cloneStrings(tpl.Tools)
+			tpl.Tags = maps.Clone(tpl.Tags)
+			templates[name] = tpl
+		}
+		o.Templates = templates
+	}
+	if len(o.Experiments) > 0 {
+		exps := make([]Experiment, len(o.Experiments))
+		for i, exp := range o.Experiments {
+			exp.Variants = append([]Variant(nil), exp.Variants...)
+			for j := range exp.Variants {
+				exp.Variants[j].Tools = cloneStrings(exp.Variants[j].Tools)
+			}
+			exps[i] = exp
+		}
+		o.Experiments = exps
+	}
 
 	return o
 }
@@ -566,9 +1060,14 @@ func (r Request) normalized(defaultMode ModeContext, fallbackSession string) Req
 	if req.SessionID == "" {
 		req.SessionID = strings.TrimSpace(fallbackSession)
 	}
+	// Tags and Metadata are written during the run (templates, skills,
+	// commands); copy them so callers can share one map across requests.
+	req.Tags = maps.Clone(req.Tags)
 	if req.Tags == nil {
 		req.Tags = map[string]string{}
 	}
+	req.PromptVars = maps.Clone(req.PromptVars)
+	req.Metadata = maps.Clone(req.Metadata)
 	if req.Metadata == nil {
 		req.Metadata = map[string]any{}
 	}
@@ -578,6 +1077,12 @@ func (r Request) normalized(defaultMode ModeContext, fallbackSession string) Req
 	if len(req.ContentBlocks) > 0 {
 		req.ContentBlocks = append([]model.ContentBlock(nil), req.ContentBlocks...)
 	}
+	if len(req.AttachmentIDs) > 0 {
+		req.AttachmentIDs = cloneStrings(req.AttachmentIDs)
+	}
+	if len(req.Attachments) > 0 {
+		req.Attachments = append([]Attachment(nil), req.Attachments...)
+	}
 	if len(req.Channels) > 0 {
 		req.Channels = cloneStrings(req.Channels)
 	}
@@ -665,16 +1170,55 @@ func defaultHookRecorder() *hookRecorder {
 
 // runtimeHookAdapter wraps the hook executor and recorder.
 type runtimeHookAdapter struct {
-	executor *corehooks.Executor
-	recorder HookRecorder
+	executor  *corehooks.Executor
+	recorder  HookRecorder
+	audit     *auditEmitter
+	sessionID string
+}
+
+// maxStopHookContinuations bounds how often Stop hooks can send the agent
+// back to work within one run.
+const maxStopHookContinuations = 8
+
+// blockingFeedback returns the stderr of a hook that exited with code 2.
+func blockingFeedback(err error) (string, bool) {
+	var blocked *corehooks.BlockingError
+	if errors.As(err, &blocked) {
+		return blocked.Feedback(), true
+	}
+	return "", false
+}
+
+// hookContext collects the context hooks add on exit 0: plain stdout or
+// hookSpecificOutput.additionalContext.
+func hookContext(results []corehooks.Result) string {
+	var parts []string
+	for _, res := range results {
+		if res.Output == nil {
+			if text := strings.TrimSpace(res.Stdout); text != "" {
+				parts = append(parts, text)
+			}
+			continue
+		}
+		if hso := res.Output.HookSpecificOutput; hso != nil && strings.TrimSpace(hso.AdditionalContext) != "" {
+			parts = append(parts, strings.TrimSpace(hso.AdditionalContext))
+		}
+	}
+	return strings.Join(parts, "\n\n")
 }
 
 func (h *runtimeHookAdapter) PreToolUse(ctx context.Context, evt coreevents.ToolUsePayload) (map[string]any, error) {
 	if h == nil || h.executor == nil {
 		return evt.Params, nil
 	}
-	results, err := h.executor.Execute(ctx, coreevents.Event{Type: coreevents.PreToolUse, Payload: evt})
+	results, err := h.executor.Execute(ctx, coreevents.Event{Type: coreevents.PreToolUse, SessionID: h.sessionID, Payload: evt})
 	if err != nil {
+		if feedback, ok := blockingFeedback(err); ok {
+			// Exit 2 blocks the call; the model sees the hook's stderr.
+			h.audit.hook(ctx, coreevents.PreToolUse, evt.Name, "deny", feedback)
+			return nil, fmt.Errorf("%w: %s: %s", ErrToolUseDenied, evt.Name, feedback)
+		}
+		h.audit.hook(ctx, coreevents.PreToolUse, evt.Name, "error", err.Error())
 		return nil, err
 	}
 	h.record(coreevents.Event{Type: coreevents.PreToolUse, Payload: evt})
@@ -687,41 +1231,69 @@ func (h *runtimeHookAdapter) PreToolUse(ctx context.Context, evt coreevents.Tool
 	}
 
 	params := evt.Params
+	decision := "allow"
+	var decisionErr error
 	for _, res := range results {
 		if res.Output == nil {
 			continue
 		}
 		// Check top-level decision
 		if res.Output.Decision == "deny" {
-			return nil, fmt.Errorf("%w: %s", ErrToolUseDenied, evt.Name)
+			decision, decisionErr = "deny", fmt.Errorf("%w: %s", ErrToolUseDenied, evt.Name)
+			break
 		}
 		// Check continue=false
 		if res.Output.Continue != nil && !*res.Output.Continue {
-			return nil, fmt.Errorf("%w: %s", ErrToolUseDenied, evt.Name)
+			decision, decisionErr = "deny", fmt.Errorf("%w: %s", ErrToolUseDenied, evt.Name)
+			break
 		}
 		// Check hookSpecificOutput for PreToolUse
 		if hso := res.Output.HookSpecificOutput; hso != nil {
 			switch hso.PermissionDecision {
 			case "deny":
-				return nil, fmt.Errorf("%w: %s", ErrToolUseDenied, evt.Name)
+				decision, decisionErr = "deny", fmt.Errorf("%w: %s", ErrToolUseDenied, evt.Name)
 			case "ask":
-				return nil, fmt.Errorf("%w: %s", ErrToolUseRequiresApproval, evt.Name)
+				decision, decisionErr = "ask", fmt.Errorf("%w: %s", ErrToolUseRequiresApproval, evt.Name)
+			}
+			if decisionErr != nil {
+				break
 			}
 			if hso.UpdatedInput != nil {
 				params = hso.UpdatedInput
+				decision = "modified"
 			}
 		}
 	}
+	if len(results) > 0 {
+		h.audit.hook(ctx, coreevents.PreToolUse, evt.Name, decision, "")
+	}
+	if decisionErr != nil {
+		return nil, decisionErr
+	}
 	return params, nil
 }
 
 func (h *runtimeHookAdapter) PostToolUse(ctx context.Context, evt coreevents.ToolResultPayload) error {
+	_, err := h.postToolUse(ctx, evt)
+	return err
+}
+
+// postToolUse runs PostToolUse hooks and returns feedback for the model:
+// the stderr of a hook exiting with code 2 (returned together with its
+// *corehooks.BlockingError) or the reason of a "block" decision. The tool
+// has already run, so neither undoes it.
+func (h *runtimeHookAdapter) postToolUse(ctx context.Context, evt coreevents.ToolResultPayload) (string, error) {
 	if h == nil || h.executor == nil {
-		return nil
+		return "", nil
 	}
-	results, err := h.executor.Execute(ctx, coreevents.Event{Type: coreevents.PostToolUse, Payload: evt})
+	results, err := h.executor.Execute(ctx, coreevents.Event{Type: coreevents.PostToolUse, SessionID: h.sessionID, Payload: evt})
 	if err != nil {
-		return err
+		if feedback, ok := blockingFeedback(err); ok {
+			h.audit.hook(ctx, coreevents.PostToolUse, evt.Name, "block", feedback)
+			return feedback, err
+		}
+		h.audit.hook(ctx, coreevents.PostToolUse, evt.Name, "error", err.Error())
+		return "", err
 	}
 	h.record(coreevents.Event{Type: coreevents.PostToolUse, Payload: evt})
 
@@ -733,44 +1305,116 @@ func (h *runtimeHookAdapter) PostToolUse(ctx context.Context, evt coreevents.Too
 	}
 
 	// Check if any hook wants to stop
+	var feedback []string
 	for _, res := range results {
 		if res.Output != nil && res.Output.Continue != nil && !*res.Output.Continue {
-			return fmt.Errorf("hooks: PostToolUse hook requested stop: %s", res.Output.StopReason)
+			h.audit.hook(ctx, coreevents.PostToolUse, evt.Name, "deny", res.Output.StopReason)
+			return "", fmt.Errorf("hooks: PostToolUse hook requested stop: %s", res.Output.StopReason)
+		}
+		if res.Output != nil && res.Output.Decision == "block" && strings.TrimSpace(res.Output.Reason) != "" {
+			feedback = append(feedback, strings.TrimSpace(res.Output.Reason))
 		}
 	}
-	return nil
+	if len(feedback) > 0 {
+		h.audit.hook(ctx, coreevents.PostToolUse, evt.Name, "block", strings.Join(feedback, "; "))
+	} else if len(results) > 0 {
+		h.audit.hook(ctx, coreevents.PostToolUse, evt.Name, "allow", "")
+	}
+	return strings.Join(feedback, "\n\n"), nil
 }
 
 func (h *runtimeHookAdapter) UserPrompt(ctx context.Context, prompt string) error {
+	_, err := h.submitPrompt(ctx, prompt)
+	return err
+}
+
+// submitPrompt runs UserPromptSubmit hooks before the prompt reaches the
+// history and returns the context they add. Exit code 2, a "block"
+// decision or continue=false reject the prompt with ErrPromptBlocked.
+func (h *runtimeHookAdapter) submitPrompt(ctx context.Context, prompt string) (string, error) {
 	if h == nil || h.executor == nil {
-		return nil
+		return "", nil
 	}
-	payload := coreevents.UserPromptPayload{Prompt: prompt}
-	if err := h.executor.Publish(coreevents.Event{Type: coreevents.UserPromptSubmit, Payload: payload}); err != nil {
-		return err
+	evt := coreevents.Event{Type: coreevents.UserPromptSubmit, SessionID: h.sessionID, Payload: coreevents.UserPromptPayload{Prompt: prompt}}
+	results, err := h.executor.Execute(ctx, evt)
+	if err != nil {
+		if feedback, ok := blockingFeedback(err); ok {
+			h.audit.hook(ctx, coreevents.UserPromptSubmit, "", "deny", feedback)
+			return "", fmt.Errorf("%w: %s", ErrPromptBlocked, feedback)
+		}
+		return "", err
 	}
-	h.record(coreevents.Event{Type: coreevents.UserPromptSubmit, Payload: payload})
-	return nil
+	h.record(evt)
+	for _, res := range results {
+		if out := res.Output; out != nil && (out.Decision == "block" || (out.Continue != nil && !*out.Continue)) {
+			reason := strings.TrimSpace(out.Reason)
+			if reason == "" {
+				reason = strings.TrimSpace(out.StopReason)
+			}
+			h.audit.hook(ctx, coreevents.UserPromptSubmit, "", "deny", reason)
+			return "", fmt.Errorf("%w: %s", ErrPromptBlocked, reason)
+		}
+	}
+	return hookContext(results), nil
 }
 
 func (h *runtimeHookAdapter) Stop(ctx context.Context, reason string) error {
+	feedback, err := h.stop(ctx, coreevents.StopPayload{Reason: reason})
+	if err == nil && feedback != "" {
+		err = fmt.Errorf("hooks: Stop hook blocked: %s", feedback)
+	}
+	return err
+}
+
+// stop runs Stop hooks when the model has finished. A non-empty result
+// means a hook blocked the stop (exit code 2 or a "block" decision) and
+// the agent should continue with that feedback; continue=false always
+// lets it stop.
+func (h *runtimeHookAdapter) stop(ctx context.Context, payload coreevents.StopPayload) (string, error) {
 	if h == nil || h.executor == nil {
-		return nil
+		return "", nil
 	}
-	payload := coreevents.StopPayload{Reason: reason}
-	if err := h.executor.Publish(coreevents.Event{Type: coreevents.Stop, Payload: payload}); err != nil {
-		return err
+	evt := coreevents.Event{Type: coreevents.Stop, SessionID: h.sessionID, Payload: payload}
+	results, err := h.executor.Execute(ctx, evt)
+	if err != nil {
+		if feedback, ok := blockingFeedback(err); ok {
+			h.record(evt)
+			h.audit.hook(ctx, coreevents.Stop, "", "block", feedback)
+			return feedback, nil
+		}
+		return "", err
 	}
-	h.record(coreevents.Event{Type: coreevents.Stop, Payload: payload})
-	return nil
+	h.record(evt)
+	var feedback []string
+	for _, res := range results {
+		out := res.Output
+		if out == nil {
+			continue
+		}
+		if out.Continue != nil && !*out.Continue {
+			return "", nil
+		}
+		if out.Decision == "block" {
+			reason := strings.TrimSpace(out.Reason)
+			if reason == "" {
+				reason = "Stop hook asked to continue."
+			}
+			feedback = append(feedback, reason)
+		}
+	}
+	if len(feedback) > 0 {
+		h.audit.hook(ctx, coreevents.Stop, "", "block", strings.Join(feedback, "; "))
+	}
+	return strings.Join(feedback, "\n\n"), nil
 }
 
 func (h *runtimeHookAdapter) PermissionRequest(ctx context.Context, evt coreevents.PermissionRequestPayload) (coreevents.PermissionDecisionType, error) {
 	if h == nil || h.executor == nil {
 		return coreevents.PermissionAsk, nil
 	}
-	results, err := h.executor.Execute(ctx, coreevents.Event{Type: coreevents.PermissionRequest, Payload: evt})
+	results, err := h.executor.Execute(ctx, coreevents.Event{Type: coreevents.PermissionRequest, SessionID: h.sessionID, Payload: evt})
 	if err != nil {
+		h.audit.hook(ctx, coreevents.PermissionRequest, evt.ToolName, "error", err.Error())
 		return coreevents.PermissionAsk, err
 	}
 
@@ -795,6 +1439,7 @@ func (h *runtimeHookAdapter) PermissionRequest(ctx context.Context, evt coreeven
 			// keep current decision
 		}
 	}
+	h.audit.hook(ctx, coreevents.PermissionRequest, evt.ToolName, string(decision), evt.Reason)
 	h.record(coreevents.Event{Type: coreevents.PermissionRequest, Payload: evt})
 	return decision, nil
 }
@@ -810,6 +1455,23 @@ func (h *runtimeHookAdapter) SessionStart(ctx context.Context, evt coreevents.Se
 	return nil
 }
 
+// startSession runs SessionStart hooks for a new session and returns the
+// context they add. SessionStart cannot block: exit code 2 and other hook
+// failures are logged and the run goes ahead.
+func (h *runtimeHookAdapter) startSession(ctx context.Context, payload coreevents.SessionStartPayload) string {
+	if h == nil || h.executor == nil {
+		return ""
+	}
+	evt := coreevents.Event{Type: coreevents.SessionStart, SessionID: payload.SessionID, Payload: payload}
+	results, err := h.executor.Execute(ctx, evt)
+	if err != nil {
+		log.Printf("api: SessionStart hook failed: %v", err)
+		return ""
+	}
+	h.record(evt)
+	return hookContext(results)
+}
+
 func (h *runtimeHookAdapter) SessionEnd(ctx context.Context, evt coreevents.SessionPayload) error {
 	if h == nil || h.executor == nil {
 		return nil
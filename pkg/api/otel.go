@@ -5,12 +5,17 @@ package api
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/cexll/agentsdk-go/pkg/core/tracecontext"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
@@ -101,6 +106,30 @@ func (t *otelTracer) StartAgentSpan(sessionID, requestID string, iteration int)
 	return &otelSpan{ctx: ctx, span: span}
 }
 
+// startAgentSpanFrom starts agent.run as a child of the remote parent.
+func (t *otelTracer) startAgentSpanFrom(parent tracecontext.TraceContext, sessionID, requestID string) SpanContext {
+	ctx := context.Background()
+	traceID, errT := trace.TraceIDFromHex(parent.TraceID)
+	spanID, errS := trace.SpanIDFromHex(parent.ParentID)
+	if errT == nil && errS == nil {
+		state, _ := trace.ParseTraceState(parent.State) //nolint:errcheck // invalid tracestate is dropped
+		ctx = trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: trace.TraceFlags(parent.Flags),
+			TraceState: state,
+			Remote:     true,
+		}))
+	}
+	ctx, span := t.tracer.Start(ctx, "agent.run",
+		trace.WithAttributes(
+			attribute.String("agent.session_id", sessionID),
+			attribute.String("agent.request_id", requestID),
+		),
+	)
+	return &otelSpan{ctx: ctx, span: span}
+}
+
 func (t *otelTracer) StartModelSpan(parent SpanContext, modelName string) SpanContext {
 	parentCtx := context.Background()
 	if ps, ok := parent.(*otelSpan); ok && ps != nil {
@@ -217,3 +246,85 @@ type noopSpanReal struct{}
 func (s *noopSpanReal) TraceID() string   { return "" }
 func (s *noopSpanReal) SpanID() string    { return "" }
 func (s *noopSpanReal) IsRecording() bool { return false }
+
+// otelAuditLogger emits audit records through the global OTEL LoggerProvider.
+// Hosts configure the exporter (e.g. otlploghttp) via global.SetLoggerProvider.
+type otelAuditLogger struct {
+	logger otellog.Logger
+}
+
+// NewAuditLogger creates an OpenTelemetry-backed audit logger. Records are
+// dropped unless both Enabled and Logs are set.
+func NewAuditLogger(cfg OTELConfig) (AuditLogger, error) {
+	if !cfg.Enabled || !cfg.Logs {
+		return noopAuditLoggerReal{}, nil
+	}
+	name := cfg.ServiceName
+	if name == "" {
+		name = "agentsdk-go"
+	}
+	return &otelAuditLogger{logger: global.GetLoggerProvider().Logger(name)}, nil
+}
+
+func (l *otelAuditLogger) Emit(ctx context.Context, rec AuditRecord) {
+	if l == nil || l.logger == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ts := rec.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	var r otellog.Record
+	r.SetTimestamp(ts)
+	r.SetObservedTimestamp(time.Now())
+	r.SetEventName("agentsdk." + string(rec.Kind))
+	r.SetSeverity(auditSeverity(rec.Decision))
+	r.SetSeverityText(rec.Decision)
+	r.SetBody(otellog.StringValue(fmt.Sprintf("%s %s %s", rec.Kind, rec.Tool, rec.Decision)))
+	attrs := []otellog.KeyValue{
+		otellog.String("audit.kind", string(rec.Kind)),
+		otellog.String("audit.decision", rec.Decision),
+	}
+	for _, kv := range []struct{ key, val string }{
+		{"agent.session_id", rec.SessionID},
+		{"agent.request_id", rec.RequestID},
+		{"trace_id", rec.TraceID},
+		{"tool.use_id", rec.ToolUseID},
+		{"agent.iteration_id", rec.IterationID},
+		{"tool.name", rec.Tool},
+		{"hook.event", rec.Event},
+		{"permission.rule", rec.Rule},
+		{"permission.target", rec.Target},
+		{"permission.protected", rec.Protected},
+		{"permission.owners", strings.Join(rec.Owners, ",")},
+		{"audit.reason", rec.Reason},
+	} {
+		if kv.val != "" {
+			attrs = append(attrs, otellog.String(kv.key, kv.val))
+		}
+	}
+	r.AddAttributes(attrs...)
+	l.logger.Emit(ctx, r)
+}
+
+func (l *otelAuditLogger) Shutdown() error { return nil }
+
+func auditSeverity(decision string) otellog.Severity {
+	switch decision {
+	case "deny", "error", "abort":
+		return otellog.SeverityWarn
+	case "ask":
+		return otellog.SeverityInfo2
+	default:
+		return otellog.SeverityInfo
+	}
+}
+
+type noopAuditLoggerReal struct{}
+
+func (noopAuditLoggerReal) Emit(_ context.Context, _ AuditRecord) {}
+
+func (noopAuditLoggerReal) Shutdown() error { return nil }
@@ -1,5 +1,10 @@
 package api
 
+import (
+	"context"
+	"time"
+)
+
 // OTELConfig configures OpenTelemetry integration for the SDK.
 // When Enabled is true, spans are created for agent runs, model calls, and tool executions.
 // OTEL dependencies are optional and only loaded when using build tag 'otel'.
@@ -21,6 +26,10 @@ type OTELConfig struct {
 
 	// Insecure allows non-TLS connections to the endpoint.
 	Insecure bool `json:"insecure,omitempty"`
+
+	// Logs exports audit log records (permission decisions, hook executions,
+	// sandbox violations) through the global OpenTelemetry LoggerProvider.
+	Logs bool `json:"logs,omitempty"`
 }
 
 // DefaultOTELConfig returns sensible defaults for OTEL configuration.
@@ -61,3 +70,58 @@ type SpanContext interface {
 	// IsRecording returns true if the span is being recorded.
 	IsRecording() bool
 }
+
+// AuditKind classifies audit log records emitted by the runtime.
+type AuditKind string
+
+const (
+	// AuditPermission records the outcome of a tool permission evaluation.
+	AuditPermission AuditKind = "permission"
+	// AuditHook records the outcome of a hook execution.
+	AuditHook AuditKind = "hook"
+	// AuditSandbox records a sandbox policy violation.
+	AuditSandbox AuditKind = "sandbox"
+	// AuditContentFilter records a response stopped by the provider's
+	// content filter: Decision is the action taken (report, retry, abort)
+	// and Reason the provider's category.
+	AuditContentFilter AuditKind = "content_filter"
+)
+
+// AuditRecord is a structured log record for a security-relevant decision.
+// SessionID/RequestID correlate the record with the run; when the context
+// passed to Emit carries an active span, exporters attach its trace/span IDs.
+type AuditRecord struct {
+	Kind      AuditKind
+	Timestamp time.Time
+	SessionID string
+	RequestID string
+	// TraceID is the W3C trace ID of the run (see Response.TraceID).
+	TraceID string
+	// ToolUseID and IterationID identify the tool call and model turn the
+	// record was emitted under; they match the StreamEvent fields.
+	ToolUseID   string
+	IterationID string
+	Tool        string
+	// Event names the hook event (PreToolUse, PermissionRequest...) for hook records.
+	Event string
+	// Decision is the resulting action: allow, deny, ask, modified or error.
+	Decision string
+	Rule     string
+	Target   string
+	Reason   string
+	// Protected and Owners describe edits of protected paths: the matched
+	// pattern and the CODEOWNERS owners of Target.
+	Protected string
+	Owners    []string
+}
+
+// AuditLogger exports audit records. With the 'otel' build tag and
+// OTELConfig.Logs enabled, records are emitted as OpenTelemetry log records;
+// otherwise a noop implementation is used.
+type AuditLogger interface {
+	// Emit records a single audit entry. Implementations must not block.
+	Emit(ctx context.Context, rec AuditRecord)
+
+	// Shutdown flushes pending records.
+	Shutdown() error
+}
@@ -2,6 +2,8 @@
 
 package api
 
+import "context"
+
 // noopTracer provides a no-operation tracer when OTEL is not enabled.
 // This is the default implementation used without the 'otel' build tag.
 type noopTracer struct{}
@@ -35,3 +37,15 @@ type noopSpan struct{}
 func (s *noopSpan) TraceID() string   { return "" }
 func (s *noopSpan) SpanID() string    { return "" }
 func (s *noopSpan) IsRecording() bool { return false }
+
+// noopAuditLogger discards audit records when OTEL is not enabled.
+type noopAuditLogger struct{}
+
+// NewAuditLogger creates an audit logger. Without the otel build tag, returns a noop logger.
+func NewAuditLogger(_ OTELConfig) (AuditLogger, error) {
+	return noopAuditLogger{}, nil
+}
+
+func (noopAuditLogger) Emit(_ context.Context, _ AuditRecord) {}
+
+func (noopAuditLogger) Shutdown() error { return nil }
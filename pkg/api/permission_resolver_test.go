@@ -20,9 +20,9 @@ func TestBuildPermissionResolverHandlerAndApprovals(t *testing.T) {
 	if err != nil {
 		t.Fatalf("approval queue: %v", err)
 	}
-	resolver := buildPermissionResolver(nil, func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error) {
+	resolver := buildPermissionResolver(nil, legacyPermissionHandler(func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error) {
 		return coreevents.PermissionAllow, nil
-	}, queue, "tester", time.Hour, false)
+	}), queue, "tester", time.Hour, false)
 	if resolver == nil {
 		t.Fatalf("expected resolver")
 	}
@@ -46,9 +46,9 @@ func TestBuildPermissionResolverHandlerAndApprovals(t *testing.T) {
 }
 
 func TestBuildPermissionResolverHandlerUnknown(t *testing.T) {
-	resolver := buildPermissionResolver(nil, func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error) {
+	resolver := buildPermissionResolver(nil, legacyPermissionHandler(func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error) {
 		return coreevents.PermissionAsk, nil
-	}, nil, "", 0, false)
+	}), nil, "", 0, false)
 	decision := security.PermissionDecision{Action: security.PermissionAsk, Rule: "rule", Target: "target"}
 	res, err := resolver(context.Background(), tool.Call{Name: "Bash"}, decision)
 	if err != nil {
@@ -66,9 +66,9 @@ func TestBuildPermissionResolverWaitsForApproval(t *testing.T) {
 		t.Fatalf("approval queue: %v", err)
 	}
 
-	resolver := buildPermissionResolver(nil, func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error) {
+	resolver := buildPermissionResolver(nil, legacyPermissionHandler(func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error) {
 		return coreevents.PermissionAsk, nil
-	}, queue, "tester", 0, true)
+	}), queue, "tester", 0, true)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -40,9 +40,9 @@ func TestBuildPermissionResolverAllowDeny(t *testing.T) {
 		t.Fatalf("queue init failed: %v", err)
 	}
 
-	allowResolver := buildPermissionResolver(nil, func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error) {
+	allowResolver := buildPermissionResolver(nil, legacyPermissionHandler(func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error) {
 		return coreevents.PermissionAllow, nil
-	}, queue, "tester", time.Hour, false)
+	}), queue, "tester", time.Hour, false)
 
 	call := tool.Call{Name: "Bash", Params: map[string]any{"command": "ls"}, SessionID: "sess"}
 	decision := security.PermissionDecision{Action: security.PermissionAsk, Rule: "rule", Target: "ls"}
@@ -61,9 +61,9 @@ func TestBuildPermissionResolverAllowDeny(t *testing.T) {
 	if err != nil {
 		t.Fatalf("queue init failed: %v", err)
 	}
-	denyResolver := buildPermissionResolver(nil, func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error) {
+	denyResolver := buildPermissionResolver(nil, legacyPermissionHandler(func(context.Context, PermissionRequest) (coreevents.PermissionDecisionType, error) {
 		return coreevents.PermissionDeny, nil
-	}, queue2, "tester", 0, false)
+	}), queue2, "tester", 0, false)
 	denied, err := denyResolver(context.Background(), call, decision)
 	if err != nil {
 		t.Fatalf("resolver failed: %v", err)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/cexll/agentsdk-go/pkg/agent"
 	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
 )
 
 // streamEmitFunc is stored on context so tools can push incremental output
@@ -53,23 +54,28 @@ func (p *progressMiddleware) AfterModel(ctx context.Context, st *middleware.Stat
 		return nil
 	}
 
-	idx := 0
-	text := out.Content
-	p.textBlock(ctx, idx, text)
-	if text != "" {
-		idx++
-	}
+	// Content blocks were already streamed while the model generated them.
+	if live, _ := st.Values[liveStreamStateKey].(bool); live {
+		delete(st.Values, liveStreamStateKey)
+	} else {
+		idx := 0
+		text := out.Content
+		p.textBlock(ctx, idx, text)
+		if text != "" {
+			idx++
+		}
 
-	for _, call := range out.ToolCalls {
-		p.toolBlock(ctx, idx, call)
-		idx++
+		for _, call := range out.ToolCalls {
+			p.toolBlock(ctx, idx, call)
+			idx++
+		}
 	}
 
 	reason := "end_turn"
 	if len(out.ToolCalls) > 0 {
 		reason = "tool_use"
 	}
-	p.emit(ctx, StreamEvent{Type: EventMessageDelta, Delta: &Delta{StopReason: reason}, Usage: &Usage{}})
+	p.emit(ctx, StreamEvent{Type: EventMessageDelta, Delta: &Delta{StopReason: reason}, Usage: &Usage{InputTokens: out.Usage.InputTokens, OutputTokens: out.Usage.OutputTokens}})
 	p.emit(ctx, StreamEvent{Type: EventMessageStop})
 	return nil
 }
@@ -117,30 +123,184 @@ func (p *progressMiddleware) AfterAgent(ctx context.Context, st *middleware.Stat
 }
 
 func (p *progressMiddleware) textBlock(ctx context.Context, idx int, content string) {
+	emitTextBlock(ctx, p.emit, idx, content)
+}
+
+func (p *progressMiddleware) toolBlock(ctx context.Context, idx int, call agent.ToolCall) {
+	emitToolBlock(ctx, p.emit, idx, call.ID, call.Name, call.Input)
+}
+
+func emitTextBlock(ctx context.Context, emit streamEmitFunc, idx int, content string) {
 	if content == "" {
 		return
 	}
-	p.emit(ctx, StreamEvent{Type: EventContentBlockStart, Index: &idx, ContentBlock: &ContentBlock{Type: "text"}})
+	emit(ctx, StreamEvent{Type: EventContentBlockStart, Index: &idx, ContentBlock: &ContentBlock{Type: "text"}})
 	for _, r := range content {
-		p.emit(ctx, StreamEvent{Type: EventContentBlockDelta, Index: &idx, Delta: &Delta{Type: "text_delta", Text: string(r)}})
+		emit(ctx, StreamEvent{Type: EventContentBlockDelta, Index: &idx, Delta: &Delta{Type: "text_delta", Text: string(r)}})
 	}
-	p.emit(ctx, StreamEvent{Type: EventContentBlockStop, Index: &idx})
+	emit(ctx, StreamEvent{Type: EventContentBlockStop, Index: &idx})
 }
 
-func (p *progressMiddleware) toolBlock(ctx context.Context, idx int, call agent.ToolCall) {
-	p.emit(ctx, StreamEvent{Type: EventContentBlockStart, Index: &idx, ContentBlock: &ContentBlock{Type: "tool_use", ID: call.ID, Name: call.Name}})
-	raw, err := json.Marshal(call.Input)
+func emitToolBlock(ctx context.Context, emit streamEmitFunc, idx int, id, name string, input map[string]any) {
+	emit(ctx, StreamEvent{Type: EventContentBlockStart, Index: &idx, ContentBlock: &ContentBlock{Type: "tool_use", ID: id, Name: name}})
+	raw, err := json.Marshal(input)
 	if err != nil {
 		raw = []byte("{}")
 	}
 	for _, chunk := range chunkString(string(raw), 10) {
-		encoded, err := json.Marshal(chunk)
-		if err != nil {
-			encoded = []byte(`""`)
+		emit(ctx, inputJSONDelta(idx, id, name, chunk))
+	}
+	emit(ctx, StreamEvent{Type: EventContentBlockStop, Index: &idx})
+}
+
+// inputJSONDelta encodes a fragment of tool input the way Anthropic does: as
+// a JSON string whose contents concatenate to the arguments object.
+func inputJSONDelta(idx int, id, name, fragment string) StreamEvent {
+	encoded, err := json.Marshal(fragment)
+	if err != nil {
+		encoded = []byte(`""`)
+	}
+	return StreamEvent{Type: EventContentBlockDelta, Index: &idx, ToolUseID: id, Name: name, Delta: &Delta{Type: "input_json_delta", PartialJSON: json.RawMessage(encoded)}}
+}
+
+// liveStreamStateKey marks, in middleware.State.Values, an iteration whose
+// content blocks were streamed live so AfterModel does not replay them.
+const liveStreamStateKey = "progress.live"
+
+// liveBlocks turns provider stream callbacks into content block events as
+// they arrive: text_delta for text and input_json_delta for tool arguments,
+// each block opened and closed around its fragments.
+type liveBlocks struct {
+	ctx  context.Context
+	emit streamEmitFunc
+
+	next    int
+	open    int
+	hasOpen bool
+	text    bool
+	// tools maps the provider's call index to its block; order keeps the
+	// blocks in the order they started.
+	tools map[int]*liveTool
+	byID  map[string]*liveTool
+	order []*liveTool
+}
+
+type liveTool struct {
+	idx      int
+	id, name string
+	closed   bool
+}
+
+// newLiveBlocks returns nil when ctx carries no stream emitter (Run rather
+// than RunStream).
+func newLiveBlocks(ctx context.Context) *liveBlocks {
+	emit := streamEmitFromContext(ctx)
+	if emit == nil {
+		return nil
+	}
+	return &liveBlocks{ctx: ctx, emit: emit, tools: map[int]*liveTool{}, byID: map[string]*liveTool{}}
+}
+
+func (l *liveBlocks) handle(sr model.StreamResult) {
+	switch {
+	case sr.Delta != "":
+		l.textDelta(sr.Delta)
+	case sr.ToolCallDelta != nil:
+		l.toolDelta(*sr.ToolCallDelta)
+	case sr.ToolCall != nil:
+		if t := l.byID[sr.ToolCall.ID]; t != nil {
+			l.stop(t)
+		}
+	}
+}
+
+func (l *liveBlocks) textDelta(text string) {
+	if !l.hasOpen || l.openTool() != nil {
+		l.closeOpen()
+		l.start(ContentBlock{Type: "text"})
+	}
+	l.text = true
+	idx := l.open
+	l.emit(l.ctx, StreamEvent{Type: EventContentBlockDelta, Index: &idx, Delta: &Delta{Type: "text_delta", Text: text}})
+}
+
+func (l *liveBlocks) toolDelta(d model.ToolCallDelta) {
+	t := l.tools[d.Index]
+	if t == nil {
+		l.closeOpen()
+		t = &liveTool{id: d.ID, name: d.Name}
+		t.idx = l.start(ContentBlock{Type: "tool_use", ID: d.ID, Name: d.Name})
+		l.tools[d.Index] = t
+		l.order = append(l.order, t)
+		if d.ID != "" {
+			l.byID[d.ID] = t
 		}
-		p.emit(ctx, StreamEvent{Type: EventContentBlockDelta, Index: &idx, Delta: &Delta{Type: "input_json_delta", PartialJSON: json.RawMessage(encoded)}})
 	}
-	p.emit(ctx, StreamEvent{Type: EventContentBlockStop, Index: &idx})
+	if d.PartialJSON != "" {
+		l.emit(l.ctx, inputJSONDelta(t.idx, t.id, t.name, d.PartialJSON))
+	}
+}
+
+// finish closes the open block and emits whatever the provider did not
+// stream (providers without deltas, or test doubles) from the final message.
+func (l *liveBlocks) finish(msg model.Message) {
+	l.closeOpen()
+	for _, t := range l.order {
+		l.stop(t)
+	}
+	if !l.text && msg.Content != "" {
+		emitTextBlock(l.ctx, l.emit, l.next, msg.Content)
+		l.next++
+	}
+	for _, call := range msg.ToolCalls {
+		if l.byID[call.ID] != nil {
+			continue
+		}
+		emitToolBlock(l.ctx, l.emit, l.next, call.ID, call.Name, call.Arguments)
+		l.next++
+	}
+}
+
+func (l *liveBlocks) start(block ContentBlock) int {
+	idx := l.next
+	l.next++
+	l.open, l.hasOpen = idx, true
+	l.emit(l.ctx, StreamEvent{Type: EventContentBlockStart, Index: &idx, ContentBlock: &block})
+	return idx
+}
+
+func (l *liveBlocks) openTool() *liveTool {
+	for _, t := range l.order {
+		if t.idx == l.open && !t.closed {
+			return t
+		}
+	}
+	return nil
+}
+
+func (l *liveBlocks) closeOpen() {
+	if !l.hasOpen {
+		return
+	}
+	if t := l.openTool(); t != nil {
+		l.stop(t)
+		return
+	}
+	idx := l.open
+	l.hasOpen = false
+	l.emit(l.ctx, StreamEvent{Type: EventContentBlockStop, Index: &idx})
+}
+
+func (l *liveBlocks) stop(t *liveTool) {
+	if t.closed {
+		return
+	}
+	t.closed = true
+	if l.hasOpen && l.open == t.idx {
+		l.hasOpen = false
+	}
+	idx := t.idx
+	l.emit(l.ctx, StreamEvent{Type: EventContentBlockStop, Index: &idx})
 }
 
 type progressEmitter struct {
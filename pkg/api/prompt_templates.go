@@ -0,0 +1,81 @@
+package api
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/prompts"
+)
+
+const (
+	// promptsDir holds project prompt templates.
+	promptsDir = ".claude/prompts"
+	// promptTemplateTag records the resolved Request.PromptTemplate.
+	promptTemplateTag = "prompt.template"
+	// systemPromptTag records the resolved RequestTemplate.SystemPromptRef.
+	systemPromptTag = "prompt.system"
+)
+
+// loadPromptLibrary returns Options.Prompts, or a library built from
+// .claude/prompts in the embedded FS and project root; project files win.
+func loadPromptLibrary(opts Options) *prompts.Library {
+	if opts.Prompts != nil {
+		return opts.Prompts
+	}
+	lib := &prompts.Library{}
+	var sources []prompts.Template
+	var errs []error
+	if opts.EmbedFS != nil {
+		templates, parseErrs := prompts.ParseTemplates(opts.EmbedFS, promptsDir)
+		sources = append(sources, templates...)
+		errs = append(errs, parseErrs...)
+	}
+	if root := strings.TrimSpace(opts.ProjectRoot); root != "" {
+		templates, parseErrs := prompts.ParseTemplates(os.DirFS(root), promptsDir)
+		sources = append(sources, templates...)
+		errs = append(errs, parseErrs...)
+	}
+	for _, t := range sources {
+		if err := lib.Register(t); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, err := range errs {
+		log.Printf("prompt loader warning: %v", err)
+	}
+	return lib
+}
+
+// renderPrompt expands Request.PromptTemplate with Request.PromptVars. The
+// rendered text becomes the prompt; an explicit Prompt is appended to it.
+func (rt *Runtime) renderPrompt(req *Request) error {
+	ref := strings.TrimSpace(req.PromptTemplate)
+	if ref == "" {
+		return nil
+	}
+	text, resolved, err := rt.renderPromptRef(ref, req.PromptVars)
+	if err != nil {
+		return err
+	}
+	if prompt := strings.TrimSpace(req.Prompt); prompt != "" {
+		text = text + "\n\n" + prompt
+	}
+	req.Prompt = text
+	req.Tags[promptTemplateTag] = resolved
+	return nil
+}
+
+// renderPromptRef renders ref and returns the pinned "name@version" it
+// resolved to.
+func (rt *Runtime) renderPromptRef(ref string, vars map[string]any) (string, string, error) {
+	tpl, err := rt.prompts.Get(ref)
+	if err != nil {
+		return "", "", err
+	}
+	text, err := rt.prompts.Render(tpl.Ref(), vars)
+	if err != nil {
+		return "", "", err
+	}
+	return text, tpl.Ref(), nil
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/prompts"
+)
+
+func TestPromptTemplatesRenderPromptAndSystemPrompt(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, ".claude", "prompts")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "triage.md"), []byte("---\nversion: \"2\"\nvariables:\n  - name: ticket\n    required: true\n---\nTriage ticket {{ ticket }}."), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "support-persona.md"), []byte("You support {{ .product }} customers."), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mdl := &systemCaptureModel{}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:         root,
+		Model:               mdl,
+		EnabledBuiltinTools: []string{},
+		RulesEnabled:        boolPtr(false),
+		Templates:           map[string]RequestTemplate{"support": {SystemPromptRef: "support-persona"}},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	resp, err := rt.Run(context.Background(), Request{
+		PromptTemplate: "triage",
+		Prompt:         "Be brief.",
+		PromptVars:     map[string]any{"ticket": "T-42", "product": "Acme"},
+		Template:       "support",
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if mdl.prompt != "Triage ticket T-42.\n\nBe brief." || mdl.system != "You support Acme customers." {
+		t.Fatalf("prompt=%q system=%q", mdl.prompt, mdl.system)
+	}
+	if resp.Tags[promptTemplateTag] != "triage@2" || resp.Tags[systemPromptTag] != "support-persona@1" {
+		t.Fatalf("tags %v", resp.Tags)
+	}
+
+	if _, err := rt.Run(context.Background(), Request{PromptTemplate: "triage"}); !errors.Is(err, prompts.ErrInvalidVariables) {
+		t.Fatalf("expected ErrInvalidVariables, got %v", err)
+	}
+	if _, err := rt.Run(context.Background(), Request{PromptTemplate: "missing"}); !errors.Is(err, prompts.ErrPromptNotFound) {
+		t.Fatalf("expected ErrPromptNotFound, got %v", err)
+	}
+}
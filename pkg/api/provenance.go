@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/artifact"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// ProvenanceOptions configures provenance stamping; see Options.Provenance.
+type ProvenanceOptions struct {
+	// Model labels every stamp. When empty, responses fall back to
+	// Usage.Provider, which a Router or KeyPool fills in.
+	Model string
+	// SkipFiles leaves files written by Write and Edit untouched. They are
+	// still recorded in the artifact store with their provenance.
+	SkipFiles bool
+}
+
+// fileWriteTools are the builtin tools whose output files are stamped and
+// scanned.
+var fileWriteTools = map[string]bool{"Write": true, "Edit": true}
+
+// provenanceStamper stamps the files and the response of one run.
+type provenanceStamper struct {
+	cfg       ProvenanceOptions
+	store     artifact.Store
+	root      string
+	runID     string
+	sessionID string
+	now       func() time.Time
+}
+
+func (rt *Runtime) newProvenanceStamper(prep preparedRun) *provenanceStamper {
+	if rt.opts.Provenance == nil {
+		return nil
+	}
+	return &provenanceStamper{
+		cfg:       *rt.opts.Provenance,
+		store:     rt.opts.ArtifactStore,
+		root:      rt.opts.ProjectRoot,
+		runID:     prep.normalized.RequestID,
+		sessionID: prep.normalized.SessionID,
+		now:       time.Now,
+	}
+}
+
+func (s *provenanceStamper) provenance(model, toolName string) artifact.Provenance {
+	if s.cfg.Model != "" {
+		model = s.cfg.Model
+	}
+	return artifact.Provenance{Model: model, RunID: s.runID, SessionID: s.sessionID, Tool: toolName, CreatedAt: s.now().UTC()}
+}
+
+// file stamps the file a Write or Edit call reported in its result data and
+// records it in the artifact store. It reports false when nothing was stored.
+func (s *provenanceStamper) file(ctx context.Context, toolName string, data any) (tool.Artifact, bool) {
+	if s == nil || !fileWriteTools[toolName] {
+		return tool.Artifact{}, false
+	}
+	fields, _ := data.(map[string]any)
+	name, _ := fields["path"].(string)
+	if name == "" || isDryRun(data) {
+		return tool.Artifact{}, false
+	}
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.root, path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("api: provenance: read %s: %v", name, err)
+		return tool.Artifact{}, false
+	}
+	stamped, p, ok := artifact.Stamp(name, content, s.provenance("", toolName))
+	if ok && !s.cfg.SkipFiles {
+		info, err := os.Stat(path)
+		if err == nil {
+			err = os.WriteFile(path, stamped, info.Mode().Perm())
+		}
+		if err != nil {
+			log.Printf("api: provenance: stamp %s: %v", name, err)
+		} else {
+			content = stamped
+		}
+	}
+	return s.record(ctx, name, provenanceMediaType(name), content, p)
+}
+
+// response attributes the final output of the run and records it.
+func (s *provenanceStamper) response(ctx context.Context, result *Result) *artifact.Provenance {
+	if s == nil || result == nil {
+		return nil
+	}
+	p := s.provenance(result.Usage.Provider, "")
+	p.Digest = artifact.Digest([]byte(result.Output))
+	s.record(ctx, "response-"+s.runID+".txt", "text/plain; charset=utf-8", []byte(result.Output), p)
+	return &p
+}
+
+func (s *provenanceStamper) record(ctx context.Context, name, mediaType string, content []byte, p artifact.Provenance) (tool.Artifact, bool) {
+	if s.store == nil {
+		return tool.Artifact{}, false
+	}
+	stored, err := s.store.Put(ctx, artifact.Artifact{
+		Name:       name,
+		MediaType:  mediaType,
+		SessionID:  s.sessionID,
+		Tool:       p.Tool,
+		Provenance: &p,
+	}, content)
+	if err != nil {
+		log.Printf("api: provenance: record %s: %v", name, err)
+		return tool.Artifact{}, false
+	}
+	return tool.Artifact{ID: stored.ID, Name: name, MediaType: mediaType, SizeBytes: stored.SizeBytes}, true
+}
+
+func provenanceMediaType(name string) string {
+	if mt := mime.TypeByExtension(filepath.Ext(name)); mt != "" {
+		return mt
+	}
+	return "text/plain; charset=utf-8"
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/artifact"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func TestProvenanceStampsFilesAndResponses(t *testing.T) {
+	root := newClaudeProject(t)
+	store := artifact.NewMemoryStore()
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{
+			ID:        "1",
+			Name:      "Write",
+			Arguments: map[string]any{"file_path": filepath.Join(root, "gen.py"), "content": "print('hi')\n"},
+		}}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:   root,
+		Model:         mdl,
+		ArtifactStore: store,
+		Provenance:    &ProvenanceOptions{Model: "test-model"},
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	defer rt.Close()
+
+	resp, err := rt.Run(context.Background(), Request{Prompt: "write it", SessionID: "prov"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(root, "gen.py"))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	stamp, ok := artifact.VerifyStamp(content)
+	if !ok || stamp.Model != "test-model" || stamp.RunID != resp.RequestID || stamp.Tool != "Write" || stamp.SessionID != "prov" {
+		t.Fatalf("file stamp = %+v ok=%v in %q", stamp, ok, content)
+	}
+	if len(resp.Artifacts) != 1 || resp.Artifacts[0].Name != "gen.py" {
+		t.Fatalf("artifacts = %+v", resp.Artifacts)
+	}
+	meta, err := store.Stat(context.Background(), resp.Artifacts[0].ID)
+	if err != nil || meta.Provenance == nil || meta.Provenance.Digest != stamp.Digest {
+		t.Fatalf("stored file = %+v err=%v", meta, err)
+	}
+
+	p := resp.Provenance
+	if p == nil || p.Model != "test-model" || p.RunID != resp.RequestID || p.Digest != artifact.Digest([]byte("done")) {
+		t.Fatalf("response provenance = %+v", p)
+	}
+	if meta, err := store.Stat(context.Background(), p.Digest); err != nil || meta.Provenance == nil {
+		t.Fatalf("response not recorded: %+v err=%v", meta, err)
+	}
+}
@@ -0,0 +1,468 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/artifact"
+)
+
+// PurgeSelector chooses the sessions removed by Runtime.PurgeData. A session
+// matches when any populated criterion matches; an empty selector matches
+// nothing so callers cannot wipe every store by accident.
+type PurgeSelector struct {
+	// SessionIDs lists exact session identifiers.
+	SessionIDs []string
+	// SessionPrefix matches session IDs that start with the prefix. Hosts that
+	// embed tenant/user identifiers in session IDs (e.g. "acme:user-42:...")
+	// can purge a tenant or user with a single prefix.
+	SessionPrefix string
+	// Tags matches sessions that served a Request carrying all of these tags.
+	// Tag associations are tracked in-process only.
+	Tags map[string]string
+	// Match is an optional custom predicate over session IDs.
+	Match func(sessionID string) bool
+}
+
+func (s PurgeSelector) empty() bool {
+	return len(s.SessionIDs) == 0 && s.SessionPrefix == "" && len(s.Tags) == 0 && s.Match == nil
+}
+
+func (s PurgeSelector) matches(sessionID string, tags map[string]string) bool {
+	for _, id := range s.SessionIDs {
+		if id == sessionID {
+			return true
+		}
+	}
+	if s.SessionPrefix != "" && strings.HasPrefix(sessionID, s.SessionPrefix) {
+		return true
+	}
+	if len(s.Tags) > 0 && len(tags) > 0 {
+		all := true
+		for k, v := range s.Tags {
+			if tags[k] != v {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return s.Match != nil && s.Match(sessionID)
+}
+
+// PurgeReport summarises what Runtime.PurgeData deleted.
+type PurgeReport struct {
+	// Sessions lists the purged session IDs in sorted order.
+	Sessions []string `json:"sessions"`
+	// HistoryFiles counts persisted history snapshots removed from disk.
+	HistoryFiles int `json:"history_files"`
+	// RolloutFiles counts compaction rollout files removed from disk.
+	RolloutFiles int `json:"rollout_files"`
+	// ApprovalRecords counts approval queue records removed.
+	ApprovalRecords int `json:"approval_records"`
+	// MemoryEntries counts in-memory history and token stats entries dropped.
+	MemoryEntries int `json:"memory_entries"`
+	// AuditRecords counts records removed from a FileAuditLogger.
+	AuditRecords int `json:"audit_records"`
+	// ReplayBuffers counts RunEvents replay buffers dropped.
+	ReplayBuffers int `json:"replay_buffers"`
+	// ArtifactBlobs counts artifacts deleted from the artifact store.
+	ArtifactBlobs int `json:"artifact_blobs"`
+}
+
+func (r *PurgeReport) deleted() int {
+	return r.HistoryFiles + r.RolloutFiles + r.ApprovalRecords + r.MemoryEntries +
+		r.AuditRecords + r.ReplayBuffers + r.ArtifactBlobs
+}
+
+// sessionTagIndex remembers the tags each session was run with so purges can
+// select by tag.
+type sessionTagIndex struct {
+	mu   sync.Mutex
+	tags map[string]map[string]string
+}
+
+func (i *sessionTagIndex) note(sessionID string, tags map[string]string) {
+	if i == nil || sessionID == "" || len(tags) == 0 {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.tags == nil {
+		i.tags = map[string]map[string]string{}
+	}
+	merged := i.tags[sessionID]
+	if merged == nil {
+		merged = make(map[string]string, len(tags))
+		i.tags[sessionID] = merged
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+}
+
+func (i *sessionTagIndex) snapshot() map[string]map[string]string {
+	if i == nil {
+		return nil
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	out := make(map[string]map[string]string, len(i.tags))
+	for id, tags := range i.tags {
+		out[id] = maps.Clone(tags)
+	}
+	return out
+}
+
+func (i *sessionTagIndex) forget(sessionID string) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.tags, sessionID)
+}
+
+// forgetEvicted prunes the tag index when sessionID is evicted from memory
+// and no persisted copy is left for a purge to select by tag.
+func (rt *Runtime) forgetEvicted(sessionID string) {
+	if rt.historyPersister != nil || rt.sessionStore != nil {
+		return
+	}
+	rt.sessionTags.forget(sessionID)
+}
+
+// PurgeData deletes every piece of session data the runtime manages for the
+// sessions matched by selector: in-memory and persisted history, compaction
+// rollouts, approval records, token statistics, tool output caches, stream
+// replay buffers, the artifacts the sessions stored and, when the audit
+// logger is a FileAuditLogger, their audit records.
+// Sessions with an in-flight run are purged once the run completes or ctx
+// expires. Errors from individual stores are joined; the report reflects
+// what was deleted before any failure.
+func (rt *Runtime) PurgeData(ctx context.Context, selector PurgeSelector) (*PurgeReport, error) {
+	if rt == nil {
+		return nil, errors.New("api: runtime is nil")
+	}
+	if selector.empty() {
+		return nil, errors.New("api: purge selector is empty")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := rt.beginRun(); err != nil {
+		return nil, err
+	}
+	defer rt.endRun()
+
+	report := &PurgeReport{}
+	tags := rt.sessionTags.snapshot()
+	stored := map[string]struct{}{}
+	for _, id := range rt.histories.SessionIDs() {
+		stored[id] = struct{}{}
+	}
+	for _, id := range rt.historyPersister.SessionIDs() {
+		stored[id] = struct{}{}
+	}
+	if rt.sessionStore != nil {
+		ids, err := rt.sessionStore.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("purge: list sessions: %w", err)
+		}
+		for _, id := range ids {
+			stored[id] = struct{}{}
+		}
+	}
+	candidates := maps.Clone(stored)
+	for id := range tags {
+		candidates[id] = struct{}{}
+	}
+	for _, id := range selector.SessionIDs {
+		candidates[id] = struct{}{}
+	}
+
+	var errs []error
+	for id := range candidates {
+		if !selector.matches(id, tags[id]) {
+			continue
+		}
+		if err := rt.sessionGate.Acquire(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("purge %q: %w", id, err))
+			continue
+		}
+		purged, err := rt.purgeSession(ctx, id, report)
+		rt.sessionGate.Release(id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("purge %q: %w", id, err))
+		}
+		if purged {
+			report.Sessions = append(report.Sessions, id)
+		}
+	}
+	// Tags of sessions no store holds any more have nothing left to select.
+	for id := range tags {
+		if _, ok := stored[id]; !ok {
+			rt.sessionTags.forget(id)
+		}
+	}
+	sort.Strings(report.Sessions)
+	return report, errors.Join(errs...)
+}
+
+// purgeSession removes sessionID from every store and reports whether any
+// data existed for it.
+func (rt *Runtime) purgeSession(ctx context.Context, sessionID string, report *PurgeReport) (bool, error) {
+	var errs []error
+	before := report.deleted()
+
+	if rt.histories.Delete(sessionID) {
+		report.MemoryEntries++
+	}
+	if rt.tokens.forget(sessionID) {
+		report.MemoryEntries++
+	}
+	rt.sessionTags.forget(sessionID)
+	rt.sessionValues.forget(sessionID)
+	rt.lineage.forget(sessionID)
+	report.ReplayBuffers += rt.replay.purgeSession(sessionID)
+
+	n, err := rt.purgeArtifacts(ctx, sessionID)
+	report.ArtifactBlobs += n
+	errs = append(errs, err)
+	rt.sessionArtifacts.forget(sessionID)
+
+	n, err = rt.historyPersister.Delete(sessionID)
+	report.HistoryFiles += n
+	errs = append(errs, err)
+	if rt.sessionStore != nil {
+		deleted, err := rt.sessionStore.Delete(ctx, sessionID)
+		if deleted {
+			report.HistoryFiles++
+		}
+		errs = append(errs, err)
+	}
+
+	if rt.compactor != nil {
+		n, err = rt.compactor.rollout.DeleteSession(sessionID)
+		report.RolloutFiles += n
+		errs = append(errs, err)
+	}
+	if rt.opts.ApprovalQueue != nil {
+		n, err = rt.opts.ApprovalQueue.PurgeSession(sessionID)
+		report.ApprovalRecords += n
+		errs = append(errs, err)
+	}
+	if logger, ok := rt.audit.(*FileAuditLogger); ok {
+		n, err = logger.PurgeSession(sessionID)
+		report.AuditRecords += n
+		errs = append(errs, err)
+	}
+	rt.releaseSessionShell(sessionID)
+	errs = append(errs, cleanupBashOutputSessionDir(sessionID), cleanupToolOutputSessionDir(sessionID))
+
+	return report.deleted() > before, errors.Join(errs...)
+}
+
+// purgeArtifacts releases the artifacts sessionID stored: those the runtime
+// noted for it and, when the store can list, those whose metadata names it.
+// Artifacts another session also stored lose sessionID as an owner but are
+// kept; the count covers the deleted ones.
+func (rt *Runtime) purgeArtifacts(ctx context.Context, sessionID string) (int, error) {
+	store := rt.opts.ArtifactStore
+	if store == nil {
+		return 0, nil
+	}
+	ids := map[string]struct{}{}
+	for _, a := range rt.sessionArtifacts.list(sessionID) {
+		ids[a.ID] = struct{}{}
+	}
+	var errs []error
+	if lister, ok := store.(artifact.Lister); ok {
+		all, err := lister.List(ctx)
+		errs = append(errs, err)
+		for _, meta := range all {
+			if slices.Contains(meta.Owners(), sessionID) {
+				ids[meta.ID] = struct{}{}
+			}
+		}
+	}
+	releaser, _ := store.(artifact.Releaser)
+	removed := 0
+	for id := range ids {
+		if releaser != nil {
+			deleted, err := releaser.Release(ctx, id, sessionID)
+			errs = append(errs, err)
+			if deleted {
+				removed++
+			}
+			continue
+		}
+		meta, err := store.Stat(ctx, id)
+		if errors.Is(err, artifact.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if owners := meta.Owners(); len(owners) > 0 && !slices.Equal(owners, []string{sessionID}) {
+			continue
+		}
+		if err := store.Delete(ctx, id); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
+
+// Delete drops the in-memory history for id without invoking eviction hooks.
+func (s *historyStore) Delete(id string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[id]; !ok {
+		return false
+	}
+	delete(s.data, id)
+	delete(s.lastUsed, id)
+	return true
+}
+
+// forget drops the aggregated stats for sessionID. Totals are kept since they
+// carry no per-session data.
+func (t *tokenTracker) forget(sessionID string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.sessions[sessionID]; !ok {
+		return false
+	}
+	delete(t.sessions, sessionID)
+	return true
+}
+
+// SessionIDs lists sessions with a persisted snapshot. IDs are read from the
+// snapshot itself because file names are sanitised.
+func (p *diskHistoryPersister) SessionIDs() []string {
+	if p == nil || strings.TrimSpace(p.dir) == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		return nil
+	}
+	seen := map[string]struct{}{}
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		stem := p.snapshotStem(entry.Name())
+		if stem == "" {
+			continue
+		}
+		id := stem
+		if data, err := p.readSnapshot(filepath.Join(p.dir, stem+".json")); err == nil {
+			var wrapper SessionTranscript
+			if json.Unmarshal(data, &wrapper) == nil && wrapper.SessionID != "" {
+				id = wrapper.SessionID
+			}
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Delete removes the plain and compressed snapshots for sessionID.
+func (p *diskHistoryPersister) Delete(sessionID string) (int, error) {
+	path := p.filePath(sessionID)
+	if path == "" {
+		return 0, nil
+	}
+	var paths []string
+	for _, codec := range storedCodecs(p.codec) {
+		paths = append(paths, path+compressorExtension(codec))
+	}
+	return removeFiles(paths)
+}
+
+// snapshotStem returns the file name without ".json" and its codec
+// extension, or "" when name is not a snapshot.
+func (p *diskHistoryPersister) snapshotStem(name string) string {
+	for _, codec := range storedCodecs(p.codec) {
+		if stem, ok := strings.CutSuffix(name, ".json"+compressorExtension(codec)); ok {
+			return stem
+		}
+	}
+	return ""
+}
+
+// DeleteSession removes every rollout file written for sessionID.
+func (w *RolloutWriter) DeleteSession(sessionID string) (int, error) {
+	if w == nil || strings.TrimSpace(w.dir) == "" {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	prefix := safeRolloutName(sessionID) + "_"
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		// Require the timestamp to follow directly so "a" does not match "a_b".
+		ts, _, ok := strings.Cut(rest, "_compact.json")
+		if !ok {
+			continue
+		}
+		if _, err := time.Parse(rolloutTimeLayout, ts); err != nil {
+			continue
+		}
+		paths = append(paths, filepath.Join(w.dir, name))
+	}
+	return removeFiles(paths)
+}
+
+func removeFiles(paths []string) (int, error) {
+	removed := 0
+	var errs []error
+	for _, path := range paths {
+		err := os.Remove(path)
+		switch {
+		case err == nil:
+			removed++
+		case !errors.Is(err, os.ErrNotExist):
+			errs = append(errs, err)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/artifact"
+	"github.com/cexll/agentsdk-go/pkg/message"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func TestRuntimePurgeDataRejectsEmptySelector(t *testing.T) {
+	rt, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: &stubModel{}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	if _, err := rt.PurgeData(context.Background(), PurgeSelector{}); err == nil {
+		t.Fatal("expected empty selector error")
+	}
+}
+
+func TestRuntimePurgeDataRemovesMatchingSessions(t *testing.T) {
+	root := newClaudeProject(t)
+	mdl := &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "ok"}}}}
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	rt.historyPersister = newDiskHistoryPersister(root)
+	rt.compactor = &compactor{rollout: newRolloutWriter(root, "rollouts")}
+
+	runs := []Request{
+		{SessionID: "acme:u1:a", Prompt: "hi"},
+		{SessionID: "acme:u1:b", Prompt: "hi"},
+		{SessionID: "tagged", Prompt: "hi", Tags: map[string]string{"user": "u2"}},
+		{SessionID: "keep", Prompt: "hi"},
+	}
+	for _, req := range runs {
+		if _, err := rt.Run(context.Background(), req); err != nil {
+			t.Fatalf("run %s: %v", req.SessionID, err)
+		}
+	}
+	// A snapshot left on disk from an earlier process.
+	if err := rt.historyPersister.Save("acme:u1:old", []message.Message{{Role: "user", Content: "x"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := rt.compactor.rollout.WriteCompactEvent("acme:u1:a", compactResult{summary: "s"}); err != nil {
+		t.Fatalf("rollout: %v", err)
+	}
+	// Shares the "tagged_" file name prefix but belongs to another session.
+	if err := rt.compactor.rollout.WriteCompactEvent("tagged_x", compactResult{summary: "s"}); err != nil {
+		t.Fatalf("rollout: %v", err)
+	}
+
+	report, err := rt.PurgeData(context.Background(), PurgeSelector{
+		SessionPrefix: "acme:u1:",
+		Tags:          map[string]string{"user": "u2"},
+	})
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	wantSessions := []string{"acme:u1:a", "acme:u1:b", "acme:u1:old", "tagged"}
+	if !reflect.DeepEqual(report.Sessions, wantSessions) {
+		t.Fatalf("unexpected sessions %v", report.Sessions)
+	}
+	if report.HistoryFiles != 4 || report.RolloutFiles != 1 || report.MemoryEntries != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	ids := rt.histories.SessionIDs()
+	if !reflect.DeepEqual(ids, []string{"keep"}) {
+		t.Fatalf("expected only keep history, got %v", ids)
+	}
+	if _, err := os.Stat(rt.historyPersister.filePath("acme:u1:old")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected snapshot removed, got %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "rollouts"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected unrelated rollout kept, got %v err=%v", entries, err)
+	}
+
+	again, err := rt.PurgeData(context.Background(), PurgeSelector{SessionIDs: []string{"acme:u1:a"}})
+	if err != nil || len(again.Sessions) != 0 {
+		t.Fatalf("expected idempotent purge, got %+v err=%v", again, err)
+	}
+}
+
+func TestRuntimeEvictionPrunesSessionTags(t *testing.T) {
+	mdl := &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "ok"}}}}
+	rt, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: mdl, MaxSessions: 1})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	rt.historyPersister = nil
+
+	for _, id := range []string{"first", "second"} {
+		if _, err := rt.Run(context.Background(), Request{SessionID: id, Prompt: "hi", Tags: map[string]string{"user": id}}); err != nil {
+			t.Fatalf("run %s: %v", id, err)
+		}
+	}
+	tags := rt.sessionTags.snapshot()
+	if _, ok := tags["first"]; ok {
+		t.Fatalf("evicted session still indexed: %v", tags)
+	}
+	if tags["second"]["user"] != "second" {
+		t.Fatalf("live session tags = %v", tags)
+	}
+
+	// Purges drop entries whose session no store holds any more.
+	rt.sessionTags.note("gone", map[string]string{"user": "gone"})
+	if _, err := rt.PurgeData(context.Background(), PurgeSelector{SessionIDs: []string{"other"}}); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if _, ok := rt.sessionTags.snapshot()["gone"]; ok {
+		t.Fatal("stale tag entry survived purge")
+	}
+}
+
+func TestRuntimePurgeDataRemovesAuditReplayAndArtifacts(t *testing.T) {
+	root := newClaudeProject(t)
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := NewFileAuditLogger(logPath, NewGzipCompressor(0))
+	if err != nil {
+		t.Fatalf("audit: %v", err)
+	}
+	store := artifact.NewMemoryStore()
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: &stubModel{}, AuditLogger: audit, ArtifactStore: store})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	ctx := context.Background()
+	for _, id := range []string{"gone", "keep"} {
+		audit.Emit(ctx, AuditRecord{Kind: AuditPermission, SessionID: id, Tool: "Bash"})
+		rt.replay.open("run-"+id, id).finish()
+	}
+	owned, err := store.Put(ctx, artifact.Artifact{Name: "a.txt", SessionID: "gone"}, []byte("gone"))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	shared, err := store.Put(ctx, artifact.Artifact{Name: "b.txt", SessionID: "keep"}, []byte("keep"))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	rt.sessionArtifacts.add("gone", HandoffArtifact{ID: shared.ID})
+	// Identical content stored by both sessions.
+	both, err := store.Put(ctx, artifact.Artifact{Name: "c.txt", SessionID: "keep"}, []byte("both"))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, err := store.Put(ctx, artifact.Artifact{Name: "c.txt", SessionID: "gone"}, []byte("both")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	report, err := rt.PurgeData(ctx, PurgeSelector{SessionIDs: []string{"gone"}})
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if report.AuditRecords != 1 || report.ReplayBuffers != 1 || report.ArtifactBlobs != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if _, err := rt.ResumeStream(ctx, "run-gone", 0); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("replay buffer kept: %v", err)
+	}
+	if _, err := rt.ResumeStream(ctx, "run-keep", 0); err != nil {
+		t.Fatalf("other replay buffer dropped: %v", err)
+	}
+	if _, err := store.Stat(ctx, owned.ID); !errors.Is(err, artifact.ErrNotFound) {
+		t.Fatalf("owned artifact kept: %v", err)
+	}
+	if _, err := store.Stat(ctx, shared.ID); err != nil {
+		t.Fatalf("artifact of another session deleted: %v", err)
+	}
+	if meta, err := store.Stat(ctx, both.ID); err != nil || !reflect.DeepEqual(meta.Owners(), []string{"keep"}) {
+		t.Fatalf("shared artifact %+v err=%v", meta, err)
+	}
+
+	// The log stays appendable after the rewrite.
+	audit.Emit(ctx, AuditRecord{Kind: AuditPermission, SessionID: "keep", Tool: "Read"})
+	if err := audit.Shutdown(); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	records, err := ReadAuditLog(logPath, NewGzipCompressor(0))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(records) != 2 || records[0].SessionID != "keep" || records[1].Tool != "Read" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+}
+
+func TestSessionTagIndexSnapshotIsDeepCopy(t *testing.T) {
+	var idx sessionTagIndex
+	idx.note("s", map[string]string{"user": "u1"})
+	snap := idx.snapshot()
+	idx.note("s", map[string]string{"team": "t1"})
+	if len(snap["s"]) != 1 {
+		t.Fatalf("snapshot shares the index map: %v", snap["s"])
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned by Run, and sent as the error event of RunStream,
+// when a run waited Options.RunQueueTimeout for one of the
+// Options.MaxConcurrentRuns slots without getting one.
+var ErrQueueFull = errors.New("api: run queue is full")
+
+// RunQueueStats reports the run queue. With no MaxConcurrentRuns limit runs
+// are never queued and only Running and Admitted move.
+type RunQueueStats struct {
+	// Limit is Options.MaxConcurrentRuns; 0 means unlimited.
+	Limit   int `json:"limit"`
+	Running int `json:"running"`
+	// Queued is the number of runs waiting for a slot.
+	Queued int `json:"queued"`
+	// PeakQueued is the largest Queued seen.
+	PeakQueued int `json:"peak_queued"`
+	// Admitted counts runs that got a slot; Rejected counts runs that gave
+	// up with ErrQueueFull.
+	Admitted uint64 `json:"admitted"`
+	Rejected uint64 `json:"rejected"`
+	// TotalWait is the time admitted runs spent queued.
+	TotalWait time.Duration `json:"total_wait"`
+}
+
+// runQueue admits at most limit runs at a time and queues the rest in
+// arrival order.
+type runQueue struct {
+	limit   int
+	timeout time.Duration
+
+	mu      sync.Mutex
+	running int
+	waiters list.List // of chan struct{}, closed when the slot is handed over
+	stats   RunQueueStats
+}
+
+func newRunQueue(limit int, timeout time.Duration) *runQueue {
+	return &runQueue{limit: limit, timeout: timeout}
+}
+
+// acquire blocks until the run may start, calling waiting first when the
+// run has to queue. It returns ErrQueueFull after the queue timeout and
+// ctx.Err() when ctx ends first. Every successful acquire must be paired
+// with release.
+func (q *runQueue) acquire(ctx context.Context, waiting func()) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	if q.limit <= 0 || (q.running < q.limit && q.waiters.Len() == 0) {
+		q.running++
+		q.stats.Admitted++
+		q.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	elem := q.waiters.PushBack(ready)
+	q.stats.PeakQueued = max(q.stats.PeakQueued, q.waiters.Len())
+	q.mu.Unlock()
+	if waiting != nil {
+		waiting()
+	}
+
+	started := time.Now()
+	var expired <-chan time.Time
+	if q.timeout > 0 {
+		timer := time.NewTimer(q.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	var err error
+	select {
+	case <-ready:
+		q.recordWait(started)
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-expired:
+		err = ErrQueueFull
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case <-ready:
+		// The slot was handed over while giving up; pass it on.
+		q.releaseLocked()
+	default:
+		q.waiters.Remove(elem)
+	}
+	if errors.Is(err, ErrQueueFull) {
+		q.stats.Rejected++
+	}
+	return err
+}
+
+func (q *runQueue) recordWait(started time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stats.TotalWait += time.Since(started)
+}
+
+// release frees the caller's slot, handing it to the oldest queued run.
+func (q *runQueue) release() {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+func (q *runQueue) releaseLocked() {
+	if front := q.waiters.Front(); front != nil {
+		q.waiters.Remove(front)
+		q.stats.Admitted++
+		close(front.Value.(chan struct{})) //nolint:errcheck // waiters only holds chan struct{}
+		return
+	}
+	q.running--
+}
+
+func (q *runQueue) snapshot() RunQueueStats {
+	if q == nil {
+		return RunQueueStats{}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := q.stats
+	stats.Limit = max(q.limit, 0)
+	stats.Running = q.running
+	stats.Queued = q.waiters.Len()
+	return stats
+}
+
+// admitRun waits for a run slot, listing run as queued meanwhile so Runs
+// shows it and Cancel can abort it.
+func (rt *Runtime) admitRun(ctx context.Context, run *activeRun) error {
+	defer run.setQueued(false)
+	if err := rt.queue.acquire(ctx, func() { run.setQueued(true) }); err != nil {
+		return canceledRunError(ctx, err)
+	}
+	return nil
+}
+
+// RunQueueStats reports how many runs hold or wait for one of the
+// Options.MaxConcurrentRuns slots.
+func (rt *Runtime) RunQueueStats() RunQueueStats {
+	if rt == nil {
+		return RunQueueStats{}
+	}
+	return rt.queue.snapshot()
+}
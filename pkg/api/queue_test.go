@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+func TestRunQueueFIFOAndTimeout(t *testing.T) {
+	q := newRunQueue(1, 0)
+	ctx := context.Background()
+	if err := q.acquire(ctx, nil); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	order := make(chan int, 2)
+	for i := 1; i <= 2; i++ {
+		waiting := make(chan struct{})
+		go func() {
+			if err := q.acquire(ctx, func() { close(waiting) }); err != nil {
+				t.Errorf("acquire %d: %v", i, err)
+				return
+			}
+			order <- i
+		}()
+		<-waiting
+	}
+	if stats := q.snapshot(); stats.Running != 1 || stats.Queued != 2 || stats.PeakQueued != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	for want := 1; want <= 2; want++ {
+		q.release()
+		select {
+		case got := <-order:
+			if got != want {
+				t.Fatalf("admitted %d, want %d", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("waiter %d not admitted", want)
+		}
+	}
+
+	q.timeout = 20 * time.Millisecond
+	if err := q.acquire(ctx, nil); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := q.acquire(canceled, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	q.release()
+	stats := q.snapshot()
+	if stats.Limit != 1 || stats.Running != 0 || stats.Queued != 0 || stats.Admitted != 3 || stats.Rejected != 1 || stats.TotalWait <= 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestRuntimeQueuedRunCancel(t *testing.T) {
+	block := &blockingTool{started: make(chan struct{})}
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "1", Name: "block", Arguments: map[string]any{"x": 1}}}}},
+	}}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:       newClaudeProject(t),
+		Model:             mdl,
+		Tools:             []tool.Tool{block},
+		MaxConcurrentRuns: 1,
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := rt.Run(context.Background(), Request{Prompt: "go", SessionID: "a", RequestID: "run-a"})
+		first <- err
+	}()
+	select {
+	case <-block.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool did not start")
+	}
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := rt.Run(context.Background(), Request{Prompt: "go", SessionID: "b", RequestID: "run-b"})
+		second <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for rt.RunQueueStats().Queued != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("second run not queued: %+v", rt.RunQueueStats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	queued := map[string]bool{}
+	for _, run := range rt.Runs() {
+		queued[run.RunID] = run.Queued
+	}
+	if len(queued) != 2 || queued["run-a"] || !queued["run-b"] {
+		t.Fatalf("runs = %+v", rt.Runs())
+	}
+
+	if err := rt.Cancel("run-b"); err != nil {
+		t.Fatalf("cancel queued: %v", err)
+	}
+	select {
+	case err := <-second:
+		if !errors.Is(err, ErrRunCanceled) {
+			t.Fatalf("expected ErrRunCanceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued run did not stop after cancel")
+	}
+	if err := rt.Cancel("run-a"); err != nil {
+		t.Fatalf("cancel running: %v", err)
+	}
+	<-first
+	if stats := rt.RunQueueStats(); stats.Running != 0 || stats.Queued != 0 || stats.Admitted != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,13 @@
+package api
+
+import "github.com/cexll/agentsdk-go/pkg/model"
+
+// RateLimits reports the provider rate-limit state tracked for the API keys
+// this runtime's model calls used, including how often and how long calls
+// were held back.
+func (rt *Runtime) RateLimits() []model.RateLimitStatus {
+	if rt == nil {
+		return nil
+	}
+	return rt.rateLimiter.Status()
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+// limiterModel records the RateLimiter attached to each call.
+type limiterModel struct {
+	seen []*model.RateLimiter
+}
+
+func (m *limiterModel) Complete(ctx context.Context, _ model.Request) (*model.Response, error) {
+	m.seen = append(m.seen, model.RateLimiterFromContext(ctx))
+	return &model.Response{Message: model.Message{Role: "assistant", Content: "ok"}}, nil
+}
+
+func (m *limiterModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	resp, err := m.Complete(ctx, req)
+	if err != nil {
+		return err
+	}
+	return cb(model.StreamResult{Final: true, Response: resp})
+}
+
+func TestRuntimeSharesRateLimiterAcrossRuns(t *testing.T) {
+	shared := model.NewRateLimiter()
+	mdl := &limiterModel{}
+	rt, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: mdl, RateLimiter: shared})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	defer rt.Close()
+	for _, session := range []string{"a", "b"} {
+		if _, err := rt.Run(context.Background(), Request{Prompt: "hi", SessionID: session}); err != nil {
+			t.Fatalf("run %s: %v", session, err)
+		}
+	}
+	if len(mdl.seen) != 2 || mdl.seen[0] != shared || mdl.seen[1] != shared {
+		t.Fatalf("model calls saw %v, want the shared limiter", mdl.seen)
+	}
+
+	own, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: &limiterModel{}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	defer own.Close()
+	if own.rateLimiter == nil || own.rateLimiter == shared || own.RateLimits() == nil {
+		t.Fatalf("runtime without RateLimiter should own one")
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"github.com/cexll/agentsdk-go/pkg/message"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+)
+
+// redactors applies every middleware.Redactor configured for a run to the
+// text the runtime writes into the conversation history and the Response.
+// A nil value leaves everything unchanged.
+type redactors []*middleware.Redactor
+
+func redactorsOf(items []middleware.Middleware) redactors {
+	var out redactors
+	for _, mw := range items {
+		if r, ok := mw.(*middleware.Redactor); ok && r != nil {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (r redactors) text(s string) string {
+	for _, red := range r {
+		s = red.Redact(s)
+	}
+	return s
+}
+
+func (r redactors) value(v any) any {
+	for _, red := range r {
+		v = red.RedactValue(v)
+	}
+	return v
+}
+
+func (r redactors) arguments(args map[string]any) map[string]any {
+	if len(r) == 0 || args == nil {
+		return args
+	}
+	out, _ := r.value(args).(map[string]any)
+	return out
+}
+
+func (r redactors) blocks(blocks []message.ContentBlock) []message.ContentBlock {
+	if len(r) == 0 {
+		return blocks
+	}
+	for i := range blocks {
+		blocks[i].Text = r.text(blocks[i].Text)
+	}
+	return blocks
+}
+
+// redactorsFirst moves Redactors ahead of the other middleware, keeping the
+// relative order of both groups, so tracing and progress middleware only
+// see masked state.
+func redactorsFirst(items []middleware.Middleware) []middleware.Middleware {
+	out := make([]middleware.Middleware, 0, len(items))
+	for _, mw := range items {
+		if _, ok := mw.(*middleware.Redactor); ok {
+			out = append(out, mw)
+		}
+	}
+	for _, mw := range items {
+		if _, ok := mw.(*middleware.Redactor); !ok {
+			out = append(out, mw)
+		}
+	}
+	return out
+}
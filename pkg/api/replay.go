@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRunNotFound is returned for unknown run IDs: by ResumeStream when the
+// run was not started with RunEvents or its buffer expired, and by Cancel
+// when the run is not in flight.
+var ErrRunNotFound = errors.New("api: run not found")
+
+const (
+	defaultStreamReplayLimit     = 10000
+	defaultStreamReplayRetention = 5 * time.Minute
+)
+
+// replayStore keeps the envelopes of RunEvents runs so clients that lost
+// their connection can catch up with ResumeStream.
+type replayStore struct {
+	mu        sync.Mutex
+	runs      map[string]*replayBuffer
+	limit     int
+	retention time.Duration
+}
+
+func newReplayStore(limit int, retention time.Duration) *replayStore {
+	return &replayStore{runs: map[string]*replayBuffer{}, limit: limit, retention: retention}
+}
+
+// open starts the buffer of runID in sessionID, replacing any earlier one,
+// and prunes buffers of runs that ended more than the retention ago.
+func (s *replayStore) open(runID, sessionID string) *replayBuffer {
+	buf := &replayBuffer{sessionID: sessionID, first: 1, limit: s.limit, notify: make(chan struct{})}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(time.Now())
+	s.runs[runID] = buf
+	return buf
+}
+
+func (s *replayStore) get(runID string) (*replayBuffer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(time.Now())
+	buf, ok := s.runs[runID]
+	return buf, ok
+}
+
+// purgeSession drops the buffers of sessionID's runs and the envelopes they
+// hold, returning how many were dropped.
+func (s *replayStore) purgeSession(sessionID string) int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dropped := 0
+	for id, buf := range s.runs {
+		if buf.sessionID != sessionID {
+			continue
+		}
+		buf.clear()
+		delete(s.runs, id)
+		dropped++
+	}
+	return dropped
+}
+
+func (s *replayStore) pruneLocked(now time.Time) {
+	for id, buf := range s.runs {
+		if ended, ok := buf.endedAt(); ok && now.Sub(ended) > s.retention {
+			delete(s.runs, id)
+		}
+	}
+}
+
+// replayBuffer holds the envelopes of one run. Once more than limit are
+// buffered the oldest are dropped; subscribers see the gap in Seq.
+type replayBuffer struct {
+	mu        sync.Mutex
+	sessionID string
+	events    []Envelope
+	first     uint64 // Seq of events[0]
+	limit     int
+	ended     time.Time
+	// notify is closed and replaced whenever events are added or the run
+	// ends, waking subscribers.
+	notify chan struct{}
+}
+
+func (b *replayBuffer) append(env Envelope) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, env)
+	if b.limit > 0 && len(b.events) > b.limit {
+		drop := len(b.events) - b.limit
+		b.events = append(b.events[:0:0], b.events[drop:]...)
+		b.first += uint64(drop)
+	}
+	b.wakeLocked()
+}
+
+func (b *replayBuffer) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ended = time.Now()
+	b.wakeLocked()
+}
+
+// clear drops the buffered envelopes and ends the buffer, so subscribers
+// still following it stop.
+func (b *replayBuffer) clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.first += uint64(len(b.events))
+	b.events = nil
+	if b.ended.IsZero() {
+		b.ended = time.Now()
+	}
+	b.wakeLocked()
+}
+
+func (b *replayBuffer) wakeLocked() {
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+func (b *replayBuffer) endedAt() (time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ended, !b.ended.IsZero()
+}
+
+// since returns the envelopes after lastSeq, whether the run has ended, and
+// a channel closed on the next change.
+func (b *replayBuffer) since(lastSeq uint64) ([]Envelope, bool, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	start := 0
+	if lastSeq >= b.first {
+		start = int(lastSeq - b.first + 1)
+	}
+	if start > len(b.events) {
+		start = len(b.events)
+	}
+	out := append([]Envelope(nil), b.events[start:]...)
+	return out, !b.ended.IsZero(), b.notify
+}
+
+// ResumeStream replays the envelopes of a RunEvents run after lastSeq and
+// then follows the run live until its done or error envelope. Pass the Seq
+// of the last envelope received, or 0 to replay from the start. Runs stay
+// resumable for Options.StreamReplayRetention after they end; when older
+// envelopes were dropped (Options.StreamReplayLimit) the replay starts at
+// the oldest one kept, which shows as a gap in Seq.
+func (rt *Runtime) ResumeStream(ctx context.Context, runID string, lastSeq uint64) (<-chan Envelope, error) {
+	if rt == nil || rt.replay == nil {
+		return nil, ErrRuntimeClosed
+	}
+	runID = strings.TrimSpace(runID)
+	buf, ok := rt.replay.get(runID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrRunNotFound, runID)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out := make(chan Envelope, 64)
+	go func() {
+		defer close(out)
+		next := lastSeq
+		for {
+			events, ended, changed := buf.since(next)
+			for _, env := range events {
+				select {
+				case out <- env:
+					next = env.Seq
+				case <-ctx.Done():
+					return
+				}
+			}
+			if ended && len(events) == 0 {
+				return
+			}
+			if len(events) > 0 {
+				continue
+			}
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
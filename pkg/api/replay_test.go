@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+func TestResumeStreamReplaysMissedEvents(t *testing.T) {
+	rt, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: &deltaStreamModel{}, Tools: []tool.Tool{&echoTool{}}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	events, err := rt.RunEvents(context.Background(), Request{Prompt: "go", RequestID: "run-1"})
+	if err != nil {
+		t.Fatalf("RunEvents: %v", err)
+	}
+	var all []Envelope
+	for env := range events {
+		if env.RunID != "run-1" {
+			t.Fatalf("run id = %q", env.RunID)
+		}
+		all = append(all, env)
+	}
+	if len(all) < 4 {
+		t.Fatalf("too few envelopes: %d", len(all))
+	}
+
+	resumed, err := rt.ResumeStream(context.Background(), "run-1", 2)
+	if err != nil {
+		t.Fatalf("ResumeStream: %v", err)
+	}
+	var replayed []Envelope
+	for env := range resumed {
+		replayed = append(replayed, env)
+	}
+	if len(replayed) != len(all)-2 || replayed[0].Seq != 3 || replayed[len(replayed)-1].Type != EnvelopeDone {
+		t.Fatalf("replayed %d envelopes starting at %d, want %d from 3", len(replayed), replayed[0].Seq, len(all)-2)
+	}
+
+	if _, err := rt.ResumeStream(context.Background(), "missing", 0); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound, got %v", err)
+	}
+}
+
+func TestResumeStreamFollowsLiveRun(t *testing.T) {
+	rt := &Runtime{replay: newReplayStore(3, time.Minute)}
+	buf := rt.replay.open("r", "s")
+	enc := NewEnvelopeEncoder("s")
+	for i := 0; i < 5; i++ {
+		env, _ := enc.Encode(StreamEvent{Type: EventToolExecutionStart, Name: "echo"})
+		buf.append(env)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := rt.ResumeStream(ctx, "r", 0)
+	if err != nil {
+		t.Fatalf("ResumeStream: %v", err)
+	}
+	// Only the last three are kept.
+	for _, want := range []uint64{3, 4, 5} {
+		if env := <-stream; env.Seq != want {
+			t.Fatalf("seq = %d, want %d", env.Seq, want)
+		}
+	}
+	done, _ := enc.Done()
+	go func() {
+		buf.append(done)
+		buf.finish()
+	}()
+	if env := <-stream; env.Type != EnvelopeDone || env.Seq != 6 {
+		t.Fatalf("live envelope = %+v", env)
+	}
+	if _, ok := <-stream; ok {
+		t.Fatal("stream should close after the run ends")
+	}
+}
+
+func TestReplayStorePrunesExpiredRuns(t *testing.T) {
+	store := newReplayStore(10, time.Minute)
+	store.open("old", "s").finish()
+	store.open("live", "s")
+	store.mu.Lock()
+	store.pruneLocked(time.Now().Add(2 * time.Minute))
+	store.mu.Unlock()
+	if _, ok := store.get("old"); ok {
+		t.Fatal("expired run still buffered")
+	}
+	if _, ok := store.get("live"); !ok {
+		t.Fatal("running run pruned")
+	}
+}
@@ -10,7 +10,9 @@ import (
 )
 
 type RolloutWriter struct {
-	dir string
+	dir   string
+	codec Compressor
+	enc   Encryptor
 }
 
 type CompactEvent struct {
@@ -23,6 +25,8 @@ type CompactEvent struct {
 	EstimatedTokensAfter  int       `json:"estimated_tokens_after"`
 }
 
+const rolloutTimeLayout = "20060102T150405.000000000Z"
+
 func newRolloutWriter(projectRoot, dir string) *RolloutWriter {
 	dir = strings.TrimSpace(dir)
 	if dir == "" {
@@ -62,7 +66,11 @@ func (w *RolloutWriter) WriteCompactEvent(sessionID string, res compactResult) e
 	}
 	data = append(data, '\n')
 
-	filename := fmt.Sprintf("%s_%s_compact.json", safeRolloutName(sessionID), ts.Format("20060102T150405.000000000Z"))
+	filename := fmt.Sprintf("%s_%s_compact.json", safeRolloutName(sessionID), ts.Format(rolloutTimeLayout))
+	filename += compressorExtension(w.codec)
+	if data, err = sealAtRest(data, w.codec, w.enc); err != nil {
+		return fmt.Errorf("api: seal compact event: %w", err)
+	}
 	path := filepath.Join(dir, filename)
 	if err := atomicWriteFile(path, data, 0o600); err != nil {
 		return fmt.Errorf("api: write compact event: %w", err)
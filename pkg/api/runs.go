@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRunCanceled is returned (wrapped around the context error) by runs
+// aborted with Runtime.Cancel.
+var ErrRunCanceled = errors.New("api: run canceled")
+
+// ActiveRun describes a run currently holding its session.
+type ActiveRun struct {
+	// RunID is the Request.RequestID of the run; pass it to Cancel.
+	RunID     string    `json:"run_id"`
+	SessionID string    `json:"session_id"`
+	Streaming bool      `json:"streaming"`
+	StartedAt time.Time `json:"started_at"`
+	// Iteration is the model call in progress, starting at 0.
+	Iteration int `json:"iteration"`
+	// Queued reports that the run is waiting for one of the
+	// Options.MaxConcurrentRuns slots.
+	Queued bool `json:"queued,omitempty"`
+}
+
+// activeRun is the registry entry of one run.
+type activeRun struct {
+	info      ActiveRun
+	iteration atomic.Int64
+	queued    atomic.Bool
+	cancel    context.CancelCauseFunc
+}
+
+func (r *activeRun) setIteration(n int) {
+	if r != nil {
+		r.iteration.Store(int64(n))
+	}
+}
+
+func (r *activeRun) setQueued(queued bool) {
+	if r != nil {
+		r.queued.Store(queued)
+	}
+}
+
+type activeRuns struct {
+	mu   sync.Mutex
+	seq  uint64
+	runs map[uint64]*activeRun
+}
+
+// add registers a run; cancel aborts its context. The returned id is
+// passed to remove when the run ends.
+func (a *activeRuns) add(runID, sessionID string, streaming bool, cancel context.CancelCauseFunc) (uint64, *activeRun) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.runs == nil {
+		a.runs = map[uint64]*activeRun{}
+	}
+	a.seq++
+	run := &activeRun{
+		info:   ActiveRun{RunID: runID, SessionID: sessionID, Streaming: streaming, StartedAt: time.Now()},
+		cancel: cancel,
+	}
+	a.runs[a.seq] = run
+	return a.seq, run
+}
+
+func (a *activeRuns) remove(id uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.runs, id)
+}
+
+func (a *activeRuns) list() []ActiveRun {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]ActiveRun, 0, len(a.runs))
+	for _, run := range a.runs {
+		info := run.info
+		info.Iteration = int(run.iteration.Load())
+		info.Queued = run.queued.Load()
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out
+}
+
+// cancel aborts every in-flight run with runID and reports how many.
+func (a *activeRuns) cancel(runID string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := 0
+	for _, run := range a.runs {
+		if run.info.RunID == runID && run.cancel != nil {
+			run.cancel(ErrRunCanceled)
+			n++
+		}
+	}
+	return n
+}
+
+type activeRunKey struct{}
+
+func withActiveRun(ctx context.Context, run *activeRun) context.Context {
+	return context.WithValue(ctx, activeRunKey{}, run)
+}
+
+func activeRunFromContext(ctx context.Context) *activeRun {
+	if ctx == nil {
+		return nil
+	}
+	run, _ := ctx.Value(activeRunKey{}).(*activeRun)
+	return run
+}
+
+// Runs lists the in-flight runs, oldest first, including runs queued for a
+// MaxConcurrentRuns slot (ActiveRun.Queued). Runs waiting for a busy session
+// are not listed; see QueueDepth.
+func (rt *Runtime) Runs() []ActiveRun {
+	if rt == nil {
+		return nil
+	}
+	return rt.active.list()
+}
+
+// Cancel aborts the in-flight run with runID. Cancellation is cooperative:
+// the run's context is canceled, which stops the model call or tools in
+// progress, and the agent loop exits before its next iteration. Run then
+// returns an error wrapping ErrRunCanceled and RunStream ends with an error
+// event. Unknown or finished runs return ErrRunNotFound.
+func (rt *Runtime) Cancel(runID string) error {
+	if rt == nil {
+		return ErrRuntimeClosed
+	}
+	runID = strings.TrimSpace(runID)
+	if runID == "" || rt.active.cancel(runID) == 0 {
+		return fmt.Errorf("%w: %q", ErrRunNotFound, runID)
+	}
+	return nil
+}
+
+// canceledRunError marks err as caused by Cancel when ctx was canceled that
+// way.
+func canceledRunError(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(context.Cause(ctx), ErrRunCanceled) || errors.Is(err, ErrRunCanceled) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrRunCanceled, err)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// blockingTool waits until its context is canceled.
+type blockingTool struct{ started chan struct{} }
+
+func (*blockingTool) Name() string             { return "block" }
+func (*blockingTool) Description() string      { return "blocks until canceled" }
+func (*blockingTool) Schema() *tool.JSONSchema { return &tool.JSONSchema{Type: "object"} }
+func (b *blockingTool) Execute(ctx context.Context, _ map[string]interface{}) (*tool.ToolResult, error) {
+	close(b.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func newBlockingRuntime(t *testing.T) (*Runtime, *blockingTool) {
+	t.Helper()
+	block := &blockingTool{started: make(chan struct{})}
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "1", Name: "block", Arguments: map[string]any{"x": 1}}}}},
+		{Message: model.Message{Role: "assistant", Content: "unreachable"}},
+	}}
+	rt, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: mdl, Tools: []tool.Tool{block}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	return rt, block
+}
+
+func TestRuntimeCancelRun(t *testing.T) {
+	rt, block := newBlockingRuntime(t)
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := rt.Run(context.Background(), Request{Prompt: "go", SessionID: "s", RequestID: "run-1"})
+		errc <- err
+	}()
+	select {
+	case <-block.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool did not start")
+	}
+
+	runs := rt.Runs()
+	if len(runs) != 1 || runs[0].RunID != "run-1" || runs[0].SessionID != "s" || runs[0].Iteration != 0 || runs[0].StartedAt.IsZero() {
+		t.Fatalf("runs = %+v", runs)
+	}
+	if err := rt.Cancel("run-1"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrRunCanceled) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected ErrRunCanceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not stop after cancel")
+	}
+	if len(rt.Runs()) != 0 {
+		t.Fatalf("finished run still listed: %+v", rt.Runs())
+	}
+	if err := rt.Cancel("run-1"); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound, got %v", err)
+	}
+}
+
+func TestRuntimeCancelStream(t *testing.T) {
+	rt, block := newBlockingRuntime(t)
+
+	events, err := rt.RunStream(context.Background(), Request{Prompt: "go", RequestID: "run-2"})
+	if err != nil {
+		t.Fatalf("RunStream: %v", err)
+	}
+	go func() {
+		<-block.started
+		if len(rt.Runs()) != 1 || !rt.Runs()[0].Streaming {
+			t.Errorf("runs = %+v", rt.Runs())
+		}
+		if err := rt.Cancel("run-2"); err != nil {
+			t.Errorf("cancel: %v", err)
+		}
+	}()
+	var last StreamEvent
+	for evt := range events {
+		last = evt
+	}
+	if last.Type != EventError || !strings.Contains(last.Output.(string), ErrRunCanceled.Error()) {
+		t.Fatalf("last event = %+v", last)
+	}
+}
@@ -2,13 +2,16 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cexll/agentsdk-go/pkg/config"
@@ -105,6 +108,28 @@ func convertContentBlocksToModel(blocks []message.ContentBlock) []model.ContentB
 	return out
 }
 
+// mediaContentBlocks converts tool result media to history content blocks.
+func mediaContentBlocks(media []tool.Media) []message.ContentBlock {
+	if len(media) == 0 {
+		return nil
+	}
+	out := make([]message.ContentBlock, 0, len(media))
+	for _, m := range media {
+		block := message.ContentBlock{Type: message.ContentBlockImage, MediaType: m.MediaType, URL: m.URL}
+		if m.Kind == tool.MediaDocument {
+			block.Type = message.ContentBlockDocument
+		}
+		if len(m.Data) > 0 {
+			block.Data = base64.StdEncoding.EncodeToString(m.Data)
+		}
+		if block.Data == "" && block.URL == "" {
+			continue
+		}
+		out = append(out, block)
+	}
+	return out
+}
+
 func convertAPIContentBlocks(blocks []model.ContentBlock) []message.ContentBlock {
 	if len(blocks) == 0 {
 		return nil
@@ -129,10 +154,12 @@ func convertToolCalls(calls []message.ToolCall) []model.ToolCall {
 	out := make([]model.ToolCall, len(calls))
 	for i, call := range calls {
 		out[i] = model.ToolCall{
-			ID:        call.ID,
-			Name:      call.Name,
-			Arguments: cloneArguments(call.Arguments),
-			Result:    call.Result,
+			ID:           call.ID,
+			Name:         call.Name,
+			Arguments:    cloneArguments(call.Arguments),
+			Result:       call.Result,
+			IsError:      call.IsError,
+			ResultBlocks: convertContentBlocksToModel(call.ResultBlocks),
 		}
 	}
 	return out
@@ -181,10 +208,11 @@ func registerSubagents(registrations []SubagentRegistration) (*subagents.Manager
 	}
 	mgr := subagents.NewManager()
 	for _, entry := range registrations {
-		if entry.Handler == nil {
+		handler := subagentHandler(entry.Definition, entry.Handler)
+		if handler == nil {
 			return nil, errors.New("api: subagent handler is nil")
 		}
-		if err := mgr.Register(entry.Definition, entry.Handler); err != nil {
+		if err := mgr.Register(entry.Definition, handler); err != nil {
 			return nil, err
 		}
 	}
@@ -344,6 +372,7 @@ func mergeSubagentRegistrations(manual []SubagentRegistration, project []subagen
 			*errs = append(*errs, fmt.Errorf("api: subagent name is empty (%s)", source))
 			return
 		}
+		handler = subagentHandler(def, handler)
 		if handler == nil {
 			*errs = append(*errs, fmt.Errorf("api: subagent %s handler is nil", key))
 			return
@@ -366,6 +395,21 @@ func mergeSubagentRegistrations(manual []SubagentRegistration, project []subagen
 	return merged
 }
 
+// subagentHandler returns handler, or for a definition registered without
+// one but with a SystemPrompt, a handler that answers with the prompt like
+// a .claude/agents file does. The Task tool runs such definitions as nested
+// agent loops.
+func subagentHandler(def subagents.Definition, handler subagents.Handler) subagents.Handler {
+	if handler != nil || strings.TrimSpace(def.SystemPrompt) == "" {
+		return handler
+	}
+	prompt := strings.TrimSpace(def.SystemPrompt)
+	meta := maps.Clone(def.BaseContext.Metadata)
+	return subagents.HandlerFunc(func(context.Context, subagents.Context, subagents.Request) (subagents.Result, error) {
+		return subagents.Result{Output: prompt, Metadata: meta}, nil
+	})
+}
+
 type historyStore struct {
 	mu       sync.Mutex
 	data     map[string]*message.History
@@ -472,6 +516,47 @@ func cleanupToolOutputSessionDir(sessionID string) error {
 	return os.RemoveAll(toolOutputSessionDir(sessionID))
 }
 
+// newOutputPersister builds the tool output persister from settings'
+// toolOutput block, then applies Options.ToolOutputLimit(s) on top.
+func newOutputPersister(opts Options, settings *config.Settings) *tool.OutputPersister {
+	p := tool.NewOutputPersister()
+	p.BaseDir = toolOutputBaseDir()
+	if settings != nil && settings.ToolOutput != nil {
+		cfg := settings.ToolOutput
+		if cfg.DefaultThresholdBytes > 0 {
+			p.DefaultThresholdBytes = cfg.DefaultThresholdBytes
+		}
+		if len(cfg.PerToolThresholdBytes) > 0 {
+			p.PerToolThresholdBytes = make(map[string]int, len(cfg.PerToolThresholdBytes))
+			for name, v := range cfg.PerToolThresholdBytes {
+				p.PerToolThresholdBytes[strings.ToLower(strings.TrimSpace(name))] = v
+			}
+		}
+		if cfg.HeadBytes != nil {
+			p.HeadBytes = *cfg.HeadBytes
+		}
+		if cfg.TailBytes != nil {
+			p.TailBytes = *cfg.TailBytes
+		}
+		if cfg.Spill != nil {
+			p.NoSpill = !*cfg.Spill
+		}
+	}
+	if limit := opts.ToolOutputLimit; limit != (tool.OutputLimit{}) {
+		if limit.MaxBytes > 0 {
+			p.DefaultThresholdBytes = limit.MaxBytes
+		}
+		p.HeadBytes, p.TailBytes, p.NoSpill = limit.HeadBytes, limit.TailBytes, limit.NoSpill
+	}
+	if len(opts.ToolOutputLimits) > 0 {
+		p.PerToolLimits = make(map[string]tool.OutputLimit, len(opts.ToolOutputLimits))
+		for name, limit := range opts.ToolOutputLimits {
+			p.PerToolLimits[strings.ToLower(strings.TrimSpace(name))] = limit
+		}
+	}
+	return p
+}
+
 func sanitizePathComponent(value string) string {
 	const fallback = "default"
 	trimmed := strings.TrimSpace(value)
@@ -519,7 +604,8 @@ func snapshotSandbox(mgr *sandbox.Manager) SandboxReport {
 }
 
 type sessionGate struct {
-	gates sync.Map // map[string]chan struct{}
+	gates   sync.Map // map[string]chan struct{}
+	waiting atomic.Int64
 }
 
 func newSessionGate() *sessionGate {
@@ -542,10 +628,13 @@ func (g *sessionGate) Acquire(ctx context.Context, sessionID string) error {
 		}
 
 		held := existing.(chan struct{}) //nolint:errcheck // sync.Map guarantees type safety for stored values
+		g.waiting.Add(1)
 		select {
 		case <-held:
+			g.waiting.Add(-1)
 			continue
 		case <-ctx.Done():
+			g.waiting.Add(-1)
 			return ctx.Err()
 		}
 	}
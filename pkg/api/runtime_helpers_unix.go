@@ -11,3 +11,7 @@ func bashOutputBaseDir() string {
 func toolOutputBaseDir() string {
 	return filepath.Join(string(filepath.Separator), "tmp", "agentsdk", "tool-output")
 }
+
+func scratchBaseDir() string {
+	return filepath.Join(string(filepath.Separator), "tmp", "agentsdk", "scratch")
+}
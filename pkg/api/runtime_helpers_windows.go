@@ -14,3 +14,7 @@ func bashOutputBaseDir() string {
 func toolOutputBaseDir() string {
 	return filepath.Join(os.TempDir(), "agentsdk", "tool-output")
 }
+
+func scratchBaseDir() string {
+	return filepath.Join(os.TempDir(), "agentsdk", "scratch")
+}
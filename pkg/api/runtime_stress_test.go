@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/artifact"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// sessionEchoModel is stateless: the prompt carries the session ID, the model
+// calls the tagged tool once and then echoes both, so any cross-run leakage
+// shows up in outputs.
+type sessionEchoModel struct{}
+
+func (sessionEchoModel) Complete(_ context.Context, req model.Request) (*model.Response, error) {
+	if len(req.Messages) == 0 {
+		return nil, fmt.Errorf("no messages")
+	}
+	session := req.Messages[0].TextContent()
+	if last := req.Messages[len(req.Messages)-1]; last.Role == "tool" {
+		result := last.Content
+		if len(last.ToolCalls) > 0 {
+			result = last.ToolCalls[0].Result
+		}
+		return &model.Response{Message: model.Message{Role: "assistant", Content: "session=" + session + " tool=" + result}}, nil
+	}
+	return &model.Response{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{
+		ID:        "call-" + session,
+		Name:      "tagged",
+		Arguments: map[string]any{"session": session},
+	}}}}, nil
+}
+
+func (m sessionEchoModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	resp, err := m.Complete(ctx, req)
+	if err != nil {
+		return err
+	}
+	return cb(model.StreamResult{Final: true, Response: resp})
+}
+
+type taggedTool struct {
+	calls atomic.Int64
+}
+
+func (t *taggedTool) Name() string             { return "tagged" }
+func (t *taggedTool) Description() string      { return "echoes the session argument" }
+func (t *taggedTool) Schema() *tool.JSONSchema { return &tool.JSONSchema{Type: "object"} }
+func (t *taggedTool) Execute(_ context.Context, params map[string]interface{}) (*tool.ToolResult, error) {
+	t.calls.Add(1)
+	session := fmt.Sprint(params["session"])
+	return &tool.ToolResult{
+		Success:   true,
+		Output:    session,
+		Artifacts: []tool.Artifact{{Name: session + ".txt", Data: []byte(session)}},
+	}, nil
+}
+
+// TestRuntimeConcurrentRunsAreIsolated drives hundreds of Run and RunStream
+// calls through one Runtime. Run it with -race to check shared state.
+func TestRuntimeConcurrentRunsAreIsolated(t *testing.T) {
+	const runs = 200
+	tagged := &taggedTool{}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:   newClaudeProject(t),
+		Model:         sessionEchoModel{},
+		Tools:         []tool.Tool{tagged},
+		ArtifactStore: artifact.NewMemoryStore(),
+		Templates: map[string]RequestTemplate{
+			"tpl": {SystemPrompt: "templated", Tags: map[string]string{"source": "template"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	// Callers commonly share one tags map across requests; runs must not
+	// write template or skill tags back into it.
+	shared := map[string]string{"team": "core"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			session := fmt.Sprintf("s-%03d", i)
+			req := Request{Prompt: session, SessionID: session, Tags: shared}
+			if i%3 == 0 {
+				req.Template = "tpl"
+			}
+			if i%2 == 0 {
+				errs <- checkIsolatedRun(rt, req)
+				return
+			}
+			errs <- checkIsolatedStream(rt, req)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if got := tagged.calls.Load(); got != runs {
+		t.Fatalf("expected %d tool calls, got %d", runs, got)
+	}
+	if len(shared) != 1 {
+		t.Fatalf("request tags map was mutated: %v", shared)
+	}
+}
+
+func checkIsolatedRun(rt *Runtime, req Request) error {
+	resp, err := rt.Run(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("%s: run: %w", req.SessionID, err)
+	}
+	want := "session=" + req.SessionID + " tool=" + req.SessionID
+	if resp.Result == nil || resp.Result.Output != want {
+		return fmt.Errorf("%s: leaked output %+v", req.SessionID, resp.Result)
+	}
+	if resp.Tags["team"] != "core" {
+		return fmt.Errorf("%s: lost request tags %v", req.SessionID, resp.Tags)
+	}
+	if (req.Template != "") != (resp.Tags["source"] == "template") {
+		return fmt.Errorf("%s: template tags applied to wrong run: %v", req.SessionID, resp.Tags)
+	}
+	if len(resp.Artifacts) != 1 || resp.Artifacts[0].Name != req.SessionID+".txt" {
+		return fmt.Errorf("%s: leaked artifacts %+v", req.SessionID, resp.Artifacts)
+	}
+	return nil
+}
+
+func checkIsolatedStream(rt *Runtime, req Request) error {
+	stream, err := rt.RunStream(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("%s: stream: %w", req.SessionID, err)
+	}
+	var out strings.Builder
+	for evt := range stream {
+		if evt.Type == EventError {
+			return fmt.Errorf("%s: stream error: %v", req.SessionID, evt.Output)
+		}
+		if evt.Delta != nil {
+			out.WriteString(evt.Delta.Text)
+		}
+	}
+	if want := "session=" + req.SessionID + " tool=" + req.SessionID; !strings.Contains(out.String(), want) {
+		return fmt.Errorf("%s: leaked stream output %q", req.SessionID, out.String())
+	}
+	return nil
+}
@@ -1,11 +1,15 @@
 package api
 
 import (
+	"net"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/cexll/agentsdk-go/pkg/config"
 	"github.com/cexll/agentsdk-go/pkg/sandbox"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	toolbuiltin "github.com/cexll/agentsdk-go/pkg/tool/builtin"
 )
 
 type noopFileSystemPolicy struct {
@@ -99,3 +103,96 @@ func additionalSandboxPaths(settings *config.Settings) []string {
 	}
 	return out
 }
+
+// buildCommandIsolator prepares isolation for the Bash tool when
+// settings.Sandbox.Enabled is true: a per-session container when
+// sandbox.container is set, the OS sandbox otherwise. Writes are limited to
+// the project and sandbox roots, additional directories,
+// Sandbox.AllowedPaths and the scratch root. It returns nil when the sandbox
+// is not enabled.
+//
+// A running egress proxy is exported to sandboxed commands through the
+// proxy environment variables; on macOS other outbound TCP is blocked.
+func buildCommandIsolator(opts Options, settings *config.Settings, sbRoot string, proxy *sandbox.EgressProxy) sandbox.CommandIsolator {
+	if !sandboxEnabled(settings) {
+		return nil
+	}
+	cfg := settings.Sandbox
+	writable := []string{opts.ProjectRoot}
+	writable = append(writable, additionalSandboxPaths(settings)...)
+	writable = append(writable, opts.Sandbox.AllowedPaths...)
+	if strings.TrimSpace(opts.ScratchDir) != "" {
+		writable = append(writable, opts.ScratchDir)
+	}
+	if c := cfg.Container; c != nil {
+		return sandbox.NewContainerSandbox(sandbox.ContainerConfig{
+			Runtime:          c.Runtime,
+			Image:            c.Image,
+			Root:             sbRoot,
+			WritablePaths:    writable,
+			CPUs:             c.CPUs,
+			Memory:           c.Memory,
+			Network:          c.Network,
+			ExcludedCommands: cfg.ExcludedCommands,
+			Env:              proxy.Env(),
+		})
+	}
+	osCfg := sandbox.OSConfig{
+		Root:             sbRoot,
+		WritablePaths:    writable,
+		ExcludedCommands: cfg.ExcludedCommands,
+		Weaker:           cfg.EnableWeakerNestedSandbox != nil && *cfg.EnableWeakerNestedSandbox,
+		Env:              proxy.Env(),
+	}
+	if proxy != nil {
+		osCfg.ProxyPorts = proxy.Ports()
+	}
+	if cfg.Network != nil {
+		osCfg.AllowUnixSockets = cfg.Network.AllowUnixSockets
+		osCfg.AllowLocalBinding = cfg.Network.AllowLocalBinding != nil && *cfg.Network.AllowLocalBinding
+	}
+	return sandbox.NewOSSandbox(osCfg)
+}
+
+// attachIsolator hands iso to the registered Bash tool.
+func attachIsolator(registry *tool.Registry, iso sandbox.CommandIsolator) {
+	if registry == nil || iso == nil {
+		return
+	}
+	impl, err := registry.Get("Bash")
+	if err != nil {
+		return
+	}
+	if bash, ok := impl.(*toolbuiltin.BashTool); ok {
+		bash.SetIsolator(iso)
+	}
+}
+
+// sandboxEnabled reports whether settings.Sandbox.Enabled is true.
+func sandboxEnabled(settings *config.Settings) bool {
+	return settings != nil && settings.Sandbox != nil && settings.Sandbox.Enabled != nil && *settings.Sandbox.Enabled
+}
+
+// startEgressProxy starts the filtering proxy on the loopback ports set by
+// sandbox.network.httpProxyPort and socksProxyPort. Only hosts the sandbox
+// manager accepts (network allowlist and policy) are reached. It returns nil
+// when the sandbox is disabled or no port is set.
+func startEgressProxy(settings *config.Settings, mgr *sandbox.Manager) (*sandbox.EgressProxy, error) {
+	if !sandboxEnabled(settings) || settings.Sandbox.Network == nil {
+		return nil, nil
+	}
+	network := settings.Sandbox.Network
+	var httpAddr, socksAddr string
+	if network.HTTPProxyPort != nil {
+		httpAddr = net.JoinHostPort("127.0.0.1", strconv.Itoa(*network.HTTPProxyPort))
+	}
+	if network.SocksProxyPort != nil {
+		socksAddr = net.JoinHostPort("127.0.0.1", strconv.Itoa(*network.SocksProxyPort))
+	}
+	if httpAddr == "" && socksAddr == "" {
+		return nil, nil
+	}
+	return sandbox.NewEgressProxy(func(host string) error {
+		return mgr.Enforce("", host, sandbox.ResourceUsage{})
+	}, httpAddr, socksAddr)
+}
@@ -1,10 +1,22 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/sandbox"
+	"github.com/cexll/agentsdk-go/pkg/security"
+	toolbuiltin "github.com/cexll/agentsdk-go/pkg/tool/builtin"
 )
 
 func TestAdditionalSandboxPathsHandlesNilAndDedup(t *testing.T) {
@@ -97,3 +109,149 @@ func TestNoopFileSystemPolicyRootsAndAllow(t *testing.T) {
 		t.Fatalf("expected nil roots for blank root, got %+v", roots)
 	}
 }
+
+func TestRuntimeAttachesIsolatorToBash(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{"sandbox":{"enabled":true,"excludedCommands":["docker"],"network":{"allowUnixSockets":["/var/run/docker.sock"]}}}`)
+	mdl := &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "ok"}}}}
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl})
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	defer rt.Close()
+
+	impl, err := rt.registry.Get("Bash")
+	if err != nil {
+		t.Fatalf("bash tool: %v", err)
+	}
+	bash, ok := impl.(*toolbuiltin.BashTool)
+	if !ok || bash.Isolator() == nil || bash.Isolator() != rt.isolator {
+		t.Fatalf("expected runtime OS sandbox on Bash, got %#v", impl)
+	}
+
+	status := rt.sandboxReport().OS
+	if status.Enforced == (status.Reason != "") {
+		t.Fatalf("status must explain a missing backend: %+v", status)
+	}
+	if !slices.Contains(status.WritablePaths, root) {
+		t.Fatalf("project root should be writable: %+v", status.WritablePaths)
+	}
+	if !slices.Equal(status.ExcludedCommands, []string{"docker"}) || !slices.Contains(status.AllowUnixSockets, "/var/run/docker.sock") {
+		t.Fatalf("settings not honoured: %+v", status)
+	}
+}
+
+func TestBuildCommandIsolatorPrefersContainer(t *testing.T) {
+	enabled := true
+	root := t.TempDir()
+	settings := &config.Settings{Sandbox: &config.SandboxConfig{
+		Enabled:   &enabled,
+		Container: &config.SandboxContainerConfig{Runtime: "podman", Image: "golang:1.24", Memory: "2g"},
+	}}
+	iso := buildCommandIsolator(Options{ProjectRoot: root}, settings, root, nil)
+	container, ok := iso.(*sandbox.ContainerSandbox)
+	if !ok {
+		t.Fatalf("expected container sandbox, got %T", iso)
+	}
+	status := container.Status()
+	if status.Image != "golang:1.24" || !slices.Contains(status.WritablePaths, root) {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if !status.Enforced && status.Reason == "" {
+		t.Fatalf("unenforced container sandbox must explain why: %+v", status)
+	}
+	if err := container.Close(); err != nil {
+		t.Fatalf("close without containers: %v", err)
+	}
+}
+
+func TestBuildCommandIsolatorRequiresEnabledSetting(t *testing.T) {
+	disabled := false
+	for _, settings := range []*config.Settings{nil, {}, {Sandbox: &config.SandboxConfig{Enabled: &disabled}}} {
+		if iso := buildCommandIsolator(Options{ProjectRoot: t.TempDir()}, settings, "", nil); iso != nil {
+			t.Fatalf("expected no OS sandbox for %+v", settings)
+		}
+	}
+}
+
+func freeLoopbackPort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestRuntimeEgressProxyFeedsBashAndReport(t *testing.T) {
+	port := freeLoopbackPort(t)
+	root := newClaudeProjectWithSettings(t, fmt.Sprintf(`{"sandbox":{"enabled":true,"network":{"httpProxyPort":%d}}}`, port))
+	mdl := &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "ok"}}}}
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl, Sandbox: SandboxOptions{NetworkAllow: []string{"allowed.example"}}})
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	defer rt.Close()
+
+	proxyURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+	if got := rt.sandboxReport().Egress.HTTPAddr; got != strings.TrimPrefix(proxyURL, "http://") {
+		t.Fatalf("unexpected proxy address %q", got)
+	}
+
+	impl, err := rt.registry.Get("Bash")
+	if err != nil {
+		t.Fatalf("bash tool: %v", err)
+	}
+	res, err := impl.Execute(context.Background(), map[string]any{"command": "printenv HTTPS_PROXY"})
+	if err != nil {
+		t.Fatalf("bash: %v", err)
+	}
+	if strings.TrimSpace(res.Output) != proxyURL {
+		t.Fatalf("expected proxy env in sandboxed command, got %q", res.Output)
+	}
+
+	proxy, _ := url.Parse(proxyURL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxy)}}
+	resp, err := client.Get("http://blocked.example/")
+	if err != nil {
+		t.Fatalf("proxy request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected blocked request, got %d", resp.StatusCode)
+	}
+	requests := rt.sandboxReport().Egress.Requests
+	if len(requests) != 1 || requests[0].Allowed || requests[0].Host != "blocked.example" {
+		t.Fatalf("unexpected egress log %+v", requests)
+	}
+
+	if err := rt.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if conn, err := net.Dial("tcp", proxy.Host); err == nil {
+		conn.Close()
+		t.Fatal("expected proxy to stop on Close")
+	}
+}
+
+func TestBuiltinToolsWriteToAdditionalDirectories(t *testing.T) {
+	root := t.TempDir()
+	extra := t.TempDir()
+	outside := t.TempDir()
+	settings := &config.Settings{Permissions: &config.PermissionsConfig{AdditionalDirectories: []string{extra}}}
+	factories := builtinToolFactories(root, "", false, EntryPointCLI, settings, nil, nil, nil)
+
+	write := factories["file_write"]()
+	if _, err := write.Execute(context.Background(), map[string]any{"file_path": filepath.Join(extra, "notes.md"), "content": "x"}); err != nil {
+		t.Fatalf("write to additional directory: %v", err)
+	}
+	_, err := write.Execute(context.Background(), map[string]any{"file_path": filepath.Join(outside, "notes.md"), "content": "x"})
+	if !errors.Is(err, security.ErrWriteOutsideScope) {
+		t.Fatalf("expected write scope error, got %v", err)
+	}
+
+	bash := factories["bash"]()
+	if _, err := bash.Execute(context.Background(), map[string]any{"command": "echo hi > " + filepath.Join(outside, "out.txt")}); !errors.Is(err, security.ErrWriteOutsideScope) {
+		t.Fatalf("expected bash write scope error, got %v", err)
+	}
+}
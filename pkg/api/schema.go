@@ -0,0 +1,128 @@
+package api
+
+//go:generate go run ../../cmd/schemagen -out ../../schemas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// schemaBaseURL prefixes the $id of every published schema.
+const schemaBaseURL = "https://github.com/cexll/agentsdk-go/schemas/"
+
+// schemaSpec describes one published schema: the Go type it is generated
+// from and the version of that type's wire format.
+type schemaSpec struct {
+	name    string
+	title   string
+	version int
+	typ     reflect.Type
+}
+
+var schemaSpecs = []schemaSpec{
+	{"stream_event", "StreamEvent", EventSchemaVersion, reflect.TypeFor[StreamEvent]()},
+	{"envelope", "Envelope", EventSchemaVersion, reflect.TypeFor[Envelope]()},
+	{"transcript", "SessionTranscript", TranscriptVersion, reflect.TypeFor[SessionTranscript]()},
+}
+
+// SchemaFile is one generated JSON Schema.
+type SchemaFile struct {
+	// Path is relative to the schema root, e.g. "v1/stream_event.schema.json".
+	Path    string
+	Name    string
+	Version int
+	Data    []byte
+}
+
+// JSONSchemas generates the JSON Schemas (draft 2020-12) of the stream
+// events, RunEvents envelopes and persisted session transcripts from their
+// Go types. Objects accept unknown properties, matching the rule that
+// fields are added without a version bump. `go generate ./pkg/api` writes
+// them under schemas/.
+func JSONSchemas() ([]SchemaFile, error) {
+	opts := &jsonschema.ForOptions{TypeSchemas: map[reflect.Type]*jsonschema.Schema{
+		reflect.TypeFor[json.RawMessage](): {},
+	}}
+	out := make([]SchemaFile, 0, len(schemaSpecs))
+	for _, spec := range schemaSpecs {
+		schema, err := jsonschema.ForType(spec.typ, opts)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %w", spec.name, err)
+		}
+		openObjects(schema)
+		file := path.Join(fmt.Sprintf("v%d", spec.version), spec.name+".schema.json")
+		schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+		schema.ID = schemaBaseURL + file
+		schema.Title = spec.title
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("encode schema %s: %w", spec.name, err)
+		}
+		out = append(out, SchemaFile{Path: file, Name: spec.name, Version: spec.version, Data: append(data, '\n')})
+	}
+	return out, nil
+}
+
+// openObjects drops the additionalProperties:false the generator puts on
+// structs so older clients keep validating newer payloads.
+func openObjects(s *jsonschema.Schema) {
+	if s == nil {
+		return
+	}
+	if ap := s.AdditionalProperties; ap != nil && ap.Not != nil && s.Properties != nil {
+		s.AdditionalProperties = nil
+	}
+	openObjects(s.AdditionalProperties)
+	openObjects(s.Items)
+	for _, prop := range s.Properties {
+		openObjects(prop)
+	}
+}
+
+// SchemaHandler serves the schemas from JSONSchemas: GET / lists them and
+// GET /<path> (e.g. /v1/stream_event.schema.json) returns one. Mount it
+// with http.StripPrefix.
+func SchemaHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		files, err := JSONSchemas()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		want := strings.Trim(r.URL.Path, "/")
+		if want == "" {
+			type entry struct {
+				Name    string `json:"name"`
+				Version int    `json:"version"`
+				Path    string `json:"path"`
+			}
+			index := make([]entry, 0, len(files))
+			for _, f := range files {
+				index = append(index, entry{Name: f.Name, Version: f.Version, Path: f.Path})
+			}
+			sort.Slice(index, func(i, j int) bool { return index[i].Path < index[j].Path })
+			writeAdminJSON(w, index)
+			return
+		}
+		for _, f := range files {
+			if f.Path == want {
+				w.Header().Set("Content-Type", "application/schema+json")
+				_, _ = w.Write(f.Data)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCommittedSchemasUpToDate fails when a wire type changed without
+// rerunning `go generate ./pkg/api`.
+func TestCommittedSchemasUpToDate(t *testing.T) {
+	files, err := JSONSchemas()
+	if err != nil {
+		t.Fatalf("JSONSchemas: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 schemas, got %d", len(files))
+	}
+	for _, f := range files {
+		committed, err := os.ReadFile(filepath.Join("..", "..", "schemas", filepath.FromSlash(f.Path)))
+		if err != nil {
+			t.Fatalf("read %s: %v (run go generate ./pkg/api)", f.Path, err)
+		}
+		if !bytes.Equal(committed, f.Data) {
+			t.Fatalf("schemas/%s is stale; run go generate ./pkg/api", f.Path)
+		}
+	}
+}
+
+func TestJSONSchemasDescribeWireFields(t *testing.T) {
+	files, err := JSONSchemas()
+	if err != nil {
+		t.Fatalf("JSONSchemas: %v", err)
+	}
+	byName := map[string]map[string]any{}
+	for _, f := range files {
+		var doc map[string]any
+		if err := json.Unmarshal(f.Data, &doc); err != nil {
+			t.Fatalf("%s is not JSON: %v", f.Path, err)
+		}
+		if _, closed := doc["additionalProperties"]; closed {
+			t.Fatalf("%s should accept unknown properties", f.Path)
+		}
+		byName[f.Name] = doc
+	}
+	props := func(name string) map[string]any {
+		p, _ := byName[name]["properties"].(map[string]any)
+		return p
+	}
+	for _, field := range []string{"type", "run_id", "event_id", "tool_use_id"} {
+		if _, ok := props("stream_event")[field]; !ok {
+			t.Fatalf("stream_event schema missing %q", field)
+		}
+	}
+	for _, field := range []string{"version", "type", "seq", "payload"} {
+		if _, ok := props("envelope")[field]; !ok {
+			t.Fatalf("envelope schema missing %q", field)
+		}
+	}
+	if id := byName["transcript"]["$id"]; id != schemaBaseURL+"v1/transcript.schema.json" {
+		t.Fatalf("unexpected transcript $id %v", id)
+	}
+}
+
+func TestSchemaHandler(t *testing.T) {
+	h := http.StripPrefix("/schemas", SchemaHandler())
+	get := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	var index []struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	}
+	if rec := get(http.MethodGet, "/schemas/"); rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &index) != nil || len(index) != 3 {
+		t.Fatalf("index status %d: %s", rec.Code, rec.Body)
+	}
+	rec := get(http.MethodGet, "/schemas/"+index[0].Path)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/schema+json" {
+		t.Fatalf("schema status %d type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec := get(http.MethodGet, "/schemas/v9/missing.schema.json"); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing schema status %d", rec.Code)
+	}
+	if rec := get(http.MethodPost, "/schemas/"); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("post status %d", rec.Code)
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+const (
+	// scratchPromptVar exposes the run scratch directory to prompt templates.
+	scratchPromptVar = "scratch_dir"
+
+	defaultScratchRetention = 24 * time.Hour
+	minJanitorInterval      = time.Minute
+	maxJanitorInterval      = time.Hour
+)
+
+// scratchSpace manages per-run scratch directories laid out as
+// <root>/<session>/<request>/iter-<n>. A run removes its directory when it
+// ends; the janitor sweeps directories left behind by crashed processes or
+// runs that never finished.
+type scratchSpace struct {
+	root string
+	ttl  time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newScratchSpace(root string, ttl time.Duration) *scratchSpace {
+	if root == "" {
+		return nil
+	}
+	if ttl <= 0 {
+		ttl = defaultScratchRetention
+	}
+	return &scratchSpace{root: filepath.Clean(root), ttl: ttl}
+}
+
+// runDir returns the scratch directory of a run. It is created lazily by
+// iterationDir so runs that call no tools leave nothing behind.
+func (s *scratchSpace) runDir(sessionID, requestID string) string {
+	if s == nil {
+		return ""
+	}
+	return filepath.Join(s.root, sanitizePathComponent(sessionID), sanitizePathComponent(requestID))
+}
+
+// iterationDir creates and returns the directory of one model iteration.
+func iterationDir(runDir string, iteration int) (string, error) {
+	dir := filepath.Join(runDir, "iter-"+strconv.Itoa(iteration))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// release removes a run directory and its session directory once empty.
+func (s *scratchSpace) release(runDir string) {
+	if s == nil || runDir == "" {
+		return
+	}
+	if err := os.RemoveAll(runDir); err != nil {
+		log.Printf("api: scratch cleanup %s failed: %v", runDir, err)
+		return
+	}
+	_ = os.Remove(filepath.Dir(runDir)) // fails while other runs of the session hold it
+}
+
+// sweep removes run directories not modified since now-ttl and returns how
+// many it removed.
+func (s *scratchSpace) sweep(now time.Time) int {
+	if s == nil {
+		return 0
+	}
+	sessions, err := os.ReadDir(s.root)
+	if err != nil {
+		return 0
+	}
+	cutoff := now.Add(-s.ttl)
+	removed := 0
+	for _, sess := range sessions {
+		if !sess.IsDir() {
+			continue
+		}
+		sessDir := filepath.Join(s.root, sess.Name())
+		runs, err := os.ReadDir(sessDir)
+		if err != nil {
+			continue
+		}
+		for _, run := range runs {
+			info, err := run.Info()
+			if err != nil || info.ModTime().After(cutoff) {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(sessDir, run.Name())); err != nil {
+				log.Printf("api: scratch sweep %s failed: %v", run.Name(), err)
+				continue
+			}
+			removed++
+		}
+		_ = os.Remove(sessDir)
+	}
+	return removed
+}
+
+// startJanitor sweeps once and then periodically until close.
+func (s *scratchSpace) startJanitor() {
+	if s == nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.sweep(time.Now())
+	interval := s.ttl / 2
+	if interval > maxJanitorInterval {
+		interval = maxJanitorInterval
+	}
+	if interval < minJanitorInterval {
+		interval = minJanitorInterval
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case now := <-ticker.C:
+				s.sweep(now)
+			}
+		}
+	}()
+}
+
+func (s *scratchSpace) close() {
+	if s == nil || s.stop == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+// scratchContext attaches the directory of the given iteration to ctx.
+func scratchContext(ctx context.Context, runDir string, iteration int) context.Context {
+	if runDir == "" {
+		return ctx
+	}
+	dir, err := iterationDir(runDir, iteration)
+	if err != nil {
+		log.Printf("api: scratch dir %s: %v", runDir, err)
+		return ctx
+	}
+	return tool.WithScratch(ctx, tool.Scratch{Run: runDir, Iteration: dir})
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+type scratchProbeTool struct{ seen []tool.Scratch }
+
+func (*scratchProbeTool) Name() string             { return "probe" }
+func (*scratchProbeTool) Description() string      { return "records the scratch dir" }
+func (*scratchProbeTool) Schema() *tool.JSONSchema { return &tool.JSONSchema{Type: "object"} }
+func (p *scratchProbeTool) Execute(ctx context.Context, _ map[string]interface{}) (*tool.ToolResult, error) {
+	s, ok := tool.ScratchFromContext(ctx)
+	if !ok {
+		return &tool.ToolResult{Success: true, Output: "none"}, nil
+	}
+	if err := os.WriteFile(filepath.Join(s.Iteration, "out.txt"), []byte("x"), 0o600); err != nil {
+		return nil, err
+	}
+	p.seen = append(p.seen, s)
+	return &tool.ToolResult{Success: true, Output: s.Iteration}, nil
+}
+
+func TestRuntimeScratchDirPerIteration(t *testing.T) {
+	scratchRoot := t.TempDir()
+	probe := &scratchProbeTool{}
+	call := func(id string) *model.Response {
+		return &model.Response{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: id, Name: "probe", Arguments: map[string]any{"x": 1}}}}}
+	}
+	mdl := &stubModel{responses: []*model.Response{
+		call("1"), call("2"),
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	rt, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: mdl, Tools: []tool.Tool{probe}, ScratchDir: scratchRoot})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	if _, err := rt.Run(context.Background(), Request{Prompt: "go", SessionID: "sess", RequestID: "req"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(probe.seen) != 2 {
+		t.Fatalf("expected 2 scratch observations, got %+v", probe.seen)
+	}
+	wantRun := filepath.Join(scratchRoot, "sess", "req")
+	first, second := probe.seen[0], probe.seen[1]
+	if first.Run != wantRun || second.Run != wantRun {
+		t.Fatalf("unexpected run dirs %+v", probe.seen)
+	}
+	if first.Iteration == second.Iteration || filepath.Dir(first.Iteration) != wantRun {
+		t.Fatalf("expected distinct iteration dirs under run, got %+v", probe.seen)
+	}
+	if _, err := os.Stat(wantRun); !os.IsNotExist(err) {
+		t.Fatalf("expected run dir removed after run, stat err=%v", err)
+	}
+}
+
+func TestRuntimeScratchPromptVar(t *testing.T) {
+	scratchRoot := t.TempDir()
+	rt, err := New(context.Background(), Options{ProjectRoot: newClaudeProject(t), Model: &stubModel{}, ScratchDir: scratchRoot})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	prep, err := rt.prepare(context.Background(), Request{Prompt: "hi", SessionID: "s", RequestID: "r"})
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	want := filepath.Join(scratchRoot, "s", "r")
+	if prep.scratch != want || prep.normalized.PromptVars[scratchPromptVar] != want {
+		t.Fatalf("scratch=%q vars=%v", prep.scratch, prep.normalized.PromptVars)
+	}
+
+	prep, err = rt.prepare(context.Background(), Request{Prompt: "hi", SessionID: "s", PromptVars: map[string]any{scratchPromptVar: "mine"}})
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if prep.normalized.PromptVars[scratchPromptVar] != "mine" {
+		t.Fatalf("caller value overwritten: %v", prep.normalized.PromptVars)
+	}
+}
+
+func TestScratchSweepRemovesStaleRuns(t *testing.T) {
+	root := t.TempDir()
+	s := newScratchSpace(root, time.Hour)
+	stale := s.runDir("a", "old")
+	fresh := s.runDir("b", "new")
+	for _, dir := range []string{stale, fresh} {
+		if _, err := iterationDir(dir, 0); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(stale, past, past); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	if n := s.sweep(time.Now()); n != 1 {
+		t.Fatalf("expected 1 removal, got %d", n)
+	}
+	if _, err := os.Stat(filepath.Dir(stale)); !os.IsNotExist(err) {
+		t.Fatalf("expected empty session dir pruned, err=%v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Fatalf("fresh run removed: %v", err)
+	}
+}
+
+func TestScratchEnv(t *testing.T) {
+	env := tool.Scratch{Run: "/r", Iteration: "/r/iter-1"}.Env()
+	joined := strings.Join(env, "\n")
+	for _, want := range []string{tool.ScratchDirEnv + "=/r/iter-1", tool.RunScratchDirEnv + "=/r", "TMPDIR=/r/iter-1"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("missing %q in %v", want, env)
+		}
+	}
+	if (tool.Scratch{}).Env() != nil {
+		t.Fatal("expected no env without scratch")
+	}
+}
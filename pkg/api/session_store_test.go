@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/session"
+)
+
+func TestSessionStoreResumesAcrossRuntimes(t *testing.T) {
+	root := t.TempDir()
+	store, err := session.NewFileStore(filepath.Join(t.TempDir(), "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	reply := &model.Response{Message: model.Message{Role: "assistant", Content: "ok"}}
+	first := &stubModel{responses: []*model.Response{reply}}
+	rt, err := New(ctx, Options{ProjectRoot: root, Model: first, SessionStore: store})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := rt.Run(ctx, Request{Prompt: "remember me", SessionID: "s1"}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	_ = rt.Close()
+
+	second := &stubModel{responses: []*model.Response{reply}}
+	rt, err = New(ctx, Options{ProjectRoot: root, Model: second, SessionStore: store})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer rt.Close()
+	if _, err := rt.Run(ctx, Request{Prompt: "again", SessionID: "s1"}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	msgs := second.requests[0].Messages
+	if len(msgs) != 3 || msgs[0].Content != "remember me" || msgs[2].Content != "again" {
+		t.Fatalf("history not restored: %+v", msgs)
+	}
+
+	report, err := rt.PurgeData(ctx, PurgeSelector{SessionIDs: []string{"s1"}})
+	if err != nil || report.HistoryFiles != 1 {
+		t.Fatalf("purge report=%+v err=%v", report, err)
+	}
+	if ids, _ := store.List(ctx); len(ids) != 0 {
+		t.Fatalf("expected store emptied, got %v", ids)
+	}
+}
+
+func TestSessionStoreAppliesCompressionAndEncryption(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(t.TempDir(), "sessions")
+	store, err := session.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := NewKeyRing("k1", bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	reply := &model.Response{Message: model.Message{Role: "assistant", Content: "ok"}}
+	opts := Options{ProjectRoot: root, SessionStore: store, Compression: NewZstdCompressor(0), Encryption: NewAESGCMEncryptor(ring)}
+
+	opts.Model = &stubModel{responses: []*model.Response{reply}}
+	rt, err := New(ctx, opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := rt.Run(ctx, Request{Prompt: "top secret", SessionID: "s1"}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	_ = rt.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("entries %v err=%v", entries, err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(raw) || strings.Contains(string(raw), "top secret") {
+		t.Fatalf("session payload stored in the clear: %q", raw)
+	}
+
+	second := &stubModel{responses: []*model.Response{reply}}
+	opts.Model = second
+	rt, err = New(ctx, opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer rt.Close()
+	if _, err := rt.Run(ctx, Request{Prompt: "again", SessionID: "s1"}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if msgs := second.requests[0].Messages; len(msgs) != 3 || msgs[0].Content != "top secret" {
+		t.Fatalf("history not restored: %+v", msgs)
+	}
+}
+
+// plainStore is a session.Store that cannot seal its payloads.
+type plainStore struct{ session.Store }
+
+func TestSessionStoreRejectsUnsealableStoreWithEncryption(t *testing.T) {
+	store, err := session.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = New(context.Background(), Options{
+		ProjectRoot:  t.TempDir(),
+		Model:        &stubModel{},
+		SessionStore: plainStore{store},
+		Compression:  NewGzipCompressor(0),
+	})
+	if err == nil || !strings.Contains(err.Error(), "cannot apply Compression or Encryption") {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+}
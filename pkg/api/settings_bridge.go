@@ -14,6 +14,14 @@ import (
 // applies an optional explicit settings path on top. Runtime overrides from
 // api.Options always win.
 func loadSettings(opts Options) (*config.Settings, error) {
+	settings, _, err := loadSettingsWithProvenance(opts)
+	return settings, err
+}
+
+// loadSettingsWithProvenance is loadSettings that also records which layer
+// set each top-level key; an explicit settings file is reported as
+// "file:<path>".
+func loadSettingsWithProvenance(opts Options) (*config.Settings, config.SettingsProvenance, error) {
 	loader := opts.SettingsLoader
 	if loader == nil {
 		loader = &config.SettingsLoader{ProjectRoot: opts.ProjectRoot}
@@ -27,23 +35,27 @@ func loadSettings(opts Options) (*config.Settings, error) {
 		loader.RuntimeOverrides = config.MergeSettings(loader.RuntimeOverrides, opts.SettingsOverrides)
 	}
 
-	settings, err := loader.Load()
+	settings, provenance, err := loader.LoadWithProvenance()
 	if err != nil {
-		return nil, fmt.Errorf("api: load settings: %w", err)
+		return nil, nil, fmt.Errorf("api: load settings: %w", err)
 	}
 	if settings == nil {
-		return nil, errors.New("api: settings loader returned nil")
+		return nil, nil, errors.New("api: settings loader returned nil")
+	}
+	if provenance == nil {
+		provenance = config.SettingsProvenance{}
 	}
 
 	if path := strings.TrimSpace(opts.SettingsPath); path != "" {
 		overlay, err := loadSettingsFile(path)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if overlay != nil {
 			if merged := config.MergeSettings(settings, overlay); merged != nil {
 				settings = merged
 			}
+			provenance.Record("file:"+path, overlay)
 		}
 	}
 
@@ -51,12 +63,13 @@ func loadSettings(opts Options) (*config.Settings, error) {
 		if merged := config.MergeSettings(settings, opts.SettingsOverrides); merged != nil {
 			settings = merged
 		}
+		provenance.Record(config.LayerRuntime, opts.SettingsOverrides)
 	}
 
 	if settings.Env == nil {
 		settings.Env = map[string]string{}
 	}
-	return settings, nil
+	return settings, provenance, nil
 }
 
 // loadSettingsFile decodes a single settings.json file. Missing files are
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	toolbuiltin "github.com/cexll/agentsdk-go/pkg/tool/builtin"
+)
+
+// newShellSessions builds the persistent-shell manager configured by the
+// bashSession setting, or nil when shells are per command.
+func newShellSessions(settings *config.Settings) *toolbuiltin.ShellSessionManager {
+	if settings == nil || settings.BashSession == nil {
+		return nil
+	}
+	cfg := settings.BashSession
+	var scope toolbuiltin.ShellScope
+	switch strings.ToLower(strings.TrimSpace(cfg.Scope)) {
+	case "run":
+		scope = toolbuiltin.ShellScopeRun
+	case "session":
+		scope = toolbuiltin.ShellScopeSession
+	default:
+		return nil
+	}
+	return toolbuiltin.NewShellSessionManager(toolbuiltin.ShellSessionConfig{
+		Scope:          scope,
+		IdleTimeout:    time.Duration(cfg.IdleTimeoutSeconds) * time.Second,
+		MaxOutputBytes: cfg.MaxOutputBytes,
+	})
+}
+
+// shellSessions returns the manager of the registered Bash tool, if any.
+func (rt *Runtime) shellSessions() *toolbuiltin.ShellSessionManager {
+	if rt == nil || rt.registry == nil {
+		return nil
+	}
+	impl, err := rt.registry.Get("Bash")
+	if err != nil {
+		return nil
+	}
+	if bash, ok := impl.(*toolbuiltin.BashTool); ok {
+		return bash.ShellSessions()
+	}
+	return nil
+}
+
+// ShellSessions lists the persistent bash shells kept by the bashSession
+// setting.
+func (rt *Runtime) ShellSessions() []toolbuiltin.ShellSessionInfo {
+	return rt.shellSessions().Sessions()
+}
+
+// KillShellSession stops the persistent shell of a run ID (scope "run") or
+// session ID (scope "session") together with everything it started. A
+// command still running fails; the next Bash call starts a fresh shell. It
+// reports whether a shell was running.
+func (rt *Runtime) KillShellSession(id string) bool {
+	return rt.shellSessions().Kill(id)
+}
+
+// releaseRunShell closes the shell of a finished run in run scope.
+func (rt *Runtime) releaseRunShell(runID string) {
+	if mgr := rt.shellSessions(); mgr.Scope() == toolbuiltin.ShellScopeRun {
+		mgr.Kill(runID)
+	}
+}
+
+// releaseSessionShell closes the shell of a purged session in session scope.
+func (rt *Runtime) releaseSessionShell(sessionID string) {
+	if mgr := rt.shellSessions(); mgr.Scope() == toolbuiltin.ShellScopeSession {
+		mgr.Kill(sessionID)
+	}
+}
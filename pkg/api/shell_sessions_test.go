@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func bashCall(command string) *model.Response {
+	return &model.Response{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{Name: "Bash", Arguments: map[string]any{"command": command}}}}}
+}
+
+func TestRuntimeBashSessionScopes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash sessions need a POSIX shell")
+	}
+	for _, scope := range []string{"run", "session"} {
+		t.Run(scope, func(t *testing.T) {
+			root := newClaudeProjectWithSettings(t, `{"permissions":{"allow":["Bash"]},"sandbox":{"enabled":false},"bashSession":{"scope":"`+scope+`"}}`)
+			if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			mdl := &stubModel{responses: []*model.Response{
+				bashCall("cd sub"),
+				bashCall("pwd"),
+				{Message: model.Message{Role: "assistant", Content: "done"}},
+			}}
+			rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl, EnabledBuiltinTools: []string{"bash"}})
+			if err != nil {
+				t.Fatalf("runtime: %v", err)
+			}
+			t.Cleanup(func() { _ = rt.Close() })
+
+			if _, err := rt.Run(context.Background(), Request{Prompt: "go", SessionID: "sess", RequestID: "run-1"}); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			var last string
+			for _, msg := range rt.histories.Get("sess").All() {
+				if msg.Role == "tool" && len(msg.ToolCalls) > 0 {
+					last = msg.ToolCalls[0].Result
+				}
+			}
+			if want, _ := filepath.EvalSymlinks(filepath.Join(root, "sub")); last != filepath.Join(root, "sub") && last != want {
+				t.Fatalf("pwd ran in %q, cwd was not kept", last)
+			}
+
+			shells := rt.ShellSessions()
+			if scope == "run" {
+				if len(shells) != 0 {
+					t.Fatalf("run shells should close with the run, got %+v", shells)
+				}
+				return
+			}
+			if len(shells) != 1 || shells[0].ID != "sess" || shells[0].Commands != 2 {
+				t.Fatalf("unexpected shells %+v", shells)
+			}
+			if !rt.KillShellSession("sess") || len(rt.ShellSessions()) != 0 {
+				t.Fatal("KillShellSession should stop the session shell")
+			}
+		})
+	}
+}
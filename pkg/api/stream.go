@@ -27,6 +27,7 @@ const (
 	EventToolExecutionStart  = "tool_execution_start"
 	EventToolExecutionOutput = "tool_execution_output"
 	EventToolExecutionResult = "tool_execution_result"
+	EventPermissionRequest   = "permission_request"
 	EventError               = "error"
 )
 
@@ -51,6 +52,13 @@ type StreamEvent struct {
 	SessionID string      `json:"session_id,omitempty"`       // SessionID ties events to a long-lived agent session.
 	Iteration *int        `json:"iteration,omitempty"`        // Iteration indicates the current agent iteration, if applicable.
 	TotalIter *int        `json:"total_iterations,omitempty"` // TotalIter reports the planned maximum iteration count.
+
+	// Correlation IDs minted by Options.IDGenerator. RunID equals the
+	// Request.RequestID; IterationID matches AuditRecord.IterationID and
+	// ToolUseID matches the tool call in the session history.
+	RunID       string `json:"run_id,omitempty"`       // RunID identifies the run that produced the event.
+	IterationID string `json:"iteration_id,omitempty"` // IterationID identifies the model turn the event belongs to.
+	EventID     string `json:"event_id,omitempty"`     // EventID uniquely identifies the event.
 }
 
 // Message represents the Anthropic message envelope streamed over SSE.
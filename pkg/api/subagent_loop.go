@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+	"github.com/cexll/agentsdk-go/pkg/runtime/subagents"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	toolbuiltin "github.com/cexll/agentsdk-go/pkg/tool/builtin"
+)
+
+// taskToolName is excluded from nested subagent runs so subagents cannot
+// spawn further subagents.
+const taskToolName = "task"
+
+// runSubagentLoop runs def as a nested agent loop for a Task tool call. The
+// subagent gets its own session (Resume continues an earlier one), its
+// system prompt, tool whitelist and model, and only its final answer is
+// returned to the parent as the tool output.
+func (rt *Runtime) runSubagentLoop(ctx context.Context, def subagents.Definition, req toolbuiltin.TaskRequest) (*tool.ToolResult, error) {
+	sessionID := strings.TrimSpace(req.Resume)
+	if sessionID == "" {
+		sessionID = "task-" + newID(rt.idGenerator(), IDRun)
+	}
+	whitelist := rt.subagentTools(def)
+	child := Request{
+		Prompt:         strings.TrimSpace(req.Prompt),
+		Mode:           rt.mode,
+		SessionID:      sessionID,
+		TargetSubagent: def.Name,
+		ToolWhitelist:  whitelist,
+		Model:          subagentModelTier(req.Model, def.BaseContext.Model, def.DefaultModel),
+		Metadata:       map[string]any{"task.subagent": def.Name},
+	}
+	if desc := strings.TrimSpace(req.Description); desc != "" {
+		child.Metadata["task.description"] = desc
+	}
+
+	if err := rt.sessionGate.Acquire(ctx, sessionID); err != nil {
+		return nil, ErrConcurrentExecution
+	}
+	defer rt.sessionGate.Release(sessionID)
+
+	subCtx := def.BaseContext.Clone().WithSession(sessionID)
+	subCtx.ToolWhitelist = whitelist
+	ctx = subagents.WithContext(ctx, subCtx)
+	prep, err := rt.prepare(ctx, child)
+	if err != nil {
+		return nil, err
+	}
+	prep.template = subagentTemplate(prep.template, def, permissionModeFromContext(ctx))
+	defer rt.persistHistory(sessionID, prep.history)
+
+	hooks := &runtimeHookAdapter{executor: rt.hooks, recorder: prep.recorder, sessionID: sessionID}
+	if err := hooks.SubagentStart(prep.ctx, coreevents.SubagentStartPayload{Name: def.Name, AgentID: sessionID, AgentType: def.Name}); err != nil {
+		return nil, fmt.Errorf("api: SubagentStart hook: %w", err)
+	}
+	result, runErr := rt.runAgent(prep)
+	reason := "completed"
+	if runErr != nil {
+		reason = "error"
+	}
+	//nolint:errcheck // SubagentStop is a notification once the loop has finished
+	hooks.SubagentStop(prep.ctx, coreevents.SubagentStopPayload{Name: def.Name, Reason: reason, AgentID: sessionID, AgentType: def.Name})
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	res := convertRunResult(result)
+	output := strings.TrimSpace(res.Output)
+	if output == "" {
+		output = fmt.Sprintf("subagent %s completed", def.Name)
+	}
+	return &tool.ToolResult{
+		Success: true,
+		Output:  output,
+		Data: map[string]any{
+			"subagent":    def.Name,
+			"session_id":  sessionID,
+			"stop_reason": res.StopReason,
+			"tool_calls":  len(res.ToolCalls),
+			"usage":       res.Usage,
+		},
+	}, nil
+}
+
+// subagentTools returns the tools a nested subagent may call: its own
+// whitelist, or every registered tool, never including the Task tool.
+func (rt *Runtime) subagentTools(def subagents.Definition) []string {
+	names := def.BaseContext.ToolList()
+	if len(names) == 0 && rt.registry != nil {
+		for _, impl := range rt.registry.List() {
+			if impl != nil {
+				names = append(names, canonicalToolName(impl.Name()))
+			}
+		}
+	}
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		if canon := canonicalToolName(name); canon != "" && canon != taskToolName {
+			out = append(out, canon)
+		}
+	}
+	return out
+}
+
+// subagentTemplate applies the subagent's system prompt and permission mode
+// on top of the request template. Agent files can be written by the model, so
+// their mode never approves more than parent, the mode of the run that
+// started the subagent.
+func subagentTemplate(base *RequestTemplate, def subagents.Definition, parent PermissionMode) *RequestTemplate {
+	var tpl RequestTemplate
+	if base != nil {
+		tpl = *base
+	}
+	if prompt := strings.TrimSpace(def.SystemPrompt); prompt != "" {
+		tpl.SystemPrompt = prompt
+	}
+	if mode, _ := def.BaseContext.Metadata["permission-mode"].(string); mode != "" {
+		switch strings.ToLower(mode) {
+		case "acceptedits":
+			tpl.PermissionMode = PermissionModeAcceptEdits
+		case "bypasspermissions":
+			tpl.PermissionMode = PermissionModeBypass
+		}
+	}
+	tpl.PermissionMode = clampPermissionMode(tpl.PermissionMode, parent)
+	return &tpl
+}
+
+// subagentModelTier maps a Task model override or the subagent's model
+// alias (haiku, sonnet, opus) to a ModelPool tier. An empty tier leaves
+// the choice to SubagentModelMapping and the default model.
+func subagentModelTier(names ...string) ModelTier {
+	for _, name := range names {
+		name = strings.ToLower(name)
+		switch {
+		case strings.Contains(name, "haiku"):
+			return ModelTierLow
+		case strings.Contains(name, "sonnet"):
+			return ModelTierMid
+		case strings.Contains(name, "opus"):
+			return ModelTierHigh
+		}
+	}
+	return ""
+}
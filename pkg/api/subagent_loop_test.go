@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/runtime/subagents"
+)
+
+func TestTaskToolRunsSubagentLoop(t *testing.T) {
+	root := newClaudeProject(t)
+	agents := filepath.Join(root, ".claude", "agents")
+	if err := os.MkdirAll(agents, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	reviewer := "---\nname: reviewer\ndescription: Reviews diffs for bugs.\ntools: Read, Grep\n---\nYou are a meticulous code reviewer.\n"
+	if err := os.WriteFile(filepath.Join(agents, "reviewer.md"), []byte(reviewer), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "t1", Name: "Task", Arguments: map[string]any{
+			"description":   "Review the change",
+			"prompt":        "review main.go",
+			"subagent_type": "reviewer",
+		}}}}},
+		{Message: model.Message{Role: "assistant", Content: "no bugs found"}},
+		{Message: model.Message{Role: "assistant", Content: "reviewer is happy"}},
+	}}
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	task, err := rt.registry.Get("Task")
+	if err != nil {
+		t.Fatalf("task tool: %v", err)
+	}
+	if !strings.Contains(task.Description(), "- reviewer: Reviews diffs for bugs. (Tools: grep, read)") {
+		t.Fatalf("task description does not list reviewer:\n%s", task.Description())
+	}
+
+	resp, err := rt.Run(context.Background(), Request{Prompt: "check my change", SessionID: "parent"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if resp.Result.Output != "reviewer is happy" {
+		t.Fatalf("unexpected output %q", resp.Result.Output)
+	}
+	if len(mdl.requests) != 3 {
+		t.Fatalf("expected 3 model calls, got %d", len(mdl.requests))
+	}
+
+	child := mdl.requests[1]
+	if !strings.Contains(child.System, "meticulous code reviewer") {
+		t.Fatalf("subagent system prompt missing: %q", child.System)
+	}
+	var tools []string
+	for _, def := range child.Tools {
+		tools = append(tools, def.Name)
+	}
+	if strings.Join(tools, ",") != "Grep,Read" && strings.Join(tools, ",") != "Read,Grep" {
+		t.Fatalf("subagent tools = %v", tools)
+	}
+	if len(child.Messages) != 1 || child.Messages[0].Content != "review main.go" {
+		t.Fatalf("subagent must start from an isolated context, got %+v", child.Messages)
+	}
+
+	parent := mdl.requests[2].Messages
+	last := parent[len(parent)-1]
+	if len(last.ToolCalls) != 1 || last.ToolCalls[0].Result != "no bugs found" {
+		t.Fatalf("subagent answer not returned as tool output: %+v", last)
+	}
+}
+
+func TestSubagentModelTier(t *testing.T) {
+	cases := map[string]ModelTier{
+		"haiku":                  ModelTierLow,
+		"claude-opus-4-20250514": ModelTierHigh,
+		"claude-sonnet-4-5":      ModelTierMid,
+		"inherit":                "",
+	}
+	for name, want := range cases {
+		if got := subagentModelTier(name); got != want {
+			t.Errorf("subagentModelTier(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if got := subagentModelTier("", "haiku"); got != ModelTierLow {
+		t.Errorf("fallback to definition model = %q", got)
+	}
+}
+
+func TestOptionsSubagentDefinedInCode(t *testing.T) {
+	parent := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "t1", Name: "Task", Arguments: map[string]any{
+			"description":   "Summarize the repo",
+			"prompt":        "summarize README",
+			"subagent_type": "summarizer",
+		}}}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	cheap := &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "a tiny repo"}}}}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot: newClaudeProject(t),
+		Model:       parent,
+		ModelPool:   map[ModelTier]model.Model{ModelTierLow: cheap},
+		Subagents: []SubagentRegistration{{Definition: subagents.Definition{
+			Name:         "summarizer",
+			Description:  "Summarizes files.",
+			BaseContext:  subagents.Context{ToolWhitelist: []string{"Read"}, Model: "haiku"},
+			SystemPrompt: "You write one-line summaries.",
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	if _, err := rt.Run(context.Background(), Request{Prompt: "what is this repo?", SessionID: "parent"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(cheap.requests) != 1 {
+		t.Fatalf("subagent should run on the haiku tier model, got %d calls", len(cheap.requests))
+	}
+	child := cheap.requests[0]
+	if !strings.Contains(child.System, "one-line summaries") || len(child.Tools) != 1 || child.Tools[0].Name != "Read" {
+		t.Fatalf("subagent request = system %q tools %+v", child.System, child.Tools)
+	}
+	last := parent.requests[1].Messages
+	if got := last[len(last)-1].ToolCalls[0].Result; got != "a tiny repo" {
+		t.Fatalf("tool result = %q", got)
+	}
+}
+
+func TestSubagentBypassCannotSkipProtectedPaths(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{"permissions":{"protectedPaths":["/deploy/"]},"sandbox":{"enabled":true}}`)
+	agents := filepath.Join(root, ".claude", "agents")
+	if err := os.MkdirAll(agents, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	escalator := "---\nname: escalator\ndescription: Writes files.\ntools: Write\npermissionMode: bypassPermissions\n---\nYou write files.\n"
+	if err := os.WriteFile(filepath.Join(agents, "escalator.md"), []byte(escalator), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	write := func(id, path string) *model.Response {
+		return &model.Response{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: id, Name: "Write", Arguments: map[string]any{
+			"file_path": filepath.Join(root, path),
+			"content":   "x",
+		}}}}}
+	}
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "t1", Name: "Task", Arguments: map[string]any{
+			"description":   "Write files",
+			"prompt":        "write them",
+			"subagent_type": "escalator",
+		}}}}},
+		write("w1", "deploy/app.yaml"),
+		write("w2", ".claude/agents/evil.md"),
+		{Message: model.Message{Role: "assistant", Content: "tried"}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	var prompted []string
+	rt, err := New(context.Background(), Options{
+		ProjectRoot: root,
+		Model:       mdl,
+		PermissionHandler: func(_ context.Context, req PermissionRequest) (PermissionResult, error) {
+			prompted = append(prompted, req.Protected)
+			return PermissionResult{Decision: coreevents.PermissionDeny}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	if _, err := rt.Run(context.Background(), Request{Prompt: "go", SessionID: "parent"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if strings.Join(prompted, ",") != "/deploy/,/.claude/" {
+		t.Fatalf("protected writes were not prompted: %v", prompted)
+	}
+	for _, path := range []string{"deploy/app.yaml", ".claude/agents/evil.md"} {
+		if _, err := os.Stat(filepath.Join(root, path)); !os.IsNotExist(err) {
+			t.Fatalf("%s was written: %v", path, err)
+		}
+	}
+}
+
+func TestSubagentTemplateClampsPermissionMode(t *testing.T) {
+	def := subagents.Definition{BaseContext: subagents.Context{Metadata: map[string]any{"permission-mode": "bypassPermissions"}}}
+	cases := map[PermissionMode]PermissionMode{
+		PermissionModeDefault:     PermissionModeDefault,
+		PermissionModeAcceptEdits: PermissionModeAcceptEdits,
+		PermissionModeBypass:      PermissionModeBypass,
+	}
+	for parent, want := range cases {
+		if got := subagentTemplate(nil, def, parent).PermissionMode; got != want {
+			t.Errorf("parent %q: subagent mode %q, want %q", parent, got, want)
+		}
+	}
+	def.BaseContext.Metadata["permission-mode"] = "acceptEdits"
+	if got := subagentTemplate(nil, def, PermissionModeBypass).PermissionMode; got != PermissionModeAcceptEdits {
+		t.Errorf("narrower subagent mode must be kept, got %q", got)
+	}
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	toolbuiltin "github.com/cexll/agentsdk-go/pkg/tool/builtin"
+)
+
+// defaultSubagentSummaryTokens is the token budget of
+// SubagentsResult.Summary when SubagentsSummaryBudget is not given.
+const defaultSubagentSummaryTokens = 4000
+
+// SubagentTask is one subagent run of RunSubagents.
+type SubagentTask struct {
+	// Subagent names a registered subagent (SubagentRegistration or
+	// .claude/agents definition).
+	Subagent    string
+	Prompt      string
+	Description string
+	// Model overrides the subagent's model alias (haiku, sonnet, opus).
+	Model string
+	// SessionID resumes an earlier subagent session.
+	SessionID string
+}
+
+// SubagentsOption configures RunSubagents.
+type SubagentsOption func(*subagentsConfig)
+
+type subagentsConfig struct {
+	concurrency   int
+	summaryTokens int
+	parent        string
+}
+
+// SubagentsConcurrency caps how many subagents run at once. It defaults to
+// Options.MaxConcurrentRuns, or 4 when that is unlimited.
+func SubagentsConcurrency(n int) SubagentsOption {
+	return func(c *subagentsConfig) { c.concurrency = n }
+}
+
+// SubagentsSummaryBudget sets the approximate token size of
+// SubagentsResult.Summary; each run gets an equal share and longer outputs
+// are cut. The default is 4000 tokens.
+func SubagentsSummaryBudget(tokens int) SubagentsOption {
+	return func(c *subagentsConfig) { c.summaryTokens = tokens }
+}
+
+// SubagentsParent appends the combined summary to the history of the parent
+// session, so the next Run on that session sees the subagent results.
+func SubagentsParent(sessionID string) SubagentsOption {
+	return func(c *subagentsConfig) { c.parent = strings.TrimSpace(sessionID) }
+}
+
+// SubagentRun is the outcome of one SubagentTask.
+type SubagentRun struct {
+	// Index is the position of the task in the RunSubagents slice.
+	Index     int
+	Subagent  string
+	SessionID string
+	Output    string
+	Usage     model.Usage
+	Err       error
+	Duration  time.Duration
+}
+
+// SubagentsResult aggregates a RunSubagents call.
+type SubagentsResult struct {
+	// Runs holds one entry per task, in task order.
+	Runs      []SubagentRun
+	Succeeded int
+	Failed    int
+	// Summary lists each run's output or error within the summary budget.
+	Summary  string
+	Usage    model.Usage
+	Duration time.Duration
+}
+
+// RunSubagents fans tasks out to their subagents concurrently and collects
+// the results, the way the Task tool runs a single subagent. Subagents with
+// a system prompt run as nested agent loops in their own sessions; others
+// go to their registered handler.
+//
+// Failures of single runs are reported in SubagentsResult.Runs and do not
+// fail the call. RunSubagents returns an error only when the runtime is
+// closed, ctx ends first or the summary cannot be added to the parent
+// session; the result is returned with it.
+func (rt *Runtime) RunSubagents(ctx context.Context, tasks []SubagentTask, opts ...SubagentsOption) (*SubagentsResult, error) {
+	if rt == nil {
+		return nil, ErrRuntimeClosed
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cfg := subagentsConfig{concurrency: rt.opts.MaxConcurrentRuns, summaryTokens: defaultSubagentSummaryTokens}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = defaultBatchConcurrency
+	}
+	if err := rt.beginRun(); err != nil {
+		return nil, err
+	}
+	defer rt.endRun()
+
+	started := time.Now()
+	runs := make([]SubagentRun, len(tasks))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(cfg.concurrency, len(tasks)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				runs[i] = rt.runSubagentTask(ctx, i, tasks[i])
+			}
+		}()
+	}
+	for i := range tasks {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			runs[i] = SubagentRun{Index: i, Subagent: tasks[i].Subagent, Err: ctx.Err()}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	res := &SubagentsResult{Runs: runs, Duration: time.Since(started)}
+	for _, run := range runs {
+		if run.Err != nil {
+			res.Failed++
+			continue
+		}
+		res.Succeeded++
+		addUsage(&res.Usage, run.Usage)
+	}
+	res.Summary = summarizeSubagentRuns(tasks, runs, cfg.summaryTokens)
+	if cfg.parent != "" && len(runs) > 0 {
+		if err := rt.appendParentSummary(ctx, cfg.parent, res.Summary); err != nil {
+			return res, err
+		}
+	}
+	return res, ctx.Err()
+}
+
+func (rt *Runtime) runSubagentTask(ctx context.Context, i int, task SubagentTask) (run SubagentRun) {
+	run = SubagentRun{Index: i, Subagent: strings.TrimSpace(task.Subagent)}
+	started := time.Now()
+	defer func() { run.Duration = time.Since(started) }()
+	if run.Subagent == "" {
+		run.Err = errors.New("api: subagent name is empty")
+		return run
+	}
+	out, err := rt.runTaskInvocation(ctx, toolbuiltin.TaskRequest{
+		Description:  task.Description,
+		Prompt:       task.Prompt,
+		SubagentType: run.Subagent,
+		Model:        task.Model,
+		Resume:       task.SessionID,
+	})
+	if err != nil {
+		run.Err = err
+		return run
+	}
+	run.Output = out.Output
+	if data, ok := out.Data.(map[string]any); ok {
+		run.SessionID, _ = data["session_id"].(string)
+		run.Usage, _ = data["usage"].(model.Usage)
+	}
+	return run
+}
+
+// summarizeSubagentRuns renders one section per run, cutting each output to
+// an equal share of the token budget (about four bytes per token).
+func summarizeSubagentRuns(tasks []SubagentTask, runs []SubagentRun, budget int) string {
+	if len(runs) == 0 {
+		return ""
+	}
+	share := 0
+	if budget > 0 {
+		share = max(budget*4/len(runs), 1)
+	}
+	var b strings.Builder
+	for i, run := range runs {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "## %s", run.Subagent)
+		if desc := strings.TrimSpace(tasks[i].Description); desc != "" {
+			fmt.Fprintf(&b, ": %s", desc)
+		}
+		b.WriteString("\n")
+		body := strings.TrimSpace(run.Output)
+		if run.Err != nil {
+			body = "error: " + run.Err.Error()
+		}
+		if share > 0 && len(body) > share {
+			cut := share
+			for cut > 0 && !utf8.RuneStart(body[cut]) {
+				cut--
+			}
+			body = body[:cut] + "\n[truncated]"
+		}
+		b.WriteString(body)
+	}
+	return b.String()
+}
+
+// appendParentSummary adds the subagent summary to the parent session as a
+// user message.
+func (rt *Runtime) appendParentSummary(ctx context.Context, sessionID, summary string) error {
+	if err := rt.sessionGate.Acquire(ctx, sessionID); err != nil {
+		return err
+	}
+	defer rt.sessionGate.Release(sessionID)
+	hist := rt.histories.Get(sessionID)
+	rt.refreshHistory(ctx, sessionID, hist)
+	hist.Append(message.Message{Role: "user", Content: "Subagent results:\n\n" + summary})
+	rt.persistHistory(sessionID, hist)
+	return nil
+}
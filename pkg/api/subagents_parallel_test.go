@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSubagentsFansOutAndSummarizes(t *testing.T) {
+	root := newClaudeProject(t)
+	agents := filepath.Join(root, ".claude", "agents")
+	if err := os.MkdirAll(agents, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	reviewer := "---\nname: reviewer\ndescription: Reviews code.\n---\nYou review code.\n"
+	if err := os.WriteFile(filepath.Join(agents, "reviewer.md"), []byte(reviewer), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mdl := &batchModel{}
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	tasks := []SubagentTask{
+		{Subagent: "reviewer", Description: "api", Prompt: "review pkg/api " + strings.Repeat("x", 200)},
+		{Subagent: "reviewer", Description: "model", Prompt: "review pkg/model"},
+		{Subagent: "reviewer", Prompt: "fail please"},
+		{Subagent: "ghost", Prompt: "anything"},
+	}
+	res, err := rt.RunSubagents(context.Background(), tasks,
+		SubagentsConcurrency(2), SubagentsSummaryBudget(40), SubagentsParent("parent"))
+	if err != nil {
+		t.Fatalf("run subagents: %v", err)
+	}
+	if res.Succeeded != 2 || res.Failed != 2 {
+		t.Fatalf("result = %+v", res)
+	}
+	if mdl.peak > 2 {
+		t.Fatalf("concurrency cap exceeded: peak %d", mdl.peak)
+	}
+	for i, run := range res.Runs[:2] {
+		if run.Index != i || !strings.HasPrefix(run.SessionID, "task-") || run.Err != nil {
+			t.Fatalf("run %d = %+v", i, run)
+		}
+	}
+	if res.Runs[1].Output != "re: review pkg/model" {
+		t.Fatalf("unexpected output %q", res.Runs[1].Output)
+	}
+	if res.Usage.InputTokens != 20 || res.Usage.TotalTokens != 24 {
+		t.Fatalf("usage = %+v", res.Usage)
+	}
+
+	for _, want := range []string{"## reviewer: api\n", "[truncated]", "## reviewer: model\nre: review pkg/model", "## ghost\nerror:"} {
+		if !strings.Contains(res.Summary, want) {
+			t.Fatalf("summary missing %q:\n%s", want, res.Summary)
+		}
+	}
+	if strings.Contains(res.Summary, strings.Repeat("x", 100)) {
+		t.Fatalf("summary not cut to budget:\n%s", res.Summary)
+	}
+
+	msgs := rt.histories.Get("parent").All()
+	if len(msgs) != 1 || msgs[0].Role != "user" || !strings.Contains(msgs[0].Content, res.Summary) {
+		t.Fatalf("parent history = %+v", msgs)
+	}
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// ErrUnknownTemplate is returned when Request.Template names no registered template.
+var ErrUnknownTemplate = errors.New("api: unknown request template")
+
+// PermissionMode controls how tool calls that would require approval are
+// handled for a request. Deny rules always apply.
+type PermissionMode string
+
+const (
+	// PermissionModeDefault keeps the configured approval flow.
+	PermissionModeDefault PermissionMode = "askBeforeRunningTools"
+	// PermissionModeAcceptReadOnly auto-approves read-only file tools.
+	PermissionModeAcceptReadOnly PermissionMode = "acceptReadOnly"
+	// PermissionModeAcceptEdits auto-approves read-only and file edit tools.
+	PermissionModeAcceptEdits PermissionMode = "acceptEdits"
+	// PermissionModeBypass auto-approves every tool call that would ask,
+	// except edits of protected paths. Settings can forbid it with
+	// permissions.disableBypassPermissionsMode.
+	PermissionModeBypass PermissionMode = "bypassPermissions"
+)
+
+var (
+	readOnlyTools = map[string]struct{}{"read": {}, "grep": {}, "glob": {}}
+	editTools     = map[string]struct{}{"write": {}, "edit": {}}
+)
+
+// RequestTemplate is a reusable request preset selected with
+// Request.Template. Fields left empty fall back to the request or runtime
+// defaults; explicit request fields always win.
+type RequestTemplate struct {
+	// SystemPrompt replaces Options.SystemPrompt.
+	SystemPrompt string
+	// SystemPromptRef names a prompt template rendered with
+	// Request.PromptVars; it takes precedence over SystemPrompt.
+	SystemPromptRef string
+	// Tools is the tool whitelist used when the request sets none.
+	Tools []string
+	// Model is the tier used when the request sets none.
+	Model ModelTier
+	// OutputFormat is appended to the system prompt as output instructions.
+	OutputFormat string
+	// PermissionMode applies to tool calls that would ask for approval.
+	PermissionMode PermissionMode
+	// Tags are merged under the request tags.
+	Tags map[string]string
+}
+
+func templateFromConfig(cfg config.TemplateConfig) RequestTemplate {
+	return RequestTemplate{
+		SystemPrompt:    cfg.SystemPrompt,
+		SystemPromptRef: strings.TrimSpace(cfg.SystemPromptRef),
+		Tools:           cloneStrings(cfg.Tools),
+		Model:           ModelTier(strings.TrimSpace(cfg.Model)),
+		OutputFormat:    cfg.OutputFormat,
+		PermissionMode:  PermissionMode(strings.TrimSpace(cfg.PermissionMode)),
+		Tags:            maps.Clone(cfg.Tags),
+	}
+}
+
+// lookupTemplate resolves name against Options.Templates first, then the
+// templates declared in settings.
+func (rt *Runtime) lookupTemplate(name string) (RequestTemplate, error) {
+	name = strings.TrimSpace(name)
+	if tpl, ok := rt.opts.Templates[name]; ok {
+		return tpl, nil
+	}
+	if rt.settings != nil {
+		if cfg, ok := rt.settings.Templates[name]; ok {
+			return templateFromConfig(cfg), nil
+		}
+	}
+	return RequestTemplate{}, fmt.Errorf("%w: %q", ErrUnknownTemplate, name)
+}
+
+// applyTemplate fills unset request fields from the named template.
+func (rt *Runtime) applyTemplate(req *Request) (*RequestTemplate, error) {
+	if req == nil || strings.TrimSpace(req.Template) == "" {
+		return nil, nil
+	}
+	tpl, err := rt.lookupTemplate(req.Template)
+	if err != nil {
+		return nil, err
+	}
+	if ref := strings.TrimSpace(tpl.SystemPromptRef); ref != "" {
+		text, resolved, err := rt.renderPromptRef(ref, req.PromptVars)
+		if err != nil {
+			return nil, err
+		}
+		tpl.SystemPrompt = text
+		req.Tags[systemPromptTag] = resolved
+	}
+	if len(req.ToolWhitelist) == 0 && len(tpl.Tools) > 0 {
+		req.ToolWhitelist = cloneStrings(tpl.Tools)
+	}
+	if req.Model == "" {
+		req.Model = tpl.Model
+	}
+	for k, v := range tpl.Tags {
+		if _, ok := req.Tags[k]; !ok {
+			req.Tags[k] = v
+		}
+	}
+	return &tpl, nil
+}
+
+func (t *RequestTemplate) systemPrompt(base string) string {
+	if t == nil {
+		return base
+	}
+	prompt := base
+	if strings.TrimSpace(t.SystemPrompt) != "" {
+		prompt = strings.TrimSpace(t.SystemPrompt)
+	}
+	if format := strings.TrimSpace(t.OutputFormat); format != "" {
+		prompt = strings.TrimSpace(prompt + "\n\n## Output Format\n\n" + format)
+	}
+	return prompt
+}
+
+func (t *RequestTemplate) permissionMode() PermissionMode {
+	if t == nil {
+		return ""
+	}
+	return t.PermissionMode
+}
+
+// permissionMode returns mode unless it is PermissionModeBypass and settings
+// set permissions.disableBypassPermissionsMode, in which case the default
+// approval flow applies.
+func (rt *Runtime) permissionMode(mode PermissionMode) PermissionMode {
+	if mode != PermissionModeBypass || !rt.bypassDisabled() {
+		return mode
+	}
+	log.Printf("api: %s is disabled by settings; using the default approval flow", mode)
+	return PermissionModeDefault
+}
+
+func (rt *Runtime) bypassDisabled() bool {
+	return rt.settings != nil && rt.settings.Permissions != nil &&
+		strings.TrimSpace(rt.settings.Permissions.DisableBypassPermissionsMode) == "disable"
+}
+
+// permissionModeRank orders modes by how much they approve; unknown modes
+// rank with the default flow.
+var permissionModeRank = map[PermissionMode]int{
+	PermissionModeAcceptReadOnly: 1,
+	PermissionModeAcceptEdits:    2,
+	PermissionModeBypass:         3,
+}
+
+// clampPermissionMode returns mode, or limit when mode would approve more.
+func clampPermissionMode(mode, limit PermissionMode) PermissionMode {
+	if permissionModeRank[mode] > permissionModeRank[limit] {
+		return limit
+	}
+	return mode
+}
+
+type permissionModeKey struct{}
+
+// withPermissionMode records the mode of the run whose tools execute with ctx,
+// so subagents started by those tools can be held to it.
+func withPermissionMode(ctx context.Context, mode PermissionMode) context.Context {
+	return context.WithValue(ctx, permissionModeKey{}, mode)
+}
+
+// permissionModeFromContext returns the mode recorded by withPermissionMode,
+// or PermissionModeDefault outside a run.
+func permissionModeFromContext(ctx context.Context) PermissionMode {
+	if ctx != nil {
+		if mode, ok := ctx.Value(permissionModeKey{}).(PermissionMode); ok && mode != "" {
+			return mode
+		}
+	}
+	return PermissionModeDefault
+}
+
+// applyPermissionMode wraps resolver so calls covered by mode are approved
+// before falling back to resolver. Edits of protected paths always go to
+// resolver: no mode approves them, bypass included.
+func applyPermissionMode(mode PermissionMode, resolver tool.PermissionResolver) tool.PermissionResolver {
+	if mode == "" || mode == PermissionModeDefault {
+		return resolver
+	}
+	return func(ctx context.Context, call tool.Call, decision security.PermissionDecision) (security.PermissionDecision, error) {
+		if decision.Action == security.PermissionAsk && decision.Protected == "" && modeAllows(mode, call.Name) {
+			decision.Action = security.PermissionAllow
+			decision.Rule = "permission_mode:" + string(mode)
+			return decision, nil
+		}
+		if resolver == nil {
+			return decision, nil
+		}
+		return resolver(ctx, call, decision)
+	}
+}
+
+func modeAllows(mode PermissionMode, toolName string) bool {
+	name := canonicalToolName(toolName)
+	switch mode {
+	case PermissionModeBypass:
+		return true
+	case PermissionModeAcceptEdits:
+		if _, ok := editTools[name]; ok {
+			return true
+		}
+		_, ok := readOnlyTools[name]
+		return ok
+	case PermissionModeAcceptReadOnly:
+		_, ok := readOnlyTools[name]
+		return ok
+	default:
+		return false
+	}
+}
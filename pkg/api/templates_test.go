@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+func TestRuntimeAppliesRequestTemplate(t *testing.T) {
+	root := newClaudeProject(t)
+	mdl := &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "done"}}}}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:  root,
+		Model:        mdl,
+		SystemPrompt: "base prompt",
+		Tools:        []tool.Tool{&echoTool{}, artifactTool{}},
+		Templates: map[string]RequestTemplate{
+			"reporter": {
+				SystemPrompt: "You write reports.",
+				Tools:        []string{"echo"},
+				OutputFormat: "Respond in JSON.",
+				Tags:         map[string]string{"team": "docs", "tier": "free"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	resp, err := rt.Run(context.Background(), Request{Prompt: "hi", Template: "reporter", Tags: map[string]string{"tier": "pro"}})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	req := mdl.requests[0]
+	if !strings.HasPrefix(req.System, "You write reports.\n\n## Output Format\n\nRespond in JSON.") || strings.Contains(req.System, "base prompt") {
+		t.Fatalf("unexpected system prompt %q", req.System)
+	}
+	if len(req.Tools) != 1 || req.Tools[0].Name != "echo" {
+		t.Fatalf("expected template tool whitelist, got %+v", req.Tools)
+	}
+	if resp.Tags["team"] != "docs" || resp.Tags["tier"] != "pro" {
+		t.Fatalf("expected template tags under request tags, got %v", resp.Tags)
+	}
+
+	if _, err := rt.Run(context.Background(), Request{Prompt: "hi", Template: "missing"}); !errors.Is(err, ErrUnknownTemplate) {
+		t.Fatalf("expected ErrUnknownTemplate, got %v", err)
+	}
+}
+
+func TestRuntimeLoadsTemplatesFromSettings(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{"model":"claude-3-opus","templates":{"terse":{"systemPrompt":"Be terse.","model":"low"}}}`)
+	base := &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "base"}}}}
+	low := &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "low"}}}}
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: base, ModelPool: map[ModelTier]model.Model{ModelTierLow: low}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	resp, err := rt.Run(context.Background(), Request{Prompt: "hi", Template: "terse"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if resp.Result.Output != "low" || len(low.requests) != 1 || !strings.HasPrefix(low.requests[0].System, "Be terse.") {
+		t.Fatalf("expected low tier model with template prompt, got %q", resp.Result.Output)
+	}
+}
+
+func TestApplyPermissionMode(t *testing.T) {
+	ask := security.PermissionDecision{Action: security.PermissionAsk, Rule: "ask"}
+	fallbackCalls := 0
+	fallback := func(context.Context, tool.Call, security.PermissionDecision) (security.PermissionDecision, error) {
+		fallbackCalls++
+		return security.PermissionDecision{Action: security.PermissionDeny}, nil
+	}
+	cases := []struct {
+		mode PermissionMode
+		tool string
+		want security.PermissionAction
+	}{
+		{PermissionModeBypass, "Bash", security.PermissionAllow},
+		{PermissionModeAcceptEdits, "Edit", security.PermissionAllow},
+		{PermissionModeAcceptEdits, "Read", security.PermissionAllow},
+		{PermissionModeAcceptEdits, "Bash", security.PermissionDeny},
+		{PermissionModeAcceptReadOnly, "Grep", security.PermissionAllow},
+		{PermissionModeAcceptReadOnly, "Write", security.PermissionDeny},
+	}
+	for _, tc := range cases {
+		got, err := applyPermissionMode(tc.mode, fallback)(context.Background(), tool.Call{Name: tc.tool}, ask)
+		if err != nil || got.Action != tc.want {
+			t.Fatalf("%s/%s: got %v err=%v", tc.mode, tc.tool, got.Action, err)
+		}
+	}
+	if fallbackCalls != 2 {
+		t.Fatalf("expected fallback for uncovered tools, got %d calls", fallbackCalls)
+	}
+	if applyPermissionMode(PermissionModeDefault, nil) != nil {
+		t.Fatal("default mode should not wrap")
+	}
+}
+
+func TestApplyPermissionModeProtectedPaths(t *testing.T) {
+	protected := security.PermissionDecision{Action: security.PermissionAsk, Rule: "protected:/deploy/", Protected: "/deploy/", Owners: []string{"@org/sre"}}
+	var seen []PermissionRequest
+	handler := func(_ context.Context, req PermissionRequest) (coreevents.PermissionDecisionType, error) {
+		seen = append(seen, req)
+		return coreevents.PermissionAsk, nil
+	}
+	resolver := buildPermissionResolver(nil, legacyPermissionHandler(handler), nil, "", 0, false)
+
+	got, err := applyPermissionMode(PermissionModeAcceptEdits, resolver)(context.Background(), tool.Call{Name: "Edit"}, protected)
+	if err != nil || got.Action != security.PermissionAsk {
+		t.Fatalf("acceptEdits approved a protected edit: %+v err=%v", got, err)
+	}
+	if len(seen) != 1 || seen[0].Protected != "/deploy/" || len(seen[0].Owners) != 1 || !strings.Contains(seen[0].Reason, "owned by @org/sre") {
+		t.Fatalf("permission prompt = %+v", seen)
+	}
+
+	// Protected paths need a human even in bypass mode.
+	got, err = applyPermissionMode(PermissionModeBypass, resolver)(context.Background(), tool.Call{Name: "Edit"}, protected)
+	if err != nil || got.Action != security.PermissionAsk {
+		t.Fatalf("bypass approved a protected edit: %+v err=%v", got, err)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("bypass skipped the prompt: %+v", seen)
+	}
+}
+
+func TestRuntimePermissionModeHonoursDisableBypass(t *testing.T) {
+	rt := &Runtime{}
+	if got := rt.permissionMode(PermissionModeBypass); got != PermissionModeBypass {
+		t.Fatalf("bypass without settings = %q", got)
+	}
+	rt.settings = &config.Settings{Permissions: &config.PermissionsConfig{DisableBypassPermissionsMode: "disable"}}
+	if got := rt.permissionMode(PermissionModeBypass); got != PermissionModeDefault {
+		t.Fatalf("disabled bypass = %q, want default", got)
+	}
+	if got := rt.permissionMode(PermissionModeAcceptEdits); got != PermissionModeAcceptEdits {
+		t.Fatalf("other modes must be kept, got %q", got)
+	}
+}
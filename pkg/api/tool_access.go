@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/agent"
+	"github.com/cexll/agentsdk-go/pkg/runtime/skills"
+	"github.com/cexll/agentsdk-go/pkg/runtime/subagents"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// Tools returns the registered tools sorted by name. Lazily registered MCP
+// servers are connected first so their tools are included.
+func (rt *Runtime) Tools(ctx context.Context) []tool.Tool {
+	if rt == nil || rt.registry == nil {
+		return nil
+	}
+	rt.registry.ConnectMCP(ctx)
+	tools := rt.registry.List()
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name() < tools[j].Name() })
+	return tools
+}
+
+// CallTool runs one tool outside a model loop. The call passes through the
+// same hooks, permission rules, approvals and sandbox as a call made by the
+// model; sessionID scopes hooks and audit records and defaults to a fresh
+// id. A failed tool returns its result, with IsError set, and the error.
+func (rt *Runtime) CallTool(ctx context.Context, sessionID, name string, params map[string]any) (*tool.ToolResult, error) {
+	if rt == nil {
+		return nil, ErrRuntimeClosed
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("api: tool name is empty")
+	}
+	if err := rt.beginRun(); err != nil {
+		return nil, err
+	}
+	defer rt.endRun()
+
+	if strings.TrimSpace(sessionID) == "" {
+		sessionID = defaultSessionID(rt.mode.EntryPoint)
+	}
+	rt.registry.ConnectMCP(ctx)
+	gen := rt.idGenerator()
+	audit := newAuditEmitter(rt.audit, sessionID, newID(gen, IDRun))
+	hookAdapter := &runtimeHookAdapter{executor: rt.hooks, recorder: defaultHookRecorder(), audit: audit}
+	exec := &runtimeToolExecutor{
+		executor:           rt.executor,
+		hooks:              hookAdapter,
+		root:               rt.sbRoot,
+		host:               "localhost",
+		sessionID:          sessionID,
+		audit:              audit,
+		scan:               rt.newFileScanner(),
+		permissionResolver: buildPermissionResolver(hookAdapter, rt.rememberPermissions(rt.opts.permissionHandler()), rt.opts.ApprovalQueue, rt.opts.ApprovalApprover, rt.opts.ApprovalWhitelistTTL, rt.opts.ApprovalWait),
+	}
+	res, err := exec.Execute(ctx, agent.ToolCall{ID: newID(gen, IDToolCall), Name: name, Input: params}, nil)
+	out := &tool.ToolResult{Success: err == nil, Output: res.Output, Artifacts: exec.artifacts}
+	if res.Metadata != nil {
+		out.Data = res.Metadata["data"]
+		out.OutputRef, _ = res.Metadata["output_ref"].(*tool.OutputRef)
+		out.ErrorDetail, _ = res.Metadata["error_detail"].(*tool.ToolError)
+	}
+	if err != nil {
+		out.Error = err
+		out.IsError = true
+		if out.ErrorDetail == nil {
+			out.ErrorDetail = tool.ClassifyError(err)
+		}
+	}
+	return out, err
+}
+
+// Skills lists the registered skills.
+func (rt *Runtime) Skills() []skills.Definition {
+	if rt == nil || rt.skReg == nil {
+		return nil
+	}
+	return rt.skReg.List()
+}
+
+// RunSkill executes the named skill with prompt as its activation input.
+func (rt *Runtime) RunSkill(ctx context.Context, name, prompt string) (skills.Result, error) {
+	if rt == nil || rt.skReg == nil {
+		return skills.Result{}, skills.ErrUnknownSkill
+	}
+	return rt.skReg.Execute(ctx, name, skills.ActivationContext{Prompt: prompt})
+}
+
+// Subagents lists the registered subagents. Run one by setting
+// Request.TargetSubagent.
+func (rt *Runtime) Subagents() []subagents.Definition {
+	if rt == nil || rt.subMgr == nil {
+		return nil
+	}
+	return rt.subMgr.List()
+}
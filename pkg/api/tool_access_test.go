@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+func TestRuntimeCallTool(t *testing.T) {
+	echo := &echoTool{}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot: t.TempDir(),
+		Model:       &stubModel{},
+		Tools:       []tool.Tool{echo, &failingTool{err: errors.New("boom")}},
+	})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	var names []string
+	for _, impl := range rt.Tools(context.Background()) {
+		names = append(names, impl.Name())
+	}
+	if len(names) != 2 || names[0] != "echo" || names[1] != "fail" {
+		t.Fatalf("unexpected tools %v", names)
+	}
+
+	res, err := rt.CallTool(context.Background(), "", "echo", map[string]any{"text": "hi"})
+	if err != nil {
+		t.Fatalf("call echo: %v", err)
+	}
+	if res.Output != "hi" || res.IsError || echo.calls != 1 {
+		t.Fatalf("unexpected result %+v (calls %d)", res, echo.calls)
+	}
+
+	res, err = rt.CallTool(context.Background(), "sess", "fail", nil)
+	if err == nil || res == nil || !res.IsError || res.ErrorDetail == nil {
+		t.Fatalf("expected failed result, got %+v, %v", res, err)
+	}
+
+	if _, err := rt.CallTool(context.Background(), "sess", " ", nil); err == nil {
+		t.Fatal("expected error for empty tool name")
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+func TestNewOutputPersisterAppliesOptions(t *testing.T) {
+	p := newOutputPersister(Options{}, nil)
+	if p.DefaultThresholdBytes != 64*1024 || p.HeadBytes == 0 || p.TailBytes == 0 || p.NoSpill {
+		t.Fatalf("unexpected defaults %+v", p)
+	}
+	if p.BaseDir != toolOutputBaseDir() {
+		t.Fatalf("base dir %q does not match session cleanup dir %q", p.BaseDir, toolOutputBaseDir())
+	}
+
+	opts := Options{}
+	for _, apply := range []func(*Options){
+		WithToolOutputLimit(tool.OutputLimit{MaxBytes: 1000, HeadBytes: 100, NoSpill: true}),
+		WithToolOutputLimitFor(" Bash ", tool.OutputLimit{TailBytes: 50}),
+	} {
+		apply(&opts)
+	}
+	p = newOutputPersister(opts.frozen(), nil)
+	if p.DefaultThresholdBytes != 1000 || p.HeadBytes != 100 || p.TailBytes != 0 || !p.NoSpill {
+		t.Fatalf("default limit not applied: %+v", p)
+	}
+	if got := p.PerToolLimits["bash"]; got.TailBytes != 50 {
+		t.Fatalf("per-tool limit not applied: %+v", p.PerToolLimits)
+	}
+}
+
+func TestNewOutputPersisterAppliesSettings(t *testing.T) {
+	zero, noSpill := 0, false
+	settings := &config.Settings{ToolOutput: &config.ToolOutputConfig{
+		DefaultThresholdBytes: 500,
+		PerToolThresholdBytes: map[string]int{"grep": 50},
+		HeadBytes:             &zero,
+		Spill:                 &noSpill,
+	}}
+	p := newOutputPersister(Options{}, settings)
+	if p.DefaultThresholdBytes != 500 || p.PerToolThresholdBytes["grep"] != 50 || p.HeadBytes != 0 || p.TailBytes == 0 || !p.NoSpill {
+		t.Fatalf("settings not applied: %+v", p)
+	}
+
+	// Options win over settings.
+	p = newOutputPersister(Options{ToolOutputLimit: tool.OutputLimit{HeadBytes: 10}}, settings)
+	if p.DefaultThresholdBytes != 500 || p.HeadBytes != 10 || p.TailBytes != 0 || p.NoSpill {
+		t.Fatalf("options did not override settings: %+v", p)
+	}
+}
+
+func TestReadToolOpensSpilledToolOutput(t *testing.T) {
+	root := t.TempDir()
+	p := newOutputPersister(Options{ToolOutputLimit: tool.OutputLimit{MaxBytes: 16, HeadBytes: 8}}, nil)
+	session := fmt.Sprintf("spill-%d", os.Getpid())
+	t.Cleanup(func() { _ = cleanupToolOutputSessionDir(session) })
+
+	res := &tool.ToolResult{Output: strings.Repeat("line\n", 10)}
+	if err := p.MaybePersist(tool.Call{Name: "Bash", SessionID: session}, res); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	if res.OutputRef == nil || !strings.Contains(res.Output, res.OutputRef.Path) {
+		t.Fatalf("expected spilled output reference, got %q", res.Output)
+	}
+
+	read := builtinToolFactories(root, "", false, EntryPointCLI, nil, nil, nil, nil)["file_read"]()
+	out, err := read.Execute(context.Background(), map[string]interface{}{"file_path": res.OutputRef.Path})
+	if err != nil {
+		t.Fatalf("read spilled output: %v", err)
+	}
+	if strings.Count(out.Output, "line") != 10 {
+		t.Fatalf("unexpected read output %q", out.Output)
+	}
+
+	write := builtinToolFactories(root, "", false, EntryPointCLI, nil, nil, nil, nil)["file_write"]()
+	if _, err := write.Execute(context.Background(), map[string]interface{}{"file_path": res.OutputRef.Path, "content": "x"}); err == nil {
+		t.Fatalf("expected write outside the project to be rejected")
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/agent"
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// ToolsChange lists the tools that appeared in or left a run's tool list
+// since its previous model call.
+type ToolsChange struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// ToolsChangedKey holds the ToolsChange of the current iteration in
+// middleware.State.Values from StageBeforeModel on. It is absent when the
+// tool list did not change.
+var ToolsChangedKey = agent.NewKey[ToolsChange]("tools.changed", agent.Transient())
+
+// applyToolPolicies sets the settings' toolPolicies on registry. Later
+// Registry.SetPolicy calls replace them.
+func applyToolPolicies(registry *tool.Registry, settings *config.Settings) error {
+	if settings == nil {
+		return nil
+	}
+	for name, cfg := range settings.ToolPolicies {
+		p := tool.Policy{
+			Timeout:    time.Duration(cfg.TimeoutSeconds * float64(time.Second)),
+			MaxRetries: cfg.MaxRetries,
+			RetryOn:    cfg.RetryOn,
+		}
+		if err := registry.SetPolicy(name, p); err != nil {
+			return fmt.Errorf("api: %w", err)
+		}
+	}
+	return nil
+}
+
+// ToolRegistry returns the registry behind the runtime's tools. Tools
+// registered or unregistered on it are offered to the model from the next
+// model call on, including in runs already in progress; request tool
+// whitelists still apply. Policies set with Registry.SetPolicy apply to the
+// next call of the tool.
+func (rt *Runtime) ToolRegistry() *tool.Registry {
+	if rt == nil {
+		return nil
+	}
+	return rt.registry
+}
+
+// toolRefresher rebuilds the run's tool definitions before every model
+// call and publishes the difference under ToolsChangedKey. It runs ahead of
+// the caller's middleware so they see the change in StageBeforeModel.
+func (rt *Runtime) toolRefresher(m *conversationModel, allow map[string]struct{}) middleware.Middleware {
+	return middleware.Funcs{Identifier: "tool-registry", OnBeforeModel: func(_ context.Context, st *middleware.State) error {
+		next := availableTools(rt.registry, allow)
+		change := diffTools(m.tools, next)
+		m.tools = next
+		if len(change.Added) == 0 && len(change.Removed) == 0 {
+			delete(st.Values, ToolsChangedKey.Name())
+			return nil
+		}
+		ToolsChangedKey.Store(st.Values, change)
+		return nil
+	}}
+}
+
+// diffTools compares two name-sorted tool lists.
+func diffTools(prev, next []model.ToolDefinition) ToolsChange {
+	var change ToolsChange
+	i, j := 0, 0
+	for i < len(prev) || j < len(next) {
+		switch {
+		case j == len(next) || (i < len(prev) && prev[i].Name < next[j].Name):
+			change.Removed = append(change.Removed, prev[i].Name)
+			i++
+		case i == len(prev) || next[j].Name < prev[i].Name:
+			change.Added = append(change.Added, next[j].Name)
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return change
+}
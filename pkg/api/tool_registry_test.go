@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/agent"
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+func TestToolRegistryChangesApplyToNextModelCall(t *testing.T) {
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{
+			{ID: "c1", Name: "echo", Arguments: map[string]any{"text": "hi"}},
+		}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	var (
+		rt      *Runtime
+		changes []ToolsChange
+	)
+	swap := middleware.Funcs{
+		Identifier: "swap",
+		OnAfterTool: func(context.Context, *middleware.State) error {
+			if err := rt.ToolRegistry().Register(&namedTool{name: "late"}); err != nil {
+				return err
+			}
+			rt.ToolRegistry().Unregister("echo")
+			return nil
+		},
+		OnBeforeModel: func(_ context.Context, st *middleware.State) error {
+			change, _ := ToolsChangedKey.Lookup(st.Values)
+			changes = append(changes, change)
+			return nil
+		},
+	}
+	var err error
+	rt, err = New(context.Background(), Options{
+		ProjectRoot: newClaudeProject(t),
+		Model:       mdl,
+		Tools:       []tool.Tool{&echoTool{}},
+		Middleware:  []middleware.Middleware{swap},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	if _, err := rt.Run(context.Background(), Request{Prompt: "go", SessionID: "s"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(mdl.requests) != 2 {
+		t.Fatalf("expected 2 model calls, got %d", len(mdl.requests))
+	}
+	names := func(req model.Request) []string {
+		var out []string
+		for _, def := range req.Tools {
+			out = append(out, def.Name)
+		}
+		return out
+	}
+	if got := names(mdl.requests[0]); !reflect.DeepEqual(got, []string{"echo"}) {
+		t.Fatalf("first call tools %v", got)
+	}
+	if got := names(mdl.requests[1]); !reflect.DeepEqual(got, []string{"late"}) {
+		t.Fatalf("second call tools %v", got)
+	}
+	want := []ToolsChange{{}, {Added: []string{"late"}, Removed: []string{"echo"}}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("middleware saw %+v", changes)
+	}
+}
+
+type rateLimitedTool struct {
+	calls int
+}
+
+func (r *rateLimitedTool) Name() string             { return "fetch" }
+func (r *rateLimitedTool) Description() string      { return "fails once" }
+func (r *rateLimitedTool) Schema() *tool.JSONSchema { return nil }
+func (r *rateLimitedTool) Execute(context.Context, map[string]interface{}) (*tool.ToolResult, error) {
+	r.calls++
+	if r.calls == 1 {
+		return nil, errors.New("HTTP 429: slow down")
+	}
+	return &tool.ToolResult{Success: true, Output: "fetched"}, nil
+}
+
+func TestSettingsToolPoliciesRetryAndReportAttempts(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{"toolPolicies":{"Fetch":{"timeoutSeconds":5,"maxRetries":1,"retryOn":["429"]}}}`)
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "c1", Name: "fetch"}}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	var seen map[string]any
+	observe := middleware.Funcs{
+		Identifier: "observe",
+		OnAfterTool: func(_ context.Context, st *middleware.State) error {
+			if res, ok := st.ToolResult.(agent.ToolResult); ok {
+				seen = res.Metadata
+			}
+			return nil
+		},
+	}
+	fetch := &rateLimitedTool{}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot: root,
+		Model:       mdl,
+		Tools:       []tool.Tool{fetch},
+		Middleware:  []middleware.Middleware{observe},
+		ToolRetry:   &tool.RetryPolicy{Backoff: func(int) time.Duration { return 0 }},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	policy, ok := rt.ToolRegistry().Policy("fetch")
+	if !ok || policy.Timeout != 5*time.Second || policy.MaxRetries == nil || *policy.MaxRetries != 1 {
+		t.Fatalf("settings policy not applied: %+v ok=%v", policy, ok)
+	}
+	if _, err := rt.Run(context.Background(), Request{Prompt: "go", SessionID: "s"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if fetch.calls != 2 {
+		t.Fatalf("expected one retry, got %d calls", fetch.calls)
+	}
+	if seen["attempts"] != 2 {
+		t.Fatalf("AfterTool metadata %+v", seen)
+	}
+	if failures, _ := seen["attempt_errors"].([]string); len(failures) != 1 || failures[0] != "HTTP 429: slow down" {
+		t.Fatalf("attempt errors %+v", seen["attempt_errors"])
+	}
+}
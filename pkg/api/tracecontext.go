@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+
+	"github.com/cexll/agentsdk-go/pkg/core/tracecontext"
+)
+
+// runTraceContext picks the trace a run joins: Request.Traceparent first,
+// then one already on ctx (set by tracecontext.Handler or a parent run),
+// otherwise a new trace. The run gets its own parent ID so downstream calls
+// point at it rather than at the caller.
+func runTraceContext(ctx context.Context, req Request) tracecontext.TraceContext {
+	if tc, err := tracecontext.Parse(req.Traceparent, req.Tracestate); err == nil {
+		return tc.Child()
+	}
+	if tc, ok := tracecontext.FromContext(ctx); ok {
+		return tc.Child()
+	}
+	return tracecontext.New()
+}
+
+// remoteParentTracer is implemented by tracers that can parent the agent
+// span on an inbound trace context.
+type remoteParentTracer interface {
+	startAgentSpanFrom(parent tracecontext.TraceContext, sessionID, requestID string) SpanContext
+}
+
+// startRunSpan opens the agent.run span. When the tracer records it, the
+// span's ID replaces the run's parent ID on prep.ctx so provider and MCP
+// requests nest under it.
+func (rt *Runtime) startRunSpan(prep *preparedRun) SpanContext {
+	if rt.tracer == nil {
+		return nil
+	}
+	var span SpanContext
+	if rp, ok := rt.tracer.(remoteParentTracer); ok {
+		span = rp.startAgentSpanFrom(prep.trace, prep.normalized.SessionID, prep.normalized.RequestID)
+	} else {
+		span = rt.tracer.StartAgentSpan(prep.normalized.SessionID, prep.normalized.RequestID, 0)
+	}
+	if span != nil && span.TraceID() == prep.trace.TraceID && span.SpanID() != "" {
+		prep.trace.ParentID = span.SpanID()
+		prep.ctx = tracecontext.WithContext(prep.ctx, prep.trace)
+	}
+	return span
+}
+
+func (rt *Runtime) endRunSpan(span SpanContext, attrs map[string]any, err error) {
+	if rt.tracer == nil || span == nil {
+		return
+	}
+	rt.tracer.EndSpan(span, attrs, err)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/core/tracecontext"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+type traceCapturingModel struct {
+	stubModel
+	seen []tracecontext.TraceContext
+}
+
+func (m *traceCapturingModel) Complete(ctx context.Context, req model.Request) (*model.Response, error) {
+	tc, _ := tracecontext.FromContext(ctx)
+	m.seen = append(m.seen, tc)
+	return m.stubModel.Complete(ctx, req)
+}
+
+func (m *traceCapturingModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	tc, _ := tracecontext.FromContext(ctx)
+	m.seen = append(m.seen, tc)
+	return m.stubModel.CompleteStream(ctx, req, cb)
+}
+
+func TestRunPropagatesTraceContext(t *testing.T) {
+	ctx := context.Background()
+	mdl := &traceCapturingModel{stubModel: stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "ok"}}}}}
+	rt, err := New(ctx, Options{ProjectRoot: t.TempDir(), Model: mdl})
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	defer rt.Close()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	resp, err := rt.Run(ctx, Request{
+		Prompt:      "hi",
+		SessionID:   "s1",
+		Traceparent: "00-" + traceID + "-00f067aa0ba902b7-01",
+		Tracestate:  "congo=t61rcWkgMzE",
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if resp.TraceID != traceID {
+		t.Fatalf("response trace id = %q", resp.TraceID)
+	}
+	got := mdl.seen[len(mdl.seen)-1]
+	if got.TraceID != traceID || got.ParentID == "00f067aa0ba902b7" || got.State != "congo=t61rcWkgMzE" {
+		t.Fatalf("model saw %+v", got)
+	}
+
+	inbound := tracecontext.New()
+	resp, err = rt.Run(tracecontext.WithContext(ctx, inbound), Request{Prompt: "hi", SessionID: "s2"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if resp.TraceID != inbound.TraceID {
+		t.Fatalf("context trace not joined: %q vs %q", resp.TraceID, inbound.TraceID)
+	}
+
+	resp, err = rt.Run(ctx, Request{Prompt: "hi", SessionID: "s3", Traceparent: "garbage"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if resp.TraceID == "" || resp.TraceID == traceID || resp.TraceID == inbound.TraceID {
+		t.Fatalf("expected a new trace, got %q", resp.TraceID)
+	}
+}
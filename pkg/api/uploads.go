@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/artifact"
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+// ErrNoArtifactStore is returned when uploads or attachment IDs are used
+// without Options.ArtifactStore.
+var ErrNoArtifactStore = errors.New("api: artifact store is not configured")
+
+// UploadHandler returns an http.Handler accepting multipart uploads into
+// Options.ArtifactStore, limited by the settings "uploads" block. The
+// returned artifact IDs can be passed in Request.AttachmentIDs.
+func (rt *Runtime) UploadHandler() (http.Handler, error) {
+	if rt == nil || rt.opts.ArtifactStore == nil {
+		return nil, ErrNoArtifactStore
+	}
+	return artifact.UploadHandler(rt.opts.ArtifactStore, uploadLimits(rt.Settings())), nil
+}
+
+func uploadLimits(settings *config.Settings) artifact.UploadLimits {
+	if settings == nil || settings.Uploads == nil {
+		return artifact.UploadLimits{}
+	}
+	return artifact.UploadLimits{
+		MaxFileBytes:       settings.Uploads.MaxFileBytes,
+		MaxRequestBytes:    settings.Uploads.MaxRequestBytes,
+		AllowedMimeTypes:   append([]string(nil), settings.Uploads.AllowedMimeTypes...),
+		AllowActiveContent: settings.Uploads.AllowActiveContent != nil && *settings.Uploads.AllowActiveContent,
+	}
+}
+
+// resolveAttachments loads uploaded artifacts and converts them to content
+// blocks: images and PDFs natively, text inline. Uploads recorded for a
+// different session are rejected.
+func (rt *Runtime) resolveAttachments(ctx context.Context, sessionID string, ids []string) ([]model.ContentBlock, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if rt.opts.ArtifactStore == nil {
+		return nil, ErrNoArtifactStore
+	}
+	blocks := make([]model.ContentBlock, 0, len(ids))
+	for _, id := range ids {
+		meta, data, err := rt.opts.ArtifactStore.Get(ctx, strings.TrimSpace(id))
+		if err != nil {
+			return nil, fmt.Errorf("api: attachment %s: %w", id, err)
+		}
+		if !meta.OwnedBy(sessionID) {
+			return nil, fmt.Errorf("api: attachment %s: %w", id, artifact.ErrNotFound)
+		}
+		block, err := Attachment{Name: meta.Name, MediaType: meta.MediaType, Data: data}.contentBlock()
+		if err != nil {
+			return nil, fmt.Errorf("api: attachment %s: %w", id, err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// Attachment is a file sent with a Request. Set Data to the raw bytes or
+// URL to a location the provider fetches itself. Images (JPEG, PNG, GIF,
+// WebP) and PDFs become image and document content blocks; text and JSON
+// are inlined into the prompt.
+type Attachment struct {
+	Name string
+	// MediaType is detected from Data, or from the URL extension, when
+	// empty.
+	MediaType string
+	Data      []byte
+	URL       string
+}
+
+// attachmentBlocks converts request attachments to content blocks.
+func attachmentBlocks(attachments []Attachment) ([]model.ContentBlock, error) {
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+	blocks := make([]model.ContentBlock, 0, len(attachments))
+	for i, att := range attachments {
+		block, err := att.contentBlock()
+		if err != nil {
+			name := att.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			return nil, fmt.Errorf("api: attachment %s: %w", name, err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func (a Attachment) contentBlock() (model.ContentBlock, error) {
+	if len(a.Data) > 0 && a.URL != "" {
+		return model.ContentBlock{}, errors.New("set either data or url, not both")
+	}
+	if len(a.Data) == 0 && strings.TrimSpace(a.URL) == "" {
+		return model.ContentBlock{}, errors.New("data or url is required")
+	}
+	mediaType := a.MediaType
+	if mediaType == "" {
+		if a.URL != "" {
+			if u, err := url.Parse(a.URL); err == nil {
+				mediaType = mime.TypeByExtension(path.Ext(u.Path))
+			}
+		} else {
+			mediaType = http.DetectContentType(a.Data)
+		}
+	}
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	var data string
+	if len(a.Data) > 0 {
+		data = base64.StdEncoding.EncodeToString(a.Data)
+	}
+	switch {
+	case mediaType == "image/jpeg" || mediaType == "image/png" || mediaType == "image/gif" || mediaType == "image/webp":
+		return model.ContentBlock{Type: model.ContentBlockImage, MediaType: mediaType, Data: data, URL: a.URL}, nil
+	case mediaType == "application/pdf":
+		return model.ContentBlock{Type: model.ContentBlockDocument, MediaType: mediaType, Data: data, URL: a.URL}, nil
+	case (strings.HasPrefix(mediaType, "text/") || mediaType == "application/json") && a.URL == "":
+		return model.ContentBlock{Type: model.ContentBlockText, Text: fmt.Sprintf("Attachment %s:\n%s", a.Name, a.Data)}, nil
+	case mediaType == "":
+		return model.ContentBlock{}, errors.New("unknown media type")
+	default:
+		return model.ContentBlock{}, fmt.Errorf("unsupported media type %s", mediaType)
+	}
+}
+
+// pruneArtifacts removes artifacts older than the history retention window
+// when the store can list its contents.
+func pruneArtifacts(store artifact.Store, retainDays int) {
+	if store == nil || retainDays <= 0 {
+		return
+	}
+	if _, ok := store.(artifact.Lister); !ok {
+		return
+	}
+	cutoff := time.Now().Add(-time.Duration(retainDays) * 24 * time.Hour)
+	if _, err := artifact.Prune(context.Background(), store, cutoff); err != nil {
+		log.Printf("artifact cleanup warning: %v", err)
+	}
+}
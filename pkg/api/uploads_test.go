@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/artifact"
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+type blockCaptureModel struct {
+	mu     sync.Mutex
+	blocks []model.ContentBlock
+}
+
+func (m *blockCaptureModel) Complete(_ context.Context, req model.Request) (*model.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n := len(req.Messages); n > 0 {
+		m.blocks = req.Messages[n-1].ContentBlocks
+	}
+	return &model.Response{Message: model.Message{Role: "assistant", Content: "seen"}}, nil
+}
+
+func (m *blockCaptureModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	resp, err := m.Complete(ctx, req)
+	if err != nil {
+		return err
+	}
+	return cb(model.StreamResult{Final: true, Response: resp})
+}
+
+func TestUploadsBecomeAttachments(t *testing.T) {
+	store := artifact.NewMemoryStore()
+	mdl := &blockCaptureModel{}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:         t.TempDir(),
+		Model:               mdl,
+		EnabledBuiltinTools: []string{},
+		RulesEnabled:        boolPtr(false),
+		ArtifactStore:       store,
+		SettingsOverrides:   &config.Settings{Uploads: &config.UploadsConfig{AllowedMimeTypes: []string{"image/png", "text/*"}}},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	handler, err := rt.UploadHandler()
+	if err != nil {
+		t.Fatalf("upload handler: %v", err)
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("session_id", "s1")
+	part, _ := mw.CreateFormFile("file", "shot.png")
+	_, _ = part.Write([]byte("\x89PNG\r\n\x1a\n"))
+	part, _ = mw.CreateFormFile("file", "notes.txt")
+	_, _ = part.Write([]byte("remember the milk"))
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/v1/uploads", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var uploaded artifact.UploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &uploaded); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("upload status %d: %s", rec.Code, rec.Body)
+	}
+	ids := []string{uploaded.Attachments[0].ID, uploaded.Attachments[1].ID}
+
+	if _, err := rt.Run(context.Background(), Request{Prompt: "look", SessionID: "s1", AttachmentIDs: ids}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	var image, text bool
+	for _, block := range mdl.blocks {
+		image = image || (block.Type == model.ContentBlockImage && block.MediaType == "image/png")
+		text = text || (block.Type == model.ContentBlockText && strings.Contains(block.Text, "remember the milk"))
+	}
+	if !image || !text {
+		t.Fatalf("attachment blocks = %+v", mdl.blocks)
+	}
+
+	if _, err := rt.Run(context.Background(), Request{Prompt: "look", SessionID: "other", AttachmentIDs: ids[:1]}); !errors.Is(err, artifact.ErrNotFound) {
+		t.Fatalf("expected cross-session attachment to be rejected, got %v", err)
+	}
+
+	noStore := newTestRuntime(t, staticModel{content: "x"}, CompactConfig{})
+	if _, err := noStore.UploadHandler(); !errors.Is(err, ErrNoArtifactStore) {
+		t.Fatalf("expected ErrNoArtifactStore, got %v", err)
+	}
+}
+
+func TestRequestAttachmentsBecomeContentBlocks(t *testing.T) {
+	mdl := &blockCaptureModel{}
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:         t.TempDir(),
+		Model:               mdl,
+		EnabledBuiltinTools: []string{},
+		RulesEnabled:        boolPtr(false),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	req := Request{Prompt: "look", Attachments: []Attachment{
+		{Name: "shot.png", Data: []byte("\x89PNG\r\n\x1a\n")},
+		{Name: "spec", URL: "https://example.com/spec.pdf"},
+		{Name: "notes.txt", MediaType: "text/plain; charset=utf-8", Data: []byte("remember the milk")},
+	}}
+	if _, err := rt.Run(context.Background(), req); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(mdl.blocks) != 3 {
+		t.Fatalf("expected three attachment blocks, got %+v", mdl.blocks)
+	}
+	if b := mdl.blocks[0]; b.Type != model.ContentBlockImage || b.MediaType != "image/png" || b.Data == "" {
+		t.Fatalf("unexpected image block %+v", b)
+	}
+	if b := mdl.blocks[1]; b.Type != model.ContentBlockDocument || b.MediaType != "application/pdf" || b.URL != "https://example.com/spec.pdf" {
+		t.Fatalf("unexpected document block %+v", b)
+	}
+	if b := mdl.blocks[2]; b.Type != model.ContentBlockText || !strings.Contains(b.Text, "remember the milk") {
+		t.Fatalf("unexpected text block %+v", b)
+	}
+
+	for _, bad := range []Attachment{
+		{Name: "empty"},
+		{Name: "both", Data: []byte("x"), URL: "https://example.com/a.png"},
+		{Name: "zip", Data: []byte("PK\x03\x04")},
+		{Name: "remote.txt", URL: "https://example.com/remote.txt"},
+	} {
+		_, err := rt.Run(context.Background(), Request{Prompt: "look", Attachments: []Attachment{bad}})
+		if err == nil || !strings.Contains(err.Error(), "api: attachment "+bad.Name) {
+			t.Fatalf("expected %s to be rejected, got %v", bad.Name, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/voice"
+)
+
+// Voice events emitted by RunStream.
+const (
+	// EventTranscript carries the Request.Audio transcript (Output string)
+	// before the agent starts.
+	EventTranscript = "transcript"
+	// EventAudioDelta carries one synthesized voice.Audio clip per sentence
+	// of streamed reply text when Request.Speak is set.
+	EventAudioDelta = "audio_delta"
+)
+
+var (
+	ErrSpeechToTextUnavailable = errors.New("api: Request.Audio requires Options.SpeechToText")
+	ErrTextToSpeechUnavailable = errors.New("api: Request.Speak requires Options.TextToSpeech")
+)
+
+// checkVoice rejects voice requests the runtime cannot serve before any
+// work starts.
+func (rt *Runtime) checkVoice(req Request) error {
+	if req.Audio != nil && rt.opts.SpeechToText == nil {
+		return ErrSpeechToTextUnavailable
+	}
+	if req.Speak && rt.opts.TextToSpeech == nil {
+		return ErrTextToSpeechUnavailable
+	}
+	return nil
+}
+
+// transcribe converts Request.Audio to text and appends it to the prompt.
+func (rt *Runtime) transcribe(ctx context.Context, req *Request) (string, error) {
+	if req.Audio == nil {
+		return "", nil
+	}
+	text, err := rt.opts.SpeechToText.Transcribe(ctx, *req.Audio)
+	if err != nil {
+		return "", fmt.Errorf("api: transcribe audio: %w", err)
+	}
+	text = strings.TrimSpace(text)
+	if prompt := strings.TrimSpace(req.Prompt); prompt != "" && text != "" {
+		req.Prompt = prompt + "\n\n" + text
+	} else if text != "" {
+		req.Prompt = text
+	}
+	return text, nil
+}
+
+// speak synthesizes the final output for Run. Failures are logged rather
+// than failing an otherwise successful run.
+func (rt *Runtime) speak(ctx context.Context, resp *Response) {
+	text := responseText(resp)
+	if text == "" {
+		return
+	}
+	if out, ok := resp.Outputs["text"]; ok && out.Err == nil && out.Text != "" {
+		text = out.Text
+	} else {
+		text = plainText(text)
+	}
+	audio, err := rt.opts.TextToSpeech.Synthesize(ctx, text)
+	if err != nil {
+		log.Printf("api: text-to-speech failed: %v", err)
+		return
+	}
+	if audio.Text == "" {
+		audio.Text = text
+	}
+	resp.Audio = &audio
+}
+
+// newSpeechStream wires a streaming TTS adapter that emits audio_delta events.
+func (rt *Runtime) newSpeechStream(ctx context.Context, sessionID string, out chan<- StreamEvent) *voice.Stream {
+	tts := rt.opts.TextToSpeech
+	// Deltas are markdown; speak the plain rendering of each sentence.
+	plain := voice.TextToSpeechFunc(func(ctx context.Context, text string) (voice.Audio, error) {
+		text = plainText(text)
+		audio, err := tts.Synthesize(ctx, text)
+		if err == nil && audio.Text == "" {
+			audio.Text = text
+		}
+		return audio, err
+	})
+	return voice.NewStream(ctx, plain, func(audio voice.Audio) {
+		select {
+		case out <- StreamEvent{Type: EventAudioDelta, SessionID: sessionID, Output: audio}:
+		case <-ctx.Done():
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/voice"
+)
+
+type echoPromptModel struct {
+	mu     sync.Mutex
+	prompt string
+}
+
+func (m *echoPromptModel) Complete(_ context.Context, req model.Request) (*model.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n := len(req.Messages); n > 0 {
+		m.prompt = req.Messages[n-1].Content
+	}
+	return &model.Response{Message: model.Message{Role: "assistant", Content: "Sure, **on it**. The lights are now off in the kitchen and hall."}}, nil
+}
+
+func (m *echoPromptModel) CompleteStream(ctx context.Context, req model.Request, cb model.StreamHandler) error {
+	resp, err := m.Complete(ctx, req)
+	if err != nil {
+		return err
+	}
+	return cb(model.StreamResult{Final: true, Response: resp})
+}
+
+func newVoiceRuntime(t *testing.T, mdl model.Model, stt voice.SpeechToText, tts voice.TextToSpeech) *Runtime {
+	t.Helper()
+	rt, err := New(context.Background(), Options{
+		ProjectRoot:         t.TempDir(),
+		Model:               mdl,
+		EnabledBuiltinTools: []string{},
+		RulesEnabled:        boolPtr(false),
+		SpeechToText:        stt,
+		TextToSpeech:        tts,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	return rt
+}
+
+func TestRunTranscribesAndSpeaks(t *testing.T) {
+	mdl := &echoPromptModel{}
+	stt := voice.SpeechToTextFunc(func(_ context.Context, audio voice.Audio) (string, error) {
+		if string(audio.Data) != "pcm" {
+			return "", errors.New("unexpected audio")
+		}
+		return " turn off the lights ", nil
+	})
+	var spoken []string
+	tts := voice.TextToSpeechFunc(func(_ context.Context, text string) (voice.Audio, error) {
+		spoken = append(spoken, text)
+		return voice.Audio{Data: []byte("mp3"), MediaType: "audio/mpeg"}, nil
+	})
+	rt := newVoiceRuntime(t, mdl, stt, tts)
+
+	resp, err := rt.Run(context.Background(), Request{Prompt: "Voice command:", Audio: &voice.Audio{Data: []byte("pcm"), MediaType: "audio/wav"}, Speak: true})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if resp.Transcript != "turn off the lights" || !strings.Contains(mdl.prompt, "Voice command:\n\nturn off the lights") {
+		t.Fatalf("transcript %q, prompt %q", resp.Transcript, mdl.prompt)
+	}
+	if resp.Audio == nil || string(resp.Audio.Data) != "mp3" || len(spoken) != 1 || strings.Contains(spoken[0], "**") {
+		t.Fatalf("audio %+v, spoken %q", resp.Audio, spoken)
+	}
+
+	if _, err := newVoiceRuntime(t, mdl, nil, nil).Run(context.Background(), Request{Prompt: "hi", Speak: true}); !errors.Is(err, ErrTextToSpeechUnavailable) {
+		t.Fatalf("expected ErrTextToSpeechUnavailable, got %v", err)
+	}
+}
+
+func TestRunStreamEmitsTranscriptAndAudio(t *testing.T) {
+	stt := voice.SpeechToTextFunc(func(context.Context, voice.Audio) (string, error) { return "lights off", nil })
+	tts := voice.TextToSpeechFunc(func(_ context.Context, text string) (voice.Audio, error) {
+		return voice.Audio{Data: []byte(text), MediaType: "audio/mpeg"}, nil
+	})
+	rt := newVoiceRuntime(t, &echoPromptModel{}, stt, tts)
+	events, err := rt.RunStream(context.Background(), Request{Audio: &voice.Audio{Data: []byte("pcm")}, Speak: true})
+	if err != nil {
+		t.Fatalf("run stream: %v", err)
+	}
+	var (
+		transcript string
+		clips      []string
+	)
+	for evt := range events {
+		switch evt.Type {
+		case EventTranscript:
+			transcript = evt.Output.(string)
+		case EventAudioDelta:
+			clips = append(clips, evt.Output.(voice.Audio).Text)
+		case EventError:
+			t.Fatalf("stream error: %v", evt.Output)
+		}
+	}
+	if transcript != "lights off" {
+		t.Fatalf("transcript = %q", transcript)
+	}
+	if strings.Join(clips, " ") != "Sure, on it. The lights are now off in the kitchen and hall." {
+		t.Fatalf("clips = %q", clips)
+	}
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+)
+
+// WarmStateVersion is the format version of ExportWarmState blobs.
+const WarmStateVersion = 1
+
+// maxWarmStateFiles caps the bytes of .claude files a blob carries.
+const maxWarmStateFiles = 16 << 20
+
+var (
+	// ErrWarmStateVersion reports a blob written by an incompatible SDK.
+	ErrWarmStateVersion = errors.New("api: unsupported warm state version")
+	// ErrWarmStateMismatch reports a blob exported under Options that
+	// resolve settings or the system prompt differently.
+	ErrWarmStateMismatch = errors.New("api: warm state was exported with different options")
+)
+
+// warmStateDirs are the .claude directories the skill, command, subagent
+// and prompt loaders read at startup.
+var warmStateDirs = []string{"skills", "commands", "agents", "prompts"}
+
+// warmState is the decoded payload of an ExportWarmState blob.
+type warmState struct {
+	Version      int                       `json:"version"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	OptionsKey   string                    `json:"options_key"`
+	SystemPrompt string                    `json:"system_prompt,omitempty"`
+	Settings     *config.Settings          `json:"settings"`
+	Provenance   config.SettingsProvenance `json:"provenance,omitempty"`
+	// Files maps slash-separated paths relative to the project root to
+	// their contents.
+	Files map[string][]byte `json:"files,omitempty"`
+}
+
+// ExportWarmState captures what New resolves from disk — the merged
+// settings with their provenance, the system prompt including CLAUDE.md
+// memory, the .claude skill, command, agent and prompt files, and the
+// resources skills declare — as a gzip-compressed blob. Pass it as
+// Options.WarmState to start replicas without re-reading and re-validating
+// that configuration. The blob holds unredacted settings (including env
+// values), so store it like a secret.
+func (rt *Runtime) ExportWarmState() ([]byte, error) {
+	if rt == nil {
+		return nil, ErrRuntimeClosed
+	}
+	rt.mu.RLock()
+	settings := config.MergeSettings(nil, rt.settings)
+	provenance := make(config.SettingsProvenance, len(rt.provenance))
+	for k, v := range rt.provenance {
+		provenance[k] = v
+	}
+	rt.mu.RUnlock()
+
+	var resources []string
+	if rt.skReg != nil {
+		for _, def := range rt.skReg.List() {
+			resources = append(resources, def.Resources...)
+		}
+	}
+	files, err := snapshotClaudeFiles(rt.opts.ProjectRoot, rt.fs, resources...)
+	if err != nil {
+		return nil, err
+	}
+	state := warmState{
+		Version:      WarmStateVersion,
+		CreatedAt:    time.Now().UTC(),
+		OptionsKey:   rt.warmKey,
+		SystemPrompt: rt.opts.SystemPrompt,
+		Settings:     settings,
+		Provenance:   provenance,
+		Files:        files,
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(state); err != nil {
+		return nil, fmt.Errorf("api: encode warm state: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("api: encode warm state: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeWarmState parses opts.WarmState and checks it was exported under
+// equivalent options. It returns nil when no blob is configured.
+func decodeWarmState(opts Options, key string) (*warmState, error) {
+	if len(opts.WarmState) == 0 {
+		return nil, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(opts.WarmState))
+	if err != nil {
+		return nil, fmt.Errorf("api: decode warm state: %w", err)
+	}
+	defer zr.Close()
+	var state warmState
+	if err := json.NewDecoder(io.LimitReader(zr, 4*maxWarmStateFiles)).Decode(&state); err != nil {
+		return nil, fmt.Errorf("api: decode warm state: %w", err)
+	}
+	if state.Version != WarmStateVersion {
+		return nil, fmt.Errorf("%w: %d", ErrWarmStateVersion, state.Version)
+	}
+	if state.OptionsKey != key {
+		return nil, ErrWarmStateMismatch
+	}
+	if state.Settings == nil {
+		return nil, errors.New("api: decode warm state: settings missing")
+	}
+	if state.Provenance == nil {
+		state.Provenance = config.SettingsProvenance{}
+	}
+	if state.Settings.Env == nil {
+		state.Settings.Env = map[string]string{}
+	}
+	return &state, nil
+}
+
+// warmOptionsKey fingerprints the Options that shape what a warm state
+// holds. ProjectRoot is left out so replicas may mount the project
+// elsewhere.
+func warmOptionsKey(opts Options) string {
+	payload, _ := json.Marshal(struct {
+		EntryPoint        EntryPoint       `json:"entry_point"`
+		SystemPrompt      string           `json:"system_prompt"`
+		SettingsPath      string           `json:"settings_path"`
+		SettingsOverrides *config.Settings `json:"settings_overrides"`
+	}{opts.EntryPoint, opts.SystemPrompt, opts.SettingsPath, opts.SettingsOverrides})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// snapshotClaudeFiles reads the files under warmStateDirs of the project's
+// .claude directory, plus the files and directories in extra (skill
+// resources, which may live elsewhere in the project).
+func snapshotClaudeFiles(root string, fsLayer *config.FS, extra ...string) (map[string][]byte, error) {
+	root = strings.TrimSpace(root)
+	if root == "" || fsLayer == nil {
+		return nil, nil
+	}
+	bases := make([]string, 0, len(warmStateDirs)+len(extra))
+	for _, dir := range warmStateDirs {
+		bases = append(bases, filepath.Join(root, ".claude", dir))
+	}
+	bases = append(bases, extra...)
+	files := map[string][]byte{}
+	total := 0
+	for _, base := range bases {
+		err := fsLayer.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() || !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			if _, seen := files[filepath.ToSlash(rel)]; seen || strings.HasPrefix(filepath.ToSlash(rel), "../") {
+				return nil
+			}
+			data, err := fsLayer.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			if total += len(data); total > maxWarmStateFiles {
+				return fmt.Errorf("api: warm state files exceed %d bytes", maxWarmStateFiles)
+			}
+			files[filepath.ToSlash(rel)] = data
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("api: snapshot %s: %w", base, err)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	return files, nil
+}
+
+// warmFS serves the files of a warm state, falling back to next (the
+// caller's EmbedFS) for anything the blob does not carry.
+func warmFS(files map[string][]byte, next fs.FS) fs.FS {
+	if len(files) == 0 {
+		return next
+	}
+	mapped := make(fstest.MapFS, len(files))
+	for name, data := range files {
+		mapped[path.Clean(name)] = &fstest.MapFile{Data: data, Mode: 0o644}
+	}
+	if next == nil {
+		return mapped
+	}
+	return layeredFS{mapped, next}
+}
+
+// layeredFS opens names from the first layer that has them.
+type layeredFS []fs.FS
+
+func (l layeredFS) Open(name string) (fs.File, error) {
+	var firstErr error
+	for _, layer := range l {
+		f, err := layer.Open(name)
+		if err == nil {
+			return f, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func newWarmRuntime(t *testing.T, root string, opts Options) *Runtime {
+	t.Helper()
+	opts.ProjectRoot = root
+	opts.Model = &stubModel{responses: []*model.Response{{Message: model.Message{Role: "assistant", Content: "ok"}}}}
+	rt, err := New(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+	return rt
+}
+
+func TestWarmStateRoundTrip(t *testing.T) {
+	root := newClaudeProjectWithSettings(t, `{"permissions": {"deny": ["Bash(rm:*)"]}, "env": {"TOKEN": "secret"}}`)
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("Use tabs."), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	skillDir := filepath.Join(root, ".claude", "skills", "lint")
+	if err := os.MkdirAll(skillDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: lint\ndescription: Run linters\nresources: [../../../tools/lint.yml]\n---\nRun golangci-lint.\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "tools"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "tools", "lint.yml"), []byte("linters: all\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{SystemPrompt: "Be brief."}
+	source := newWarmRuntime(t, root, opts)
+	blob, err := source.ExportWarmState()
+	if err != nil {
+		t.Fatalf("ExportWarmState: %v", err)
+	}
+
+	// The replica has no project files of its own.
+	opts.WarmState = blob
+	replica := newWarmRuntime(t, t.TempDir(), opts)
+	settings := replica.Settings()
+	if settings.Permissions == nil || len(settings.Permissions.Deny) != 1 || settings.Env["TOKEN"] != "secret" {
+		t.Fatalf("settings not restored: %+v", settings)
+	}
+	if replica.provenance["permissions"] != source.provenance["permissions"] {
+		t.Fatalf("provenance not restored: %v", replica.provenance)
+	}
+	if !strings.Contains(replica.opts.SystemPrompt, "Be brief.") || !strings.Contains(replica.opts.SystemPrompt, "Use tabs.") {
+		t.Fatalf("system prompt not restored: %q", replica.opts.SystemPrompt)
+	}
+	if _, ok := replica.skReg.Get("lint"); !ok {
+		t.Fatalf("skill not restored: %+v", replica.skReg.List())
+	}
+	if data, err := replica.fs.ReadFile(filepath.Join(replica.opts.ProjectRoot, "tools", "lint.yml")); err != nil || string(data) != "linters: all\n" {
+		t.Fatalf("skill resource not packaged: %q, %v", data, err)
+	}
+
+	again, err := replica.ExportWarmState()
+	if err != nil || len(again) == 0 {
+		t.Fatalf("replica re-export: %v", err)
+	}
+	third := newWarmRuntime(t, t.TempDir(), Options{SystemPrompt: "Be brief.", WarmState: again})
+	if _, ok := third.skReg.Get("lint"); !ok {
+		t.Fatal("re-exported state lost the skill")
+	}
+}
+
+func TestWarmStateRejectsMismatchedOrCorruptBlobs(t *testing.T) {
+	root := newClaudeProject(t)
+	source := newWarmRuntime(t, root, Options{SystemPrompt: "a"})
+	blob, err := source.ExportWarmState()
+	if err != nil {
+		t.Fatalf("ExportWarmState: %v", err)
+	}
+	cases := []struct {
+		opts Options
+		want error
+	}{
+		{Options{SystemPrompt: "b", WarmState: blob}, ErrWarmStateMismatch},
+		{Options{SystemPrompt: "a", SettingsOverrides: &config.Settings{Model: "x"}, WarmState: blob}, ErrWarmStateMismatch},
+		{Options{SystemPrompt: "a", WarmState: []byte("not gzip")}, nil},
+	}
+	for i, tc := range cases {
+		tc.opts.ProjectRoot = root
+		tc.opts.Model = &stubModel{}
+		_, err := New(context.Background(), tc.opts)
+		if err == nil || (tc.want != nil && !errors.Is(err, tc.want)) {
+			t.Fatalf("case %d: got %v, want %v", i, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	toolbuiltin "github.com/cexll/agentsdk-go/pkg/tool/builtin"
+)
+
+// webSearchAPIKeyEnv is the default variable holding each provider's key.
+var webSearchAPIKeyEnv = map[string]string{
+	toolbuiltin.SearchProviderBrave:  "BRAVE_API_KEY",
+	toolbuiltin.SearchProviderTavily: "TAVILY_API_KEY",
+}
+
+// newWebSearchTool builds WebSearch with the backend settings.webSearch
+// selects. Keys come from settings.env, then the process environment. A
+// misconfigured backend fails every search instead of silently sending
+// queries to a different provider.
+func newWebSearchTool(settings *config.Settings) tool.Tool {
+	if settings == nil || settings.WebSearch == nil {
+		return toolbuiltin.NewWebSearchTool(nil)
+	}
+	cfg := settings.WebSearch
+	provider := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	keyEnv := strings.TrimSpace(cfg.APIKeyEnv)
+	if keyEnv == "" {
+		keyEnv = webSearchAPIKeyEnv[provider]
+	}
+	var key string
+	if keyEnv != "" {
+		key = settings.Env[keyEnv]
+		if key == "" {
+			key = os.Getenv(keyEnv)
+		}
+	}
+	backend, err := toolbuiltin.NewSearchBackend(toolbuiltin.SearchBackendConfig{Provider: provider, APIKey: key, Endpoint: cfg.Endpoint})
+	if err != nil {
+		backend = brokenSearchBackend{provider: provider, err: err}
+	}
+	return toolbuiltin.NewWebSearchTool(&toolbuiltin.WebSearchOptions{MaxResults: cfg.MaxResults, Backend: backend})
+}
+
+type brokenSearchBackend struct {
+	provider string
+	err      error
+}
+
+func (b brokenSearchBackend) Name() string { return b.provider }
+
+func (b brokenSearchBackend) Search(context.Context, toolbuiltin.SearchRequest) ([]toolbuiltin.SearchResult, error) {
+	return nil, b.err
+}
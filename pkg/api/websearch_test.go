@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	toolbuiltin "github.com/cexll/agentsdk-go/pkg/tool/builtin"
+)
+
+func TestNewWebSearchToolFromSettings(t *testing.T) {
+	t.Setenv("TAVILY_API_KEY", "")
+	tavily := newWebSearchTool(&config.Settings{WebSearch: &config.WebSearchConfig{Provider: "tavily"}})
+	_, err := tavily.Execute(context.Background(), map[string]interface{}{"query": "golang"})
+	if err == nil || !strings.Contains(err.Error(), "API key") {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+
+	tool := newWebSearchTool(&config.Settings{
+		Env:       map[string]string{"SEARCH_KEY": "k"},
+		WebSearch: &config.WebSearchConfig{Provider: "brave", APIKeyEnv: "SEARCH_KEY", Endpoint: "http://127.0.0.1:0"},
+	})
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"query": "golang"})
+	if err == nil || !strings.Contains(err.Error(), "search request") {
+		t.Fatalf("expected the brave backend to be used, got %v", err)
+	}
+	if _, ok := newWebSearchTool(nil).(*toolbuiltin.WebSearchTool); !ok {
+		t.Fatal("expected default web search tool")
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/workspace"
+)
+
+// Workspace reports the monorepo detected around ProjectRoot. ok is false
+// for single-project repositories.
+func (rt *Runtime) Workspace() (workspace.Info, bool) {
+	if rt == nil || rt.workspace == nil {
+		return workspace.Info{}, false
+	}
+	return *rt.workspace, true
+}
+
+// workspaceScope is the part of the repository a run is scoped to.
+type workspaceScope struct {
+	// project is the sub-project owning Request.WorkDir (or ProjectRoot).
+	project *workspace.Project
+	// workDir is the default working directory for tools; empty keeps the
+	// tool root.
+	workDir string
+}
+
+// scopeWorkspace resolves Request.WorkDir to a sub-project. Directories
+// outside ProjectRoot are ignored since tools could not use them.
+func (rt *Runtime) scopeWorkspace(workDir string) workspaceScope {
+	root := rt.opts.ProjectRoot
+	path := strings.TrimSpace(workDir)
+	if path == "" {
+		path = root
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+	if !pathWithin(path, root) {
+		log.Printf("api: work dir %s is outside the project root, ignoring", path)
+		return workspaceScope{}
+	}
+	var scope workspaceScope
+	if rt.workspace != nil {
+		if p, ok := rt.workspace.ProjectFor(path); ok && pathWithin(p.Path, root) {
+			scope.project = &p
+			scope.workDir = p.Path
+		}
+	}
+	if scope.workDir == "" && strings.TrimSpace(workDir) != "" {
+		if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			scope.workDir = path
+		}
+	}
+	return scope
+}
+
+// systemPrompt appends the environment section and the sub-project's
+// CLAUDE.md to base.
+func (s workspaceScope) systemPrompt(rt *Runtime, base string) string {
+	var sections []string
+	if env := s.environment(rt.workspace); env != "" {
+		sections = append(sections, env)
+	}
+	if s.project != nil {
+		if memory := rt.projectMemory.load(s.project.Path, rt.opts.ProjectRoot, rt.fs); memory != "" {
+			sections = append(sections, fmt.Sprintf("## Memory (%s)\n\n%s", s.project.Rel, memory))
+		}
+	}
+	if len(sections) == 0 {
+		return base
+	}
+	if strings.TrimSpace(base) != "" {
+		sections = append([]string{strings.TrimSpace(base)}, sections...)
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// environment describes the workspace and scope for the model. It is empty
+// outside monorepos unless the request set a working directory.
+func (s workspaceScope) environment(info *workspace.Info) string {
+	if info == nil && s.workDir == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Environment\n")
+	if info != nil {
+		fmt.Fprintf(&b, "\n- Workspace: %s (%s) at %s", info.Kind, filepath.Base(info.Manifest), info.Root)
+		if len(info.Projects) > 0 {
+			fmt.Fprintf(&b, " with %d projects", len(info.Projects))
+		}
+	}
+	if s.project != nil {
+		fmt.Fprintf(&b, "\n- Sub-project: %s (%s)", s.project.Name, s.project.Rel)
+	}
+	if s.workDir != "" {
+		fmt.Fprintf(&b, "\n- Working directory: %s", s.workDir)
+	}
+	return b.String()
+}
+
+// projectMemoryCache loads each sub-project's CLAUDE.md once.
+type projectMemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]string
+}
+
+func (c *projectMemoryCache) load(dir, root string, fsLayer *config.FS) string {
+	// The root CLAUDE.md is already part of the base system prompt.
+	if filepath.Clean(dir) == filepath.Clean(root) {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if memory, ok := c.entries[dir]; ok {
+		return memory
+	}
+	memory, err := config.LoadClaudeMD(dir, fsLayer)
+	if err != nil {
+		log.Printf("claude.md loader warning (%s): %v", dir, err)
+	}
+	if c.entries == nil {
+		c.entries = map[string]string{}
+	}
+	c.entries[dir] = memory
+	return memory
+}
+
+func pathWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
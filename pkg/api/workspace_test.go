@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+type workDirProbe struct{ dir string }
+
+func (*workDirProbe) Name() string             { return "probe" }
+func (*workDirProbe) Description() string      { return "records the scoped work dir" }
+func (*workDirProbe) Schema() *tool.JSONSchema { return &tool.JSONSchema{Type: "object"} }
+func (p *workDirProbe) Execute(ctx context.Context, _ map[string]interface{}) (*tool.ToolResult, error) {
+	p.dir, _ = tool.WorkDirFromContext(ctx)
+	return &tool.ToolResult{Success: true, Output: p.dir}, nil
+}
+
+func TestRuntimeScopesRequestToSubProject(t *testing.T) {
+	root := newClaudeProject(t)
+	for name, body := range map[string]string{
+		"go.work":                         "go 1.24\n\nuse ./services/api\n",
+		"services/api/go.mod":             "module example.com/api\n",
+		"services/api/CLAUDE.md":          "Run make test-api.",
+		"services/api/handlers/handle.go": "package handlers\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	probe := &workDirProbe{}
+	mdl := &stubModel{responses: []*model.Response{
+		{Message: model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "1", Name: "probe", Arguments: map[string]any{"x": 1}}}}},
+		{Message: model.Message{Role: "assistant", Content: "done"}},
+	}}
+	rt, err := New(context.Background(), Options{ProjectRoot: root, Model: mdl, Tools: []tool.Tool{probe}})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close() })
+
+	info, ok := rt.Workspace()
+	if !ok || info.Kind != "go" || len(info.Projects) != 1 {
+		t.Fatalf("workspace = %+v ok=%v", info, ok)
+	}
+	if _, err := rt.Run(context.Background(), Request{Prompt: "fix", SessionID: "s", WorkDir: "services/api/handlers"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := filepath.Join(root, "services", "api")
+	if probe.dir != want {
+		t.Fatalf("work dir = %q, want %q", probe.dir, want)
+	}
+	system := mdl.requests[0].System
+	for _, part := range []string{"## Environment", "Sub-project: example.com/api (services/api)", "## Memory (services/api)", "Run make test-api."} {
+		if !strings.Contains(system, part) {
+			t.Fatalf("system prompt missing %q:\n%s", part, system)
+		}
+	}
+}
+
+func TestScopeWorkspaceIgnoresOutsideRoot(t *testing.T) {
+	rt := &Runtime{opts: Options{ProjectRoot: t.TempDir()}}
+	if scope := rt.scopeWorkspace(t.TempDir()); scope.workDir != "" || scope.project != nil {
+		t.Fatalf("expected empty scope, got %+v", scope)
+	}
+	if prompt := rt.scopeWorkspace("").systemPrompt(rt, "base"); prompt != "base" {
+		t.Fatalf("single-project prompt changed: %q", prompt)
+	}
+}
@@ -0,0 +1,186 @@
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FileStore keeps artifacts on disk. The layout is:
+//
+//	{dir}/{id[:2]}/{id}       payload
+//	{dir}/{id[:2]}/{id}.json  metadata
+//
+// Payloads and metadata are written by the first Put of a payload; later
+// Puts of the same content only add their session to Artifact.Sessions.
+type FileStore struct {
+	dir string
+	// mu serialises metadata updates within the process.
+	mu sync.Mutex
+}
+
+// NewFileStore returns a FileStore rooted at dir. The directory is created on
+// first write.
+func NewFileStore(dir string) (*FileStore, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, errors.New("artifact: store directory is empty")
+	}
+	return &FileStore{dir: filepath.Clean(dir)}, nil
+}
+
+func (s *FileStore) paths(id string) (blob, meta string, err error) {
+	if !ValidID(id) {
+		return "", "", fmt.Errorf("%w: invalid id %q", ErrNotFound, id)
+	}
+	base := filepath.Join(s.dir, id[:2], id)
+	return base, base + ".json", nil
+}
+
+func (s *FileStore) Put(ctx context.Context, meta Artifact, data []byte) (Artifact, error) {
+	if err := ctx.Err(); err != nil {
+		return Artifact{}, err
+	}
+	meta = fill(meta, data)
+	blobPath, metaPath, err := s.paths(meta.ID)
+	if err != nil {
+		return Artifact{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0o700); err != nil {
+		return Artifact{}, fmt.Errorf("artifact: mkdir: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := os.Stat(blobPath); errors.Is(err, os.ErrNotExist) {
+		if err := writeFileAtomic(blobPath, data); err != nil {
+			return Artifact{}, err
+		}
+	}
+	existing, err := s.Stat(ctx, meta.ID)
+	switch {
+	case err == nil:
+		meta = merge(existing, meta)
+		if slices.Equal(meta.Sessions, existing.Sessions) {
+			return meta, nil
+		}
+	case !errors.Is(err, ErrNotFound):
+		return Artifact{}, err
+	}
+	if err := s.writeMeta(metaPath, meta); err != nil {
+		return Artifact{}, err
+	}
+	return meta, nil
+}
+
+func (s *FileStore) writeMeta(path string, meta Artifact) error {
+	encoded, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("artifact: encode metadata: %w", err)
+	}
+	return writeFileAtomic(path, encoded)
+}
+
+// Release implements Releaser.
+func (s *FileStore) Release(ctx context.Context, id, sessionID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta, err := s.Stat(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	next, remaining, owned := release(meta, sessionID)
+	if !owned {
+		return false, nil
+	}
+	if remaining {
+		_, metaPath, _ := s.paths(id) //nolint:errcheck // id validated by Stat
+		return false, s.writeMeta(metaPath, next)
+	}
+	if err := s.Delete(ctx, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *FileStore) Get(ctx context.Context, id string) (Artifact, []byte, error) {
+	meta, err := s.Stat(ctx, id)
+	if err != nil {
+		return Artifact{}, nil, err
+	}
+	blobPath, _, _ := s.paths(id) //nolint:errcheck // id validated by Stat
+	data, err := os.ReadFile(blobPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Artifact{}, nil, ErrNotFound
+		}
+		return Artifact{}, nil, fmt.Errorf("artifact: read: %w", err)
+	}
+	return meta, data, nil
+}
+
+func (s *FileStore) Stat(ctx context.Context, id string) (Artifact, error) {
+	if err := ctx.Err(); err != nil {
+		return Artifact{}, err
+	}
+	_, metaPath, err := s.paths(id)
+	if err != nil {
+		return Artifact{}, err
+	}
+	raw, err := os.ReadFile(metaPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Artifact{}, ErrNotFound
+		}
+		return Artifact{}, fmt.Errorf("artifact: read metadata: %w", err)
+	}
+	var meta Artifact
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return Artifact{}, fmt.Errorf("artifact: decode metadata: %w", err)
+	}
+	return meta, nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	blobPath, metaPath, err := s.paths(id)
+	if err != nil {
+		return nil
+	}
+	var errs []error
+	for _, path := range []string{metaPath, blobPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".artifact-*")
+	if err != nil {
+		return fmt.Errorf("artifact: create temp: %w", err)
+	}
+	name := tmp.Name()
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		_ = os.Remove(name)
+		return fmt.Errorf("artifact: write: %w", err)
+	}
+	if err := os.Rename(name, path); err != nil {
+		_ = os.Remove(name)
+		return fmt.Errorf("artifact: rename: %w", err)
+	}
+	return nil
+}
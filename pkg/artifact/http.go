@@ -0,0 +1,94 @@
+package artifact
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handler serves artifacts from store. Mount it with http.StripPrefix so the
+// remaining path is the artifact ID, e.g.
+//
+//	mux.Handle("/v1/artifacts/", http.StripPrefix("/v1/artifacts/", artifact.Handler(store)))
+//
+// Appending "/meta" to the ID returns the metadata as JSON instead of the
+// payload. Because IDs are content digests, responses carry a strong ETag.
+// Payloads are served with nosniff and a sandboxing CSP, and only raster
+// images and PDFs are shown inline; everything else is a download, so a
+// stored HTML or SVG artifact cannot run scripts on the serving origin.
+func Handler(store Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "only GET supported", http.StatusMethodNotAllowed)
+			return
+		}
+		id, wantMeta := strings.CutSuffix(strings.Trim(r.URL.Path, "/"), "/meta")
+		if wantMeta {
+			meta, err := store.Stat(r.Context(), id)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(meta)
+			return
+		}
+		meta, data, err := store.Get(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		etag := `"` + meta.ID + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		mediaType := meta.MediaType
+		if mediaType == "" {
+			mediaType = http.DetectContentType(data)
+		}
+		w.Header().Set("Content-Type", mediaType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, immutable")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		disposition := "attachment"
+		if inlineMediaType(mediaType) {
+			disposition = "inline"
+		}
+		if meta.Name != "" {
+			disposition += "; filename=" + strconv.Quote(meta.Name)
+		}
+		w.Header().Set("Content-Disposition", disposition)
+		if r.Method == http.MethodHead {
+			return
+		}
+		_, _ = w.Write(data)
+	})
+}
+
+// inlineMediaType reports whether mediaType is a raster image or PDF, the
+// only payloads a browser may display in place.
+func inlineMediaType(mediaType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp", "image/bmp", "image/avif", "application/pdf":
+		return true
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,140 @@
+package artifact
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ProvenanceMarker starts every provenance stamp.
+const ProvenanceMarker = "agentsdk-provenance:"
+
+// Provenance attributes generated content to the run that produced it.
+// Digest is the SHA-256 of the content without its stamp, so a stamped file
+// can be checked with VerifyStamp after the fact.
+type Provenance struct {
+	Model     string    `json:"model,omitempty"`
+	RunID     string    `json:"run_id"`
+	SessionID string    `json:"session_id,omitempty"`
+	Tool      string    `json:"tool,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Digest    string    `json:"digest"`
+}
+
+// commentStyles maps file extensions to the line comment that carries the
+// stamp. Formats without comments (JSON, plain text, binaries) are not
+// stamped.
+var commentStyles = buildCommentStyles()
+
+func buildCommentStyles() map[string][2]string {
+	commentStyles := map[string][2]string{}
+	for _, ext := range strings.Fields(".go .js .jsx .mjs .cjs .ts .tsx .c .h .cc .cpp .hpp .java .kt .kts .swift .rs .scala .cs .dart .php .proto .groovy .gradle") {
+		commentStyles[ext] = [2]string{"// ", ""}
+	}
+	for _, ext := range strings.Fields(".py .sh .bash .zsh .rb .pl .r .yaml .yml .toml .tf .ps1 .conf dockerfile makefile") {
+		commentStyles[ext] = [2]string{"# ", ""}
+	}
+	for _, ext := range strings.Fields(".sql .lua .hs") {
+		commentStyles[ext] = [2]string{"-- ", ""}
+	}
+	for _, ext := range strings.Fields(".md .html .htm .xml .svg .vue") {
+		commentStyles[ext] = [2]string{"<!-- ", " -->"}
+	}
+	for _, ext := range strings.Fields(".css .scss .less") {
+		commentStyles[ext] = [2]string{"/* ", " */"}
+	}
+	return commentStyles
+}
+
+func commentStyle(name string) ([2]string, bool) {
+	key := strings.ToLower(filepath.Ext(name))
+	if key == "" {
+		key = strings.ToLower(filepath.Base(name))
+	}
+	style, ok := commentStyles[key]
+	return style, ok
+}
+
+// Stamp appends p as a trailing comment in the comment syntax of name's
+// file type, replacing any earlier stamp. p.Digest is set from the
+// unstamped content. It reports false, returning content unchanged, when
+// the file type has no comment syntax.
+func Stamp(name string, content []byte, p Provenance) ([]byte, Provenance, bool) {
+	style, ok := commentStyle(name)
+	if !ok {
+		p.Digest = Digest(content)
+		return content, p, false
+	}
+	_, body, _ := ParseStamp(content)
+	var buf bytes.Buffer
+	buf.Grow(len(body) + 256)
+	buf.Write(body)
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	p.Digest = Digest(buf.Bytes())
+	fmt.Fprintf(&buf, "%s%s %s%s\n", style[0], ProvenanceMarker, formatProvenance(p), style[1])
+	return buf.Bytes(), p, true
+}
+
+// ParseStamp finds the stamp on the last line of content and returns it
+// with the content that precedes it.
+func ParseStamp(content []byte) (Provenance, []byte, bool) {
+	trimmed := bytes.TrimRight(content, "\n")
+	start := bytes.LastIndexByte(trimmed, '\n') + 1
+	line := string(trimmed[start:])
+	idx := strings.Index(line, ProvenanceMarker)
+	if idx < 0 {
+		return Provenance{}, content, false
+	}
+	fields := line[idx+len(ProvenanceMarker):]
+	for _, suffix := range []string{"-->", "*/"} {
+		fields = strings.TrimSuffix(strings.TrimSpace(fields), suffix)
+	}
+	var p Provenance
+	for _, field := range strings.Fields(fields) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "model":
+			p.Model = value
+		case "run":
+			p.RunID = value
+		case "session":
+			p.SessionID = value
+		case "tool":
+			p.Tool = value
+		case "at":
+			p.CreatedAt, _ = time.Parse(time.RFC3339, value)
+		case "sha256":
+			p.Digest = value
+		}
+	}
+	return p, content[:start], true
+}
+
+// VerifyStamp reports whether content carries a stamp whose digest matches
+// the content it follows.
+func VerifyStamp(content []byte) (Provenance, bool) {
+	p, body, ok := ParseStamp(content)
+	return p, ok && p.Digest == Digest(body)
+}
+
+func formatProvenance(p Provenance) string {
+	parts := make([]string, 0, 6)
+	add := func(key, value string) {
+		if value = strings.Join(strings.Fields(value), "_"); value != "" {
+			parts = append(parts, key+"="+value)
+		}
+	}
+	add("model", p.Model)
+	add("run", p.RunID)
+	add("session", p.SessionID)
+	add("tool", p.Tool)
+	if !p.CreatedAt.IsZero() {
+		add("at", p.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	add("sha256", p.Digest)
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,80 @@
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStampRoundTrip(t *testing.T) {
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	p := Provenance{Model: "claude sonnet", RunID: "run-1", SessionID: "s", Tool: "Write", CreatedAt: at}
+
+	stamped, got, ok := Stamp("main.go", []byte("package main"), p)
+	if !ok {
+		t.Fatalf("go files should be stamped")
+	}
+	want := "package main\n// agentsdk-provenance: model=claude_sonnet run=run-1 session=s tool=Write at=2025-03-01T12:00:00Z sha256=" + got.Digest + "\n"
+	if string(stamped) != want {
+		t.Fatalf("stamped = %q\nwant %q", stamped, want)
+	}
+	parsed, ok := VerifyStamp(stamped)
+	if !ok || parsed.RunID != "run-1" || !parsed.CreatedAt.Equal(at) || parsed.Digest != Digest([]byte("package main\n")) {
+		t.Fatalf("verify = %+v ok=%v", parsed, ok)
+	}
+
+	// Restamping after an edit replaces the trailer instead of stacking.
+	edited := bytes.Replace(stamped, []byte("package main"), []byte("package other"), 1)
+	if _, ok := VerifyStamp(edited); ok {
+		t.Fatalf("edited content should fail verification")
+	}
+	p.RunID = "run-2"
+	restamped, _, _ := Stamp("main.go", edited, p)
+	if strings.Count(string(restamped), ProvenanceMarker) != 1 {
+		t.Fatalf("restamped = %q", restamped)
+	}
+	if parsed, ok := VerifyStamp(restamped); !ok || parsed.RunID != "run-2" {
+		t.Fatalf("restamped verify = %+v ok=%v", parsed, ok)
+	}
+}
+
+func TestStampCommentStyles(t *testing.T) {
+	p := Provenance{RunID: "r"}
+	for name, prefix := range map[string]string{
+		"README.md":  "<!-- agentsdk-provenance: run=r",
+		"style.css":  "/* agentsdk-provenance: run=r",
+		"q.sql":      "-- agentsdk-provenance: run=r",
+		"Dockerfile": "# agentsdk-provenance: run=r",
+	} {
+		stamped, _, ok := Stamp(name, []byte("x\n"), p)
+		if !ok || !strings.HasPrefix(strings.Split(string(stamped), "\n")[1], prefix) {
+			t.Fatalf("%s stamped = %q", name, stamped)
+		}
+		if _, ok := VerifyStamp(stamped); !ok {
+			t.Fatalf("%s does not verify", name)
+		}
+	}
+
+	data := []byte(`{"a":1}`)
+	out, got, ok := Stamp("data.json", data, p)
+	if ok || !bytes.Equal(out, data) || got.Digest != Digest(data) {
+		t.Fatalf("json should be left alone with its digest, got %q %+v", out, got)
+	}
+}
+
+func TestStoreKeepsProvenance(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	meta, err := store.Put(context.Background(), Artifact{Name: "a.go", Provenance: &Provenance{RunID: "r", Digest: "d"}}, []byte("x"))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	got, err := store.Stat(context.Background(), meta.ID)
+	if err != nil || got.Provenance == nil || got.Provenance.RunID != "r" {
+		t.Fatalf("stat = %+v err=%v", got, err)
+	}
+}
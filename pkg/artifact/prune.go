@@ -0,0 +1,90 @@
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Lister is implemented by stores that can enumerate their artifacts.
+type Lister interface {
+	List(ctx context.Context) ([]Artifact, error)
+}
+
+// Prune deletes artifacts created before cutoff and returns how many were
+// removed. The store must implement Lister.
+func Prune(ctx context.Context, store Store, cutoff time.Time) (int, error) {
+	lister, ok := store.(Lister)
+	if !ok {
+		return 0, errors.New("artifact: store does not support listing")
+	}
+	all, err := lister.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	var errs []error
+	for _, meta := range all {
+		if !meta.CreatedAt.Before(cutoff) {
+			continue
+		}
+		if err := store.Delete(ctx, meta.ID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
+
+// List returns the metadata of every stored artifact.
+func (s *MemoryStore) List(ctx context.Context) ([]Artifact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Artifact, 0, len(s.meta))
+	for _, meta := range s.meta {
+		out = append(out, meta)
+	}
+	return out, nil
+}
+
+// List walks the store directory and returns every artifact's metadata.
+func (s *FileStore) List(ctx context.Context) ([]Artifact, error) {
+	var out []Artifact
+	err := filepath.WalkDir(s.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") || !ValidID(strings.TrimSuffix(d.Name(), ".json")) {
+			return nil
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("artifact: read metadata: %w", err)
+		}
+		var meta Artifact
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return fmt.Errorf("artifact: decode metadata %s: %w", d.Name(), err)
+		}
+		out = append(out, meta)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
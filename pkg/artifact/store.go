@@ -0,0 +1,215 @@
+// Package artifact stores files produced by tools (reports, patches, images)
+// outside of tool output. Artifacts are content-addressed: the ID is the
+// SHA-256 digest of the payload, so identical blobs are stored once.
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when no artifact exists for an ID.
+var ErrNotFound = errors.New("artifact: not found")
+
+// Artifact describes a stored blob.
+type Artifact struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	SizeBytes int64  `json:"size_bytes"`
+	SessionID string `json:"session_id,omitempty"`
+	// Sessions lists every session that stored the payload, starting with
+	// SessionID. Storing identical content again keeps the first writer's
+	// metadata and only adds the session here.
+	Sessions  []string  `json:"sessions,omitempty"`
+	Tool      string    `json:"tool,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Provenance attributes generated files and responses to their run.
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// Owners returns the sessions that stored the artifact.
+func (a Artifact) Owners() []string {
+	if len(a.Sessions) == 0 && a.SessionID != "" {
+		return []string{a.SessionID}
+	}
+	return a.Sessions
+}
+
+// OwnedBy reports whether sessionID may use the artifact: it was stored
+// without a session or sessionID is one of its owners.
+func (a Artifact) OwnedBy(sessionID string) bool {
+	owners := a.Owners()
+	return len(owners) == 0 || slices.Contains(owners, sessionID)
+}
+
+// Store persists artifacts. Implementations must be safe for concurrent use.
+type Store interface {
+	// Put stores data and returns meta with ID, SizeBytes and CreatedAt set.
+	// Storing identical content again returns the same ID.
+	Put(ctx context.Context, meta Artifact, data []byte) (Artifact, error)
+	// Get returns the metadata and payload for id.
+	Get(ctx context.Context, id string) (Artifact, []byte, error)
+	// Stat returns the metadata for id without reading the payload.
+	Stat(ctx context.Context, id string) (Artifact, error)
+	// Delete removes the artifact. Deleting a missing artifact is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// Releaser is implemented by stores that can drop one owner of an
+// artifact, for purging a session's data without touching other sessions.
+type Releaser interface {
+	// Release removes sessionID from the owners of id and deletes the
+	// artifact once no owner is left, reporting whether it was deleted.
+	// Artifacts sessionID does not own are left alone.
+	Release(ctx context.Context, id, sessionID string) (bool, error)
+}
+
+// Digest returns the content address used as artifact ID.
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidID reports whether id looks like a Digest value. Stores use it to
+// reject IDs that could escape their storage directory.
+func ValidID(id string) bool {
+	if len(id) != sha256.Size*2 {
+		return false
+	}
+	for _, r := range id {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func fill(meta Artifact, data []byte) Artifact {
+	meta.ID = Digest(data)
+	meta.SizeBytes = int64(len(data))
+	if meta.CreatedAt.IsZero() {
+		meta.CreatedAt = time.Now().UTC()
+	}
+	meta.Sessions = nil
+	if meta.SessionID != "" {
+		meta.Sessions = []string{meta.SessionID}
+	}
+	return meta
+}
+
+// merge returns the metadata kept when meta is stored over existing: the
+// first writer's, with meta's session added to the owners.
+func merge(existing, meta Artifact) Artifact {
+	owners := slices.Clone(existing.Owners())
+	if meta.SessionID != "" && !slices.Contains(owners, meta.SessionID) {
+		owners = append(owners, meta.SessionID)
+	}
+	existing.Sessions = owners
+	return existing
+}
+
+// release returns meta without sessionID among its owners and whether any
+// owner is left. ok is false when sessionID does not own meta.
+func release(meta Artifact, sessionID string) (next Artifact, remaining, ok bool) {
+	owners := meta.Owners()
+	if !slices.Contains(owners, sessionID) {
+		return meta, true, false
+	}
+	rest := slices.DeleteFunc(slices.Clone(owners), func(s string) bool { return s == sessionID })
+	if len(rest) == 0 {
+		return meta, false, true
+	}
+	meta.Sessions = rest
+	if meta.SessionID == sessionID {
+		meta.SessionID = rest[0]
+	}
+	return meta, true, true
+}
+
+// MemoryStore keeps artifacts in process memory.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	meta  map[string]Artifact
+	blobs map[string][]byte
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{meta: map[string]Artifact{}, blobs: map[string][]byte{}}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, meta Artifact, data []byte) (Artifact, error) {
+	if err := ctx.Err(); err != nil {
+		return Artifact{}, err
+	}
+	meta = fill(meta, data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.meta[meta.ID]; ok {
+		meta = merge(existing, meta)
+	} else {
+		s.blobs[meta.ID] = append([]byte(nil), data...)
+	}
+	s.meta[meta.ID] = meta
+	return meta, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (Artifact, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return Artifact{}, nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	meta, ok := s.meta[id]
+	if !ok {
+		return Artifact{}, nil, ErrNotFound
+	}
+	return meta, append([]byte(nil), s.blobs[id]...), nil
+}
+
+func (s *MemoryStore) Stat(ctx context.Context, id string) (Artifact, error) {
+	meta, _, err := s.Get(ctx, id)
+	return meta, err
+}
+
+// Release implements Releaser.
+func (s *MemoryStore) Release(ctx context.Context, id, sessionID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta, ok := s.meta[id]
+	if !ok {
+		return false, nil
+	}
+	next, remaining, owned := release(meta, sessionID)
+	if !owned {
+		return false, nil
+	}
+	if remaining {
+		s.meta[id] = next
+		return false, nil
+	}
+	delete(s.meta, id)
+	delete(s.blobs, id)
+	return true, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.meta, id)
+	delete(s.blobs, id)
+	return nil
+}
@@ -0,0 +1,171 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestStoresAreContentAddressed(t *testing.T) {
+	fileStore, err := NewFileStore(filepath.Join(t.TempDir(), "artifacts"))
+	if err != nil {
+		t.Fatalf("file store: %v", err)
+	}
+	for name, store := range map[string]Store{"memory": NewMemoryStore(), "file": fileStore} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			first, err := store.Put(ctx, Artifact{Name: "a.patch", MediaType: "text/x-diff", Tool: "edit"}, []byte("diff"))
+			if err != nil {
+				t.Fatalf("put: %v", err)
+			}
+			if first.ID != Digest([]byte("diff")) || first.SizeBytes != 4 || first.CreatedAt.IsZero() {
+				t.Fatalf("unexpected metadata %+v", first)
+			}
+			second, err := store.Put(ctx, Artifact{Name: "b.patch"}, []byte("diff"))
+			if err != nil || second.ID != first.ID {
+				t.Fatalf("expected same id, got %+v err=%v", second, err)
+			}
+
+			meta, data, err := store.Get(ctx, first.ID)
+			// The first writer's metadata is kept.
+			if err != nil || string(data) != "diff" || meta.Name != "a.patch" {
+				t.Fatalf("get mismatch %+v %q err=%v", meta, data, err)
+			}
+			if err := store.Delete(ctx, first.ID); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			if _, err := store.Stat(ctx, first.ID); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStoresTrackSessionOwnership(t *testing.T) {
+	fileStore, err := NewFileStore(filepath.Join(t.TempDir(), "artifacts"))
+	if err != nil {
+		t.Fatalf("file store: %v", err)
+	}
+	for name, store := range map[string]interface {
+		Store
+		Releaser
+	}{"memory": NewMemoryStore(), "file": fileStore} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.Put(ctx, Artifact{Name: "a.txt", SessionID: "s1"}, []byte("same")); err != nil {
+				t.Fatalf("put: %v", err)
+			}
+			second, err := store.Put(ctx, Artifact{Name: "b.txt", SessionID: "s2"}, []byte("same"))
+			if err != nil {
+				t.Fatalf("put: %v", err)
+			}
+			if second.SessionID != "s1" || second.Name != "a.txt" || !slices.Equal(second.Sessions, []string{"s1", "s2"}) {
+				t.Fatalf("second put took over ownership: %+v", second)
+			}
+			if !second.OwnedBy("s1") || !second.OwnedBy("s2") || second.OwnedBy("s3") {
+				t.Fatalf("unexpected owners %v", second.Owners())
+			}
+
+			if deleted, err := store.Release(ctx, second.ID, "s3"); err != nil || deleted {
+				t.Fatalf("release by non-owner deleted=%v err=%v", deleted, err)
+			}
+			if deleted, err := store.Release(ctx, second.ID, "s1"); err != nil || deleted {
+				t.Fatalf("release s1 deleted=%v err=%v", deleted, err)
+			}
+			meta, err := store.Stat(ctx, second.ID)
+			if err != nil || meta.SessionID != "s2" || !slices.Equal(meta.Sessions, []string{"s2"}) {
+				t.Fatalf("after release %+v err=%v", meta, err)
+			}
+			if deleted, err := store.Release(ctx, second.ID, "s2"); err != nil || !deleted {
+				t.Fatalf("release last owner deleted=%v err=%v", deleted, err)
+			}
+			if _, err := store.Stat(ctx, second.ID); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFileStoreRejectsInvalidIDs(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewFileStore(filepath.Join(root, "artifacts"))
+	if err != nil {
+		t.Fatalf("file store: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "secret"), []byte("x"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, _, err := store.Get(context.Background(), "../secret"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := NewFileStore(" "); err == nil {
+		t.Fatal("expected empty dir error")
+	}
+}
+
+func TestHandlerServesArtifacts(t *testing.T) {
+	store := NewMemoryStore()
+	meta, err := store.Put(context.Background(), Artifact{Name: "report.txt", MediaType: "text/plain"}, []byte("hello"))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	srv := httptest.NewServer(http.StripPrefix("/v1/artifacts/", Handler(store)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/artifacts/" + meta.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/plain" || resp.Header.Get("ETag") != `"`+meta.ID+`"` {
+		t.Fatalf("unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp.Header.Get("X-Content-Type-Options") != "nosniff" || resp.Header.Get("Content-Security-Policy") != "sandbox" || resp.Header.Get("Content-Disposition") != `attachment; filename="report.txt"` {
+		t.Fatalf("text should download with nosniff and a sandbox CSP, got %v", resp.Header)
+	}
+
+	for _, tc := range []struct{ mediaType, disposition string }{
+		{"image/png", "inline"},
+		{"application/pdf", "inline"},
+		{"text/html", "attachment"},
+		{"image/svg+xml", "attachment"},
+	} {
+		stored, err := store.Put(context.Background(), Artifact{MediaType: tc.mediaType}, []byte(tc.mediaType))
+		if err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		resp, err := http.Get(srv.URL + "/v1/artifacts/" + stored.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Get("Content-Disposition"); got != tc.disposition {
+			t.Fatalf("%s: Content-Disposition %q, want %q", tc.mediaType, got, tc.disposition)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/artifacts/"+meta.ID, nil)
+	req.Header.Set("If-None-Match", `"`+meta.ID+`"`)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("conditional get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/v1/artifacts/" + Digest([]byte("missing")))
+	if err != nil {
+		t.Fatalf("get missing: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
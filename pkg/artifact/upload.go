@@ -0,0 +1,249 @@
+package artifact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// DefaultMaxUploadBytes is the per-file limit used when UploadLimits leaves
+// MaxFileBytes unset.
+const DefaultMaxUploadBytes int64 = 20 << 20
+
+// DefaultMaxUploadRequestBytes is the limit on all files of one request used
+// when UploadLimits leaves MaxRequestBytes unset.
+const DefaultMaxUploadRequestBytes int64 = 64 << 20
+
+// maxUploadFiles bounds the number of files accepted per request.
+const maxUploadFiles = 32
+
+// ErrUploadRejected wraps size and media type violations.
+var ErrUploadRejected = errors.New("artifact: upload rejected")
+
+// UploadLimits constrains files accepted by UploadHandler.
+type UploadLimits struct {
+	// MaxFileBytes caps each file; zero uses DefaultMaxUploadBytes.
+	MaxFileBytes int64
+	// MaxRequestBytes caps the files of one request together, which bounds
+	// the memory a request holds; zero uses DefaultMaxUploadRequestBytes.
+	// It is raised to MaxFileBytes when lower.
+	MaxRequestBytes int64
+	// AllowedMimeTypes lists accepted media types; "image/*" style wildcards
+	// match any subtype. Empty accepts everything except active content.
+	AllowedMimeTypes []string
+	// AllowActiveContent accepts HTML, XHTML and SVG, which browsers run
+	// scripts from. They are rejected by default.
+	AllowActiveContent bool
+}
+
+func (l UploadLimits) maxBytes() int64 {
+	if l.MaxFileBytes > 0 {
+		return l.MaxFileBytes
+	}
+	return DefaultMaxUploadBytes
+}
+
+func (l UploadLimits) maxRequestBytes() int64 {
+	limit := l.MaxRequestBytes
+	if limit <= 0 {
+		limit = DefaultMaxUploadRequestBytes
+	}
+	return max(limit, l.maxBytes())
+}
+
+// Allows reports whether mediaType passes AllowedMimeTypes.
+func (l UploadLimits) Allows(mediaType string) bool {
+	if len(l.AllowedMimeTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range l.AllowedMimeTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadResponse is the JSON body returned by UploadHandler.
+type UploadResponse struct {
+	Attachments []Artifact `json:"attachments"`
+}
+
+// UploadHandler accepts multipart/form-data POSTs and stores every "file"
+// part in store. The response lists the stored artifacts; their IDs can be
+// passed as attachment IDs in later run requests. An optional "session_id"
+// form field is recorded on each artifact.
+//
+// Files over the size limits answer 413. Disallowed media types, HTML, XHTML
+// and SVG unless AllowActiveContent is set, and content that does not match
+// its declared type answer 415. Nothing from a rejected
+// request is stored.
+func UploadHandler(store Store, limits UploadLimits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST supported", http.StatusMethodNotAllowed)
+			return
+		}
+		// Bound the whole body: the request limit plus some room for
+		// multipart framing and form fields.
+		r.Body = http.MaxBytesReader(w, r.Body, limits.maxRequestBytes()+1<<20)
+		files, sessionID, err := readUploads(r, limits)
+		if err != nil {
+			status := http.StatusBadRequest
+			var maxErr *http.MaxBytesError
+			switch {
+			case errors.As(err, &maxErr), errors.Is(err, errTooLarge):
+				status = http.StatusRequestEntityTooLarge
+			case errors.Is(err, errMediaType):
+				status = http.StatusUnsupportedMediaType
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		resp := UploadResponse{Attachments: make([]Artifact, 0, len(files))}
+		for _, f := range files {
+			meta, err := store.Put(r.Context(), Artifact{Name: f.name, MediaType: f.mediaType, SessionID: sessionID, Tool: "upload"}, f.data)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			resp.Attachments = append(resp.Attachments, meta)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+var (
+	errTooLarge  = fmt.Errorf("%w: file too large", ErrUploadRejected)
+	errMediaType = fmt.Errorf("%w: media type not allowed", ErrUploadRejected)
+)
+
+type uploadedFile struct {
+	name      string
+	mediaType string
+	data      []byte
+}
+
+func readUploads(r *http.Request, limits UploadLimits) ([]uploadedFile, string, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: expected multipart/form-data", ErrUploadRejected)
+	}
+	var (
+		files     []uploadedFile
+		sessionID string
+		total     int64
+	)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		switch part.FormName() {
+		case "session_id":
+			raw, err := io.ReadAll(io.LimitReader(part, 256))
+			if err != nil {
+				return nil, "", err
+			}
+			sessionID = strings.TrimSpace(string(raw))
+		case "file":
+			if len(files) == maxUploadFiles {
+				return nil, "", fmt.Errorf("%w: at most %d files per request", ErrUploadRejected, maxUploadFiles)
+			}
+			data, err := io.ReadAll(io.LimitReader(part, limits.maxBytes()+1))
+			if err != nil {
+				return nil, "", err
+			}
+			name := path.Base(strings.ReplaceAll(part.FileName(), `\`, "/"))
+			if int64(len(data)) > limits.maxBytes() {
+				return nil, "", fmt.Errorf("%w: %s exceeds %d bytes", errTooLarge, name, limits.maxBytes())
+			}
+			if total += int64(len(data)); total > limits.maxRequestBytes() {
+				return nil, "", fmt.Errorf("%w: files exceed %d bytes per request", errTooLarge, limits.maxRequestBytes())
+			}
+			mediaType, ok := uploadMediaType(part.Header.Get("Content-Type"), data)
+			if !ok {
+				return nil, "", fmt.Errorf("%w: %s content does not match %s", errMediaType, name, mediaType)
+			}
+			if activeMediaType(mediaType) && !limits.AllowActiveContent {
+				return nil, "", fmt.Errorf("%w: %s (%s) can run scripts in a browser", errMediaType, name, mediaType)
+			}
+			if !limits.Allows(mediaType) {
+				return nil, "", fmt.Errorf("%w: %s (%s)", errMediaType, name, mediaType)
+			}
+			files = append(files, uploadedFile{name: name, mediaType: mediaType, data: data})
+		}
+		part.Close()
+	}
+	if len(files) == 0 {
+		return nil, "", fmt.Errorf("%w: no file parts", ErrUploadRejected)
+	}
+	return files, sessionID, nil
+}
+
+// uploadMediaType returns the media type of an upload and whether its
+// content agrees with it. Missing or generic declared types are replaced by
+// the sniffed one. Otherwise a specific sniffed type (PNG, PDF, HTML ...)
+// must equal the declared type, and a generic one (text, XML, zip or unknown
+// binary) must be of the same kind, so a client cannot pass content of one
+// type under an allowed other type.
+func uploadMediaType(declared string, data []byte) (string, bool) {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	mediaType, _, err := mime.ParseMediaType(declared)
+	if err != nil || mediaType == "application/octet-stream" {
+		return sniffed, true
+	}
+	switch sniffed {
+	case mediaType:
+		return mediaType, true
+	case "text/plain", "text/xml":
+		return mediaType, textualMediaType(mediaType) && mediaType != "text/html"
+	case "application/zip":
+		// Office documents, EPUB and JARs are zip containers.
+		return mediaType, strings.HasPrefix(mediaType, "application/") && !textualMediaType(mediaType)
+	case "application/octet-stream":
+		return mediaType, !textualMediaType(mediaType)
+	}
+	return mediaType, false
+}
+
+// activeMediaType reports whether browsers execute scripts embedded in
+// content of mediaType.
+func activeMediaType(mediaType string) bool {
+	switch mediaType {
+	case "text/html", "application/xhtml+xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// textualMediaType reports whether mediaType is a text format.
+func textualMediaType(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/yaml",
+		"application/x-yaml", "application/x-ndjson", "application/sql", "application/x-sh":
+		return true
+	}
+	return false
+}
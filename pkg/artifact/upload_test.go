@@ -0,0 +1,156 @@
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type uploadPart struct {
+	name, mediaType string
+	data            []byte
+}
+
+func postUpload(t *testing.T, h http.Handler, sessionID string, files ...uploadPart) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if sessionID != "" {
+		_ = mw.WriteField("session_id", sessionID)
+	}
+	for _, f := range files {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="file"; filename="`+f.name+`"`)
+		if f.mediaType != "" {
+			header.Set("Content-Type", f.mediaType)
+		}
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			t.Fatalf("create part: %v", err)
+		}
+		_, _ = part.Write(f.data)
+	}
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/v1/uploads", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUploadHandlerStoresFilesWithinLimits(t *testing.T) {
+	store := NewMemoryStore()
+	h := UploadHandler(store, UploadLimits{MaxFileBytes: 16, AllowedMimeTypes: []string{"image/*", "text/plain"}})
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+
+	rec := postUpload(t, h, "s1",
+		uploadPart{name: `C:\tmp\shot.png`, data: png},
+		uploadPart{name: "notes.txt", mediaType: "text/plain", data: []byte("hello")},
+	)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp UploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Attachments) != 2 {
+		t.Fatalf("response %s err=%v", rec.Body, err)
+	}
+	shot := resp.Attachments[0]
+	if shot.ID != Digest(png) || shot.Name != "shot.png" || shot.MediaType != "image/png" || shot.SessionID != "s1" || shot.Tool != "upload" {
+		t.Fatalf("unexpected artifact %+v", shot)
+	}
+	if _, _, err := store.Get(context.Background(), resp.Attachments[1].ID); err != nil {
+		t.Fatalf("stored text: %v", err)
+	}
+
+	if rec := postUpload(t, h, "", uploadPart{name: "big.txt", mediaType: "text/plain", data: bytes.Repeat([]byte("x"), 17)}); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized upload status %d", rec.Code)
+	}
+	if rec := postUpload(t, h, "", uploadPart{name: "a.pdf", mediaType: "application/pdf", data: []byte("%PDF")}); rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("disallowed type status %d", rec.Code)
+	}
+	if rec := postUpload(t, h, ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("empty upload status %d", rec.Code)
+	}
+}
+
+func TestUploadHandlerChecksContentAndRequestSize(t *testing.T) {
+	store := NewMemoryStore()
+	h := UploadHandler(store, UploadLimits{MaxFileBytes: 64, MaxRequestBytes: 80, AllowedMimeTypes: []string{"image/png", "text/*", "application/json"}})
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+
+	if rec := postUpload(t, h, "", uploadPart{name: "x.png", mediaType: "image/png", data: []byte("<html><script>alert(1)</script>")}); rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("html declared as png: status %d", rec.Code)
+	}
+	if rec := postUpload(t, h, "", uploadPart{name: "notes.txt", mediaType: "text/plain", data: png}); rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("png declared as text: status %d", rec.Code)
+	}
+	rec := postUpload(t, h, "",
+		uploadPart{name: "data.json", mediaType: "application/json", data: []byte(`{"a":1}`)},
+		uploadPart{name: "shot.png", mediaType: "image/png", data: png},
+	)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("matching types: status %d: %s", rec.Code, rec.Body)
+	}
+
+	half := bytes.Repeat([]byte("x"), 48)
+	if rec := postUpload(t, h, "", uploadPart{name: "a.txt", mediaType: "text/plain", data: half}, uploadPart{name: "b.txt", mediaType: "text/plain", data: half}); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("request over total limit: status %d", rec.Code)
+	}
+}
+
+func TestUploadHandlerRejectsActiveContent(t *testing.T) {
+	html := []byte("<html><script>alert(1)</script></html>")
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)
+	store := NewMemoryStore()
+	h := UploadHandler(store, UploadLimits{})
+	for _, f := range []uploadPart{
+		{name: "page", data: html},
+		{name: "page.html", mediaType: "text/html", data: html},
+		{name: "page.xhtml", mediaType: "application/xhtml+xml", data: svg},
+		{name: "logo.svg", mediaType: "image/svg+xml", data: svg},
+	} {
+		if rec := postUpload(t, h, "", f); rec.Code != http.StatusUnsupportedMediaType {
+			t.Fatalf("%s: status %d: %s", f.name, rec.Code, rec.Body)
+		}
+	}
+
+	allowed := UploadHandler(store, UploadLimits{AllowActiveContent: true})
+	if rec := postUpload(t, allowed, "", uploadPart{name: "page", data: html}); rec.Code != http.StatusCreated {
+		t.Fatalf("allowed active content: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestPruneRemovesOldArtifacts(t *testing.T) {
+	fileStore, err := NewFileStore(filepath.Join(t.TempDir(), "artifacts"))
+	if err != nil {
+		t.Fatalf("file store: %v", err)
+	}
+	for name, store := range map[string]Store{"memory": NewMemoryStore(), "file": fileStore} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			old, _ := store.Put(ctx, Artifact{Name: "old", CreatedAt: time.Now().Add(-48 * time.Hour)}, []byte("old"))
+			fresh, _ := store.Put(ctx, Artifact{Name: "fresh"}, []byte("fresh"))
+			removed, err := Prune(ctx, store, time.Now().Add(-24*time.Hour))
+			if err != nil || removed != 1 {
+				t.Fatalf("prune = %d, %v", removed, err)
+			}
+			if _, err := store.Stat(ctx, old.ID); err == nil {
+				t.Fatal("old artifact should be gone")
+			}
+			if _, err := store.Stat(ctx, fresh.ID); err != nil {
+				t.Fatalf("fresh artifact: %v", err)
+			}
+		})
+	}
+	empty, _ := NewFileStore(filepath.Join(t.TempDir(), "missing"))
+	if n, err := Prune(context.Background(), empty, time.Now()); err != nil || n != 0 {
+		t.Fatalf("prune missing dir = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CompliancePolicy is an organisation baseline the effective settings must
+// satisfy. Zero fields impose nothing.
+type CompliancePolicy struct {
+	RequireSandbox          bool     `json:"requireSandbox,omitempty"`          // sandbox.enabled must be true.
+	ForbidUnsandboxed       bool     `json:"forbidUnsandboxed,omitempty"`       // sandbox.allowUnsandboxedCommands must be false.
+	DeniedTools             []string `json:"deniedTools,omitempty"`             // Tools that must be unavailable: listed in disallowedTools or denied outright.
+	RequiredDenyRules       []string `json:"requiredDenyRules,omitempty"`       // Exact rules permissions.deny must contain.
+	ForbiddenAllowRules     []string `json:"forbiddenAllowRules,omitempty"`     // Rules permissions.allow must not contain.
+	ForbidBypassPermissions bool     `json:"forbidBypassPermissions,omitempty"` // bypassPermissions must be disabled and not the default mode.
+	RequireHooks            bool     `json:"requireHooks,omitempty"`            // disableAllHooks must not be set.
+	RequirePolicy           bool     `json:"requirePolicy,omitempty"`           // An OPA policy must be configured.
+	ProtectedPaths          []string `json:"protectedPaths,omitempty"`          // Patterns permissions.protectedPaths must contain.
+	AllowedModels           []string `json:"allowedModels,omitempty"`           // When set, model must be one of these.
+	AllowedMCPServers       []string `json:"allowedMcpServers,omitempty"`       // When set, every configured MCP server name must be listed.
+}
+
+// ComplianceViolation is one baseline requirement the settings miss.
+type ComplianceViolation struct {
+	Key     string `json:"key"`     // Settings key at fault, e.g. "sandbox.enabled".
+	Rule    string `json:"rule"`    // Baseline field that was violated.
+	Message string `json:"message"` // Human-readable explanation.
+}
+
+func (v ComplianceViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Key, v.Message)
+}
+
+// LoadCompliancePolicy reads a baseline from a JSON file.
+func LoadCompliancePolicy(path string) (*CompliancePolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read compliance policy: %w", err)
+	}
+	var policy CompliancePolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("decode compliance policy %s: %w", path, err)
+	}
+	return &policy, nil
+}
+
+// CheckCompliance reports every way effective falls short of baseline, in a
+// stable order. A nil baseline is always satisfied; nil settings are checked
+// as empty settings.
+func CheckCompliance(effective *Settings, baseline *CompliancePolicy) []ComplianceViolation {
+	if baseline == nil {
+		return nil
+	}
+	if effective == nil {
+		effective = &Settings{}
+	}
+	var out []ComplianceViolation
+	add := func(key, rule, format string, args ...any) {
+		out = append(out, ComplianceViolation{Key: key, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	perms := effective.Permissions
+	if perms == nil {
+		perms = &PermissionsConfig{}
+	}
+	sandbox := effective.Sandbox
+	if sandbox == nil {
+		sandbox = &SandboxConfig{}
+	}
+
+	if baseline.RequireSandbox && !boolValue(sandbox.Enabled) {
+		add("sandbox.enabled", "requireSandbox", "sandbox must be enabled")
+	}
+	if baseline.ForbidUnsandboxed && (sandbox.AllowUnsandboxedCommands == nil || *sandbox.AllowUnsandboxedCommands) {
+		add("sandbox.allowUnsandboxedCommands", "forbidUnsandboxed", "unsandboxed commands must be disallowed explicitly")
+	}
+	for _, name := range baseline.DeniedTools {
+		name = strings.TrimSpace(name)
+		if name != "" && !toolDenied(effective, perms, name) {
+			add("permissions.deny", "deniedTools", "tool %s must be denied or disallowed", name)
+		}
+	}
+	for _, rule := range baseline.RequiredDenyRules {
+		if rule = strings.TrimSpace(rule); rule != "" && !containsRule(perms.Deny, rule) {
+			add("permissions.deny", "requiredDenyRules", "deny rule %q is required", rule)
+		}
+	}
+	for _, rule := range baseline.ForbiddenAllowRules {
+		if rule = strings.TrimSpace(rule); rule != "" && containsRule(perms.Allow, rule) {
+			add("permissions.allow", "forbiddenAllowRules", "allow rule %q is not permitted", rule)
+		}
+	}
+	if baseline.ForbidBypassPermissions {
+		if strings.TrimSpace(perms.DisableBypassPermissionsMode) != "disable" {
+			add("permissions.disableBypassPermissionsMode", "forbidBypassPermissions", `must be "disable"`)
+		}
+		if strings.EqualFold(strings.TrimSpace(perms.DefaultMode), "bypassPermissions") {
+			add("permissions.defaultMode", "forbidBypassPermissions", "bypassPermissions cannot be the default mode")
+		}
+	}
+	if baseline.RequireHooks && boolValue(effective.DisableAllHooks) {
+		add("disableAllHooks", "requireHooks", "hooks cannot be disabled")
+	}
+	if baseline.RequirePolicy && (effective.Policy == nil || (effective.Policy.URL == "" && len(effective.Policy.Rego) == 0)) {
+		add("policy", "requirePolicy", "an OPA policy must be configured")
+	}
+	for _, pattern := range baseline.ProtectedPaths {
+		if pattern = strings.TrimSpace(pattern); pattern != "" && !containsRule(perms.ProtectedPaths, pattern) {
+			add("permissions.protectedPaths", "protectedPaths", "path %q must be protected", pattern)
+		}
+	}
+	if len(baseline.AllowedModels) > 0 && !slices.Contains(baseline.AllowedModels, strings.TrimSpace(effective.Model)) {
+		add("model", "allowedModels", "model %q is not approved", effective.Model)
+	}
+	if len(baseline.AllowedMCPServers) > 0 && effective.MCP != nil {
+		names := make([]string, 0, len(effective.MCP.Servers))
+		for name := range effective.MCP.Servers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !slices.Contains(baseline.AllowedMCPServers, name) {
+				add("mcp.servers."+name, "allowedMcpServers", "MCP server %s is not approved", name)
+			}
+		}
+	}
+	return out
+}
+
+// toolDenied reports whether settings keep name from running at all: it is
+// disallowed, or denied by a rule without a narrowing pattern.
+func toolDenied(s *Settings, perms *PermissionsConfig, name string) bool {
+	for _, t := range s.DisallowedTools {
+		if strings.EqualFold(strings.TrimSpace(t), name) {
+			return true
+		}
+	}
+	for _, rule := range perms.Deny {
+		rule = strings.TrimSpace(rule)
+		if strings.EqualFold(rule, name) {
+			return true
+		}
+		for _, all := range []string{"(*)", "(**)", "(*:*)"} {
+			if strings.EqualFold(rule, name+all) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsRule(rules []string, want string) bool {
+	for _, rule := range rules {
+		if strings.TrimSpace(rule) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
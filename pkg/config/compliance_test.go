@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCompliance(t *testing.T) {
+	baseline := &CompliancePolicy{
+		RequireSandbox:          true,
+		ForbidUnsandboxed:       true,
+		DeniedTools:             []string{"WebFetch", "Bash"},
+		RequiredDenyRules:       []string{"Read(.env)"},
+		ForbiddenAllowRules:     []string{"Bash(*)"},
+		ForbidBypassPermissions: true,
+		RequireHooks:            true,
+		RequirePolicy:           true,
+		ProtectedPaths:          []string{"/infra/**"},
+		AllowedModels:           []string{"claude-sonnet-4"},
+		AllowedMCPServers:       []string{"github"},
+	}
+
+	var rules []string
+	for _, v := range CheckCompliance(&Settings{
+		Model:           "gpt-x",
+		DisableAllHooks: boolPtr(true),
+		DisallowedTools: []string{"webfetch"},
+		Permissions: &PermissionsConfig{
+			Allow:       []string{"Bash(*)"},
+			Deny:        []string{"Bash(rm:*)"},
+			DefaultMode: "bypassPermissions",
+		},
+		MCP: &MCPConfig{Servers: map[string]MCPServerConfig{"github": {}, "scraper": {}}},
+	}, baseline) {
+		rules = append(rules, v.Rule+" "+v.Key)
+	}
+	require.Equal(t, []string{
+		"requireSandbox sandbox.enabled",
+		"forbidUnsandboxed sandbox.allowUnsandboxedCommands",
+		"deniedTools permissions.deny",
+		"requiredDenyRules permissions.deny",
+		"forbiddenAllowRules permissions.allow",
+		"forbidBypassPermissions permissions.disableBypassPermissionsMode",
+		"forbidBypassPermissions permissions.defaultMode",
+		"requireHooks disableAllHooks",
+		"requirePolicy policy",
+		"protectedPaths permissions.protectedPaths",
+		"allowedModels model",
+		"allowedMcpServers mcp.servers.scraper",
+	}, rules)
+
+	compliant := &Settings{
+		Model:   "claude-sonnet-4",
+		Sandbox: &SandboxConfig{Enabled: boolPtr(true), AllowUnsandboxedCommands: boolPtr(false)},
+		Permissions: &PermissionsConfig{
+			Deny:                         []string{"WebFetch", "Bash(*)", "Read(.env)"},
+			DisableBypassPermissionsMode: "disable",
+			ProtectedPaths:               []string{"/infra/**"},
+		},
+		Policy: &PolicyConfig{URL: "http://opa:8181"},
+		MCP:    &MCPConfig{Servers: map[string]MCPServerConfig{"github": {}}},
+	}
+	require.Empty(t, CheckCompliance(compliant, baseline))
+	require.Empty(t, CheckCompliance(nil, nil))
+	require.Len(t, CheckCompliance(nil, &CompliancePolicy{RequireSandbox: true}), 1)
+}
+
+func TestLoadCompliancePolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"requireSandbox":true,"deniedTools":["WebFetch"]}`), 0o600))
+	policy, err := LoadCompliancePolicy(path)
+	require.NoError(t, err)
+	require.Equal(t, &CompliancePolicy{RequireSandbox: true, DeniedTools: []string{"WebFetch"}}, policy)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{`), 0o600))
+	_, err = LoadCompliancePolicy(path)
+	require.Error(t, err)
+	_, err = LoadCompliancePolicy(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -514,3 +514,37 @@ func TestParseHookField(t *testing.T) {
 		})
 	}
 }
+
+func TestHooksConfig_UnmarshalJSON_Paths(t *testing.T) {
+	t.Parallel()
+	input := `{
+		"PostToolUse": [{"matcher": "Edit|Write", "paths": ["*.go", "cmd/**/*.go"], "hooks": [{"command": "gofmt -w"}]}]
+	}`
+
+	var got HooksConfig
+	require.NoError(t, json.Unmarshal([]byte(input), &got))
+	require.Equal(t, []HookMatcherEntry{{
+		Matcher: "Edit|Write",
+		Paths:   []string{"*.go", "cmd/**/*.go"},
+		Hooks:   []HookDefinition{{Type: "command", Command: "gofmt -w"}},
+	}}, got.PostToolUse)
+	require.Empty(t, validateHooksConfig(&got))
+
+	cloned := cloneHookEntries(got.PostToolUse)
+	cloned[0].Paths[0] = "*.ts"
+	require.Equal(t, "*.go", got.PostToolUse[0].Paths[0])
+}
+
+func TestValidateHookMatcherGlobs(t *testing.T) {
+	t.Parallel()
+	cmd := []HookDefinition{{Type: "command", Command: "true"}}
+	require.Empty(t, validateHookEntries("hooks.PreToolUse", []HookMatcherEntry{
+		{Matcher: "*Edit|mcp__github__*", Hooks: cmd},
+	}))
+	errs := validateHookEntries("hooks.PostToolUse", []HookMatcherEntry{
+		{Matcher: "Edit", Paths: []string{"", "*.{go"}, Hooks: cmd},
+	})
+	require.Len(t, errs, 2)
+	require.Contains(t, errs[0].Error(), "hooks.PostToolUse[0].paths[0]")
+	require.Contains(t, errs[1].Error(), "unbalanced braces")
+}
@@ -22,30 +22,39 @@ type SettingsLoader struct {
 
 // Load resolves and merges settings across all layers.
 func (l *SettingsLoader) Load() (*Settings, error) {
+	settings, _, err := l.LoadWithProvenance()
+	return settings, err
+}
+
+// LoadWithProvenance is Load that also reports which layer last set each
+// top-level settings key.
+func (l *SettingsLoader) LoadWithProvenance() (*Settings, SettingsProvenance, error) {
 	if strings.TrimSpace(l.ProjectRoot) == "" {
-		return nil, errors.New("project root is required for settings loading")
+		return nil, nil, errors.New("project root is required for settings loading")
 	}
 
 	root := l.ProjectRoot
 	if abs, err := filepath.Abs(root); err == nil {
 		root = abs
 	} else {
-		return nil, fmt.Errorf("resolve project root: %w", err)
+		return nil, nil, fmt.Errorf("resolve project root: %w", err)
 	}
 
 	merged := GetDefaultSettings()
+	provenance := SettingsProvenance{}
+	provenance.Record(LayerDefault, &merged)
 
 	layers := []struct {
 		name string
 		path string
 	}{
-		{name: "project", path: getProjectSettingsPath(root)},
-		{name: "local", path: getLocalSettingsPath(root)},
+		{name: LayerProject, path: getProjectSettingsPath(root)},
+		{name: LayerLocal, path: getLocalSettingsPath(root)},
 	}
 
 	for _, layer := range layers {
-		if err := applySettingsLayer(&merged, layer.name, layer.path, l.FS); err != nil {
-			return nil, err
+		if err := applySettingsLayer(&merged, provenance, layer.name, layer.path, l.FS); err != nil {
+			return nil, nil, err
 		}
 	}
 
@@ -54,11 +63,12 @@ func (l *SettingsLoader) Load() (*Settings, error) {
 		if next := MergeSettings(&merged, l.RuntimeOverrides); next != nil {
 			merged = *next
 		}
+		provenance.Record(LayerRuntime, l.RuntimeOverrides)
 	} else {
 		log.Printf("settings: no runtime overrides provided")
 	}
 
-	return &merged, nil
+	return &merged, provenance, nil
 }
 
 // getProjectSettingsPath returns the tracked project settings path.
@@ -104,7 +114,7 @@ func loadJSONFile(path string, filesystem *FS) (*Settings, error) {
 	return &s, nil
 }
 
-func applySettingsLayer(dst *Settings, name, path string, filesystem *FS) error {
+func applySettingsLayer(dst *Settings, provenance SettingsProvenance, name, path string, filesystem *FS) error {
 	if path == "" {
 		log.Printf("settings: %s layer skipped (no path)", name)
 		return nil
@@ -121,5 +131,36 @@ func applySettingsLayer(dst *Settings, name, path string, filesystem *FS) error
 	if next := MergeSettings(dst, cfg); next != nil {
 		*dst = *next
 	}
+	provenance.Record(name, cfg)
 	return nil
 }
+
+// Settings layer names reported by SettingsProvenance.
+const (
+	LayerDefault = "default"
+	LayerProject = "project"
+	LayerLocal   = "local"
+	LayerRuntime = "runtime"
+)
+
+// SettingsProvenance maps top-level settings keys (their JSON names) to the
+// layer that last set them.
+type SettingsProvenance map[string]string
+
+// Record attributes every top-level key set in s to layer.
+func (p SettingsProvenance) Record(layer string, s *Settings) {
+	if p == nil || s == nil {
+		return
+	}
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return
+	}
+	for key := range keys {
+		p[key] = layer
+	}
+}
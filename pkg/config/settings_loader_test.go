@@ -278,6 +278,38 @@ func TestSettingsLoader_ToolOutputConfigMerge(t *testing.T) {
 	}, got.ToolOutput.PerToolThresholdBytes)
 }
 
+func TestSettingsLoader_ToolPoliciesMerge(t *testing.T) {
+	t.Parallel()
+	projectRoot, projectPath, localPath := newIsolatedPaths(t)
+
+	retries := 2
+	writeSettingsFile(t, projectPath, Settings{
+		Model: "project",
+		ToolPolicies: ToolPolicySet{
+			"bash":  {TimeoutSeconds: 30},
+			"fetch": {MaxRetries: &retries, RetryOn: []string{"429"}},
+		},
+	})
+	writeSettingsFile(t, localPath, Settings{
+		ToolPolicies: ToolPolicySet{"bash": {TimeoutSeconds: 5}},
+	})
+
+	got := loadSettings(t, projectRoot, nil)
+	require.Len(t, got.ToolPolicies, 2)
+	require.Equal(t, 5.0, got.ToolPolicies["bash"].TimeoutSeconds)
+	require.Equal(t, 2, *got.ToolPolicies["fetch"].MaxRetries)
+	require.Equal(t, []string{"429"}, got.ToolPolicies["fetch"].RetryOn)
+
+	negative := -1
+	err := ValidateSettings(&Settings{Model: "m", ToolPolicies: ToolPolicySet{
+		"bash": {TimeoutSeconds: -1, MaxRetries: &negative, RetryOn: []string{"("}},
+	}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "toolPolicies[bash].timeoutSeconds")
+	require.Contains(t, err.Error(), "toolPolicies[bash].maxRetries")
+	require.Contains(t, err.Error(), "toolPolicies[bash].retryOn")
+}
+
 func TestSettingsLoader_MissingFiles(t *testing.T) {
 	t.Run("all layers missing returns defaults", func(t *testing.T) {
 		t.Parallel()
@@ -356,3 +388,18 @@ func TestLoadJSONFileMissingReturnsNil(t *testing.T) {
 	require.NoError(t, err)
 	require.Nil(t, settings)
 }
+
+func TestSettingsLoaderProvenance(t *testing.T) {
+	projectRoot, projectPath, localPath := newIsolatedPaths(t)
+	writeSettingsFile(t, projectPath, Settings{Model: "project-model", OutputStyle: "terse"})
+	writeSettingsFile(t, localPath, Settings{Model: "local-model"})
+
+	loader := SettingsLoader{ProjectRoot: projectRoot, RuntimeOverrides: &Settings{Env: map[string]string{"A": "1"}}}
+	settings, provenance, err := loader.LoadWithProvenance()
+	require.NoError(t, err)
+	require.Equal(t, "local-model", settings.Model)
+	require.Equal(t, LayerLocal, provenance["model"])
+	require.Equal(t, LayerProject, provenance["outputStyle"])
+	require.Equal(t, LayerRuntime, provenance["env"])
+	require.Equal(t, LayerDefault, provenance["permissions"])
+}
@@ -56,7 +56,12 @@ func MergeSettings(lower, higher *Settings) *Settings {
 	}
 	result.Sandbox = mergeSandbox(lower.Sandbox, higher.Sandbox)
 	result.BashOutput = mergeBashOutput(lower.BashOutput, higher.BashOutput)
+	result.BashSession = mergeBashSession(lower.BashSession, higher.BashSession)
 	result.ToolOutput = mergeToolOutput(lower.ToolOutput, higher.ToolOutput)
+	result.ToolPolicies = mergeToolPolicies(lower.ToolPolicies, higher.ToolPolicies)
+	result.Uploads = mergeUploads(lower.Uploads, higher.Uploads)
+	result.Policy = mergePolicy(lower.Policy, higher.Policy)
+	result.WebSearch = mergeWebSearch(lower.WebSearch, higher.WebSearch)
 	result.AllowedMcpServers = mergeMCPServerRules(lower.AllowedMcpServers, higher.AllowedMcpServers)
 	result.DeniedMcpServers = mergeMCPServerRules(lower.DeniedMcpServers, higher.DeniedMcpServers)
 	if higher.AWSAuthRefresh != "" {
@@ -65,9 +70,59 @@ func MergeSettings(lower, higher *Settings) *Settings {
 	if higher.AWSCredentialExport != "" {
 		result.AWSCredentialExport = higher.AWSCredentialExport
 	}
+	result.Templates = mergeTemplates(lower.Templates, higher.Templates)
 	return result
 }
 
+// mergeTemplates merges template maps; a higher template replaces the lower
+// template of the same name entirely.
+func mergeTemplates(lower, higher TemplateSet) TemplateSet {
+	if len(lower) == 0 && len(higher) == 0 {
+		return nil
+	}
+	out := make(TemplateSet, len(lower)+len(higher))
+	for name, tpl := range lower {
+		out[name] = cloneTemplate(tpl)
+	}
+	for name, tpl := range higher {
+		out[name] = cloneTemplate(tpl)
+	}
+	return out
+}
+
+func cloneTemplate(src TemplateConfig) TemplateConfig {
+	out := src
+	out.Tools = mergeStringSlices(nil, src.Tools)
+	out.Tags = mergeMaps(nil, src.Tags)
+	return out
+}
+
+// mergeToolPolicies merges policy maps; a higher entry replaces the lower
+// one for the same tool.
+func mergeToolPolicies(lower, higher ToolPolicySet) ToolPolicySet {
+	if len(lower) == 0 && len(higher) == 0 {
+		return nil
+	}
+	out := make(ToolPolicySet, len(lower)+len(higher))
+	for name, p := range lower {
+		out[name] = cloneToolPolicy(p)
+	}
+	for name, p := range higher {
+		out[name] = cloneToolPolicy(p)
+	}
+	return out
+}
+
+func cloneToolPolicy(src ToolPolicyConfig) ToolPolicyConfig {
+	out := src
+	if src.MaxRetries != nil {
+		v := *src.MaxRetries
+		out.MaxRetries = &v
+	}
+	out.RetryOn = mergeStringSlices(nil, src.RetryOn)
+	return out
+}
+
 // mergePermissions merges permission lists with de-duplication and overrides scalar fields.
 func mergePermissions(lower, higher *PermissionsConfig) *PermissionsConfig {
 	if lower == nil && higher == nil {
@@ -84,6 +139,8 @@ func mergePermissions(lower, higher *PermissionsConfig) *PermissionsConfig {
 	out.Ask = mergeStringSlices(lower.Ask, higher.Ask)
 	out.Deny = mergeStringSlices(lower.Deny, higher.Deny)
 	out.AdditionalDirectories = mergeStringSlices(lower.AdditionalDirectories, higher.AdditionalDirectories)
+	out.ProtectedPaths = mergeStringSlices(lower.ProtectedPaths, higher.ProtectedPaths)
+	out.CommandAnalyzer = mergeMaps(lower.CommandAnalyzer, higher.CommandAnalyzer)
 	if higher.DefaultMode != "" {
 		out.DefaultMode = higher.DefaultMode
 	}
@@ -139,7 +196,7 @@ func cloneHookEntries(src []HookMatcherEntry) []HookMatcherEntry {
 	}
 	out := make([]HookMatcherEntry, len(src))
 	for i, entry := range src {
-		out[i] = HookMatcherEntry{Matcher: entry.Matcher}
+		out[i] = HookMatcherEntry{Matcher: entry.Matcher, Paths: append([]string(nil), entry.Paths...)}
 		if len(entry.Hooks) > 0 {
 			out[i].Hooks = make([]HookDefinition, len(entry.Hooks))
 			copy(out[i].Hooks, entry.Hooks)
@@ -174,9 +231,37 @@ func mergeSandbox(lower, higher *SandboxConfig) *SandboxConfig {
 		out.EnableWeakerNestedSandbox = boolPtr(*higher.EnableWeakerNestedSandbox)
 	}
 	out.Network = mergeSandboxNetwork(lower.Network, higher.Network)
+	out.Container = mergeSandboxContainer(lower.Container, higher.Container)
 	return out
 }
 
+// mergeSandboxContainer overlays the fields higher sets.
+func mergeSandboxContainer(lower, higher *SandboxContainerConfig) *SandboxContainerConfig {
+	if higher == nil {
+		return cloneSandboxContainer(lower)
+	}
+	if lower == nil {
+		return cloneSandboxContainer(higher)
+	}
+	out := *lower
+	if higher.Runtime != "" {
+		out.Runtime = higher.Runtime
+	}
+	if higher.Image != "" {
+		out.Image = higher.Image
+	}
+	if higher.CPUs != 0 {
+		out.CPUs = higher.CPUs
+	}
+	if higher.Memory != "" {
+		out.Memory = higher.Memory
+	}
+	if higher.Network != "" {
+		out.Network = higher.Network
+	}
+	return &out
+}
+
 // mergeSandboxNetwork merges network-level sandbox knobs.
 func mergeSandboxNetwork(lower, higher *SandboxNetworkConfig) *SandboxNetworkConfig {
 	if lower == nil && higher == nil {
@@ -190,6 +275,7 @@ func mergeSandboxNetwork(lower, higher *SandboxNetworkConfig) *SandboxNetworkCon
 	}
 	out := cloneSandboxNetwork(lower)
 	out.AllowUnixSockets = mergeStringSlices(lower.AllowUnixSockets, higher.AllowUnixSockets)
+	out.AllowPrivateHosts = mergeStringSlices(lower.AllowPrivateHosts, higher.AllowPrivateHosts)
 	if higher.AllowLocalBinding != nil {
 		out.AllowLocalBinding = boolPtr(*higher.AllowLocalBinding)
 	}
@@ -226,6 +312,36 @@ func mergeBashOutput(lower, higher *BashOutputConfig) *BashOutputConfig {
 	return out
 }
 
+// mergeBashSession lets a higher layer override single fields; zero values
+// inherit from the layer below.
+func mergeBashSession(lower, higher *BashSessionConfig) *BashSessionConfig {
+	if lower == nil || higher == nil {
+		if higher != nil {
+			return cloneBashSession(higher)
+		}
+		return cloneBashSession(lower)
+	}
+	out := cloneBashSession(lower)
+	if higher.Scope != "" {
+		out.Scope = higher.Scope
+	}
+	if higher.IdleTimeoutSeconds != 0 {
+		out.IdleTimeoutSeconds = higher.IdleTimeoutSeconds
+	}
+	if higher.MaxOutputBytes != 0 {
+		out.MaxOutputBytes = higher.MaxOutputBytes
+	}
+	return out
+}
+
+func cloneBashSession(src *BashSessionConfig) *BashSessionConfig {
+	if src == nil {
+		return nil
+	}
+	out := *src
+	return &out
+}
+
 func mergeToolOutput(lower, higher *ToolOutputConfig) *ToolOutputConfig {
 	if lower == nil && higher == nil {
 		return nil
@@ -241,9 +357,55 @@ func mergeToolOutput(lower, higher *ToolOutputConfig) *ToolOutputConfig {
 		out.DefaultThresholdBytes = higher.DefaultThresholdBytes
 	}
 	out.PerToolThresholdBytes = mergeIntMap(lower.PerToolThresholdBytes, higher.PerToolThresholdBytes)
+	if higher.HeadBytes != nil {
+		v := *higher.HeadBytes
+		out.HeadBytes = &v
+	}
+	if higher.TailBytes != nil {
+		v := *higher.TailBytes
+		out.TailBytes = &v
+	}
+	if higher.Spill != nil {
+		out.Spill = cloneBoolPtr(higher.Spill)
+	}
+	return out
+}
+
+func mergeUploads(lower, higher *UploadsConfig) *UploadsConfig {
+	if lower == nil && higher == nil {
+		return nil
+	}
+	if lower == nil {
+		return cloneUploads(higher)
+	}
+	if higher == nil {
+		return cloneUploads(lower)
+	}
+	out := cloneUploads(lower)
+	if higher.MaxFileBytes != 0 {
+		out.MaxFileBytes = higher.MaxFileBytes
+	}
+	if higher.MaxRequestBytes != 0 {
+		out.MaxRequestBytes = higher.MaxRequestBytes
+	}
+	if len(higher.AllowedMimeTypes) > 0 {
+		out.AllowedMimeTypes = append([]string(nil), higher.AllowedMimeTypes...)
+	}
+	if higher.AllowActiveContent != nil {
+		out.AllowActiveContent = cloneBoolPtr(higher.AllowActiveContent)
+	}
 	return out
 }
 
+// mergePolicy replaces the policy as a whole: a higher layer that sets a
+// policy source must not inherit the files or server of a lower one.
+func mergePolicy(lower, higher *PolicyConfig) *PolicyConfig {
+	if higher != nil {
+		return clonePolicy(higher)
+	}
+	return clonePolicy(lower)
+}
+
 // mergeMaps merges string maps; higher values override lower keys.
 func mergeMaps(lower, higher map[string]string) map[string]string {
 	if len(lower) == 0 && len(higher) == 0 {
@@ -375,11 +537,17 @@ func cloneSettings(src *Settings) *Settings {
 	out.StatusLine = cloneStatusLine(src.StatusLine)
 	out.Sandbox = cloneSandbox(src.Sandbox)
 	out.BashOutput = cloneBashOutput(src.BashOutput)
+	out.BashSession = cloneBashSession(src.BashSession)
 	out.ToolOutput = cloneToolOutput(src.ToolOutput)
+	out.ToolPolicies = mergeToolPolicies(nil, src.ToolPolicies)
+	out.Uploads = cloneUploads(src.Uploads)
+	out.Policy = clonePolicy(src.Policy)
+	out.WebSearch = cloneWebSearch(src.WebSearch)
 	out.AllowedMcpServers = mergeMCPServerRules(nil, src.AllowedMcpServers)
 	out.DeniedMcpServers = mergeMCPServerRules(nil, src.DeniedMcpServers)
 	out.MCP = cloneMCPConfig(src.MCP)
 	out.LegacyMCPServers = mergeStringSlices(nil, src.LegacyMCPServers)
+	out.Templates = mergeTemplates(nil, src.Templates)
 	return &out
 }
 
@@ -392,6 +560,8 @@ func clonePermissions(src *PermissionsConfig) *PermissionsConfig {
 	out.Ask = mergeStringSlices(nil, src.Ask)
 	out.Deny = mergeStringSlices(nil, src.Deny)
 	out.AdditionalDirectories = mergeStringSlices(nil, src.AdditionalDirectories)
+	out.ProtectedPaths = mergeStringSlices(nil, src.ProtectedPaths)
+	out.CommandAnalyzer = mergeMaps(nil, src.CommandAnalyzer)
 	return &out
 }
 
@@ -426,6 +596,15 @@ func cloneSandbox(src *SandboxConfig) *SandboxConfig {
 	out.AllowUnsandboxedCommands = cloneBoolPtr(src.AllowUnsandboxedCommands)
 	out.EnableWeakerNestedSandbox = cloneBoolPtr(src.EnableWeakerNestedSandbox)
 	out.Network = cloneSandboxNetwork(src.Network)
+	out.Container = cloneSandboxContainer(src.Container)
+	return &out
+}
+
+func cloneSandboxContainer(src *SandboxContainerConfig) *SandboxContainerConfig {
+	if src == nil {
+		return nil
+	}
+	out := *src
 	return &out
 }
 
@@ -435,6 +614,7 @@ func cloneSandboxNetwork(src *SandboxNetworkConfig) *SandboxNetworkConfig {
 	}
 	out := *src
 	out.AllowUnixSockets = mergeStringSlices(nil, src.AllowUnixSockets)
+	out.AllowPrivateHosts = mergeStringSlices(nil, src.AllowPrivateHosts)
 	if src.HTTPProxyPort != nil {
 		v := *src.HTTPProxyPort
 		out.HTTPProxyPort = &v
@@ -473,6 +653,51 @@ func cloneToolOutput(src *ToolOutputConfig) *ToolOutputConfig {
 	}
 	out := *src
 	out.PerToolThresholdBytes = mergeIntMap(nil, src.PerToolThresholdBytes)
+	if src.HeadBytes != nil {
+		v := *src.HeadBytes
+		out.HeadBytes = &v
+	}
+	if src.TailBytes != nil {
+		v := *src.TailBytes
+		out.TailBytes = &v
+	}
+	out.Spill = cloneBoolPtr(src.Spill)
+	return &out
+}
+
+func cloneUploads(src *UploadsConfig) *UploadsConfig {
+	if src == nil {
+		return nil
+	}
+	out := *src
+	out.AllowedMimeTypes = append([]string(nil), src.AllowedMimeTypes...)
+	out.AllowActiveContent = cloneBoolPtr(src.AllowActiveContent)
+	return &out
+}
+
+func clonePolicy(src *PolicyConfig) *PolicyConfig {
+	if src == nil {
+		return nil
+	}
+	out := *src
+	out.Rego = append([]string(nil), src.Rego...)
+	return &out
+}
+
+// mergeWebSearch replaces the config as a whole so a layer that switches
+// provider does not inherit the endpoint or key variable of another.
+func mergeWebSearch(lower, higher *WebSearchConfig) *WebSearchConfig {
+	if higher != nil {
+		return cloneWebSearch(higher)
+	}
+	return cloneWebSearch(lower)
+}
+
+func cloneWebSearch(src *WebSearchConfig) *WebSearchConfig {
+	if src == nil {
+		return nil
+	}
+	out := *src
 	return &out
 }
 
@@ -48,3 +48,27 @@ func TestMergeSettingsNilInputs(t *testing.T) {
 		t.Fatalf("expected lower preserved")
 	}
 }
+
+func TestMergeSettingsTemplates(t *testing.T) {
+	t.Parallel()
+
+	lower := &Settings{Templates: TemplateSet{
+		"review": {SystemPrompt: "old", Tools: []string{"Read"}},
+		"triage": {Model: "low"},
+	}}
+	higher := &Settings{Templates: TemplateSet{
+		"review": {SystemPrompt: "new"},
+	}}
+
+	merged := MergeSettings(lower, higher)
+	if got := merged.Templates["review"]; got.SystemPrompt != "new" || len(got.Tools) != 0 {
+		t.Fatalf("expected higher template to replace lower, got %+v", got)
+	}
+	if merged.Templates["triage"].Model != "low" {
+		t.Fatalf("expected lower-only template kept, got %+v", merged.Templates)
+	}
+	merged.Templates["triage"] = TemplateConfig{}
+	if lower.Templates["triage"].Model != "low" {
+		t.Fatal("merge must not alias inputs")
+	}
+}
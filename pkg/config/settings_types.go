@@ -26,22 +26,46 @@ type Settings struct {
 	ForceLoginOrgUUID    string             `json:"forceLoginOrgUUID,omitempty"`    // Org UUID to auto-select during login when set.
 	Sandbox              *SandboxConfig     `json:"sandbox,omitempty"`              // Bash sandbox configuration.
 	BashOutput           *BashOutputConfig  `json:"bashOutput,omitempty"`           // Thresholds for spooling bash output to disk.
+	BashSession          *BashSessionConfig `json:"bashSession,omitempty"`          // Persistent shells for the bash tool.
 	ToolOutput           *ToolOutputConfig  `json:"toolOutput,omitempty"`           // Thresholds for persisting large tool outputs to disk.
+	ToolPolicies         ToolPolicySet      `json:"toolPolicies,omitempty"`         // Per-tool timeout and retry policies keyed by tool name.
 	AllowedMcpServers    []MCPServerRule    `json:"allowedMcpServers,omitempty"`    // Managed allowlist of user-configurable MCP servers.
 	DeniedMcpServers     []MCPServerRule    `json:"deniedMcpServers,omitempty"`     // Managed denylist of user-configurable MCP servers.
 	AWSAuthRefresh       string             `json:"awsAuthRefresh,omitempty"`       // Script to refresh AWS SSO credentials.
 	AWSCredentialExport  string             `json:"awsCredentialExport,omitempty"`  // Script that prints JSON AWS credentials.
 	RespectGitignore     *bool              `json:"respectGitignore,omitempty"`     // Whether Glob/Grep tools should respect .gitignore patterns.
+	Templates            TemplateSet        `json:"templates,omitempty"`            // Named request presets selectable per request.
+	Uploads              *UploadsConfig     `json:"uploads,omitempty"`              // Limits for files uploaded as run attachments.
+	Policy               *PolicyConfig      `json:"policy,omitempty"`               // OPA policy consulted for permission and sandbox decisions.
+	WebSearch            *WebSearchConfig   `json:"webSearch,omitempty"`            // Backend used by the WebSearch tool.
+}
+
+// TemplateSet maps template names to request presets.
+type TemplateSet map[string]TemplateConfig
+
+// TemplateConfig is a reusable request preset. Empty fields leave the
+// runtime or request defaults untouched.
+type TemplateConfig struct {
+	Description     string            `json:"description,omitempty"`     // Human-readable summary.
+	SystemPrompt    string            `json:"systemPrompt,omitempty"`    // Replaces the runtime system prompt.
+	SystemPromptRef string            `json:"systemPromptRef,omitempty"` // Prompt template ("name" or "name@version") rendering the system prompt.
+	Tools           []string          `json:"tools,omitempty"`           // Tool whitelist applied when the request sets none.
+	Model           string            `json:"model,omitempty"`           // Model tier: low, mid or high.
+	OutputFormat    string            `json:"outputFormat,omitempty"`    // Output instructions appended to the system prompt.
+	PermissionMode  string            `json:"permissionMode,omitempty"`  // askBeforeRunningTools, acceptReadOnly, acceptEdits or bypassPermissions.
+	Tags            map[string]string `json:"tags,omitempty"`            // Default request tags.
 }
 
 // PermissionsConfig defines per-tool permission rules.
 type PermissionsConfig struct {
-	Allow                        []string `json:"allow,omitempty"`                        // Rules that auto-allow tool use.
-	Ask                          []string `json:"ask,omitempty"`                          // Rules that require confirmation.
-	Deny                         []string `json:"deny,omitempty"`                         // Rules that block tool use.
-	AdditionalDirectories        []string `json:"additionalDirectories,omitempty"`        // Extra working directories Claude may access.
-	DefaultMode                  string   `json:"defaultMode,omitempty"`                  // Default permission mode when opening Claude Code.
-	DisableBypassPermissionsMode string   `json:"disableBypassPermissionsMode,omitempty"` // Set to "disable" to forbid bypassPermissions mode.
+	Allow                        []string          `json:"allow,omitempty"`                        // Rules that auto-allow tool use.
+	Ask                          []string          `json:"ask,omitempty"`                          // Rules that require confirmation.
+	Deny                         []string          `json:"deny,omitempty"`                         // Rules that block tool use.
+	AdditionalDirectories        []string          `json:"additionalDirectories,omitempty"`        // Extra working directories Claude may access.
+	DefaultMode                  string            `json:"defaultMode,omitempty"`                  // Default permission mode when opening Claude Code.
+	DisableBypassPermissionsMode string            `json:"disableBypassPermissionsMode,omitempty"` // Set to "disable" to forbid bypassPermissions mode.
+	ProtectedPaths               []string          `json:"protectedPaths,omitempty"`               // CODEOWNERS-style patterns whose edits need approval or bypassPermissions.
+	CommandAnalyzer              map[string]string `json:"commandAnalyzer,omitempty"`              // Per-risk allow/ask/deny overrides for the bash command analyzer.
 }
 
 // HookDefinition describes a single hook action bound to a matcher entry.
@@ -58,8 +82,12 @@ type HookDefinition struct {
 }
 
 // HookMatcherEntry pairs a matcher pattern with one or more hook definitions.
+// Matcher is a regex or a tool-name glob ("Edit|Write", "mcp__github__*");
+// Paths further restricts tool events to file paths matching one of the
+// globs ("*.go", "pkg/**/*.ts").
 type HookMatcherEntry struct {
 	Matcher string           `json:"matcher"`
+	Paths   []string         `json:"paths,omitempty"`
 	Hooks   []HookDefinition `json:"hooks"`
 }
 
@@ -86,20 +114,32 @@ type HooksConfig struct {
 
 // SandboxConfig controls bash sandboxing.
 type SandboxConfig struct {
-	Enabled                   *bool                 `json:"enabled,omitempty"`                   // Enable filesystem/network sandboxing for bash.
-	AutoAllowBashIfSandboxed  *bool                 `json:"autoAllowBashIfSandboxed,omitempty"`  // Auto-approve bash commands when sandboxed.
-	ExcludedCommands          []string              `json:"excludedCommands,omitempty"`          // Commands that must run outside the sandbox.
-	AllowUnsandboxedCommands  *bool                 `json:"allowUnsandboxedCommands,omitempty"`  // Whether dangerouslyDisableSandbox escape hatch is allowed.
-	EnableWeakerNestedSandbox *bool                 `json:"enableWeakerNestedSandbox,omitempty"` // Allow weaker sandbox for unprivileged Docker.
-	Network                   *SandboxNetworkConfig `json:"network,omitempty"`                   // Network-level sandbox knobs.
+	Enabled                   *bool                   `json:"enabled,omitempty"`                   // Enable filesystem/network sandboxing for bash.
+	AutoAllowBashIfSandboxed  *bool                   `json:"autoAllowBashIfSandboxed,omitempty"`  // Auto-approve bash commands when sandboxed.
+	ExcludedCommands          []string                `json:"excludedCommands,omitempty"`          // Commands that must run outside the sandbox.
+	AllowUnsandboxedCommands  *bool                   `json:"allowUnsandboxedCommands,omitempty"`  // Whether dangerouslyDisableSandbox escape hatch is allowed.
+	EnableWeakerNestedSandbox *bool                   `json:"enableWeakerNestedSandbox,omitempty"` // Allow weaker sandbox for unprivileged Docker.
+	Network                   *SandboxNetworkConfig   `json:"network,omitempty"`                   // Network-level sandbox knobs.
+	Container                 *SandboxContainerConfig `json:"container,omitempty"`                 // Run bash in a per-session container instead of the OS sandbox.
+}
+
+// SandboxContainerConfig runs bash commands inside a per-session Docker or
+// Podman container with the project root bind-mounted at the same path.
+type SandboxContainerConfig struct {
+	Runtime string  `json:"runtime,omitempty"` // "docker" (default) or "podman".
+	Image   string  `json:"image,omitempty"`   // Container image; required.
+	CPUs    float64 `json:"cpus,omitempty"`    // CPU limit (--cpus); 0 = unlimited.
+	Memory  string  `json:"memory,omitempty"`  // Memory limit (--memory), e.g. "2g"; empty = unlimited.
+	Network string  `json:"network,omitempty"` // Container network (--network), e.g. "none"; empty = runtime default.
 }
 
 // SandboxNetworkConfig tunes sandbox network access.
 type SandboxNetworkConfig struct {
 	AllowUnixSockets  []string `json:"allowUnixSockets,omitempty"`  // Unix sockets exposed inside sandbox (SSH agent, docker socket).
 	AllowLocalBinding *bool    `json:"allowLocalBinding,omitempty"` // Allow binding to localhost ports (macOS).
-	HTTPProxyPort     *int     `json:"httpProxyPort,omitempty"`     // Port for custom HTTP proxy if bringing your own.
-	SocksProxyPort    *int     `json:"socksProxyPort,omitempty"`    // Port for custom SOCKS5 proxy if bringing your own.
+	HTTPProxyPort     *int     `json:"httpProxyPort,omitempty"`     // Loopback port of the filtering HTTP egress proxy for sandboxed commands.
+	SocksProxyPort    *int     `json:"socksProxyPort,omitempty"`    // Loopback port of the filtering SOCKS5 egress proxy for sandboxed commands.
+	AllowPrivateHosts []string `json:"allowPrivateHosts,omitempty"` // Internal hosts, IPs or CIDRs WebFetch may reach despite SSRF protection.
 }
 
 // BashOutputConfig configures when bash output is spooled to disk.
@@ -108,10 +148,61 @@ type BashOutputConfig struct {
 	AsyncThresholdBytes *int `json:"asyncThresholdBytes,omitempty"` // Spool async output to disk after exceeding this many bytes.
 }
 
+// BashSessionConfig keeps one bash process per run or session so cwd,
+// exported variables and shell functions carry over between Bash calls.
+type BashSessionConfig struct {
+	Scope              string `json:"scope,omitempty"`              // "run", "session", or "off" (default): one fresh shell per command.
+	IdleTimeoutSeconds int    `json:"idleTimeoutSeconds,omitempty"` // Close shells idle this long (0 = 600).
+	MaxOutputBytes     int    `json:"maxOutputBytes,omitempty"`     // Truncate a command's output past this many bytes (0 = 8 MiB).
+}
+
 // ToolOutputConfig configures when tool output is persisted to disk.
 type ToolOutputConfig struct {
 	DefaultThresholdBytes int            `json:"defaultThresholdBytes,omitempty"` // Persist output to disk after exceeding this many bytes (0 = SDK default).
 	PerToolThresholdBytes map[string]int `json:"perToolThresholdBytes,omitempty"` // Optional per-tool thresholds keyed by canonical tool name.
+	HeadBytes             *int           `json:"headBytes,omitempty"`             // Bytes of oversized output kept inline from the start (nil = 8 KiB).
+	TailBytes             *int           `json:"tailBytes,omitempty"`             // Bytes of oversized output kept inline from the end (nil = 8 KiB).
+	Spill                 *bool          `json:"spill,omitempty"`                 // Save oversized output to a file the Read tool can open (nil = true).
+}
+
+// ToolPolicySet maps tool names (case-insensitive) to execution policies.
+type ToolPolicySet map[string]ToolPolicyConfig
+
+// ToolPolicyConfig bounds how one tool is executed; see tool.Policy.
+type ToolPolicyConfig struct {
+	TimeoutSeconds float64  `json:"timeoutSeconds,omitempty"` // Per-attempt time limit (0 = none).
+	MaxRetries     *int     `json:"maxRetries,omitempty"`     // Retries after the first attempt; also retries non-idempotent tools.
+	RetryOn        []string `json:"retryOn,omitempty"`        // Regular expressions over the error message or stderr that make a failure retryable.
+}
+
+// UploadsConfig limits files accepted by the upload endpoint.
+type UploadsConfig struct {
+	MaxFileBytes       int64    `json:"maxFileBytes,omitempty"`       // Per-file size limit (0 = SDK default of 20 MiB).
+	MaxRequestBytes    int64    `json:"maxRequestBytes,omitempty"`    // Limit on all files of one request (0 = SDK default of 64 MiB).
+	AllowedMimeTypes   []string `json:"allowedMimeTypes,omitempty"`   // Accepted media types; "type/*" wildcards allowed. Empty accepts all but active content.
+	AllowActiveContent *bool    `json:"allowActiveContent,omitempty"` // Accept HTML, XHTML and SVG uploads, which browsers run scripts from (default false).
+}
+
+// WebSearchConfig selects the backend of the WebSearch tool. API keys are
+// read from the environment, never from settings files.
+type WebSearchConfig struct {
+	Provider   string `json:"provider,omitempty"`   // "duckduckgo" (default), "brave" or "tavily".
+	APIKeyEnv  string `json:"apiKeyEnv,omitempty"`  // Variable holding the API key (default BRAVE_API_KEY or TAVILY_API_KEY).
+	Endpoint   string `json:"endpoint,omitempty"`   // Overrides the provider's API URL, e.g. for a proxy.
+	MaxResults int    `json:"maxResults,omitempty"` // Results returned per search (0 = tool default of 8).
+}
+
+// PolicyConfig delegates permission and sandbox decisions to Open Policy
+// Agent. Set URL to query an OPA server, or Rego to evaluate policy files
+// with the opa binary.
+type PolicyConfig struct {
+	URL         string   `json:"url,omitempty"`         // OPA server base URL; decisions are POSTed to /v1/data/<decision>.
+	Rego        []string `json:"rego,omitempty"`        // Rego files or bundle directories, relative to the project root.
+	Binary      string   `json:"binary,omitempty"`      // opa executable used with Rego (default "opa").
+	Decision    string   `json:"decision,omitempty"`    // Rule path of the decision (default "agentsdk/authz").
+	TimeoutMs   int      `json:"timeoutMs,omitempty"`   // Per-decision timeout (default 5000).
+	OnError     string   `json:"onError,omitempty"`     // Action when evaluation fails: deny (default), ask or allow.
+	DecisionLog string   `json:"decisionLog,omitempty"` // JSONL file receiving every decision, relative to the project root.
 }
 
 // MCPConfig nests Model Context Protocol server definitions.
@@ -66,6 +66,22 @@ func TestSandboxValidateErrors(t *testing.T) {
 	require.Contains(t, err.Error(), "socksProxyPort")
 }
 
+func TestSandboxContainerValidateAndMerge(t *testing.T) {
+	err := (&SandboxConfig{Container: &SandboxContainerConfig{Runtime: "lxc", CPUs: -1, Memory: "lots"}}).Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "sandbox.container.runtime")
+	require.Contains(t, err.Error(), "sandbox.container.image is required")
+	require.Contains(t, err.Error(), "sandbox.container.cpus")
+	require.Contains(t, err.Error(), "sandbox.container.memory")
+	require.NoError(t, (&SandboxConfig{Container: &SandboxContainerConfig{Runtime: "podman", Image: "alpine", CPUs: 2, Memory: "512m"}}).Validate())
+
+	merged := mergeSandbox(
+		&SandboxConfig{Container: &SandboxContainerConfig{Image: "alpine", Memory: "1g"}},
+		&SandboxConfig{Container: &SandboxContainerConfig{Image: "golang:1.24", CPUs: 2}},
+	)
+	require.Equal(t, &SandboxContainerConfig{Image: "golang:1.24", CPUs: 2, Memory: "1g"}, merged.Container)
+}
+
 func TestMCPServerRuleValidate(t *testing.T) {
 	var rule MCPServerRule
 	err := rule.Validate()
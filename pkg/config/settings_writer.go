@@ -0,0 +1,110 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// localSettingsMu serialises read-modify-write cycles on settings.local.json
+// within the process.
+var localSettingsMu sync.Mutex
+
+// AddLocalPermissionRule appends rule to permissions.<action> ("allow",
+// "ask" or "deny") in the project's .claude/settings.local.json, creating
+// the file if needed. Other keys are kept; a rule already present is not
+// added again.
+func AddLocalPermissionRule(projectRoot, action, rule string) error {
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		return errors.New("permission rule is empty")
+	}
+	switch action {
+	case "allow", "ask", "deny":
+	default:
+		return fmt.Errorf("unsupported permission action %q", action)
+	}
+	path := getLocalSettingsPath(projectRoot)
+	if path == "" {
+		return errors.New("project root is required")
+	}
+
+	localSettingsMu.Lock()
+	defer localSettingsMu.Unlock()
+
+	doc := map[string]json.RawMessage{}
+	mode := os.FileMode(0o644)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if info, statErr := os.Stat(path); statErr == nil {
+			mode = info.Mode().Perm()
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			if err := json.Unmarshal(data, &doc); err != nil {
+				return fmt.Errorf("decode %s: %w", path, err)
+			}
+		}
+	case errors.Is(err, iofs.ErrNotExist):
+	default:
+		return err
+	}
+
+	permissions := map[string]json.RawMessage{}
+	if raw, ok := doc["permissions"]; ok && len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &permissions); err != nil {
+			return fmt.Errorf("decode %s permissions: %w", path, err)
+		}
+	}
+	var rules []string
+	if raw, ok := permissions[action]; ok && len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &rules); err != nil {
+			return fmt.Errorf("decode %s permissions.%s: %w", path, action, err)
+		}
+	}
+	if slices.Contains(rules, rule) {
+		return nil
+	}
+	rules = append(rules, rule)
+
+	encoded, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	permissions[action] = encoded
+	if doc["permissions"], err = json.Marshal(permissions); err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".settings.local-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // best-effort cleanup after rename
+	if _, err := tmp.Write(out); err != nil {
+		tmp.Close() //nolint:errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
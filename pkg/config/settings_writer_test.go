@@ -0,0 +1,46 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddLocalPermissionRule(t *testing.T) {
+	projectRoot, _, localPath := newIsolatedPaths(t)
+
+	require.NoError(t, AddLocalPermissionRule(projectRoot, "allow", "Bash(git:*)"))
+	settings := loadSettings(t, projectRoot, nil)
+	require.Contains(t, settings.Permissions.Allow, "Bash(git:*)")
+
+	// Existing keys and file mode survive and duplicates are skipped.
+	require.NoError(t, os.Remove(localPath))
+	require.NoError(t, os.WriteFile(localPath, []byte(`{"model":"opus","permissions":{"allow":["Read"],"defaultMode":"plan"},"futureKey":{"x":1}}`), 0o600))
+	require.NoError(t, AddLocalPermissionRule(projectRoot, "deny", "Bash(rm:*)"))
+	require.NoError(t, AddLocalPermissionRule(projectRoot, "deny", "Bash(rm:*)"))
+
+	data, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	var doc struct {
+		Model       string                     `json:"model"`
+		FutureKey   map[string]int             `json:"futureKey"`
+		Permissions map[string]json.RawMessage `json:"permissions"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	require.Equal(t, "opus", doc.Model)
+	require.Equal(t, 1, doc.FutureKey["x"])
+	require.JSONEq(t, `["Read"]`, string(doc.Permissions["allow"]))
+	require.JSONEq(t, `["Bash(rm:*)"]`, string(doc.Permissions["deny"]))
+	require.JSONEq(t, `"plan"`, string(doc.Permissions["defaultMode"]))
+
+	info, err := os.Stat(localPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	require.Error(t, AddLocalPermissionRule(projectRoot, "maybe", "Bash"))
+	require.Error(t, AddLocalPermissionRule(projectRoot, "allow", " "))
+	require.NoError(t, os.WriteFile(localPath, []byte(`{`), 0o600))
+	require.Error(t, AddLocalPermissionRule(projectRoot, "allow", "Bash"))
+}
@@ -10,6 +10,8 @@ import (
 
 var (
 	toolNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+	// containerMemoryPattern matches docker --memory values.
+	containerMemoryPattern = regexp.MustCompile(`^[0-9]+[bkmgBKMG]?$`)
 )
 
 // ValidateSettings checks the merged Settings structure for logical consistency.
@@ -37,9 +39,16 @@ func ValidateSettings(s *Settings) error {
 
 	// bash output spooling thresholds
 	errs = append(errs, validateBashOutputConfig(s.BashOutput)...)
+	errs = append(errs, validateBashSessionConfig(s.BashSession)...)
 
 	// tool output persistence thresholds
 	errs = append(errs, validateToolOutputConfig(s.ToolOutput)...)
+	errs = append(errs, validateToolPolicies(s.ToolPolicies)...)
+
+	// upload limits
+	errs = append(errs, validateUploadsConfig(s.Uploads)...)
+	errs = append(errs, validateWebSearchConfig(s.WebSearch)...)
+	errs = append(errs, validatePolicyConfig(s.Policy)...)
 
 	// mcp
 	errs = append(errs, validateMCPConfig(s.MCP, s.LegacyMCPServers)...)
@@ -50,12 +59,41 @@ func ValidateSettings(s *Settings) error {
 	// force login options
 	errs = append(errs, validateForceLoginConfig(s.ForceLoginMethod, s.ForceLoginOrgUUID)...)
 
+	// request templates
+	errs = append(errs, validateTemplates(s.Templates)...)
+
 	if len(errs) == 0 {
 		return nil
 	}
 	return errors.Join(errs...)
 }
 
+func validateTemplates(templates TemplateSet) []error {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		tpl := templates[name]
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, errors.New("templates: name cannot be empty"))
+		}
+		switch strings.TrimSpace(tpl.Model) {
+		case "", "low", "mid", "high":
+		default:
+			errs = append(errs, fmt.Errorf("templates[%s].model %q must be low, mid or high", name, tpl.Model))
+		}
+		switch strings.TrimSpace(tpl.PermissionMode) {
+		case "", "askBeforeRunningTools", "acceptReadOnly", "acceptEdits", "bypassPermissions":
+		default:
+			errs = append(errs, fmt.Errorf("templates[%s].permissionMode %q is not supported", name, tpl.PermissionMode))
+		}
+	}
+	return errs
+}
+
 func validatePermissionsConfig(p *PermissionsConfig) []error {
 	if p == nil {
 		return nil
@@ -85,6 +123,25 @@ func validatePermissionsConfig(p *PermissionsConfig) []error {
 		}
 	}
 
+	risks := make([]string, 0, len(p.CommandAnalyzer))
+	for risk := range p.CommandAnalyzer {
+		risks = append(risks, risk)
+	}
+	sort.Strings(risks)
+	for _, risk := range risks {
+		switch risk {
+		case "pipe_to_shell", "recursive_delete_root", "privilege_escalation", "fork_bomb":
+		default:
+			errs = append(errs, fmt.Errorf("permissions.commandAnalyzer: unknown risk %q", risk))
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(p.CommandAnalyzer[risk])) {
+		case "allow", "ask", "deny":
+		default:
+			errs = append(errs, fmt.Errorf("permissions.commandAnalyzer.%s must be allow, ask or deny, got %q", risk, p.CommandAnalyzer[risk]))
+		}
+	}
+
 	return errs
 }
 
@@ -147,7 +204,7 @@ func validateToolPattern(pattern string) error {
 	if pattern == "" {
 		return errors.New("tool pattern is empty")
 	}
-	if pattern == "*" {
+	if pattern == "*" || isToolGlob(pattern) {
 		return nil
 	}
 	if _, err := regexp.Compile(pattern); err != nil {
@@ -156,6 +213,14 @@ func validateToolPattern(pattern string) error {
 	return nil
 }
 
+// toolGlobPattern matches tool-name globs such as "mcp__github__*|Edit",
+// which hooks accept alongside regular expressions.
+var toolGlobPattern = regexp.MustCompile(`^[A-Za-z0-9_\-*?|]+$`)
+
+func isToolGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?") && toolGlobPattern.MatchString(pattern)
+}
+
 func validateHooksConfig(h *HooksConfig) []error {
 	if h == nil {
 		return nil
@@ -187,6 +252,13 @@ func validateHookEntries(label string, entries []HookMatcherEntry) []error {
 				errs = append(errs, fmt.Errorf("%s[%d].matcher: %w", label, i, err))
 			}
 		}
+		for j, glob := range entry.Paths {
+			if strings.TrimSpace(glob) == "" {
+				errs = append(errs, fmt.Errorf("%s[%d].paths[%d]: glob is empty", label, i, j))
+			} else if strings.Count(glob, "{") != strings.Count(glob, "}") {
+				errs = append(errs, fmt.Errorf("%s[%d].paths[%d]: unbalanced braces in %q", label, i, j, glob))
+			}
+		}
 		if len(entry.Hooks) == 0 {
 			errs = append(errs, fmt.Errorf("%s[%d]: hooks array is empty", label, i))
 			continue
@@ -239,6 +311,22 @@ func validateSandboxConfig(s *SandboxConfig) []error {
 			}
 		}
 	}
+	if c := s.Container; c != nil {
+		switch strings.ToLower(strings.TrimSpace(c.Runtime)) {
+		case "", "docker", "podman":
+		default:
+			errs = append(errs, fmt.Errorf("sandbox.container.runtime must be docker or podman, got %q", c.Runtime))
+		}
+		if strings.TrimSpace(c.Image) == "" {
+			errs = append(errs, errors.New("sandbox.container.image is required"))
+		}
+		if c.CPUs < 0 {
+			errs = append(errs, fmt.Errorf("sandbox.container.cpus must be >=0, got %v", c.CPUs))
+		}
+		if c.Memory != "" && !containerMemoryPattern.MatchString(c.Memory) {
+			errs = append(errs, fmt.Errorf("sandbox.container.memory %q must be a size like 512m or 2g", c.Memory))
+		}
+	}
 	return errs
 }
 
@@ -260,6 +348,111 @@ func validateBashOutputConfig(cfg *BashOutputConfig) []error {
 	return errs
 }
 
+func validateBashSessionConfig(cfg *BashSessionConfig) []error {
+	if cfg == nil {
+		return nil
+	}
+	var errs []error
+	switch strings.ToLower(strings.TrimSpace(cfg.Scope)) {
+	case "", "off", "run", "session":
+	default:
+		errs = append(errs, fmt.Errorf("bashSession.scope %q must be off, run or session", cfg.Scope))
+	}
+	if cfg.IdleTimeoutSeconds < 0 {
+		errs = append(errs, fmt.Errorf("bashSession.idleTimeoutSeconds must be >=0, got %d", cfg.IdleTimeoutSeconds))
+	}
+	if cfg.MaxOutputBytes < 0 {
+		errs = append(errs, fmt.Errorf("bashSession.maxOutputBytes must be >=0, got %d", cfg.MaxOutputBytes))
+	}
+	return errs
+}
+
+func validateUploadsConfig(cfg *UploadsConfig) []error {
+	if cfg == nil {
+		return nil
+	}
+	var errs []error
+	if cfg.MaxFileBytes < 0 {
+		errs = append(errs, fmt.Errorf("uploads.maxFileBytes must be >=0, got %d", cfg.MaxFileBytes))
+	}
+	if cfg.MaxRequestBytes < 0 {
+		errs = append(errs, fmt.Errorf("uploads.maxRequestBytes must be >=0, got %d", cfg.MaxRequestBytes))
+	}
+	for i, mt := range cfg.AllowedMimeTypes {
+		major, minor, ok := strings.Cut(strings.TrimSpace(mt), "/")
+		if !ok || major == "" || minor == "" || major == "*" {
+			errs = append(errs, fmt.Errorf("uploads.allowedMimeTypes[%d] %q must look like type/subtype or type/*", i, mt))
+		}
+	}
+	return errs
+}
+
+func validateWebSearchConfig(cfg *WebSearchConfig) []error {
+	if cfg == nil {
+		return nil
+	}
+	var errs []error
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case "", "duckduckgo", "brave", "tavily":
+	default:
+		errs = append(errs, fmt.Errorf("webSearch.provider %q must be duckduckgo, brave or tavily", cfg.Provider))
+	}
+	if cfg.MaxResults < 0 {
+		errs = append(errs, fmt.Errorf("webSearch.maxResults must be >=0, got %d", cfg.MaxResults))
+	}
+	return errs
+}
+
+func validatePolicyConfig(cfg *PolicyConfig) []error {
+	if cfg == nil {
+		return nil
+	}
+	var errs []error
+	if strings.TrimSpace(cfg.URL) == "" && len(cfg.Rego) == 0 {
+		errs = append(errs, errors.New("policy requires url or rego"))
+	}
+	if strings.TrimSpace(cfg.URL) != "" && len(cfg.Rego) > 0 {
+		errs = append(errs, errors.New("policy.url and policy.rego are mutually exclusive"))
+	}
+	if cfg.TimeoutMs < 0 {
+		errs = append(errs, fmt.Errorf("policy.timeoutMs must be >=0, got %d", cfg.TimeoutMs))
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.OnError)) {
+	case "", "deny", "ask", "allow":
+	default:
+		errs = append(errs, fmt.Errorf("policy.onError %q must be deny, ask or allow", cfg.OnError))
+	}
+	return errs
+}
+
+func validateToolPolicies(policies ToolPolicySet) []error {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		p := policies[name]
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, errors.New("toolPolicies has an empty tool name"))
+			continue
+		}
+		if p.TimeoutSeconds < 0 {
+			errs = append(errs, fmt.Errorf("toolPolicies[%s].timeoutSeconds must be >=0, got %v", name, p.TimeoutSeconds))
+		}
+		if p.MaxRetries != nil && *p.MaxRetries < 0 {
+			errs = append(errs, fmt.Errorf("toolPolicies[%s].maxRetries must be >=0, got %d", name, *p.MaxRetries))
+		}
+		for _, pattern := range p.RetryOn {
+			if _, err := regexp.Compile(pattern); err != nil {
+				errs = append(errs, fmt.Errorf("toolPolicies[%s].retryOn %q: %w", name, pattern, err))
+			}
+		}
+	}
+	return errs
+}
+
 func validateToolOutputConfig(cfg *ToolOutputConfig) []error {
 	if cfg == nil {
 		return nil
@@ -269,6 +462,12 @@ func validateToolOutputConfig(cfg *ToolOutputConfig) []error {
 	if cfg.DefaultThresholdBytes < 0 {
 		errs = append(errs, fmt.Errorf("toolOutput.defaultThresholdBytes must be >=0, got %d", cfg.DefaultThresholdBytes))
 	}
+	if cfg.HeadBytes != nil && *cfg.HeadBytes < 0 {
+		errs = append(errs, fmt.Errorf("toolOutput.headBytes must be >=0, got %d", *cfg.HeadBytes))
+	}
+	if cfg.TailBytes != nil && *cfg.TailBytes < 0 {
+		errs = append(errs, fmt.Errorf("toolOutput.tailBytes must be >=0, got %d", *cfg.TailBytes))
+	}
 
 	if len(cfg.PerToolThresholdBytes) == 0 {
 		return errs
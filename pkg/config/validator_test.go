@@ -47,6 +47,7 @@ func TestValidateSettingsSuccess(t *testing.T) {
 }
 
 func TestValidateToolOutputConfigRejectsInvalidThresholds(t *testing.T) {
+	negative := -1
 	s := &Settings{
 		Model: "claude-3",
 		ToolOutput: &ToolOutputConfig{
@@ -54,6 +55,7 @@ func TestValidateToolOutputConfigRejectsInvalidThresholds(t *testing.T) {
 			PerToolThresholdBytes: map[string]int{
 				"Bash": 0,
 			},
+			HeadBytes: &negative,
 		},
 	}
 
@@ -62,6 +64,7 @@ func TestValidateToolOutputConfigRejectsInvalidThresholds(t *testing.T) {
 	msg := err.Error()
 	require.Contains(t, msg, "toolOutput.defaultThresholdBytes")
 	require.Contains(t, msg, "toolOutput.perToolThresholdBytes")
+	require.Contains(t, msg, "toolOutput.headBytes")
 }
 
 func TestValidateSettingsAggregatesErrors(t *testing.T) {
@@ -146,6 +149,24 @@ func TestValidatePermissionsConfig_DisableAndDirs(t *testing.T) {
 	require.Contains(t, err[0].Error()+err[1].Error(), "permissions.additionalDirectories[1]")
 }
 
+func TestValidatePermissionsConfig_CommandAnalyzer(t *testing.T) {
+	p := &PermissionsConfig{
+		DefaultMode: "askBeforeRunningTools",
+		CommandAnalyzer: map[string]string{
+			"fork_bomb":            "deny",
+			"pipe_to_shell":        "Allow",
+			"sudo":                 "deny",
+			"rm_root":              "",
+			"privilege_escalation": "prompt",
+		},
+	}
+	err := validatePermissionsConfig(p)
+	require.Len(t, err, 3)
+	require.Contains(t, err[0].Error(), "privilege_escalation must be allow, ask or deny")
+	require.Contains(t, err[1].Error(), `unknown risk "rm_root"`)
+	require.Contains(t, err[2].Error(), `unknown risk "sudo"`)
+}
+
 func TestValidateHooksConfig_EmptyCommand(t *testing.T) {
 	errs := validateHooksConfig(&HooksConfig{
 		PreToolUse: []HookMatcherEntry{
@@ -191,3 +212,42 @@ func TestValidateForceLoginConfig_BlankOrg(t *testing.T) {
 
 	require.NoError(t, ValidateSettings(&Settings{Model: "m", Permissions: &PermissionsConfig{DefaultMode: "askBeforeRunningTools"}}))
 }
+
+func TestValidateTemplates(t *testing.T) {
+	s := &Settings{
+		Model: "claude-3",
+		Templates: TemplateSet{
+			"review": {Model: "high", PermissionMode: "acceptReadOnly"},
+			"broken": {Model: "turbo", PermissionMode: "yolo"},
+		},
+	}
+	err := ValidateSettings(s)
+	require.Error(t, err)
+	msg := err.Error()
+	require.Contains(t, msg, "templates[broken].model")
+	require.Contains(t, msg, "templates[broken].permissionMode")
+	require.NotContains(t, msg, "templates[review]")
+}
+
+func TestValidateWebSearchConfig(t *testing.T) {
+	err := ValidateSettings(&Settings{Model: "m", WebSearch: &WebSearchConfig{Provider: "bing", MaxResults: -1}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "webSearch.provider")
+	require.Contains(t, err.Error(), "webSearch.maxResults")
+
+	require.NoError(t, ValidateSettings(&Settings{Model: "m", WebSearch: &WebSearchConfig{Provider: "Tavily"}}))
+	merged := MergeSettings(&Settings{WebSearch: &WebSearchConfig{Provider: "brave", Endpoint: "http://proxy"}}, &Settings{WebSearch: &WebSearchConfig{Provider: "tavily"}})
+	require.Equal(t, &WebSearchConfig{Provider: "tavily"}, merged.WebSearch)
+}
+
+func TestValidateBashSessionConfig(t *testing.T) {
+	err := ValidateSettings(&Settings{Model: "m", BashSession: &BashSessionConfig{Scope: "forever", IdleTimeoutSeconds: -1, MaxOutputBytes: -1}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bashSession.scope")
+	require.Contains(t, err.Error(), "bashSession.idleTimeoutSeconds")
+	require.Contains(t, err.Error(), "bashSession.maxOutputBytes")
+
+	require.NoError(t, ValidateSettings(&Settings{Model: "m", BashSession: &BashSessionConfig{Scope: "Session"}}))
+	merged := MergeSettings(&Settings{BashSession: &BashSessionConfig{Scope: "run", IdleTimeoutSeconds: 30}}, &Settings{BashSession: &BashSessionConfig{Scope: "off"}})
+	require.Equal(t, &BashSessionConfig{Scope: "off", IdleTimeoutSeconds: 30}, merged.BashSession)
+}
@@ -11,22 +11,23 @@ import (
 type EventType string
 
 const (
-	PreToolUse         EventType = "PreToolUse"
-	PostToolUse        EventType = "PostToolUse"
-	PostToolUseFailure EventType = "PostToolUseFailure"
-	PreCompact         EventType = "PreCompact"
-	ContextCompacted   EventType = "ContextCompacted"
-	UserPromptSubmit   EventType = "UserPromptSubmit"
-	SessionStart       EventType = "SessionStart"
-	SessionEnd         EventType = "SessionEnd"
-	Stop               EventType = "Stop"
-	SubagentStart      EventType = "SubagentStart"
-	SubagentStop       EventType = "SubagentStop"
-	Notification       EventType = "Notification"
-	TokenUsage         EventType = "TokenUsage"
-	PermissionRequest  EventType = "PermissionRequest"
-	ModelSelected      EventType = "ModelSelected"
-	MCPToolsChanged    EventType = "MCPToolsChanged"
+	PreToolUse          EventType = "PreToolUse"
+	PostToolUse         EventType = "PostToolUse"
+	PostToolUseFailure  EventType = "PostToolUseFailure"
+	PreCompact          EventType = "PreCompact"
+	ContextCompacted    EventType = "ContextCompacted"
+	UserPromptSubmit    EventType = "UserPromptSubmit"
+	SessionStart        EventType = "SessionStart"
+	SessionEnd          EventType = "SessionEnd"
+	Stop                EventType = "Stop"
+	SubagentStart       EventType = "SubagentStart"
+	SubagentStop        EventType = "SubagentStop"
+	Notification        EventType = "Notification"
+	TokenUsage          EventType = "TokenUsage"
+	PermissionRequest   EventType = "PermissionRequest"
+	ModelSelected       EventType = "ModelSelected"
+	MCPToolsChanged     EventType = "MCPToolsChanged"
+	DefinitionsReloaded EventType = "DefinitionsReloaded"
 )
 
 // Event represents a single occurrence in the system. It is intentionally
@@ -78,6 +79,9 @@ type PreCompactPayload struct {
 
 // ContextCompactedPayload is emitted after context compaction completes.
 type ContextCompactedPayload struct {
+	// Strategy is the compaction strategy applied (summarize, truncate,
+	// drop-tool-outputs).
+	Strategy              string `json:"strategy,omitempty"`
 	Summary               string `json:"summary"`
 	OriginalMessages      int    `json:"original_messages"`
 	PreservedMessages     int    `json:"preserved_messages"`
@@ -170,6 +174,8 @@ type TokenUsagePayload struct {
 	Model         string `json:"model,omitempty"`
 	SessionID     string `json:"session_id,omitempty"`
 	RequestID     string `json:"request_id,omitempty"`
+	// Experiments maps experiment name to the run's assigned variant.
+	Experiments map[string]string `json:"experiments,omitempty"`
 }
 
 // ModelSelectedPayload is emitted when a model is selected for tool execution.
@@ -179,6 +185,17 @@ type ModelSelectedPayload struct {
 	Reason    string
 }
 
+// DefinitionsReloadedPayload is emitted after watched .claude/skills or
+// .claude/agents files changed and the runtime reloaded them.
+type DefinitionsReloadedPayload struct {
+	// Kind is "skills" or "agents".
+	Kind string
+	// Names lists the definitions registered after the reload.
+	Names []string
+	// Errors holds load errors of files that were skipped.
+	Errors []string
+}
+
 // MCPToolsChangedPayload is emitted when an MCP server notifies the client that
 // its tool list changed (notifications/tools/list_changed) and the client has
 // refreshed its tool snapshot.
@@ -15,6 +15,7 @@ import (
 
 	"github.com/cexll/agentsdk-go/pkg/core/events"
 	"github.com/cexll/agentsdk-go/pkg/core/middleware"
+	"github.com/cexll/agentsdk-go/pkg/core/retry"
 )
 
 // Default timeouts per Claude Code spec.
@@ -79,17 +80,44 @@ type Result struct {
 	Stderr   string
 }
 
-// Selector filters hooks by matcher target and/or payload pattern.
+// BlockingError is returned when a hook exits with code 2 (or cannot be
+// started). Stderr is the feedback the hook wants delivered: to the model
+// for tool and Stop events, to the caller for UserPromptSubmit.
+type BlockingError struct {
+	Event    events.EventType
+	Hook     string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *BlockingError) Error() string {
+	return fmt.Sprintf("hooks: blocking error: %s", e.Stderr)
+}
+
+// Feedback returns the trimmed stderr, or a generic message when the hook
+// wrote nothing.
+func (e *BlockingError) Feedback() string {
+	if msg := strings.TrimSpace(e.Stderr); msg != "" {
+		return msg
+	}
+	return fmt.Sprintf("%s hook exited with code %d", e.Event, e.ExitCode)
+}
+
+// Selector filters hooks by matcher target, tool input path and/or payload
+// pattern.
 type Selector struct {
 	ToolName *regexp.Regexp
+	Path     *regexp.Regexp
 	Pattern  *regexp.Regexp
 }
 
-// NewSelector compiles optional regex patterns. Empty strings are treated as wildcards.
+// NewSelector compiles optional patterns. Empty strings are treated as
+// wildcards. toolPattern is a regex or a tool-name glob (see
+// compileToolMatcher); payloadPattern is a regex over the JSON payload.
 func NewSelector(toolPattern, payloadPattern string) (Selector, error) {
 	sel := Selector{}
 	if strings.TrimSpace(toolPattern) != "" {
-		re, err := regexp.Compile(toolPattern)
+		re, err := compileToolMatcher(toolPattern)
 		if err != nil {
 			return sel, fmt.Errorf("hooks: compile tool matcher: %w", err)
 		}
@@ -105,6 +133,18 @@ func NewSelector(toolPattern, payloadPattern string) (Selector, error) {
 	return sel, nil
 }
 
+// WithPaths restricts the selector to tool calls whose file_path,
+// notebook_path or path input matches one of the globs. Events without a
+// tool input path never match a path-restricted selector.
+func (s Selector) WithPaths(globs ...string) (Selector, error) {
+	re, err := compilePathMatcher(globs)
+	if err != nil {
+		return s, err
+	}
+	s.Path = re
+	return s, nil
+}
+
 // Match returns true when the event satisfies all configured selectors.
 func (s Selector) Match(evt events.Event) bool {
 	if s.ToolName != nil {
@@ -113,6 +153,12 @@ func (s Selector) Match(evt events.Event) bool {
 			return false
 		}
 	}
+	if s.Path != nil {
+		target := toolInputPath(evt.Payload)
+		if target == "" || !s.Path.MatchString(target) {
+			return false
+		}
+	}
 	if s.Pattern != nil {
 		payload, err := json.Marshal(evt.Payload)
 		if err != nil {
@@ -125,10 +171,18 @@ func (s Selector) Match(evt events.Event) bool {
 	return true
 }
 
+// Callback is an in-process hook. It receives the typed event and returns
+// the structured output a shell hook would print on exit 0; a nil output
+// means allow. A returned error blocks like exit code 2, with the error
+// text as feedback, unless the caller's context is already done.
+type Callback func(context.Context, events.Event) (*HookOutput, error)
+
 // ShellHook describes a single shell command bound to an event type.
+// When Callback is set it runs in-process instead of Command.
 type ShellHook struct {
 	Event         events.EventType
 	Command       string
+	Callback      Callback
 	Selector      Selector
 	Timeout       time.Duration
 	Env           map[string]string
@@ -316,6 +370,10 @@ func (e *Executor) matchingHooks(evt events.Event) []ShellHook {
 }
 
 func (e *Executor) executeHook(ctx context.Context, hook ShellHook, payload []byte, evt events.Event) (Result, error) {
+	if hook.Callback != nil {
+		return e.executeCallback(ctx, hook, evt)
+	}
+
 	var res Result
 	res.Event = evt
 
@@ -352,6 +410,14 @@ func (e *Executor) executeHook(ctx context.Context, hook ShellHook, payload []by
 			// nolint:errcheck // Process cleanup, error not actionable
 			cmd.Process.Kill()
 		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// The caller's budget ran out, not the hook's own timeout.
+			exhausted := &retry.DeadlineExhausted{Stage: "hook " + string(evt.Type), Attempts: 1}
+			if trimmed := strings.TrimSpace(errStr); trimmed != "" {
+				exhausted.Err = errors.New(trimmed)
+			}
+			return res, exhausted
+		}
 		return res, fmt.Errorf("hooks: command timed out after %s: %s", deadline, errStr)
 	}
 
@@ -363,8 +429,9 @@ func (e *Executor) executeHook(ctx context.Context, hook ShellHook, payload []by
 
 	switch decision {
 	case DecisionAllow:
-		// Exit 0: parse JSON stdout if present
-		if trimmed := strings.TrimSpace(outStr); trimmed != "" {
+		// Exit 0: parse JSON stdout if present. Plain text is kept in
+		// Stdout; UserPromptSubmit and SessionStart add it as context.
+		if trimmed := strings.TrimSpace(outStr); strings.HasPrefix(trimmed, "{") {
 			output, parseErr := decodeHookOutput(trimmed)
 			if parseErr != nil {
 				return res, parseErr
@@ -373,7 +440,7 @@ func (e *Executor) executeHook(ctx context.Context, hook ShellHook, payload []by
 		}
 	case DecisionBlockingError:
 		// Exit 2: blocking error, stderr is the error message
-		return res, fmt.Errorf("hooks: blocking error: %s", errStr)
+		return res, &BlockingError{Event: evt.Type, Hook: hook.Name, ExitCode: exitCode, Stderr: errStr}
 	case DecisionNonBlocking:
 		// Exit 1, 3+: non-blocking, log stderr and continue
 		if errStr != "" {
@@ -384,6 +451,37 @@ func (e *Executor) executeHook(ctx context.Context, hook ShellHook, payload []by
 	return res, nil
 }
 
+// executeCallback runs an in-process hook with the same timeout budget and
+// blocking semantics as a shell command.
+func (e *Executor) executeCallback(ctx context.Context, hook ShellHook, evt events.Event) (Result, error) {
+	res := Result{Event: evt}
+
+	deadline := effectiveTimeout(hook.Timeout, e.timeout)
+	runCtx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+
+	output, err := hook.Callback(runCtx, evt)
+	if err != nil {
+		if ctx.Err() != nil {
+			return res, err
+		}
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return res, fmt.Errorf("hooks: callback timed out after %s: %w", deadline, err)
+		}
+		var blocked *BlockingError
+		if errors.As(err, &blocked) {
+			return res, blocked
+		}
+		res.Decision = DecisionBlockingError
+		res.ExitCode = 2
+		res.Stderr = err.Error()
+		return res, &BlockingError{Event: evt.Type, Hook: hook.Name, ExitCode: 2, Stderr: res.Stderr}
+	}
+	res.Decision = DecisionAllow
+	res.Output = output
+	return res, nil
+}
+
 func effectiveTimeout(hookTimeout, defaultTimeout time.Duration) time.Duration {
 	if hookTimeout > 0 {
 		return hookTimeout
@@ -559,6 +657,12 @@ func buildPayload(evt events.Event) ([]byte, error) {
 			envelope["reason"] = p.Reason
 		}
 		envelope["stop_hook_active"] = p.StopHookActive
+	case events.DefinitionsReloadedPayload:
+		envelope["kind"] = p.Kind
+		envelope["names"] = p.Names
+		if len(p.Errors) > 0 {
+			envelope["errors"] = p.Errors
+		}
 	case events.ModelSelectedPayload:
 		envelope["tool_name"] = p.ToolName
 		envelope["model_tier"] = p.ModelTier
@@ -600,6 +704,7 @@ func mergeEnv(base []string, extra map[string]string) []string {
 // - SessionStart → source; SessionEnd → reason
 // - Notification → notification_type; PreCompact → trigger
 // - SubagentStart/SubagentStop → agent_type (fallback to name)
+// - DefinitionsReloaded → kind (skills or agents)
 // - UserPromptSubmit/Stop → always match (return empty to skip matcher)
 func extractMatcherTarget(eventType events.EventType, payload any) string {
 	switch eventType {
@@ -655,6 +760,10 @@ func extractMatcherTarget(eventType events.EventType, payload any) string {
 			}
 			return p.Name
 		}
+	case events.DefinitionsReloaded:
+		if p, ok := payload.(events.DefinitionsReloadedPayload); ok {
+			return p.Kind
+		}
 	case events.UserPromptSubmit, events.Stop:
 		// These events always match (no matcher support)
 		return ""
@@ -668,7 +777,7 @@ func validateEvent(t events.EventType) error {
 		events.Notification, events.UserPromptSubmit,
 		events.SessionStart, events.SessionEnd, events.Stop, events.TokenUsage,
 		events.SubagentStart, events.SubagentStop,
-		events.PermissionRequest, events.ModelSelected:
+		events.PermissionRequest, events.ModelSelected, events.DefinitionsReloaded:
 		return nil
 	default:
 		return fmt.Errorf("hooks: unsupported event %s", t)
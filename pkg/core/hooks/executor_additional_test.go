@@ -2,6 +2,7 @@ package hooks
 
 import (
 	"context"
+	"errors"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -28,3 +29,46 @@ func TestExecutorWithWorkDirAndClose(t *testing.T) {
 
 	exec.Close()
 }
+
+func TestExecutorRunsCallbackHooks(t *testing.T) {
+	exec := NewExecutor()
+	var seen string
+	exec.Register(ShellHook{
+		Event: events.PreToolUse,
+		Callback: func(_ context.Context, evt events.Event) (*HookOutput, error) {
+			seen = evt.Payload.(events.ToolUsePayload).Name
+			return &HookOutput{Decision: "deny"}, nil
+		},
+	})
+
+	results, err := exec.Execute(context.Background(), events.Event{Type: events.PreToolUse, Payload: events.ToolUsePayload{Name: "Bash"}})
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if seen != "Bash" {
+		t.Fatalf("callback saw %q", seen)
+	}
+	if len(results) != 1 || results[0].Output == nil || results[0].Output.Decision != "deny" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+}
+
+func TestExecutorCallbackErrorBlocks(t *testing.T) {
+	exec := NewExecutor()
+	exec.Register(ShellHook{
+		Event: events.Stop,
+		Name:  "go:stop",
+		Callback: func(context.Context, events.Event) (*HookOutput, error) {
+			return nil, errors.New("tests are failing")
+		},
+	})
+
+	_, err := exec.Execute(context.Background(), events.Event{Type: events.Stop, Payload: events.StopPayload{}})
+	var blocked *BlockingError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("expected BlockingError, got %v", err)
+	}
+	if blocked.Feedback() != "tests are failing" || blocked.Hook != "go:stop" {
+		t.Fatalf("unexpected blocking error %+v", blocked)
+	}
+}
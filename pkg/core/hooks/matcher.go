@@ -0,0 +1,140 @@
+package hooks
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/core/events"
+)
+
+// toolGlobAlt matches one alternative of a tool-name glob such as
+// "mcp__github__*" or "Notebook?dit".
+var toolGlobAlt = regexp.MustCompile(`^[A-Za-z0-9_\-*?]+$`)
+
+// compileToolMatcher compiles a hook matcher for tool names, agent types and
+// similar targets. A matcher made only of names and "|" whose alternatives
+// use "*" or "?" is a glob anchored at both ends ("Edit|mcp__github__*");
+// anything else is an unanchored regular expression, so a bare MCP server
+// prefix such as "mcp__github" matches every tool of that server.
+func compileToolMatcher(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimSpace(pattern)
+	if !strings.ContainsAny(pattern, "*?") {
+		return regexp.Compile(pattern)
+	}
+	alts := strings.Split(pattern, "|")
+	for _, alt := range alts {
+		if !toolGlobAlt.MatchString(alt) {
+			return regexp.Compile(pattern)
+		}
+	}
+	parts := make([]string, len(alts))
+	for i, alt := range alts {
+		if !strings.ContainsAny(alt, "*?") {
+			// Plain names keep their regex meaning inside a glob matcher.
+			parts[i] = ".*" + regexp.QuoteMeta(alt) + ".*"
+			continue
+		}
+		parts[i] = toolGlobToRegex(alt)
+	}
+	return regexp.Compile("^(?:" + strings.Join(parts, "|") + ")$")
+}
+
+func toolGlobToRegex(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// compilePathMatcher compiles file path globs into a single expression.
+// "*" and "?" stay within a path segment, "**" spans segments and
+// "{a,b}" lists alternatives. A glob without "/" matches the base name
+// ("*.go"); a relative glob with "/" matches any trailing run of segments
+// ("pkg/**/*.go") and an absolute glob matches the whole path.
+func compilePathMatcher(globs []string) (*regexp.Regexp, error) {
+	var parts []string
+	for _, glob := range globs {
+		glob = strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(glob)), "./")
+		if glob == "" {
+			continue
+		}
+		expr, err := pathGlobToRegex(glob)
+		if err != nil {
+			return nil, fmt.Errorf("hooks: path glob %q: %w", glob, err)
+		}
+		if strings.HasPrefix(glob, "/") {
+			parts = append(parts, "^"+expr+"$")
+		} else {
+			parts = append(parts, "(?:^|/)"+expr+"$")
+		}
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	return regexp.Compile(strings.Join(parts, "|"))
+}
+
+func pathGlobToRegex(glob string) (string, error) {
+	var b strings.Builder
+	inBrace := false
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '{' && !inBrace:
+			inBrace = true
+			b.WriteString("(?:")
+		case c == '}' && inBrace:
+			inBrace = false
+			b.WriteString(")")
+		case c == ',' && inBrace:
+			b.WriteString("|")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if inBrace {
+		return "", fmt.Errorf("unclosed {")
+	}
+	return b.String(), nil
+}
+
+// toolInputPath returns the file path a tool call targets, or "" when the
+// event carries no tool input with a path.
+func toolInputPath(payload any) string {
+	var params map[string]any
+	switch p := payload.(type) {
+	case events.ToolUsePayload:
+		params = p.Params
+	case events.ToolResultPayload:
+		params = p.Params
+	case events.PermissionRequestPayload:
+		params = p.ToolParams
+	}
+	for _, key := range []string{"file_path", "notebook_path", "path"} {
+		if s, ok := params[key].(string); ok && strings.TrimSpace(s) != "" {
+			return path.Clean(filepath.ToSlash(strings.TrimSpace(s)))
+		}
+	}
+	return ""
+}
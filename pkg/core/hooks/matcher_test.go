@@ -0,0 +1,76 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/core/events"
+)
+
+func TestSelectorToolGlobs(t *testing.T) {
+	cases := []struct {
+		pattern string
+		tool    string
+		want    bool
+	}{
+		{"mcp__github__*", "mcp__github__create_issue", true},
+		{"mcp__github__*", "mcp__gitlab__create_issue", false},
+		{"mcp__github", "mcp__github__create_issue", true},
+		{"Edit|Write", "Write", true},
+		{"Edit|Write", "Bash", false},
+		{"*Edit", "NotebookEdit", true},
+		{"*Edit", "EditFile", false},
+		{"Bash|mcp__*", "Bash", true},
+		{"Bash|mcp__*", "Read", false},
+		{"Notebook.*", "NotebookEdit", true},
+	}
+	for _, tc := range cases {
+		sel, err := NewSelector(tc.pattern, "")
+		if err != nil {
+			t.Fatalf("%q: %v", tc.pattern, err)
+		}
+		evt := events.Event{Type: events.PreToolUse, Payload: events.ToolUsePayload{Name: tc.tool}}
+		if got := sel.Match(evt); got != tc.want {
+			t.Errorf("%q vs %q = %v, want %v", tc.pattern, tc.tool, got, tc.want)
+		}
+	}
+}
+
+func TestSelectorPathGlobs(t *testing.T) {
+	cases := []struct {
+		globs []string
+		path  string
+		want  bool
+	}{
+		{[]string{"*.go"}, "/repo/pkg/api/agent.go", true},
+		{[]string{"*.go"}, "/repo/README.md", false},
+		{[]string{"*.{ts,tsx}"}, "web/app.tsx", true},
+		{[]string{"pkg/**/*.go"}, "/repo/pkg/core/hooks/executor.go", true},
+		{[]string{"pkg/**/*.go"}, "/repo/cmd/main.go", false},
+		{[]string{"pkg/*.go"}, "/repo/pkg/core/x.go", false},
+		{[]string{"/repo/docs/**"}, "/repo/docs/guide/a.md", true},
+		{[]string{"/repo/docs/**"}, "/other/repo/docs/a.md", false},
+		{[]string{"*.md", "*.go"}, "main.go", true},
+	}
+	for _, tc := range cases {
+		sel, err := NewSelector("Edit|Write", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sel, err = sel.WithPaths(tc.globs...); err != nil {
+			t.Fatalf("%v: %v", tc.globs, err)
+		}
+		evt := events.Event{Type: events.PostToolUse, Payload: events.ToolResultPayload{Name: "Edit", Params: map[string]any{"file_path": tc.path}}}
+		if got := sel.Match(evt); got != tc.want {
+			t.Errorf("%v vs %q = %v, want %v", tc.globs, tc.path, got, tc.want)
+		}
+	}
+
+	sel, _ := NewSelector("", "")
+	sel, _ = sel.WithPaths("*.go")
+	if sel.Match(events.Event{Type: events.PreToolUse, Payload: events.ToolUsePayload{Name: "Bash", Params: map[string]any{"command": "go test"}}}) {
+		t.Fatal("tool calls without a path must not match a path selector")
+	}
+	if _, err := sel.WithPaths("*.{go"); err == nil {
+		t.Fatal("expected error for unclosed brace")
+	}
+}
@@ -3,6 +3,7 @@ package hooks
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/cexll/agentsdk-go/pkg/core/events"
 	"github.com/cexll/agentsdk-go/pkg/core/middleware"
+	"github.com/cexll/agentsdk-go/pkg/core/retry"
 	"github.com/stretchr/testify/require"
 )
 
@@ -142,6 +144,26 @@ func TestTimeoutIsHonored(t *testing.T) {
 	}
 }
 
+func TestCallerDeadlineReportsExhaustedStage(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	script := writeScript(t, dir, "slow.sh", "#!/bin/sh\nsleep 1\n")
+
+	exec := NewExecutor(WithTimeout(time.Minute))
+	exec.Register(ShellHook{Event: events.Notification, Command: script})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := exec.Execute(ctx, events.Event{Type: events.Notification})
+	var exhausted *retry.DeadlineExhausted
+	if !errors.As(err, &exhausted) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExhausted, got %v", err)
+	}
+	if exhausted.Stage != "hook "+string(events.Notification) {
+		t.Fatalf("unexpected stage %q", exhausted.Stage)
+	}
+}
+
 func TestStderrCapturedOnBlockingError(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -0,0 +1,136 @@
+// Package retry runs bounded retry loops that respect the caller's context
+// deadline. Backoff sleeps never outlive the deadline: when the remaining
+// budget cannot cover the next wait the loop stops early with a
+// *DeadlineExhausted error naming the stage that ran out of time.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DeadlineExhausted reports that a stage used up the context deadline.
+// errors.Is(err, context.DeadlineExceeded) holds for it, so callers that only
+// check the context error keep working.
+type DeadlineExhausted struct {
+	// Stage names the loop that consumed the time, e.g. "anthropic" or
+	// "hook PreToolUse".
+	Stage string
+	// Attempts is the number of calls made before giving up.
+	Attempts int
+	// Err is the last attempt error, if any.
+	Err error
+}
+
+func (e *DeadlineExhausted) Error() string {
+	msg := fmt.Sprintf("deadline exhausted in %s after %d attempt(s)", e.Stage, e.Attempts)
+	if e.Err != nil && !errors.Is(e.Err, context.DeadlineExceeded) {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *DeadlineExhausted) Unwrap() []error {
+	if e.Err == nil {
+		return []error{context.DeadlineExceeded}
+	}
+	return []error{context.DeadlineExceeded, e.Err}
+}
+
+// Loop describes a retry loop.
+type Loop struct {
+	// Stage labels DeadlineExhausted errors.
+	Stage string
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	// Backoff returns the wait before retry n (1-based). Nil uses Quadratic.
+	Backoff func(n int) time.Duration
+	// Retryable reports whether err warrants another attempt. Nil retries
+	// every error.
+	Retryable func(error) bool
+	// RetryAfter optionally extracts a server-requested wait (e.g. a
+	// Retry-After header) from err; when it reports ok the wait replaces
+	// Backoff for that retry.
+	RetryAfter func(error) (time.Duration, bool)
+	// AttemptTimeout caps a single attempt. Each attempt gets the smaller of
+	// AttemptTimeout and the time left until the context deadline; an attempt
+	// that hits its own cap while the parent is still live is retried. Zero
+	// lets an attempt use the whole remaining budget.
+	AttemptTimeout time.Duration
+}
+
+// Quadratic is the default backoff: 100ms, 400ms, 900ms, ...
+func Quadratic(n int) time.Duration {
+	return time.Duration(n*n) * 100 * time.Millisecond
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, exhausts
+// MaxRetries or runs out of deadline.
+func (l Loop) Do(ctx context.Context, fn func(context.Context) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	backoff := l.Backoff
+	if backoff == nil {
+		backoff = Quadratic
+	}
+	for attempt := 1; ; attempt++ {
+		err := l.attempt(ctx, fn)
+		if err == nil {
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return l.contextError(ctxErr, attempt, err)
+		}
+		if (l.Retryable != nil && !l.Retryable(err)) || attempt > l.MaxRetries {
+			return err
+		}
+		wait := backoff(attempt)
+		if l.RetryAfter != nil {
+			if hint, ok := l.RetryAfter(err); ok {
+				wait = hint
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			// Sleeping would consume the rest of the budget; fail now so the
+			// caller still has time to react.
+			return &DeadlineExhausted{Stage: l.Stage, Attempts: attempt, Err: err}
+		}
+		if err := Sleep(ctx, wait); err != nil {
+			return l.contextError(err, attempt, nil)
+		}
+	}
+}
+
+func (l Loop) attempt(ctx context.Context, fn func(context.Context) error) error {
+	if l.AttemptTimeout <= 0 {
+		return fn(ctx)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, l.AttemptTimeout)
+	defer cancel()
+	return fn(attemptCtx)
+}
+
+func (l Loop) contextError(ctxErr error, attempts int, last error) error {
+	if errors.Is(ctxErr, context.DeadlineExceeded) {
+		return &DeadlineExhausted{Stage: l.Stage, Attempts: attempts, Err: last}
+	}
+	return ctxErr
+}
+
+// Sleep waits for d or until ctx is done, whichever comes first.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoopRetriesUntilSuccess(t *testing.T) {
+	calls := 0
+	err := Loop{Stage: "test", MaxRetries: 3, Backoff: func(int) time.Duration { return time.Millisecond }}.Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("err=%v calls=%d", err, calls)
+	}
+}
+
+func TestLoopStopsOnNonRetryableAndMaxRetries(t *testing.T) {
+	fatal := errors.New("fatal")
+	calls := 0
+	err := Loop{MaxRetries: 5, Retryable: func(err error) bool { return !errors.Is(err, fatal) }}.Do(context.Background(), func(context.Context) error {
+		calls++
+		return fatal
+	})
+	if !errors.Is(err, fatal) || calls != 1 {
+		t.Fatalf("err=%v calls=%d", err, calls)
+	}
+
+	calls = 0
+	err = Loop{MaxRetries: 2, Backoff: func(int) time.Duration { return 0 }}.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errors.New("flaky")
+	})
+	if err == nil || calls != 3 {
+		t.Fatalf("err=%v calls=%d", err, calls)
+	}
+}
+
+func TestLoopSkipsBackoffPastDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	flaky := errors.New("flaky")
+	start := time.Now()
+	err := Loop{Stage: "provider", MaxRetries: 10, Backoff: func(int) time.Duration { return time.Hour }}.Do(ctx, func(context.Context) error {
+		return flaky
+	})
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("expected early return, waited %s", elapsed)
+	}
+	var exhausted *DeadlineExhausted
+	if !errors.As(err, &exhausted) || exhausted.Stage != "provider" || exhausted.Attempts != 1 {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, flaky) {
+		t.Fatalf("expected error to match deadline and last error, got %v", err)
+	}
+}
+
+func TestLoopAttemptTimeoutRetriesWhileParentLive(t *testing.T) {
+	calls := 0
+	err := Loop{MaxRetries: 1, AttemptTimeout: 20 * time.Millisecond, Backoff: func(int) time.Duration { return 0 }}.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("err=%v calls=%d", err, calls)
+	}
+}
+
+func TestLoopCancellationPassesThrough(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Loop{MaxRetries: 3}.Do(ctx, func(ctx context.Context) error { return ctx.Err() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	var exhausted *DeadlineExhausted
+	if errors.As(err, &exhausted) {
+		t.Fatalf("cancellation must not be reported as deadline exhaustion")
+	}
+}
+
+func TestLoopHonoursRetryAfterHint(t *testing.T) {
+	calls := 0
+	start := time.Now()
+	err := Loop{
+		MaxRetries: 1,
+		Backoff:    func(int) time.Duration { return time.Hour },
+		RetryAfter: func(error) (time.Duration, bool) { return time.Millisecond, true },
+	}.Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls == 1 {
+			return errors.New("slow down")
+		}
+		return nil
+	})
+	if err != nil || calls != 2 || time.Since(start) > time.Second {
+		t.Fatalf("err=%v calls=%d elapsed=%s", err, calls, time.Since(start))
+	}
+}
@@ -0,0 +1,216 @@
+// Package tracecontext implements W3C Trace Context propagation
+// (https://www.w3.org/TR/trace-context/). Inbound traceparent/tracestate
+// headers are parsed into a TraceContext carried on context.Context, and
+// outbound HTTP clients copy it back onto their requests so provider and MCP
+// calls join the caller's trace.
+package tracecontext
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	// TraceparentHeader carries version, trace ID, parent ID and flags.
+	TraceparentHeader = "Traceparent"
+	// TracestateHeader carries vendor-specific trace data.
+	TracestateHeader = "Tracestate"
+	// TraceresponseHeader echoes the server's trace context to the caller.
+	TraceresponseHeader = "Traceresponse"
+	// TraceIDHeader is a plain copy of the trace ID for log correlation.
+	TraceIDHeader = "X-Trace-Id"
+
+	// FlagSampled is the sampled bit of TraceContext.Flags.
+	FlagSampled byte = 0x01
+)
+
+// ErrInvalidTraceparent is returned for malformed traceparent values.
+var ErrInvalidTraceparent = errors.New("tracecontext: invalid traceparent")
+
+// TraceContext identifies a position in a distributed trace.
+type TraceContext struct {
+	// TraceID is 32 lowercase hex characters.
+	TraceID string
+	// ParentID is the 16 hex character ID of the calling span.
+	ParentID string
+	// Flags holds trace flags; FlagSampled marks sampled traces.
+	Flags byte
+	// State is the raw tracestate header, passed through unchanged.
+	State string
+}
+
+// New starts a sampled trace with random IDs.
+func New() TraceContext {
+	return TraceContext{TraceID: randomHex(16), ParentID: randomHex(8), Flags: FlagSampled}
+}
+
+// Parse decodes a traceparent header and attaches tracestate. Future
+// versions are accepted as long as the version 00 fields parse.
+func Parse(traceparent, tracestate string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	if !isHex(traceID, 32) || isZero(traceID) || !isHex(parentID, 16) || isZero(parentID) || !isHex(flags, 2) {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	raw, _ := hex.DecodeString(flags) //nolint:errcheck // validated by isHex
+	return TraceContext{
+		TraceID:  traceID,
+		ParentID: parentID,
+		Flags:    raw[0],
+		State:    strings.TrimSpace(tracestate),
+	}, nil
+}
+
+// IsValid reports whether tc carries usable trace and parent IDs.
+func (tc TraceContext) IsValid() bool {
+	return isHex(tc.TraceID, 32) && !isZero(tc.TraceID) && isHex(tc.ParentID, 16) && !isZero(tc.ParentID)
+}
+
+// Sampled reports whether the sampled flag is set.
+func (tc TraceContext) Sampled() bool { return tc.Flags&FlagSampled != 0 }
+
+// Child returns tc with a fresh parent ID, for a new span in the same trace.
+func (tc TraceContext) Child() TraceContext {
+	tc.ParentID = randomHex(8)
+	return tc
+}
+
+// Traceparent formats tc as a version 00 traceparent header value.
+func (tc TraceContext) Traceparent() string {
+	if !tc.IsValid() {
+		return ""
+	}
+	return "00-" + tc.TraceID + "-" + tc.ParentID + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// Extract reads traceparent/tracestate from h. ok is false when the header
+// is missing or malformed; per the spec callers then start a new trace.
+func Extract(h http.Header) (TraceContext, bool) {
+	if h == nil {
+		return TraceContext{}, false
+	}
+	tc, err := Parse(h.Get(TraceparentHeader), h.Get(TracestateHeader))
+	return tc, err == nil
+}
+
+// Inject writes tc onto h, replacing existing trace headers. Invalid
+// contexts are ignored.
+func Inject(h http.Header, tc TraceContext) {
+	if h == nil || !tc.IsValid() {
+		return
+	}
+	h.Set(TraceparentHeader, tc.Traceparent())
+	if tc.State != "" {
+		h.Set(TracestateHeader, tc.State)
+	} else {
+		h.Del(TracestateHeader)
+	}
+}
+
+type contextKey struct{}
+
+// WithContext returns ctx carrying tc.
+func WithContext(ctx context.Context, tc TraceContext) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, contextKey{}, tc)
+}
+
+// FromContext returns the trace context stored by WithContext.
+func FromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+	tc, ok := ctx.Value(contextKey{}).(TraceContext)
+	return tc, ok && tc.IsValid()
+}
+
+// Handler extracts the inbound trace context (starting a new trace when
+// absent), stores it on the request context and echoes it in the
+// Traceresponse and X-Trace-Id response headers.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc, ok := Extract(r.Header)
+		if !ok {
+			tc = New()
+		}
+		w.Header().Set(TraceresponseHeader, tc.Traceparent())
+		w.Header().Set(TraceIDHeader, tc.TraceID)
+		next.ServeHTTP(w, r.WithContext(WithContext(r.Context(), tc)))
+	})
+}
+
+// Transport injects the trace context of each request's context into its
+// headers before delegating to Base (http.DefaultTransport when nil).
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	tc, ok := FromContext(req.Context())
+	if !ok {
+		return base.RoundTrip(req)
+	}
+	clone := req.Clone(req.Context())
+	Inject(clone.Header, tc)
+	return base.RoundTrip(clone)
+}
+
+// WrapClient returns client with its transport wrapped by Transport. A nil
+// client yields a new one; an already wrapped client is returned unchanged.
+func WrapClient(client *http.Client) *http.Client {
+	if client == nil {
+		client = &http.Client{}
+	}
+	if _, ok := client.Transport.(*Transport); ok {
+		return client
+	}
+	client.Transport = &Transport{Base: client.Transport}
+	return client
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	for {
+		_, _ = rand.Read(buf) //nolint:errcheck // crypto/rand.Read never fails
+		if !isZero(hex.EncodeToString(buf)) {
+			return hex.EncodeToString(buf)
+		}
+	}
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
@@ -0,0 +1,91 @@
+package tracecontext
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const sample = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseRoundTrip(t *testing.T) {
+	tc, err := Parse(sample, "congo=t61rcWkgMzE")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.ParentID != "00f067aa0ba902b7" || !tc.Sampled() {
+		t.Fatalf("unexpected context %+v", tc)
+	}
+	if got := tc.Traceparent(); got != sample {
+		t.Fatalf("traceparent = %q", got)
+	}
+	child := tc.Child()
+	if child.TraceID != tc.TraceID || child.ParentID == tc.ParentID || !child.IsValid() {
+		t.Fatalf("unexpected child %+v", child)
+	}
+	if _, err := Parse("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra", ""); err != nil {
+		t.Fatalf("future version rejected: %v", err)
+	}
+}
+
+func TestParseRejectsMalformed(t *testing.T) {
+	for _, v := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, err := Parse(v, ""); !errors.Is(err, ErrInvalidTraceparent) {
+			t.Fatalf("Parse(%q) err = %v", v, err)
+		}
+	}
+}
+
+func TestHandlerAndTransportPropagate(t *testing.T) {
+	var outbound http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outbound = r.Header.Clone()
+	}))
+	defer upstream.Close()
+	client := WrapClient(nil)
+
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("upstream: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TraceparentHeader, sample)
+	req.Header.Set(TracestateHeader, "congo=t61rcWkgMzE")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(TraceIDHeader); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace id header = %q", got)
+	}
+	if got := outbound.Get(TraceparentHeader); got != sample {
+		t.Fatalf("outbound traceparent = %q", got)
+	}
+	if got := outbound.Get(TracestateHeader); got != "congo=t61rcWkgMzE" {
+		t.Fatalf("outbound tracestate = %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	fresh, err := Parse(rec.Header().Get(TraceresponseHeader), "")
+	if err != nil || fresh.TraceID != rec.Header().Get(TraceIDHeader) {
+		t.Fatalf("expected new trace, got %+v (%v)", fresh, err)
+	}
+	if _, ok := FromContext(context.Background()); ok {
+		t.Fatal("empty context reported a trace")
+	}
+}
@@ -0,0 +1,197 @@
+// Package flags evaluates feature flags that gate tools, models, middleware
+// and experimental behaviour per tenant or session. A Provider is consulted
+// at run start; the evaluation target travels on the run context so tools
+// and middleware can check experimental flags with Enabled and Value.
+package flags
+
+import (
+	"context"
+	"hash/fnv"
+	"slices"
+	"strings"
+)
+
+// Flag keys consulted by the runtime.
+const (
+	// ModelFlag selects the model tier ("low", "mid", "high") for runs that
+	// do not request one.
+	ModelFlag        = "model"
+	toolPrefix       = "tool."
+	middlewarePrefix = "middleware."
+)
+
+// ToolFlag is the boolean flag gating a tool; tools stay enabled unless the
+// provider reports false.
+func ToolFlag(name string) string { return toolPrefix + name }
+
+// MiddlewareFlag is the boolean flag gating a middleware by Name().
+func MiddlewareFlag(name string) string { return middlewarePrefix + name }
+
+// Target identifies who a flag is evaluated for.
+type Target struct {
+	SessionID string
+	// Tenant comes from the request's "tenant" tag.
+	Tenant string
+	// Attributes carries the remaining request tags for targeting rules.
+	Attributes map[string]string
+}
+
+// Key is the stable identity used for percentage rollouts: the tenant when
+// known, otherwise the session.
+func (t Target) Key() string {
+	if t.Tenant != "" {
+		return t.Tenant
+	}
+	return t.SessionID
+}
+
+// Provider evaluates flags. Implementations return def when a flag is
+// unknown or evaluation fails, and must be safe for concurrent use.
+type Provider interface {
+	Bool(ctx context.Context, flag string, target Target, def bool) bool
+	String(ctx context.Context, flag string, target Target, def string) string
+}
+
+// Bucket maps (flag, key) to a stable point in [0, 100) so percentage
+// rollouts keep assigning the same tenant or session to the same side.
+func Bucket(flag, key string) float64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(flag))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+	return float64(h.Sum64()%10000) / 100
+}
+
+// Rule configures one flag of a StaticProvider.
+type Rule struct {
+	// Percent enables the flag for that share (0-100) of targets, bucketed
+	// by Target.Key.
+	Percent float64
+	// Tenants are always enabled regardless of Percent.
+	Tenants []string
+	// Value is returned by String for enabled targets.
+	Value string
+}
+
+// StaticProvider serves flags from an in-memory rule set. Flags without a
+// rule evaluate to the caller's default.
+type StaticProvider map[string]Rule
+
+// Bool implements Provider.
+func (p StaticProvider) Bool(_ context.Context, flag string, target Target, def bool) bool {
+	rule, ok := p[flag]
+	if !ok {
+		return def
+	}
+	return rule.matches(flag, target)
+}
+
+// String implements Provider.
+func (p StaticProvider) String(_ context.Context, flag string, target Target, def string) string {
+	rule, ok := p[flag]
+	if !ok || !rule.matches(flag, target) {
+		return def
+	}
+	return rule.Value
+}
+
+func (r Rule) matches(flag string, target Target) bool {
+	if target.Tenant != "" && slices.Contains(r.Tenants, target.Tenant) {
+		return true
+	}
+	return r.Percent > 0 && Bucket(flag, target.Key()) < r.Percent
+}
+
+// OpenFeatureEvaluator is the slice of an OpenFeature client the adapter
+// needs. The OpenFeature Go SDK takes an EvaluationContext; a small shim
+// builds one from the targeting key and attributes:
+//
+//	func (s shim) BooleanValue(ctx context.Context, flag string, def bool, key string, attrs map[string]any) (bool, error) {
+//		return s.client.BooleanValue(ctx, flag, def, openfeature.NewEvaluationContext(key, attrs))
+//	}
+type OpenFeatureEvaluator interface {
+	BooleanValue(ctx context.Context, flag string, def bool, targetingKey string, attrs map[string]any) (bool, error)
+	StringValue(ctx context.Context, flag string, def string, targetingKey string, attrs map[string]any) (string, error)
+}
+
+// OpenFeature adapts an OpenFeature client to Provider. The targeting key is
+// Target.Key; session, tenant and attributes are passed as context
+// attributes. Evaluation errors fall back to the default.
+func OpenFeature(e OpenFeatureEvaluator) Provider {
+	return openFeatureProvider{e: e}
+}
+
+type openFeatureProvider struct {
+	e OpenFeatureEvaluator
+}
+
+func (p openFeatureProvider) Bool(ctx context.Context, flag string, target Target, def bool) bool {
+	v, err := p.e.BooleanValue(ctx, flag, def, target.Key(), attributes(target))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func (p openFeatureProvider) String(ctx context.Context, flag string, target Target, def string) string {
+	v, err := p.e.StringValue(ctx, flag, def, target.Key(), attributes(target))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func attributes(t Target) map[string]any {
+	attrs := make(map[string]any, len(t.Attributes)+2)
+	for k, v := range t.Attributes {
+		attrs[k] = v
+	}
+	if t.SessionID != "" {
+		attrs["sessionId"] = t.SessionID
+	}
+	if t.Tenant != "" {
+		attrs["tenant"] = t.Tenant
+	}
+	return attrs
+}
+
+type evaluatorKey struct{}
+
+type evaluator struct {
+	provider Provider
+	target   Target
+}
+
+// WithProvider binds p and target to ctx for Enabled and Value.
+func WithProvider(ctx context.Context, p Provider, target Target) context.Context {
+	if p == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, evaluatorKey{}, evaluator{provider: p, target: target})
+}
+
+// TargetFromContext returns the target bound by WithProvider.
+func TargetFromContext(ctx context.Context) (Target, bool) {
+	ev, ok := ctx.Value(evaluatorKey{}).(evaluator)
+	return ev.target, ok
+}
+
+// Enabled evaluates a boolean flag for the run bound to ctx, returning def
+// when no provider is bound.
+func Enabled(ctx context.Context, flag string, def bool) bool {
+	ev, ok := ctx.Value(evaluatorKey{}).(evaluator)
+	if !ok {
+		return def
+	}
+	return ev.provider.Bool(ctx, flag, ev.target, def)
+}
+
+// Value evaluates a string flag for the run bound to ctx, returning def when
+// no provider is bound.
+func Value(ctx context.Context, flag, def string) string {
+	ev, ok := ctx.Value(evaluatorKey{}).(evaluator)
+	if !ok {
+		return def
+	}
+	return strings.TrimSpace(ev.provider.String(ctx, flag, ev.target, def))
+}
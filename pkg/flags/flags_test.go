@@ -0,0 +1,75 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestStaticProviderPercentRollout(t *testing.T) {
+	p := StaticProvider{
+		ToolFlag("WebSearch"): {Percent: 5, Tenants: []string{"beta-co"}},
+		ModelFlag:             {Percent: 100, Value: "high"},
+	}
+	ctx := context.Background()
+	enabled := 0
+	for i := 0; i < 2000; i++ {
+		target := Target{SessionID: fmt.Sprintf("s-%d", i)}
+		first := p.Bool(ctx, ToolFlag("WebSearch"), target, false)
+		if first != p.Bool(ctx, ToolFlag("WebSearch"), target, false) {
+			t.Fatal("assignment must be stable")
+		}
+		if first {
+			enabled++
+		}
+	}
+	if enabled < 40 || enabled > 160 {
+		t.Fatalf("5%% rollout enabled %d of 2000", enabled)
+	}
+	if !p.Bool(ctx, ToolFlag("WebSearch"), Target{Tenant: "beta-co"}, false) {
+		t.Fatal("listed tenant should be enabled")
+	}
+	if !p.Bool(ctx, "unknown", Target{}, true) || p.String(ctx, ModelFlag, Target{SessionID: "x"}, "") != "high" {
+		t.Fatal("defaults and values not honoured")
+	}
+}
+
+type fakeOpenFeature struct {
+	key   string
+	attrs map[string]any
+}
+
+func (f *fakeOpenFeature) BooleanValue(_ context.Context, flag string, def bool, key string, attrs map[string]any) (bool, error) {
+	f.key, f.attrs = key, attrs
+	if flag == "broken" {
+		return false, errors.New("provider down")
+	}
+	return !def, nil
+}
+
+func (f *fakeOpenFeature) StringValue(_ context.Context, _ string, _ string, key string, _ map[string]any) (string, error) {
+	return "v-" + key, nil
+}
+
+func TestOpenFeatureAdapterAndContext(t *testing.T) {
+	of := &fakeOpenFeature{}
+	target := Target{SessionID: "s1", Tenant: "acme", Attributes: map[string]string{"plan": "pro"}}
+	ctx := WithProvider(context.Background(), OpenFeature(of), target)
+
+	if Enabled(ctx, "x", true) || of.key != "acme" || of.attrs["plan"] != "pro" || of.attrs["sessionId"] != "s1" {
+		t.Fatalf("unexpected evaluation key=%s attrs=%v", of.key, of.attrs)
+	}
+	if !Enabled(ctx, "broken", true) {
+		t.Fatal("errors must fall back to the default")
+	}
+	if Value(ctx, "y", "") != "v-acme" {
+		t.Fatal("string flag not evaluated")
+	}
+	if got, ok := TargetFromContext(ctx); !ok || got.SessionID != "s1" {
+		t.Fatalf("target = %+v", got)
+	}
+	if !Enabled(context.Background(), "x", true) || Value(context.Background(), "y", "d") != "d" {
+		t.Fatal("unbound context must return defaults")
+	}
+}
@@ -0,0 +1,203 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapClient speaks the small subset of IMAP4rev1 (RFC 3501) the poller
+// needs: LOGIN, SELECT, UID SEARCH, UID FETCH, UID STORE and LOGOUT.
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapResponse is one untagged response line with any literals it carried.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+func dialIMAP(ctx context.Context, cfg IMAPConfig) (*imapClient, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if cfg.Plaintext {
+		conn, err = dialer.DialContext(ctx, "tcp", cfg.Addr)
+	} else {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsConfig(cfg.TLS, cfg.Addr)}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", cfg.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("email: imap dial: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readLine()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("email: imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("email: imap greeting: %s", greeting)
+	}
+	return c, nil
+}
+
+func tlsConfig(base *tls.Config, addr string) *tls.Config {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		cfg = base.Clone()
+	}
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		cfg.ServerName = host
+	}
+	return cfg
+}
+
+func (c *imapClient) Close() error { return c.conn.Close() }
+
+func (c *imapClient) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// command sends one tagged command and collects untagged responses until the
+// tagged completion. Non-OK completions are returned as errors.
+func (c *imapClient) command(format string, args ...any) ([]imapResponse, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	cmd := fmt.Sprintf(format, args...)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, cmd); err != nil {
+		return nil, fmt.Errorf("email: imap write: %w", err)
+	}
+	verb, _, _ := strings.Cut(cmd, " ")
+	var responses []imapResponse
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, fmt.Errorf("email: imap %s: %w", verb, err)
+		}
+		if rest, ok := strings.CutPrefix(line, tag+" "); ok {
+			if !strings.HasPrefix(rest, "OK") {
+				return nil, fmt.Errorf("email: imap %s: %s", verb, rest)
+			}
+			return responses, nil
+		}
+		resp := imapResponse{line: line}
+		// A line ending in {n} announces an n-byte literal followed by the
+		// remainder of the response on further lines.
+		for {
+			size, ok := literalSize(line)
+			if !ok {
+				break
+			}
+			data := make([]byte, size)
+			if _, err := io.ReadFull(c.r, data); err != nil {
+				return nil, fmt.Errorf("email: imap %s: read literal: %w", verb, err)
+			}
+			resp.literals = append(resp.literals, data)
+			if line, err = c.readLine(); err != nil {
+				return nil, fmt.Errorf("email: imap %s: %w", verb, err)
+			}
+			resp.line += line
+		}
+		responses = append(responses, resp)
+	}
+}
+
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[open+1:len(line)-1], "+"))
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+func quoteIMAP(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func (c *imapClient) login(user, pass string) error {
+	_, err := c.command("LOGIN %s %s", quoteIMAP(user), quoteIMAP(pass))
+	return err
+}
+
+func (c *imapClient) selectMailbox(name string) error {
+	_, err := c.command("SELECT %s", quoteIMAP(name))
+	return err
+}
+
+// searchUnseen returns the UIDs of messages without the \Seen flag.
+func (c *imapClient) searchUnseen() ([]uint32, error) {
+	responses, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, resp := range responses {
+		rest, ok := strings.CutPrefix(resp.line, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Fields(rest) {
+			uid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("email: imap SEARCH: bad uid %q", field)
+			}
+			uids = append(uids, uint32(uid))
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns the full RFC 5322 message without setting \Seen.
+func (c *imapClient) fetch(uid uint32) ([]byte, error) {
+	responses, err := c.command("UID FETCH %d (BODY.PEEK[])", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, resp := range responses {
+		if strings.Contains(resp.line, "FETCH") && len(resp.literals) > 0 {
+			return resp.literals[0], nil
+		}
+	}
+	return nil, errors.New("email: imap FETCH: message body missing")
+}
+
+func (c *imapClient) markSeen(uid uint32) error {
+	_, err := c.command(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+func (c *imapClient) logout() {
+	_, _ = c.command("LOGOUT")
+}
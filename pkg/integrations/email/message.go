@@ -0,0 +1,232 @@
+package email
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// Message is a parsed inbound email.
+type Message struct {
+	ID          string // Message-ID without angle brackets
+	From        *mail.Address
+	ReplyTo     *mail.Address
+	Subject     string
+	InReplyTo   string
+	References  []string
+	AutoReply   bool // Auto-Submitted or Precedence headers mark it as automated
+	Text        string
+	Attachments []Attachment
+}
+
+// Attachment is a file carried by an inbound email.
+type Attachment struct {
+	Filename  string
+	MediaType string
+	Data      []byte
+}
+
+// ThreadID returns the Message-ID of the first message in the thread.
+func (m *Message) ThreadID() string {
+	switch {
+	case len(m.References) > 0:
+		return m.References[0]
+	case m.InReplyTo != "":
+		return m.InReplyTo
+	default:
+		return m.ID
+	}
+}
+
+// SessionID maps an email thread to an agent session.
+func SessionID(threadID string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(threadID)))
+	return "email-" + hex.EncodeToString(sum[:8])
+}
+
+var (
+	msgIDRe   = regexp.MustCompile(`<([^<>\s]+)>`)
+	htmlTagRe = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]+>`)
+	blankRe   = regexp.MustCompile(`\n{3,}`)
+	decoder   = &mime.WordDecoder{}
+)
+
+// ParseMessage parses a raw RFC 5322 message. Parts larger than maxPart bytes
+// are dropped; zero disables the limit.
+func ParseMessage(raw []byte, maxPart int) (*Message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("email: parse message: %w", err)
+	}
+	h := msg.Header
+	out := &Message{
+		ID:         firstMessageID(h.Get("Message-Id")),
+		InReplyTo:  firstMessageID(h.Get("In-Reply-To")),
+		References: messageIDs(h.Get("References")),
+	}
+	if subject, err := decoder.DecodeHeader(h.Get("Subject")); err == nil {
+		out.Subject = subject
+	} else {
+		out.Subject = h.Get("Subject")
+	}
+	if out.From, err = mail.ParseAddress(h.Get("From")); err != nil {
+		return nil, fmt.Errorf("email: parse From: %w", err)
+	}
+	if replyTo := h.Get("Reply-To"); replyTo != "" {
+		out.ReplyTo, _ = mail.ParseAddress(replyTo) //nolint:errcheck // fall back to From
+	}
+	auto := strings.ToLower(strings.TrimSpace(h.Get("Auto-Submitted")))
+	precedence := strings.ToLower(strings.TrimSpace(h.Get("Precedence")))
+	out.AutoReply = (auto != "" && auto != "no") || precedence == "bulk" || precedence == "junk" || precedence == "list"
+
+	var html string
+	err = walkPart(h, msg.Body, maxPart, func(mediaType, filename string, data []byte) {
+		switch {
+		case filename != "":
+			out.Attachments = append(out.Attachments, Attachment{Filename: filename, MediaType: mediaType, Data: data})
+		case mediaType == "text/plain" && out.Text == "":
+			out.Text = string(data)
+		case mediaType == "text/html" && html == "":
+			html = string(data)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Text == "" && html != "" {
+		out.Text = htmlToText(html)
+	}
+	out.Text = stripQuoted(strings.ReplaceAll(out.Text, "\r\n", "\n"))
+	return out, nil
+}
+
+type partHeader interface {
+	Get(key string) string
+}
+
+// walkPart decodes a MIME entity and reports every leaf part.
+func walkPart(h partHeader, body io.Reader, maxPart int, leaf func(mediaType, filename string, data []byte)) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("email: read multipart: %w", err)
+			}
+			if err := walkPart(part.Header, part, maxPart, leaf); err != nil {
+				return err
+			}
+		}
+	}
+	data, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("email: decode %s part: %w", mediaType, err)
+	}
+	if maxPart > 0 && len(data) > maxPart {
+		return nil
+	}
+	filename := ""
+	if _, dparams, err := mime.ParseMediaType(h.Get("Content-Disposition")); err == nil {
+		filename = dparams["filename"]
+	}
+	if filename == "" {
+		filename = params["name"]
+	}
+	if filename != "" {
+		if decoded, err := decoder.DecodeHeader(filename); err == nil {
+			filename = decoded
+		}
+	}
+	leaf(mediaType, filename, data)
+	return nil
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, newlineStripper{r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// newlineStripper removes CR/LF so base64 bodies wrapped at 76 columns decode.
+type newlineStripper struct{ r io.Reader }
+
+func (n newlineStripper) Read(p []byte) (int, error) {
+	count, err := n.r.Read(p)
+	out := p[:0]
+	for _, b := range p[:count] {
+		if b != '\r' && b != '\n' {
+			out = append(out, b)
+		}
+	}
+	return len(out), err
+}
+
+func firstMessageID(v string) string {
+	if ids := messageIDs(v); len(ids) > 0 {
+		return ids[0]
+	}
+	return strings.Trim(strings.TrimSpace(v), "<>")
+}
+
+func messageIDs(v string) []string {
+	var ids []string
+	for _, m := range msgIDRe.FindAllStringSubmatch(v, -1) {
+		ids = append(ids, m[1])
+	}
+	return ids
+}
+
+func htmlToText(html string) string {
+	text := strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n\n", "</div>", "\n").Replace(html)
+	text = htmlTagRe.ReplaceAllString(text, "")
+	text = strings.NewReplacer("&nbsp;", " ", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&#39;", "'", "&amp;", "&").Replace(text)
+	return strings.TrimSpace(blankRe.ReplaceAllString(text, "\n\n"))
+}
+
+// stripQuoted drops quoted history ("> " lines and the "On ... wrote:" line
+// introducing them); the thread's session already holds that context.
+func stripQuoted(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := make([]string, 0, len(lines))
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		if strings.HasSuffix(trimmed, "wrote:") && i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(nextNonEmpty(lines[i+1:])), ">") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+func nextNonEmpty(lines []string) string {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,287 @@
+// Package email turns messages in an IMAP mailbox into agent requests and
+// answers them over SMTP.
+//
+// Each email thread (identified by the first Message-ID in References) maps
+// to one agent session, so follow-up replies continue the conversation.
+// Only senders matching Config.AllowedSenders are processed.
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/core/retry"
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+const (
+	defaultPollInterval   = time.Minute
+	defaultMaxAttachment  = 10 << 20
+	defaultMailbox        = "INBOX"
+	maxInlineTextAttached = 256 << 10
+)
+
+// Runner executes agent requests; *api.Runtime implements it.
+type Runner interface {
+	Run(ctx context.Context, req api.Request) (*api.Response, error)
+}
+
+// IMAPConfig locates the mailbox to poll.
+type IMAPConfig struct {
+	Addr     string // host:port, usually :993
+	Username string
+	Password string
+	Mailbox  string // defaults to INBOX
+	TLS      *tls.Config
+	// Plaintext disables TLS; only for local test servers.
+	Plaintext bool
+}
+
+// SMTPConfig configures reply delivery.
+type SMTPConfig struct {
+	Addr     string // host:port, usually :587 (STARTTLS) or :465
+	Username string
+	Password string
+	// ImplicitTLS dials TLS directly (port 465) instead of upgrading with
+	// STARTTLS.
+	ImplicitTLS bool
+	TLS         *tls.Config
+}
+
+// Config configures a Poller.
+type Config struct {
+	IMAP IMAPConfig
+	SMTP SMTPConfig
+	// From is the address replies are sent from; mail from it is ignored.
+	From string
+	// AllowedSenders lists accepted addresses ("alice@example.com") and
+	// domains ("@example.com"); "*" accepts everyone. Required.
+	AllowedSenders []string
+	PollInterval   time.Duration // defaults to 1m
+	// MaxAttachmentBytes drops larger attachments. Defaults to 10 MiB.
+	MaxAttachmentBytes int
+	// Template and Tags are applied to every request.
+	Template string
+	Tags     map[string]string
+}
+
+// Poller processes unseen mail with a Runner.
+type Poller struct {
+	cfg     Config
+	from    *mail.Address
+	allowed []string
+	now     func() time.Time
+}
+
+// New validates cfg and returns a Poller.
+func New(cfg Config) (*Poller, error) {
+	if strings.TrimSpace(cfg.IMAP.Addr) == "" || strings.TrimSpace(cfg.SMTP.Addr) == "" {
+		return nil, errors.New("email: imap and smtp addresses are required")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("email: invalid From address: %w", err)
+	}
+	var allowed []string
+	for _, entry := range cfg.AllowedSenders {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			allowed = append(allowed, entry)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, errors.New("email: AllowedSenders is required (use \"*\" to accept any sender)")
+	}
+	if cfg.IMAP.Mailbox == "" {
+		cfg.IMAP.Mailbox = defaultMailbox
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.MaxAttachmentBytes <= 0 {
+		cfg.MaxAttachmentBytes = defaultMaxAttachment
+	}
+	return &Poller{cfg: cfg, from: from, allowed: allowed, now: time.Now}, nil
+}
+
+// Allowed reports whether mail from addr passes the sender policy.
+func (p *Poller) Allowed(addr string) bool {
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	at := strings.LastIndexByte(addr, '@')
+	for _, entry := range p.allowed {
+		switch {
+		case entry == "*":
+			return true
+		case strings.HasPrefix(entry, "@"):
+			if at >= 0 && addr[at:] == entry {
+				return true
+			}
+		case entry == addr:
+			return true
+		}
+	}
+	return false
+}
+
+// Run polls every PollInterval until ctx is cancelled. Poll errors are
+// logged and retried on the next tick.
+func (p *Poller) Run(ctx context.Context, runner Runner) error {
+	if runner == nil {
+		return errors.New("email: runner is nil")
+	}
+	for {
+		if _, err := p.Poll(ctx, runner); err != nil && ctx.Err() == nil {
+			log.Printf("email: poll: %v", err)
+		}
+		if err := retry.Sleep(ctx, p.cfg.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// Poll processes the currently unseen messages once and returns how many
+// were answered. Each message is marked \Seen before the agent runs, so a
+// crash never answers the same email twice.
+func (p *Poller) Poll(ctx context.Context, runner Runner) (int, error) {
+	client, err := dialIMAP(ctx, p.cfg.IMAP)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+	defer client.logout()
+	if err := client.login(p.cfg.IMAP.Username, p.cfg.IMAP.Password); err != nil {
+		return 0, err
+	}
+	if err := client.selectMailbox(p.cfg.IMAP.Mailbox); err != nil {
+		return 0, err
+	}
+	uids, err := client.searchUnseen()
+	if err != nil {
+		return 0, err
+	}
+	answered := 0
+	for _, uid := range uids {
+		if err := ctx.Err(); err != nil {
+			return answered, err
+		}
+		raw, err := client.fetch(uid)
+		if err != nil {
+			return answered, err
+		}
+		if err := client.markSeen(uid); err != nil {
+			return answered, err
+		}
+		msg, err := ParseMessage(raw, p.cfg.MaxAttachmentBytes)
+		if err != nil {
+			log.Printf("email: skip uid %d: %v", uid, err)
+			continue
+		}
+		if !p.accept(msg) {
+			continue
+		}
+		if err := p.answer(ctx, runner, msg); err != nil {
+			log.Printf("email: answer %q from %s: %v", msg.Subject, msg.From.Address, err)
+			continue
+		}
+		answered++
+	}
+	return answered, nil
+}
+
+func (p *Poller) accept(msg *Message) bool {
+	if msg.AutoReply || strings.EqualFold(msg.From.Address, p.from.Address) {
+		return false
+	}
+	return p.Allowed(msg.From.Address)
+}
+
+func (p *Poller) answer(ctx context.Context, runner Runner, msg *Message) error {
+	resp, err := runner.Run(ctx, p.buildRequest(msg))
+	var body string
+	switch {
+	case err != nil:
+		body = "Sorry, the request failed: " + err.Error()
+	case resp != nil && resp.Outputs["text"].Err == nil && resp.Outputs["text"].Text != "":
+		body = resp.Outputs["text"].Text
+	case resp != nil && resp.Result != nil:
+		body = resp.Result.Output
+	}
+	reply := newReply(msg, body, nil)
+	if resp != nil {
+		reply.Attachments = resp.Artifacts
+	}
+	raw, err := buildMIME(p.from, reply, p.now())
+	if err != nil {
+		return err
+	}
+	return p.cfg.SMTP.send(ctx, p.from, reply.To.Address, raw)
+}
+
+// buildRequest turns an email into a request. Images and PDFs become content
+// blocks, text attachments are inlined, other files are listed by name.
+func (p *Poller) buildRequest(msg *Message) api.Request {
+	var prompt strings.Builder
+	if msg.Subject != "" {
+		fmt.Fprintf(&prompt, "Subject: %s\n\n", msg.Subject)
+	}
+	prompt.WriteString(msg.Text)
+
+	var blocks []model.ContentBlock
+	var skipped []string
+	for _, att := range msg.Attachments {
+		switch {
+		case isImage(att.MediaType):
+			blocks = append(blocks, model.ContentBlock{Type: model.ContentBlockImage, MediaType: att.MediaType, Data: base64.StdEncoding.EncodeToString(att.Data)})
+		case att.MediaType == "application/pdf":
+			blocks = append(blocks, model.ContentBlock{Type: model.ContentBlockDocument, MediaType: att.MediaType, Data: base64.StdEncoding.EncodeToString(att.Data)})
+		case strings.HasPrefix(att.MediaType, "text/") && len(att.Data) <= maxInlineTextAttached:
+			blocks = append(blocks, model.ContentBlock{Type: model.ContentBlockText, Text: fmt.Sprintf("Attachment %s:\n%s", att.Filename, att.Data)})
+		default:
+			skipped = append(skipped, att.Filename)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(&prompt, "\n\n(Unsupported attachments not included: %s)", strings.Join(skipped, ", "))
+	}
+
+	tags := map[string]string{"email.from": msg.From.Address}
+	if msg.ID != "" {
+		tags["email.message_id"] = msg.ID
+	}
+	for k, v := range p.cfg.Tags {
+		tags[k] = v
+	}
+	return api.Request{
+		Prompt:        strings.TrimSpace(prompt.String()),
+		ContentBlocks: blocks,
+		SessionID:     SessionID(msg.ThreadID()),
+		Channels:      []string{"text"},
+		Template:      p.cfg.Template,
+		Tags:          tags,
+	}
+}
+
+func isImage(mediaType string) bool {
+	switch mediaType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+func newToken() string {
+	var buf [6]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf[:])
+}
@@ -0,0 +1,190 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// Reply is an outbound answer to a Message.
+type Reply struct {
+	To        *mail.Address
+	Subject   string
+	Body      string
+	InReplyTo string
+	// References is the full chain ending with the message being answered.
+	References  []string
+	Attachments []tool.Artifact
+}
+
+// newReply answers msg, threading it via In-Reply-To and References.
+func newReply(msg *Message, body string, artifacts []tool.Artifact) *Reply {
+	to := msg.From
+	if msg.ReplyTo != nil {
+		to = msg.ReplyTo
+	}
+	subject := msg.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	refs := append([]string(nil), msg.References...)
+	if msg.ID != "" {
+		refs = append(refs, msg.ID)
+	}
+	return &Reply{To: to, Subject: subject, Body: body, InReplyTo: msg.ID, References: refs, Attachments: artifacts}
+}
+
+// buildMIME renders the reply as an RFC 5322 message. Replies are marked
+// Auto-Submitted so well-behaved responders do not answer them.
+func buildMIME(from *mail.Address, r *Reply, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+	header("From", from.String())
+	header("To", r.To.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", r.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", "<"+newMessageID(from.Address)+">")
+	if r.InReplyTo != "" {
+		header("In-Reply-To", "<"+r.InReplyTo+">")
+	}
+	if len(r.References) > 0 {
+		header("References", "<"+strings.Join(r.References, "> <")+">")
+	}
+	header("Auto-Submitted", "auto-replied")
+	header("MIME-Version", "1.0")
+
+	if len(r.Attachments) == 0 {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, r.Body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+	buf.WriteString("\r\n")
+	text, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintable(text, r.Body); err != nil {
+		return nil, err
+	}
+	for _, art := range r.Attachments {
+		mediaType := art.MediaType
+		if mediaType == "" {
+			mediaType = "application/octet-stream"
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mediaType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": art.Name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(art.Data)
+		for len(encoded) > 76 {
+			fmt.Fprintf(part, "%s\r\n", encoded[:76])
+			encoded = encoded[76:]
+		}
+		fmt.Fprintf(part, "%s\r\n", encoded)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func newMessageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndexByte(from, '@'); at >= 0 {
+		domain = from[at+1:]
+	}
+	return fmt.Sprintf("%d.%s@%s", time.Now().UnixNano(), newToken(), domain)
+}
+
+// send delivers one message through the configured SMTP server. STARTTLS is
+// used whenever the server offers it.
+func (cfg SMTPConfig) send(ctx context.Context, from *mail.Address, to string, msg []byte) error {
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("email: smtp addr: %w", err)
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	if cfg.ImplicitTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig(cfg.TLS, cfg.Addr)}).DialContext(ctx, "tcp", cfg.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", cfg.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("email: smtp dial: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("email: smtp: %w", err)
+	}
+	defer client.Close()
+	if !cfg.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig(cfg.TLS, cfg.Addr)); err != nil {
+				return fmt.Errorf("email: smtp starttls: %w", err)
+			}
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, host)); err != nil {
+			return fmt.Errorf("email: smtp auth: %w", err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("email: smtp MAIL: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("email: smtp RCPT: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("email: smtp DATA: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("email: smtp DATA: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("email: smtp DATA: %w", err)
+	}
+	return client.Quit()
+}
@@ -0,0 +1,176 @@
+package slack
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+)
+
+// Action IDs of the permission prompt buttons.
+const (
+	ActionApprove = "agentsdk_approve"
+	ActionDeny    = "agentsdk_deny"
+)
+
+type approval struct {
+	decided chan decision
+}
+
+type decision struct {
+	allow bool
+	user  string
+}
+
+// PermissionHandler returns an api.PermissionRequestHandler that asks in the
+// originating Slack thread. Requests from sessions the bot is not serving
+// return PermissionAsk so the runtime's own approval flow applies.
+// Unanswered prompts are denied after Config.ApprovalTimeout.
+func (b *Bot) PermissionHandler() api.PermissionRequestHandler {
+	return func(ctx context.Context, req api.PermissionRequest) (coreevents.PermissionDecisionType, error) {
+		b.mu.Lock()
+		thread, ok := b.threads[req.SessionID]
+		b.mu.Unlock()
+		if !ok {
+			return coreevents.PermissionAsk, nil
+		}
+
+		id := newApprovalID()
+		pending := &approval{decided: make(chan decision, 1)}
+		b.mu.Lock()
+		b.pending[id] = pending
+		b.mu.Unlock()
+		defer func() {
+			b.mu.Lock()
+			delete(b.pending, id)
+			b.mu.Unlock()
+		}()
+
+		summary := permissionSummary(req)
+		ts, err := b.api.postMessage(ctx, b.cfg.BotToken, map[string]any{
+			"channel":   thread.channel,
+			"thread_ts": thread.ts,
+			"text":      "Permission requested: " + summary,
+			"blocks":    approvalBlocks(id, summary, req.Reason),
+		})
+		if err != nil {
+			return coreevents.PermissionDeny, err
+		}
+
+		timer := time.NewTimer(b.cfg.ApprovalTimeout)
+		defer timer.Stop()
+		var (
+			result  = coreevents.PermissionDeny
+			outcome string
+		)
+		select {
+		case d := <-pending.decided:
+			if d.allow {
+				result = coreevents.PermissionAllow
+				outcome = fmt.Sprintf(":white_check_mark: Approved by <@%s>: %s", d.user, summary)
+			} else {
+				outcome = fmt.Sprintf(":no_entry: Denied by <@%s>: %s", d.user, summary)
+			}
+		case <-timer.C:
+			outcome = ":hourglass: Timed out, denied: " + summary
+		case <-ctx.Done():
+			return coreevents.PermissionDeny, ctx.Err()
+		}
+		// Replace the buttons so the prompt cannot be answered twice.
+		if err := b.api.updateMessage(ctx, b.cfg.BotToken, map[string]any{
+			"channel": thread.channel,
+			"ts":      ts,
+			"text":    outcome,
+			"blocks":  []any{},
+		}); err != nil {
+			log.Printf("slack: update approval message: %v", err)
+		}
+		return result, nil
+	}
+}
+
+// handleInteraction resolves pending prompts from block_actions payloads.
+func (b *Bot) handleInteraction(_ context.Context, payload json.RawMessage) {
+	var body struct {
+		Type string `json:"type"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		Actions []struct {
+			ActionID string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		log.Printf("slack: decode interaction: %v", err)
+		return
+	}
+	if body.Type != "block_actions" {
+		return
+	}
+	if len(b.cfg.Approvers) > 0 && !slices.Contains(b.cfg.Approvers, body.User.ID) {
+		return
+	}
+	for _, action := range body.Actions {
+		if action.ActionID != ActionApprove && action.ActionID != ActionDeny {
+			continue
+		}
+		b.mu.Lock()
+		pending, ok := b.pending[action.Value]
+		b.mu.Unlock()
+		if !ok {
+			continue
+		}
+		select {
+		case pending.decided <- decision{allow: action.ActionID == ActionApprove, user: body.User.ID}:
+		default:
+		}
+	}
+}
+
+func permissionSummary(req api.PermissionRequest) string {
+	summary := "`" + req.ToolName + "`"
+	if target := strings.TrimSpace(req.Target); target != "" {
+		summary += " on `" + target + "`"
+	}
+	return summary
+}
+
+func approvalBlocks(id, summary, reason string) []any {
+	text := "*Permission requested:* " + summary
+	if reason = strings.TrimSpace(reason); reason != "" {
+		text += "\n" + reason
+	}
+	button := func(label, actionID, style string) map[string]any {
+		return map[string]any{
+			"type":      "button",
+			"text":      map[string]any{"type": "plain_text", "text": label},
+			"action_id": actionID,
+			"value":     id,
+			"style":     style,
+		}
+	}
+	return []any{
+		map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": text}},
+		map[string]any{"type": "actions", "elements": []any{
+			button("Approve", ActionApprove, "primary"),
+			button("Deny", ActionDeny, "danger"),
+		}},
+	}
+}
+
+func newApprovalID() string {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf[:])
+}
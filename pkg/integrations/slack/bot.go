@@ -0,0 +1,368 @@
+// Package slack connects an agent runtime to Slack over Socket Mode.
+//
+// Mentions of the bot and direct messages become api.Requests; every Slack
+// thread maps to one agent session. Replies are streamed by editing a
+// placeholder message, the final answer is rendered with the runtime's
+// "slack" output adapter, and sandbox permission prompts can be answered with
+// Approve/Deny buttons in the thread.
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/core/retry"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	defaultUpdateInterval  = time.Second
+	defaultReconnectDelay  = 2 * time.Second
+	defaultApprovalTimeout = 10 * time.Minute
+	placeholderText        = "_Thinking…_"
+)
+
+// Runner executes agent requests; *api.Runtime implements it.
+type Runner interface {
+	RunStream(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error)
+}
+
+// ChannelSettings overlays request fields for messages from one channel.
+type ChannelSettings struct {
+	// Disabled ignores messages from the channel.
+	Disabled      bool
+	Template      string
+	Model         api.ModelTier
+	ToolWhitelist []string
+	Tags          map[string]string
+	Metadata      map[string]any
+}
+
+// Config configures a Bot.
+type Config struct {
+	// AppToken is the app-level token (xapp-...) with connections:write.
+	AppToken string
+	// BotToken is the bot token (xoxb-...) used for chat.* calls.
+	BotToken   string
+	APIURL     string // defaults to DefaultAPIURL
+	HTTPClient *http.Client
+	// Channels holds per-channel overlays keyed by channel ID. The "*" entry
+	// applies to channels without their own entry.
+	Channels map[string]ChannelSettings
+	// Approvers restricts who may answer permission prompts; empty allows
+	// any member of the thread.
+	Approvers []string
+	// UpdateInterval throttles streaming message edits. Defaults to 1s.
+	UpdateInterval time.Duration
+	// ReconnectDelay is the pause before reopening a dropped connection.
+	ReconnectDelay time.Duration
+	// ApprovalTimeout denies unanswered permission prompts. Defaults to 10m.
+	ApprovalTimeout time.Duration
+}
+
+// Bot bridges Slack Socket Mode events to a Runner.
+type Bot struct {
+	cfg Config
+	api *webAPI
+
+	mu       sync.Mutex
+	threads  map[string]threadRef
+	pending  map[string]*approval
+	inflight sync.WaitGroup
+}
+
+type threadRef struct {
+	channel string
+	ts      string
+	user    string
+}
+
+// New returns a Bot. Pass PermissionHandler to api.Options before creating
+// the runtime, then call Run with it.
+func New(cfg Config) (*Bot, error) {
+	if strings.TrimSpace(cfg.AppToken) == "" || strings.TrimSpace(cfg.BotToken) == "" {
+		return nil, errors.New("slack: app token and bot token are required")
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = DefaultAPIURL
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.UpdateInterval <= 0 {
+		cfg.UpdateInterval = defaultUpdateInterval
+	}
+	if cfg.ReconnectDelay <= 0 {
+		cfg.ReconnectDelay = defaultReconnectDelay
+	}
+	if cfg.ApprovalTimeout <= 0 {
+		cfg.ApprovalTimeout = defaultApprovalTimeout
+	}
+	return &Bot{
+		cfg:     cfg,
+		api:     &webAPI{baseURL: cfg.APIURL, client: cfg.HTTPClient},
+		threads: map[string]threadRef{},
+		pending: map[string]*approval{},
+	}, nil
+}
+
+// Run serves Socket Mode events until ctx is cancelled, reconnecting when
+// Slack drops or refreshes the connection. It waits for in-flight replies
+// before returning.
+func (b *Bot) Run(ctx context.Context, runner Runner) error {
+	if runner == nil {
+		return errors.New("slack: runner is nil")
+	}
+	defer b.inflight.Wait()
+	for {
+		err := b.serve(ctx, runner)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Printf("slack: socket mode connection ended: %v", err)
+		}
+		if err := retry.Sleep(ctx, b.cfg.ReconnectDelay); err != nil {
+			return err
+		}
+	}
+}
+
+// envelope is a Socket Mode frame.
+type envelope struct {
+	EnvelopeID string          `json:"envelope_id"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+func (b *Bot) serve(ctx context.Context, runner Runner) error {
+	url, err := b.api.openConnection(ctx, b.cfg.AppToken)
+	if err != nil {
+		return err
+	}
+	wsCfg, err := websocket.NewConfig(url, "https://slack.com")
+	if err != nil {
+		return err
+	}
+	ws, err := wsCfg.DialContext(ctx)
+	if err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = ws.Close()
+		case <-stop:
+			_ = ws.Close()
+		}
+	}()
+
+	for {
+		var env envelope
+		if err := websocket.JSON.Receive(ws, &env); err != nil {
+			return err
+		}
+		// Slack redelivers envelopes that are not acknowledged promptly, so
+		// ack before doing any work.
+		if env.EnvelopeID != "" {
+			if err := websocket.JSON.Send(ws, map[string]string{"envelope_id": env.EnvelopeID}); err != nil {
+				return err
+			}
+		}
+		switch env.Type {
+		case "disconnect":
+			return nil
+		case "events_api":
+			b.handleEvent(ctx, runner, env.Payload)
+		case "interactive":
+			b.handleInteraction(ctx, env.Payload)
+		}
+	}
+}
+
+type messageEvent struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	User        string `json:"user"`
+	BotID       string `json:"bot_id"`
+	Text        string `json:"text"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+}
+
+func (b *Bot) handleEvent(ctx context.Context, runner Runner, payload json.RawMessage) {
+	var body struct {
+		Event messageEvent `json:"event"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		log.Printf("slack: decode event: %v", err)
+		return
+	}
+	ev := body.Event
+	if ev.BotID != "" || ev.Subtype != "" || ev.User == "" {
+		return
+	}
+	switch {
+	case ev.Type == "app_mention":
+	case ev.Type == "message" && ev.ChannelType == "im":
+	default:
+		return
+	}
+	settings, ok := b.channelSettings(ev.Channel)
+	if ok && settings.Disabled {
+		return
+	}
+	b.inflight.Add(1)
+	go func() {
+		defer b.inflight.Done()
+		b.respond(ctx, runner, ev, settings)
+	}()
+}
+
+func (b *Bot) channelSettings(channel string) (ChannelSettings, bool) {
+	if s, ok := b.cfg.Channels[channel]; ok {
+		return s, true
+	}
+	s, ok := b.cfg.Channels["*"]
+	return s, ok
+}
+
+var mentionRe = regexp.MustCompile(`<@[A-Z0-9]+>`)
+
+// SessionID returns the agent session used for a Slack thread.
+func SessionID(channel, threadTS string) string {
+	return "slack-" + channel + "-" + threadTS
+}
+
+func buildRequest(ev messageEvent, settings ChannelSettings) api.Request {
+	thread := ev.ThreadTS
+	if thread == "" {
+		thread = ev.TS
+	}
+	req := api.Request{
+		Prompt:        strings.TrimSpace(mentionRe.ReplaceAllString(ev.Text, "")),
+		SessionID:     SessionID(ev.Channel, thread),
+		Channels:      []string{"slack"},
+		Template:      settings.Template,
+		Model:         settings.Model,
+		ToolWhitelist: append([]string(nil), settings.ToolWhitelist...),
+		Tags:          map[string]string{"slack.channel": ev.Channel, "slack.user": ev.User},
+	}
+	for k, v := range settings.Tags {
+		req.Tags[k] = v
+	}
+	if len(settings.Metadata) > 0 {
+		req.Metadata = make(map[string]any, len(settings.Metadata))
+		for k, v := range settings.Metadata {
+			req.Metadata[k] = v
+		}
+	}
+	return req
+}
+
+func (b *Bot) respond(ctx context.Context, runner Runner, ev messageEvent, settings ChannelSettings) {
+	req := buildRequest(ev, settings)
+	if req.Prompt == "" {
+		return
+	}
+	thread := threadRef{channel: ev.Channel, ts: ev.ThreadTS, user: ev.User}
+	if thread.ts == "" {
+		thread.ts = ev.TS
+	}
+	b.mu.Lock()
+	b.threads[req.SessionID] = thread
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.threads, req.SessionID)
+		b.mu.Unlock()
+	}()
+
+	ts, err := b.api.postMessage(ctx, b.cfg.BotToken, map[string]any{
+		"channel":   thread.channel,
+		"thread_ts": thread.ts,
+		"text":      placeholderText,
+	})
+	if err != nil {
+		log.Printf("slack: post placeholder: %v", err)
+		return
+	}
+	update := func(msg map[string]any) {
+		msg["channel"] = thread.channel
+		msg["ts"] = ts
+		if err := b.api.updateMessage(ctx, b.cfg.BotToken, msg); err != nil {
+			log.Printf("slack: update message: %v", err)
+		}
+	}
+
+	events, err := runner.RunStream(ctx, req)
+	if err != nil {
+		update(map[string]any{"text": ":warning: " + err.Error()})
+		return
+	}
+	var (
+		text       strings.Builder
+		lastUpdate time.Time
+		sent       string
+		final      *api.ChannelOutput
+		runErr     string
+	)
+	for evt := range events {
+		switch evt.Type {
+		case api.EventContentBlockDelta:
+			if evt.Delta == nil || evt.Delta.Text == "" {
+				continue
+			}
+			text.WriteString(evt.Delta.Text)
+			if time.Since(lastUpdate) >= b.cfg.UpdateInterval && text.String() != sent {
+				sent = text.String()
+				lastUpdate = time.Now()
+				update(map[string]any{"text": sent})
+			}
+		case api.EventChannelOutput:
+			if out, ok := evt.Output.(api.ChannelOutput); ok && evt.Name == "slack" && out.Err == nil {
+				final = &out
+			}
+		case api.EventError:
+			runErr = strings.TrimSpace(stringify(evt.Output))
+		}
+	}
+	switch {
+	case runErr != "":
+		update(map[string]any{"text": ":warning: " + runErr})
+	case final != nil:
+		msg := map[string]any{"text": final.Text}
+		if blocks, ok := final.Payload["blocks"]; ok {
+			msg["blocks"] = blocks
+		}
+		update(msg)
+	default:
+		update(map[string]any{"text": text.String()})
+	}
+}
+
+func stringify(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case error:
+		return val.Error()
+	case nil:
+		return ""
+	default:
+		raw, _ := json.Marshal(val) //nolint:errcheck // best-effort rendering
+		return string(raw)
+	}
+}
@@ -0,0 +1,193 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+	"golang.org/x/net/websocket"
+)
+
+type fakeSlack struct {
+	mu       sync.Mutex
+	posts    []map[string]any
+	updates  []map[string]any
+	acks     []string
+	approval chan string
+	final    chan struct{}
+}
+
+func (f *fakeSlack) handler(t *testing.T, wsURL func() string) http.Handler {
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, body map[string]any) {
+		body["ok"] = true
+		_ = json.NewEncoder(w).Encode(body)
+	}
+	decode := func(r *http.Request) map[string]any {
+		var msg map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decode %s: %v", r.URL.Path, err)
+		}
+		return msg
+	}
+	mux.HandleFunc("/api/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xapp-test" {
+			t.Errorf("connections.open must use the app token, got %q", r.Header.Get("Authorization"))
+		}
+		reply(w, map[string]any{"url": wsURL()})
+	})
+	mux.HandleFunc("/api/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		msg := decode(r)
+		f.mu.Lock()
+		f.posts = append(f.posts, msg)
+		f.mu.Unlock()
+		if blocks, ok := msg["blocks"].([]any); ok && len(blocks) == 2 {
+			button := blocks[1].(map[string]any)["elements"].([]any)[0].(map[string]any)
+			f.approval <- button["value"].(string)
+		}
+		reply(w, map[string]any{"ts": "200.1"})
+	})
+	mux.HandleFunc("/api/chat.update", func(w http.ResponseWriter, r *http.Request) {
+		msg := decode(r)
+		f.mu.Lock()
+		f.updates = append(f.updates, msg)
+		f.mu.Unlock()
+		if blocks, ok := msg["blocks"].([]any); ok && len(blocks) > 0 {
+			close(f.final)
+		}
+		reply(w, map[string]any{"ts": msg["ts"]})
+	})
+	mux.Handle("/socket", websocket.Handler(func(ws *websocket.Conn) {
+		send := func(env map[string]any) {
+			if err := websocket.JSON.Send(ws, env); err != nil {
+				return
+			}
+			var ack map[string]string
+			if err := websocket.JSON.Receive(ws, &ack); err == nil {
+				f.mu.Lock()
+				f.acks = append(f.acks, ack["envelope_id"])
+				f.mu.Unlock()
+			}
+		}
+		send(map[string]any{"envelope_id": "e1", "type": "events_api", "payload": map[string]any{
+			"event": map[string]any{"type": "app_mention", "user": "U1", "text": "<@UBOT> clean the build", "ts": "100.1", "channel": "C1"},
+		}})
+		id := <-f.approval
+		send(map[string]any{"envelope_id": "e2", "type": "interactive", "payload": map[string]any{
+			"type":    "block_actions",
+			"user":    map[string]any{"id": "U2"},
+			"actions": []any{map[string]any{"action_id": ActionApprove, "value": id}},
+		}})
+		var discard any
+		_ = websocket.JSON.Receive(ws, &discard)
+	}))
+	return mux
+}
+
+type runnerFunc func(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error)
+
+func (fn runnerFunc) RunStream(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error) {
+	return fn(ctx, req)
+}
+
+func TestBotRunsThreadWithApproval(t *testing.T) {
+	fake := &fakeSlack{approval: make(chan string, 1), final: make(chan struct{})}
+	var srv *httptest.Server
+	srv = httptest.NewServer(fake.handler(t, func() string {
+		return "ws" + strings.TrimPrefix(srv.URL, "http") + "/socket"
+	}))
+	defer srv.Close()
+
+	bot, err := New(Config{
+		AppToken:       "xapp-test",
+		BotToken:       "xoxb-test",
+		APIURL:         srv.URL + "/api/",
+		Channels:       map[string]ChannelSettings{"*": {Tags: map[string]string{"team": "infra"}}},
+		UpdateInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	permit := bot.PermissionHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var (
+		gotReq      api.Request
+		gotDecision coreevents.PermissionDecisionType
+	)
+	runner := runnerFunc(func(ctx context.Context, req api.Request) (<-chan api.StreamEvent, error) {
+		gotReq = req
+		out := make(chan api.StreamEvent)
+		go func() {
+			defer close(out)
+			gotDecision, _ = permit(ctx, api.PermissionRequest{ToolName: "bash", SessionID: req.SessionID, Target: "rm -rf build"})
+			out <- api.StreamEvent{Type: api.EventContentBlockDelta, Delta: &api.Delta{Type: "text_delta", Text: "Cleaned."}}
+			out <- api.StreamEvent{Type: api.EventChannelOutput, Name: "slack", Output: api.ChannelOutput{
+				Channel: "slack", Text: "Cleaned.",
+				Payload: map[string]any{"blocks": []any{map[string]any{"type": "section"}}},
+			}}
+		}()
+		return out, nil
+	})
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- bot.Run(ctx, runner) }()
+	select {
+	case <-fake.final:
+	case <-time.After(5 * time.Second):
+		t.Fatal("final reply was not posted")
+	}
+	cancel()
+	if err := <-runErr; err != context.Canceled {
+		t.Fatalf("run returned %v", err)
+	}
+
+	if gotReq.Prompt != "clean the build" || gotReq.SessionID != "slack-C1-100.1" || gotReq.Tags["team"] != "infra" || gotReq.Tags["slack.user"] != "U1" {
+		t.Fatalf("unexpected request %+v", gotReq)
+	}
+	if gotDecision != coreevents.PermissionAllow {
+		t.Fatalf("expected approval, got %q", gotDecision)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if strings.Join(fake.acks, ",") != "e1,e2" {
+		t.Fatalf("acks = %v", fake.acks)
+	}
+	if len(fake.posts) != 2 || fake.posts[0]["thread_ts"] != "100.1" {
+		t.Fatalf("posts = %v", fake.posts)
+	}
+	var approved, final bool
+	for _, upd := range fake.updates {
+		if text, _ := upd["text"].(string); strings.Contains(text, "Approved by <@U2>") {
+			approved = true
+		}
+		if blocks, ok := upd["blocks"].([]any); ok && len(blocks) == 1 && upd["text"] == "Cleaned." {
+			final = true
+		}
+	}
+	if !approved || !final {
+		t.Fatalf("updates = %v", fake.updates)
+	}
+}
+
+func TestPermissionHandlerUnknownSessionAsks(t *testing.T) {
+	bot, err := New(Config{AppToken: "xapp", BotToken: "xoxb"})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	got, err := bot.PermissionHandler()(context.Background(), api.PermissionRequest{SessionID: "cli"})
+	if err != nil || got != coreevents.PermissionAsk {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if _, err := New(Config{BotToken: "xoxb"}); err == nil {
+		t.Fatal("expected missing app token error")
+	}
+}
@@ -0,0 +1,92 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultAPIURL is the base URL of the Slack Web API.
+const DefaultAPIURL = "https://slack.com/api/"
+
+// webAPI is a minimal Slack Web API client covering the methods the bot uses.
+type webAPI struct {
+	baseURL string
+	client  *http.Client
+}
+
+// call POSTs payload as JSON to method and decodes the response into out.
+// Slack reports failures with HTTP 200 and "ok": false.
+func (w *webAPI) call(ctx context.Context, token, method string, payload, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("slack: encode %s: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(w.baseURL, "/")+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("slack: %s: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack: %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("slack: %s: read response: %w", method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack: %s: http %d: %s", method, resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return fmt.Errorf("slack: %s: decode response: %w", method, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack: %s: %s", method, status.Error)
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return fmt.Errorf("slack: %s: decode response: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// postMessage posts into a thread and returns the message ts.
+func (w *webAPI) postMessage(ctx context.Context, token string, msg map[string]any) (string, error) {
+	var out struct {
+		TS string `json:"ts"`
+	}
+	if err := w.call(ctx, token, "chat.postMessage", msg, &out); err != nil {
+		return "", err
+	}
+	return out.TS, nil
+}
+
+func (w *webAPI) updateMessage(ctx context.Context, token string, msg map[string]any) error {
+	return w.call(ctx, token, "chat.update", msg, nil)
+}
+
+// openConnection asks for a Socket Mode WebSocket URL using the app token.
+func (w *webAPI) openConnection(ctx context.Context, appToken string) (string, error) {
+	var out struct {
+		URL string `json:"url"`
+	}
+	if err := w.call(ctx, appToken, "apps.connections.open", map[string]any{}, &out); err != nil {
+		return "", err
+	}
+	if out.URL == "" {
+		return "", fmt.Errorf("slack: apps.connections.open: empty url")
+	}
+	return out.URL, nil
+}
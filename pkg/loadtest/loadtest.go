@@ -0,0 +1,277 @@
+// Package loadtest drives Run/RunStream traffic against an in-process
+// Runtime or an HTTP deployment and reports latency, error and cost figures
+// for capacity planning. cmd/loadtest wraps it as a command.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+// ErrNoPrompts is returned when Config.Prompts is empty.
+var ErrNoPrompts = errors.New("loadtest: no prompts configured")
+
+// Call is one request issued by the harness.
+type Call struct {
+	// Seq is the zero-based sequence number of the call.
+	Seq       int
+	Prompt    string
+	SessionID string
+}
+
+// Outcome is what a Target observed for one call.
+type Outcome struct {
+	Usage model.Usage
+	// FirstEvent is the time until the first streamed event; zero for
+	// blocking calls.
+	FirstEvent time.Duration
+	StopReason string
+}
+
+// Target executes calls. Implementations must be safe for concurrent use.
+type Target interface {
+	Do(ctx context.Context, call Call) (Outcome, error)
+}
+
+// TargetFunc adapts a function to Target.
+type TargetFunc func(ctx context.Context, call Call) (Outcome, error)
+
+// Do calls f.
+func (f TargetFunc) Do(ctx context.Context, call Call) (Outcome, error) { return f(ctx, call) }
+
+// Config shapes the generated load.
+type Config struct {
+	// Concurrency is the number of parallel workers; zero uses 1.
+	Concurrency int
+	// Requests caps the total number of calls. With Duration set, whichever
+	// limit is reached first ends the test; with neither, one round of
+	// Prompts is sent.
+	Requests int
+	// Duration bounds the test wall time.
+	Duration time.Duration
+	// Prompts are sent round-robin.
+	Prompts []string
+	// SessionPrefix prefixes per-call session IDs ("<prefix>-<seq>");
+	// empty uses "loadtest". Set SharedSession to reuse one session.
+	SessionPrefix string
+	SharedSession bool
+	// Timeout bounds each call; zero leaves calls to the parent context.
+	Timeout time.Duration
+	// CostFunc prices usage; nil uses Usage.CostUSD.
+	CostFunc func(model.Usage) float64
+}
+
+// Summary holds latency percentiles.
+type Summary struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+// Report aggregates a finished test.
+type Report struct {
+	Requests     int            `json:"requests"`
+	Errors       int            `json:"errors"`
+	Elapsed      time.Duration  `json:"elapsed"`
+	Throughput   float64        `json:"throughput_rps"`
+	Latency      Summary        `json:"latency"`
+	FirstEvent   *Summary       `json:"first_event,omitempty"`
+	InputTokens  int            `json:"input_tokens"`
+	OutputTokens int            `json:"output_tokens"`
+	CostUSD      float64        `json:"cost_usd"`
+	StopReasons  map[string]int `json:"stop_reasons,omitempty"`
+	// ErrorKinds counts errors by message.
+	ErrorKinds map[string]int `json:"error_kinds,omitempty"`
+}
+
+// ErrorRate returns Errors/Requests.
+func (r *Report) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// WriteText prints a human readable summary.
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "requests:    %d (%.1f/s over %s)\n", r.Requests, r.Throughput, r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "errors:      %d (%.2f%%)\n", r.Errors, 100*r.ErrorRate())
+	writeSummary(&b, "latency:", r.Latency)
+	if r.FirstEvent != nil {
+		writeSummary(&b, "first event:", *r.FirstEvent)
+	}
+	fmt.Fprintf(&b, "tokens:      %d in / %d out\n", r.InputTokens, r.OutputTokens)
+	fmt.Fprintf(&b, "cost:        $%.4f\n", r.CostUSD)
+	for _, kind := range sortedKeys(r.ErrorKinds) {
+		fmt.Fprintf(&b, "  error x%d: %s\n", r.ErrorKinds[kind], kind)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeSummary(b *strings.Builder, label string, s Summary) {
+	fmt.Fprintf(b, "%-12s p50=%s p90=%s p99=%s max=%s mean=%s\n", label,
+		s.P50.Round(time.Millisecond), s.P90.Round(time.Millisecond), s.P99.Round(time.Millisecond),
+		s.Max.Round(time.Millisecond), s.Mean.Round(time.Millisecond))
+}
+
+type sample struct {
+	latency time.Duration
+	outcome Outcome
+	err     error
+}
+
+// Run generates load against target until the configured limits are hit or
+// ctx ends, then returns the report. Call errors are counted, not returned.
+func Run(ctx context.Context, target Target, cfg Config) (*Report, error) {
+	if target == nil {
+		return nil, errors.New("loadtest: target is nil")
+	}
+	if len(cfg.Prompts) == 0 {
+		return nil, ErrNoPrompts
+	}
+	workers := max(cfg.Concurrency, 1)
+	limit := cfg.Requests
+	if limit <= 0 && cfg.Duration <= 0 {
+		limit = len(cfg.Prompts)
+	}
+	prefix := strings.TrimSpace(cfg.SessionPrefix)
+	if prefix == "" {
+		prefix = "loadtest"
+	}
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	var (
+		next    atomic.Int64
+		mu      sync.Mutex
+		samples []sample
+		wg      sync.WaitGroup
+	)
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				seq := int(next.Add(1) - 1)
+				if limit > 0 && seq >= limit {
+					return
+				}
+				call := Call{Seq: seq, Prompt: cfg.Prompts[seq%len(cfg.Prompts)], SessionID: prefix}
+				if !cfg.SharedSession {
+					call.SessionID = fmt.Sprintf("%s-%d", prefix, seq)
+				}
+				s := do(ctx, target, call, cfg.Timeout)
+				if s.err != nil && ctx.Err() != nil {
+					// Calls cut off by the end of the test are not failures.
+					return
+				}
+				mu.Lock()
+				samples = append(samples, s)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return buildReport(samples, time.Since(start), cfg.CostFunc), nil
+}
+
+func do(ctx context.Context, target Target, call Call, timeout time.Duration) sample {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	started := time.Now()
+	out, err := target.Do(ctx, call)
+	return sample{latency: time.Since(started), outcome: out, err: err}
+}
+
+func buildReport(samples []sample, elapsed time.Duration, cost func(model.Usage) float64) *Report {
+	r := &Report{Requests: len(samples), Elapsed: elapsed, StopReasons: map[string]int{}, ErrorKinds: map[string]int{}}
+	if elapsed > 0 {
+		r.Throughput = float64(len(samples)) / elapsed.Seconds()
+	}
+	latencies := make([]time.Duration, 0, len(samples))
+	var firsts []time.Duration
+	for _, s := range samples {
+		latencies = append(latencies, s.latency)
+		if s.outcome.FirstEvent > 0 {
+			firsts = append(firsts, s.outcome.FirstEvent)
+		}
+		r.InputTokens += s.outcome.Usage.InputTokens
+		r.OutputTokens += s.outcome.Usage.OutputTokens
+		if cost != nil {
+			r.CostUSD += cost(s.outcome.Usage)
+		} else {
+			r.CostUSD += s.outcome.Usage.CostUSD
+		}
+		if s.err != nil {
+			r.Errors++
+			r.ErrorKinds[s.err.Error()]++
+			continue
+		}
+		if s.outcome.StopReason != "" {
+			r.StopReasons[s.outcome.StopReason]++
+		}
+	}
+	r.Latency = summarize(latencies)
+	if len(firsts) > 0 {
+		first := summarize(firsts)
+		r.FirstEvent = &first
+	}
+	return r
+}
+
+func summarize(values []time.Duration) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, v := range sorted {
+		total += v
+	}
+	return Summary{
+		Min:  sorted[0],
+		Mean: total / time.Duration(len(sorted)),
+		P50:  percentile(sorted, 0.50),
+		P90:  percentile(sorted, 0.90),
+		P99:  percentile(sorted, 0.99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// percentile uses the nearest-rank method on sorted values.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+)
+
+func TestRunAggregatesOutcomes(t *testing.T) {
+	var mu sync.Mutex
+	sessions := map[string]bool{}
+	target := TargetFunc(func(_ context.Context, call Call) (Outcome, error) {
+		mu.Lock()
+		sessions[call.SessionID] = true
+		mu.Unlock()
+		if call.Prompt == "fail" {
+			return Outcome{}, errors.New("boom")
+		}
+		return Outcome{Usage: model.Usage{InputTokens: 10, OutputTokens: 5}, StopReason: "end_turn"}, nil
+	})
+	report, err := Run(context.Background(), target, Config{
+		Concurrency: 3,
+		Requests:    9,
+		Prompts:     []string{"a", "b", "fail"},
+		CostFunc:    func(u model.Usage) float64 { return float64(u.InputTokens) / 100 },
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Requests != 9 || report.Errors != 3 || report.ErrorKinds["boom"] != 3 {
+		t.Fatalf("report %+v", report)
+	}
+	if report.InputTokens != 60 || report.OutputTokens != 30 || report.StopReasons["end_turn"] != 6 {
+		t.Fatalf("usage %+v", report)
+	}
+	if fmt.Sprintf("%.2f", report.CostUSD) != "0.60" || len(sessions) != 9 {
+		t.Fatalf("cost=%v sessions=%d", report.CostUSD, len(sessions))
+	}
+	var out strings.Builder
+	if err := report.WriteText(&out); err != nil || !strings.Contains(out.String(), "errors:      3 (33.33%)") {
+		t.Fatalf("text %q err=%v", out.String(), err)
+	}
+
+	if _, err := Run(context.Background(), target, Config{}); !errors.Is(err, ErrNoPrompts) {
+		t.Fatalf("expected ErrNoPrompts, got %v", err)
+	}
+}
+
+func TestRunStopsAtDuration(t *testing.T) {
+	target := TargetFunc(func(ctx context.Context, _ Call) (Outcome, error) {
+		select {
+		case <-time.After(5 * time.Millisecond):
+			return Outcome{}, nil
+		case <-ctx.Done():
+			return Outcome{}, ctx.Err()
+		}
+	})
+	report, err := Run(context.Background(), target, Config{Concurrency: 2, Duration: 50 * time.Millisecond, Prompts: []string{"p"}})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Requests == 0 || report.Errors != 0 {
+		t.Fatalf("report %+v", report)
+	}
+}
+
+func TestHTTPTargetParsesSSE(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/run/stream" || r.Header.Get("Authorization") != "Bearer t" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"type\":\"ping\"}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"message_start\"}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\n")
+		fmt.Fprint(w, "data: \"usage\":{\"input_tokens\":7,\"output_tokens\":3}}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"input_tokens\":9,\"output_tokens\":4}}\n\n")
+	}))
+	defer srv.Close()
+
+	target := HTTPTarget{BaseURL: srv.URL, Stream: true, Header: http.Header{"Authorization": {"Bearer t"}}}
+	out, err := target.Do(context.Background(), Call{Prompt: "hi", SessionID: "s"})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if out.Usage.InputTokens != 16 || out.Usage.OutputTokens != 7 || out.StopReason != "end_turn" || out.FirstEvent <= 0 {
+		t.Fatalf("outcome %+v", out)
+	}
+
+	target.Header = nil
+	if _, err := target.Do(context.Background(), Call{Prompt: "hi"}); err == nil || !strings.Contains(err.Error(), "HTTP 400") {
+		t.Fatalf("expected HTTP error, got %v", err)
+	}
+}
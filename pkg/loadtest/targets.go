@@ -0,0 +1,200 @@
+package loadtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+)
+
+// RuntimeTarget sends calls to an in-process Runtime.
+type RuntimeTarget struct {
+	Runtime *api.Runtime
+	// Stream uses RunStream and records time to first event.
+	Stream bool
+	// Request is the template for every call; Prompt and SessionID are
+	// overwritten.
+	Request api.Request
+}
+
+// Do implements Target.
+func (t RuntimeTarget) Do(ctx context.Context, call Call) (Outcome, error) {
+	if t.Runtime == nil {
+		return Outcome{}, errors.New("loadtest: runtime is nil")
+	}
+	req := t.Request
+	req.Prompt = call.Prompt
+	req.SessionID = call.SessionID
+	if !t.Stream {
+		resp, err := t.Runtime.Run(ctx, req)
+		if err != nil {
+			return Outcome{}, err
+		}
+		if resp == nil || resp.Result == nil {
+			return Outcome{}, nil
+		}
+		return Outcome{Usage: resp.Result.Usage, StopReason: resp.Result.StopReason}, nil
+	}
+	started := time.Now()
+	events, err := t.Runtime.RunStream(ctx, req)
+	if err != nil {
+		return Outcome{}, err
+	}
+	var acc streamAccumulator
+	for evt := range events {
+		acc.add(evt, started)
+	}
+	return acc.outcome()
+}
+
+// HTTPTarget posts calls to a server exposing the /v1/run and /v1/run/stream
+// JSON endpoints (see examples/03-http).
+type HTTPTarget struct {
+	// BaseURL is the server root, e.g. http://localhost:8080.
+	BaseURL string
+	// Stream posts to /v1/run/stream and parses the SSE response.
+	Stream bool
+	// Header is added to every request (e.g. Authorization).
+	Header http.Header
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Do implements Target.
+func (t HTTPTarget) Do(ctx context.Context, call Call) (Outcome, error) {
+	path := "/v1/run"
+	if t.Stream {
+		path = "/v1/run/stream"
+	}
+	body, err := json.Marshal(map[string]string{"prompt": call.Prompt, "session_id": call.SessionID})
+	if err != nil {
+		return Outcome{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(t.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return Outcome{}, err
+	}
+	for k, vs := range t.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	started := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Outcome{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Outcome{}, fmt.Errorf("loadtest: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if !t.Stream {
+		var payload struct {
+			StopReason string `json:"stop_reason"`
+			Usage      struct {
+				InputTokens  int
+				OutputTokens int
+				CostUSD      float64
+			} `json:"usage"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+			return Outcome{}, fmt.Errorf("loadtest: decode response: %w", err)
+		}
+		out := Outcome{StopReason: payload.StopReason}
+		out.Usage.InputTokens = payload.Usage.InputTokens
+		out.Usage.OutputTokens = payload.Usage.OutputTokens
+		out.Usage.CostUSD = payload.Usage.CostUSD
+		return out, nil
+	}
+	return readSSE(resp.Body, started)
+}
+
+// readSSE parses "data:" frames of api.StreamEvent JSON until the stream
+// ends. Multi-line data fields are joined per the SSE spec.
+func readSSE(r io.Reader, started time.Time) (Outcome, error) {
+	var acc streamAccumulator
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var data []string
+	flush := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		raw := strings.Join(data, "\n")
+		data = data[:0]
+		var evt api.StreamEvent
+		if err := json.Unmarshal([]byte(raw), &evt); err != nil {
+			return fmt.Errorf("loadtest: decode event: %w", err)
+		}
+		acc.add(evt, started)
+		return nil
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := flush(); err != nil {
+				return Outcome{}, err
+			}
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Outcome{}, err
+	}
+	if err := flush(); err != nil {
+		return Outcome{}, err
+	}
+	return acc.outcome()
+}
+
+// streamAccumulator folds stream events into an Outcome.
+type streamAccumulator struct {
+	out    Outcome
+	seen   bool
+	errMsg string
+}
+
+func (a *streamAccumulator) add(evt api.StreamEvent, started time.Time) {
+	if evt.Type == api.EventPing {
+		return
+	}
+	if !a.seen {
+		a.seen = true
+		a.out.FirstEvent = time.Since(started)
+	}
+	if evt.Type == api.EventMessageDelta {
+		if evt.Usage != nil {
+			a.out.Usage.InputTokens += evt.Usage.InputTokens
+			a.out.Usage.OutputTokens += evt.Usage.OutputTokens
+		}
+		if evt.Delta != nil && evt.Delta.StopReason != "" {
+			a.out.StopReason = evt.Delta.StopReason
+		}
+	}
+	if evt.Type == api.EventError && a.errMsg == "" {
+		a.errMsg = fmt.Sprint(evt.Output)
+	}
+}
+
+func (a *streamAccumulator) outcome() (Outcome, error) {
+	if a.errMsg != "" {
+		return a.out, errors.New(a.errMsg)
+	}
+	return a.out, nil
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/url"
-	"os/exec"
 	"strings"
 	"time"
 
 	coreevents "github.com/cexll/agentsdk-go/pkg/core/events"
+	"github.com/cexll/agentsdk-go/pkg/core/tracecontext"
 	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -41,6 +41,7 @@ type (
 	ListToolsResult             = mcpsdk.ListToolsResult
 	Content                     = mcpsdk.Content
 	TextContent                 = mcpsdk.TextContent
+	ImageContent                = mcpsdk.ImageContent
 	InitializeParams            = mcpsdk.InitializeParams
 	InitializeResult            = mcpsdk.InitializeResult
 	ServerCapabilities          = mcpsdk.ServerCapabilities
@@ -337,14 +338,15 @@ func buildSessionTransport(ctx context.Context, spec string) (Transport, error)
 	return buildStdioTransport(ctx, spec)
 }
 
-func buildStdioTransport(ctx context.Context, cmdSpec string) (Transport, error) {
-	cmdSpec = strings.TrimSpace(cmdSpec)
+// buildStdioTransport splits cmdSpec on whitespace; use NewStdioTransport
+// for arguments that contain spaces. The process outlives ctx, which only
+// bounds the dial, and stops when the session closes.
+func buildStdioTransport(_ context.Context, cmdSpec string) (Transport, error) {
 	parts := strings.Fields(cmdSpec)
 	if len(parts) == 0 {
 		return nil, fmt.Errorf("mcp stdio command is empty")
 	}
-	command := exec.CommandContext(nonNilContext(ctx), parts[0], parts[1:]...) // #nosec G204
-	return &CommandTransport{Command: command}, nil
+	return NewStdioTransport(parts[0], parts[1:], nil)
 }
 
 func buildSSETransport(endpoint string, allowSchemeGuess bool) (Transport, error) {
@@ -352,7 +354,7 @@ func buildSSETransport(endpoint string, allowSchemeGuess bool) (Transport, error
 	if err != nil {
 		return nil, fmt.Errorf("invalid SSE endpoint: %w", err)
 	}
-	return &SSEClientTransport{Endpoint: normalized}, nil
+	return NewSSETransport(normalized, tracecontext.WrapClient(nil)), nil
 }
 
 func buildStreamableTransport(endpoint string) (Transport, error) {
@@ -360,7 +362,7 @@ func buildStreamableTransport(endpoint string) (Transport, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid streamable endpoint: %w", err)
 	}
-	return &StreamableClientTransport{Endpoint: normalized}, nil
+	return &StreamableClientTransport{Endpoint: normalized, HTTPClient: tracecontext.WrapClient(nil)}, nil
 }
 
 func parseHTTPFamilySpec(spec string) (kind string, endpoint string, matched bool, err error) {
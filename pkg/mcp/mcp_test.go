@@ -218,7 +218,7 @@ func TestBuildMCPTransport(t *testing.T) {
 				if len(tt.wantArgs) > 0 && !equalStrings(v.Command.Args, tt.wantArgs) {
 					t.Fatalf("args = %v want %v", v.Command.Args, tt.wantArgs)
 				}
-			case *SSEClientTransport:
+			case *SSETransport:
 				if tt.wantType != "sse" {
 					t.Fatalf("unexpected transport %T", tr)
 				}
@@ -671,3 +671,25 @@ func TestNonNilContextNil(t *testing.T) {
 		t.Fatalf("expected non-nil context")
 	}
 }
+
+func TestNewStdioTransportEnv(t *testing.T) {
+	if _, err := NewStdioTransport("  ", nil, nil); err == nil {
+		t.Fatal("expected empty command error")
+	}
+	tr, err := NewStdioTransport("server", []string{"--root", "my dir"}, map[string]string{"TOKEN": "x", "PATH": "/opt/bin"})
+	if err != nil {
+		t.Fatalf("transport: %v", err)
+	}
+	if got := tr.Command.Args; len(got) != 3 || got[2] != "my dir" {
+		t.Fatalf("args = %v", got)
+	}
+	var paths []string
+	for _, kv := range tr.Command.Env {
+		if strings.HasPrefix(kv, "PATH=") {
+			paths = append(paths, kv)
+		}
+	}
+	if len(paths) != 1 || paths[0] != "PATH=/opt/bin" || tr.Command.Env[len(tr.Command.Env)-1] != "TOKEN=x" {
+		t.Fatalf("env not merged: %v", tr.Command.Env)
+	}
+}
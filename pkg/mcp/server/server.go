@@ -0,0 +1,330 @@
+// Package server exposes an agentsdk-go runtime as an MCP server, so other
+// agent hosts can use it as a tool provider.
+//
+// Registered tools are served under their own names. Skills become
+// "skill__<name>" tools and subagents "agent__<name>" tools; both take a
+// "prompt" argument. Tool calls pass through the runtime's hooks, permission
+// rules and sandbox, and subagent calls are full runtime runs.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/runtime/skills"
+	"github.com/cexll/agentsdk-go/pkg/runtime/subagents"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// SkillToolPrefix prefixes the tools that run skills.
+	SkillToolPrefix = "skill__"
+	// SubagentToolPrefix prefixes the tools that run subagents.
+	SubagentToolPrefix = "agent__"
+)
+
+// Runtime is the part of an agent runtime a Server serves; *api.Runtime
+// implements it.
+type Runtime interface {
+	Tools(ctx context.Context) []tool.Tool
+	CallTool(ctx context.Context, sessionID, name string, params map[string]any) (*tool.ToolResult, error)
+	Skills() []skills.Definition
+	RunSkill(ctx context.Context, name, prompt string) (skills.Result, error)
+	Subagents() []subagents.Definition
+	Run(ctx context.Context, req api.Request) (*api.Response, error)
+}
+
+// Options configures a Server.
+type Options struct {
+	// Name and Version identify the server to clients. They default to
+	// "agentsdk-go" and "dev".
+	Name    string
+	Version string
+	// Tools limits the served tools to these names; empty serves all.
+	Tools []string
+	// NoSkills and NoSubagents hide skills and subagents.
+	NoSkills    bool
+	NoSubagents bool
+}
+
+// Server serves a runtime over MCP.
+type Server struct {
+	rt   Runtime
+	opts Options
+	srv  *mcpsdk.Server
+
+	mu     sync.Mutex
+	served map[string]struct{}
+}
+
+// New builds a server for rt and registers its current tools, skills and
+// subagents. Call Refresh after the runtime's tools change.
+func New(ctx context.Context, rt Runtime, opts Options) (*Server, error) {
+	if rt == nil {
+		return nil, errors.New("mcp server: runtime is nil")
+	}
+	if opts.Name == "" {
+		opts.Name = "agentsdk-go"
+	}
+	if opts.Version == "" {
+		opts.Version = "dev"
+	}
+	s := &Server{
+		rt:     rt,
+		opts:   opts,
+		srv:    mcpsdk.NewServer(&mcpsdk.Implementation{Name: opts.Name, Version: opts.Version}, nil),
+		served: map[string]struct{}{},
+	}
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MCPServer returns the underlying MCP server, for transports other than
+// stdio and HTTP.
+func (s *Server) MCPServer() *mcpsdk.Server { return s.srv }
+
+// ServeStdio serves one client over stdin and stdout until ctx is cancelled
+// or the client disconnects.
+func (s *Server) ServeStdio(ctx context.Context) error {
+	return s.srv.Run(ctx, &mcpsdk.StdioTransport{})
+}
+
+// Handler serves the streamable HTTP transport.
+func (s *Server) Handler() http.Handler {
+	return mcpsdk.NewStreamableHTTPHandler(func(*http.Request) *mcpsdk.Server { return s.srv }, nil)
+}
+
+// Refresh re-reads the runtime's tools, skills and subagents. Connected
+// clients are notified when the list changes.
+func (s *Server) Refresh(ctx context.Context) error {
+	type entry struct {
+		tool    *mcpsdk.Tool
+		handler mcpsdk.ToolHandler
+	}
+	var entries []entry
+	allowed := toSet(s.opts.Tools)
+	for _, impl := range s.rt.Tools(ctx) {
+		name := impl.Name()
+		if len(allowed) > 0 {
+			if _, ok := allowed[name]; !ok {
+				continue
+			}
+		}
+		schema, err := inputSchema(impl.Schema())
+		if err != nil {
+			return fmt.Errorf("mcp server: tool %s: %w", name, err)
+		}
+		entries = append(entries, entry{
+			tool:    &mcpsdk.Tool{Name: name, Description: impl.Description(), InputSchema: schema},
+			handler: s.callTool(name),
+		})
+	}
+	if !s.opts.NoSkills {
+		for _, def := range s.rt.Skills() {
+			entries = append(entries, entry{
+				tool:    &mcpsdk.Tool{Name: SkillToolPrefix + def.Name, Description: def.Description, InputSchema: promptSchema(false)},
+				handler: s.runSkill(def.Name),
+			})
+		}
+	}
+	if !s.opts.NoSubagents {
+		for _, def := range s.rt.Subagents() {
+			entries = append(entries, entry{
+				tool:    &mcpsdk.Tool{Name: SubagentToolPrefix + def.Name, Description: def.Description, InputSchema: promptSchema(true)},
+				handler: s.runSubagent(def.Name),
+			})
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		next[e.tool.Name] = struct{}{}
+	}
+	var stale []string
+	for name := range s.served {
+		if _, ok := next[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	if len(stale) > 0 {
+		s.srv.RemoveTools(stale...)
+	}
+	for _, e := range entries {
+		s.srv.AddTool(e.tool, e.handler)
+	}
+	s.served = next
+	return nil
+}
+
+// sessionID maps an MCP session to a runtime session, so hooks and audit
+// records of one client stay together.
+func sessionID(req *mcpsdk.CallToolRequest) string {
+	if req != nil && req.Session != nil && req.Session.ID() != "" {
+		return "mcp-" + req.Session.ID()
+	}
+	return "mcp"
+}
+
+func (s *Server) callTool(name string) mcpsdk.ToolHandler {
+	return func(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+		var params map[string]any
+		if raw := req.Params.Arguments; len(raw) > 0 {
+			if err := json.Unmarshal(raw, &params); err != nil {
+				return errorResult(fmt.Errorf("decode arguments: %w", err)), nil
+			}
+		}
+		res, err := s.rt.CallTool(ctx, sessionID(req), name, params)
+		if err != nil {
+			if res != nil && res.ErrorDetail != nil {
+				return errorResult(errors.New(tool.ErrorContent(res.ErrorDetail, res.Output))), nil
+			}
+			return errorResult(err), nil
+		}
+		out := &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: res.Output}}}
+		if res.Data != nil {
+			out.StructuredContent = structured(res.Data)
+		}
+		return out, nil
+	}
+}
+
+type promptArgs struct {
+	Prompt    string `json:"prompt"`
+	SessionID string `json:"session_id"`
+}
+
+func decodePrompt(req *mcpsdk.CallToolRequest) (promptArgs, error) {
+	var args promptArgs
+	if raw := req.Params.Arguments; len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return args, fmt.Errorf("decode arguments: %w", err)
+		}
+	}
+	return args, nil
+}
+
+func (s *Server) runSkill(name string) mcpsdk.ToolHandler {
+	return func(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+		args, err := decodePrompt(req)
+		if err != nil {
+			return errorResult(err), nil
+		}
+		res, err := s.rt.RunSkill(ctx, name, args.Prompt)
+		if err != nil {
+			return errorResult(err), nil
+		}
+		return textResult(outputText(res.Output)), nil
+	}
+}
+
+func (s *Server) runSubagent(name string) mcpsdk.ToolHandler {
+	return func(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+		args, err := decodePrompt(req)
+		if err != nil {
+			return errorResult(err), nil
+		}
+		if strings.TrimSpace(args.Prompt) == "" {
+			return errorResult(errors.New("prompt is required")), nil
+		}
+		resp, err := s.rt.Run(ctx, api.Request{Prompt: args.Prompt, SessionID: args.SessionID, TargetSubagent: name})
+		if err != nil {
+			return errorResult(err), nil
+		}
+		var text string
+		if resp != nil && resp.Result != nil {
+			text = resp.Result.Output
+		}
+		return textResult(text), nil
+	}
+}
+
+// outputText renders a skill output: strings as is, anything else as JSON.
+func outputText(v any) string {
+	switch out := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return out
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(raw)
+}
+
+func textResult(text string) *mcpsdk.CallToolResult {
+	return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: text}}}
+}
+
+func errorResult(err error) *mcpsdk.CallToolResult {
+	res := textResult(err.Error())
+	res.IsError = true
+	return res
+}
+
+// structured returns data as MCP structured content, which must be a JSON
+// object; other values are wrapped as {"result": data}.
+func structured(data any) any {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var obj map[string]any
+	if json.Unmarshal(raw, &obj) == nil && obj != nil {
+		return obj
+	}
+	return map[string]any{"result": json.RawMessage(raw)}
+}
+
+// inputSchema converts a tool schema to the JSON object MCP expects. Tools
+// without a schema accept any object.
+func inputSchema(schema *tool.JSONSchema) (map[string]any, error) {
+	out := map[string]any{}
+	if schema != nil {
+		raw, err := json.Marshal(schema)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &out); err != nil {
+			return nil, err
+		}
+	}
+	out["type"] = "object"
+	return out, nil
+}
+
+func promptSchema(required bool) map[string]any {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"prompt":     map[string]any{"type": "string", "description": "Instruction for the skill or subagent."},
+			"session_id": map[string]any{"type": "string", "description": "Runtime session to continue; subagents only."},
+		},
+	}
+	if required {
+		schema["required"] = []string{"prompt"}
+	}
+	return schema
+}
+
+func toSet(names []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			set[name] = struct{}{}
+		}
+	}
+	return set
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/api"
+	"github.com/cexll/agentsdk-go/pkg/runtime/skills"
+	"github.com/cexll/agentsdk-go/pkg/runtime/subagents"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+type echoTool struct{ name string }
+
+func (t echoTool) Name() string        { return t.name }
+func (t echoTool) Description() string { return "echoes text" }
+func (t echoTool) Schema() *tool.JSONSchema {
+	return &tool.JSONSchema{Type: "object", Properties: map[string]any{"text": map[string]any{"type": "string"}}, Required: []string{"text"}}
+}
+func (t echoTool) Execute(_ context.Context, params map[string]any) (*tool.ToolResult, error) {
+	return &tool.ToolResult{Success: true, Output: params["text"].(string)}, nil
+}
+
+type fakeRuntime struct {
+	tools    []tool.Tool
+	sessions []string
+	runs     []api.Request
+}
+
+func (f *fakeRuntime) Tools(context.Context) []tool.Tool { return f.tools }
+
+func (f *fakeRuntime) CallTool(ctx context.Context, sessionID, name string, params map[string]any) (*tool.ToolResult, error) {
+	f.sessions = append(f.sessions, sessionID)
+	for _, impl := range f.tools {
+		if impl.Name() == name {
+			if params["text"] == "fail" {
+				err := errors.New("denied")
+				return &tool.ToolResult{IsError: true, Error: err}, err
+			}
+			res, err := impl.Execute(ctx, params)
+			if res != nil {
+				res.Data = map[string]any{"len": len(res.Output)}
+			}
+			return res, err
+		}
+	}
+	return nil, errors.New("unknown tool")
+}
+
+func (f *fakeRuntime) Skills() []skills.Definition {
+	return []skills.Definition{{Name: "summarize", Description: "summarizes"}}
+}
+
+func (f *fakeRuntime) RunSkill(_ context.Context, name, prompt string) (skills.Result, error) {
+	return skills.Result{Skill: name, Output: map[string]any{"prompt": prompt}}, nil
+}
+
+func (f *fakeRuntime) Subagents() []subagents.Definition {
+	return []subagents.Definition{{Name: "explore", Description: "explores"}}
+}
+
+func (f *fakeRuntime) Run(_ context.Context, req api.Request) (*api.Response, error) {
+	f.runs = append(f.runs, req)
+	return &api.Response{Result: &api.Result{Output: "explored " + req.Prompt}}, nil
+}
+
+func connect(t *testing.T, srv *Server) *mcpsdk.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+	serverT, clientT := mcpsdk.NewInMemoryTransports()
+	ss, err := srv.MCPServer().Connect(ctx, serverT, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ss.Close() })
+	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "test", Version: "v0"}, nil)
+	cs, err := client.Connect(ctx, clientT, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cs.Close() })
+	return cs
+}
+
+func toolNames(t *testing.T, cs *mcpsdk.ClientSession) []string {
+	t.Helper()
+	list, err := cs.ListTools(context.Background(), nil)
+	require.NoError(t, err)
+	var names []string
+	for _, tl := range list.Tools {
+		names = append(names, tl.Name)
+	}
+	return names
+}
+
+func text(t *testing.T, res *mcpsdk.CallToolResult) string {
+	t.Helper()
+	require.Len(t, res.Content, 1)
+	return res.Content[0].(*mcpsdk.TextContent).Text
+}
+
+func TestServerServesToolsSkillsAndSubagents(t *testing.T) {
+	rt := &fakeRuntime{tools: []tool.Tool{echoTool{name: "echo"}}}
+	srv, err := New(context.Background(), rt, Options{})
+	require.NoError(t, err)
+	cs := connect(t, srv)
+	ctx := context.Background()
+
+	require.ElementsMatch(t, []string{"echo", "skill__summarize", "agent__explore"}, toolNames(t, cs))
+
+	res, err := cs.CallTool(ctx, &mcpsdk.CallToolParams{Name: "echo", Arguments: map[string]any{"text": "hi"}})
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+	require.Equal(t, "hi", text(t, res))
+	require.Equal(t, map[string]any{"len": float64(2)}, res.StructuredContent)
+	require.Equal(t, "mcp", rt.sessions[0], "in-memory sessions have no id")
+
+	res, err = cs.CallTool(ctx, &mcpsdk.CallToolParams{Name: "echo", Arguments: map[string]any{"text": "fail"}})
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+	require.Contains(t, text(t, res), "denied")
+
+	res, err = cs.CallTool(ctx, &mcpsdk.CallToolParams{Name: "skill__summarize", Arguments: map[string]any{"prompt": "notes"}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"prompt":"notes"}`, text(t, res))
+
+	res, err = cs.CallTool(ctx, &mcpsdk.CallToolParams{Name: "agent__explore", Arguments: map[string]any{"prompt": "repo", "session_id": "s1"}})
+	require.NoError(t, err)
+	require.Equal(t, "explored repo", text(t, res))
+	require.Equal(t, api.Request{Prompt: "repo", SessionID: "s1", TargetSubagent: "explore"}, rt.runs[0])
+
+	res, err = cs.CallTool(ctx, &mcpsdk.CallToolParams{Name: "agent__explore", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+}
+
+func TestServerRefreshAndFilter(t *testing.T) {
+	rt := &fakeRuntime{tools: []tool.Tool{echoTool{name: "echo"}, echoTool{name: "other"}}}
+	srv, err := New(context.Background(), rt, Options{Tools: []string{"echo", "later"}, NoSkills: true, NoSubagents: true})
+	require.NoError(t, err)
+	cs := connect(t, srv)
+	require.Equal(t, []string{"echo"}, toolNames(t, cs))
+
+	rt.tools = []tool.Tool{echoTool{name: "later"}}
+	require.NoError(t, srv.Refresh(context.Background()))
+	require.Equal(t, []string{"later"}, toolNames(t, cs))
+}
+
+func TestServerHTTPHandler(t *testing.T) {
+	srv, err := New(context.Background(), &fakeRuntime{tools: []tool.Tool{echoTool{name: "echo"}}}, Options{NoSkills: true, NoSubagents: true})
+	require.NoError(t, err)
+	httpSrv := httptest.NewServer(srv.Handler())
+	defer httpSrv.Close()
+
+	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "test", Version: "v0"}, nil)
+	cs, err := client.Connect(context.Background(), &mcpsdk.StreamableClientTransport{Endpoint: httpSrv.URL}, nil)
+	require.NoError(t, err)
+	defer cs.Close()
+	res, err := cs.CallTool(context.Background(), &mcpsdk.CallToolParams{Name: "echo", Arguments: map[string]any{"text": "over http"}})
+	require.NoError(t, err)
+	require.Equal(t, "over http", text(t, res))
+}
+
+func TestInputSchemaDefaultsToObject(t *testing.T) {
+	schema, err := inputSchema(nil)
+	require.NoError(t, err)
+	raw, _ := json.Marshal(schema)
+	require.JSONEq(t, `{"type":"object"}`, string(raw))
+
+	_, err = New(context.Background(), nil, Options{})
+	require.Error(t, err)
+}
@@ -0,0 +1,415 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+)
+
+// Reconnect defaults for SSETransport.
+const (
+	DefaultSSEMaxReconnects  = 5
+	DefaultSSEReconnectDelay = 500 * time.Millisecond
+
+	maxSSEReconnectDelay = 30 * time.Second
+)
+
+var errSSEMissingEndpoint = errors.New("mcp sse: stream did not announce a message endpoint")
+
+// SSETransport is a client transport for MCP servers that speak the HTTP+SSE
+// protocol: a long-lived GET delivers server messages as "message" events and
+// client messages are POSTed to the URL announced by the first "endpoint"
+// event.
+//
+// Unlike SSEClientTransport it survives dropped streams. When the GET ends
+// without the connection being closed it reconnects with exponential backoff,
+// sending the last event id it saw as Last-Event-ID so servers that keep an
+// event log can replay what was missed. A server "retry:" field replaces the
+// initial delay, and an "endpoint" event on the new stream moves subsequent
+// POSTs. Writes issued while the stream is down wait for it to come back.
+type SSETransport struct {
+	Endpoint   string
+	HTTPClient *http.Client
+	// MaxReconnects bounds consecutive failed reconnect attempts before the
+	// connection is closed. Zero uses DefaultSSEMaxReconnects; negative
+	// disables reconnection.
+	MaxReconnects int
+	// ReconnectDelay is the first backoff delay, doubled per failed attempt
+	// up to 30s. Zero uses DefaultSSEReconnectDelay.
+	ReconnectDelay time.Duration
+}
+
+// NewSSETransport returns a reconnecting SSE transport for endpoint. A nil
+// client uses http.DefaultClient.
+func NewSSETransport(endpoint string, client *http.Client) *SSETransport {
+	return &SSETransport{Endpoint: endpoint, HTTPClient: client}
+}
+
+// Connect opens the event stream and waits for the endpoint event. ctx bounds
+// only the handshake; the stream lives until the connection is closed.
+func (t *SSETransport) Connect(ctx context.Context) (Connection, error) {
+	if t == nil {
+		return nil, errors.New("mcp sse transport is nil")
+	}
+	base, err := url.Parse(t.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	client := t.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	maxReconnects := t.MaxReconnects
+	if maxReconnects == 0 {
+		maxReconnects = DefaultSSEMaxReconnects
+	}
+	delay := t.ReconnectDelay
+	if delay <= 0 {
+		delay = DefaultSSEReconnectDelay
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	c := &sseConn{
+		client:        client,
+		endpoint:      base,
+		maxReconnects: maxReconnects,
+		delay:         delay,
+		ctx:           streamCtx,
+		cancel:        cancel,
+		incoming:      make(chan []byte, 100),
+		done:          make(chan struct{}),
+		live:          make(chan struct{}),
+	}
+
+	stop := context.AfterFunc(ctx, cancel)
+	body, err := c.open()
+	if err != nil {
+		stop()
+		cancel()
+		return nil, err
+	}
+	events := newSSEReader(body)
+	evt, err := events.next()
+	if !stop() {
+		_ = body.Close()
+		cancel()
+		return nil, ctx.Err()
+	}
+	if err == nil && evt.name != "endpoint" {
+		err = fmt.Errorf("%w: first event is %q", errSSEMissingEndpoint, evt.name)
+	} else if err != nil {
+		err = fmt.Errorf("%w: %w", errSSEMissingEndpoint, err)
+	} else {
+		err = c.handle(evt)
+	}
+	if err != nil {
+		_ = body.Close()
+		cancel()
+		return nil, err
+	}
+
+	c.body = body
+	close(c.live)
+	go c.run(events)
+	return c, nil
+}
+
+type sseConn struct {
+	client        *http.Client
+	endpoint      *url.URL
+	maxReconnects int
+	delay         time.Duration
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	incoming chan []byte
+	done     chan struct{}
+
+	mu          sync.Mutex
+	body        io.ReadCloser
+	msgEndpoint *url.URL
+	lastEventID string
+	retry       time.Duration
+	live        chan struct{} // closed while the stream is connected
+	closed      bool
+}
+
+func (c *sseConn) SessionID() string { return "" }
+
+func (c *sseConn) Read(ctx context.Context) (jsonrpc.Message, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, io.EOF
+	case data := <-c.incoming:
+		return jsonrpc.DecodeMessage(data)
+	}
+}
+
+func (c *sseConn) Write(ctx context.Context, msg jsonrpc.Message) error {
+	data, err := jsonrpc.EncodeMessage(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	live := c.live
+	c.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+		return io.EOF
+	case <-live:
+	}
+
+	c.mu.Lock()
+	target := c.msgEndpoint.String()
+	c.mu.Unlock()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to write: %s", resp.Status)
+	}
+	return nil
+}
+
+func (c *sseConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.cancel()
+	if c.body != nil {
+		_ = c.body.Close()
+	}
+	close(c.done)
+	return nil
+}
+
+// run pumps events until the stream ends, then reconnects. The connection is
+// closed once reconnecting gives up.
+func (c *sseConn) run(events *sseReader) {
+	defer c.Close()
+	for {
+		for {
+			evt, err := events.next()
+			if err != nil {
+				break
+			}
+			if err := c.handle(evt); err != nil {
+				return
+			}
+		}
+		if !c.markDown() {
+			return
+		}
+		body, err := c.reconnect()
+		if err != nil {
+			return
+		}
+		events = newSSEReader(body)
+	}
+}
+
+// handle records the event id and retry hint, then routes the payload.
+func (c *sseConn) handle(evt sseEvent) error {
+	c.mu.Lock()
+	if evt.hasID {
+		c.lastEventID = evt.id
+	}
+	if evt.retry > 0 {
+		c.retry = evt.retry
+	}
+	c.mu.Unlock()
+
+	switch evt.name {
+	case "endpoint":
+		target, err := c.endpoint.Parse(strings.TrimSpace(string(evt.data)))
+		if err != nil {
+			return fmt.Errorf("invalid message endpoint: %w", err)
+		}
+		c.mu.Lock()
+		c.msgEndpoint = target
+		c.mu.Unlock()
+	case "", "message":
+		if len(evt.data) == 0 {
+			return nil
+		}
+		select {
+		case c.incoming <- evt.data:
+		case <-c.done:
+			return io.EOF
+		}
+	}
+	return nil
+}
+
+// markDown releases the dead stream and makes writers wait for the next one.
+// It reports false when the connection has been closed.
+func (c *sseConn) markDown() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	if c.body != nil {
+		_ = c.body.Close()
+		c.body = nil
+	}
+	c.live = make(chan struct{})
+	return true
+}
+
+func (c *sseConn) reconnect() (io.ReadCloser, error) {
+	if c.maxReconnects < 0 {
+		return nil, errors.New("mcp sse: reconnect disabled")
+	}
+	c.mu.Lock()
+	delay := c.delay
+	if c.retry > 0 {
+		delay = c.retry
+	}
+	c.mu.Unlock()
+
+	var lastErr error
+	for attempt := 0; attempt < c.maxReconnects; attempt++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.done:
+			timer.Stop()
+			return nil, io.EOF
+		case <-timer.C:
+		}
+		if delay *= 2; delay > maxSSEReconnectDelay {
+			delay = maxSSEReconnectDelay
+		}
+
+		body, err := c.open()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			_ = body.Close()
+			return nil, io.EOF
+		}
+		c.body = body
+		close(c.live)
+		c.mu.Unlock()
+		return body, nil
+	}
+	return nil, fmt.Errorf("mcp sse: reconnect failed after %d attempts: %w", c.maxReconnects, lastErr)
+}
+
+func (c *sseConn) open() (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, c.endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	c.mu.Lock()
+	if c.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", c.lastEventID)
+	}
+	c.mu.Unlock()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("mcp sse: unexpected status %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+type sseEvent struct {
+	name  string
+	id    string
+	hasID bool
+	data  []byte
+	retry time.Duration
+}
+
+// sseReader parses a text/event-stream body. Unlike a bufio.Scanner it has
+// no line length limit, since a single message event can carry a large tool
+// result.
+type sseReader struct {
+	r *bufio.Reader
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{r: bufio.NewReader(r)}
+}
+
+// next returns the next dispatched event. A partial event at end of stream is
+// dropped, as the specification requires.
+func (s *sseReader) next() (sseEvent, error) {
+	var (
+		evt     sseEvent
+		data    [][]byte
+		pending bool
+	)
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return sseEvent{}, io.EOF
+			}
+			return sseEvent{}, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if line == "" {
+			if !pending {
+				continue
+			}
+			evt.data = bytes.Join(data, []byte("\n"))
+			return evt, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		pending = true
+		switch field {
+		case "event":
+			evt.name = value
+		case "data":
+			data = append(data, []byte(value))
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				evt.id, evt.hasID = value, true
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				evt.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+)
+
+func TestSSETransportResumesWithLastEventID(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		gets     int
+		resumeID string
+		posted   []string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gets++
+		n := gets
+		if n > 1 {
+			resumeID = r.Header.Get("Last-Event-ID")
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		if n == 1 {
+			fmt.Fprint(w, "event: endpoint\ndata: /messages?session=1\n\n")
+			fmt.Fprint(w, ": keepalive\nretry: 5\nid: 1\nevent: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"first\"}\n\n")
+			flusher.Flush()
+			return // drop the stream
+		}
+		fmt.Fprint(w, "event: endpoint\ndata: /messages?session=2\n\n")
+		fmt.Fprint(w, "id: 2\ndata: {\"jsonrpc\":\"2.0\",\n")
+		fmt.Fprint(w, "data: \"method\":\"second\"}\n\n")
+		flusher.Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		posted = append(posted, r.URL.RawQuery+" "+string(body))
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	transport := &SSETransport{Endpoint: ts.URL + "/sse", ReconnectDelay: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := transport.Connect(ctx)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	for _, want := range []string{"first", "second"} {
+		msg, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("read %s: %v", want, err)
+		}
+		req, ok := msg.(*jsonrpc.Request)
+		if !ok || req.Method != want {
+			t.Fatalf("got %#v, want request %q", msg, want)
+		}
+	}
+
+	if err := conn.Write(ctx, &jsonrpc.Request{Method: "ping"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if gets != 2 || resumeID != "1" {
+		t.Fatalf("gets=%d Last-Event-ID=%q, want 2 and %q", gets, resumeID, "1")
+	}
+	if len(posted) != 1 || !strings.HasPrefix(posted[0], "session=2 ") || !strings.Contains(posted[0], `"ping"`) {
+		t.Fatalf("posted = %q, want ping on the resumed endpoint", posted)
+	}
+}
+
+func TestSSETransportGivesUpAfterMaxReconnects(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu   sync.Mutex
+		gets int
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gets++
+		n := gets
+		mu.Unlock()
+		if n > 1 {
+			http.Error(w, "gone", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: endpoint\ndata: /messages\n\n")
+	}))
+	defer ts.Close()
+
+	transport := &SSETransport{Endpoint: ts.URL, MaxReconnects: 2, ReconnectDelay: time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := transport.Connect(ctx)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if _, err := conn.Read(ctx); !errors.Is(err, io.EOF) {
+		t.Fatalf("read err = %v, want EOF", err)
+	}
+	if err := conn.Write(ctx, &jsonrpc.Request{Method: "ping"}); !errors.Is(err, io.EOF) {
+		t.Fatalf("write err = %v, want EOF", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if gets != 3 {
+		t.Fatalf("gets = %d, want initial + 2 reconnects", gets)
+	}
+}
+
+func TestSSETransportRequiresEndpointEvent(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message\ndata: {}\n\n")
+	}))
+	defer ts.Close()
+
+	_, err := NewSSETransport(ts.URL, nil).Connect(context.Background())
+	if !errors.Is(err, errSSEMissingEndpoint) {
+		t.Fatalf("err = %v, want errSSEMissingEndpoint", err)
+	}
+}
@@ -0,0 +1,48 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// NewStdioTransport returns a transport that starts command with args and
+// exchanges newline-delimited JSON-RPC messages over its stdin and stdout.
+// env entries are added to the inherited environment, overriding duplicates.
+//
+// The process is not tied to the dial context: it runs until the session is
+// closed, which closes its stdin and terminates it if it does not exit.
+func NewStdioTransport(command string, args []string, env map[string]string) (*CommandTransport, error) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil, fmt.Errorf("mcp stdio command is empty")
+	}
+	cmd := exec.Command(command, args...) // #nosec G204
+	if len(env) > 0 {
+		cmd.Env = stdioEnv(os.Environ(), env)
+	}
+	return &CommandTransport{Command: cmd}, nil
+}
+
+func stdioEnv(base []string, overrides map[string]string) []string {
+	out := make([]string, 0, len(base)+len(overrides))
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := overrides[key]; !ok {
+			out = append(out, kv)
+		}
+	}
+	keys := make([]string, 0, len(overrides))
+	for k := range overrides {
+		if strings.TrimSpace(k) != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out = append(out, k+"="+overrides[k])
+	}
+	return out
+}
@@ -35,6 +35,10 @@ type ToolCall struct {
 	Name      string
 	Arguments map[string]any
 	Result    string
+	// IsError marks Result as a failed call (see tool.ErrorContent).
+	IsError bool `json:",omitempty"`
+	// ResultBlocks carries images and documents returned with Result.
+	ResultBlocks []ContentBlock `json:",omitempty"`
 }
 
 // CloneMessage performs a deep clone of a model.Message, duplicating nested
@@ -64,7 +68,7 @@ func cloneToolCalls(calls []ToolCall) []ToolCall {
 	}
 	out := make([]ToolCall, len(calls))
 	for i, call := range calls {
-		out[i] = ToolCall{ID: call.ID, Name: call.Name, Arguments: cloneMap(call.Arguments), Result: call.Result}
+		out[i] = ToolCall{ID: call.ID, Name: call.Name, Arguments: cloneMap(call.Arguments), Result: call.Result, IsError: call.IsError, ResultBlocks: cloneContentBlocks(call.ResultBlocks)}
 	}
 	return out
 }
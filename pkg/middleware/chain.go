@@ -41,45 +41,33 @@ func NewChain(mw []Middleware, opts ...ChainOption) *Chain {
 	return c
 }
 
-// Use appends middleware at runtime.
+// Use appends middleware at runtime. The slice is replaced rather than grown
+// in place so Execute can iterate a snapshot without copying it.
 func (c *Chain) Use(m Middleware) {
 	if m == nil {
 		return
 	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.middlewares = append(c.middlewares, m)
+	next := make([]Middleware, len(c.middlewares), len(c.middlewares)+1)
+	copy(next, c.middlewares)
+	c.middlewares = append(next, m)
 }
 
 // Execute runs the requested stage on all middleware in order. It stops on
 // the first error and returns it.
 func (c *Chain) Execute(ctx context.Context, stage Stage, st *State) error {
 	c.mu.RLock()
-	mws := make([]Middleware, len(c.middlewares))
-	copy(mws, c.middlewares)
+	mws := c.middlewares
 	c.mu.RUnlock()
 
 	for _, mw := range mws {
 		var err error
-		exec := func(ctx context.Context) error {
-			switch stage {
-			case StageBeforeAgent:
-				return mw.BeforeAgent(ctx, st)
-			case StageBeforeModel:
-				return mw.BeforeModel(ctx, st)
-			case StageAfterModel:
-				return mw.AfterModel(ctx, st)
-			case StageBeforeTool:
-				return mw.BeforeTool(ctx, st)
-			case StageAfterTool:
-				return mw.AfterTool(ctx, st)
-			case StageAfterAgent:
-				return mw.AfterAgent(ctx, st)
-			default:
-				return fmt.Errorf("middleware: unknown stage %d", stage)
-			}
+		if c.timeout <= 0 {
+			err = runStage(ctx, stage, mw, st)
+		} else {
+			err = c.runWithTimeout(ctx, stage, mw, st)
 		}
-		err = c.runWithTimeout(ctx, exec, mw)
 		if err != nil {
 			return fmt.Errorf("middleware %s failed: %w", middlewareName(mw), err)
 		}
@@ -87,18 +75,33 @@ func (c *Chain) Execute(ctx context.Context, stage Stage, st *State) error {
 	return nil
 }
 
-func (c *Chain) runWithTimeout(ctx context.Context, fn func(context.Context) error, mw Middleware) error {
-	if c.timeout <= 0 {
-		return fn(ctx)
+func runStage(ctx context.Context, stage Stage, mw Middleware, st *State) error {
+	switch stage {
+	case StageBeforeAgent:
+		return mw.BeforeAgent(ctx, st)
+	case StageBeforeModel:
+		return mw.BeforeModel(ctx, st)
+	case StageAfterModel:
+		return mw.AfterModel(ctx, st)
+	case StageBeforeTool:
+		return mw.BeforeTool(ctx, st)
+	case StageAfterTool:
+		return mw.AfterTool(ctx, st)
+	case StageAfterAgent:
+		return mw.AfterAgent(ctx, st)
+	default:
+		return fmt.Errorf("middleware: unknown stage %d", stage)
 	}
+}
 
+func (c *Chain) runWithTimeout(ctx context.Context, stage Stage, mw Middleware, st *State) error {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
 	done := make(chan error, 1)
 	go func() {
 		defer close(done)
-		done <- fn(ctx)
+		done <- runStage(ctx, stage, mw, st)
 	}()
 
 	select {
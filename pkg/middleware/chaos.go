@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"sync"
+)
+
+// ErrChaos is wrapped by every failure injected by Chaos.
+var ErrChaos = errors.New("middleware: chaos fault injected")
+
+// ChaosFault names a failure mode Chaos can inject.
+type ChaosFault string
+
+const (
+	// FaultProviderOverload fails a model call with an HTTP 529 overloaded
+	// error before it is sent.
+	FaultProviderOverload ChaosFault = "provider_overload"
+	// FaultStreamTruncated fails a model call after the response arrived,
+	// as if the stream ended early.
+	FaultStreamTruncated ChaosFault = "stream_truncated"
+	// FaultToolTimeout fails a non-MCP tool call with a deadline error.
+	FaultToolTimeout ChaosFault = "tool_timeout"
+	// FaultMCPDisconnect fails an MCP tool call as if the server went away.
+	FaultMCPDisconnect ChaosFault = "mcp_disconnect"
+)
+
+// ChaosStateKey records the fault injected into the current run in
+// State.Values.
+const ChaosStateKey = "chaos.fault"
+
+// ChaosConfig sets the probability (0..1) of each fault per opportunity:
+// model calls for provider faults, tool calls for tool faults.
+type ChaosConfig struct {
+	ProviderOverload float64
+	StreamTruncation float64
+	ToolTimeout      float64
+	MCPDisconnect    float64
+	// Tools limits tool faults to these tool names; empty targets every tool.
+	Tools []string
+	// IsMCPTool reports whether a tool is served over MCP; nil treats names
+	// of the form "server__tool" as MCP tools.
+	IsMCPTool func(name string) bool
+	// Seed makes injection reproducible; zero seeds randomly.
+	Seed uint64
+}
+
+// ChaosError is returned for injected faults. It unwraps to ErrChaos and to
+// the error the real failure would produce (context.DeadlineExceeded for
+// tool timeouts, io.ErrUnexpectedEOF for truncated streams), and reports an
+// HTTP status for provider faults so model.IsTransientError treats it like
+// the real thing.
+type ChaosError struct {
+	Fault      ChaosFault
+	StatusCode int
+	Tool       string
+	cause      error
+}
+
+func (e *ChaosError) Error() string {
+	msg := fmt.Sprintf("chaos: %s", e.Fault)
+	if e.Tool != "" {
+		msg += " (" + e.Tool + ")"
+	}
+	if e.cause != nil {
+		msg += ": " + e.cause.Error()
+	}
+	return msg
+}
+
+// Unwrap exposes ErrChaos and the simulated cause.
+func (e *ChaosError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{ErrChaos}
+	}
+	return []error{ErrChaos, e.cause}
+}
+
+// HTTPStatusCode returns the simulated status, zero for non-HTTP faults.
+func (e *ChaosError) HTTPStatusCode() int { return e.StatusCode }
+
+// errMCPDisconnected simulates a closed MCP transport.
+var errMCPDisconnected = errors.New("mcp: connection closed")
+
+// Chaos is a middleware that injects failures at configurable rates, for
+// checking that retry, failover and budget handling around the runtime
+// holds up under partial failure. Do not install it in production.
+type Chaos struct {
+	cfg   ChaosConfig
+	tools map[string]struct{}
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	injected map[ChaosFault]int
+}
+
+// NewChaos builds a Chaos middleware.
+func NewChaos(cfg ChaosConfig) *Chaos {
+	c := &Chaos{cfg: cfg, injected: map[ChaosFault]int{}}
+	if cfg.Seed != 0 {
+		c.rng = rand.New(rand.NewPCG(cfg.Seed, cfg.Seed))
+	}
+	if len(cfg.Tools) > 0 {
+		c.tools = make(map[string]struct{}, len(cfg.Tools))
+		for _, name := range cfg.Tools {
+			c.tools[strings.TrimSpace(name)] = struct{}{}
+		}
+	}
+	return c
+}
+
+// Injected returns how many times each fault has fired.
+func (c *Chaos) Injected() map[ChaosFault]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[ChaosFault]int, len(c.injected))
+	for k, v := range c.injected {
+		out[k] = v
+	}
+	return out
+}
+
+func (c *Chaos) Name() string { return "chaos" }
+
+func (c *Chaos) BeforeAgent(context.Context, *State) error { return nil }
+func (c *Chaos) AfterAgent(context.Context, *State) error  { return nil }
+func (c *Chaos) AfterTool(context.Context, *State) error   { return nil }
+
+func (c *Chaos) BeforeModel(_ context.Context, st *State) error {
+	if !c.roll(c.cfg.ProviderOverload) {
+		return nil
+	}
+	return c.inject(st, &ChaosError{Fault: FaultProviderOverload, StatusCode: 529, cause: errors.New("overloaded_error")})
+}
+
+func (c *Chaos) AfterModel(_ context.Context, st *State) error {
+	if !c.roll(c.cfg.StreamTruncation) {
+		return nil
+	}
+	return c.inject(st, &ChaosError{Fault: FaultStreamTruncated, cause: io.ErrUnexpectedEOF})
+}
+
+func (c *Chaos) BeforeTool(_ context.Context, st *State) error {
+	if st == nil {
+		return nil
+	}
+	name, _ := toolCallPayload(st.ToolCall)["name"].(string)
+	if c.tools != nil {
+		if _, ok := c.tools[name]; !ok {
+			return nil
+		}
+	}
+	if c.isMCPTool(name) {
+		if !c.roll(c.cfg.MCPDisconnect) {
+			return nil
+		}
+		return c.inject(st, &ChaosError{Fault: FaultMCPDisconnect, Tool: name, cause: errMCPDisconnected})
+	}
+	if !c.roll(c.cfg.ToolTimeout) {
+		return nil
+	}
+	return c.inject(st, &ChaosError{Fault: FaultToolTimeout, Tool: name, cause: context.DeadlineExceeded})
+}
+
+func (c *Chaos) isMCPTool(name string) bool {
+	if c.cfg.IsMCPTool != nil {
+		return c.cfg.IsMCPTool(name)
+	}
+	return strings.Contains(name, "__")
+}
+
+func (c *Chaos) roll(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rng != nil {
+		return c.rng.Float64() < p
+	}
+	return rand.Float64() < p
+}
+
+func (c *Chaos) inject(st *State, err *ChaosError) error {
+	c.mu.Lock()
+	c.injected[err.Fault]++
+	c.mu.Unlock()
+	if st != nil {
+		ensureStateValues(st)
+		st.Values[ChaosStateKey] = err.Fault
+	}
+	return err
+}
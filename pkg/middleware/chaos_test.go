@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+func TestChaosInjectsClassifiedFaults(t *testing.T) {
+	ctx := context.Background()
+	c := NewChaos(ChaosConfig{ProviderOverload: 1, StreamTruncation: 1, ToolTimeout: 1, MCPDisconnect: 1, Seed: 7})
+
+	st := &State{}
+	err := c.BeforeModel(ctx, st)
+	var chaosErr *ChaosError
+	if !errors.As(err, &chaosErr) || chaosErr.StatusCode != 529 || !errors.Is(err, ErrChaos) {
+		t.Fatalf("overload err=%v", err)
+	}
+	if !model.IsTransientError(err) {
+		t.Fatal("injected overload should look transient to retry logic")
+	}
+	if st.Values[ChaosStateKey] != FaultProviderOverload {
+		t.Fatalf("state %v", st.Values)
+	}
+	if err := c.AfterModel(ctx, st); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("truncation err=%v", err)
+	}
+	st.ToolCall = tool.Call{Name: "bash"}
+	if err := c.BeforeTool(ctx, st); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("timeout err=%v", err)
+	}
+	st.ToolCall = tool.Call{Name: "time__now"}
+	if err := c.BeforeTool(ctx, st); !errors.As(err, &chaosErr) || chaosErr.Fault != FaultMCPDisconnect || chaosErr.Tool != "time__now" {
+		t.Fatalf("mcp err=%v", err)
+	}
+	got := c.Injected()
+	for _, f := range []ChaosFault{FaultProviderOverload, FaultStreamTruncated, FaultToolTimeout, FaultMCPDisconnect} {
+		if got[f] != 1 {
+			t.Fatalf("injected %v", got)
+		}
+	}
+}
+
+func TestChaosRatesAndToolFilter(t *testing.T) {
+	ctx := context.Background()
+	off := NewChaos(ChaosConfig{})
+	if err := off.BeforeModel(ctx, &State{}); err != nil {
+		t.Fatalf("zero rate injected %v", err)
+	}
+
+	filtered := NewChaos(ChaosConfig{ToolTimeout: 1, Tools: []string{"read"}})
+	if err := filtered.BeforeTool(ctx, &State{ToolCall: tool.Call{Name: "bash"}}); err != nil {
+		t.Fatalf("filtered tool injected %v", err)
+	}
+
+	run := func() []bool {
+		c := NewChaos(ChaosConfig{ProviderOverload: 0.5, Seed: 42})
+		out := make([]bool, 32)
+		for i := range out {
+			out[i] = c.BeforeModel(ctx, &State{}) != nil
+		}
+		return out
+	}
+	a, b := run(), run()
+	hits := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatal("seeded chaos should be reproducible")
+		}
+		if a[i] {
+			hits++
+		}
+	}
+	if hits == 0 || hits == len(a) {
+		t.Fatalf("expected a mix of faults, got %d/%d", hits, len(a))
+	}
+}
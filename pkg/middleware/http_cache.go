@@ -0,0 +1,394 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHTTPCacheTTL        = 30 * time.Second
+	defaultHTTPCacheMaxEntries = 1024
+	defaultHTTPCacheBodyLimit  = 1 << 20 // 1 MiB per cached request/response body
+)
+
+// HTTPCacheableHeader marks a POST request as idempotent so its response may
+// be cached, e.g. dry runs or template renders. GET and HEAD are always
+// eligible.
+const HTTPCacheableHeader = "X-Agentsdk-Cacheable"
+
+// HTTPCacheConfig configures HTTPCache.
+type HTTPCacheConfig struct {
+	// TTL is how long a response stays fresh; zero uses 30s.
+	TTL time.Duration
+	// Routes overrides TTL per URL path prefix; the longest matching prefix
+	// wins. A non-positive duration disables caching for that prefix.
+	Routes map[string]time.Duration
+	// MaxEntries bounds the cache (least recently used entries are evicted);
+	// zero uses 1024.
+	MaxEntries int
+	// MaxBodyBytes bounds cacheable request and response bodies; zero uses
+	// 1 MiB. Larger exchanges bypass the cache.
+	MaxBodyBytes int64
+	// Vary lists request headers that become part of the cache key, e.g.
+	// "Authorization" when responses are per caller.
+	Vary []string
+}
+
+// HTTPCache is an http.Handler middleware that stores successful responses
+// to idempotent requests, tags them with strong ETags and answers matching
+// If-None-Match requests with 304. Identical concurrent misses are collapsed
+// into a single upstream call, so polling clients cost one run per TTL.
+type HTTPCache struct {
+	cfg HTTPCacheConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List
+	inflight map[string]*httpCacheCall
+}
+
+type httpCacheEntry struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	etag    string
+	stored  time.Time
+	expires time.Time
+}
+
+type httpCacheCall struct {
+	done  chan struct{}
+	entry *httpCacheEntry
+}
+
+// NewHTTPCache builds an empty cache.
+func NewHTTPCache(cfg HTTPCacheConfig) *HTTPCache {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultHTTPCacheTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaultHTTPCacheMaxEntries
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = defaultHTTPCacheBodyLimit
+	}
+	return &HTTPCache{
+		cfg:      cfg,
+		now:      time.Now,
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+		inflight: map[string]*httpCacheCall{},
+	}
+}
+
+// Handler wraps next with the cache.
+func (c *HTTPCache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ttl := c.ttl(r)
+		if ttl <= 0 || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, ok := c.key(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if entry := c.lookup(key); entry != nil {
+			c.serve(w, r, entry)
+			return
+		}
+
+		c.mu.Lock()
+		if call, ok := c.inflight[key]; ok {
+			c.mu.Unlock()
+			select {
+			case <-call.done:
+			case <-r.Context().Done():
+				return
+			}
+			if call.entry != nil {
+				c.serve(w, r, call.entry)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		call := &httpCacheCall{done: make(chan struct{})}
+		c.inflight[key] = call
+		c.mu.Unlock()
+
+		defer func() {
+			c.mu.Lock()
+			delete(c.inflight, key)
+			c.mu.Unlock()
+			close(call.done)
+		}()
+
+		rec := &httpCacheRecorder{w: w, header: http.Header{}, limit: c.cfg.MaxBodyBytes}
+		next.ServeHTTP(rec, r)
+		entry := c.store(key, ttl, rec)
+		if entry == nil {
+			rec.flushThrough()
+			return
+		}
+		call.entry = entry
+		c.serve(w, r, entry)
+	})
+}
+
+// Purge drops every cached response whose key path starts with prefix; an
+// empty prefix clears the cache.
+func (c *HTTPCache) Purge(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, el := range c.entries {
+		_, path, _ := strings.Cut(key, " ")
+		if strings.HasPrefix(path, prefix) {
+			c.lru.Remove(el)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Len reports the number of cached responses.
+func (c *HTTPCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *HTTPCache) ttl(r *http.Request) time.Duration {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		if v, err := strconv.ParseBool(r.Header.Get(HTTPCacheableHeader)); err != nil || !v {
+			return 0
+		}
+	default:
+		return 0
+	}
+	if strings.Contains(r.Header.Get("Cache-Control"), "no-store") {
+		return 0
+	}
+	ttl, matched := c.cfg.TTL, -1
+	for prefix, d := range c.cfg.Routes {
+		if strings.HasPrefix(r.URL.Path, prefix) && len(prefix) > matched {
+			ttl, matched = d, len(prefix)
+		}
+	}
+	return ttl
+}
+
+// key identifies a request by method class, URI, varied headers and body
+// digest. The request body is restored so next can still read it.
+func (c *HTTPCache) key(r *http.Request) (string, bool) {
+	method := http.MethodGet // HEAD shares GET entries
+	if r.Method == http.MethodPost {
+		method = http.MethodPost
+	}
+	var b strings.Builder
+	b.WriteString(method)
+	b.WriteByte(' ')
+	b.WriteString(r.URL.RequestURI())
+	for _, name := range c.cfg.Vary {
+		b.WriteString("\n" + name + ":" + strings.Join(r.Header.Values(name), ","))
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(io.LimitReader(r.Body, c.cfg.MaxBodyBytes+1))
+		if err != nil {
+			return "", false
+		}
+		if int64(len(body)) > c.cfg.MaxBodyBytes {
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			return "", false
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		b.WriteString("\nbody:" + hex.EncodeToString(sum[:]))
+	}
+	return b.String(), true
+}
+
+func (c *HTTPCache) lookup(key string) *httpCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := el.Value.(*httpCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return entry
+}
+
+// store caches complete 200 responses that did not opt out via no-store.
+func (c *HTTPCache) store(key string, ttl time.Duration, rec *httpCacheRecorder) *httpCacheEntry {
+	if rec.bypass || rec.status() != http.StatusOK {
+		return nil
+	}
+	cc := rec.header.Get("Cache-Control")
+	if strings.Contains(cc, "no-store") || rec.header.Get("Set-Cookie") != "" {
+		return nil
+	}
+	etag := rec.header.Get("ETag")
+	if etag == "" {
+		sum := sha256.Sum256(rec.body.Bytes())
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+	now := c.now()
+	entry := &httpCacheEntry{
+		key:     key,
+		status:  http.StatusOK,
+		header:  rec.header.Clone(),
+		body:    bytes.Clone(rec.body.Bytes()),
+		etag:    etag,
+		stored:  now,
+		expires: now.Add(ttl),
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.lru.Remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.cfg.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*httpCacheEntry).key)
+	}
+	return entry
+}
+
+func (c *HTTPCache) serve(w http.ResponseWriter, r *http.Request, entry *httpCacheEntry) {
+	h := w.Header()
+	for k, v := range entry.header {
+		h[k] = append([]string(nil), v...)
+	}
+	now := c.now()
+	remaining := int(entry.expires.Sub(now).Round(time.Second) / time.Second)
+	h.Set("ETag", entry.etag)
+	h.Set("Cache-Control", "private, max-age="+strconv.Itoa(max(remaining, 0)))
+	h.Set("Age", strconv.Itoa(int(now.Sub(entry.stored)/time.Second)))
+	if etagMatches(r.Header.Get("If-None-Match"), entry.etag) {
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Length", strconv.Itoa(len(entry.body)))
+	w.WriteHeader(entry.status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(entry.body)
+	}
+}
+
+// etagMatches implements the weak comparison If-None-Match requires.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// httpCacheRecorder buffers a response for caching. When the handler streams
+// (Flush) or outgrows the body limit, the recorder switches to pass-through
+// and the response is not cached.
+type httpCacheRecorder struct {
+	w       http.ResponseWriter
+	header  http.Header
+	code    int
+	body    bytes.Buffer
+	limit   int64
+	bypass  bool
+	written bool
+}
+
+func (r *httpCacheRecorder) Header() http.Header {
+	if r.bypass {
+		return r.w.Header()
+	}
+	return r.header
+}
+
+func (r *httpCacheRecorder) WriteHeader(code int) {
+	if r.bypass {
+		if !r.written {
+			r.written = true
+			r.w.WriteHeader(code)
+		}
+		return
+	}
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+func (r *httpCacheRecorder) Write(p []byte) (int, error) {
+	if !r.bypass && int64(r.body.Len()+len(p)) > r.limit {
+		r.flushThrough()
+	}
+	if r.bypass {
+		if !r.written {
+			r.WriteHeader(http.StatusOK)
+		}
+		return r.w.Write(p)
+	}
+	return r.body.Write(p)
+}
+
+func (r *httpCacheRecorder) Flush() {
+	r.flushThrough()
+	if f, ok := r.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *httpCacheRecorder) status() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
+
+// flushThrough writes everything buffered so far to the client and makes
+// further writes go straight through.
+func (r *httpCacheRecorder) flushThrough() {
+	if r.bypass {
+		return
+	}
+	r.bypass = true
+	dst := r.w.Header()
+	for k, v := range r.header {
+		dst[k] = v
+	}
+	r.WriteHeader(r.status())
+	if r.body.Len() > 0 {
+		_, _ = r.w.Write(r.body.Bytes())
+		r.body.Reset()
+	}
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPCacheServesETagsAndNotModified(t *testing.T) {
+	var calls atomic.Int32
+	cache := NewHTTPCache(HTTPCacheConfig{TTL: time.Minute, Routes: map[string]time.Duration{"/live": 0}})
+	now := time.Unix(1000, 0)
+	cache.now = func() time.Time { return now }
+	h := cache.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"n":%d,"body":%q}`, n, body)
+	}))
+	do := func(method, path, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := do(http.MethodGet, "/v1/capabilities", "", nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Header().Get("Cache-Control") != "private, max-age=60" {
+		t.Fatalf("first response %d headers=%v", first.Code, first.Header())
+	}
+	now = now.Add(10 * time.Second)
+	second := do(http.MethodGet, "/v1/capabilities", "", nil)
+	if second.Body.String() != first.Body.String() || calls.Load() != 1 || second.Header().Get("Age") != "10" {
+		t.Fatalf("expected cached body, calls=%d headers=%v", calls.Load(), second.Header())
+	}
+	notModified := do(http.MethodGet, "/v1/capabilities", "", http.Header{"If-None-Match": {`W/"x", ` + etag}})
+	if notModified.Code != http.StatusNotModified || notModified.Body.Len() != 0 {
+		t.Fatalf("expected 304, got %d", notModified.Code)
+	}
+
+	now = now.Add(time.Minute)
+	if do(http.MethodGet, "/v1/capabilities", "", nil); calls.Load() != 2 {
+		t.Fatalf("expired entry should refetch, calls=%d", calls.Load())
+	}
+
+	// POST is cached only when marked, keyed by body.
+	do(http.MethodPost, "/v1/run", `{"prompt":"a"}`, nil)
+	do(http.MethodPost, "/v1/run", `{"prompt":"a"}`, nil)
+	if calls.Load() != 4 {
+		t.Fatalf("unmarked POST must not be cached, calls=%d", calls.Load())
+	}
+	marked := http.Header{HTTPCacheableHeader: {"true"}}
+	a := do(http.MethodPost, "/v1/run", `{"prompt":"a"}`, marked)
+	b := do(http.MethodPost, "/v1/run", `{"prompt":"b"}`, marked)
+	again := do(http.MethodPost, "/v1/run", `{"prompt":"a"}`, marked)
+	if calls.Load() != 6 || again.Body.String() != a.Body.String() || !strings.Contains(b.Body.String(), `prompt\":\"b`) {
+		t.Fatalf("marked POST caching wrong, calls=%d a=%s b=%s", calls.Load(), a.Body, b.Body)
+	}
+
+	do(http.MethodGet, "/live", "", nil)
+	do(http.MethodGet, "/live", "", nil)
+	if calls.Load() != 8 {
+		t.Fatalf("route with zero TTL must bypass, calls=%d", calls.Load())
+	}
+
+	cache.Purge("/v1/run")
+	if cache.Len() != 1 {
+		t.Fatalf("purge left %d entries", cache.Len())
+	}
+}
+
+func TestHTTPCacheSkipsErrorsAndStreams(t *testing.T) {
+	var calls atomic.Int32
+	h := NewHTTPCache(HTTPCacheConfig{}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/stream":
+			_, _ = io.WriteString(w, "data: 1\n\n")
+			w.(http.Flusher).Flush()
+			_, _ = io.WriteString(w, "data: 2\n\n")
+		}
+	}))
+	for _, path := range []string{"/missing", "/missing", "/stream", "/stream"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if path == "/stream" && (rec.Body.String() != "data: 1\n\ndata: 2\n\n" || !rec.Flushed) {
+			t.Fatalf("stream body %q flushed=%v", rec.Body, rec.Flushed)
+		}
+		if path == "/missing" && rec.Code != http.StatusNotFound {
+			t.Fatalf("status %d", rec.Code)
+		}
+	}
+	if calls.Load() != 4 {
+		t.Fatalf("errors and streams must not be cached, calls=%d", calls.Load())
+	}
+}
+
+func TestHTTPCacheCollapsesConcurrentMisses(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	h := NewHTTPCache(HTTPCacheConfig{}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		_, _ = io.WriteString(w, "ok")
+	}))
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/poll", nil))
+			if rec.Body.String() != "ok" {
+				t.Errorf("body %q", rec.Body)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Fatalf("expected one upstream call, got %d", calls.Load())
+	}
+}
@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultRedactionMask replaces detected secrets when RedactorConfig.Mask
+// is empty.
+const DefaultRedactionMask = "[REDACTED]"
+
+// maxRedactDepth bounds the walk over nested values in RedactValue.
+const maxRedactDepth = 32
+
+// builtinSecretPatterns detect common credentials. When a pattern has a
+// capture group only the first group is masked, so the surrounding key
+// name stays readable.
+var builtinSecretPatterns = []string{
+	// Private keys in PEM form.
+	`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`,
+	// AWS access key IDs and secret access keys.
+	`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`,
+	`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`,
+	// GitHub, Slack, Google, Stripe and OpenAI/Anthropic style API tokens.
+	`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}\b`,
+	`\bgithub_pat_[A-Za-z0-9_]{22,}`,
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+	`\bAIza[0-9A-Za-z_-]{35}\b`,
+	`\b[sr]k_live_[0-9A-Za-z]{24,}`,
+	`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`,
+	// JSON Web Tokens and bearer credentials.
+	`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`,
+	`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{20,}=*)`,
+	// key=value assignments of passwords, secrets and tokens.
+	`(?i)\b(?:password|passwd|secret|token|api_?key|access_?key)["']?\s*[:=]\s*["']?([^\s"',;]{8,})`,
+}
+
+// RedactorConfig configures Redactor.
+type RedactorConfig struct {
+	// Patterns are extra regular expressions to mask. As with the built-in
+	// patterns, a pattern with a capture group masks only its first group.
+	Patterns []string
+	// Mask replaces each secret; empty uses DefaultRedactionMask.
+	Mask string
+	// DisableBuiltins drops the built-in credential patterns, leaving only
+	// Patterns.
+	DisableBuiltins bool
+}
+
+// Redactor is a middleware that masks credentials: AWS keys, API tokens,
+// private keys and any configured pattern.
+//
+// Within the chain it replaces the tool call, tool result and model
+// input/output held in State with masked copies, so middleware running after
+// it (tracing, logging) never sees the secrets. The tool still runs with its
+// original input. The api runtime additionally masks the prompt, model
+// output and tool output before they are written to the conversation
+// history or the final Response; it runs every Redactor ahead of other
+// middleware.
+type Redactor struct {
+	patterns []*regexp.Regexp
+	mask     string
+	count    atomic.Int64
+}
+
+// NewRedactor compiles the configured patterns.
+func NewRedactor(cfg RedactorConfig) (*Redactor, error) {
+	var sources []string
+	if !cfg.DisableBuiltins {
+		sources = append(sources, builtinSecretPatterns...)
+	}
+	sources = append(sources, cfg.Patterns...)
+	r := &Redactor{mask: cfg.Mask}
+	if r.mask == "" {
+		r.mask = DefaultRedactionMask
+	}
+	for _, src := range sources {
+		if strings.TrimSpace(src) == "" {
+			continue
+		}
+		re, err := regexp.Compile(src)
+		if err != nil {
+			return nil, fmt.Errorf("middleware: redactor pattern %q: %w", src, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Redactions returns how many replacements have been made so far. A secret
+// seen at several points (state, history, response) counts each time.
+func (r *Redactor) Redactions() int {
+	if r == nil {
+		return 0
+	}
+	return int(r.count.Load())
+}
+
+// Redact returns s with every detected secret replaced by the mask.
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, re := range r.patterns {
+		matches := re.FindAllStringSubmatchIndex(s, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if start < last || s[start:end] == r.mask {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(r.mask)
+			last = end
+			r.count.Add(1)
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s
+}
+
+// RedactValue returns a copy of v with Redact applied to every string it
+// contains, walking maps, slices, pointers and exported struct fields. v
+// itself is not modified.
+func (r *Redactor) RedactValue(v any) any {
+	if r == nil || v == nil {
+		return v
+	}
+	out := r.redactReflect(reflect.ValueOf(v), 0)
+	if !out.IsValid() || !out.CanInterface() {
+		return v
+	}
+	return out.Interface()
+}
+
+func (r *Redactor) redactReflect(v reflect.Value, depth int) reflect.Value {
+	if !v.IsValid() || depth > maxRedactDepth {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(r.Redact(v.String())).Convert(v.Type())
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(r.redactReflect(v.Elem(), depth+1))
+		return out
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(r.redactReflect(v.Elem(), depth+1))
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), r.redactReflect(iter.Value(), depth+1))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return reflect.ValueOf([]byte(r.Redact(string(v.Bytes())))).Convert(v.Type())
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(r.redactReflect(v.Index(i), depth+1))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(r.redactReflect(v.Index(i), depth+1))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := out.Field(i); field.CanSet() {
+				field.Set(r.redactReflect(v.Field(i), depth+1))
+			}
+		}
+		return out
+	}
+	return v
+}
+
+func (r *Redactor) Name() string { return "redactor" }
+
+func (r *Redactor) BeforeAgent(context.Context, *State) error { return nil }
+func (r *Redactor) BeforeModel(context.Context, *State) error { return nil }
+
+func (r *Redactor) AfterModel(_ context.Context, st *State) error {
+	if st == nil {
+		return nil
+	}
+	st.ModelInput = r.RedactValue(st.ModelInput)
+	st.ModelOutput = r.RedactValue(st.ModelOutput)
+	for _, key := range []string{"model.request", "model.response"} {
+		if v, ok := st.Values[key]; ok {
+			st.Values[key] = r.RedactValue(v)
+		}
+	}
+	return nil
+}
+
+func (r *Redactor) BeforeTool(_ context.Context, st *State) error {
+	if st != nil {
+		st.ToolCall = r.RedactValue(st.ToolCall)
+	}
+	return nil
+}
+
+func (r *Redactor) AfterTool(_ context.Context, st *State) error {
+	if st != nil {
+		st.ToolCall = r.RedactValue(st.ToolCall)
+		st.ToolResult = r.RedactValue(st.ToolResult)
+	}
+	return nil
+}
+
+func (r *Redactor) AfterAgent(_ context.Context, st *State) error {
+	if st != nil {
+		st.ModelOutput = r.RedactValue(st.ModelOutput)
+	}
+	return nil
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/anthropics/anthropic-sdk-go/packages/param"
 	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+	"github.com/cexll/agentsdk-go/pkg/core/retry"
+	"github.com/cexll/agentsdk-go/pkg/core/tracecontext"
 )
 
 // AnthropicConfig wires a plain anthropic-sdk-go client into the Model interface.
@@ -28,6 +30,21 @@ type AnthropicConfig struct {
 	System      string
 	Temperature *float64
 	HTTPClient  *http.Client
+	// TokenSource authenticates with OAuth bearer tokens instead of APIKey,
+	// e.g. an *OAuthTokenSource backed by ~/.claude credentials.
+	TokenSource TokenSource
+	// Vertex routes requests through Google Vertex AI; APIKey and BaseURL
+	// are ignored.
+	Vertex *VertexConfig
+	// Retry replaces the default retry loop (MaxRetries attempts with
+	// quadratic backoff) with an exponential backoff policy.
+	Retry *RetryPolicy
+	// PromptCache places cache_control breakpoints on every request rather
+	// than only those with Request.EnablePromptCache set.
+	PromptCache bool
+	// PromptCacheTTL selects the cache lifetime; empty uses the API default
+	// of five minutes.
+	PromptCacheTTL PromptCacheTTL
 }
 
 type anthropicMessages interface {
@@ -41,9 +58,15 @@ type anthropicModel struct {
 	model            anthropicsdk.Model
 	maxTokens        int
 	maxRetries       int
+	retryPolicy      *RetryPolicy
+	promptCache      bool
+	promptCacheTTL   PromptCacheTTL
 	system           string
 	temperature      *float64
 	configuredAPIKey string
+	// bearerAuth is set when requests authenticate via a token source
+	// (OAuth or Vertex); environment API keys are then ignored.
+	bearerAuth bool
 }
 
 var anthropicPredefinedHeaders = map[string]string{
@@ -97,7 +120,7 @@ func (m *anthropicModel) requestOptions() []option.RequestOption {
 	headers := newAnthropicHeaders(nil, nil)
 
 	apiKey := strings.TrimSpace(m.configuredAPIKey)
-	if apiKey == "" {
+	if apiKey == "" && !m.bearerAuth {
 		if envKey := strings.TrimSpace(os.Getenv("ANTHROPIC_API_KEY")); envKey != "" {
 			apiKey = envKey
 		} else if authToken := strings.TrimSpace(os.Getenv("ANTHROPIC_AUTH_TOKEN")); authToken != "" {
@@ -111,6 +134,17 @@ func (m *anthropicModel) requestOptions() []option.RequestOption {
 		headers["x-api-key"] = apiKey
 	}
 
+	if m.promptCacheTTL == PromptCacheTTL1h {
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		if beta := headers["anthropic-beta"]; beta != "" {
+			headers["anthropic-beta"] = beta + "," + extendedCacheTTLBeta
+		} else {
+			headers["anthropic-beta"] = extendedCacheTTLBeta
+		}
+	}
+
 	if len(headers) == 0 {
 		return nil
 	}
@@ -127,24 +161,38 @@ func (m *anthropicModel) requestOptions() []option.RequestOption {
 // NewAnthropic constructs a production-ready Anthropic-backed Model.
 func NewAnthropic(cfg AnthropicConfig) (Model, error) {
 	apiKey := strings.TrimSpace(cfg.APIKey)
-	if apiKey == "" {
+	var opts []option.RequestOption
+	bearer := false
+	switch {
+	case cfg.Vertex != nil:
+		vertex, err := cfg.Vertex.resolve()
+		if err != nil {
+			return nil, err
+		}
+		apiKey, bearer = "", true
+		opts = append(opts, option.WithBaseURL(vertex.BaseURL), option.WithMiddleware(vertexMiddleware(vertex)))
+	case apiKey == "" && cfg.TokenSource != nil:
+		bearer = true
+		opts = append(opts, option.WithMiddleware(bearerMiddleware(cfg.TokenSource, oauthBetaHeader)))
+	case apiKey == "":
 		return nil, errors.New("anthropic: api key required")
-	}
-
-	opts := []option.RequestOption{
-		// Explicitly set the API key so it overrides any ANTHROPIC_AUTH_TOKEN
-		// or ANTHROPIC_API_KEY from the environment (DefaultClientOptions).
-		option.WithAPIKey(apiKey),
-		// Also set auth token for providers that require Authorization: Bearer
-		// (e.g. DeepSeek's Anthropic-compatible endpoint).
-		option.WithAuthToken(apiKey),
-	}
-	if cfg.BaseURL != "" {
+	default:
+		opts = append(opts,
+			// Explicitly set the API key so it overrides any ANTHROPIC_AUTH_TOKEN
+			// or ANTHROPIC_API_KEY from the environment (DefaultClientOptions).
+			option.WithAPIKey(apiKey),
+			// Also set auth token for providers that require Authorization: Bearer
+			// (e.g. DeepSeek's Anthropic-compatible endpoint).
+			option.WithAuthToken(apiKey),
+		)
+	}
+	if cfg.BaseURL != "" && cfg.Vertex == nil {
 		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
 	}
 	if cfg.HTTPClient != nil {
 		opts = append(opts, option.WithHTTPClient(cfg.HTTPClient))
 	}
+	opts = append(opts, option.WithMiddleware(anthropicTraceMiddleware, rateLimitMiddleware))
 
 	client := anthropicsdk.NewClient(opts...)
 	maxTokens := cfg.MaxTokens
@@ -161,12 +209,45 @@ func NewAnthropic(cfg AnthropicConfig) (Model, error) {
 		model:            mapModelName(cfg.Model),
 		maxTokens:        maxTokens,
 		maxRetries:       retries,
+		retryPolicy:      cfg.Retry,
+		promptCache:      cfg.PromptCache,
+		promptCacheTTL:   cfg.PromptCacheTTL,
 		system:           strings.TrimSpace(cfg.System),
 		temperature:      cfg.Temperature,
 		configuredAPIKey: apiKey,
+		bearerAuth:       bearer,
 	}, nil
 }
 
+// bearerMiddleware swaps API key auth for a bearer token from ts on every
+// request, so refreshed tokens take effect without rebuilding the client.
+// beta, when set, is appended to the anthropic-beta header.
+// anthropicTraceMiddleware forwards the W3C trace context of the call.
+func anthropicTraceMiddleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	if tc, ok := tracecontext.FromContext(req.Context()); ok {
+		tracecontext.Inject(req.Header, tc)
+	}
+	return next(req)
+}
+
+func bearerMiddleware(ts TokenSource, beta string) option.Middleware {
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		token, err := ts.Token(req.Context())
+		if err != nil {
+			return nil, fmt.Errorf("anthropic: bearer token: %w", err)
+		}
+		req.Header.Del("x-api-key")
+		req.Header.Set("Authorization", "Bearer "+token)
+		if current := req.Header.Get("anthropic-beta"); beta != "" && !strings.Contains(current, beta) {
+			if current != "" {
+				current += ","
+			}
+			req.Header.Set("anthropic-beta", current+beta)
+		}
+		return next(req)
+	}
+}
+
 // Complete issues a non-streaming completion.
 func (m *anthropicModel) Complete(ctx context.Context, req Request) (*Response, error) {
 	recordModelRequest(ctx, req)
@@ -233,7 +314,22 @@ func (m *anthropicModel) CompleteStream(ctx context.Context, req Request, cb Str
 			}
 
 			switch ev := event.AsAny().(type) {
+			case anthropicsdk.ContentBlockStartEvent:
+				if ev.ContentBlock.Type == "tool_use" {
+					delta := &ToolCallDelta{Index: int(ev.Index), ID: ev.ContentBlock.ID, Name: ev.ContentBlock.Name}
+					if err := cb(StreamResult{ToolCallDelta: delta}); err != nil {
+						return err
+					}
+				}
 			case anthropicsdk.ContentBlockDeltaEvent:
+				if ev.Delta.Type == "input_json_delta" {
+					if ev.Delta.PartialJSON != "" {
+						if err := cb(StreamResult{ToolCallDelta: &ToolCallDelta{Index: int(ev.Index), PartialJSON: ev.Delta.PartialJSON}}); err != nil {
+							return err
+						}
+					}
+					continue
+				}
 				if text := ev.Delta.AsTextDelta().Text; text != "" {
 					if err := cb(StreamResult{Delta: text}); err != nil {
 						return err
@@ -245,10 +341,24 @@ func (m *anthropicModel) CompleteStream(ctx context.Context, req Request, cb Str
 						return err
 					}
 				}
+			case anthropicsdk.MessageStartEvent:
+				// Cache reads and writes are reported up front; later deltas
+				// may leave them zero.
+				usage.CacheCreationTokens = int(ev.Message.Usage.CacheCreationInputTokens)
+				usage.CacheReadTokens = int(ev.Message.Usage.CacheReadInputTokens)
+				if in := int(ev.Message.Usage.InputTokens); in > 0 {
+					usage.InputTokens = in
+				}
 			case anthropicsdk.MessageDeltaEvent:
-				usage.CacheCreationTokens = int(ev.Usage.CacheCreationInputTokens)
-				usage.CacheReadTokens = int(ev.Usage.CacheReadInputTokens)
-				usage.InputTokens = int(ev.Usage.InputTokens)
+				if n := int(ev.Usage.CacheCreationInputTokens); n > 0 {
+					usage.CacheCreationTokens = n
+				}
+				if n := int(ev.Usage.CacheReadInputTokens); n > 0 {
+					usage.CacheReadTokens = n
+				}
+				if n := int(ev.Usage.InputTokens); n > 0 {
+					usage.InputTokens = n
+				}
 				usage.OutputTokens = int(ev.Usage.OutputTokens)
 				usage.TotalTokens = usage.InputTokens + usage.OutputTokens
 			}
@@ -269,7 +379,7 @@ func (m *anthropicModel) CompleteStream(ctx context.Context, req Request, cb Str
 }
 
 func (m *anthropicModel) buildParams(req Request) (anthropicsdk.MessageNewParams, error) {
-	systemBlocks, messageParams, err := convertMessages(req.Messages, req.EnablePromptCache, m.system, req.System)
+	systemBlocks, messageParams, err := convertMessages(req.Messages, false, m.system, req.System)
 	if err != nil {
 		return anthropicsdk.MessageNewParams{}, err
 	}
@@ -296,6 +406,9 @@ func (m *anthropicModel) buildParams(req Request) (anthropicsdk.MessageNewParams
 		}
 		params.Tools = tools
 	}
+	if req.EnablePromptCache || m.promptCache {
+		markCacheBreakpoints(params.Tools, params.System, params.Messages, m.promptCacheTTL)
+	}
 
 	if m.temperature != nil {
 		params.Temperature = param.NewOpt(*m.temperature)
@@ -314,27 +427,17 @@ func (m *anthropicModel) buildParams(req Request) (anthropicsdk.MessageNewParams
 }
 
 func (m *anthropicModel) doWithRetry(ctx context.Context, fn func(context.Context) error) error {
-	attempts := 0
-	for {
-		err := fn(ctx)
-		if err == nil {
-			return nil
-		}
-		// Check context before deciding to retry
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-		if !isRetryable(err) || attempts >= m.maxRetries {
-			return err
-		}
-		attempts++
-		backoff := time.Duration(attempts*attempts) * 100 * time.Millisecond
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(backoff):
-		}
+	if m.retryPolicy != nil {
+		return m.retryPolicy.loop("anthropic").Do(ctx, fn)
 	}
+	return retry.Loop{Stage: "anthropic", MaxRetries: m.maxRetries, Retryable: isRetryable, RetryAfter: cappedRetryAfter}.Do(ctx, fn)
+}
+
+// cappedRetryAfter honours provider Retry-After hints up to the default
+// maximum backoff.
+func cappedRetryAfter(err error) (time.Duration, bool) {
+	d, ok := RetryAfter(err)
+	return min(d, defaultRetryMax), ok
 }
 
 func isRetryable(err error) bool {
@@ -449,36 +552,8 @@ func convertMessages(msgs []Message, enableCache bool, defaults ...string) ([]an
 		})
 	}
 
-	// Apply cache control if enabled
 	if enableCache {
-		// Mark the last system block for caching
-		if len(systemBlocks) > 0 {
-			systemBlocks[len(systemBlocks)-1].CacheControl = anthropicsdk.NewCacheControlEphemeralParam()
-		}
-
-		// Mark the last 2-3 user messages for caching to optimize multi-turn conversations
-		userMsgCount := 0
-		for i := len(messageParams) - 1; i >= 0 && userMsgCount < 3; i-- {
-			if messageParams[i].Role == anthropicsdk.MessageParamRoleUser && len(messageParams[i].Content) > 0 {
-				// Walk backward through content blocks to find the last text block
-				cached := false
-				for j := len(messageParams[i].Content) - 1; j >= 0; j-- {
-					if text := messageParams[i].Content[j].GetText(); text != nil && *text != "" {
-						messageParams[i].Content[j] = anthropicsdk.ContentBlockParamUnion{
-							OfText: &anthropicsdk.TextBlockParam{
-								Text:         *text,
-								CacheControl: anthropicsdk.NewCacheControlEphemeralParam(),
-							},
-						}
-						cached = true
-						break
-					}
-				}
-				if cached {
-					userMsgCount++
-				}
-			}
-		}
+		markCacheBreakpoints(nil, systemBlocks, messageParams, "")
 	}
 
 	return systemBlocks, messageParams, nil
@@ -524,7 +599,11 @@ func buildToolResults(msg Message) []anthropicsdk.ContentBlockParamUnion {
 		if strings.TrimSpace(text) == "" {
 			text = msg.Content
 		}
-		blocks = append(blocks, anthropicsdk.NewToolResultBlock(id, text, toolResultIsError(text)))
+		block := anthropicsdk.NewToolResultBlock(id, text, call.IsError || toolResultIsError(text))
+		if len(call.ResultBlocks) > 0 {
+			block.OfToolResult.Content = append(block.OfToolResult.Content, toolResultContent(call.ResultBlocks)...)
+		}
+		blocks = append(blocks, block)
 	}
 	if len(blocks) == 0 {
 		blocks = append(blocks, anthropicsdk.NewTextBlock(msg.Content))
@@ -532,6 +611,29 @@ func buildToolResults(msg Message) []anthropicsdk.ContentBlockParamUnion {
 	return blocks
 }
 
+// toolResultContent maps the media returned with a tool result to tool
+// result content blocks.
+func toolResultContent(blocks []ContentBlock) []anthropicsdk.ToolResultBlockParamContentUnion {
+	out := make([]anthropicsdk.ToolResultBlockParamContentUnion, 0, len(blocks))
+	for _, b := range blocks {
+		if b.Type == ContentBlockText {
+			if strings.TrimSpace(b.Text) != "" {
+				out = append(out, anthropicsdk.ToolResultBlockParamContentUnion{OfText: &anthropicsdk.TextBlockParam{Text: b.Text}})
+			}
+			continue
+		}
+		for _, converted := range convertContentBlocks([]ContentBlock{b}) {
+			switch {
+			case converted.OfImage != nil:
+				out = append(out, anthropicsdk.ToolResultBlockParamContentUnion{OfImage: converted.OfImage})
+			case converted.OfDocument != nil:
+				out = append(out, anthropicsdk.ToolResultBlockParamContentUnion{OfDocument: converted.OfDocument})
+			}
+		}
+	}
+	return out
+}
+
 // convertContentBlocks maps SDK ContentBlocks to Anthropic API content blocks.
 func convertContentBlocks(blocks []ContentBlock) []anthropicsdk.ContentBlockParamUnion {
 	out := make([]anthropicsdk.ContentBlockParamUnion, 0, len(blocks))
@@ -552,6 +654,8 @@ func convertContentBlocks(blocks []ContentBlock) []anthropicsdk.ContentBlockPara
 		case ContentBlockDocument:
 			if b.Data != "" {
 				out = append(out, anthropicsdk.NewDocumentBlock(anthropicsdk.Base64PDFSourceParam{Data: b.Data}))
+			} else if b.URL != "" {
+				out = append(out, anthropicsdk.NewDocumentBlock(anthropicsdk.URLPDFSourceParam{URL: b.URL}))
 			}
 		default:
 			log.Printf("WARNING: unknown content block type %q, skipping", b.Type)
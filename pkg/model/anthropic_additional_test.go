@@ -6,11 +6,13 @@ import (
 	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	anthropicsdk "github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
 	"github.com/anthropics/anthropic-sdk-go/shared/constant"
+	"github.com/cexll/agentsdk-go/pkg/core/retry"
 )
 
 type fakeDecoder struct {
@@ -177,6 +179,54 @@ func TestAnthropicCompleteStreamWithStubMessages(t *testing.T) {
 	}
 }
 
+func TestAnthropicCompleteStreamEmitsToolCallDeltas(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"calc","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"a\":"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"1}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","usage":{"output_tokens":2}}`,
+		`{"type":"message_stop"}`,
+	}
+	m := &anthropicModel{
+		msgs:             &fakeMessages{stream: buildStream(t, events), countResp: &anthropicsdk.MessageTokensCount{InputTokens: 1}},
+		model:            mapModelName(""),
+		maxTokens:        16,
+		configuredAPIKey: "key",
+	}
+	var deltas []ToolCallDelta
+	var texts []string
+	var calls []ToolCall
+	err := m.CompleteStream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}, func(res StreamResult) error {
+		if res.ToolCallDelta != nil {
+			deltas = append(deltas, *res.ToolCallDelta)
+		}
+		if res.Delta != "" {
+			texts = append(texts, res.Delta)
+		}
+		if res.ToolCall != nil {
+			calls = append(calls, *res.ToolCall)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if len(texts) != 0 {
+		t.Fatalf("tool input leaked into text deltas: %v", texts)
+	}
+	if len(deltas) != 3 || deltas[0].ID != "toolu_1" || deltas[0].Name != "calc" {
+		t.Fatalf("unexpected deltas %+v", deltas)
+	}
+	if got := deltas[1].PartialJSON + deltas[2].PartialJSON; got != `{"a":1}` {
+		t.Fatalf("fragments = %q", got)
+	}
+	if len(calls) != 1 || calls[0].Arguments["a"] != float64(1) {
+		t.Fatalf("unexpected tool calls %+v", calls)
+	}
+}
+
 func TestAnthropicHelpers(t *testing.T) {
 	if !toolResultIsError(`{"error":true}`) {
 		t.Fatalf("expected error=true")
@@ -331,6 +381,17 @@ func TestDoWithRetry(t *testing.T) {
 	if attempts != 1 {
 		t.Fatalf("expected 1 attempt, got %d", attempts)
 	}
+
+	// A backoff that would outlive the deadline fails fast with the stage.
+	deadlineCtx, cancelDeadline := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelDeadline()
+	err := (&anthropicModel{maxRetries: 5}).doWithRetry(deadlineCtx, func(context.Context) error {
+		return errors.New("overloaded")
+	})
+	var exhausted *retry.DeadlineExhausted
+	if !errors.As(err, &exhausted) || exhausted.Stage != "anthropic" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected anthropic DeadlineExhausted, got %v", err)
+	}
 }
 
 type netErr struct {
@@ -409,3 +470,41 @@ func TestBuildToolResultsFallbacks(t *testing.T) {
 		t.Fatalf("expected single block fallback")
 	}
 }
+
+func TestBuildToolResultsHonorsIsError(t *testing.T) {
+	blocks := buildToolResults(Message{ToolCalls: []ToolCall{
+		{ID: "id1", Result: "exit status 1", IsError: true},
+		{ID: "id2", Result: "ok"},
+	}})
+	if len(blocks) != 2 || blocks[0].OfToolResult == nil || blocks[1].OfToolResult == nil {
+		t.Fatalf("unexpected blocks %+v", blocks)
+	}
+	if !blocks[0].OfToolResult.IsError.Value || blocks[1].OfToolResult.IsError.Value {
+		t.Fatalf("is_error not propagated: %+v / %+v", blocks[0].OfToolResult.IsError, blocks[1].OfToolResult.IsError)
+	}
+}
+
+func TestBuildToolResultsPassesMediaThrough(t *testing.T) {
+	blocks := buildToolResults(Message{ToolCalls: []ToolCall{{
+		ID:     "id1",
+		Result: "captured",
+		ResultBlocks: []ContentBlock{
+			{Type: ContentBlockImage, MediaType: "image/png", Data: "iVBORw=="},
+			{Type: ContentBlockDocument, URL: "https://example.com/report.pdf"},
+			{Type: ContentBlockText, Text: "  "},
+		},
+	}}})
+	if len(blocks) != 1 || blocks[0].OfToolResult == nil {
+		t.Fatalf("unexpected blocks %+v", blocks)
+	}
+	content := blocks[0].OfToolResult.Content
+	if len(content) != 3 || content[0].OfText == nil || content[0].OfText.Text != "captured" {
+		t.Fatalf("unexpected tool result content %+v", content)
+	}
+	if img := content[1].OfImage; img == nil || img.Source.OfBase64 == nil || img.Source.OfBase64.Data != "iVBORw==" {
+		t.Fatalf("image not passed through: %+v", content[1])
+	}
+	if doc := content[2].OfDocument; doc == nil || doc.Source.OfURL == nil || doc.Source.OfURL.URL != "https://example.com/report.pdf" {
+		t.Fatalf("document not passed through: %+v", content[2])
+	}
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	anthropicsdk "github.com/anthropics/anthropic-sdk-go"
+)
+
+// maxCacheBreakpoints is the number of cache_control markers Anthropic
+// accepts per request.
+const maxCacheBreakpoints = 4
+
+// extendedCacheTTLBeta enables the one-hour cache lifetime.
+const extendedCacheTTLBeta = "extended-cache-ttl-2025-04-11"
+
+// PromptCacheTTL is the lifetime of Anthropic prompt cache entries.
+type PromptCacheTTL string
+
+const (
+	// PromptCacheTTL5m is the API default lifetime.
+	PromptCacheTTL5m PromptCacheTTL = "5m"
+	// PromptCacheTTL1h keeps entries for an hour at a higher write price.
+	PromptCacheTTL1h PromptCacheTTL = "1h"
+)
+
+func (ttl PromptCacheTTL) param() anthropicsdk.CacheControlEphemeralParam {
+	cc := anthropicsdk.NewCacheControlEphemeralParam()
+	if ttl != "" {
+		cc.TTL = anthropicsdk.CacheControlEphemeralTTL(ttl)
+	}
+	return cc
+}
+
+// markCacheBreakpoints places cache_control breakpoints in prefix order:
+// the last tool schema, the last system block, then the latest user turns
+// (prompts and tool results, which carry skill bodies and tool output) with
+// whatever budget remains. Each breakpoint caches everything before it, so
+// successive agent iterations read the shared prefix from cache.
+func markCacheBreakpoints(tools []anthropicsdk.ToolUnionParam, system []anthropicsdk.TextBlockParam, msgs []anthropicsdk.MessageParam, ttl PromptCacheTTL) {
+	budget := maxCacheBreakpoints
+	if n := len(tools); n > 0 {
+		if cc := tools[n-1].GetCacheControl(); cc != nil {
+			*cc = ttl.param()
+			budget--
+		}
+	}
+	if n := len(system); n > 0 {
+		system[n-1].CacheControl = ttl.param()
+		budget--
+	}
+	for i := len(msgs) - 1; i >= 0 && budget > 0; i-- {
+		if msgs[i].Role != anthropicsdk.MessageParamRoleUser {
+			continue
+		}
+		// Images and documents are skipped so a breakpoint is not spent on a
+		// block that does not end the turn's text.
+		for j := len(msgs[i].Content) - 1; j >= 0; j-- {
+			block := msgs[i].Content[j]
+			if text := block.GetText(); (text != nil && *text != "") || block.OfToolResult != nil {
+				*block.GetCacheControl() = ttl.param()
+				budget--
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+
+	anthropicsdk "github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestBuildParamsPlacesCacheBreakpoints(t *testing.T) {
+	m := &anthropicModel{maxTokens: 1024, promptCache: true, promptCacheTTL: PromptCacheTTL1h}
+	params, err := m.buildParams(Request{
+		System: "long system prompt",
+		Tools:  []ToolDefinition{{Name: "a", Parameters: map[string]any{"type": "object"}}, {Name: "b", Parameters: map[string]any{"type": "object"}}},
+		Messages: []Message{
+			{Role: "user", Content: "first"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "t1", Name: "Skill", Arguments: map[string]any{}}}},
+			{Role: "tool", ToolCalls: []ToolCall{{ID: "t1", Result: "skill body"}}},
+			{Role: "user", Content: "second"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "t2", Name: "a", Arguments: map[string]any{}}}},
+			{Role: "tool", ToolCalls: []ToolCall{{ID: "t2", Result: "tool output"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("buildParams: %v", err)
+	}
+
+	count := 0
+	mark := func(cc *anthropicsdk.CacheControlEphemeralParam) bool {
+		if cc == nil || cc.Type == "" {
+			return false
+		}
+		if cc.TTL != anthropicsdk.CacheControlEphemeralTTLTTL1h {
+			t.Fatalf("unexpected ttl %q", cc.TTL)
+		}
+		count++
+		return true
+	}
+	if mark(params.Tools[0].GetCacheControl()) || !mark(params.Tools[1].GetCacheControl()) {
+		t.Fatal("expected a breakpoint on the last tool only")
+	}
+	if !mark(&params.System[len(params.System)-1].CacheControl) {
+		t.Fatal("expected a breakpoint on the system prompt")
+	}
+	var cachedMsgs []int
+	for i, msg := range params.Messages {
+		for _, block := range msg.Content {
+			if mark(block.GetCacheControl()) {
+				cachedMsgs = append(cachedMsgs, i)
+			}
+		}
+	}
+	if count != maxCacheBreakpoints || len(cachedMsgs) != 2 || cachedMsgs[1] != len(params.Messages)-1 {
+		t.Fatalf("breakpoints=%d on messages %v", count, cachedMsgs)
+	}
+
+	m.promptCache = false
+	params, _ = m.buildParams(Request{System: "s", Messages: []Message{{Role: "user", Content: "hi"}}})
+	if params.System[0].CacheControl.Type != "" {
+		t.Fatal("caching must stay off unless requested")
+	}
+}
@@ -0,0 +1,298 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ClaudeCLIConfig drives the installed `claude` CLI in headless stream-json
+// mode instead of calling the API. It reuses whatever credentials the CLI is
+// logged in with, which makes it usable where only subscription (OAuth)
+// auth is available.
+//
+// The CLI runs exactly one model turn per call. It cannot invoke the SDK's
+// tools, so Request.Tools is ignored and responses are text only. The CLI's
+// own tools and MCP servers are disabled so nothing runs outside the SDK's
+// sandbox, permission rules and hooks.
+type ClaudeCLIConfig struct {
+	// Path to the binary; defaults to "claude" resolved via PATH.
+	Path   string
+	Model  string
+	System string
+	// WorkDir is the working directory of the subprocess.
+	WorkDir string
+	// Env is appended to the current environment.
+	Env []string
+	// ExtraArgs are passed through verbatim before the fixed flags.
+	ExtraArgs []string
+}
+
+// ClaudeCLIProvider implements Provider for the claude CLI backend.
+type ClaudeCLIProvider struct {
+	Path      string
+	ModelName string
+	System    string
+	WorkDir   string
+}
+
+// Model implements Provider.
+func (p *ClaudeCLIProvider) Model(context.Context) (Model, error) {
+	return NewClaudeCLI(ClaudeCLIConfig{Path: p.Path, Model: p.ModelName, System: p.System, WorkDir: p.WorkDir})
+}
+
+type claudeCLIModel struct {
+	path      string
+	model     string
+	system    string
+	workDir   string
+	env       []string
+	extraArgs []string
+}
+
+// NewClaudeCLI returns a Model backed by the claude CLI.
+func NewClaudeCLI(cfg ClaudeCLIConfig) (Model, error) {
+	path := strings.TrimSpace(cfg.Path)
+	if path == "" {
+		path = "claude"
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("claude cli: %w", err)
+	}
+	return &claudeCLIModel{
+		path:      resolved,
+		model:     strings.TrimSpace(cfg.Model),
+		system:    cfg.System,
+		workDir:   cfg.WorkDir,
+		env:       append([]string(nil), cfg.Env...),
+		extraArgs: append([]string(nil), cfg.ExtraArgs...),
+	}, nil
+}
+
+func (m *claudeCLIModel) Complete(ctx context.Context, req Request) (*Response, error) {
+	var final *Response
+	err := m.CompleteStream(ctx, req, func(sr StreamResult) error {
+		if sr.Final {
+			final = sr.Response
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if final == nil {
+		return nil, errors.New("claude cli: no result")
+	}
+	return final, nil
+}
+
+func (m *claudeCLIModel) CompleteStream(ctx context.Context, req Request, cb StreamHandler) error {
+	if cb == nil {
+		return errors.New("stream callback required")
+	}
+	cmd := exec.CommandContext(ctx, m.path, m.args(req)...)
+	cmd.Dir = m.workDir
+	if len(m.env) > 0 {
+		cmd.Env = append(os.Environ(), m.env...)
+	}
+	cmd.Stdin = strings.NewReader(renderCLITranscript(req.Messages))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("claude cli: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("claude cli: start: %w", err)
+	}
+
+	resp, streamErr := decodeCLIStream(stdout, cb)
+	// Drain so the process is not blocked on a full pipe before Wait.
+	_, _ = io.Copy(io.Discard, stdout) //nolint:errcheck // best-effort drain
+	waitErr := cmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if streamErr != nil {
+		return streamErr
+	}
+	if waitErr != nil {
+		return fmt.Errorf("claude cli: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+	}
+	if resp == nil {
+		return errors.New("claude cli: stream ended without result")
+	}
+	return cb(StreamResult{Final: true, Response: resp})
+}
+
+func (m *claudeCLIModel) args(req Request) []string {
+	args := append([]string(nil), m.extraArgs...)
+	args = append(args,
+		"-p",
+		"--output-format", "stream-json",
+		"--verbose",
+		"--include-partial-messages",
+		"--max-turns", "1",
+		"--tools", "",
+		"--strict-mcp-config",
+	)
+	if name := firstNonEmpty(req.Model, m.model); name != "" {
+		args = append(args, "--model", name)
+	}
+	if system := firstNonEmpty(req.System, m.system); system != "" {
+		args = append(args, "--system-prompt", system)
+	}
+	return args
+}
+
+// renderCLITranscript flattens the conversation into the single prompt the
+// CLI accepts on stdin.
+func renderCLITranscript(msgs []Message) string {
+	if len(msgs) == 1 && msgs[0].Role == "user" {
+		return msgs[0].TextContent()
+	}
+	var b strings.Builder
+	for _, msg := range msgs {
+		text := strings.TrimSpace(msg.TextContent())
+		switch msg.Role {
+		case "assistant":
+			for _, call := range msg.ToolCalls {
+				args, _ := json.Marshal(call.Arguments) //nolint:errcheck // arguments come from JSON
+				text = strings.TrimSpace(text + fmt.Sprintf("\n[called %s %s]", call.Name, args))
+			}
+			fmt.Fprintf(&b, "Assistant: %s\n\n", text)
+		case "tool":
+			for _, call := range msg.ToolCalls {
+				fmt.Fprintf(&b, "Tool result (%s): %s\n\n", call.Name, strings.TrimSpace(call.Result+describeResultBlocks(call.ResultBlocks)))
+			}
+			if len(msg.ToolCalls) == 0 && text != "" {
+				fmt.Fprintf(&b, "Tool result: %s\n\n", text)
+			}
+		default:
+			fmt.Fprintf(&b, "User: %s\n\n", text)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// cliStreamLine covers the stream-json event shapes the CLI emits:
+//
+//	{"type":"stream_event","event":{"type":"content_block_delta","delta":{"type":"text_delta","text":"..."}}}
+//	{"type":"assistant","message":{"content":[{"type":"text","text":"..."}],"stop_reason":"end_turn"}}
+//	{"type":"result","subtype":"success","is_error":false,"result":"...","usage":{...}}
+type cliStreamLine struct {
+	Type  string `json:"type"`
+	Event struct {
+		Type  string `json:"type"`
+		Delta struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"delta"`
+	} `json:"event"`
+	Message struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+	} `json:"message"`
+	Subtype string `json:"subtype"`
+	IsError bool   `json:"is_error"`
+	Result  string `json:"result"`
+	Usage   struct {
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	} `json:"usage"`
+}
+
+func decodeCLIStream(r io.Reader, cb StreamHandler) (*Response, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var (
+		text       strings.Builder
+		streamed   bool
+		stopReason string
+	)
+	for scanner.Scan() {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var line cliStreamLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			// The CLI may print non-JSON notices; skip them.
+			continue
+		}
+		switch line.Type {
+		case "stream_event":
+			if line.Event.Type == "content_block_delta" && line.Event.Delta.Type == "text_delta" && line.Event.Delta.Text != "" {
+				streamed = true
+				text.WriteString(line.Event.Delta.Text)
+				if err := cb(StreamResult{Delta: line.Event.Delta.Text}); err != nil {
+					return nil, err
+				}
+			}
+		case "assistant":
+			if line.Message.StopReason != "" {
+				stopReason = line.Message.StopReason
+			}
+			if streamed {
+				continue
+			}
+			for _, block := range line.Message.Content {
+				if block.Type == "text" && block.Text != "" {
+					text.WriteString(block.Text)
+					if err := cb(StreamResult{Delta: block.Text}); err != nil {
+						return nil, err
+					}
+				}
+			}
+		case "result":
+			if line.IsError {
+				return nil, fmt.Errorf("claude cli: %s: %s", line.Subtype, strings.TrimSpace(line.Result))
+			}
+			content := text.String()
+			if content == "" {
+				content = line.Result
+			}
+			if stopReason == "" {
+				stopReason = "end_turn"
+			}
+			usage := Usage{
+				InputTokens:         line.Usage.InputTokens,
+				OutputTokens:        line.Usage.OutputTokens,
+				CacheReadTokens:     line.Usage.CacheReadInputTokens,
+				CacheCreationTokens: line.Usage.CacheCreationInputTokens,
+			}
+			usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+			return &Response{
+				Message:    Message{Role: "assistant", Content: content},
+				Usage:      usage,
+				StopReason: stopReason,
+			}, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("claude cli: read stream: %w", err)
+	}
+	return nil, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if trimmed := strings.TrimSpace(v); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
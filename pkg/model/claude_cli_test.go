@@ -0,0 +1,134 @@
+package model
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFakeClaude(t *testing.T, body string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > " + argsFile + "\ncat > " + filepath.Join(dir, "stdin") + "\n" + body
+	path := filepath.Join(dir, "claude")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake cli: %v", err)
+	}
+	return path, dir
+}
+
+func TestClaudeCLIStreamsPartialMessages(t *testing.T) {
+	path, dir := writeFakeClaude(t, `cat <<'JSON'
+{"type":"system","subtype":"init"}
+{"type":"stream_event","event":{"type":"content_block_delta","delta":{"type":"text_delta","text":"Hel"}}}
+{"type":"stream_event","event":{"type":"content_block_delta","delta":{"type":"text_delta","text":"lo"}}}
+{"type":"assistant","message":{"content":[{"type":"text","text":"Hello"}],"stop_reason":"end_turn"}}
+{"type":"result","subtype":"success","is_error":false,"result":"Hello","usage":{"input_tokens":3,"output_tokens":2,"cache_read_input_tokens":1}}
+JSON
+`)
+	mdl, err := NewClaudeCLI(ClaudeCLIConfig{Path: path, Model: "sonnet", System: "be brief"})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	var deltas []string
+	var final *Response
+	err = mdl.CompleteStream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}, func(sr StreamResult) error {
+		if sr.Final {
+			final = sr.Response
+		} else {
+			deltas = append(deltas, sr.Delta)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if strings.Join(deltas, "|") != "Hel|lo" {
+		t.Fatalf("unexpected deltas %v", deltas)
+	}
+	if final == nil || final.Message.Content != "Hello" || final.Usage.TotalTokens != 5 || final.Usage.CacheReadTokens != 1 || final.StopReason != "end_turn" {
+		t.Fatalf("unexpected final %+v", final)
+	}
+
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	for _, want := range []string{"-p", "stream-json", "--include-partial-messages", "--model\nsonnet", "--system-prompt\nbe brief"} {
+		if !strings.Contains(string(args), want) {
+			t.Fatalf("args missing %q:\n%s", want, args)
+		}
+	}
+	stdin, _ := os.ReadFile(filepath.Join(dir, "stdin"))
+	if string(stdin) != "hi" {
+		t.Fatalf("unexpected stdin %q", stdin)
+	}
+}
+
+func TestClaudeCLICompleteFallsBackToAssistantMessage(t *testing.T) {
+	path, dir := writeFakeClaude(t, `echo 'not json'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"full"}]}}'
+echo '{"type":"result","subtype":"success","result":"full"}'
+`)
+	mdl, err := NewClaudeCLI(ClaudeCLIConfig{Path: path})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	resp, err := mdl.Complete(context.Background(), Request{Messages: []Message{
+		{Role: "user", Content: "list files"},
+		{Role: "assistant", ToolCalls: []ToolCall{{Name: "glob", Arguments: map[string]any{"pattern": "*"}}}},
+		{Role: "tool", ToolCalls: []ToolCall{{Name: "glob", Result: "a.go"}}},
+	}})
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if resp.Message.Content != "full" {
+		t.Fatalf("unexpected content %q", resp.Message.Content)
+	}
+	stdin, _ := os.ReadFile(filepath.Join(dir, "stdin"))
+	if !strings.Contains(string(stdin), "User: list files") || !strings.Contains(string(stdin), "Tool result (glob): a.go") {
+		t.Fatalf("unexpected transcript %q", stdin)
+	}
+}
+
+func TestClaudeCLIErrors(t *testing.T) {
+	if _, err := NewClaudeCLI(ClaudeCLIConfig{Path: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Fatalf("expected missing binary error")
+	}
+
+	path, _ := writeFakeClaude(t, `echo '{"type":"result","subtype":"error_during_execution","is_error":true,"result":"not logged in"}'`)
+	mdl, err := NewClaudeCLI(ClaudeCLIConfig{Path: path})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if _, err := mdl.Complete(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}); err == nil || !strings.Contains(err.Error(), "not logged in") {
+		t.Fatalf("expected result error, got %v", err)
+	}
+
+	path, _ = writeFakeClaude(t, "echo boom >&2\nexit 3\n")
+	mdl, err = NewClaudeCLI(ClaudeCLIConfig{Path: path})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if _, err := mdl.Complete(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected exit error with stderr, got %v", err)
+	}
+}
+
+func TestClaudeCLIArgsDisableCLITools(t *testing.T) {
+	mdl, err := NewClaudeCLI(ClaudeCLIConfig{Path: os.Args[0], Model: "sonnet", ExtraArgs: []string{"--tools", "default"}})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	args := mdl.(*claudeCLIModel).args(Request{System: "be brief"})
+	want := []string{
+		"--tools", "default",
+		"-p", "--output-format", "stream-json", "--verbose", "--include-partial-messages", "--max-turns", "1",
+		"--tools", "", "--strict-mcp-config",
+		"--model", "sonnet", "--system-prompt", "be brief",
+	}
+	if !slices.Equal(args, want) {
+		t.Fatalf("args = %q, want %q", args, want)
+	}
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// StopReasonContentFilter is the provider-neutral stop reason the runtime
+// reports when a provider's safety or content filter ended a response.
+const StopReasonContentFilter = "content_filter"
+
+// ErrContentFiltered matches every *ContentFilterError via errors.Is.
+var ErrContentFiltered = errors.New("model: response stopped by content filter")
+
+// ContentFilterError reports a response a provider stopped for safety
+// reasons. Category is the provider's own reason, lower-cased: "refusal"
+// (Anthropic), "content_filter" (OpenAI), "safety", "recitation",
+// "prohibited_content" and so on (Gemini).
+type ContentFilterError struct {
+	Provider string
+	Category string
+}
+
+func (e *ContentFilterError) Error() string {
+	if e.Provider != "" {
+		return fmt.Sprintf("model: %s stopped the response: content filter (%s)", e.Provider, e.Category)
+	}
+	return fmt.Sprintf("model: response stopped by content filter (%s)", e.Category)
+}
+
+// Is reports whether target is ErrContentFiltered.
+func (e *ContentFilterError) Is(target error) bool { return target == ErrContentFiltered }
+
+// contentFilterReasons are the raw stop reasons providers use for filtered
+// responses.
+var contentFilterReasons = map[string]struct{}{
+	"refusal":            {},
+	"content_filter":     {},
+	"content_filtered":   {},
+	"safety":             {},
+	"image_safety":       {},
+	"recitation":         {},
+	"prohibited_content": {},
+	"blocklist":          {},
+	"spii":               {},
+}
+
+// ContentFilterCategory reports whether stopReason is a content-filter stop
+// and returns its category.
+func ContentFilterCategory(stopReason string) (string, bool) {
+	reason := strings.ToLower(strings.TrimSpace(stopReason))
+	if _, ok := contentFilterReasons[reason]; ok {
+		return reason, true
+	}
+	return "", false
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/openai/openai-go/responses"
+)
+
+func TestContentFilterCategory(t *testing.T) {
+	cases := map[string]string{
+		"refusal":        "refusal",
+		"content_filter": "content_filter",
+		" SAFETY ":       "safety",
+		"RECITATION":     "recitation",
+	}
+	for in, want := range cases {
+		got, ok := ContentFilterCategory(in)
+		if !ok || got != want {
+			t.Fatalf("%q: got %q, %v", in, got, ok)
+		}
+	}
+	for _, in := range []string{"", "end_turn", "stop", "max_tokens", "tool_calls"} {
+		if _, ok := ContentFilterCategory(in); ok {
+			t.Fatalf("%q should not be a content-filter stop", in)
+		}
+	}
+}
+
+func TestContentFilterError(t *testing.T) {
+	err := fmt.Errorf("run: %w", &ContentFilterError{Provider: "openai", Category: "content_filter"})
+	if !errors.Is(err, ErrContentFiltered) {
+		t.Fatal("expected errors.Is ErrContentFiltered")
+	}
+	var cf *ContentFilterError
+	if !errors.As(err, &cf) || cf.Category != "content_filter" {
+		t.Fatalf("expected typed error, got %v", err)
+	}
+	if msg := (&ContentFilterError{Category: "refusal"}).Error(); msg != "model: response stopped by content filter (refusal)" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+}
+
+func TestResponsesStopReasonIncomplete(t *testing.T) {
+	resp := &responses.Response{Status: responses.ResponseStatusIncomplete}
+	resp.IncompleteDetails.Reason = "content_filter"
+	if got := responsesStopReason(resp); got != "content_filter" {
+		t.Fatalf("expected incomplete reason, got %q", got)
+	}
+	if got := responsesStopReason(&responses.Response{Status: responses.ResponseStatusCompleted}); got != "completed" {
+		t.Fatalf("expected status, got %q", got)
+	}
+}
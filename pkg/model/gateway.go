@@ -0,0 +1,217 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/option"
+)
+
+// DefaultOpenRouterBaseURL is the OpenAI-compatible endpoint of OpenRouter.
+const DefaultOpenRouterBaseURL = "https://openrouter.ai/api/v1"
+
+// liteLLMCostHeader carries the request cost on LiteLLM proxy responses.
+const liteLLMCostHeader = "x-litellm-response-cost"
+
+// GatewayConfig targets an OpenAI-compatible LLM gateway such as LiteLLM or
+// OpenRouter. Requests use the chat completions API; Routes attach extra
+// headers and body parameters per model, and the cost the gateway reports
+// (OpenRouter's usage.cost or LiteLLM's x-litellm-response-cost header) is
+// surfaced as Usage.CostUSD.
+type GatewayConfig struct {
+	BaseURL     string // required
+	APIKey      string // optional for unauthenticated local proxies
+	Model       string // e.g. "anthropic/claude-sonnet-4"
+	MaxTokens   int
+	MaxRetries  int
+	System      string
+	Temperature *float64
+	HTTPClient  *http.Client
+	// Headers are sent with every request, e.g. OpenRouter's HTTP-Referer
+	// and X-Title attribution headers.
+	Headers map[string]string
+	// Routes customise requests by model name prefix. The longest matching
+	// prefix wins.
+	Routes []GatewayRoute
+}
+
+// GatewayRoute applies to models whose name starts with Prefix.
+type GatewayRoute struct {
+	Prefix string
+	// Headers are added to matching requests, overriding GatewayConfig.Headers.
+	Headers map[string]string
+	// Params are merged into the JSON request body, e.g.
+	// {"provider": {"order": ["anthropic"]}} for OpenRouter.
+	Params map[string]any
+	// StripPrefix sends the model name without Prefix, for gateways where the
+	// prefix only selects the route.
+	StripPrefix bool
+}
+
+type gatewayModel struct {
+	inner  *openaiModel
+	routes []GatewayRoute
+}
+
+// NewGateway constructs a Model for an OpenAI-compatible gateway.
+func NewGateway(cfg GatewayConfig) (Model, error) {
+	baseURL := strings.TrimSpace(cfg.BaseURL)
+	if baseURL == "" {
+		return nil, errors.New("gateway: base url required")
+	}
+	var extra []option.RequestOption
+	for k, v := range cfg.Headers {
+		extra = append(extra, option.WithHeader(k, v))
+	}
+	inner := newOpenAIModel(OpenAIConfig{
+		APIKey:      cfg.APIKey,
+		BaseURL:     baseURL,
+		Model:       cfg.Model,
+		MaxTokens:   cfg.MaxTokens,
+		MaxRetries:  cfg.MaxRetries,
+		System:      cfg.System,
+		Temperature: cfg.Temperature,
+		HTTPClient:  cfg.HTTPClient,
+	}, extra...)
+	routes := make([]GatewayRoute, len(cfg.Routes))
+	copy(routes, cfg.Routes)
+	return &gatewayModel{inner: inner, routes: routes}, nil
+}
+
+func (g *gatewayModel) Complete(ctx context.Context, req Request) (*Response, error) {
+	inner, req, cost := g.prepare(req)
+	resp, err := inner.Complete(ctx, req)
+	if resp != nil {
+		cost.apply(&resp.Usage)
+	}
+	return resp, err
+}
+
+func (g *gatewayModel) CompleteStream(ctx context.Context, req Request, cb StreamHandler) error {
+	if cb == nil {
+		return errors.New("stream callback required")
+	}
+	inner, req, cost := g.prepare(req)
+	return inner.CompleteStream(ctx, req, func(sr StreamResult) error {
+		if sr.Final && sr.Response != nil {
+			cost.apply(&sr.Response.Usage)
+		}
+		return cb(sr)
+	})
+}
+
+// prepare returns a per-call copy of the inner model carrying the options of
+// the matching route plus a hook that captures the cost header.
+func (g *gatewayModel) prepare(req Request) (*openaiModel, Request, *gatewayCost) {
+	cost := &gatewayCost{}
+	call := *g.inner
+	call.callOpts = []option.RequestOption{option.WithMiddleware(cost.middleware)}
+	name := call.selectModel(req.Model)
+	if route, ok := g.route(name); ok {
+		for k, v := range route.Headers {
+			call.callOpts = append(call.callOpts, option.WithHeader(k, v))
+		}
+		for k, v := range route.Params {
+			call.callOpts = append(call.callOpts, option.WithJSONSet(k, v))
+		}
+		if route.StripPrefix {
+			req.Model = strings.TrimPrefix(name, route.Prefix)
+		}
+	}
+	return &call, req, cost
+}
+
+func (g *gatewayModel) route(name string) (GatewayRoute, bool) {
+	var (
+		best  GatewayRoute
+		found bool
+	)
+	for _, r := range g.routes {
+		if strings.HasPrefix(name, r.Prefix) && (!found || len(r.Prefix) > len(best.Prefix)) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// gatewayCost records the cost header of the last response of one call.
+type gatewayCost struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func (c *gatewayCost) middleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	resp, err := next(req)
+	if resp != nil {
+		if v, perr := strconv.ParseFloat(strings.TrimSpace(resp.Header.Get(liteLLMCostHeader)), 64); perr == nil {
+			c.mu.Lock()
+			c.value = v
+			c.mu.Unlock()
+		}
+	}
+	return resp, err
+}
+
+func (c *gatewayCost) apply(u *Usage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if u.CostUSD == 0 {
+		u.CostUSD = c.value
+	}
+}
+
+// GatewayProvider caches gateway clients with optional TTL.
+type GatewayProvider struct {
+	APIKey      string
+	BaseURL     string
+	ModelName   string
+	MaxTokens   int
+	MaxRetries  int
+	System      string
+	Temperature *float64
+	Headers     map[string]string
+	Routes      []GatewayRoute
+	CacheTTL    time.Duration
+
+	mu      sync.Mutex
+	cached  Model
+	expires time.Time
+}
+
+// Model implements Provider.
+func (p *GatewayProvider) Model(context.Context) (Model, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cached != nil && p.CacheTTL > 0 && time.Now().Before(p.expires) {
+		return p.cached, nil
+	}
+	mdl, err := NewGateway(GatewayConfig{
+		BaseURL:     p.BaseURL,
+		APIKey:      p.resolveAPIKey(),
+		Model:       strings.TrimSpace(p.ModelName),
+		MaxTokens:   p.MaxTokens,
+		MaxRetries:  p.MaxRetries,
+		System:      p.System,
+		Temperature: p.Temperature,
+		Headers:     p.Headers,
+		Routes:      p.Routes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.CacheTTL > 0 {
+		p.cached = mdl
+		p.expires = time.Now().Add(p.CacheTTL)
+	}
+	return mdl, nil
+}
+
+func (p *GatewayProvider) resolveAPIKey() string {
+	return firstNonEmpty(p.APIKey, os.Getenv("OPENROUTER_API_KEY"), os.Getenv("LITELLM_API_KEY"))
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type gatewayCapture struct {
+	headers http.Header
+	body    map[string]any
+}
+
+func newGatewayServer(t *testing.T, respond func(w http.ResponseWriter)) (*httptest.Server, *gatewayCapture) {
+	t.Helper()
+	captured := &gatewayCapture{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.headers = r.Header.Clone()
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, &captured.body)
+		w.Header().Set("Content-Type", "application/json")
+		respond(w)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, captured
+}
+
+func TestGatewayRoutesByModelPrefix(t *testing.T) {
+	srv, got := newGatewayServer(t, func(w http.ResponseWriter) {
+		_, _ = w.Write([]byte(`{"id":"c","object":"chat.completion","model":"m","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5,"cost":0.0042}}`))
+	})
+	mdl, err := NewGateway(GatewayConfig{
+		BaseURL:    srv.URL,
+		APIKey:     "or-key",
+		Model:      "anthropic/claude-sonnet-4",
+		MaxRetries: 1,
+		Headers:    map[string]string{"X-Title": "agentsdk", "X-Route": "default"},
+		Routes: []GatewayRoute{
+			{Prefix: "anthropic/", Headers: map[string]string{"X-Route": "anthropic"}, Params: map[string]any{"provider": map[string]any{"order": []string{"anthropic"}}}},
+			{Prefix: "openai/", Headers: map[string]string{"X-Route": "openai"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new gateway: %v", err)
+	}
+	resp, err := mdl.Complete(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if got.headers.Get("Authorization") != "Bearer or-key" || got.headers.Get("X-Title") != "agentsdk" || got.headers.Get("X-Route") != "anthropic" {
+		t.Fatalf("unexpected headers %v", got.headers)
+	}
+	if got.body["model"] != "anthropic/claude-sonnet-4" {
+		t.Fatalf("model = %v", got.body["model"])
+	}
+	if _, ok := got.body["provider"].(map[string]any); !ok {
+		t.Fatalf("route params missing from body: %v", got.body)
+	}
+	if resp.Usage.CostUSD != 0.0042 || resp.Usage.TotalTokens != 5 {
+		t.Fatalf("unexpected usage %+v", resp.Usage)
+	}
+}
+
+func TestGatewayLiteLLMCostHeaderAndStripPrefix(t *testing.T) {
+	srv, got := newGatewayServer(t, func(w http.ResponseWriter) {
+		w.Header().Set(liteLLMCostHeader, "0.015")
+		_, _ = w.Write([]byte(`{"id":"c","object":"chat.completion","model":"m","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`))
+	})
+	provider := &GatewayProvider{
+		BaseURL:    srv.URL,
+		ModelName:  "litellm/team-a/gpt-4o",
+		MaxRetries: 1,
+		Routes: []GatewayRoute{
+			{Prefix: "litellm/", StripPrefix: true},
+			{Prefix: "litellm/team-a/", StripPrefix: true, Params: map[string]any{"metadata": map[string]any{"team": "a"}}},
+		},
+	}
+	mdl, err := provider.Model(context.Background())
+	if err != nil {
+		t.Fatalf("model: %v", err)
+	}
+	resp, err := mdl.Complete(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if got.body["model"] != "gpt-4o" {
+		t.Fatalf("longest prefix should be stripped, model = %v", got.body["model"])
+	}
+	if _, ok := got.body["metadata"]; !ok {
+		t.Fatalf("expected team-a params, body %v", got.body)
+	}
+	if resp.Usage.CostUSD != 0.015 {
+		t.Fatalf("cost = %v", resp.Usage.CostUSD)
+	}
+}
+
+func TestGatewayRequiresBaseURL(t *testing.T) {
+	if _, err := NewGateway(GatewayConfig{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,226 @@
+package model
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	googleCloudScope    = "https://www.googleapis.com/auth/cloud-platform"
+	googleTokenURL      = "https://oauth2.googleapis.com/token"
+	googleMetadataHost  = "169.254.169.254"
+	googleJWTBearerType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+// GoogleDefaultTokenSource resolves Google Application Default Credentials in
+// the usual order: the file named by GOOGLE_APPLICATION_CREDENTIALS, gcloud's
+// application_default_credentials.json, then the GCE metadata server. It
+// supports service_account and authorized_user credential files and returns
+// the project ID they carry, if any.
+func GoogleDefaultTokenSource() (TokenSource, string, error) {
+	if path := strings.TrimSpace(os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")); path != "" {
+		return GoogleCredentialsFile(path)
+	}
+	if path := gcloudADCPath(); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			return GoogleCredentialsFile(path)
+		}
+	}
+	host := firstNonEmpty(os.Getenv("GCE_METADATA_HOST"), googleMetadataHost)
+	return &cachedTokenSource{fetch: metadataFetcher(host)}, "", nil
+}
+
+func gcloudADCPath() string {
+	if dir := os.Getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return filepath.Join(dir, "application_default_credentials.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+type googleCredentialsFile struct {
+	Type           string `json:"type"`
+	ProjectID      string `json:"project_id"`
+	QuotaProjectID string `json:"quota_project_id"`
+	ClientEmail    string `json:"client_email"`
+	PrivateKey     string `json:"private_key"`
+	TokenURI       string `json:"token_uri"`
+	ClientID       string `json:"client_id"`
+	ClientSecret   string `json:"client_secret"`
+	RefreshToken   string `json:"refresh_token"`
+}
+
+// GoogleCredentialsFile builds a TokenSource from a service_account or
+// authorized_user JSON credentials file.
+func GoogleCredentialsFile(path string) (TokenSource, string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("google credentials: %w", err)
+	}
+	var creds googleCredentialsFile
+	if err := json.Unmarshal(raw, &creds); err != nil {
+		return nil, "", fmt.Errorf("google credentials: decode %s: %w", path, err)
+	}
+	tokenURL := firstNonEmpty(creds.TokenURI, googleTokenURL)
+	switch creds.Type {
+	case "service_account":
+		key, err := parseRSAPrivateKey(creds.PrivateKey)
+		if err != nil {
+			return nil, "", err
+		}
+		return &cachedTokenSource{fetch: serviceAccountFetcher(creds.ClientEmail, key, tokenURL)}, creds.ProjectID, nil
+	case "authorized_user":
+		form := url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		}
+		fetch := func(ctx context.Context) (*OAuthToken, error) {
+			return postGoogleToken(ctx, tokenURL, form)
+		}
+		return &cachedTokenSource{fetch: fetch}, creds.QuotaProjectID, nil
+	default:
+		return nil, "", fmt.Errorf("google credentials: unsupported type %q", creds.Type)
+	}
+}
+
+func parseRSAPrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("google credentials: private key is not PEM")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+			return rsaKey, nil
+		}
+		return nil, errors.New("google credentials: private key is not RSA")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("google credentials: parse private key: %w", err)
+	}
+	return key, nil
+}
+
+// serviceAccountFetcher exchanges a self-signed RS256 JWT for an access
+// token (the OAuth 2.0 JWT bearer grant).
+func serviceAccountFetcher(email string, key *rsa.PrivateKey, tokenURL string) func(context.Context) (*OAuthToken, error) {
+	return func(ctx context.Context) (*OAuthToken, error) {
+		now := time.Now()
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+		claims, err := json.Marshal(map[string]any{
+			"iss":   email,
+			"scope": googleCloudScope,
+			"aud":   tokenURL,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		signing := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+		sum := sha256.Sum256([]byte(signing))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		if err != nil {
+			return nil, fmt.Errorf("google credentials: sign assertion: %w", err)
+		}
+		return postGoogleToken(ctx, tokenURL, url.Values{
+			"grant_type": {googleJWTBearerType},
+			"assertion":  {signing + "." + base64.RawURLEncoding.EncodeToString(sig)},
+		})
+	}
+}
+
+func metadataFetcher(host string) func(context.Context) (*OAuthToken, error) {
+	return func(ctx context.Context) (*OAuthToken, error) {
+		endpoint := "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token"
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		return doGoogleToken(req)
+	}
+}
+
+func postGoogleToken(ctx context.Context, tokenURL string, form url.Values) (*OAuthToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doGoogleToken(req)
+}
+
+func doGoogleToken(req *http.Request) (*OAuthToken, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("google credentials: token request: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("google credentials: read token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("google credentials: token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var decoded struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("google credentials: decode token: %w", err)
+	}
+	if decoded.AccessToken == "" {
+		return nil, errors.New("google credentials: response missing access_token")
+	}
+	tok := &OAuthToken{AccessToken: decoded.AccessToken}
+	if decoded.ExpiresIn > 0 {
+		tok.ExpiresAt = time.Now().Add(time.Duration(decoded.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+// cachedTokenSource reuses a fetched token until shortly before it expires.
+type cachedTokenSource struct {
+	fetch func(context.Context) (*OAuthToken, error)
+
+	mu      sync.Mutex
+	current *OAuthToken
+}
+
+func (s *cachedTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current.valid(time.Now()) {
+		return s.current.AccessToken, nil
+	}
+	tok, err := s.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.current = tok
+	return tok.AccessToken, nil
+}
@@ -63,6 +63,10 @@ type ToolCall struct {
 	Name      string
 	Arguments map[string]any
 	Result    string // Result stores the execution result for this specific tool call
+	IsError   bool   // IsError marks Result as a failed call
+	// ResultBlocks holds image and document blocks returned with Result.
+	// Providers without multi-part tool results describe them as text.
+	ResultBlocks []ContentBlock
 }
 
 // ToolDefinition describes a callable function exposed to the model.
@@ -91,6 +95,10 @@ type Usage struct {
 	TotalTokens         int
 	CacheReadTokens     int
 	CacheCreationTokens int
+	// CostUSD is the charge reported by an LLM gateway, when available.
+	CostUSD float64
+	// Provider names the Router provider that served the completion.
+	Provider string
 }
 
 // Response wraps the final assistant message and accounting.
@@ -102,10 +110,26 @@ type Response struct {
 
 // StreamResult delivers incremental updates during streaming calls.
 type StreamResult struct {
-	Delta    string
-	ToolCall *ToolCall
-	Final    bool
-	Response *Response
+	Delta string
+	// ToolCallDelta carries a fragment of a tool call while the provider is
+	// still generating it; ToolCall follows once the call is complete.
+	ToolCallDelta *ToolCallDelta
+	ToolCall      *ToolCall
+	Final         bool
+	Response      *Response
+}
+
+// ToolCallDelta is a fragment of a tool call as it streams in.
+type ToolCallDelta struct {
+	// Index identifies the call within the response; fragments sharing an
+	// Index belong to the same call.
+	Index int
+	// ID and Name are set on the first fragment of a call.
+	ID   string
+	Name string
+	// PartialJSON continues the arguments object; the fragments of a call
+	// concatenate to its full JSON input.
+	PartialJSON string
 }
 
 // StreamHandler consumes streaming updates in order.
@@ -0,0 +1,423 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults applied to zero KeyPoolConfig fields.
+const (
+	defaultKeyQuarantine = 15 * time.Minute
+	keyBudgetWindow      = time.Minute
+)
+
+var (
+	// ErrNoKeys is returned by NewKeyPool when no key is configured.
+	ErrNoKeys = errors.New("model key pool: no keys configured")
+	// ErrNoHealthyKeys is returned when every key is quarantined.
+	ErrNoHealthyKeys = errors.New("model key pool: every key is quarantined")
+
+	// errKeysTried signals that every usable key already failed the request.
+	errKeysTried = errors.New("model key pool: every key failed")
+)
+
+// PoolKey is one API key behind a KeyPool.
+type PoolKey struct {
+	// Name identifies the key in Usage.Provider and Status so the secret
+	// itself never shows up; empty uses "key-<index>".
+	Name   string
+	APIKey string
+	// Weight is the key's share of traffic relative to the other keys;
+	// zero uses 1.
+	Weight int
+	// RequestsPerMinute is the key's own budget; zero is unlimited. A key
+	// that spent its budget is skipped until its minute rolls over.
+	RequestsPerMinute int
+}
+
+// KeyPoolConfig configures NewKeyPool.
+type KeyPoolConfig struct {
+	Keys []PoolKey
+	// New builds the model that serves one key.
+	New func(key PoolKey) (Model, error)
+	// Cooldown is how long a key that failed with 429, 5xx or a transport
+	// error is skipped; zero uses 30s.
+	Cooldown time.Duration
+	// Quarantine is how long a key rejected with 401, 403 or an exhausted
+	// quota is pulled from rotation; zero uses 15m. A passing health check
+	// releases it early.
+	Quarantine time.Duration
+	// HealthCheck optionally probes a key; CheckHealth and RunHealthChecks
+	// use it to quarantine and release keys between requests.
+	HealthCheck func(ctx context.Context, key PoolKey, m Model) error
+	// HealthInterval is the RunHealthChecks period; zero uses Cooldown.
+	HealthInterval time.Duration
+}
+
+// KeyStatus reports a pooled key's rotation state.
+type KeyStatus struct {
+	Name        string    `json:"name"`
+	Weight      int       `json:"weight"`
+	Healthy     bool      `json:"healthy"`
+	Quarantined bool      `json:"quarantined,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	DownUntil   time.Time `json:"down_until,omitempty"`
+	// Requests counts the calls sent with the key; BudgetUsed the ones in
+	// its current RequestsPerMinute window.
+	Requests   int `json:"requests"`
+	BudgetUsed int `json:"budget_used,omitempty"`
+}
+
+// KeyPool is a Model that spreads requests over several API keys of one
+// provider by smooth weighted round robin, for batch workloads that outgrow
+// a single key. Keys over their per-minute budget are skipped, and when
+// every key is over budget the call waits for the earliest window to roll
+// over. A key failing with 429, 5xx or a transport error cools down and the
+// request moves to the next key; a key rejected with 401, 403 or an
+// exhausted quota is quarantined. Other errors are returned as-is. The
+// serving key's name is recorded in Response.Usage.Provider.
+type KeyPool struct {
+	keys       []pooledKey
+	cooldown   time.Duration
+	quarantine time.Duration
+	check      func(context.Context, PoolKey, Model) error
+	interval   time.Duration
+	now        func() time.Time
+
+	mu sync.Mutex
+}
+
+type pooledKey struct {
+	PoolKey
+	model Model
+	state *keyState
+}
+
+type keyState struct {
+	current     int
+	downUntil   time.Time
+	quarantined bool
+	lastErr     string
+	requests    int
+	windowStart time.Time
+	windowUsed  int
+}
+
+// NewKeyPool validates cfg and builds one model per key.
+func NewKeyPool(cfg KeyPoolConfig) (*KeyPool, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	if cfg.New == nil {
+		return nil, errors.New("model key pool: New is required")
+	}
+	keys := make([]pooledKey, 0, len(cfg.Keys))
+	seen := make(map[string]bool, len(cfg.Keys))
+	for i, k := range cfg.Keys {
+		k.Name = strings.TrimSpace(k.Name)
+		if k.Name == "" {
+			k.Name = fmt.Sprintf("key-%d", i)
+		}
+		if seen[k.Name] {
+			return nil, fmt.Errorf("model key pool: duplicate key %s", k.Name)
+		}
+		seen[k.Name] = true
+		if strings.TrimSpace(k.APIKey) == "" {
+			return nil, fmt.Errorf("model key pool: key %s: api key is empty", k.Name)
+		}
+		if k.Weight <= 0 {
+			k.Weight = 1
+		}
+		mdl, err := cfg.New(k)
+		if err != nil {
+			return nil, fmt.Errorf("model key pool: key %s: %w", k.Name, err)
+		}
+		keys = append(keys, pooledKey{PoolKey: k, model: mdl, state: &keyState{}})
+	}
+	cooldown := cfg.Cooldown
+	if cooldown <= 0 {
+		cooldown = defaultRouterCooldown
+	}
+	quarantine := cfg.Quarantine
+	if quarantine <= 0 {
+		quarantine = defaultKeyQuarantine
+	}
+	interval := cfg.HealthInterval
+	if interval <= 0 {
+		interval = cooldown
+	}
+	return &KeyPool{
+		keys:       keys,
+		cooldown:   cooldown,
+		quarantine: quarantine,
+		check:      cfg.HealthCheck,
+		interval:   interval,
+		now:        time.Now,
+	}, nil
+}
+
+// Complete implements Model.
+func (p *KeyPool) Complete(ctx context.Context, req Request) (*Response, error) {
+	var errs []error
+	tried := map[string]bool{}
+	for {
+		k, err := p.next(ctx, tried)
+		if err != nil {
+			return nil, p.exhausted(err, errs)
+		}
+		resp, err := k.model.Complete(ctx, req)
+		if err == nil {
+			p.markUp(k)
+			if resp != nil && resp.Usage.Provider == "" {
+				resp.Usage.Provider = k.Name
+			}
+			return resp, nil
+		}
+		if !p.rotate(ctx, k, err) {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", k.Name, err))
+	}
+}
+
+// CompleteStream implements Model. Rotation only happens before the first
+// update reaches cb; once output has streamed, errors are returned.
+func (p *KeyPool) CompleteStream(ctx context.Context, req Request, cb StreamHandler) error {
+	var errs []error
+	tried := map[string]bool{}
+	for {
+		k, err := p.next(ctx, tried)
+		if err != nil {
+			return p.exhausted(err, errs)
+		}
+		emitted := false
+		err = k.model.CompleteStream(ctx, req, func(res StreamResult) error {
+			emitted = true
+			if res.Response != nil && res.Response.Usage.Provider == "" {
+				res.Response.Usage.Provider = k.Name
+			}
+			if cb == nil {
+				return nil
+			}
+			return cb(res)
+		})
+		if err == nil {
+			p.markUp(k)
+			return nil
+		}
+		if emitted || !p.rotate(ctx, k, err) {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", k.Name, err))
+	}
+}
+
+// Status reports every key in configuration order.
+func (p *KeyPool) Status() []KeyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	out := make([]KeyStatus, 0, len(p.keys))
+	for _, k := range p.keys {
+		st := k.state
+		status := KeyStatus{
+			Name:        k.Name,
+			Weight:      k.Weight,
+			Healthy:     !now.Before(st.downUntil),
+			Quarantined: st.quarantined && now.Before(st.downUntil),
+			LastError:   st.lastErr,
+			Requests:    st.requests,
+		}
+		if !status.Healthy {
+			status.DownUntil = st.downUntil
+		}
+		if now.Sub(st.windowStart) < keyBudgetWindow {
+			status.BudgetUsed = st.windowUsed
+		}
+		out = append(out, status)
+	}
+	return out
+}
+
+// CheckHealth runs KeyPoolConfig.HealthCheck against every key, releasing
+// the ones that pass and pulling the ones that fail. It is a no-op without
+// a health check.
+func (p *KeyPool) CheckHealth(ctx context.Context) {
+	if p.check == nil {
+		return
+	}
+	for i := range p.keys {
+		k := &p.keys[i]
+		if err := p.check(ctx, k.PoolKey, k.model); err != nil {
+			p.markDown(k, err)
+			continue
+		}
+		p.markUp(k)
+	}
+}
+
+// RunHealthChecks calls CheckHealth every HealthInterval until ctx is done.
+func (p *KeyPool) RunHealthChecks(ctx context.Context) {
+	if p.check == nil {
+		return
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.CheckHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// next picks the key for the next attempt, skipping tried and quarantined
+// keys. Healthy keys under budget are chosen by weight; cooling-down keys
+// are a last resort. When only over-budget keys remain it waits for the
+// earliest budget window to roll over.
+func (p *KeyPool) next(ctx context.Context, tried map[string]bool) (*pooledKey, error) {
+	for {
+		k, wait, err := p.pick(tried)
+		if err != nil || k != nil {
+			if k != nil {
+				tried[k.Name] = true
+			}
+			return k, err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *KeyPool) pick(tried map[string]bool) (*pooledKey, time.Duration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	var (
+		ready, cooling []*pooledKey
+		wait           time.Duration
+		live           int
+	)
+	for i := range p.keys {
+		k := &p.keys[i]
+		st := k.state
+		down := now.Before(st.downUntil)
+		if st.quarantined && down {
+			continue
+		}
+		live++
+		if tried[k.Name] {
+			continue
+		}
+		if now.Sub(st.windowStart) >= keyBudgetWindow {
+			st.windowStart, st.windowUsed = now, 0
+		}
+		if k.RequestsPerMinute > 0 && st.windowUsed >= k.RequestsPerMinute {
+			if w := st.windowStart.Add(keyBudgetWindow).Sub(now); wait == 0 || w < wait {
+				wait = w
+			}
+			continue
+		}
+		if down {
+			cooling = append(cooling, k)
+			continue
+		}
+		ready = append(ready, k)
+	}
+	if live == 0 {
+		return nil, 0, ErrNoHealthyKeys
+	}
+
+	var chosen *pooledKey
+	if len(ready) > 0 {
+		total := 0
+		for _, k := range ready {
+			k.state.current += k.Weight
+			total += k.Weight
+			if chosen == nil || k.state.current > chosen.state.current {
+				chosen = k
+			}
+		}
+		chosen.state.current -= total
+	} else if len(cooling) > 0 {
+		chosen = cooling[0]
+	}
+	if chosen == nil {
+		if wait <= 0 {
+			return nil, 0, errKeysTried
+		}
+		return nil, wait, nil
+	}
+	chosen.state.requests++
+	chosen.state.windowUsed++
+	return chosen, 0, nil
+}
+
+func (p *KeyPool) exhausted(err error, errs []error) error {
+	switch {
+	case len(errs) == 0:
+		return err
+	case errors.Is(err, errKeysTried):
+		return fmt.Errorf("model key pool: all keys failed: %w", errors.Join(errs...))
+	default:
+		return fmt.Errorf("%w: %w", err, errors.Join(errs...))
+	}
+}
+
+// rotate records err against the key and reports whether the request
+// should move to the next key.
+func (p *KeyPool) rotate(ctx context.Context, k *pooledKey, err error) bool {
+	if ctx.Err() != nil || (!isKeyRejected(err) && !IsFailoverError(err)) {
+		return false
+	}
+	p.markDown(k, err)
+	return true
+}
+
+func (p *KeyPool) markDown(k *pooledKey, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := k.state
+	st.quarantined = isKeyRejected(err)
+	if st.quarantined {
+		st.downUntil = p.now().Add(p.quarantine)
+	} else {
+		st.downUntil = p.now().Add(p.cooldown)
+	}
+	st.lastErr = err.Error()
+}
+
+func (p *KeyPool) markUp(k *pooledKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := k.state
+	st.downUntil = time.Time{}
+	st.quarantined = false
+	st.lastErr = ""
+}
+
+// isKeyRejected reports whether err blames the key itself: authentication
+// or permission failures (401, 403, including abuse blocks) and exhausted
+// billing quotas, which providers report as 429 insufficient_quota.
+func isKeyRejected(err error) bool {
+	if err == nil {
+		return false
+	}
+	if code, ok := errorStatusCode(err); ok && (code == http.StatusUnauthorized || code == http.StatusForbidden) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "authentication_error") || strings.Contains(msg, "permission_error") || strings.Contains(msg, "insufficient_quota")
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newTestKeyPool(t *testing.T, cfg KeyPoolConfig, models map[string]*scriptedModel) *KeyPool {
+	t.Helper()
+	cfg.New = func(key PoolKey) (Model, error) {
+		m := &scriptedModel{}
+		models[key.Name] = m
+		return m, nil
+	}
+	pool, err := NewKeyPool(cfg)
+	if err != nil {
+		t.Fatalf("pool: %v", err)
+	}
+	return pool
+}
+
+func TestKeyPoolWeightedRotation(t *testing.T) {
+	models := map[string]*scriptedModel{}
+	pool := newTestKeyPool(t, KeyPoolConfig{Keys: []PoolKey{
+		{Name: "big", APIKey: "k1", Weight: 3},
+		{Name: "small", APIKey: "k2"},
+	}}, models)
+
+	served := map[string]int{}
+	for i := 0; i < 8; i++ {
+		resp, err := pool.Complete(context.Background(), Request{})
+		if err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+		served[resp.Usage.Provider]++
+	}
+	if served["big"] != 6 || served["small"] != 2 {
+		t.Fatalf("served = %v, want 3:1", served)
+	}
+	if status := pool.Status(); status[0].Requests != 6 || status[1].Requests != 2 {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+func TestKeyPoolQuarantinesRejectedKeys(t *testing.T) {
+	models := map[string]*scriptedModel{}
+	pool := newTestKeyPool(t, KeyPoolConfig{Keys: []PoolKey{
+		{Name: "a", APIKey: "k1", Weight: 5},
+		{Name: "b", APIKey: "k2"},
+	}}, models)
+	models["a"].errs = []error{statusError(http.StatusUnauthorized)}
+
+	resp, err := pool.Complete(context.Background(), Request{})
+	if err != nil || resp.Usage.Provider != "b" {
+		t.Fatalf("resp=%+v err=%v, want b to serve", resp, err)
+	}
+	status := pool.Status()
+	if !status[0].Quarantined || status[0].Healthy || status[0].LastError == "" || !status[1].Healthy {
+		t.Fatalf("status = %+v", status)
+	}
+	for i := 0; i < 3; i++ {
+		if resp, err := pool.Complete(context.Background(), Request{}); err != nil || resp.Usage.Provider != "b" {
+			t.Fatalf("quarantined key used: resp=%+v err=%v", resp, err)
+		}
+	}
+
+	models["b"].errs = []error{statusError(http.StatusForbidden)}
+	if _, err := pool.Complete(context.Background(), Request{}); !errors.Is(err, ErrNoHealthyKeys) {
+		t.Fatalf("err = %v, want ErrNoHealthyKeys", err)
+	}
+
+	// Request errors are the caller's fault and do not rotate.
+	pool = newTestKeyPool(t, KeyPoolConfig{Keys: []PoolKey{{Name: "a", APIKey: "k1"}, {Name: "b", APIKey: "k2"}}}, models)
+	models["a"].errs = []error{statusError(http.StatusBadRequest)}
+	if _, err := pool.Complete(context.Background(), Request{}); err == nil || models["b"].calls != 0 {
+		t.Fatalf("err=%v b.calls=%d, want bad request returned", err, models["b"].calls)
+	}
+}
+
+func TestKeyPoolCoolsDownAndJoinsErrors(t *testing.T) {
+	models := map[string]*scriptedModel{}
+	pool := newTestKeyPool(t, KeyPoolConfig{Keys: []PoolKey{{Name: "a", APIKey: "k1"}, {Name: "b", APIKey: "k2"}}}, models)
+	models["a"].errs = []error{statusError(http.StatusTooManyRequests)}
+	models["b"].errs = []error{statusError(http.StatusBadGateway)}
+
+	_, err := pool.Complete(context.Background(), Request{})
+	var se statusError
+	if err == nil || !errors.As(err, &se) || errors.Is(err, ErrNoHealthyKeys) {
+		t.Fatalf("err = %v, want joined provider errors", err)
+	}
+	for _, st := range pool.Status() {
+		if st.Healthy || st.Quarantined {
+			t.Fatalf("status = %+v, want cooling down", st)
+		}
+	}
+	// Cooling keys remain a last resort.
+	if _, err := pool.Complete(context.Background(), Request{}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+}
+
+func TestKeyPoolBudgetsAndHealthChecks(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	models := map[string]*scriptedModel{}
+	healthy := map[string]error{"a": nil, "b": statusError(http.StatusUnauthorized)}
+	pool := newTestKeyPool(t, KeyPoolConfig{
+		Keys: []PoolKey{{Name: "a", APIKey: "k1", RequestsPerMinute: 1}, {Name: "b", APIKey: "k2", RequestsPerMinute: 1}},
+		HealthCheck: func(_ context.Context, key PoolKey, _ Model) error {
+			return healthy[key.Name]
+		},
+	}, models)
+	pool.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := pool.Complete(context.Background(), Request{}); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Complete(ctx, Request{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want to wait for the budget window", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := pool.Complete(context.Background(), Request{}); err != nil {
+		t.Fatalf("complete after window: %v", err)
+	}
+
+	pool.CheckHealth(context.Background())
+	if status := pool.Status(); !status[0].Healthy || !status[1].Quarantined {
+		t.Fatalf("status = %+v", status)
+	}
+	healthy["b"] = nil
+	pool.CheckHealth(context.Background())
+	if status := pool.Status(); !status[1].Healthy {
+		t.Fatalf("health check did not release b: %+v", status)
+	}
+}
+
+func TestAnthropicProviderKeyPool(t *testing.T) {
+	provider := &AnthropicProvider{Keys: []PoolKey{{Name: "a", APIKey: "k1"}, {Name: "b", APIKey: "k2", Weight: 2}}}
+	first, err := provider.Model(context.Background())
+	if err != nil {
+		t.Fatalf("model: %v", err)
+	}
+	second, _ := provider.Model(context.Background())
+	pool, ok := first.(*KeyPool)
+	if !ok || first != second || len(pool.Status()) != 2 {
+		t.Fatalf("want one shared key pool, got %T %T", first, second)
+	}
+	if _, err := (&OpenAIProvider{Keys: []PoolKey{{Name: "a"}}}).Model(context.Background()); err == nil {
+		t.Fatalf("expected empty api key error")
+	}
+}
@@ -0,0 +1,338 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default endpoints of the Anthropic OAuth server.
+const (
+	DefaultOAuthAuthorizeURL = "https://claude.ai/oauth/authorize"
+	DefaultOAuthTokenURL     = "https://console.anthropic.com/v1/oauth/token"
+	// oauthBetaHeader must accompany requests authenticated with OAuth access
+	// tokens.
+	oauthBetaHeader = "oauth-2025-04-20"
+	// oauthRefreshSkew refreshes tokens slightly before they expire.
+	oauthRefreshSkew = time.Minute
+)
+
+// ErrNoOAuthToken is returned when no OAuth credentials are stored.
+var ErrNoOAuthToken = errors.New("oauth: no stored token")
+
+// TokenSource supplies bearer tokens for Authorization headers. It is the
+// alternative to a static API key in AnthropicConfig.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// OAuthToken is a stored OAuth credential.
+type OAuthToken struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+	Scopes       []string
+}
+
+func (t *OAuthToken) valid(now time.Time) bool {
+	if t == nil || strings.TrimSpace(t.AccessToken) == "" {
+		return false
+	}
+	return t.ExpiresAt.IsZero() || now.Add(oauthRefreshSkew).Before(t.ExpiresAt)
+}
+
+// TokenStore persists OAuth tokens.
+type TokenStore interface {
+	Load() (*OAuthToken, error)
+	Save(*OAuthToken) error
+}
+
+// FileTokenStore keeps tokens in the credentials file layout used under
+// ~/.claude, so a login performed by the CLI is picked up and refreshed
+// tokens are visible to it:
+//
+//	{"claudeAiOauth": {"accessToken": "...", "refreshToken": "...", "expiresAt": <unix ms>, "scopes": [...]}}
+//
+// Other top-level keys in the file are preserved on Save.
+type FileTokenStore struct {
+	Path string
+}
+
+// DefaultTokenStore returns the store at ~/.claude/.credentials.json.
+func DefaultTokenStore() (*FileTokenStore, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("oauth: resolve home: %w", err)
+	}
+	return &FileTokenStore{Path: filepath.Join(home, ".claude", ".credentials.json")}, nil
+}
+
+type fileOAuthToken struct {
+	AccessToken  string   `json:"accessToken"`
+	RefreshToken string   `json:"refreshToken,omitempty"`
+	ExpiresAt    int64    `json:"expiresAt,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+const credentialsKey = "claudeAiOauth"
+
+func (s *FileTokenStore) Load() (*OAuthToken, error) {
+	raw, err := os.ReadFile(s.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNoOAuthToken
+		}
+		return nil, fmt.Errorf("oauth: read credentials: %w", err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("oauth: decode credentials: %w", err)
+	}
+	entry, ok := doc[credentialsKey]
+	if !ok {
+		return nil, ErrNoOAuthToken
+	}
+	var stored fileOAuthToken
+	if err := json.Unmarshal(entry, &stored); err != nil {
+		return nil, fmt.Errorf("oauth: decode credentials: %w", err)
+	}
+	if strings.TrimSpace(stored.AccessToken) == "" {
+		return nil, ErrNoOAuthToken
+	}
+	tok := &OAuthToken{AccessToken: stored.AccessToken, RefreshToken: stored.RefreshToken, Scopes: stored.Scopes}
+	if stored.ExpiresAt > 0 {
+		tok.ExpiresAt = time.UnixMilli(stored.ExpiresAt)
+	}
+	return tok, nil
+}
+
+func (s *FileTokenStore) Save(tok *OAuthToken) error {
+	if tok == nil {
+		return errors.New("oauth: token is nil")
+	}
+	doc := map[string]json.RawMessage{}
+	if raw, err := os.ReadFile(s.Path); err == nil {
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return fmt.Errorf("oauth: decode credentials: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("oauth: read credentials: %w", err)
+	}
+	stored := fileOAuthToken{AccessToken: tok.AccessToken, RefreshToken: tok.RefreshToken, Scopes: tok.Scopes}
+	if !tok.ExpiresAt.IsZero() {
+		stored.ExpiresAt = tok.ExpiresAt.UnixMilli()
+	}
+	entry, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("oauth: encode credentials: %w", err)
+	}
+	doc[credentialsKey] = entry
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("oauth: encode credentials: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return fmt.Errorf("oauth: mkdir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), ".credentials-*")
+	if err != nil {
+		return fmt.Errorf("oauth: create temp: %w", err)
+	}
+	name := tmp.Name()
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		_ = os.Remove(name)
+		return fmt.Errorf("oauth: write credentials: %w", err)
+	}
+	if err := os.Rename(name, s.Path); err != nil {
+		_ = os.Remove(name)
+		return fmt.Errorf("oauth: rename credentials: %w", err)
+	}
+	return nil
+}
+
+// OAuthConfig describes an OAuth client. ClientID is required for the
+// browser flow and for refreshing tokens.
+type OAuthConfig struct {
+	ClientID     string
+	AuthorizeURL string // defaults to DefaultOAuthAuthorizeURL
+	TokenURL     string // defaults to DefaultOAuthTokenURL
+	RedirectURL  string
+	Scopes       []string
+	HTTPClient   *http.Client
+}
+
+// NewPKCEVerifier returns a random PKCE code verifier for AuthCodeURL and
+// Exchange.
+func NewPKCEVerifier() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("oauth: verifier: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// AuthCodeURL returns the URL to open in a browser to start the
+// authorization-code flow with PKCE (S256).
+func (c OAuthConfig) AuthCodeURL(state, verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ClientID},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+	}
+	if c.RedirectURL != "" {
+		q.Set("redirect_uri", c.RedirectURL)
+	}
+	if len(c.Scopes) > 0 {
+		q.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	base := c.AuthorizeURL
+	if base == "" {
+		base = DefaultOAuthAuthorizeURL
+	}
+	return base + "?" + q.Encode()
+}
+
+// Exchange trades an authorization code for a token.
+func (c OAuthConfig) Exchange(ctx context.Context, code, verifier string) (*OAuthToken, error) {
+	// Some consoles display "code#state"; only the code is exchanged.
+	code, _, _ = strings.Cut(strings.TrimSpace(code), "#")
+	return c.tokenRequest(ctx, map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          code,
+		"code_verifier": verifier,
+		"redirect_uri":  c.RedirectURL,
+	})
+}
+
+// Refresh obtains a new access token using tok.RefreshToken.
+func (c OAuthConfig) Refresh(ctx context.Context, tok *OAuthToken) (*OAuthToken, error) {
+	if tok == nil || strings.TrimSpace(tok.RefreshToken) == "" {
+		return nil, errors.New("oauth: no refresh token")
+	}
+	next, err := c.tokenRequest(ctx, map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": tok.RefreshToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if next.RefreshToken == "" {
+		next.RefreshToken = tok.RefreshToken
+	}
+	return next, nil
+}
+
+func (c OAuthConfig) tokenRequest(ctx context.Context, body map[string]string) (*OAuthToken, error) {
+	if strings.TrimSpace(c.ClientID) == "" {
+		return nil, errors.New("oauth: client id required")
+	}
+	body["client_id"] = c.ClientID
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: encode request: %w", err)
+	}
+	endpoint := c.TokenURL
+	if endpoint == "" {
+		endpoint = DefaultOAuthTokenURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("oauth: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: token request: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oauth: read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oauth: token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var decoded struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		Scope        string `json:"scope"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("oauth: decode response: %w", err)
+	}
+	if decoded.AccessToken == "" {
+		return nil, errors.New("oauth: response missing access_token")
+	}
+	tok := &OAuthToken{AccessToken: decoded.AccessToken, RefreshToken: decoded.RefreshToken, Scopes: strings.Fields(decoded.Scope)}
+	if decoded.ExpiresIn > 0 {
+		tok.ExpiresAt = time.Now().Add(time.Duration(decoded.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+// OAuthTokenSource serves access tokens from Store, refreshing and saving
+// them through Config shortly before they expire. It is safe for concurrent
+// use.
+type OAuthTokenSource struct {
+	Config OAuthConfig
+	Store  TokenStore
+
+	mu      sync.Mutex
+	current *OAuthToken
+	now     func() time.Time
+}
+
+func (s *OAuthTokenSource) Token(ctx context.Context) (string, error) {
+	if s == nil || s.Store == nil {
+		return "", errors.New("oauth: token store not configured")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	if s.current.valid(now()) {
+		return s.current.AccessToken, nil
+	}
+	// Another process (e.g. the CLI) may have refreshed the stored token.
+	tok, err := s.Store.Load()
+	if err != nil {
+		return "", err
+	}
+	if !tok.valid(now()) {
+		refreshed, err := s.Config.Refresh(ctx, tok)
+		if err != nil {
+			return "", err
+		}
+		if err := s.Store.Save(refreshed); err != nil {
+			return "", err
+		}
+		tok = refreshed
+	}
+	s.current = tok
+	return tok.AccessToken, nil
+}
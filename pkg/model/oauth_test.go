@@ -0,0 +1,129 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileTokenStoreRoundTripPreservesOtherKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".credentials.json")
+	if err := os.WriteFile(path, []byte(`{"other":{"keep":true}}`), 0o600); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	store := &FileTokenStore{Path: path}
+	if _, err := store.Load(); err != ErrNoOAuthToken {
+		t.Fatalf("expected ErrNoOAuthToken, got %v", err)
+	}
+	expires := time.UnixMilli(time.Now().Add(time.Hour).UnixMilli())
+	if err := store.Save(&OAuthToken{AccessToken: "at", RefreshToken: "rt", ExpiresAt: expires, Scopes: []string{"user:inference"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	tok, err := store.Load()
+	if err != nil || tok.AccessToken != "at" || tok.RefreshToken != "rt" || !tok.ExpiresAt.Equal(expires) {
+		t.Fatalf("unexpected token %+v err=%v", tok, err)
+	}
+	raw, _ := os.ReadFile(path)
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil || doc["other"] == nil {
+		t.Fatalf("expected other keys preserved, got %s", raw)
+	}
+}
+
+func TestOAuthTokenSourceRefreshesExpiredToken(t *testing.T) {
+	var refreshes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["grant_type"] != "refresh_token" || body["refresh_token"] != "old-rt" || body["client_id"] != "client" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		refreshes.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "new-at", "expires_in": 3600})
+	}))
+	defer srv.Close()
+
+	store := &FileTokenStore{Path: filepath.Join(t.TempDir(), "creds.json")}
+	if err := store.Save(&OAuthToken{AccessToken: "old-at", RefreshToken: "old-rt", ExpiresAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	src := &OAuthTokenSource{Config: OAuthConfig{ClientID: "client", TokenURL: srv.URL}, Store: store}
+	for i := 0; i < 3; i++ {
+		tok, err := src.Token(context.Background())
+		if err != nil || tok != "new-at" {
+			t.Fatalf("token=%q err=%v", tok, err)
+		}
+	}
+	if refreshes.Load() != 1 {
+		t.Fatalf("expected a single refresh, got %d", refreshes.Load())
+	}
+	saved, err := store.Load()
+	if err != nil || saved.AccessToken != "new-at" || saved.RefreshToken != "old-rt" {
+		t.Fatalf("refreshed token not persisted: %+v err=%v", saved, err)
+	}
+}
+
+func TestOAuthAuthCodeURLAndExchange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["grant_type"] != "authorization_code" || body["code"] != "the-code" || body["code_verifier"] != "verifier" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "at", "refresh_token": "rt", "scope": "a b"})
+	}))
+	defer srv.Close()
+
+	cfg := OAuthConfig{ClientID: "client", TokenURL: srv.URL, RedirectURL: "http://localhost/cb"}
+	u, err := url.Parse(cfg.AuthCodeURL("state", "verifier"))
+	if err != nil || !strings.HasPrefix(u.String(), DefaultOAuthAuthorizeURL) {
+		t.Fatalf("unexpected url %v err=%v", u, err)
+	}
+	if q := u.Query(); q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" || q.Get("state") != "state" {
+		t.Fatalf("unexpected query %v", q)
+	}
+	tok, err := cfg.Exchange(context.Background(), "the-code#state", "verifier")
+	if err != nil || tok.AccessToken != "at" || len(tok.Scopes) != 2 {
+		t.Fatalf("unexpected token %+v err=%v", tok, err)
+	}
+	if _, err := (OAuthConfig{}).Refresh(context.Background(), &OAuthToken{RefreshToken: "x"}); err == nil {
+		t.Fatalf("expected client id error")
+	}
+}
+
+type staticTokenSource string
+
+func (s staticTokenSource) Token(context.Context) (string, error) { return string(s), nil }
+
+func TestAnthropicOAuthSendsBearerToken(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "env-key")
+	var gotAuth, gotKey, gotBeta string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotKey, gotBeta = r.Header.Get("Authorization"), r.Header.Get("X-Api-Key"), r.Header.Get("Anthropic-Beta")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"m","type":"message","role":"assistant","model":"claude","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer srv.Close()
+
+	provider := &AnthropicProvider{BaseURL: srv.URL, MaxRetries: 1, TokenSource: staticTokenSource("oauth-at")}
+	mdl, err := provider.Model(context.Background())
+	if err != nil {
+		t.Fatalf("model: %v", err)
+	}
+	if _, err := mdl.Complete(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if gotAuth != "Bearer oauth-at" || gotKey != "" || !strings.Contains(gotBeta, oauthBetaHeader) {
+		t.Fatalf("unexpected auth headers auth=%q key=%q beta=%q", gotAuth, gotKey, gotBeta)
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"sort"
 	"strings"
-	"time"
 
+	"github.com/cexll/agentsdk-go/pkg/core/retry"
+	"github.com/cexll/agentsdk-go/pkg/core/tracecontext"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/openai/openai-go/packages/ssestream"
@@ -41,6 +43,8 @@ type openaiModel struct {
 	maxRetries  int
 	system      string
 	temperature *float64
+	// callOpts are appended to every API call (used by gateway routes).
+	callOpts []option.RequestOption
 }
 
 const (
@@ -49,15 +53,27 @@ const (
 	defaultOpenAIMaxRetries = 10
 )
 
+// openaiTraceMiddleware forwards the W3C trace context of the call.
+func openaiTraceMiddleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	if tc, ok := tracecontext.FromContext(req.Context()); ok {
+		tracecontext.Inject(req.Header, tc)
+	}
+	return next(req)
+}
+
 // NewOpenAI constructs a production-ready OpenAI-backed Model.
 func NewOpenAI(cfg OpenAIConfig) (Model, error) {
 	apiKey := strings.TrimSpace(cfg.APIKey)
 	if apiKey == "" {
 		return nil, errors.New("openai: api key required")
 	}
+	return newOpenAIModel(cfg), nil
+}
 
-	opts := []option.RequestOption{
-		option.WithAPIKey(apiKey),
+func newOpenAIModel(cfg OpenAIConfig, extra ...option.RequestOption) *openaiModel {
+	var opts []option.RequestOption
+	if apiKey := strings.TrimSpace(cfg.APIKey); apiKey != "" {
+		opts = append(opts, option.WithAPIKey(apiKey))
 	}
 	if cfg.BaseURL != "" {
 		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
@@ -65,6 +81,8 @@ func NewOpenAI(cfg OpenAIConfig) (Model, error) {
 	if cfg.HTTPClient != nil {
 		opts = append(opts, option.WithHTTPClient(cfg.HTTPClient))
 	}
+	opts = append(opts, option.WithMiddleware(openaiTraceMiddleware, rateLimitMiddleware))
+	opts = append(opts, extra...)
 
 	client := openai.NewClient(opts...)
 	maxTokens := cfg.MaxTokens
@@ -88,7 +106,7 @@ func NewOpenAI(cfg OpenAIConfig) (Model, error) {
 		maxRetries:  retries,
 		system:      strings.TrimSpace(cfg.System),
 		temperature: cfg.Temperature,
-	}, nil
+	}
 }
 
 // Complete issues a non-streaming completion.
@@ -101,7 +119,7 @@ func (m *openaiModel) Complete(ctx context.Context, req Request) (*Response, err
 			return err
 		}
 
-		completion, err := m.completions.New(ctx, params)
+		completion, err := m.completions.New(ctx, params, m.callOpts...)
 		if err != nil {
 			return err
 		}
@@ -132,7 +150,7 @@ func (m *openaiModel) CompleteStream(ctx context.Context, req Request, cb Stream
 			IncludeUsage: openai.Bool(true),
 		}
 
-		stream := m.completions.NewStreaming(ctx, params)
+		stream := m.completions.NewStreaming(ctx, params, m.callOpts...)
 		if stream == nil {
 			return errors.New("openai stream not available")
 		}
@@ -198,6 +216,12 @@ func (m *openaiModel) CompleteStream(ctx context.Context, req Request, cb Stream
 						acc.name = tc.Function.Name
 					}
 					acc.arguments.WriteString(tc.Function.Arguments)
+					if tc.ID != "" || tc.Function.Name != "" || tc.Function.Arguments != "" {
+						delta := &ToolCallDelta{Index: idx, ID: tc.ID, Name: tc.Function.Name, PartialJSON: tc.Function.Arguments}
+						if err := cb(StreamResult{ToolCallDelta: delta}); err != nil {
+							return err
+						}
+					}
 				}
 			}
 		}
@@ -304,26 +328,7 @@ func (m *openaiModel) buildParams(req Request) (openai.ChatCompletionNewParams,
 }
 
 func (m *openaiModel) doWithRetry(ctx context.Context, fn func(context.Context) error) error {
-	attempts := 0
-	for {
-		err := fn(ctx)
-		if err == nil {
-			return nil
-		}
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-		if !isOpenAIRetryable(err) || attempts >= m.maxRetries {
-			return err
-		}
-		attempts++
-		backoff := time.Duration(attempts*attempts) * 100 * time.Millisecond
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(backoff):
-		}
-	}
+	return retry.Loop{Stage: "openai", MaxRetries: m.maxRetries, Retryable: isOpenAIRetryable}.Do(ctx, fn)
 }
 
 func isOpenAIRetryable(err error) bool {
@@ -456,7 +461,7 @@ func buildOpenAIToolResults(msg Message) []openai.ChatCompletionMessageParamUnio
 		if strings.TrimSpace(content) == "" {
 			content = msg.Content
 		}
-		results = append(results, openai.ToolMessage(content, id))
+		results = append(results, openai.ToolMessage(content+describeResultBlocks(call.ResultBlocks), id))
 	}
 
 	if len(results) == 0 {
@@ -466,6 +471,27 @@ func buildOpenAIToolResults(msg Message) []openai.ChatCompletionMessageParamUnio
 	return results
 }
 
+// describeResultBlocks summarises tool result media for providers whose
+// tool messages only carry text.
+func describeResultBlocks(blocks []ContentBlock) string {
+	var b strings.Builder
+	for _, block := range blocks {
+		switch block.Type {
+		case ContentBlockText:
+			if strings.TrimSpace(block.Text) != "" {
+				b.WriteString("\n" + block.Text)
+			}
+		case ContentBlockImage, ContentBlockDocument:
+			source := block.URL
+			if source == "" {
+				source = "inline data"
+			}
+			fmt.Fprintf(&b, "\n[%s omitted: %s, %s]", block.Type, block.MediaType, source)
+		}
+	}
+	return b.String()
+}
+
 func convertToolsToOpenAI(tools []ToolDefinition) []openai.ChatCompletionToolParam {
 	var result []openai.ChatCompletionToolParam
 	for _, def := range tools {
@@ -549,9 +575,19 @@ func convertOpenAIResponse(completion *openai.ChatCompletion) *Response {
 }
 
 func convertOpenAIUsage(usage openai.CompletionUsage) Usage {
-	return Usage{
+	out := Usage{
 		InputTokens:  int(usage.PromptTokens),
 		OutputTokens: int(usage.CompletionTokens),
 		TotalTokens:  int(usage.TotalTokens),
 	}
+	// Gateways such as OpenRouter report the charge as usage.cost.
+	if raw := usage.RawJSON(); raw != "" {
+		var extra struct {
+			Cost float64 `json:"cost"`
+		}
+		if json.Unmarshal([]byte(raw), &extra) == nil {
+			out.CostUSD = extra.Cost
+		}
+	}
+	return out
 }
@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"strings"
-	"time"
 
+	"github.com/cexll/agentsdk-go/pkg/core/retry"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/openai/openai-go/packages/param"
@@ -45,6 +45,7 @@ func NewOpenAIResponses(cfg OpenAIConfig) (Model, error) {
 	if cfg.HTTPClient != nil {
 		opts = append(opts, option.WithHTTPClient(cfg.HTTPClient))
 	}
+	opts = append(opts, option.WithMiddleware(openaiTraceMiddleware, rateLimitMiddleware))
 
 	client := openai.NewClient(opts...)
 	maxTokens := cfg.MaxTokens
@@ -136,7 +137,13 @@ func (m *openaiResponsesModel) CompleteStream(ctx context.Context, req Request,
 						acc = &responsesToolCallAccumulator{id: event.ItemID}
 						accumulatedCalls[event.ItemID] = acc
 					}
-					acc.arguments.WriteString(event.Arguments)
+					acc.arguments.WriteString(event.Delta.OfString)
+					if event.Delta.OfString != "" {
+						delta := &ToolCallDelta{Index: int(event.OutputIndex), PartialJSON: event.Delta.OfString}
+						if err := cb(StreamResult{ToolCallDelta: delta}); err != nil {
+							return err
+						}
+					}
 				}
 
 			case "response.function_call_arguments.done":
@@ -162,6 +169,13 @@ func (m *openaiResponsesModel) CompleteStream(ctx context.Context, req Request,
 					}
 					acc.name = event.Item.Name
 					acc.callID = event.Item.CallID
+					id := acc.callID
+					if id == "" {
+						id = acc.id
+					}
+					if err := cb(StreamResult{ToolCallDelta: &ToolCallDelta{Index: int(event.OutputIndex), ID: id, Name: acc.name}}); err != nil {
+						return err
+					}
 				}
 
 			case "response.completed":
@@ -192,7 +206,7 @@ func (m *openaiResponsesModel) CompleteStream(ctx context.Context, req Request,
 		// Determine stop reason
 		stopReason := "stop"
 		if finalResponse != nil && finalResponse.Status != "" {
-			stopReason = string(finalResponse.Status)
+			stopReason = responsesStopReason(finalResponse)
 		}
 		if len(toolCalls) > 0 {
 			stopReason = "tool_calls"
@@ -284,26 +298,7 @@ func (m *openaiResponsesModel) selectModel(override string) string {
 }
 
 func (m *openaiResponsesModel) doWithRetry(ctx context.Context, fn func(context.Context) error) error {
-	attempts := 0
-	for {
-		err := fn(ctx)
-		if err == nil {
-			return nil
-		}
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-		if !isOpenAIRetryable(err) || attempts >= m.maxRetries {
-			return err
-		}
-		attempts++
-		backoff := time.Duration(attempts*attempts) * 100 * time.Millisecond
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(backoff):
-		}
-	}
+	return retry.Loop{Stage: "openai_responses", MaxRetries: m.maxRetries, Retryable: isOpenAIRetryable}.Do(ctx, fn)
 }
 
 func buildResponsesInput(msgs []Message) responses.ResponseNewParamsInputUnion {
@@ -395,7 +390,7 @@ func convertResponsesAPIResponse(resp *responses.Response) *Response {
 	}
 
 	// Determine stop reason
-	stopReason := string(resp.Status)
+	stopReason := responsesStopReason(resp)
 	if len(toolCalls) > 0 {
 		stopReason = "tool_calls"
 	}
@@ -418,3 +413,12 @@ func convertResponsesUsage(usage responses.ResponseUsage) Usage {
 		TotalTokens:  int(usage.TotalTokens),
 	}
 }
+
+// responsesStopReason reports why an incomplete response stopped
+// (max_output_tokens, content_filter) rather than the bare status.
+func responsesStopReason(resp *responses.Response) string {
+	if resp.Status == responses.ResponseStatusIncomplete && resp.IncompleteDetails.Reason != "" {
+		return resp.IncompleteDetails.Reason
+	}
+	return string(resp.Status)
+}
@@ -697,6 +697,22 @@ func TestBuildOpenAIToolResults(t *testing.T) {
 
 		assert.Len(t, results, 1)
 	})
+
+	t.Run("media is described as text", func(t *testing.T) {
+		msg := Message{
+			Role: "tool",
+			ToolCalls: []ToolCall{{
+				ID:           "call_1",
+				Result:       "captured",
+				ResultBlocks: []ContentBlock{{Type: ContentBlockImage, MediaType: "image/png", Data: "iVBORw=="}},
+			}},
+		}
+		results := buildOpenAIToolResults(msg)
+
+		require.Len(t, results, 1)
+		require.NotNil(t, results[0].OfTool)
+		assert.Equal(t, "captured\n[image omitted: image/png, inline data]", results[0].OfTool.Content.OfString.Value)
+	})
 }
 
 func TestToolCallAccumulator(t *testing.T) {
@@ -36,14 +36,32 @@ type AnthropicProvider struct {
 	System      string
 	Temperature *float64
 	CacheTTL    time.Duration
+	// TokenSource authenticates with OAuth when no API key is configured.
+	TokenSource TokenSource
+	// Vertex calls Claude through Google Vertex AI instead of the
+	// Anthropic API.
+	Vertex *VertexConfig
+	// Retry sets an exponential backoff policy; see AnthropicConfig.Retry.
+	Retry *RetryPolicy
+	// PromptCache and PromptCacheTTL configure prompt caching; see
+	// AnthropicConfig.
+	PromptCache    bool
+	PromptCacheTTL PromptCacheTTL
+	// Keys rotates requests over several API keys through a KeyPool built
+	// on first use; APIKey and TokenSource are then ignored.
+	Keys []PoolKey
 
 	mu      sync.RWMutex
 	cached  Model
 	expires time.Time
+	pool    *KeyPool
 }
 
 // Model implements Provider with caching using double-checked locking.
 func (p *AnthropicProvider) Model(ctx context.Context) (Model, error) {
+	if len(p.Keys) > 0 {
+		return p.keyPool()
+	}
 	// Fast path: check cache with read lock
 	if mdl := p.cachedModel(); mdl != nil {
 		return mdl, nil
@@ -58,14 +76,24 @@ func (p *AnthropicProvider) Model(ctx context.Context) (Model, error) {
 		return p.cached, nil
 	}
 
+	apiKey := p.resolveAPIKey()
+	if p.TokenSource != nil && strings.TrimSpace(p.APIKey) == "" {
+		// An explicit token source wins over keys from the environment.
+		apiKey = ""
+	}
 	mdl, err := NewAnthropic(AnthropicConfig{
-		APIKey:      p.resolveAPIKey(),
-		BaseURL:     strings.TrimSpace(p.BaseURL),
-		Model:       strings.TrimSpace(p.ModelName),
-		MaxTokens:   p.MaxTokens,
-		MaxRetries:  p.MaxRetries,
-		System:      p.System,
-		Temperature: p.Temperature,
+		APIKey:         apiKey,
+		BaseURL:        strings.TrimSpace(p.BaseURL),
+		Model:          strings.TrimSpace(p.ModelName),
+		MaxTokens:      p.MaxTokens,
+		MaxRetries:     p.MaxRetries,
+		System:         p.System,
+		Temperature:    p.Temperature,
+		TokenSource:    p.TokenSource,
+		Vertex:         p.Vertex,
+		Retry:          p.Retry,
+		PromptCache:    p.PromptCache,
+		PromptCacheTTL: p.PromptCacheTTL,
 	})
 	if err != nil {
 		return nil, err
@@ -79,6 +107,33 @@ func (p *AnthropicProvider) Model(ctx context.Context) (Model, error) {
 	return mdl, nil
 }
 
+func (p *AnthropicProvider) keyPool() (*KeyPool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pool != nil {
+		return p.pool, nil
+	}
+	pool, err := NewKeyPool(KeyPoolConfig{Keys: p.Keys, New: func(key PoolKey) (Model, error) {
+		return NewAnthropic(AnthropicConfig{
+			APIKey:         strings.TrimSpace(key.APIKey),
+			BaseURL:        strings.TrimSpace(p.BaseURL),
+			Model:          strings.TrimSpace(p.ModelName),
+			MaxTokens:      p.MaxTokens,
+			MaxRetries:     p.MaxRetries,
+			System:         p.System,
+			Temperature:    p.Temperature,
+			Retry:          p.Retry,
+			PromptCache:    p.PromptCache,
+			PromptCacheTTL: p.PromptCacheTTL,
+		})
+	}})
+	if err != nil {
+		return nil, err
+	}
+	p.pool = pool
+	return pool, nil
+}
+
 func (p *AnthropicProvider) resolveAPIKey() string {
 	if key := strings.TrimSpace(p.APIKey); key != "" {
 		return key
@@ -124,14 +179,21 @@ type OpenAIProvider struct {
 	System      string
 	Temperature *float64
 	CacheTTL    time.Duration
+	// Keys rotates requests over several API keys through a KeyPool built
+	// on first use; APIKey is then ignored.
+	Keys []PoolKey
 
 	mu      sync.RWMutex
 	cached  Model
 	expires time.Time
+	pool    *KeyPool
 }
 
 // Model implements Provider with caching using double-checked locking.
 func (p *OpenAIProvider) Model(ctx context.Context) (Model, error) {
+	if len(p.Keys) > 0 {
+		return p.keyPool()
+	}
 	// Fast path: check cache with read lock
 	if mdl := p.cachedModel(); mdl != nil {
 		return mdl, nil
@@ -167,6 +229,30 @@ func (p *OpenAIProvider) Model(ctx context.Context) (Model, error) {
 	return mdl, nil
 }
 
+func (p *OpenAIProvider) keyPool() (*KeyPool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pool != nil {
+		return p.pool, nil
+	}
+	pool, err := NewKeyPool(KeyPoolConfig{Keys: p.Keys, New: func(key PoolKey) (Model, error) {
+		return NewOpenAI(OpenAIConfig{
+			APIKey:      strings.TrimSpace(key.APIKey),
+			BaseURL:     strings.TrimSpace(p.BaseURL),
+			Model:       strings.TrimSpace(p.ModelName),
+			MaxTokens:   p.MaxTokens,
+			MaxRetries:  p.MaxRetries,
+			System:      p.System,
+			Temperature: p.Temperature,
+		})
+	}})
+	if err != nil {
+		return nil, err
+	}
+	p.pool = pool
+	return pool, nil
+}
+
 func (p *OpenAIProvider) resolveAPIKey() string {
 	if key := strings.TrimSpace(p.APIKey); key != "" {
 		return key
@@ -0,0 +1,311 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults applied to zero RateLimiter fields.
+const (
+	defaultRateLimitReserve  = 0.1
+	defaultRateLimitMaxDelay = time.Minute
+	defaultRateLimitBackoff  = time.Second
+)
+
+// RateLimitWindow is one provider quota as last reported in response
+// headers, e.g. requests or input tokens per minute.
+type RateLimitWindow struct {
+	Limit     int64
+	Remaining int64
+	Reset     time.Time
+}
+
+// RateLimitStatus is the tracked state of one provider key.
+type RateLimitStatus struct {
+	// Key identifies the provider host and credential; the credential is
+	// hashed and never stored.
+	Key string
+	// Windows holds quotas by name: "requests", "tokens", "input-tokens",
+	// "output-tokens".
+	Windows map[string]RateLimitWindow
+	// BlockedUntil is set by a 429 response and its Retry-After hint.
+	BlockedUntil time.Time
+	// Delayed counts calls that were held back, Delay their total wait.
+	Delayed   int
+	Delay     time.Duration
+	UpdatedAt time.Time
+}
+
+// RateLimiter tracks provider rate-limit headers per API key and paces
+// calls that share a key. While every quota has headroom calls go straight
+// through. Once a quota drops to its reserve the remaining calls are spread
+// evenly until the window resets, an exhausted quota holds calls until the
+// reset, and a 429 holds every call on the key for the Retry-After period.
+// Callers are delayed rather than failed; only context cancellation ends a
+// wait early.
+//
+// Share one RateLimiter between runtimes that use the same keys. It is
+// attached to calls with ContextWithRateLimiter and consulted by the
+// Anthropic and OpenAI providers.
+type RateLimiter struct {
+	// Reserve is the fraction of a quota below which calls are paced; zero
+	// uses 0.1.
+	Reserve float64
+	// MaxDelay caps a single wait so stale or skewed reset times cannot
+	// stall a run; zero uses one minute.
+	MaxDelay time.Duration
+
+	mu     sync.Mutex
+	states map[string]*rateLimitState
+	now    func() time.Time
+}
+
+type rateLimitState struct {
+	windows      map[string]RateLimitWindow
+	blockedUntil time.Time
+	nextSlot     time.Time
+	delayed      int
+	delay        time.Duration
+	updated      time.Time
+}
+
+// NewRateLimiter returns a RateLimiter with default settings.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{}
+}
+
+// Wait blocks until a call on key may proceed and reserves its slot.
+func (r *RateLimiter) Wait(ctx context.Context, key string) error {
+	if r == nil {
+		return nil
+	}
+	d := r.reserve(key)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (r *RateLimiter) reserve(key string) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st, ok := r.states[key]
+	if !ok {
+		return 0
+	}
+	now := r.clock()
+	reserve := r.Reserve
+	if reserve <= 0 {
+		reserve = defaultRateLimitReserve
+	}
+
+	slot := now
+	if st.nextSlot.After(slot) {
+		slot = st.nextSlot
+	}
+	if st.blockedUntil.After(slot) {
+		slot = st.blockedUntil
+	}
+	var gap time.Duration
+	for name, w := range st.windows {
+		if w.Limit <= 0 || !w.Reset.After(now) {
+			continue
+		}
+		switch {
+		case w.Remaining <= 0:
+			if w.Reset.After(slot) {
+				slot = w.Reset
+			}
+		case float64(w.Remaining) <= float64(w.Limit)*reserve:
+			gap = max(gap, w.Reset.Sub(now)/time.Duration(w.Remaining+1))
+		}
+		if name == "requests" && w.Remaining > 0 {
+			w.Remaining--
+			st.windows[name] = w
+		}
+	}
+	st.nextSlot = slot.Add(gap)
+
+	maxDelay := r.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRateLimitMaxDelay
+	}
+	d := min(slot.Sub(now), maxDelay)
+	if d > 0 {
+		st.delayed++
+		st.delay += d
+	}
+	return d
+}
+
+// Observe records the rate-limit headers of a response on key. A 429
+// blocks the key for the Retry-After period, or one second without one.
+func (r *RateLimiter) Observe(key string, status int, header http.Header) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.clock()
+	if r.states == nil {
+		r.states = map[string]*rateLimitState{}
+	}
+	st, ok := r.states[key]
+	if !ok {
+		st = &rateLimitState{windows: map[string]RateLimitWindow{}}
+		r.states[key] = st
+	}
+	for name, w := range parseRateLimitHeaders(header, now) {
+		st.windows[name] = w
+	}
+	if status == http.StatusTooManyRequests {
+		wait, ok := parseRetryAfter(header, now)
+		if !ok {
+			wait = defaultRateLimitBackoff
+		}
+		st.blockedUntil = now.Add(wait)
+	}
+	st.updated = now
+}
+
+// Status reports every tracked key, sorted by key.
+func (r *RateLimiter) Status() []RateLimitStatus {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]RateLimitStatus, 0, len(r.states))
+	for key, st := range r.states {
+		windows := make(map[string]RateLimitWindow, len(st.windows))
+		for name, w := range st.windows {
+			windows[name] = w
+		}
+		out = append(out, RateLimitStatus{
+			Key:          key,
+			Windows:      windows,
+			BlockedUntil: st.blockedUntil,
+			Delayed:      st.delayed,
+			Delay:        st.delay,
+			UpdatedAt:    st.updated,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func (r *RateLimiter) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+type rateLimiterCtxKey struct{}
+
+// ContextWithRateLimiter attaches r to ctx so provider calls made with ctx
+// are paced and reported to it.
+func ContextWithRateLimiter(ctx context.Context, r *RateLimiter) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rateLimiterCtxKey{}, r)
+}
+
+// RateLimiterFromContext returns the RateLimiter attached to ctx, if any.
+func RateLimiterFromContext(ctx context.Context) *RateLimiter {
+	if ctx == nil {
+		return nil
+	}
+	r, _ := ctx.Value(rateLimiterCtxKey{}).(*RateLimiter)
+	return r
+}
+
+// rateLimitMiddleware paces provider requests through the RateLimiter of
+// the request context and feeds it the response headers. The Anthropic and
+// OpenAI SDKs share the middleware signature.
+func rateLimitMiddleware(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	r := RateLimiterFromContext(req.Context())
+	if r == nil {
+		return next(req)
+	}
+	key := rateLimitKey(req)
+	if err := r.Wait(req.Context(), key); err != nil {
+		return nil, err
+	}
+	resp, err := next(req)
+	if resp != nil {
+		r.Observe(key, resp.StatusCode, resp.Header)
+	}
+	return resp, err
+}
+
+// rateLimitKey identifies the quota a request draws from: the provider host
+// plus a digest of the credential it carries.
+func rateLimitKey(req *http.Request) string {
+	cred := req.Header.Get("x-api-key")
+	if cred == "" {
+		cred = req.Header.Get("api-key")
+	}
+	if cred == "" {
+		cred = req.Header.Get("Authorization")
+	}
+	host := req.URL.Host
+	if cred == "" {
+		return host
+	}
+	sum := sha256.Sum256([]byte(cred))
+	return host + "/" + hex.EncodeToString(sum[:4])
+}
+
+var rateLimitWindowNames = []string{"requests", "tokens", "input-tokens", "output-tokens"}
+
+// parseRateLimitHeaders reads the Anthropic (anthropic-ratelimit-<name>-*,
+// RFC 3339 resets) and OpenAI (x-ratelimit-*-<name>, duration resets)
+// header families.
+func parseRateLimitHeaders(header http.Header, now time.Time) map[string]RateLimitWindow {
+	out := map[string]RateLimitWindow{}
+	for _, name := range rateLimitWindowNames {
+		prefix := "anthropic-ratelimit-" + name + "-"
+		if w, ok := parseRateLimitWindow(header.Get(prefix+"limit"), header.Get(prefix+"remaining")); ok {
+			if at, err := time.Parse(time.RFC3339, strings.TrimSpace(header.Get(prefix+"reset"))); err == nil {
+				w.Reset = at
+			}
+			out[name] = w
+			continue
+		}
+		if w, ok := parseRateLimitWindow(header.Get("x-ratelimit-limit-"+name), header.Get("x-ratelimit-remaining-"+name)); ok {
+			if d, err := time.ParseDuration(strings.TrimSpace(header.Get("x-ratelimit-reset-" + name))); err == nil {
+				w.Reset = now.Add(d)
+			}
+			out[name] = w
+		}
+	}
+	return out
+}
+
+func parseRateLimitWindow(limit, remaining string) (RateLimitWindow, bool) {
+	l, err := strconv.ParseInt(strings.TrimSpace(limit), 10, 64)
+	if err != nil {
+		return RateLimitWindow{}, false
+	}
+	r, err := strconv.ParseInt(strings.TrimSpace(remaining), 10, 64)
+	if err != nil {
+		return RateLimitWindow{}, false
+	}
+	return RateLimitWindow{Limit: l, Remaining: r}, true
+}
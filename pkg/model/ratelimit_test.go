@@ -0,0 +1,124 @@
+package model
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := http.Header{}
+	h.Set("anthropic-ratelimit-requests-limit", "50")
+	h.Set("anthropic-ratelimit-requests-remaining", "49")
+	h.Set("anthropic-ratelimit-requests-reset", "2025-01-01T00:00:30Z")
+	h.Set("x-ratelimit-limit-tokens", "1000")
+	h.Set("x-ratelimit-remaining-tokens", "10")
+	h.Set("x-ratelimit-reset-tokens", "6m0s")
+	h.Set("anthropic-ratelimit-output-tokens-limit", "bogus")
+
+	got := parseRateLimitHeaders(h, now)
+	if len(got) != 2 {
+		t.Fatalf("windows = %+v", got)
+	}
+	if w := got["requests"]; w.Limit != 50 || w.Remaining != 49 || !w.Reset.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("requests = %+v", w)
+	}
+	if w := got["tokens"]; w.Limit != 1000 || w.Remaining != 10 || !w.Reset.Equal(now.Add(6*time.Minute)) {
+		t.Fatalf("tokens = %+v", w)
+	}
+}
+
+func TestRateLimiterPacing(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rl := &RateLimiter{now: func() time.Time { return now }}
+	header := func(remaining string) http.Header {
+		h := http.Header{}
+		h.Set("anthropic-ratelimit-requests-limit", "100")
+		h.Set("anthropic-ratelimit-requests-remaining", remaining)
+		h.Set("anthropic-ratelimit-requests-reset", now.Add(10*time.Second).Format(time.RFC3339))
+		return h
+	}
+
+	if d := rl.reserve("unknown"); d != 0 {
+		t.Fatalf("untracked key delayed %v", d)
+	}
+
+	rl.Observe("a", http.StatusOK, header("50"))
+	if d := rl.reserve("a"); d != 0 {
+		t.Fatalf("headroom delayed %v", d)
+	}
+
+	// Four left of a hundred: calls are spread over the rest of the window.
+	rl.Observe("a", http.StatusOK, header("4"))
+	if d := rl.reserve("a"); d != 0 {
+		t.Fatalf("first paced call delayed %v", d)
+	}
+	if d := rl.reserve("a"); d != 2*time.Second {
+		t.Fatalf("second paced call delayed %v, want 2s", d)
+	}
+
+	rl.Observe("a", http.StatusOK, header("0"))
+	if d := rl.reserve("a"); d != 10*time.Second {
+		t.Fatalf("exhausted quota delayed %v, want until reset", d)
+	}
+	if d := rl.reserve("b"); d != 0 {
+		t.Fatalf("other key delayed %v", d)
+	}
+
+	limited := http.Header{}
+	limited.Set("retry-after", "3")
+	rl.Observe("b", http.StatusTooManyRequests, limited)
+	if d := rl.reserve("b"); d != 3*time.Second {
+		t.Fatalf("429 delayed %v, want retry-after", d)
+	}
+
+	status := rl.Status()
+	if len(status) != 2 || status[0].Key != "a" || status[0].Delayed != 2 || status[1].BlockedUntil.IsZero() {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+func TestRateLimitMiddlewarePacesAnthropicCalls(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, time.Now())
+		mu.Unlock()
+		w.Header().Set("anthropic-ratelimit-requests-limit", "50")
+		w.Header().Set("anthropic-ratelimit-requests-remaining", "0")
+		w.Header().Set("anthropic-ratelimit-requests-reset", time.Now().Add(300*time.Millisecond).Format(time.RFC3339Nano))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"m","type":"message","role":"assistant","model":"claude","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer srv.Close()
+
+	mdl, err := NewAnthropic(AnthropicConfig{APIKey: "key", BaseURL: srv.URL, MaxRetries: 1})
+	if err != nil {
+		t.Fatalf("model: %v", err)
+	}
+	rl := NewRateLimiter()
+	ctx := ContextWithRateLimiter(context.Background(), rl)
+	req := Request{Messages: []Message{{Role: "user", Content: "hi"}}}
+	for i := 0; i < 2; i++ {
+		if _, err := mdl.Complete(ctx, req); err != nil {
+			t.Fatalf("complete %d: %v", i, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if gap := calls[1].Sub(calls[0]); gap < 200*time.Millisecond {
+		t.Fatalf("second call after %v, want it held until the reset", gap)
+	}
+	status := rl.Status()
+	if len(status) != 1 || status[0].Delayed != 1 || status[0].Windows["requests"].Limit != 50 {
+		t.Fatalf("status = %+v", status)
+	}
+}
@@ -0,0 +1,192 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	anthropicsdk "github.com/anthropics/anthropic-sdk-go"
+	"github.com/cexll/agentsdk-go/pkg/core/retry"
+	"github.com/openai/openai-go"
+)
+
+// Defaults applied to zero RetryPolicy fields.
+const (
+	defaultRetryAttempts   = 4
+	defaultRetryInitial    = 500 * time.Millisecond
+	defaultRetryMax        = 30 * time.Second
+	defaultRetryMultiplier = 2
+	defaultRetryJitter     = 0.2
+)
+
+// RetryPolicy configures retries of transient model errors with exponential
+// backoff. Zero fields use the defaults noted on each field.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of calls including the first; zero
+	// uses 4, one disables retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; zero uses 500ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps every wait, including Retry-After hints; zero uses 30s.
+	MaxBackoff time.Duration
+	// Multiplier grows the wait per retry; values below 1 use 2.
+	Multiplier float64
+	// Jitter randomizes each wait by up to ±Jitter of its value; zero uses
+	// 0.2, negative disables jitter.
+	Jitter float64
+	// IgnoreRetryAfter disables honouring Retry-After / retry-after-ms
+	// response headers.
+	IgnoreRetryAfter bool
+	// Retryable decides which errors are retried; nil uses IsTransientError.
+	Retryable func(error) bool
+}
+
+// Backoff returns the wait before retry n (1-based), jitter included.
+func (p RetryPolicy) Backoff(n int) time.Duration {
+	initial, maxWait := p.InitialBackoff, p.MaxBackoff
+	if initial <= 0 {
+		initial = defaultRetryInitial
+	}
+	if maxWait <= 0 {
+		maxWait = defaultRetryMax
+	}
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = defaultRetryMultiplier
+	}
+	wait := float64(initial) * math.Pow(mult, float64(n-1))
+	jitter := p.Jitter
+	if jitter == 0 {
+		jitter = defaultRetryJitter
+	}
+	if jitter > 0 {
+		wait += wait * jitter * (2*rand.Float64() - 1)
+	}
+	return min(time.Duration(wait), maxWait)
+}
+
+func (p RetryPolicy) loop(stage string) retry.Loop {
+	attempts := p.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultRetryAttempts
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsTransientError
+	}
+	l := retry.Loop{Stage: stage, MaxRetries: attempts - 1, Backoff: p.Backoff, Retryable: retryable}
+	if !p.IgnoreRetryAfter {
+		maxWait := p.MaxBackoff
+		if maxWait <= 0 {
+			maxWait = defaultRetryMax
+		}
+		l.RetryAfter = func(err error) (time.Duration, bool) {
+			d, ok := RetryAfter(err)
+			return min(d, maxWait), ok
+		}
+	}
+	return l
+}
+
+// WithRetry wraps any Model so transient failures are retried according to
+// policy. Streaming calls are only retried while nothing has reached the
+// handler, so callers never see duplicated output.
+func WithRetry(m Model, policy RetryPolicy) Model {
+	if m == nil {
+		return nil
+	}
+	return &retryModel{inner: m, policy: policy}
+}
+
+type retryModel struct {
+	inner  Model
+	policy RetryPolicy
+}
+
+func (m *retryModel) Complete(ctx context.Context, req Request) (*Response, error) {
+	var resp *Response
+	err := m.policy.loop("model").Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = m.inner.Complete(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (m *retryModel) CompleteStream(ctx context.Context, req Request, cb StreamHandler) error {
+	emitted := false
+	l := m.policy.loop("model")
+	retryable := l.Retryable
+	l.Retryable = func(err error) bool { return !emitted && retryable(err) }
+	return l.Do(ctx, func(ctx context.Context) error {
+		return m.inner.CompleteStream(ctx, req, func(res StreamResult) error {
+			emitted = true
+			if cb == nil {
+				return nil
+			}
+			return cb(res)
+		})
+	})
+}
+
+// IsTransientError reports whether err is worth retrying: HTTP 408, 409,
+// 429 and 5xx (including Anthropic's 529 overloaded), overloaded errors
+// delivered mid-stream, and transport failures. Context cancellation is
+// never transient.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if code, ok := errorStatusCode(err); ok {
+		return code == http.StatusRequestTimeout || code == http.StatusConflict || code == http.StatusTooManyRequests || code >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "overloaded_error") || strings.Contains(msg, "rate_limit_error") || strings.Contains(msg, "api_error")
+}
+
+// RetryAfter extracts the wait requested by the provider through the
+// retry-after-ms or Retry-After response headers (seconds or HTTP date).
+func RetryAfter(err error) (time.Duration, bool) {
+	var header http.Header
+	var anthropicErr *anthropicsdk.Error
+	var openaiErr *openai.Error
+	var hinted interface{ RetryAfter() time.Duration }
+	switch {
+	case errors.As(err, &hinted):
+		return hinted.RetryAfter(), true
+	case errors.As(err, &anthropicErr) && anthropicErr.Response != nil:
+		header = anthropicErr.Response.Header
+	case errors.As(err, &openaiErr) && openaiErr.Response != nil:
+		header = openaiErr.Response.Header
+	default:
+		return 0, false
+	}
+	return parseRetryAfter(header, time.Now())
+}
+
+func parseRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms >= 0 {
+		return time.Duration(ms * float64(time.Millisecond)), true
+	}
+	raw := strings.TrimSpace(header.Get("Retry-After"))
+	if raw == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseFloat(raw, 64); err == nil && secs >= 0 {
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	if at, err := http.ParseTime(raw); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithRetryRetriesTransientErrors(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Jitter: -1}
+	flaky := &scriptedModel{errs: []error{statusError(529), statusError(http.StatusTooManyRequests)}}
+	resp, err := WithRetry(flaky, policy).Complete(context.Background(), Request{})
+	if err != nil || resp == nil || flaky.calls != 3 {
+		t.Fatalf("resp=%v err=%v calls=%d", resp, err, flaky.calls)
+	}
+
+	bad := &scriptedModel{errs: []error{statusError(http.StatusBadRequest)}}
+	if _, err := WithRetry(bad, policy).Complete(context.Background(), Request{}); err == nil || bad.calls != 1 {
+		t.Fatalf("400 must not be retried, err=%v calls=%d", err, bad.calls)
+	}
+
+	stream := &scriptedModel{errs: []error{errors.New(`stream error: {"type":"overloaded_error"}`)}}
+	var finals int
+	err = WithRetry(stream, policy).CompleteStream(context.Background(), Request{}, func(res StreamResult) error {
+		finals++
+		return nil
+	})
+	if err != nil || stream.calls != 2 || finals != 1 {
+		t.Fatalf("stream err=%v calls=%d finals=%d", err, stream.calls, finals)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: -1}
+	if got := []time.Duration{p.Backoff(1), p.Backoff(2), p.Backoff(3), p.Backoff(10)}; got[0] != 100*time.Millisecond || got[1] != 200*time.Millisecond || got[2] != 400*time.Millisecond || got[3] != time.Second {
+		t.Fatalf("backoff = %v", got)
+	}
+	p.Jitter = 0.5
+	for i := 0; i < 50; i++ {
+		if d := p.Backoff(1); d < 50*time.Millisecond || d > 150*time.Millisecond {
+			t.Fatalf("jittered backoff %s out of range", d)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		header http.Header
+		want   time.Duration
+		ok     bool
+	}{
+		{http.Header{"Retry-After-Ms": {"250"}}, 250 * time.Millisecond, true},
+		{http.Header{"Retry-After": {"3"}}, 3 * time.Second, true},
+		{http.Header{"Retry-After": {now.Add(2 * time.Second).Format(http.TimeFormat)}}, 2 * time.Second, true},
+		{http.Header{"Retry-After": {"soon"}}, 0, false},
+		{http.Header{}, 0, false},
+	}
+	for _, tc := range cases {
+		got, ok := parseRetryAfter(tc.header, now)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("parseRetryAfter(%v) = %s, %v", tc.header, got, ok)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	anthropicsdk "github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// defaultRouterCooldown is how long a failed provider is skipped.
+const defaultRouterCooldown = 30 * time.Second
+
+// ErrNoProviders is returned by NewRouter when no provider is configured.
+var ErrNoProviders = errors.New("model router: no providers configured")
+
+// RouterProvider is one backend behind a Router.
+type RouterProvider struct {
+	// Name identifies the provider in Usage.Provider and Status.
+	Name  string
+	Model Model
+	// Priority orders providers; lower values are tried first. Providers
+	// with equal priority keep their configuration order.
+	Priority int
+}
+
+// RouterConfig configures NewRouter.
+type RouterConfig struct {
+	Providers []RouterProvider
+	// Cooldown is how long a provider that failed with 429, 5xx or a
+	// transport error is skipped; zero uses 30s. After the cooldown the next
+	// request probes it again.
+	Cooldown time.Duration
+	// HealthCheck optionally probes a provider; CheckHealth and
+	// RunHealthChecks use it to mark providers up or down between requests.
+	HealthCheck func(ctx context.Context, provider RouterProvider) error
+	// HealthInterval is the RunHealthChecks period; zero uses Cooldown.
+	HealthInterval time.Duration
+}
+
+// ProviderStatus reports a provider's routing state.
+type ProviderStatus struct {
+	Name      string    `json:"name"`
+	Priority  int       `json:"priority"`
+	Healthy   bool      `json:"healthy"`
+	LastError string    `json:"last_error,omitempty"`
+	DownUntil time.Time `json:"down_until,omitempty"`
+}
+
+// Router is a Model that sends each request to the highest-priority healthy
+// provider and fails over to the next one on rate limits (429), server
+// errors (5xx) and transport failures. Other errors, such as invalid
+// requests or authentication failures, are returned as-is. The serving
+// provider's name is recorded in Response.Usage.Provider.
+type Router struct {
+	providers []RouterProvider
+	cooldown  time.Duration
+	check     func(context.Context, RouterProvider) error
+	interval  time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	state map[string]*providerState
+}
+
+type providerState struct {
+	downUntil time.Time
+	lastErr   string
+}
+
+// NewRouter validates cfg and builds a Router.
+func NewRouter(cfg RouterConfig) (*Router, error) {
+	if len(cfg.Providers) == 0 {
+		return nil, ErrNoProviders
+	}
+	providers := append([]RouterProvider(nil), cfg.Providers...)
+	state := make(map[string]*providerState, len(providers))
+	for i, p := range providers {
+		name := strings.TrimSpace(p.Name)
+		if name == "" {
+			return nil, fmt.Errorf("model router: provider %d: name is required", i)
+		}
+		if p.Model == nil {
+			return nil, fmt.Errorf("model router: provider %s: model is nil", name)
+		}
+		if _, dup := state[name]; dup {
+			return nil, fmt.Errorf("model router: duplicate provider %s", name)
+		}
+		providers[i].Name = name
+		state[name] = &providerState{}
+	}
+	sort.SliceStable(providers, func(i, j int) bool { return providers[i].Priority < providers[j].Priority })
+	cooldown := cfg.Cooldown
+	if cooldown <= 0 {
+		cooldown = defaultRouterCooldown
+	}
+	interval := cfg.HealthInterval
+	if interval <= 0 {
+		interval = cooldown
+	}
+	return &Router{
+		providers: providers,
+		cooldown:  cooldown,
+		check:     cfg.HealthCheck,
+		interval:  interval,
+		now:       time.Now,
+		state:     state,
+	}, nil
+}
+
+// Complete implements Model.
+func (r *Router) Complete(ctx context.Context, req Request) (*Response, error) {
+	var errs []error
+	for _, p := range r.candidates() {
+		resp, err := p.Model.Complete(ctx, req)
+		if err == nil {
+			r.markUp(p.Name)
+			if resp != nil && resp.Usage.Provider == "" {
+				resp.Usage.Provider = p.Name
+			}
+			return resp, nil
+		}
+		if !r.failover(ctx, p.Name, err) {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+	}
+	return nil, fmt.Errorf("model router: all providers failed: %w", errors.Join(errs...))
+}
+
+// CompleteStream implements Model. Failover only happens before the first
+// update reaches cb; once output has streamed, errors are returned.
+func (r *Router) CompleteStream(ctx context.Context, req Request, cb StreamHandler) error {
+	var errs []error
+	for _, p := range r.candidates() {
+		name := p.Name
+		emitted := false
+		err := p.Model.CompleteStream(ctx, req, func(res StreamResult) error {
+			emitted = true
+			if res.Response != nil && res.Response.Usage.Provider == "" {
+				res.Response.Usage.Provider = name
+			}
+			if cb == nil {
+				return nil
+			}
+			return cb(res)
+		})
+		if err == nil {
+			r.markUp(name)
+			return nil
+		}
+		if emitted || !r.failover(ctx, name, err) {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+	return fmt.Errorf("model router: all providers failed: %w", errors.Join(errs...))
+}
+
+// Status reports every provider in priority order.
+func (r *Router) Status() []ProviderStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	out := make([]ProviderStatus, 0, len(r.providers))
+	for _, p := range r.providers {
+		st := r.state[p.Name]
+		status := ProviderStatus{Name: p.Name, Priority: p.Priority, Healthy: !now.Before(st.downUntil), LastError: st.lastErr}
+		if !status.Healthy {
+			status.DownUntil = st.downUntil
+		}
+		out = append(out, status)
+	}
+	return out
+}
+
+// CheckHealth runs RouterConfig.HealthCheck against every provider and
+// updates their state. It is a no-op without a health check.
+func (r *Router) CheckHealth(ctx context.Context) {
+	if r.check == nil {
+		return
+	}
+	for _, p := range r.providers {
+		if err := r.check(ctx, p); err != nil {
+			r.markDown(p.Name, err)
+			continue
+		}
+		r.markUp(p.Name)
+	}
+}
+
+// RunHealthChecks calls CheckHealth every HealthInterval until ctx is done.
+func (r *Router) RunHealthChecks(ctx context.Context) {
+	if r.check == nil {
+		return
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.CheckHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// candidates returns healthy providers first, then cooling-down ones as a
+// last resort, each group in priority order.
+func (r *Router) candidates() []RouterProvider {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	healthy := make([]RouterProvider, 0, len(r.providers))
+	var down []RouterProvider
+	for _, p := range r.providers {
+		if now.Before(r.state[p.Name].downUntil) {
+			down = append(down, p)
+			continue
+		}
+		healthy = append(healthy, p)
+	}
+	return append(healthy, down...)
+}
+
+// failover records err against the provider and reports whether the next
+// provider should be tried.
+func (r *Router) failover(ctx context.Context, name string, err error) bool {
+	if ctx.Err() != nil || !IsFailoverError(err) {
+		return false
+	}
+	r.markDown(name, err)
+	return true
+}
+
+func (r *Router) markDown(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.state[name]
+	st.downUntil = r.now().Add(r.cooldown)
+	st.lastErr = err.Error()
+}
+
+func (r *Router) markUp(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.state[name]
+	st.downUntil = time.Time{}
+	st.lastErr = ""
+}
+
+// IsFailoverError reports whether err indicates the provider, rather than
+// the request, is at fault: HTTP 429 or 5xx from a provider SDK, or a
+// transport failure. Context cancellation never fails over.
+func IsFailoverError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if code, ok := errorStatusCode(err); ok {
+		return code == http.StatusTooManyRequests || code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func errorStatusCode(err error) (int, bool) {
+	var anthropicErr *anthropicsdk.Error
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.StatusCode, true
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode, true
+	}
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		return statusErr.HTTPStatusCode(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type statusError int
+
+func (e statusError) Error() string       { return http.StatusText(int(e)) }
+func (e statusError) HTTPStatusCode() int { return int(e) }
+
+type scriptedModel struct {
+	errs  []error
+	calls int
+}
+
+func (m *scriptedModel) next() error {
+	m.calls++
+	if len(m.errs) == 0 {
+		return nil
+	}
+	err := m.errs[0]
+	m.errs = m.errs[1:]
+	return err
+}
+
+func (m *scriptedModel) Complete(context.Context, Request) (*Response, error) {
+	if err := m.next(); err != nil {
+		return nil, err
+	}
+	return &Response{Message: Message{Role: "assistant", Content: "ok"}}, nil
+}
+
+func (m *scriptedModel) CompleteStream(_ context.Context, _ Request, cb StreamHandler) error {
+	if err := m.next(); err != nil {
+		return err
+	}
+	return cb(StreamResult{Final: true, Response: &Response{Message: Message{Role: "assistant", Content: "ok"}}})
+}
+
+func TestRouterFailsOverAndAttributesProvider(t *testing.T) {
+	primary := &scriptedModel{errs: []error{statusError(http.StatusTooManyRequests)}}
+	backup := &scriptedModel{}
+	r, err := NewRouter(RouterConfig{
+		Providers: []RouterProvider{{Name: "backup", Model: backup, Priority: 2}, {Name: "primary", Model: primary, Priority: 1}},
+		Cooldown:  time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	now := time.Unix(0, 0)
+	r.now = func() time.Time { return now }
+
+	resp, err := r.Complete(context.Background(), Request{})
+	if err != nil || resp.Usage.Provider != "backup" {
+		t.Fatalf("resp=%+v err=%v", resp, err)
+	}
+	// Primary is cooling down, so the next request goes straight to backup.
+	var final *Response
+	if err := r.CompleteStream(context.Background(), Request{}, func(res StreamResult) error {
+		final = res.Response
+		return nil
+	}); err != nil || final.Usage.Provider != "backup" || primary.calls != 1 {
+		t.Fatalf("stream provider=%v primary calls=%d err=%v", final, primary.calls, err)
+	}
+	if status := r.Status(); status[0].Name != "primary" || status[0].Healthy || status[0].LastError == "" {
+		t.Fatalf("status %+v", status)
+	}
+
+	now = now.Add(time.Minute)
+	if resp, _ := r.Complete(context.Background(), Request{}); resp.Usage.Provider != "primary" {
+		t.Fatalf("primary should be probed after cooldown, got %s", resp.Usage.Provider)
+	}
+}
+
+func TestRouterReturnsRequestErrorsWithoutFailover(t *testing.T) {
+	primary := &scriptedModel{errs: []error{statusError(http.StatusBadRequest)}}
+	backup := &scriptedModel{}
+	r, _ := NewRouter(RouterConfig{Providers: []RouterProvider{{Name: "a", Model: primary}, {Name: "b", Model: backup}}})
+	if _, err := r.Complete(context.Background(), Request{}); err == nil || backup.calls != 0 {
+		t.Fatalf("400 must not fail over, err=%v backup calls=%d", err, backup.calls)
+	}
+
+	failing := &scriptedModel{errs: []error{statusError(502), statusError(503)}}
+	r, _ = NewRouter(RouterConfig{Providers: []RouterProvider{{Name: "a", Model: failing}, {Name: "b", Model: &scriptedModel{errs: []error{statusError(500)}}}}})
+	if _, err := r.Complete(context.Background(), Request{}); err == nil {
+		t.Fatal("expected error when every provider fails")
+	}
+
+	if _, err := NewRouter(RouterConfig{}); !errors.Is(err, ErrNoProviders) {
+		t.Fatalf("expected ErrNoProviders, got %v", err)
+	}
+}
+
+func TestRouterHealthChecks(t *testing.T) {
+	down := errors.New("unreachable")
+	r, _ := NewRouter(RouterConfig{
+		Providers: []RouterProvider{{Name: "a", Model: &scriptedModel{}}, {Name: "b", Model: &scriptedModel{}}},
+		HealthCheck: func(_ context.Context, p RouterProvider) error {
+			if p.Name == "a" {
+				return down
+			}
+			return nil
+		},
+	})
+	r.CheckHealth(context.Background())
+	if resp, _ := r.Complete(context.Background(), Request{}); resp.Usage.Provider != "b" {
+		t.Fatalf("unhealthy provider should be skipped, got %s", resp.Usage.Provider)
+	}
+}
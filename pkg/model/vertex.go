@@ -0,0 +1,108 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// vertexAnthropicVersion is the Messages API version Vertex expects in the
+// request body in place of the anthropic-version header.
+const vertexAnthropicVersion = "vertex-2023-10-16"
+
+// VertexConfig routes the Anthropic provider through Google Vertex AI.
+// Requests keep the Messages API shape; the transport moves the model into
+// the URL, adds the Vertex envelope and authenticates with Google
+// credentials.
+type VertexConfig struct {
+	// Region is the Vertex location, e.g. "us-east5", or "global". Defaults
+	// to CLOUD_ML_REGION.
+	Region string
+	// ProjectID defaults to ANTHROPIC_VERTEX_PROJECT_ID, GOOGLE_CLOUD_PROJECT,
+	// then the project of the resolved credentials.
+	ProjectID string
+	// TokenSource defaults to GoogleDefaultTokenSource (ADC).
+	TokenSource TokenSource
+	// BaseURL overrides the regional endpoint, mainly for tests and proxies.
+	BaseURL string
+}
+
+func (c VertexConfig) resolve() (VertexConfig, error) {
+	c.Region = firstNonEmpty(c.Region, os.Getenv("CLOUD_ML_REGION"))
+	if c.Region == "" {
+		return c, errors.New("vertex: region required")
+	}
+	c.ProjectID = firstNonEmpty(c.ProjectID, os.Getenv("ANTHROPIC_VERTEX_PROJECT_ID"), os.Getenv("GOOGLE_CLOUD_PROJECT"))
+	if c.TokenSource == nil {
+		ts, project, err := GoogleDefaultTokenSource()
+		if err != nil {
+			return c, err
+		}
+		c.TokenSource = ts
+		c.ProjectID = firstNonEmpty(c.ProjectID, project)
+	}
+	if c.ProjectID == "" {
+		return c, errors.New("vertex: project id required")
+	}
+	if c.BaseURL == "" {
+		if c.Region == "global" {
+			c.BaseURL = "https://aiplatform.googleapis.com/"
+		} else {
+			c.BaseURL = fmt.Sprintf("https://%s-aiplatform.googleapis.com/", c.Region)
+		}
+	}
+	return c, nil
+}
+
+// vertexMiddleware rewrites Messages API calls into Vertex rawPredict calls
+// and attaches a Google bearer token.
+func vertexMiddleware(cfg VertexConfig) option.Middleware {
+	auth := bearerMiddleware(cfg.TokenSource, "")
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		if req.Body != nil && req.Method == http.MethodPost {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			_ = req.Body.Close()
+			var payload map[string]json.RawMessage
+			if err := json.Unmarshal(body, &payload); err != nil {
+				return nil, fmt.Errorf("vertex: decode request: %w", err)
+			}
+			if _, ok := payload["anthropic_version"]; !ok {
+				payload["anthropic_version"] = json.RawMessage(`"` + vertexAnthropicVersion + `"`)
+			}
+			base := fmt.Sprintf("/v1/projects/%s/locations/%s/publishers/anthropic/models/", cfg.ProjectID, cfg.Region)
+			switch strings.TrimSuffix(req.URL.Path, "/") {
+			case "/v1/messages":
+				var model string
+				_ = json.Unmarshal(payload["model"], &model) //nolint:errcheck // validated by the API
+				var stream bool
+				_ = json.Unmarshal(payload["stream"], &stream) //nolint:errcheck // absent means false
+				delete(payload, "model")
+				verb := "rawPredict"
+				if stream {
+					verb = "streamRawPredict"
+				}
+				req.URL.Path = base + model + ":" + verb
+			case "/v1/messages/count_tokens":
+				req.URL.Path = base + "count-tokens:rawPredict"
+			}
+			body, err = json.Marshal(payload)
+			if err != nil {
+				return nil, fmt.Errorf("vertex: encode request: %w", err)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+			req.ContentLength = int64(len(body))
+		}
+		return auth(req, next)
+	}
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVertexRewritesMessagesRequest(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "env-key")
+	var gotPath, gotAuth, gotKey string
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth, gotKey = r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("X-Api-Key")
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, &body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"m","type":"message","role":"assistant","model":"claude","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer srv.Close()
+
+	provider := &AnthropicProvider{
+		ModelName:  "claude-sonnet-4@20250514",
+		MaxRetries: 1,
+		Vertex:     &VertexConfig{Region: "us-east5", ProjectID: "proj", TokenSource: staticTokenSource("gcp-at"), BaseURL: srv.URL},
+	}
+	mdl, err := provider.Model(context.Background())
+	if err != nil {
+		t.Fatalf("model: %v", err)
+	}
+	if _, err := mdl.Complete(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	want := "/v1/projects/proj/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:rawPredict"
+	if gotPath != want {
+		t.Fatalf("path = %q, want %q", gotPath, want)
+	}
+	if gotAuth != "Bearer gcp-at" || gotKey != "" {
+		t.Fatalf("unexpected auth headers auth=%q key=%q", gotAuth, gotKey)
+	}
+	if body["anthropic_version"] != vertexAnthropicVersion {
+		t.Fatalf("anthropic_version = %v", body["anthropic_version"])
+	}
+	if _, ok := body["model"]; ok {
+		t.Fatalf("model should be moved into the URL: %v", body)
+	}
+}
+
+func TestVertexConfigResolve(t *testing.T) {
+	t.Setenv("CLOUD_ML_REGION", "")
+	t.Setenv("ANTHROPIC_VERTEX_PROJECT_ID", "")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	if _, err := (VertexConfig{TokenSource: staticTokenSource("t")}).resolve(); err == nil {
+		t.Fatal("expected missing region error")
+	}
+	t.Setenv("CLOUD_ML_REGION", "europe-west1")
+	t.Setenv("ANTHROPIC_VERTEX_PROJECT_ID", "env-proj")
+	cfg, err := (VertexConfig{TokenSource: staticTokenSource("t")}).resolve()
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.ProjectID != "env-proj" || cfg.BaseURL != "https://europe-west1-aiplatform.googleapis.com/" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	cfg, err = (VertexConfig{Region: "global", TokenSource: staticTokenSource("t")}).resolve()
+	if err != nil || cfg.BaseURL != "https://aiplatform.googleapis.com/" {
+		t.Fatalf("global endpoint: %+v %v", cfg, err)
+	}
+}
+
+func TestGoogleServiceAccountCredentials(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("form: %v", err)
+		}
+		if r.Form.Get("grant_type") != googleJWTBearerType || strings.Count(r.Form.Get("assertion"), ".") != 2 {
+			t.Errorf("unexpected form %v", r.Form)
+		}
+		_, _ = w.Write([]byte(`{"access_token":"sa-at","expires_in":3600}`))
+	}))
+	defer srv.Close()
+
+	creds, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "sa-proj",
+		"client_email": "svc@sa-proj.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    srv.URL,
+	})
+	path := filepath.Join(t.TempDir(), "sa.json")
+	if err := os.WriteFile(path, creds, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+
+	ts, project, err := GoogleDefaultTokenSource()
+	if err != nil {
+		t.Fatalf("adc: %v", err)
+	}
+	if project != "sa-proj" {
+		t.Fatalf("project = %q", project)
+	}
+	for i := 0; i < 2; i++ {
+		tok, err := ts.Token(context.Background())
+		if err != nil || tok != "sa-at" {
+			t.Fatalf("token = %q, %v", tok, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected cached token, got %d token requests", calls.Load())
+	}
+}
@@ -30,7 +30,10 @@ type Builtins struct {
 	Commands  []CommandRegistration
 	Subagents []SubagentRegistration
 	Hooks     []corehooks.ShellHook
-	Errors    []error
+	// Prompts are the versioned prompt templates; load them with
+	// NewLibrary for api.Options.Prompts.
+	Prompts []Template
+	Errors  []error
 }
 
 // SkillRegistration wires a skill definition to its handler.
@@ -61,6 +64,8 @@ type ParseOptions struct {
 	SubagentsDir string
 	// HooksDir is the path to hooks directory (default: ".claude/hooks")
 	HooksDir string
+	// PromptsDir is the path to prompt templates (default: ".claude/prompts")
+	PromptsDir string
 	// Validate enables strict validation of parsed content
 	Validate bool
 }
@@ -72,6 +77,7 @@ func defaultOptions() ParseOptions {
 		CommandsDir:  ".claude/commands",
 		SubagentsDir: ".claude/agents",
 		HooksDir:     ".claude/hooks",
+		PromptsDir:   ".claude/prompts",
 		Validate:     false,
 	}
 }
@@ -96,6 +102,9 @@ func ParseWithOptions(fsys fs.FS, opts ParseOptions) Builtins {
 	if opts.HooksDir == "" {
 		opts.HooksDir = ".claude/hooks"
 	}
+	if opts.PromptsDir == "" {
+		opts.PromptsDir = ".claude/prompts"
+	}
 
 	var errs []error
 
@@ -111,11 +120,15 @@ func ParseWithOptions(fsys fs.FS, opts ParseOptions) Builtins {
 	hookRegs, hookErrs := parseHooks(fsys, opts.HooksDir)
 	errs = append(errs, hookErrs...)
 
+	promptTemplates, promptErrs := ParseTemplates(fsys, opts.PromptsDir)
+	errs = append(errs, promptErrs...)
+
 	return Builtins{
 		Skills:    skillRegs,
 		Commands:  cmdRegs,
 		Subagents: subagentRegs,
 		Hooks:     hookRegs,
+		Prompts:   promptTemplates,
 		Errors:    errs,
 	}
 }
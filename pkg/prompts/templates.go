@@ -0,0 +1,465 @@
+package prompts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrPromptNotFound is returned when a reference names no registered template.
+	ErrPromptNotFound = errors.New("prompts: template not found")
+	// ErrInvalidVariables is returned when render variables do not satisfy
+	// the template's variable schema.
+	ErrInvalidVariables = errors.New("prompts: invalid template variables")
+)
+
+// defaultPromptVersion is assigned to templates that declare no version.
+const defaultPromptVersion = "1"
+
+// bareVariableRegexp matches Jinja-style {{ name }} placeholders.
+var bareVariableRegexp = regexp.MustCompile(`\{\{(-?\s*)([A-Za-z_][A-Za-z0-9_]*)(\s*-?)\}\}`)
+
+// Variable describes one template input.
+type Variable struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Type is "string" (default), "number" or "boolean".
+	Type     string `yaml:"type,omitempty" json:"type,omitempty"`
+	Required bool   `yaml:"required,omitempty" json:"required,omitempty"`
+	// Default is used when the variable is not supplied.
+	Default any `yaml:"default,omitempty" json:"default,omitempty"`
+}
+
+// Template is a named, versioned prompt. Body uses Go text/template syntax
+// with variables available as {{ .name }}; declared variables may also be
+// written Jinja-style as {{ name }}.
+type Template struct {
+	Name        string     `yaml:"name" json:"name"`
+	Version     string     `yaml:"version,omitempty" json:"version,omitempty"`
+	Description string     `yaml:"description,omitempty" json:"description,omitempty"`
+	Variables   []Variable `yaml:"variables,omitempty" json:"variables,omitempty"`
+	Body        string     `yaml:"-" json:"body"`
+	// Source records where the template was loaded from.
+	Source string `yaml:"-" json:"source,omitempty"`
+}
+
+// Ref is the "name@version" reference pinning this template.
+func (t Template) Ref() string { return t.Name + "@" + t.Version }
+
+// Source supplies templates to a Library, e.g. a directory or a remote store.
+type Source interface {
+	Templates(ctx context.Context) ([]Template, error)
+}
+
+// Library holds compiled templates keyed by name and version. It is safe
+// for concurrent use.
+type Library struct {
+	mu        sync.RWMutex
+	templates map[string][]compiledTemplate // sorted by ascending version
+}
+
+type compiledTemplate struct {
+	Template
+	tmpl *template.Template
+}
+
+// NewLibrary compiles and registers templates.
+func NewLibrary(templates ...Template) (*Library, error) {
+	lib := &Library{}
+	for _, t := range templates {
+		if err := lib.Register(t); err != nil {
+			return nil, err
+		}
+	}
+	return lib, nil
+}
+
+// Register compiles t and adds it, replacing a template with the same name
+// and version.
+func (l *Library) Register(t Template) error {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Version = strings.TrimSpace(t.Version)
+	if t.Name == "" {
+		return errors.New("prompts: template name is required")
+	}
+	if strings.Contains(t.Name, "@") {
+		return fmt.Errorf("prompts: template name %q must not contain @", t.Name)
+	}
+	if t.Version == "" {
+		t.Version = defaultPromptVersion
+	}
+	seen := make(map[string]struct{}, len(t.Variables))
+	for i, v := range t.Variables {
+		if strings.TrimSpace(v.Name) == "" {
+			return fmt.Errorf("prompts: template %s: variable %d: name is required", t.Ref(), i)
+		}
+		if _, dup := seen[v.Name]; dup {
+			return fmt.Errorf("prompts: template %s: duplicate variable %q", t.Ref(), v.Name)
+		}
+		seen[v.Name] = struct{}{}
+		switch v.Type {
+		case "", "string", "number", "boolean":
+		default:
+			return fmt.Errorf("prompts: template %s: variable %s: unsupported type %q", t.Ref(), v.Name, v.Type)
+		}
+	}
+	tmpl, err := template.New(t.Ref()).Option("missingkey=error").Parse(expandBareVariables(t.Body, seen))
+	if err != nil {
+		return fmt.Errorf("prompts: template %s: %w", t.Ref(), err)
+	}
+	t.Variables = append([]Variable(nil), t.Variables...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.templates == nil {
+		l.templates = make(map[string][]compiledTemplate)
+	}
+	versions := l.templates[t.Name]
+	versions = withoutVersion(versions, t.Version)
+	versions = append(versions, compiledTemplate{Template: t, tmpl: tmpl})
+	sort.SliceStable(versions, func(i, j int) bool { return compareVersions(versions[i].Version, versions[j].Version) < 0 })
+	l.templates[t.Name] = versions
+	return nil
+}
+
+// Load registers every template from src.
+func (l *Library) Load(ctx context.Context, src Source) error {
+	templates, err := src.Templates(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, t := range templates {
+		if err := l.Register(t); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Get resolves ref: "name" selects the highest version, "name@version" pins
+// one.
+func (l *Library) Get(ref string) (Template, error) {
+	ct, err := l.lookup(ref)
+	if err != nil {
+		return Template{}, err
+	}
+	return ct.Template, nil
+}
+
+// Versions lists the registered versions of name in ascending order.
+func (l *Library) Versions(name string) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	versions := l.templates[strings.TrimSpace(name)]
+	out := make([]string, 0, len(versions))
+	for _, v := range versions {
+		out = append(out, v.Version)
+	}
+	return out
+}
+
+// List returns every registered template sorted by name and version.
+func (l *Library) List() []Template {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	names := make([]string, 0, len(l.templates))
+	for name := range l.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var out []Template
+	for _, name := range names {
+		for _, ct := range l.templates[name] {
+			out = append(out, ct.Template)
+		}
+	}
+	return out
+}
+
+// Render resolves ref and executes it with vars after checking them against
+// the template's variable schema. Missing optional variables take their
+// default; undeclared variables are passed through unchecked so one set of
+// variables can serve several templates.
+func (l *Library) Render(ref string, vars map[string]any) (string, error) {
+	ct, err := l.lookup(ref)
+	if err != nil {
+		return "", err
+	}
+	data, err := bindVariables(ct.Template, vars)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := ct.tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("prompts: render %s: %w", ct.Ref(), err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func (l *Library) lookup(ref string) (compiledTemplate, error) {
+	if l == nil {
+		return compiledTemplate{}, fmt.Errorf("%w: %q", ErrPromptNotFound, ref)
+	}
+	name, version, pinned := strings.Cut(strings.TrimSpace(ref), "@")
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	versions := l.templates[strings.TrimSpace(name)]
+	if len(versions) == 0 {
+		return compiledTemplate{}, fmt.Errorf("%w: %q", ErrPromptNotFound, ref)
+	}
+	if !pinned {
+		return versions[len(versions)-1], nil
+	}
+	for _, ct := range versions {
+		if ct.Version == strings.TrimSpace(version) {
+			return ct, nil
+		}
+	}
+	return compiledTemplate{}, fmt.Errorf("%w: %q", ErrPromptNotFound, ref)
+}
+
+func bindVariables(t Template, vars map[string]any) (map[string]any, error) {
+	data := make(map[string]any, len(t.Variables)+len(vars))
+	declared := make(map[string]struct{}, len(t.Variables))
+	var errs []error
+	for _, v := range t.Variables {
+		declared[v.Name] = struct{}{}
+		val, ok := vars[v.Name]
+		if !ok {
+			switch {
+			case v.Required:
+				errs = append(errs, fmt.Errorf("%s is required", v.Name))
+			case v.Default != nil:
+				data[v.Name] = v.Default
+			default:
+				data[v.Name] = ""
+			}
+			continue
+		}
+		if err := checkVariableType(v, val); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		data[v.Name] = val
+	}
+	for name, val := range vars {
+		if _, ok := declared[name]; !ok {
+			data[name] = val
+		}
+	}
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+		return nil, fmt.Errorf("%w for %s: %w", ErrInvalidVariables, t.Ref(), errors.Join(errs...))
+	}
+	return data, nil
+}
+
+func checkVariableType(v Variable, val any) error {
+	switch v.Type {
+	case "number":
+		switch n := val.(type) {
+		case int, int32, int64, float32, float64, json.Number:
+			return nil
+		case string:
+			if _, err := strconv.ParseFloat(n, 64); err == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s must be a number", v.Name)
+	case "boolean":
+		if _, ok := val.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", v.Name)
+		}
+	}
+	return nil
+}
+
+// expandBareVariables rewrites {{ name }} to {{ .name }} for declared
+// variables so Jinja-style placeholders work alongside Go template syntax.
+func expandBareVariables(body string, declared map[string]struct{}) string {
+	if len(declared) == 0 {
+		return body
+	}
+	return bareVariableRegexp.ReplaceAllStringFunc(body, func(m string) string {
+		parts := bareVariableRegexp.FindStringSubmatch(m)
+		if _, ok := declared[parts[2]]; !ok {
+			return m
+		}
+		return "{{" + parts[1] + "." + parts[2] + parts[3] + "}}"
+	})
+}
+
+func withoutVersion(versions []compiledTemplate, version string) []compiledTemplate {
+	out := versions[:0]
+	for _, v := range versions {
+		if v.Version != version {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// compareVersions orders dotted versions numerically where both segments
+// are numbers ("1.10" > "1.9") and lexically otherwise.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case xerr == nil && yerr == nil && xn != yn:
+			if xn < yn {
+				return -1
+			}
+			return 1
+		case (xerr != nil || yerr != nil) && x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
+
+// ParseTemplates reads every .md file under dir as a prompt template. YAML
+// frontmatter is optional and may set name, version, description and
+// variables; the name defaults to the file name.
+func ParseTemplates(fsys fs.FS, dir string) ([]Template, []error) {
+	info, err := fs.Stat(fsys, dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, []error{fmt.Errorf("prompts: stat prompts dir %s: %w", dir, err)}
+	}
+	if !info.IsDir() {
+		return nil, []error{fmt.Errorf("prompts: prompts path %s is not a directory", dir)}
+	}
+
+	var (
+		out  []Template
+		errs []error
+	)
+	walkErr := fs.WalkDir(fsys, dir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			errs = append(errs, fmt.Errorf("prompts: walk prompts %s: %w", path, walkErr))
+			return nil
+		}
+		if d.IsDir() || strings.ToLower(filepath.Ext(d.Name())) != ".md" {
+			return nil
+		}
+		t, err := parseTemplateFile(fsys, path, strings.TrimSuffix(d.Name(), filepath.Ext(d.Name())))
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		out = append(out, t)
+		return nil
+	})
+	if walkErr != nil {
+		errs = append(errs, walkErr)
+	}
+	return out, errs
+}
+
+func parseTemplateFile(fsys fs.FS, path, fallback string) (Template, error) {
+	content, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return Template{}, fmt.Errorf("prompts: read prompt %s: %w", path, err)
+	}
+	text := strings.TrimPrefix(string(content), "\uFEFF")
+	var t Template
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		meta, body, found := strings.Cut(rest, "\n---")
+		if !found {
+			return Template{}, fmt.Errorf("prompts: parse prompt %s: missing closing frontmatter separator", path)
+		}
+		if err := yaml.Unmarshal([]byte(meta), &t); err != nil {
+			return Template{}, fmt.Errorf("prompts: parse prompt %s: decode YAML: %w", path, err)
+		}
+		text = strings.TrimPrefix(strings.TrimPrefix(body, "\r"), "\n")
+	}
+	if strings.TrimSpace(t.Name) == "" {
+		t.Name = fallback
+	}
+	t.Body = text
+	t.Source = path
+	return t, nil
+}
+
+// DirSource loads templates from dir inside fsys (see ParseTemplates).
+// Parse errors fail the load.
+func DirSource(fsys fs.FS, dir string) Source {
+	return dirSource{fsys: fsys, dir: dir}
+}
+
+type dirSource struct {
+	fsys fs.FS
+	dir  string
+}
+
+func (s dirSource) Templates(context.Context) ([]Template, error) {
+	templates, errs := ParseTemplates(s.fsys, s.dir)
+	return templates, errors.Join(errs...)
+}
+
+// HTTPSource fetches templates from a remote store serving a JSON array of
+// Template objects. A nil client uses http.DefaultClient.
+func HTTPSource(url string, client *http.Client) Source {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return httpSource{url: url, client: client}
+}
+
+type httpSource struct {
+	url    string
+	client *http.Client
+}
+
+func (s httpSource) Templates(ctx context.Context) ([]Template, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("prompts: remote store: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("prompts: remote store: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("prompts: remote store %s: status %d", s.url, resp.StatusCode)
+	}
+	var templates []Template
+	if err := json.NewDecoder(resp.Body).Decode(&templates); err != nil {
+		return nil, fmt.Errorf("prompts: remote store %s: decode: %w", s.url, err)
+	}
+	for i := range templates {
+		if templates[i].Source == "" {
+			templates[i].Source = s.url
+		}
+	}
+	return templates, nil
+}
@@ -0,0 +1,94 @@
+package prompts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParse_PromptTemplatesAndVersions(t *testing.T) {
+	fsys := fstest.MapFS{
+		".claude/prompts/review.md": &fstest.MapFile{Data: []byte(`---
+version: "1.9"
+variables:
+  - name: language
+    required: true
+  - name: depth
+    type: number
+    default: 2
+---
+Review this {{ language }} change at depth {{ .depth }}.
+`)},
+		".claude/prompts/v2/review.md": &fstest.MapFile{Data: []byte(`---
+name: review
+version: "1.10"
+variables:
+  - name: language
+    required: true
+---
+Review the {{ language }} diff.`)},
+		".claude/prompts/plain.md": &fstest.MapFile{Data: []byte("Say hello.")},
+	}
+	builtins := Parse(fsys)
+	if len(builtins.Errors) != 0 || len(builtins.Prompts) != 3 {
+		t.Fatalf("prompts=%v errors=%v", builtins.Prompts, builtins.Errors)
+	}
+	lib, err := NewLibrary(builtins.Prompts...)
+	if err != nil {
+		t.Fatalf("NewLibrary: %v", err)
+	}
+	if got := lib.Versions("review"); len(got) != 2 || got[1] != "1.10" {
+		t.Fatalf("versions %v", got)
+	}
+
+	out, err := lib.Render("review", map[string]any{"language": "Go"})
+	if err != nil || out != "Review the Go diff." {
+		t.Fatalf("latest render %q err=%v", out, err)
+	}
+	out, err = lib.Render("review@1.9", map[string]any{"language": "Go"})
+	if err != nil || out != "Review this Go change at depth 2." {
+		t.Fatalf("pinned render %q err=%v", out, err)
+	}
+	if out, err := lib.Render("plain", nil); err != nil || out != "Say hello." {
+		t.Fatalf("plain render %q err=%v", out, err)
+	}
+
+	for _, vars := range []map[string]any{
+		nil,
+		{"language": "Go", "depth": "deep"},
+		{"depth": 3},
+	} {
+		if _, err := lib.Render("review@1.9", vars); !errors.Is(err, ErrInvalidVariables) {
+			t.Fatalf("vars %v: expected ErrInvalidVariables, got %v", vars, err)
+		}
+	}
+	if _, err := lib.Render("review@3", nil); !errors.Is(err, ErrPromptNotFound) {
+		t.Fatalf("expected ErrPromptNotFound, got %v", err)
+	}
+}
+
+func TestHTTPSourceLoadsRemoteTemplates(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode([]Template{{Name: "greet", Version: "2", Body: "Hi {{ .name }}", Variables: []Variable{{Name: "name"}}}})
+	}))
+	defer srv.Close()
+
+	lib := &Library{}
+	if err := lib.Load(context.Background(), HTTPSource(srv.URL, nil)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	tpl, err := lib.Get("greet")
+	if err != nil || tpl.Ref() != "greet@2" || tpl.Source != srv.URL {
+		t.Fatalf("template %+v err=%v", tpl, err)
+	}
+	if out, _ := lib.Render("greet", map[string]any{"name": "Ada"}); out != "Hi Ada" {
+		t.Fatalf("render %q", out)
+	}
+	if err := lib.Register(Template{Name: "bad", Body: "{{ .x"}); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
@@ -0,0 +1,125 @@
+// Package blackboard provides a run-scoped key/value store shared by a parent
+// agent and the subagents it dispatches. Writes use optimistic versioning so
+// concurrent agents can detect and retry conflicting updates.
+package blackboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrVersionConflict is returned when a conditional write or delete observes
+// a version other than the expected one.
+var ErrVersionConflict = errors.New("blackboard: version conflict")
+
+// AnyVersion disables the version check on Put and Delete.
+const AnyVersion int64 = -1
+
+// Entry is a stored value. Version starts at 1 and increases on every write.
+type Entry struct {
+	Key       string    `json:"key"`
+	Value     any       `json:"value"`
+	Version   int64     `json:"version"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Board is a concurrency-safe key/value store.
+type Board struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// New returns an empty Board.
+func New() *Board {
+	return &Board{entries: map[string]Entry{}}
+}
+
+// Get returns the entry for key. Keys are trimmed of surrounding space, as
+// in Put and Delete.
+func (b *Board) Get(key string) (Entry, bool) {
+	key = strings.TrimSpace(key)
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	entry, ok := b.entries[key]
+	return entry, ok
+}
+
+// Put stores value under key. expected is the version the caller last read:
+// 0 requires the key to be absent, AnyVersion skips the check.
+func (b *Board) Put(key string, value any, expected int64, writer string) (Entry, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return Entry{}, errors.New("blackboard: key is empty")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	current := b.entries[key]
+	if expected != AnyVersion && current.Version != expected {
+		return current, fmt.Errorf("%w: %q is at version %d, expected %d", ErrVersionConflict, key, current.Version, expected)
+	}
+	entry := Entry{
+		Key:       key,
+		Value:     value,
+		Version:   current.Version + 1,
+		UpdatedBy: writer,
+		UpdatedAt: time.Now().UTC(),
+	}
+	b.entries[key] = entry
+	return entry, nil
+}
+
+// Delete removes key. expected follows the same rules as Put; deleting an
+// absent key with AnyVersion is a no-op.
+func (b *Board) Delete(key string, expected int64) error {
+	key = strings.TrimSpace(key)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	current, ok := b.entries[key]
+	if expected != AnyVersion && current.Version != expected {
+		return fmt.Errorf("%w: %q is at version %d, expected %d", ErrVersionConflict, key, current.Version, expected)
+	}
+	if ok {
+		delete(b.entries, key)
+	}
+	return nil
+}
+
+// List returns the entries whose key starts with prefix, sorted by key.
+func (b *Board) List(prefix string) []Entry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make([]Entry, 0, len(b.entries))
+	for key, entry := range b.entries {
+		if strings.HasPrefix(key, prefix) {
+			out = append(out, entry)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+type boardKey struct{}
+
+// WithBoard attaches b to ctx. Subagents dispatched with the returned context
+// share the same board.
+func WithBoard(ctx context.Context, b *Board) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, boardKey{}, b)
+}
+
+// FromContext returns the board attached to ctx, if any.
+func FromContext(ctx context.Context) (*Board, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	b, ok := ctx.Value(boardKey{}).(*Board)
+	return b, ok && b != nil
+}
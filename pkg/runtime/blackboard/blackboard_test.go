@@ -0,0 +1,97 @@
+package blackboard
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestBoardOptimisticVersioning(t *testing.T) {
+	b := New()
+	first, err := b.Put("plan", "draft", 0, "parent")
+	if err != nil || first.Version != 1 || first.UpdatedBy != "parent" {
+		t.Fatalf("unexpected first write %+v err=%v", first, err)
+	}
+	if _, err := b.Put("plan", "other", 0, "child"); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected conflict on create, got %v", err)
+	}
+	second, err := b.Put("plan", "final", 1, "child")
+	if err != nil || second.Version != 2 {
+		t.Fatalf("unexpected second write %+v err=%v", second, err)
+	}
+	if err := b.Delete("plan", 1); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected stale delete conflict, got %v", err)
+	}
+	if err := b.Delete("plan", 2); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok := b.Get("plan"); ok {
+		t.Fatal("expected key removed")
+	}
+	if _, err := b.Put(" ", 1, AnyVersion, ""); err == nil {
+		t.Fatal("expected empty key error")
+	}
+}
+
+func TestBoardTrimsKeys(t *testing.T) {
+	b := New()
+	if _, err := b.Put(" k ", "v", 0, ""); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	for _, key := range []string{" k ", "k"} {
+		if entry, ok := b.Get(key); !ok || entry.Key != "k" {
+			t.Fatalf("Get(%q) = %+v, %v", key, entry, ok)
+		}
+	}
+	if err := b.Delete(" k ", 1); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok := b.Get("k"); ok {
+		t.Fatal("expected key removed")
+	}
+}
+
+func TestBoardConcurrentIncrements(t *testing.T) {
+	b := New()
+	const workers = 16
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				cur, _ := b.Get("count")
+				n, _ := cur.Value.(int)
+				if _, err := b.Put("count", n+1, cur.Version, ""); err == nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	entry, _ := b.Get("count")
+	if entry.Value != workers || entry.Version != workers {
+		t.Fatalf("lost update: %+v", entry)
+	}
+}
+
+func TestBoardListAndContext(t *testing.T) {
+	b := New()
+	for _, key := range []string{"task/b", "task/a", "note"} {
+		if _, err := b.Put(key, key, AnyVersion, ""); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	list := b.List("task/")
+	if len(list) != 2 || list[0].Key != "task/a" || list[1].Key != "task/b" {
+		t.Fatalf("unexpected list %+v", list)
+	}
+	ctx := WithBoard(context.Background(), b)
+	if got, ok := FromContext(ctx); !ok || got != b {
+		t.Fatal("expected board from context")
+	}
+	if _, ok := FromContext(context.Background()); ok {
+		t.Fatal("expected no board")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -71,7 +72,9 @@ type SkillFile struct {
 	Name     string
 	Path     string
 	Metadata SkillMetadata
-	fs       *config.FS
+	// Resources are the absolute paths of Metadata.Resources.
+	Resources []string
+	fs        *config.FS
 }
 
 // readFile is swappable in tests to track filesystem IO.
@@ -135,6 +138,9 @@ type SkillMetadata struct {
 	Compatibility string            `yaml:"compatibility,omitempty"`
 	Metadata      map[string]string `yaml:"metadata,omitempty"`
 	AllowedTools  ToolList          `yaml:"allowed-tools,omitempty"`
+	// Resources lists auxiliary files or directories the skill uses, relative
+	// to its directory. They must exist and stay inside the project.
+	Resources []string `yaml:"resources,omitempty"`
 }
 
 // SkillRegistration wires a definition to its handler.
@@ -192,10 +198,18 @@ func LoadFromFS(opts LoaderOptions) ([]SkillRegistration, []error) {
 		}
 		seen[file.Metadata.Name] = file.Path
 
+		resources, err := resolveResources(opts.ProjectRoot, file, fsLayer)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		file.Resources = resources
+
 		def := Definition{
 			Name:        file.Metadata.Name,
 			Description: file.Metadata.Description,
 			Metadata:    buildDefinitionMetadata(file),
+			Resources:   resources,
 		}
 		reg := SkillRegistration{
 			Definition: def,
@@ -355,6 +369,44 @@ func parseFrontMatter(content string) (SkillMetadata, string, error) {
 	return meta, body, nil
 }
 
+// resolveResources turns the declared resources of file into absolute
+// paths, rejecting absolute entries, entries outside the project and
+// entries that do not exist.
+func resolveResources(projectRoot string, file SkillFile, fsLayer *config.FS) ([]string, error) {
+	if len(file.Metadata.Resources) == 0 {
+		return nil, nil
+	}
+	root, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("skills: %s: resolve project root: %w", file.Path, err)
+	}
+	dir, err := filepath.Abs(filepath.Dir(file.Path))
+	if err != nil {
+		return nil, fmt.Errorf("skills: %s: resolve skill dir: %w", file.Path, err)
+	}
+	var out []string
+	for _, raw := range file.Metadata.Resources {
+		entry := strings.TrimSpace(raw)
+		if entry == "" {
+			continue
+		}
+		if filepath.IsAbs(entry) || strings.HasPrefix(entry, "/") {
+			return nil, fmt.Errorf("skills: %s: resource %q must be relative to the skill directory", file.Path, entry)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(entry))
+		if rel, err := filepath.Rel(root, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("skills: %s: resource %q is outside the project", file.Path, entry)
+		}
+		if _, err := fsLayer.Stat(path); err != nil {
+			return nil, fmt.Errorf("skills: %s: resource %q: %w", file.Path, entry, err)
+		}
+		if !slices.Contains(out, path) {
+			out = append(out, path)
+		}
+	}
+	return out, nil
+}
+
 func validateMetadata(meta SkillMetadata) error {
 	name := strings.TrimSpace(meta.Name)
 	if name == "" {
@@ -518,6 +570,11 @@ func loadSkillContent(file SkillFile) (Result, error) {
 	}
 	meta["source"] = file.Path
 
+	if len(file.Resources) > 0 {
+		output["resources"] = slices.Clone(file.Resources)
+		meta["resource-count"] = len(file.Resources)
+	}
+
 	if len(support) > 0 {
 		output["support_files"] = support
 		count := 0
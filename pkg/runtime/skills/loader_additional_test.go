@@ -277,3 +277,51 @@ func (m *mockFileInfo) Mode() fs.FileMode  { return 0o644 }
 func (m *mockFileInfo) ModTime() time.Time { return m.modTime }
 func (m *mockFileInfo) IsDir() bool        { return false }
 func (m *mockFileInfo) Sys() any           { return nil }
+
+func TestLoadFromFSResolvesResources(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, ".claude", "skills", "forms")
+	mustWrite(t, filepath.Join(dir, "SKILL.md"), "---\nname: forms\ndescription: Fill forms\nresources:\n  - scripts/fill.py\n  - ../../../templates\n---\nbody\n")
+	mustWrite(t, filepath.Join(dir, "scripts", "fill.py"), "print()")
+	mustWrite(t, filepath.Join(root, "templates", "w9.txt"), "w9")
+
+	regs, errs := LoadFromFS(LoaderOptions{ProjectRoot: root})
+	if len(errs) != 0 || len(regs) != 1 {
+		t.Fatalf("regs=%d errs=%v", len(regs), errs)
+	}
+	want := []string{filepath.Join(dir, "scripts", "fill.py"), filepath.Join(root, "templates")}
+	if got := regs[0].Definition.Resources; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("resources = %v, want %v", got, want)
+	}
+	res, err := regs[0].Handler.Execute(context.Background(), ActivationContext{})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	output := res.Output.(map[string]any)
+	if got, _ := output["resources"].([]string); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("activation resources = %v", output["resources"])
+	}
+	if res.Metadata["resource-count"] != 2 {
+		t.Fatalf("metadata = %v", res.Metadata)
+	}
+}
+
+func TestLoadFromFSRejectsBadResources(t *testing.T) {
+	cases := map[string]string{
+		"missing":  "scripts/nope.sh",
+		"escapes":  "../../../../outside.txt",
+		"absolute": "/etc/passwd",
+	}
+	for name, resource := range cases {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, ".claude", "skills", "bad")
+			mustWrite(t, filepath.Join(dir, "SKILL.md"), "---\nname: bad\ndescription: Bad\nresources: ["+resource+"]\n---\nbody\n")
+
+			regs, errs := LoadFromFS(LoaderOptions{ProjectRoot: root})
+			if len(regs) != 0 || len(errs) != 1 || !strings.Contains(errs[0].Error(), "resource") {
+				t.Fatalf("regs=%d errs=%v", len(regs), errs)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -28,6 +29,9 @@ type Definition struct {
 	DisableAutoActivation bool
 	Metadata              map[string]string
 	Matchers              []Matcher
+	// Resources holds the absolute paths of auxiliary files (scripts,
+	// templates) the skill declares in its frontmatter.
+	Resources []string
 }
 
 // Validate performs cheap sanity checks before accepting a definition.
@@ -89,6 +93,7 @@ func (s *Skill) Definition() Definition {
 		def.Metadata = maps.Clone(def.Metadata)
 	}
 	def.Matchers = append([]Matcher(nil), def.Matchers...)
+	def.Resources = slices.Clone(def.Resources)
 	return def
 }
 
@@ -146,6 +151,20 @@ func (r *Registry) Register(def Definition, handler Handler) error {
 	return nil
 }
 
+// Replace swaps the registered skills for those of other in one step, so
+// holders of r see a reloaded set without a window where it is empty.
+func (r *Registry) Replace(other *Registry) {
+	next := map[string]*Skill{}
+	if other != nil {
+		other.mu.RLock()
+		maps.Copy(next, other.skills)
+		other.mu.RUnlock()
+	}
+	r.mu.Lock()
+	r.skills = next
+	r.mu.Unlock()
+}
+
 // Get fetches a skill by name.
 func (r *Registry) Get(name string) (*Skill, bool) {
 	key := strings.ToLower(strings.TrimSpace(name))
@@ -295,5 +314,8 @@ func normalizeDefinition(def Definition) Definition {
 	if len(def.Matchers) > 0 {
 		normalized.Matchers = append([]Matcher(nil), def.Matchers...)
 	}
+	if len(def.Resources) > 0 {
+		normalized.Resources = slices.Clone(def.Resources)
+	}
 	return normalized
 }
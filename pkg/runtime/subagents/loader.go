@@ -107,6 +107,7 @@ func LoadFromFS(opts LoaderOptions) ([]SubagentRegistration, []error) {
 			Description:  file.Metadata.Description,
 			BaseContext:  Context{ToolWhitelist: whitelist, Model: model, Metadata: meta},
 			DefaultModel: model,
+			SystemPrompt: file.Body,
 		}
 
 		reg := SubagentRegistration{
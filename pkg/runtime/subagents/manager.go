@@ -90,6 +90,10 @@ type Definition struct {
 	BaseContext  Context
 	Matchers     []skills.Matcher
 	DefaultModel string
+	// SystemPrompt, when set, makes the Task tool run the subagent as a
+	// nested agent loop with this system prompt instead of returning the
+	// handler output.
+	SystemPrompt string
 }
 
 // Validate ensures the definition is safe to register.
@@ -210,6 +214,7 @@ func (m *Manager) Register(def Definition, handler Handler) error {
 			BaseContext:  baseCtx,
 			Matchers:     append([]skills.Matcher(nil), def.Matchers...),
 			DefaultModel: strings.TrimSpace(def.DefaultModel),
+			SystemPrompt: strings.TrimSpace(def.SystemPrompt),
 		},
 		handler: handler,
 	}
@@ -227,6 +232,20 @@ func (m *Manager) Register(def Definition, handler Handler) error {
 	return nil
 }
 
+// Replace swaps the registered subagents for those of other in one step, so
+// holders of m see a reloaded set without a window where it is empty.
+func (m *Manager) Replace(other *Manager) {
+	next := map[string]*registeredSubagent{}
+	if other != nil {
+		other.mu.RLock()
+		maps.Copy(next, other.subagents)
+		other.mu.RUnlock()
+	}
+	m.mu.Lock()
+	m.subagents = next
+	m.mu.Unlock()
+}
+
 // List returns registered subagent definitions sorted by priority + name.
 func (m *Manager) List() []Definition {
 	m.mu.RLock()
@@ -244,6 +263,17 @@ func (m *Manager) List() []Definition {
 	return defs
 }
 
+// Lookup returns the registered definition for name.
+func (m *Manager) Lookup(name string) (Definition, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sub, ok := m.subagents[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Definition{}, false
+	}
+	return cloneDefinition(sub.definition), true
+}
+
 // Dispatch selects and executes a subagent. When Target is empty, automatic
 // matchers choose the best candidate subject to priority/mutex ordering.
 func (m *Manager) Dispatch(ctx context.Context, req Request) (Result, error) {
@@ -376,6 +406,7 @@ func cloneDefinition(def Definition) Definition {
 		BaseContext:  def.BaseContext.Clone(),
 		Matchers:     append([]skills.Matcher(nil), def.Matchers...),
 		DefaultModel: def.DefaultModel,
+		SystemPrompt: def.SystemPrompt,
 	}
 	return cloned
 }
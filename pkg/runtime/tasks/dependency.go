@@ -41,7 +41,7 @@ func (s *TaskStore) AddDependency(taskID, blockedByID string) error {
 	s.reconcileBlockedStatusLocked(task)
 	task.UpdatedAt = now
 	blocker.UpdatedAt = now
-	return nil
+	return s.persistLocked()
 }
 
 func (s *TaskStore) RemoveDependency(taskID, blockedByID string) error {
@@ -67,7 +67,7 @@ func (s *TaskStore) RemoveDependency(taskID, blockedByID string) error {
 
 	task.UpdatedAt = now
 	blocker.UpdatedAt = now
-	return nil
+	return s.persistLocked()
 }
 
 func (s *TaskStore) onTaskCompleted(taskID string) {
@@ -0,0 +1,105 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ledgerVersion is the format version written to ledger files.
+const ledgerVersion = 1
+
+type ledgerFile struct {
+	Version int     `json:"version"`
+	Tasks   []*Task `json:"tasks"`
+}
+
+// OpenLedger returns a store backed by the JSON file at path, loading the
+// tasks saved by earlier sessions. Every change is written back before the
+// call returns, so a later runtime opening the same file picks up where
+// this one stopped. A missing file starts an empty ledger. The file is not
+// locked: open it from one runtime at a time.
+func OpenLedger(path string) (*TaskStore, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, errors.New("tasks: ledger path is required")
+	}
+	store := NewTaskStore()
+	store.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tasks: read ledger: %w", err)
+	}
+	var file ledgerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("tasks: parse ledger %s: %w", path, err)
+	}
+	if file.Version > ledgerVersion {
+		return nil, fmt.Errorf("tasks: ledger %s has unsupported version %d", path, file.Version)
+	}
+	for _, task := range file.Tasks {
+		if task == nil || strings.TrimSpace(task.ID) == "" {
+			continue
+		}
+		if _, dup := store.tasks[task.ID]; dup {
+			continue
+		}
+		if !validStatus(task.Status) {
+			task.Status = TaskPending
+		}
+		store.tasks[task.ID] = cloneTask(task)
+		store.order = append(store.order, task.ID)
+	}
+	return store, nil
+}
+
+// Path returns the ledger file of a store opened with OpenLedger, or "" for
+// an in-memory store.
+func (s *TaskStore) Path() string {
+	if s == nil {
+		return ""
+	}
+	return s.path
+}
+
+// persistLocked writes the ledger file atomically. In-memory stores skip it.
+func (s *TaskStore) persistLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	file := ledgerFile{Version: ledgerVersion, Tasks: make([]*Task, 0, len(s.order))}
+	for _, id := range s.order {
+		if task := s.tasks[id]; task != nil {
+			file.Tasks = append(file.Tasks, task)
+		}
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("tasks: encode ledger: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("tasks: persist ledger: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".ledger-*.tmp")
+	if err != nil {
+		return fmt.Errorf("tasks: persist ledger: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("tasks: persist ledger: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("tasks: persist ledger: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("tasks: persist ledger: %w", err)
+	}
+	return nil
+}
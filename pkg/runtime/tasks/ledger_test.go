@@ -0,0 +1,60 @@
+package tasks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenLedgerPersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "ledger.json")
+
+	store, err := OpenLedger(path)
+	require.NoError(t, err)
+	require.Equal(t, path, store.Path())
+	require.Empty(t, store.List())
+
+	first, err := store.Create("design", "", "designing")
+	require.NoError(t, err)
+	second, err := store.Create("build", "", "building")
+	require.NoError(t, err)
+	require.NoError(t, store.AddDependency(second.ID, first.ID))
+
+	status := TaskInProgress
+	session := "sess-1"
+	_, err = store.Update(first.ID, TaskUpdate{Status: &status, Session: &session, AddArtifacts: []string{"docs/design.md"}})
+	require.NoError(t, err)
+
+	reopened, err := OpenLedger(path)
+	require.NoError(t, err)
+	list := reopened.List()
+	require.Len(t, list, 2)
+	require.Equal(t, first.ID, list[0].ID)
+	require.Equal(t, TaskInProgress, list[0].Status)
+	require.Equal(t, "sess-1", list[0].Session)
+	require.Equal(t, []string{"docs/design.md"}, list[0].Artifacts)
+	require.Equal(t, []string{first.ID}, list[1].BlockedBy)
+
+	require.NoError(t, reopened.Delete(second.ID))
+	again, err := OpenLedger(path)
+	require.NoError(t, err)
+	require.Len(t, again.List(), 1)
+}
+
+func TestOpenLedgerErrors(t *testing.T) {
+	_, err := OpenLedger("  ")
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+	_, err = OpenLedger(path)
+	require.ErrorContains(t, err, "parse ledger")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":99,"tasks":[]}`), 0o600))
+	_, err = OpenLedger(path)
+	require.ErrorContains(t, err, "unsupported version")
+
+	require.Equal(t, "", NewTaskStore().Path())
+}
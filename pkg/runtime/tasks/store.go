@@ -24,12 +24,17 @@ type TaskUpdate struct {
 	ActiveForm  *string
 	Status      *TaskStatus
 	Owner       *string
+	Session     *string
+	// AddArtifacts appends to Task.Artifacts, skipping duplicates.
+	AddArtifacts []string
 }
 
 type TaskStore struct {
 	mu    sync.RWMutex
 	tasks map[string]*Task
 	order []string // 保持插入顺序
+	// path is the ledger file of a store opened with OpenLedger.
+	path string
 }
 
 func NewTaskStore() *TaskStore {
@@ -68,6 +73,9 @@ func (s *TaskStore) Create(subject, description, activeForm string) (*Task, erro
 	s.tasks[id] = task
 	s.order = append(s.order, id)
 
+	if err := s.persistLocked(); err != nil {
+		return nil, err
+	}
 	return cloneTask(task), nil
 }
 
@@ -117,6 +125,14 @@ func (s *TaskStore) Update(id string, updates TaskUpdate) (*Task, error) {
 	if updates.Owner != nil {
 		task.Owner = strings.TrimSpace(*updates.Owner)
 	}
+	if updates.Session != nil {
+		task.Session = strings.TrimSpace(*updates.Session)
+	}
+	for _, artifact := range updates.AddArtifacts {
+		if artifact = strings.TrimSpace(artifact); artifact != "" {
+			task.Artifacts = addUnique(task.Artifacts, artifact)
+		}
+	}
 
 	previousStatus := task.Status
 	if updates.Status != nil {
@@ -139,6 +155,9 @@ func (s *TaskStore) Update(id string, updates TaskUpdate) (*Task, error) {
 		s.onTaskStatusChangedLocked(task.ID, now)
 	}
 
+	if err := s.persistLocked(); err != nil {
+		return nil, err
+	}
 	return cloneTask(task), nil
 }
 
@@ -194,7 +213,7 @@ func (s *TaskStore) Delete(id string) error {
 
 	delete(s.tasks, id)
 	s.order = removeString(s.order, id)
-	return nil
+	return s.persistLocked()
 }
 
 func (s *TaskStore) initLocked() {
@@ -231,6 +250,7 @@ func cloneTask(task *Task) *Task {
 	dup := *task
 	dup.Blocks = cloneStrings(task.Blocks)
 	dup.BlockedBy = cloneStrings(task.BlockedBy)
+	dup.Artifacts = cloneStrings(task.Artifacts)
 	return &dup
 }
 
@@ -18,8 +18,13 @@ type Task struct {
 	ActiveForm  string     `json:"activeForm"`
 	Status      TaskStatus `json:"status"`
 	Owner       string     `json:"owner"`
-	Blocks      []string   `json:"blocks"`
-	BlockedBy   []string   `json:"blockedBy"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	UpdatedAt   time.Time  `json:"updatedAt"`
+	// Session is the runtime session that created or last claimed the task.
+	Session string `json:"session,omitempty"`
+	// Artifacts lists what the task produced: file paths, artifact IDs or
+	// URLs.
+	Artifacts []string  `json:"artifacts,omitempty"`
+	Blocks    []string  `json:"blocks"`
+	BlockedBy []string  `json:"blockedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
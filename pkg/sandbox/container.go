@@ -0,0 +1,321 @@
+package sandbox
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrContainerSandboxClosed is returned by Isolate after Close.
+var ErrContainerSandboxClosed = errors.New("sandbox: container sandbox closed")
+
+// containerStopTimeout bounds the `rm -f` issued by Close.
+const containerStopTimeout = 30 * time.Second
+
+// ContainerConfig configures ContainerSandbox.
+type ContainerConfig struct {
+	// Runtime is the container CLI: "docker" (default) or "podman".
+	Runtime string
+	Image   string
+	// Root is bind-mounted read-write at the same path and is the default
+	// working directory inside the container.
+	Root string
+	// WritablePaths are extra read-write bind mounts at the same paths.
+	WritablePaths []string
+	// CPUs is passed as --cpus; 0 leaves CPU unlimited.
+	CPUs float64
+	// Memory is passed as --memory (for example "2g"); empty is unlimited.
+	Memory string
+	// Network is passed as --network (for example "none"); empty keeps the
+	// runtime default.
+	Network string
+	// ExcludedCommands run on the host, matched like OSConfig.ExcludedCommands.
+	ExcludedCommands []string
+	// Env lists KEY=VALUE pairs set for every command, such as the
+	// EgressProxy variables.
+	Env []string
+}
+
+// ContainerSandbox runs shell commands in one long-lived container per
+// session, started on first use with `<runtime> run` and entered with
+// `<runtime> exec`. Files written under the mounts persist across calls;
+// shell state such as cwd and variables does not. Close removes every
+// container it started.
+type ContainerSandbox struct {
+	cfg     ContainerConfig
+	backend OSBackend
+	binary  string
+	reason  string
+	prefix  string
+	run     func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+	mu         sync.Mutex
+	containers map[string]string
+	starting   map[string]*containerStart
+	closed     bool
+}
+
+// containerStart is a container being started; callers for the same session
+// wait on done instead of starting a second one.
+type containerStart struct {
+	done chan struct{}
+	name string
+	err  error
+}
+
+// NewContainerSandbox resolves the container CLI. When it is missing the
+// sandbox is returned unenforced with Status().Reason explaining why.
+func NewContainerSandbox(cfg ContainerConfig) *ContainerSandbox {
+	return newContainerSandbox(cfg, exec.LookPath, runContainerCLI)
+}
+
+func newContainerSandbox(cfg ContainerConfig, lookPath func(string) (string, error), run func(context.Context, string, ...string) ([]byte, error)) *ContainerSandbox {
+	runtimeName := strings.ToLower(strings.TrimSpace(cfg.Runtime))
+	if runtimeName == "" {
+		runtimeName = string(OSBackendDocker)
+	}
+	cfg.Runtime = runtimeName
+	cfg.Image = strings.TrimSpace(cfg.Image)
+	cfg.Root = cleanAbs(cfg.Root)
+	cfg.WritablePaths = uniquePaths(append([]string{cfg.Root}, cfg.WritablePaths...))
+	cfg.ExcludedCommands = normalizeCommands(cfg.ExcludedCommands)
+
+	c := &ContainerSandbox{cfg: cfg, run: run, containers: map[string]string{}}
+	switch {
+	case cfg.Image == "":
+		c.reason = "container image is not configured"
+	case runtimeName != string(OSBackendDocker) && runtimeName != string(OSBackendPodman):
+		c.reason = fmt.Sprintf("unsupported container runtime %q", cfg.Runtime)
+	default:
+		bin, err := lookPath(runtimeName)
+		if err != nil {
+			c.reason = fmt.Sprintf("%s not found in PATH", runtimeName)
+			break
+		}
+		c.binary = bin
+		c.backend = OSBackend(runtimeName)
+	}
+	c.prefix = randomSuffix()
+	return c
+}
+
+func runContainerCLI(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return out, fmt.Errorf("%w: %s", err, msg)
+		}
+	}
+	return out, err
+}
+
+func randomSuffix() string {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// Enforced reports whether commands run inside containers.
+func (c *ContainerSandbox) Enforced() bool { return c != nil && c.backend != OSBackendNone }
+
+// Status describes the sandbox for runtime reports.
+func (c *ContainerSandbox) Status() OSStatus {
+	if c == nil {
+		return OSStatus{}
+	}
+	return OSStatus{
+		Backend:          c.backend,
+		Enforced:         c.Enforced(),
+		Reason:           c.reason,
+		EgressEnforced:   c.Enforced() && strings.TrimSpace(c.cfg.Network) == "none",
+		Image:            c.cfg.Image,
+		WritablePaths:    append([]string(nil), c.cfg.WritablePaths...),
+		ExcludedCommands: append([]string(nil), c.cfg.ExcludedCommands...),
+	}
+}
+
+// Excluded reports whether command matches ExcludedCommands and so runs on
+// the host.
+func (c *ContainerSandbox) Excluded(command string) bool {
+	return c != nil && matchExcluded(c.cfg.ExcludedCommands, command)
+}
+
+// Isolate starts the session's container if needed and returns an exec
+// invocation running cmd.Argv in cmd.Dir with cmd.Env set. When the sandbox
+// is not enforced cmd.Argv runs on the host with only the configured Env.
+func (c *ContainerSandbox) Isolate(ctx context.Context, cmd IsolatedCommand) ([]string, error) {
+	if c == nil || len(cmd.Argv) == 0 {
+		return cmd.Argv, nil
+	}
+	if !c.Enforced() {
+		return withEnv(c.cfg.Env, cmd.Argv), nil
+	}
+	dir := strings.TrimSpace(cmd.Dir)
+	if dir != "" && !c.mountedPath(dir) {
+		return nil, fmt.Errorf("%w: %s is not mounted in the container", ErrPathDenied, dir)
+	}
+	name, err := c.container(ctx, cmd.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	argv := []string{c.binary, "exec", "-i"}
+	if dir != "" {
+		argv = append(argv, "-w", dir)
+	}
+	for _, kv := range append(append([]string(nil), c.cfg.Env...), cmd.Env...) {
+		argv = append(argv, "-e", kv)
+	}
+	argv = append(argv, name)
+	return append(argv, cmd.Argv...), nil
+}
+
+// container returns the running container of sessionID, starting it on
+// first use. The start, which may pull the image, runs without holding c.mu
+// so other sessions are not blocked; concurrent callers for the same session
+// share it.
+func (c *ContainerSandbox) container(ctx context.Context, sessionID string) (string, error) {
+	if strings.TrimSpace(sessionID) == "" {
+		sessionID = "default"
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return "", ErrContainerSandboxClosed
+	}
+	if name, ok := c.containers[sessionID]; ok {
+		c.mu.Unlock()
+		return name, nil
+	}
+	if start, ok := c.starting[sessionID]; ok {
+		c.mu.Unlock()
+		select {
+		case <-start.done:
+			return start.name, start.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if c.starting == nil {
+		c.starting = map[string]*containerStart{}
+	}
+	start := &containerStart{done: make(chan struct{})}
+	c.starting[sessionID] = start
+	c.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(sessionID))
+	name := fmt.Sprintf("agentsdk-%s-%s", c.prefix, hex.EncodeToString(sum[:6]))
+	_, err := c.run(ctx, c.binary, c.runArgs(name, sessionID)...)
+	if err != nil {
+		err = fmt.Errorf("sandbox: start %s container: %w", c.backend, err)
+	}
+
+	c.mu.Lock()
+	delete(c.starting, sessionID)
+	closed := c.closed
+	if err == nil && !closed {
+		c.containers[sessionID] = name
+	}
+	c.mu.Unlock()
+	if err == nil && closed {
+		// Close ran while the container started and did not see it.
+		rmCtx, cancel := context.WithTimeout(context.Background(), containerStopTimeout)
+		//nolint:errcheck // best effort; the sandbox is already closed
+		c.run(rmCtx, c.binary, "rm", "-f", name)
+		cancel()
+		err = ErrContainerSandboxClosed
+	}
+	if err == nil {
+		start.name = name
+	}
+	start.err = err
+	close(start.done)
+	return start.name, err
+}
+
+func (c *ContainerSandbox) runArgs(name, sessionID string) []string {
+	args := []string{"run", "-d", "--rm", "--init", "--name", name, "--label", "agentsdk.session=" + sessionID}
+	for _, p := range c.cfg.WritablePaths {
+		args = append(args, "-v", p+":"+p)
+	}
+	if c.cfg.Root != "" {
+		args = append(args, "-w", c.cfg.Root)
+	}
+	if c.cfg.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(c.cfg.CPUs, 'f', -1, 64))
+	}
+	if mem := strings.TrimSpace(c.cfg.Memory); mem != "" {
+		args = append(args, "--memory", mem)
+	}
+	if network := strings.TrimSpace(c.cfg.Network); network != "" {
+		args = append(args, "--network", network)
+	}
+	return append(args, c.cfg.Image, "sleep", "infinity")
+}
+
+// Containers lists the names of the running containers.
+func (c *ContainerSandbox) Containers() []string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]string, 0, len(c.containers))
+	for _, name := range c.containers {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Close removes every container started by the sandbox. Later Isolate calls
+// fail with ErrContainerSandboxClosed.
+func (c *ContainerSandbox) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	names := make([]string, 0, len(c.containers))
+	for _, name := range c.containers {
+		names = append(names, name)
+	}
+	c.containers = map[string]string{}
+	c.mu.Unlock()
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	ctx, cancel := context.WithTimeout(context.Background(), containerStopTimeout)
+	defer cancel()
+	if _, err := c.run(ctx, c.binary, append([]string{"rm", "-f"}, names...)...); err != nil {
+		return fmt.Errorf("sandbox: remove containers: %w", err)
+	}
+	return nil
+}
+
+// mountedPath reports whether p lies under one of the bind mounts.
+func (c *ContainerSandbox) mountedPath(p string) bool {
+	p = cleanAbs(p)
+	for _, mount := range c.cfg.WritablePaths {
+		if rel, err := filepath.Rel(mount, p); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeContainerCLI struct {
+	mu    sync.Mutex
+	calls [][]string
+	err   error
+}
+
+func (f *fakeContainerCLI) run(_ context.Context, name string, args ...string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, append([]string{name}, args...))
+	return nil, f.err
+}
+
+func TestContainerSandboxStartsOneContainerPerSession(t *testing.T) {
+	root := t.TempDir()
+	cli := &fakeContainerCLI{}
+	c := newContainerSandbox(ContainerConfig{
+		Image:            "golang:1.24",
+		Root:             root,
+		CPUs:             1.5,
+		Memory:           "2g",
+		Network:          "none",
+		ExcludedCommands: []string{"gh"},
+	}, fakeLookPath(map[string]string{"docker": "/usr/bin/docker"}), cli.run)
+	if status := c.Status(); !status.Enforced || status.Backend != OSBackendDocker || status.Image != "golang:1.24" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	sub := filepath.Join(root, "pkg")
+	argv, err := c.Isolate(context.Background(), IsolatedCommand{
+		Argv:      []string{"bash", "-c", "go test ./..."},
+		SessionID: "s1",
+		Dir:       sub,
+		Env:       []string{"AGENTSDK_SCRATCH=/tmp/x"},
+	})
+	if err != nil {
+		t.Fatalf("isolate: %v", err)
+	}
+	if len(cli.calls) != 1 {
+		t.Fatalf("expected one docker run, got %v", cli.calls)
+	}
+	run := strings.Join(cli.calls[0], " ")
+	for _, want := range []string{"/usr/bin/docker run -d --rm", "-v " + root + ":" + root, "-w " + root, "--cpus 1.5", "--memory 2g", "--network none", "golang:1.24 sleep infinity", "agentsdk.session=s1"} {
+		if !strings.Contains(run, want) {
+			t.Fatalf("run args missing %q: %s", want, run)
+		}
+	}
+	name := c.Containers()[0]
+	want := []string{"/usr/bin/docker", "exec", "-i", "-w", sub, "-e", "AGENTSDK_SCRATCH=/tmp/x", name, "bash", "-c", "go test ./..."}
+	if !slices.Equal(argv, want) {
+		t.Fatalf("exec argv = %v, want %v", argv, want)
+	}
+
+	if _, err := c.Isolate(context.Background(), IsolatedCommand{Argv: []string{"true"}, SessionID: "s1"}); err != nil {
+		t.Fatalf("reuse: %v", err)
+	}
+	if _, err := c.Isolate(context.Background(), IsolatedCommand{Argv: []string{"true"}, SessionID: "s2"}); err != nil {
+		t.Fatalf("second session: %v", err)
+	}
+	if len(cli.calls) != 2 || len(c.Containers()) != 2 {
+		t.Fatalf("expected a container per session, calls %v", cli.calls)
+	}
+	if !c.Excluded("gh pr list") || c.Excluded("gh pr list | tee out") {
+		t.Fatal("unexpected exclusion match")
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	last := cli.calls[len(cli.calls)-1]
+	if !slices.Equal(last[:3], []string{"/usr/bin/docker", "rm", "-f"}) || len(last) != 5 {
+		t.Fatalf("expected rm -f of both containers, got %v", last)
+	}
+	if _, err := c.Isolate(context.Background(), IsolatedCommand{Argv: []string{"true"}, SessionID: "s1"}); !errors.Is(err, ErrContainerSandboxClosed) {
+		t.Fatalf("expected closed error, got %v", err)
+	}
+}
+
+func TestContainerSandboxStartsDoNotBlockOtherSessions(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	runs := map[string]int{}
+	run := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		session := ""
+		for _, arg := range args {
+			if v, ok := strings.CutPrefix(arg, "agentsdk.session="); ok {
+				session = v
+			}
+		}
+		mu.Lock()
+		runs[session]++
+		mu.Unlock()
+		if session == "slow" {
+			<-release
+		}
+		return nil, nil
+	}
+	c := newContainerSandbox(ContainerConfig{Image: "alpine", Root: t.TempDir()}, fakeLookPath(map[string]string{"docker": "/usr/bin/docker"}), run)
+
+	var wg sync.WaitGroup
+	names := make([]string, 2)
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names[i], _ = c.container(context.Background(), "slow")
+		}()
+	}
+	// The slow start (an image pull, say) must not hold up another session.
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.container(context.Background(), "fast")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("fast session: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("fast session blocked behind slow start")
+	}
+	close(release)
+	wg.Wait()
+	if names[0] == "" || names[0] != names[1] {
+		t.Fatalf("concurrent callers got %q", names)
+	}
+	if runs["slow"] != 1 {
+		t.Fatalf("expected one start for the slow session, got %d", runs["slow"])
+	}
+}
+
+func TestContainerSandboxRejectsUnmountedWorkdir(t *testing.T) {
+	cli := &fakeContainerCLI{}
+	c := newContainerSandbox(ContainerConfig{Image: "alpine", Root: t.TempDir()}, fakeLookPath(map[string]string{"docker": "docker"}), cli.run)
+	_, err := c.Isolate(context.Background(), IsolatedCommand{Argv: []string{"ls"}, Dir: t.TempDir()})
+	if !errors.Is(err, ErrPathDenied) {
+		t.Fatalf("expected ErrPathDenied, got %v", err)
+	}
+	if len(cli.calls) != 0 {
+		t.Fatalf("no container should start, got %v", cli.calls)
+	}
+}
+
+func TestContainerSandboxStartFailure(t *testing.T) {
+	cli := &fakeContainerCLI{err: errors.New("image not found")}
+	c := newContainerSandbox(ContainerConfig{Runtime: "podman", Image: "missing", Root: t.TempDir()}, fakeLookPath(map[string]string{"podman": "podman"}), cli.run)
+	_, err := c.Isolate(context.Background(), IsolatedCommand{Argv: []string{"ls"}, SessionID: "s"})
+	if err == nil || !strings.Contains(err.Error(), "image not found") {
+		t.Fatalf("expected start error, got %v", err)
+	}
+	if len(c.Containers()) != 0 {
+		t.Fatal("failed start must not be recorded")
+	}
+}
+
+func TestContainerSandboxUnavailable(t *testing.T) {
+	cases := []struct {
+		cfg    ContainerConfig
+		reason string
+	}{
+		{ContainerConfig{Image: "alpine"}, "docker not found"},
+		{ContainerConfig{Image: "alpine", Runtime: "lxc"}, "unsupported"},
+		{ContainerConfig{}, "image"},
+	}
+	for _, tc := range cases {
+		c := newContainerSandbox(tc.cfg, fakeLookPath(nil), (&fakeContainerCLI{}).run)
+		if c.Enforced() || !strings.Contains(c.Status().Reason, tc.reason) {
+			t.Fatalf("unexpected status %+v", c.Status())
+		}
+		argv, err := c.Isolate(context.Background(), IsolatedCommand{Argv: []string{"ls"}})
+		if err != nil || !slices.Equal(argv, []string{"ls"}) {
+			t.Fatalf("unenforced sandbox should pass argv through, got %v %v", argv, err)
+		}
+	}
+}
@@ -0,0 +1,428 @@
+package sandbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxEgressRecords bounds the request log kept by EgressProxy.
+const maxEgressRecords = 1000
+
+// egressDialTimeout bounds connecting to an allowed upstream.
+const egressDialTimeout = 30 * time.Second
+
+// EgressRecord is one request seen by EgressProxy.
+type EgressRecord struct {
+	Time     time.Time
+	Protocol string // "http", "connect" or "socks5"
+	Host     string
+	Port     int
+	Allowed  bool
+	Reason   string // why a request was blocked
+}
+
+// EgressStatus reports the proxy addresses and the request log.
+type EgressStatus struct {
+	HTTPAddr  string
+	SOCKSAddr string
+	Requests  []EgressRecord
+}
+
+// EgressProxy is a local HTTP and SOCKS5 proxy that only connects to hosts
+// accepted by its check function. Sandboxed commands reach the network
+// through it via the variables returned by Env.
+type EgressProxy struct {
+	check func(host string) error
+	dial  func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	httpLn  net.Listener
+	socksLn net.Listener
+	server  *http.Server
+
+	mu      sync.Mutex
+	records []EgressRecord
+	conns   map[net.Conn]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// NewEgressProxy listens on httpAddr (HTTP CONNECT and plain HTTP
+// forwarding) and socksAddr (SOCKS5 CONNECT); an empty address skips that
+// listener. check decides per destination host; a non-nil error blocks it.
+func NewEgressProxy(check func(host string) error, httpAddr, socksAddr string) (*EgressProxy, error) {
+	if check == nil {
+		return nil, errors.New("sandbox: egress proxy needs a host check")
+	}
+	p := &EgressProxy{
+		check: check,
+		dial:  (&net.Dialer{Timeout: egressDialTimeout}).DialContext,
+		conns: map[net.Conn]struct{}{},
+	}
+	if httpAddr != "" {
+		ln, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			return nil, fmt.Errorf("sandbox: egress http listener: %w", err)
+		}
+		p.httpLn = ln
+		p.server = &http.Server{Handler: http.HandlerFunc(p.serveHTTP), ReadHeaderTimeout: egressDialTimeout}
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			_ = p.server.Serve(ln)
+		}()
+	}
+	if socksAddr != "" {
+		ln, err := net.Listen("tcp", socksAddr)
+		if err != nil {
+			_ = p.Close()
+			return nil, fmt.Errorf("sandbox: egress socks listener: %w", err)
+		}
+		p.socksLn = ln
+		p.wg.Add(1)
+		go p.acceptSOCKS(ln)
+	}
+	return p, nil
+}
+
+// HTTPAddr returns the HTTP listener address, or "".
+func (p *EgressProxy) HTTPAddr() string {
+	if p == nil || p.httpLn == nil {
+		return ""
+	}
+	return p.httpLn.Addr().String()
+}
+
+// SOCKSAddr returns the SOCKS5 listener address, or "".
+func (p *EgressProxy) SOCKSAddr() string {
+	if p == nil || p.socksLn == nil {
+		return ""
+	}
+	return p.socksLn.Addr().String()
+}
+
+// Ports lists the listening ports.
+func (p *EgressProxy) Ports() []int {
+	var out []int
+	for _, addr := range []string{p.HTTPAddr(), p.SOCKSAddr()} {
+		if _, port, err := net.SplitHostPort(addr); err == nil {
+			if n, err := strconv.Atoi(port); err == nil {
+				out = append(out, n)
+			}
+		}
+	}
+	return out
+}
+
+// Env returns the proxy variables for sandboxed commands. HTTP(S)_PROXY
+// point at the HTTP listener; ALL_PROXY prefers SOCKS5 when it runs.
+func (p *EgressProxy) Env() []string {
+	if p == nil {
+		return nil
+	}
+	var env []string
+	all := ""
+	if addr := p.HTTPAddr(); addr != "" {
+		url := "http://" + addr
+		all = url
+		env = append(env, "HTTP_PROXY="+url, "HTTPS_PROXY="+url, "http_proxy="+url, "https_proxy="+url)
+	}
+	if addr := p.SOCKSAddr(); addr != "" {
+		all = "socks5h://" + addr
+	}
+	if all != "" {
+		env = append(env, "ALL_PROXY="+all, "all_proxy="+all, "NO_PROXY=", "no_proxy=")
+	}
+	return env
+}
+
+// Status returns the addresses and a copy of the request log.
+func (p *EgressProxy) Status() EgressStatus {
+	if p == nil {
+		return EgressStatus{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return EgressStatus{
+		HTTPAddr:  p.HTTPAddr(),
+		SOCKSAddr: p.SOCKSAddr(),
+		Requests:  append([]EgressRecord(nil), p.records...),
+	}
+}
+
+// Close stops the listeners and drops open tunnels.
+func (p *EgressProxy) Close() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	for conn := range p.conns {
+		_ = conn.Close()
+	}
+	p.mu.Unlock()
+	var err error
+	if p.server != nil {
+		err = errors.Join(err, p.server.Close())
+	}
+	if p.socksLn != nil {
+		if e := p.socksLn.Close(); e != nil && !errors.Is(e, net.ErrClosed) {
+			err = errors.Join(err, e)
+		}
+	}
+	p.wg.Wait()
+	return err
+}
+
+// allow checks host, records the outcome and returns the blocking error.
+func (p *EgressProxy) allow(protocol, host string, port int) error {
+	err := p.check(host)
+	rec := EgressRecord{Time: time.Now(), Protocol: protocol, Host: host, Port: port, Allowed: err == nil}
+	if err != nil {
+		rec.Reason = err.Error()
+	}
+	p.mu.Lock()
+	p.records = append(p.records, rec)
+	if over := len(p.records) - maxEgressRecords; over > 0 {
+		p.records = append(p.records[:0:0], p.records[over:]...)
+	}
+	p.mu.Unlock()
+	return err
+}
+
+func (p *EgressProxy) track(conn net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.conns[conn] = struct{}{}
+	return true
+}
+
+func (p *EgressProxy) untrack(conn net.Conn) {
+	p.mu.Lock()
+	delete(p.conns, conn)
+	p.mu.Unlock()
+	_ = conn.Close()
+}
+
+func splitHostPort(hostport string, defaultPort int) (string, int) {
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return hostport, defaultPort
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return host, defaultPort
+	}
+	return host, port
+}
+
+func (p *EgressProxy) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.serveConnect(w, r)
+		return
+	}
+	if r.URL.Host == "" {
+		http.Error(w, "egress proxy: absolute URL required", http.StatusBadRequest)
+		return
+	}
+	host, port := splitHostPort(r.URL.Host, 80)
+	if err := p.allow("http", host, port); err != nil {
+		http.Error(w, "egress proxy: blocked by sandbox network policy: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	out.Header.Del("Proxy-Connection")
+	out.Header.Del("Proxy-Authorization")
+	transport := &http.Transport{Proxy: nil, DialContext: p.dial}
+	defer transport.CloseIdleConnections()
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, "egress proxy: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+func (p *EgressProxy) serveConnect(w http.ResponseWriter, r *http.Request) {
+	host, port := splitHostPort(r.Host, 443)
+	if err := p.allow("connect", host, port); err != nil {
+		http.Error(w, "egress proxy: blocked by sandbox network policy: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	upstream, err := p.dial(r.Context(), "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		http.Error(w, "egress proxy: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		_ = upstream.Close()
+		http.Error(w, "egress proxy: hijacking unsupported", http.StatusInternalServerError)
+		return
+	}
+	client, buf, err := hijacker.Hijack()
+	if err != nil {
+		_ = upstream.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		_ = client.Close()
+		_ = upstream.Close()
+		return
+	}
+	p.tunnel(client, buf.Reader, upstream)
+}
+
+// tunnel copies between client and upstream until either side closes.
+func (p *EgressProxy) tunnel(client net.Conn, clientReader io.Reader, upstream net.Conn) {
+	if !p.track(client) {
+		_ = client.Close()
+		_ = upstream.Close()
+		return
+	}
+	if !p.track(upstream) {
+		p.untrack(client)
+		_ = upstream.Close()
+		return
+	}
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, clientReader)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+	p.untrack(client)
+	p.untrack(upstream)
+	<-done
+}
+
+func (p *EgressProxy) acceptSOCKS(ln net.Listener) {
+	defer p.wg.Done()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go p.serveSOCKS(conn)
+	}
+}
+
+// SOCKS5 reply codes (RFC 1928).
+const (
+	socksSucceeded          = 0x00
+	socksNotAllowed         = 0x02
+	socksHostUnreachable    = 0x04
+	socksCommandUnsupported = 0x07
+	socksAddressUnsupported = 0x08
+)
+
+// serveSOCKS handles one SOCKS5 CONNECT without authentication.
+func (p *EgressProxy) serveSOCKS(conn net.Conn) {
+	_ = conn.SetDeadline(time.Now().Add(egressDialTimeout))
+	r := bufio.NewReader(conn)
+	reply := func(code byte) {
+		_, _ = conn.Write([]byte{0x05, code, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+	}
+
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil || head[0] != 0x05 {
+		_ = conn.Close()
+		return
+	}
+	methods := make([]byte, head[1])
+	if _, err := io.ReadFull(r, methods); err != nil {
+		_ = conn.Close()
+		return
+	}
+	if _, err := conn.Write([]byte{0x05, 0x00}); err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(r, req[:]); err != nil || req[0] != 0x05 {
+		_ = conn.Close()
+		return
+	}
+	var host string
+	switch req[3] {
+	case 0x01, 0x04:
+		ip := make(net.IP, 4)
+		if req[3] == 0x04 {
+			ip = make(net.IP, 16)
+		}
+		if _, err := io.ReadFull(r, ip); err != nil {
+			_ = conn.Close()
+			return
+		}
+		host = ip.String()
+	case 0x03:
+		n, err := r.ReadByte()
+		if err != nil {
+			_ = conn.Close()
+			return
+		}
+		name := make([]byte, n)
+		if _, err := io.ReadFull(r, name); err != nil {
+			_ = conn.Close()
+			return
+		}
+		host = string(name)
+	default:
+		reply(socksAddressUnsupported)
+		_ = conn.Close()
+		return
+	}
+	var portBuf [2]byte
+	if _, err := io.ReadFull(r, portBuf[:]); err != nil {
+		_ = conn.Close()
+		return
+	}
+	port := int(binary.BigEndian.Uint16(portBuf[:]))
+	if req[1] != 0x01 {
+		reply(socksCommandUnsupported)
+		_ = conn.Close()
+		return
+	}
+	if err := p.allow("socks5", host, port); err != nil {
+		reply(socksNotAllowed)
+		_ = conn.Close()
+		return
+	}
+	upstream, err := p.dial(context.Background(), "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		reply(socksHostUnreachable)
+		_ = conn.Close()
+		return
+	}
+	reply(socksSucceeded)
+	_ = conn.SetDeadline(time.Time{})
+	p.tunnel(conn, r, upstream)
+}
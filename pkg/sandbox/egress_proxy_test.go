@@ -0,0 +1,170 @@
+package sandbox
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func startTestProxy(t *testing.T) *EgressProxy {
+	t.Helper()
+	p, err := NewEgressProxy(func(host string) error {
+		if host == "127.0.0.1" {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrDomainDenied, host)
+	}, "127.0.0.1:0", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("start proxy: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	return p
+}
+
+func TestEgressProxyHTTPFiltersAndLogs(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "plain")
+	}))
+	defer upstream.Close()
+	tlsUpstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "tunnelled")
+	}))
+	defer tlsUpstream.Close()
+
+	p := startTestProxy(t)
+	proxyURL, _ := url.Parse("http://" + p.HTTPAddr())
+	transport := tlsUpstream.Client().Transport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	client := &http.Client{Transport: transport}
+
+	for _, target := range []struct{ url, body string }{{upstream.URL, "plain"}, {tlsUpstream.URL, "tunnelled"}} {
+		resp, err := client.Get(target.url)
+		if err != nil {
+			t.Fatalf("get %s: %v", target.url, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != target.body {
+			t.Fatalf("get %s: %d %q", target.url, resp.StatusCode, body)
+		}
+	}
+
+	resp, err := client.Get("http://blocked.example/")
+	if err != nil {
+		t.Fatalf("blocked get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for blocked host, got %d", resp.StatusCode)
+	}
+
+	records := p.Status().Requests
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", records)
+	}
+	if records[0].Protocol != "http" || !records[0].Allowed || records[1].Protocol != "connect" || !records[1].Allowed {
+		t.Fatalf("unexpected allowed records %+v", records[:2])
+	}
+	if blocked := records[2]; blocked.Allowed || blocked.Host != "blocked.example" || blocked.Port != 80 || !strings.Contains(blocked.Reason, "domain denied") {
+		t.Fatalf("unexpected blocked record %+v", blocked)
+	}
+}
+
+func socksConnect(t *testing.T, proxyAddr string, addr []byte, port int) (net.Conn, byte) {
+	t.Helper()
+	conn, err := net.Dial("tcp", proxyAddr)
+	if err != nil {
+		t.Fatalf("dial proxy: %v", err)
+	}
+	if _, err := conn.Write([]byte{0x05, 0x01, 0x00}); err != nil {
+		t.Fatalf("greeting: %v", err)
+	}
+	var choice [2]byte
+	if _, err := io.ReadFull(conn, choice[:]); err != nil || choice != [2]byte{0x05, 0x00} {
+		t.Fatalf("method selection %v: %v", choice, err)
+	}
+	req := append([]byte{0x05, 0x01, 0x00}, addr...)
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	var reply [10]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	return conn, reply[1]
+}
+
+func TestEgressProxySOCKS5(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "via socks")
+	}))
+	defer upstream.Close()
+	_, portStr, _ := net.SplitHostPort(strings.TrimPrefix(upstream.URL, "http://"))
+	var port int
+	fmt.Sscan(portStr, &port)
+
+	p := startTestProxy(t)
+
+	domain := append([]byte{0x03, byte(len("blocked.example"))}, "blocked.example"...)
+	conn, code := socksConnect(t, p.SOCKSAddr(), domain, 443)
+	conn.Close()
+	if code != socksNotAllowed {
+		t.Fatalf("expected not-allowed reply, got %#x", code)
+	}
+
+	conn, code = socksConnect(t, p.SOCKSAddr(), []byte{0x01, 127, 0, 0, 1}, port)
+	defer conn.Close()
+	if code != socksSucceeded {
+		t.Fatalf("expected success reply, got %#x", code)
+	}
+	if _, err := io.WriteString(conn, "GET / HTTP/1.0\r\nHost: x\r\n\r\n"); err != nil {
+		t.Fatalf("write through tunnel: %v", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("read through tunnel: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "via socks" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	records := p.Status().Requests
+	if len(records) != 2 || records[0].Allowed || records[0].Protocol != "socks5" || !records[1].Allowed || records[1].Port != port {
+		t.Fatalf("unexpected records %+v", records)
+	}
+}
+
+func TestEgressProxyEnvAndClose(t *testing.T) {
+	p := startTestProxy(t)
+	env := p.Env()
+	if !slices.Contains(env, "HTTPS_PROXY=http://"+p.HTTPAddr()) || !slices.Contains(env, "ALL_PROXY=socks5h://"+p.SOCKSAddr()) {
+		t.Fatalf("unexpected env %v", env)
+	}
+	if len(p.Ports()) != 2 {
+		t.Fatalf("expected two ports, got %v", p.Ports())
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, err := net.Dial("tcp", p.HTTPAddr()); err == nil {
+		t.Fatal("expected listener to be closed")
+	}
+	if _, err := NewEgressProxy(nil, "127.0.0.1:0", ""); err == nil {
+		t.Fatal("expected error without a host check")
+	}
+
+	var nilProxy *EgressProxy
+	if nilProxy.Env() != nil || nilProxy.Close() != nil || len(nilProxy.Status().Requests) != 0 {
+		t.Fatal("nil proxy should be inert")
+	}
+}
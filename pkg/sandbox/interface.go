@@ -1,6 +1,7 @@
 package sandbox
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -34,6 +35,25 @@ type NetworkPolicy interface {
 	Allowed() []string
 }
 
+// IsolatedCommand is a shell invocation handed to a CommandIsolator.
+type IsolatedCommand struct {
+	Argv      []string
+	SessionID string
+	Dir       string
+	// Env lists KEY=VALUE pairs the runtime adds for this call.
+	Env []string
+}
+
+// CommandIsolator runs shell commands inside an isolation backend such as
+// OSSandbox or ContainerSandbox.
+type CommandIsolator interface {
+	// Isolate returns the argv that runs cmd inside the backend.
+	Isolate(ctx context.Context, cmd IsolatedCommand) ([]string, error)
+	// Excluded reports whether command bypasses the backend.
+	Excluded(command string) bool
+	Status() OSStatus
+}
+
 // ResourceUsage captures measured resource consumption.
 type ResourceUsage struct {
 	CPUPercent  float64
@@ -142,7 +162,8 @@ func (m *Manager) CheckUsage(usage ResourceUsage) error {
 	return m.rp.Validate(usage)
 }
 
-// Enforce executes every configured guard in order.
+// Enforce executes every configured guard in order, then asks the policy
+// from settings, if any, about the path and host.
 func (m *Manager) Enforce(path string, host string, usage ResourceUsage) error {
 	if err := m.CheckPath(path); err != nil {
 		return err
@@ -150,7 +171,16 @@ func (m *Manager) Enforce(path string, host string, usage ResourceUsage) error {
 	if err := m.CheckNetwork(host); err != nil {
 		return err
 	}
-	return m.CheckUsage(usage)
+	if err := m.CheckUsage(usage); err != nil {
+		return err
+	}
+	if m == nil || m.permSandbox == nil || (path == "" && host == "") {
+		return nil
+	}
+	if err := m.ensurePermissionsLoaded(); err != nil {
+		return err
+	}
+	return m.permSandbox.CheckSandboxPolicy(path, host)
 }
 
 // Limits reports the resource ceilings when configured.
@@ -173,6 +203,18 @@ func (m *Manager) CheckToolPermission(tool string, params map[string]any) (secur
 	return m.permSandbox.CheckToolPermission(tool, params)
 }
 
+// AddPermissionRule adds a permission rule to the loaded rules; see
+// security.Sandbox.AddPermissionRule.
+func (m *Manager) AddPermissionRule(action security.PermissionAction, rule string) error {
+	if m == nil || m.permSandbox == nil {
+		return nil
+	}
+	if err := m.ensurePermissionsLoaded(); err != nil {
+		return err
+	}
+	return m.permSandbox.AddPermissionRule(action, rule)
+}
+
 // PermissionAudits returns a snapshot of the latest audited permission decisions.
 func (m *Manager) PermissionAudits() []security.PermissionAudit {
 	if m == nil || m.permSandbox == nil {
@@ -0,0 +1,408 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// OSBackend names the operating-system facility that isolates shell commands.
+type OSBackend string
+
+const (
+	// OSBackendNone means commands run without OS isolation.
+	OSBackendNone OSBackend = ""
+	// OSBackendBubblewrap wraps commands in bwrap mount/pid namespaces (Linux).
+	OSBackendBubblewrap OSBackend = "bubblewrap"
+	// OSBackendSeatbelt wraps commands in a sandbox-exec profile (macOS).
+	OSBackendSeatbelt OSBackend = "seatbelt"
+	// OSBackendDocker runs commands in a per-session Docker container.
+	OSBackendDocker OSBackend = "docker"
+	// OSBackendPodman runs commands in a per-session Podman container.
+	OSBackendPodman OSBackend = "podman"
+)
+
+// OSConfig describes the isolation applied to shell commands. Reads are
+// allowed everywhere; writes are limited to Root and WritablePaths.
+type OSConfig struct {
+	Root          string
+	WritablePaths []string
+	// ExcludedCommands run outside the sandbox. An entry matches a command
+	// equal to it or starting with it followed by a space ("docker",
+	// "git push"); commands containing shell metacharacters never match.
+	ExcludedCommands []string
+	// AllowUnixSockets lists socket paths reachable from inside the sandbox.
+	// Other sockets under /tmp and /run (Linux) or anywhere (macOS) are not.
+	AllowUnixSockets  []string
+	AllowLocalBinding bool
+	// Weaker skips the pid namespace and fresh /proc and /dev mounts that
+	// fail inside unprivileged containers (Linux only).
+	Weaker bool
+	// Env lists KEY=VALUE pairs set inside the sandbox, such as the
+	// EgressProxy variables.
+	Env []string
+	// ProxyPorts limits outbound TCP to these localhost ports; empty leaves
+	// outbound TCP open. On Linux this needs socat: the command gets a
+	// private network namespace and socat relays the ports into it. Without
+	// socat the network stays shared and Status().EgressReason says so.
+	ProxyPorts []int
+}
+
+// OSStatus reports whether shell commands are isolated and how.
+type OSStatus struct {
+	Backend          OSBackend
+	Enforced         bool
+	Reason           string
+	Image            string
+	WritablePaths    []string
+	ExcludedCommands []string
+	AllowUnixSockets []string
+	// EgressEnforced reports that outbound TCP reaches at most ProxyPorts.
+	EgressEnforced bool
+	// EgressReason explains why ProxyPorts are set but not enforced.
+	EgressReason string
+}
+
+// OSSandbox wraps shell invocations in bubblewrap on Linux or sandbox-exec
+// on macOS. A nil *OSSandbox leaves commands unchanged; one whose backend is
+// unavailable only adds its Env.
+type OSSandbox struct {
+	cfg     OSConfig
+	backend OSBackend
+	binary  string
+	reason  string
+	// socat and bridge confine bubblewrap commands to ProxyPorts.
+	socat        string
+	bridge       *proxyBridge
+	egressReason string
+}
+
+// NewOSSandbox detects the backend for the current platform. When none is
+// usable the sandbox is returned unenforced with Status().Reason explaining
+// why.
+func NewOSSandbox(cfg OSConfig) *OSSandbox {
+	return newOSSandbox(cfg, runtime.GOOS, exec.LookPath, probeBubblewrap)
+}
+
+func newOSSandbox(cfg OSConfig, goos string, lookPath func(string) (string, error), probe func(bin string, args []string) error) *OSSandbox {
+	s := &OSSandbox{cfg: normalizeOSConfig(cfg)}
+	switch goos {
+	case "linux":
+		bin, err := lookPath("bwrap")
+		if err != nil {
+			s.reason = "bubblewrap (bwrap) not found in PATH"
+			return s
+		}
+		s.binary = bin
+		if probe != nil {
+			if err := probe(bin, s.bubblewrapArgs(nil, []string{"true"})); err != nil {
+				s.reason = fmt.Sprintf("bubblewrap unusable: %v", err)
+				return s
+			}
+		}
+		s.backend = OSBackendBubblewrap
+		s.confineEgress(lookPath, probe)
+	case "darwin":
+		bin, err := lookPath("sandbox-exec")
+		if err != nil {
+			s.reason = "sandbox-exec not found in PATH"
+			return s
+		}
+		s.binary = bin
+		s.backend = OSBackendSeatbelt
+	default:
+		s.reason = fmt.Sprintf("no OS sandbox backend for %s", goos)
+	}
+	return s
+}
+
+// confineEgress gives bubblewrap commands a private network namespace that
+// only reaches ProxyPorts. When that is not possible the network stays
+// shared and egressReason records why.
+func (s *OSSandbox) confineEgress(lookPath func(string) (string, error), probe func(bin string, args []string) error) {
+	if len(s.cfg.ProxyPorts) == 0 {
+		return
+	}
+	socat, err := lookPath("socat")
+	if err != nil {
+		s.egressReason = "socat not found in PATH; outbound network is not confined to the egress proxy"
+		return
+	}
+	bridge, err := newProxyBridge(s.cfg.ProxyPorts)
+	if err != nil {
+		s.egressReason = err.Error()
+		return
+	}
+	s.socat, s.bridge = socat, bridge
+	if probe != nil {
+		if err := probe(s.binary, s.bubblewrapArgs(nil, []string{"true"})); err != nil {
+			s.socat, s.bridge = "", nil
+			_ = bridge.Close()
+			s.egressReason = fmt.Sprintf("bubblewrap network namespace unusable: %v", err)
+		}
+	}
+}
+
+func probeBubblewrap(bin string, args []string) error {
+	out, err := exec.Command(bin, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+func normalizeOSConfig(cfg OSConfig) OSConfig {
+	out := OSConfig{
+		Root:              cleanAbs(cfg.Root),
+		AllowLocalBinding: cfg.AllowLocalBinding,
+		Weaker:            cfg.Weaker,
+		Env:               append([]string(nil), cfg.Env...),
+		ProxyPorts:        append([]int(nil), cfg.ProxyPorts...),
+	}
+	out.WritablePaths = uniquePaths(append([]string{cfg.Root}, cfg.WritablePaths...))
+	out.AllowUnixSockets = uniquePaths(cfg.AllowUnixSockets)
+	out.ExcludedCommands = normalizeCommands(cfg.ExcludedCommands)
+	return out
+}
+
+// uniquePaths cleans paths and adds their symlink-resolved forms, since both
+// bwrap binds and seatbelt subpaths operate on real paths.
+func uniquePaths(paths []string) []string {
+	var out []string
+	seen := map[string]struct{}{}
+	add := func(p string) {
+		if _, ok := seen[p]; ok || p == "" {
+			return
+		}
+		seen[p] = struct{}{}
+		out = append(out, p)
+	}
+	for _, p := range paths {
+		clean := cleanAbs(p)
+		add(clean)
+		if resolved, err := filepath.EvalSymlinks(clean); err == nil {
+			add(resolved)
+		}
+	}
+	return out
+}
+
+func cleanAbs(p string) string {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return filepath.Clean(p)
+}
+
+// Enforced reports whether commands are wrapped.
+func (s *OSSandbox) Enforced() bool { return s != nil && s.backend != OSBackendNone }
+
+// Status describes the sandbox for runtime reports.
+func (s *OSSandbox) Status() OSStatus {
+	if s == nil {
+		return OSStatus{}
+	}
+	return OSStatus{
+		Backend:          s.backend,
+		Enforced:         s.Enforced(),
+		Reason:           s.reason,
+		EgressEnforced:   s.bridge != nil || (s.backend == OSBackendSeatbelt && len(s.cfg.ProxyPorts) > 0),
+		EgressReason:     s.egressReason,
+		WritablePaths:    append([]string(nil), s.cfg.WritablePaths...),
+		ExcludedCommands: append([]string(nil), s.cfg.ExcludedCommands...),
+		AllowUnixSockets: append([]string(nil), s.cfg.AllowUnixSockets...),
+	}
+}
+
+// Excluded reports whether command matches ExcludedCommands and so runs
+// without isolation.
+func (s *OSSandbox) Excluded(command string) bool {
+	return s != nil && matchExcluded(s.cfg.ExcludedCommands, command)
+}
+
+// matchExcluded reports whether command equals a prefix or starts with one
+// followed by a space. Commands with shell metacharacters never match, so an
+// excluded program cannot carry other commands out of the sandbox.
+func matchExcluded(prefixes []string, command string) bool {
+	if strings.ContainsAny(command, ";&|`$<>()\n") {
+		return false
+	}
+	command = strings.Join(strings.Fields(command), " ")
+	for _, prefix := range prefixes {
+		if command == prefix || strings.HasPrefix(command, prefix+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCommands collapses whitespace and drops empty entries.
+func normalizeCommands(commands []string) []string {
+	var out []string
+	for _, cmd := range commands {
+		if cmd = strings.Join(strings.Fields(cmd), " "); cmd != "" {
+			out = append(out, cmd)
+		}
+	}
+	return out
+}
+
+// Wrap returns argv prefixed with the sandbox launcher. extraWritable adds
+// writable paths for this invocation only (for example a script directory).
+// When the sandbox is not enforced argv only gets the configured Env.
+func (s *OSSandbox) Wrap(argv []string, extraWritable ...string) []string {
+	if s == nil || len(argv) == 0 {
+		return argv
+	}
+	if !s.Enforced() {
+		return withEnv(s.cfg.Env, argv)
+	}
+	var args []string
+	switch s.backend {
+	case OSBackendBubblewrap:
+		args = s.bubblewrapArgs(extraWritable, argv)
+	case OSBackendSeatbelt:
+		args = s.seatbeltArgs(extraWritable, argv)
+	}
+	return append([]string{s.binary}, args...)
+}
+
+// Close stops the egress relay used by bubblewrap commands.
+func (s *OSSandbox) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.bridge.Close()
+}
+
+// Isolate implements CommandIsolator. The command keeps the caller's
+// environment, so cmd.Env and cmd.SessionID are not used.
+func (s *OSSandbox) Isolate(_ context.Context, cmd IsolatedCommand) ([]string, error) {
+	return s.Wrap(cmd.Argv), nil
+}
+
+// WrapCommand wraps `bash -c command` unless the command is excluded.
+func (s *OSSandbox) WrapCommand(command string) []string {
+	argv := []string{"bash", "-c", command}
+	if s.Excluded(command) {
+		return argv
+	}
+	return s.Wrap(argv)
+}
+
+// bubblewrapArgs mounts the host read-only, gives the command a private
+// /tmp, hides the daemon sockets under /run and binds the writable paths and
+// allowed sockets back in. Later mounts shadow earlier ones. With a proxy
+// bridge the network is unshared and argv runs behind the socat relays.
+func (s *OSSandbox) bubblewrapArgs(extraWritable, argv []string) []string {
+	args := []string{"--die-with-parent", "--unshare-ipc", "--unshare-uts", "--ro-bind", "/", "/"}
+	if s.bridge != nil {
+		args = append(args, "--unshare-net")
+	}
+	if s.cfg.Weaker {
+		args = append(args, "--dev-bind", "/dev", "/dev")
+	} else {
+		args = append(args, "--unshare-pid", "--dev", "/dev", "--proc", "/proc")
+	}
+	args = append(args, "--tmpfs", "/tmp")
+	for _, dir := range []string{"/run", "/var/run"} {
+		if info, err := os.Lstat(dir); err == nil && info.IsDir() {
+			args = append(args, "--tmpfs", dir)
+		}
+	}
+	for _, p := range append(append([]string(nil), s.cfg.WritablePaths...), uniquePaths(extraWritable)...) {
+		if _, err := os.Stat(p); err == nil {
+			args = append(args, "--bind", p, p)
+		}
+	}
+	for _, sock := range s.cfg.AllowUnixSockets {
+		if _, err := os.Stat(sock); err == nil {
+			args = append(args, "--bind", sock, sock)
+		}
+	}
+	if s.bridge != nil {
+		args = append(args, "--bind", s.bridge.dir, s.bridge.dir)
+		argv = append([]string{"sh", "-c", s.bridge.script(s.socat), "sh"}, argv...)
+	}
+	for _, kv := range s.cfg.Env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			args = append(args, "--setenv", k, v)
+		}
+	}
+	args = append(args, "--")
+	return append(args, argv...)
+}
+
+// seatbeltArgs passes paths as -D parameters so the profile never has to
+// quote them.
+func (s *OSSandbox) seatbeltArgs(extraWritable, argv []string) []string {
+	writable := append(append([]string(nil), s.cfg.WritablePaths...), uniquePaths(extraWritable)...)
+	args := []string{"-p", seatbeltProfile(len(writable), len(s.cfg.AllowUnixSockets), s.cfg.AllowLocalBinding, s.cfg.ProxyPorts)}
+	for i, p := range writable {
+		args = append(args, "-D", fmt.Sprintf("WRITABLE_%d=%s", i, p))
+	}
+	for i, p := range s.cfg.AllowUnixSockets {
+		args = append(args, "-D", fmt.Sprintf("SOCKET_%d=%s", i, p))
+	}
+	return append(args, withEnv(s.cfg.Env, argv)...)
+}
+
+// withEnv prefixes argv with `env KEY=VALUE...` when env is set.
+func withEnv(env, argv []string) []string {
+	if len(env) == 0 {
+		return argv
+	}
+	out := append([]string{"env"}, env...)
+	return append(out, argv...)
+}
+
+func seatbeltProfile(writable, sockets int, localBinding bool, proxyPorts []int) string {
+	var b strings.Builder
+	b.WriteString(`(version 1)
+(deny default)
+(allow process*)
+(allow signal (target same-sandbox))
+(allow sysctl-read)
+(allow mach-lookup)
+(allow ipc-posix*)
+(allow file-read*)
+(allow file-ioctl)
+(allow file-write*
+  (literal "/dev/null")
+  (literal "/dev/tty")
+  (literal "/dev/dtracehelper")
+  (regex #"^/dev/fd/")
+  (subpath "/private/tmp")
+  (subpath "/private/var/folders"))
+`)
+	for i := 0; i < writable; i++ {
+		fmt.Fprintf(&b, "(allow file-write* (subpath (param \"WRITABLE_%d\")))\n", i)
+	}
+	b.WriteString("(allow network*)\n")
+	if len(proxyPorts) > 0 {
+		b.WriteString("(deny network-outbound (remote ip \"*:*\"))\n")
+		for _, port := range proxyPorts {
+			fmt.Fprintf(&b, "(allow network-outbound (remote ip \"localhost:%d\"))\n", port)
+		}
+	}
+	b.WriteString("(deny network-outbound (remote unix-socket))\n")
+	for i := 0; i < sockets; i++ {
+		fmt.Fprintf(&b, "(allow network-outbound (remote unix-socket (path-literal (param \"SOCKET_%d\"))))\n", i)
+	}
+	b.WriteString("(deny network-bind)\n")
+	if localBinding {
+		b.WriteString("(allow network-bind (local ip \"localhost:*\"))\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,201 @@
+package sandbox
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func fakeLookPath(found map[string]string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		if path, ok := found[name]; ok {
+			return path, nil
+		}
+		return "", errors.New("not found")
+	}
+}
+
+func TestOSSandboxBubblewrapArgs(t *testing.T) {
+	root := t.TempDir()
+	extra := t.TempDir()
+	var probed []string
+	s := newOSSandbox(OSConfig{
+		Root:             root,
+		ExcludedCommands: []string{" docker ", "git  push"},
+	}, "linux", fakeLookPath(map[string]string{"bwrap": "/usr/bin/bwrap"}), func(bin string, args []string) error {
+		probed = args
+		return nil
+	})
+	if !s.Enforced() || s.Status().Backend != OSBackendBubblewrap {
+		t.Fatalf("expected bubblewrap backend, got %+v", s.Status())
+	}
+	if len(probed) == 0 || probed[len(probed)-1] != "true" {
+		t.Fatalf("expected probe to run true, got %v", probed)
+	}
+
+	argv := s.Wrap([]string{"bash", "-c", "make"}, extra)
+	joined := strings.Join(argv, " ")
+	if argv[0] != "/usr/bin/bwrap" {
+		t.Fatalf("expected bwrap launcher, got %v", argv)
+	}
+	for _, want := range []string{"--ro-bind / /", "--proc /proc", "--tmpfs /tmp", "--bind " + root + " " + root, "--bind " + extra + " " + extra, "-- bash -c make"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("argv missing %q: %s", want, joined)
+		}
+	}
+	if strings.Index(joined, "--tmpfs /tmp") > strings.Index(joined, "--bind "+root) {
+		t.Fatalf("writable binds must follow the /tmp tmpfs: %s", joined)
+	}
+
+	if got := s.WrapCommand("docker ps -a"); !slices.Equal(got, []string{"bash", "-c", "docker ps -a"}) {
+		t.Fatalf("excluded command should run unwrapped, got %v", got)
+	}
+	if !s.Excluded("git push origin") || s.Excluded("git pull") || s.Excluded("dockerd") {
+		t.Fatal("unexpected exclusion match")
+	}
+	if s.Excluded("docker ps && rm -rf /") {
+		t.Fatal("compound commands must not escape the sandbox")
+	}
+}
+
+func TestOSSandboxWeakerNestedMode(t *testing.T) {
+	s := newOSSandbox(OSConfig{Root: t.TempDir(), Weaker: true}, "linux", fakeLookPath(map[string]string{"bwrap": "bwrap"}), nil)
+	joined := strings.Join(s.Wrap([]string{"true"}), " ")
+	if strings.Contains(joined, "--proc") || strings.Contains(joined, "--unshare-pid") {
+		t.Fatalf("weaker mode should skip pid namespace and /proc: %s", joined)
+	}
+	if !strings.Contains(joined, "--dev-bind /dev /dev") {
+		t.Fatalf("weaker mode should bind host /dev: %s", joined)
+	}
+}
+
+func TestOSSandboxUnavailableLeavesCommandsUnchanged(t *testing.T) {
+	cases := []struct {
+		name   string
+		goos   string
+		found  map[string]string
+		probe  func(string, []string) error
+		reason string
+	}{
+		{"missing bwrap", "linux", nil, nil, "not found"},
+		{"probe fails", "linux", map[string]string{"bwrap": "bwrap"}, func(string, []string) error { return errors.New("no user namespaces") }, "no user namespaces"},
+		{"missing sandbox-exec", "darwin", nil, nil, "sandbox-exec"},
+		{"unsupported", "windows", nil, nil, "windows"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newOSSandbox(OSConfig{Root: t.TempDir()}, tc.goos, fakeLookPath(tc.found), tc.probe)
+			status := s.Status()
+			if status.Enforced || status.Backend != OSBackendNone || !strings.Contains(status.Reason, tc.reason) {
+				t.Fatalf("unexpected status %+v", status)
+			}
+			if got := s.WrapCommand("ls"); !slices.Equal(got, []string{"bash", "-c", "ls"}) {
+				t.Fatalf("expected plain argv, got %v", got)
+			}
+		})
+	}
+
+	var nilSandbox *OSSandbox
+	if got := nilSandbox.WrapCommand("ls"); len(got) != 3 || nilSandbox.Enforced() {
+		t.Fatalf("nil sandbox should not wrap, got %v", got)
+	}
+}
+
+func TestOSSandboxSeatbeltProfile(t *testing.T) {
+	root := t.TempDir()
+	s := newOSSandbox(OSConfig{
+		Root:              root,
+		AllowUnixSockets:  []string{"/var/run/docker.sock"},
+		AllowLocalBinding: true,
+	}, "darwin", fakeLookPath(map[string]string{"sandbox-exec": "/usr/bin/sandbox-exec"}), nil)
+	if s.Status().Backend != OSBackendSeatbelt {
+		t.Fatalf("expected seatbelt backend, got %+v", s.Status())
+	}
+	argv := s.WrapCommand("ls")
+	if argv[0] != "/usr/bin/sandbox-exec" || argv[1] != "-p" {
+		t.Fatalf("unexpected launcher %v", argv)
+	}
+	profile := argv[2]
+	for _, want := range []string{
+		"(deny default)",
+		`(allow file-write* (subpath (param "WRITABLE_0")))`,
+		"(deny network-outbound (remote unix-socket))",
+		`(path-literal (param "SOCKET_0"))`,
+		`(allow network-bind (local ip "localhost:*"))`,
+	} {
+		if !strings.Contains(profile, want) {
+			t.Fatalf("profile missing %q:\n%s", want, profile)
+		}
+	}
+	rest := strings.Join(argv[3:], " ")
+	if !strings.Contains(rest, "-D WRITABLE_0="+root) || !strings.Contains(rest, "-D SOCKET_0=/var/run/docker.sock") {
+		t.Fatalf("missing profile parameters: %s", rest)
+	}
+	if !strings.HasSuffix(rest, "bash -c ls") {
+		t.Fatalf("command should follow the parameters: %s", rest)
+	}
+}
+
+func TestOSSandboxProxyEnvironment(t *testing.T) {
+	env := []string{"HTTPS_PROXY=http://127.0.0.1:3128"}
+	linux := newOSSandbox(OSConfig{Root: t.TempDir(), Env: env}, "linux", fakeLookPath(map[string]string{"bwrap": "bwrap"}), nil)
+	if joined := strings.Join(linux.Wrap([]string{"true"}), " "); !strings.Contains(joined, "--setenv HTTPS_PROXY http://127.0.0.1:3128 -- true") {
+		t.Fatalf("bwrap should set proxy env: %s", joined)
+	}
+
+	darwin := newOSSandbox(OSConfig{Root: t.TempDir(), Env: env, ProxyPorts: []int{3128}}, "darwin", fakeLookPath(map[string]string{"sandbox-exec": "sandbox-exec"}), nil)
+	argv := darwin.Wrap([]string{"true"})
+	if !strings.Contains(argv[2], `(deny network-outbound (remote ip "*:*"))`) || !strings.Contains(argv[2], `(allow network-outbound (remote ip "localhost:3128"))`) {
+		t.Fatalf("profile should confine outbound TCP to the proxy:\n%s", argv[2])
+	}
+	if got := argv[len(argv)-3:]; !slices.Equal(got, []string{"env", env[0], "true"}) {
+		t.Fatalf("seatbelt should set proxy env, got %v", argv)
+	}
+
+	unenforced := newOSSandbox(OSConfig{Env: env}, "plan9", fakeLookPath(nil), nil)
+	if got := unenforced.WrapCommand("curl x"); !slices.Equal(got, []string{"env", env[0], "bash", "-c", "curl x"}) {
+		t.Fatalf("unenforced sandbox should still export proxy env, got %v", got)
+	}
+}
+
+func TestOSSandboxBubblewrapConfinesEgress(t *testing.T) {
+	cfg := OSConfig{Root: t.TempDir(), ProxyPorts: []int{3128}}
+	s := newOSSandbox(cfg, "linux", fakeLookPath(map[string]string{"bwrap": "bwrap", "socat": "/usr/bin/socat"}), nil)
+	t.Cleanup(func() { _ = s.Close() })
+	status := s.Status()
+	if !status.EgressEnforced || status.EgressReason != "" {
+		t.Fatalf("expected egress enforced, got %+v", status)
+	}
+	argv := s.Wrap([]string{"curl", "x"})
+	joined := strings.Join(argv, " ")
+	if !strings.Contains(joined, "--unshare-net") || !strings.Contains(joined, "--bind "+s.bridge.dir+" "+s.bridge.dir) {
+		t.Fatalf("expected private network with the bridge dir bound: %s", joined)
+	}
+	if got := argv[len(argv)-6:]; got[0] != "sh" || got[1] != "-c" || got[3] != "sh" || !slices.Equal(got[4:], []string{"curl", "x"}) {
+		t.Fatalf("command should run behind the relay script, got %v", got)
+	}
+	if script := argv[len(argv)-4]; !strings.Contains(script, "'/usr/bin/socat' TCP-LISTEN:3128,bind=127.0.0.1") || !strings.Contains(script, ":0C38 ") {
+		t.Fatalf("unexpected relay script:\n%s", script)
+	}
+
+	shared := newOSSandbox(cfg, "linux", fakeLookPath(map[string]string{"bwrap": "bwrap"}), nil)
+	status = shared.Status()
+	if !status.Enforced || status.EgressEnforced || !strings.Contains(status.EgressReason, "socat") {
+		t.Fatalf("expected unenforced egress without socat, got %+v", status)
+	}
+	if strings.Contains(strings.Join(shared.Wrap([]string{"true"}), " "), "--unshare-net") {
+		t.Fatal("network should stay shared without socat")
+	}
+
+	failing := newOSSandbox(cfg, "linux", fakeLookPath(map[string]string{"bwrap": "bwrap", "socat": "socat"}), func(_ string, args []string) error {
+		if slices.Contains(args, "--unshare-net") {
+			return errors.New("no network namespaces")
+		}
+		return nil
+	})
+	status = failing.Status()
+	if !status.Enforced || status.EgressEnforced || !strings.Contains(status.EgressReason, "no network namespaces") || failing.bridge != nil {
+		t.Fatalf("expected shared network after failed probe, got %+v", status)
+	}
+}
@@ -0,0 +1,126 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// proxyBridge carries the egress proxy into a bubblewrap network namespace.
+// The host side listens on one unix socket per proxy port in dir; inside the
+// sandbox socat re-exposes each socket on 127.0.0.1 at the same port, so the
+// proxy variables keep working while every other destination is unreachable.
+type proxyBridge struct {
+	dir       string
+	ports     []int
+	listeners []net.Listener
+	wg        sync.WaitGroup
+}
+
+func newProxyBridge(ports []int) (*proxyBridge, error) {
+	dir, err := os.MkdirTemp("", "agentsdk-egress-")
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: proxy bridge dir: %w", err)
+	}
+	b := &proxyBridge{dir: dir, ports: append([]int(nil), ports...)}
+	for _, port := range ports {
+		ln, err := net.Listen("unix", b.socket(port))
+		if err != nil {
+			b.Close()
+			return nil, fmt.Errorf("sandbox: proxy bridge listen: %w", err)
+		}
+		b.listeners = append(b.listeners, ln)
+		b.wg.Add(1)
+		go b.serve(ln, net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	}
+	return b, nil
+}
+
+func (b *proxyBridge) socket(port int) string {
+	return filepath.Join(b.dir, strconv.Itoa(port)+".sock")
+}
+
+func (b *proxyBridge) serve(ln net.Listener, addr string) {
+	defer b.wg.Done()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go forward(conn, addr)
+	}
+}
+
+// forward copies conn to the proxy at addr in both directions, half-closing
+// each side when the other finishes writing.
+func forward(conn net.Conn, addr string) {
+	defer conn.Close()
+	upstream, err := net.Dial("tcp", addr)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+	done := make(chan struct{})
+	go func() {
+		//nolint:errcheck // copy errors end the connection either way
+		io.Copy(upstream, conn)
+		closeWrite(upstream)
+		close(done)
+	}()
+	//nolint:errcheck // copy errors end the connection either way
+	io.Copy(conn, upstream)
+	closeWrite(conn)
+	<-done
+}
+
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+	}
+}
+
+// script returns a sh program that starts one socat relay per port, waits
+// until each listens, runs "$@" and stops the relays with the command's
+// exit status.
+func (b *proxyBridge) script(socat string) string {
+	var s strings.Builder
+	s.WriteString("pids=\n")
+	for _, port := range b.ports {
+		fmt.Fprintf(&s, "%s TCP-LISTEN:%d,bind=127.0.0.1,reuseaddr,fork UNIX-CONNECT:%s & pids=\"$pids $!\"\n",
+			shellQuote(socat), port, shellQuote(b.socket(port)))
+	}
+	for _, port := range b.ports {
+		fmt.Fprintf(&s, "i=0; while [ $i -lt 200 ] && ! grep -qi ' 0100007F:%04X 00000000:0000 0A ' /proc/net/tcp; do i=$((i+1)); sleep 0.01; done\n", port)
+	}
+	s.WriteString("\"$@\"; rc=$?\nkill $pids 2>/dev/null\nexit $rc\n")
+	return s.String()
+}
+
+// Close stops accepting connections and removes the socket directory.
+func (b *proxyBridge) Close() error {
+	if b == nil {
+		return nil
+	}
+	var err error
+	for _, ln := range b.listeners {
+		if e := ln.Close(); e != nil && !errors.Is(e, net.ErrClosed) {
+			err = errors.Join(err, e)
+		}
+	}
+	b.wg.Wait()
+	if e := os.RemoveAll(b.dir); e != nil {
+		err = errors.Join(err, e)
+	}
+	return err
+}
+
+// shellQuote single-quotes s for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
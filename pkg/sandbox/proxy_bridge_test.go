@@ -0,0 +1,51 @@
+package sandbox
+
+import (
+	"io"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestProxyBridgeForwardsToLocalPort(t *testing.T) {
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		//nolint:errcheck // echo until the client half-closes
+		io.Copy(conn, conn)
+	}()
+
+	port := upstream.Addr().(*net.TCPAddr).Port
+	b, err := newProxyBridge([]int{port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("unix", b.socket(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	closeWrite(conn)
+	got, err := io.ReadAll(conn)
+	conn.Close()
+	if err != nil || string(got) != "ping" {
+		t.Fatalf("expected echo through the bridge, got %q, %v", got, err)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(b.dir); !os.IsNotExist(err) {
+		t.Fatalf("bridge dir should be removed, got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package sdktest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+)
+
+const defaultContainerStartup = time.Minute
+
+// ContainerRequest describes a container started by StartContainer.
+type ContainerRequest struct {
+	Image string
+	Cmd   []string
+	Env   map[string]string
+	// Ports are container ports ("8080" or "8080/tcp") published on random
+	// loopback ports; see Container.Address.
+	Ports []string
+	// Mounts bind host paths (keys) to container paths (values).
+	Mounts map[string]string
+	// Ready is polled until it returns nil or StartupTimeout (default 1m)
+	// passes. Nil waits for published ports to accept connections.
+	Ready          func(ctx context.Context, c *Container) error
+	StartupTimeout time.Duration
+}
+
+// Container is a running Docker container, removed when the test ends.
+type Container struct {
+	ID    string
+	Image string
+
+	addrs map[string]string
+}
+
+var (
+	dockerOnce sync.Once
+	dockerErr  error
+)
+
+// DockerAvailable reports whether the docker CLI can reach a daemon.
+func DockerAvailable() bool {
+	dockerOnce.Do(func() {
+		if _, err := exec.LookPath("docker"); err != nil {
+			dockerErr = err
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if out, err := exec.CommandContext(ctx, "docker", "info", "--format", "{{.ServerVersion}}").CombinedOutput(); err != nil {
+			dockerErr = fmt.Errorf("docker info: %w: %s", err, bytes.TrimSpace(out))
+		}
+	})
+	return dockerErr == nil
+}
+
+// RequireDocker skips the test when Docker is unavailable.
+func RequireDocker(t testing.TB) {
+	t.Helper()
+	if !DockerAvailable() {
+		t.Skipf("sdktest: docker unavailable: %v", dockerErr)
+	}
+}
+
+// StartContainer runs req detached and waits until it is ready. The test is
+// skipped when Docker is unavailable.
+func StartContainer(t testing.TB, req ContainerRequest) *Container {
+	t.Helper()
+	RequireDocker(t)
+	if strings.TrimSpace(req.Image) == "" {
+		t.Fatalf("sdktest: container image is required")
+	}
+	timeout := req.StartupTimeout
+	if timeout <= 0 {
+		timeout = defaultContainerStartup
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	out, err := docker(ctx, runArgs(req)...)
+	if err != nil {
+		t.Fatalf("sdktest: start %s: %v", req.Image, err)
+	}
+	c := &Container{ID: strings.TrimSpace(out), Image: req.Image, addrs: map[string]string{}}
+	t.Cleanup(func() {
+		rmCtx, rmCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer rmCancel()
+		_, _ = docker(rmCtx, "rm", "-f", "-v", c.ID)
+	})
+
+	for _, port := range req.Ports {
+		port = containerPort(port)
+		mapped, err := docker(ctx, "port", c.ID, port)
+		if err != nil {
+			t.Fatalf("sdktest: port %s of %s: %v", port, req.Image, err)
+		}
+		addr, err := loopbackAddress(mapped)
+		if err != nil {
+			t.Fatalf("sdktest: port %s of %s: %v", port, req.Image, err)
+		}
+		c.addrs[port] = addr
+	}
+
+	ready := req.Ready
+	if ready == nil {
+		ready = portsOpen
+	}
+	for {
+		err := ready(ctx, c)
+		if err == nil {
+			return c
+		}
+		select {
+		case <-ctx.Done():
+			logs, _ := docker(context.Background(), "logs", "--tail", "50", c.ID)
+			t.Fatalf("sdktest: %s not ready after %s: %v\n%s", req.Image, timeout, err, logs)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// StartShellContainer keeps a container of image idle so tests can run
+// commands in it with Exec, e.g. to check sandboxed command behaviour.
+func StartShellContainer(t testing.TB, image string) *Container {
+	t.Helper()
+	return StartContainer(t, ContainerRequest{Image: image, Cmd: []string{"sleep", "infinity"}})
+}
+
+// Address returns the host:port a published container port is reachable on.
+func (c *Container) Address(port string) string {
+	return c.addrs[containerPort(port)]
+}
+
+// MCPConfig returns the settings entry for an SSE MCP server listening on
+// port at path inside the container.
+func (c *Container) MCPConfig(port, path string) config.MCPServerConfig {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return config.MCPServerConfig{Type: "sse", URL: "http://" + c.Address(port) + path}
+}
+
+// Exec runs cmd in the container and returns its combined output.
+func (c *Container) Exec(ctx context.Context, cmd ...string) (string, error) {
+	return docker(ctx, append([]string{"exec", c.ID}, cmd...)...)
+}
+
+func portsOpen(ctx context.Context, c *Container) error {
+	var dialer net.Dialer
+	for port, addr := range c.addrs {
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("port %s: %w", port, err)
+		}
+		_ = conn.Close()
+	}
+	return nil
+}
+
+func runArgs(req ContainerRequest) []string {
+	args := []string{"run", "-d", "--label", "agentsdk.sdktest=1"}
+	for _, k := range sortedKeys(req.Env) {
+		args = append(args, "-e", k+"="+req.Env[k])
+	}
+	for _, host := range sortedKeys(req.Mounts) {
+		args = append(args, "-v", host+":"+req.Mounts[host])
+	}
+	for _, port := range req.Ports {
+		args = append(args, "-p", "127.0.0.1::"+containerPort(port))
+	}
+	args = append(args, req.Image)
+	return append(args, req.Cmd...)
+}
+
+func containerPort(port string) string {
+	port = strings.TrimSpace(port)
+	if !strings.Contains(port, "/") {
+		port += "/tcp"
+	}
+	return port
+}
+
+// loopbackAddress picks the IPv4 mapping from `docker port` output such as
+// "127.0.0.1:49153".
+func loopbackAddress(out string) (string, error) {
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.TrimSpace(line)
+		if host, _, err := net.SplitHostPort(line); err == nil && net.ParseIP(host).To4() != nil {
+			return line, nil
+		}
+	}
+	return "", fmt.Errorf("no IPv4 mapping in %q", out)
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return stdout.String(), err
+		}
+		return stdout.String(), fmt.Errorf("%w: %s", err, msg)
+	}
+	return stdout.String(), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,45 @@
+package sdktest
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunArgs(t *testing.T) {
+	got := runArgs(ContainerRequest{
+		Image:  "alpine:3.20",
+		Cmd:    []string{"sleep", "infinity"},
+		Env:    map[string]string{"B": "2", "A": "1"},
+		Ports:  []string{"8080", "53/udp"},
+		Mounts: map[string]string{"/host": "/data"},
+	})
+	want := []string{"run", "-d", "--label", "agentsdk.sdktest=1",
+		"-e", "A=1", "-e", "B=2", "-v", "/host:/data",
+		"-p", "127.0.0.1::8080/tcp", "-p", "127.0.0.1::53/udp",
+		"alpine:3.20", "sleep", "infinity"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("runArgs = %v", got)
+	}
+	if addr, err := loopbackAddress("[::1]:49154\n127.0.0.1:49153\n"); err != nil || addr != "127.0.0.1:49153" {
+		t.Fatalf("address %q err %v", addr, err)
+	}
+	if _, err := loopbackAddress("[::]:1"); err == nil {
+		t.Fatal("expected error without an IPv4 mapping")
+	}
+}
+
+func TestStartShellContainer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a container")
+	}
+	c := StartShellContainer(t, "alpine:3.20")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := c.Exec(ctx, "echo", "hi")
+	if err != nil || strings.TrimSpace(out) != "hi" {
+		t.Fatalf("exec %q err %v", out, err)
+	}
+}
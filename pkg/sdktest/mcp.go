@@ -0,0 +1,122 @@
+package sdktest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/mcp"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// MCPTool is one tool served by StartMCPServer. Handler receives the call
+// arguments and returns the text result; an error becomes an error result.
+type MCPTool struct {
+	Name        string
+	Description string
+	// InputSchema is the JSON Schema of the arguments; nil accepts any object.
+	InputSchema map[string]any
+	Handler     func(ctx context.Context, args map[string]any) (string, error)
+}
+
+// EchoTool returns its "text" argument.
+func EchoTool() MCPTool {
+	return MCPTool{
+		Name:        "echo",
+		Description: "Echo the text argument.",
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"text": map[string]any{"type": "string"}},
+			"required":   []string{"text"},
+		},
+		Handler: func(_ context.Context, args map[string]any) (string, error) {
+			text, _ := args["text"].(string)
+			return text, nil
+		},
+	}
+}
+
+// MCPCall records one tool call received by an MCPServer.
+type MCPCall struct {
+	Tool string
+	Args map[string]any
+}
+
+// MCPServer is an in-process MCP server speaking SSE over httptest.
+type MCPServer struct {
+	// URL is the SSE endpoint.
+	URL string
+
+	mu    sync.Mutex
+	calls []MCPCall
+}
+
+// StartMCPServer serves tools over SSE until the test ends.
+func StartMCPServer(t testing.TB, tools ...MCPTool) *MCPServer {
+	t.Helper()
+	srv := &MCPServer{}
+	server := mcp.NewServer(&mcp.Implementation{Name: "sdktest", Version: "test"}, nil)
+	for _, tl := range tools {
+		if tl.Name == "" || tl.Handler == nil {
+			t.Fatalf("sdktest: MCP tool needs a name and a handler")
+		}
+		schema := tl.InputSchema
+		if schema == nil {
+			schema = map[string]any{"type": "object"}
+		}
+		server.AddTool(&mcp.Tool{Name: tl.Name, Description: tl.Description, InputSchema: schema}, srv.handler(tl))
+	}
+	ts := httptest.NewServer(mcpsdk.NewSSEHandler(func(*http.Request) *mcpsdk.Server { return server }, nil))
+	t.Cleanup(ts.Close)
+	srv.URL = ts.URL
+	return srv
+}
+
+func (s *MCPServer) handler(tl MCPTool) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := map[string]any{}
+		if len(req.Params.Arguments) > 0 {
+			if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
+				return nil, fmt.Errorf("decode arguments: %w", err)
+			}
+		}
+		s.mu.Lock()
+		s.calls = append(s.calls, MCPCall{Tool: tl.Name, Args: args})
+		s.mu.Unlock()
+		text, err := tl.Handler(ctx, args)
+		if err != nil {
+			return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}}}, nil
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: text}}}, nil
+	}
+}
+
+// Calls returns the tool calls received so far.
+func (s *MCPServer) Calls() []MCPCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]MCPCall(nil), s.calls...)
+}
+
+// Config returns the settings entry that connects to the server, for
+// config.Settings.MCP.Servers.
+func (s *MCPServer) Config() config.MCPServerConfig {
+	return config.MCPServerConfig{Type: "sse", URL: s.URL}
+}
+
+// WithMCPServer registers server under name. Servers are written to
+// .claude/settings.local.json so they combine with WithSettings; they cannot
+// be combined with WithLocalSettings.
+func WithMCPServer(name string, server *MCPServer) ProjectOption {
+	return func(s *projectSpec) {
+		if s.mcpServers == nil {
+			s.mcpServers = map[string]config.MCPServerConfig{}
+		}
+		s.mcpServers[name] = server.Config()
+	}
+}
@@ -0,0 +1,47 @@
+package sdktest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/mcp"
+)
+
+func TestStartMCPServer(t *testing.T) {
+	failing := MCPTool{Name: "fail", Handler: func(context.Context, map[string]any) (string, error) {
+		return "", errors.New("boom")
+	}}
+	srv := StartMCPServer(t, EchoTool(), failing)
+
+	client, err := mcp.NewSpecClient(srv.URL)
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	ctx := context.Background()
+	tools, err := client.ListTools(ctx)
+	if err != nil || len(tools) != 2 {
+		t.Fatalf("tools %+v err %v", tools, err)
+	}
+	res, err := client.InvokeTool(ctx, "echo", map[string]any{"text": "ping"})
+	if err != nil || res.IsError || res.Content[0].(*mcp.TextContent).Text != "ping" {
+		t.Fatalf("echo %+v err %v", res, err)
+	}
+	if res, err := client.InvokeTool(ctx, "fail", nil); err != nil || !res.IsError {
+		t.Fatalf("fail %+v err %v", res, err)
+	}
+	if calls := srv.Calls(); len(calls) != 2 || calls[0].Args["text"] != "ping" {
+		t.Fatalf("calls %+v", calls)
+	}
+
+	p := NewProject(t, WithSettings(`{"model":"m"}`), WithMCPServer("local", srv))
+	settings, err := (&config.SettingsLoader{ProjectRoot: p.Root}).Load()
+	if err != nil {
+		t.Fatalf("settings: %v", err)
+	}
+	if settings.Model != "m" || settings.MCP == nil || settings.MCP.Servers["local"].URL != srv.URL {
+		t.Fatalf("unexpected settings %+v", settings)
+	}
+}
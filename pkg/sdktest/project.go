@@ -0,0 +1,255 @@
+// Package sdktest provides fixtures for integration tests of code built on
+// the SDK: scaffolded project trees (.claude settings, memory, skills, slash
+// commands, subagents and rules), an in-process MCP server, and helpers that
+// run Docker containers for sandbox tests.
+//
+//	root := sdktest.NewProject(t,
+//		sdktest.WithSettings(`{"permissions":{"allow":["Bash"]}}`),
+//		sdktest.WithSkill("lint", "Run the linters.", "Run golangci-lint."),
+//	).Root
+//	rt, err := api.New(ctx, api.Options{ProjectRoot: root, Model: mdl})
+//
+// The SDK has no plugin or marketplace loader, so there are no fixtures for
+// them; extra files of any kind can be added with WithFile.
+package sdktest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+)
+
+//go:embed all:fixtures
+var fixtures embed.FS
+
+// Fixture names accepted by Fixture.
+const (
+	// FixtureMinimal holds settings.json and CLAUDE.md only.
+	FixtureMinimal = "minimal"
+	// FixtureFull adds the "summarize" skill, the "review" slash command,
+	// the "explorer" subagent and a rule.
+	FixtureFull = "full"
+)
+
+// Fixtures lists the embedded example projects.
+func Fixtures() []string {
+	entries, err := fixtures.ReadDir("fixtures")
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Fixture copies the named embedded project into a temporary directory and
+// returns its root. The directory is removed when the test ends.
+func Fixture(t testing.TB, name string) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := CopyFixture(name, root); err != nil {
+		t.Fatalf("sdktest: %v", err)
+	}
+	return root
+}
+
+// CopyFixture writes the named embedded project under dir.
+func CopyFixture(name, dir string) error {
+	base := path.Join("fixtures", name)
+	if _, err := fs.Stat(fixtures, base); err != nil {
+		return fmt.Errorf("unknown fixture %q (have %s)", name, strings.Join(Fixtures(), ", "))
+	}
+	return fs.WalkDir(fixtures, base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, base), "/")
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		data, err := fixtures.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0o600)
+	})
+}
+
+const localSettingsPath = ".claude/settings.local.json"
+
+// Project is a scaffolded project tree.
+type Project struct {
+	// Root is the project root, suitable for api.Options.ProjectRoot.
+	Root string
+}
+
+// ProjectOption adds content to a project built by NewProject.
+type ProjectOption func(*projectSpec)
+
+type projectSpec struct {
+	fixture    string
+	files      map[string]string
+	mcpServers map[string]config.MCPServerConfig
+	err        error
+}
+
+func (s *projectSpec) add(rel, content string) {
+	s.files[filepath.ToSlash(rel)] = content
+}
+
+// FromFixture starts the project from an embedded fixture; later options
+// overwrite its files.
+func FromFixture(name string) ProjectOption {
+	return func(s *projectSpec) { s.fixture = name }
+}
+
+// WithSettings writes .claude/settings.json. settings may be raw JSON (a
+// string or []byte) or any value that marshals to it, such as a
+// config.Settings.
+func WithSettings(settings any) ProjectOption {
+	return func(s *projectSpec) { s.addSettings(".claude/settings.json", settings) }
+}
+
+// WithLocalSettings writes .claude/settings.local.json, the layer above
+// settings.json.
+func WithLocalSettings(settings any) ProjectOption {
+	return func(s *projectSpec) { s.addSettings(localSettingsPath, settings) }
+}
+
+// WithMemory writes CLAUDE.md at the project root.
+func WithMemory(content string) ProjectOption {
+	return func(s *projectSpec) { s.add("CLAUDE.md", content) }
+}
+
+// WithSkill writes .claude/skills/<name>/SKILL.md.
+func WithSkill(name, description, body string) ProjectOption {
+	return func(s *projectSpec) {
+		s.add(path.Join(".claude/skills", name, "SKILL.md"), frontmatter([][2]string{{"name", name}, {"description", description}}, body))
+	}
+}
+
+// WithCommand writes the slash command .claude/commands/<name>.md; $ARGUMENTS
+// in body is replaced by the command arguments.
+func WithCommand(name, description, body string) ProjectOption {
+	return func(s *projectSpec) {
+		s.add(path.Join(".claude/commands", name+".md"), frontmatter([][2]string{{"description", description}}, body))
+	}
+}
+
+// WithAgent writes the subagent .claude/agents/<name>.md. tools is the
+// comma-separated tool allowlist; empty inherits every tool.
+func WithAgent(name, description, tools, prompt string) ProjectOption {
+	return func(s *projectSpec) {
+		fields := [][2]string{{"name", name}, {"description", description}}
+		if tools != "" {
+			fields = append(fields, [2]string{"tools", tools})
+		}
+		s.add(path.Join(".claude/agents", name+".md"), frontmatter(fields, prompt))
+	}
+}
+
+// WithRule writes .claude/rules/<name>.md.
+func WithRule(name, content string) ProjectOption {
+	return func(s *projectSpec) { s.add(path.Join(".claude/rules", name+".md"), content) }
+}
+
+// WithFile writes an arbitrary file; rel is slash-separated and relative to
+// the project root.
+func WithFile(rel, content string) ProjectOption {
+	return func(s *projectSpec) { s.add(rel, content) }
+}
+
+// NewProject scaffolds a project in a temporary directory removed when the
+// test ends. Without options it is an empty project with a .claude directory.
+func NewProject(t testing.TB, opts ...ProjectOption) *Project {
+	t.Helper()
+	spec := &projectSpec{files: map[string]string{}}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(spec)
+		}
+	}
+	if len(spec.mcpServers) > 0 {
+		if _, ok := spec.files[localSettingsPath]; ok {
+			t.Fatalf("sdktest: WithMCPServer cannot be combined with WithLocalSettings")
+		}
+		spec.addSettings(localSettingsPath, config.Settings{MCP: &config.MCPConfig{Servers: spec.mcpServers}})
+	}
+	if spec.err != nil {
+		t.Fatalf("sdktest: %v", spec.err)
+	}
+	root := t.TempDir()
+	if spec.fixture != "" {
+		if err := CopyFixture(spec.fixture, root); err != nil {
+			t.Fatalf("sdktest: %v", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, ".claude"), 0o755); err != nil {
+		t.Fatalf("sdktest: %v", err)
+	}
+	for rel, content := range spec.files {
+		target := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			t.Fatalf("sdktest: %v", err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0o600); err != nil {
+			t.Fatalf("sdktest: %v", err)
+		}
+	}
+	return &Project{Root: root}
+}
+
+// Path joins rel (slash-separated) onto the project root.
+func (p *Project) Path(rel string) string {
+	return filepath.Join(p.Root, filepath.FromSlash(rel))
+}
+
+func (s *projectSpec) addSettings(rel string, settings any) {
+	switch v := settings.(type) {
+	case string:
+		s.add(rel, v)
+		return
+	case []byte:
+		s.add(rel, string(v))
+		return
+	case json.RawMessage:
+		s.add(rel, string(v))
+		return
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		s.err = fmt.Errorf("marshal %s: %w", rel, err)
+		return
+	}
+	s.add(rel, string(data))
+}
+
+func frontmatter(fields [][2]string, body string) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	for _, f := range fields {
+		fmt.Fprintf(&b, "%s: %s\n", f[0], strconv.Quote(f[1]))
+	}
+	b.WriteString("---\n")
+	b.WriteString(body)
+	if !strings.HasSuffix(body, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
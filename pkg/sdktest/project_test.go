@@ -0,0 +1,81 @@
+package sdktest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+	"github.com/cexll/agentsdk-go/pkg/runtime/commands"
+	"github.com/cexll/agentsdk-go/pkg/runtime/skills"
+	"github.com/cexll/agentsdk-go/pkg/runtime/subagents"
+)
+
+func TestFixturesLoad(t *testing.T) {
+	if got := Fixtures(); len(got) != 2 || got[0] != FixtureFull || got[1] != FixtureMinimal {
+		t.Fatalf("unexpected fixtures %v", got)
+	}
+	root := Fixture(t, FixtureFull)
+
+	settings, err := (&config.SettingsLoader{ProjectRoot: root}).Load()
+	if err != nil {
+		t.Fatalf("settings: %v", err)
+	}
+	if settings.Env["FIXTURE"] != "full" || len(settings.Permissions.Deny) != 1 {
+		t.Fatalf("unexpected settings %+v", settings)
+	}
+	if regs, errs := skills.LoadFromFS(skills.LoaderOptions{ProjectRoot: root}); len(errs) > 0 || len(regs) != 1 || regs[0].Definition.Name != "summarize" {
+		t.Fatalf("skills %+v errs %v", regs, errs)
+	}
+	if regs, errs := commands.LoadFromFS(commands.LoaderOptions{ProjectRoot: root}); len(errs) > 0 || len(regs) != 1 || regs[0].Definition.Name != "review" {
+		t.Fatalf("commands %+v errs %v", regs, errs)
+	}
+	if regs, errs := subagents.LoadFromFS(subagents.LoaderOptions{ProjectRoot: root}); len(errs) > 0 || len(regs) != 1 || regs[0].Definition.Name != "explorer" {
+		t.Fatalf("subagents %+v errs %v", regs, errs)
+	}
+	if _, err := os.Stat(root + "/.claude/rules/style.md"); err != nil {
+		t.Fatalf("rule missing: %v", err)
+	}
+	if err := CopyFixture("nope", t.TempDir()); err == nil {
+		t.Fatal("expected unknown fixture error")
+	}
+}
+
+func TestNewProjectScaffolds(t *testing.T) {
+	p := NewProject(t,
+		FromFixture(FixtureMinimal),
+		WithSettings(config.Settings{Model: "fixture-model"}),
+		WithMemory("# Overridden"),
+		WithSkill("lint", "Run linters: golangci-lint and gofmt.", "Run them."),
+		WithCommand("ship", "Ship it.", "Ship $ARGUMENTS"),
+		WithAgent("reviewer", "Reviews diffs.", "Read, Grep", "Review carefully."),
+		WithRule("go", "Use gofmt."),
+		WithFile("src/app.txt", "hello"),
+	)
+	settings, err := (&config.SettingsLoader{ProjectRoot: p.Root}).Load()
+	if err != nil || settings.Model != "fixture-model" {
+		t.Fatalf("settings %+v err %v", settings, err)
+	}
+	if data, err := os.ReadFile(p.Path("CLAUDE.md")); err != nil || string(data) != "# Overridden" {
+		t.Fatalf("memory %q err %v", data, err)
+	}
+	if regs, errs := skills.LoadFromFS(skills.LoaderOptions{ProjectRoot: p.Root}); len(errs) > 0 || len(regs) != 1 || regs[0].Definition.Description != "Run linters: golangci-lint and gofmt." {
+		t.Fatalf("skills %+v errs %v", regs, errs)
+	}
+	if regs, errs := commands.LoadFromFS(commands.LoaderOptions{ProjectRoot: p.Root}); len(errs) > 0 || len(regs) != 1 {
+		t.Fatalf("commands %+v errs %v", regs, errs)
+	}
+	regs, errs := subagents.LoadFromFS(subagents.LoaderOptions{ProjectRoot: p.Root})
+	if len(errs) > 0 || len(regs) != 1 || len(regs[0].Definition.BaseContext.ToolWhitelist) != 2 {
+		t.Fatalf("subagents %+v errs %v", regs, errs)
+	}
+	for _, rel := range []string{".claude/rules/go.md", "src/app.txt"} {
+		if _, err := os.Stat(p.Path(rel)); err != nil {
+			t.Fatalf("%s: %v", rel, err)
+		}
+	}
+
+	empty := NewProject(t)
+	if info, err := os.Stat(empty.Path(".claude")); err != nil || !info.IsDir() {
+		t.Fatalf("empty project should have .claude: %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -205,6 +206,87 @@ func (q *ApprovalQueue) IsWhitelisted(sessionID string) bool {
 	return true
 }
 
+// WhitelistExpiry reports when the active whitelist of sessionID ends.
+func (q *ApprovalQueue) WhitelistExpiry(sessionID string) (time.Time, bool) {
+	if q == nil {
+		return time.Time{}, false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	expiry, ok := q.whitelist[sessionID]
+	if !ok || !expiry.After(q.clock()) {
+		return time.Time{}, false
+	}
+	return expiry, true
+}
+
+// Whitelist lets sessionID bypass manual review until expiry, e.g. to carry
+// a grant over to a session that continues another one. An expiry in the
+// past is ignored.
+func (q *ApprovalQueue) Whitelist(sessionID string, expiry time.Time) error {
+	if q == nil {
+		return nil
+	}
+	if sessionID == "" {
+		return fmt.Errorf("security: session id required")
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ensureCondLocked()
+	if !expiry.After(q.clock()) {
+		return nil
+	}
+	q.whitelist[sessionID] = expiry
+	return q.persistLocked()
+}
+
+// SessionRecords returns the records of sessionID, oldest first.
+func (q *ApprovalQueue) SessionRecords(sessionID string) []*ApprovalRecord {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []*ApprovalRecord
+	for _, rec := range q.records {
+		if rec.SessionID == sessionID {
+			out = append(out, cloneRecord(rec))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RequestedAt.Before(out[j].RequestedAt) })
+	return out
+}
+
+// PurgeSession removes every record and whitelist entry for sessionID and
+// returns the number of records deleted. Pending waiters observe a
+// "not found" error.
+func (q *ApprovalQueue) PurgeSession(sessionID string) (int, error) {
+	if q == nil || sessionID == "" {
+		return 0, nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ensureCondLocked()
+
+	removed := 0
+	for id, rec := range q.records {
+		if rec.SessionID == sessionID {
+			delete(q.records, id)
+			removed++
+		}
+	}
+	_, whitelisted := q.whitelist[sessionID]
+	delete(q.whitelist, sessionID)
+	if removed == 0 && !whitelisted {
+		return 0, nil
+	}
+	q.cond.Broadcast()
+	if err := q.persistLocked(); err != nil {
+		return removed, err
+	}
+	return removed, nil
+}
+
 // Wait blocks until the approval is resolved or the context is cancelled.
 func (q *ApprovalQueue) Wait(ctx context.Context, id string) (*ApprovalRecord, error) {
 	if q == nil {
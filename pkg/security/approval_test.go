@@ -237,6 +237,35 @@ func TestApprovalQueueWhitelistExpiry(t *testing.T) {
 	}
 }
 
+func TestApprovalQueueWhitelistGrant(t *testing.T) {
+	q, clock := newTestQueue(t)
+	if err := q.Whitelist("", clock.now.Add(time.Hour)); err == nil {
+		t.Fatalf("expected session id error")
+	}
+	if err := q.Whitelist("sess", clock.now.Add(-time.Second)); err != nil || q.IsWhitelisted("sess") {
+		t.Fatalf("past expiry should be ignored: %v", err)
+	}
+	until := clock.now.Add(time.Hour)
+	if err := q.Whitelist("sess", until); err != nil {
+		t.Fatalf("whitelist: %v", err)
+	}
+	if got, ok := q.WhitelistExpiry("sess"); !ok || !got.Equal(until) {
+		t.Fatalf("expiry = %v, %v", got, ok)
+	}
+	first, _ := q.Request("sess", "bash ls", nil)
+	clock.Advance(time.Second)
+	second, _ := q.Request("sess", "bash pwd", nil)
+	_, _ = q.Request("other", "bash id", nil)
+	records := q.SessionRecords("sess")
+	if len(records) != 2 || records[0].ID != first.ID || records[1].ID != second.ID {
+		t.Fatalf("unexpected records %+v", records)
+	}
+	clock.Advance(2 * time.Hour)
+	if _, ok := q.WhitelistExpiry("sess"); ok {
+		t.Fatalf("expected expired whitelist")
+	}
+}
+
 func TestApprovalQueueLoadExistingState(t *testing.T) {
 	dir := t.TempDir()
 	store := filepath.Join(dir, "state", "approvals.json")
@@ -431,3 +460,41 @@ func TestNewApprovalIDProducesUniqueValues(t *testing.T) {
 		t.Fatalf("expected unique ids, got %s", first)
 	}
 }
+
+func TestApprovalQueuePurgeSession(t *testing.T) {
+	q, _ := newTestQueue(t)
+	rec, err := q.Request("purge-me", "rm file", nil)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if _, err := q.Approve(rec.ID, "admin", time.Hour); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if _, err := q.Request("keep", "ls", nil); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+
+	removed, err := q.PurgeSession("purge-me")
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 removed, got %d err=%v", removed, err)
+	}
+	if q.IsWhitelisted("purge-me") {
+		t.Fatal("expected whitelist entry removed")
+	}
+
+	reloaded, err := NewApprovalQueue(q.storePath)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	for _, r := range reloaded.records {
+		if r.SessionID == "purge-me" {
+			t.Fatalf("purged record persisted: %+v", r)
+		}
+	}
+	if len(reloaded.records) != 1 {
+		t.Fatalf("expected other session kept, got %d records", len(reloaded.records))
+	}
+	if n, err := q.PurgeSession("missing"); n != 0 || err != nil {
+		t.Fatalf("unexpected purge of missing session: %d %v", n, err)
+	}
+}
@@ -0,0 +1,145 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// codeOwnersLocations are searched in order, matching GitHub and GitLab.
+var codeOwnersLocations = []string{
+	filepath.Join(".github", "CODEOWNERS"),
+	"CODEOWNERS",
+	filepath.Join("docs", "CODEOWNERS"),
+	filepath.Join(".gitlab", "CODEOWNERS"),
+}
+
+// CodeOwners maps repository paths to their owning teams or users.
+type CodeOwners struct {
+	rules []codeOwnersRule
+}
+
+type codeOwnersRule struct {
+	pattern string
+	match   func(string) bool
+	owners  []string
+}
+
+// ParseCodeOwners parses a CODEOWNERS file. Each non-comment line holds a
+// path pattern followed by owners; when several patterns match a path the
+// last one wins. GitLab section headers ([Section]) are skipped.
+func ParseCodeOwners(data []byte) (*CodeOwners, error) {
+	co := &CodeOwners{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		fields := strings.Fields(line)
+		match, err := compileOwnerPattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("codeowners line %d: %w", lineNo, err)
+		}
+		co.rules = append(co.rules, codeOwnersRule{pattern: fields[0], match: match, owners: fields[1:]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return co, nil
+}
+
+// LoadCodeOwners reads the first CODEOWNERS file found under root. A
+// repository without one yields nil and no error.
+func LoadCodeOwners(root string) (*CodeOwners, error) {
+	for _, loc := range codeOwnersLocations {
+		data, err := os.ReadFile(filepath.Join(root, loc))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ParseCodeOwners(data)
+	}
+	return nil, nil
+}
+
+// Owners returns the owners of rel, a slash-separated path relative to the
+// repository root. A matching rule without owners clears ownership.
+func (c *CodeOwners) Owners(rel string) []string {
+	if c == nil {
+		return nil
+	}
+	rel = strings.TrimPrefix(filepath.ToSlash(rel), "/")
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if c.rules[i].match(rel) {
+			return append([]string(nil), c.rules[i].owners...)
+		}
+	}
+	return nil
+}
+
+// compileOwnerPattern compiles a gitignore-style pattern as used by
+// CODEOWNERS: a leading or inner slash anchors the pattern to the root,
+// otherwise it matches at any depth. A matched directory covers everything
+// below it, except that "dir/*" covers direct children only. "*" stays
+// within a segment and "**" spans them.
+func compileOwnerPattern(pattern string) (func(string) bool, error) {
+	p := strings.TrimSpace(pattern)
+	if p == "" {
+		return nil, errors.New("empty path pattern")
+	}
+	anchored := strings.Contains(strings.TrimSuffix(p, "/"), "/")
+	childrenOnly := strings.HasSuffix(p, "/*")
+	p = strings.TrimPrefix(strings.TrimSuffix(p, "/"), "/")
+	if p == "*" || p == "**" {
+		return func(string) bool { return true }, nil
+	}
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				i++
+				if i+1 < len(p) && p[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if !childrenOnly {
+		b.WriteString("(?:/.*)?")
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
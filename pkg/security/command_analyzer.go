@@ -0,0 +1,470 @@
+package security
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CommandRisk names a class of dangerous shell construct found by
+// AnalyzeCommand.
+type CommandRisk string
+
+const (
+	// RiskPipeToShell is downloaded content executed by an interpreter:
+	// curl ... | sh, bash <(curl ...), sh -c "$(wget -O- ...)".
+	RiskPipeToShell CommandRisk = "pipe_to_shell"
+	// RiskRecursiveDeleteRoot is a recursive rm of /, the home directory or
+	// a top-level system directory, or any rm --no-preserve-root.
+	RiskRecursiveDeleteRoot CommandRisk = "recursive_delete_root"
+	// RiskPrivilegeEscalation is sudo, su, doas, pkexec or runuser.
+	RiskPrivilegeEscalation CommandRisk = "privilege_escalation"
+	// RiskForkBomb is a function that pipes to or backgrounds itself.
+	RiskForkBomb CommandRisk = "fork_bomb"
+)
+
+// defaultRiskActions is applied to risks not configured in
+// permissions.commandAnalyzer.
+var defaultRiskActions = map[CommandRisk]PermissionAction{
+	RiskPipeToShell:         PermissionAsk,
+	RiskRecursiveDeleteRoot: PermissionDeny,
+	RiskPrivilegeEscalation: PermissionAsk,
+	RiskForkBomb:            PermissionDeny,
+}
+
+// CommandFinding is one dangerous construct in a command.
+type CommandFinding struct {
+	Risk CommandRisk
+	// Command is the simple command (or function definition) that triggered
+	// the finding, as written.
+	Command string
+	Detail  string
+}
+
+// CommandAnalysis is the result of AnalyzeCommand.
+type CommandAnalysis struct {
+	// Commands lists every simple command in source order, including those
+	// in substitutions, subshells, function bodies and bash -c strings.
+	Commands []string
+	Findings []CommandFinding
+	// Err is set when the command could not be parsed; Commands and
+	// Findings are then empty.
+	Err error
+}
+
+// AnalyzeCommand parses a shell command and reports dangerous constructs.
+// Unlike substring matching it sees through quoting, wrappers such as env,
+// nohup and xargs, command substitutions and nested bash -c strings.
+func AnalyzeCommand(command string) CommandAnalysis {
+	script, err := parseShell(command, 0)
+	if err != nil {
+		return CommandAnalysis{Err: err}
+	}
+	a := &commandWalker{}
+	a.script(script, 0)
+	return CommandAnalysis{Commands: a.commands, Findings: a.findings}
+}
+
+var (
+	downloaderCommands  = map[string]bool{"curl": true, "wget": true, "fetch": true}
+	shellCommands       = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true, "csh": true, "tcsh": true}
+	interpreterCommands = map[string]bool{
+		"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true, "csh": true, "tcsh": true,
+		"python": true, "python2": true, "python3": true, "perl": true, "ruby": true, "node": true, "php": true,
+	}
+	// evalCommands run their operands as code, e.g. eval "$(curl ...)".
+	evalCommands      = map[string]bool{"eval": true, "source": true, ".": true}
+	privilegeCommands = map[string]bool{"sudo": true, "su": true, "doas": true, "pkexec": true, "runuser": true}
+	// systemDirs are top-level directories whose recursive removal is
+	// treated like removing /.
+	systemDirs = map[string]bool{
+		"/bin": true, "/boot": true, "/dev": true, "/etc": true, "/home": true, "/lib": true, "/lib32": true,
+		"/lib64": true, "/opt": true, "/proc": true, "/root": true, "/sbin": true, "/srv": true, "/sys": true,
+		"/usr": true, "/var": true, "/Applications": true, "/Library": true, "/System": true, "/Users": true,
+		"/private": true,
+	}
+)
+
+type commandWalker struct {
+	commands []string
+	findings []CommandFinding
+}
+
+func (w *commandWalker) add(risk CommandRisk, command, detail string) {
+	w.findings = append(w.findings, CommandFinding{Risk: risk, Command: command, Detail: detail})
+}
+
+func (w *commandWalker) script(s *shellScript, depth int) {
+	if s == nil {
+		return
+	}
+	for _, pl := range s.pipelines {
+		w.pipeline(pl, depth)
+	}
+}
+
+func (w *commandWalker) pipeline(pl *shellPipeline, depth int) {
+	downloader := ""
+	for _, cmd := range pl.commands {
+		w.command(cmd, depth)
+		name := effectiveCommand(cmd.args)
+		switch {
+		case downloaderCommands[name]:
+			if downloader == "" {
+				downloader = cmd.raw
+			}
+		case downloader != "" && interpreterCommands[name]:
+			w.add(RiskPipeToShell, cmd.raw, fmt.Sprintf("output of %q is executed by %s", downloader, name))
+		case interpreterCommands[name] || evalCommands[name]:
+			for _, sub := range cmd.subs {
+				if scriptDownloads(sub) {
+					w.add(RiskPipeToShell, cmd.raw, fmt.Sprintf("downloaded content is executed by %s", name))
+					break
+				}
+			}
+		}
+	}
+}
+
+func (w *commandWalker) command(cmd *shellCommand, depth int) {
+	if cmd == nil {
+		return
+	}
+	for _, sub := range cmd.subs {
+		w.script(sub, depth+1)
+	}
+	if cmd.funcName != "" {
+		if selfSpawning(cmd.body, cmd.funcName, 0) {
+			w.add(RiskForkBomb, cmd.funcName+"()", fmt.Sprintf("function %s spawns copies of itself", cmd.funcName))
+		}
+		w.script(cmd.body, depth+1)
+		return
+	}
+	if cmd.body != nil {
+		w.script(cmd.body, depth+1)
+		return
+	}
+	if len(cmd.args) == 0 {
+		return
+	}
+	w.commands = append(w.commands, cmd.raw)
+
+	args := cmd.args
+	for len(args) > 0 {
+		name := commandBase(args[0])
+		if privilegeCommands[name] {
+			w.add(RiskPrivilegeEscalation, cmd.raw, fmt.Sprintf("%s runs commands with elevated privileges", name))
+		}
+		if name == "rm" {
+			if detail := rootDelete(args[1:]); detail != "" {
+				w.add(RiskRecursiveDeleteRoot, cmd.raw, detail)
+			}
+		}
+		if script := inlineScript(name, args[1:]); script != "" && depth < maxShellDepth {
+			if inner, err := parseShell(script, depth+1); err == nil {
+				w.script(inner, depth+1)
+			}
+		}
+		next := unwrapCommand(args)
+		if len(next) == len(args) {
+			break
+		}
+		args = next
+	}
+}
+
+// effectiveCommand strips wrapper commands (env, sudo, nohup, xargs, ...)
+// and returns the base name of the command that ultimately runs.
+func effectiveCommand(args []string) string {
+	for len(args) > 0 {
+		next := unwrapCommand(args)
+		if len(next) == len(args) {
+			return commandBase(args[0])
+		}
+		args = next
+	}
+	return ""
+}
+
+func commandBase(word string) string {
+	word = strings.TrimSpace(word)
+	if word == "" {
+		return ""
+	}
+	return filepath.Base(word)
+}
+
+// wrapperValueFlags lists, per wrapper, the options that take a separate
+// value, so that value is not mistaken for the wrapped command.
+var wrapperValueFlags = map[string]map[string]bool{
+	"sudo":    {"-u": true, "-g": true, "-C": true, "-D": true, "-h": true, "-p": true, "-r": true, "-t": true, "-U": true},
+	"doas":    {"-u": true, "-C": true},
+	"runuser": {"-u": true, "-g": true, "-G": true},
+	"env":     {"-u": true, "-C": true, "-S": true},
+	"nice":    {"-n": true},
+	"timeout": {"-s": true, "-k": true},
+	"xargs":   {"-I": true, "-n": true, "-P": true, "-L": true, "-d": true, "-E": true, "-s": true, "-a": true},
+	"stdbuf":  {"-i": true, "-o": true, "-e": true},
+}
+
+var wrapperCommands = map[string]bool{
+	"sudo": true, "doas": true, "runuser": true, "env": true, "nice": true, "nohup": true, "timeout": true,
+	"xargs": true, "stdbuf": true, "command": true, "builtin": true, "exec": true, "time": true, "pkexec": true,
+}
+
+// unwrapCommand returns the wrapped command's words when args[0] is a
+// wrapper, or args unchanged.
+func unwrapCommand(args []string) []string {
+	name := commandBase(args[0])
+	if !wrapperCommands[name] {
+		return args
+	}
+	rest := args[1:]
+	for len(rest) > 0 {
+		word := rest[0]
+		switch {
+		case word == "--":
+			rest = rest[1:]
+			return rest
+		case strings.HasPrefix(word, "-"):
+			rest = rest[1:]
+			if wrapperValueFlags[name][word] && len(rest) > 0 {
+				rest = rest[1:]
+			}
+		case name == "env" && isAssignment(word):
+			rest = rest[1:]
+		case name == "timeout" && len(word) > 0 && word[0] >= '0' && word[0] <= '9':
+			rest = rest[1:]
+			return rest
+		default:
+			return rest
+		}
+	}
+	return rest
+}
+
+// inlineScript returns the code string passed to sh -c, bash -c, su -c or
+// eval, if any.
+func inlineScript(name string, args []string) string {
+	switch {
+	case name == "eval":
+		return strings.Join(args, " ")
+	case shellCommands[name] || name == "su":
+		for i, arg := range args {
+			if arg == "-c" || (strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.HasSuffix(arg, "c")) {
+				if i+1 < len(args) {
+					return args[i+1]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// rootDelete describes why an rm invocation would remove /, the home
+// directory or a system directory, or returns "".
+func rootDelete(args []string) string {
+	recursive := false
+	var targets []string
+	options := true
+	for _, arg := range args {
+		switch {
+		case options && arg == "--":
+			options = false
+		case options && arg == "--no-preserve-root":
+			return "rm --no-preserve-root disables the safeguard against deleting /"
+		case options && (arg == "--recursive"):
+			recursive = true
+		case options && strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && len(arg) > 1:
+			if strings.ContainsAny(arg[1:], "rR") {
+				recursive = true
+			}
+		case options && strings.HasPrefix(arg, "--"):
+		default:
+			targets = append(targets, arg)
+		}
+	}
+	if !recursive {
+		return ""
+	}
+	for _, target := range targets {
+		if isRootTarget(target) {
+			return fmt.Sprintf("rm -r %s deletes a root, home or system directory", target)
+		}
+	}
+	return ""
+}
+
+func isRootTarget(arg string) bool {
+	t := strings.TrimSpace(arg)
+	for _, home := range []string{"${HOME}", "$HOME"} {
+		if strings.HasPrefix(t, home) {
+			t = "~" + strings.TrimPrefix(t, home)
+		}
+	}
+	if !strings.HasPrefix(t, "/") && !strings.HasPrefix(t, "~") {
+		return false
+	}
+	if strings.HasPrefix(t, "/") {
+		t = path.Clean(t)
+	}
+	for {
+		trimmed := strings.TrimSuffix(strings.TrimSuffix(t, "*"), "/")
+		if strings.HasSuffix(trimmed, "/.") {
+			trimmed = strings.TrimSuffix(trimmed, "/.")
+		}
+		if trimmed == t {
+			break
+		}
+		t = trimmed
+	}
+	return t == "" || t == "~" || systemDirs[t]
+}
+
+// scriptDownloads reports whether s runs a downloader anywhere.
+func scriptDownloads(s *shellScript) bool {
+	if s == nil {
+		return false
+	}
+	for _, pl := range s.pipelines {
+		for _, cmd := range pl.commands {
+			if downloaderCommands[effectiveCommand(cmd.args)] {
+				return true
+			}
+			if scriptDownloads(cmd.body) {
+				return true
+			}
+			for _, sub := range cmd.subs {
+				if scriptDownloads(sub) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// selfSpawning reports whether a function body calls name as part of a
+// multi-command pipeline or in the background, the shape of a fork bomb.
+func selfSpawning(s *shellScript, name string, depth int) bool {
+	if s == nil || depth > maxShellDepth {
+		return false
+	}
+	for _, pl := range s.pipelines {
+		for _, cmd := range pl.commands {
+			if len(cmd.args) > 0 && cmd.args[0] == name && (len(pl.commands) > 1 || pl.background) {
+				return true
+			}
+			if selfSpawning(cmd.body, name, depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CommandAnalyzer maps AnalyzeCommand findings and per-command permission
+// rules onto Bash permission decisions. A nil analyzer uses the default
+// actions: fork bombs and root deletion are denied, pipe-to-shell and
+// privilege escalation ask.
+type CommandAnalyzer struct {
+	actions map[CommandRisk]PermissionAction
+}
+
+// NewCommandAnalyzer overrides the default action per risk. Keys are
+// CommandRisk names, values "allow", "ask" or "deny"; "allow" disables the
+// check.
+func NewCommandAnalyzer(actions map[string]string) (*CommandAnalyzer, error) {
+	a := &CommandAnalyzer{actions: make(map[CommandRisk]PermissionAction, len(defaultRiskActions))}
+	for risk, action := range defaultRiskActions {
+		a.actions[risk] = action
+	}
+	keys := make([]string, 0, len(actions))
+	for key := range actions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		risk := CommandRisk(strings.TrimSpace(key))
+		if _, ok := defaultRiskActions[risk]; !ok {
+			return nil, fmt.Errorf("security: command analyzer: unknown risk %q", key)
+		}
+		action := PermissionAction(strings.ToLower(strings.TrimSpace(actions[key])))
+		switch action {
+		case PermissionAllow, PermissionAsk, PermissionDeny:
+		default:
+			return nil, fmt.Errorf("security: command analyzer: %s: action must be allow, ask or deny, got %q", key, actions[key])
+		}
+		a.actions[risk] = action
+	}
+	return a, nil
+}
+
+func (a *CommandAnalyzer) action(risk CommandRisk) PermissionAction {
+	if a != nil {
+		if action, ok := a.actions[risk]; ok {
+			return action
+		}
+	}
+	return defaultRiskActions[risk]
+}
+
+// actionSeverity orders decisions from least to most restrictive.
+func actionSeverity(action PermissionAction) int {
+	switch action {
+	case PermissionAllow:
+		return 0
+	case PermissionAsk:
+		return 2
+	case PermissionDeny:
+		return 3
+	}
+	return 1
+}
+
+// apply tightens a Bash decision. Every simple command in a compound
+// command is matched against the rules on its own, so Bash(git:*) no
+// longer approves "git status && rm -rf build" and a deny rule for rm also
+// catches "cd x && rm y"; then each finding escalates to its configured
+// action. A command the parser cannot read asks, since neither its
+// sub-commands nor its findings are known.
+func (a *CommandAnalyzer) apply(matcher *PermissionMatcher, decision PermissionDecision, params map[string]any) PermissionDecision {
+	if !strings.EqualFold(strings.TrimSpace(decision.Tool), "bash") {
+		return decision
+	}
+	command := firstString(params, "command")
+	if strings.TrimSpace(command) == "" {
+		return decision
+	}
+	analysis := AnalyzeCommand(command)
+	if analysis.Err != nil && actionSeverity(PermissionAsk) > actionSeverity(decision.Action) {
+		decision.Action = PermissionAsk
+		decision.Rule = "analyzer:unparsed"
+		decision.Reason = fmt.Sprintf("command could not be analyzed: %v", analysis.Err)
+	}
+	if matcher != nil && len(analysis.Commands) > 1 {
+		for _, segment := range analysis.Commands {
+			sub := matcher.Match(decision.Tool, map[string]any{"command": segment})
+			if actionSeverity(sub.Action) > actionSeverity(decision.Action) {
+				decision.Action, decision.Rule = sub.Action, sub.Rule
+				decision.Reason = fmt.Sprintf("sub-command %q", segment)
+				if sub.Rule != "" {
+					decision.Reason += " matched " + sub.Rule
+				} else {
+					decision.Reason += " matched no allow rule"
+				}
+			}
+		}
+	}
+	for _, finding := range analysis.Findings {
+		action := a.action(finding.Risk)
+		if actionSeverity(action) > actionSeverity(decision.Action) {
+			decision.Action = action
+			decision.Rule = "analyzer:" + string(finding.Risk)
+			decision.Reason = finding.Detail
+		}
+	}
+	return decision
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -28,6 +29,61 @@ type PermissionDecision struct {
 	Rule   string
 	Tool   string
 	Target string
+	// Protected is the protected-path pattern covering Target, if any.
+	Protected string
+	// Owners lists the CODEOWNERS owners of Target for file edits.
+	Owners []string
+	// Reason explains a policy decision; see Sandbox.CheckToolPermission.
+	Reason string
+	// UpdatedInput, set by a resolver that allows the call, replaces the
+	// tool parameters.
+	UpdatedInput map[string]any
+}
+
+// SuggestRule returns a permission rule that would match the decision's
+// call, for hosts offering "always allow". Bash commands match exactly,
+// except that the subcommand of a tool such as git or go is kept as a
+// prefix (Bash(git:status*)); shells, interpreters and other programs are
+// never generalised, since their arguments decide what runs. Other targets
+// match exactly.
+func SuggestRule(decision PermissionDecision) string {
+	tool := strings.TrimSpace(decision.Tool)
+	if tool == "" {
+		return ""
+	}
+	target := strings.TrimSpace(decision.Target)
+	if strings.EqualFold(tool, "bash") {
+		return suggestBashRule(tool, target)
+	}
+	if target == "" || strings.ContainsAny(target, "*?()") {
+		return tool
+	}
+	return fmt.Sprintf("%s(%s)", tool, target)
+}
+
+// subcommandTools are programs whose first argument selects a subcommand,
+// and execSubcommands the subcommands that run arbitrary code and so are
+// never generalised.
+var (
+	subcommandTools = map[string]bool{"git": true, "go": true, "cargo": true, "npm": true, "pnpm": true, "yarn": true, "docker": true, "kubectl": true, "gh": true}
+	execSubcommands = map[string]bool{"run": true, "exec": true, "x": true, "dlx": true, "eval": true, "shell": true, "sh": true, "generate": true, "-c": true}
+)
+
+func suggestBashRule(tool, target string) string {
+	name, args, ok := strings.Cut(target, ":")
+	if !ok || name == "" {
+		return ""
+	}
+	sub, _, _ := strings.Cut(args, " ")
+	if subcommandTools[commandBase(name)] && sub != "" && !strings.HasPrefix(sub, "-") && !execSubcommands[sub] && !strings.ContainsAny(sub, "*?$`;&|<>") {
+		return fmt.Sprintf("%s(%s:%s*)", tool, name, sub)
+	}
+	if strings.ContainsAny(target, "*?") {
+		// Glob characters in the command would widen the rule; match it
+		// literally instead.
+		return fmt.Sprintf("%s(regex:^%s$)", tool, regexp.QuoteMeta(target))
+	}
+	return fmt.Sprintf("%s(%s)", tool, target)
 }
 
 // PermissionAudit records executed decisions for later inspection.
@@ -36,6 +92,9 @@ type PermissionAudit struct {
 	Target    string
 	Rule      string
 	Action    PermissionAction
+	Protected string
+	Owners    []string
+	Reason    string
 	Timestamp time.Time
 }
 
@@ -51,6 +110,11 @@ type permissionRule struct {
 	tool      string
 	toolMatch func(string) bool
 	match     func(string) bool
+	// targeted marks Tool(pattern) rules.
+	targeted bool
+	// remembered marks rules added with withRule, i.e. approvals given
+	// during this session.
+	remembered bool
 }
 
 // NewPermissionMatcher builds a matcher from the provided permissions config.
@@ -69,7 +133,7 @@ func NewPermissionMatcher(cfg *config.PermissionsConfig) (*PermissionMatcher, er
 			}
 			compiled = append(compiled, r)
 		}
-		sort.SliceStable(compiled, func(i, j int) bool { return compiled[i].raw < compiled[j].raw })
+		sortRules(compiled)
 		return compiled, nil
 	}
 
@@ -89,7 +153,11 @@ func NewPermissionMatcher(cfg *config.PermissionsConfig) (*PermissionMatcher, er
 	return &PermissionMatcher{allow: allow, ask: ask, deny: deny}, nil
 }
 
-// Match resolves the decision for a tool invocation. Priority: deny > ask > allow.
+// Match resolves the decision for a tool invocation. Priority: deny > ask >
+// allow, except that an ask rule naming only a tool ("Bash") yields to a
+// remembered allow rule with a target pattern for it ("Bash(git:status*)"),
+// so an approval given in this session stops the prompt. Configured allow
+// rules never override an ask rule.
 func (m *PermissionMatcher) Match(toolName string, params map[string]any) PermissionDecision {
 	if m == nil {
 		return PermissionDecision{Action: PermissionAllow, Tool: toolName}
@@ -97,20 +165,28 @@ func (m *PermissionMatcher) Match(toolName string, params map[string]any) Permis
 
 	tool := strings.TrimSpace(toolName)
 	target := deriveTarget(tool, params)
+	decide := func(rule *permissionRule, action PermissionAction) PermissionDecision {
+		return PermissionDecision{Action: action, Rule: rule.raw, Tool: tool, Target: target}
+	}
 
-	if decision, ok := m.matchRules(tool, target, m.deny, PermissionDeny); ok {
-		return decision
+	if rule := matchRule(tool, target, m.deny); rule != nil {
+		return decide(rule, PermissionDeny)
 	}
-	if decision, ok := m.matchRules(tool, target, m.ask, PermissionAsk); ok {
-		return decision
+	if rule := matchRule(tool, target, m.ask); rule != nil {
+		if !rule.targeted {
+			if allow := matchRememberedRule(tool, target, m.allow); allow != nil {
+				return decide(allow, PermissionAllow)
+			}
+		}
+		return decide(rule, PermissionAsk)
 	}
-	if decision, ok := m.matchRules(tool, target, m.allow, PermissionAllow); ok {
-		return decision
+	if rule := matchRule(tool, target, m.allow); rule != nil {
+		return decide(rule, PermissionAllow)
 	}
 	return PermissionDecision{Action: PermissionUnknown, Tool: tool, Target: target}
 }
 
-func (m *PermissionMatcher) matchRules(tool, target string, rules []*permissionRule, action PermissionAction) (PermissionDecision, bool) {
+func matchRule(tool, target string, rules []*permissionRule) *permissionRule {
 	for _, rule := range rules {
 		if rule.toolMatch != nil {
 			if !rule.toolMatch(tool) {
@@ -120,10 +196,58 @@ func (m *PermissionMatcher) matchRules(tool, target string, rules []*permissionR
 			continue
 		}
 		if rule.match(target) {
-			return PermissionDecision{Action: action, Rule: rule.raw, Tool: tool, Target: target}, true
+			return rule
 		}
 	}
-	return PermissionDecision{}, false
+	return nil
+}
+
+// matchRememberedRule is matchRule restricted to remembered Tool(pattern)
+// rules, so the ask refinement in Match holds whatever other allow rules
+// match first.
+func matchRememberedRule(tool, target string, rules []*permissionRule) *permissionRule {
+	for _, rule := range rules {
+		if rule.remembered && rule.targeted && strings.EqualFold(rule.tool, tool) && rule.match(target) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// withRule returns a copy of m (which may be nil) with rule added to the
+// action's list as a remembered rule.
+func (m *PermissionMatcher) withRule(action PermissionAction, rule string) (*PermissionMatcher, error) {
+	compiled, err := compilePermissionRule(rule)
+	if err != nil {
+		return nil, err
+	}
+	compiled.remembered = true
+	next := &PermissionMatcher{}
+	if m != nil {
+		next.allow = slices.Clone(m.allow)
+		next.ask = slices.Clone(m.ask)
+		next.deny = slices.Clone(m.deny)
+	}
+	switch action {
+	case PermissionAllow:
+		next.allow = append(next.allow, compiled)
+		sortRules(next.allow)
+	case PermissionAsk:
+		next.ask = append(next.ask, compiled)
+		sortRules(next.ask)
+	case PermissionDeny:
+		next.deny = append(next.deny, compiled)
+		sortRules(next.deny)
+	default:
+		return nil, fmt.Errorf("unsupported permission action %q", action)
+	}
+	return next, nil
+}
+
+// sortRules orders rules by their text so the first match does not depend on
+// the order rules were configured or added in.
+func sortRules(rules []*permissionRule) {
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].raw < rules[j].raw })
 }
 
 func compilePermissionRule(rule string) (*permissionRule, error) {
@@ -175,6 +299,7 @@ func compilePermissionRule(rule string) (*permissionRule, error) {
 		tool:      tool,
 		toolMatch: func(name string) bool { return strings.EqualFold(tool, name) },
 		match:     matcher,
+		targeted:  true,
 	}, nil
 }
 
@@ -262,10 +387,21 @@ func deriveTarget(tool string, params map[string]any) string {
 		if p := firstString(params, "file_path", "path"); p != "" {
 			return filepath.Clean(p)
 		}
+	case "notebookedit":
+		if p := firstString(params, "notebook_path", "file_path", "path"); p != "" {
+			return filepath.Clean(p)
+		}
 	case "taskcreate", "taskget", "taskupdate", "tasklist":
 		if id := firstString(params, "task_id", "id"); id != "" {
 			return id
 		}
+	case "dependencyaudit":
+		// Lookups go to osv.dev and deps.dev, so rules like
+		// DependencyAudit(online) can gate network access.
+		if online, ok := params["online"]; ok && (online == true || strings.EqualFold(coerceToString(online), "true")) {
+			return "online"
+		}
+		return "offline"
 	}
 	if p := firstString(params, "path", "file", "target"); p != "" {
 		return filepath.Clean(p)
@@ -51,7 +51,10 @@ func TestDeriveTargetCoverage(t *testing.T) {
 		{name: "bash no args", tool: "bash", params: map[string]any{"command": "ls"}, want: "ls:"},
 		{name: "bash empty", tool: "bash", params: map[string]any{"command": "   "}, want: ""},
 		{name: "read path", tool: "Read", params: map[string]any{"file_path": tmp}, want: filepath.Clean(tmp)},
+		{name: "notebook path", tool: "NotebookEdit", params: map[string]any{"notebook_path": tmp, "cell_id": "a1"}, want: filepath.Clean(tmp)},
 		{name: "taskget prefers id", tool: "TaskGet", params: map[string]any{"task_id": "task-123", "path": "/tmp/ignored"}, want: "task-123"},
+		{name: "dependency audit online", tool: "DependencyAudit", params: map[string]any{"online": true, "workdir": "svc"}, want: "online"},
+		{name: "dependency audit offline", tool: "DependencyAudit", params: map[string]any{"workdir": "svc"}, want: "offline"},
 		{name: "generic target key", tool: "Custom", params: map[string]any{"target": "/foo/bar"}, want: filepath.Clean("/foo/bar")},
 		{name: "first string fallback", tool: "Other", params: map[string]any{"misc": []byte(" hi ")}, want: "hi"},
 	}
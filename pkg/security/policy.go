@@ -0,0 +1,374 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/config"
+)
+
+// ErrPolicyDenied is returned when a policy denies a sandbox access.
+var ErrPolicyDenied = errors.New("security: denied by policy")
+
+// Policy input kinds.
+const (
+	PolicyKindTool    = "tool"
+	PolicyKindPath    = "path"
+	PolicyKindNetwork = "network"
+)
+
+const (
+	defaultPolicyDecision = "agentsdk/authz"
+	defaultPolicyTimeout  = 5 * time.Second
+)
+
+// PolicyInput is the document a policy sees as input. Tool inputs carry the
+// decision of the settings rules in Rule and Action, so a policy can defer
+// to them or override them.
+type PolicyInput struct {
+	Kind      string           `json:"kind"`
+	Tool      string           `json:"tool,omitempty"`
+	Target    string           `json:"target,omitempty"`
+	Params    map[string]any   `json:"params,omitempty"`
+	Rule      string           `json:"rule,omitempty"`
+	Action    PermissionAction `json:"action,omitempty"`
+	Protected string           `json:"protected,omitempty"`
+	Owners    []string         `json:"owners,omitempty"`
+}
+
+// PolicyResult is the answer of a policy. An empty Action means the policy
+// left the decision to the settings rules.
+type PolicyResult struct {
+	Action PermissionAction `json:"action,omitempty"`
+	Reason string           `json:"reason,omitempty"`
+}
+
+// PolicyEngine evaluates policy inputs.
+type PolicyEngine interface {
+	Evaluate(ctx context.Context, input PolicyInput) (PolicyResult, error)
+}
+
+// OPAConfig configures an OPAEngine. Exactly one of URL and Files is set.
+type OPAConfig struct {
+	// URL is the base URL of an OPA server.
+	URL string
+	// Files are Rego files or bundle directories evaluated with Binary.
+	Files []string
+	// Binary is the opa executable; "opa" when empty.
+	Binary string
+	// Decision is the slash-separated path of the rule to query, such as
+	// "agentsdk/authz" for data.agentsdk.authz.
+	Decision   string
+	HTTPClient *http.Client
+}
+
+// OPAEngine evaluates Rego policies with Open Policy Agent, either through
+// an OPA server's data API or by running `opa eval` on local files. The
+// decision rule may produce a boolean (allow or deny), an action string, or
+// an object with "action" (or boolean "allow") and "reason".
+type OPAEngine struct {
+	cfg OPAConfig
+}
+
+// NewOPAEngine validates cfg and returns an engine.
+func NewOPAEngine(cfg OPAConfig) (*OPAEngine, error) {
+	cfg.URL = strings.TrimRight(strings.TrimSpace(cfg.URL), "/")
+	if (cfg.URL == "") == (len(cfg.Files) == 0) {
+		return nil, errors.New("security: opa engine needs either a server URL or policy files")
+	}
+	cfg.Decision = strings.Trim(strings.TrimSpace(cfg.Decision), "/")
+	if cfg.Decision == "" {
+		cfg.Decision = defaultPolicyDecision
+	}
+	if cfg.Binary == "" {
+		cfg.Binary = "opa"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	return &OPAEngine{cfg: cfg}, nil
+}
+
+// Evaluate queries the decision rule with input.
+func (e *OPAEngine) Evaluate(ctx context.Context, input PolicyInput) (PolicyResult, error) {
+	if e.cfg.URL != "" {
+		return e.evaluateServer(ctx, input)
+	}
+	return e.evaluateBinary(ctx, input)
+}
+
+func (e *OPAEngine) evaluateServer(ctx context.Context, input PolicyInput) (PolicyResult, error) {
+	body, err := json.Marshal(map[string]any{"input": input})
+	if err != nil {
+		return PolicyResult{}, fmt.Errorf("security: encode policy input: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.URL+"/v1/data/"+e.cfg.Decision, bytes.NewReader(body))
+	if err != nil {
+		return PolicyResult{}, fmt.Errorf("security: opa request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return PolicyResult{}, fmt.Errorf("security: opa request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return PolicyResult{}, fmt.Errorf("security: opa returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var out struct {
+		Result any `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return PolicyResult{}, fmt.Errorf("security: decode opa response: %w", err)
+	}
+	return decodePolicyValue(out.Result)
+}
+
+func (e *OPAEngine) evaluateBinary(ctx context.Context, input PolicyInput) (PolicyResult, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return PolicyResult{}, fmt.Errorf("security: encode policy input: %w", err)
+	}
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, file := range e.cfg.Files {
+		args = append(args, "--data", file)
+	}
+	args = append(args, "data."+strings.ReplaceAll(e.cfg.Decision, "/", "."))
+	cmd := exec.CommandContext(ctx, e.cfg.Binary, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil {
+		return PolicyResult{}, fmt.Errorf("security: opa eval: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var out struct {
+		Result []struct {
+			Expressions []struct {
+				Value any `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(stdout, &out); err != nil {
+		return PolicyResult{}, fmt.Errorf("security: decode opa eval output: %w", err)
+	}
+	if len(out.Result) == 0 || len(out.Result[0].Expressions) == 0 {
+		return PolicyResult{}, nil
+	}
+	return decodePolicyValue(out.Result[0].Expressions[0].Value)
+}
+
+// decodePolicyValue maps the value of the decision rule to a result. An
+// undefined rule (nil) leaves the decision open.
+func decodePolicyValue(v any) (PolicyResult, error) {
+	switch val := v.(type) {
+	case nil:
+		return PolicyResult{}, nil
+	case bool:
+		if val {
+			return PolicyResult{Action: PermissionAllow}, nil
+		}
+		return PolicyResult{Action: PermissionDeny}, nil
+	case string:
+		action, err := parsePolicyAction(val)
+		return PolicyResult{Action: action}, err
+	case map[string]any:
+		var res PolicyResult
+		res.Reason, _ = val["reason"].(string)
+		switch action := val["action"].(type) {
+		case string:
+			parsed, err := parsePolicyAction(action)
+			if err != nil {
+				return PolicyResult{}, err
+			}
+			res.Action = parsed
+		case nil:
+			if allow, ok := val["allow"].(bool); ok {
+				res.Action = PermissionDeny
+				if allow {
+					res.Action = PermissionAllow
+				}
+			}
+		default:
+			return PolicyResult{}, fmt.Errorf("security: policy action has type %T", action)
+		}
+		return res, nil
+	default:
+		return PolicyResult{}, fmt.Errorf("security: unsupported policy result of type %T", v)
+	}
+}
+
+func parsePolicyAction(s string) (PermissionAction, error) {
+	switch action := PermissionAction(strings.ToLower(strings.TrimSpace(s))); action {
+	case PermissionAllow, PermissionAsk, PermissionDeny:
+		return action, nil
+	case "":
+		return "", nil
+	default:
+		return "", fmt.Errorf("security: unknown policy action %q", s)
+	}
+}
+
+// PolicyDecisionLog is one line of the decision log. Tool parameters are
+// left out because they may hold file contents or secrets.
+type PolicyDecisionLog struct {
+	Time       time.Time    `json:"time"`
+	Decision   string       `json:"decision"`
+	Input      PolicyInput  `json:"input"`
+	Result     PolicyResult `json:"result"`
+	Error      string       `json:"error,omitempty"`
+	DurationMs int64        `json:"duration_ms"`
+}
+
+// policyGate applies a policy engine to the decisions of a Sandbox.
+type policyGate struct {
+	engine   PolicyEngine
+	decision string
+	timeout  time.Duration
+	onError  PermissionAction
+	logPath  string
+
+	logMu sync.Mutex
+}
+
+// newPolicyGate builds the gate described by cfg. Relative paths resolve
+// against root. A nil cfg yields a nil gate.
+func newPolicyGate(root string, cfg *config.PolicyConfig) (*policyGate, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	resolve := func(path string) string {
+		path = strings.TrimSpace(path)
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(root, path)
+	}
+	files := make([]string, 0, len(cfg.Rego))
+	for _, file := range cfg.Rego {
+		files = append(files, resolve(file))
+	}
+	engine, err := NewOPAEngine(OPAConfig{URL: cfg.URL, Files: files, Binary: cfg.Binary, Decision: cfg.Decision})
+	if err != nil {
+		return nil, err
+	}
+	onError, err := parsePolicyAction(cfg.OnError)
+	if err != nil {
+		return nil, err
+	}
+	if onError == "" {
+		onError = PermissionDeny
+	}
+	timeout := time.Duration(cfg.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultPolicyTimeout
+	}
+	return &policyGate{
+		engine:   engine,
+		decision: engine.cfg.Decision,
+		timeout:  timeout,
+		onError:  onError,
+		logPath:  resolve(cfg.DecisionLog),
+	}, nil
+}
+
+func (g *policyGate) evaluate(input PolicyInput) (PolicyResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+	start := time.Now()
+	result, err := g.engine.Evaluate(ctx, input)
+	g.log(input, result, err, time.Since(start))
+	return result, err
+}
+
+func (g *policyGate) log(input PolicyInput, result PolicyResult, evalErr error, took time.Duration) {
+	if g.logPath == "" {
+		return
+	}
+	input.Params = nil
+	entry := PolicyDecisionLog{Time: time.Now().UTC(), Decision: g.decision, Input: input, Result: result, DurationMs: took.Milliseconds()}
+	if evalErr != nil {
+		entry.Error = evalErr.Error()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	g.logMu.Lock()
+	defer g.logMu.Unlock()
+	f, err := os.OpenFile(g.logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	_, _ = f.Write(append(line, '\n'))
+	_ = f.Close()
+}
+
+// apply lets the policy decide a tool call. An undecided policy keeps the
+// settings decision; a failed evaluation yields the configured onError
+// action.
+func (g *policyGate) apply(decision PermissionDecision, params map[string]any) PermissionDecision {
+	if g == nil {
+		return decision
+	}
+	if decision.Target == "" {
+		decision.Target = deriveTarget(decision.Tool, params)
+	}
+	result, err := g.evaluate(PolicyInput{
+		Kind:      PolicyKindTool,
+		Tool:      decision.Tool,
+		Target:    decision.Target,
+		Params:    params,
+		Rule:      decision.Rule,
+		Action:    decision.Action,
+		Protected: decision.Protected,
+		Owners:    decision.Owners,
+	})
+	switch {
+	case err != nil:
+		decision.Action = g.onError
+		decision.Reason = err.Error()
+	case result.Action == "":
+		return decision
+	default:
+		decision.Action = result.Action
+		decision.Reason = result.Reason
+	}
+	decision.Rule = "policy:" + g.decision
+	return decision
+}
+
+// check asks the policy about one sandbox access. Only deny blocks it.
+func (g *policyGate) check(kind, target string) error {
+	if g == nil || strings.TrimSpace(target) == "" {
+		return nil
+	}
+	result, err := g.evaluate(PolicyInput{Kind: kind, Target: target})
+	action := result.Action
+	if err != nil {
+		action = g.onError
+	}
+	if action != PermissionDeny {
+		return nil
+	}
+	reason := result.Reason
+	if err != nil {
+		reason = err.Error()
+	}
+	if reason == "" {
+		return fmt.Errorf("%w: %s %s", ErrPolicyDenied, kind, target)
+	}
+	return fmt.Errorf("%w: %s %s: %s", ErrPolicyDenied, kind, target, reason)
+}
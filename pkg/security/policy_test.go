@@ -0,0 +1,173 @@
+package security
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodePolicyValue(t *testing.T) {
+	cases := []struct {
+		in   any
+		want PolicyResult
+	}{
+		{nil, PolicyResult{}},
+		{true, PolicyResult{Action: PermissionAllow}},
+		{false, PolicyResult{Action: PermissionDeny}},
+		{"Ask", PolicyResult{Action: PermissionAsk}},
+		{map[string]any{"action": "deny", "reason": "prod"}, PolicyResult{Action: PermissionDeny, Reason: "prod"}},
+		{map[string]any{"allow": true}, PolicyResult{Action: PermissionAllow}},
+		{map[string]any{"reason": "no opinion"}, PolicyResult{Reason: "no opinion"}},
+	}
+	for _, tc := range cases {
+		got, err := decodePolicyValue(tc.in)
+		require.NoError(t, err, "%v", tc.in)
+		require.Equal(t, tc.want, got, "%v", tc.in)
+	}
+	for _, bad := range []any{"maybe", 3.0, map[string]any{"action": 1.0}} {
+		_, err := decodePolicyValue(bad)
+		require.Error(t, err, "%v", bad)
+	}
+}
+
+// newOPAServer serves data.agentsdk.authz: Bash commands containing "rm"
+// and the host evil.example are denied, everything else is undecided.
+func newOPAServer(t *testing.T, inputs *[]PolicyInput) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/data/agentsdk/authz" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Input PolicyInput `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		*inputs = append(*inputs, body.Input)
+		in := body.Input
+		switch {
+		case in.Kind == PolicyKindTool && in.Tool == "Bash" && strings.Contains(in.Target, "rm"):
+			_, _ = w.Write([]byte(`{"result":{"action":"deny","reason":"destructive command"}}`))
+		case in.Kind == PolicyKindNetwork && in.Target == "evil.example":
+			_, _ = w.Write([]byte(`{"result":false}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writePolicySettings(t *testing.T, root string, settings map[string]any) {
+	t.Helper()
+	claudeDir := filepath.Join(root, ".claude")
+	require.NoError(t, os.MkdirAll(claudeDir, 0o755))
+	data, err := json.Marshal(settings)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(claudeDir, "settings.json"), data, 0o600))
+}
+
+func TestSandboxDelegatesToOPAServer(t *testing.T) {
+	var inputs []PolicyInput
+	srv := newOPAServer(t, &inputs)
+	root := t.TempDir()
+	writePolicySettings(t, root, map[string]any{
+		"permissions": map[string]any{"allow": []string{"Bash(rm:*)"}},
+		"policy":      map[string]any{"url": srv.URL, "decisionLog": "decisions.jsonl"},
+	})
+	sb := NewSandbox(root)
+	require.NoError(t, sb.LoadPermissions(root))
+
+	deny := sb.mustDecision(t, "Bash", map[string]any{"command": "rm -rf build"})
+	require.Equal(t, PermissionDeny, deny.Action)
+	require.Equal(t, "policy:agentsdk/authz", deny.Rule)
+	require.Equal(t, "destructive command", deny.Reason)
+	require.Equal(t, PermissionAllow, inputs[0].Action, "policy sees the settings decision")
+	require.Equal(t, "Bash(rm:*)", inputs[0].Rule)
+
+	// Undecided keeps the settings outcome.
+	ls := sb.mustDecision(t, "Bash", map[string]any{"command": "ls"})
+	require.Equal(t, PermissionUnknown, ls.Action)
+
+	err := sb.CheckSandboxPolicy(filepath.Join(root, "a.txt"), "evil.example")
+	require.ErrorIs(t, err, ErrPolicyDenied)
+	require.NoError(t, sb.CheckSandboxPolicy("", "good.example"))
+
+	audits := sb.PermissionAudits()
+	require.Len(t, audits, 1)
+	require.Equal(t, "destructive command", audits[0].Reason)
+
+	f, err := os.Open(filepath.Join(root, "decisions.jsonl"))
+	require.NoError(t, err)
+	defer f.Close()
+	var entries []PolicyDecisionLog
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry PolicyDecisionLog
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.Len(t, entries, 5)
+	require.Equal(t, PermissionDeny, entries[0].Result.Action)
+	require.Nil(t, entries[0].Input.Params, "params stay out of the decision log")
+}
+
+func TestSandboxPolicyOnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	for onError, want := range map[string]PermissionAction{"": PermissionDeny, "allow": PermissionAllow} {
+		root := t.TempDir()
+		writePolicySettings(t, root, map[string]any{"policy": map[string]any{"url": srv.URL, "onError": onError}})
+		sb := NewSandbox(root)
+		require.NoError(t, sb.LoadPermissions(root))
+		got := sb.mustDecision(t, "Read", map[string]any{"file_path": "x"})
+		require.Equal(t, want, got.Action, "onError %q", onError)
+		require.Contains(t, got.Reason, "500")
+		err := sb.CheckSandboxPolicy("", "host.example")
+		require.Equal(t, want == PermissionDeny, errors.Is(err, ErrPolicyDenied), "onError %q: %v", onError, err)
+	}
+}
+
+func TestOPAEngineEvaluatesRegoWithBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake opa is a shell script")
+	}
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + args + "\ncat > " + filepath.Join(dir, "input") + "\n" +
+		`echo '{"result":[{"expressions":[{"value":"ask","text":"data.team.tools"}]}]}'` + "\n"
+	opa := filepath.Join(dir, "opa")
+	require.NoError(t, os.WriteFile(opa, []byte(script), 0o755))
+
+	engine, err := NewOPAEngine(OPAConfig{Files: []string{"policy.rego"}, Binary: opa, Decision: "/team/tools/"})
+	require.NoError(t, err)
+	res, err := engine.Evaluate(context.Background(), PolicyInput{Kind: PolicyKindTool, Tool: "Write"})
+	require.NoError(t, err)
+	require.Equal(t, PermissionAsk, res.Action)
+
+	gotArgs, err := os.ReadFile(args)
+	require.NoError(t, err)
+	require.Equal(t, "eval --format json --stdin-input --data policy.rego data.team.tools", strings.TrimSpace(string(gotArgs)))
+	input, err := os.ReadFile(filepath.Join(dir, "input"))
+	require.NoError(t, err)
+	require.Contains(t, string(input), `"tool":"Write"`)
+
+	_, err = NewOPAEngine(OPAConfig{})
+	require.Error(t, err)
+}
@@ -0,0 +1,127 @@
+package security
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// protectedEditTools are the tools whose targets are checked against
+// protected paths. Bash is checked through the files its command writes.
+var protectedEditTools = map[string]struct{}{"write": {}, "edit": {}, "notebookedit": {}}
+
+// builtinProtectedPaths are protected whatever the settings say: .claude
+// holds the settings, agents, skills and hooks, so editing it can widen what
+// the agent is allowed to do.
+var builtinProtectedPaths = []string{"/.claude/"}
+
+// ProtectedPaths flags edits to sensitive parts of a repository. Patterns use
+// CODEOWNERS syntax relative to the root; owners come from the repository's
+// CODEOWNERS file.
+type ProtectedPaths struct {
+	root     string
+	patterns []protectedPattern
+	owners   *CodeOwners
+}
+
+type protectedPattern struct {
+	raw   string
+	match func(string) bool
+}
+
+// NewProtectedPaths compiles patterns for paths under root. owners may be nil.
+func NewProtectedPaths(root string, patterns []string, owners *CodeOwners) (*ProtectedPaths, error) {
+	p := &ProtectedPaths{root: normalizePath(root), owners: owners}
+	for _, raw := range patterns {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		match, err := compileOwnerPattern(raw)
+		if err != nil {
+			return nil, fmt.Errorf("compile protected path %q: %w", raw, err)
+		}
+		p.patterns = append(p.patterns, protectedPattern{raw: raw, match: match})
+	}
+	return p, nil
+}
+
+// Match reports the first protected pattern covering path and the owners of
+// path. Paths outside the root are never protected.
+func (p *ProtectedPaths) Match(path string) (pattern string, owners []string, ok bool) {
+	rel, inside := p.rel(path)
+	if !inside {
+		return "", nil, false
+	}
+	for _, pat := range p.patterns {
+		if pat.match(rel) {
+			return pat.raw, p.owners.Owners(rel), true
+		}
+	}
+	return "", nil, false
+}
+
+// Owners returns the CODEOWNERS owners of path.
+func (p *ProtectedPaths) Owners(path string) []string {
+	rel, inside := p.rel(path)
+	if !inside {
+		return nil
+	}
+	return p.owners.Owners(rel)
+}
+
+func (p *ProtectedPaths) rel(path string) (string, bool) {
+	if p == nil || strings.TrimSpace(path) == "" {
+		return "", false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.root, path)
+	}
+	rel, err := filepath.Rel(p.root, filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// apply escalates an edit of a protected path to PermissionAsk and attaches
+// its owners. Bash commands are checked against the files WriteTargets finds
+// (redirects, tee, cp/mv/install destinations, sed -i, dd of=), relative to
+// their workdir param. Deny decisions keep their action.
+func (p *ProtectedPaths) apply(decision PermissionDecision, params map[string]any) PermissionDecision {
+	if p == nil {
+		return decision
+	}
+	tool := strings.ToLower(decision.Tool)
+	if tool == "bash" {
+		workdir := firstString(params, "workdir")
+		for _, target := range WriteTargets(firstString(params, "command")) {
+			if !filepath.IsAbs(target) && workdir != "" {
+				target = filepath.Join(workdir, target)
+			}
+			if pattern, owners, ok := p.Match(target); ok {
+				return p.escalate(decision, pattern, owners)
+			}
+		}
+		return decision
+	}
+	if _, ok := protectedEditTools[tool]; !ok {
+		return decision
+	}
+	pattern, owners, ok := p.Match(decision.Target)
+	if !ok {
+		decision.Owners = p.Owners(decision.Target)
+		return decision
+	}
+	return p.escalate(decision, pattern, owners)
+}
+
+func (p *ProtectedPaths) escalate(decision PermissionDecision, pattern string, owners []string) PermissionDecision {
+	decision.Protected = pattern
+	decision.Owners = owners
+	if decision.Action != PermissionDeny {
+		decision.Action = PermissionAsk
+		decision.Rule = "protected:" + pattern
+	}
+	return decision
+}
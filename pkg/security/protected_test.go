@@ -0,0 +1,136 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCodeOwnersLastMatchWins(t *testing.T) {
+	t.Parallel()
+
+	co, err := ParseCodeOwners([]byte(`# owners
+*                 @org/everyone
+*.go              @org/gophers
+/deploy/          @org/sre @alice
+docs/*            @org/docs
+[Section]
+/deploy/dev/      # no owners: unowned
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	cases := map[string][]string{
+		"README.md":           {"@org/everyone"},
+		"pkg/api/agent.go":    {"@org/gophers"},
+		"deploy/prod/app.yml": {"@org/sre", "@alice"},
+		"deploy/dev/app.yml":  {},
+		"docs/intro.md":       {"@org/docs"},
+		"docs/guide/intro.md": {"@org/everyone"},
+	}
+	for path, want := range cases {
+		got := co.Owners(path)
+		if len(got) == 0 && len(want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: owners = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestSandboxProtectedPathsRequireApproval(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeFile := func(rel, body string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatalf("write %s: %v", rel, err)
+		}
+	}
+	writeFile(".claude/settings.json", `{"permissions":{"allow":["Write(**)"],"deny":["Edit(**/secrets.yml)"],"protectedPaths":["/deploy/","go.mod"]}}`)
+	writeFile(".github/CODEOWNERS", "/deploy/ @org/sre\n")
+
+	s := NewSandbox(root)
+	if err := s.LoadPermissions(root); err != nil {
+		t.Fatalf("load permissions: %v", err)
+	}
+
+	decision, err := s.CheckToolPermission("Write", map[string]any{"file_path": filepath.Join(root, "deploy", "prod.yml")})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if decision.Action != PermissionAsk || decision.Protected != "/deploy/" || decision.Rule != "protected:/deploy/" || !reflect.DeepEqual(decision.Owners, []string{"@org/sre"}) {
+		t.Fatalf("protected write = %+v", decision)
+	}
+
+	decision, _ = s.CheckToolPermission("Edit", map[string]any{"file_path": "deploy/secrets.yml"})
+	if decision.Action != PermissionDeny || decision.Protected != "/deploy/" {
+		t.Fatalf("deny rule should win over protection: %+v", decision)
+	}
+	decision, _ = s.CheckToolPermission("Write", map[string]any{"file_path": filepath.Join(root, "main.go")})
+	if decision.Action != PermissionAllow || decision.Protected != "" {
+		t.Fatalf("unprotected write = %+v", decision)
+	}
+	decision, _ = s.CheckToolPermission("NotebookEdit", map[string]any{"notebook_path": filepath.Join(root, "deploy", "run.ipynb")})
+	if decision.Action != PermissionAsk || decision.Protected != "/deploy/" {
+		t.Fatalf("protected notebook edit = %+v", decision)
+	}
+	decision, _ = s.CheckToolPermission("Bash", map[string]any{"command": "echo x | tee -a prod.yml", "workdir": filepath.Join(root, "deploy")})
+	if decision.Action != PermissionAsk || decision.Protected != "/deploy/" || !reflect.DeepEqual(decision.Owners, []string{"@org/sre"}) {
+		t.Fatalf("protected bash write = %+v", decision)
+	}
+	decision, _ = s.CheckToolPermission("Bash", map[string]any{"command": "echo x > .claude/settings.json"})
+	if decision.Action != PermissionAsk || decision.Protected != "/.claude/" {
+		t.Fatalf("bash write to .claude = %+v", decision)
+	}
+	for _, command := range []string{
+		"cp /tmp/evil.yml deploy/prod.yml",
+		"mv new.yml deploy/",
+		"sed -i 's/replicas: 1/replicas: 0/' deploy/prod.yml",
+		"dd if=/tmp/x of=deploy/prod.yml",
+	} {
+		decision, _ = s.CheckToolPermission("Bash", map[string]any{"command": command})
+		if decision.Action != PermissionAsk || decision.Protected != "/deploy/" {
+			t.Fatalf("%q = %+v", command, decision)
+		}
+	}
+	decision, _ = s.CheckToolPermission("Bash", map[string]any{"command": "cp deploy/prod.yml backup.yml"})
+	if decision.Protected != "" {
+		t.Fatalf("copying from a protected path is not an edit: %+v", decision)
+	}
+	decision, _ = s.CheckToolPermission("Bash", map[string]any{"command": "cat deploy/prod.yml > out.txt"})
+	if decision.Protected != "" {
+		t.Fatalf("reading a protected path from bash is not an edit: %+v", decision)
+	}
+	decision, _ = s.CheckToolPermission("Read", map[string]any{"file_path": "go.mod"})
+	if decision.Protected != "" {
+		t.Fatalf("reads are never protected: %+v", decision)
+	}
+
+	audits := s.PermissionAudits()
+	if len(audits) == 0 || audits[0].Protected != "/deploy/" || !reflect.DeepEqual(audits[0].Owners, []string{"@org/sre"}) {
+		t.Fatalf("audit = %+v", audits)
+	}
+}
+
+func TestProtectedPathsIgnoreOutsideRoot(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	p, err := NewProtectedPaths(root, []string{"*"}, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if _, _, ok := p.Match(filepath.Join(filepath.Dir(root), "other")); ok {
+		t.Fatal("path outside root matched")
+	}
+	if _, _, ok := p.Match("nested/file.txt"); !ok {
+		t.Fatal("relative path inside root not matched")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -27,6 +28,9 @@ type Sandbox struct {
 
 	permissionRoot string
 	permissions    *PermissionMatcher
+	protected      *ProtectedPaths
+	policy         *policyGate
+	analyzer       *CommandAnalyzer
 	permOnce       sync.Once
 	permErr        error
 	permLoaded     bool
@@ -110,6 +114,24 @@ func (s *Sandbox) ValidatePath(path string) error {
 	return fmt.Errorf("%w: %s", ErrPathNotAllowed, abs)
 }
 
+// ValidateWrite ensures path may be modified: it must lie under the allow
+// list without crossing a symlink. Blocked writes return a *WriteScopeError.
+func (s *Sandbox) ValidateWrite(path string) error {
+	if s != nil && s.disabled {
+		return nil
+	}
+	if s == nil {
+		return errors.New("security: sandbox is nil")
+	}
+	s.mu.RLock()
+	roots := append([]string(nil), s.allowList...)
+	s.mu.RUnlock()
+	if len(roots) == 0 {
+		return &WriteScopeError{Path: normalizePath(path), Reason: WriteOutsideRoots}
+	}
+	return NewWriteScope(roots[0], roots[1:]...).Check(path)
+}
+
 // ValidateCommand is the second defense line, preventing obviously dangerous commands.
 func (s *Sandbox) ValidateCommand(cmd string) error {
 	if s != nil && s.disabled {
@@ -161,10 +183,41 @@ func (s *Sandbox) LoadPermissions(projectRoot string) error {
 		s.mu.Unlock()
 		return fmt.Errorf("security: build permission matcher: %w", err)
 	}
+	protected, err := loadProtectedPaths(effectiveRoot, settings.Permissions)
+	if err != nil {
+		s.mu.Lock()
+		s.permErr = err
+		s.permLoaded = true
+		s.mu.Unlock()
+		return fmt.Errorf("security: load protected paths: %w", err)
+	}
+	policy, err := newPolicyGate(effectiveRoot, settings.Policy)
+	if err != nil {
+		s.mu.Lock()
+		s.permErr = err
+		s.permLoaded = true
+		s.mu.Unlock()
+		return fmt.Errorf("security: load policy: %w", err)
+	}
+	var analyzerActions map[string]string
+	if settings.Permissions != nil {
+		analyzerActions = settings.Permissions.CommandAnalyzer
+	}
+	analyzer, err := NewCommandAnalyzer(analyzerActions)
+	if err != nil {
+		s.mu.Lock()
+		s.permErr = err
+		s.permLoaded = true
+		s.mu.Unlock()
+		return fmt.Errorf("security: build command analyzer: %w", err)
+	}
 
 	s.mu.Lock()
 	s.permissionRoot = effectiveRoot
 	s.permissions = matcher
+	s.protected = protected
+	s.policy = policy
+	s.analyzer = analyzer
 	s.permErr = nil
 	s.permLoaded = true
 	s.auditLog = nil
@@ -172,9 +225,33 @@ func (s *Sandbox) LoadPermissions(projectRoot string) error {
 	return nil
 }
 
+// AddPermissionRule adds an allow, ask or deny rule to the loaded rules
+// without reloading settings, e.g. after persisting a remembered decision.
+// An added targeted allow rule also overrides a bare tool ask rule (see
+// PermissionMatcher.Match) until the permissions are reloaded.
+func (s *Sandbox) AddPermissionRule(action PermissionAction, rule string) error {
+	if s == nil {
+		return errors.New("security: sandbox is nil")
+	}
+	if err := s.ensurePermissionsLoaded(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next, err := s.permissions.withRule(action, rule)
+	if err != nil {
+		return fmt.Errorf("security: add permission rule: %w", err)
+	}
+	s.permissions = next
+	return nil
+}
+
 // CheckToolPermission evaluates tool invocation against configured allow/ask/deny
-// rules. Denials and prompts are returned to the caller; missing or empty rules
-// default to allow to preserve backward compatibility.
+// rules, then lets a configured policy override the outcome. Denials and
+// prompts are returned to the caller; missing or empty rules default to allow
+// to preserve backward compatibility. Bash commands are additionally parsed:
+// each sub-command is matched on its own and dangerous constructs found by
+// AnalyzeCommand escalate the decision (see CommandAnalyzer).
 func (s *Sandbox) CheckToolPermission(toolName string, params map[string]any) (PermissionDecision, error) {
 	if s == nil || s.disabled {
 		return PermissionDecision{Action: PermissionAllow}, nil
@@ -186,18 +263,41 @@ func (s *Sandbox) CheckToolPermission(toolName string, params map[string]any) (P
 
 	s.mu.RLock()
 	matcher := s.permissions
+	protected := s.protected
+	policy := s.policy
+	analyzer := s.analyzer
 	s.mu.RUnlock()
-	if matcher == nil {
+
+	decision := analyzer.apply(matcher, matcher.Match(toolName, params), params)
+	if matcher == nil && protected == nil && policy == nil && decision.Action == PermissionAllow {
 		return PermissionDecision{Action: PermissionAllow}, nil
 	}
-
-	decision := matcher.Match(toolName, params)
+	decision = policy.apply(protected.apply(decision, params), params)
 	if decision.Action != PermissionUnknown {
 		s.recordAudit(decision)
 	}
 	return decision, nil
 }
 
+// CheckSandboxPolicy asks the configured policy about a filesystem path and
+// an outbound host that the sandbox allowlists accepted. Policies can only
+// narrow the sandbox: a deny returns ErrPolicyDenied, anything else passes.
+func (s *Sandbox) CheckSandboxPolicy(path, host string) error {
+	if s == nil || s.disabled {
+		return nil
+	}
+	if err := s.ensurePermissionsLoaded(); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	policy := s.policy
+	s.mu.RUnlock()
+	if err := policy.check(PolicyKindPath, path); err != nil {
+		return err
+	}
+	return policy.check(PolicyKindNetwork, host)
+}
+
 // PermissionAudits returns a snapshot of audited permission decisions.
 func (s *Sandbox) PermissionAudits() []PermissionAudit {
 	if s == nil {
@@ -241,6 +341,9 @@ func (s *Sandbox) recordAudit(decision PermissionDecision) {
 		Target:    decision.Target,
 		Rule:      decision.Rule,
 		Action:    decision.Action,
+		Protected: decision.Protected,
+		Owners:    decision.Owners,
+		Reason:    decision.Reason,
 		Timestamp: time.Now(),
 	}
 	s.mu.Lock()
@@ -248,6 +351,20 @@ func (s *Sandbox) recordAudit(decision PermissionDecision) {
 	s.mu.Unlock()
 }
 
+// loadProtectedPaths combines the built-in protected paths and
+// permissions.protectedPaths with the repository's CODEOWNERS file.
+func loadProtectedPaths(root string, cfg *config.PermissionsConfig) (*ProtectedPaths, error) {
+	patterns := slices.Clone(builtinProtectedPaths)
+	if cfg != nil {
+		patterns = append(patterns, cfg.ProtectedPaths...)
+	}
+	owners, err := LoadCodeOwners(root)
+	if err != nil {
+		return nil, err
+	}
+	return NewProtectedPaths(root, patterns, owners)
+}
+
 func normalizePath(path string) string {
 	if path == "" {
 		return ""
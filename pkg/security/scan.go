@@ -0,0 +1,126 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Finding severities.
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Finding is one issue an Analyzer reported in a file.
+type Finding struct {
+	Analyzer string `json:"analyzer"`
+	Path     string `json:"path"`
+	Line     int    `json:"line,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (f Finding) String() string {
+	loc := f.Path
+	if f.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", f.Path, f.Line)
+	}
+	return fmt.Sprintf("%s: [%s/%s] %s", loc, f.Analyzer, f.Rule, f.Message)
+}
+
+// Analyzer inspects the content of a file the agent wrote. Implementations
+// wrap secret scanners, linters or SAST tools; path is informational and
+// may not exist on disk yet.
+type Analyzer interface {
+	Name() string
+	Analyze(ctx context.Context, path string, content []byte) ([]Finding, error)
+}
+
+// ScanFile runs every analyzer over content and returns their findings.
+// Analyzer errors are reported as findings of rule "error" so a broken
+// analyzer cannot silently pass a file.
+func ScanFile(ctx context.Context, analyzers []Analyzer, path string, content []byte) []Finding {
+	var out []Finding
+	for _, a := range analyzers {
+		if a == nil {
+			continue
+		}
+		findings, err := a.Analyze(ctx, path, content)
+		if err != nil {
+			out = append(out, Finding{Analyzer: a.Name(), Path: path, Rule: "error", Severity: SeverityMedium, Message: err.Error()})
+			continue
+		}
+		for _, f := range findings {
+			if f.Analyzer == "" {
+				f.Analyzer = a.Name()
+			}
+			if f.Path == "" {
+				f.Path = path
+			}
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+type secretRule struct {
+	id       string
+	severity string
+	pattern  *regexp.Regexp
+}
+
+// secretRules match well-known credential formats. The generic assignment
+// rule only fires on long values so placeholders like "changeme" pass.
+var secretRules = []secretRule{
+	{"private-key", SeverityCritical, regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP )?PRIVATE KEY(?: BLOCK)?-----`)},
+	{"aws-access-key", SeverityHigh, regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-token", SeverityHigh, regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,})\b`)},
+	{"slack-token", SeverityHigh, regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{"api-key", SeverityHigh, regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}\b`)},
+	{"google-api-key", SeverityHigh, regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"generic-secret", SeverityMedium, regexp.MustCompile(`(?i)(?:api[_-]?key|secret|token|passw(?:or)?d)["']?\s*[:=]\s*["']([A-Za-z0-9+/_\-.=]{16,})["']`)},
+}
+
+// SecretScanner is the built-in Analyzer that flags credentials. Findings
+// name the rule and line but never echo the matched secret.
+type SecretScanner struct{}
+
+// NewSecretScanner returns the built-in secret scanner.
+func NewSecretScanner() *SecretScanner { return &SecretScanner{} }
+
+// Name implements Analyzer.
+func (*SecretScanner) Name() string { return "secrets" }
+
+// Analyze implements Analyzer.
+func (*SecretScanner) Analyze(ctx context.Context, path string, content []byte) ([]Finding, error) {
+	var out []Finding
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+		text := scanner.Text()
+		for _, rule := range secretRules {
+			if !rule.pattern.MatchString(text) {
+				continue
+			}
+			out = append(out, Finding{
+				Path:     path,
+				Line:     line,
+				Rule:     rule.id,
+				Severity: rule.severity,
+				Message:  "possible " + strings.ReplaceAll(rule.id, "-", " ") + " committed to file",
+			})
+			break
+		}
+	}
+	return out, scanner.Err()
+}
@@ -0,0 +1,724 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxShellDepth bounds how deeply substitutions and `bash -c` strings are
+// parsed.
+const maxShellDepth = 16
+
+// shellScript is a parsed command list: pipelines joined by ;, &&, ||, &
+// or newlines.
+type shellScript struct {
+	pipelines []*shellPipeline
+}
+
+type shellPipeline struct {
+	commands   []*shellCommand
+	background bool
+}
+
+// shellCommand is a simple command, a subshell or brace group (body) or a
+// function definition (funcName and body).
+type shellCommand struct {
+	raw       string
+	args      []string
+	assigns   []string
+	redirects []shellRedirect
+	// subs are the command and process substitutions found in the words.
+	subs     []*shellScript
+	body     *shellScript
+	funcName string
+}
+
+type shellRedirect struct {
+	op     string
+	target string
+}
+
+type shellTokenKind int
+
+const (
+	shellWord shellTokenKind = iota
+	shellOp
+)
+
+type shellToken struct {
+	kind       shellTokenKind
+	text       string
+	start, end int
+	// subs holds the source of each $(...), `...`, <(...) and >(...) in a word.
+	subs []string
+}
+
+// shellReserved are words that only introduce or close a compound command;
+// the parser skips them and keeps the commands they wrap.
+var shellReserved = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "fi": true,
+	"while": true, "until": true, "do": true, "done": true,
+	"esac": true, "time": true, "!": true,
+}
+
+// parseShell parses the subset of POSIX/bash syntax an analyzer needs.
+// Control flow is flattened: the commands inside if/while/for bodies are
+// kept, their structure is not.
+func parseShell(src string, depth int) (*shellScript, error) {
+	if depth > maxShellDepth {
+		return nil, errors.New("security: shell nesting too deep")
+	}
+	tokens, err := lexShell(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &shellParser{src: src, tokens: tokens, depth: depth}
+	script, err := p.list("")
+	if err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+type shellParser struct {
+	src    string
+	tokens []shellToken
+	pos    int
+	depth  int
+}
+
+func (p *shellParser) peek() (shellToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return shellToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// list parses pipelines until the closing token ")" or "}" (when non-empty)
+// or the end of input.
+func (p *shellParser) list(closer string) (*shellScript, error) {
+	script := &shellScript{}
+	for {
+		tok, ok := p.peek()
+		if !ok {
+			if closer != "" {
+				return nil, fmt.Errorf("security: missing %q", closer)
+			}
+			return script, nil
+		}
+		if tok.kind == shellOp {
+			switch tok.text {
+			case ")":
+				if closer == ")" {
+					return script, nil
+				}
+				// A stray ")" such as a case pattern terminator.
+				p.pos++
+				continue
+			case ";", ";;", "\n", "&&", "||", "&":
+				p.pos++
+				continue
+			}
+		}
+		if tok.kind == shellWord && tok.text == "}" && closer == "}" {
+			return script, nil
+		}
+		pipeline, err := p.pipeline(closer)
+		if err != nil {
+			return nil, err
+		}
+		if len(pipeline.commands) > 0 {
+			script.pipelines = append(script.pipelines, pipeline)
+		}
+	}
+}
+
+func (p *shellParser) pipeline(closer string) (*shellPipeline, error) {
+	pipeline := &shellPipeline{}
+	for {
+		cmd, err := p.command(closer)
+		if err != nil {
+			return nil, err
+		}
+		if cmd != nil {
+			pipeline.commands = append(pipeline.commands, cmd)
+		}
+		tok, ok := p.peek()
+		if !ok || tok.kind != shellOp {
+			return pipeline, nil
+		}
+		switch tok.text {
+		case "|", "|&":
+			p.pos++
+		case "&":
+			p.pos++
+			pipeline.background = true
+			return pipeline, nil
+		default:
+			return pipeline, nil
+		}
+	}
+}
+
+func (p *shellParser) command(closer string) (*shellCommand, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, nil
+	}
+	if tok.kind == shellOp && tok.text == "(" {
+		p.pos++
+		body, err := p.list(")")
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		cmd := &shellCommand{body: body}
+		return cmd, p.trailingRedirects(cmd)
+	}
+	if tok.kind != shellWord {
+		if isRedirectOp(tok.text) {
+			return p.simple()
+		}
+		return nil, nil
+	}
+	switch {
+	case tok.text == "{":
+		p.pos++
+		body, err := p.list("}")
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		cmd := &shellCommand{body: body}
+		return cmd, p.trailingRedirects(cmd)
+	case tok.text == "}" && closer == "}":
+		return nil, nil
+	case shellReserved[tok.text]:
+		p.pos++
+		return p.command(closer)
+	case tok.text == "for" || tok.text == "select" || tok.text == "case":
+		// The loop header or case subject runs nothing; skip to the body.
+		for p.pos < len(p.tokens) {
+			next := p.tokens[p.pos]
+			if next.kind == shellOp && (next.text == ";" || next.text == "\n") {
+				break
+			}
+			if tok.text == "case" && next.kind == shellWord && next.text == "in" {
+				p.pos++
+				break
+			}
+			p.pos++
+		}
+		return nil, nil
+	case tok.text == "function":
+		p.pos++
+		name, ok := p.peek()
+		if !ok || name.kind != shellWord {
+			return nil, errors.New("security: function name expected")
+		}
+		p.pos++
+		p.skipParens()
+		return p.function(name.text, closer)
+	}
+	return p.simple()
+}
+
+// simple parses words and redirections up to the next operator. A single
+// word followed by "()" starts a function definition.
+func (p *shellParser) simple() (*shellCommand, error) {
+	cmd := &shellCommand{}
+	first, last := -1, -1
+	for {
+		tok, ok := p.peek()
+		if !ok {
+			break
+		}
+		if tok.kind == shellOp {
+			if tok.text == "(" && len(cmd.args) == 1 && len(cmd.assigns) == 0 && len(cmd.redirects) == 0 {
+				p.skipParens()
+				return p.function(cmd.args[0], "")
+			}
+			if !isRedirectOp(tok.text) {
+				break
+			}
+			p.pos++
+			target, ok := p.peek()
+			if !ok || target.kind != shellWord {
+				return nil, fmt.Errorf("security: missing target for %s", tok.text)
+			}
+			p.pos++
+			if first < 0 {
+				first = tok.start
+			}
+			last = target.end
+			cmd.redirects = append(cmd.redirects, shellRedirect{op: tok.text, target: target.text})
+			if err := p.addSubs(cmd, target); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		p.pos++
+		if first < 0 {
+			first = tok.start
+		}
+		last = tok.end
+		if len(cmd.args) == 0 && isAssignment(tok.text) {
+			cmd.assigns = append(cmd.assigns, tok.text)
+		} else {
+			cmd.args = append(cmd.args, tok.text)
+		}
+		if err := p.addSubs(cmd, tok); err != nil {
+			return nil, err
+		}
+	}
+	if first < 0 {
+		return nil, nil
+	}
+	cmd.raw = strings.TrimSpace(p.src[first:last])
+	return cmd, nil
+}
+
+func (p *shellParser) function(name, closer string) (*shellCommand, error) {
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != shellOp || tok.text != "\n" {
+			break
+		}
+		p.pos++
+	}
+	body, err := p.command(closer)
+	if err != nil {
+		return nil, err
+	}
+	fn := &shellCommand{funcName: name, body: &shellScript{}}
+	if body != nil {
+		fn.body.pipelines = []*shellPipeline{{commands: []*shellCommand{body}}}
+	}
+	return fn, nil
+}
+
+// skipParens consumes the "()" of a function definition.
+func (p *shellParser) skipParens() {
+	if tok, ok := p.peek(); ok && tok.kind == shellOp && tok.text == "(" {
+		p.pos++
+		if tok, ok := p.peek(); ok && tok.kind == shellOp && tok.text == ")" {
+			p.pos++
+		}
+	}
+}
+
+func (p *shellParser) trailingRedirects(cmd *shellCommand) error {
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != shellOp || !isRedirectOp(tok.text) {
+			return nil
+		}
+		p.pos++
+		target, ok := p.peek()
+		if !ok || target.kind != shellWord {
+			return fmt.Errorf("security: missing target for %s", tok.text)
+		}
+		p.pos++
+		cmd.redirects = append(cmd.redirects, shellRedirect{op: tok.text, target: target.text})
+		if err := p.addSubs(cmd, target); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *shellParser) addSubs(cmd *shellCommand, tok shellToken) error {
+	for _, src := range tok.subs {
+		sub, err := parseShell(src, p.depth+1)
+		if err != nil {
+			return err
+		}
+		cmd.subs = append(cmd.subs, sub)
+	}
+	return nil
+}
+
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return false
+	}
+	name = strings.TrimSuffix(name, "+")
+	for i, r := range name {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return name != ""
+}
+
+var shellRedirectOps = []string{"&>>", "<<<", "<<-", "&>", ">>", ">|", ">&", "<&", "<<", "<>", ">", "<"}
+
+var shellControlOps = []string{"&&", "||", "|&", ";;", "|", ";", "&", "(", ")"}
+
+func isRedirectOp(op string) bool {
+	op = strings.TrimLeft(op, "0123456789")
+	for _, candidate := range shellRedirectOps {
+		if op == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// lexShell splits src into words (quotes removed, substitutions recorded)
+// and operators. Here-document bodies are skipped.
+func lexShell(src string) ([]shellToken, error) {
+	var (
+		tokens  []shellToken
+		heredoc []string
+		pending bool
+		dashed  []bool
+		strip   bool
+	)
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			i += 2
+			continue
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '\n':
+			tokens = append(tokens, shellToken{kind: shellOp, text: "\n", start: i, end: i + 1})
+			i++
+			for n, delim := range heredoc {
+				i = skipHeredoc(src, i, delim, dashed[n])
+			}
+			heredoc, dashed = nil, nil
+			continue
+		}
+		if op := matchRedirect(src[i:]); op != "" {
+			tokens = append(tokens, shellToken{kind: shellOp, text: op, start: i, end: i + len(op)})
+			i += len(op)
+			trimmed := strings.TrimLeft(op, "0123456789")
+			if trimmed == "<<" || trimmed == "<<-" {
+				pending, strip = true, trimmed == "<<-"
+			}
+			continue
+		}
+		if op := matchPrefix(src[i:], shellControlOps); op != "" {
+			tokens = append(tokens, shellToken{kind: shellOp, text: op, start: i, end: i + len(op)})
+			i += len(op)
+			continue
+		}
+		tok, next, err := lexWord(src, i)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		i = next
+		if pending {
+			heredoc = append(heredoc, tok.text)
+			dashed = append(dashed, strip)
+			pending = false
+		}
+	}
+	return tokens, nil
+}
+
+// matchRedirect matches a redirection operator with an optional file
+// descriptor prefix such as "2>". Process substitutions <( and >( are words.
+func matchRedirect(s string) string {
+	digits := 0
+	for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	rest := s[digits:]
+	if strings.HasPrefix(rest, "<(") || strings.HasPrefix(rest, ">(") {
+		return ""
+	}
+	if digits > 0 && strings.HasPrefix(rest, "&") {
+		return ""
+	}
+	op := matchPrefix(rest, shellRedirectOps)
+	if op == "" {
+		return ""
+	}
+	return s[:digits+len(op)]
+}
+
+func matchPrefix(s string, ops []string) string {
+	for _, op := range ops {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// skipHeredoc returns the offset just past the line equal to delim.
+func skipHeredoc(src string, i int, delim string, dashed bool) int {
+	for i < len(src) {
+		end := strings.IndexByte(src[i:], '\n')
+		line := src[i:]
+		next := len(src)
+		if end >= 0 {
+			line = src[i : i+end]
+			next = i + end + 1
+		}
+		if dashed {
+			line = strings.TrimLeft(line, "\t")
+		}
+		i = next
+		if line == delim {
+			break
+		}
+	}
+	return i
+}
+
+func lexWord(src string, start int) (shellToken, int, error) {
+	var b strings.Builder
+	tok := shellToken{kind: shellWord, start: start}
+	i := start
+	for i < len(src) {
+		c := src[i]
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';' || c == '&' || c == '|' || c == ')' {
+			break
+		}
+		if c == '(' {
+			break
+		}
+		switch c {
+		case '\\':
+			if i+1 < len(src) {
+				if src[i+1] != '\n' {
+					b.WriteByte(src[i+1])
+				}
+				i += 2
+				continue
+			}
+			i++
+		case '\'':
+			end := strings.IndexByte(src[i+1:], '\'')
+			if end < 0 {
+				return tok, 0, errors.New("security: unterminated single quote")
+			}
+			b.WriteString(src[i+1 : i+1+end])
+			i += end + 2
+		case '"':
+			next, err := lexDoubleQuoted(src, i+1, &b, &tok)
+			if err != nil {
+				return tok, 0, err
+			}
+			i = next
+		case '$', '`':
+			if strings.HasPrefix(src[i:], "$'") {
+				next, err := lexANSIC(src, i+1, &b)
+				if err != nil {
+					return tok, 0, err
+				}
+				i = next
+				continue
+			}
+			next, err := lexExpansion(src, i, &b, &tok)
+			if err != nil {
+				return tok, 0, err
+			}
+			i = next
+		case '<', '>':
+			if i+1 < len(src) && src[i+1] == '(' {
+				end, err := matchParen(src, i+1)
+				if err != nil {
+					return tok, 0, err
+				}
+				tok.subs = append(tok.subs, src[i+2:end])
+				b.WriteString(src[i : end+1])
+				i = end + 1
+				continue
+			}
+			tok.text, tok.end = b.String(), i
+			return tok, i, nil
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	tok.text, tok.end = b.String(), i
+	return tok, i, nil
+}
+
+func lexDoubleQuoted(src string, i int, b *strings.Builder, tok *shellToken) (int, error) {
+	for i < len(src) {
+		switch c := src[i]; c {
+		case '"':
+			return i + 1, nil
+		case '\\':
+			if i+1 < len(src) && strings.IndexByte("$`\"\\\n", src[i+1]) >= 0 {
+				if src[i+1] != '\n' {
+					b.WriteByte(src[i+1])
+				}
+				i += 2
+				continue
+			}
+			b.WriteByte(c)
+			i++
+		case '$', '`':
+			next, err := lexExpansion(src, i, b, tok)
+			if err != nil {
+				return 0, err
+			}
+			i = next
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return 0, errors.New("security: unterminated double quote")
+}
+
+// ansiCEscapes maps the single-character escapes of $'...' strings.
+var ansiCEscapes = map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', 'a': '\a', 'b': '\b', 'e': 0x1b, 'E': 0x1b, 'f': '\f', 'v': '\v'}
+
+// lexANSIC handles the bash $'...' string whose opening quote is at i,
+// where backslash escapes, including \', are decoded.
+func lexANSIC(src string, i int, b *strings.Builder) (int, error) {
+	for j := i + 1; j < len(src); j++ {
+		switch c := src[j]; c {
+		case '\'':
+			return j + 1, nil
+		case '\\':
+			if j+1 >= len(src) {
+				break
+			}
+			j++
+			if esc, ok := ansiCEscapes[src[j]]; ok {
+				b.WriteByte(esc)
+			} else {
+				b.WriteByte(src[j])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return 0, errors.New("security: unterminated $' quote")
+}
+
+// lexExpansion handles $(...), $((...)), ${...} and `...` starting at i,
+// writing the source text to b and recording command substitutions,
+// including those nested in ${...} operands such as ${x:-$(cmd)}.
+func lexExpansion(src string, i int, b *strings.Builder, tok *shellToken) (int, error) {
+	if src[i] == '`' {
+		var inner strings.Builder
+		j := i + 1
+		for j < len(src) && src[j] != '`' {
+			if src[j] == '\\' && j+1 < len(src) {
+				inner.WriteByte(src[j+1])
+				j += 2
+				continue
+			}
+			inner.WriteByte(src[j])
+			j++
+		}
+		if j >= len(src) {
+			return 0, errors.New("security: unterminated backquote")
+		}
+		tok.subs = append(tok.subs, inner.String())
+		b.WriteString(src[i : j+1])
+		return j + 1, nil
+	}
+	if i+1 >= len(src) {
+		b.WriteByte('$')
+		return i + 1, nil
+	}
+	switch src[i+1] {
+	case '(':
+		end, err := matchParen(src, i+1)
+		if err != nil {
+			return 0, err
+		}
+		if !strings.HasPrefix(src[i+1:], "((") {
+			tok.subs = append(tok.subs, src[i+2:end])
+		}
+		b.WriteString(src[i : end+1])
+		return end + 1, nil
+	case '{':
+		var operand strings.Builder
+		j := i + 2
+		for j < len(src) && src[j] != '}' {
+			var err error
+			switch c := src[j]; {
+			case c == '\\':
+				j += 2
+			case c == '\'':
+				end := strings.IndexByte(src[j+1:], '\'')
+				if end < 0 {
+					return 0, errors.New("security: unterminated single quote")
+				}
+				j += end + 2
+			case c == '"':
+				j, err = lexDoubleQuoted(src, j+1, &operand, tok)
+			case strings.HasPrefix(src[j:], "$'"):
+				j, err = lexANSIC(src, j+1, &operand)
+			case c == '$' || c == '`':
+				j, err = lexExpansion(src, j, &operand, tok)
+			default:
+				j++
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		if j >= len(src) {
+			return 0, errors.New("security: unterminated parameter expansion")
+		}
+		b.WriteString(src[i : j+1])
+		return j + 1, nil
+	}
+	b.WriteByte('$')
+	return i + 1, nil
+}
+
+// matchParen returns the index of the ")" closing the "(" at open, skipping
+// quoted text.
+func matchParen(src string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '$':
+			if strings.HasPrefix(src[i:], "$'") {
+				end, err := lexANSIC(src, i+1, &strings.Builder{})
+				if err != nil {
+					return 0, err
+				}
+				i = end - 1
+			}
+		case '\'':
+			end := strings.IndexByte(src[i+1:], '\'')
+			if end < 0 {
+				return 0, errors.New("security: unterminated single quote")
+			}
+			i += end + 1
+		case '"':
+			for i++; i < len(src) && src[i] != '"'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, errors.New("security: unterminated substitution")
+}
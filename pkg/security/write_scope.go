@@ -0,0 +1,377 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrWriteOutsideScope is returned, wrapped in a *WriteScopeError, when a
+// write targets a path outside the writable roots.
+var ErrWriteOutsideScope = errors.New("security: write outside allowed directories")
+
+// WriteBlockReason says why WriteScope refused a path.
+type WriteBlockReason string
+
+const (
+	// WriteOutsideRoots means the path is not under any writable root.
+	WriteOutsideRoots WriteBlockReason = "outside_roots"
+	// WriteSymlinkEscape means a symlink below a root leads outside the roots.
+	WriteSymlinkEscape WriteBlockReason = "symlink_escape"
+	// WriteSymlink means the path crosses a symlink that stays inside the
+	// roots; writes never follow symlinks.
+	WriteSymlink WriteBlockReason = "symlink"
+)
+
+// WriteScopeError describes a blocked write. It unwraps to
+// ErrWriteOutsideScope, to ErrPathNotAllowed for WriteOutsideRoots, and to
+// the resolver error, if any.
+type WriteScopeError struct {
+	Path   string
+	Reason WriteBlockReason
+	// Target is where a rejected symlink points, when known.
+	Target string
+	// Roots are the directories writes are allowed under.
+	Roots []string
+	Err   error
+}
+
+func (e *WriteScopeError) Error() string {
+	if e == nil {
+		return ""
+	}
+	var msg string
+	switch e.Reason {
+	case WriteSymlinkEscape:
+		msg = fmt.Sprintf("security: write to %s blocked: symlink resolves to %s outside the allowed directories", e.Path, e.Target)
+	case WriteSymlink:
+		msg = fmt.Sprintf("security: write to %s blocked: path crosses a symlink", e.Path)
+	default:
+		msg = fmt.Sprintf("security: write to %s blocked: path not in sandbox allowlist", e.Path)
+	}
+	if len(e.Roots) > 0 {
+		msg += " (writable: " + strings.Join(e.Roots, ", ") + ")"
+	}
+	return msg
+}
+
+func (e *WriteScopeError) Unwrap() []error {
+	if e == nil {
+		return nil
+	}
+	errs := []error{ErrWriteOutsideScope}
+	if e.Reason == WriteOutsideRoots {
+		errs = append(errs, ErrPathNotAllowed)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// WriteScope confines writes to a project root plus additional directories.
+// Paths below a root are walked with the O_NOFOLLOW PathResolver, so a
+// symlink planted inside the project cannot redirect a write elsewhere.
+type WriteScope struct {
+	roots     []string
+	canonical []string
+	resolver  *PathResolver
+}
+
+// NewWriteScope allows writes under root and every additional directory.
+// Blank entries are ignored.
+func NewWriteScope(root string, additional ...string) *WriteScope {
+	w := &WriteScope{resolver: NewPathResolver()}
+	for _, dir := range append([]string{root}, additional...) {
+		if strings.TrimSpace(dir) == "" {
+			continue
+		}
+		clean := normalizePath(dir)
+		canonical := clean
+		if resolved, err := filepath.EvalSymlinks(clean); err == nil {
+			canonical = resolved
+		}
+		if slices.Contains(w.roots, clean) {
+			continue
+		}
+		w.roots = append(w.roots, clean)
+		w.canonical = append(w.canonical, canonical)
+	}
+	return w
+}
+
+// Roots returns the writable directories.
+func (w *WriteScope) Roots() []string {
+	if w == nil {
+		return nil
+	}
+	return append([]string(nil), w.roots...)
+}
+
+// Check returns a *WriteScopeError unless path lies under a writable root
+// without crossing a symlink. Relative paths resolve against the working
+// directory.
+func (w *WriteScope) Check(path string) error {
+	if w == nil {
+		return nil
+	}
+	if strings.TrimSpace(path) == "" {
+		return fmt.Errorf("security: empty path supplied")
+	}
+	abs := normalizePath(path)
+	for i, root := range w.roots {
+		for _, base := range []string{root, w.canonical[i]} {
+			rel, err := filepath.Rel(base, abs)
+			if err != nil || !withinSandbox(abs, base) {
+				continue
+			}
+			// The canonical root has no symlinks, so any the resolver
+			// finds lie below it.
+			return w.checkBelow(path, filepath.Join(w.canonical[i], rel))
+		}
+	}
+	return &WriteScopeError{Path: abs, Reason: WriteOutsideRoots, Roots: w.Roots()}
+}
+
+func (w *WriteScope) checkBelow(path, canonical string) error {
+	if _, err := w.resolver.Resolve(canonical); err != nil {
+		if !strings.Contains(err.Error(), "symlink") {
+			return fmt.Errorf("security: resolve failed: %w", err)
+		}
+		blocked := &WriteScopeError{Path: normalizePath(path), Reason: WriteSymlink, Roots: w.Roots(), Err: err}
+		if target, ok := w.resolveExisting(canonical); !ok {
+			blocked.Reason, blocked.Target = WriteSymlinkEscape, target
+		}
+		return blocked
+	}
+	return nil
+}
+
+// resolveExisting follows symlinks on the longest existing prefix of path
+// and reports whether the result stays under a canonical root. Dangling
+// links count as escapes.
+func (w *WriteScope) resolveExisting(path string) (string, bool) {
+	existing, rest := path, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path, false
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return existing, false
+	}
+	resolved = filepath.Join(resolved, rest)
+	for _, root := range w.canonical {
+		if withinSandbox(resolved, root) {
+			return resolved, true
+		}
+	}
+	return resolved, false
+}
+
+// WriteTargets lists the files a shell command visibly writes: output
+// redirections (>, >>, >|, &>, 2> ...), tee arguments, the destination of
+// cp, mv and install, the files sed -i edits and dd's of= operand. Targets
+// holding expansions ($, `, ~, globs) cannot be resolved statically and are
+// skipped, as are device files such as /dev/null, so this is a best-effort
+// check; the OS sandbox is the complete one. Unparseable commands yield nil.
+func WriteTargets(command string) []string {
+	tokens, err := splitCommand(command)
+	if err != nil {
+		return nil
+	}
+	var targets []string
+	add := func(target string) {
+		if target == "" || strings.HasPrefix(target, "&") || strings.HasPrefix(target, "/dev/") || strings.ContainsAny(target, "$`~*?[") {
+			return
+		}
+		targets = append(targets, target)
+	}
+	var argv []string
+	endCommand := func() {
+		for _, target := range commandWriteTargets(argv) {
+			add(target)
+		}
+		argv = argv[:0]
+	}
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if op, rest, ok := splitRedirect(tok); ok {
+			if rest == "" && i+1 < len(tokens) {
+				i++
+				rest = tokens[i]
+			}
+			if op != "" {
+				add(rest)
+			}
+			continue
+		}
+		switch tok {
+		case "|", ";", "&&", "||", "&":
+			endCommand()
+			continue
+		}
+		// A separator glued to the word ("a.txt;") ends the command too.
+		if trimmed := strings.TrimRight(tok, ";|&"); trimmed != tok {
+			if trimmed != "" {
+				argv = append(argv, trimmed)
+			}
+			endCommand()
+			continue
+		}
+		argv = append(argv, tok)
+	}
+	endCommand()
+	return targets
+}
+
+// writeWrappers run their operands as a command, e.g. sudo cp a b.
+var writeWrappers = map[string]bool{"sudo": true, "doas": true, "env": true, "nohup": true, "nice": true, "command": true, "time": true, "exec": true}
+
+// commandWriteTargets returns the files one simple command writes through
+// its arguments.
+func commandWriteTargets(argv []string) []string {
+	for len(argv) > 0 {
+		word := argv[0]
+		if strings.Contains(word, "=") && !strings.HasPrefix(word, "-") && !strings.Contains(word[:strings.Index(word, "=")], "/") {
+			argv = argv[1:] // VAR=value prefix
+			continue
+		}
+		if !writeWrappers[commandBase(word)] {
+			break
+		}
+		argv = argv[1:]
+		for len(argv) > 0 && strings.HasPrefix(argv[0], "-") {
+			argv = argv[1:]
+		}
+	}
+	if len(argv) == 0 {
+		return nil
+	}
+	name, args := commandBase(argv[0]), argv[1:]
+	switch name {
+	case "tee":
+		return operands(args)
+	case "cp", "mv", "install":
+		for i, arg := range args {
+			if arg == "-t" && i+1 < len(args) {
+				return []string{args[i+1]}
+			}
+			if dir, ok := strings.CutPrefix(arg, "--target-directory="); ok {
+				return []string{dir}
+			}
+		}
+		ops := operands(args)
+		if len(ops) < 2 {
+			return nil
+		}
+		return ops[len(ops)-1:]
+	case "sed", "gsed":
+		return sedInPlaceFiles(args)
+	case "dd":
+		var out []string
+		for _, arg := range args {
+			if path, ok := strings.CutPrefix(arg, "of="); ok {
+				out = append(out, path)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// operands returns the arguments that are not options, honouring "--".
+func operands(args []string) []string {
+	var out []string
+	for i, arg := range args {
+		if arg == "--" {
+			return append(out, args[i+1:]...)
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			out = append(out, arg)
+		}
+	}
+	return out
+}
+
+// sedInPlaceFiles returns the files sed edits when -i or --in-place is set.
+// The script is the first operand unless -e or -f supplies it.
+func sedInPlaceFiles(args []string) []string {
+	inPlace, scripted := false, false
+	var ops []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			ops = append(ops, args[i+1:]...)
+			i = len(args)
+		case arg == "-e" || arg == "-f" || arg == "--expression" || arg == "--file":
+			scripted = true
+			i++
+		case strings.HasPrefix(arg, "--expression=") || strings.HasPrefix(arg, "--file="):
+			scripted = true
+		case strings.HasPrefix(arg, "--in-place"):
+			inPlace = true
+		case strings.HasPrefix(arg, "--"):
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// Short options cluster ("-ni"); -i takes an optional glued
+			// suffix and -e/-f a script, which end the cluster.
+			for j := 1; j < len(arg); j++ {
+				c := arg[j]
+				if c == 'i' {
+					inPlace = true
+					break
+				}
+				if c == 'e' || c == 'f' {
+					scripted = true
+					if j == len(arg)-1 {
+						i++
+					}
+					break
+				}
+			}
+		default:
+			ops = append(ops, arg)
+		}
+	}
+	if !inPlace {
+		return nil
+	}
+	if !scripted && len(ops) > 0 {
+		ops = ops[1:]
+	}
+	return ops
+}
+
+// splitRedirect recognises an output redirection token such as ">", "2>>",
+// "&>" or ">out.txt". It returns the operator and any target glued to it.
+// Input redirections report ok with an empty operator so their operand is
+// skipped.
+func splitRedirect(tok string) (op, rest string, ok bool) {
+	i := 0
+	for i < len(tok) && tok[i] >= '0' && tok[i] <= '9' {
+		i++
+	}
+	if i == 0 && strings.HasPrefix(tok, "&>") {
+		i = 1
+	}
+	switch {
+	case strings.HasPrefix(tok[i:], ">>"), strings.HasPrefix(tok[i:], ">|"):
+		return tok[:i+2], tok[i+2:], true
+	case strings.HasPrefix(tok[i:], ">"):
+		return tok[:i+1], tok[i+1:], true
+	case strings.HasPrefix(tok[i:], "<"):
+		return "", strings.TrimLeft(tok[i:], "<"), true
+	}
+	return "", "", false
+}
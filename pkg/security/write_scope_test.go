@@ -0,0 +1,103 @@
+package security
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteScopeCheck(t *testing.T) {
+	root := tempDirClean(t)
+	extra := tempDirClean(t)
+	outside := tempDirClean(t)
+	if err := os.Mkdir(filepath.Join(root, "pkg"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	mustSymlink(t, outside, filepath.Join(root, "escape"))
+	mustSymlink(t, filepath.Join(root, "pkg"), filepath.Join(root, "alias"))
+
+	scope := NewWriteScope(root, extra, " ")
+	if got := scope.Roots(); !slices.Equal(got, []string{root, extra}) {
+		t.Fatalf("unexpected roots %v", got)
+	}
+	for _, ok := range []string{filepath.Join(root, "pkg", "new.go"), filepath.Join(extra, "a", "b.txt"), root} {
+		if err := scope.Check(ok); err != nil {
+			t.Fatalf("expected %s to be writable: %v", ok, err)
+		}
+	}
+
+	cases := []struct {
+		path   string
+		reason WriteBlockReason
+	}{
+		{filepath.Join(outside, "x"), WriteOutsideRoots},
+		{filepath.Join(root, "escape", "x"), WriteSymlinkEscape},
+		{filepath.Join(root, "alias", "x"), WriteSymlink},
+	}
+	for _, tc := range cases {
+		err := scope.Check(tc.path)
+		var blocked *WriteScopeError
+		if !errors.As(err, &blocked) || blocked.Reason != tc.reason {
+			t.Fatalf("%s: expected %s, got %v", tc.path, tc.reason, err)
+		}
+		if !errors.Is(err, ErrWriteOutsideScope) || !slices.Equal(blocked.Roots, []string{root, extra}) {
+			t.Fatalf("%s: unexpected error %#v", tc.path, blocked)
+		}
+	}
+	err := scope.Check(filepath.Join(root, "escape", "x"))
+	var blocked *WriteScopeError
+	if !errors.As(err, &blocked) || blocked.Target != filepath.Join(outside, "x") {
+		t.Fatalf("expected escape target, got %v", err)
+	}
+	if !errors.Is(scope.Check(filepath.Join(outside, "x")), ErrPathNotAllowed) {
+		t.Fatal("writes outside the roots should match ErrPathNotAllowed")
+	}
+}
+
+func TestSandboxValidateWrite(t *testing.T) {
+	root := tempDirClean(t)
+	extra := tempDirClean(t)
+	sb := NewSandbox(root)
+	if err := sb.ValidateWrite(filepath.Join(extra, "f")); !errors.Is(err, ErrWriteOutsideScope) {
+		t.Fatalf("expected blocked write, got %v", err)
+	}
+	sb.Allow(extra)
+	if err := sb.ValidateWrite(filepath.Join(extra, "f")); err != nil {
+		t.Fatalf("allowed directory should be writable: %v", err)
+	}
+	if err := NewDisabledSandbox().ValidateWrite("/etc/passwd"); err != nil {
+		t.Fatalf("disabled sandbox should not check writes: %v", err)
+	}
+}
+
+func TestWriteTargets(t *testing.T) {
+	cases := []struct {
+		command string
+		want    []string
+	}{
+		{"echo hi > out.txt", []string{"out.txt"}},
+		{"make 2>>build.log >/tmp/x", []string{"build.log", "/tmp/x"}},
+		{"cmd &> all.log 2>&1", []string{"all.log"}},
+		{"sort < in.txt >| sorted.txt", []string{"sorted.txt"}},
+		{"go test | tee -a report.txt ../copy.txt | grep FAIL", []string{"report.txt", "../copy.txt"}},
+		{"echo x > $HOME/f > ~/g > *.txt 2>/dev/null", nil},
+		{"ls -la", nil},
+		{"cp -r src/ dst/ && mv a.txt b.txt", []string{"dst/", "b.txt"}},
+		{"sudo install -m 0644 -t /etc/app conf.yml", []string{"/etc/app"}},
+		{"cp --target-directory=out a b", []string{"out"}},
+		{"sed -i.bak 's/a/b/' x.txt y.txt", []string{"x.txt", "y.txt"}},
+		{"sed -ni -e p -- z.txt", []string{"z.txt"}},
+		{"sed 's/a/b/' x.txt", nil},
+		{"dd if=/dev/zero of=disk.img bs=1M", []string{"disk.img"}},
+		{"FOO=1 cp a.txt .env;", []string{".env"}},
+		{"cat a.txt | tee out.txt", []string{"out.txt"}},
+		{"echo 'unterminated", nil},
+	}
+	for _, tc := range cases {
+		if got := WriteTargets(tc.command); !slices.Equal(got, tc.want) {
+			t.Fatalf("%q: got %v want %v", tc.command, got, tc.want)
+		}
+	}
+}
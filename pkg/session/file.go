@@ -0,0 +1,209 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+// fileExt is the suffix of FileStore snapshots.
+const fileExt = ".session.json"
+
+// FileStore keeps one JSON snapshot per session in a directory. File names
+// encode the session ID, so distinct IDs never collide.
+type FileStore struct {
+	dir    string
+	now    func() time.Time
+	sealer Sealer
+	mu     sync.Mutex
+}
+
+// NewFileStore returns a store rooted at dir, creating it on first save.
+func NewFileStore(dir string) (*FileStore, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, errors.New("session: file store dir is empty")
+	}
+	return &FileStore{dir: dir, now: time.Now}, nil
+}
+
+// SetSealer implements Sealable.
+func (s *FileStore) SetSealer(sealer Sealer) { s.sealer = sealer }
+
+func (s *FileStore) path(sessionID string) string {
+	return filepath.Join(s.dir, base64.RawURLEncoding.EncodeToString([]byte(sessionID))+fileExt)
+}
+
+// Load implements Store.
+func (s *FileStore) Load(_ context.Context, sessionID string) ([]message.Message, error) {
+	id, err := normalizeID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("session: read %q: %w", id, err)
+	}
+	if s.sealer != nil {
+		if data, err = s.sealer.Open(data); err != nil {
+			return nil, fmt.Errorf("session: open %q: %w", id, err)
+		}
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("session: decode %q: %w", id, err)
+	}
+	return message.CloneMessages(snap.Messages), nil
+}
+
+// Save implements Store. Snapshots are written atomically.
+func (s *FileStore) Save(_ context.Context, sessionID string, msgs []message.Message) error {
+	id, err := normalizeID(sessionID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(Snapshot{
+		Version:   snapshotVersion,
+		SessionID: id,
+		UpdatedAt: s.now().UTC(),
+		Messages:  message.CloneMessages(msgs),
+	})
+	if err != nil {
+		return fmt.Errorf("session: encode %q: %w", id, err)
+	}
+	if s.sealer != nil {
+		if data, err = s.sealer.Seal(data); err != nil {
+			return fmt.Errorf("session: seal %q: %w", id, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("session: mkdir: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".session-*.tmp")
+	if err != nil {
+		return fmt.Errorf("session: create temp: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("session: write temp: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("session: close temp: %w", err)
+	}
+	path := s.path(id)
+	if err := os.Rename(tmpPath, path); err != nil {
+		// Windows can't rename over an existing file.
+		_ = os.Remove(path)
+		if retry := os.Rename(tmpPath, path); retry != nil {
+			return fmt.Errorf("session: rename: %w", retry)
+		}
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(_ context.Context, sessionID string) (bool, error) {
+	id, err := normalizeID(sessionID)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(id)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("session: delete %q: %w", id, err)
+	}
+	return true, nil
+}
+
+// List implements Store.
+func (s *FileStore) List(context.Context) ([]string, error) {
+	entries, err := s.entries()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Cleanup implements Store using file modification times.
+func (s *FileStore) Cleanup(_ context.Context, cutoff time.Time) (int, error) {
+	entries, err := s.entries()
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	var errs []error
+	for _, e := range entries {
+		if !e.modTime.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
+
+type fileEntry struct {
+	id      string
+	path    string
+	modTime time.Time
+}
+
+func (s *FileStore) entries() ([]fileEntry, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("session: read dir: %w", err)
+	}
+	var out []fileEntry
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, fileExt) {
+			continue
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(strings.TrimSuffix(name, fileExt))
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, fileEntry{id: string(raw), path: filepath.Join(s.dir, name), modTime: info.ModTime()})
+	}
+	return out, nil
+}
@@ -0,0 +1,485 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+// ErrConflict is returned by RedisStore.Save when another writer updated the
+// session since this process last loaded or saved it.
+var ErrConflict = errors.New("session: concurrent update")
+
+const (
+	defaultRedisPrefix      = "agentsdk:session:"
+	defaultRedisDialTimeout = 5 * time.Second
+	defaultRedisMaxIdle     = 8
+	redisScanCount          = "200"
+)
+
+// RedisOptions configures RedisStore.
+type RedisOptions struct {
+	// Addr is host:port of the Redis server.
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	// Prefix namespaces session keys; empty uses "agentsdk:session:".
+	Prefix string
+	// TTL expires sessions that were not saved for this long; zero keeps
+	// them until Cleanup or Delete.
+	TTL time.Duration
+	// DialTimeout bounds connection setup; zero uses 5s.
+	DialTimeout time.Duration
+	// MaxIdle bounds pooled idle connections; zero uses 8.
+	MaxIdle int
+	// Dial replaces net.Dialer, e.g. for TLS.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// RedisStore keeps each session in a Redis hash so every replica of a
+// deployment can resume it. Writes use WATCH/MULTI/EXEC against a per-session
+// version: a Save from a replica holding a stale copy fails with ErrConflict
+// instead of overwriting newer history. The store speaks RESP directly, so
+// no client library is required.
+type RedisStore struct {
+	opts   RedisOptions
+	now    func() time.Time
+	sealer Sealer
+
+	mu       sync.Mutex
+	idle     []*redisConn
+	versions map[string]int64
+}
+
+// NewRedisStore returns a store for opts.Addr and verifies the connection.
+func NewRedisStore(ctx context.Context, opts RedisOptions) (*RedisStore, error) {
+	if strings.TrimSpace(opts.Addr) == "" {
+		return nil, errors.New("session: redis addr is empty")
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = defaultRedisPrefix
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = defaultRedisDialTimeout
+	}
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = defaultRedisMaxIdle
+	}
+	s := &RedisStore{opts: opts, now: time.Now, versions: map[string]int64{}}
+	conn, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.do("PING"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("session: redis ping: %w", err)
+	}
+	s.put(conn)
+	return s, nil
+}
+
+// Close releases pooled connections.
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	idle := s.idle
+	s.idle = nil
+	s.mu.Unlock()
+	for _, c := range idle {
+		c.Close()
+	}
+	return nil
+}
+
+// SetSealer implements Sealable. Sealed payloads are stored base64-encoded.
+func (s *RedisStore) SetSealer(sealer Sealer) { s.sealer = sealer }
+
+func (s *RedisStore) key(id string) string { return s.opts.Prefix + id }
+
+// Load implements Store and remembers the version for the next Save.
+func (s *RedisStore) Load(ctx context.Context, sessionID string) ([]message.Message, error) {
+	id, err := normalizeID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := s.do(ctx, "HMGET", s.key(id), "version", "messages")
+	if err != nil {
+		return nil, fmt.Errorf("session: load %q: %w", id, err)
+	}
+	fields, _ := reply.([]any)
+	if len(fields) != 2 || fields[0] == nil {
+		s.setVersion(id, 0)
+		return nil, nil
+	}
+	version, err := strconv.ParseInt(asString(fields[0]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("session: load %q: bad version: %w", id, err)
+	}
+	data, err := openText(s.sealer, asString(fields[1]))
+	if err != nil {
+		return nil, fmt.Errorf("session: open %q: %w", id, err)
+	}
+	var msgs []message.Message
+	if err := json.Unmarshal(data, &msgs); err != nil {
+		return nil, fmt.Errorf("session: decode %q: %w", id, err)
+	}
+	s.setVersion(id, version)
+	return msgs, nil
+}
+
+// Save implements Store. It fails with ErrConflict when the stored version
+// differs from the one this process last loaded or saved, and keeps failing
+// until Load refreshes it; sessions never loaded here are written
+// unconditionally.
+func (s *RedisStore) Save(ctx context.Context, sessionID string, msgs []message.Message) error {
+	id, err := normalizeID(sessionID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(msgs)
+	if err != nil {
+		return fmt.Errorf("session: encode %q: %w", id, err)
+	}
+	payload, err := sealText(s.sealer, data)
+	if err != nil {
+		return fmt.Errorf("session: seal %q: %w", id, err)
+	}
+	s.mu.Lock()
+	expected, known := s.versions[id]
+	s.mu.Unlock()
+
+	conn, err := s.get(ctx)
+	if err != nil {
+		return err
+	}
+	version, err := s.save(conn, s.key(id), payload, expected, known)
+	if err != nil && !errors.Is(err, ErrConflict) {
+		conn.Close()
+		return fmt.Errorf("session: save %q: %w", id, err)
+	}
+	s.put(conn)
+	if err != nil {
+		// Keep the stale version so later saves fail too until Load
+		// picks up the newer history.
+		return fmt.Errorf("session: save %q: %w", id, err)
+	}
+	s.setVersion(id, version)
+	return nil
+}
+
+func (s *RedisStore) save(conn *redisConn, key, data string, expected int64, known bool) (int64, error) {
+	if _, err := conn.do("WATCH", key); err != nil {
+		return 0, err
+	}
+	reply, err := conn.do("HGET", key, "version")
+	if err != nil {
+		return 0, err
+	}
+	current := int64(0)
+	if reply != nil {
+		if current, err = strconv.ParseInt(asString(reply), 10, 64); err != nil {
+			return 0, fmt.Errorf("bad version: %w", err)
+		}
+	}
+	if known && current != expected {
+		if _, err := conn.do("UNWATCH"); err != nil {
+			return 0, err
+		}
+		return 0, ErrConflict
+	}
+	next := current + 1
+	cmds := [][]string{
+		{"MULTI"},
+		{"HSET", key, "version", strconv.FormatInt(next, 10), "messages", data, "updated_at", strconv.FormatInt(s.now().UnixMilli(), 10)},
+	}
+	if s.opts.TTL > 0 {
+		cmds = append(cmds, []string{"PEXPIRE", key, strconv.FormatInt(s.opts.TTL.Milliseconds(), 10)})
+	}
+	for _, cmd := range cmds {
+		if _, err := conn.do(cmd...); err != nil {
+			return 0, err
+		}
+	}
+	reply, err = conn.do("EXEC")
+	if err != nil {
+		return 0, err
+	}
+	if reply == nil {
+		// A watched key changed between WATCH and EXEC.
+		return 0, ErrConflict
+	}
+	return next, nil
+}
+
+// Delete implements Store.
+func (s *RedisStore) Delete(ctx context.Context, sessionID string) (bool, error) {
+	id, err := normalizeID(sessionID)
+	if err != nil {
+		return false, err
+	}
+	reply, err := s.do(ctx, "DEL", s.key(id))
+	if err != nil {
+		return false, fmt.Errorf("session: delete %q: %w", id, err)
+	}
+	s.forgetVersion(id)
+	n, _ := reply.(int64)
+	return n > 0, nil
+}
+
+// List implements Store.
+func (s *RedisStore) List(ctx context.Context) ([]string, error) {
+	keys, err := s.scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("session: list: %w", err)
+	}
+	ids := make([]string, 0, len(keys))
+	for _, k := range keys {
+		ids = append(ids, strings.TrimPrefix(k, s.opts.Prefix))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Cleanup implements Store. Sessions with a TTL also expire on their own.
+func (s *RedisStore) Cleanup(ctx context.Context, cutoff time.Time) (int, error) {
+	keys, err := s.scan(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("session: cleanup: %w", err)
+	}
+	removed := 0
+	for _, key := range keys {
+		reply, err := s.do(ctx, "HGET", key, "updated_at")
+		if err != nil {
+			return removed, fmt.Errorf("session: cleanup: %w", err)
+		}
+		ms, err := strconv.ParseInt(asString(reply), 10, 64)
+		if err != nil || !time.UnixMilli(ms).Before(cutoff) {
+			continue
+		}
+		if _, err := s.do(ctx, "DEL", key); err != nil {
+			return removed, fmt.Errorf("session: cleanup: %w", err)
+		}
+		s.forgetVersion(strings.TrimPrefix(key, s.opts.Prefix))
+		removed++
+	}
+	return removed, nil
+}
+
+func (s *RedisStore) scan(ctx context.Context) ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := s.do(ctx, "SCAN", cursor, "MATCH", escapeGlob(s.opts.Prefix)+"*", "COUNT", redisScanCount)
+		if err != nil {
+			return nil, err
+		}
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 2 {
+			return nil, errors.New("unexpected SCAN reply")
+		}
+		cursor = asString(parts[0])
+		batch, _ := parts[1].([]any)
+		for _, k := range batch {
+			keys = append(keys, asString(k))
+		}
+		if cursor == "0" {
+			return keys, nil
+		}
+	}
+}
+
+func (s *RedisStore) setVersion(id string, v int64) {
+	s.mu.Lock()
+	s.versions[id] = v
+	s.mu.Unlock()
+}
+
+func (s *RedisStore) forgetVersion(id string) {
+	s.mu.Lock()
+	delete(s.versions, id)
+	s.mu.Unlock()
+}
+
+// do runs one command on a pooled connection.
+func (s *RedisStore) do(ctx context.Context, args ...string) (any, error) {
+	conn, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		conn.Close()
+		return nil, err
+	}
+	s.put(conn)
+	return reply, err
+}
+
+func (s *RedisStore) get(ctx context.Context) (*redisConn, error) {
+	s.mu.Lock()
+	if n := len(s.idle); n > 0 {
+		c := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		c.setDeadline(ctx)
+		return c, nil
+	}
+	s.mu.Unlock()
+
+	dial := s.opts.Dial
+	if dial == nil {
+		d := &net.Dialer{Timeout: s.opts.DialTimeout}
+		dial = d.DialContext
+	}
+	nc, err := dial(ctx, "tcp", s.opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("session: redis dial: %w", err)
+	}
+	c := &redisConn{conn: nc, r: bufio.NewReader(nc)}
+	c.setDeadline(ctx)
+	if s.opts.Password != "" {
+		args := []string{"AUTH", s.opts.Password}
+		if s.opts.Username != "" {
+			args = []string{"AUTH", s.opts.Username, s.opts.Password}
+		}
+		if _, err := c.do(args...); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("session: redis auth: %w", err)
+		}
+	}
+	if s.opts.DB != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(s.opts.DB)); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("session: redis select: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (s *RedisStore) put(c *redisConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.idle) >= s.opts.MaxIdle {
+		c.Close()
+		return
+	}
+	s.idle = append(s.idle, c)
+}
+
+// redisError is an error reply from the server; the connection stays usable.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisConn is a minimal RESP2 connection.
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *redisConn) Close() { _ = c.conn.Close() }
+
+func (c *redisConn) setDeadline(ctx context.Context) {
+	deadline, _ := ctx.Deadline()
+	_ = c.conn.SetDeadline(deadline)
+}
+
+func (c *redisConn) do(args ...string) (any, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		return nil, err
+	}
+	return readRESP(c.r)
+}
+
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	body := line[1:]
+	switch line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		out := make([]any, n)
+		for i := range out {
+			if out[i], err = readRESP(r); err != nil {
+				var redisErr redisError
+				if !errors.As(err, &redisErr) {
+					return nil, err
+				}
+				out[i] = err
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+func asString(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case int64:
+		return strconv.FormatInt(t, 10)
+	}
+	return ""
+}
+
+// escapeGlob quotes glob metacharacters in a SCAN MATCH pattern.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,258 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+func TestRedisStore(t *testing.T) {
+	srv := newFakeRedis(t)
+	ctx := context.Background()
+	newStore := func() *RedisStore {
+		s, err := NewRedisStore(ctx, RedisOptions{Addr: srv.addr, Password: "secret", DB: 2, TTL: time.Hour})
+		if err != nil {
+			t.Fatalf("NewRedisStore: %v", err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		return s
+	}
+	a, b := newStore(), newStore()
+
+	if msgs, err := a.Load(ctx, "s1"); err != nil || msgs != nil {
+		t.Fatalf("missing session: %v %v", msgs, err)
+	}
+	if err := a.Save(ctx, "s1", []message.Message{{Role: "user", Content: "hi"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if ttl := srv.ttl(defaultRedisPrefix + "s1"); ttl != time.Hour {
+		t.Fatalf("ttl %v", ttl)
+	}
+
+	// Replica b resumes the session and writes a newer version.
+	msgs, err := b.Load(ctx, "s1")
+	if err != nil || len(msgs) != 1 || msgs[0].Content != "hi" {
+		t.Fatalf("replica load %+v err=%v", msgs, err)
+	}
+	if err := b.Save(ctx, "s1", append(msgs, message.Message{Role: "assistant", Content: "hello"})); err != nil {
+		t.Fatalf("replica save: %v", err)
+	}
+	// a still holds version 1 and must not clobber b's write.
+	if err := a.Save(ctx, "s1", []message.Message{{Role: "user", Content: "stale"}}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	// Retrying without a reload must not overwrite either.
+	if err := a.Save(ctx, "s1", []message.Message{{Role: "user", Content: "stale"}}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict on retry, got %v", err)
+	}
+	if msgs, _ := a.Load(ctx, "s1"); len(msgs) != 2 {
+		t.Fatalf("history clobbered: %+v", msgs)
+	}
+	if err := a.Save(ctx, "s1", []message.Message{{Role: "user", Content: "fresh"}}); err != nil {
+		t.Fatalf("save after reload: %v", err)
+	}
+
+	if err := a.Save(ctx, "old", nil); err != nil {
+		t.Fatal(err)
+	}
+	if ids, err := a.List(ctx); err != nil || strings.Join(ids, ",") != "old,s1" {
+		t.Fatalf("list %v err=%v", ids, err)
+	}
+	a.now = func() time.Time { return time.Now().Add(time.Minute) }
+	if err := a.Save(ctx, "s1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := a.Cleanup(ctx, time.Now().Add(time.Second)); err != nil || n != 1 {
+		t.Fatalf("cleanup n=%d err=%v", n, err)
+	}
+	if ok, err := a.Delete(ctx, "s1"); err != nil || !ok {
+		t.Fatalf("delete ok=%v err=%v", ok, err)
+	}
+	if ids, _ := a.List(ctx); len(ids) != 0 {
+		t.Fatalf("expected empty, got %v", ids)
+	}
+
+	if _, err := NewRedisStore(ctx, RedisOptions{Addr: srv.addr, Password: "wrong"}); err == nil {
+		t.Fatal("expected auth error")
+	}
+}
+
+// fakeRedis implements the handful of commands RedisStore uses.
+type fakeRedis struct {
+	addr string
+	mu   sync.Mutex
+	data map[string]map[string]string
+	ttls map[string]time.Duration
+	mods map[string]int
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	f := &fakeRedis{addr: ln.Addr().String(), data: map[string]map[string]string{}, ttls: map[string]time.Duration{}, mods: map[string]int{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) ttl(key string) time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ttls[key]
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	watched := map[string]int{}
+	var queue [][]string
+	inMulti := false
+	for {
+		reply, err := readRESP(r)
+		if err != nil {
+			return
+		}
+		parts, _ := reply.([]any)
+		args := make([]string, len(parts))
+		for i, p := range parts {
+			args[i] = asString(p)
+		}
+		cmd := strings.ToUpper(args[0])
+		var out string
+		switch {
+		case inMulti && cmd != "EXEC":
+			queue = append(queue, args)
+			out = "+QUEUED\r\n"
+		case cmd == "MULTI":
+			inMulti = true
+			out = "+OK\r\n"
+		case cmd == "EXEC":
+			inMulti = false
+			f.mu.Lock()
+			ok := true
+			for k, v := range watched {
+				if f.mods[k] != v {
+					ok = false
+				}
+			}
+			f.mu.Unlock()
+			watched = map[string]int{}
+			if !ok {
+				queue = nil
+				out = "*-1\r\n"
+				break
+			}
+			out = fmt.Sprintf("*%d\r\n", len(queue))
+			for _, q := range queue {
+				out += f.exec(q)
+			}
+			queue = nil
+		case cmd == "WATCH":
+			f.mu.Lock()
+			watched[args[1]] = f.mods[args[1]]
+			f.mu.Unlock()
+			out = "+OK\r\n"
+		case cmd == "UNWATCH":
+			watched = map[string]int{}
+			out = "+OK\r\n"
+		default:
+			out = f.exec(args)
+		}
+		if _, err := conn.Write([]byte(out)); err != nil {
+			return
+		}
+	}
+}
+
+func bulk(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		if args[len(args)-1] != "secret" {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "HGET":
+		v, ok := f.data[args[1]][args[2]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "HMGET":
+		out := fmt.Sprintf("*%d\r\n", len(args)-2)
+		for _, field := range args[2:] {
+			if v, ok := f.data[args[1]][field]; ok {
+				out += bulk(v)
+			} else {
+				out += "$-1\r\n"
+			}
+		}
+		return out
+	case "HSET":
+		h := f.data[args[1]]
+		if h == nil {
+			h = map[string]string{}
+			f.data[args[1]] = h
+		}
+		for i := 2; i+1 < len(args); i += 2 {
+			h[args[i]] = args[i+1]
+		}
+		f.mods[args[1]]++
+		return ":1\r\n"
+	case "PEXPIRE":
+		ms, _ := strconv.Atoi(args[2])
+		f.ttls[args[1]] = time.Duration(ms) * time.Millisecond
+		return ":1\r\n"
+	case "DEL":
+		n := 0
+		for _, k := range args[1:] {
+			if _, ok := f.data[k]; ok {
+				delete(f.data, k)
+				f.mods[k]++
+				n++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	case "SCAN":
+		pattern := strings.ReplaceAll(args[3], `\`, "")
+		var keys []string
+		for k := range f.data {
+			if ok, _ := path.Match(pattern, k); ok {
+				keys = append(keys, k)
+			}
+		}
+		out := "*2\r\n" + bulk("0") + fmt.Sprintf("*%d\r\n", len(keys))
+		for _, k := range keys {
+			out += bulk(k)
+		}
+		return out
+	}
+	return "-ERR unknown command\r\n"
+}
@@ -0,0 +1,159 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+// defaultSQLiteTable stores sessions when SQLiteOptions.Table is empty.
+const defaultSQLiteTable = "agent_sessions"
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLiteOptions configures SQLiteStore.
+type SQLiteOptions struct {
+	// Table names the sessions table; empty uses "agent_sessions".
+	Table string
+}
+
+// SQLiteStore keeps sessions in one SQLite table. The caller opens the
+// *sql.DB with the driver of its choice (e.g. modernc.org/sqlite or
+// github.com/mattn/go-sqlite3), so the SDK does not pull in cgo or a driver.
+type SQLiteStore struct {
+	db     *sql.DB
+	table  string
+	now    func() time.Time
+	sealer Sealer
+}
+
+// NewSQLiteStore creates the sessions table if needed and returns the store.
+func NewSQLiteStore(ctx context.Context, db *sql.DB, opts SQLiteOptions) (*SQLiteStore, error) {
+	if db == nil {
+		return nil, errors.New("session: sqlite db is nil")
+	}
+	table := strings.TrimSpace(opts.Table)
+	if table == "" {
+		table = defaultSQLiteTable
+	}
+	if !tableNamePattern.MatchString(table) {
+		return nil, fmt.Errorf("session: invalid table name %q", table)
+	}
+	ddl := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	messages TEXT NOT NULL,
+	updated_at INTEGER NOT NULL
+)`, table)
+	if _, err := db.ExecContext(ctx, ddl); err != nil {
+		return nil, fmt.Errorf("session: create table: %w", err)
+	}
+	return &SQLiteStore{db: db, table: table, now: time.Now}, nil
+}
+
+// SetSealer implements Sealable. Sealed payloads are stored base64-encoded.
+func (s *SQLiteStore) SetSealer(sealer Sealer) { s.sealer = sealer }
+
+// Load implements Store.
+func (s *SQLiteStore) Load(ctx context.Context, sessionID string) ([]message.Message, error) {
+	id, err := normalizeID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var raw string
+	err = s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT messages FROM %s WHERE id = ?", s.table), id).Scan(&raw)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("session: load %q: %w", id, err)
+	}
+	data, err := openText(s.sealer, raw)
+	if err != nil {
+		return nil, fmt.Errorf("session: open %q: %w", id, err)
+	}
+	var msgs []message.Message
+	if err := json.Unmarshal(data, &msgs); err != nil {
+		return nil, fmt.Errorf("session: decode %q: %w", id, err)
+	}
+	return msgs, nil
+}
+
+// Save implements Store.
+func (s *SQLiteStore) Save(ctx context.Context, sessionID string, msgs []message.Message) error {
+	id, err := normalizeID(sessionID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(msgs)
+	if err != nil {
+		return fmt.Errorf("session: encode %q: %w", id, err)
+	}
+	payload, err := sealText(s.sealer, data)
+	if err != nil {
+		return fmt.Errorf("session: seal %q: %w", id, err)
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (id, messages, updated_at) VALUES (?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET messages = excluded.messages, updated_at = excluded.updated_at`, s.table)
+	if _, err := s.db.ExecContext(ctx, query, id, payload, s.now().UnixMilli()); err != nil {
+		return fmt.Errorf("session: save %q: %w", id, err)
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *SQLiteStore) Delete(ctx context.Context, sessionID string) (bool, error) {
+	id, err := normalizeID(sessionID)
+	if err != nil {
+		return false, err
+	}
+	res, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ?", s.table), id)
+	if err != nil {
+		return false, fmt.Errorf("session: delete %q: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("session: delete %q: %w", id, err)
+	}
+	return n > 0, nil
+}
+
+// List implements Store.
+func (s *SQLiteStore) List(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT id FROM %s ORDER BY id", s.table))
+	if err != nil {
+		return nil, fmt.Errorf("session: list: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("session: list: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("session: list: %w", err)
+	}
+	return ids, nil
+}
+
+// Cleanup implements Store.
+func (s *SQLiteStore) Cleanup(ctx context.Context, cutoff time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE updated_at < ?", s.table), cutoff.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("session: cleanup: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("session: cleanup: %w", err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,96 @@
+// Package session persists conversation history so a SessionID resumes
+// across process restarts. A Store is plugged into api.Options.SessionStore;
+// FileStore, SQLiteStore and RedisStore are the bundled backends.
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+// ErrInvalidSessionID is returned for empty session identifiers.
+var ErrInvalidSessionID = errors.New("session: invalid session id")
+
+// Store loads and saves the message history of a session. Implementations
+// must be safe for concurrent use.
+type Store interface {
+	// Load returns the saved history, or nil and no error when the session
+	// is unknown.
+	Load(ctx context.Context, sessionID string) ([]message.Message, error)
+	// Save replaces the saved history of the session.
+	Save(ctx context.Context, sessionID string, msgs []message.Message) error
+	// Delete removes the session and reports whether it existed.
+	Delete(ctx context.Context, sessionID string) (bool, error)
+	// List returns the saved session IDs.
+	List(ctx context.Context) ([]string, error)
+	// Cleanup removes sessions last saved before cutoff and returns how many
+	// were removed.
+	Cleanup(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// Sealer transforms encoded history before a store writes it and after it
+// reads it back, e.g. to compress and encrypt it at rest. Open must accept
+// plain JSON written before a Sealer was set.
+type Sealer interface {
+	Seal(data []byte) ([]byte, error)
+	Open(data []byte) ([]byte, error)
+}
+
+// Sealable is implemented by stores that can pass their payloads through a
+// Sealer; FileStore, SQLiteStore and RedisStore all do. api.New installs one
+// built from Options.Compression and Options.Encryption. SetSealer must be
+// called before the store is used.
+type Sealable interface {
+	SetSealer(sealer Sealer)
+}
+
+// sealText seals data for stores with text columns, base64-encoding the
+// sealed bytes.
+func sealText(sealer Sealer, data []byte) (string, error) {
+	if sealer == nil {
+		return string(data), nil
+	}
+	sealed, err := sealer.Seal(data)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openText reverses sealText. Plain JSON, written before a Sealer was set,
+// is returned as is.
+func openText(sealer Sealer, raw string) ([]byte, error) {
+	if sealer == nil || json.Valid([]byte(raw)) {
+		return []byte(raw), nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	return sealer.Open(sealed)
+}
+
+// Snapshot is the serialized form of a saved session.
+type Snapshot struct {
+	Version   int               `json:"version"`
+	SessionID string            `json:"session_id,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at,omitempty"`
+	Messages  []message.Message `json:"messages,omitempty"`
+}
+
+// snapshotVersion is written into every Snapshot.
+const snapshotVersion = 1
+
+func normalizeID(sessionID string) (string, error) {
+	id := strings.TrimSpace(sessionID)
+	if id == "" {
+		return "", ErrInvalidSessionID
+	}
+	return id, nil
+}
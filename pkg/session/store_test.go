@@ -0,0 +1,286 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/message"
+)
+
+var (
+	_ Store = (*FileStore)(nil)
+	_ Store = (*SQLiteStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+	backends := map[string]func(t *testing.T) (Store, func(time.Time)){
+		"file": func(t *testing.T) (Store, func(time.Time)) {
+			s, err := NewFileStore(filepath.Join(t.TempDir(), "sessions"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s, func(at time.Time) { s.now = func() time.Time { return at } }
+		},
+		"sqlite": func(t *testing.T) (Store, func(time.Time)) {
+			db, err := sql.Open(fakeDriverName, t.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = db.Close() })
+			s, err := NewSQLiteStore(ctx, db, SQLiteOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s, func(at time.Time) { s.now = func() time.Time { return at } }
+		},
+	}
+	for name, build := range backends {
+		t.Run(name, func(t *testing.T) {
+			store, setNow := build(t)
+			if msgs, err := store.Load(ctx, "missing"); err != nil || msgs != nil {
+				t.Fatalf("missing session: %v %v", msgs, err)
+			}
+			if err := store.Save(ctx, " ", nil); !errors.Is(err, ErrInvalidSessionID) {
+				t.Fatalf("expected ErrInvalidSessionID, got %v", err)
+			}
+
+			old := time.Now().Add(-48 * time.Hour)
+			setNow(old)
+			if err := store.Save(ctx, "tenant:a/1", []message.Message{{Role: "user", Content: "hi"}}); err != nil {
+				t.Fatal(err)
+			}
+			if fs, ok := store.(*FileStore); ok {
+				// FileStore ages sessions by modification time.
+				path := fs.path("tenant:a/1")
+				if err := os.Chtimes(path, old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+			setNow(time.Now())
+			want := []message.Message{{Role: "user", Content: "hello"}, {Role: "assistant", Content: "hey", ToolCalls: []message.ToolCall{{ID: "t1", Name: "bash"}}}}
+			if err := store.Save(ctx, "tenant-a-1", want); err != nil {
+				t.Fatal(err)
+			}
+			got, err := store.Load(ctx, "tenant-a-1")
+			if err != nil || len(got) != 2 || got[1].ToolCalls[0].Name != "bash" {
+				t.Fatalf("load %+v err=%v", got, err)
+			}
+			if ids, err := store.List(ctx); err != nil || strings.Join(ids, ",") != "tenant-a-1,tenant:a/1" {
+				t.Fatalf("list %v err=%v", ids, err)
+			}
+
+			if n, err := store.Cleanup(ctx, time.Now().Add(-24*time.Hour)); err != nil || n != 1 {
+				t.Fatalf("cleanup n=%d err=%v", n, err)
+			}
+			if ok, err := store.Delete(ctx, "tenant-a-1"); err != nil || !ok {
+				t.Fatalf("delete ok=%v err=%v", ok, err)
+			}
+			if ok, _ := store.Delete(ctx, "tenant-a-1"); ok {
+				t.Fatal("second delete should report missing")
+			}
+			if ids, _ := store.List(ctx); len(ids) != 0 {
+				t.Fatalf("expected empty store, got %v", ids)
+			}
+		})
+	}
+}
+
+// xorSealer flips every byte; Open passes plain JSON through.
+type xorSealer struct{}
+
+func (xorSealer) Seal(data []byte) ([]byte, error) {
+	out := make([]byte, len(data))
+	for i, c := range data {
+		out[i] = c ^ 0xff
+	}
+	return out, nil
+}
+
+func (x xorSealer) Open(data []byte) ([]byte, error) {
+	if json.Valid(data) {
+		return data, nil
+	}
+	return x.Seal(data)
+}
+
+func TestStoresSealPayloads(t *testing.T) {
+	ctx := context.Background()
+	fileStore, err := NewFileStore(filepath.Join(t.TempDir(), "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open(fakeDriverName, t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	sqliteStore, err := NewSQLiteStore(ctx, db, SQLiteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, store := range map[string]interface {
+		Store
+		Sealable
+	}{"file": fileStore, "sqlite": sqliteStore} {
+		t.Run(name, func(t *testing.T) {
+			// Written before the sealer was set.
+			if err := store.Save(ctx, "plain", []message.Message{{Role: "user", Content: "old"}}); err != nil {
+				t.Fatal(err)
+			}
+			store.SetSealer(xorSealer{})
+			if err := store.Save(ctx, "sealed", []message.Message{{Role: "user", Content: "secret"}}); err != nil {
+				t.Fatal(err)
+			}
+			for id, want := range map[string]string{"plain": "old", "sealed": "secret"} {
+				got, err := store.Load(ctx, id)
+				if err != nil || len(got) != 1 || got[0].Content != want {
+					t.Fatalf("load %s: %+v err=%v", id, got, err)
+				}
+			}
+		})
+	}
+	raw, err := os.ReadFile(fileStore.path("sealed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "secret") {
+		t.Fatalf("file payload not sealed: %q", raw)
+	}
+}
+
+func TestNewSQLiteStoreRejectsBadTable(t *testing.T) {
+	db, err := sql.Open(fakeDriverName, t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := NewSQLiteStore(context.Background(), db, SQLiteOptions{Table: "x; DROP"}); err == nil {
+		t.Fatal("expected invalid table error")
+	}
+}
+
+// fakeDriver is a minimal database/sql driver understanding the statements
+// SQLiteStore issues, so the store is tested without a cgo SQLite build.
+const fakeDriverName = "session-fake"
+
+func init() { sql.Register(fakeDriverName, &fakeDriver{dbs: map[string]*fakeDB{}}) }
+
+type fakeRow struct {
+	messages string
+	updated  int64
+}
+
+type fakeDB struct {
+	mu   sync.Mutex
+	rows map[string]fakeRow
+}
+
+type fakeDriver struct {
+	mu  sync.Mutex
+	dbs map[string]*fakeDB
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	db, ok := d.dbs[name]
+	if !ok {
+		db = &fakeDB{rows: map[string]fakeRow{}}
+		d.dbs[name] = db
+	}
+	return &fakeConn{db: db}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("fake: no transactions") }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(s.query, "INSERT INTO"):
+		s.db.rows[args[0].(string)] = fakeRow{messages: args[1].(string), updated: args[2].(int64)}
+		return driver.RowsAffected(1), nil
+	case strings.Contains(s.query, "WHERE id = ?"):
+		if _, ok := s.db.rows[args[0].(string)]; !ok {
+			return driver.RowsAffected(0), nil
+		}
+		delete(s.db.rows, args[0].(string))
+		return driver.RowsAffected(1), nil
+	case strings.Contains(s.query, "WHERE updated_at < ?"):
+		n := 0
+		for id, row := range s.db.rows {
+			if row.updated < args[0].(int64) {
+				delete(s.db.rows, id)
+				n++
+			}
+		}
+		return driver.RowsAffected(n), nil
+	}
+	return nil, errors.New("fake: unsupported exec " + s.query)
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "SELECT messages"):
+		row, ok := s.db.rows[args[0].(string)]
+		if !ok {
+			return &fakeRows{cols: []string{"messages"}}, nil
+		}
+		return &fakeRows{cols: []string{"messages"}, vals: []string{row.messages}}, nil
+	case strings.HasPrefix(s.query, "SELECT id"):
+		ids := make([]string, 0, len(s.db.rows))
+		for id := range s.db.rows {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		return &fakeRows{cols: []string{"id"}, vals: ids}, nil
+	}
+	return nil, errors.New("fake: unsupported query " + s.query)
+}
+
+type fakeRows struct {
+	cols []string
+	vals []string
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.vals) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.vals[0]
+	r.vals = r.vals[1:]
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/cexll/agentsdk-go/pkg/sandbox"
 	"github.com/cexll/agentsdk-go/pkg/tool"
 )
 
@@ -85,6 +86,11 @@ func (m *AsyncTaskManager) Start(id, command string) error {
 }
 
 func (m *AsyncTaskManager) startWithContext(ctx context.Context, id, command, workdir string, timeout time.Duration) error {
+	return m.startIsolated(ctx, id, command, workdir, timeout, nil)
+}
+
+// startIsolated is startWithContext with the command wrapped by iso.
+func (m *AsyncTaskManager) startIsolated(ctx context.Context, id, command, workdir string, timeout time.Duration, iso sandbox.CommandIsolator) error {
 	if m == nil {
 		return errors.New("async task manager is nil")
 	}
@@ -143,7 +149,16 @@ func (m *AsyncTaskManager) startWithContext(ctx context.Context, id, command, wo
 	task.cancel = cancel
 	task.mu.Unlock()
 
-	cmd := exec.CommandContext(execCtx, "bash", "-c", trimmedCmd)
+	argv, err := isolatedArgv(execCtx, iso, trimmedCmd, workdir)
+	if err != nil {
+		cancel()
+		_ = task.output.Close()
+		m.mu.Lock()
+		delete(m.tasks, trimmedID)
+		m.mu.Unlock()
+		return err
+	}
+	cmd := exec.CommandContext(execCtx, argv[0], argv[1:]...)
 	cmd.Env = os.Environ()
 	if strings.TrimSpace(workdir) != "" {
 		cmd.Dir = workdir
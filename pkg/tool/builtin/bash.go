@@ -14,9 +14,11 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/cexll/agentsdk-go/pkg/middleware"
 	"github.com/cexll/agentsdk-go/pkg/model"
+	"github.com/cexll/agentsdk-go/pkg/sandbox"
 	"github.com/cexll/agentsdk-go/pkg/security"
 	"github.com/cexll/agentsdk-go/pkg/tool"
 )
@@ -214,6 +216,11 @@ type BashTool struct {
 	timeout time.Duration
 
 	outputThresholdBytes int
+	// shells keeps persistent shells; nil runs every command in a fresh one.
+	shells *ShellSessionManager
+	// isolation wraps processes in an OS or container sandbox; nil runs
+	// them directly.
+	isolation sandbox.CommandIsolator
 }
 
 // NewBashTool builds a BashTool rooted at the current directory.
@@ -261,6 +268,82 @@ func (b *BashTool) effectiveOutputThresholdBytes() int {
 	return b.outputThresholdBytes
 }
 
+// SetShellSessions runs foreground commands in the persistent shells of m
+// instead of a fresh bash process per call. Async commands are unaffected.
+func (b *BashTool) SetShellSessions(m *ShellSessionManager) {
+	if b != nil {
+		b.shells = m
+	}
+}
+
+// ShellSessions returns the manager set by SetShellSessions, or nil.
+func (b *BashTool) ShellSessions() *ShellSessionManager {
+	if b == nil {
+		return nil
+	}
+	return b.shells
+}
+
+// SetIsolator runs commands through iso (an *sandbox.OSSandbox or
+// *sandbox.ContainerSandbox), except those matching its ExcludedCommands.
+// Persistent shells started afterwards are wrapped by an OSSandbox too and
+// excluded commands then run in a fresh process; a ContainerSandbox runs
+// every command in a fresh exec instead of a persistent shell.
+func (b *BashTool) SetIsolator(iso sandbox.CommandIsolator) {
+	if b == nil {
+		return
+	}
+	b.isolation = iso
+	if osbox, ok := iso.(*sandbox.OSSandbox); ok {
+		b.shells.setIsolation(osbox)
+	} else {
+		b.shells.setIsolation(nil)
+	}
+}
+
+// Isolator returns the backend set by SetIsolator, or nil.
+func (b *BashTool) Isolator() sandbox.CommandIsolator {
+	if b == nil {
+		return nil
+	}
+	return b.isolation
+}
+
+// useShell reports whether command runs in a persistent shell.
+func (b *BashTool) useShell(command string) bool {
+	if !b.shells.enabled() || b.isolation == nil {
+		return b.shells.enabled()
+	}
+	if _, ok := b.isolation.(*sandbox.ContainerSandbox); ok {
+		return false
+	}
+	return !b.isolation.Excluded(command)
+}
+
+// commandContext builds the bash process for command, run through the
+// isolation backend when one applies.
+func (b *BashTool) commandContext(ctx context.Context, command, workdir string) (*exec.Cmd, error) {
+	argv, err := isolatedArgv(ctx, b.isolation, command, workdir)
+	if err != nil {
+		return nil, err
+	}
+	return exec.CommandContext(ctx, argv[0], argv[1:]...), nil
+}
+
+// isolatedArgv returns the argv running `bash -c command` in workdir through
+// iso, or directly when iso is nil or the command is excluded.
+func isolatedArgv(ctx context.Context, iso sandbox.CommandIsolator, command, workdir string) ([]string, error) {
+	argv := []string{"bash", "-c", command}
+	if iso == nil || iso.Excluded(command) {
+		return argv, nil
+	}
+	var env []string
+	if scratch, ok := tool.ScratchFromContext(ctx); ok {
+		env = scratch.Env()
+	}
+	return iso.Isolate(ctx, sandbox.IsolatedCommand{Argv: argv, SessionID: bashSessionID(ctx), Dir: workdir, Env: env})
+}
+
 // AllowShellMetachars enables shell pipes and metacharacters (CLI mode).
 func (b *BashTool) AllowShellMetachars(allow bool) {
 	if b != nil && b.sandbox != nil {
@@ -294,10 +377,13 @@ func (b *BashTool) Execute(ctx context.Context, params map[string]interface{}) (
 	if err := b.sandbox.ValidateCommand(command); err != nil {
 		return nil, err
 	}
-	workdir, err := b.resolveWorkdir(params)
+	workdir, err := b.resolveWorkdir(ctx, params)
 	if err != nil {
 		return nil, err
 	}
+	if err := b.checkWriteTargets(command, workdir); err != nil {
+		return nil, err
+	}
 	timeout, err := b.resolveTimeout(params)
 	if err != nil {
 		return nil, err
@@ -311,7 +397,7 @@ func (b *BashTool) Execute(ctx context.Context, params map[string]interface{}) (
 		if id == "" {
 			id = generateAsyncTaskID()
 		}
-		if err := DefaultAsyncTaskManager().startWithContext(ctx, id, command, workdir, timeout); err != nil {
+		if err := DefaultAsyncTaskManager().startIsolated(ctx, id, command, workdir, timeout, b.isolation); err != nil {
 			return nil, err
 		}
 		payload := map[string]interface{}{
@@ -324,6 +410,9 @@ func (b *BashTool) Execute(ctx context.Context, params map[string]interface{}) (
 		}
 		return &tool.ToolResult{Success: true, Output: string(out), Data: payload}, nil
 	}
+	if b.useShell(command) {
+		return b.executeInShell(ctx, params, command, workdir, timeout, nil)
+	}
 
 	execCtx := ctx
 	var cancel context.CancelFunc
@@ -332,8 +421,11 @@ func (b *BashTool) Execute(ctx context.Context, params map[string]interface{}) (
 		defer cancel()
 	}
 
-	cmd := exec.CommandContext(execCtx, "bash", "-c", command)
-	cmd.Env = os.Environ()
+	cmd, err := b.commandContext(execCtx, command, workdir)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Env = commandEnv(ctx)
 	cmd.Dir = workdir
 
 	spool := newBashOutputSpool(ctx, b.effectiveOutputThresholdBytes())
@@ -365,16 +457,61 @@ func (b *BashTool) Execute(ctx context.Context, params map[string]interface{}) (
 	}
 
 	if runErr != nil {
-		if errors.Is(execCtx.Err(), context.DeadlineExceeded) {
-			return result, fmt.Errorf("command timeout after %s", timeout)
-		}
-		return result, fmt.Errorf("command failed: %w", runErr)
+		return result, bashFailure(execCtx, timeout, runErr, spool)
 	}
 	return result, nil
 }
 
-func (b *BashTool) resolveWorkdir(params map[string]interface{}) (string, error) {
+// bashErrorStreamLimit caps the stdout/stderr tails kept in a bash ToolError.
+const bashErrorStreamLimit = 4 << 10
+
+// bashFailure builds the structured error for a failed command, keeping
+// stdout and stderr apart so the model can tell diagnostics from output.
+func bashFailure(execCtx context.Context, timeout time.Duration, runErr error, spool *bashOutputSpool) error {
+	var te *tool.ToolError
+	switch {
+	case errors.Is(execCtx.Err(), context.DeadlineExceeded):
+		te = tool.NewToolError(tool.ErrorTimeout, fmt.Errorf("command timeout after %s", timeout))
+	case errors.Is(execCtx.Err(), context.Canceled):
+		return tool.NewToolError(tool.ErrorCanceled, execCtx.Err())
+	default:
+		te = tool.NewToolError(tool.ErrorExecution, fmt.Errorf("command failed: %w", runErr))
+		var exitErr *exec.ExitError
+		if errors.As(runErr, &exitErr) {
+			code := exitErr.ExitCode()
+			te.ExitCode = &code
+		}
+	}
+	attachStreams(te, spool)
+	return te
+}
+
+func attachStreams(te *tool.ToolError, spool *bashOutputSpool) {
+	if spool != nil {
+		te.Stdout = tailString(strings.TrimRight(spool.stdout.String(), "\r\n"), bashErrorStreamLimit)
+		te.Stderr = tailString(strings.TrimRight(spool.stderr.String(), "\r\n"), bashErrorStreamLimit)
+	}
+}
+
+func tailString(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	cut := len(s) - limit
+	for cut < len(s) && !utf8.RuneStart(s[cut]) {
+		cut++
+	}
+	return "..." + s[cut:]
+}
+
+// resolveWorkdir picks the workdir parameter, then the directory the runtime
+// scoped the request to (tool.WithWorkDir), then the tool root. Relative
+// workdirs resolve against the root.
+func (b *BashTool) resolveWorkdir(ctx context.Context, params map[string]interface{}) (string, error) {
 	dir := b.root
+	if scoped, ok := tool.WorkDirFromContext(ctx); ok {
+		dir = scoped
+	}
 	if raw, ok := params["workdir"]; ok && raw != nil {
 		value, err := coerceString(raw)
 		if err != nil {
@@ -392,6 +529,21 @@ func (b *BashTool) resolveWorkdir(params map[string]interface{}) (string, error)
 	return b.ensureDirectory(dir)
 }
 
+// checkWriteTargets rejects commands that write files outside the
+// sandbox's write scope (see security.WriteTargets). Relative targets
+// resolve against workdir.
+func (b *BashTool) checkWriteTargets(command, workdir string) error {
+	for _, target := range security.WriteTargets(command) {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(workdir, target)
+		}
+		if err := b.sandbox.ValidateWrite(target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (b *BashTool) ensureDirectory(path string) (string, error) {
 	if err := b.sandbox.ValidatePath(path); err != nil {
 		return "", err
@@ -532,6 +684,17 @@ func combineOutput(stdout, stderr string) string {
 	}
 }
 
+// commandEnv is the environment for foreground commands: the process
+// environment plus the run's scratch directories when the runtime set them.
+// Async tasks outlive the run and keep the plain environment.
+func commandEnv(ctx context.Context) []string {
+	env := os.Environ()
+	if scratch, ok := tool.ScratchFromContext(ctx); ok {
+		env = append(env, scratch.Env()...)
+	}
+	return env
+}
+
 func resolveRoot(dir string) string {
 	trimmed := strings.TrimSpace(dir)
 	if trimmed == "" {
@@ -778,7 +941,14 @@ func appendStderr(out *os.File, stdoutLen int64, stderrPath, stderrText string)
 }
 
 func bashSessionID(ctx context.Context) string {
-	const fallback = "default"
+	if session := sessionIDFromContext(ctx); session != "" {
+		return session
+	}
+	return "default"
+}
+
+// sessionIDFromContext returns the runtime session of a tool call, or "".
+func sessionIDFromContext(ctx context.Context) string {
 	var session string
 	if ctx != nil {
 		if st, ok := ctx.Value(model.MiddlewareStateKey).(*middleware.State); ok && st != nil {
@@ -803,11 +973,7 @@ func bashSessionID(ctx context.Context) string {
 			}
 		}
 	}
-	session = strings.TrimSpace(session)
-	if session == "" {
-		return fallback
-	}
-	return session
+	return strings.TrimSpace(session)
 }
 
 func sanitizePathComponent(value string) string {
@@ -0,0 +1,35 @@
+package toolbuiltin
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+func TestBashToolFailureSeparatesStreams(t *testing.T) {
+	skipIfWindows(t)
+	dir := cleanTempDir(t)
+	script := writeScript(t, dir, "fail.sh", "#!/bin/sh\necho partial\necho boom >&2\nexit 3")
+
+	bash := NewBashToolWithRoot(dir)
+	_, err := bash.Execute(context.Background(), map[string]interface{}{
+		"command": "./" + filepath.Base(script),
+		"workdir": dir,
+	})
+	var te *tool.ToolError
+	if !errors.As(err, &te) {
+		t.Fatalf("expected *tool.ToolError, got %T %v", err, err)
+	}
+	if te.Category != tool.ErrorExecution || te.Retryable {
+		t.Fatalf("unexpected classification %+v", te)
+	}
+	if te.ExitCode == nil || *te.ExitCode != 3 {
+		t.Fatalf("exit code = %v", te.ExitCode)
+	}
+	if te.Stdout != "partial" || te.Stderr != "boom" {
+		t.Fatalf("stdout=%q stderr=%q", te.Stdout, te.Stderr)
+	}
+}
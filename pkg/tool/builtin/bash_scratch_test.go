@@ -0,0 +1,30 @@
+package toolbuiltin
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+func TestBashToolExportsScratchDirs(t *testing.T) {
+	skipIfWindows(t)
+	dir := cleanTempDir(t)
+	script := writeScript(t, dir, "env.sh", "#!/bin/sh\necho \"$AGENTSDK_SCRATCH_DIR|$AGENTSDK_RUN_SCRATCH_DIR|$TMPDIR\"")
+	scratch := tool.Scratch{Run: filepath.Join(dir, "run"), Iteration: filepath.Join(dir, "run", "iter-0")}
+	ctx := tool.WithScratch(context.Background(), scratch)
+
+	res, err := NewBashToolWithRoot(dir).Execute(ctx, map[string]interface{}{
+		"command": "./" + filepath.Base(script),
+		"workdir": dir,
+	})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := scratch.Iteration + "|" + scratch.Run + "|" + scratch.Iteration
+	if got := strings.TrimSpace(res.Output); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,619 @@
+package toolbuiltin
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/sandbox"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+// ShellScope selects how long BashTool keeps a shell alive between calls.
+type ShellScope string
+
+const (
+	// ShellScopeOff runs every command in a fresh bash process.
+	ShellScopeOff ShellScope = ""
+	// ShellScopeRun keeps one shell per run; it is closed when the run ends.
+	ShellScopeRun ShellScope = "run"
+	// ShellScopeSession keeps one shell per session ID across runs.
+	ShellScopeSession ShellScope = "session"
+)
+
+const (
+	defaultShellIdleTimeout    = 10 * time.Minute
+	defaultShellMaxOutputBytes = 8 << 20
+)
+
+var (
+	// ErrShellSessionsClosed is returned once the manager has been closed.
+	ErrShellSessionsClosed = errors.New("bash: shell sessions closed")
+	// ErrShellSessionKilled reports a command whose shell was killed through
+	// Kill, KillAll or the idle timeout while it ran.
+	ErrShellSessionKilled = errors.New("bash: shell session killed")
+)
+
+// ShellSessionConfig configures a ShellSessionManager.
+type ShellSessionConfig struct {
+	Scope ShellScope
+	// IdleTimeout closes shells unused this long (default 10m).
+	IdleTimeout time.Duration
+	// MaxOutputBytes truncates the output of a single command (default 8 MiB).
+	MaxOutputBytes int
+}
+
+// ShellSessionInfo describes a live shell.
+type ShellSessionInfo struct {
+	ID        string    `json:"id"`
+	PID       int       `json:"pid"`
+	Cwd       string    `json:"cwd"`
+	Commands  int       `json:"commands"`
+	StartedAt time.Time `json:"started_at"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+// ShellSessionManager keeps persistent bash processes for BashTool, keyed by
+// run or session ID, so cwd, exported variables and functions survive between
+// calls. Commands on one shell run one at a time.
+type ShellSessionManager struct {
+	cfg ShellSessionConfig
+
+	mu        sync.Mutex
+	sessions  map[string]*shellSession
+	closed    bool
+	isolation *sandbox.OSSandbox
+}
+
+// NewShellSessionManager builds a manager; a zero Scope disables it.
+func NewShellSessionManager(cfg ShellSessionConfig) *ShellSessionManager {
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = defaultShellIdleTimeout
+	}
+	if cfg.MaxOutputBytes <= 0 {
+		cfg.MaxOutputBytes = defaultShellMaxOutputBytes
+	}
+	return &ShellSessionManager{cfg: cfg, sessions: map[string]*shellSession{}}
+}
+
+// setIsolation wraps shells started from now on in s.
+func (m *ShellSessionManager) setIsolation(s *sandbox.OSSandbox) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.isolation = s
+	m.mu.Unlock()
+}
+
+// Scope reports the configured scope; a nil manager is ShellScopeOff.
+func (m *ShellSessionManager) Scope() ShellScope {
+	if m == nil {
+		return ShellScopeOff
+	}
+	return m.cfg.Scope
+}
+
+func (m *ShellSessionManager) enabled() bool {
+	scope := m.Scope()
+	return scope == ShellScopeRun || scope == ShellScopeSession
+}
+
+// key names the shell a call uses: its run ID in run scope (falling back to
+// the session), otherwise its session ID.
+func (m *ShellSessionManager) key(ctx context.Context) string {
+	if m.Scope() == ShellScopeRun {
+		if id, ok := tool.RunIDFromContext(ctx); ok {
+			return id
+		}
+	}
+	return bashSessionID(ctx)
+}
+
+// Sessions lists the live shells ordered by ID.
+func (m *ShellSessionManager) Sessions() []ShellSessionInfo {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]ShellSessionInfo, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		out = append(out, ShellSessionInfo{
+			ID:        s.id,
+			PID:       s.cmd.Process.Pid,
+			Cwd:       s.cwd,
+			Commands:  s.commands,
+			StartedAt: s.startedAt,
+			LastUsed:  s.lastUsed,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Kill stops the shell of a run or session ID and every process it started.
+// A running command fails with ErrShellSessionKilled; the next call starts a
+// fresh shell. It reports whether a shell was found.
+func (m *ShellSessionManager) Kill(id string) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	s := m.sessions[id]
+	if s != nil {
+		delete(m.sessions, id)
+	}
+	m.mu.Unlock()
+	if s == nil {
+		return false
+	}
+	s.kill()
+	return true
+}
+
+// KillAll stops every shell and returns how many were running.
+func (m *ShellSessionManager) KillAll() int {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	sessions := m.sessions
+	m.sessions = map[string]*shellSession{}
+	m.mu.Unlock()
+	for _, s := range sessions {
+		s.kill()
+	}
+	return len(sessions)
+}
+
+// Close kills every shell and rejects further commands.
+func (m *ShellSessionManager) Close() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+	m.KillAll()
+	return nil
+}
+
+// executeInShell runs command on the caller's persistent shell. An explicit
+// workdir parameter moves the shell there first; otherwise the command runs
+// wherever the previous one left it.
+func (b *BashTool) executeInShell(ctx context.Context, params map[string]interface{}, command, workdir string, timeout time.Duration, emit func(chunk string, isStderr bool)) (*tool.ToolResult, error) {
+	execCtx := ctx
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		execCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req := shellRequest{command: command, dir: workdir}
+	if raw, ok := params["workdir"]; ok && raw != nil {
+		if value, _ := coerceString(raw); strings.TrimSpace(value) != "" {
+			req.cd = workdir
+		}
+	}
+	if scratch, ok := tool.ScratchFromContext(ctx); ok {
+		req.env = scratch.Env()
+	}
+	spool := newBashOutputSpool(ctx, b.effectiveOutputThresholdBytes())
+	req.sink = func(line string, isStderr bool) {
+		if emit != nil {
+			emit(line, isStderr)
+		}
+		_ = spool.Append(line+"\n", isStderr) //nolint:errcheck // best-effort spool
+	}
+
+	key := b.shells.key(ctx)
+	start := time.Now()
+	res, runErr := b.shells.run(execCtx, key, req)
+	duration := time.Since(start)
+	output, outputFile, spoolErr := spool.Finalize()
+	if res.truncated {
+		output += fmt.Sprintf("\n[output truncated after %d bytes]", b.shells.cfg.MaxOutputBytes)
+	}
+
+	cwd := res.cwd
+	if cwd == "" {
+		cwd = workdir
+	}
+	data := map[string]interface{}{
+		"workdir":       cwd,
+		"duration_ms":   duration.Milliseconds(),
+		"timeout_ms":    timeout.Milliseconds(),
+		"shell_session": key,
+	}
+	if res.started {
+		data["shell_started"] = true
+	}
+	if res.truncated {
+		data["truncated"] = true
+	}
+	if outputFile != "" {
+		data["output_file"] = outputFile
+	}
+	if spoolErr != nil {
+		data["spool_error"] = spoolErr.Error()
+	}
+	result := &tool.ToolResult{
+		Success: runErr == nil && res.exitCode == 0,
+		Output:  output,
+		Data:    data,
+	}
+	switch {
+	case errors.Is(runErr, ErrShellSessionKilled):
+		te := tool.NewToolError(tool.ErrorCanceled, runErr)
+		attachStreams(te, spool)
+		return result, te
+	case runErr != nil:
+		return result, bashFailure(execCtx, timeout, runErr, spool)
+	case res.exitCode != 0:
+		code := res.exitCode
+		te := tool.NewToolError(tool.ErrorExecution, fmt.Errorf("command failed: exit status %d", code))
+		te.ExitCode = &code
+		attachStreams(te, spool)
+		return result, te
+	}
+	return result, nil
+}
+
+// shellRequest is one command for a persistent shell.
+type shellRequest struct {
+	command string
+	// dir starts a new shell; cd, when set, moves an existing one first.
+	dir string
+	cd  string
+	env []string
+	// sink receives output lines without their newline.
+	sink func(line string, isStderr bool)
+}
+
+type shellResult struct {
+	exitCode  int
+	cwd       string
+	started   bool
+	truncated bool
+}
+
+// run executes req on the shell named key, starting one if needed. A
+// context error or a dead shell discards the shell; a non-zero exit status
+// keeps it.
+func (m *ShellSessionManager) run(ctx context.Context, key string, req shellRequest) (shellResult, error) {
+	s, started, err := m.acquire(key, req.dir)
+	if err != nil {
+		return shellResult{}, err
+	}
+	s.runMu.Lock()
+	res, runErr := s.exec(ctx, req, m.cfg.MaxOutputBytes)
+	s.runMu.Unlock()
+	res.started = started
+
+	m.mu.Lock()
+	s.pending--
+	if runErr != nil {
+		if m.sessions[key] == s {
+			delete(m.sessions, key)
+		}
+	} else {
+		s.cwd = res.cwd
+		s.commands++
+		s.lastUsed = time.Now()
+		s.idle.Reset(m.cfg.IdleTimeout)
+	}
+	m.mu.Unlock()
+	if runErr != nil {
+		s.kill()
+	}
+	return res, runErr
+}
+
+func (m *ShellSessionManager) acquire(key, dir string) (*shellSession, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, false, ErrShellSessionsClosed
+	}
+	if s := m.sessions[key]; s != nil && !s.isDead() {
+		s.pending++
+		return s, false, nil
+	}
+	s, err := startShell(key, dir, m.isolation)
+	if err != nil {
+		return nil, false, err
+	}
+	s.pending = 1
+	s.idle = time.AfterFunc(m.cfg.IdleTimeout, func() { m.expire(s) })
+	m.sessions[key] = s
+	return s, true, nil
+}
+
+// expire closes s when it is still idle.
+func (m *ShellSessionManager) expire(s *shellSession) {
+	m.mu.Lock()
+	if s.pending > 0 || m.sessions[s.id] != s {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.sessions, s.id)
+	m.mu.Unlock()
+	s.kill()
+}
+
+type shellSession struct {
+	id        string
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdout    *bufio.Reader
+	stderr    *bufio.Reader
+	pipes     []io.Closer
+	scriptDir string
+	startedAt time.Time
+	exited    chan struct{}
+
+	runMu sync.Mutex
+
+	// Guarded by ShellSessionManager.mu.
+	cwd      string
+	commands int
+	lastUsed time.Time
+	pending  int
+	idle     *time.Timer
+
+	killOnce sync.Once
+	killed   chan struct{}
+}
+
+func startShell(id, dir string, iso *sandbox.OSSandbox) (*shellSession, error) {
+	scriptDir, err := os.MkdirTemp("", "agentsdk-shell-")
+	if err != nil {
+		return nil, fmt.Errorf("shell script dir: %w", err)
+	}
+	// Plain os pipes rather than StdoutPipe: Wait would close those while
+	// output is still being read.
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		_ = os.RemoveAll(scriptDir)
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		_ = os.RemoveAll(scriptDir)
+		_ = outR.Close()
+		_ = outW.Close()
+		return nil, fmt.Errorf("stderr pipe: %w", err)
+	}
+	// The shell sources scripts from scriptDir, so it stays writable.
+	argv := iso.Wrap([]string{"bash", "--noprofile", "--norc"}, scriptDir)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	cmd.Stdout = outW
+	cmd.Stderr = errW
+	startProcessGroup(cmd)
+	stdin, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	_ = outW.Close()
+	_ = errW.Close()
+	if err != nil {
+		_ = os.RemoveAll(scriptDir)
+		_ = outR.Close()
+		_ = errR.Close()
+		return nil, fmt.Errorf("start shell: %w", err)
+	}
+	now := time.Now()
+	s := &shellSession{
+		id:        id,
+		cmd:       cmd,
+		stdin:     stdin,
+		stdout:    bufio.NewReaderSize(outR, 64*1024),
+		stderr:    bufio.NewReaderSize(errR, 64*1024),
+		pipes:     []io.Closer{outR, errR},
+		scriptDir: scriptDir,
+		startedAt: now,
+		exited:    make(chan struct{}),
+		cwd:       dir,
+		lastUsed:  now,
+		killed:    make(chan struct{}),
+	}
+	go func() {
+		_ = cmd.Wait()
+		close(s.exited)
+	}()
+	return s, nil
+}
+
+func (s *shellSession) isDead() bool {
+	select {
+	case <-s.exited:
+		return true
+	case <-s.killed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *shellSession) wasKilled() bool {
+	select {
+	case <-s.killed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *shellSession) kill() {
+	s.killOnce.Do(func() {
+		close(s.killed)
+		if s.idle != nil {
+			s.idle.Stop()
+		}
+		_ = s.stdin.Close()
+		killProcessGroup(s.cmd)
+		for _, p := range s.pipes {
+			_ = p.Close()
+		}
+		_ = os.RemoveAll(s.scriptDir)
+	})
+}
+
+// exec sources the command from a script file so heredocs and syntax errors
+// stay contained, then prints a marker with the exit status and cwd on
+// stdout and a bare marker on stderr to find the end of its output.
+func (s *shellSession) exec(ctx context.Context, req shellRequest, maxOutput int) (shellResult, error) {
+	script, err := os.CreateTemp(s.scriptDir, "cmd-*.sh")
+	if err != nil {
+		return shellResult{}, fmt.Errorf("write command: %w", err)
+	}
+	defer os.Remove(script.Name())
+	_, err = script.WriteString(req.command + "\n")
+	if closeErr := script.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return shellResult{}, fmt.Errorf("write command: %w", err)
+	}
+
+	marker := "__agentsdk_done_" + randomHex(8)
+	var line strings.Builder
+	for _, kv := range req.env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			fmt.Fprintf(&line, "export %s=%s; ", k, shellQuote(v))
+		}
+	}
+	if req.cd != "" {
+		fmt.Fprintf(&line, "cd -- %s && ", shellQuote(req.cd))
+	}
+	fmt.Fprintf(&line, ". %s </dev/null; printf '%%s %%d %%s\\n' %s \"$?\" \"$PWD\"; printf '%%s\\n' %s >&2\n",
+		shellQuote(script.Name()), marker, marker)
+
+	if _, err := io.WriteString(s.stdin, line.String()); err != nil {
+		if s.wasKilled() {
+			return shellResult{}, ErrShellSessionKilled
+		}
+		return shellResult{}, fmt.Errorf("shell exited: %w", err)
+	}
+
+	limit := &outputLimit{max: maxOutput, sink: req.sink}
+	type streamEnd struct {
+		status string
+		err    error
+	}
+	ends := make(chan streamEnd, 2)
+	read := func(r *bufio.Reader, isStderr bool) {
+		status, err := readUntilMarker(r, marker, func(text string) { limit.write(text, isStderr) })
+		ends <- streamEnd{status: status, err: err}
+	}
+	go read(s.stdout, false)
+	go read(s.stderr, true)
+
+	var status string
+	for i := 0; i < 2; i++ {
+		select {
+		case end := <-ends:
+			if end.err != nil {
+				if s.wasKilled() {
+					return shellResult{truncated: limit.truncated()}, ErrShellSessionKilled
+				}
+				return shellResult{truncated: limit.truncated()}, fmt.Errorf("shell exited: %w", end.err)
+			}
+			if end.status != "" {
+				status = end.status
+			}
+		case <-ctx.Done():
+			s.kill()
+			return shellResult{truncated: limit.truncated()}, ctx.Err()
+		case <-s.killed:
+			return shellResult{truncated: limit.truncated()}, ErrShellSessionKilled
+		}
+	}
+	codeText, cwd, _ := strings.Cut(status, " ")
+	code, err := strconv.Atoi(codeText)
+	if err != nil {
+		return shellResult{truncated: limit.truncated()}, fmt.Errorf("shell status %q: %w", status, err)
+	}
+	return shellResult{exitCode: code, cwd: cwd, truncated: limit.truncated()}, nil
+}
+
+// readUntilMarker passes lines to emit until one contains marker and returns
+// the rest of that line. Output without a trailing newline ends up in front
+// of the marker.
+func readUntilMarker(r *bufio.Reader, marker string, emit func(string)) (string, error) {
+	for {
+		line, err := r.ReadString('\n')
+		if i := strings.Index(line, marker); i >= 0 {
+			if i > 0 {
+				emit(line[:i])
+			}
+			return strings.TrimSpace(line[i+len(marker):]), nil
+		}
+		if line != "" {
+			emit(strings.TrimSuffix(line, "\n"))
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// outputLimit forwards lines until max bytes have been seen, then drops the
+// rest.
+type outputLimit struct {
+	max  int
+	sink func(string, bool)
+
+	mu      sync.Mutex
+	written int
+	over    bool
+}
+
+func (l *outputLimit) write(text string, isStderr bool) {
+	l.mu.Lock()
+	if l.over {
+		l.mu.Unlock()
+		return
+	}
+	if l.max > 0 && l.written+len(text)+1 > l.max {
+		l.over = true
+		l.mu.Unlock()
+		return
+	}
+	l.written += len(text) + 1
+	l.mu.Unlock()
+	if l.sink != nil {
+		l.sink(text, isStderr)
+	}
+}
+
+func (l *outputLimit) truncated() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.over
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
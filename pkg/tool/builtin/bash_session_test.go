@@ -0,0 +1,190 @@
+package toolbuiltin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+func newSessionBash(t *testing.T, cfg ShellSessionConfig) (*BashTool, string) {
+	t.Helper()
+	skipIfWindows(t)
+	dir := cleanTempDir(t)
+	bash := NewBashToolWithRoot(dir)
+	bash.AllowShellMetachars(true)
+	mgr := NewShellSessionManager(cfg)
+	t.Cleanup(func() { _ = mgr.Close() })
+	bash.SetShellSessions(mgr)
+	return bash, dir
+}
+
+func sessionCtx(session string) context.Context {
+	return context.WithValue(context.Background(), middleware.SessionIDContextKey, session)
+}
+
+func TestBashSessionKeepsCwdAndEnv(t *testing.T) {
+	bash, dir := newSessionBash(t, ShellSessionConfig{Scope: ShellScopeSession})
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ctx := sessionCtx("s1")
+	for _, cmd := range []string{"cd sub", "export GREETING=hello", "greet() { echo \"$GREETING from $(basename $PWD)\"; }"} {
+		if _, err := bash.Execute(ctx, map[string]interface{}{"command": cmd}); err != nil {
+			t.Fatalf("%s: %v", cmd, err)
+		}
+	}
+	res, err := bash.Execute(ctx, map[string]interface{}{"command": "greet"})
+	if err != nil {
+		t.Fatalf("greet: %v", err)
+	}
+	if res.Output != "hello from sub" {
+		t.Fatalf("unexpected output %q", res.Output)
+	}
+	data := res.Data.(map[string]interface{})
+	if data["shell_session"] != "s1" || !strings.HasSuffix(data["workdir"].(string), "sub") || data["shell_started"] != nil {
+		t.Fatalf("unexpected data %+v", data)
+	}
+
+	other, err := bash.Execute(sessionCtx("s2"), map[string]interface{}{"command": "echo ${GREETING:-unset}"})
+	if err != nil || other.Output != "unset" {
+		t.Fatalf("sessions should not share state: %q %v", other.Output, err)
+	}
+	if infos := bash.ShellSessions().Sessions(); len(infos) != 2 || infos[0].ID != "s1" || infos[0].Commands != 4 {
+		t.Fatalf("unexpected sessions %+v", infos)
+	}
+}
+
+func TestBashSessionRunScopeAndWorkdir(t *testing.T) {
+	bash, dir := newSessionBash(t, ShellSessionConfig{Scope: ShellScopeRun})
+	sub := filepath.Join(dir, "pkg")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	run1 := tool.WithRunID(sessionCtx("s"), "run-1")
+	if _, err := bash.Execute(run1, map[string]interface{}{"command": "X=1", "workdir": "pkg"}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := bash.Execute(run1, map[string]interface{}{"command": "echo $X $PWD"})
+	if err != nil || res.Output != "1 "+sub {
+		t.Fatalf("run shell lost state: %q %v", res.Output, err)
+	}
+	res, err = bash.Execute(tool.WithRunID(sessionCtx("s"), "run-2"), map[string]interface{}{"command": "echo ${X:-none}"})
+	if err != nil || res.Output != "none" {
+		t.Fatalf("runs should not share a shell: %q %v", res.Output, err)
+	}
+	if !bash.ShellSessions().Kill("run-1") || bash.ShellSessions().Kill("run-1") {
+		t.Fatal("Kill should report the shell once")
+	}
+	res, err = bash.Execute(run1, map[string]interface{}{"command": "echo ${X:-fresh}"})
+	if err != nil || res.Output != "fresh" || res.Data.(map[string]interface{})["shell_started"] != true {
+		t.Fatalf("expected a fresh shell after Kill: %q %v", res.Output, err)
+	}
+}
+
+func TestBashSessionFailuresKeepShell(t *testing.T) {
+	bash, _ := newSessionBash(t, ShellSessionConfig{Scope: ShellScopeSession})
+	ctx := sessionCtx("s")
+	if _, err := bash.Execute(ctx, map[string]interface{}{"command": "Y=kept"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := bash.Execute(ctx, map[string]interface{}{"command": "echo oops >&2; false"})
+	var te *tool.ToolError
+	if !errors.As(err, &te) || te.ExitCode == nil || *te.ExitCode != 1 || te.Stderr != "oops" {
+		t.Fatalf("expected exit status 1 with stderr, got %#v", err)
+	}
+	if _, err := bash.Execute(ctx, map[string]interface{}{"command": "if then"}); err == nil {
+		t.Fatal("expected syntax error")
+	}
+	res, err := bash.Execute(ctx, map[string]interface{}{"command": "echo $Y"})
+	if err != nil || res.Output != "kept" {
+		t.Fatalf("shell should survive failed commands: %q %v", res.Output, err)
+	}
+
+	_, err = bash.Execute(ctx, map[string]interface{}{"command": "exit 3"})
+	if err == nil {
+		t.Fatal("expected error when the shell exits")
+	}
+	res, err = bash.Execute(ctx, map[string]interface{}{"command": "echo ${Y:-gone}"})
+	if err != nil || res.Output != "gone" {
+		t.Fatalf("expected a new shell after exit: %q %v", res.Output, err)
+	}
+}
+
+func TestBashSessionTimeoutKillsShell(t *testing.T) {
+	bash, _ := newSessionBash(t, ShellSessionConfig{Scope: ShellScopeSession})
+	ctx := sessionCtx("s")
+	if _, err := bash.Execute(ctx, map[string]interface{}{"command": "Z=1"}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err := bash.Execute(ctx, map[string]interface{}{"command": "sleep 30", "timeout": 0.2})
+	var te *tool.ToolError
+	if !errors.As(err, &te) || te.Category != tool.ErrorTimeout {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Fatal("timeout did not stop the command")
+	}
+	res, err := bash.Execute(ctx, map[string]interface{}{"command": "echo ${Z:-reset}"})
+	if err != nil || res.Output != "reset" {
+		t.Fatalf("expected a new shell after timeout: %q %v", res.Output, err)
+	}
+}
+
+func TestBashSessionKillRunningCommand(t *testing.T) {
+	bash, _ := newSessionBash(t, ShellSessionConfig{Scope: ShellScopeSession})
+	errs := make(chan error, 1)
+	go func() {
+		_, err := bash.Execute(sessionCtx("s"), map[string]interface{}{"command": "sleep 30"})
+		errs <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !bash.ShellSessions().Kill("s") {
+		if time.Now().After(deadline) {
+			t.Fatal("shell never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrShellSessionKilled) {
+			t.Fatalf("expected ErrShellSessionKilled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("kill did not stop the command")
+	}
+
+	_ = bash.ShellSessions().Close()
+	if _, err := bash.Execute(sessionCtx("s"), map[string]interface{}{"command": "true"}); !errors.Is(err, ErrShellSessionsClosed) {
+		t.Fatalf("expected ErrShellSessionsClosed, got %v", err)
+	}
+}
+
+func TestBashSessionIdleTimeoutAndTruncation(t *testing.T) {
+	bash, _ := newSessionBash(t, ShellSessionConfig{Scope: ShellScopeSession, IdleTimeout: 50 * time.Millisecond, MaxOutputBytes: 64})
+	var streamed int
+	res, err := bash.StreamExecute(sessionCtx("s"), map[string]interface{}{"command": "seq 1 1000"}, func(string, bool) { streamed++ })
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if !strings.HasSuffix(res.Output, "[output truncated after 64 bytes]") || res.Data.(map[string]interface{})["truncated"] != true {
+		t.Fatalf("expected truncated output, got %q", res.Output)
+	}
+	if streamed == 0 || streamed >= 1000 {
+		t.Fatalf("expected a truncated stream, got %d lines", streamed)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(bash.ShellSessions().Sessions()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("idle shell was not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
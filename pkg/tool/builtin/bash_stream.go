@@ -6,8 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
-	"os/exec"
 	"sync"
 	"time"
 
@@ -32,15 +30,22 @@ func (b *BashTool) StreamExecute(ctx context.Context, params map[string]interfac
 	if err := b.sandbox.ValidateCommand(command); err != nil {
 		return nil, err
 	}
-	workdir, err := b.resolveWorkdir(params)
+	workdir, err := b.resolveWorkdir(ctx, params)
 	if err != nil {
 		return nil, err
 	}
+	if err := b.checkWriteTargets(command, workdir); err != nil {
+		return nil, err
+	}
 	timeout, err := b.resolveTimeout(params)
 	if err != nil {
 		return nil, err
 	}
 
+	if b.useShell(command) {
+		return b.executeInShell(ctx, params, command, workdir, timeout, emit)
+	}
+
 	execCtx := ctx
 	var cancel context.CancelFunc
 	if timeout > 0 {
@@ -48,8 +53,11 @@ func (b *BashTool) StreamExecute(ctx context.Context, params map[string]interfac
 		defer cancel()
 	}
 
-	cmd := exec.CommandContext(execCtx, "bash", "-c", command)
-	cmd.Env = os.Environ()
+	cmd, err := b.commandContext(execCtx, command, workdir)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Env = commandEnv(ctx)
 	cmd.Dir = workdir
 
 	stdoutPipe, err := cmd.StdoutPipe()
@@ -115,13 +123,7 @@ func (b *BashTool) StreamExecute(ctx context.Context, params map[string]interfac
 	}
 
 	if runErr != nil {
-		if errors.Is(execCtx.Err(), context.DeadlineExceeded) {
-			return result, fmt.Errorf("command timeout after %s", timeout)
-		}
-		if errors.Is(execCtx.Err(), context.Canceled) {
-			return result, execCtx.Err()
-		}
-		return result, fmt.Errorf("command failed: %w", runErr)
+		return result, bashFailure(execCtx, timeout, runErr, spool)
 	}
 	return result, nil
 }
@@ -2,8 +2,26 @@
 
 package toolbuiltin
 
-import "path/filepath"
+import (
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
 
 func bashOutputBaseDir() string {
 	return filepath.Join(string(filepath.Separator), "tmp", "agentsdk", "bash-output")
 }
+
+// startProcessGroup makes cmd lead its own process group so
+// killProcessGroup also stops the commands it started.
+func startProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	_ = cmd.Process.Kill()
+}
@@ -4,9 +4,19 @@ package toolbuiltin
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 )
 
 func bashOutputBaseDir() string {
 	return filepath.Join(os.TempDir(), "agentsdk", "bash-output")
 }
+
+func startProcessGroup(*exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	_ = cmd.Process.Kill()
+}
@@ -0,0 +1,185 @@
+package toolbuiltin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/runtime/blackboard"
+	"github.com/cexll/agentsdk-go/pkg/runtime/subagents"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+const blackboardDescription = `Read and write the shared blackboard for this run. The parent agent and every subagent it dispatches see the same entries, so use it to publish findings, claim work items and coordinate without writing scratch files.
+
+Operations:
+- get: read "key"
+- set: write "value" under "key". Pass "expected_version" (the version you last read, 0 for "must not exist") to avoid overwriting another agent's update; on conflict re-read and retry.
+- delete: remove "key", optionally guarded by "expected_version"
+- list: list entries, optionally filtered by "prefix"`
+
+var blackboardSchema = &tool.JSONSchema{
+	Type: "object",
+	Properties: map[string]interface{}{
+		"operation": map[string]interface{}{
+			"type": "string",
+			"enum": []string{"get", "set", "delete", "list"},
+		},
+		"key": map[string]interface{}{
+			"type":        "string",
+			"description": "Entry key (required for get, set and delete)",
+		},
+		"value": map[string]interface{}{
+			"description": "JSON value to store (set only)",
+		},
+		"expected_version": map[string]interface{}{
+			"type":        "integer",
+			"description": "Optimistic concurrency guard for set/delete",
+		},
+		"prefix": map[string]interface{}{
+			"type":        "string",
+			"description": "Key prefix filter for list",
+		},
+	},
+	Required: []string{"operation"},
+}
+
+// BlackboardTool exposes the run-scoped blackboard attached to the execution
+// context. Without a board in context it falls back to its own board so the
+// tool stays usable outside the runtime.
+type BlackboardTool struct {
+	fallback *blackboard.Board
+}
+
+func NewBlackboardTool() *BlackboardTool {
+	return &BlackboardTool{fallback: blackboard.New()}
+}
+
+func (t *BlackboardTool) Name() string { return "Blackboard" }
+
+func (t *BlackboardTool) Description() string { return blackboardDescription }
+
+func (t *BlackboardTool) Schema() *tool.JSONSchema { return blackboardSchema }
+
+func (t *BlackboardTool) Execute(ctx context.Context, params map[string]interface{}) (*tool.ToolResult, error) {
+	if ctx == nil {
+		return nil, errors.New("context is nil")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	board, ok := blackboard.FromContext(ctx)
+	if !ok {
+		if t == nil || t.fallback == nil {
+			return nil, errors.New("blackboard is not configured")
+		}
+		board = t.fallback
+	}
+	op, err := requiredString(params, "operation")
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(op) {
+	case "get":
+		key, err := requiredString(params, "key")
+		if err != nil {
+			return nil, err
+		}
+		entry, found := board.Get(key)
+		if !found {
+			return &tool.ToolResult{Success: true, Output: fmt.Sprintf("%s: not set (version 0)", key), Data: map[string]interface{}{"found": false}}, nil
+		}
+		return entryResult(entry)
+	case "set":
+		key, err := requiredString(params, "key")
+		if err != nil {
+			return nil, err
+		}
+		value, ok := params["value"]
+		if !ok {
+			return nil, errors.New("value is required")
+		}
+		expected, err := expectedVersion(params)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := board.Put(key, value, expected, writerFromContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		return entryResult(entry)
+	case "delete":
+		key, err := requiredString(params, "key")
+		if err != nil {
+			return nil, err
+		}
+		expected, err := expectedVersion(params)
+		if err != nil {
+			return nil, err
+		}
+		if err := board.Delete(key, expected); err != nil {
+			return nil, err
+		}
+		return &tool.ToolResult{Success: true, Output: fmt.Sprintf("%s deleted", key)}, nil
+	case "list":
+		prefix, err := optionalTrimmedString(params, "prefix")
+		if err != nil {
+			return nil, err
+		}
+		entries := board.List(prefix)
+		var b strings.Builder
+		if len(entries) == 0 {
+			b.WriteString("(empty)")
+		}
+		for i, entry := range entries {
+			if i > 0 {
+				b.WriteByte('\n')
+			}
+			fmt.Fprintf(&b, "%s (v%d): %s", entry.Key, entry.Version, encodeValue(entry.Value))
+		}
+		return &tool.ToolResult{Success: true, Output: b.String(), Data: map[string]interface{}{"entries": entries}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operation %q", op)
+	}
+}
+
+func entryResult(entry blackboard.Entry) (*tool.ToolResult, error) {
+	return &tool.ToolResult{
+		Success: true,
+		Output:  fmt.Sprintf("%s (v%d): %s", entry.Key, entry.Version, encodeValue(entry.Value)),
+		Data:    map[string]interface{}{"found": true, "entry": entry},
+	}, nil
+}
+
+func expectedVersion(params map[string]interface{}) (int64, error) {
+	raw, ok := params["expected_version"]
+	if !ok || raw == nil {
+		return blackboard.AnyVersion, nil
+	}
+	v, err := coerceInt(raw)
+	if err != nil {
+		return 0, fmt.Errorf("expected_version must be integer: %w", err)
+	}
+	if v < 0 {
+		return 0, errors.New("expected_version cannot be negative")
+	}
+	return int64(v), nil
+}
+
+func writerFromContext(ctx context.Context) string {
+	if sub, ok := subagents.FromContext(ctx); ok {
+		return sub.SessionID
+	}
+	return ""
+}
+
+func encodeValue(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,63 @@
+package toolbuiltin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/runtime/blackboard"
+)
+
+func TestBlackboardToolUsesContextBoard(t *testing.T) {
+	board := blackboard.New()
+	ctx := blackboard.WithBoard(context.Background(), board)
+	bb := NewBlackboardTool()
+
+	res, err := bb.Execute(ctx, map[string]interface{}{"operation": "set", "key": "findings/auth", "value": map[string]interface{}{"ok": true}, "expected_version": float64(0)})
+	if err != nil || !strings.Contains(res.Output, "(v1)") {
+		t.Fatalf("set: %+v err=%v", res, err)
+	}
+	if entry, ok := board.Get("findings/auth"); !ok || entry.Version != 1 {
+		t.Fatalf("expected write on shared board, got %+v", entry)
+	}
+
+	_, err = bb.Execute(ctx, map[string]interface{}{"operation": "set", "key": "findings/auth", "value": "x", "expected_version": 0})
+	if !errors.Is(err, blackboard.ErrVersionConflict) {
+		t.Fatalf("expected version conflict, got %v", err)
+	}
+
+	res, err = bb.Execute(ctx, map[string]interface{}{"operation": "list", "prefix": "findings/"})
+	if err != nil || !strings.Contains(res.Output, `findings/auth (v1): {"ok":true}`) {
+		t.Fatalf("list: %+v err=%v", res, err)
+	}
+	res, err = bb.Execute(ctx, map[string]interface{}{"operation": "delete", "key": "findings/auth", "expected_version": 1})
+	if err != nil || !res.Success {
+		t.Fatalf("delete: %+v err=%v", res, err)
+	}
+	res, err = bb.Execute(ctx, map[string]interface{}{"operation": "get", "key": "findings/auth"})
+	if err != nil || !strings.Contains(res.Output, "not set") {
+		t.Fatalf("get: %+v err=%v", res, err)
+	}
+}
+
+func TestBlackboardToolValidation(t *testing.T) {
+	bb := NewBlackboardTool()
+	ctx := context.Background()
+	cases := []map[string]interface{}{
+		{},
+		{"operation": "merge"},
+		{"operation": "set", "key": "k"},
+		{"operation": "set", "key": "k", "value": 1, "expected_version": -1},
+		{"operation": "get"},
+	}
+	for _, params := range cases {
+		if _, err := bb.Execute(ctx, params); err == nil {
+			t.Fatalf("expected error for %v", params)
+		}
+	}
+	// Without a board in context the tool uses its own.
+	if _, err := bb.Execute(ctx, map[string]interface{}{"operation": "set", "key": "k", "value": 1}); err != nil {
+		t.Fatalf("fallback set: %v", err)
+	}
+}
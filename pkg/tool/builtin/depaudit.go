@@ -0,0 +1,358 @@
+package toolbuiltin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+const (
+	defaultAuditTimeout = 2 * time.Minute
+	maxAuditTimeout     = 10 * time.Minute
+	// auditListLimit caps the dependencies listed in Output; Data keeps all
+	// of them.
+	auditListLimit             = 200
+	dependencyAuditDescription = `
+	Inspects the project's dependency manifests and reports versions, licenses and known vulnerabilities.
+
+	- Reads go.mod, package.json (with package-lock.json or node_modules for resolved versions and transitive packages) and requirements*.txt from the working directory; use 'manifests' to pick files explicitly
+	- Offline by default: versions come from the manifests and licenses from lockfiles, node_modules and the Go module cache
+	- Set online=true to query the OSV database (osv.dev) for known vulnerabilities and deps.dev for missing licenses. This sends package names and versions over the network and may require approval
+	- Use direct_only=true to skip transitive dependencies
+	- Python requirements are only checked for vulnerabilities when pinned with ==
+	`
+)
+
+var dependencyAuditSchema = &tool.JSONSchema{
+	Type: "object",
+	Properties: map[string]interface{}{
+		"manifests": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string"},
+			"description": "Manifest files relative to the project directory (go.mod, package.json, requirements*.txt). Defaults to those found in the project directory.",
+		},
+		"online": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Query osv.dev for vulnerabilities and deps.dev for licenses. Requires network access.",
+		},
+		"direct_only": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Only report dependencies declared directly in the manifests.",
+		},
+		"workdir": map[string]interface{}{
+			"type":        "string",
+			"description": "Optional project directory relative to the sandbox root.",
+		},
+		"timeout": map[string]interface{}{
+			"type":        "number",
+			"description": "Optional timeout in seconds (defaults to 120, caps at 600).",
+		},
+	},
+}
+
+// Dependency is one package required by a manifest.
+type Dependency struct {
+	Ecosystem Ecosystem `json:"ecosystem"`
+	Name      string    `json:"name"`
+	// Version is the resolved version; empty when the manifest does not pin
+	// one.
+	Version string `json:"version,omitempty"`
+	// Constraint is the version specifier as declared, for npm and PyPI.
+	Constraint string `json:"constraint,omitempty"`
+	// Replaced is the target of a go.mod replace directive.
+	Replaced string `json:"replaced,omitempty"`
+	Manifest string `json:"manifest"`
+	Direct   bool   `json:"direct"`
+	Dev      bool   `json:"dev,omitempty"`
+	// License is an SPDX identifier or expression when known;
+	// LicenseSource tells where it was found.
+	License         string          `json:"license,omitempty"`
+	LicenseSource   string          `json:"license_source,omitempty"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+}
+
+func (d *Dependency) setLicense(license, source string) {
+	if license = strings.TrimSpace(license); license != "" {
+		d.License, d.LicenseSource = license, source
+	}
+}
+
+// Vulnerability is an OSV advisory affecting a dependency.
+type Vulnerability struct {
+	ID      string   `json:"id"`
+	Aliases []string `json:"aliases,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+	// Severity is the advisory's rating (e.g. HIGH) when given, else its
+	// CVSS vector.
+	Severity string `json:"severity,omitempty"`
+	// FixedIn lists the versions that fix the advisory.
+	FixedIn []string `json:"fixed_in,omitempty"`
+	URL     string   `json:"url"`
+}
+
+// DependencyReport is the structured result of a dependency audit,
+// returned as ToolResult.Data.
+type DependencyReport struct {
+	Manifests    []string     `json:"manifests"`
+	Dependencies []Dependency `json:"dependencies"`
+	// Online reports that vulnerabilities were looked up; without it the
+	// report has no vulnerability data.
+	Online     bool           `json:"online"`
+	Vulnerable int            `json:"vulnerable"`
+	Licenses   map[string]int `json:"licenses,omitempty"`
+	// Errors holds manifests or lookups that failed.
+	Errors     []string `json:"errors,omitempty"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+// DependencyAuditTool reports the dependencies of a project with their
+// licenses and, online, their known vulnerabilities.
+type DependencyAuditTool struct {
+	sandbox    *security.Sandbox
+	root       string
+	timeout    time.Duration
+	client     *http.Client
+	osvURL     string
+	depsDevURL string
+	modCache   func() string
+}
+
+// NewDependencyAuditTool builds a DependencyAuditTool rooted at the provided
+// directory.
+func NewDependencyAuditTool(root string) *DependencyAuditTool {
+	resolved := resolveRoot(root)
+	return NewDependencyAuditToolWithSandbox(resolved, security.NewSandbox(resolved))
+}
+
+// NewDependencyAuditToolWithSandbox builds a DependencyAuditTool with a
+// custom sandbox.
+func NewDependencyAuditToolWithSandbox(root string, sandbox *security.Sandbox) *DependencyAuditTool {
+	return &DependencyAuditTool{
+		sandbox:    sandbox,
+		root:       resolveRoot(root),
+		timeout:    defaultAuditTimeout,
+		client:     &http.Client{},
+		osvURL:     defaultOSVURL,
+		depsDevURL: defaultDepsDevURL,
+		modCache:   goModCache,
+	}
+}
+
+// SetHTTPClient overrides the client used for online lookups, e.g. to route
+// them through a proxy.
+func (d *DependencyAuditTool) SetHTTPClient(client *http.Client) {
+	if d != nil {
+		d.client = cloneHTTPClient(client)
+	}
+}
+
+func (d *DependencyAuditTool) Name() string { return "DependencyAudit" }
+
+// Idempotent allows automatic retries: audits only read.
+func (d *DependencyAuditTool) Idempotent() bool { return true }
+
+func (d *DependencyAuditTool) Description() string { return dependencyAuditDescription }
+
+func (d *DependencyAuditTool) Schema() *tool.JSONSchema { return dependencyAuditSchema }
+
+type auditParams struct {
+	manifests  []string
+	online     bool
+	directOnly bool
+	dir        string
+	timeout    time.Duration
+}
+
+func (d *DependencyAuditTool) Execute(ctx context.Context, params map[string]interface{}) (*tool.ToolResult, error) {
+	if ctx == nil {
+		return nil, errors.New("context is nil")
+	}
+	if d == nil || d.sandbox == nil {
+		return nil, errors.New("dependency audit tool is not initialised")
+	}
+	p, err := d.parseParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.manifests) == 0 {
+		if p.manifests = detectManifests(p.dir); len(p.manifests) == 0 {
+			return nil, fmt.Errorf("no dependency manifests found in %s", p.dir)
+		}
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	started := time.Now()
+	report := &DependencyReport{Manifests: p.manifests, Online: p.online}
+	for _, manifest := range p.manifests {
+		deps, err := parseManifest(filepath.Join(p.dir, manifest))
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", manifest, err))
+			continue
+		}
+		for _, dep := range deps {
+			if p.directOnly && !dep.Direct {
+				continue
+			}
+			dep.Manifest = filepath.ToSlash(manifest)
+			report.Dependencies = append(report.Dependencies, dep)
+		}
+	}
+	d.goLicenses(report.Dependencies)
+	if p.online {
+		report.Errors = append(report.Errors, d.lookup(execCtx, report.Dependencies)...)
+		if errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+			return nil, tool.NewToolError(tool.ErrorTimeout, fmt.Errorf("dependency audit timed out after %s", p.timeout))
+		}
+	}
+	for _, dep := range report.Dependencies {
+		if len(dep.Vulnerabilities) > 0 {
+			report.Vulnerable++
+		}
+		if dep.License != "" {
+			if report.Licenses == nil {
+				report.Licenses = map[string]int{}
+			}
+			report.Licenses[dep.License]++
+		}
+	}
+	report.DurationMs = time.Since(started).Milliseconds()
+	return &tool.ToolResult{
+		Success: len(report.Errors) == 0 && report.Vulnerable == 0,
+		Output:  formatDependencyReport(report),
+		Data:    report,
+	}, nil
+}
+
+func (d *DependencyAuditTool) parseParams(ctx context.Context, params map[string]interface{}) (auditParams, error) {
+	p := auditParams{timeout: d.timeout}
+	for key, dst := range map[string]*bool{"online": &p.online, "direct_only": &p.directOnly} {
+		if raw, ok := params[key]; ok && raw != nil {
+			value, err := coerceBool(raw)
+			if err != nil {
+				return p, fmt.Errorf("%s must be boolean: %w", key, err)
+			}
+			*dst = value
+		}
+	}
+	if raw, ok := params["timeout"]; ok && raw != nil {
+		dur, err := durationFromParam(raw)
+		if err != nil {
+			return p, fmt.Errorf("invalid timeout: %w", err)
+		}
+		if dur > 0 {
+			p.timeout = min(dur, maxAuditTimeout)
+		}
+	}
+	dir, err := resolveProjectDir(ctx, d.sandbox, d.root, params)
+	if err != nil {
+		return p, err
+	}
+	p.dir = dir
+	if raw, ok := params["manifests"]; ok && raw != nil {
+		items, err := coerceInterfaceArray(raw, "manifests")
+		if err != nil {
+			return p, err
+		}
+		for _, item := range items {
+			path, err := coerceString(item)
+			if err != nil {
+				return p, fmt.Errorf("manifests must be strings: %w", err)
+			}
+			if path = strings.TrimSpace(path); path == "" {
+				continue
+			}
+			abs := path
+			if !filepath.IsAbs(abs) {
+				abs = filepath.Join(dir, abs)
+			}
+			abs = filepath.Clean(abs)
+			if err := d.sandbox.ValidatePath(abs); err != nil {
+				return p, err
+			}
+			base := filepath.Base(abs)
+			if base != "go.mod" && base != "package.json" && !strings.HasSuffix(base, ".txt") {
+				return p, fmt.Errorf("unsupported manifest %q", path)
+			}
+			rel, err := filepath.Rel(dir, abs)
+			if err != nil {
+				rel = abs
+			}
+			p.manifests = append(p.manifests, rel)
+		}
+	}
+	return p, nil
+}
+
+func formatDependencyReport(r *DependencyReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d dependencies", strings.Join(r.Manifests, ", "), len(r.Dependencies))
+	if r.Online {
+		fmt.Fprintf(&b, ", %d vulnerable", r.Vulnerable)
+	} else {
+		b.WriteString(" (offline; set online=true to check vulnerabilities)")
+	}
+	for _, e := range r.Errors {
+		b.WriteString("\nERROR " + e)
+	}
+	for _, dep := range r.Dependencies {
+		for _, v := range dep.Vulnerabilities {
+			fmt.Fprintf(&b, "\nVULN %s %s@%s: %s", v.ID, dep.Name, dep.Version, v.Summary)
+			if v.Severity != "" {
+				fmt.Fprintf(&b, " [%s]", v.Severity)
+			}
+			if len(v.FixedIn) > 0 {
+				fmt.Fprintf(&b, " (fixed in %s)", strings.Join(v.FixedIn, ", "))
+			}
+		}
+	}
+	if len(r.Licenses) > 0 {
+		licenses := sortedStringKeys(r.Licenses)
+		sort.SliceStable(licenses, func(i, j int) bool { return r.Licenses[licenses[i]] > r.Licenses[licenses[j]] })
+		parts := make([]string, len(licenses))
+		for i, license := range licenses {
+			parts[i] = fmt.Sprintf("%s %d", license, r.Licenses[license])
+		}
+		b.WriteString("\nlicenses: " + strings.Join(parts, ", "))
+	}
+	b.WriteString("\n")
+	for i, dep := range r.Dependencies {
+		if i == auditListLimit {
+			fmt.Fprintf(&b, "\n... %d more", len(r.Dependencies)-i)
+			break
+		}
+		version := dep.Version
+		if version == "" {
+			version = "?"
+			if dep.Constraint != "" {
+				version += " (" + dep.Constraint + ")"
+			}
+		}
+		fmt.Fprintf(&b, "\n%s %s %s", dep.Ecosystem, dep.Name, version)
+		var notes []string
+		if !dep.Direct {
+			notes = append(notes, "indirect")
+		}
+		if dep.Dev {
+			notes = append(notes, "dev")
+		}
+		if dep.Replaced != "" {
+			notes = append(notes, "replaced by "+dep.Replaced)
+		}
+		license := dep.License
+		if license == "" {
+			license = "license unknown"
+		}
+		notes = append(notes, license)
+		fmt.Fprintf(&b, " [%s]", strings.Join(notes, ", "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
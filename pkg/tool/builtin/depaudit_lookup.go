@@ -0,0 +1,415 @@
+package toolbuiltin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	defaultOSVURL     = "https://api.osv.dev/v1"
+	defaultDepsDevURL = "https://api.deps.dev/v3"
+	// osvBatchSize is the query limit of the OSV querybatch endpoint.
+	osvBatchSize = 1000
+	// auditLookupWorkers bounds concurrent advisory and license requests.
+	auditLookupWorkers = 8
+	// licenseLookupLimit caps the deps.dev requests of one audit.
+	licenseLookupLimit = 500
+	auditResponseLimit = 8 << 20
+	licenseFileLimit   = 64 << 10
+)
+
+// lookup adds OSV vulnerabilities and deps.dev licenses to deps and returns
+// the lookups that failed.
+func (d *DependencyAuditTool) lookup(ctx context.Context, deps []Dependency) []string {
+	var errs []string
+	if err := d.osvVulnerabilities(ctx, deps); err != nil {
+		errs = append(errs, "osv: "+err.Error())
+	}
+	if err := d.depsDevLicenses(ctx, deps); err != nil {
+		errs = append(errs, "deps.dev: "+err.Error())
+	}
+	return errs
+}
+
+type osvPackage struct {
+	Name      string `json:"name"`
+	Ecosystem string `json:"ecosystem"`
+}
+
+type osvVuln struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases"`
+	Summary  string   `json:"summary"`
+	Details  string   `json:"details"`
+	Severity []struct {
+		Score string `json:"score"`
+	} `json:"severity"`
+	Affected []struct {
+		Package osvPackage `json:"package"`
+		Ranges  []struct {
+			Events []struct {
+				Fixed string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+		DatabaseSpecific struct {
+			Severity string `json:"severity"`
+		} `json:"database_specific"`
+	} `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// osvVersion converts a manifest version to the form OSV matches against.
+func osvVersion(dep Dependency) string {
+	if dep.Ecosystem == EcosystemGo {
+		return strings.TrimPrefix(dep.Version, "v")
+	}
+	return dep.Version
+}
+
+func (d *DependencyAuditTool) osvVulnerabilities(ctx context.Context, deps []Dependency) error {
+	type query struct {
+		Package osvPackage `json:"package"`
+		Version string     `json:"version"`
+	}
+	var (
+		queries []query
+		indexes []int
+	)
+	for i, dep := range deps {
+		if dep.Version == "" {
+			continue
+		}
+		queries = append(queries, query{Package: osvPackage{Name: dep.Name, Ecosystem: string(dep.Ecosystem)}, Version: osvVersion(dep)})
+		indexes = append(indexes, i)
+	}
+
+	affected := map[string][]int{}
+	for start := 0; start < len(queries); start += osvBatchSize {
+		end := min(start+osvBatchSize, len(queries))
+		var resp struct {
+			Results []struct {
+				Vulns []struct {
+					ID string `json:"id"`
+				} `json:"vulns"`
+			} `json:"results"`
+		}
+		if err := d.postJSON(ctx, d.osvURL+"/querybatch", map[string]any{"queries": queries[start:end]}, &resp); err != nil {
+			return err
+		}
+		if len(resp.Results) != end-start {
+			return fmt.Errorf("querybatch returned %d results for %d queries", len(resp.Results), end-start)
+		}
+		for i, result := range resp.Results {
+			for _, v := range result.Vulns {
+				affected[v.ID] = append(affected[v.ID], indexes[start+i])
+			}
+		}
+	}
+
+	ids := sortedStringKeys(affected)
+	details := make([]*osvVuln, len(ids))
+	err := forEachLimited(ctx, len(ids), func(i int) error {
+		var v osvVuln
+		if err := d.getJSON(ctx, d.osvURL+"/vulns/"+url.PathEscape(ids[i]), &v); err != nil {
+			return fmt.Errorf("%s: %w", ids[i], err)
+		}
+		details[i] = &v
+		return nil
+	})
+	for i, id := range ids {
+		for _, idx := range affected[id] {
+			deps[idx].Vulnerabilities = append(deps[idx].Vulnerabilities, osvToVulnerability(id, details[i], deps[idx]))
+		}
+	}
+	return err
+}
+
+// osvToVulnerability summarizes an advisory for dep; v is nil when its
+// details could not be fetched.
+func osvToVulnerability(id string, v *osvVuln, dep Dependency) Vulnerability {
+	out := Vulnerability{ID: id, URL: "https://osv.dev/vulnerability/" + id}
+	if v == nil {
+		return out
+	}
+	out.Aliases = v.Aliases
+	out.Summary = v.Summary
+	if out.Summary == "" {
+		out.Summary, _, _ = strings.Cut(strings.TrimSpace(v.Details), "\n")
+	}
+	out.Severity = v.DatabaseSpecific.Severity
+	for _, a := range v.Affected {
+		if !strings.EqualFold(a.Package.Ecosystem, string(dep.Ecosystem)) || a.Package.Name != dep.Name {
+			continue
+		}
+		if out.Severity == "" {
+			out.Severity = a.DatabaseSpecific.Severity
+		}
+		for _, r := range a.Ranges {
+			for _, e := range r.Events {
+				if e.Fixed != "" && !containsString(out.FixedIn, e.Fixed) {
+					out.FixedIn = append(out.FixedIn, e.Fixed)
+				}
+			}
+		}
+	}
+	if out.Severity == "" && len(v.Severity) > 0 {
+		out.Severity = v.Severity[0].Score
+	}
+	return out
+}
+
+// depsDevSystems maps ecosystems to deps.dev system names.
+var depsDevSystems = map[Ecosystem]string{EcosystemGo: "go", EcosystemNPM: "npm", EcosystemPyPI: "pypi"}
+
+func (d *DependencyAuditTool) depsDevLicenses(ctx context.Context, deps []Dependency) error {
+	var missing []int
+	for i, dep := range deps {
+		if dep.License == "" && dep.Version != "" && len(missing) < licenseLookupLimit {
+			missing = append(missing, i)
+		}
+	}
+	var (
+		mu     sync.Mutex
+		failed int
+	)
+	err := forEachLimited(ctx, len(missing), func(n int) error {
+		dep := &deps[missing[n]]
+		var resp struct {
+			Licenses []string `json:"licenses"`
+		}
+		endpoint := fmt.Sprintf("%s/systems/%s/packages/%s/versions/%s", d.depsDevURL, depsDevSystems[dep.Ecosystem], url.PathEscape(dep.Name), url.PathEscape(dep.Version))
+		err := d.getJSON(ctx, endpoint, &resp)
+		if errors.Is(err, errLookupNotFound) {
+			return nil
+		}
+		if err != nil {
+			mu.Lock()
+			failed++
+			mu.Unlock()
+			return err
+		}
+		var licenses []string
+		for _, l := range resp.Licenses {
+			if l = strings.TrimSpace(l); l != "" && l != "non-standard" {
+				licenses = append(licenses, l)
+			}
+		}
+		dep.setLicense(strings.Join(licenses, " AND "), "deps.dev")
+		return nil
+	})
+	if err != nil && failed > 1 {
+		return fmt.Errorf("%d lookups failed, first: %w", failed, err)
+	}
+	return err
+}
+
+var errLookupNotFound = errors.New("not found")
+
+func (d *DependencyAuditTool) postJSON(ctx context.Context, endpoint string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return d.doJSON(req, out)
+}
+
+func (d *DependencyAuditTool) getJSON(ctx context.Context, endpoint string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	return d.doJSON(req, out)
+}
+
+func (d *DependencyAuditTool) doJSON(req *http.Request, out any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, auditResponseLimit))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return errLookupNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: HTTP %d", req.Method, req.URL.Host, resp.StatusCode)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// forEachLimited calls fn for 0..n-1 on auditLookupWorkers goroutines and
+// returns the first error; the remaining calls still run.
+func forEachLimited(ctx context.Context, n int, fn func(i int) error) error {
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	next := make(chan int)
+	for w := 0; w < min(auditLookupWorkers, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := fn(i); err != nil {
+					once.Do(func() { first = err })
+				}
+			}
+		}()
+	}
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if first == nil {
+		first = ctx.Err()
+	}
+	return first
+}
+
+// goLicenses fills in the licenses of Go modules found in the module cache.
+func (d *DependencyAuditTool) goLicenses(deps []Dependency) {
+	cache := ""
+	if d.modCache != nil {
+		cache = d.modCache()
+	}
+	if cache == "" {
+		return
+	}
+	for i := range deps {
+		dep := &deps[i]
+		if dep.Ecosystem != EcosystemGo || dep.License != "" || dep.Version == "" {
+			continue
+		}
+		dir := filepath.Join(cache, filepath.FromSlash(escapeModulePath(dep.Name)+"@"+escapeModulePath(dep.Version)))
+		dep.setLicense(licenseInDir(dir), "module cache")
+	}
+}
+
+// goModCache locates the Go module cache without running the go command.
+func goModCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		gopath = filepath.Join(home, "go")
+	}
+	return filepath.Join(filepath.SplitList(gopath)[0], "pkg", "mod")
+}
+
+// escapeModulePath applies the module cache case encoding: each upper-case
+// letter becomes '!' followed by its lower-case form.
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// licenseInDir classifies the license file at the top of dir.
+func licenseInDir(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		name := strings.ToUpper(entry.Name())
+		if entry.IsDir() || !(strings.HasPrefix(name, "LICENSE") || strings.HasPrefix(name, "LICENCE") || strings.HasPrefix(name, "COPYING")) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(f, licenseFileLimit))
+		f.Close()
+		if err != nil {
+			continue
+		}
+		if license := classifyLicense(string(data)); license != "" {
+			return license
+		}
+	}
+	return ""
+}
+
+// licenseMarkers identify common licenses by phrases of their text, most
+// specific first.
+var licenseMarkers = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 2.1"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 2"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+}
+
+// classifyLicense returns the SPDX identifier of a license text, or "" when
+// it is not recognised.
+func classifyLicense(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	for _, marker := range licenseMarkers {
+		matched := true
+		for _, phrase := range marker.phrases {
+			if !strings.Contains(text, phrase) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return marker.id
+		}
+	}
+	return ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
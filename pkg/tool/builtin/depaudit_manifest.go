@@ -0,0 +1,333 @@
+package toolbuiltin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ecosystem names a package ecosystem using the OSV spelling.
+type Ecosystem string
+
+const (
+	EcosystemGo   Ecosystem = "Go"
+	EcosystemNPM  Ecosystem = "npm"
+	EcosystemPyPI Ecosystem = "PyPI"
+)
+
+// detectManifests lists the dependency manifests in dir, in a stable order.
+func detectManifests(dir string) []string {
+	var out []string
+	for _, name := range []string{"go.mod", "package.json"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.Mode().IsRegular() {
+			out = append(out, name)
+		}
+	}
+	reqs, _ := filepath.Glob(filepath.Join(dir, "requirements*.txt"))
+	sort.Strings(reqs)
+	for _, path := range reqs {
+		out = append(out, filepath.Base(path))
+	}
+	return out
+}
+
+// parseManifest reads the dependencies declared by the manifest at path.
+func parseManifest(path string) ([]Dependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(path)
+	switch {
+	case name == "go.mod":
+		return parseGoMod(data)
+	case name == "package.json":
+		return parsePackageJSON(data, filepath.Dir(path))
+	case strings.HasSuffix(name, ".txt"):
+		lower := strings.ToLower(name)
+		return parseRequirements(data, strings.Contains(lower, "dev") || strings.Contains(lower, "test")), nil
+	}
+	return nil, fmt.Errorf("unsupported manifest %s", name)
+}
+
+// parseGoMod reads the require and replace directives of a go.mod file.
+// Replacements with a version stand in for the module they replace; local
+// replacements leave Version empty.
+func parseGoMod(data []byte) ([]Dependency, error) {
+	var deps []Dependency
+	replaces := map[string][]string{}
+	handle := func(lineNo int, directive string, fields []string, comment string) error {
+		switch directive {
+		case "require":
+			if len(fields) < 2 {
+				return fmt.Errorf("go.mod:%d: malformed require", lineNo)
+			}
+			deps = append(deps, Dependency{
+				Ecosystem: EcosystemGo,
+				Name:      unquoteGoMod(fields[0]),
+				Version:   fields[1],
+				Direct:    !strings.HasPrefix(comment, "indirect"),
+			})
+		case "replace":
+			arrow := -1
+			for i, f := range fields {
+				if f == "=>" {
+					arrow = i
+				}
+			}
+			if arrow < 1 || arrow == len(fields)-1 {
+				return fmt.Errorf("go.mod:%d: malformed replace", lineNo)
+			}
+			replaces[unquoteGoMod(fields[0])] = fields[arrow+1:]
+		}
+		return nil
+	}
+
+	block := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line, comment, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		comment = strings.TrimSpace(comment)
+		if len(fields) == 0 {
+			continue
+		}
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			if err := handle(lineNo, block, fields, comment); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if fields[0] != "require" && fields[0] != "replace" {
+			continue
+		}
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		if err := handle(lineNo, fields[0], fields[1:], comment); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for i := range deps {
+		target, ok := replaces[deps[i].Name]
+		if !ok {
+			continue
+		}
+		path := unquoteGoMod(target[0])
+		if len(target) < 2 {
+			deps[i].Replaced = path
+			deps[i].Version = ""
+			continue
+		}
+		deps[i].Replaced = path + "@" + target[1]
+		deps[i].Name, deps[i].Version = path, target[1]
+	}
+	return deps, nil
+}
+
+func unquoteGoMod(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+// packageLock is the subset of package-lock.json (lockfileVersion 1-3) used
+// to resolve versions and licenses.
+type packageLock struct {
+	Packages map[string]struct {
+		Version string          `json:"version"`
+		License json.RawMessage `json:"license"`
+		Dev     bool            `json:"dev"`
+		Link    bool            `json:"link"`
+	} `json:"packages"`
+	Dependencies map[string]struct {
+		Version string `json:"version"`
+		Dev     bool   `json:"dev"`
+	} `json:"dependencies"`
+}
+
+// parsePackageJSON reads the dependencies of package.json. Versions and the
+// transitive dependencies come from package-lock.json when present, else
+// from the installed node_modules.
+func parsePackageJSON(data []byte, dir string) ([]Dependency, error) {
+	var manifest struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("package.json: %w", err)
+	}
+	var lock packageLock
+	if raw, err := os.ReadFile(filepath.Join(dir, "package-lock.json")); err == nil {
+		if err := json.Unmarshal(raw, &lock); err != nil {
+			return nil, fmt.Errorf("package-lock.json: %w", err)
+		}
+	}
+
+	var deps []Dependency
+	direct := map[string]bool{}
+	add := func(specs map[string]string, dev bool) {
+		for _, name := range sortedStringKeys(specs) {
+			if direct[name] {
+				continue
+			}
+			direct[name] = true
+			dep := Dependency{Ecosystem: EcosystemNPM, Name: name, Constraint: specs[name], Direct: true, Dev: dev}
+			if entry, ok := lock.Packages["node_modules/"+name]; ok {
+				dep.Version = entry.Version
+				dep.setLicense(npmLicense(entry.License), "package-lock.json")
+			} else if entry, ok := lock.Dependencies[name]; ok {
+				dep.Version = entry.Version
+			}
+			if dep.Version == "" || dep.License == "" {
+				version, license := installedNPMPackage(dir, name)
+				if dep.Version == "" {
+					dep.Version = version
+				}
+				if dep.License == "" {
+					dep.setLicense(license, "node_modules")
+				}
+			}
+			deps = append(deps, dep)
+		}
+	}
+	add(manifest.Dependencies, false)
+	add(manifest.OptionalDependencies, false)
+	add(manifest.DevDependencies, true)
+
+	// Transitive dependencies pinned by the lockfile.
+	seen := map[string]bool{}
+	for _, key := range sortedStringKeys(lock.Packages) {
+		idx := strings.LastIndex(key, "node_modules/")
+		if idx < 0 {
+			continue
+		}
+		entry := lock.Packages[key]
+		name := key[idx+len("node_modules/"):]
+		if entry.Link || entry.Version == "" || (direct[name] && key == "node_modules/"+name) || seen[name+"@"+entry.Version] {
+			continue
+		}
+		seen[name+"@"+entry.Version] = true
+		dep := Dependency{Ecosystem: EcosystemNPM, Name: name, Version: entry.Version, Dev: entry.Dev}
+		dep.setLicense(npmLicense(entry.License), "package-lock.json")
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// installedNPMPackage reads the version and license of an installed package.
+func installedNPMPackage(dir, name string) (version, license string) {
+	data, err := os.ReadFile(filepath.Join(dir, "node_modules", filepath.FromSlash(name), "package.json"))
+	if err != nil {
+		return "", ""
+	}
+	var pkg struct {
+		Version string          `json:"version"`
+		License json.RawMessage `json:"license"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return "", ""
+	}
+	return pkg.Version, npmLicense(pkg.License)
+}
+
+// npmLicense reads a license field, either an SPDX string or the legacy
+// {"type": ...} object.
+func npmLicense(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	var obj struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(raw, &obj) == nil {
+		return strings.TrimSpace(obj.Type)
+	}
+	return ""
+}
+
+var requirementPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(\[[^\]]*\])?\s*(.*)$`)
+
+// parseRequirements reads a pip requirements file. Only == and === pins set
+// Version; other specifiers are kept as Constraint. Options, includes,
+// editable installs and URLs are skipped.
+func parseRequirements(data []byte, dev bool) []Dependency {
+	var deps []Dependency
+	var pending string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasSuffix(line, "\\") {
+			pending += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		line = pending + line
+		pending = ""
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+			continue
+		}
+		spec, _, _ := strings.Cut(line, ";")
+		if i := strings.Index(spec, " --"); i >= 0 {
+			spec = spec[:i]
+		}
+		m := requirementPattern.FindStringSubmatch(strings.TrimSpace(spec))
+		if m == nil {
+			continue
+		}
+		dep := Dependency{
+			Ecosystem:  EcosystemPyPI,
+			Name:       normalizePyPIName(m[1]),
+			Constraint: strings.ReplaceAll(strings.TrimSpace(m[3]), " ", ""),
+			Direct:     true,
+			Dev:        dev,
+		}
+		if version, ok := strings.CutPrefix(dep.Constraint, "==="); ok {
+			dep.Version = version
+		} else if version, ok := strings.CutPrefix(dep.Constraint, "=="); ok && !strings.ContainsAny(version, ",*") {
+			dep.Version = version
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
+
+var pypiNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// normalizePyPIName applies the PEP 503 name normalization.
+func normalizePyPIName(name string) string {
+	return strings.ToLower(pypiNameSeparators.ReplaceAllString(name, "-"))
+}
+
+func sortedStringKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
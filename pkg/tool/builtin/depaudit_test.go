@@ -0,0 +1,261 @@
+package toolbuiltin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGoMod(t *testing.T) {
+	t.Parallel()
+
+	gomod := `module example.com/app
+
+go 1.22
+
+require github.com/pkg/errors v0.9.1
+
+require (
+	github.com/BurntSushi/toml v1.3.2 // indirect
+	golang.org/x/text v0.3.0
+	example.com/local v1.0.0
+)
+
+replace golang.org/x/text => golang.org/x/text v0.3.8
+
+replace (
+	example.com/local => ../local
+)
+`
+	deps, err := parseGoMod([]byte(gomod))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(deps) != 4 {
+		t.Fatalf("got %d deps: %+v", len(deps), deps)
+	}
+	if d := deps[0]; d.Name != "github.com/pkg/errors" || d.Version != "v0.9.1" || !d.Direct {
+		t.Fatalf("unexpected single-line require %+v", d)
+	}
+	if d := deps[1]; d.Name != "github.com/BurntSushi/toml" || d.Direct {
+		t.Fatalf("indirect not detected %+v", d)
+	}
+	if d := deps[2]; d.Version != "v0.3.8" || d.Replaced != "golang.org/x/text@v0.3.8" {
+		t.Fatalf("versioned replace not applied %+v", d)
+	}
+	if d := deps[3]; d.Version != "" || d.Replaced != "../local" {
+		t.Fatalf("local replace not applied %+v", d)
+	}
+	if _, err := parseGoMod([]byte("require (\n\tbroken\n)\n")); err == nil {
+		t.Fatal("expected malformed require error")
+	}
+}
+
+func TestParsePackageJSONWithLock(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeAuditFile(t, dir, "package-lock.json", `{"lockfileVersion":3,"packages":{
+"":{"name":"app"},
+"node_modules/lodash":{"version":"4.17.20","license":"MIT"},
+"node_modules/jest":{"version":"29.0.0","dev":true,"license":"MIT"},
+"node_modules/jest/node_modules/chalk":{"version":"4.1.2","dev":true,"license":"MIT"},
+"node_modules/chalk":{"version":"5.0.0","license":"MIT"},
+"node_modules/app-lib":{"link":true}}}`)
+	writeAuditFile(t, dir, "node_modules/left-pad/package.json", `{"version":"1.3.0","license":{"type":"WTFPL"}}`)
+	manifest := `{"dependencies":{"lodash":"^4.17.0","left-pad":"^1.0.0"},"devDependencies":{"jest":"^29.0.0"}}`
+
+	deps, err := parsePackageJSON([]byte(manifest), dir)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	byName := map[string]Dependency{}
+	for _, d := range deps {
+		byName[d.Name+"@"+d.Version] = d
+	}
+	if len(deps) != 5 {
+		t.Fatalf("got %d deps: %+v", len(deps), deps)
+	}
+	if d := byName["lodash@4.17.20"]; !d.Direct || d.Constraint != "^4.17.0" || d.License != "MIT" || d.LicenseSource != "package-lock.json" {
+		t.Fatalf("unexpected lodash %+v", d)
+	}
+	if d := byName["left-pad@1.3.0"]; d.License != "WTFPL" || d.LicenseSource != "node_modules" {
+		t.Fatalf("installed package not read %+v", d)
+	}
+	if d := byName["jest@29.0.0"]; !d.Dev || !d.Direct {
+		t.Fatalf("unexpected jest %+v", d)
+	}
+	if d := byName["chalk@4.1.2"]; d.Direct || !d.Dev {
+		t.Fatalf("unexpected nested chalk %+v", d)
+	}
+	if d := byName["chalk@5.0.0"]; d.Direct {
+		t.Fatalf("unexpected chalk %+v", d)
+	}
+}
+
+func TestParseRequirements(t *testing.T) {
+	t.Parallel()
+
+	reqs := `# pinned
+Django==3.2.0  # web
+requests[security] >= 2.0, <3 ; python_version > "3.6"
+zope.interface===5.4.0
+numpy==1.*
+-r base.txt
+-e git+https://example.com/x.git#egg=x
+https://example.com/pkg.tar.gz
+flask==2.0.1 \
+    --hash=sha256:abc
+`
+	deps := parseRequirements([]byte(reqs), true)
+	if len(deps) != 5 {
+		t.Fatalf("got %d deps: %+v", len(deps), deps)
+	}
+	want := []struct{ name, version, constraint string }{
+		{"django", "3.2.0", "==3.2.0"},
+		{"requests", "", ">=2.0,<3"},
+		{"zope-interface", "5.4.0", "===5.4.0"},
+		{"numpy", "", "==1.*"},
+		{"flask", "2.0.1", "==2.0.1"},
+	}
+	for i, w := range want {
+		if d := deps[i]; d.Name != w.name || d.Version != w.version || d.Constraint != w.constraint || !d.Dev || d.Ecosystem != EcosystemPyPI {
+			t.Fatalf("dep %d = %+v, want %+v", i, d, w)
+		}
+	}
+}
+
+func TestClassifyLicense(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"Permission is hereby granted, free of charge, to any person":                           "MIT",
+		"Apache License\n   Version 2.0, January 2004":                                          "Apache-2.0",
+		"Redistribution and use in source and binary forms ... Neither the name of Google Inc.": "BSD-3-Clause",
+		"Redistribution and use in source and binary\n forms, with or without modification":     "BSD-2-Clause",
+		"GNU LESSER GENERAL PUBLIC LICENSE Version 3, 29 June 2007":                             "LGPL-3.0",
+		"Some custom terms": "",
+	}
+	for text, want := range cases {
+		if got := classifyLicense(text); got != want {
+			t.Errorf("classifyLicense(%q) = %q, want %q", text, got, want)
+		}
+	}
+	if got := escapeModulePath("github.com/BurntSushi/toml"); got != "github.com/!burnt!sushi/toml" {
+		t.Fatalf("escapeModulePath = %q", got)
+	}
+}
+
+func TestDependencyAuditOnline(t *testing.T) {
+	t.Parallel()
+
+	var queries []map[string]any
+	osv := http.NewServeMux()
+	osv.HandleFunc("/querybatch", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Queries []map[string]any `json:"queries"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		queries = body.Queries
+		results := make([]map[string]any, len(body.Queries))
+		for i, q := range body.Queries {
+			results[i] = map[string]any{}
+			if q["version"] == "0.3.0" {
+				results[i]["vulns"] = []map[string]string{{"id": "GO-2020-0015"}}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": results})
+	})
+	osv.HandleFunc("/vulns/GO-2020-0015", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"GO-2020-0015","aliases":["CVE-2020-14040"],"summary":"Infinite loop when decoding some inputs",
+"affected":[{"package":{"name":"golang.org/x/text","ecosystem":"Go"},"ranges":[{"type":"SEMVER","events":[{"introduced":"0"},{"fixed":"0.3.3"}]}]}],
+"database_specific":{"severity":"HIGH"}}`))
+	})
+	osvSrv := httptest.NewServer(osv)
+	defer osvSrv.Close()
+	depsDev := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.EscapedPath(), "/systems/go/packages/github.com%2Fpkg%2Ferrors/versions/v0.9.1") {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"licenses":["BSD-2-Clause"]}`))
+	}))
+	defer depsDev.Close()
+
+	root := t.TempDir()
+	writeAuditFile(t, root, "go.mod", "module example.com/app\n\nrequire (\n\tgolang.org/x/text v0.3.0\n\tgithub.com/pkg/errors v0.9.1\n)\n")
+	cache := t.TempDir()
+	writeAuditFile(t, cache, "golang.org/x/text@v0.3.0/LICENSE", "Redistribution and use in source and binary forms ... Neither the name of Google Inc.")
+
+	at := NewDependencyAuditTool(root)
+	at.osvURL, at.depsDevURL = osvSrv.URL, depsDev.URL
+	at.modCache = func() string { return cache }
+
+	res, err := at.Execute(context.Background(), map[string]interface{}{"online": true})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	report := res.Data.(*DependencyReport)
+	if res.Success || report.Vulnerable != 1 || len(report.Errors) != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if len(queries) != 2 || queries[0]["version"] != "0.3.0" {
+		t.Fatalf("unexpected OSV queries %v", queries)
+	}
+	text := report.Dependencies[0]
+	if text.License != "BSD-3-Clause" || text.LicenseSource != "module cache" {
+		t.Fatalf("module cache license not read %+v", text)
+	}
+	if len(text.Vulnerabilities) != 1 {
+		t.Fatalf("expected one vulnerability, got %+v", text.Vulnerabilities)
+	}
+	if v := text.Vulnerabilities[0]; v.Severity != "HIGH" || len(v.FixedIn) != 1 || v.FixedIn[0] != "0.3.3" || v.Aliases[0] != "CVE-2020-14040" {
+		t.Fatalf("unexpected vulnerability %+v", v)
+	}
+	if errs := report.Dependencies[1]; errs.License != "BSD-2-Clause" || errs.LicenseSource != "deps.dev" {
+		t.Fatalf("deps.dev license not applied %+v", errs)
+	}
+	if !strings.Contains(res.Output, "VULN GO-2020-0015 golang.org/x/text@v0.3.0") {
+		t.Fatalf("vulnerability missing from output:\n%s", res.Output)
+	}
+
+	// Offline audits never touch the network.
+	queries = nil
+	res, err = at.Execute(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("offline execute: %v", err)
+	}
+	if report := res.Data.(*DependencyReport); report.Online || queries != nil || !res.Success {
+		t.Fatalf("offline audit queried OSV: %+v", report)
+	}
+}
+
+func TestDependencyAuditRejectsUnknownManifest(t *testing.T) {
+	t.Parallel()
+
+	at := NewDependencyAuditTool(t.TempDir())
+	if _, err := at.Execute(context.Background(), map[string]interface{}{"manifests": []interface{}{"Cargo.toml"}}); err == nil {
+		t.Fatal("expected unsupported manifest error")
+	}
+	if _, err := at.Execute(context.Background(), map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "no dependency manifests") {
+		t.Fatalf("expected missing manifest error, got %v", err)
+	}
+}
+
+func writeAuditFile(t *testing.T, dir, name, body string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
@@ -20,6 +20,7 @@ Usage:
 - Only use emojis if the user explicitly requests it. Avoid adding emojis to files unless asked.
 - The edit will FAIL if 'old_string' is not unique in the file. Either provide a larger string with more surrounding context to make it unique or use 'replace_all' to change every instance of 'old_string'.
 - Use 'replace_all' for replacing and renaming strings across the file. This parameter is useful if you want to rename a variable for instance.
+- Set 'dry_run' to preview the edit as a unified diff without modifying the file.
 `
 
 var editSchema = &tool.JSONSchema{
@@ -42,6 +43,7 @@ var editSchema = &tool.JSONSchema{
 			"default":     false,
 			"description": "Replace all occurences of old_string (default false)",
 		},
+		"dry_run": dryRunProperty,
 	},
 	Required: []string{"file_path", "old_string", "new_string"},
 }
@@ -101,6 +103,10 @@ func (e *EditTool) Execute(ctx context.Context, params map[string]interface{}) (
 	if err != nil {
 		return nil, err
 	}
+	dryRun, err := parseDryRun(params)
+	if err != nil {
+		return nil, err
+	}
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
@@ -141,19 +147,23 @@ func (e *EditTool) Execute(ctx context.Context, params map[string]interface{}) (
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	if err := os.WriteFile(path, []byte(updated), info.Mode()); err != nil {
-		return nil, fmt.Errorf("write file: %w", err)
+	data := map[string]interface{}{
+		"path":        displayPath(path, e.base.root),
+		"matches":     matches,
+		"replaced":    replacements,
+		"replace_all": replaceAll,
+	}
+	if dryRun {
+		return dryRunResult(displayPath(path, e.base.root), content, updated, data), nil
+	}
+	if err := writeFileAtomic(path, []byte(updated)); err != nil {
+		return nil, err
 	}
 
 	return &tool.ToolResult{
 		Success: true,
 		Output:  fmt.Sprintf("applied %d replacement(s)", replacements),
-		Data: map[string]interface{}{
-			"path":        displayPath(path, e.base.root),
-			"matches":     matches,
-			"replaced":    replacements,
-			"replace_all": replaceAll,
-		},
+		Data:    data,
 	}, nil
 }
 
@@ -165,7 +175,7 @@ func (e *EditTool) resolveFilePath(params map[string]interface{}) (string, error
 	if !ok {
 		return "", errors.New("file_path is required")
 	}
-	return e.base.resolvePath(raw)
+	return e.base.resolveWritePath(raw)
 }
 
 func (e *EditTool) parseRequiredString(params map[string]interface{}, key string) (string, error) {
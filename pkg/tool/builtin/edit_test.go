@@ -252,3 +252,62 @@ func TestEditToolHelperErrors(t *testing.T) {
 		t.Fatalf("expected type error for replace_all helper")
 	}
 }
+
+func TestEditAndWriteDryRunReturnDiff(t *testing.T) {
+	dir := cleanTempDir(t)
+	path := filepath.Join(dir, "main.go")
+	original := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	res, err := NewEditToolWithRoot(dir).Execute(context.Background(), map[string]any{
+		"file_path":  path,
+		"old_string": `println("hi")`,
+		"new_string": `println("bye")`,
+		"dry_run":    true,
+	})
+	if err != nil {
+		t.Fatalf("dry-run edit failed: %v", err)
+	}
+	want := "--- a/main.go\n+++ b/main.go\n@@ -1,5 +1,5 @@\n package main\n \n func main() {\n-\tprintln(\"hi\")\n+\tprintln(\"bye\")\n }\n"
+	if res.Output != want {
+		t.Fatalf("unexpected diff:\n%s", res.Output)
+	}
+	data := res.Data.(map[string]any)
+	if data["dry_run"] != true || data["diff"] != want || data["replaced"].(int) != 1 {
+		t.Fatalf("unexpected data %#v", data)
+	}
+
+	res, err = NewWriteToolWithRoot(dir).Execute(context.Background(), map[string]any{
+		"file_path": "new.txt",
+		"content":   "one\n",
+		"dry_run":   "true",
+	})
+	if err != nil {
+		t.Fatalf("dry-run write failed: %v", err)
+	}
+	if res.Output != "--- a/new.txt\n+++ b/new.txt\n@@ -0,0 +1 @@\n+one\n" {
+		t.Fatalf("unexpected diff:\n%s", res.Output)
+	}
+	res, err = NewWriteToolWithRoot(dir).Execute(context.Background(), map[string]any{
+		"file_path": path,
+		"content":   original,
+		"dry_run":   true,
+	})
+	if err != nil || res.Output != "no changes" {
+		t.Fatalf("unchanged dry run = %v, %v", res, err)
+	}
+
+	if got, _ := os.ReadFile(path); string(got) != original {
+		t.Fatalf("dry run modified file: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("dry run created file: %v", err)
+	}
+	if _, err := NewWriteToolWithRoot(dir).Execute(context.Background(), map[string]any{
+		"file_path": path, "content": "x", "dry_run": "maybe",
+	}); err == nil {
+		t.Fatal("expected invalid dry_run error")
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/fs"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/tool"
 )
 
 const defaultMaxFileBytes = 1 << 20 // 1 MiB
@@ -34,6 +37,31 @@ func newFileSandboxWithSandbox(root string, sandbox *security.Sandbox) *fileSand
 }
 
 func (f *fileSandbox) resolvePath(raw interface{}) (string, error) {
+	candidate, err := f.candidatePath(raw)
+	if err != nil {
+		return "", err
+	}
+	if err := f.sandbox.ValidatePath(candidate); err != nil {
+		return "", err
+	}
+	return candidate, nil
+}
+
+// resolveWritePath is resolvePath for files about to be modified: the path
+// must stay inside the write scope, and a blocked write is reported as a
+// *security.WriteScopeError naming the writable roots.
+func (f *fileSandbox) resolveWritePath(raw interface{}) (string, error) {
+	candidate, err := f.candidatePath(raw)
+	if err != nil {
+		return "", err
+	}
+	if err := f.sandbox.ValidateWrite(candidate); err != nil {
+		return "", err
+	}
+	return candidate, nil
+}
+
+func (f *fileSandbox) candidatePath(raw interface{}) (string, error) {
 	if f == nil || f.sandbox == nil {
 		return "", errors.New("file sandbox is not initialised")
 	}
@@ -52,11 +80,7 @@ func (f *fileSandbox) resolvePath(raw interface{}) (string, error) {
 	if !filepath.IsAbs(candidate) {
 		candidate = filepath.Join(f.root, candidate)
 	}
-	candidate = filepath.Clean(candidate)
-	if err := f.sandbox.ValidatePath(candidate); err != nil {
-		return "", err
-	}
-	return candidate, nil
+	return filepath.Clean(candidate), nil
 }
 
 func (f *fileSandbox) readFile(path string) (string, error) {
@@ -97,8 +121,97 @@ func (f *fileSandbox) writeFile(path string, content string) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("ensure directory: %w", err)
 	}
-	if err := os.WriteFile(path, data, 0o666); err != nil { //nolint:gosec // respect umask for created files
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so readers never see a partial write. An existing file
+// keeps its permissions; new files get 0o666 minus the umask. Symlinks are
+// rejected earlier by the sandbox.
+func writeFileAtomic(path string, data []byte) error {
+	perm := os.FileMode(0o666)
+	keepPerm := false
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", path)
+		}
+		perm, keepPerm = info.Mode().Perm(), true
+	}
+
+	dir, base := filepath.Split(path)
+	var tmp *os.File
+	var err error
+	for range 10 {
+		name := filepath.Join(dir, fmt.Sprintf(".%s.%d.tmp", base, rand.Uint32()))
+		tmp, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm) //nolint:gosec // respect umask for created files
+		if !errors.Is(err, fs.ErrExist) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	if keepPerm {
+		// The umask may have narrowed the temporary file's mode.
+		if err := tmp.Chmod(perm); err != nil {
+			return fmt.Errorf("write file: %w", err)
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
 		return fmt.Errorf("write file: %w", err)
 	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	committed = true
 	return nil
 }
+
+// dryRunProperty is the schema of the dry_run flag shared by Write and Edit.
+var dryRunProperty = map[string]interface{}{
+	"type":        "boolean",
+	"default":     false,
+	"description": "Return a unified diff of the change without modifying the file",
+}
+
+// parseDryRun reads the optional dry_run flag of Write and Edit.
+func parseDryRun(params map[string]interface{}) (bool, error) {
+	raw, ok := params["dry_run"]
+	if !ok || raw == nil {
+		return false, nil
+	}
+	value, err := coerceBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("dry_run must be boolean: %w", err)
+	}
+	return value, nil
+}
+
+// dryRunResult reports the unified diff a Write or Edit would apply. data
+// is extended with the diff and the dry_run marker.
+func dryRunResult(display, before, after string, data map[string]interface{}) *tool.ToolResult {
+	diff := unifiedDiff(filepath.ToSlash(display), before, after)
+	if before == after {
+		diff = ""
+	}
+	data["dry_run"] = true
+	data["diff"] = diff
+	output := diff
+	if output == "" {
+		output = "no changes"
+	}
+	return &tool.ToolResult{Success: true, Output: output, Data: data}
+}
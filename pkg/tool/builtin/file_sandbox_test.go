@@ -1,12 +1,15 @@
 package toolbuiltin
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/tool"
 )
 
 func TestFileSandboxResolveReadWrite(t *testing.T) {
@@ -105,3 +108,40 @@ func TestFileSandboxNilAndDirErrors(t *testing.T) {
 		t.Fatalf("expected directory error, got %v", err)
 	}
 }
+
+func TestFileToolsEnforceWriteScope(t *testing.T) {
+	skipIfWindows(t)
+	root := cleanTempDir(t)
+	extra := cleanTempDir(t)
+	outside := cleanTempDir(t)
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	sb := security.NewSandbox(root)
+	sb.Allow(extra)
+	write := NewWriteToolWithSandbox(root, sb)
+
+	if _, err := write.Execute(context.Background(), map[string]any{"file_path": filepath.Join(extra, "ok.txt"), "content": "x"}); err != nil {
+		t.Fatalf("write to additional directory: %v", err)
+	}
+	_, err := write.Execute(context.Background(), map[string]any{"file_path": "link/pwned.txt", "content": "x"})
+	var blocked *security.WriteScopeError
+	if !errors.As(err, &blocked) || blocked.Reason != security.WriteSymlinkEscape {
+		t.Fatalf("expected symlink escape, got %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(outside, "pwned.txt")); !os.IsNotExist(statErr) {
+		t.Fatalf("write escaped through the symlink: %v", statErr)
+	}
+	if detail := tool.ClassifyError(err); detail.Category != tool.ErrorPermission || !strings.Contains(detail.Message, extra) {
+		t.Fatalf("expected permission error listing writable roots, got %+v", detail)
+	}
+
+	bash := NewBashToolWithSandbox(root, sb)
+	bash.AllowShellMetachars(true)
+	if _, err := bash.Execute(context.Background(), map[string]any{"command": "echo hi > " + filepath.Join(outside, "out.txt")}); !errors.Is(err, security.ErrWriteOutsideScope) {
+		t.Fatalf("expected bash redirection to be blocked, got %v", err)
+	}
+	if _, err := bash.Execute(context.Background(), map[string]any{"command": "echo hi | tee " + filepath.Join(extra, "tee.txt")}); err != nil {
+		t.Fatalf("tee into additional directory: %v", err)
+	}
+}
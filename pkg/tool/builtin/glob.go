@@ -84,6 +84,9 @@ func (g *GlobTool) SetRespectGitignore(respect bool) {
 
 func (g *GlobTool) Name() string { return "Glob" }
 
+// Idempotent allows automatic retries: matching files has no side effects.
+func (g *GlobTool) Idempotent() bool { return true }
+
 func (g *GlobTool) Description() string { return globToolDesc }
 
 func (g *GlobTool) Schema() *tool.JSONSchema { return globSchema }
@@ -158,6 +158,9 @@ func (g *GrepTool) SetRespectGitignore(respect bool) {
 
 func (g *GrepTool) Name() string { return "Grep" }
 
+// Idempotent allows automatic retries: searching has no side effects.
+func (g *GrepTool) Idempotent() bool { return true }
+
 func (g *GrepTool) Description() string { return grepToolDesc }
 
 func (g *GrepTool) Schema() *tool.JSONSchema { return grepSchema }
@@ -0,0 +1,642 @@
+package toolbuiltin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+const (
+	defaultLintTimeout = 5 * time.Minute
+	maxLintTimeout     = 30 * time.Minute
+	// lintDiagnosticLimit caps the diagnostics listed in Output; Data keeps
+	// all of them.
+	lintDiagnosticLimit = 200
+	lintDescription     = `
+	Runs the project's linters and formatters and returns their diagnostics.
+
+	- Detects configured tools from the working directory: golangci-lint (.golangci.*), gofmt (go.mod), ruff (ruff.toml, pyproject.toml [tool.ruff]) and eslint (eslint config with eslint installed in node_modules)
+	- Use 'linters' to pick tools explicitly and 'paths' to limit the files or directories checked
+	- Set fix=true to apply the tools' auto-fixes; the result lists the changed files with a diff and the diagnostics that remain
+	- Run this after editing files, fix what remains, and run it again until it is clean
+	`
+)
+
+var lintSchema = &tool.JSONSchema{
+	Type: "object",
+	Properties: map[string]interface{}{
+		"linters": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string", "enum": []string{"golangci-lint", "gofmt", "ruff", "eslint"}},
+			"description": "Linters to run. Defaults to those configured in the project.",
+		},
+		"paths": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string"},
+			"description": "Files or directories to check, relative to the project directory. Defaults to the whole project.",
+		},
+		"fix": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Apply auto-fixes and formatting before reporting the remaining diagnostics.",
+		},
+		"workdir": map[string]interface{}{
+			"type":        "string",
+			"description": "Optional project directory relative to the sandbox root.",
+		},
+		"timeout": map[string]interface{}{
+			"type":        "number",
+			"description": "Optional timeout in seconds (defaults to 300, caps at 1800).",
+		},
+	},
+}
+
+// Linter names a supported linter or formatter.
+type Linter string
+
+const (
+	LinterGolangci Linter = "golangci-lint"
+	LinterGofmt    Linter = "gofmt"
+	LinterRuff     Linter = "ruff"
+	LinterESLint   Linter = "eslint"
+)
+
+// LintDiagnostic is one problem reported by a linter. File is relative to
+// the project directory.
+type LintDiagnostic struct {
+	Linter   Linter `json:"linter"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Rule     string `json:"rule,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Fixable reports that the linter can fix the problem with fix=true.
+	Fixable bool `json:"fixable,omitempty"`
+}
+
+// FileChange is a file modified by an auto-fix.
+type FileChange struct {
+	Path string `json:"path"`
+	Diff string `json:"diff"`
+}
+
+// LintReport is the structured result of a lint call, returned as
+// ToolResult.Data.
+type LintReport struct {
+	Linters     []Linter         `json:"linters"`
+	Diagnostics []LintDiagnostic `json:"diagnostics,omitempty"`
+	// Changes lists the files modified by fix=true.
+	Changes []FileChange `json:"changes,omitempty"`
+	// Errors holds linter failures that produced no diagnostics.
+	Errors     []string `json:"errors,omitempty"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+// LintTool runs linters and formatters with optional auto-fix.
+type LintTool struct {
+	sandbox *security.Sandbox
+	root    string
+	timeout time.Duration
+	// run executes a linter command; replaced in tests.
+	run func(ctx context.Context, dir string, name string, args ...string) (stdout, stderr []byte, err error)
+}
+
+// NewLintTool builds a LintTool rooted at the provided directory.
+func NewLintTool(root string) *LintTool {
+	resolved := resolveRoot(root)
+	return NewLintToolWithSandbox(resolved, security.NewSandbox(resolved))
+}
+
+// NewLintToolWithSandbox builds a LintTool with a custom sandbox.
+func NewLintToolWithSandbox(root string, sandbox *security.Sandbox) *LintTool {
+	return &LintTool{
+		sandbox: sandbox,
+		root:    resolveRoot(root),
+		timeout: defaultLintTimeout,
+		run:     runProjectCommand,
+	}
+}
+
+func (l *LintTool) Name() string { return "Lint" }
+
+func (l *LintTool) Description() string { return lintDescription }
+
+func (l *LintTool) Schema() *tool.JSONSchema { return lintSchema }
+
+type lintParams struct {
+	linters []Linter
+	paths   []string
+	fix     bool
+	dir     string
+	timeout time.Duration
+}
+
+func (l *LintTool) Execute(ctx context.Context, params map[string]interface{}) (*tool.ToolResult, error) {
+	if ctx == nil {
+		return nil, errors.New("context is nil")
+	}
+	if l == nil || l.sandbox == nil {
+		return nil, errors.New("lint tool is not initialised")
+	}
+	p, err := l.parseParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.linters) == 0 {
+		if p.linters = DetectLinters(p.dir); len(p.linters) == 0 {
+			return nil, fmt.Errorf("no configured linters found in %s", p.dir)
+		}
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	started := time.Now()
+	report := &LintReport{Linters: p.linters}
+	var before *sourceSnapshot
+	if p.fix {
+		if before, err = snapshotSources(p.dir, p.paths); err != nil {
+			return nil, err
+		}
+	}
+	for _, linter := range p.linters {
+		diags, err := l.runLinter(execCtx, linter, p)
+		if errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+			return nil, tool.NewToolError(tool.ErrorTimeout, fmt.Errorf("linters timed out after %s", p.timeout))
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", linter, err))
+			continue
+		}
+		report.Diagnostics = append(report.Diagnostics, diags...)
+	}
+	var artifacts []tool.Artifact
+	if before != nil {
+		report.Changes = before.changes()
+		if patch := joinChanges(report.Changes); patch != "" {
+			artifacts = append(artifacts, tool.Artifact{Name: "lint-fixes.patch", MediaType: "text/x-diff", Data: []byte(patch)})
+		}
+	}
+	report.DurationMs = time.Since(started).Milliseconds()
+	return &tool.ToolResult{
+		Success:   len(report.Errors) == 0 && !hasLintErrors(report.Diagnostics),
+		Output:    formatLintReport(report),
+		Data:      report,
+		Artifacts: artifacts,
+	}, nil
+}
+
+func (l *LintTool) parseParams(ctx context.Context, params map[string]interface{}) (lintParams, error) {
+	p := lintParams{timeout: l.timeout}
+	if raw, ok := params["linters"]; ok && raw != nil {
+		items, err := coerceInterfaceArray(raw, "linters")
+		if err != nil {
+			return p, err
+		}
+		seen := map[Linter]bool{}
+		for _, item := range items {
+			value, err := coerceString(item)
+			if err != nil {
+				return p, fmt.Errorf("linters must be strings: %w", err)
+			}
+			linter := Linter(strings.ToLower(strings.TrimSpace(value)))
+			switch linter {
+			case LinterGolangci, LinterGofmt, LinterRuff, LinterESLint:
+			default:
+				return p, fmt.Errorf("unsupported linter %q", value)
+			}
+			if !seen[linter] {
+				seen[linter] = true
+				p.linters = append(p.linters, linter)
+			}
+		}
+	}
+	if raw, ok := params["fix"]; ok && raw != nil {
+		value, err := coerceBool(raw)
+		if err != nil {
+			return p, fmt.Errorf("fix must be boolean: %w", err)
+		}
+		p.fix = value
+	}
+	if raw, ok := params["timeout"]; ok && raw != nil {
+		dur, err := durationFromParam(raw)
+		if err != nil {
+			return p, fmt.Errorf("invalid timeout: %w", err)
+		}
+		if dur > 0 {
+			p.timeout = min(dur, maxLintTimeout)
+		}
+	}
+	dir, err := resolveProjectDir(ctx, l.sandbox, l.root, params)
+	if err != nil {
+		return p, err
+	}
+	p.dir = dir
+	if raw, ok := params["paths"]; ok && raw != nil {
+		items, err := coerceInterfaceArray(raw, "paths")
+		if err != nil {
+			return p, err
+		}
+		for _, item := range items {
+			path, err := coerceString(item)
+			if err != nil {
+				return p, fmt.Errorf("paths must be strings: %w", err)
+			}
+			if path = strings.TrimSpace(path); path == "" {
+				continue
+			}
+			if strings.HasPrefix(path, "-") {
+				return p, fmt.Errorf("invalid path %q", path)
+			}
+			abs := path
+			if !filepath.IsAbs(abs) {
+				abs = filepath.Join(dir, abs)
+			}
+			// Go package patterns like ./pkg/... are checked by their
+			// directory.
+			if err := l.sandbox.ValidatePath(filepath.Clean(strings.TrimSuffix(abs, "..."))); err != nil {
+				return p, err
+			}
+			p.paths = append(p.paths, path)
+		}
+	}
+	return p, nil
+}
+
+// DetectLinters lists the linters configured for the project in dir whose
+// executables are available.
+func DetectLinters(dir string) []Linter {
+	exists := func(names ...string) bool {
+		for _, name := range names {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+	installed := func(name string) bool {
+		_, err := exec.LookPath(name)
+		return err == nil
+	}
+	var linters []Linter
+	if exists(".golangci.yml", ".golangci.yaml", ".golangci.toml", ".golangci.json") && installed("golangci-lint") {
+		linters = append(linters, LinterGolangci)
+	}
+	if exists("go.mod") && installed("gofmt") {
+		linters = append(linters, LinterGofmt)
+	}
+	if (exists("ruff.toml", ".ruff.toml") || fileContains(filepath.Join(dir, "pyproject.toml"), "[tool.ruff")) && installed("ruff") {
+		linters = append(linters, LinterRuff)
+	}
+	if hasESLintConfig(dir) && exists(filepath.Join("node_modules", ".bin", "eslint")) {
+		linters = append(linters, LinterESLint)
+	}
+	return linters
+}
+
+func hasESLintConfig(dir string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "eslint.config.*"))
+	legacy, _ := filepath.Glob(filepath.Join(dir, ".eslintrc*"))
+	return len(matches) > 0 || len(legacy) > 0 || fileContains(filepath.Join(dir, "package.json"), `"eslintConfig"`)
+}
+
+func (l *LintTool) runLinter(ctx context.Context, linter Linter, p lintParams) ([]LintDiagnostic, error) {
+	switch linter {
+	case LinterGolangci:
+		return l.runGolangci(ctx, p)
+	case LinterGofmt:
+		return l.runGofmt(ctx, p)
+	case LinterRuff:
+		return l.runRuff(ctx, p)
+	case LinterESLint:
+		return l.runESLint(ctx, p)
+	}
+	return nil, fmt.Errorf("unsupported linter %q", linter)
+}
+
+func (l *LintTool) runGolangci(ctx context.Context, p lintParams) ([]LintDiagnostic, error) {
+	version, _, _ := l.run(ctx, p.dir, "golangci-lint", "--version")
+	args := []string{"run"}
+	// v2 replaced --out-format with per-format output flags.
+	if bytes.Contains(version, []byte("version 2.")) {
+		args = append(args, "--output.json.path=stdout")
+	} else {
+		args = append(args, "--out-format=json")
+	}
+	if p.fix {
+		args = append(args, "--fix")
+	}
+	args = append(args, pathsOr(p.paths, "./...")...)
+	stdout, stderr, err := l.run(ctx, p.dir, "golangci-lint", args...)
+	diags, perr := parseGolangciJSON(stdout)
+	if perr != nil {
+		return nil, linterFailure(err, perr, stderr)
+	}
+	return diags, nil
+}
+
+// gofmtErrorLine matches the syntax errors gofmt prints for files it cannot
+// parse.
+var gofmtErrorLine = regexp.MustCompile(`^(.+?):(\d+):(\d+): (.+)$`)
+
+func (l *LintTool) runGofmt(ctx context.Context, p lintParams) ([]LintDiagnostic, error) {
+	flag := "-l"
+	if p.fix {
+		flag = "-w"
+	}
+	args := append([]string{flag}, pathsOr(p.paths, ".")...)
+	stdout, stderr, err := l.run(ctx, p.dir, "gofmt", args...)
+	var diags []LintDiagnostic
+	if !p.fix {
+		for _, line := range strings.Split(strings.TrimSpace(string(stdout)), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				diags = append(diags, LintDiagnostic{
+					Linter:   LinterGofmt,
+					File:     displayPath(absUnder(p.dir, line), p.dir),
+					Severity: "error",
+					Message:  "file is not gofmt-formatted",
+					Fixable:  true,
+				})
+			}
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(stderr)), "\n") {
+		m := gofmtErrorLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		lineNo, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		diags = append(diags, LintDiagnostic{
+			Linter:   LinterGofmt,
+			File:     displayPath(absUnder(p.dir, m[1]), p.dir),
+			Line:     lineNo,
+			Column:   col,
+			Severity: "error",
+			Message:  m[4],
+		})
+	}
+	if err != nil && len(diags) == 0 {
+		return nil, linterFailure(err, nil, stderr)
+	}
+	return diags, nil
+}
+
+func (l *LintTool) runRuff(ctx context.Context, p lintParams) ([]LintDiagnostic, error) {
+	paths := pathsOr(p.paths, ".")
+	if p.fix {
+		// ruff check --fix does not format; run the formatter first so the
+		// remaining diagnostics reflect the final file contents.
+		if _, stderr, err := l.run(ctx, p.dir, "ruff", append([]string{"format"}, paths...)...); err != nil {
+			return nil, linterFailure(err, nil, stderr)
+		}
+	}
+	args := []string{"check", "--output-format=json"}
+	if p.fix {
+		args = append(args, "--fix")
+	}
+	stdout, stderr, err := l.run(ctx, p.dir, "ruff", append(args, paths...)...)
+	diags, perr := parseRuffJSON(stdout, p.dir)
+	if perr != nil {
+		return nil, linterFailure(err, perr, stderr)
+	}
+	if !p.fix {
+		out, _, _ := l.run(ctx, p.dir, "ruff", append([]string{"format", "--check"}, paths...)...)
+		for _, line := range strings.Split(string(out), "\n") {
+			if file, ok := strings.CutPrefix(strings.TrimSpace(line), "Would reformat: "); ok {
+				diags = append(diags, LintDiagnostic{
+					Linter:   LinterRuff,
+					File:     displayPath(absUnder(p.dir, file), p.dir),
+					Severity: "error",
+					Message:  "file is not ruff-formatted",
+					Fixable:  true,
+				})
+			}
+		}
+	}
+	return diags, nil
+}
+
+func (l *LintTool) runESLint(ctx context.Context, p lintParams) ([]LintDiagnostic, error) {
+	args := []string{"--no-install", "eslint", "--format", "json"}
+	if p.fix {
+		args = append(args, "--fix")
+	}
+	stdout, stderr, err := l.run(ctx, p.dir, "npx", append(args, pathsOr(p.paths, ".")...)...)
+	diags, perr := parseESLintJSON(stdout, p.dir)
+	if perr != nil {
+		return nil, linterFailure(err, perr, stderr)
+	}
+	return diags, nil
+}
+
+type golangciReport struct {
+	Issues []struct {
+		FromLinter     string          `json:"FromLinter"`
+		Text           string          `json:"Text"`
+		Severity       string          `json:"Severity"`
+		Replacement    json.RawMessage `json:"Replacement"`
+		SuggestedFixes json.RawMessage `json:"SuggestedFixes"`
+		Pos            struct {
+			Filename string `json:"Filename"`
+			Line     int    `json:"Line"`
+			Column   int    `json:"Column"`
+		} `json:"Pos"`
+	} `json:"Issues"`
+}
+
+// parseGolangciJSON parses golangci-lint JSON output. Only the first line is
+// read because v1 prints a text summary after it.
+func parseGolangciJSON(data []byte) ([]LintDiagnostic, error) {
+	data = bytes.TrimSpace(data)
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+	var report golangciReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("parse golangci-lint output: %w", err)
+	}
+	diags := make([]LintDiagnostic, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		severity := issue.Severity
+		if severity == "" {
+			severity = "error"
+		}
+		diags = append(diags, LintDiagnostic{
+			Linter:   LinterGolangci,
+			File:     filepath.ToSlash(issue.Pos.Filename),
+			Line:     issue.Pos.Line,
+			Column:   issue.Pos.Column,
+			Rule:     issue.FromLinter,
+			Severity: severity,
+			Message:  issue.Text,
+			Fixable:  rawPresent(issue.Replacement) || rawPresent(issue.SuggestedFixes),
+		})
+	}
+	return diags, nil
+}
+
+type ruffDiagnostic struct {
+	Code     *string         `json:"code"`
+	Message  string          `json:"message"`
+	Filename string          `json:"filename"`
+	Fix      json.RawMessage `json:"fix"`
+	Location struct {
+		Row    int `json:"row"`
+		Column int `json:"column"`
+	} `json:"location"`
+}
+
+func parseRuffJSON(data []byte, dir string) ([]LintDiagnostic, error) {
+	var items []ruffDiagnostic
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("parse ruff output: %w", err)
+	}
+	diags := make([]LintDiagnostic, 0, len(items))
+	for _, item := range items {
+		d := LintDiagnostic{
+			Linter:   LinterRuff,
+			File:     displayPath(absUnder(dir, item.Filename), dir),
+			Line:     item.Location.Row,
+			Column:   item.Location.Column,
+			Severity: "error",
+			Message:  item.Message,
+			Fixable:  rawPresent(item.Fix),
+		}
+		if item.Code != nil {
+			d.Rule = *item.Code
+		}
+		diags = append(diags, d)
+	}
+	return diags, nil
+}
+
+type eslintFileResult struct {
+	FilePath string `json:"filePath"`
+	Messages []struct {
+		RuleID   *string         `json:"ruleId"`
+		Severity int             `json:"severity"`
+		Message  string          `json:"message"`
+		Line     int             `json:"line"`
+		Column   int             `json:"column"`
+		Fix      json.RawMessage `json:"fix"`
+	} `json:"messages"`
+}
+
+func parseESLintJSON(data []byte, dir string) ([]LintDiagnostic, error) {
+	var files []eslintFileResult
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("parse eslint output: %w", err)
+	}
+	var diags []LintDiagnostic
+	for _, file := range files {
+		for _, msg := range file.Messages {
+			d := LintDiagnostic{
+				Linter:   LinterESLint,
+				File:     displayPath(absUnder(dir, file.FilePath), dir),
+				Line:     msg.Line,
+				Column:   msg.Column,
+				Severity: "warning",
+				Message:  msg.Message,
+				Fixable:  rawPresent(msg.Fix),
+			}
+			if msg.Severity >= 2 {
+				d.Severity = "error"
+			}
+			if msg.RuleID != nil {
+				d.Rule = *msg.RuleID
+			}
+			diags = append(diags, d)
+		}
+	}
+	return diags, nil
+}
+
+func rawPresent(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && !bytes.Equal(trimmed, []byte("null"))
+}
+
+func absUnder(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func pathsOr(paths []string, fallback string) []string {
+	if len(paths) == 0 {
+		return []string{fallback}
+	}
+	return paths
+}
+
+// linterFailure reports a linter whose output could not be used.
+func linterFailure(runErr, parseErr error, stderr []byte) error {
+	msg := tailString(strings.TrimSpace(string(stderr)), testFailureOutputLimit)
+	var exitErr *exec.ExitError
+	switch {
+	case runErr != nil && !errors.As(runErr, &exitErr):
+		return runErr
+	case msg != "":
+		return errors.New(msg)
+	case parseErr != nil:
+		return parseErr
+	}
+	return runErr
+}
+
+func hasLintErrors(diags []LintDiagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+func formatLintReport(r *LintReport) string {
+	var b strings.Builder
+	names := make([]string, len(r.Linters))
+	for i, linter := range r.Linters {
+		names[i] = string(linter)
+	}
+	fmt.Fprintf(&b, "%s: %d diagnostics", strings.Join(names, ", "), len(r.Diagnostics))
+	if len(r.Changes) > 0 {
+		fmt.Fprintf(&b, ", %d files fixed", len(r.Changes))
+	}
+	for _, e := range r.Errors {
+		b.WriteString("\nERROR " + e)
+	}
+	for i, d := range r.Diagnostics {
+		if i == lintDiagnosticLimit {
+			fmt.Fprintf(&b, "\n... %d more", len(r.Diagnostics)-i)
+			break
+		}
+		b.WriteString("\n" + d.File)
+		if d.Line > 0 {
+			fmt.Fprintf(&b, ":%d:%d", d.Line, d.Column)
+		}
+		fmt.Fprintf(&b, ": %s: %s", d.Severity, d.Message)
+		rule := string(d.Linter)
+		if d.Rule != "" {
+			rule += "/" + d.Rule
+		}
+		fmt.Fprintf(&b, " (%s)", rule)
+	}
+	for _, c := range r.Changes {
+		b.WriteString("\n\n" + c.Diff)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,273 @@
+package toolbuiltin
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// snapshotFileLimit skips files too large to be hand-written sources.
+	snapshotFileLimit = 1 << 20
+	// snapshotMaxFiles bounds the files tracked by one fix run.
+	snapshotMaxFiles = 20000
+	// diffCellLimit bounds the line-matching table; larger changes are
+	// shown as one replaced block.
+	diffCellLimit = 4 << 20
+	diffContext   = 3
+)
+
+// snapshotExtensions are the source files linters may rewrite.
+var snapshotExtensions = map[string]bool{
+	".go": true, ".py": true, ".pyi": true,
+	".js": true, ".jsx": true, ".mjs": true, ".cjs": true,
+	".ts": true, ".tsx": true, ".mts": true, ".cts": true,
+}
+
+// snapshotSkipDirs are never tracked: dependencies, build output and VCS
+// metadata.
+var snapshotSkipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "target": true,
+	".venv": true, "venv": true, "__pycache__": true,
+}
+
+// sourceSnapshot records source files before auto-fixes run so the changes
+// can be reported afterwards.
+type sourceSnapshot struct {
+	dir   string
+	files map[string]string
+}
+
+// snapshotSources reads the source files under dir, limited to paths when
+// given (Go package patterns like ./pkg/... are read as their directory).
+func snapshotSources(dir string, paths []string) (*sourceSnapshot, error) {
+	snap := &sourceSnapshot{dir: dir, files: map[string]string{}}
+	roots := []string{dir}
+	if len(paths) > 0 {
+		roots = roots[:0]
+		for _, p := range paths {
+			roots = append(roots, filepath.Clean(absUnder(dir, strings.TrimSuffix(p, "..."))))
+		}
+	}
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == root && os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				if path != root && snapshotSkipDirs[d.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() || !snapshotExtensions[filepath.Ext(path)] {
+				return nil
+			}
+			if _, seen := snap.files[path]; seen || len(snap.files) >= snapshotMaxFiles {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || info.Size() > snapshotFileLimit {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			snap.files[path] = string(data)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("snapshot sources: %w", err)
+		}
+	}
+	return snap, nil
+}
+
+// changes diffs the snapshot against the files on disk, sorted by path.
+// Files created by a fix are not tracked.
+func (s *sourceSnapshot) changes() []FileChange {
+	paths := make([]string, 0, len(s.files))
+	for path := range s.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var out []FileChange
+	for _, path := range paths {
+		before := s.files[path]
+		data, err := os.ReadFile(path)
+		after := string(data)
+		if err != nil {
+			after = ""
+		}
+		if after == before {
+			continue
+		}
+		rel := filepath.ToSlash(displayPath(path, s.dir))
+		out = append(out, FileChange{Path: rel, Diff: unifiedDiff(rel, before, after)})
+	}
+	return out
+}
+
+func joinChanges(changes []FileChange) string {
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(c.Diff)
+	}
+	return b.String()
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff renders a unified diff of before and after for path.
+func unifiedDiff(path, before, after string) string {
+	a, b := diffLines(before), diffLines(after)
+	ops := diffLineOps(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- a/%s\n+++ b/%s\n", path, path)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Grow the hunk until diffContext*2 unchanged lines separate it
+		// from the next change.
+		start := max(i-diffContext, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > diffContext*2 {
+				end = min(end+diffContext, len(ops))
+				break
+			}
+			end = run
+		}
+		oldStart, newStart := lineNumbers(ops[:start])
+		oldCount, newCount := lineNumbers(ops[start:end])
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			out.WriteByte('\n')
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// diffLines splits s into lines, keeping carriage returns so line-ending
+// fixes show up in the diff.
+func diffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLineOps matches the lines of a and b by longest common subsequence
+// after trimming their common prefix and suffix.
+func diffLineOps(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(ma)*len(mb) > diffCellLimit {
+		for _, line := range ma {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range mb {
+			ops = append(ops, diffOp{'+', line})
+		}
+	} else {
+		ops = append(ops, lcsOps(ma, mb)...)
+	}
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func lcsOps(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// lineNumbers counts the old and new lines covered by ops.
+func lineNumbers(ops []diffOp) (oldLines, newLines int) {
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldLines++
+		}
+		if op.kind != '-' {
+			newLines++
+		}
+	}
+	return oldLines, newLines
+}
+
+// hunkRange formats a hunk range given the lines before it and its length.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
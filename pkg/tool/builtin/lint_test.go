@@ -0,0 +1,189 @@
+package toolbuiltin
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGolangciJSON(t *testing.T) {
+	t.Parallel()
+
+	out := `{"Issues":[{"FromLinter":"errcheck","Text":"Error return value is not checked","Severity":"","Pos":{"Filename":"pkg/a.go","Line":12,"Column":3},"Replacement":null},{"FromLinter":"gofumpt","Text":"File is not formatted","Severity":"warning","Pos":{"Filename":"b.go","Line":1,"Column":1},"Replacement":{"NewLines":["x"]}}]}
+level=info msg="summary"`
+	diags, err := parseGolangciJSON([]byte(out))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics", len(diags))
+	}
+	if d := diags[0]; d.File != "pkg/a.go" || d.Line != 12 || d.Rule != "errcheck" || d.Severity != "error" || d.Fixable {
+		t.Fatalf("unexpected first diagnostic %+v", d)
+	}
+	if d := diags[1]; d.Severity != "warning" || !d.Fixable {
+		t.Fatalf("unexpected second diagnostic %+v", d)
+	}
+	if _, err := parseGolangciJSON([]byte("level=error msg=boom")); err == nil {
+		t.Fatal("expected parse error for non-JSON output")
+	}
+}
+
+func TestParseRuffJSON(t *testing.T) {
+	t.Parallel()
+
+	out := `[{"code":"F401","message":"os imported but unused","filename":"/repo/app/main.py","location":{"row":1,"column":8},"fix":{"applicability":"safe"}},
+{"code":null,"message":"SyntaxError: bad","filename":"/repo/b.py","location":{"row":2,"column":1},"fix":null}]`
+	diags, err := parseRuffJSON([]byte(out), "/repo")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(diags) != 2 || diags[0].File != "app/main.py" || diags[0].Rule != "F401" || !diags[0].Fixable {
+		t.Fatalf("unexpected diagnostics %+v", diags)
+	}
+	if diags[1].Rule != "" || diags[1].Fixable {
+		t.Fatalf("unexpected syntax error diagnostic %+v", diags[1])
+	}
+}
+
+func TestParseESLintJSON(t *testing.T) {
+	t.Parallel()
+
+	out := `[{"filePath":"/repo/src/a.js","messages":[
+{"ruleId":"no-unused-vars","severity":2,"message":"'x' is assigned a value but never used.","line":1,"column":7},
+{"ruleId":"semi","severity":1,"message":"Missing semicolon.","line":2,"column":10,"fix":{"range":[9,9],"text":";"}}]},
+{"filePath":"/repo/src/b.js","messages":[]}]`
+	diags, err := parseESLintJSON([]byte(out), "/repo")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics", len(diags))
+	}
+	if d := diags[0]; d.File != "src/a.js" || d.Severity != "error" || d.Rule != "no-unused-vars" || d.Fixable {
+		t.Fatalf("unexpected first diagnostic %+v", d)
+	}
+	if d := diags[1]; d.Severity != "warning" || !d.Fixable {
+		t.Fatalf("unexpected second diagnostic %+v", d)
+	}
+	if hasLintErrors(diags[1:]) {
+		t.Fatal("warnings alone must not count as errors")
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Parallel()
+
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	after := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	want := `--- a/x.go
++++ b/x.go
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+`
+	if got := unifiedDiff("x.go", before, after); got != want {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+	if got := unifiedDiff("x.go", "a\r\n", "a\n"); !strings.Contains(got, "-a\r\n+a\n") {
+		t.Fatalf("line-ending change not shown: %q", got)
+	}
+}
+
+func TestLintToolRejectsFlagPaths(t *testing.T) {
+	t.Parallel()
+
+	lt := NewLintTool(t.TempDir())
+	_, err := lt.Execute(context.Background(), map[string]interface{}{
+		"linters": []interface{}{"gofmt"},
+		"paths":   []interface{}{"--help"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid path") {
+		t.Fatalf("expected invalid path error, got %v", err)
+	}
+	if _, err := lt.Execute(context.Background(), map[string]interface{}{"linters": []interface{}{"pylint"}}); err == nil {
+		t.Fatal("expected unsupported linter error")
+	}
+}
+
+func TestLintToolGofmtFix(t *testing.T) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt not installed")
+	}
+	t.Parallel()
+
+	root := t.TempDir()
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	unformatted := "package demo\n\nfunc  Add(a,b int) int {\nreturn a+b\n}\n"
+	for name, body := range map[string]string{
+		"go.mod":      "module example.com/demo\n\ngo 1.21\n",
+		"add.go":      unformatted,
+		"ok.go":       "package demo\n\nfunc One() int { return 1 }\n",
+		"broken.go":   "package demo\n\nfunc {\n",
+		"notes/a.txt": "not go",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if got := DetectLinters(root); len(got) == 0 || got[len(got)-1] != LinterGofmt {
+		t.Fatalf("expected gofmt to be detected, got %v", got)
+	}
+
+	lt := NewLintTool(root)
+	res, err := lt.Execute(context.Background(), map[string]interface{}{"linters": []interface{}{"gofmt"}})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	report := res.Data.(*LintReport)
+	if res.Success || len(report.Diagnostics) != 2 {
+		t.Fatalf("expected unformatted and syntax diagnostics, got %+v", report)
+	}
+	files := map[string]LintDiagnostic{}
+	for _, d := range report.Diagnostics {
+		files[d.File] = d
+	}
+	if !files["add.go"].Fixable || files["broken.go"].Line != 3 {
+		t.Fatalf("unexpected diagnostics %+v", report.Diagnostics)
+	}
+
+	res, err = lt.Execute(context.Background(), map[string]interface{}{"linters": []interface{}{"gofmt"}, "fix": true})
+	if err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	report = res.Data.(*LintReport)
+	if len(report.Changes) != 1 || report.Changes[0].Path != "add.go" {
+		t.Fatalf("expected add.go to change, got %+v", report.Changes)
+	}
+	if !strings.Contains(report.Changes[0].Diff, "-func  Add(a,b int) int {\n-return a+b\n+func Add(a, b int) int {\n+\treturn a + b\n }") {
+		t.Fatalf("unexpected diff:\n%s", report.Changes[0].Diff)
+	}
+	if len(res.Artifacts) != 1 || string(res.Artifacts[0].Data) != report.Changes[0].Diff {
+		t.Fatalf("expected patch artifact, got %+v", res.Artifacts)
+	}
+	if len(report.Diagnostics) != 1 || report.Diagnostics[0].File != "broken.go" {
+		t.Fatalf("expected only the syntax error to remain, got %+v", report.Diagnostics)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "add.go"))
+	if err != nil || string(data) == unformatted {
+		t.Fatalf("add.go not rewritten: %v", err)
+	}
+}
@@ -0,0 +1,419 @@
+package toolbuiltin
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+const notebookEditDescription = `Edits a Jupyter notebook (.ipynb) one cell at a time.
+
+Usage:
+- notebook_path is the notebook to edit. Use this tool instead of Bash, Write or Edit for .ipynb files so the JSON stays valid.
+- Identify the target cell with cell_id (the cell's "id" field) or cell_index (0-based).
+- edit_mode "replace" (default) replaces the cell's source; pass cell_type to change its type. Code cells lose their outputs and execution count.
+- edit_mode "insert" adds a new cell of cell_type after the target cell, or at the top when no target is given.
+- edit_mode "delete" removes the target cell.
+- Set 'dry_run' to preview the change as a unified diff without modifying the file.
+`
+
+const maxNotebookBytes = 16 << 20 // 16 MiB; notebooks carry outputs
+
+var notebookEditSchema = &tool.JSONSchema{
+	Type: "object",
+	Properties: map[string]interface{}{
+		"notebook_path": map[string]interface{}{
+			"type":        "string",
+			"description": "The path to the .ipynb file to edit",
+		},
+		"cell_id": map[string]interface{}{
+			"type":        "string",
+			"description": "The id of the cell to edit; for insert, the new cell goes after it",
+		},
+		"cell_index": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     0,
+			"description": "The 0-based index of the cell to edit, as an alternative to cell_id",
+		},
+		"new_source": map[string]interface{}{
+			"type":        "string",
+			"description": "The new source of the cell (required for replace and insert)",
+		},
+		"cell_type": map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{"code", "markdown", "raw"},
+			"description": "The cell type; required for insert, optional for replace",
+		},
+		"edit_mode": map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{"replace", "insert", "delete"},
+			"default":     "replace",
+			"description": "The kind of edit to make",
+		},
+		"dry_run": dryRunProperty,
+	},
+	Required: []string{"notebook_path"},
+}
+
+// NotebookEditTool edits Jupyter notebooks cell by cell. Fields it does not
+// touch are written back verbatim, in nbformat's sorted-key layout.
+type NotebookEditTool struct {
+	base *fileSandbox
+}
+
+// NewNotebookEditTool builds a NotebookEditTool rooted at the current directory.
+func NewNotebookEditTool() *NotebookEditTool {
+	return NewNotebookEditToolWithRoot("")
+}
+
+// NewNotebookEditToolWithRoot builds a NotebookEditTool rooted at the provided directory.
+func NewNotebookEditToolWithRoot(root string) *NotebookEditTool {
+	base := newFileSandbox(root)
+	base.maxBytes = maxNotebookBytes
+	return &NotebookEditTool{base: base}
+}
+
+// NewNotebookEditToolWithSandbox builds a NotebookEditTool using a custom sandbox.
+func NewNotebookEditToolWithSandbox(root string, sandbox *security.Sandbox) *NotebookEditTool {
+	base := newFileSandboxWithSandbox(root, sandbox)
+	base.maxBytes = maxNotebookBytes
+	return &NotebookEditTool{base: base}
+}
+
+func (n *NotebookEditTool) Name() string { return "NotebookEdit" }
+
+func (n *NotebookEditTool) Description() string { return notebookEditDescription }
+
+func (n *NotebookEditTool) Schema() *tool.JSONSchema { return notebookEditSchema }
+
+// notebookEdit is one parsed NotebookEdit call.
+type notebookEdit struct {
+	mode      string
+	cellID    string
+	cellIndex int // -1 when unset
+	source    string
+	hasSource bool
+	cellType  string
+}
+
+func (n *NotebookEditTool) Execute(ctx context.Context, params map[string]interface{}) (*tool.ToolResult, error) {
+	if ctx == nil {
+		return nil, errors.New("context is nil")
+	}
+	if n == nil || n.base == nil || n.base.sandbox == nil {
+		return nil, errors.New("notebook edit tool is not initialised")
+	}
+	if params == nil {
+		return nil, errors.New("params is nil")
+	}
+	raw, ok := params["notebook_path"]
+	if !ok {
+		return nil, errors.New("notebook_path is required")
+	}
+	path, err := n.base.resolveWritePath(raw)
+	if err != nil {
+		return nil, err
+	}
+	edit, err := parseNotebookEdit(params)
+	if err != nil {
+		return nil, err
+	}
+	dryRun, err := parseDryRun(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	content, err := n.base.readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	nb, err := parseNotebook([]byte(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", displayPath(path, n.base.root), err)
+	}
+	index, cellID, err := nb.apply(edit)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := nb.encode()
+	if err != nil {
+		return nil, err
+	}
+	if n.base.maxBytes > 0 && int64(len(updated)) > n.base.maxBytes {
+		return nil, fmt.Errorf("edited notebook exceeds %d bytes limit", n.base.maxBytes)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"path":       displayPath(path, n.base.root),
+		"edit_mode":  edit.mode,
+		"cell_index": index,
+		"cells":      len(nb.cells),
+	}
+	if cellID != "" {
+		data["cell_id"] = cellID
+	}
+	if edit.cellType != "" {
+		data["cell_type"] = edit.cellType
+	}
+	if dryRun {
+		return dryRunResult(displayPath(path, n.base.root), content, string(updated), data), nil
+	}
+	if err := writeFileAtomic(path, updated); err != nil {
+		return nil, err
+	}
+	verb := map[string]string{"replace": "replaced", "insert": "inserted", "delete": "deleted"}[edit.mode]
+	return &tool.ToolResult{
+		Success: true,
+		Output:  fmt.Sprintf("%s cell %d", verb, index),
+		Data:    data,
+	}, nil
+}
+
+func parseNotebookEdit(params map[string]interface{}) (notebookEdit, error) {
+	edit := notebookEdit{mode: "replace", cellIndex: -1}
+	if raw, ok := params["edit_mode"]; ok && raw != nil {
+		mode, err := coerceString(raw)
+		if err != nil {
+			return edit, fmt.Errorf("edit_mode must be string: %w", err)
+		}
+		edit.mode = strings.ToLower(strings.TrimSpace(mode))
+		switch edit.mode {
+		case "":
+			edit.mode = "replace"
+		case "replace", "insert", "delete":
+		default:
+			return edit, fmt.Errorf("edit_mode must be replace, insert or delete, got %q", mode)
+		}
+	}
+	if raw, ok := params["cell_id"]; ok && raw != nil {
+		id, err := coerceString(raw)
+		if err != nil {
+			return edit, fmt.Errorf("cell_id must be string: %w", err)
+		}
+		edit.cellID = strings.TrimSpace(id)
+	}
+	if raw, ok := params["cell_index"]; ok && raw != nil {
+		idx, err := coerceInt(raw)
+		if err != nil {
+			return edit, fmt.Errorf("cell_index must be integer: %w", err)
+		}
+		if idx < 0 {
+			return edit, errors.New("cell_index must be >= 0")
+		}
+		edit.cellIndex = idx
+	}
+	if edit.cellID != "" && edit.cellIndex >= 0 {
+		return edit, errors.New("cell_id and cell_index are mutually exclusive")
+	}
+	if raw, ok := params["new_source"]; ok && raw != nil {
+		src, err := coerceString(raw)
+		if err != nil {
+			return edit, fmt.Errorf("new_source must be string: %w", err)
+		}
+		edit.source, edit.hasSource = src, true
+	}
+	if raw, ok := params["cell_type"]; ok && raw != nil {
+		typ, err := coerceString(raw)
+		if err != nil {
+			return edit, fmt.Errorf("cell_type must be string: %w", err)
+		}
+		edit.cellType = strings.ToLower(strings.TrimSpace(typ))
+		switch edit.cellType {
+		case "", "code", "markdown", "raw":
+		default:
+			return edit, fmt.Errorf("cell_type must be code, markdown or raw, got %q", typ)
+		}
+	}
+
+	switch edit.mode {
+	case "replace":
+		if !edit.hasSource {
+			return edit, errors.New("new_source is required for replace")
+		}
+	case "insert":
+		if !edit.hasSource {
+			return edit, errors.New("new_source is required for insert")
+		}
+		if edit.cellType == "" {
+			return edit, errors.New("cell_type is required for insert")
+		}
+	}
+	if edit.mode != "insert" && edit.cellID == "" && edit.cellIndex < 0 {
+		return edit, fmt.Errorf("cell_id or cell_index is required for %s", edit.mode)
+	}
+	return edit, nil
+}
+
+// notebook is a decoded .ipynb document. Objects are kept as raw fields so
+// metadata, outputs and unknown keys survive an edit byte for byte.
+type notebook struct {
+	fields map[string]json.RawMessage
+	cells  []map[string]json.RawMessage
+	minor  int
+}
+
+func parseNotebook(data []byte) (*notebook, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid notebook JSON: %w", err)
+	}
+	var major, minor int
+	if err := json.Unmarshal(fields["nbformat"], &major); err != nil || major < 4 {
+		return nil, errors.New("only nbformat 4 notebooks are supported")
+	}
+	if raw, ok := fields["nbformat_minor"]; ok {
+		_ = json.Unmarshal(raw, &minor)
+	}
+	var cells []map[string]json.RawMessage
+	if err := json.Unmarshal(fields["cells"], &cells); err != nil || cells == nil {
+		return nil, errors.New("notebook has no cells array")
+	}
+	return &notebook{fields: fields, cells: cells, minor: minor}, nil
+}
+
+// encode writes the notebook the way nbformat does: sorted keys, one-space
+// indent, no HTML escaping and a trailing newline.
+func (nb *notebook) encode() ([]byte, error) {
+	doc := make(map[string]interface{}, len(nb.fields))
+	for k, v := range nb.fields {
+		doc[k] = v
+	}
+	doc["cells"] = nb.cells
+	data, err := encodeJSON(doc, " ")
+	if err != nil {
+		return nil, fmt.Errorf("encode notebook: %w", err)
+	}
+	return data, nil
+}
+
+func (nb *notebook) find(edit notebookEdit) (int, error) {
+	if edit.cellIndex >= 0 {
+		if edit.cellIndex >= len(nb.cells) {
+			return -1, fmt.Errorf("cell_index %d out of range (notebook has %d cells)", edit.cellIndex, len(nb.cells))
+		}
+		return edit.cellIndex, nil
+	}
+	for i, cell := range nb.cells {
+		var id string
+		if json.Unmarshal(cell["id"], &id) == nil && id == edit.cellID {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("cell %q not found", edit.cellID)
+}
+
+// apply performs edit and reports the index and id of the affected cell.
+func (nb *notebook) apply(edit notebookEdit) (int, string, error) {
+	target := -1
+	if edit.cellID != "" || edit.cellIndex >= 0 {
+		idx, err := nb.find(edit)
+		if err != nil {
+			return -1, "", err
+		}
+		target = idx
+	}
+	switch edit.mode {
+	case "delete":
+		id := cellString(nb.cells[target], "id")
+		nb.cells = append(nb.cells[:target], nb.cells[target+1:]...)
+		return target, id, nil
+	case "insert":
+		cell := map[string]json.RawMessage{"metadata": json.RawMessage("{}")}
+		id := ""
+		if nb.minor >= 5 {
+			id = newCellID()
+			cell["id"] = mustJSON(id)
+		}
+		setCellContent(cell, edit.cellType, edit.source, true)
+		at := target + 1
+		nb.cells = append(nb.cells[:at], append([]map[string]json.RawMessage{cell}, nb.cells[at:]...)...)
+		return at, id, nil
+	default:
+		cell := nb.cells[target]
+		typ := edit.cellType
+		if typ == "" {
+			typ = cellString(cell, "cell_type")
+		}
+		setCellContent(cell, typ, edit.source, typ != cellString(cell, "cell_type"))
+		return target, cellString(cell, "id"), nil
+	}
+}
+
+// setCellContent sets the type and source of cell. Code cells are reset
+// to unexecuted since their outputs no longer match the source.
+func setCellContent(cell map[string]json.RawMessage, typ, source string, typeChanged bool) {
+	cell["cell_type"] = mustJSON(typ)
+	cell["source"] = mustJSON(sourceLines(source))
+	if typ == "code" {
+		cell["execution_count"] = json.RawMessage("null")
+		cell["outputs"] = json.RawMessage("[]")
+		return
+	}
+	delete(cell, "execution_count")
+	delete(cell, "outputs")
+	if typeChanged && typ != "markdown" {
+		delete(cell, "attachments")
+	}
+}
+
+// sourceLines splits source into nbformat's multiline form: lines that
+// keep their trailing newline.
+func sourceLines(source string) []string {
+	lines := strings.SplitAfter(source, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if lines == nil {
+		lines = []string{}
+	}
+	return lines
+}
+
+func cellString(cell map[string]json.RawMessage, key string) string {
+	var s string
+	_ = json.Unmarshal(cell[key], &s)
+	return s
+}
+
+func newCellID() string {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// encodeJSON marshals v without HTML escaping; json.Marshal would turn
+// "<" in cell sources into "\u003c".
+func encodeJSON(v interface{}, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if indent != "" {
+		enc.SetIndent("", indent)
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func mustJSON(v interface{}) json.RawMessage {
+	data, err := encodeJSON(v, "")
+	if err != nil {
+		panic(err)
+	}
+	return bytes.TrimSuffix(data, []byte("\n"))
+}
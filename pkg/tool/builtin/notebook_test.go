@@ -0,0 +1,157 @@
+package toolbuiltin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleNotebook = `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "id": "intro",
+   "metadata": {"tags": ["keep"]},
+   "source": ["# Title <b>bold</b>\n", "text"]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 3,
+   "id": "calc",
+   "metadata": {},
+   "outputs": [{"name": "stdout", "output_type": "stream", "text": ["4\n"]}],
+   "source": "print(2 + 2)"
+  }
+ ],
+ "metadata": {"kernelspec": {"display_name": "Python 3", "language": "python", "name": "python3"}, "x-custom": {"z": 1, "a": 2}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}
+`
+
+func writeNotebook(t *testing.T) (*NotebookEditTool, string) {
+	t.Helper()
+	dir := cleanTempDir(t)
+	path := filepath.Join(dir, "nb.ipynb")
+	if err := os.WriteFile(path, []byte(sampleNotebook), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return NewNotebookEditToolWithRoot(dir), path
+}
+
+func readCells(t *testing.T, path string) ([]map[string]interface{}, string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nb struct {
+		Cells []map[string]interface{} `json:"cells"`
+	}
+	if err := json.Unmarshal(data, &nb); err != nil {
+		t.Fatalf("edited notebook is not valid JSON: %v\n%s", err, data)
+	}
+	return nb.Cells, string(data)
+}
+
+func TestNotebookEditReplaceKeepsOtherFields(t *testing.T) {
+	nb, path := writeNotebook(t)
+	res, err := nb.Execute(context.Background(), map[string]interface{}{
+		"notebook_path": "nb.ipynb",
+		"cell_id":       "calc",
+		"new_source":    "x = 1\nprint(x)\n",
+	})
+	if err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if res.Output != "replaced cell 1" {
+		t.Fatalf("unexpected output %q", res.Output)
+	}
+	cells, raw := readCells(t, path)
+	calc := cells[1]
+	if calc["execution_count"] != nil || len(calc["outputs"].([]interface{})) != 0 {
+		t.Fatalf("code cell should be reset: %+v", calc)
+	}
+	if src := calc["source"].([]interface{}); len(src) != 2 || src[0] != "x = 1\n" || src[1] != "print(x)\n" {
+		t.Fatalf("unexpected source %#v", src)
+	}
+	for _, want := range []string{"<b>bold</b>", `"x-custom": {`, `"keep"`, "\n \"nbformat\": 4,"} {
+		if !strings.Contains(raw, want) {
+			t.Fatalf("missing %q in\n%s", want, raw)
+		}
+	}
+	if strings.Index(raw, `"z": 1`) > strings.Index(raw, `"a": 2`) {
+		t.Fatalf("untouched objects should keep their key order:\n%s", raw)
+	}
+}
+
+func TestNotebookEditInsertDeleteAndTypeChange(t *testing.T) {
+	nb, path := writeNotebook(t)
+	ctx := context.Background()
+	if _, err := nb.Execute(ctx, map[string]interface{}{"notebook_path": path, "edit_mode": "insert", "cell_type": "code", "new_source": "import os"}); err != nil {
+		t.Fatalf("insert at top: %v", err)
+	}
+	res, err := nb.Execute(ctx, map[string]interface{}{"notebook_path": path, "edit_mode": "insert", "cell_id": "intro", "cell_type": "raw", "new_source": ""})
+	if err != nil {
+		t.Fatalf("insert after: %v", err)
+	}
+	data := res.Data.(map[string]interface{})
+	if data["cell_index"] != 2 || len(data["cell_id"].(string)) != 8 || data["cells"] != 4 {
+		t.Fatalf("unexpected data %+v", data)
+	}
+	if _, err := nb.Execute(ctx, map[string]interface{}{"notebook_path": path, "cell_index": 3, "cell_type": "markdown", "new_source": "now prose"}); err != nil {
+		t.Fatalf("change type: %v", err)
+	}
+	if _, err := nb.Execute(ctx, map[string]interface{}{"notebook_path": path, "edit_mode": "delete", "cell_id": "intro"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	cells, _ := readCells(t, path)
+	if len(cells) != 3 || cells[0]["cell_type"] != "code" || cells[1]["cell_type"] != "raw" || cells[2]["id"] != "calc" {
+		t.Fatalf("unexpected cells %+v", cells)
+	}
+	if _, ok := cells[2]["outputs"]; ok || cells[2]["cell_type"] != "markdown" {
+		t.Fatalf("markdown cell should drop outputs: %+v", cells[2])
+	}
+}
+
+func TestNotebookEditErrorsAndDryRun(t *testing.T) {
+	nb, path := writeNotebook(t)
+	ctx := context.Background()
+	cases := []map[string]interface{}{
+		{"notebook_path": path, "cell_id": "missing", "new_source": "x"},
+		{"notebook_path": path, "cell_index": 9, "new_source": "x"},
+		{"notebook_path": path, "cell_id": "calc", "cell_index": 0, "new_source": "x"},
+		{"notebook_path": path, "cell_id": "calc"},
+		{"notebook_path": path, "edit_mode": "insert", "new_source": "x"},
+		{"notebook_path": path, "edit_mode": "move", "cell_id": "calc"},
+		{"notebook_path": path, "cell_id": "calc", "cell_type": "sql", "new_source": "x"},
+		{"notebook_path": "../outside.ipynb", "cell_id": "calc", "new_source": "x"},
+	}
+	for _, params := range cases {
+		if _, err := nb.Execute(ctx, params); err == nil {
+			t.Fatalf("expected error for %v", params)
+		}
+	}
+
+	res, err := nb.Execute(ctx, map[string]interface{}{"notebook_path": path, "edit_mode": "delete", "cell_index": 0, "dry_run": true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !strings.Contains(res.Output, "-   \"cell_type\": \"markdown\"") {
+		t.Fatalf("expected diff, got %q", res.Output)
+	}
+	if data, _ := os.ReadFile(path); string(data) != sampleNotebook {
+		t.Fatal("dry run modified the notebook")
+	}
+
+	bad := filepath.Join(filepath.Dir(path), "old.ipynb")
+	if err := os.WriteFile(bad, []byte(`{"nbformat": 3, "worksheets": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nb.Execute(ctx, map[string]interface{}{"notebook_path": bad, "cell_index": 0, "edit_mode": "delete"}); err == nil || !strings.Contains(err.Error(), "nbformat 4") {
+		t.Fatalf("expected nbformat error, got %v", err)
+	}
+}
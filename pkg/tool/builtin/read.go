@@ -83,6 +83,9 @@ func NewReadToolWithSandbox(root string, sandbox *security.Sandbox) *ReadTool {
 
 func (r *ReadTool) Name() string { return "Read" }
 
+// Idempotent allows automatic retries: reading a file has no side effects.
+func (r *ReadTool) Idempotent() bool { return true }
+
 func (r *ReadTool) Description() string { return readDescription }
 
 func (r *ReadTool) Schema() *tool.JSONSchema { return readSchema }
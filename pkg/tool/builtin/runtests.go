@@ -0,0 +1,578 @@
+package toolbuiltin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/security"
+	"github.com/cexll/agentsdk-go/pkg/tool"
+)
+
+const (
+	defaultTestTimeout = 10 * time.Minute
+	maxTestTimeout     = 60 * time.Minute
+	// testFailureOutputLimit caps the output kept per failing test.
+	testFailureOutputLimit = 4 << 10
+	runTestsDescription    = `
+	Runs the project's tests and returns structured results.
+
+	- Detects the framework from the working directory: go test (go.mod), cargo test (Cargo.toml), jest (package.json) or pytest (pytest.ini, pyproject.toml, setup.cfg, conftest.py)
+	- Use 'targets' to run only some packages or files (Go packages like ./pkg/foo/..., test files for pytest/jest, crate names for cargo) and 'filter' to select tests by name
+	- Returns pass/fail/skip counts plus the name and output of every failing test
+	- Go packages whose sources and local dependencies did not change since the last run are not re-run; their previous results are reported as cached. Set no_cache=true to force a run
+	- Prefer this tool over Bash for running tests
+	`
+)
+
+var runTestsSchema = &tool.JSONSchema{
+	Type: "object",
+	Properties: map[string]interface{}{
+		"framework": map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{"go", "pytest", "jest", "cargo"},
+			"description": "Test framework; detected from the project when omitted.",
+		},
+		"targets": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string"},
+			"description": "Packages, files or crates to test. Defaults to the whole project.",
+		},
+		"filter": map[string]interface{}{
+			"type":        "string",
+			"description": "Only run tests whose name matches (go -run, pytest -k, jest -t, cargo name filter).",
+		},
+		"workdir": map[string]interface{}{
+			"type":        "string",
+			"description": "Optional project directory relative to the sandbox root.",
+		},
+		"timeout": map[string]interface{}{
+			"type":        "number",
+			"description": "Optional timeout in seconds (defaults to 600, caps at 3600).",
+		},
+		"no_cache": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Re-run every package even when its files are unchanged.",
+		},
+	},
+}
+
+// TestFramework names a supported test runner.
+type TestFramework string
+
+const (
+	FrameworkGo     TestFramework = "go"
+	FrameworkPytest TestFramework = "pytest"
+	FrameworkJest   TestFramework = "jest"
+	FrameworkCargo  TestFramework = "cargo"
+)
+
+// TestStatus is the outcome of one test.
+type TestStatus string
+
+const (
+	TestPassed  TestStatus = "pass"
+	TestFailed  TestStatus = "fail"
+	TestSkipped TestStatus = "skip"
+)
+
+// TestCase is the result of a single test.
+type TestCase struct {
+	Name    string     `json:"name"`
+	Package string     `json:"package,omitempty"`
+	Status  TestStatus `json:"status"`
+	// Output is kept for failing tests only.
+	Output     string `json:"output,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+// TestReport is the structured result of a run_tests call, returned as
+// ToolResult.Data.
+type TestReport struct {
+	Framework TestFramework `json:"framework"`
+	Command   string        `json:"command,omitempty"`
+	Passed    int           `json:"passed"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped"`
+	// Failures lists the failing tests with their output.
+	Failures []TestCase `json:"failures,omitempty"`
+	// Cached lists the Go packages reported from the cache.
+	Cached []string `json:"cached,omitempty"`
+	// Errors holds failures outside any test, such as build errors.
+	Errors     []string `json:"errors,omitempty"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+// add records c and updates the counts.
+func (r *TestReport) add(c TestCase) {
+	switch c.Status {
+	case TestPassed:
+		r.Passed++
+	case TestFailed:
+		r.Failed++
+		c.Output = tailString(strings.TrimRight(c.Output, "\r\n"), testFailureOutputLimit)
+		r.Failures = append(r.Failures, c)
+	case TestSkipped:
+		r.Skipped++
+	}
+}
+
+// RunTestsTool runs a project's tests with its detected framework.
+type RunTestsTool struct {
+	sandbox *security.Sandbox
+	root    string
+	timeout time.Duration
+	cache   *testResultCache
+	// run executes a test command; replaced in tests.
+	run func(ctx context.Context, dir string, name string, args ...string) (stdout, stderr []byte, err error)
+}
+
+// NewRunTestsTool builds a RunTestsTool rooted at the provided directory.
+func NewRunTestsTool(root string) *RunTestsTool {
+	resolved := resolveRoot(root)
+	return NewRunTestsToolWithSandbox(resolved, security.NewSandbox(resolved))
+}
+
+// NewRunTestsToolWithSandbox builds a RunTestsTool with a custom sandbox.
+func NewRunTestsToolWithSandbox(root string, sandbox *security.Sandbox) *RunTestsTool {
+	return &RunTestsTool{
+		sandbox: sandbox,
+		root:    resolveRoot(root),
+		timeout: defaultTestTimeout,
+		cache:   newTestResultCache(),
+		run:     runProjectCommand,
+	}
+}
+
+func (t *RunTestsTool) Name() string { return "RunTests" }
+
+func (t *RunTestsTool) Description() string { return runTestsDescription }
+
+func (t *RunTestsTool) Schema() *tool.JSONSchema { return runTestsSchema }
+
+type runTestsParams struct {
+	framework TestFramework
+	targets   []string
+	filter    string
+	dir       string
+	timeout   time.Duration
+	noCache   bool
+}
+
+func (t *RunTestsTool) Execute(ctx context.Context, params map[string]interface{}) (*tool.ToolResult, error) {
+	if ctx == nil {
+		return nil, errors.New("context is nil")
+	}
+	if t == nil || t.sandbox == nil {
+		return nil, errors.New("run_tests tool is not initialised")
+	}
+	p, err := t.parseParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if p.framework == "" {
+		framework, ok := DetectTestFramework(p.dir)
+		if !ok {
+			return nil, fmt.Errorf("no supported test framework found in %s", p.dir)
+		}
+		p.framework = framework
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	started := time.Now()
+	var report *TestReport
+	switch p.framework {
+	case FrameworkGo:
+		report, err = t.runGo(execCtx, p)
+	case FrameworkPytest:
+		report, err = t.runPytest(execCtx, p)
+	case FrameworkJest:
+		report, err = t.runJest(execCtx, p)
+	case FrameworkCargo:
+		report, err = t.runCargo(execCtx, p)
+	default:
+		return nil, fmt.Errorf("unsupported test framework %q", p.framework)
+	}
+	if err != nil {
+		if errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+			return nil, tool.NewToolError(tool.ErrorTimeout, fmt.Errorf("tests timed out after %s", p.timeout))
+		}
+		return nil, err
+	}
+	report.DurationMs = time.Since(started).Milliseconds()
+	return &tool.ToolResult{
+		Success: report.Failed == 0 && len(report.Errors) == 0,
+		Output:  formatTestReport(report),
+		Data:    report,
+	}, nil
+}
+
+func (t *RunTestsTool) parseParams(ctx context.Context, params map[string]interface{}) (runTestsParams, error) {
+	p := runTestsParams{timeout: t.timeout}
+	if raw, ok := params["framework"]; ok && raw != nil {
+		value, err := coerceString(raw)
+		if err != nil {
+			return p, fmt.Errorf("framework must be string: %w", err)
+		}
+		switch fw := TestFramework(strings.ToLower(strings.TrimSpace(value))); fw {
+		case "", FrameworkGo, FrameworkPytest, FrameworkJest, FrameworkCargo:
+			p.framework = fw
+		default:
+			return p, fmt.Errorf("unsupported test framework %q", value)
+		}
+	}
+	if raw, ok := params["targets"]; ok && raw != nil {
+		items, err := coerceInterfaceArray(raw, "targets")
+		if err != nil {
+			return p, err
+		}
+		for _, item := range items {
+			target, err := coerceString(item)
+			if err != nil {
+				return p, fmt.Errorf("targets must be strings: %w", err)
+			}
+			target = strings.TrimSpace(target)
+			if target == "" {
+				continue
+			}
+			// Targets are passed as arguments; never let them become flags.
+			if strings.HasPrefix(target, "-") {
+				return p, fmt.Errorf("invalid target %q", target)
+			}
+			p.targets = append(p.targets, target)
+		}
+	}
+	if raw, ok := params["filter"]; ok && raw != nil {
+		value, err := coerceString(raw)
+		if err != nil {
+			return p, fmt.Errorf("filter must be string: %w", err)
+		}
+		p.filter = strings.TrimSpace(value)
+	}
+	if raw, ok := params["no_cache"]; ok && raw != nil {
+		value, err := coerceBool(raw)
+		if err != nil {
+			return p, fmt.Errorf("no_cache must be boolean: %w", err)
+		}
+		p.noCache = value
+	}
+	if raw, ok := params["timeout"]; ok && raw != nil {
+		dur, err := durationFromParam(raw)
+		if err != nil {
+			return p, fmt.Errorf("invalid timeout: %w", err)
+		}
+		if dur > 0 {
+			p.timeout = min(dur, maxTestTimeout)
+		}
+	}
+	dir, err := resolveProjectDir(ctx, t.sandbox, t.root, params)
+	if err != nil {
+		return p, err
+	}
+	p.dir = dir
+	return p, nil
+}
+
+// resolveProjectDir picks the workdir parameter, then the runtime's scoped
+// directory, then root, like Bash, and checks it against the sandbox.
+func resolveProjectDir(ctx context.Context, sandbox *security.Sandbox, root string, params map[string]interface{}) (string, error) {
+	dir := root
+	if scoped, ok := tool.WorkDirFromContext(ctx); ok {
+		dir = scoped
+	}
+	if raw, ok := params["workdir"]; ok && raw != nil {
+		value, err := coerceString(raw)
+		if err != nil {
+			return "", fmt.Errorf("workdir must be string: %w", err)
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			dir = value
+		}
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	dir = filepath.Clean(dir)
+	if err := sandbox.ValidatePath(dir); err != nil {
+		return "", err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("workdir stat: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("workdir %s is not a directory", dir)
+	}
+	return dir, nil
+}
+
+// DetectTestFramework picks the test framework of the project in dir by its
+// manifest files. Go and Cargo win over JavaScript and Python because mixed
+// repositories usually keep tooling scripts next to the main code.
+func DetectTestFramework(dir string) (TestFramework, bool) {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	switch {
+	case exists("go.mod"):
+		return FrameworkGo, true
+	case exists("Cargo.toml"):
+		return FrameworkCargo, true
+	case usesJest(filepath.Join(dir, "package.json")):
+		return FrameworkJest, true
+	case exists("pytest.ini") || exists("conftest.py") || fileContains(filepath.Join(dir, "pyproject.toml"), "[tool.pytest") || fileContains(filepath.Join(dir, "setup.cfg"), "[tool:pytest]"):
+		return FrameworkPytest, true
+	}
+	return "", false
+}
+
+func usesJest(manifest string) bool {
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return false
+	}
+	var pkg struct {
+		Scripts         map[string]string `json:"scripts"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+		Jest            json.RawMessage   `json:"jest"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return false
+	}
+	_, dep := pkg.Dependencies["jest"]
+	_, devDep := pkg.DevDependencies["jest"]
+	return dep || devDep || len(pkg.Jest) > 0 || strings.Contains(pkg.Scripts["test"], "jest")
+}
+
+func fileContains(path, needle string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && bytes.Contains(data, []byte(needle))
+}
+
+func (t *RunTestsTool) runGo(ctx context.Context, p runTestsParams) (*TestReport, error) {
+	targets := p.targets
+	if len(targets) == 0 {
+		targets = []string{"./..."}
+	}
+	report := &TestReport{Framework: FrameworkGo}
+	pending := targets
+	var plan *goTestPlan
+	if !p.noCache {
+		var err error
+		plan, err = t.planGo(ctx, p.dir, targets, p.filter)
+		if err == nil {
+			pending = nil
+			for _, pkg := range plan.packages {
+				if cases, ok := t.cache.get(plan.keys[pkg]); ok {
+					report.Cached = append(report.Cached, pkg)
+					for _, c := range cases {
+						report.add(c)
+					}
+					continue
+				}
+				pending = append(pending, pkg)
+			}
+		}
+	}
+	if len(pending) == 0 {
+		report.Command = "go test (all packages cached)"
+		return report, nil
+	}
+
+	args := []string{"test", "-json"}
+	if p.filter != "" {
+		args = append(args, "-run", p.filter)
+	}
+	args = append(args, pending...)
+	report.Command = "go " + strings.Join(args, " ")
+	stdout, stderr, err := t.run(ctx, p.dir, "go", args...)
+	if err != nil && len(stdout) == 0 {
+		return nil, testCommandError(err, stderr)
+	}
+	results, buildErrors := parseGoTestJSON(stdout)
+	for _, pkg := range sortedResultKeys(results) {
+		res := results[pkg]
+		for _, c := range res.cases {
+			report.add(c)
+		}
+		if res.failed && !res.hasFailedCase() {
+			report.Errors = append(report.Errors, strings.TrimSpace(pkg+": "+tailString(res.output, testFailureOutputLimit)))
+		}
+		// Only clean results are cached; failures are re-run so fixes show.
+		if plan != nil && !res.failed {
+			if key, ok := plan.keys[pkg]; ok {
+				t.cache.put(key, res.cases)
+			}
+		}
+	}
+	report.Errors = append(report.Errors, buildErrors...)
+	if msg := strings.TrimSpace(string(stderr)); msg != "" && report.Failed == 0 && len(report.Errors) == 0 && err != nil {
+		report.Errors = append(report.Errors, tailString(msg, testFailureOutputLimit))
+	}
+	return report, nil
+}
+
+func (t *RunTestsTool) runPytest(ctx context.Context, p runTestsParams) (*TestReport, error) {
+	junit, cleanup, err := testOutputFile("pytest-*.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	python := "python3"
+	if _, err := exec.LookPath(python); err != nil {
+		python = "python"
+	}
+	args := []string{"-m", "pytest", "-q", "--junitxml=" + junit}
+	if p.filter != "" {
+		args = append(args, "-k", p.filter)
+	}
+	args = append(args, p.targets...)
+	report := &TestReport{Framework: FrameworkPytest, Command: python + " " + strings.Join(args, " ")}
+	stdout, stderr, runErr := t.run(ctx, p.dir, python, args...)
+	data, err := os.ReadFile(junit)
+	if err != nil || len(data) == 0 {
+		return nil, testCommandError(runErr, append(stdout, stderr...))
+	}
+	cases, err := parseJUnitXML(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cases {
+		report.add(c)
+	}
+	return report, nil
+}
+
+func (t *RunTestsTool) runJest(ctx context.Context, p runTestsParams) (*TestReport, error) {
+	out, cleanup, err := testOutputFile("jest-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	args := []string{"--no-install", "jest", "--json", "--outputFile=" + out}
+	if p.filter != "" {
+		args = append(args, "--testNamePattern", p.filter)
+	}
+	args = append(args, p.targets...)
+	report := &TestReport{Framework: FrameworkJest, Command: "npx " + strings.Join(args, " ")}
+	stdout, stderr, runErr := t.run(ctx, p.dir, "npx", args...)
+	data, err := os.ReadFile(out)
+	if err != nil || len(data) == 0 {
+		return nil, testCommandError(runErr, append(stdout, stderr...))
+	}
+	cases, suiteErrors, err := parseJestJSON(data, p.dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cases {
+		report.add(c)
+	}
+	report.Errors = suiteErrors
+	return report, nil
+}
+
+func (t *RunTestsTool) runCargo(ctx context.Context, p runTestsParams) (*TestReport, error) {
+	args := []string{"test"}
+	for _, crate := range p.targets {
+		args = append(args, "-p", crate)
+	}
+	if p.filter != "" {
+		args = append(args, p.filter)
+	}
+	report := &TestReport{Framework: FrameworkCargo, Command: "cargo " + strings.Join(args, " ")}
+	stdout, stderr, runErr := t.run(ctx, p.dir, "cargo", args...)
+	cases := parseCargoTestOutput(string(stdout))
+	if len(cases) == 0 && runErr != nil {
+		return nil, testCommandError(runErr, stderr)
+	}
+	for _, c := range cases {
+		report.add(c)
+	}
+	if runErr != nil && report.Failed == 0 {
+		report.Errors = append(report.Errors, tailString(strings.TrimSpace(string(stderr)), testFailureOutputLimit))
+	}
+	return report, nil
+}
+
+// runProjectCommand runs name with args in dir. A non-zero exit is returned
+// as err alongside the captured output; test runners and linters exit
+// non-zero when they find problems.
+func runProjectCommand(ctx context.Context, dir, name string, args ...string) ([]byte, []byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = commandEnv(ctx)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+func testCommandError(err error, output []byte) error {
+	msg := tailString(strings.TrimSpace(string(output)), testFailureOutputLimit)
+	if err == nil {
+		err = errors.New("test runner produced no results")
+	}
+	te := tool.NewToolError(tool.ErrorExecution, fmt.Errorf("run tests: %w", err))
+	te.Stderr = msg
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		te.ExitCode = &code
+	}
+	return te
+}
+
+// testOutputFile reserves a temp file for a runner's report.
+func testOutputFile(pattern string) (string, func(), error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", nil, fmt.Errorf("create report file: %w", err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	return name, func() { _ = os.Remove(name) }, nil
+}
+
+func formatTestReport(r *TestReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d passed, %d failed, %d skipped", r.Framework, r.Passed, r.Failed, r.Skipped)
+	if len(r.Cached) > 0 {
+		fmt.Fprintf(&b, " (%d packages cached)", len(r.Cached))
+	}
+	for _, e := range r.Errors {
+		b.WriteString("\n\nERROR ")
+		b.WriteString(e)
+	}
+	for _, f := range r.Failures {
+		b.WriteString("\n\nFAIL ")
+		if f.Package != "" {
+			b.WriteString(f.Package + " ")
+		}
+		b.WriteString(f.Name)
+		if f.Output != "" {
+			b.WriteString("\n" + f.Output)
+		}
+	}
+	return b.String()
+}
+
+func sortedResultKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
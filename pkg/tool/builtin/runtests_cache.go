@@ -0,0 +1,225 @@
+package toolbuiltin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// testCacheLimit bounds the cached package results per tool.
+const testCacheLimit = 1024
+
+// testResultCache keeps the results of passing Go packages keyed by a hash of
+// everything that can change them.
+type testResultCache struct {
+	mu      sync.Mutex
+	results map[string][]TestCase
+}
+
+func newTestResultCache() *testResultCache {
+	return &testResultCache{results: map[string][]TestCase{}}
+}
+
+func (c *testResultCache) get(key string) ([]TestCase, bool) {
+	if c == nil || key == "" {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cases, ok := c.results[key]
+	return cases, ok
+}
+
+func (c *testResultCache) put(key string, cases []TestCase) {
+	if c == nil || key == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.results) >= testCacheLimit {
+		// Entries of edited packages are never hit again; start over
+		// rather than tracking recency.
+		c.results = map[string][]TestCase{}
+	}
+	c.results[key] = append([]TestCase(nil), cases...)
+}
+
+// goTestPlan lists the packages selected by the targets and their cache keys.
+type goTestPlan struct {
+	packages []string
+	keys     map[string]string
+}
+
+type goListPackage struct {
+	ImportPath   string
+	Dir          string
+	ForTest      string
+	DepOnly      bool
+	Standard     bool
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+	Module       *struct {
+		Main  bool
+		GoMod string
+	}
+}
+
+// planGo resolves targets with `go list -deps -test` and keys every selected
+// package by the contents of its directory and of the main-module packages it
+// and its tests import, plus go.mod, go.sum and the test filter. Packages from
+// other modules are pinned by go.sum.
+func (t *RunTestsTool) planGo(ctx context.Context, dir string, targets []string, filter string) (*goTestPlan, error) {
+	args := append([]string{"list", "-json", "-deps", "-test"}, targets...)
+	stdout, stderr, err := t.run(ctx, dir, "go", args...)
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w: %s", err, strings.TrimSpace(string(stderr)))
+	}
+	pkgs := map[string]*goListPackage{}
+	var selected []string
+	dec := json.NewDecoder(strings.NewReader(string(stdout)))
+	for {
+		var pkg goListPackage
+		if err := dec.Decode(&pkg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("decode go list: %w", err)
+		}
+		// Test variants ("p [p.test]") share the directory of p; the plain
+		// entry carries all import lists.
+		if pkg.ForTest != "" || strings.HasSuffix(pkg.ImportPath, ".test") {
+			continue
+		}
+		if _, seen := pkgs[pkg.ImportPath]; seen {
+			continue
+		}
+		p := pkg
+		pkgs[pkg.ImportPath] = &p
+		if !pkg.DepOnly {
+			selected = append(selected, pkg.ImportPath)
+		}
+	}
+	sort.Strings(selected)
+
+	plan := &goTestPlan{packages: selected, keys: map[string]string{}}
+	dirHashes := map[string]string{}
+	for _, path := range selected {
+		root := pkgs[path]
+		if root.Module == nil || !root.Module.Main {
+			continue
+		}
+		h := sha256.New()
+		fmt.Fprintf(h, "pkg %s\nfilter %s\n", path, filter)
+		for _, name := range []string{root.Module.GoMod, strings.TrimSuffix(root.Module.GoMod, ".mod") + ".sum"} {
+			sum, err := hashFile(name)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			fmt.Fprintf(h, "file %s %s\n", filepath.Base(name), sum)
+		}
+		for _, dep := range localClosure(pkgs, root) {
+			sum, ok := dirHashes[dep.Dir]
+			if !ok {
+				var err error
+				if sum, err = hashPackageDir(dep.Dir); err != nil {
+					return nil, err
+				}
+				dirHashes[dep.Dir] = sum
+			}
+			fmt.Fprintf(h, "dir %s %s\n", dep.ImportPath, sum)
+		}
+		plan.keys[path] = hex.EncodeToString(h.Sum(nil))
+	}
+	return plan, nil
+}
+
+// localClosure returns root and every main-module package reachable through
+// its imports, test imports included for root itself, sorted by path.
+func localClosure(pkgs map[string]*goListPackage, root *goListPackage) []*goListPackage {
+	seen := map[string]bool{root.ImportPath: true}
+	out := []*goListPackage{root}
+	queue := append(append(append([]string(nil), root.Imports...), root.TestImports...), root.XTestImports...)
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		pkg, ok := pkgs[path]
+		if !ok || pkg.Standard || pkg.Module == nil || !pkg.Module.Main {
+			continue
+		}
+		out = append(out, pkg)
+		queue = append(queue, pkg.Imports...)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ImportPath < out[j].ImportPath })
+	return out
+}
+
+// hashPackageDir hashes the regular files directly in dir and everything
+// under its testdata directory, which is what go test itself reads.
+func hashPackageDir(dir string) (string, error) {
+	h := sha256.New()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		sum, err := hashFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %s\n", entry.Name(), sum)
+	}
+	testdata := filepath.Join(dir, "testdata")
+	err = filepath.WalkDir(testdata, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == testdata {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		sum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		fmt.Fprintf(h, "%s %s\n", filepath.ToSlash(rel), sum)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
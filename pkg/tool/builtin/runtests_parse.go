@@ -0,0 +1,302 @@
+package toolbuiltin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// goPackageResult collects the test2json events of one package.
+type goPackageResult struct {
+	cases  []TestCase
+	failed bool
+	// output is the package-level output, used when the package fails
+	// without a failing test (build errors, TestMain failures, panics).
+	output string
+}
+
+func (r *goPackageResult) hasFailedCase() bool {
+	for _, c := range r.cases {
+		if c.Status == TestFailed {
+			return true
+		}
+	}
+	return false
+}
+
+type goTestEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Output  string  `json:"Output"`
+	Elapsed float64 `json:"Elapsed"`
+}
+
+// parseGoTestJSON parses `go test -json` output into per-package results.
+// Lines that are not JSON events, such as compiler errors printed before a
+// package runs, are returned as errors.
+func parseGoTestJSON(data []byte) (map[string]*goPackageResult, []string) {
+	results := map[string]*goPackageResult{}
+	outputs := map[string]*strings.Builder{}
+	var stray []string
+	pkgResult := func(pkg string) *goPackageResult {
+		res, ok := results[pkg]
+		if !ok {
+			res = &goPackageResult{}
+			results[pkg] = res
+		}
+		return res
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64<<10), 8<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var ev goTestEvent
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &ev) != nil {
+			if text := strings.TrimSpace(string(line)); text != "" {
+				stray = append(stray, text)
+			}
+			continue
+		}
+		if ev.Package == "" {
+			continue
+		}
+		res := pkgResult(ev.Package)
+		key := ev.Package + "\x00" + ev.Test
+		switch ev.Action {
+		case "output":
+			b, ok := outputs[key]
+			if !ok {
+				b = &strings.Builder{}
+				outputs[key] = b
+			}
+			b.WriteString(ev.Output)
+		case "pass", "fail", "skip":
+			if ev.Test == "" {
+				res.failed = ev.Action == "fail"
+				if b, ok := outputs[key]; ok {
+					res.output = b.String()
+				}
+				continue
+			}
+			c := TestCase{
+				Name:       ev.Test,
+				Package:    ev.Package,
+				Status:     goTestStatus(ev.Action),
+				DurationMs: int64(ev.Elapsed * 1000),
+			}
+			if c.Status == TestFailed {
+				if b, ok := outputs[key]; ok {
+					c.Output = b.String()
+				}
+			}
+			res.cases = append(res.cases, c)
+		}
+	}
+	var errs []string
+	if len(stray) > 0 {
+		errs = append(errs, tailString(strings.Join(stray, "\n"), testFailureOutputLimit))
+	}
+	return results, errs
+}
+
+func goTestStatus(action string) TestStatus {
+	switch action {
+	case "pass":
+		return TestPassed
+	case "fail":
+		return TestFailed
+	default:
+		return TestSkipped
+	}
+}
+
+type junitSuites struct {
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Cases []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// parseJUnitXML parses a JUnit XML report as written by pytest --junitxml.
+// Both a <testsuites> root and a bare <testsuite> root are accepted.
+func parseJUnitXML(data []byte) ([]TestCase, error) {
+	var suites junitSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		return nil, fmt.Errorf("parse junit report: %w", err)
+	}
+	if len(suites.Suites) == 0 {
+		var single junitSuite
+		if err := xml.Unmarshal(data, &single); err != nil {
+			return nil, fmt.Errorf("parse junit report: %w", err)
+		}
+		suites.Suites = []junitSuite{single}
+	}
+	var cases []TestCase
+	for _, suite := range suites.Suites {
+		for _, jc := range suite.Cases {
+			c := TestCase{
+				Name:       jc.Name,
+				Package:    jc.ClassName,
+				Status:     TestPassed,
+				DurationMs: int64(jc.Time * 1000),
+			}
+			switch {
+			case jc.Failure != nil:
+				c.Status, c.Output = TestFailed, junitOutput(jc.Failure)
+			case jc.Error != nil:
+				c.Status, c.Output = TestFailed, junitOutput(jc.Error)
+			case jc.Skipped != nil:
+				c.Status = TestSkipped
+			}
+			cases = append(cases, c)
+		}
+	}
+	return cases, nil
+}
+
+func junitOutput(m *junitMessage) string {
+	if body := strings.TrimSpace(m.Body); body != "" {
+		return body
+	}
+	return m.Message
+}
+
+type jestReport struct {
+	TestResults []struct {
+		Name             string `json:"name"`
+		Status           string `json:"status"`
+		Message          string `json:"message"`
+		AssertionResults []struct {
+			FullName        string   `json:"fullName"`
+			Title           string   `json:"title"`
+			Status          string   `json:"status"`
+			Duration        *float64 `json:"duration"`
+			FailureMessages []string `json:"failureMessages"`
+		} `json:"assertionResults"`
+	} `json:"testResults"`
+}
+
+// parseJestJSON parses a `jest --json` report. Suites that failed without
+// running any test (syntax errors, missing modules) are returned as errors.
+// Suite paths are made relative to dir.
+func parseJestJSON(data []byte, dir string) ([]TestCase, []string, error) {
+	var report jestReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, nil, fmt.Errorf("parse jest report: %w", err)
+	}
+	var cases []TestCase
+	var errs []string
+	for _, suite := range report.TestResults {
+		name := suite.Name
+		if rel, err := filepath.Rel(dir, name); err == nil && !strings.HasPrefix(rel, "..") {
+			name = filepath.ToSlash(rel)
+		}
+		if suite.Status == "failed" && len(suite.AssertionResults) == 0 {
+			errs = append(errs, strings.TrimSpace(name+": "+tailString(suite.Message, testFailureOutputLimit)))
+			continue
+		}
+		for _, a := range suite.AssertionResults {
+			c := TestCase{Name: a.FullName, Package: name}
+			if c.Name == "" {
+				c.Name = a.Title
+			}
+			if a.Duration != nil {
+				c.DurationMs = int64(*a.Duration)
+			}
+			switch a.Status {
+			case "passed":
+				c.Status = TestPassed
+			case "failed":
+				c.Status = TestFailed
+				c.Output = strings.Join(a.FailureMessages, "\n")
+			default:
+				c.Status = TestSkipped
+			}
+			cases = append(cases, c)
+		}
+	}
+	return cases, errs, nil
+}
+
+var (
+	cargoResultLine  = regexp.MustCompile(`^test (\S+) \.\.\. (ok|FAILED|ignored)`)
+	cargoStdoutStart = regexp.MustCompile(`^---- (\S+) stdout ----$`)
+	cargoRunning     = regexp.MustCompile(`^\s*Running (?:unittests )?(\S+)`)
+)
+
+// parseCargoTestOutput parses the libtest text output of `cargo test`. The
+// failure output libtest prints after the results is attached to each
+// failing test.
+func parseCargoTestOutput(out string) []TestCase {
+	var cases []TestCase
+	index := map[string]int{}
+	target := ""
+	current := -1
+	var captured strings.Builder
+	flush := func() {
+		if current >= 0 {
+			cases[current].Output = strings.TrimSpace(captured.String())
+		}
+		current = -1
+		captured.Reset()
+	}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := cargoRunning.FindStringSubmatch(line); m != nil {
+			flush()
+			target = m[1]
+			continue
+		}
+		if m := cargoResultLine.FindStringSubmatch(line); m != nil {
+			c := TestCase{Name: m[1], Package: target}
+			switch m[2] {
+			case "ok":
+				c.Status = TestPassed
+			case "FAILED":
+				c.Status = TestFailed
+			default:
+				c.Status = TestSkipped
+			}
+			index[target+"\x00"+c.Name] = len(cases)
+			cases = append(cases, c)
+			continue
+		}
+		if m := cargoStdoutStart.FindStringSubmatch(line); m != nil {
+			flush()
+			if i, ok := index[target+"\x00"+m[1]]; ok {
+				current = i
+			}
+			continue
+		}
+		if current >= 0 {
+			if line == "failures:" || strings.HasPrefix(line, "test result:") {
+				flush()
+				continue
+			}
+			captured.WriteString(line + "\n")
+		}
+	}
+	flush()
+	return cases
+}
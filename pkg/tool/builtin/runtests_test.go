@@ -0,0 +1,237 @@
+package toolbuiltin
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectTestFramework(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		files map[string]string
+		want  TestFramework
+	}{
+		{"go", map[string]string{"go.mod": "module x\n", "package.json": `{"devDependencies":{"jest":"29"}}`}, FrameworkGo},
+		{"cargo", map[string]string{"Cargo.toml": "[package]\n"}, FrameworkCargo},
+		{"jest", map[string]string{"package.json": `{"scripts":{"test":"jest --ci"}}`}, FrameworkJest},
+		{"pytest", map[string]string{"pyproject.toml": "[tool.pytest.ini_options]\n"}, FrameworkPytest},
+		{"conftest", map[string]string{"conftest.py": ""}, FrameworkPytest},
+		{"none", map[string]string{"package.json": `{"scripts":{"test":"mocha"}}`}, ""},
+	}
+	for _, tc := range cases {
+		dir := t.TempDir()
+		for name, body := range tc.files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o600); err != nil {
+				t.Fatalf("write %s: %v", name, err)
+			}
+		}
+		got, ok := DetectTestFramework(dir)
+		if got != tc.want || ok != (tc.want != "") {
+			t.Fatalf("%s: got %q/%v want %q", tc.name, got, ok, tc.want)
+		}
+	}
+}
+
+func TestParseGoTestJSON(t *testing.T) {
+	t.Parallel()
+
+	out := strings.Join([]string{
+		`{"Action":"run","Package":"ex/a","Test":"TestOK"}`,
+		`{"Action":"pass","Package":"ex/a","Test":"TestOK","Elapsed":0.01}`,
+		`{"Action":"output","Package":"ex/a","Test":"TestBad","Output":"    a_test.go:9: want 2\n"}`,
+		`{"Action":"fail","Package":"ex/a","Test":"TestBad","Elapsed":0}`,
+		`{"Action":"skip","Package":"ex/a","Test":"TestSkip"}`,
+		`{"Action":"fail","Package":"ex/a","Elapsed":0.1}`,
+		`{"Action":"output","Package":"ex/b","Output":"panic: boom\n"}`,
+		`{"Action":"fail","Package":"ex/b"}`,
+		`# ex/c`,
+		`c.go:3:1: syntax error`,
+	}, "\n")
+	results, errs := parseGoTestJSON([]byte(out))
+	a := results["ex/a"]
+	if a == nil || !a.failed || len(a.cases) != 3 || !a.hasFailedCase() {
+		t.Fatalf("unexpected ex/a result %+v", a)
+	}
+	if a.cases[1].Status != TestFailed || !strings.Contains(a.cases[1].Output, "want 2") {
+		t.Fatalf("failure output not captured: %+v", a.cases[1])
+	}
+	b := results["ex/b"]
+	if b == nil || !b.failed || b.hasFailedCase() || !strings.Contains(b.output, "panic: boom") {
+		t.Fatalf("unexpected ex/b result %+v", b)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0], "syntax error") {
+		t.Fatalf("expected build error, got %v", errs)
+	}
+}
+
+func TestParseJUnitXML(t *testing.T) {
+	t.Parallel()
+
+	data := `<?xml version="1.0"?>
+<testsuites><testsuite name="pytest">
+<testcase classname="tests.test_a" name="test_ok" time="0.5"/>
+<testcase classname="tests.test_a" name="test_bad"><failure message="assert 1 == 2">def test_bad():
+&gt;   assert 1 == 2</failure></testcase>
+<testcase classname="tests.test_a" name="test_err"><error message="fixture missing"/></testcase>
+<testcase classname="tests.test_a" name="test_skip"><skipped message="later"/></testcase>
+</testsuite></testsuites>`
+	cases, err := parseJUnitXML([]byte(data))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []TestStatus{TestPassed, TestFailed, TestFailed, TestSkipped}
+	if len(cases) != len(want) {
+		t.Fatalf("got %d cases", len(cases))
+	}
+	for i, status := range want {
+		if cases[i].Status != status {
+			t.Fatalf("case %d: got %s want %s", i, cases[i].Status, status)
+		}
+	}
+	if !strings.Contains(cases[1].Output, "> ") || cases[2].Output != "fixture missing" || cases[0].DurationMs != 500 {
+		t.Fatalf("unexpected details %+v", cases)
+	}
+
+	single, err := parseJUnitXML([]byte(`<testsuite><testcase name="t"/></testsuite>`))
+	if err != nil || len(single) != 1 {
+		t.Fatalf("bare testsuite: %v %+v", err, single)
+	}
+}
+
+func TestParseJestJSON(t *testing.T) {
+	t.Parallel()
+
+	data := `{"testResults":[
+{"name":"/repo/src/a.test.js","status":"failed","assertionResults":[
+ {"fullName":"a adds","status":"passed","duration":3},
+ {"fullName":"a subtracts","status":"failed","failureMessages":["expected 1"]},
+ {"title":"todo","status":"todo"}]},
+{"name":"/repo/src/b.test.js","status":"failed","message":"SyntaxError: bad","assertionResults":[]}]}`
+	cases, errs, err := parseJestJSON([]byte(data), "/repo")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(cases) != 3 || cases[1].Status != TestFailed || cases[1].Output != "expected 1" || cases[2].Status != TestSkipped || cases[2].Name != "todo" {
+		t.Fatalf("unexpected cases %+v", cases)
+	}
+	if cases[0].Package != "src/a.test.js" {
+		t.Fatalf("expected relative suite path, got %q", cases[0].Package)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0], "src/b.test.js: SyntaxError") {
+		t.Fatalf("unexpected suite errors %v", errs)
+	}
+}
+
+func TestParseCargoTestOutput(t *testing.T) {
+	t.Parallel()
+
+	out := `     Running unittests src/lib.rs (target/debug/deps/demo-1234)
+
+running 3 tests
+test tests::adds ... ok
+test tests::subtracts ... FAILED
+test tests::slow ... ignored
+
+failures:
+
+---- tests::subtracts stdout ----
+thread 'tests::subtracts' panicked at src/lib.rs:10:9:
+assertion failed
+
+failures:
+    tests::subtracts
+
+test result: FAILED. 1 passed; 1 failed; 1 ignored; 0 measured; 0 filtered out
+`
+	cases := parseCargoTestOutput(out)
+	if len(cases) != 3 {
+		t.Fatalf("got %d cases", len(cases))
+	}
+	if cases[0].Status != TestPassed || cases[1].Status != TestFailed || cases[2].Status != TestSkipped {
+		t.Fatalf("unexpected statuses %+v", cases)
+	}
+	if cases[1].Package != "src/lib.rs" || !strings.Contains(cases[1].Output, "assertion failed") || strings.Contains(cases[1].Output, "failures:") {
+		t.Fatalf("unexpected failure %+v", cases[1])
+	}
+}
+
+func TestRunTestsRejectsFlagTargets(t *testing.T) {
+	t.Parallel()
+
+	rt := NewRunTestsTool(t.TempDir())
+	_, err := rt.Execute(context.Background(), map[string]interface{}{
+		"framework": "go",
+		"targets":   []interface{}{"-exec=rm"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid target") {
+		t.Fatalf("expected invalid target error, got %v", err)
+	}
+}
+
+func TestRunTestsGoCachesUnchangedPackages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go test")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	root := t.TempDir()
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	write := func(rel, body string) {
+		t.Helper()
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatalf("write %s: %v", rel, err)
+		}
+	}
+	write("go.mod", "module example.com/demo\n\ngo 1.21\n")
+	write("util/util.go", "package util\n\nfunc Two() int { return 2 }\n")
+	write("util/util_test.go", "package util\n\nimport \"testing\"\n\nfunc TestTwo(t *testing.T) {\n\tif Two() != 2 {\n\t\tt.Fatal(\"bad\")\n\t}\n}\n")
+	write("app/app.go", "package app\n\nimport \"example.com/demo/util\"\n\nfunc Four() int { return util.Two() * 2 }\n")
+	write("app/app_test.go", "package app\n\nimport \"testing\"\n\nfunc TestFour(t *testing.T) {\n\tif Four() != 4 {\n\t\tt.Fatalf(\"got %d\", Four())\n\t}\n}\n")
+	write("other/other_test.go", "package other\n\nimport \"testing\"\n\nfunc TestOther(t *testing.T) {}\n")
+
+	rt := NewRunTestsTool(root)
+	run := func() *TestReport {
+		t.Helper()
+		res, err := rt.Execute(context.Background(), map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("execute: %v", err)
+		}
+		report, ok := res.Data.(*TestReport)
+		if !ok {
+			t.Fatalf("unexpected data %T", res.Data)
+		}
+		return report
+	}
+
+	first := run()
+	if first.Framework != FrameworkGo || first.Passed != 3 || first.Failed != 0 || len(first.Cached) != 0 {
+		t.Fatalf("unexpected first run %+v", first)
+	}
+	second := run()
+	if second.Passed != 3 || len(second.Cached) != 3 {
+		t.Fatalf("expected all packages cached, got %+v", second)
+	}
+
+	// Changing util invalidates util and app, which imports it, but not other.
+	write("util/util.go", "package util\n\nfunc Two() int { return 3 }\n")
+	third := run()
+	if third.Failed != 2 || strings.Join(third.Cached, ",") != "example.com/demo/other" {
+		t.Fatalf("expected util and app to re-run and fail, got %+v", third)
+	}
+	if len(third.Failures) != 2 || !strings.Contains(third.Failures[0].Output, "got 6") {
+		t.Fatalf("expected failure output, got %+v", third.Failures)
+	}
+}
@@ -1,12 +1,18 @@
 package toolbuiltin
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
 	"github.com/cexll/agentsdk-go/pkg/runtime/skills"
 	"github.com/cexll/agentsdk-go/pkg/tool"
 )
@@ -134,12 +140,19 @@ func (s *SkillTool) Execute(ctx context.Context, params map[string]interface{})
 	if err != nil {
 		return nil, err
 	}
-	output := formatSkillOutput(result)
+	output, files := formatSkillContext(name, result)
+	if output == "" {
+		output = formatSkillOutput(result)
+	}
+	recordSkillActivation(ctx, name)
 	data := map[string]interface{}{
 		"skill":    result.Skill,
 		"output":   result.Output,
 		"metadata": result.Metadata,
 	}
+	if len(files) > 0 {
+		data["files"] = files
+	}
 	return &tool.ToolResult{
 		Success: true,
 		Output:  output,
@@ -147,6 +160,91 @@ func (s *SkillTool) Execute(ctx context.Context, params map[string]interface{})
 	}, nil
 }
 
+// SkillActivationStateKey is the middleware State value listing the skills
+// the model activated through the Skill tool in this run, in order.
+const SkillActivationStateKey = "skill.activated"
+
+// maxSkillReferenceBytes caps how much of the files a skill body references
+// is inlined into the tool result.
+const maxSkillReferenceBytes = 64 << 10
+
+// formatSkillContext renders a file-backed skill (a lazily loaded SKILL.md)
+// for the conversation: the body, the reference files it mentions inlined,
+// and the absolute paths of its other bundled and declared resource files. It returns "" for
+// skills registered in code, whose output is used as is.
+func formatSkillContext(name string, result skills.Result) (string, []string) {
+	out, ok := result.Output.(map[string]any)
+	if !ok {
+		return "", nil
+	}
+	body, ok := out["body"].(string)
+	if !ok {
+		return "", nil
+	}
+	if result.Skill != "" {
+		name = result.Skill
+	}
+	var dir string
+	if source, ok := result.Metadata["source"].(string); ok && source != "" {
+		dir = filepath.Dir(source)
+	}
+	support, _ := out["support_files"].(map[string][]string)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<command-message>The %q skill is loading</command-message>\n\n", name)
+	fmt.Fprintf(&b, "<skill name=%q>\n%s\n</skill>\n", name, strings.TrimSpace(body))
+
+	var listed []string
+	budget := maxSkillReferenceBytes
+	for _, sub := range []string{"references", "scripts", "assets"} {
+		for _, rel := range support[sub] {
+			rel = sub + "/" + rel
+			path := rel
+			if dir != "" {
+				path = filepath.Join(dir, filepath.FromSlash(rel))
+			}
+			listed = append(listed, path)
+			if sub != "references" || dir == "" || !strings.Contains(body, rel) {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil || len(data) > budget || bytes.IndexByte(data, 0) >= 0 {
+				continue
+			}
+			budget -= len(data)
+			fmt.Fprintf(&b, "\n<file path=%q>\n%s\n</file>\n", path, strings.TrimRight(string(data), "\n"))
+		}
+	}
+	resources, _ := out["resources"].([]string)
+	for _, path := range resources {
+		if !slices.Contains(listed, path) {
+			listed = append(listed, path)
+		}
+	}
+	if len(listed) > 0 {
+		b.WriteString("\nSkill files:\n")
+		for _, path := range listed {
+			fmt.Fprintf(&b, "- %s\n", path)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n"), listed
+}
+
+// recordSkillActivation appends name to the run's SkillActivationStateKey.
+func recordSkillActivation(ctx context.Context, name string) {
+	st, ok := ctx.Value(model.MiddlewareStateKey).(*middleware.State)
+	if !ok || st == nil {
+		return
+	}
+	if st.Values == nil {
+		st.Values = map[string]any{}
+	}
+	activated, _ := st.Values[SkillActivationStateKey].([]string)
+	if !slices.Contains(activated, name) {
+		st.Values[SkillActivationStateKey] = append(slices.Clone(activated), name)
+	}
+}
+
 func parseSkillName(params map[string]interface{}) (string, error) {
 	if params == nil {
 		return "", errors.New("params is nil")
@@ -3,9 +3,14 @@ package toolbuiltin
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/cexll/agentsdk-go/pkg/middleware"
+	"github.com/cexll/agentsdk-go/pkg/model"
 	"github.com/cexll/agentsdk-go/pkg/runtime/skills"
 )
 
@@ -117,3 +122,57 @@ func TestFormatSkillOutputVariants(t *testing.T) {
 		})
 	}
 }
+
+func TestSkillToolInjectsSkillContext(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, ".claude", "skills", "pdf")
+	files := map[string]string{
+		"SKILL.md":              "---\nname: pdf\ndescription: Work with PDFs\nresources: [../../../forms/w9.pdf]\n---\nRead references/forms.md before filling forms.\n",
+		"references/forms.md":   "Use pdftk for forms.\n",
+		"references/unused.md":  "not mentioned\n",
+		"scripts/fill.sh":       "#!/bin/sh\n",
+		"../../../forms/w9.pdf": "%PDF",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	regs, errs := skills.LoadFromFS(skills.LoaderOptions{ProjectRoot: root})
+	if len(errs) != 0 || len(regs) != 1 {
+		t.Fatalf("load: regs=%d errs=%v", len(regs), errs)
+	}
+	reg := skills.NewRegistry()
+	if err := reg.Register(regs[0].Definition, regs[0].Handler); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	st := &middleware.State{}
+	ctx := context.WithValue(context.Background(), model.MiddlewareStateKey, st)
+	res, err := NewSkillTool(reg, nil).Execute(ctx, map[string]interface{}{"command": "pdf"})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	forms := filepath.Join(dir, "references", "forms.md")
+	for _, want := range []string{
+		`<command-message>The "pdf" skill is loading</command-message>`,
+		"Read references/forms.md before filling forms.",
+		"<file path=\"" + forms + "\">\nUse pdftk for forms.\n</file>",
+		"- " + filepath.Join(dir, "scripts", "fill.sh"),
+		"- " + filepath.Join(root, "forms", "w9.pdf"),
+	} {
+		if !strings.Contains(res.Output, want) {
+			t.Fatalf("output missing %q:\n%s", want, res.Output)
+		}
+	}
+	if strings.Contains(res.Output, "not mentioned") {
+		t.Fatalf("unreferenced file inlined:\n%s", res.Output)
+	}
+	if got := st.Values[SkillActivationStateKey]; !reflect.DeepEqual(got, []string{"pdf"}) {
+		t.Fatalf("activation state = %#v", got)
+	}
+}
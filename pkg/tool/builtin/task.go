@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"strings"
 	"sync"
 
@@ -11,15 +12,17 @@ import (
 	"github.com/cexll/agentsdk-go/pkg/tool"
 )
 
+const taskBuiltinAgentList = `- general-purpose: Full-access agent for multi-step research, coding, and remediation. Default model: sonnet. (Tools: all)
+- explore: Fast read-only explorer for globbing, grep, and focused file reads when you need quick answers. Default model: haiku. (Tools: Glob, Grep, Read only)
+- plan: Planner agent that produces multi-step strategies and implementation outlines. Default model: sonnet. (Tools: all)
+`
+
 const taskToolDescription = `Launch a new agent to handle complex, multi-step tasks autonomously. 
 
 The Task tool launches specialized agents (subprocesses) that autonomously handle complex tasks. Each agent type has specific capabilities and tools available to it.
 
 Available agent types and the tools they have access to:
-- general-purpose: Full-access agent for multi-step research, coding, and remediation. Default model: sonnet. (Tools: all)
-- explore: Fast read-only explorer for globbing, grep, and focused file reads when you need quick answers. Default model: haiku. (Tools: Glob, Grep, Read only)
-- plan: Planner agent that produces multi-step strategies and implementation outlines. Default model: sonnet. (Tools: all)
-
+` + taskBuiltinAgentList + `
 
 When using the Task tool, you must specify a subagent_type parameter to select which agent type to use.
 
@@ -156,6 +159,11 @@ type TaskRequest struct {
 type TaskTool struct {
 	mu     sync.RWMutex
 	runner TaskRunner
+
+	// Registered subagents offered alongside the builtin types.
+	description string
+	schema      *tool.JSONSchema
+	allowed     map[string]struct{}
 }
 
 // NewTaskTool constructs an instance with no runner attached.
@@ -165,9 +173,73 @@ func NewTaskTool() *TaskTool {
 
 func (t *TaskTool) Name() string { return "Task" }
 
-func (t *TaskTool) Description() string { return taskToolDescription }
+func (t *TaskTool) Description() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.description != "" {
+		return t.description
+	}
+	return taskToolDescription
+}
+
+func (t *TaskTool) Schema() *tool.JSONSchema {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.schema != nil {
+		return t.schema
+	}
+	return taskSchema
+}
+
+// SetSubagents advertises registered subagents to the model: their names
+// are accepted as subagent_type and listed with their descriptions and
+// tools next to the builtin types.
+func (t *TaskTool) SetSubagents(defs []subagents.Definition) {
+	allowed := maps.Clone(supportedTaskSubagentSet)
+	names := append([]string(nil), supportedTaskSubagents...)
+	var extra strings.Builder
+	for _, def := range defs {
+		name := strings.ToLower(strings.TrimSpace(def.Name))
+		if name == "" {
+			continue
+		}
+		if _, ok := allowed[name]; ok {
+			continue
+		}
+		allowed[name] = struct{}{}
+		names = append(names, name)
+		tools := "all"
+		if list := def.BaseContext.ToolList(); len(list) > 0 {
+			tools = strings.Join(list, ", ")
+		}
+		desc := strings.TrimSpace(def.Description)
+		if desc == "" {
+			desc = "Custom agent."
+		}
+		fmt.Fprintf(&extra, "- %s: %s (Tools: %s)\n", name, desc, tools)
+	}
+
+	schema := *taskSchema
+	schema.Properties = maps.Clone(taskSchema.Properties)
+	subagentType := maps.Clone(taskSchema.Properties["subagent_type"].(map[string]interface{}))
+	subagentType["enum"] = names
+	schema.Properties["subagent_type"] = subagentType
 
-func (t *TaskTool) Schema() *tool.JSONSchema { return taskSchema }
+	t.mu.Lock()
+	t.allowed = allowed
+	t.schema = &schema
+	t.description = strings.Replace(taskToolDescription, taskBuiltinAgentList, taskBuiltinAgentList+extra.String(), 1)
+	t.mu.Unlock()
+}
+
+func (t *TaskTool) allowedSubagents() map[string]struct{} {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.allowed != nil {
+		return t.allowed
+	}
+	return supportedTaskSubagentSet
+}
 
 // SetRunner wires the runtime callback that executes task invocations.
 func (t *TaskTool) SetRunner(runner TaskRunner) {
@@ -190,7 +262,7 @@ func (t *TaskTool) Execute(ctx context.Context, params map[string]interface{}) (
 	if runner == nil {
 		return nil, errors.New("task runner is not configured")
 	}
-	payload, err := parseTaskParams(params)
+	payload, err := parseTaskParamsFor(params, t.allowedSubagents())
 	if err != nil {
 		return nil, err
 	}
@@ -201,6 +273,11 @@ func (t *TaskTool) Execute(ctx context.Context, params map[string]interface{}) (
 }
 
 func parseTaskParams(params map[string]interface{}) (TaskRequest, error) {
+	return parseTaskParamsFor(params, supportedTaskSubagentSet)
+}
+
+// parseTaskParamsFor validates params, accepting the subagent types in allowed.
+func parseTaskParamsFor(params map[string]interface{}, allowed map[string]struct{}) (TaskRequest, error) {
 	if params == nil {
 		return TaskRequest{}, errors.New("params is nil")
 	}
@@ -223,7 +300,7 @@ func parseTaskParams(params map[string]interface{}) (TaskRequest, error) {
 		return TaskRequest{}, err
 	}
 	subagentType = strings.ToLower(subagentType)
-	if _, ok := supportedTaskSubagentSet[subagentType]; !ok {
+	if _, ok := allowed[subagentType]; !ok {
 		return TaskRequest{}, fmt.Errorf("unknown subagent_type %q", subagentType)
 	}
 
@@ -13,7 +13,8 @@ import (
 
 const taskCreateDescription = `Create a new task in the task store.
 
-Use this when you want to persist a task with a required subject and activeForm (plus an optional description).`
+Use this when you want to persist a task with a required subject and activeForm (plus an optional description
+and the artifacts it produces).`
 
 var taskCreateSchema = &tool.JSONSchema{
 	Type: "object",
@@ -32,6 +33,13 @@ var taskCreateSchema = &tool.JSONSchema{
 			"description": "Identifier for the active form associated with this task.",
 			"minLength":   1,
 		},
+		"artifacts": map[string]interface{}{
+			"type":        "array",
+			"description": "Optional files, branches or URLs produced by the task.",
+			"items": map[string]interface{}{
+				"type": "string",
+			},
+		},
 	},
 	Required: []string{"subject", "activeForm"},
 }
@@ -61,6 +69,10 @@ func (t *TaskCreateTool) Execute(ctx context.Context, params map[string]interfac
 	if err != nil {
 		return nil, err
 	}
+	artifacts, err := parseTaskArtifacts(params["artifacts"])
+	if err != nil {
+		return nil, err
+	}
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
@@ -72,6 +84,15 @@ func (t *TaskCreateTool) Execute(ctx context.Context, params map[string]interfac
 	if created != nil {
 		taskID = created.ID
 	}
+	if session := sessionIDFromContext(ctx); taskID != "" && (session != "" || len(artifacts) > 0) {
+		update := tasks.TaskUpdate{AddArtifacts: artifacts}
+		if session != "" {
+			update.Session = &session
+		}
+		if _, err := t.store.Update(taskID, update); err != nil {
+			return nil, err
+		}
+	}
 	payload := map[string]interface{}{
 		"taskId": taskID,
 	}
@@ -116,3 +137,25 @@ func optionalTrimmedString(params map[string]interface{}, key string) (string, e
 	}
 	return strings.TrimSpace(value), nil
 }
+
+// parseTaskArtifacts reads an optional list of artifact references.
+func parseTaskArtifacts(raw interface{}) ([]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	items, err := coerceInterfaceArray(raw, "artifacts")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(items))
+	for i, item := range items {
+		value, err := coerceString(item)
+		if err != nil {
+			return nil, fmt.Errorf("artifacts[%d] must be string: %w", i, err)
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			out = append(out, value)
+		}
+	}
+	return out, nil
+}
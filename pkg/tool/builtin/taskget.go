@@ -33,6 +33,9 @@ func NewTaskGetTool(store *tasks.TaskStore) *TaskGetTool {
 
 func (t *TaskGetTool) Name() string { return "TaskGet" }
 
+// Idempotent allows automatic retries: it only reads the task.
+func (t *TaskGetTool) Idempotent() bool { return true }
+
 func (t *TaskGetTool) Description() string { return taskGetDescription }
 
 func (t *TaskGetTool) Schema() *tool.JSONSchema { return taskGetSchema }
@@ -108,6 +111,7 @@ func formatTaskGetOutput(task tasks.Task, blockedBy []TaskRef, blocks []TaskRef)
 	if strings.TrimSpace(task.Owner) != "" {
 		fmt.Fprintf(&b, "owner: %s\n", strings.TrimSpace(task.Owner))
 	}
+	writeTaskLedgerFields(&b, task)
 	if len(blockedBy) == 0 {
 		b.WriteString("blockedBy: (none)\n")
 	} else {
@@ -43,6 +43,9 @@ func NewTaskListTool(store *tasks.TaskStore) *TaskListTool {
 
 func (t *TaskListTool) Name() string { return "TaskList" }
 
+// Idempotent allows automatic retries: it only reads the task list.
+func (t *TaskListTool) Idempotent() bool { return true }
+
 func (t *TaskListTool) Description() string { return taskListDescription }
 
 func (t *TaskListTool) Schema() *tool.JSONSchema { return taskListSchema }
@@ -306,6 +309,9 @@ func formatTaskListOutput(taskList []tasks.Task, order []string, depth map[strin
 		if strings.TrimSpace(task.Owner) != "" {
 			fmt.Fprintf(&b, " (owner=%s)", strings.TrimSpace(task.Owner))
 		}
+		if strings.TrimSpace(task.Session) != "" {
+			fmt.Fprintf(&b, " (session=%s)", strings.TrimSpace(task.Session))
+		}
 		b.WriteByte('\n')
 		if len(task.BlockedBy) > 0 {
 			fmt.Fprintf(&b, "%s  blockedBy: %s\n", prefix, strings.Join(task.BlockedBy, ", "))
@@ -13,7 +13,7 @@ import (
 	"github.com/cexll/agentsdk-go/pkg/tool"
 )
 
-const taskUpdateDescription = "Update a task's status, owner, and dependencies. Use delete=true to delete tasks."
+const taskUpdateDescription = "Update a task's status, owner, artifacts, and dependencies. Setting status to in_progress claims the task for the current session. Use delete=true to delete tasks."
 
 var taskUpdateSchema = &tool.JSONSchema{
 	Type: "object",
@@ -39,6 +39,13 @@ var taskUpdateSchema = &tool.JSONSchema{
 			"type":        "string",
 			"description": "Optional task owner. Pass empty string to clear.",
 		},
+		"artifacts": map[string]interface{}{
+			"type":        "array",
+			"description": "Files, branches or URLs produced by the task; appended to the existing list.",
+			"items": map[string]interface{}{
+				"type": "string",
+			},
+		},
 		"blocks": map[string]interface{}{
 			"type":        "array",
 			"description": "Replace the list of tasks blocked by this task.",
@@ -147,8 +154,14 @@ func (t *TaskUpdateTool) Execute(ctx context.Context, params map[string]interfac
 	if req.Status != nil {
 		status := *req.Status
 		updates.Status = &status
+		if status == tasks.TaskInProgress {
+			if session := sessionIDFromContext(ctx); session != "" {
+				updates.Session = &session
+			}
+		}
 	}
-	if updates.Owner != nil || updates.Status != nil {
+	updates.AddArtifacts = req.Artifacts
+	if updates.Owner != nil || updates.Status != nil || len(updates.AddArtifacts) > 0 {
 		if _, err := t.store.Update(req.TaskID, updates); err != nil {
 			return nil, err
 		}
@@ -204,6 +217,7 @@ type taskUpdateRequest struct {
 	Delete       bool
 	Status       *tasks.TaskStatus
 	Owner        *string
+	Artifacts    []string
 	Blocks       []string
 	HasBlocks    bool
 	BlockedBy    []string
@@ -252,6 +266,14 @@ func parseTaskUpdateParams(params map[string]interface{}) (taskUpdateRequest, er
 		req.Owner = &owner
 	}
 
+	if raw, ok := params["artifacts"]; ok {
+		artifacts, err := parseTaskArtifacts(raw)
+		if err != nil {
+			return taskUpdateRequest{}, err
+		}
+		req.Artifacts = artifacts
+	}
+
 	if raw, ok := params["blocks"]; ok {
 		req.HasBlocks = true
 		list, err := parseTaskIDList(raw, "blocks", taskID)
@@ -479,6 +501,7 @@ func formatTaskUpdateOutput(task tasks.Task, unblocked []string, revision uint64
 	if strings.TrimSpace(task.Owner) != "" {
 		fmt.Fprintf(&b, "owner: %s\n", strings.TrimSpace(task.Owner))
 	}
+	writeTaskLedgerFields(&b, task)
 
 	blockedBy := append([]string(nil), task.BlockedBy...)
 	sort.Strings(blockedBy)
@@ -503,3 +526,12 @@ func formatTaskUpdateOutput(task tasks.Task, unblocked []string, revision uint64
 	return b.String()
 }
 
+// writeTaskLedgerFields renders the session and artifacts of a task, when set.
+func writeTaskLedgerFields(b *strings.Builder, task tasks.Task) {
+	if strings.TrimSpace(task.Session) != "" {
+		fmt.Fprintf(b, "session: %s\n", strings.TrimSpace(task.Session))
+	}
+	if len(task.Artifacts) > 0 {
+		fmt.Fprintf(b, "artifacts: %s\n", strings.Join(task.Artifacts, ", "))
+	}
+}
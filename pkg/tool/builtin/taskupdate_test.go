@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/cexll/agentsdk-go/pkg/middleware"
 	"github.com/cexll/agentsdk-go/pkg/runtime/tasks"
 )
 
@@ -235,3 +236,43 @@ func TestTaskUpdateToolExecuteNilContext(t *testing.T) {
 		t.Fatalf("expected nil context error")
 	}
 }
+
+func TestTaskUpdateToolClaimsAndRecordsArtifacts(t *testing.T) {
+	t.Parallel()
+
+	store := tasks.NewTaskStore()
+	ctx := context.WithValue(context.Background(), middleware.SessionIDContextKey, "sess-2")
+	created, err := NewTaskCreateTool(store).Execute(ctx, map[string]interface{}{
+		"subject":    "migrate",
+		"activeForm": "migrating",
+		"artifacts":  []interface{}{"docs/plan.md"},
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	id := created.Data.(map[string]interface{})["taskId"].(string)
+
+	tool := NewTaskUpdateTool(store)
+	claimCtx := context.WithValue(context.Background(), middleware.SessionIDContextKey, "sess-3")
+	res, err := tool.Execute(claimCtx, map[string]interface{}{
+		"taskId":    id,
+		"status":    "in_progress",
+		"artifacts": []interface{}{"branch:migrate", "docs/plan.md"},
+	})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	snap, _ := tool.Snapshot(id)
+	if snap.Session != "sess-3" {
+		t.Fatalf("expected claim by sess-3, got %q", snap.Session)
+	}
+	if strings.Join(snap.Artifacts, ",") != "docs/plan.md,branch:migrate" {
+		t.Fatalf("unexpected artifacts %v", snap.Artifacts)
+	}
+	if !strings.Contains(res.Output, "session: sess-3") || !strings.Contains(res.Output, "artifacts: docs/plan.md, branch:migrate") {
+		t.Fatalf("ledger fields missing from output:\n%s", res.Output)
+	}
+	if _, err := tool.Execute(claimCtx, map[string]interface{}{"taskId": id, "artifacts": "x"}); err == nil {
+		t.Fatal("expected artifacts type error")
+	}
+}
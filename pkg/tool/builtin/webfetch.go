@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	xhtml "golang.org/x/net/html"
@@ -68,13 +69,21 @@ var webFetchSchema = &tool.JSONSchema{
 
 // WebFetchOptions configures WebFetchTool behaviour.
 type WebFetchOptions struct {
-	HTTPClient        *http.Client
-	Timeout           time.Duration
-	CacheTTL          time.Duration
-	MaxContentSize    int64
-	AllowedHosts      []string
-	BlockedHosts      []string
+	HTTPClient     *http.Client
+	Timeout        time.Duration
+	CacheTTL       time.Duration
+	MaxContentSize int64
+	AllowedHosts   []string
+	BlockedHosts   []string
+	// AllowPrivateHosts disables SSRF protection entirely. Prefer
+	// PrivateHostAllowlist.
 	AllowPrivateHosts bool
+	// PrivateHostAllowlist names the internal hosts, IPs and CIDRs that may
+	// be fetched while loopback, private, link-local and other internal
+	// addresses stay blocked for everything else. Resolved addresses are
+	// checked at dial time, so DNS names and redirects cannot reach
+	// internal hosts indirectly.
+	PrivateHostAllowlist []string
 }
 
 // WebFetchTool fetches remote web pages and returns Markdown content.
@@ -109,15 +118,19 @@ func NewWebFetchTool(opts *WebFetchOptions) *WebFetchTool {
 		cacheTTL = defaultFetchCacheTTL
 	}
 
+	validator := newHostValidator(cfg.AllowedHosts, cfg.BlockedHosts, cfg.AllowPrivateHosts).withPrivateAllowlist(cfg.PrivateHostAllowlist)
 	client := cloneHTTPClient(cfg.HTTPClient)
 	client.Timeout = timeout
+	if !cfg.AllowPrivateHosts {
+		client.Transport = validator.guardTransport(client.Transport)
+	}
 
 	tool := &WebFetchTool{
 		client:    client,
 		timeout:   timeout,
 		maxBytes:  maxBytes,
 		cache:     newFetchCache(cacheTTL),
-		validator: newHostValidator(cfg.AllowedHosts, cfg.BlockedHosts, cfg.AllowPrivateHosts),
+		validator: validator,
 		now:       time.Now,
 	}
 	tool.client.CheckRedirect = tool.redirectPolicy()
@@ -126,6 +139,9 @@ func NewWebFetchTool(opts *WebFetchOptions) *WebFetchTool {
 
 func (w *WebFetchTool) Name() string { return "WebFetch" }
 
+// Idempotent allows automatic retries: fetches are GET requests.
+func (w *WebFetchTool) Idempotent() bool { return true }
+
 func (w *WebFetchTool) Description() string { return webFetchDescription }
 
 func (w *WebFetchTool) Schema() *tool.JSONSchema { return webFetchSchema }
@@ -352,7 +368,7 @@ func readBounded(r io.Reader, limit int64) ([]byte, error) {
 
 func cloneHTTPClient(c *http.Client) *http.Client {
 	if c == nil {
-		return &http.Client{}
+		return &http.Client{Transport: http.DefaultTransport}
 	}
 	clone := *c
 	if clone.Transport == nil {
@@ -441,6 +457,10 @@ type hostValidator struct {
 	allowed      map[string]struct{}
 	blocked      map[string]struct{}
 	allowPrivate bool
+	// privateHosts and privateNets are internal destinations reachable even
+	// though allowPrivate is false.
+	privateHosts map[string]struct{}
+	privateNets  []*net.IPNet
 }
 
 var defaultBlockedHosts = map[string]struct{}{
@@ -491,14 +511,12 @@ func (h hostValidator) Validate(host string) error {
 			return fmt.Errorf("host %s is not in whitelist", hostname)
 		}
 	}
-	if !h.allowPrivate {
+	if !h.allowPrivate && !h.privateAllowed(hostname) {
 		if _, ok := defaultBlockedHosts[hostname]; ok {
 			return fmt.Errorf("host %s is blocked", hostname)
 		}
-		if ip := net.ParseIP(hostname); ip != nil {
-			if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
-				return fmt.Errorf("ip %s is not reachable", hostname)
-			}
+		if ip := net.ParseIP(hostname); ip != nil && isInternalIP(ip) {
+			return fmt.Errorf("ip %s is not reachable", hostname)
 		}
 	}
 	for block := range h.blocked {
@@ -509,6 +527,144 @@ func (h hostValidator) Validate(host string) error {
 	return nil
 }
 
+// withPrivateAllowlist returns h with entries (hostnames, IPs or CIDRs)
+// exempted from the internal-address checks.
+func (h hostValidator) withPrivateAllowlist(entries []string) hostValidator {
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+				h.privateNets = append(h.privateNets, ipNet)
+			}
+		default:
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 8 * len(ip.To16())
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				h.privateNets = append(h.privateNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+			if h.privateHosts == nil {
+				h.privateHosts = map[string]struct{}{}
+			}
+			h.privateHosts[entry] = struct{}{}
+		}
+	}
+	return h
+}
+
+// privateAllowed reports whether hostname, a name or an IP literal, is on
+// the private allowlist.
+func (h hostValidator) privateAllowed(hostname string) bool {
+	if ip := net.ParseIP(hostname); ip != nil {
+		return h.privateIPAllowed(ip)
+	}
+	for allow := range h.privateHosts {
+		if domainMatches(hostname, allow) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h hostValidator) privateIPAllowed(ip net.IP) bool {
+	for _, ipNet := range h.privateNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// internalNets are the ranges beyond net.IP's classifiers that must not be
+// reachable from fetched URLs: "this network", carrier-grade NAT, IETF
+// protocol assignments, benchmarking and NAT64.
+var internalNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "64:ff9b::/96"} {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}()
+
+// isInternalIP reports whether ip addresses the local machine, a private
+// network or another non-public destination.
+func isInternalIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, ipNet := range internalNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// guardTransport makes rt refuse connections to internal addresses after
+// DNS resolution, which also covers redirects and DNS rebinding. Proxies
+// chosen by the transport stay reachable. A nil rt guards a clone of
+// http.DefaultTransport; other transports cannot be guarded at dial time and
+// fail every request, so callers with custom transports must set
+// AllowPrivateHosts.
+func (h hostValidator) guardTransport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	base, ok := rt.(*http.Transport)
+	if !ok {
+		return unguardedTransport{rt: rt}
+	}
+	transport := base.Clone()
+	var proxies sync.Map
+	if proxy := transport.Proxy; proxy != nil {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			u, err := proxy(req)
+			if u != nil {
+				proxies.Store(strings.ToLower(u.Hostname()), struct{}{})
+			}
+			return u, err
+		}
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host := strings.ToLower(hostWithoutPort(addr))
+		if _, proxied := proxies.Load(host); proxied || h.privateAllowed(host) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		guarded := *dialer
+		guarded.Control = func(_, address string, _ syscall.RawConn) error {
+			ip := net.ParseIP(hostWithoutPort(address))
+			if ip != nil && isInternalIP(ip) && !h.privateIPAllowed(ip) {
+				return fmt.Errorf("host %s resolves to internal address %s", host, ip)
+			}
+			return nil
+		}
+		return guarded.DialContext(ctx, network, addr)
+	}
+	return transport
+}
+
+// unguardedTransport fails closed for transports guardTransport cannot wrap.
+type unguardedTransport struct {
+	rt http.RoundTripper
+}
+
+func (u unguardedTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("transport %T cannot be guarded against internal addresses; use *http.Transport or set AllowPrivateHosts", u.rt)
+}
+
 func domainMatches(host, domain string) bool {
 	host = strings.ToLower(strings.TrimSpace(host))
 	domain = strings.ToLower(strings.TrimSpace(domain))
@@ -3,6 +3,7 @@ package toolbuiltin
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -203,3 +204,93 @@ func TestWebFetchHelperBranches(t *testing.T) {
 		t.Fatalf("expected stringValue error")
 	}
 }
+
+func TestWebFetchPrivateHostAllowlist(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hop" {
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("<p>internal</p>"))
+	}))
+	defer server.Close()
+	params := map[string]interface{}{"url": server.URL + "/hop", "prompt": "read"}
+
+	blocked := NewWebFetchTool(&WebFetchOptions{HTTPClient: server.Client()})
+	if _, err := blocked.Execute(context.Background(), params); err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Fatalf("expected loopback to be blocked, got %v", err)
+	}
+
+	allowed := NewWebFetchTool(&WebFetchOptions{HTTPClient: server.Client(), PrivateHostAllowlist: []string{"127.0.0.0/8"}})
+	res, err := allowed.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatalf("allowlisted fetch failed: %v", err)
+	}
+	if data := res.Data.(map[string]interface{}); data["content_markdown"] != "internal" || !strings.HasSuffix(data["url"].(string), "/page") {
+		t.Fatalf("unexpected data %#v", data)
+	}
+}
+
+func TestWebFetchGuardBlocksResolvedInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+	_, port, _ := strings.Cut(server.Listener.Addr().String(), ":")
+
+	transport := newHostValidator(nil, nil, false).guardTransport(http.DefaultTransport).(*http.Transport)
+	// Any name resolving to loopback is refused when dialing, whatever
+	// the URL checks concluded.
+	if _, err := transport.DialContext(context.Background(), "tcp", "localhost:"+port); err == nil || !strings.Contains(err.Error(), "internal address") {
+		t.Fatalf("expected dial to loopback to be refused, got %v", err)
+	}
+
+	byName := newHostValidator(nil, nil, false).withPrivateAllowlist([]string{"localhost"}).guardTransport(http.DefaultTransport).(*http.Transport)
+	conn, err := byName.DialContext(context.Background(), "tcp", "localhost:"+port)
+	if err != nil {
+		t.Fatalf("allowlisted host dial failed: %v", err)
+	}
+	_ = conn.Close()
+
+	custom := (hostValidator{}).guardTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+		t.Fatal("unguarded transport was used")
+		return nil, nil
+	}))
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	if _, err := custom.RoundTrip(req); err == nil || !strings.Contains(err.Error(), "cannot be guarded") {
+		t.Fatalf("expected custom transport to fail closed, got %v", err)
+	}
+}
+
+func TestNewWebFetchToolGuardsDefaultTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+	_, port, _ := strings.Cut(server.Listener.Addr().String(), ":")
+
+	// nil options, and the options the runtime's web_fetch factory builds.
+	for _, opts := range []*WebFetchOptions{nil, {PrivateHostAllowlist: []string{"10.0.0.0/8"}}} {
+		tool := NewWebFetchTool(opts)
+		transport, ok := tool.client.Transport.(*http.Transport)
+		if !ok || transport == http.DefaultTransport || transport.DialContext == nil {
+			t.Fatalf("default client is not guarded: %T", tool.client.Transport)
+		}
+		if _, err := transport.DialContext(context.Background(), "tcp", "localhost:"+port); err == nil || !strings.Contains(err.Error(), "internal address") {
+			t.Fatalf("expected dial to loopback to be refused, got %v", err)
+		}
+	}
+}
+
+func TestIsInternalIP(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fd00::1", "fe80::1", "::ffff:127.0.0.1", "224.0.0.1"} {
+		if !isInternalIP(net.ParseIP(ip)) {
+			t.Errorf("%s should be internal", ip)
+		}
+	}
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		if isInternalIP(net.ParseIP(ip)) {
+			t.Errorf("%s should be public", ip)
+		}
+	}
+	v := newHostValidator(nil, nil, false).withPrivateAllowlist([]string{"10.0.0.5", "wiki.corp", "bad/cidr", ""})
+	if v.Validate("10.0.0.5") != nil || v.Validate("docs.wiki.corp") != nil || v.Validate("10.0.0.6") == nil {
+		t.Fatal("unexpected allowlist behaviour")
+	}
+}
@@ -1,11 +1,9 @@
 package toolbuiltin
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -72,11 +70,14 @@ type WebSearchOptions struct {
 	HTTPClient *http.Client
 	Timeout    time.Duration
 	MaxResults int
+	// Backend runs the searches; nil scrapes DuckDuckGo with HTTPClient.
+	Backend SearchBackend
 }
 
-// WebSearchTool proxies search queries to an HTTP endpoint and filters domains.
+// WebSearchTool proxies search queries to a SearchBackend and filters domains.
 type WebSearchTool struct {
 	client     *http.Client
+	backend    SearchBackend
 	timeout    time.Duration
 	maxResults int
 }
@@ -99,9 +100,14 @@ func NewWebSearchTool(opts *WebSearchOptions) *WebSearchTool {
 	}
 	client := cloneHTTPClient(cfg.HTTPClient)
 	client.Timeout = timeout
+	backend := cfg.Backend
+	if backend == nil {
+		backend = &DuckDuckGoBackend{Client: client}
+	}
 
 	return &WebSearchTool{
 		client:     client,
+		backend:    backend,
 		timeout:    timeout,
 		maxResults: maxResults,
 	}
@@ -109,6 +115,9 @@ func NewWebSearchTool(opts *WebSearchOptions) *WebSearchTool {
 
 func (w *WebSearchTool) Name() string { return "WebSearch" }
 
+// Idempotent allows automatic retries: searches have no side effects.
+func (w *WebSearchTool) Idempotent() bool { return true }
+
 func (w *WebSearchTool) Description() string { return webSearchDescription }
 
 func (w *WebSearchTool) Schema() *tool.JSONSchema { return webSearchSchema }
@@ -118,7 +127,7 @@ func (w *WebSearchTool) Execute(ctx context.Context, params map[string]interface
 	if ctx == nil {
 		return nil, errors.New("context is nil")
 	}
-	if w == nil || w.client == nil {
+	if w == nil || w.backend == nil {
 		return nil, errors.New("web search tool is not initialised")
 	}
 	if params == nil {
@@ -149,7 +158,7 @@ func (w *WebSearchTool) Execute(ctx context.Context, params map[string]interface
 		defer cancel()
 	}
 
-	results, err := w.search(reqCtx, query)
+	results, err := w.search(reqCtx, SearchRequest{Query: query, MaxResults: w.maxResults, AllowedDomains: allowed, BlockedDomains: blocked})
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +171,7 @@ func (w *WebSearchTool) Execute(ctx context.Context, params map[string]interface
 		"results":         filtered,
 		"allowed_domains": allowed,
 		"blocked_domains": blocked,
+		"backend":         w.backend.Name(),
 	}
 
 	return &tool.ToolResult{
@@ -171,45 +181,8 @@ func (w *WebSearchTool) Execute(ctx context.Context, params map[string]interface
 	}, nil
 }
 
-func (w *WebSearchTool) search(ctx context.Context, query string) ([]SearchResult, error) {
-	endpoint := strings.TrimSpace(duckDuckGoEndpoint)
-	if endpoint == "" {
-		return nil, errors.New("duckduckgo endpoint is not configured")
-	}
-	form := url.Values{}
-	form.Set("q", query)
-	form.Set("kl", "us-en")
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
-	if err != nil {
-		return nil, fmt.Errorf("build request: %w", err)
-	}
-	req.Header.Set("User-Agent", defaultSearchUserAgent)
-	req.Header.Set("Content-Type", duckDuckGoFormContentType)
-
-	resp, err := w.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("search request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("search failed with status %d", resp.StatusCode)
-	}
-
-	reader := io.LimitReader(resp.Body, int64(maxSearchResponseBytes)+1)
-	body, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, fmt.Errorf("read search response: %w", err)
-	}
-	if len(body) > maxSearchResponseBytes {
-		return nil, fmt.Errorf("search response exceeded %d bytes", maxSearchResponseBytes)
-	}
-
-	doc, err := xhtml.Parse(bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("parse search HTML: %w", err)
-	}
-	return extractDuckDuckGoResults(doc), nil
+func (w *WebSearchTool) search(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	return w.backend.Search(ctx, req)
 }
 
 // SearchResult describes a single search hit.
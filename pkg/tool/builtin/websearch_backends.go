@@ -0,0 +1,283 @@
+package toolbuiltin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Search providers understood by NewSearchBackend.
+const (
+	SearchProviderDuckDuckGo = "duckduckgo"
+	SearchProviderBrave      = "brave"
+	SearchProviderTavily     = "tavily"
+)
+
+const (
+	braveDefaultEndpoint  = "https://api.search.brave.com/res/v1/web/search"
+	tavilyDefaultEndpoint = "https://api.tavily.com/search"
+	// maxBackendResults caps how many hits a backend is asked for; domain
+	// filtering happens afterwards, so backends are asked for more than
+	// the tool returns when filters are set.
+	maxBackendResults = 20
+)
+
+// SearchRequest is one query handed to a SearchBackend. Backends that can
+// filter by domain server-side may use the domain lists; WebSearchTool
+// filters the results again either way.
+type SearchRequest struct {
+	Query          string
+	MaxResults     int
+	AllowedDomains []string
+	BlockedDomains []string
+}
+
+// SearchBackend runs web searches for WebSearchTool.
+type SearchBackend interface {
+	Name() string
+	Search(ctx context.Context, req SearchRequest) ([]SearchResult, error)
+}
+
+// SearchBackendConfig selects and configures a backend.
+type SearchBackendConfig struct {
+	Provider   string // duckduckgo (default), brave or tavily.
+	APIKey     string // Required by brave and tavily.
+	Endpoint   string // Overrides the provider's API URL.
+	HTTPClient *http.Client
+}
+
+// NewSearchBackend builds the backend named by cfg.Provider.
+func NewSearchBackend(cfg SearchBackendConfig) (SearchBackend, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	key := strings.TrimSpace(cfg.APIKey)
+	switch provider {
+	case "", SearchProviderDuckDuckGo:
+		return &DuckDuckGoBackend{Endpoint: cfg.Endpoint, Client: cfg.HTTPClient}, nil
+	case SearchProviderBrave:
+		if key == "" {
+			return nil, errors.New("brave search requires an API key")
+		}
+		return &BraveBackend{APIKey: key, Endpoint: cfg.Endpoint, Client: cfg.HTTPClient}, nil
+	case SearchProviderTavily:
+		if key == "" {
+			return nil, errors.New("tavily search requires an API key")
+		}
+		return &TavilyBackend{APIKey: key, Endpoint: cfg.Endpoint, Client: cfg.HTTPClient}, nil
+	default:
+		return nil, fmt.Errorf("unknown search provider %q", cfg.Provider)
+	}
+}
+
+// DuckDuckGoBackend scrapes the DuckDuckGo HTML endpoint. It needs no API
+// key and is the default backend.
+type DuckDuckGoBackend struct {
+	Endpoint string
+	Client   *http.Client
+}
+
+// Name implements SearchBackend.
+func (*DuckDuckGoBackend) Name() string { return SearchProviderDuckDuckGo }
+
+// Search implements SearchBackend.
+func (b *DuckDuckGoBackend) Search(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	endpoint := strings.TrimSpace(b.Endpoint)
+	if endpoint == "" {
+		endpoint = strings.TrimSpace(duckDuckGoEndpoint)
+	}
+	if endpoint == "" {
+		return nil, errors.New("duckduckgo endpoint is not configured")
+	}
+	form := url.Values{}
+	form.Set("q", req.Query)
+	form.Set("kl", "us-en")
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	httpReq.Header.Set("User-Agent", defaultSearchUserAgent)
+	httpReq.Header.Set("Content-Type", duckDuckGoFormContentType)
+
+	body, err := doSearchRequest(b.Client, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := xhtml.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse search HTML: %w", err)
+	}
+	return extractDuckDuckGoResults(doc), nil
+}
+
+// BraveBackend queries the Brave Search web API.
+type BraveBackend struct {
+	APIKey   string
+	Endpoint string
+	Client   *http.Client
+}
+
+// Name implements SearchBackend.
+func (*BraveBackend) Name() string { return SearchProviderBrave }
+
+// Search implements SearchBackend.
+func (b *BraveBackend) Search(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	endpoint := strings.TrimSpace(b.Endpoint)
+	if endpoint == "" {
+		endpoint = braveDefaultEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("brave endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("q", req.Query)
+	q.Set("count", strconv.Itoa(backendLimit(req)))
+	u.RawQuery = q.Encode()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("X-Subscription-Token", b.APIKey)
+
+	body, err := doSearchRequest(b.Client, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	var payload struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("decode brave response: %w", err)
+	}
+	results := make([]SearchResult, 0, len(payload.Web.Results))
+	for _, r := range payload.Web.Results {
+		results = append(results, SearchResult{Title: stripTags(r.Title), URL: cleanResultURL(r.URL), Snippet: stripTags(r.Description)})
+	}
+	return deduplicateResults(results), nil
+}
+
+// TavilyBackend queries the Tavily search API, passing domain filters
+// through to the service.
+type TavilyBackend struct {
+	APIKey   string
+	Endpoint string
+	Client   *http.Client
+}
+
+// Name implements SearchBackend.
+func (*TavilyBackend) Name() string { return SearchProviderTavily }
+
+// Search implements SearchBackend.
+func (b *TavilyBackend) Search(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	endpoint := strings.TrimSpace(b.Endpoint)
+	if endpoint == "" {
+		endpoint = tavilyDefaultEndpoint
+	}
+	payload, err := json.Marshal(map[string]any{
+		"query":           req.Query,
+		"max_results":     backendLimit(req),
+		"include_domains": nonNilStrings(req.AllowedDomains),
+		"exclude_domains": nonNilStrings(req.BlockedDomains),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode tavily request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+b.APIKey)
+
+	body, err := doSearchRequest(b.Client, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decode tavily response: %w", err)
+	}
+	results := make([]SearchResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		results = append(results, SearchResult{Title: collapseWhitespace(r.Title), URL: cleanResultURL(r.URL), Snippet: collapseWhitespace(r.Content)})
+	}
+	return deduplicateResults(results), nil
+}
+
+// doSearchRequest sends req and returns the body, rejecting error statuses
+// and responses over maxSearchResponseBytes.
+func doSearchRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("search request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("search failed with status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSearchResponseBytes)+1))
+	if err != nil {
+		return nil, fmt.Errorf("read search response: %w", err)
+	}
+	if len(body) > maxSearchResponseBytes {
+		return nil, fmt.Errorf("search response exceeded %d bytes", maxSearchResponseBytes)
+	}
+	return body, nil
+}
+
+// backendLimit asks for extra hits when domain filters may drop some.
+func backendLimit(req SearchRequest) int {
+	limit := req.MaxResults
+	if limit <= 0 {
+		limit = defaultSearchMaxResults
+	}
+	if len(req.AllowedDomains) > 0 || len(req.BlockedDomains) > 0 {
+		limit *= 2
+	}
+	if limit > maxBackendResults {
+		limit = maxBackendResults
+	}
+	return limit
+}
+
+// stripTags drops the <strong> highlighting Brave puts in titles and
+// descriptions.
+func stripTags(s string) string {
+	doc, err := xhtml.Parse(strings.NewReader(s))
+	if err != nil {
+		return collapseWhitespace(s)
+	}
+	return nodeText(doc)
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,108 @@
+package toolbuiltin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBraveBackendSearch(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Subscription-Token") != "brave-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("q") != "golang" || r.URL.Query().Get("count") != "16" {
+			http.Error(w, "bad query "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"web":{"results":[
+			{"title":"The <strong>Go</strong> Programming Language","url":"https://go.dev/","description":"Build <strong>simple</strong> software"},
+			{"title":"Blocked","url":"https://spam.example/","description":"x"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	backend, err := NewSearchBackend(SearchBackendConfig{Provider: "Brave", APIKey: "brave-key", Endpoint: server.URL, HTTPClient: server.Client()})
+	if err != nil {
+		t.Fatalf("backend: %v", err)
+	}
+	tool := NewWebSearchTool(&WebSearchOptions{Backend: backend})
+	res, err := tool.Execute(context.Background(), map[string]interface{}{
+		"query":           "golang",
+		"blocked_domains": []string{"spam.example"},
+	})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	data := res.Data.(map[string]interface{})
+	results := data["results"].([]SearchResult)
+	if len(results) != 1 || results[0].Title != "The Go Programming Language" || results[0].Snippet != "Build simple software" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if data["backend"] != SearchProviderBrave {
+		t.Fatalf("unexpected backend %v", data["backend"])
+	}
+
+	bad, _ := NewSearchBackend(SearchBackendConfig{Provider: "brave", APIKey: "wrong", Endpoint: server.URL})
+	if _, err := bad.Search(context.Background(), SearchRequest{Query: "golang"}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected status error, got %v", err)
+	}
+}
+
+func TestTavilyBackendSearch(t *testing.T) {
+	t.Parallel()
+
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tvly-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"results":[{"title":"Docs","url":"https://docs.example.com/a#frag","content":"  Some   content "}]}`))
+	}))
+	defer server.Close()
+
+	backend := &TavilyBackend{APIKey: "tvly-key", Endpoint: server.URL, Client: server.Client()}
+	results, err := backend.Search(context.Background(), SearchRequest{Query: "docs", MaxResults: 3, AllowedDomains: []string{"docs.example.com"}})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(results) != 1 || results[0].URL != "https://docs.example.com/a" || results[0].Snippet != "Some content" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if got["query"] != "docs" || got["max_results"] != float64(6) {
+		t.Fatalf("unexpected request %v", got)
+	}
+	if domains, _ := got["include_domains"].([]any); len(domains) != 1 || domains[0] != "docs.example.com" {
+		t.Fatalf("domains not passed through: %v", got)
+	}
+}
+
+func TestNewSearchBackend(t *testing.T) {
+	t.Parallel()
+
+	if b, err := NewSearchBackend(SearchBackendConfig{}); err != nil || b.Name() != SearchProviderDuckDuckGo {
+		t.Fatalf("expected duckduckgo default, got %v %v", b, err)
+	}
+	for _, provider := range []string{"brave", "tavily"} {
+		if _, err := NewSearchBackend(SearchBackendConfig{Provider: provider}); err == nil || !strings.Contains(err.Error(), "API key") {
+			t.Fatalf("%s: expected missing key error, got %v", provider, err)
+		}
+	}
+	if _, err := NewSearchBackend(SearchBackendConfig{Provider: "bing"}); err == nil {
+		t.Fatal("expected unknown provider error")
+	}
+	if got := backendLimit(SearchRequest{MaxResults: 15, BlockedDomains: []string{"x"}}); got != maxBackendResults {
+		t.Fatalf("expected limit capped at %d, got %d", maxBackendResults, got)
+	}
+}
@@ -109,7 +109,7 @@ func TestWebSearchSearchErrors(t *testing.T) {
 
 	duckDuckGoEndpoint = ""
 	tool := NewWebSearchTool(nil)
-	if _, err := tool.search(context.Background(), "q"); err == nil {
+	if _, err := tool.search(context.Background(), SearchRequest{Query: "q"}); err == nil {
 		t.Fatalf("expected empty endpoint error")
 	}
 
@@ -120,7 +120,7 @@ func TestWebSearchSearchErrors(t *testing.T) {
 
 	duckDuckGoEndpoint = server.URL
 	tool = NewWebSearchTool(&WebSearchOptions{HTTPClient: server.Client()})
-	if _, err := tool.search(context.Background(), "q"); err == nil || !strings.Contains(err.Error(), "status") {
+	if _, err := tool.search(context.Background(), SearchRequest{Query: "q"}); err == nil || !strings.Contains(err.Error(), "status") {
 		t.Fatalf("expected status error, got %v", err)
 	}
 
@@ -132,7 +132,7 @@ func TestWebSearchSearchErrors(t *testing.T) {
 	defer large.Close()
 	duckDuckGoEndpoint = large.URL
 	tool = NewWebSearchTool(&WebSearchOptions{HTTPClient: large.Client()})
-	if _, err := tool.search(context.Background(), "q"); err == nil || !strings.Contains(err.Error(), "exceeded") {
+	if _, err := tool.search(context.Background(), SearchRequest{Query: "q"}); err == nil || !strings.Contains(err.Error(), "exceeded") {
 		t.Fatalf("expected size error, got %v", err)
 	}
 
@@ -142,7 +142,7 @@ func TestWebSearchSearchErrors(t *testing.T) {
 		return nil, errors.New("network down")
 	})}
 	tool = NewWebSearchTool(&WebSearchOptions{HTTPClient: client})
-	if _, err := tool.search(context.Background(), "q"); err == nil || !strings.Contains(err.Error(), "search request") {
+	if _, err := tool.search(context.Background(), SearchRequest{Query: "q"}); err == nil || !strings.Contains(err.Error(), "search request") {
 		t.Fatalf("expected request error, got %v", err)
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 
 	"github.com/cexll/agentsdk-go/pkg/security"
 	"github.com/cexll/agentsdk-go/pkg/tool"
@@ -17,6 +18,7 @@ Usage:
 - ALWAYS prefer editing existing files in the codebase. NEVER write new files unless explicitly required.
 - NEVER proactively create documentation files (*.md) or README files. Only create documentation files if explicitly requested by the User.
 - Only use emojis if the user explicitly requests it. Avoid writing emojis to files unless asked.
+- Set 'dry_run' to preview the change as a unified diff without writing the file.
 `
 
 var writeSchema = &tool.JSONSchema{
@@ -30,6 +32,7 @@ var writeSchema = &tool.JSONSchema{
 			"type":        "string",
 			"description": "The content to write to the file",
 		},
+		"dry_run": dryRunProperty,
 	},
 	Required: []string{"file_path", "content"},
 }
@@ -56,6 +59,9 @@ func NewWriteToolWithSandbox(root string, sandbox *security.Sandbox) *WriteTool
 
 func (w *WriteTool) Name() string { return "Write" }
 
+// Idempotent allows automatic retries: rewriting the same content yields the same file.
+func (w *WriteTool) Idempotent() bool { return true }
+
 func (w *WriteTool) Description() string { return writeDescription }
 
 func (w *WriteTool) Schema() *tool.JSONSchema { return writeSchema }
@@ -75,10 +81,26 @@ func (w *WriteTool) Execute(ctx context.Context, params map[string]interface{})
 	if err != nil {
 		return nil, err
 	}
+	dryRun, err := parseDryRun(params)
+	if err != nil {
+		return nil, err
+	}
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
+	if dryRun {
+		var before string
+		if _, statErr := os.Stat(path); statErr == nil {
+			if before, err = w.base.readFile(path); err != nil {
+				return nil, err
+			}
+		}
+		return dryRunResult(displayPath(path, w.base.root), before, content, map[string]interface{}{
+			"path":  displayPath(path, w.base.root),
+			"bytes": len(content),
+		}), nil
+	}
 	if err := w.base.writeFile(path, content); err != nil {
 		return nil, err
 	}
@@ -101,7 +123,7 @@ func (w *WriteTool) resolveFilePath(params map[string]interface{}) (string, erro
 	if !ok {
 		return "", errors.New("file_path is required")
 	}
-	return w.base.resolvePath(raw)
+	return w.base.resolveWritePath(raw)
 }
 
 func (w *WriteTool) parseContent(params map[string]interface{}) (string, error) {
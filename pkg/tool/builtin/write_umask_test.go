@@ -33,3 +33,40 @@ func TestWriteToolRespectsUmask(t *testing.T) {
 		t.Fatalf("unexpected file permissions: got %o want %o", got, want)
 	}
 }
+
+func TestWriteAndEditPreservePermissions(t *testing.T) {
+	dir := cleanTempDir(t)
+	target := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(target, []byte("echo one\n"), 0o750); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	if _, err := NewWriteToolWithRoot(dir).Execute(context.Background(), map[string]any{
+		"file_path": target,
+		"content":   "echo two\n",
+	}); err != nil {
+		t.Fatalf("write execute failed: %v", err)
+	}
+	if _, err := NewEditToolWithRoot(dir).Execute(context.Background(), map[string]any{
+		"file_path":  target,
+		"old_string": "two",
+		"new_string": "three",
+	}); err != nil {
+		t.Fatalf("edit execute failed: %v", err)
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("stat target: %v", err)
+	}
+	if got := info.Mode().Perm(); got != 0o750 {
+		t.Fatalf("permissions not preserved: %o", got)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "echo three\n" {
+		t.Fatalf("unexpected content %q", data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("temporary files left behind: %v", entries)
+	}
+}
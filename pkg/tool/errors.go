@@ -0,0 +1,171 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"syscall"
+
+	"github.com/cexll/agentsdk-go/pkg/sandbox"
+	"github.com/cexll/agentsdk-go/pkg/security"
+)
+
+// ErrorCategory classifies why a tool call failed so the model can decide
+// whether to retry, fix its input or give up.
+type ErrorCategory string
+
+const (
+	// ErrorInvalidInput means the arguments were rejected.
+	ErrorInvalidInput ErrorCategory = "invalid_input"
+	// ErrorPermission means a rule or the sandbox blocked the call.
+	ErrorPermission ErrorCategory = "permission_denied"
+	// ErrorNotFound means the tool or a referenced resource does not exist.
+	ErrorNotFound ErrorCategory = "not_found"
+	// ErrorTimeout means the call ran out of time.
+	ErrorTimeout ErrorCategory = "timeout"
+	// ErrorCanceled means the caller canceled the call.
+	ErrorCanceled ErrorCategory = "canceled"
+	// ErrorExecution means the tool ran and failed, e.g. a non-zero exit.
+	ErrorExecution ErrorCategory = "execution_failed"
+	// ErrorUnavailable means a backing service could not be reached.
+	ErrorUnavailable ErrorCategory = "unavailable"
+	// ErrorConflict means a lock or concurrent writer got in the way.
+	ErrorConflict ErrorCategory = "conflict"
+)
+
+// ToolError is the structured payload of a failed tool call. It implements
+// error, so tools may return it directly; ClassifyError derives one for any
+// other error.
+type ToolError struct {
+	Category  ErrorCategory `json:"category"`
+	Message   string        `json:"message"`
+	Retryable bool          `json:"retryable"`
+	// ExitCode, Stdout and Stderr are set by command tools such as bash.
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	// Attempts is the number of executions, including automatic retries.
+	Attempts int `json:"attempts,omitempty"`
+	// Violations lists the schema violations of an ErrorInvalidInput
+	// failure raised before the tool ran.
+	Violations []Violation `json:"violations,omitempty"`
+	// Err is the underlying error.
+	Err error `json:"-"`
+}
+
+func (e *ToolError) Error() string {
+	if e == nil {
+		return ""
+	}
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return string(e.Category)
+}
+
+func (e *ToolError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// NewToolError wraps err with a category. The message is taken from err.
+func NewToolError(category ErrorCategory, err error) *ToolError {
+	te := &ToolError{Category: category, Err: err}
+	if err != nil {
+		te.Message = err.Error()
+	}
+	te.Retryable = category == ErrorTimeout || category == ErrorUnavailable || category == ErrorConflict
+	return te
+}
+
+// ClassifyError returns the ToolError carried by err or derives one from
+// well-known sentinel errors. Unrecognized errors are ErrorExecution.
+func ClassifyError(err error) *ToolError {
+	if err == nil {
+		return nil
+	}
+	var te *ToolError
+	if errors.As(err, &te) {
+		if te.Message == "" || te.Message != err.Error() {
+			clone := *te
+			clone.Message = err.Error()
+			return &clone
+		}
+		return te
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewToolError(ErrorTimeout, err)
+	case errors.Is(err, context.Canceled):
+		return NewToolError(ErrorCanceled, err)
+	case errors.Is(err, sandbox.ErrPathDenied), errors.Is(err, sandbox.ErrSymlinkDetected),
+		errors.Is(err, sandbox.ErrDomainDenied), errors.Is(err, sandbox.ErrResourceExceeded),
+		errors.Is(err, security.ErrPathNotAllowed), errors.Is(err, security.ErrWriteOutsideScope),
+		errors.Is(err, fs.ErrPermission):
+		return NewToolError(ErrorPermission, err)
+	case errors.Is(err, fs.ErrNotExist):
+		return NewToolError(ErrorNotFound, err)
+	case isNetworkError(err):
+		return NewToolError(ErrorUnavailable, err)
+	}
+	return NewToolError(ErrorExecution, err)
+}
+
+// isNetworkError reports connection failures worth retrying.
+func isNetworkError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsTemporary
+}
+
+// ErrorContent renders a failed call as the JSON tool_result content sent to
+// the model. The "error" key holds the message, so older readers that only
+// look for it keep working; output is the partial tool output, if any.
+func ErrorContent(te *ToolError, output string) string {
+	if te == nil {
+		return ""
+	}
+	payload := struct {
+		Error      string        `json:"error"`
+		Category   ErrorCategory `json:"category"`
+		Retryable  bool          `json:"retryable"`
+		ExitCode   *int          `json:"exit_code,omitempty"`
+		Stdout     string        `json:"stdout,omitempty"`
+		Stderr     string        `json:"stderr,omitempty"`
+		Attempts   int           `json:"attempts,omitempty"`
+		Violations []Violation   `json:"violations,omitempty"`
+		Output     string        `json:"output,omitempty"`
+	}{
+		Error:      te.Error(),
+		Category:   te.Category,
+		Retryable:  te.Retryable,
+		ExitCode:   te.ExitCode,
+		Stdout:     te.Stdout,
+		Stderr:     te.Stderr,
+		Attempts:   te.Attempts,
+		Violations: te.Violations,
+	}
+	if te.Stdout == "" && te.Stderr == "" {
+		payload.Output = output
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,71 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/cexll/agentsdk-go/pkg/sandbox"
+)
+
+func TestClassifyError(t *testing.T) {
+	wrapped := fmt.Errorf("fetch: %w", NewToolError(ErrorUnavailable, errors.New("connection refused")))
+	cases := []struct {
+		err       error
+		category  ErrorCategory
+		retryable bool
+	}{
+		{context.DeadlineExceeded, ErrorTimeout, true},
+		{context.Canceled, ErrorCanceled, false},
+		{fmt.Errorf("read: %w", sandbox.ErrPathDenied), ErrorPermission, false},
+		{fmt.Errorf("open: %w", os.ErrNotExist), ErrorNotFound, false},
+		{errors.New("boom"), ErrorExecution, false},
+		{wrapped, ErrorUnavailable, true},
+	}
+	for _, tc := range cases {
+		got := ClassifyError(tc.err)
+		if got.Category != tc.category || got.Retryable != tc.retryable || got.Error() != tc.err.Error() {
+			t.Fatalf("ClassifyError(%v) = %+v", tc.err, got)
+		}
+	}
+	if ClassifyError(nil) != nil {
+		t.Fatal("nil error classified")
+	}
+}
+
+func TestErrorContentAndExecutorFlags(t *testing.T) {
+	code := 2
+	content := ErrorContent(&ToolError{Category: ErrorExecution, Message: "command failed", ExitCode: &code, Stderr: "bad flag"}, "ignored")
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(content), &payload); err != nil {
+		t.Fatalf("invalid json %q: %v", content, err)
+	}
+	if payload["error"] != "command failed" || payload["stderr"] != "bad flag" || payload["exit_code"] != float64(2) || payload["output"] != nil {
+		t.Fatalf("unexpected payload %v", payload)
+	}
+
+	reg := NewRegistry()
+	if err := reg.Register(&partialFailTool{}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	cr, err := NewExecutor(reg, nil).Execute(context.Background(), Call{Name: "partial_fail"})
+	if err == nil || cr == nil || !cr.Result.IsError || cr.Result.ErrorDetail.Category != ErrorExecution {
+		t.Fatalf("expected flagged result, got %+v, %v", cr, err)
+	}
+	_, err = NewExecutor(reg, nil).Execute(context.Background(), Call{Name: "missing"})
+	if ClassifyError(err).Category != ErrorNotFound {
+		t.Fatalf("missing tool classified as %+v", ClassifyError(err))
+	}
+}
+
+type partialFailTool struct{}
+
+func (partialFailTool) Name() string        { return "partial_fail" }
+func (partialFailTool) Description() string { return "fails after output" }
+func (partialFailTool) Schema() *JSONSchema { return nil }
+func (partialFailTool) Execute(context.Context, map[string]interface{}) (*ToolResult, error) {
+	return &ToolResult{Success: true, Output: "half"}, errors.New("boom")
+}
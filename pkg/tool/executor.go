@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/cexll/agentsdk-go/pkg/artifact"
 	"github.com/cexll/agentsdk-go/pkg/sandbox"
 	"github.com/cexll/agentsdk-go/pkg/security"
 )
@@ -18,7 +19,10 @@ type Executor struct {
 	registry  *Registry
 	sandbox   *sandbox.Manager
 	persister *OutputPersister
+	artifacts artifact.Store
 	permCheck PermissionResolver
+	permSeen  PermissionObserver
+	retry     RetryPolicy
 }
 
 // NewExecutor constructs an executor backed by the provided registry. When
@@ -34,8 +38,11 @@ func NewExecutor(registry *Registry, sb *sandbox.Manager) *Executor {
 // Registry exposes the underlying registry primarily for tests.
 func (e *Executor) Registry() *Registry { return e.registry }
 
-// Execute runs a single tool call. Parameters are shallow-cloned before being
-// handed over to the tool to avoid concurrent callers mutating shared maps.
+// Execute runs a single tool call. Params are validated against the tool's
+// schema before permission checks, so malformed calls fail with an
+// ErrorInvalidInput *ToolError instead of prompting for approval or reaching
+// the tool. Parameters are shallow-cloned before being handed over to the
+// tool to avoid concurrent callers mutating shared maps.
 func (e *Executor) Execute(ctx context.Context, call Call) (*CallResult, error) {
 	if e == nil || e.registry == nil {
 		return nil, errors.New("executor is not initialised")
@@ -44,6 +51,14 @@ func (e *Executor) Execute(ctx context.Context, call Call) (*CallResult, error)
 		return nil, errors.New("tool name is empty")
 	}
 
+	tool, err := e.registry.Get(call.Name)
+	if err != nil {
+		return nil, NewToolError(ErrorNotFound, err)
+	}
+	if err := e.registry.validate(tool, call.Params); err != nil {
+		return nil, err
+	}
+
 	if e.sandbox != nil {
 		decision, err := e.sandbox.CheckToolPermission(call.Name, call.Params)
 		if err != nil {
@@ -53,11 +68,23 @@ func (e *Executor) Execute(ctx context.Context, call Call) (*CallResult, error)
 		if err != nil {
 			return nil, err
 		}
+		if e.permSeen != nil {
+			e.permSeen(ctx, call, decision)
+		}
 		switch decision.Action {
 		case security.PermissionDeny:
-			return nil, fmt.Errorf("tool %s denied by rule %q for %s", call.Name, decision.Rule, decision.Target)
+			if reason := strings.TrimSpace(decision.Reason); reason != "" {
+				return nil, NewToolError(ErrorPermission, fmt.Errorf("tool %s denied by rule %q for %s: %s", call.Name, decision.Rule, decision.Target, reason))
+			}
+			return nil, NewToolError(ErrorPermission, fmt.Errorf("tool %s denied by rule %q for %s", call.Name, decision.Rule, decision.Target))
 		case security.PermissionAsk:
-			return nil, fmt.Errorf("tool %s requires approval (rule %q for %s)", call.Name, decision.Rule, decision.Target)
+			return nil, NewToolError(ErrorPermission, fmt.Errorf("tool %s requires approval (rule %q for %s)", call.Name, decision.Rule, decision.Target))
+		}
+		if decision.UpdatedInput != nil {
+			call.Params = decision.UpdatedInput
+			if err := e.registry.validate(tool, call.Params); err != nil {
+				return nil, err
+			}
 		}
 
 		if err := e.sandbox.Enforce(call.Path, call.Host, call.Usage); err != nil {
@@ -65,32 +92,54 @@ func (e *Executor) Execute(ctx context.Context, call Call) (*CallResult, error)
 		}
 	}
 
-	tool, err := e.registry.Get(call.Name)
-	if err != nil {
-		return nil, err
-	}
-
-	params := call.cloneParams()
 	started := time.Now()
+	policy := e.registry.policyFor(call.Name)
+	run := policy.withTimeout(call.Name, func(ctx context.Context) (*ToolResult, error) {
+		params := call.cloneParams()
+		if streamingTool, ok := tool.(StreamingTool); ok && call.StreamSink != nil {
+			return streamingTool.StreamExecute(ctx, params, call.StreamSink)
+		}
+		return tool.Execute(ctx, params)
+	})
 	var (
-		res     *ToolResult
-		execErr error
+		res      *ToolResult
+		execErr  error
+		failures []error
+		attempts = 1
 	)
-	if streamingTool, ok := tool.(StreamingTool); ok && call.StreamSink != nil {
-		res, execErr = streamingTool.StreamExecute(ctx, params, call.StreamSink)
+	retry, retryable := policy.retryPolicy(e.retry, isIdempotent(tool))
+	if retry.MaxRetries > 0 && retryable {
+		res, attempts, failures, execErr = retry.run(ctx, call.Name, run)
 	} else {
-		res, execErr = tool.Execute(ctx, params)
+		res, execErr = run(ctx)
 	}
 	if e.persister != nil && res != nil {
 		// MaybePersist errors are logged internally; ignore return value
 		e.persister.MaybePersist(call, res) //nolint:errcheck
 	}
+	if e.artifacts != nil && res != nil {
+		e.storeArtifacts(ctx, call, res)
+	}
+	if execErr != nil && res != nil {
+		res.IsError = true
+		res.Success = false
+		if res.ErrorDetail == nil {
+			res.ErrorDetail = ClassifyError(execErr)
+		}
+		if attempts > 1 {
+			detail := *res.ErrorDetail
+			detail.Attempts = attempts
+			res.ErrorDetail = &detail
+		}
+	}
 	cr := &CallResult{
-		Call:        call,
-		Result:      res,
-		Err:         execErr,
-		StartedAt:   started,
-		CompletedAt: time.Now(),
+		Call:          call,
+		Result:        res,
+		Err:           execErr,
+		Attempts:      attempts,
+		AttemptErrors: failures,
+		StartedAt:     started,
+		CompletedAt:   time.Now(),
 	}
 	return cr, execErr
 }
@@ -152,6 +201,23 @@ func (e *Executor) WithPermissionResolver(resolver PermissionResolver) *Executor
 	return &clone
 }
 
+// PermissionObserver is notified of the final permission decision for every
+// call evaluated against the sandbox, after any resolver has run. Observers
+// are used for auditing and must not block.
+type PermissionObserver func(context.Context, Call, security.PermissionDecision)
+
+// WithPermissionObserver returns a shallow copy using the provided observer.
+func (e *Executor) WithPermissionObserver(observer PermissionObserver) *Executor {
+	if e == nil {
+		exec := NewExecutor(nil, nil)
+		exec.permSeen = observer
+		return exec
+	}
+	clone := *e
+	clone.permSeen = observer
+	return &clone
+}
+
 // WithOutputPersister returns a shallow copy using the provided persister.
 func (e *Executor) WithOutputPersister(persister *OutputPersister) *Executor {
 	if e == nil {
@@ -164,6 +230,42 @@ func (e *Executor) WithOutputPersister(persister *OutputPersister) *Executor {
 	return &clone
 }
 
+// WithArtifactStore returns a shallow copy that moves ToolResult.Artifacts
+// payloads into store.
+func (e *Executor) WithArtifactStore(store artifact.Store) *Executor {
+	if e == nil {
+		exec := NewExecutor(nil, nil)
+		exec.artifacts = store
+		return exec
+	}
+	clone := *e
+	clone.artifacts = store
+	return &clone
+}
+
+// storeArtifacts replaces inline artifact payloads with store references.
+// Artifacts that fail to store keep their payload so nothing is lost.
+func (e *Executor) storeArtifacts(ctx context.Context, call Call, res *ToolResult) {
+	for i := range res.Artifacts {
+		a := &res.Artifacts[i]
+		if a.Data == nil {
+			continue
+		}
+		stored, err := e.artifacts.Put(ctx, artifact.Artifact{
+			Name:      a.Name,
+			MediaType: a.MediaType,
+			SessionID: call.SessionID,
+			Tool:      call.Name,
+		}, a.Data)
+		if err != nil {
+			continue
+		}
+		a.ID = stored.ID
+		a.SizeBytes = stored.SizeBytes
+		a.Data = nil
+	}
+}
+
 func (e *Executor) resolvePermission(ctx context.Context, call Call, decision security.PermissionDecision) (security.PermissionDecision, error) {
 	if decision.Action != security.PermissionAsk || e == nil || e.permCheck == nil {
 		return decision, nil
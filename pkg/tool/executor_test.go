@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cexll/agentsdk-go/pkg/artifact"
 	"github.com/cexll/agentsdk-go/pkg/sandbox"
 	"github.com/cexll/agentsdk-go/pkg/security"
 )
@@ -593,6 +594,39 @@ func TestExecutorAllowsWhenPermissionMatches(t *testing.T) {
 	}
 }
 
+func TestExecutorPermissionObserverSeesResolvedDecision(t *testing.T) {
+	root := canonicalTempDir(t)
+	claude := filepath.Join(root, ".claude")
+	if err := os.MkdirAll(claude, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	settings := `{"permissions":{"ask":["Bash(ls:*)"]}}`
+	if err := os.WriteFile(filepath.Join(claude, "settings.json"), []byte(settings), 0o600); err != nil {
+		t.Fatalf("write settings: %v", err)
+	}
+
+	reg := NewRegistry()
+	if err := reg.Register(&stubTool{name: "Bash"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	var seen []security.PermissionDecision
+	exec := NewExecutor(reg, sandbox.NewManager(sandbox.NewFileSystemAllowList(root), nil, nil)).
+		WithPermissionResolver(func(context.Context, Call, security.PermissionDecision) (security.PermissionDecision, error) {
+			return security.PermissionDecision{Action: security.PermissionDeny}, nil
+		}).
+		WithPermissionObserver(func(_ context.Context, _ Call, decision security.PermissionDecision) {
+			seen = append(seen, decision)
+		})
+
+	if _, err := exec.Execute(context.Background(), Call{Name: "Bash", Params: map[string]any{"command": "ls -la"}, Path: root}); err == nil {
+		t.Fatalf("expected deny error")
+	}
+	if len(seen) != 1 || seen[0].Action != security.PermissionDeny || seen[0].Rule == "" {
+		t.Fatalf("unexpected observed decisions: %+v", seen)
+	}
+}
+
 func canonicalTempDir(t *testing.T) string {
 	t.Helper()
 	dir := t.TempDir()
@@ -601,3 +635,74 @@ func canonicalTempDir(t *testing.T) string {
 	}
 	return dir
 }
+
+type artifactTool struct{}
+
+func (artifactTool) Name() string        { return "report" }
+func (artifactTool) Description() string { return "emits an artifact" }
+func (artifactTool) Schema() *JSONSchema { return nil }
+func (artifactTool) Execute(context.Context, map[string]interface{}) (*ToolResult, error) {
+	return &ToolResult{Success: true, Output: "see artifact", Artifacts: []Artifact{{Name: "report.md", MediaType: "text/markdown", Data: []byte("# report")}}}, nil
+}
+
+func TestExecutorStoresArtifacts(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Register(artifactTool{}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	store := artifact.NewMemoryStore()
+	exec := NewExecutor(reg, nil).WithArtifactStore(store)
+
+	res, err := exec.Execute(context.Background(), Call{Name: "report", SessionID: "sess"})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	got := res.Result.Artifacts
+	if len(got) != 1 || got[0].Data != nil || got[0].ID == "" || got[0].SizeBytes != 8 {
+		t.Fatalf("expected stored artifact reference, got %+v", got)
+	}
+	meta, data, err := store.Get(context.Background(), got[0].ID)
+	if err != nil || string(data) != "# report" || meta.SessionID != "sess" || meta.Tool != "report" {
+		t.Fatalf("stored artifact mismatch %+v %q err=%v", meta, data, err)
+	}
+}
+
+func TestExecutorValidatesParamsBeforePermissions(t *testing.T) {
+	reg := NewRegistry()
+	tool := &spyTool{name: "Read", schema: &JSONSchema{
+		Type:       "object",
+		Properties: map[string]any{"file_path": map[string]any{"type": "string"}, "limit": map[string]any{"type": "integer"}},
+		Required:   []string{"file_path"},
+	}}
+	if err := reg.Register(tool); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	asked := false
+	exec := NewExecutor(reg, sandbox.NewManager(nil, nil, nil)).WithPermissionResolver(func(context.Context, Call, security.PermissionDecision) (security.PermissionDecision, error) {
+		asked = true
+		return security.PermissionDecision{Action: security.PermissionAllow}, nil
+	})
+
+	_, err := exec.Execute(context.Background(), Call{Name: "Read", Params: map[string]any{"limit": "ten"}})
+	var te *ToolError
+	if !errors.As(err, &te) || te.Category != ErrorInvalidInput || te.Retryable {
+		t.Fatalf("expected invalid input error, got %v", err)
+	}
+	if len(te.Violations) != 2 || te.Violations[0].Field != "file_path" || te.Violations[1].Field != "limit" {
+		t.Fatalf("unexpected violations %+v", te.Violations)
+	}
+	if tool.calls != 0 || asked {
+		t.Fatalf("malformed call reached tool=%d resolver=%v", tool.calls, asked)
+	}
+	content := ErrorContent(ClassifyError(err), "")
+	if !strings.Contains(content, `"category":"invalid_input"`) || !strings.Contains(content, `"violations":[{"field":"file_path"`) {
+		t.Fatalf("unexpected error content %s", content)
+	}
+
+	if _, err := exec.Execute(context.Background(), Call{Name: "Read", Params: map[string]any{"file_path": "a.txt", "limit": 10}}); err != nil {
+		t.Fatalf("valid call failed: %v", err)
+	}
+	if tool.calls != 1 {
+		t.Fatalf("expected one execution, got %d", tool.calls)
+	}
+}
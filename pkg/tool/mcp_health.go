@@ -0,0 +1,323 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/mcp"
+)
+
+// MCP server states reported in MCPServerStatus.State.
+const (
+	// MCPStatePending marks a lazily registered server that has not been
+	// needed yet.
+	MCPStatePending = "pending"
+	// MCPStateConnected marks a server whose session answers pings.
+	MCPStateConnected = "connected"
+	// MCPStateDegraded marks a connected server whose last ping failed. Its
+	// tools stay registered until the failures persist.
+	MCPStateDegraded = "degraded"
+	// MCPStateFailed marks a server that could not be connected or was
+	// dropped after repeated ping failures. It is retried with backoff.
+	MCPStateFailed = "failed"
+)
+
+// DefaultMCPHealthInterval is the ping interval of StartMCPHealthChecks
+// when none is given.
+const DefaultMCPHealthInterval = 30 * time.Second
+
+var (
+	// mcpHealthFailures is the number of consecutive failed pings after
+	// which a degraded server is disconnected and connected again.
+	mcpHealthFailures = 3
+	// mcpRetryBackoff is the delay before retrying a failed connection; it
+	// doubles per consecutive failure up to mcpRetryMaxBackoff.
+	mcpRetryBackoff    = 5 * time.Second
+	mcpRetryMaxBackoff = 5 * time.Minute
+)
+
+// lazyMCPServer is a server registered with RegisterLazyMCPServer. The
+// fields below connectMu are guarded by Registry.mu.
+type lazyMCPServer struct {
+	spec string
+	name string
+	opts MCPServerOptions
+
+	// connectMu serialises connection attempts, so concurrent callers wait
+	// for the one in flight instead of dialing twice.
+	connectMu sync.Mutex
+
+	state    string
+	lastErr  string
+	failures int
+	retryAt  time.Time
+}
+
+// RegisterLazyMCPServer records an MCP server without connecting to it. The
+// server is connected by the first ConnectMCP call, typically made when its
+// tools are about to be offered to a model, and its tools are registered
+// then. A server that cannot be reached is reported as failed by MCPStatus
+// and retried later instead of failing the caller.
+func (r *Registry) RegisterLazyMCPServer(serverPath, serverName string, opts MCPServerOptions) error {
+	serverPath = strings.TrimSpace(serverPath)
+	if serverPath == "" {
+		return fmt.Errorf("server path is empty")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.mcpLazy {
+		if s.spec == serverPath {
+			return fmt.Errorf("MCP server %s already registered", serverPath)
+		}
+	}
+	r.mcpLazy = append(r.mcpLazy, &lazyMCPServer{
+		spec:  serverPath,
+		name:  strings.TrimSpace(serverName),
+		opts:  opts,
+		state: MCPStatePending,
+	})
+	return nil
+}
+
+// ConnectMCP connects, in parallel, every lazily registered server that is
+// pending or failed and due for a retry. Each attempt is bounded by the
+// server's Timeout. Failures are logged and surface in MCPStatus rather than
+// being returned, so one unreachable server does not hold up the others.
+func (r *Registry) ConnectMCP(ctx context.Context) {
+	ctx = nonNilContext(ctx)
+	now := time.Now()
+	r.mu.RLock()
+	var due []*lazyMCPServer
+	for _, s := range r.mcpLazy {
+		if s.dueLocked(now) {
+			due = append(due, s)
+		}
+	}
+	r.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, s := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.connectLazyMCP(ctx, s)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *lazyMCPServer) dueLocked(now time.Time) bool {
+	switch s.state {
+	case MCPStatePending:
+		return true
+	case MCPStateFailed:
+		return !now.Before(s.retryAt)
+	default:
+		return false
+	}
+}
+
+func (r *Registry) connectLazyMCP(ctx context.Context, s *lazyMCPServer) {
+	s.connectMu.Lock()
+	defer s.connectMu.Unlock()
+	r.mu.RLock()
+	due := s.dueLocked(time.Now())
+	r.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	var err error
+	if s.opts.isZero() {
+		err = r.RegisterMCPServer(ctx, s.spec, s.name)
+	} else {
+		err = r.RegisterMCPServerWithOptions(ctx, s.spec, s.name, s.opts)
+	}
+
+	r.mu.Lock()
+	if !slices.Contains(r.mcpLazy, s) {
+		// Close ran while we were connecting.
+		r.mu.Unlock()
+		if err == nil {
+			r.dropMCPSession(s.spec)
+		}
+		return
+	}
+	if err != nil {
+		s.failures++
+		s.state = MCPStateFailed
+		s.lastErr = err.Error()
+		s.retryAt = time.Now().Add(mcpRetryDelay(s.failures))
+		r.mu.Unlock()
+		log.Printf("tool registry: connect MCP server %s: %v", s.spec, err)
+		return
+	}
+	s.state = MCPStateConnected
+	s.lastErr = ""
+	s.failures = 0
+	r.mu.Unlock()
+}
+
+func mcpRetryDelay(failures int) time.Duration {
+	delay := mcpRetryBackoff << (failures - 1)
+	if delay <= 0 || delay > mcpRetryMaxBackoff {
+		delay = mcpRetryMaxBackoff
+	}
+	return delay
+}
+
+// CheckMCPHealth pings every connected lazily registered server, each ping
+// bounded by the server's Timeout, then retries the failed servers that are
+// due. A failed ping marks a server degraded; after mcpHealthFailures in a
+// row its session is closed, its tools are removed and it is connected
+// again.
+func (r *Registry) CheckMCPHealth(ctx context.Context) {
+	ctx = nonNilContext(ctx)
+	type probe struct {
+		server  *lazyMCPServer
+		session *mcp.ClientSession
+	}
+	r.mu.RLock()
+	var probes []probe
+	for _, s := range r.mcpLazy {
+		if s.state != MCPStateConnected && s.state != MCPStateDegraded {
+			continue
+		}
+		var session *mcp.ClientSession
+		if info := r.findMCPSessionLocked(s.spec, ""); info != nil {
+			session = info.session
+		}
+		probes = append(probes, probe{server: s, session: session})
+	}
+	r.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := errMCPSessionUntracked
+			if p.session != nil {
+				pingCtx, cancel := context.WithTimeout(ctx, p.server.opts.timeout())
+				err = p.session.Ping(pingCtx, nil)
+				cancel()
+			}
+			r.recordMCPPing(p.server, err)
+		}()
+	}
+	wg.Wait()
+	r.ConnectMCP(ctx)
+}
+
+func (r *Registry) recordMCPPing(s *lazyMCPServer, err error) {
+	r.mu.Lock()
+	if !slices.Contains(r.mcpLazy, s) {
+		r.mu.Unlock()
+		return
+	}
+	if err == nil {
+		s.state = MCPStateConnected
+		s.lastErr = ""
+		s.failures = 0
+		r.mu.Unlock()
+		return
+	}
+	s.failures++
+	s.lastErr = err.Error()
+	s.state = MCPStateDegraded
+	drop := s.failures >= mcpHealthFailures
+	if drop {
+		s.state = MCPStateFailed
+		s.failures = 0
+		s.retryAt = time.Time{}
+	}
+	r.mu.Unlock()
+	if drop {
+		log.Printf("tool registry: MCP server %s failed %d health checks: %v", s.spec, mcpHealthFailures, err)
+		r.dropMCPSession(s.spec)
+	}
+}
+
+// dropMCPSession closes the session of serverID and removes its tools. The
+// session leaves the registry first so a stdio supervisor does not restart
+// it.
+func (r *Registry) dropMCPSession(serverID string) {
+	r.mu.Lock()
+	info := r.findMCPSessionLocked(serverID, "")
+	if info == nil {
+		r.mu.Unlock()
+		return
+	}
+	r.mcpSessions = slices.DeleteFunc(r.mcpSessions, func(s *mcpSessionInfo) bool { return s == info })
+	for name := range info.toolNames {
+		delete(r.tools, name)
+	}
+	r.mu.Unlock()
+	if info.session != nil {
+		if err := info.session.Close(); err != nil {
+			log.Printf("tool registry: close MCP session: %v", err)
+		}
+	}
+}
+
+// StartMCPHealthChecks runs CheckMCPHealth every interval until Close. A
+// non-positive interval uses DefaultMCPHealthInterval. Later calls are
+// no-ops.
+func (r *Registry) StartMCPHealthChecks(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultMCPHealthInterval
+	}
+	r.mu.Lock()
+	if r.healthStop != nil {
+		r.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	r.healthStop = stop
+	r.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				r.CheckMCPHealth(context.Background())
+			}
+		}
+	}()
+}
+
+// lazyMCPStatusLocked reports the lazily registered servers that have no
+// session: pending ones and failed ones awaiting a retry.
+func (r *Registry) lazyMCPStatusLocked() []MCPServerStatus {
+	var out []MCPServerStatus
+	for _, s := range r.mcpLazy {
+		if s.state != MCPStatePending && s.state != MCPStateFailed {
+			continue
+		}
+		if r.findMCPSessionLocked(s.spec, "") != nil {
+			continue
+		}
+		out = append(out, MCPServerStatus{ID: s.spec, Name: s.name, State: s.state, Error: s.lastErr})
+	}
+	return out
+}
+
+func (o MCPServerOptions) isZero() bool {
+	return len(o.Headers) == 0 && len(o.Env) == 0 && o.Timeout <= 0 && o.Command == "" && len(o.Args) == 0 && o.MaxRestarts == 0
+}
+
+func (o MCPServerOptions) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return 10 * time.Second
+}
@@ -0,0 +1,115 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/mcp"
+)
+
+func TestLazyMCPServerConnectsOnDemandAndRetries(t *testing.T) {
+	origBackoff := mcpRetryBackoff
+	mcpRetryBackoff = time.Hour
+	defer func() { mcpRetryBackoff = origBackoff }()
+
+	server := &stubMCPServer{tools: []*mcp.Tool{{Name: "echo", InputSchema: map[string]any{"type": "object"}}}}
+	dials := 0
+	dialErr := errors.New("connection refused")
+	restore := withStubMCPClient(t, func(context.Context, string, mcpListChangedHandler) (*mcp.ClientSession, error) {
+		dials++
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		return server.newSession()
+	})
+	defer restore()
+
+	r := NewRegistry()
+	defer r.Close()
+	if err := r.RegisterLazyMCPServer("http://stub", "stub", MCPServerOptions{}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := r.RegisterLazyMCPServer("http://stub", "stub", MCPServerOptions{}); err == nil {
+		t.Fatal("expected duplicate server error")
+	}
+	if status := r.MCPStatus(context.Background()); dials != 0 || len(status) != 1 || status[0].State != MCPStatePending {
+		t.Fatalf("dials=%d status=%+v, want pending without dialing", dials, status)
+	}
+
+	r.ConnectMCP(context.Background())
+	if status := r.MCPStatus(context.Background()); len(status) != 1 || status[0].State != MCPStateFailed || status[0].Error == "" {
+		t.Fatalf("status = %+v, want failed", status)
+	}
+	// Not retried before the backoff elapses.
+	dialErr = nil
+	r.ConnectMCP(context.Background())
+	if dials != 1 {
+		t.Fatalf("dials = %d, want retry held back", dials)
+	}
+
+	r.mu.Lock()
+	r.mcpLazy[0].retryAt = time.Time{}
+	r.mu.Unlock()
+	r.ConnectMCP(context.Background())
+	if _, err := r.Get("stub__echo"); err != nil {
+		t.Fatalf("tool not registered after connect: %v", err)
+	}
+	status := r.MCPStatus(context.Background())
+	if len(status) != 1 || status[0].State != MCPStateConnected || !status[0].Healthy {
+		t.Fatalf("status = %+v, want connected", status)
+	}
+	r.ConnectMCP(context.Background())
+	if dials != 2 {
+		t.Fatalf("dials = %d, connected server dialed again", dials)
+	}
+}
+
+func TestCheckMCPHealthDegradesAndReconnects(t *testing.T) {
+	server := &stubMCPServer{tools: []*mcp.Tool{{Name: "echo", InputSchema: map[string]any{"type": "object"}}}}
+	dials := 0
+	restore := withStubMCPClient(t, func(context.Context, string, mcpListChangedHandler) (*mcp.ClientSession, error) {
+		dials++
+		return server.newSession()
+	})
+	defer restore()
+
+	r := NewRegistry()
+	defer r.Close()
+	if err := r.RegisterLazyMCPServer("http://stub", "stub", MCPServerOptions{}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	r.ConnectMCP(context.Background())
+
+	server.pingErr = errors.New("overloaded")
+	for i := 1; i < mcpHealthFailures; i++ {
+		r.CheckMCPHealth(context.Background())
+		r.mu.RLock()
+		state := r.mcpLazy[0].state
+		r.mu.RUnlock()
+		if state != MCPStateDegraded {
+			t.Fatalf("check %d: state = %s, want degraded", i, state)
+		}
+		if _, err := r.Get("stub__echo"); err != nil {
+			t.Fatalf("degraded server lost its tools: %v", err)
+		}
+	}
+
+	// The last failure drops the session and reconnects in the same check.
+	r.CheckMCPHealth(context.Background())
+	server.pingErr = nil
+	if dials != 2 {
+		t.Fatalf("dials = %d, want a reconnect", dials)
+	}
+	status := r.MCPStatus(context.Background())
+	if len(status) != 1 || status[0].State != MCPStateConnected || len(status[0].Tools) != 1 {
+		t.Fatalf("status = %+v, want reconnected", status)
+	}
+
+	r.StartMCPHealthChecks(time.Millisecond)
+	r.Close()
+	if status := r.MCPStatus(context.Background()); len(status) != 0 {
+		t.Fatalf("servers survived Close: %+v", status)
+	}
+}
@@ -0,0 +1,183 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/mcp"
+)
+
+// DefaultMCPMaxRestarts is the restart budget of a crashed stdio MCP server
+// when MCPServerOptions.MaxRestarts is 0.
+const DefaultMCPMaxRestarts = 5
+
+var (
+	// mcpRestartBackoff is the delay before the first restart; it doubles
+	// per consecutive restart up to mcpRestartMaxBackoff.
+	mcpRestartBackoff    = 200 * time.Millisecond
+	mcpRestartMaxBackoff = 10 * time.Second
+	// mcpRestartResetAfter is the uptime after which the restart budget
+	// is refilled.
+	mcpRestartResetAfter = time.Minute
+)
+
+type mcpReconnectFunc func(context.Context) (*mcp.ClientSession, error)
+
+// isStdioMCPServer reports whether the server runs as a child process.
+// Specs without a scheme fall back to stdio, like mcp.BuildSessionTransport.
+func isStdioMCPServer(spec string, opts MCPServerOptions) bool {
+	if strings.TrimSpace(opts.Command) != "" {
+		return true
+	}
+	lowered := strings.ToLower(strings.TrimSpace(spec))
+	return strings.HasPrefix(lowered, "stdio://") || !strings.Contains(lowered, "://")
+}
+
+// superviseMCPSession restarts a stdio server whose process exits while the
+// registry still tracks it. Close stops supervision: the session leaves the
+// registry before it is closed, so its exit is not treated as a crash.
+func (r *Registry) superviseMCPSession(serverID string, session *mcp.ClientSession, maxRestarts int, reconnect mcpReconnectFunc) {
+	if session == nil || reconnect == nil || maxRestarts < 0 {
+		return
+	}
+	if maxRestarts == 0 {
+		maxRestarts = DefaultMCPMaxRestarts
+	}
+	go func() {
+		consecutive := 0
+		for {
+			started := time.Now()
+			_ = session.Wait()
+			if !r.tracksMCPSession(session) {
+				return
+			}
+			if time.Since(started) >= mcpRestartResetAfter {
+				consecutive = 0
+			}
+			next, attempts, err := r.restartMCPSession(serverID, session, reconnect, maxRestarts-consecutive)
+			consecutive += attempts
+			if err != nil {
+				log.Printf("tool registry: MCP server %s not restarted: %v", serverID, err)
+				return
+			}
+			if next == nil {
+				return
+			}
+			session = next
+		}
+	}()
+}
+
+// restartMCPSession reconnects with exponential backoff, spending at most
+// budget attempts. It returns a nil session when the registry was closed.
+func (r *Registry) restartMCPSession(serverID string, dead *mcp.ClientSession, reconnect mcpReconnectFunc, budget int) (*mcp.ClientSession, int, error) {
+	var lastErr error
+	for attempt := 1; attempt <= budget; attempt++ {
+		delay := mcpRestartBackoff << (attempt - 1)
+		if delay <= 0 || delay > mcpRestartMaxBackoff {
+			delay = mcpRestartMaxBackoff
+		}
+		time.Sleep(delay)
+		if !r.tracksMCPSession(dead) {
+			return nil, attempt, nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		session, err := reconnect(ctx)
+		if err == nil && session == nil {
+			err = errors.New("session is nil")
+		}
+		if err == nil {
+			err = r.replaceMCPSession(ctx, serverID, dead, session)
+			if err != nil {
+				_ = session.Close()
+			}
+		}
+		cancel()
+		if err == nil {
+			return session, attempt, nil
+		}
+		if errors.Is(err, errMCPSessionUntracked) {
+			return nil, attempt, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = errors.New("restart budget exhausted")
+	}
+	return nil, budget, lastErr
+}
+
+var errMCPSessionUntracked = errors.New("mcp session not tracked")
+
+// replaceMCPSession swaps the tools of dead for those served by session.
+func (r *Registry) replaceMCPSession(ctx context.Context, serverID string, dead, session *mcp.ClientSession) error {
+	r.mu.RLock()
+	info := r.mcpSessionInfoLocked(dead)
+	var serverName string
+	if info != nil {
+		serverName = info.serverName
+	}
+	r.mu.RUnlock()
+	if info == nil {
+		return errMCPSessionUntracked
+	}
+
+	var tools []*mcp.Tool
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			return fmt.Errorf("list MCP tools: %w", err)
+		}
+		tools = append(tools, tool)
+	}
+	if len(tools) == 0 {
+		return fmt.Errorf("MCP server returned no tools")
+	}
+	wrappers, names, err := buildRemoteToolWrappers(session, serverName, tools)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mcpSessionInfoLocked(dead) != info {
+		return errMCPSessionUntracked
+	}
+	for _, name := range names {
+		if _, exists := r.tools[name]; exists {
+			if _, own := info.toolNames[name]; !own {
+				return fmt.Errorf("tool %s already registered", name)
+			}
+		}
+	}
+	for name := range info.toolNames {
+		delete(r.tools, name)
+	}
+	for i, tool := range wrappers {
+		r.tools[names[i]] = tool
+	}
+	info.toolNames = toNameSet(names)
+	info.session = session
+	info.sessionID = session.ID()
+	info.restarts++
+	log.Printf("tool registry: restarted MCP server %s (restart %d)", serverID, info.restarts)
+	return nil
+}
+
+func (r *Registry) tracksMCPSession(session *mcp.ClientSession) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mcpSessionInfoLocked(session) != nil
+}
+
+func (r *Registry) mcpSessionInfoLocked(session *mcp.ClientSession) *mcpSessionInfo {
+	for _, info := range r.mcpSessions {
+		if info != nil && info.session == session {
+			return info
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// TestMCPStdioHelperProcess is not a real test: the stdio tests start the
+// test binary with MCP_STDIO_HELPER=1 to serve MCP over stdin and stdout.
+func TestMCPStdioHelperProcess(t *testing.T) {
+	if os.Getenv("MCP_STDIO_HELPER") != "1" {
+		return
+	}
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "stdio-helper", Version: "dev"}, nil)
+	server.AddTool(&mcpsdk.Tool{Name: "greet", InputSchema: map[string]any{"type": "object"}},
+		func(context.Context, *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+			return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: os.Getenv("GREETING") + " from " + os.Args[len(os.Args)-1]}}}, nil
+		})
+	server.AddTool(&mcpsdk.Tool{Name: "crash", InputSchema: map[string]any{"type": "object"}},
+		func(context.Context, *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+			os.Exit(3)
+			return nil, nil
+		})
+	_ = server.Run(context.Background(), &mcpsdk.StdioTransport{})
+	os.Exit(0)
+}
+
+func TestRegisterMCPStdioServerRestartsOnCrash(t *testing.T) {
+	origBackoff := mcpRestartBackoff
+	mcpRestartBackoff = 10 * time.Millisecond
+	defer func() { mcpRestartBackoff = origBackoff }()
+
+	r := NewRegistry()
+	err := r.RegisterMCPServerWithOptions(context.Background(), "stdio://helper", "helper", MCPServerOptions{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestMCPStdioHelperProcess$", "--", "two words"},
+		Env:     map[string]string{"MCP_STDIO_HELPER": "1", "GREETING": "hello"},
+	})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	defer r.Close()
+
+	// The server must outlive the registration context.
+	greet := func() (*ToolResult, error) {
+		return r.Execute(context.Background(), "helper__greet", map[string]interface{}{})
+	}
+	res, err := greet()
+	if err != nil || res.Output != "hello from two words" {
+		t.Fatalf("greet = %+v, %v", res, err)
+	}
+
+	if _, err := r.Execute(context.Background(), "helper__crash", map[string]interface{}{}); err == nil {
+		t.Fatal("expected crash call to fail")
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		status := r.MCPStatus(context.Background())
+		if len(status) == 1 && status[0].Restarts == 1 && status[0].Healthy {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server not restarted: %+v", status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if res, err := greet(); err != nil || !strings.HasPrefix(res.Output, "hello") {
+		t.Fatalf("greet after restart = %+v, %v", res, err)
+	}
+
+	r.Close()
+	if status := r.MCPStatus(context.Background()); len(status) != 0 {
+		t.Fatalf("sessions survived Close: %+v", status)
+	}
+}
+
+func TestIsStdioMCPServer(t *testing.T) {
+	cases := map[string]bool{
+		"stdio://srv --flag": true,
+		"npx server":         true,
+		"http://host/mcp":    false,
+		"sse://host":         false,
+		"https+sse://host":   false,
+	}
+	for spec, want := range cases {
+		if got := isStdioMCPServer(spec, MCPServerOptions{}); got != want {
+			t.Errorf("isStdioMCPServer(%q) = %v, want %v", spec, got, want)
+		}
+	}
+	if !isStdioMCPServer("http://ignored", MCPServerOptions{Command: "srv"}) {
+		t.Error("Command should force stdio")
+	}
+}
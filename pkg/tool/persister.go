@@ -8,12 +8,34 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
-const defaultToolOutputThresholdBytes = 64 * 1024
+const (
+	defaultToolOutputThresholdBytes = 64 * 1024
+	defaultToolOutputHeadBytes      = 8 * 1024
+	defaultToolOutputTailBytes      = 8 * 1024
+)
+
+// OutputLimit bounds the output of a tool before it reaches the model.
+type OutputLimit struct {
+	// MaxBytes is the largest output passed through unchanged; 0 uses the
+	// persister's threshold.
+	MaxBytes int `json:"max_bytes,omitempty"`
+	// HeadBytes and TailBytes of larger output are kept inline around a
+	// marker saying how much was dropped. When both are zero the output is
+	// replaced by the marker alone.
+	HeadBytes int `json:"head_bytes,omitempty"`
+	TailBytes int `json:"tail_bytes,omitempty"`
+	// NoSpill drops the omitted bytes instead of saving the full output to
+	// a file the model can page through with the Read tool.
+	NoSpill bool `json:"no_spill,omitempty"`
+}
 
-// OutputPersister stores large ToolResult.Output payloads on disk and replaces
-// the inline output with a reference string plus OutputRef metadata.
+// OutputPersister bounds large ToolResult.Output payloads. Output over the
+// threshold is written to disk and replaced by its head and tail plus a
+// reference the model can open with the Read tool; OutputRef records the
+// file.
 //
 // The persisted file layout is:
 //
@@ -24,15 +46,27 @@ type OutputPersister struct {
 	BaseDir               string
 	DefaultThresholdBytes int
 	PerToolThresholdBytes map[string]int
+	// HeadBytes, TailBytes and NoSpill apply to tools without a
+	// PerToolLimits entry; see OutputLimit.
+	HeadBytes int
+	TailBytes int
+	NoSpill   bool
+	// PerToolLimits replaces the limits of individual tools, keyed by
+	// lower-case tool name.
+	PerToolLimits map[string]OutputLimit
 }
 
 func NewOutputPersister() *OutputPersister {
 	return &OutputPersister{
 		BaseDir:               toolOutputBaseDir(),
 		DefaultThresholdBytes: defaultToolOutputThresholdBytes,
+		HeadBytes:             defaultToolOutputHeadBytes,
+		TailBytes:             defaultToolOutputTailBytes,
 	}
 }
 
+// MaybePersist applies the tool's OutputLimit to result. When the full
+// output cannot be saved result is left untouched and the error returned.
 func (p *OutputPersister) MaybePersist(call Call, result *ToolResult) error {
 	if p == nil || result == nil {
 		return nil
@@ -45,42 +79,72 @@ func (p *OutputPersister) MaybePersist(call Call, result *ToolResult) error {
 		return nil
 	}
 
-	threshold := p.thresholdFor(call.Name)
-	if threshold <= 0 || len(output) <= threshold {
+	limit := p.limitFor(call.Name)
+	if limit.MaxBytes <= 0 || len(output) <= limit.MaxBytes {
+		return nil
+	}
+
+	if limit.NoSpill {
+		result.Output = truncateToolOutput(output, limit, "")
+		result.OutputRef = &OutputRef{SizeBytes: int64(len(output)), Truncated: true}
 		return nil
 	}
 
+	path, err := p.spill(call, output)
+	if err != nil {
+		return err
+	}
+
+	result.Output = truncateToolOutput(output, limit, path)
+	result.OutputRef = &OutputRef{
+		Path:      path,
+		SizeBytes: int64(len(output)),
+		Truncated: false,
+	}
+	return nil
+}
+
+// spill writes output to a new file under BaseDir and returns its path.
+func (p *OutputPersister) spill(call Call, output string) (string, error) {
 	base := strings.TrimSpace(p.BaseDir)
 	if base == "" {
-		return errors.New("tool output base directory is empty")
+		return "", errors.New("tool output base directory is empty")
 	}
 
 	sessionDir := sanitizePathComponent(call.SessionID)
 	toolDir := sanitizePathComponent(call.Name)
 	dir := filepath.Join(base, sessionDir, toolDir)
 	if err := os.MkdirAll(dir, 0o700); err != nil {
-		return err
+		return "", err
 	}
 
 	f, path, err := createToolOutputFile(dir)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	_, writeErr := f.WriteString(output)
 	closeErr := f.Close()
 	if writeErr != nil || closeErr != nil {
 		_ = os.Remove(path)
-		return errors.Join(writeErr, closeErr)
+		return "", errors.Join(writeErr, closeErr)
 	}
+	return path, nil
+}
 
-	result.Output = formatToolOutputReference(path)
-	result.OutputRef = &OutputRef{
-		Path:      path,
-		SizeBytes: int64(len(output)),
-		Truncated: false,
+func (p *OutputPersister) limitFor(toolName string) OutputLimit {
+	if p == nil {
+		return OutputLimit{}
 	}
-	return nil
+	canon := strings.ToLower(strings.TrimSpace(toolName))
+	limit, ok := p.PerToolLimits[canon]
+	if !ok || canon == "" {
+		limit = OutputLimit{HeadBytes: p.HeadBytes, TailBytes: p.TailBytes, NoSpill: p.NoSpill}
+	}
+	if limit.MaxBytes <= 0 {
+		limit.MaxBytes = p.thresholdFor(toolName)
+	}
+	return limit
 }
 
 func (p *OutputPersister) thresholdFor(toolName string) int {
@@ -99,6 +163,49 @@ func (p *OutputPersister) thresholdFor(toolName string) int {
 	return defaultToolOutputThresholdBytes
 }
 
+// truncateToolOutput keeps the head and tail of output allowed by limit,
+// cut on UTF-8 boundaries, around a marker. path names the saved full
+// output, if any. Without a head or tail and without a path the first
+// MaxBytes are kept so the output is never dropped entirely.
+func truncateToolOutput(output string, limit OutputLimit, path string) string {
+	head, tail := max(limit.HeadBytes, 0), max(limit.TailBytes, 0)
+	if head == 0 && tail == 0 {
+		if path != "" {
+			return formatToolOutputReference(path)
+		}
+		head = limit.MaxBytes
+	}
+	if head+tail >= len(output) {
+		return output
+	}
+	for head > 0 && !utf8.RuneStart(output[head]) {
+		head--
+	}
+	from := len(output) - tail
+	for from < len(output) && !utf8.RuneStart(output[from]) {
+		from++
+	}
+	headPart, tailPart := output[:head], output[from:]
+	omitted := len(output) - len(headPart) - len(tailPart)
+
+	var b strings.Builder
+	b.Grow(len(headPart) + len(tailPart) + 256)
+	b.WriteString(headPart)
+	if headPart != "" && !strings.HasSuffix(headPart, "\n") {
+		b.WriteByte('\n')
+	}
+	if path != "" {
+		fmt.Fprintf(&b, "[... %d of %d bytes truncated. Full output saved to: %s — use the Read tool with offset/limit to see the rest ...]", omitted, len(output), path)
+	} else {
+		fmt.Fprintf(&b, "[... %d of %d bytes truncated ...]", omitted, len(output))
+	}
+	if tailPart != "" {
+		b.WriteByte('\n')
+		b.WriteString(tailPart)
+	}
+	return b.String()
+}
+
 func createToolOutputFile(dir string) (*os.File, string, error) {
 	if strings.TrimSpace(dir) == "" {
 		return nil, "", errors.New("output directory is empty")
@@ -122,7 +229,7 @@ func createToolOutputFile(dir string) (*os.File, string, error) {
 }
 
 func formatToolOutputReference(path string) string {
-	return fmt.Sprintf("[Output saved to: %s]", path)
+	return fmt.Sprintf("[Output saved to: %s — use the Read tool to view it]", path)
 }
 
 func sanitizePathComponent(value string) string {
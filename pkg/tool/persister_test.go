@@ -1,6 +1,7 @@
 package tool
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -52,3 +53,70 @@ func TestOutputPersisterErrors(t *testing.T) {
 		t.Fatalf("expected empty base dir error")
 	}
 }
+
+func TestOutputPersisterKeepsHeadAndTail(t *testing.T) {
+	t.Parallel()
+
+	p := &OutputPersister{BaseDir: t.TempDir(), DefaultThresholdBytes: 10, HeadBytes: 4, TailBytes: 3}
+	output := "head-" + strings.Repeat("m", 20) + "-end"
+	res := &ToolResult{Output: output}
+	if err := p.MaybePersist(Call{Name: "Tool", SessionID: "sess"}, res); err != nil {
+		t.Fatalf("persist failed: %v", err)
+	}
+	if res.OutputRef == nil || res.OutputRef.Truncated || res.OutputRef.SizeBytes != int64(len(output)) {
+		t.Fatalf("unexpected output ref %+v", res.OutputRef)
+	}
+	if !strings.HasPrefix(res.Output, "head\n") || !strings.HasSuffix(res.Output, "\nend") {
+		t.Fatalf("expected head and tail kept, got %q", res.Output)
+	}
+	if !strings.Contains(res.Output, "22 of 29 bytes truncated") || !strings.Contains(res.Output, res.OutputRef.Path) || !strings.Contains(res.Output, "Read tool") {
+		t.Fatalf("expected marker with path, got %q", res.Output)
+	}
+	data, err := os.ReadFile(res.OutputRef.Path)
+	if err != nil || string(data) != output {
+		t.Fatalf("expected full output on disk, got %q err=%v", data, err)
+	}
+}
+
+func TestOutputPersisterNoSpillAndPerToolLimits(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	p := &OutputPersister{
+		BaseDir:               dir,
+		DefaultThresholdBytes: 100,
+		PerToolLimits: map[string]OutputLimit{
+			"grep": {MaxBytes: 8, HeadBytes: 6, NoSpill: true},
+			"bash": {MaxBytes: 8, NoSpill: true},
+		},
+	}
+	res := &ToolResult{Output: "héllo wörld and more"}
+	if err := p.MaybePersist(Call{Name: "Grep", SessionID: "sess"}, res); err != nil {
+		t.Fatalf("persist failed: %v", err)
+	}
+	if !strings.HasPrefix(res.Output, "héllo\n") || !strings.Contains(res.Output, "bytes truncated ...]") {
+		t.Fatalf("unexpected truncated output %q", res.Output)
+	}
+	if res.OutputRef == nil || !res.OutputRef.Truncated || res.OutputRef.Path != "" {
+		t.Fatalf("expected truncated ref without path, got %+v", res.OutputRef)
+	}
+
+	// Without head or tail, NoSpill keeps the first MaxBytes on a rune boundary.
+	res = &ToolResult{Output: "abcdefgé and more"}
+	if err := p.MaybePersist(Call{Name: "bash", SessionID: "sess"}, res); err != nil {
+		t.Fatalf("persist failed: %v", err)
+	}
+	if !strings.HasPrefix(res.Output, "abcdefg\n[...") {
+		t.Fatalf("expected rune-safe head, got %q", res.Output)
+	}
+
+	// Other tools use the defaults and stay inline below the threshold.
+	res = &ToolResult{Output: "short"}
+	if err := p.MaybePersist(Call{Name: "Read", SessionID: "sess"}, res); err != nil || res.Output != "short" || res.OutputRef != nil {
+		t.Fatalf("unexpected result %+v err=%v", res, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Fatalf("expected nothing spilled, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,188 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Policy overrides how the executor runs one tool. The zero value keeps the
+// executor defaults.
+type Policy struct {
+	// Timeout bounds each attempt. An attempt still running when it elapses
+	// fails with ErrorTimeout, even if the tool ignores its context. Zero
+	// means no limit.
+	Timeout time.Duration
+	// MaxRetries, when set, replaces the executor's RetryPolicy.MaxRetries
+	// for this tool and retries it even if it is not an IdempotentTool, so
+	// only set it for tools that are safe to run again.
+	MaxRetries *int
+	// RetryOn lists regular expressions matched against the error message
+	// and stderr of a failed attempt. A match makes the failure retryable in
+	// addition to the failures the RetryPolicy already retries.
+	RetryOn []string
+}
+
+// toolPolicy is a Policy with its patterns compiled.
+type toolPolicy struct {
+	Policy
+	retryOn []*regexp.Regexp
+}
+
+func compilePolicy(p Policy) (*toolPolicy, error) {
+	if p.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must be >= 0, got %s", p.Timeout)
+	}
+	if p.MaxRetries != nil && *p.MaxRetries < 0 {
+		return nil, fmt.Errorf("max retries must be >= 0, got %d", *p.MaxRetries)
+	}
+	compiled := &toolPolicy{Policy: p}
+	if p.MaxRetries != nil {
+		n := *p.MaxRetries
+		compiled.MaxRetries = &n
+	}
+	compiled.RetryOn = append([]string(nil), p.RetryOn...)
+	for _, pattern := range p.RetryOn {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("retry pattern %q: %w", pattern, err)
+		}
+		compiled.retryOn = append(compiled.retryOn, re)
+	}
+	return compiled, nil
+}
+
+// SetPolicy sets the execution policy of the named tool, matched
+// case-insensitively. Policies are kept by name, so they may be set before
+// the tool is registered and survive Unregister. A zero Policy removes it.
+func (r *Registry) SetPolicy(name string, p Policy) error {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		return errors.New("tool name is empty")
+	}
+	compiled, err := compilePolicy(p)
+	if err != nil {
+		return fmt.Errorf("tool %s policy: %w", name, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p.Timeout == 0 && p.MaxRetries == nil && len(p.RetryOn) == 0 {
+		delete(r.policies, key)
+		return nil
+	}
+	if r.policies == nil {
+		r.policies = map[string]*toolPolicy{}
+	}
+	r.policies[key] = compiled
+	return nil
+}
+
+// RegisterWithPolicy registers tool and sets its policy; neither happens
+// when either is invalid.
+func (r *Registry) RegisterWithPolicy(tool Tool, p Policy) error {
+	if tool == nil {
+		return fmt.Errorf("tool is nil")
+	}
+	if _, err := compilePolicy(p); err != nil {
+		return fmt.Errorf("tool %s policy: %w", tool.Name(), err)
+	}
+	if err := r.Register(tool); err != nil {
+		return err
+	}
+	return r.SetPolicy(tool.Name(), p)
+}
+
+// Policy returns the execution policy set for the named tool.
+func (r *Registry) Policy(name string) (Policy, bool) {
+	p := r.policyFor(name)
+	if p == nil {
+		return Policy{}, false
+	}
+	out := p.Policy
+	if p.MaxRetries != nil {
+		n := *p.MaxRetries
+		out.MaxRetries = &n
+	}
+	out.RetryOn = append([]string(nil), p.RetryOn...)
+	return out, true
+}
+
+func (r *Registry) policyFor(name string) *toolPolicy {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.policies[strings.ToLower(strings.TrimSpace(name))]
+}
+
+// retryPolicy returns base adjusted by p and whether the tool may be
+// retried at all.
+func (p *toolPolicy) retryPolicy(base RetryPolicy, idempotent bool) (RetryPolicy, bool) {
+	if p == nil {
+		return base, idempotent
+	}
+	if p.MaxRetries != nil {
+		base.MaxRetries = *p.MaxRetries
+		idempotent = true
+	}
+	if len(p.retryOn) > 0 {
+		retryable := base.Retryable
+		if retryable == nil {
+			retryable = func(te *ToolError) bool { return te.Retryable }
+		}
+		base.Retryable = func(te *ToolError) bool {
+			if retryable(te) {
+				return true
+			}
+			for _, re := range p.retryOn {
+				if re.MatchString(te.Error()) || te.Stderr != "" && re.MatchString(te.Stderr) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return base, idempotent
+}
+
+// withTimeout bounds fn by the policy timeout. fn keeps running in the
+// background if it ignores cancellation; its result is then discarded.
+func (p *toolPolicy) withTimeout(name string, fn func(context.Context) (*ToolResult, error)) func(context.Context) (*ToolResult, error) {
+	if p == nil || p.Timeout <= 0 {
+		return fn
+	}
+	timeout := p.Timeout
+	return func(ctx context.Context) (*ToolResult, error) {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		type outcome struct {
+			res *ToolResult
+			err error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			res, err := fn(attemptCtx)
+			done <- outcome{res, err}
+		}()
+		select {
+		case out := <-done:
+			if out.err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+				out.err = timeoutError(name, timeout)
+			}
+			return out.res, out.err
+		case <-attemptCtx.Done():
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, timeoutError(name, timeout)
+		}
+	}
+}
+
+func timeoutError(name string, timeout time.Duration) error {
+	return NewToolError(ErrorTimeout, fmt.Errorf("tool %s timed out after %s: %w", name, timeout, context.DeadlineExceeded))
+}
@@ -0,0 +1,145 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type blockingTool struct {
+	name    string
+	release chan struct{}
+}
+
+func (b *blockingTool) Name() string        { return b.name }
+func (b *blockingTool) Description() string { return "ignores cancellation" }
+func (b *blockingTool) Schema() *JSONSchema { return nil }
+func (b *blockingTool) Execute(context.Context, map[string]interface{}) (*ToolResult, error) {
+	<-b.release
+	return &ToolResult{Success: true}, nil
+}
+
+func TestExecutorPolicyTimeout(t *testing.T) {
+	reg := NewRegistry()
+	slow := &blockingTool{name: "slow", release: make(chan struct{})}
+	defer close(slow.release)
+	if err := reg.RegisterWithPolicy(slow, Policy{Timeout: 20 * time.Millisecond}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	start := time.Now()
+	cr, err := NewExecutor(reg, nil).Execute(context.Background(), Call{Name: "slow"})
+	if time.Since(start) > time.Second {
+		t.Fatalf("timeout not enforced")
+	}
+	te := ClassifyError(err)
+	if te == nil || te.Category != ErrorTimeout || !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out after 20ms") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if cr == nil || cr.Attempts != 1 {
+		t.Fatalf("unexpected call result %+v", cr)
+	}
+}
+
+func TestExecutorPolicyRetries(t *testing.T) {
+	noWait := func(int) time.Duration { return 0 }
+	two, zero := 2, 0
+	cases := []struct {
+		name      string
+		tool      *flakyTool
+		policy    Policy
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "max retries applies to non-idempotent tools",
+			tool:      &flakyTool{name: "deploy", failures: 2, err: NewToolError(ErrorUnavailable, errors.New("registry unreachable"))},
+			policy:    Policy{MaxRetries: &two},
+			wantCalls: 3,
+		},
+		{
+			name:      "retry on matches otherwise permanent failures",
+			tool:      &flakyTool{name: "fetch", idempotent: true, failures: 1, err: errors.New("HTTP 429 Too Many Requests")},
+			policy:    Policy{RetryOn: []string{`\b429\b`}},
+			wantCalls: 2,
+		},
+		{
+			name:      "unmatched failures are not retried",
+			tool:      &flakyTool{name: "fetch", idempotent: true, failures: 1, err: errors.New("HTTP 404")},
+			policy:    Policy{RetryOn: []string{`\b429\b`}},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "zero max retries disables retries",
+			tool:      &flakyTool{name: "lookup", idempotent: true, failures: 1, err: NewToolError(ErrorTimeout, errors.New("slow"))},
+			policy:    Policy{MaxRetries: &zero},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := NewRegistry()
+			if err := reg.RegisterWithPolicy(tc.tool, tc.policy); err != nil {
+				t.Fatalf("register: %v", err)
+			}
+			exec := NewExecutor(reg, nil).WithRetryPolicy(RetryPolicy{MaxRetries: 1, Backoff: noWait})
+			cr, err := exec.Execute(context.Background(), Call{Name: tc.tool.name})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.tool.calls != tc.wantCalls || cr.Attempts != tc.wantCalls {
+				t.Fatalf("calls=%d attempts=%d, want %d", tc.tool.calls, cr.Attempts, tc.wantCalls)
+			}
+			if len(cr.AttemptErrors) != tc.wantCalls-1 {
+				t.Fatalf("attempt errors = %v", cr.AttemptErrors)
+			}
+			for _, failure := range cr.AttemptErrors {
+				if !errors.Is(failure, tc.tool.err) {
+					t.Fatalf("unexpected attempt error %v", failure)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistryPolicies(t *testing.T) {
+	reg := NewRegistry()
+	three := 3
+	if err := reg.SetPolicy("Bash", Policy{Timeout: time.Second, MaxRetries: &three, RetryOn: []string{"reset"}}); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+	got, ok := reg.Policy("bash")
+	if !ok || got.Timeout != time.Second || *got.MaxRetries != 3 || len(got.RetryOn) != 1 {
+		t.Fatalf("unexpected policy %+v ok=%v", got, ok)
+	}
+	*got.MaxRetries = 9
+	if again, _ := reg.Policy("BASH"); *again.MaxRetries != 3 {
+		t.Fatalf("policy aliased caller memory")
+	}
+
+	if err := reg.SetPolicy("bash", Policy{}); err != nil {
+		t.Fatalf("clear policy: %v", err)
+	}
+	if _, ok := reg.Policy("bash"); ok {
+		t.Fatalf("expected zero policy to clear")
+	}
+
+	for _, bad := range []Policy{{Timeout: -time.Second}, {RetryOn: []string{"("}}} {
+		if err := reg.SetPolicy("bash", bad); err == nil {
+			t.Fatalf("expected %+v to be rejected", bad)
+		}
+	}
+	if err := reg.SetPolicy(" ", Policy{Timeout: time.Second}); err == nil {
+		t.Fatalf("expected empty name to be rejected")
+	}
+	if err := reg.RegisterWithPolicy(&spyTool{name: "spy"}, Policy{RetryOn: []string{"["}}); err == nil {
+		t.Fatalf("expected invalid policy to be rejected")
+	}
+	if _, err := reg.Get("spy"); err == nil {
+		t.Fatalf("tool registered despite invalid policy")
+	}
+}
@@ -21,7 +21,11 @@ type Registry struct {
 	mu          sync.RWMutex
 	tools       map[string]Tool
 	mcpSessions []*mcpSessionInfo
+	mcpLazy     []*lazyMCPServer
+	healthStop  chan struct{}
 	validator   Validator
+	// policies holds execution policies by lower-case tool name.
+	policies map[string]*toolPolicy
 }
 
 type mcpListChangedHandler = func(context.Context, *mcp.ClientSession)
@@ -36,6 +40,14 @@ type MCPServerOptions struct {
 	Headers map[string]string
 	Env     map[string]string
 	Timeout time.Duration
+	// Command and Args start a stdio server directly instead of splitting
+	// the spec on whitespace, so arguments may contain spaces.
+	Command string
+	Args    []string
+	// MaxRestarts caps how often a crashed stdio server is restarted in a
+	// row; 0 uses DefaultMCPMaxRestarts and a negative value disables
+	// restarts. The count resets once a server stays up for a minute.
+	MaxRestarts int
 }
 
 var newMCPClientWithOptions = func(ctx context.Context, spec string, opts MCPServerOptions, handler mcpListChangedHandler) (*mcp.ClientSession, error) {
@@ -71,6 +83,25 @@ func (r *Registry) Register(tool Tool) error {
 	return nil
 }
 
+// Unregister removes a tool and reports whether it was registered. Calls
+// already running keep their tool; later lookups fail. A tool discovered
+// from an MCP server returns when the server next changes its tool list.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tools[name]; !exists {
+		return false
+	}
+	delete(r.tools, name)
+	for _, info := range r.mcpSessions {
+		if info != nil {
+			delete(info.toolNames, name)
+		}
+	}
+	return true
+}
+
 // Get fetches a tool by name.
 func (r *Registry) Get(name string) (Tool, error) {
 	r.mu.RLock()
@@ -103,29 +134,46 @@ func (r *Registry) SetValidator(v Validator) {
 }
 
 // Execute runs a registered tool after optional schema validation.
-
+// Params that fail validation yield an ErrorInvalidInput *ToolError.
 func (r *Registry) Execute(ctx context.Context, name string, params map[string]interface{}) (_ *ToolResult, err error) {
 	tool, err := r.Get(name)
 	if err != nil {
 		return nil, err
 	}
-
-	if schema := tool.Schema(); schema != nil {
-		r.mu.RLock()
-		validator := r.validator
-		r.mu.RUnlock()
-
-		if validator != nil {
-			if err := validator.Validate(params, schema); err != nil {
-				return nil, fmt.Errorf("tool %s validation failed: %w", name, err)
-			}
-		}
+	if err := r.validate(tool, params); err != nil {
+		return nil, err
 	}
 
 	result, execErr := tool.Execute(ctx, params)
 	return result, execErr
 }
 
+// validate checks params against the tool's schema with the configured
+// validator. Violations reported as *ValidationError are copied onto the
+// returned *ToolError so they reach the model.
+func (r *Registry) validate(tool Tool, params map[string]interface{}) error {
+	schema := tool.Schema()
+	if schema == nil {
+		return nil
+	}
+	r.mu.RLock()
+	validator := r.validator
+	r.mu.RUnlock()
+	if validator == nil {
+		return nil
+	}
+	err := validator.Validate(params, schema)
+	if err == nil {
+		return nil
+	}
+	te := NewToolError(ErrorInvalidInput, fmt.Errorf("tool %s validation failed: %w", tool.Name(), err))
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		te.Violations = append([]Violation(nil), verr.Violations...)
+	}
+	return te
+}
+
 // RegisterMCPServer discovers tools exposed by an MCP server and registers them.
 // serverPath accepts either an http(s) URL (SSE transport) or a stdio command.
 func (r *Registry) RegisterMCPServer(ctx context.Context, serverPath, serverName string) error {
@@ -185,6 +233,11 @@ func (r *Registry) RegisterMCPServer(ctx context.Context, serverPath, serverName
 	if err := r.registerMCPSession(serverPath, serverName, session, wrappers, names); err != nil {
 		return err
 	}
+	if isStdioMCPServer(serverPath, MCPServerOptions{}) {
+		r.superviseMCPSession(serverPath, session, DefaultMCPMaxRestarts, func(ctx context.Context) (*mcp.ClientSession, error) {
+			return newMCPClient(ctx, serverPath, r.mcpToolsChangedHandler(serverPath))
+		})
+	}
 
 	success = true
 	return nil
@@ -253,19 +306,31 @@ func (r *Registry) RegisterMCPServerWithOptions(ctx context.Context, serverPath,
 	if err := r.registerMCPSession(serverPath, serverName, session, wrappers, names); err != nil {
 		return err
 	}
+	if isStdioMCPServer(serverPath, opts) {
+		r.superviseMCPSession(serverPath, session, opts.MaxRestarts, func(ctx context.Context) (*mcp.ClientSession, error) {
+			return newMCPClientWithOptions(ctx, serverPath, opts, r.mcpToolsChangedHandler(serverPath))
+		})
+	}
 
 	success = true
 	return nil
 }
 
-// Close terminates all tracked MCP sessions.
+// Close terminates all tracked MCP sessions and stops health checks.
 // Errors are logged and ignored to avoid masking shutdown flows.
 func (r *Registry) Close() {
 	r.mu.Lock()
 	sessions := r.mcpSessions
 	r.mcpSessions = nil
+	r.mcpLazy = nil
+	stop := r.healthStop
+	r.healthStop = nil
 	r.mu.Unlock()
 
+	if stop != nil {
+		close(stop)
+	}
+
 	for _, info := range sessions {
 		if info == nil || info.session == nil {
 			continue
@@ -276,8 +341,70 @@ func (r *Registry) Close() {
 	}
 }
 
+// MCPServerStatus reports an MCP server and, when it is connected, the
+// outcome of a ping.
+type MCPServerStatus struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name,omitempty"`
+	SessionID string   `json:"session_id,omitempty"`
+	Tools     []string `json:"tools"`
+	Healthy   bool     `json:"healthy"`
+	// State is one of the MCPState constants.
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+	// Restarts counts the times a crashed stdio server was restarted.
+	Restarts int `json:"restarts,omitempty"`
+}
+
+// MCPStatus pings every tracked MCP session and reports its state, followed
+// by the lazily registered servers that are pending or failed. ctx bounds
+// the pings.
+func (r *Registry) MCPStatus(ctx context.Context) []MCPServerStatus {
+	r.mu.RLock()
+	out := make([]MCPServerStatus, 0, len(r.mcpSessions)+len(r.mcpLazy))
+	sessions := make([]*mcp.ClientSession, 0, len(r.mcpSessions))
+	for _, info := range r.mcpSessions {
+		if info == nil {
+			continue
+		}
+		status := MCPServerStatus{ID: info.serverID, Name: info.serverName, SessionID: info.sessionID, Restarts: info.restarts}
+		for name := range info.toolNames {
+			status.Tools = append(status.Tools, name)
+		}
+		sort.Strings(status.Tools)
+		out = append(out, status)
+		sessions = append(sessions, info.session)
+	}
+	lazy := r.lazyMCPStatusLocked()
+	r.mu.RUnlock()
+
+	for i, session := range sessions {
+		out[i].State = MCPStateDegraded
+		switch {
+		case session == nil:
+			out[i].Error = "session closed"
+		default:
+			if err := session.Ping(ctx, nil); err != nil {
+				out[i].Error = err.Error()
+			} else {
+				out[i].Healthy = true
+				out[i].State = MCPStateConnected
+			}
+		}
+	}
+	return append(out, lazy...)
+}
+
 func connectMCPClientWithOptions(ctx context.Context, spec string, opts MCPServerOptions, handler mcpListChangedHandler) (*mcp.ClientSession, error) {
-	transport, err := buildMCPTransport(ctx, spec)
+	var (
+		transport mcp.Transport
+		err       error
+	)
+	if strings.TrimSpace(opts.Command) != "" {
+		transport, err = mcp.NewStdioTransport(opts.Command, opts.Args, nil)
+	} else {
+		transport, err = buildMCPTransport(ctx, spec)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -323,6 +450,7 @@ type mcpSessionInfo struct {
 	sessionID  string
 	session    *mcp.ClientSession
 	toolNames  map[string]struct{}
+	restarts   int
 }
 
 func (r *Registry) registerMCPSession(serverID, serverName string, session *mcp.ClientSession, wrappers []Tool, names []string) error {
@@ -380,6 +508,7 @@ func buildRemoteToolWrappers(session *mcp.ClientSession, serverName string, tool
 			description: desc.Description,
 			schema:      schema,
 			session:     session,
+			idempotent:  desc.Annotations != nil && (desc.Annotations.IdempotentHint || desc.Annotations.ReadOnlyHint),
 		})
 		names = append(names, toolName)
 	}
@@ -543,6 +672,11 @@ func applyMCPTransportOptions(transport mcp.Transport, opts MCPServerOptions) er
 			return errors.New("mcp stdio transport missing command")
 		}
 		impl.Command.Env = mergeEnv(impl.Command.Env, opts.Env)
+	case *mcp.SSETransport:
+		if len(opts.Headers) == 0 {
+			return nil
+		}
+		impl.HTTPClient = withInjectedHeaders(impl.HTTPClient, opts.Headers)
 	case *mcp.SSEClientTransport:
 		if len(opts.Headers) == 0 {
 			return nil
@@ -738,12 +872,16 @@ type remoteTool struct {
 	description string
 	schema      *JSONSchema
 	session     *mcp.ClientSession
+	idempotent  bool
 }
 
 func (r *remoteTool) Name() string        { return r.name }
 func (r *remoteTool) Description() string { return r.description }
 func (r *remoteTool) Schema() *JSONSchema { return r.schema }
 
+// Idempotent follows the server's idempotentHint/readOnlyHint annotations.
+func (r *remoteTool) Idempotent() bool { return r.idempotent }
+
 func (r *remoteTool) Execute(ctx context.Context, params map[string]interface{}) (*ToolResult, error) {
 	if r.session == nil {
 		return nil, fmt.Errorf("mcp session is nil")
@@ -766,7 +904,8 @@ func (r *remoteTool) Execute(ctx context.Context, params map[string]interface{})
 		return nil, fmt.Errorf("MCP call returned nil result")
 	}
 	output := firstTextContent(res.Content)
-	if output == "" {
+	media := mcpMedia(res.Content)
+	if output == "" && len(media) == 0 && res.StructuredContent == nil {
 		if payload, err := json.Marshal(res.Content); err == nil {
 			output = string(payload)
 		}
@@ -775,9 +914,23 @@ func (r *remoteTool) Execute(ctx context.Context, params map[string]interface{})
 		Success: true,
 		Output:  output,
 		Data:    res.Content,
+		Content: res.StructuredContent,
+		Media:   media,
 	}, nil
 }
 
+// mcpMedia extracts the images in an MCP result so they reach the model
+// as media rather than as JSON text.
+func mcpMedia(content []mcp.Content) []Media {
+	var media []Media
+	for _, part := range content {
+		if img, ok := part.(*mcp.ImageContent); ok && len(img.Data) > 0 {
+			media = append(media, Media{Kind: MediaImage, MediaType: img.MIMEType, Data: img.Data})
+		}
+	}
+	return media
+}
+
 func firstTextContent(content []mcp.Content) string {
 	for _, part := range content {
 		if txt, ok := part.(*mcp.TextContent); ok {
@@ -228,11 +228,8 @@ func TestRegisterMCPServerSuccessAddsClient(t *testing.T) {
 	if server.Closed() {
 		t.Fatalf("session should remain open after success")
 	}
-	for _, info := range r.mcpSessions {
-		if info != nil && info.session != nil {
-			_ = info.session.Close()
-		}
-	}
+	// Close through the registry so the stdio supervisor does not restart it.
+	r.Close()
 }
 
 func TestRegisterMCPServerNamespacesRemoteTools(t *testing.T) {
@@ -268,6 +265,39 @@ func TestRegisterMCPServerNamespacesRemoteTools(t *testing.T) {
 	}
 }
 
+func TestRemoteToolMapsImagesAndStructuredContent(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G'}
+	server := &stubMCPServer{tools: []*mcp.Tool{{Name: "shot", Description: "screenshot", InputSchema: map[string]any{"type": "object"}}}}
+	server.callFn = func(context.Context, *mcp.CallToolParams) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{
+			Content:           []mcp.Content{&mcp.ImageContent{Data: png, MIMEType: "image/png"}},
+			StructuredContent: map[string]any{"width": 640},
+		}, nil
+	}
+	restore := withStubMCPClient(t, sessionFactory(server))
+	defer restore()
+
+	r := NewRegistry()
+	if err := r.RegisterMCPServer(context.Background(), "fake", ""); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	t.Cleanup(r.Close)
+
+	res, err := r.Execute(context.Background(), "shot", nil)
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if res.Output != "" {
+		t.Fatalf("expected image to stay out of the text output, got %q", res.Output)
+	}
+	if len(res.Media) != 1 || res.Media[0].Kind != MediaImage || res.Media[0].MediaType != "image/png" || string(res.Media[0].Data) != string(png) {
+		t.Fatalf("unexpected media %+v", res.Media)
+	}
+	if content, ok := res.Content.(map[string]any); !ok || content["width"] != float64(640) {
+		t.Fatalf("unexpected structured content %#v", res.Content)
+	}
+}
+
 func TestRegistryCloseClosesSessions(t *testing.T) {
 	server := &stubMCPServer{tools: []*mcp.Tool{{Name: "echo", Description: "remote", InputSchema: map[string]any{"type": "object"}}}}
 	restore := withStubMCPClient(t, sessionFactory(server))
@@ -498,6 +528,7 @@ type stubMCPServer struct {
 	tools         []*mcp.Tool
 	listErr       error
 	initializeErr error
+	pingErr       error
 	callFn        func(context.Context, *mcp.CallToolParams) (*mcp.CallToolResult, error)
 
 	mu     sync.Mutex
@@ -623,6 +654,11 @@ func (s *stubMCPServer) handleCall(req *jsonrpc.Request) jsonrpc.Message {
 			Capabilities:    &mcp.ServerCapabilities{},
 		}
 		return toResponse(req.ID, result, nil)
+	case "ping":
+		if s.pingErr != nil {
+			return toResponse(req.ID, nil, s.pingErr)
+		}
+		return toResponse(req.ID, map[string]any{}, nil)
 	case "tools/list":
 		if s.listErr != nil {
 			return toResponse(req.ID, nil, s.listErr)
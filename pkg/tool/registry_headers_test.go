@@ -88,6 +88,14 @@ func TestApplyMCPTransportOptions(t *testing.T) {
 	if sse.HTTPClient == nil || sse.HTTPClient.Transport == nil {
 		t.Fatalf("expected injected headers")
 	}
+
+	resumable := mcp.NewSSETransport("http://example.com/sse", nil)
+	if err := applyMCPTransportOptions(resumable, MCPServerOptions{Headers: map[string]string{"X-Test": "1"}}); err != nil {
+		t.Fatalf("apply headers failed: %v", err)
+	}
+	if resumable.HTTPClient == nil || resumable.HTTPClient.Transport == nil {
+		t.Fatalf("expected injected headers on resumable transport")
+	}
 }
 
 func TestWithInjectedHeaders(t *testing.T) {
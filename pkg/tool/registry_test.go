@@ -74,6 +74,22 @@ func TestRegistryRegister(t *testing.T) {
 	}
 }
 
+func TestRegistryUnregister(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(&spyTool{name: "echo"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if !r.Unregister("echo") || r.Unregister("echo") {
+		t.Fatal("Unregister should report the tool once")
+	}
+	if _, err := r.Get("echo"); err == nil {
+		t.Fatal("expected lookup to fail after Unregister")
+	}
+	if err := r.Register(&spyTool{name: "echo"}); err != nil {
+		t.Fatalf("name should be free again: %v", err)
+	}
+}
+
 func TestRegistryExecute(t *testing.T) {
 	ctx := context.Background()
 	tests := []struct {
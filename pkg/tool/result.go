@@ -8,11 +8,52 @@ type OutputRef struct {
 	Truncated bool   `json:"truncated,omitempty"`
 }
 
+// Artifact is a named file emitted by a tool. Tools set Data; when the
+// executor has an artifact store the payload is stored, Data is cleared and
+// ID/SizeBytes reference the stored blob.
+type Artifact struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	MediaType string `json:"media_type,omitempty"`
+	SizeBytes int64  `json:"size_bytes,omitempty"`
+	Data      []byte `json:"-"`
+}
+
+// MediaKind discriminates the kind of a Media block.
+type MediaKind string
+
+const (
+	MediaImage    MediaKind = "image"
+	MediaDocument MediaKind = "document"
+)
+
+// Media is an image or file returned to the model alongside the text
+// output. Set either Data (raw bytes) or URL. Providers that accept
+// multi-part tool results (Anthropic) pass it through; others describe it
+// in the text instead.
+type Media struct {
+	Kind      MediaKind `json:"kind"`
+	MediaType string    `json:"media_type,omitempty"` // e.g. "image/png", "application/pdf"
+	Data      []byte    `json:"data,omitempty"`
+	URL       string    `json:"url,omitempty"`
+}
+
 // ToolResult captures the outcome of a tool invocation.
 type ToolResult struct {
 	Success   bool
 	Output    string
 	OutputRef *OutputRef
+	Artifacts []Artifact
 	Data      interface{}
-	Error     error
+	// Content is a JSON-serializable structured result. When Output is
+	// empty the model receives it encoded as JSON.
+	Content interface{}
+	// Media holds images and files sent to the model with the output.
+	Media []Media
+	Error error
+	// IsError marks a failed call. The executor sets it, together with
+	// ErrorDetail, whenever the tool returns an error.
+	IsError bool
+	// ErrorDetail describes the failure; see ClassifyError.
+	ErrorDetail *ToolError
 }
@@ -0,0 +1,90 @@
+package tool
+
+import (
+	"context"
+	"time"
+
+	"github.com/cexll/agentsdk-go/pkg/core/retry"
+)
+
+// IdempotentTool is implemented by tools that can safely run again with the
+// same parameters. Only idempotent tools are retried automatically.
+type IdempotentTool interface {
+	Tool
+	Idempotent() bool
+}
+
+// RetryPolicy controls automatic retries of transient tool failures.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero
+	// disables retries.
+	MaxRetries int
+	// Backoff returns the wait before retry n (1-based). Nil uses
+	// retry.Quadratic.
+	Backoff func(n int) time.Duration
+	// Retryable decides whether a failure is transient. Nil retries errors
+	// whose ToolError is Retryable (timeouts, unavailable services, lock
+	// conflicts).
+	Retryable func(*ToolError) bool
+}
+
+// DefaultRetryPolicy retries idempotent tools twice on transient failures.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxRetries: 2}
+}
+
+// WithRetryPolicy returns a shallow copy that retries transient failures of
+// idempotent tools according to policy.
+func (e *Executor) WithRetryPolicy(policy RetryPolicy) *Executor {
+	if e == nil {
+		exec := NewExecutor(nil, nil)
+		exec.retry = policy
+		return exec
+	}
+	clone := *e
+	clone.retry = policy
+	return &clone
+}
+
+// isIdempotent reports whether t declared itself safe to repeat.
+func isIdempotent(t Tool) bool {
+	it, ok := t.(IdempotentTool)
+	return ok && it.Idempotent()
+}
+
+// run calls fn until it succeeds, fails permanently or exhausts the policy.
+// It returns the last result, the number of attempts, the errors of the
+// attempts that were retried and the last error.
+func (p RetryPolicy) run(ctx context.Context, name string, fn func(context.Context) (*ToolResult, error)) (*ToolResult, int, []error, error) {
+	var (
+		res      *ToolResult
+		lastErr  error
+		attempts int
+		failures []error
+	)
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = func(te *ToolError) bool { return te.Retryable }
+	}
+	loop := retry.Loop{
+		Stage:      "tool " + name,
+		MaxRetries: p.MaxRetries,
+		Backoff:    p.Backoff,
+		Retryable: func(err error) bool {
+			return retryable(ClassifyError(err))
+		},
+	}
+	loopErr := loop.Do(ctx, func(attemptCtx context.Context) error {
+		if lastErr != nil {
+			failures = append(failures, lastErr)
+		}
+		attempts++
+		res, lastErr = fn(attemptCtx)
+		return lastErr
+	})
+	if loopErr != nil && lastErr == nil {
+		// The context ended between attempts.
+		lastErr = loopErr
+	}
+	return res, attempts, failures, lastErr
+}
@@ -0,0 +1,64 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type flakyTool struct {
+	name       string
+	idempotent bool
+	failures   int
+	err        error
+	calls      int
+}
+
+func (f *flakyTool) Name() string        { return f.name }
+func (f *flakyTool) Description() string { return "fails a few times" }
+func (f *flakyTool) Schema() *JSONSchema { return nil }
+func (f *flakyTool) Idempotent() bool    { return f.idempotent }
+func (f *flakyTool) Execute(context.Context, map[string]interface{}) (*ToolResult, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return &ToolResult{}, f.err
+	}
+	return &ToolResult{Success: true, Output: "ok"}, nil
+}
+
+func TestExecutorRetriesTransientFailures(t *testing.T) {
+	noWait := func(int) time.Duration { return 0 }
+	conflict := NewToolError(ErrorConflict, errors.New("database is locked"))
+	cases := []struct {
+		name         string
+		tool         *flakyTool
+		wantCalls    int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{"recovers", &flakyTool{name: "a", idempotent: true, failures: 2, err: conflict}, 3, false, 3},
+		{"exhausts", &flakyTool{name: "b", idempotent: true, failures: 5, err: conflict}, 3, true, 3},
+		{"not idempotent", &flakyTool{name: "c", failures: 1, err: conflict}, 1, true, 1},
+		{"permanent error", &flakyTool{name: "d", idempotent: true, failures: 1, err: errors.New("bad input")}, 1, true, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := NewRegistry()
+			if err := reg.Register(tc.tool); err != nil {
+				t.Fatalf("register: %v", err)
+			}
+			exec := NewExecutor(reg, nil).WithRetryPolicy(RetryPolicy{MaxRetries: 2, Backoff: noWait})
+			cr, err := exec.Execute(context.Background(), Call{Name: tc.tool.name})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.tool.calls != tc.wantCalls || cr.Attempts != tc.wantAttempts {
+				t.Fatalf("calls=%d attempts=%d, want %d/%d", tc.tool.calls, cr.Attempts, tc.wantCalls, tc.wantAttempts)
+			}
+			if tc.wantErr && tc.wantAttempts > 1 && cr.Result.ErrorDetail.Attempts != tc.wantAttempts {
+				t.Fatalf("error detail attempts = %d", cr.Result.ErrorDetail.Attempts)
+			}
+		})
+	}
+}
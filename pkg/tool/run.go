@@ -0,0 +1,29 @@
+package tool
+
+import (
+	"context"
+	"strings"
+)
+
+type runIDKey struct{}
+
+// WithRunID tags ctx with the ID of the run a tool call belongs to, so tools
+// can scope state to the run.
+func WithRunID(ctx context.Context, id string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// RunIDFromContext returns the run ID set by WithRunID.
+func RunIDFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(runIDKey{}).(string)
+	if !ok || strings.TrimSpace(id) == "" {
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,61 @@
+package tool
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// ScratchDirEnv names the iteration scratch directory in the environment
+	// of commands started by tools.
+	ScratchDirEnv = "AGENTSDK_SCRATCH_DIR"
+	// RunScratchDirEnv names the run scratch directory, shared by every
+	// iteration of the run.
+	RunScratchDirEnv = "AGENTSDK_RUN_SCRATCH_DIR"
+)
+
+// Scratch locates the managed scratch directories of the current call. Files
+// written there are removed when the run ends, so tools should keep
+// intermediate output there instead of /tmp or the project root.
+type Scratch struct {
+	// Run is shared by all iterations of the run.
+	Run string
+	// Iteration is private to the current model iteration and lives under
+	// Run.
+	Iteration string
+}
+
+type scratchKey struct{}
+
+// WithScratch attaches the scratch directories to ctx.
+func WithScratch(ctx context.Context, s Scratch) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, scratchKey{}, s)
+}
+
+// ScratchFromContext returns the scratch directories attached by the runtime.
+func ScratchFromContext(ctx context.Context) (Scratch, bool) {
+	if ctx == nil {
+		return Scratch{}, false
+	}
+	s, ok := ctx.Value(scratchKey{}).(Scratch)
+	if !ok || strings.TrimSpace(s.Iteration) == "" {
+		return Scratch{}, false
+	}
+	return s, true
+}
+
+// Env returns the environment entries that expose s to a subprocess. TMPDIR
+// points at the iteration directory so ordinary temp files land there too.
+func (s Scratch) Env() []string {
+	if strings.TrimSpace(s.Iteration) == "" {
+		return nil
+	}
+	return []string{
+		ScratchDirEnv + "=" + s.Iteration,
+		RunScratchDirEnv + "=" + s.Run,
+		"TMPDIR=" + s.Iteration,
+	}
+}
@@ -0,0 +1,167 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// Runner is implemented by params structs passed to FromStruct: the struct
+// is decoded from the call's arguments and then run.
+type Runner interface {
+	Run(ctx context.Context) (*ToolResult, error)
+}
+
+// FromFunc builds a Tool from a typed function. The JSON Schema of P, a
+// struct, is generated from its json tags (fields without omitempty or
+// omitzero are required) and jsonschema tags (property descriptions).
+// Each call is validated against that schema and decoded into a fresh P
+// before fn runs. fn's result becomes the ToolResult: a *ToolResult is
+// returned as is, a string becomes Output, and any other value is encoded
+// as JSON into Output and kept in Data.
+func FromFunc[P, R any](name, description string, fn func(ctx context.Context, params P) (R, error)) (Tool, error) {
+	if fn == nil {
+		return nil, errors.New("tool: FromFunc function is nil")
+	}
+	return newTypedTool(name, description, reflect.TypeFor[P](), func(ctx context.Context, v reflect.Value) (*ToolResult, error) {
+		out, err := fn(ctx, v.Interface().(P))
+		if err != nil {
+			return nil, err
+		}
+		return typedResult(out)
+	})
+}
+
+// FromStruct builds a Tool whose params are the Runner struct P itself; the
+// schema is generated as for FromFunc. P may be a struct or a pointer to
+// one, whichever implements Runner.
+func FromStruct[P Runner](name, description string) (Tool, error) {
+	typ := reflect.TypeFor[P]()
+	return newTypedTool(name, description, typ, func(ctx context.Context, v reflect.Value) (*ToolResult, error) {
+		return v.Interface().(P).Run(ctx)
+	})
+}
+
+// typedTool is the Tool returned by FromFunc and FromStruct.
+type typedTool struct {
+	name        string
+	description string
+	schema      *JSONSchema
+	resolved    *jsonschema.Resolved
+	params      reflect.Type // P; a pointer when the callback takes one
+	call        func(context.Context, reflect.Value) (*ToolResult, error)
+}
+
+func newTypedTool(name, description string, params reflect.Type, call func(context.Context, reflect.Value) (*ToolResult, error)) (*typedTool, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("tool: name is empty")
+	}
+	elem := params
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("tool %s: params must be a struct, got %s", name, params)
+	}
+	generated, err := jsonschema.ForType(elem, &jsonschema.ForOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: generate schema: %w", name, err)
+	}
+	resolved, err := generated.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: resolve schema: %w", name, err)
+	}
+	schema, err := toolSchema(generated)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", name, err)
+	}
+	return &typedTool{
+		name:        name,
+		description: strings.TrimSpace(description),
+		schema:      schema,
+		resolved:    resolved,
+		params:      params,
+		call:        call,
+	}, nil
+}
+
+func (t *typedTool) Name() string { return t.name }
+
+func (t *typedTool) Description() string { return t.description }
+
+func (t *typedTool) Schema() *JSONSchema { return t.schema }
+
+func (t *typedTool) Execute(ctx context.Context, params map[string]interface{}) (*ToolResult, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	// Round-trip through JSON so Go-typed callers (ints, structs) validate
+	// the same way as decoded model output.
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, NewToolError(ErrorInvalidInput, fmt.Errorf("encode params: %w", err))
+	}
+	var instance any
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return nil, NewToolError(ErrorInvalidInput, fmt.Errorf("decode params: %w", err))
+	}
+	if err := t.resolved.Validate(instance); err != nil {
+		return nil, NewToolError(ErrorInvalidInput, fmt.Errorf("invalid params: %w", err))
+	}
+	elem := t.params
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	value := reflect.New(elem)
+	if err := json.Unmarshal(data, value.Interface()); err != nil {
+		return nil, NewToolError(ErrorInvalidInput, fmt.Errorf("decode params: %w", err))
+	}
+	if t.params.Kind() != reflect.Pointer {
+		value = value.Elem()
+	}
+	return t.call(ctx, value)
+}
+
+// toolSchema converts a generated schema to the JSONSchema the registry
+// validates against and advertises to models. Nested schemas are kept as
+// plain maps under Properties.
+func toolSchema(s *jsonschema.Schema) (*JSONSchema, error) {
+	out := &JSONSchema{Type: "object", Properties: map[string]interface{}{}, Required: append([]string(nil), s.Required...)}
+	for name, prop := range s.Properties {
+		data, err := json.Marshal(prop)
+		if err != nil {
+			return nil, fmt.Errorf("encode schema of %s: %w", name, err)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("decode schema of %s: %w", name, err)
+		}
+		out.Properties[name] = m
+	}
+	return out, nil
+}
+
+func typedResult(out any) (*ToolResult, error) {
+	switch v := out.(type) {
+	case *ToolResult:
+		if v == nil {
+			return &ToolResult{Success: true}, nil
+		}
+		return v, nil
+	case string:
+		return &ToolResult{Success: true, Output: v}, nil
+	case nil:
+		return &ToolResult{Success: true}, nil
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("encode result: %w", err)
+	}
+	return &ToolResult{Success: true, Output: string(data), Data: out}, nil
+}
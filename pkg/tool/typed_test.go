@@ -0,0 +1,173 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type weatherParams struct {
+	City  string `json:"city" jsonschema:"city to look up"`
+	Days  int    `json:"days,omitempty" jsonschema:"forecast length in days"`
+	Units string `json:"units,omitempty"`
+}
+
+type weatherReport struct {
+	City string `json:"city"`
+	Days int    `json:"days"`
+}
+
+func TestFromFuncSchemaAndExecute(t *testing.T) {
+	tl, err := FromFunc("weather", " forecast ", func(ctx context.Context, p weatherParams) (weatherReport, error) {
+		return weatherReport{City: p.City, Days: p.Days}, nil
+	})
+	if err != nil {
+		t.Fatalf("FromFunc: %v", err)
+	}
+	if tl.Name() != "weather" || tl.Description() != "forecast" {
+		t.Fatalf("unexpected name/description %q %q", tl.Name(), tl.Description())
+	}
+	schema := tl.Schema()
+	if schema.Type != "object" || len(schema.Required) != 1 || schema.Required[0] != "city" {
+		t.Fatalf("unexpected schema %+v", schema)
+	}
+	city, ok := schema.Properties["city"].(map[string]interface{})
+	if !ok || city["type"] != "string" || city["description"] != "city to look up" {
+		t.Fatalf("unexpected city property %#v", schema.Properties["city"])
+	}
+	if days, _ := schema.Properties["days"].(map[string]interface{}); days["type"] != "integer" {
+		t.Fatalf("unexpected days property %#v", schema.Properties["days"])
+	}
+
+	res, err := tl.Execute(context.Background(), map[string]interface{}{"city": "Oslo", "days": 3})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !res.Success || res.Output != `{"city":"Oslo","days":3}` {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if report, ok := res.Data.(weatherReport); !ok || report.Days != 3 {
+		t.Fatalf("unexpected data %#v", res.Data)
+	}
+}
+
+func TestFromFuncValidatesParams(t *testing.T) {
+	called := false
+	tl, err := FromFunc("weather", "", func(ctx context.Context, p weatherParams) (string, error) {
+		called = true
+		return p.City, nil
+	})
+	if err != nil {
+		t.Fatalf("FromFunc: %v", err)
+	}
+	for name, params := range map[string]map[string]interface{}{
+		"missing required": {"days": 2},
+		"wrong type":       {"city": "Oslo", "days": "two"},
+		"unknown field":    {"city": "Oslo", "wind": true},
+	} {
+		_, err := tl.Execute(context.Background(), params)
+		var te *ToolError
+		if !errors.As(err, &te) || te.Category != ErrorInvalidInput {
+			t.Fatalf("%s: expected invalid input error, got %v", name, err)
+		}
+	}
+	if called {
+		t.Fatalf("function ran with invalid params")
+	}
+
+	res, err := tl.Execute(context.Background(), map[string]interface{}{"city": "Bergen"})
+	if err != nil || res.Output != "Bergen" {
+		t.Fatalf("unexpected result %+v err=%v", res, err)
+	}
+}
+
+func TestFromFuncResultsAndErrors(t *testing.T) {
+	direct := &ToolResult{Success: true, Output: "direct"}
+	tl, err := FromFunc("direct", "", func(ctx context.Context, p struct{}) (*ToolResult, error) {
+		return direct, nil
+	})
+	if err != nil {
+		t.Fatalf("FromFunc: %v", err)
+	}
+	if res, err := tl.Execute(context.Background(), nil); err != nil || res != direct {
+		t.Fatalf("expected result passed through, got %+v err=%v", res, err)
+	}
+
+	boom := errors.New("boom")
+	failing, err := FromFunc("failing", "", func(ctx context.Context, p struct{}) (string, error) {
+		return "", boom
+	})
+	if err != nil {
+		t.Fatalf("FromFunc: %v", err)
+	}
+	if _, err := failing.Execute(context.Background(), nil); !errors.Is(err, boom) {
+		t.Fatalf("expected function error, got %v", err)
+	}
+}
+
+func TestFromFuncRejectsInvalidDefinitions(t *testing.T) {
+	if _, err := FromFunc("", "", func(ctx context.Context, p struct{}) (string, error) { return "", nil }); err == nil {
+		t.Fatalf("expected empty name error")
+	}
+	if _, err := FromFunc[struct{}, string]("nil", "", nil); err == nil {
+		t.Fatalf("expected nil function error")
+	}
+	_, err := FromFunc("scalar", "", func(ctx context.Context, p string) (string, error) { return p, nil })
+	if err == nil || !strings.Contains(err.Error(), "must be a struct") {
+		t.Fatalf("expected struct error, got %v", err)
+	}
+}
+
+type greetTool struct {
+	Name     string `json:"name" jsonschema:"who to greet"`
+	Greeting string `json:"greeting,omitempty"`
+}
+
+func (g *greetTool) Run(ctx context.Context) (*ToolResult, error) {
+	greeting := g.Greeting
+	if greeting == "" {
+		greeting = "hello"
+	}
+	return &ToolResult{Success: true, Output: greeting + " " + g.Name}, nil
+}
+
+func TestFromStruct(t *testing.T) {
+	tl, err := FromStruct[*greetTool]("greet", "say hello")
+	if err != nil {
+		t.Fatalf("FromStruct: %v", err)
+	}
+	if got := tl.Schema().Required; len(got) != 1 || got[0] != "name" {
+		t.Fatalf("unexpected required %v", got)
+	}
+	res, err := tl.Execute(context.Background(), map[string]interface{}{"name": "Ada"})
+	if err != nil || res.Output != "hello Ada" {
+		t.Fatalf("unexpected result %+v err=%v", res, err)
+	}
+	// Each call decodes into a fresh struct.
+	res, err = tl.Execute(context.Background(), map[string]interface{}{"name": "Bob", "greeting": "hi"})
+	if err != nil || res.Output != "hi Bob" {
+		t.Fatalf("unexpected result %+v err=%v", res, err)
+	}
+	res, err = tl.Execute(context.Background(), map[string]interface{}{"name": "Cy"})
+	if err != nil || res.Output != "hello Cy" {
+		t.Fatalf("state leaked between calls: %+v err=%v", res, err)
+	}
+}
+
+func TestFromFuncThroughRegistry(t *testing.T) {
+	tl, err := FromFunc("weather", "", func(ctx context.Context, p weatherParams) (string, error) {
+		return p.City, nil
+	})
+	if err != nil {
+		t.Fatalf("FromFunc: %v", err)
+	}
+	r := NewRegistry()
+	if err := r.Register(tl); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	res, err := r.Execute(context.Background(), "weather", map[string]interface{}{"city": "Rome", "days": float64(2)})
+	if err != nil || res.Output != "Rome" {
+		t.Fatalf("unexpected result %+v err=%v", res, err)
+	}
+}
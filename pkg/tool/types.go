@@ -42,11 +42,15 @@ func (c Call) cloneParams() map[string]any {
 
 // CallResult holds the outcome of executing a Call.
 type CallResult struct {
-	Call        Call
-	Result      *ToolResult
-	Err         error
-	StartedAt   time.Time
-	CompletedAt time.Time
+	Call   Call
+	Result *ToolResult
+	Err    error
+	// Attempts counts executions, including automatic retries.
+	Attempts int
+	// AttemptErrors holds the errors of the attempts that were retried.
+	AttemptErrors []error
+	StartedAt     time.Time
+	CompletedAt   time.Time
 }
 
 // Duration reports how long the execution took. Zero when timestamps are not
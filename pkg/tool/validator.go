@@ -6,6 +6,8 @@ import (
 	"math"
 	"reflect"
 	"regexp"
+	"sort"
+	"strings"
 )
 
 // Validator validates tool parameters before execution.
@@ -13,9 +15,34 @@ type Validator interface {
 	Validate(params map[string]interface{}, schema *JSONSchema) error
 }
 
+// Violation is one way a call's params failed their schema.
+type Violation struct {
+	// Field is the dotted path of the offending value ("user.name",
+	// "people[1]"); empty for the params object itself.
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every Violation found by DefaultValidator, so a
+// model can fix all of its arguments in one retry.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	if e == nil || len(e.Violations) == 0 {
+		return "invalid params"
+	}
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
 // DefaultValidator implements a small subset of JSON Schema validation for tool
 // parameters (required fields, primitive types, nested objects/arrays, enum,
-// pattern, minimum/maximum).
+// pattern, minimum/maximum). Failures are reported as *ValidationError.
 type DefaultValidator struct{}
 
 // Validate ensures that params satisfy the provided schema.
@@ -28,12 +55,22 @@ func (v DefaultValidator) Validate(params map[string]interface{}, schema *JSONSc
 		params = map[string]interface{}{}
 	}
 
-	return v.validateValue(params, schema, "")
+	var violations []Violation
+	v.validateValue(params, schema, "", &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
 }
 
-func (v DefaultValidator) validateValue(value any, schema *JSONSchema, path string) error {
+// validateValue appends the violations of value to out. A value of the wrong
+// type is reported once, without checking its constraints or children.
+func (v DefaultValidator) validateValue(value any, schema *JSONSchema, path string, out *[]Violation) {
 	if schema == nil {
-		return nil
+		return
+	}
+	fail := func(err error) {
+		*out = append(*out, Violation{Field: path, Message: wrapFieldError(path, err).Error()})
 	}
 
 	expectedType := schema.Type
@@ -48,38 +85,46 @@ func (v DefaultValidator) validateValue(value any, schema *JSONSchema, path stri
 
 	if expectedType != "" {
 		if err := validateType(value, expectedType); err != nil {
-			return wrapFieldError(path, err)
+			fail(err)
+			return
 		}
 	}
 
 	if len(schema.Enum) > 0 && !valueInEnum(value, schema.Enum) {
-		return wrapFieldError(path, fmt.Errorf("expected one of %v but got %v", schema.Enum, value))
+		fail(fmt.Errorf("expected one of %v but got %v", schema.Enum, value))
+		return
 	}
 
 	if schema.Pattern != "" {
 		str, ok := value.(string)
 		if !ok {
-			return wrapFieldError(path, fmt.Errorf("expected string but got %T", value))
+			fail(fmt.Errorf("expected string but got %T", value))
+			return
 		}
 		re, err := regexp.Compile(schema.Pattern)
 		if err != nil {
-			return wrapFieldError(path, fmt.Errorf("invalid pattern %q: %w", schema.Pattern, err))
+			fail(fmt.Errorf("invalid pattern %q: %w", schema.Pattern, err))
+			return
 		}
 		if !re.MatchString(str) {
-			return wrapFieldError(path, fmt.Errorf("string %q does not match pattern %q", str, schema.Pattern))
+			fail(fmt.Errorf("string %q does not match pattern %q", str, schema.Pattern))
+			return
 		}
 	}
 
 	if schema.Minimum != nil || schema.Maximum != nil {
 		num, ok := toFloat64(value)
 		if !ok {
-			return wrapFieldError(path, fmt.Errorf("expected number but got %T", value))
+			fail(fmt.Errorf("expected number but got %T", value))
+			return
 		}
 		if schema.Minimum != nil && num < *schema.Minimum {
-			return wrapFieldError(path, fmt.Errorf("value %v is less than minimum %v", num, *schema.Minimum))
+			fail(fmt.Errorf("value %v is less than minimum %v", num, *schema.Minimum))
+			return
 		}
 		if schema.Maximum != nil && num > *schema.Maximum {
-			return wrapFieldError(path, fmt.Errorf("value %v exceeds maximum %v", num, *schema.Maximum))
+			fail(fmt.Errorf("value %v exceeds maximum %v", num, *schema.Maximum))
+			return
 		}
 	}
 
@@ -87,14 +132,21 @@ func (v DefaultValidator) validateValue(value any, schema *JSONSchema, path stri
 	case "object":
 		obj, ok := value.(map[string]interface{})
 		if !ok {
-			return wrapFieldError(path, fmt.Errorf("expected object but got %T", value))
+			fail(fmt.Errorf("expected object but got %T", value))
+			return
 		}
 		for _, field := range schema.Required {
 			if _, exists := obj[field]; !exists {
-				return fmt.Errorf("missing required field: %s", joinPath(path, field))
+				field = joinPath(path, field)
+				*out = append(*out, Violation{Field: field, Message: "missing required field: " + field})
 			}
 		}
-		for key, child := range obj {
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
 			propDef, ok := schema.Properties[key]
 			if !ok {
 				continue
@@ -103,27 +155,22 @@ func (v DefaultValidator) validateValue(value any, schema *JSONSchema, path stri
 			if !ok {
 				continue
 			}
-			if err := v.validateValue(child, propSchema, joinPath(path, key)); err != nil {
-				return err
-			}
+			v.validateValue(obj[key], propSchema, joinPath(path, key), out)
 		}
 	case "array":
 		items := schema.Items
 		if items == nil {
-			return nil
+			return
 		}
 		arr, ok := value.([]interface{})
 		if !ok {
-			return wrapFieldError(path, fmt.Errorf("expected array but got %T", value))
+			fail(fmt.Errorf("expected array but got %T", value))
+			return
 		}
 		for idx, item := range arr {
-			if err := v.validateValue(item, items, indexPath(path, idx)); err != nil {
-				return err
-			}
+			v.validateValue(item, items, indexPath(path, idx), out)
 		}
 	}
-
-	return nil
 }
 
 func schemaFromDefinition(definition interface{}) (*JSONSchema, bool) {
@@ -2,6 +2,7 @@ package tool
 
 import (
 	"encoding/json"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -153,3 +154,33 @@ func TestValidatorMinimumMaximumValidation(t *testing.T) {
 		t.Fatalf("expected minimum failure")
 	}
 }
+
+func TestValidatorReportsEveryViolation(t *testing.T) {
+	t.Parallel()
+
+	min := 1.0
+	schema := &JSONSchema{
+		Type: "object",
+		Properties: map[string]any{
+			"path":  map[string]any{"type": "string"},
+			"mode":  map[string]any{"type": "string", "enum": []any{"read", "write"}},
+			"limit": &JSONSchema{Type: "integer", Minimum: &min},
+		},
+		Required: []string{"path"},
+	}
+	err := DefaultValidator{}.Validate(map[string]any{"mode": "append", "limit": 0.0}, schema)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T %v", err, err)
+	}
+	fields := make([]string, 0, len(verr.Violations))
+	for _, v := range verr.Violations {
+		fields = append(fields, v.Field)
+	}
+	if strings.Join(fields, ",") != "path,limit,mode" {
+		t.Fatalf("unexpected violations %+v", verr.Violations)
+	}
+	if !strings.Contains(err.Error(), "missing required field: path") || !strings.Contains(err.Error(), "field mode: expected one of") {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+}
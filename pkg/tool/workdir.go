@@ -0,0 +1,29 @@
+package tool
+
+import (
+	"context"
+	"strings"
+)
+
+type workDirKey struct{}
+
+// WithWorkDir sets the directory tools default to when a call names none,
+// such as the sub-project a request is scoped to.
+func WithWorkDir(ctx context.Context, dir string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, workDirKey{}, dir)
+}
+
+// WorkDirFromContext returns the directory set by WithWorkDir.
+func WorkDirFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	dir, ok := ctx.Value(workDirKey{}).(string)
+	if !ok || strings.TrimSpace(dir) == "" {
+		return "", false
+	}
+	return dir, true
+}
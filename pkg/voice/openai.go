@@ -0,0 +1,213 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DefaultOpenAIBaseURL is the OpenAI API root used when OpenAIConfig.BaseURL
+// is empty.
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+const (
+	defaultSTTModel    = "whisper-1"
+	defaultTTSModel    = "tts-1"
+	defaultTTSVoice    = "alloy"
+	defaultTTSFormat   = "mp3"
+	maxAudioRespBytes  = 64 << 20
+	maxErrorBodyLength = 512
+)
+
+// OpenAIConfig configures the OpenAI-compatible audio endpoints
+// (/audio/transcriptions and /audio/speech). Servers such as LocalAI,
+// faster-whisper-server or OpenedAI Speech expose the same API.
+type OpenAIConfig struct {
+	BaseURL string // defaults to DefaultOpenAIBaseURL
+	APIKey  string // falls back to OPENAI_API_KEY
+	Model   string // defaults to whisper-1 (STT) or tts-1 (TTS)
+	// Voice and Format apply to speech synthesis; defaults alloy and mp3.
+	Voice  string
+	Format string
+	// Language is an optional ISO-639-1 hint for transcription.
+	Language   string
+	HTTPClient *http.Client
+}
+
+func (c OpenAIConfig) endpoint(path string) string {
+	base := strings.TrimRight(strings.TrimSpace(c.BaseURL), "/")
+	if base == "" {
+		base = DefaultOpenAIBaseURL
+	}
+	return base + path
+}
+
+func (c OpenAIConfig) do(req *http.Request) ([]byte, error) {
+	key := strings.TrimSpace(c.APIKey)
+	if key == "" {
+		key = strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAudioRespBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > maxErrorBodyLength {
+			msg = msg[:maxErrorBodyLength]
+		}
+		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, msg)
+	}
+	return body, nil
+}
+
+type openAISTT struct{ cfg OpenAIConfig }
+
+// NewOpenAISpeechToText returns a SpeechToText backed by
+// POST {BaseURL}/audio/transcriptions.
+func NewOpenAISpeechToText(cfg OpenAIConfig) SpeechToText {
+	if cfg.Model == "" {
+		cfg.Model = defaultSTTModel
+	}
+	return &openAISTT{cfg: cfg}
+}
+
+func (s *openAISTT) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	if len(audio.Data) == 0 {
+		return "", errors.New("voice: audio is empty")
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fields := map[string]string{"model": s.cfg.Model, "response_format": "json"}
+	if s.cfg.Language != "" {
+		fields["language"] = s.cfg.Language
+	}
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			return "", fmt.Errorf("voice: transcribe: %w", err)
+		}
+	}
+	part, err := mw.CreateFormFile("file", "audio"+audioExtension(audio.MediaType))
+	if err != nil {
+		return "", fmt.Errorf("voice: transcribe: %w", err)
+	}
+	if _, err := part.Write(audio.Data); err != nil {
+		return "", fmt.Errorf("voice: transcribe: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return "", fmt.Errorf("voice: transcribe: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.endpoint("/audio/transcriptions"), &body)
+	if err != nil {
+		return "", fmt.Errorf("voice: transcribe: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	raw, err := s.cfg.do(req)
+	if err != nil {
+		return "", fmt.Errorf("voice: transcribe: %w", err)
+	}
+	var out struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return "", fmt.Errorf("voice: transcribe: decode response: %w", err)
+	}
+	return strings.TrimSpace(out.Text), nil
+}
+
+type openAITTS struct{ cfg OpenAIConfig }
+
+// NewOpenAITextToSpeech returns a TextToSpeech backed by
+// POST {BaseURL}/audio/speech.
+func NewOpenAITextToSpeech(cfg OpenAIConfig) TextToSpeech {
+	if cfg.Model == "" {
+		cfg.Model = defaultTTSModel
+	}
+	if cfg.Voice == "" {
+		cfg.Voice = defaultTTSVoice
+	}
+	if cfg.Format == "" {
+		cfg.Format = defaultTTSFormat
+	}
+	return &openAITTS{cfg: cfg}
+}
+
+func (t *openAITTS) Synthesize(ctx context.Context, text string) (Audio, error) {
+	payload, err := json.Marshal(map[string]string{
+		"model":           t.cfg.Model,
+		"input":           text,
+		"voice":           t.cfg.Voice,
+		"response_format": t.cfg.Format,
+	})
+	if err != nil {
+		return Audio{}, fmt.Errorf("voice: synthesize: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.endpoint("/audio/speech"), bytes.NewReader(payload))
+	if err != nil {
+		return Audio{}, fmt.Errorf("voice: synthesize: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	data, err := t.cfg.do(req)
+	if err != nil {
+		return Audio{}, fmt.Errorf("voice: synthesize: %w", err)
+	}
+	return Audio{Data: data, MediaType: formatMediaType(t.cfg.Format), Text: text}, nil
+}
+
+var formatTypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"opus": "audio/ogg",
+	"aac":  "audio/aac",
+	"flac": "audio/flac",
+	"wav":  "audio/wav",
+	"pcm":  "audio/pcm",
+}
+
+func formatMediaType(format string) string {
+	if mt, ok := formatTypes[strings.ToLower(format)]; ok {
+		return mt
+	}
+	return "application/octet-stream"
+}
+
+// audioExtension picks a filename extension the transcription endpoint uses
+// to detect the container format.
+func audioExtension(mediaType string) string {
+	mediaType, _, _ = mime.ParseMediaType(mediaType)
+	switch mediaType {
+	case "audio/mpeg", "audio/mp3":
+		return ".mp3"
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return ".wav"
+	case "audio/ogg", "audio/opus":
+		return ".ogg"
+	case "audio/webm", "video/webm":
+		return ".webm"
+	case "audio/flac":
+		return ".flac"
+	case "audio/mp4", "audio/m4a", "audio/x-m4a":
+		return ".m4a"
+	default:
+		return ".wav"
+	}
+}
@@ -0,0 +1,166 @@
+// Package voice defines speech-to-text and text-to-speech hooks for voice
+// agents. The api runtime transcribes Request.Audio before a run and, when
+// Request.Speak is set, synthesizes the reply: once for Run, or sentence by
+// sentence from text deltas for RunStream.
+package voice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Audio is an encoded audio clip.
+type Audio struct {
+	Data []byte `json:"-"`
+	// MediaType is the MIME type, e.g. "audio/mpeg" or "audio/wav".
+	MediaType string `json:"media_type"`
+	// Text is the transcript or the text the clip was synthesized from.
+	Text string `json:"text,omitempty"`
+}
+
+// SpeechToText transcribes audio into text.
+type SpeechToText interface {
+	Transcribe(ctx context.Context, audio Audio) (string, error)
+}
+
+// TextToSpeech synthesizes speech for text.
+type TextToSpeech interface {
+	Synthesize(ctx context.Context, text string) (Audio, error)
+}
+
+// SpeechToTextFunc adapts a function to SpeechToText.
+type SpeechToTextFunc func(ctx context.Context, audio Audio) (string, error)
+
+// Transcribe implements SpeechToText.
+func (fn SpeechToTextFunc) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	if fn == nil {
+		return "", errors.New("voice: speech-to-text func is nil")
+	}
+	return fn(ctx, audio)
+}
+
+// TextToSpeechFunc adapts a function to TextToSpeech.
+type TextToSpeechFunc func(ctx context.Context, text string) (Audio, error)
+
+// Synthesize implements TextToSpeech.
+func (fn TextToSpeechFunc) Synthesize(ctx context.Context, text string) (Audio, error) {
+	if fn == nil {
+		return Audio{}, errors.New("voice: text-to-speech func is nil")
+	}
+	return fn(ctx, text)
+}
+
+// defaultMinChars keeps very short fragments ("Yes.") from becoming separate
+// synthesis calls.
+const defaultMinChars = 40
+
+// Stream is a streaming TTS adapter: Write text deltas as they arrive and it
+// synthesizes complete sentences in order on a background goroutine, passing
+// each clip to emit. Close flushes the remaining text and waits.
+type Stream struct {
+	ctx  context.Context
+	tts  TextToSpeech
+	emit func(Audio)
+
+	buf     strings.Builder
+	queue   chan string
+	done    chan struct{}
+	errOnce sync.Once
+	err     error
+	closed  bool
+}
+
+// NewStream starts a streaming adapter around tts.
+func NewStream(ctx context.Context, tts TextToSpeech, emit func(Audio)) *Stream {
+	s := &Stream{
+		ctx:   ctx,
+		tts:   tts,
+		emit:  emit,
+		queue: make(chan string, 64),
+		done:  make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *Stream) loop() {
+	defer close(s.done)
+	for text := range s.queue {
+		if s.ctx.Err() != nil {
+			continue
+		}
+		audio, err := s.tts.Synthesize(s.ctx, text)
+		if err != nil {
+			s.errOnce.Do(func() { s.err = err })
+			continue
+		}
+		if audio.Text == "" {
+			audio.Text = text
+		}
+		s.emit(audio)
+	}
+}
+
+// Write buffers delta and queues any complete sentences. It must not be
+// called concurrently or after Close.
+func (s *Stream) Write(delta string) {
+	if s.closed || delta == "" {
+		return
+	}
+	s.buf.WriteString(delta)
+	text := s.buf.String()
+	cut := lastSentenceEnd(text)
+	if cut < defaultMinChars {
+		return
+	}
+	s.enqueue(text[:cut])
+	s.buf.Reset()
+	s.buf.WriteString(text[cut:])
+}
+
+func (s *Stream) enqueue(text string) {
+	if text = strings.TrimSpace(text); text == "" {
+		return
+	}
+	select {
+	case s.queue <- text:
+	case <-s.ctx.Done():
+	}
+}
+
+// Close synthesizes the buffered remainder, waits for pending clips and
+// returns the first synthesis error.
+func (s *Stream) Close() error {
+	if !s.closed {
+		s.closed = true
+		s.enqueue(s.buf.String())
+		s.buf.Reset()
+		close(s.queue)
+	}
+	<-s.done
+	return s.err
+}
+
+// lastSentenceEnd returns the index just past the last sentence terminator
+// that is followed by whitespace, or a paragraph break; -1 when none.
+func lastSentenceEnd(text string) int {
+	end := -1
+	runes := []rune(text)
+	offset := 0
+	for i, r := range runes {
+		size := len(string(r))
+		switch {
+		case r == '\n' && i+1 < len(runes) && runes[i+1] == '\n':
+			end = offset + size
+		case strings.ContainsRune(".!?。！？", r) && i+1 < len(runes) && unicode.IsSpace(runes[i+1]):
+			end = offset + size
+		case strings.ContainsRune("。！？", r):
+			end = offset + size
+		}
+		offset += size
+	}
+	return end
+}
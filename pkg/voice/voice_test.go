@@ -0,0 +1,98 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStreamSynthesizesSentencesInOrder(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		clips  []string
+		inputs []string
+	)
+	tts := TextToSpeechFunc(func(_ context.Context, text string) (Audio, error) {
+		mu.Lock()
+		inputs = append(inputs, text)
+		mu.Unlock()
+		if strings.Contains(text, "fail") {
+			return Audio{}, errors.New("tts down")
+		}
+		return Audio{Data: []byte(text)}, nil
+	})
+	s := NewStream(context.Background(), tts, func(a Audio) {
+		mu.Lock()
+		clips = append(clips, a.Text)
+		mu.Unlock()
+	})
+	reply := "Hi. The deployment finished without errors today. Three services were restarted and all health checks pass. Then fail"
+	for _, r := range reply {
+		s.Write(string(r))
+	}
+	if err := s.Close(); err == nil || err.Error() != "tts down" {
+		t.Fatalf("close err = %v", err)
+	}
+	want := []string{
+		"Hi. The deployment finished without errors today.",
+		"Three services were restarted and all health checks pass.",
+	}
+	if strings.Join(clips, "|") != strings.Join(want, "|") || len(inputs) != 3 || inputs[2] != "Then fail" {
+		t.Fatalf("clips %q inputs %q", clips, inputs)
+	}
+}
+
+func TestOpenAIAudioEndpoints(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/audio/transcriptions":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"text": r.FormValue("model") + ":" + r.FormValue("language") + ":" + header.Filename + ":" + string(data),
+			})
+		case "/v1/audio/speech":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_, _ = io.WriteString(w, body["model"]+"/"+body["voice"]+"/"+body["response_format"]+"/"+body["input"])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := OpenAIConfig{BaseURL: srv.URL + "/v1/", APIKey: "sk-test", Language: "en"}
+	text, err := NewOpenAISpeechToText(cfg).Transcribe(context.Background(), Audio{Data: []byte("RIFF"), MediaType: "audio/mpeg"})
+	if err != nil || text != "whisper-1:en:audio.mp3:RIFF" {
+		t.Fatalf("transcribe = %q, %v", text, err)
+	}
+
+	cfg.Voice, cfg.Format = "nova", "wav"
+	audio, err := NewOpenAITextToSpeech(cfg).Synthesize(context.Background(), "hello")
+	if err != nil || string(audio.Data) != "tts-1/nova/wav/hello" || audio.MediaType != "audio/wav" || audio.Text != "hello" {
+		t.Fatalf("synthesize = %+v, %v", audio, err)
+	}
+
+	cfg.APIKey = "wrong"
+	if _, err := NewOpenAITextToSpeech(cfg).Synthesize(context.Background(), "x"); err == nil || !strings.Contains(err.Error(), "http 401") {
+		t.Fatalf("expected http error, got %v", err)
+	}
+}
@@ -0,0 +1,378 @@
+// Package workspace detects monorepo layouts (go.work, package.json
+// workspaces, Bazel and Cargo workspaces) and maps a path to the sub-project
+// that owns it, so the runtime can scope tool working directories and
+// memory files to the part of the repository a request is about.
+package workspace
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kind names the build system that declared the workspace.
+type Kind string
+
+const (
+	KindGo    Kind = "go"
+	KindNPM   Kind = "npm"
+	KindBazel Kind = "bazel"
+	KindCargo Kind = "cargo"
+)
+
+// Project is one member of a workspace.
+type Project struct {
+	// Name is the module path, package name or Bazel label; it falls back to
+	// the directory name.
+	Name string `json:"name"`
+	// Path is the absolute project directory.
+	Path string `json:"path"`
+	// Rel is Path relative to the workspace root, using forward slashes.
+	Rel string `json:"rel"`
+}
+
+// Info describes a detected workspace.
+type Info struct {
+	Kind Kind `json:"kind"`
+	// Root is the directory holding the workspace manifest.
+	Root string `json:"root"`
+	// Manifest is the file that declared the workspace.
+	Manifest string `json:"manifest"`
+	// Projects lists the declared members sorted by Rel. Bazel workspaces do
+	// not enumerate packages; ProjectFor finds them on demand.
+	Projects []Project `json:"projects,omitempty"`
+}
+
+// detector recognises one manifest in dir.
+type detector func(dir string) (Info, bool)
+
+var detectors = []detector{detectGoWork, detectNPM, detectCargo, detectBazel}
+
+// Detect looks for a workspace manifest in dir and its ancestors and returns
+// the nearest one. The search stops at a repository boundary (a directory
+// containing .git) so unrelated parents are never considered.
+func Detect(dir string) (Info, bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return Info{}, false
+	}
+	for current := abs; ; {
+		for _, detect := range detectors {
+			if info, ok := detect(current); ok {
+				return info, true
+			}
+		}
+		if exists(filepath.Join(current, ".git")) {
+			return Info{}, false
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return Info{}, false
+		}
+		current = parent
+	}
+}
+
+// ProjectFor returns the member containing path, preferring the deepest one
+// when members nest. Relative paths resolve against Root.
+func (i Info) ProjectFor(path string) (Project, bool) {
+	if i.Root == "" {
+		return Project{}, false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(i.Root, path)
+	}
+	path = filepath.Clean(path)
+	if !within(path, i.Root) {
+		return Project{}, false
+	}
+	if i.Kind == KindBazel {
+		return i.bazelPackage(path)
+	}
+	var best Project
+	found := false
+	for _, p := range i.Projects {
+		if within(path, p.Path) && (!found || len(p.Path) > len(best.Path)) {
+			best, found = p, true
+		}
+	}
+	return best, found
+}
+
+// bazelPackage walks from path up to Root looking for the nearest BUILD
+// file; the root package itself does not count as a sub-project.
+func (i Info) bazelPackage(path string) (Project, bool) {
+	dir := path
+	if fi, err := os.Stat(dir); err == nil && !fi.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	for ; dir != i.Root && within(dir, i.Root); dir = filepath.Dir(dir) {
+		if exists(filepath.Join(dir, "BUILD.bazel")) || exists(filepath.Join(dir, "BUILD")) {
+			p := i.project(dir, "")
+			p.Name = "//" + p.Rel
+			return p, true
+		}
+	}
+	return Project{}, false
+}
+
+func (i Info) project(dir, name string) Project {
+	rel, err := filepath.Rel(i.Root, dir)
+	if err != nil {
+		rel = dir
+	}
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+	return Project{Name: name, Path: dir, Rel: filepath.ToSlash(rel)}
+}
+
+// members expands member patterns (directories or globs relative to Root)
+// into projects named by nameOf.
+func (i Info) members(patterns []string, nameOf func(dir string) string) []Project {
+	seen := map[string]struct{}{}
+	var out []Project
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(strings.TrimPrefix(pattern, "./"))
+		if pattern == "" || strings.HasPrefix(pattern, "!") {
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(i.Root, filepath.FromSlash(pattern)))
+		if err != nil {
+			continue
+		}
+		for _, dir := range matches {
+			dir = filepath.Clean(dir)
+			if _, dup := seen[dir]; dup || dir == i.Root || !isDir(dir) {
+				continue
+			}
+			seen[dir] = struct{}{}
+			out = append(out, i.project(dir, nameOf(dir)))
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Rel < out[b].Rel })
+	return out
+}
+
+func detectGoWork(dir string) (Info, bool) {
+	manifest := filepath.Join(dir, "go.work")
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return Info{}, false
+	}
+	info := Info{Kind: KindGo, Root: dir, Manifest: manifest}
+	info.Projects = info.members(parseGoWorkUses(string(data)), goModulePath)
+	return info, true
+}
+
+// parseGoWorkUses returns the directories of use directives, in both the
+// single-line and block forms.
+func parseGoWorkUses(src string) []string {
+	var uses []string
+	inBlock := false
+	for _, line := range strings.Split(src, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			uses = append(uses, strings.Trim(line, `"`))
+		case line == "use (":
+			inBlock = true
+		case strings.HasPrefix(line, "use "):
+			uses = append(uses, strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "use ")), `"`))
+		}
+	}
+	return uses
+}
+
+func goModulePath(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+func detectNPM(dir string) (Info, bool) {
+	manifest := filepath.Join(dir, "package.json")
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return Info{}, false
+	}
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if json.Unmarshal(data, &pkg) != nil || len(pkg.Workspaces) == 0 {
+		return Info{}, false
+	}
+	// Either ["packages/*"] or {"packages": ["packages/*"]} (yarn).
+	var patterns []string
+	if json.Unmarshal(pkg.Workspaces, &patterns) != nil {
+		var obj struct {
+			Packages []string `json:"packages"`
+		}
+		if json.Unmarshal(pkg.Workspaces, &obj) != nil {
+			return Info{}, false
+		}
+		patterns = obj.Packages
+	}
+	if len(patterns) == 0 {
+		return Info{}, false
+	}
+	info := Info{Kind: KindNPM, Root: dir, Manifest: manifest}
+	info.Projects = info.members(patterns, npmPackageName)
+	return info, true
+}
+
+func npmPackageName(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Name string `json:"name"`
+	}
+	_ = json.Unmarshal(data, &pkg)
+	return pkg.Name
+}
+
+func detectCargo(dir string) (Info, bool) {
+	manifest := filepath.Join(dir, "Cargo.toml")
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return Info{}, false
+	}
+	members, ok := parseCargoMembers(string(data))
+	if !ok {
+		return Info{}, false
+	}
+	info := Info{Kind: KindCargo, Root: dir, Manifest: manifest}
+	info.Projects = info.members(members, cargoPackageName)
+	return info, true
+}
+
+// parseCargoMembers reads workspace.members from a Cargo manifest. ok is
+// false when the manifest has no [workspace] table.
+func parseCargoMembers(src string) ([]string, bool) {
+	table := tomlTable(src, "workspace")
+	if table == nil {
+		return nil, false
+	}
+	raw, found := tomlValue(table, "members")
+	if !found {
+		return nil, true
+	}
+	return tomlStrings(raw), true
+}
+
+func cargoPackageName(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "Cargo.toml"))
+	if err != nil {
+		return ""
+	}
+	if raw, ok := tomlValue(tomlTable(string(data), "package"), "name"); ok {
+		if names := tomlStrings(raw); len(names) == 1 {
+			return names[0]
+		}
+	}
+	return ""
+}
+
+// tomlTable returns the lines of [name], or nil when the table is absent.
+// It understands just enough TOML for Cargo manifests.
+func tomlTable(src, name string) []string {
+	var lines []string
+	in := false
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "[[") && strings.HasSuffix(trimmed, "]") && !strings.Contains(trimmed, "=") {
+			if in {
+				break
+			}
+			in = strings.TrimSpace(strings.Trim(trimmed, "[]")) == name
+			if in {
+				lines = []string{}
+			}
+			continue
+		}
+		if in {
+			lines = append(lines, trimmed)
+		}
+	}
+	return lines
+}
+
+// tomlValue returns the raw value of key, joining multi-line arrays.
+func tomlValue(table []string, key string) (string, bool) {
+	for i, line := range table {
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(k) != key {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if strings.HasPrefix(v, "[") {
+			for j := i + 1; !strings.Contains(v, "]") && j < len(table); j++ {
+				v += " " + table[j]
+			}
+		}
+		return v, true
+	}
+	return "", false
+}
+
+// tomlStrings extracts the quoted strings of a TOML value.
+func tomlStrings(raw string) []string {
+	var out []string
+	for {
+		start := strings.IndexAny(raw, `"'`)
+		if start < 0 {
+			return out
+		}
+		quote := raw[start]
+		end := strings.IndexByte(raw[start+1:], quote)
+		if end < 0 {
+			return out
+		}
+		out = append(out, raw[start+1:start+1+end])
+		raw = raw[start+end+2:]
+	}
+}
+
+func detectBazel(dir string) (Info, bool) {
+	for _, name := range []string{"MODULE.bazel", "WORKSPACE.bazel", "WORKSPACE"} {
+		manifest := filepath.Join(dir, name)
+		if exists(manifest) {
+			return Info{Kind: KindBazel, Root: dir, Manifest: manifest}, true
+		}
+	}
+	return Info{}, false
+}
+
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
@@ -0,0 +1,119 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
+
+func TestDetectGoWork(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".git/HEAD":              "",
+		"go.work":                "go 1.24\n\nuse (\n\t./services/api // main service\n\t./libs/util\n)\nuse ./tools\n",
+		"services/api/go.mod":    "module example.com/api\n",
+		"services/api/h/main.go": "package h\n",
+		"libs/util/go.mod":       "module example.com/util\n",
+		"tools/go.mod":           "module example.com/tools\n",
+	})
+
+	info, ok := Detect(filepath.Join(root, "services", "api", "h"))
+	if !ok || info.Kind != KindGo || info.Root != root {
+		t.Fatalf("unexpected detection %+v ok=%v", info, ok)
+	}
+	if len(info.Projects) != 3 {
+		t.Fatalf("projects = %+v", info.Projects)
+	}
+	p, ok := info.ProjectFor("services/api/h/main.go")
+	if !ok || p.Name != "example.com/api" || p.Rel != "services/api" {
+		t.Fatalf("ProjectFor = %+v ok=%v", p, ok)
+	}
+	if _, ok := info.ProjectFor(root); ok {
+		t.Fatal("root should not belong to a member")
+	}
+}
+
+func TestDetectNPMWorkspaces(t *testing.T) {
+	for name, manifest := range map[string]string{
+		"array": `{"name":"mono","workspaces":["packages/*"]}`,
+		"yarn":  `{"name":"mono","workspaces":{"packages":["packages/*"]}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{
+				"package.json":              manifest,
+				"packages/web/package.json": `{"name":"@mono/web"}`,
+				"packages/cli/package.json": `{"name":"@mono/cli"}`,
+			})
+			info, ok := Detect(filepath.Join(root, "packages", "web"))
+			if !ok || info.Kind != KindNPM {
+				t.Fatalf("unexpected detection %+v", info)
+			}
+			p, ok := info.ProjectFor(filepath.Join(root, "packages", "web", "src"))
+			if !ok || p.Name != "@mono/web" {
+				t.Fatalf("ProjectFor = %+v", p)
+			}
+		})
+	}
+}
+
+func TestDetectSkipsPlainPackageJSON(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".git/HEAD":    "",
+		"package.json": `{"name":"single"}`,
+	})
+	if info, ok := Detect(root); ok {
+		t.Fatalf("unexpected workspace %+v", info)
+	}
+}
+
+func TestDetectCargoWorkspace(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"Cargo.toml":             "[workspace]\nmembers = [\n  \"crates/*\",\n]\n\n[workspace.package]\nversion = \"0.1.0\"\n",
+		"crates/core/Cargo.toml": "[package]\nname = \"mono-core\"\n",
+	})
+	info, ok := Detect(root)
+	if !ok || info.Kind != KindCargo || len(info.Projects) != 1 {
+		t.Fatalf("unexpected detection %+v", info)
+	}
+	if p := info.Projects[0]; p.Name != "mono-core" || p.Rel != "crates/core" {
+		t.Fatalf("project = %+v", p)
+	}
+}
+
+func TestDetectBazelPackages(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"MODULE.bazel":         "module(name = \"mono\")\n",
+		"BUILD.bazel":          "",
+		"app/server/BUILD":     "",
+		"app/server/lib/x.go":  "package lib\n",
+		"docs/readme/notes.md": "",
+	})
+	info, ok := Detect(root)
+	if !ok || info.Kind != KindBazel {
+		t.Fatalf("unexpected detection %+v", info)
+	}
+	p, ok := info.ProjectFor("app/server/lib/x.go")
+	if !ok || p.Name != "//app/server" {
+		t.Fatalf("ProjectFor = %+v ok=%v", p, ok)
+	}
+	if _, ok := info.ProjectFor("docs/readme"); ok {
+		t.Fatal("root package should not count as a sub-project")
+	}
+}